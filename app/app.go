@@ -3,26 +3,52 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/abrksh22/bplus/internal/config"
 	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/flags"
+	"github.com/abrksh22/bplus/internal/importer"
 	"github.com/abrksh22/bplus/internal/logging"
 	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/layers/contextmgr"
 	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/layers/plugin"
+	"github.com/abrksh22/bplus/layers/validation"
 	"github.com/abrksh22/bplus/models"
 	"github.com/abrksh22/bplus/models/providers/anthropic"
+	"github.com/abrksh22/bplus/models/providers/cohere"
+	"github.com/abrksh22/bplus/models/providers/compatible"
+	"github.com/abrksh22/bplus/models/providers/deepseek"
 	"github.com/abrksh22/bplus/models/providers/gemini"
 	"github.com/abrksh22/bplus/models/providers/lmstudio"
 	"github.com/abrksh22/bplus/models/providers/ollama"
 	"github.com/abrksh22/bplus/models/providers/openai"
 	"github.com/abrksh22/bplus/models/providers/openrouter"
-	"github.com/abrksh22/bplus/prompts"
+	"github.com/abrksh22/bplus/models/providers/vllm"
+	"github.com/abrksh22/bplus/models/router"
+	"github.com/abrksh22/bplus/models/transport"
 	"github.com/abrksh22/bplus/security"
 	"github.com/abrksh22/bplus/tools"
+	"github.com/abrksh22/bplus/tools/clipboard"
+	dbtool "github.com/abrksh22/bplus/tools/db"
+	"github.com/abrksh22/bplus/tools/docker"
+	"github.com/abrksh22/bplus/tools/docs"
 	"github.com/abrksh22/bplus/tools/exec"
 	"github.com/abrksh22/bplus/tools/file"
+	"github.com/abrksh22/bplus/tools/git"
+	"github.com/abrksh22/bplus/tools/lsp"
+	toolplugin "github.com/abrksh22/bplus/tools/plugin"
+	"github.com/abrksh22/bplus/tools/todo"
+	"github.com/abrksh22/bplus/tools/web"
 )
 
 // Application holds all the components needed to run b+.
@@ -31,10 +57,42 @@ type Application struct {
 	Logger         *logging.Logger
 	DB             *storage.SQLiteDB
 	Provider       models.Provider
+	Router         *router.Router
 	ToolRegistry   *tools.Registry
 	PermManager    *security.PermissionManager
 	Agent          *execution.Agent
 	SessionManager *execution.SessionManager
+	Events         *events.Bus
+	Perf           *models.PerfTracker
+	Context        *contextmgr.Manager
+	Plugins        *plugin.Host
+	Flags          *flags.Set              // Experimental subsystems and whether they are on
+	Substitutions  *router.Substitutions   // Models used this session instead of the configured ones
+	Shells         *exec.ShellSessions     // Persistent shell sessions of core.shell
+	Processes      *exec.ProcessManager    // Background processes of core.bash_background
+	Docker         *docker.Session         // Containers and compose projects the docker tools started
+	LSP            *lsp.Manager            // Language servers of the project; nil if tools.lsp is off
+	Databases      *dbtool.Manager         // Connections of the core.db_* tools; nil if no tools.databases are set
+	ToolPlugins    *toolplugin.Plugins     // WebAssembly tools from the plugins directory
+	Watcher        *file.Watcher           // Files watched for changes made outside b+; nil if it could not start
+	Diagnostics    *validation.Diagnostics // Language server findings of the files edited this session
+	Tests          *validation.TestTracker // Results of the core.test runs this session
+	Project        string                  // Directory the command run history is kept for
+	Offline        bool
+
+	runHooks []RunHook
+	roots    *security.Roots   // Directories attached to the session
+	health   *providerHealth   // Latest connection test of every configured provider
+	trust    *workspaceTrust   // Whether the user trusts the project directory
+	elevated *elevatedApproval // Set by /allow-elevated until the next elevated tool call
+	redactor *models.Redactor  // Patterns scrubbed from prompts for the rest of the session
+	origins  config.Origins    // Where each Config value came from
+	warmUp   *warmUp           // Nil unless a local model is kept loaded
+	backups  *backupSchedule   // Nil unless the database is backed up on a schedule
+	pull     modelPull         // Model download in progress
+	replay   io.Closer         // Nil unless provider traffic is recorded or replayed
+
+	suspension suspension // Whether idle resources have been released
 }
 
 // New creates a new Application with all components initialized.
@@ -54,6 +112,9 @@ func New(opts *Options) (*Application, error) {
 		return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "failed to load configuration")
 	}
 
+	// Load token prices before any provider computes a cost
+	loadPricing(logger)
+
 	// Initialize database
 	dbPath := getDBPath(cfg)
 	db, err := storage.NewSQLiteDB(dbPath)
@@ -61,8 +122,41 @@ func New(opts *Options) (*Application, error) {
 		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to initialize database")
 	}
 
+	db.SetContentLimits(contentLimits(cfg.Session))
+
 	logger.Info("Database initialized", "path", dbPath)
 
+	// In offline mode, force a local model before any provider is created
+	substitutions := router.NewSubstitutions()
+	if opts.Offline {
+		configured := cfg.Models.Default
+		if err := applyOfflineModel(cfg); err != nil {
+			return nil, err
+		}
+		logger.Info("Offline mode enabled", "model", cfg.Models.Default)
+		if cfg.Models.Default != configured {
+			substitutions.Record(router.Substitution{
+				Kind:       router.SubstitutionOffline,
+				Purpose:    "session",
+				Configured: configured,
+				Used:       cfg.Models.Default,
+				Reason:     "offline mode only allows local models",
+			})
+		}
+	}
+
+	// Configure the shared HTTP transport before any provider client is built
+	transport.Configure(transportConfig(cfg.Performance.HTTP))
+
+	// Record or replay provider traffic, if asked to
+	replayer, err := startReplay(opts, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if replayer != nil {
+		logger.Info("Provider traffic replay enabled", "record", opts.Record, "replay", opts.Replay)
+	}
+
 	// Initialize provider
 	provider, err := createProvider(cfg)
 	if err != nil {
@@ -71,58 +165,396 @@ func New(opts *Options) (*Application, error) {
 
 	logger.Info("Provider initialized", "provider", provider.Name())
 
+	// Measure first-token latency and throughput of every streaming call
+	perf := models.NewPerfTracker(perfWindow)
+	seedPerfTracker(perf, db)
+	provider = models.WithStreamMetrics(provider, func(m models.StreamMetrics) {
+		perf.Record(m)
+		if err := recordStreamMetrics(db, m); err != nil {
+			logger.Warn("Failed to store stream metrics", "error", err.Error())
+		}
+	})
+
+	// Shared event bus for the UI and other consumers
+	bus := events.NewBus()
+
 	// Initialize tool registry
+	project := projectDir()
 	toolReg := tools.NewRegistry()
-	if err := registerTools(toolReg); err != nil {
+	shells := exec.NewShellSessions()
+	processes := exec.NewProcessManager()
+	containers := docker.NewSession()
+	var servers *lsp.Manager
+	if cfg.Tools.LSP.Enabled {
+		servers = lsp.NewManager(project, lspOptions(cfg.Tools.LSP)...)
+	}
+	databases := databaseTools(cfg.Tools.Databases)
+	watcher := fileWatcher(bus, logger)
+	deps := toolDeps{
+		offline:    opts.Offline,
+		history:    runHistory{db: db, project: project},
+		profile:    shellProfile(cfg.Tools.Shell),
+		linters:    lintTools(cfg.Tools.Lint),
+		shells:     shells,
+		processes:  processes,
+		containers: containers,
+		servers:    servers,
+		databases:  databases,
+		todos:      todoTool(db, bus),
+		watcher:    watcher,
+	}
+	if err := registerTools(toolReg, deps); err != nil {
 		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to register tools")
 	}
+	// Sub-agents are made from the agent, which is set once it is created
+	taskTool := execution.NewTaskTool()
+	if err := toolReg.Register(taskTool); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to register tools")
+	}
+	toolPlugins := loadToolPlugins(toolReg, logger)
+
+	if err := toolReg.ApplyFilter(cfg.Tools.EnabledTools, cfg.Tools.DisabledTools); err != nil {
+		logger.Warn("Tool filter partially applied", "error", err.Error())
+	}
+
+	logger.Info("Tools registered", "count", len(toolReg.List()), "enabled", len(toolReg.EnabledTools()))
 
-	logger.Info("Tools registered", "count", len(toolReg.List()))
+	// Requests that overflow the model's context window are compacted by
+	// Layer 6 before they are sent, or fail with the number of tokens to trim
+	ctxMgr := contextmgr.NewManager(cfg.Layers.ContextManagement.MaxContextTokens, contextmgr.WithToolCategories(toolCategories(toolReg)), contextmgr.WithModel(cfg.Models.Default))
+	provider = models.WithContextGuard(provider, ctxMgr.Compactor())
+
+	// Content the user redacts is scrubbed from every later prompt
+	redactor := models.NewRedactor()
+	provider = models.WithRedaction(provider, redactor)
+
+	// Initialize router (offline mode is enforced here, not per request)
+	rt := router.NewRouter(map[string]models.Provider{provider.Name(): provider})
+	rt.SetOffline(opts.Offline)
+	if err := rt.CheckModel(cfg.Models.Default); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "model not allowed")
+	}
+	if cfg.Mode == "thorough" {
+		addLayerProviders(rt, cfg, logger)
+	}
+	setQuickPolicy(rt, cfg, logger)
+
+	// The main agent calls tools, so models known not to are rejected now
+	// rather than failing on the first turn
+	rt.SetRequirements(plugin.LayerMainAgent, models.Requirements{Tools: len(toolReg.EnabledTools()) > 0})
+	if err := rt.CheckCapabilities(cfg.Models.Default, rt.Requirements(plugin.LayerMainAgent)); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "model cannot run the main agent")
+	}
 
 	// Initialize permission manager
 	// For Phase 6 MVP, use a simple prompt handler
+	elevated := &elevatedApproval{}
 	promptHandler := func(ctx context.Context, req *security.PermissionRequest) (bool, error) {
 		// TODO: Implement proper prompting in Phase 7
-		// For now, auto-approve in interactive mode
+		// For now, auto-approve in interactive mode, except for requests that
+		// need explicit elevated permission, which only /allow-elevated gives
+		if req.Elevated {
+			if elevated.take() {
+				logger.Warn("Approving elevated tool call allowed once by the user", "tool", req.ToolName, "resource", req.Resource, "reason", req.Reason)
+				return true, nil
+			}
+			logger.Warn("Denying elevated tool call", "tool", req.ToolName, "resource", req.Resource, "reason", req.Reason)
+			return false, nil
+		}
+		if req.Untrusted {
+			logger.Warn("Approving tool call made with low-trust content in context", "tool", req.ToolName, "resource", req.Resource)
+		}
+		if req.Confirm {
+			logger.Info("Approving tool call that needs confirmation each time", "tool", req.ToolName, "resource", req.Resource)
+		}
 		return true, nil
 	}
 	permManager := security.NewPermissionManager(security.ModeInteractive, promptHandler)
+	if opts.Offline {
+		permManager.Block(security.PermissionNetwork)
+	}
+	roots, err := sessionRoots(project, opts.Roots)
+	if err != nil {
+		return nil, err
+	}
+	permManager.SetRoots(roots)
+	trust := loadWorkspaceTrust(project, logger)
+	if err := security.ApplyTrust(trust.level, permManager, roots); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to apply workspace trust")
+	}
 
 	// Create agent configuration
+	systemPrompt, err := personaPrompt(cfg)
+	if err != nil {
+		return nil, err
+	}
 	agentConfig := &execution.AgentConfig{
 		ModelName:     cfg.Models.Default,
-		SystemPrompt:  prompts.GetLayer4Prompt(),
+		SystemPrompt:  systemPrompt,
 		MaxIterations: 10,
-		Temperature:   0.7,
 		MaxTokens:     4096,
 		Streaming:     true,
+		Sampling:      layerSampling(cfg.Layers, plugin.LayerMainAgent),
+
+		ReasoningBudget:  cfg.Layers.MainAgent.ReasoningBudget,
+		MaxContinuations: cfg.Layers.MainAgent.MaxContinuations,
+		MaxParallelTools: cfg.Performance.MaxParallel,
 	}
+	var draftProvider models.Provider
+	agentConfig.DraftModel, draftProvider = draftModel(cfg, rt, provider, redactor, logger)
 
 	// Create agent
 	agent, err := execution.NewAgent(provider, agentConfig, toolReg, permManager)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to create agent")
 	}
+	if draftProvider != nil {
+		agent.SetDraftProvider(draftProvider)
+	}
+
+	if cfg.Cost.BudgetEnabled && cfg.Cost.SessionBudget > 0 {
+		agent.GetCostTracker().SetSessionBudget(execution.Budget{Cost: cfg.Cost.SessionBudget})
+	}
+	taskTool.SetAgent(agent)
+
+	// Show device codes of gateways that need the user to sign in
+	transport.SetDevicePrompt(func(name string, code transport.DeviceCode) {
+		logger.Warn("Sign in to the provider gateway", "provider", name, "url", code.VerificationURI, "code", code.UserCode)
+		bus.Publish(events.SignInRequested{
+			Provider:        name,
+			UserCode:        code.UserCode,
+			VerificationURI: code.VerificationURI,
+			ExpiresAt:       code.ExpiresAt,
+			Time:            time.Now(),
+		})
+	})
+	agent.SetEventBus(bus)
+	agent.SetRoots(roots)
+	transport.Limiter().SetListener(func(s transport.LimitState) {
+		bus.Publish(events.RateLimitUpdated{
+			Host:              s.Host,
+			RequestsLimit:     s.RequestsLimit,
+			RequestsRemaining: s.RequestsRemaining,
+			TokensRemaining:   s.TokensRemaining,
+			BlockedUntil:      s.BlockedUntil,
+			Queued:            s.Queued,
+			Time:              s.Updated,
+		})
+	})
 
 	logger.Info("Agent initialized")
 
 	// Create session manager
 	sessionManager := execution.NewSessionManager(db)
 
-	return &Application{
+	// External layer implementations are started on first use
+	plugins := plugin.NewHost(pluginConfigs(cfg.Layers.Plugins))
+	if layers := plugins.Registered(); len(layers) > 0 {
+		logger.Info("Layer plugins registered", "layers", layers)
+	}
+
+	app := &Application{
 		Config:         cfg,
 		Logger:         logger,
 		DB:             db,
 		Provider:       provider,
+		Router:         rt,
 		ToolRegistry:   toolReg,
 		PermManager:    permManager,
 		Agent:          agent,
 		SessionManager: sessionManager,
-	}, nil
+		Events:         bus,
+		Perf:           perf,
+		Context:        ctxMgr,
+		Plugins:        plugins,
+		Flags:          loadFlags(cfg, logger),
+		Substitutions:  substitutions,
+		Shells:         shells,
+		Processes:      processes,
+		Docker:         containers,
+		LSP:            servers,
+		Databases:      databases,
+		ToolPlugins:    toolPlugins,
+		Watcher:        watcher,
+		Diagnostics:    validation.NewDiagnostics(),
+		Tests:          validation.NewTestTracker(),
+		Project:        project,
+		Offline:        opts.Offline,
+		roots:          roots,
+		health:         newProviderHealth(cfg, rt),
+		trust:          trust,
+		elevated:       elevated,
+		redactor:       redactor,
+		origins:        configOrigins(opts),
+		replay:         replayer,
+	}
+	if cfg.Layers.ContextManagement.Relevance == "model" {
+		ctxMgr.SetScorer(contextmgr.ScorerFunc(app.rateRelevance))
+	}
+	if len(cfg.Tools.FavoriteCommands) > 0 {
+		app.AddRunHook(favoriteCommandsHook(cfg.Tools.FavoriteCommands))
+	}
+	agent.SetAfterToolHook(app.afterTool)
+	if cfg.Mode == "thorough" {
+		app.resolveLayers()
+	}
+
+	// Test every configured provider without delaying startup
+	app.TestProviders()
+
+	// Warn about repositories too large to explore cheaply
+	app.checkRepositorySize()
+
+	// Load a local model now rather than on the first prompt
+	if providerCfg := cfg.Providers[provider.Name()]; providerCfg.Preload {
+		app.startWarmUp(provider, cfg.Models.Default, providerCfg.KeepAlive)
+	} else {
+		app.checkModel(cfg.Models.Default)
+	}
+
+	app.startBackups()
+
+	return app, nil
+}
+
+// GetToolRegistry returns the tool registry, letting the UI toggle tools at runtime.
+func (app *Application) GetToolRegistry() *tools.Registry {
+	return app.ToolRegistry
+}
+
+// GetConfig returns the loaded configuration.
+func (app *Application) GetConfig() *config.Config {
+	return app.Config
+}
+
+// GetEventBus returns the application event bus.
+func (app *Application) GetEventBus() *events.Bus {
+	return app.Events
+}
+
+// CurrentModel returns the model the agent is using.
+func (app *Application) CurrentModel() string {
+	return app.Agent.GetConfig().ModelName
+}
+
+// SetModel switches the agent to another model served by the active provider.
+func (app *Application) SetModel(name string) error {
+	name = models.ResolveAlias(name)
+	providerName, err := models.GetProviderFromModel(name)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeConfigInvalid, "invalid model name")
+	}
+	if providerName != app.Provider.Name() {
+		return errors.Newf(errors.ErrCodeConfigInvalid, "model %s is not served by the active provider %s", name, app.Provider.Name())
+	}
+	if err := app.Router.CheckCapabilities(name, app.Router.Requirements(plugin.LayerMainAgent)); err != nil {
+		return errors.Wrap(err, errors.ErrCodeConfigInvalid, "model not allowed")
+	}
+
+	agentConfig := *app.Agent.GetConfig()
+	agentConfig.ModelName = name
+	app.Agent.UpdateConfig(&agentConfig)
+	app.Config.Models.Default = name
+	app.origins.Runtime["models.default"] = "/models"
+	app.Context.SetModel(name)
+	app.warmModel(name)
+
+	app.Logger.Info("Model changed", "model", name)
+	return nil
+}
+
+// ListModelPerformance lists the active provider's models with their observed
+// streaming performance. The current model is always included.
+func (app *Application) ListModelPerformance(ctx context.Context) ([]models.ModelPerformance, error) {
+	ctx, cancel := context.WithTimeout(ctx, modelListTimeout)
+	defer cancel()
+
+	available, err := app.Provider.ListModels(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeProvider, "failed to list models")
+	}
+
+	current := app.CurrentModel()
+	names := make([]string, 0, len(available)+1)
+	seen := make(map[string]bool, len(available)+1)
+	for _, model := range available {
+		name := models.FormatModelName(app.Provider.Name(), model.ID)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if !seen[current] {
+		names = append([]string{current}, names...)
+	}
+
+	list := make([]models.ModelPerformance, 0, len(names))
+	for _, name := range names {
+		list = append(list, models.ModelPerformance{
+			Model:            name,
+			Aliases:          models.AliasesFor(name),
+			Current:          name == current,
+			PerformanceStats: app.Perf.Stats(name),
+		})
+	}
+	return list, nil
+}
+
+// IsOffline returns whether the application is running in offline mode.
+func (app *Application) IsOffline() bool {
+	return app.Offline
 }
 
 // Close closes all resources.
 func (app *Application) Close() error {
+	app.stopWarmUp()
+	app.stopPull()
+
+	stats := transport.Shared().Stats()
+	app.Logger.Info("HTTP transport stats",
+		"requests", stats.Requests,
+		"reused_conns", stats.ReusedConns,
+		"new_conns", stats.NewConns,
+		"dns_cache_hits", stats.DNSCacheHits)
+	transport.Shared().CloseIdleConnections()
+
+	if app.replay != nil {
+		if err := app.replay.Close(); err != nil {
+			app.Logger.Warn("Failed to finish provider recording", "error", err.Error())
+		}
+	}
+
+	app.Plugins.Close()
+	app.stopBackups()
+
+	if err := app.Shells.Close(); err != nil {
+		app.Logger.Warn("Failed to close shell sessions", "error", err.Error())
+	}
+	if err := app.Processes.Close(); err != nil {
+		app.Logger.Warn("Failed to stop background processes", "error", err.Error())
+	}
+	if err := app.Docker.Close(); err != nil {
+		app.Logger.Warn("Failed to remove containers", "error", err.Error())
+	}
+	if app.LSP != nil {
+		if err := app.LSP.Close(); err != nil {
+			app.Logger.Warn("Failed to stop language servers", "error", err.Error())
+		}
+	}
+	if app.Databases != nil {
+		if err := app.Databases.Close(); err != nil {
+			app.Logger.Warn("Failed to close tool databases", "error", err.Error())
+		}
+	}
+	if app.Watcher != nil {
+		if err := app.Watcher.Close(); err != nil {
+			app.Logger.Warn("Failed to stop file watcher", "error", err.Error())
+		}
+	}
+	if err := app.ToolPlugins.Close(); err != nil {
+		app.Logger.Warn("Failed to close tool plugins", "error", err.Error())
+	}
+
 	if app.DB != nil {
 		if err := app.DB.Close(); err != nil {
 			return errors.Wrap(err, errors.ErrCodeInternal, "failed to close database")
@@ -131,6 +563,41 @@ func (app *Application) Close() error {
 	return nil
 }
 
+// ImportSessions imports conversation history from another tool into the
+// session store. Only configuration and the database are initialized, so no
+// provider credentials are needed. An empty source auto-detects the format.
+func ImportSessions(opts *Options, source, path string) ([]*importer.Result, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "failed to load configuration")
+	}
+
+	conversations, err := importer.ParsePath(source, path)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeValidation, "failed to read history")
+	}
+
+	db, err := storage.NewSQLiteDB(getDBPath(cfg))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to initialize database")
+	}
+	defer db.Close()
+
+	results := make([]*importer.Result, 0, len(conversations))
+	for _, conv := range conversations {
+		result, err := importer.Save(db, conv)
+		if err != nil {
+			return results, errors.Wrapf(err, errors.ErrCodeDatabase, "failed to import %s", conv.SourcePath)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
 // Options holds application initialization options.
 type Options struct {
 	Version    string
@@ -138,6 +605,15 @@ type Options struct {
 	DebugMode  bool
 	FastMode   bool
 	Thorough   bool
+	Offline    bool   // Disable remote providers and web tools
+	NoLSP      bool   // Start no language servers, whatever tools.lsp says
+	Model      string // Default model, or an alias for one
+	Record     string // Cassette file to record provider traffic to
+	Replay     string // Cassette file to replay provider traffic from
+
+	// Directories attached besides the project directory, each given as
+	// "[name=]path[:ro]"
+	Roots []string
 }
 
 // DefaultOptions returns default options.
@@ -157,31 +633,71 @@ func loadConfig(opts *Options) (*config.Config, error) {
 		Mode: "fast",
 		Models: config.ModelConfig{
 			Default: "anthropic/claude-sonnet-4-5",
+			Offline: "ollama/qwen2.5-coder:7b",
+			Aliases: config.DefaultModelAliases(),
 		},
 		Providers: config.ProviderConfigs{
 			"anthropic": config.ProviderConfig{
 				APIKey:  os.Getenv("ANTHROPIC_API_KEY"),
+				APIKeys: envList("ANTHROPIC_API_KEYS"),
 				BaseURL: "https://api.anthropic.com",
 			},
 			"openai": config.ProviderConfig{
 				APIKey:  os.Getenv("OPENAI_API_KEY"),
+				APIKeys: envList("OPENAI_API_KEYS"),
 				BaseURL: "https://api.openai.com/v1",
 			},
 			"gemini": config.ProviderConfig{
 				APIKey:  os.Getenv("GEMINI_API_KEY"),
+				APIKeys: envList("GEMINI_API_KEYS"),
 				BaseURL: "https://generativelanguage.googleapis.com/v1beta",
 			},
 			"openrouter": config.ProviderConfig{
 				APIKey:  os.Getenv("OPENROUTER_API_KEY"),
+				APIKeys: envList("OPENROUTER_API_KEYS"),
 				BaseURL: "https://openrouter.ai/api/v1",
 			},
+			"deepseek": config.ProviderConfig{
+				APIKey:  os.Getenv("DEEPSEEK_API_KEY"),
+				APIKeys: envList("DEEPSEEK_API_KEYS"),
+				BaseURL: "https://api.deepseek.com",
+			},
+			"cohere": config.ProviderConfig{
+				APIKey:  os.Getenv("COHERE_API_KEY"),
+				APIKeys: envList("COHERE_API_KEYS"),
+				BaseURL: "https://api.cohere.com",
+			},
 			"ollama": config.ProviderConfig{
-				BaseURL: "http://localhost:11434",
+				BaseURL:   "http://localhost:11434",
+				Preload:   true,
+				KeepAlive: 10 * time.Minute,
 			},
 			"lmstudio": config.ProviderConfig{
-				BaseURL: "http://localhost:1234/v1",
+				BaseURL:   "http://localhost:1234/v1",
+				Preload:   true,
+				KeepAlive: 10 * time.Minute,
+			},
+			"vllm": config.ProviderConfig{
+				APIKey:  os.Getenv("VLLM_API_KEY"),
+				BaseURL: "http://localhost:8000/v1",
+			},
+		},
+		Tools: config.ToolConfig{
+			Shell: config.ShellConfig{VersionManagers: []string{exec.VersionManagerAuto}},
+			LSP:   config.LSPConfig{Enabled: true, DiagnosticsWait: 3 * time.Second},
+		},
+		Session: config.SessionConfig{
+			IdleSuspend: 30 * time.Minute,
+			Backup: config.BackupConfig{
+				Enabled:  true,
+				Interval: 24 * time.Hour,
+				Keep:     7,
+				MaxAge:   30 * 24 * time.Hour,
 			},
 		},
+		Performance: config.PerformanceConfig{
+			MaxParallel: 4,
+		},
 	}
 
 	// Load from file if specified (TODO: implement config.LoadConfig in Phase 7)
@@ -197,10 +713,37 @@ func loadConfig(opts *Options) (*config.Config, error) {
 	if opts.Thorough {
 		cfg.Mode = "thorough"
 	}
+	if opts.NoLSP {
+		cfg.Tools.LSP.Enabled = false
+	}
+	if opts.Model != "" {
+		cfg.Models.Default = opts.Model
+	}
+
+	// Models are stored by full name; aliases are resolved where they are given
+	models.SetAliases(cfg.Models.Aliases)
+	cfg.Models.Default = models.ResolveAlias(cfg.Models.Default)
+	cfg.Models.Offline = models.ResolveAlias(cfg.Models.Offline)
+	cfg.Models.Quick.Local = models.ResolveAlias(cfg.Models.Quick.Local)
+	cfg.Layers.MainAgent.DraftModel = models.ResolveAlias(cfg.Layers.MainAgent.DraftModel)
+	for layer, model := range cfg.Models.Layers {
+		cfg.Models.Layers[layer] = models.ResolveAlias(model)
+	}
 
 	return cfg, nil
 }
 
+// envList splits a comma-separated environment variable, dropping blanks.
+func envList(name string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 // getDBPath returns the database path from config or default.
 func getDBPath(cfg *config.Config) string {
 	// Default to ~/.local/share/bplus/bplus.db
@@ -213,6 +756,168 @@ func getDBPath(cfg *config.Config) string {
 	return filepath.Join(dataDir, "bplus.db")
 }
 
+// contentLimits builds storage content limits from session settings,
+// keeping defaults for unset values.
+func contentLimits(sessionCfg config.SessionConfig) storage.ContentLimits {
+	limits := storage.DefaultContentLimits()
+	if sessionCfg.CompressThreshold > 0 {
+		limits.CompressThreshold = sessionCfg.CompressThreshold
+	}
+	if sessionCfg.MaxMessageSize > 0 {
+		limits.MaxSize = sessionCfg.MaxMessageSize
+	}
+	return limits
+}
+
+// transportConfig builds the shared transport configuration, keeping
+// defaults for unset values.
+func transportConfig(httpCfg config.HTTPConfig) transport.Config {
+	cfg := transport.DefaultConfig()
+	if httpCfg.MaxIdleConnsPerHost > 0 {
+		cfg.MaxIdleConnsPerHost = httpCfg.MaxIdleConnsPerHost
+	}
+	if httpCfg.IdleConnTimeout > 0 {
+		cfg.IdleConnTimeout = httpCfg.IdleConnTimeout
+	}
+	if httpCfg.KeepAlive > 0 {
+		cfg.KeepAlive = httpCfg.KeepAlive
+	}
+	if httpCfg.DNSCacheTTL > 0 {
+		cfg.DNSCacheTTL = httpCfg.DNSCacheTTL
+	}
+	if httpCfg.DisableHTTP2 {
+		cfg.ForceHTTP2 = false
+	}
+	return cfg
+}
+
+// shellProfile builds the environment shell commands run in.
+func shellProfile(shellCfg config.ShellConfig) *exec.ShellProfile {
+	return &exec.ShellProfile{
+		Shell:           shellCfg.Shell,
+		InitScripts:     shellCfg.InitScripts,
+		Path:            shellCfg.Path,
+		Env:             shellCfg.Env,
+		VersionManagers: shellCfg.VersionManagers,
+	}
+}
+
+// lintTools builds the linters and formatters of the project from
+// configuration.
+func lintTools(lintCfg config.LintConfig) *exec.Linters {
+	custom := make(map[string]exec.Linter, len(lintCfg.Tools))
+	for name, tool := range lintCfg.Tools {
+		custom[name] = exec.Linter{
+			Kind:       tool.Kind,
+			Command:    tool.Command,
+			Fix:        tool.Fix,
+			Check:      tool.Check,
+			Extensions: tool.Extensions,
+			Markers:    tool.Markers,
+		}
+	}
+	return exec.NewLinters(custom, lintCfg.Linters, lintCfg.Formatters)
+}
+
+// databaseTools returns the connections of the configured databases, or
+// nil if there are none.
+func databaseTools(databases map[string]config.DatabaseConfig) *dbtool.Manager {
+	if len(databases) == 0 {
+		return nil
+	}
+	defs := make(map[string]dbtool.Database, len(databases))
+	for name, d := range databases {
+		defs[name] = dbtool.Database{Driver: d.Driver, DSN: d.DSN, Writable: d.Writable}
+	}
+	return dbtool.NewManager(defs)
+}
+
+// Stream performance tracking
+const (
+	perfWindow       = 20 // Samples averaged per model
+	metricStreamTTFT = "ttft_ms"
+	modelListTimeout = 2 * time.Second
+)
+
+// streamMetricDetails is stored as metadata alongside each first-token sample.
+type streamMetricDetails struct {
+	TokensPerSecond float64 `json:"tokens_per_second"`
+	OutputTokens    int     `json:"output_tokens"`
+	DurationMs      int64   `json:"duration_ms"`
+}
+
+// recordStreamMetrics stores one streaming call's performance in the metrics table.
+func recordStreamMetrics(db *storage.SQLiteDB, m models.StreamMetrics) error {
+	if m.Error {
+		return nil
+	}
+	details, err := json.Marshal(streamMetricDetails{
+		TokensPerSecond: m.TokensPerSecond,
+		OutputTokens:    m.OutputTokens,
+		DurationMs:      m.Duration.Milliseconds(),
+	})
+	if err != nil {
+		return err
+	}
+	metadata := string(details)
+	return db.RecordMetric(&storage.Metric{
+		MetricType: metricStreamTTFT,
+		MetricName: &m.Model,
+		Value:      float64(m.TimeToFirstToken.Milliseconds()),
+		Metadata:   &metadata,
+	})
+}
+
+// seedPerfTracker loads recent stream metrics so averages survive restarts.
+func seedPerfTracker(perf *models.PerfTracker, db *storage.SQLiteDB) {
+	stored, err := db.GetRecentMetrics(metricStreamTTFT, perfWindow*50)
+	if err != nil {
+		return
+	}
+	// Oldest first, so the tracker's window keeps the newest samples
+	for i := len(stored) - 1; i >= 0; i-- {
+		metric := stored[i]
+		if metric.MetricName == nil {
+			continue
+		}
+		var details streamMetricDetails
+		if metric.Metadata != nil {
+			_ = json.Unmarshal([]byte(*metric.Metadata), &details)
+		}
+		perf.Record(models.StreamMetrics{
+			Model:            *metric.MetricName,
+			TimeToFirstToken: time.Duration(metric.Value) * time.Millisecond,
+			Duration:         time.Duration(details.DurationMs) * time.Millisecond,
+			OutputTokens:     details.OutputTokens,
+			TokensPerSecond:  details.TokensPerSecond,
+		})
+	}
+}
+
+// applyOfflineModel switches the default model to the configured offline
+// model when the default points at a remote provider.
+func applyOfflineModel(cfg *config.Config) error {
+	providerName, _, err := models.ParseModelName(cfg.Models.Default)
+	if err == nil && models.IsLocalProvider(providerName) {
+		return nil
+	}
+
+	if cfg.Models.Offline == "" {
+		return errors.New(errors.ErrCodeConfigInvalid, "offline mode requires models.offline to name a local model")
+	}
+
+	providerName, _, err = models.ParseModelName(cfg.Models.Offline)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeConfigInvalid, "invalid offline model name")
+	}
+	if !models.IsLocalProvider(providerName) {
+		return errors.Newf(errors.ErrCodeConfigInvalid, "offline model %s is not served by a local provider", cfg.Models.Offline)
+	}
+
+	cfg.Models.Default = cfg.Models.Offline
+	return nil
+}
+
 // createProvider creates the appropriate provider based on configuration.
 func createProvider(cfg *config.Config) (models.Provider, error) {
 	// Parse model name to extract provider
@@ -220,13 +925,41 @@ func createProvider(cfg *config.Config) (models.Provider, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "invalid model name")
 	}
+	return newProvider(cfg, providerName)
+}
 
+// newProvider creates the named provider from its configuration.
+func newProvider(cfg *config.Config, providerName string) (models.Provider, error) {
 	// Get provider config
 	providerCfg, ok := cfg.Providers[providerName]
 	if !ok {
 		return nil, errors.Newf(errors.ErrCodeToolNotFound, "provider %s not configured", providerName)
 	}
 
+	// With several keys configured, the first authenticates the provider and
+	// the rest are rotated in by the HTTP client
+	if keys := providerCfg.Keys(); len(keys) > 0 {
+		providerCfg.APIKey = keys[0]
+	}
+
+	// Behind a gateway the access token authenticates requests and the
+	// provider's own key goes unused, but providers still need one to send
+	if providerCfg.OIDC.Enabled() && providerCfg.APIKey == "" {
+		providerCfg.APIKey = "oidc"
+	}
+
+	// Spend attribution identifier forwarded to providers that support it
+	attribution := cfg.Cost.Attribution.Identifier()
+
+	// Servers configured by type need no code of their own
+	switch providerCfg.Type {
+	case "":
+	case config.ProviderTypeOpenAICompatible:
+		return newCompatibleProvider(providerName, providerCfg)
+	default:
+		return nil, errors.Newf(errors.ErrCodeConfigInvalid, "provider %s: unknown type %s", providerName, providerCfg.Type)
+	}
+
 	// Create provider based on name
 	switch providerName {
 	case "anthropic":
@@ -237,6 +970,16 @@ func createProvider(cfg *config.Config) (models.Provider, error) {
 		if providerCfg.BaseURL != "" {
 			opts = append(opts, anthropic.WithBaseURL(providerCfg.BaseURL))
 		}
+		if attribution != "" {
+			opts = append(opts, anthropic.WithUserID(attribution))
+		}
+		client, err := providerClient(providerName, providerCfg, 60*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, anthropic.WithHTTPClient(client))
+		}
 		return anthropic.New(providerCfg.APIKey, opts...), nil
 
 	case "openai":
@@ -247,6 +990,16 @@ func createProvider(cfg *config.Config) (models.Provider, error) {
 		if providerCfg.BaseURL != "" {
 			opts = append(opts, openai.WithBaseURL(providerCfg.BaseURL))
 		}
+		if attribution != "" {
+			opts = append(opts, openai.WithUser(attribution))
+		}
+		client, err := providerClient(providerName, providerCfg, 60*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, openai.WithHTTPClient(client))
+		}
 		return openai.New(providerCfg.APIKey, opts...), nil
 
 	case "gemini":
@@ -257,6 +1010,13 @@ func createProvider(cfg *config.Config) (models.Provider, error) {
 		if providerCfg.BaseURL != "" {
 			opts = append(opts, gemini.WithBaseURL(providerCfg.BaseURL))
 		}
+		client, err := providerClient(providerName, providerCfg, 60*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, gemini.WithHTTPClient(client))
+		}
 		return gemini.New(providerCfg.APIKey, opts...), nil
 
 	case "openrouter":
@@ -267,8 +1027,58 @@ func createProvider(cfg *config.Config) (models.Provider, error) {
 		if providerCfg.BaseURL != "" {
 			opts = append(opts, openrouter.WithBaseURL(providerCfg.BaseURL))
 		}
+		if attribution != "" {
+			opts = append(opts, openrouter.WithUser(attribution))
+		}
+		if cacheDir, err := config.GetCacheDir(); err == nil {
+			opts = append(opts, openrouter.WithCatalogCache(filepath.Join(cacheDir, "openrouter-models.json"), 0))
+		}
+		client, err := providerClient(providerName, providerCfg, 120*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, openrouter.WithHTTPClient(client))
+		}
 		return openrouter.New(providerCfg.APIKey, opts...), nil
 
+	case "deepseek":
+		if providerCfg.APIKey == "" {
+			return nil, errors.New(errors.ErrCodeConfigInvalid, "DEEPSEEK_API_KEY not set")
+		}
+		var opts []deepseek.Option
+		if providerCfg.BaseURL != "" {
+			opts = append(opts, deepseek.WithBaseURL(providerCfg.BaseURL))
+		}
+		if attribution != "" {
+			opts = append(opts, deepseek.WithUser(attribution))
+		}
+		client, err := providerClient(providerName, providerCfg, 300*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, deepseek.WithHTTPClient(client))
+		}
+		return deepseek.New(providerCfg.APIKey, opts...), nil
+
+	case "cohere":
+		if providerCfg.APIKey == "" {
+			return nil, errors.New(errors.ErrCodeConfigInvalid, "COHERE_API_KEY not set")
+		}
+		var opts []cohere.Option
+		if providerCfg.BaseURL != "" {
+			opts = append(opts, cohere.WithBaseURL(providerCfg.BaseURL))
+		}
+		client, err := providerClient(providerName, providerCfg, 300*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, cohere.WithHTTPClient(client))
+		}
+		return cohere.New(providerCfg.APIKey, opts...), nil
+
 	case "ollama":
 		var opts []ollama.Option
 		baseURL := providerCfg.BaseURL
@@ -276,6 +1086,16 @@ func createProvider(cfg *config.Config) (models.Provider, error) {
 			baseURL = "http://localhost:11434"
 		}
 		opts = append(opts, ollama.WithBaseURL(baseURL))
+		if providerCfg.KeepAlive > 0 {
+			opts = append(opts, ollama.WithKeepAlive(providerCfg.KeepAlive))
+		}
+		client, err := providerClient(providerName, providerCfg, 5*time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, ollama.WithHTTPClient(client))
+		}
 		return ollama.New(opts...), nil
 
 	case "lmstudio":
@@ -285,41 +1105,431 @@ func createProvider(cfg *config.Config) (models.Provider, error) {
 			baseURL = "http://localhost:1234/v1"
 		}
 		opts = append(opts, lmstudio.WithBaseURL(baseURL))
+		if providerCfg.KeepAlive > 0 {
+			opts = append(opts, lmstudio.WithKeepAlive(providerCfg.KeepAlive))
+		}
+		client, err := providerClient(providerName, providerCfg, 300*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, lmstudio.WithHTTPClient(client))
+		}
 		return lmstudio.New(opts...), nil
 
+	case "vllm":
+		var opts []vllm.Option
+		if providerCfg.BaseURL != "" {
+			opts = append(opts, vllm.WithBaseURL(providerCfg.BaseURL))
+		}
+		if providerCfg.APIKey != "" {
+			opts = append(opts, vllm.WithAPIKey(providerCfg.APIKey))
+		}
+		client, err := providerClient(providerName, providerCfg, 300*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			opts = append(opts, vllm.WithHTTPClient(client))
+		}
+		return vllm.New(opts...), nil
+
 	default:
 		return nil, errors.Newf(errors.ErrCodeToolNotFound, "unsupported provider: %s", providerName)
 	}
 }
 
+// compatibleHeaderPrefix starts the extra settings of an openai-compatible
+// provider that are sent as headers, e.g. header-x-team: platform.
+const compatibleHeaderPrefix = "header-"
+
+// newCompatibleProvider creates a provider for a server configured with
+// type openai-compatible. Its extra settings may give context_window, the
+// context length of models the server doesn't report one for, and headers
+// to send.
+func newCompatibleProvider(providerName string, providerCfg config.ProviderConfig) (models.Provider, error) {
+	if providerCfg.BaseURL == "" {
+		return nil, errors.Newf(errors.ErrCodeConfigInvalid, "provider %s: %s providers must set base_url", providerName, config.ProviderTypeOpenAICompatible)
+	}
+	var opts []compatible.Option
+	if providerCfg.APIKey != "" {
+		opts = append(opts, compatible.WithAPIKey(providerCfg.APIKey))
+	}
+	headers := make(map[string]string)
+	for key, value := range providerCfg.Extra {
+		switch {
+		case key == "context_window":
+			tokens, err := strconv.Atoi(value)
+			if err != nil || tokens <= 0 {
+				return nil, errors.Newf(errors.ErrCodeConfigInvalid, "provider %s: context_window must be a positive number of tokens", providerName)
+			}
+			opts = append(opts, compatible.WithContextWindow(tokens))
+		case strings.HasPrefix(key, compatibleHeaderPrefix):
+			headers[strings.TrimPrefix(key, compatibleHeaderPrefix)] = value
+		}
+	}
+	if len(headers) > 0 {
+		opts = append(opts, compatible.WithHeaders(headers))
+	}
+	client, err := providerClient(providerName, providerCfg, 300*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if client != nil {
+		opts = append(opts, compatible.WithHTTPClient(client))
+	}
+	return compatible.New(providerName, providerCfg.BaseURL, opts...), nil
+}
+
+// providerClient returns the HTTP client for a provider: one that spreads
+// requests across the provider's API keys, authenticates them to its
+// gateway and leaves over a transport with its proxy and TLS settings, or
+// nil when none of these apply and the provider's default client will do.
+func providerClient(providerName string, providerCfg config.ProviderConfig, defaultTimeout time.Duration) (*http.Client, error) {
+	keys := providerCfg.Keys()
+	network := transport.Network{
+		ProxyURL:           providerCfg.ProxyURL,
+		CACertPath:         providerCfg.CACertPath,
+		InsecureSkipVerify: providerCfg.InsecureSkipVerify,
+	}
+	if len(keys) < 2 && network.IsZero() && !providerCfg.OIDC.Enabled() {
+		return nil, nil
+	}
+
+	timeout := providerCfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	var rt http.RoundTripper = transport.Limiter()
+	var tr http.RoundTripper
+	if !network.IsZero() {
+		networkTransport, err := transport.NewNetworkTransport(transport.SharedConfig(), network)
+		if err != nil {
+			return nil, errors.Wrapf(err, errors.ErrCodeConfigInvalid, "provider %s network settings", providerName)
+		}
+		tr = networkTransport
+		rt = transport.Route(rt, tr)
+	}
+	if len(keys) >= 2 {
+		rt = transport.NewKeyRotator(rt, keys[0], transport.NewKeyPool(keys, providerCfg.KeyStrategy))
+	}
+	if providerCfg.OIDC.Enabled() {
+		src, err := oidcTokenSource(providerName, providerCfg.OIDC, tr)
+		if err != nil {
+			return nil, err
+		}
+		rt = transport.NewBearerTransport(rt, src)
+	}
+	return &http.Client{Timeout: timeout, Transport: rt}, nil
+}
+
+// oidcTokenSource returns the source of access tokens for a provider's
+// gateway. Requests to the identity provider leave over tr, if not nil, and
+// the refresh token is kept in the OS keychain.
+func oidcTokenSource(providerName string, cfg config.OIDCConfig, tr http.RoundTripper) (*transport.TokenSource, error) {
+	oidc := transport.OIDCConfig{
+		Name:          providerName,
+		Flow:          cfg.Flow,
+		Issuer:        cfg.Issuer,
+		TokenURL:      cfg.TokenURL,
+		DeviceAuthURL: cfg.DeviceAuthURL,
+		ClientID:      cfg.ClientID,
+		ClientSecret:  cfg.ClientSecret,
+		Scopes:        cfg.Scopes,
+		Audience:      cfg.Audience,
+		StoreKey:      "oidc/" + providerName + "/" + cfg.ClientID,
+	}
+	if dir, err := config.GetConfigDir(); err == nil {
+		oidc.Store = security.NewKeychain(filepath.Join(dir, security.SecretsFileName))
+	}
+
+	src, err := transport.NewTokenSource(oidc, &http.Client{Timeout: 30 * time.Second, Transport: tr})
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ErrCodeConfigInvalid, "provider %s OIDC settings", providerName)
+	}
+	return src, nil
+}
+
+// toolDeps are the services the built-in tools are made with.
+type toolDeps struct {
+	offline    bool // Leave out tools in the "web" category
+	history    exec.RunHistory
+	profile    *exec.ShellProfile
+	linters    *exec.Linters
+	shells     *exec.ShellSessions
+	processes  *exec.ProcessManager
+	containers *docker.Session
+	servers    *lsp.Manager    // Nil without language servers
+	databases  *dbtool.Manager // Nil without configured databases
+	todos      *todo.Tool
+	watcher    *file.Watcher // Nil without file watching
+}
+
 // registerTools registers all available tools.
-func registerTools(registry *tools.Registry) error {
+// In offline mode, tools in the "web" category are never registered.
+func registerTools(registry *tools.Registry, deps toolDeps) error {
+	register := func(tool tools.Tool) error {
+		if deps.offline && tool.Category() == "web" {
+			return nil
+		}
+		return registry.Register(tool)
+	}
+
 	// File tools
-	if err := registry.Register(file.NewReadTool()); err != nil {
+	if err := register(file.NewReadTool()); err != nil {
+		return err
+	}
+	if err := register(file.NewWriteTool()); err != nil {
+		return err
+	}
+	if err := register(file.NewEditTool()); err != nil {
 		return err
 	}
-	if err := registry.Register(file.NewWriteTool()); err != nil {
+	if err := register(file.NewBatchWriteTool()); err != nil {
 		return err
 	}
-	if err := registry.Register(file.NewEditTool()); err != nil {
+	if err := register(file.NewPatchTool()); err != nil {
 		return err
 	}
-	if err := registry.Register(file.NewGlobTool()); err != nil {
+	if err := register(file.NewGlobTool()); err != nil {
 		return err
 	}
-	if err := registry.Register(file.NewGrepTool()); err != nil {
+	if err := register(file.NewGrepTool()); err != nil {
 		return err
 	}
+	if err := register(file.NewRepoMapTool()); err != nil {
+		return err
+	}
+	if err := register(file.NewASTGrepTool()); err != nil {
+		return err
+	}
+	if deps.watcher != nil {
+		if err := register(file.NewWatchTool(deps.watcher)); err != nil {
+			return err
+		}
+	}
+
+	// Language server tools
+	if deps.servers != nil {
+		for _, tool := range lsp.Tools(deps.servers) {
+			if err := register(tool); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Task list
+	if err := register(deps.todos); err != nil {
+		return err
+	}
+
+	// Clipboard
+	if err := register(clipboard.NewClipboardTool()); err != nil {
+		return err
+	}
+
+	// Git tools
+	for _, tool := range git.Tools() {
+		if err := register(tool); err != nil {
+			return err
+		}
+	}
 
 	// Exec tools
-	if err := registry.Register(exec.NewBashTool()); err != nil {
+	if err := register(exec.NewBashTool(exec.WithProfile(deps.profile))); err != nil {
+		return err
+	}
+	if err := register(exec.NewRerunTool(deps.history, exec.WithProfile(deps.profile))); err != nil {
+		return err
+	}
+	if err := register(exec.NewShellTool(deps.shells, exec.WithProfile(deps.profile))); err != nil {
+		return err
+	}
+	if err := register(exec.NewTestTool(exec.WithProfile(deps.profile))); err != nil {
+		return err
+	}
+	if err := register(exec.NewLintTool(deps.linters, exec.WithProfile(deps.profile))); err != nil {
+		return err
+	}
+	if err := register(exec.NewFormatTool(deps.linters, exec.WithProfile(deps.profile))); err != nil {
+		return err
+	}
+	if err := register(exec.NewBashBackgroundTool(deps.processes, exec.WithProfile(deps.profile))); err != nil {
+		return err
+	}
+	for _, tool := range []tools.Tool{exec.NewProcessListTool(deps.processes), exec.NewProcessOutputTool(deps.processes), exec.NewProcessKillTool(deps.processes)} {
+		if err := register(tool); err != nil {
+			return err
+		}
+	}
+	if err := register(exec.NewInstallDependencyTool()); err != nil {
+		return err
+	}
+
+	// Docker tools
+	for _, tool := range docker.Tools(deps.containers) {
+		if err := register(tool); err != nil {
+			return err
+		}
+	}
+
+	// Database tools
+	if deps.databases != nil {
+		for _, tool := range dbtool.Tools(deps.databases) {
+			if err := register(tool); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Web tools
+	cacheDir, err := config.GetCacheDir()
+	if err != nil {
+		cacheDir = ""
+	}
+	if err := register(docs.NewLookupTool(cacheDir)); err != nil {
+		return err
+	}
+	if err := register(web.NewFetchTool(cacheDir)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// Execute runs the agent with the given request.
+// Execute runs the agent with the given request. Without explicit history the
+// managed conversation is used and the turn is appended to it; the context is
+// rated against the task the prompt is part of in the background, and only
+// optimized once the turn has ended. Shell commands the agent ran are
+// added to the project's run history, and a turn that changed files to the
+// worklog if it is kept.
 func (app *Application) Execute(ctx context.Context, req *execution.AgentRequest) (*execution.AgentResponse, error) {
-	return app.Agent.Execute(ctx, req)
+	if req == nil {
+		return app.Agent.Execute(ctx, req)
+	}
+	if req.History != nil {
+		turn := *req
+		app.addExternalChanges(&turn)
+		if err := app.runLayerPlugins(ctx, &turn); err != nil {
+			return nil, err
+		}
+		resp, err := app.Agent.Execute(ctx, &turn)
+		if resp != nil {
+			app.recordRuns(turn.SessionID, resp.ToolCalls)
+			app.logWork(&turn, resp)
+		}
+		return resp, err
+	}
+
+	app.Context.BeginTurn()
+	app.Context.SetIntent(req.UserMessage)
+	app.rescoreContext()
+	turn := *req
+	turn.History = app.Context.History()
+	app.addExternalChanges(&turn)
+	if err := app.runLayerPlugins(ctx, &turn); err != nil {
+		app.Context.EndTurn()
+		return nil, err
+	}
+	resp, err := app.Agent.Execute(ctx, &turn)
+	if resp != nil {
+		app.Context.Append(resp.Messages...)
+		app.rescoreContext()
+		app.recordRuns(turn.SessionID, resp.ToolCalls)
+		app.logWork(&turn, resp)
+	}
+
+	if plan, ok := app.Context.EndTurn(); ok {
+		app.Logger.Info("Context optimized", "pruned", len(plan.Prunes), "saved_tokens", plan.Saved())
+	}
+	return resp, err
+}
+
+// preAgentLayers are the layers, in pipeline order, that plugins can
+// implement ahead of the main agent.
+var preAgentLayers = []string{plugin.LayerIntent, plugin.LayerPlanning, plugin.LayerSynthesis}
+
+// runLayerPlugins passes the turn through the plugins registered for layers
+// ahead of the main agent. Each may replace the prompt and add context.
+func (app *Application) runLayerPlugins(ctx context.Context, req *execution.AgentRequest) error {
+	for _, layer := range preAgentLayers {
+		if !app.Plugins.Has(layer) {
+			continue
+		}
+		sampling := layerSampling(app.Config.Layers, layer)
+		out, err := app.Plugins.Run(ctx, layer, plugin.Input{
+			SessionID: req.SessionID,
+			Prompt:    req.UserMessage,
+			History:   req.History,
+			Context:   req.Context,
+			Sampling:  &sampling,
+		})
+		if err != nil {
+			return errors.Wrapf(err, errors.ErrCodeInternal, "%s plugin failed", layer)
+		}
+		if out.Prompt != "" {
+			req.UserMessage = out.Prompt
+		}
+		if out.Context != "" {
+			if req.Context != "" {
+				req.Context += "\n\n"
+			}
+			req.Context += out.Context
+		}
+	}
+	return nil
+}
+
+// layerSampling returns the sampling parameters configured for a layer.
+func layerSampling(layers config.LayerConfig, layer string) models.Sampling {
+	s := layers.Sampling(layer)
+	return models.Sampling{Temperature: s.Temperature, TopP: s.TopP, TopK: s.TopK}
+}
+
+// pluginConfigs converts layer plugin settings to host configs.
+func pluginConfigs(cfgs map[string]config.LayerPluginConfig) map[string]plugin.Config {
+	out := make(map[string]plugin.Config, len(cfgs))
+	for layer, c := range cfgs {
+		out[layer] = plugin.Config{
+			Command: c.Command,
+			Args:    c.Args,
+			Env:     c.Env,
+			Dir:     c.Dir,
+			Timeout: c.Timeout,
+		}
+	}
+	return out
+}
+
+// PreviewOptimization returns what optimizing the context would prune now.
+func (app *Application) PreviewOptimization() contextmgr.Plan {
+	return app.Context.Preview()
+}
+
+// ContextItems describes the conversation context with the provenance of
+// each item.
+func (app *Application) ContextItems() []contextmgr.Item {
+	return app.Context.Items()
+}
+
+// toolCategories looks up tool categories in registry to classify tool output.
+func toolCategories(registry *tools.Registry) contextmgr.CategoryFunc {
+	return func(name string) string {
+		tool, err := registry.Get(name)
+		if err != nil {
+			return ""
+		}
+		return tool.Category()
+	}
+}
+
+// OptimizeContext prunes the conversation context now.
+func (app *Application) OptimizeContext() (contextmgr.Plan, error) {
+	plan, err := app.Context.Optimize()
+	if err != nil {
+		return plan, err
+	}
+	app.Logger.Info("Context optimized", "pruned", len(plan.Prunes), "saved_tokens", plan.Saved())
+	return plan, nil
 }
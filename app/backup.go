@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/storage"
+)
+
+// backupStartDelay is the soonest a scheduled backup runs after startup, so
+// it doesn't compete with loading the session.
+const backupStartDelay = time.Minute
+
+// backupSchedule backs up the database while the application runs.
+type backupSchedule struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startBackups backs up the database every configured interval until
+// Close, rotating old backups out after each one. A backup missed while
+// bplus wasn't running is taken shortly after startup.
+func (app *Application) startBackups() {
+	cfg := app.Config.Session.Backup
+	if !cfg.Enabled || cfg.Interval <= 0 || app.DB == nil {
+		return
+	}
+	dir := backupDir(app.Config)
+
+	wait := cfg.Interval
+	if backups, err := storage.ListBackups(dir); err == nil && len(backups) > 0 {
+		wait = time.Until(backups[0].Time.Add(cfg.Interval))
+	}
+	wait = max(wait, backupStartDelay)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &backupSchedule{cancel: cancel, done: make(chan struct{})}
+	app.backups = b
+
+	go func() {
+		defer close(b.done)
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			path, removed, err := backUp(app.DB, app.Config)
+			if err != nil {
+				app.Logger.Warn("Scheduled backup failed", "error", err.Error())
+			} else {
+				app.Logger.Info("Database backed up", "path", path, "rotated", len(removed))
+			}
+			timer.Reset(cfg.Interval)
+		}
+	}()
+}
+
+// stopBackups stops the schedule, waiting for a backup in progress so the
+// database isn't closed under it.
+func (app *Application) stopBackups() {
+	if app.backups != nil {
+		app.backups.cancel()
+		<-app.backups.done
+	}
+}
+
+// backUp takes a backup of db and rotates the old ones out. It returns the
+// backup's path and the paths of the backups removed.
+func backUp(db *storage.SQLiteDB, cfg *config.Config) (string, []string, error) {
+	dir := backupDir(cfg)
+	now := time.Now()
+	path := filepath.Join(dir, storage.BackupName(now))
+	if err := db.Backup(path); err != nil {
+		return "", nil, errors.Wrap(err, errors.ErrCodeDatabase, "failed to back up database")
+	}
+	removed, err := storage.RotateBackups(dir, cfg.Session.Backup.Keep, cfg.Session.Backup.MaxAge, now)
+	if err != nil {
+		return path, removed, errors.Wrap(err, errors.ErrCodeFile, "failed to rotate backups")
+	}
+	return path, removed, nil
+}
+
+// backupDir returns the directory backups are kept in: session.backup.dir,
+// or a backups directory beside the database.
+func backupDir(cfg *config.Config) string {
+	dir := cfg.Session.Backup.Dir
+	if dir == "" {
+		return filepath.Join(filepath.Dir(getDBPath(cfg)), "backups")
+	}
+	if rest, ok := strings.CutPrefix(dir, "~"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, rest)
+		}
+	}
+	return dir
+}
+
+// BackupNow backs up the session database and rotates old backups out, as
+// the schedule does. Only configuration and the database are initialized.
+func BackupNow(opts *Options) (string, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeConfigInvalid, "failed to load configuration")
+	}
+
+	db, err := storage.NewSQLiteDB(getDBPath(cfg))
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeInternal, "failed to initialize database")
+	}
+	defer db.Close()
+
+	path, _, err := backUp(db, cfg)
+	return path, err
+}
+
+// ListBackups returns the backups of the session database, newest first.
+func ListBackups(opts *Options) ([]storage.BackupFile, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "failed to load configuration")
+	}
+
+	backups, err := storage.ListBackups(backupDir(cfg))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeFile, "failed to list backups")
+	}
+	return backups, nil
+}
+
+// RestoreBackup replaces the session database with a backup, given as a
+// path or as the name of a file in the backup directory. The backup is
+// verified first, and the current database is backed up before it is
+// replaced; that backup's path is returned. bplus must not be running.
+func RestoreBackup(opts *Options, file string) (string, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeConfigInvalid, "failed to load configuration")
+	}
+
+	source := file
+	if _, err := os.Stat(source); err != nil && !strings.ContainsRune(file, filepath.Separator) {
+		source = filepath.Join(backupDir(cfg), file)
+	}
+	if err := storage.VerifyBackup(source); err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeValidation, "backup failed verification; the database was not changed")
+	}
+
+	dbPath := getDBPath(cfg)
+	db, err := storage.NewSQLiteDB(dbPath)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrCodeInternal, "failed to initialize database")
+	}
+	saved := filepath.Join(backupDir(cfg), storage.BackupName(time.Now()))
+	if err := db.Backup(saved); err != nil {
+		db.Close()
+		return "", errors.Wrap(err, errors.ErrCodeDatabase, "failed to back up the current database; the database was not changed")
+	}
+	if err := db.Close(); err != nil {
+		return saved, errors.Wrap(err, errors.ErrCodeInternal, "failed to close database")
+	}
+
+	if err := storage.RestoreFile(source, dbPath); err != nil {
+		return saved, errors.Wrap(err, errors.ErrCodeDatabase, "failed to restore database")
+	}
+	return saved, nil
+}
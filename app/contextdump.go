@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"io"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/parquet"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/layers/contextmgr"
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/tools"
+	"github.com/abrksh22/bplus/tools/docker"
+	"github.com/abrksh22/bplus/tools/exec"
+	"github.com/abrksh22/bplus/tools/lsp"
+)
+
+// ContextDump is the context of a stored session as the optimizer sees it.
+type ContextDump struct {
+	Session       string            `json:"session"`
+	Model         string            `json:"model"`
+	Tokens        int               `json:"tokens"`
+	MaxTokens     int               `json:"max_tokens"`
+	KeepRecent    int               `json:"keep_recent"`
+	MaxToolOutput int               `json:"max_tool_output"`
+	TokensAfter   int               `json:"tokens_after_optimization"`
+	Tiers         map[string]int    `json:"tiers"` // Tokens per tier
+	Trust         map[string]int    `json:"trust"` // Tokens per trust level
+	Items         []ContextDumpItem `json:"items"`
+}
+
+// ContextDumpItem is one message of a context dump.
+type ContextDumpItem struct {
+	Index     int     `json:"index"`
+	Role      string  `json:"role"`
+	Name      string  `json:"name,omitempty"`
+	Tokens    int     `json:"tokens"`
+	Tier      string  `json:"tier"`
+	Relevance float64 `json:"relevance"`
+	Source    string  `json:"source"`
+	Origin    string  `json:"origin,omitempty"`
+	Trust     string  `json:"trust"`
+}
+
+// DumpContext rebuilds the context of a stored session and describes each
+// item with its tier, relevance and token count, using the configured model
+// and optimizer thresholds.
+func DumpContext(opts *Options, sessionID string) (*ContextDump, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "failed to load configuration")
+	}
+
+	db, err := storage.NewSQLiteDB(getDBPath(cfg))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to initialize database")
+	}
+	defer db.Close()
+
+	if _, err := db.GetSession(sessionID); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseNotFound, "failed to find session")
+	}
+	messages, err := execution.NewSessionManager(db).GetMessages(context.Background(), sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tool categories classify tool output, so look them up as a session would
+	toolReg := tools.NewRegistry()
+	deps := toolDeps{
+		offline:    opts.Offline,
+		history:    runHistory{db: db, project: projectDir()},
+		profile:    shellProfile(cfg.Tools.Shell),
+		linters:    lintTools(cfg.Tools.Lint),
+		shells:     exec.NewShellSessions(),
+		processes:  exec.NewProcessManager(),
+		containers: docker.NewSession(),
+		servers:    lsp.NewManager(projectDir()),
+		databases:  databaseTools(cfg.Tools.Databases),
+		todos:      todoTool(db, nil),
+	}
+	if err := registerTools(toolReg, deps); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to register tools")
+	}
+
+	mgr := contextmgr.NewManager(cfg.Layers.ContextManagement.MaxContextTokens, contextmgr.WithToolCategories(toolCategories(toolReg)), contextmgr.WithModel(cfg.Models.Default))
+	mgr.Append(messages...)
+	keepRecent, maxToolOutput := mgr.Thresholds()
+	plan := mgr.Preview()
+
+	dump := &ContextDump{
+		Session:       sessionID,
+		Model:         cfg.Models.Default,
+		Tokens:        mgr.Tokens(),
+		MaxTokens:     mgr.MaxTokens(),
+		KeepRecent:    keepRecent,
+		MaxToolOutput: maxToolOutput,
+		TokensAfter:   plan.TokensAfter,
+		Tiers:         make(map[string]int),
+		Trust:         make(map[string]int),
+	}
+	for _, item := range mgr.Items() {
+		dump.Items = append(dump.Items, ContextDumpItem{
+			Index:     item.Index,
+			Role:      item.Role,
+			Name:      item.Name,
+			Tokens:    item.Tokens,
+			Tier:      string(item.Tier),
+			Relevance: item.Relevance,
+			Source:    string(item.Provenance.Source),
+			Origin:    item.Provenance.Origin,
+			Trust:     item.Provenance.Trust.String(),
+		})
+		dump.Tiers[string(item.Tier)] += item.Tokens
+		dump.Trust[item.Provenance.Trust.String()] += item.Tokens
+	}
+	return dump, nil
+}
+
+// contextDumpColumns are the Parquet columns of a context dump, one row per item.
+var contextDumpColumns = []parquet.Column{
+	{Name: "session", Type: parquet.String},
+	{Name: "model", Type: parquet.String},
+	{Name: "index", Type: parquet.Int64},
+	{Name: "role", Type: parquet.String},
+	{Name: "name", Type: parquet.String},
+	{Name: "tokens", Type: parquet.Int64},
+	{Name: "tier", Type: parquet.String},
+	{Name: "relevance", Type: parquet.Double},
+	{Name: "source", Type: parquet.String},
+	{Name: "origin", Type: parquet.String},
+	{Name: "trust", Type: parquet.String},
+	{Name: "keep_recent", Type: parquet.Int64},
+	{Name: "max_tool_output", Type: parquet.Int64},
+}
+
+// WriteParquet writes the dump's items as a Parquet table. The thresholds
+// are repeated on each row so that dumps taken under different settings can
+// be compared in one query.
+func (d *ContextDump) WriteParquet(w io.Writer) error {
+	rows := make([][]interface{}, len(d.Items))
+	for i, item := range d.Items {
+		rows[i] = []interface{}{
+			d.Session, d.Model, item.Index, item.Role, item.Name, item.Tokens,
+			item.Tier, item.Relevance, item.Source, item.Origin, item.Trust,
+			d.KeepRecent, d.MaxToolOutput,
+		}
+	}
+	return parquet.Write(w, contextDumpColumns, rows)
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/layers/plugin"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/router"
+)
+
+// layerCheckTimeout bounds the health checks of all layers at startup.
+const layerCheckTimeout = 30 * time.Second
+
+// thoroughLayers returns the models of each enabled Thorough Mode layer,
+// keyed by layer name. The main agent and context management layers run on
+// the session model and are not included.
+func thoroughLayers(cfg *config.Config) map[string][]string {
+	layers := make(map[string][]string)
+	l := cfg.Layers
+	if l.IntentClarification.Enabled && l.IntentClarification.Model != "" {
+		layers[plugin.LayerIntent] = []string{l.IntentClarification.Model}
+	}
+	if l.ParallelPlanning.Enabled && len(l.ParallelPlanning.Models) > 0 {
+		layers[plugin.LayerPlanning] = l.ParallelPlanning.Models
+	}
+	if l.Synthesis.Enabled && l.Synthesis.Model != "" {
+		layers[plugin.LayerSynthesis] = []string{l.Synthesis.Model}
+	}
+	if l.Validation.Enabled && l.Validation.Model != "" {
+		layers[plugin.LayerValidation] = []string{l.Validation.Model}
+	}
+	return layers
+}
+
+// addLayerProviders registers with rt the providers of layer models and
+// their substitutes. Providers that cannot be created, such as ones without
+// an API key, are left out, so their models fail the health check.
+func addLayerProviders(rt *router.Router, cfg *config.Config, logger *logging.Logger) {
+	var candidates []string
+	for layer, layerModels := range thoroughLayers(cfg) {
+		candidates = append(candidates, layerModels...)
+		candidates = append(candidates, cfg.Layers.DegradationOrder(layer)...)
+	}
+
+	for _, model := range candidates {
+		providerName, _, err := models.ParseModelName(model)
+		if err != nil || (rt.IsOffline() && !models.IsLocalProvider(providerName)) {
+			continue
+		}
+		if _, ok := rt.Providers()[providerName]; ok {
+			continue
+		}
+		provider, err := newProvider(cfg, providerName)
+		if err != nil {
+			logger.Warn("Layer provider unavailable", "provider", providerName, "error", err.Error())
+			continue
+		}
+		rt.AddProvider(provider)
+	}
+}
+
+// resolveLayers health-checks the models of every enabled Thorough Mode
+// layer in the background and warns about layers that will run on a
+// substitute or be skipped.
+func (app *Application) resolveLayers() {
+	layers := thoroughLayers(app.Config)
+	if len(layers) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), layerCheckTimeout)
+		defer cancel()
+		for _, layer := range plugin.Layers {
+			for _, model := range layers[layer] {
+				// Failures are logged and published by ResolveLayer
+				_, _ = app.ResolveLayer(ctx, layer, model)
+			}
+		}
+	}()
+}
+
+// ResolveLayer returns the model to run a Thorough Mode layer with, checking
+// the configured model's health first. An unavailable model is replaced per
+// the layer's degradation order, or the layer is skipped when the order ends
+// in "skip"; either way a warning is logged and published. An error means
+// the layer can neither run nor be skipped.
+func (app *Application) ResolveLayer(ctx context.Context, layer, model string) (router.Resolution, error) {
+	res, err := app.Router.ResolveLayer(ctx, layer, model, app.Config.Layers.DegradationOrder(layer))
+	if err == nil && !res.Degraded() {
+		return res, nil
+	}
+
+	reason := strings.Join(res.Failures, "; ")
+	switch {
+	case err != nil:
+		app.Logger.Error("Layer unavailable", err, "layer", layer, "model", model)
+	case res.Skipped:
+		app.Logger.Warn("Layer skipped, model unavailable", "layer", layer, "model", model, "reason", reason)
+	default:
+		app.Logger.Warn("Layer model substituted", "layer", layer, "model", model, "substitute", res.Model, "reason", reason)
+	}
+	app.Substitutions.Record(router.Substitution{
+		Kind:       router.SubstitutionDegraded,
+		Purpose:    "layer " + layer,
+		Configured: model,
+		Used:       res.Model,
+		Reason:     reason,
+	})
+	if app.Events != nil {
+		app.Events.Publish(events.LayerDegraded{
+			Layer:      layer,
+			Configured: model,
+			Model:      res.Model,
+			Skipped:    res.Skipped,
+			Reason:     reason,
+			Time:       time.Now(),
+		})
+	}
+
+	if err != nil {
+		return res, errors.Wrapf(err, errors.ErrCodeProvider, "layer %s unavailable", layer)
+	}
+	return res, nil
+}
+
+// ModelSubstitutions returns the models used this session instead of the
+// configured ones, and why.
+func (app *Application) ModelSubstitutions() []router.Substitution {
+	return app.Substitutions.List()
+}
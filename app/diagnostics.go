@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/layers/validation"
+	"github.com/abrksh22/bplus/tools/lsp"
+)
+
+// maxEditDiagnostics is how many language server errors are shown to the
+// model after an edit; the rest are counted.
+const maxEditDiagnostics = 20
+
+// lspOptions builds the language server manager options from config.
+func lspOptions(lspCfg config.LSPConfig) []lsp.Option {
+	servers := make(map[string]lsp.ServerConfig, len(lspCfg.Servers))
+	for name, server := range lspCfg.Servers {
+		servers[name] = lsp.ServerConfig{
+			Command:    server.Command,
+			Args:       server.Args,
+			Env:        server.Env,
+			Extensions: server.Extensions,
+		}
+	}
+	return []lsp.Option{lsp.WithServers(servers), lsp.WithDiagnosticsWait(lspCfg.DiagnosticsWait)}
+}
+
+// checkEdits runs after each tool call: once a call edits files with a
+// language server, their diagnostics are fetched and recorded for the
+// validation layer, and the errors are returned so the model sees what
+// its edit broke straight away.
+func (app *Application) checkEdits(ctx context.Context, call execution.ToolExecution) string {
+	var paths []string
+	for _, path := range editedPaths(call) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if app.LSP.Serves(path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return ""
+	}
+
+	diags, err := app.LSP.Diagnose(ctx, paths, 0)
+	if err != nil {
+		app.Logger.Debug("Language server diagnostics incomplete", "error", err.Error())
+	}
+
+	files := make([]string, 0, len(diags))
+	for path := range diags {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	var lines []string
+	errorCount := 0
+	for _, path := range files {
+		rel := path
+		if r, err := filepath.Rel(app.Project, path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = filepath.ToSlash(r)
+		}
+		findings := make([]validation.Finding, 0, len(diags[path]))
+		for _, d := range diags[path] {
+			f := diagnosticFinding(rel, d)
+			findings = append(findings, f)
+			if f.Severity != validation.SeverityError {
+				continue
+			}
+			errorCount++
+			if len(lines) < maxEditDiagnostics {
+				lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", rel, f.Line, f.Column, f.Message))
+			}
+		}
+		app.Diagnostics.Update(rel, findings)
+	}
+	if errorCount == 0 {
+		return ""
+	}
+
+	note := fmt.Sprintf("Language server reports %d error(s) after this edit:\n%s", errorCount, strings.Join(lines, "\n"))
+	if errorCount > len(lines) {
+		note += fmt.Sprintf("\n... and %d more", errorCount-len(lines))
+	}
+	return note
+}
+
+// diagnosticFinding converts a language server diagnostic of the file at
+// rel into a validation finding.
+func diagnosticFinding(rel string, d lsp.Diagnostic) validation.Finding {
+	severity := validation.SeverityNotice
+	switch d.Severity {
+	case lsp.SeverityError, 0:
+		severity = validation.SeverityError
+	case lsp.SeverityWarning:
+		severity = validation.SeverityWarning
+	}
+	check := "lsp"
+	if d.Source != "" {
+		check += ":" + d.Source
+	}
+	return validation.Finding{
+		Check:    check,
+		Severity: severity,
+		Message:  d.Message,
+		Path:     rel,
+		Line:     d.Range.Start.Line + 1,
+		Column:   d.Range.Start.Character + 1,
+	}
+}
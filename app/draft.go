@@ -0,0 +1,38 @@
+package app
+
+import (
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/router"
+)
+
+// draftModel returns the model drafting answers for the main agent, and its
+// provider when it isn't p, the agent's own. The draft provider scrubs what
+// redactor does, like p. Drafts are turned off with a warning for a model
+// that isn't allowed or whose provider cannot be created.
+func draftModel(cfg *config.Config, rt *router.Router, p models.Provider, redactor *models.Redactor, logger *logging.Logger) (string, models.Provider) {
+	model := cfg.Layers.MainAgent.DraftModel
+	if model == "" {
+		return "", nil
+	}
+	if err := rt.CheckModel(model); err != nil {
+		logger.Warn("Draft model not allowed, answering without drafts", "model", model, "error", err.Error())
+		return "", nil
+	}
+	providerName, _, err := models.ParseModelName(model)
+	if err != nil {
+		logger.Warn("Invalid draft model, answering without drafts", "model", model, "error", err.Error())
+		return "", nil
+	}
+	if providerName == p.Name() {
+		return model, nil
+	}
+
+	provider, err := newProvider(cfg, providerName)
+	if err != nil {
+		logger.Warn("Draft provider unavailable, answering without drafts", "provider", providerName, "error", err.Error())
+		return "", nil
+	}
+	return model, models.WithRedaction(provider, redactor)
+}
@@ -0,0 +1,22 @@
+package app
+
+import "sync/atomic"
+
+// elevatedApproval is the user's approval of the next elevated tool call,
+// such as a command printing a .env file. The interactive prompt handler
+// cannot ask about such calls, so they are denied unless approved this way.
+type elevatedApproval struct {
+	allowed atomic.Bool
+}
+
+// take uses up the approval, reporting whether there was one.
+func (e *elevatedApproval) take() bool {
+	return e.allowed.CompareAndSwap(true, false)
+}
+
+// AllowElevatedOnce approves the next tool call that needs elevated
+// permission. The approval is used up by that call; later ones are denied
+// again.
+func (app *Application) AllowElevatedOnce() {
+	app.elevated.allowed.Store(true)
+}
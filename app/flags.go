@@ -0,0 +1,40 @@
+package app
+
+import (
+	"os"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/flags"
+	"github.com/abrksh22/bplus/internal/logging"
+)
+
+// loadFlags resolves the experimental flags from the config and the
+// environment, logging flags that are unknown or deprecated.
+func loadFlags(cfg *config.Config, logger *logging.Logger) *flags.Set {
+	set, warnings := flags.New(flags.Registry, cfg.Experimental, os.Environ())
+	for _, w := range warnings {
+		logger.Warn("Experimental flag ignored or deprecated", "warning", w)
+	}
+	for _, state := range set.States() {
+		if state.Enabled && state.Stage == flags.StageExperimental {
+			logger.Info("Experimental flag on", "flag", state.Name, "source", string(state.Source))
+		}
+	}
+	return set
+}
+
+// FeatureFlags returns every experimental flag and whether it is on.
+func (app *Application) FeatureFlags() []flags.State {
+	return app.Flags.States()
+}
+
+// ToggleFeatureFlag turns an experimental flag on or off for the rest of
+// the session. Only the config file or the environment keep it.
+func (app *Application) ToggleFeatureFlag(name string, enabled bool) error {
+	if err := app.Flags.Toggle(name, enabled); err != nil {
+		return errors.Wrap(err, errors.ErrCodeUser, "cannot toggle flag")
+	}
+	app.Logger.Info("Experimental flag toggled", "flag", name, "enabled", enabled)
+	return nil
+}
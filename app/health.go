@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/router"
+)
+
+// providerCheckTimeout bounds one round of provider connection tests.
+const providerCheckTimeout = 15 * time.Second
+
+// providerHealth holds the latest connection test of every configured
+// provider. Providers that cannot be created, e.g. for a missing API key,
+// are reported as failed without being tested.
+type providerHealth struct {
+	registry *models.Registry
+	setup    []models.ProviderHealth // Providers that could not be created
+
+	mu       sync.Mutex
+	results  []models.ProviderHealth
+	checking bool
+}
+
+// newProviderHealth collects the providers to test: those the router
+// already uses, and every other configured one. In offline mode only local
+// providers are included.
+func newProviderHealth(cfg *config.Config, rt *router.Router) *providerHealth {
+	h := &providerHealth{registry: models.NewRegistry()}
+	routed := rt.Providers()
+
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		if !rt.IsOffline() || models.IsLocalProvider(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		provider, ok := routed[name]
+		if !ok {
+			var err error
+			if provider, err = newProvider(cfg, name); err != nil {
+				h.setup = append(h.setup, models.ProviderHealth{Provider: name, Auth: models.AuthFailed, Err: err})
+				continue
+			}
+		}
+		_ = h.registry.Register(provider)
+	}
+	return h
+}
+
+// ProviderHealth returns the latest connection test of every configured
+// provider, sorted by name, and whether a test is running.
+func (app *Application) ProviderHealth() ([]models.ProviderHealth, bool) {
+	h := app.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]models.ProviderHealth(nil), h.results...), h.checking
+}
+
+// TestProviders tests the connection of every configured provider in the
+// background, unless a test is already running. events.ProvidersChecked is
+// published when it is done.
+func (app *Application) TestProviders() {
+	h := app.health
+	h.mu.Lock()
+	if h.checking {
+		h.mu.Unlock()
+		return
+	}
+	h.checking = true
+	h.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), providerCheckTimeout)
+		defer cancel()
+
+		results := h.registry.CheckAll(ctx)
+		for _, failed := range h.setup {
+			failed.CheckedAt = time.Now()
+			results = append(results, failed)
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].Provider < results[j].Provider })
+
+		healthy := 0
+		for _, r := range results {
+			switch {
+			case r.Healthy():
+				healthy++
+				// Spares quick tasks a probe of their own
+				app.Router.ObserveLatency(r.Provider, r.Latency)
+			case r.Provider == app.Provider.Name():
+				app.Logger.Warn("Active provider failed its connection test", "provider", r.Provider, "error", r.Err.Error())
+			}
+		}
+		app.Logger.Info("Providers tested", "healthy", healthy, "total", len(results))
+
+		h.mu.Lock()
+		h.results = results
+		h.checking = false
+		h.mu.Unlock()
+
+		if app.Events != nil {
+			app.Events.Publish(events.ProvidersChecked{Healthy: healthy, Total: len(results), Time: time.Now()})
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/internal/mcp"
+	"github.com/abrksh22/bplus/security"
+	"github.com/abrksh22/bplus/tools"
+)
+
+// MCPTools are the tools "bplus mcp-serve" serves unless told otherwise.
+var MCPTools = []string{"read", "write", "edit", "grep", "bash"}
+
+// mcpInstructions tell MCP clients how the served tools behave.
+const mcpInstructions = "Tools of the b+ coding assistant, run in its workspace with its permission checks and sandboxing. " +
+	"Paths are relative to the workspace root; calls outside the attached directories are refused"
+
+// ServeMCP serves the named tools to an MCP client on in and out until in
+// closes or ctx is done. Calls go through the agent, so they get the same
+// path resolution, workspace trust and permission checks as the model's;
+// permission prompts are put to the client's user when it supports
+// elicitation.
+func ServeMCP(ctx context.Context, opts *Options, names []string, in io.Reader, out io.Writer) error {
+	application, err := New(opts)
+	if err != nil {
+		return err
+	}
+	defer application.Close()
+
+	served := make([]tools.Tool, 0, len(names))
+	for _, name := range names {
+		tool, err := application.ToolRegistry.Get(name)
+		if err != nil {
+			return errors.Newf(errors.ErrCodeToolNotFound, "tool %s not found", name)
+		}
+		if !application.ToolRegistry.IsEnabled(name) {
+			return errors.Newf(errors.ErrCodeToolPermission, "tool %s is disabled", name)
+		}
+		served = append(served, tool)
+	}
+
+	server := mcp.NewServer(served, application.Agent.ExecuteTool,
+		mcp.WithInfo("bplus", opts.Version), mcp.WithInstructions(mcpInstructions))
+	application.PermManager.SetPromptHandler(mcpPromptHandler(server, application.Logger))
+	if level, _ := application.WorkspaceTrust(); level != security.TrustTrusted {
+		application.Logger.Warn("Workspace is not trusted: served tools can read files but not change them or run commands until it is trusted in b+",
+			"project", application.Project)
+	}
+	application.Logger.Info("Serving tools over MCP", "tools", strings.Join(names, ","))
+	if err := server.Serve(ctx, in, out); err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "MCP server failed")
+	}
+	return nil
+}
+
+// mcpPromptHandler asks the MCP client's user for permissions when the
+// client supports it. Otherwise requests are approved, as the client
+// already confirmed the call, except those that would expose credentials.
+func mcpPromptHandler(server *mcp.Server, logger *logging.Logger) security.PromptHandler {
+	return func(ctx context.Context, req *security.PermissionRequest) (bool, error) {
+		if server.CanElicit() {
+			return server.Confirm(ctx, permissionMessage(req))
+		}
+		if req.Elevated {
+			logger.Warn("Denying tool call that would expose credentials", "tool", req.ToolName, "resource", req.Resource, "reason", req.Reason)
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+// permissionMessage describes a permission request for the user.
+func permissionMessage(req *security.PermissionRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Allow %s to %s %s?", req.ToolName, req.Permission, req.Resource)
+	if req.Elevated {
+		fmt.Fprintf(&b, "\n%s", req.Reason)
+	}
+	if req.Preview != "" {
+		fmt.Fprintf(&b, "\n\n%s", req.Preview)
+	}
+	return b.String()
+}
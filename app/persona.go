@@ -0,0 +1,47 @@
+package app
+
+import (
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/prompts"
+)
+
+// personaPrompt returns the Layer 4 prompt for the configured persona.
+func personaPrompt(cfg *config.Config) (string, error) {
+	persona, ok := prompts.LookupPersona(cfg.Layers.MainAgent.Persona)
+	if !ok {
+		return "", errors.Newf(errors.ErrCodeConfigInvalid, "unknown persona %q", cfg.Layers.MainAgent.Persona)
+	}
+	cfg.Layers.MainAgent.Persona = persona.Name
+	return prompts.Layer4Prompt(persona), nil
+}
+
+// Personas returns the personas the agent can take on.
+func (app *Application) Personas() []prompts.Persona {
+	return prompts.Personas()
+}
+
+// CurrentPersona returns the name of the agent's persona.
+func (app *Application) CurrentPersona() string {
+	if app.Config.Layers.MainAgent.Persona == "" {
+		return prompts.DefaultPersona
+	}
+	return app.Config.Layers.MainAgent.Persona
+}
+
+// SetPersona switches the agent to another persona from its next turn.
+func (app *Application) SetPersona(name string) error {
+	persona, ok := prompts.LookupPersona(name)
+	if !ok {
+		return errors.Newf(errors.ErrCodeUser, "unknown persona %q", name)
+	}
+
+	agentConfig := *app.Agent.GetConfig()
+	agentConfig.SystemPrompt = prompts.Layer4Prompt(persona)
+	app.Agent.UpdateConfig(&agentConfig)
+	app.Config.Layers.MainAgent.Persona = persona.Name
+	app.origins.Runtime["layers.main_agent.persona"] = "/persona"
+
+	app.Logger.Info("Persona changed", "persona", persona.Name)
+	return nil
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+)
+
+// Pre-flight scan limits
+const (
+	// largeRepoTokens is the estimated source size above which a repository
+	// is large enough to warn about.
+	largeRepoTokens = 5000000
+
+	// largeRepoFiles is the file count above which a repository is large
+	// enough to warn about whatever its size.
+	largeRepoFiles = 20000
+
+	// scanFileLimit stops the scan of a huge repository early.
+	scanFileLimit = 200000
+
+	// maxScannedFile is the size above which files are taken to be binary
+	// or generated and left out of the estimate.
+	maxScannedFile = 1 << 20
+
+	// bytesPerToken estimates tokens from source size.
+	bytesPerToken = 4
+
+	// turnFiles, turnOverhead and turnOutput describe a typical exploring
+	// turn: the files it reads, its prompt and history, and its answer.
+	turnFiles    = 20
+	turnOverhead = 10000
+	turnOutput   = 1000
+)
+
+// skippedDirs are never scanned: version control metadata, dependencies and
+// build output.
+var skippedDirs = map[string]bool{
+	".git": true, ".hg": true, ".svn": true, "node_modules": true, "vendor": true,
+	"dist": true, "build": true, "target": true, "__pycache__": true, ".venv": true, "venv": true,
+}
+
+// repoScan is what the pre-flight scan found in the project directory.
+type repoScan struct {
+	files     int
+	bytes     int64
+	dirBytes  map[string]int64 // Source bytes per top-level directory
+	truncated bool
+}
+
+// scanRepository totals the source files under dir.
+func scanRepository(dir string) repoScan {
+	scan := repoScan{dirBytes: make(map[string]int64)}
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (skippedDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxScannedFile {
+			return nil
+		}
+
+		scan.files++
+		scan.bytes += info.Size()
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			if top, _, nested := strings.Cut(rel, string(os.PathSeparator)); nested {
+				scan.dirBytes[top] += info.Size()
+			}
+		}
+		if scan.files >= scanFileLimit {
+			scan.truncated = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return scan
+}
+
+// tokens estimates the size of the scanned source in tokens.
+func (s repoScan) tokens() int {
+	return int(s.bytes / bytesPerToken)
+}
+
+// large reports whether the repository is large enough to warn about.
+func (s repoScan) large() bool {
+	return s.truncated || s.files > largeRepoFiles || s.tokens() > largeRepoTokens
+}
+
+// turnTokens estimates the context of a typical exploring turn, capped at
+// the context window of model.
+func (s repoScan) turnTokens(model string) int {
+	avgFile := 0
+	if s.files > 0 {
+		avgFile = s.tokens() / s.files
+	}
+	tokens := turnOverhead + turnFiles*avgFile
+	if caps, ok := models.LookupCapabilities(model); ok && caps.ContextWindow > 0 && tokens > caps.ContextWindow {
+		tokens = caps.ContextWindow
+	}
+	return tokens
+}
+
+// largestDirs returns up to n top-level directories, largest first.
+func (s repoScan) largestDirs(n int) []string {
+	dirs := make([]string, 0, len(s.dirBytes))
+	for dir := range s.dirBytes {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if s.dirBytes[dirs[i]] != s.dirBytes[dirs[j]] {
+			return s.dirBytes[dirs[i]] > s.dirBytes[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	if len(dirs) > n {
+		dirs = dirs[:n]
+	}
+	return dirs
+}
+
+// localExplorer returns a configured local model to explore a large
+// repository with, or "" if there is none or a local model is in use.
+func localExplorer(cfg *config.Config) string {
+	if provider, err := models.GetProviderFromModel(cfg.Models.Default); err == nil && models.IsLocalProvider(provider) {
+		return ""
+	}
+	for _, model := range []string{cfg.Models.Offline, models.ResolveAlias("local")} {
+		provider, err := models.GetProviderFromModel(model)
+		if err != nil || !models.IsLocalProvider(provider) {
+			continue
+		}
+		if _, configured := cfg.Providers[provider]; configured {
+			return model
+		}
+	}
+	return ""
+}
+
+// checkRepositorySize scans the project in the background and, if it is
+// very large, warns with the estimated index size and cost of a typical
+// turn. events.RepositoryScanned is published for the UI to suggest
+// scoping the session to a subdirectory or exploring with a local model.
+func (app *Application) checkRepositorySize() {
+	go func() {
+		start := time.Now()
+		scan := scanRepository(app.Project)
+		if !scan.large() {
+			app.Logger.Debug("Repository scanned", "files", scan.files, "tokens", scan.tokens(), "duration", time.Since(start).String())
+			return
+		}
+
+		model := app.Config.Models.Default
+		e := events.RepositoryScanned{
+			Files:       scan.files,
+			Tokens:      scan.tokens(),
+			TurnTokens:  scan.turnTokens(model),
+			Model:       model,
+			LargestDirs: scan.largestDirs(3),
+			LocalModel:  localExplorer(app.Config),
+			Truncated:   scan.truncated,
+			Time:        time.Now(),
+		}
+		if provider, id, err := models.ParseModelName(model); err == nil {
+			e.TurnCost = pricing.Lookup(provider, id).Cost(e.TurnTokens, turnOutput)
+		}
+		app.Logger.Warn("Large repository: exploring it may be slow and expensive",
+			"files", e.Files, "tokens", e.Tokens, "turn_tokens", e.TurnTokens, "turn_cost", e.TurnCost, "largest_dirs", e.LargestDirs)
+
+		if app.Events != nil {
+			app.Events.Publish(e)
+		}
+	}()
+}
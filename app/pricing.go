@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// pricingTimeout bounds fetching the published pricing table.
+const pricingTimeout = 30 * time.Second
+
+// loadPricing makes the built-in pricing table, with any overrides from the
+// config directory, the one providers compute costs with.
+func loadPricing(logger *logging.Logger) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return
+	}
+	table, err := pricing.Load(dir)
+	if err != nil {
+		logger.Warn("Ignoring pricing overrides", "error", err.Error())
+	}
+	pricing.Set(table)
+}
+
+// RefreshPricing fetches the published pricing table from url, or
+// pricing.DefaultURL if empty, and saves it to the config directory. It
+// returns the table and the file it was saved to.
+func RefreshPricing(ctx context.Context, url string) (*pricing.Table, string, error) {
+	if url == "" {
+		url = pricing.DefaultURL
+	}
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, "", errors.Wrap(err, errors.ErrCodeConfig, "failed to locate config directory")
+	}
+
+	table, err := pricing.Refresh(ctx, transport.NewClient(pricingTimeout), url, dir)
+	if err != nil {
+		return nil, "", errors.Wrap(err, errors.ErrCodeNetwork, "failed to refresh pricing")
+	}
+	return table, filepath.Join(dir, pricing.FileName), nil
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/models"
+)
+
+// modelCheckTimeout bounds asking a provider whether a model is installed.
+const modelCheckTimeout = 10 * time.Second
+
+// modelPull is the model download in progress. Only one runs at a time.
+type modelPull struct {
+	mu     sync.Mutex
+	model  string // Empty unless a download is running
+	cancel context.CancelFunc
+}
+
+// modelInstalled reports whether model is installed. Providers that cannot
+// pull models, or fail to answer, are taken to have it, leaving the load or
+// the first request to report what is wrong.
+func modelInstalled(ctx context.Context, p models.Provider, model string) bool {
+	puller, ok := models.AsModelPuller(p)
+	if !ok {
+		return true
+	}
+	_, modelID, err := models.ParseModelName(model)
+	if err != nil {
+		return true
+	}
+	has, err := puller.HasModel(ctx, modelID)
+	return err != nil || has
+}
+
+// offerPull reports that model is not installed, so the UI can offer to
+// pull it.
+func (app *Application) offerPull(model string) {
+	app.Logger.Warn("Model not installed", "model", model)
+	app.publishModelLoad(model, events.ModelMissing, nil)
+}
+
+// checkModel offers to pull model in the background if it isn't installed.
+func (app *Application) checkModel(model string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), modelCheckTimeout)
+		defer cancel()
+		if !modelInstalled(ctx, app.Provider, model) {
+			app.offerPull(model)
+		}
+	}()
+}
+
+// PullModel downloads a model of the active provider in the background,
+// publishing its progress. A name without a provider is taken to be one of
+// the active provider's. Once the download is done the model is loaded, if
+// it is the current model and models are kept loaded.
+func (app *Application) PullModel(name string) error {
+	name = models.ResolveAlias(name)
+	if !strings.Contains(name, "/") {
+		name = models.FormatModelName(app.Provider.Name(), name)
+	}
+	providerName, modelID, err := models.ParseModelName(name)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeConfigInvalid, "invalid model name")
+	}
+	if providerName != app.Provider.Name() {
+		return errors.Newf(errors.ErrCodeConfigInvalid, "model %s is not served by the active provider %s", name, app.Provider.Name())
+	}
+	puller, ok := models.AsModelPuller(app.Provider)
+	if !ok {
+		return errors.Newf(errors.ErrCodeProvider, "provider %s cannot download models", providerName)
+	}
+
+	app.pull.mu.Lock()
+	defer app.pull.mu.Unlock()
+	if app.pull.model != "" {
+		return errors.Newf(errors.ErrCodeValidation, "already downloading %s", app.pull.model)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	app.pull.model = name
+	app.pull.cancel = cancel
+
+	go func() {
+		defer func() {
+			app.pull.mu.Lock()
+			app.pull.model = ""
+			app.pull.cancel = nil
+			app.pull.mu.Unlock()
+			cancel()
+		}()
+		app.runPull(ctx, puller, name, modelID)
+	}()
+	return nil
+}
+
+// runPull downloads a model, publishing its progress at most once per
+// percent.
+func (app *Application) runPull(ctx context.Context, puller models.ModelPuller, name, modelID string) {
+	app.Logger.Info("Pulling model", "model", name)
+	app.publishModelLoad(name, events.ModelPulling, nil)
+	start := time.Now()
+
+	progress := make(chan models.PullProgress, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- puller.PullModel(ctx, modelID, progress)
+	}()
+
+	// Layers are reported one after another; the total covers those seen so far
+	layers := make(map[string]models.PullProgress)
+	last := -1
+	for {
+		select {
+		case update := <-progress:
+			if update.Digest == "" || update.Total <= 0 {
+				continue
+			}
+			layers[update.Digest] = update
+			var completed, total int64
+			for _, layer := range layers {
+				completed += layer.Completed
+				total += layer.Total
+			}
+			fraction := models.PullProgress{Total: total, Completed: completed}.Fraction()
+			if percent := int(fraction * 100); percent != last && percent < 100 {
+				last = percent
+				app.Events.Publish(events.ModelLoadChanged{Model: name, State: events.ModelPulling, Progress: fraction, Time: time.Now()})
+			}
+		case err := <-done:
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				app.Logger.Warn("Failed to pull model", "model", name, "error", err.Error())
+				app.publishModelLoad(name, events.ModelFailed, err)
+				return
+			}
+			app.Logger.Info("Model pulled", "model", name, "duration", time.Since(start).String())
+			if app.warmUp != nil && name == app.CurrentModel() {
+				app.warmModel(name)
+				return
+			}
+			app.publishModelLoad(name, events.ModelReady, nil)
+			return
+		}
+	}
+}
+
+// stopPull cancels the model download in progress, if any.
+func (app *Application) stopPull() {
+	app.pull.mu.Lock()
+	defer app.pull.mu.Unlock()
+	if app.pull.cancel != nil {
+		app.pull.cancel()
+	}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/router"
+)
+
+// setQuickPolicy lets quick tasks fall back to the configured local model,
+// registering its provider with rt. A local model whose provider cannot be
+// created leaves quick tasks on the remote model.
+func setQuickPolicy(rt *router.Router, cfg *config.Config, logger *logging.Logger) {
+	local := cfg.Models.Quick.Local
+	if local == "" {
+		return
+	}
+	providerName, _, err := models.ParseModelName(local)
+	if err != nil || !models.IsLocalProvider(providerName) {
+		logger.Warn("Quick task model is not local, ignoring it", "model", local)
+		return
+	}
+	if _, ok := rt.Providers()[providerName]; !ok {
+		provider, err := newProvider(cfg, providerName)
+		if err != nil {
+			logger.Warn("Quick task provider unavailable", "provider", providerName, "error", err.Error())
+			return
+		}
+		rt.AddProvider(provider)
+	}
+	rt.SetQuickPolicy(router.QuickPolicy{Local: local, MaxLatency: cfg.Models.Quick.MaxLatency})
+}
+
+// QuickModel returns the model to run a quick task, such as naming the
+// session, with: the current model, or the local one from models.quick while
+// the remote provider is slow or unreachable.
+func (app *Application) QuickModel(ctx context.Context, task router.QuickTask) string {
+	sel := app.Router.SelectQuickModel(ctx, task, app.CurrentModel())
+	if sel.Local {
+		app.Logger.Debug("Quick task moved to local model", "task", string(sel.Task), "model", sel.Model, "reason", sel.Reason)
+		app.Substitutions.Record(router.Substitution{
+			Kind:       router.SubstitutionQuick,
+			Purpose:    "quick task " + string(sel.Task),
+			Configured: app.CurrentModel(),
+			Used:       sel.Model,
+			Reason:     sel.Reason,
+		})
+	}
+	return sel.Model
+}
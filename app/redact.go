@@ -0,0 +1,127 @@
+package app
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/models"
+)
+
+// redaction is what the user asked to redact: a stored message by ID, or
+// content matching a pattern.
+type redaction struct {
+	messageID int64
+	pattern   *regexp.Regexp
+}
+
+// parseRedaction parses "#<id>" as a stored message ID and anything else as
+// a regular expression. Patterns matching the empty string are refused, as
+// they would redact everything.
+func parseRedaction(target string) (redaction, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return redaction{}, errors.New(errors.ErrCodeValidation, "nothing to redact: give a pattern or #message-id")
+	}
+	if id, err := strconv.ParseInt(strings.TrimPrefix(target, "#"), 10, 64); err == nil && strings.HasPrefix(target, "#") {
+		return redaction{messageID: id}, nil
+	}
+	re, err := regexp.Compile(target)
+	if err != nil {
+		return redaction{}, errors.Wrapf(err, errors.ErrCodeValidation, "invalid pattern %q", target)
+	}
+	if re.MatchString("") {
+		return redaction{}, errors.Newf(errors.ErrCodeValidation, "pattern %q matches empty text and would redact everything", target)
+	}
+	return redaction{pattern: re}, nil
+}
+
+// redactor returns a redactor scrubbing what r names: the pattern, or the
+// content the message had.
+func (r redaction) redactor(stored *storage.Message) *models.Redactor {
+	redactor := models.NewRedactor()
+	if r.pattern != nil {
+		redactor.Add(r.pattern)
+	} else if stored != nil && stored.Content != "" {
+		redactor.Add(regexp.MustCompile(regexp.QuoteMeta(stored.Content)))
+	}
+	return redactor
+}
+
+// inContext returns which context messages redacting r changes: every one
+// for a pattern, and only the stored message itself for an ID, so that
+// redacting a short message like "ok" leaves other messages alone.
+func (r redaction) inContext(stored *storage.Message) func(models.Message) bool {
+	if r.pattern != nil || stored == nil {
+		return nil
+	}
+	return func(msg models.Message) bool {
+		return msg.Role == stored.Role && msg.Content == stored.Content
+	}
+}
+
+// PreviewRedaction counts the stored messages, in every session, and the
+// messages in the conversation context that redacting target would change.
+func (app *Application) PreviewRedaction(target string) (stored, inContext int, err error) {
+	r, err := parseRedaction(target)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var msg *storage.Message
+	if r.pattern != nil {
+		stored, err = app.DB.CountMatchingMessages(r.pattern)
+	} else {
+		msg, err = app.DB.GetMessage(r.messageID)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, errors.ErrCodeDatabase, "failed to find message")
+		}
+		stored = 1
+	}
+	if err != nil {
+		return 0, 0, errors.Wrap(err, errors.ErrCodeDatabase, "failed to search stored messages")
+	}
+
+	redactor, keep := r.redactor(msg), r.inContext(msg)
+	for _, m := range app.Context.History() {
+		if keep != nil && !keep(m) {
+			continue
+		}
+		if _, changed := redactor.RedactMessage(m); changed {
+			inContext++
+		}
+	}
+	return stored, inContext, nil
+}
+
+// Redact scrubs target from the stored messages of every session, their
+// search index and saved contexts, and from the conversation context,
+// replacing it with models.RedactionPlaceholder. A pattern is also scrubbed
+// from every later prompt of the session, e.g. when a tool reads the secret
+// again. It returns how many stored and context messages changed.
+func (app *Application) Redact(target string) (stored, inContext int, err error) {
+	r, err := parseRedaction(target)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var msg *storage.Message
+	if r.pattern != nil {
+		stored, err = app.DB.RedactMessages(r.pattern, models.RedactionPlaceholder)
+	} else {
+		msg, err = app.DB.RedactMessage(r.messageID, models.RedactionPlaceholder)
+		stored = 1
+	}
+	if err != nil {
+		return 0, 0, errors.Wrap(err, errors.ErrCodeDatabase, "failed to redact stored messages")
+	}
+
+	inContext = app.Context.RedactWhere(r.redactor(msg), r.inContext(msg))
+	if r.pattern != nil {
+		app.redactor.Add(r.pattern)
+	}
+	app.Logger.Info("Conversation redacted", "stored", stored, "context", inContext)
+	return stored, inContext, nil
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abrksh22/bplus/layers/contextmgr"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/router"
+)
+
+// relevanceTimeout bounds rating the context against a new user message.
+const relevanceTimeout = 30 * time.Second
+
+// relevanceBatch is how many items one rating request covers.
+const relevanceBatch = 20
+
+// relevanceMaxTokens is enough for the ratings of a batch.
+const relevanceMaxTokens = 200
+
+// rescoreContext rates the tool outputs of the history not yet rated against
+// the current task in the background, so the next pruning tracks what the
+// user is doing now. Until the ratings are in, term overlap is used.
+func (app *Application) rescoreContext() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), relevanceTimeout)
+		defer cancel()
+		if err := app.Context.Rescore(ctx); err != nil {
+			app.Logger.Debug("Failed to rate context relevance", "error", err.Error())
+		}
+	}()
+}
+
+// rateRelevance asks the quick task model how much the user's task needs
+// each of contents, in batches. The ratings are paid for like the agent's
+// own calls: their usage is added to the session's costs, and rating stops
+// once the session budget is spent.
+func (app *Application) rateRelevance(ctx context.Context, intent string, contents []string) ([]float64, error) {
+	model := app.QuickModel(ctx, router.TaskRelevance)
+	providerName, _, err := models.ParseModelName(model)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := app.Router.Providers()[providerName]
+	if !ok {
+		return nil, fmt.Errorf("provider %s not available", providerName)
+	}
+
+	costs := app.Agent.GetCostTracker()
+	scores := make([]float64, 0, len(contents))
+	for start := 0; start < len(contents); start += relevanceBatch {
+		if !costs.WithinSessionBudget() {
+			return nil, fmt.Errorf("budget exhausted, rating %d items by shared terms", len(contents))
+		}
+		batch := contents[start:min(start+relevanceBatch, len(contents))]
+		resp, err := provider.CreateCompletion(ctx, &models.CompletionRequest{
+			Model:     model,
+			Messages:  []models.Message{{Role: "user", Content: contextmgr.RatingPrompt(intent, batch)}},
+			MaxTokens: relevanceMaxTokens,
+		})
+		if err != nil {
+			return nil, err
+		}
+		costs.AddUsage(resp.Usage)
+		ratings, err := contextmgr.ParseRatings(resp.Content, len(batch))
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, ratings...)
+	}
+	return scores, nil
+}
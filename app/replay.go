@@ -0,0 +1,58 @@
+package app
+
+import (
+	"io"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/models/replay"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// startReplay hooks recording (opts.Record) or replaying (opts.Replay) of
+// provider traffic into the shared transport. It runs before any provider is
+// created. The returned closer, nil when neither is set, ends the recording.
+func startReplay(opts *Options, cfg *config.Config) (io.Closer, error) {
+	switch {
+	case opts.Record != "" && opts.Replay != "":
+		return nil, errors.New(errors.ErrCodeConfigInvalid, "--record and --replay cannot be used together")
+
+	case opts.Record != "":
+		var secrets []string
+		for _, providerCfg := range cfg.Providers {
+			secrets = append(secrets, providerCfg.Keys()...)
+		}
+		recorder, err := replay.NewRecorder(opts.Record, replay.WithSecrets(secrets...))
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to start recording")
+		}
+		transport.Intercept(recorder.Wrap)
+		return recorder, nil
+
+	case opts.Replay != "":
+		player, err := replay.NewPlayer(opts.Replay)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrCodeConfigInvalid, "failed to load recording")
+		}
+		transport.Intercept(player.Wrap)
+
+		// Recorded responses need no credentials, but providers refuse to
+		// start without a key
+		for name, providerCfg := range cfg.Providers {
+			if len(providerCfg.Keys()) == 0 {
+				providerCfg.APIKey = replay.Redacted
+				cfg.Providers[name] = providerCfg
+			}
+		}
+		return replayCloser{}, nil
+	}
+	return nil, nil
+}
+
+// replayCloser removes the player from the shared transport.
+type replayCloser struct{}
+
+func (replayCloser) Close() error {
+	transport.Intercept(nil)
+	return nil
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"os"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/layers/validation"
+)
+
+// Findings returns the validation findings of the session: language server
+// errors in the files edited and failing or flaky tests.
+func (app *Application) Findings() []validation.Finding {
+	findings := app.Diagnostics.Findings()
+	return append(findings, app.Tests.Findings()...)
+}
+
+// ReportFindings emits the session's findings as annotations of the CI
+// system b+ runs in (GitHub Actions or GitLab CI), so a failed run marks
+// up the diff under review. It does nothing outside CI.
+func (app *Application) ReportFindings() error {
+	reporter := validation.CIReporter(os.Getenv)
+	if reporter == nil {
+		return nil
+	}
+	findings := app.Findings()
+	app.Logger.Info("Reporting validation findings to CI", "findings", len(findings))
+	if err := reporter.Report(findings); err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to report validation findings")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/security"
+)
+
+// sessionRoots attaches the project directory as the primary root, then the
+// roots given with --add-dir.
+func sessionRoots(project string, specs []string) (*security.Roots, error) {
+	roots := security.NewRoots()
+	if _, err := roots.Add(security.Root{Path: project}); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to attach the project directory")
+	}
+	for _, spec := range specs {
+		root, err := security.ParseRoot(spec)
+		if err == nil {
+			_, err = roots.Add(root)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, errors.ErrCodeConfigInvalid, "invalid --add-dir %s", spec)
+		}
+	}
+	return roots, nil
+}
+
+// Roots returns the directories attached to the session, the project
+// directory first.
+func (app *Application) Roots() []security.Root {
+	return app.roots.List()
+}
+
+// AttachRoot attaches a directory given as "[name=]path[:ro]" to the
+// session. Its files are then referred to as "name:path".
+func (app *Application) AttachRoot(spec string) (security.Root, error) {
+	root, err := security.ParseRoot(spec)
+	if err != nil {
+		return security.Root{}, errors.Wrap(err, errors.ErrCodeValidation, "invalid root")
+	}
+	root, err = app.roots.Add(root)
+	if err != nil {
+		return security.Root{}, errors.Wrap(err, errors.ErrCodeValidation, "failed to attach root")
+	}
+	app.Logger.Info("Root attached", "name", root.Name, "path", root.Path, "permissions", security.DescribePermissions(root))
+	return root, nil
+}
+
+// DetachRoot detaches the root named name. The project directory cannot
+// be detached.
+func (app *Application) DetachRoot(name string) error {
+	if err := app.roots.Remove(name); err != nil {
+		return errors.Wrap(err, errors.ErrCodeValidation, "failed to detach root")
+	}
+	app.Logger.Info("Root detached", "name", name)
+	return nil
+}
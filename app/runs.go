@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/tools"
+	"github.com/abrksh22/bplus/tools/exec"
+)
+
+// RunHook inspects a command run before it is stored. Hooks may mark the run
+// as a favorite.
+type RunHook func(run *storage.CommandRun)
+
+// AddRunHook registers a hook called for every recorded command run.
+func (app *Application) AddRunHook(hook RunHook) {
+	app.runHooks = append(app.runHooks, hook)
+}
+
+// CommandRuns returns up to limit runs from the current project's history,
+// favorites first.
+func (app *Application) CommandRuns(limit int) ([]*storage.CommandRun, error) {
+	return app.DB.ListCommandRuns(app.Project, limit)
+}
+
+// SetRunFavorite marks or unmarks a run as a favorite.
+func (app *Application) SetRunFavorite(id int64, favorite bool) error {
+	return app.DB.SetCommandRunFavorite(id, favorite)
+}
+
+// RerunCommand executes a run from the history again, on the user's behalf,
+// and records it as a new run.
+func (app *Application) RerunCommand(ctx context.Context, id int64) (*storage.CommandRun, *tools.Result, error) {
+	tool, err := app.ToolRegistry.Get("rerun")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrCodeToolNotFound, "rerun tool not available")
+	}
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, errors.ErrCodeToolExecution, "failed to re-run #%d", id)
+	}
+
+	run, ok := runFromResult(result)
+	if !ok {
+		return nil, result, nil
+	}
+	app.recordRun(run)
+	return run, result, nil
+}
+
+// recordRuns stores the shell commands executed during a turn.
+func (app *Application) recordRuns(sessionID string, calls []execution.ToolExecution) {
+	for _, call := range calls {
+		switch strings.TrimPrefix(call.ToolName, "core.") {
+		case "bash", "rerun":
+		default:
+			continue
+		}
+		run, ok := runFromResult(call.Result)
+		if !ok {
+			continue
+		}
+		if sessionID != "" {
+			run.SessionID = &sessionID
+		}
+		run.Timestamp = call.Timestamp
+		app.recordRun(run)
+	}
+}
+
+// recordRun applies the run hooks and stores the run.
+func (app *Application) recordRun(run *storage.CommandRun) {
+	run.Project = app.Project
+	for _, hook := range app.runHooks {
+		hook(run)
+	}
+	if err := app.DB.RecordCommandRun(run); err != nil {
+		app.Logger.Warn("Failed to record command run", "error", err.Error())
+	}
+}
+
+// runFromResult builds a run from a bash or rerun result. Commands that never
+// ran (blocked by the safety check, unsupported shell) are not runs.
+func runFromResult(result *tools.Result) (*storage.CommandRun, bool) {
+	if result == nil || result.Metadata == nil {
+		return nil, false
+	}
+	command, _ := result.Metadata["command"].(string)
+	exitCode, ok := result.Metadata["exit_code"].(int)
+	if command == "" || !ok {
+		return nil, false
+	}
+
+	run := &storage.CommandRun{
+		Command:  command,
+		ExitCode: exitCode,
+		Duration: result.Duration,
+	}
+	run.WorkingDir, _ = result.Metadata["working_dir"].(string)
+	run.Shell, _ = result.Metadata["shell"].(string)
+	return run, true
+}
+
+// favoriteCommandsHook marks runs whose command starts with one of prefixes.
+func favoriteCommandsHook(prefixes []string) RunHook {
+	return func(run *storage.CommandRun) {
+		for _, prefix := range prefixes {
+			if prefix != "" && strings.HasPrefix(strings.TrimSpace(run.Command), prefix) {
+				run.Favorite = true
+				return
+			}
+		}
+	}
+}
+
+// runHistory serves the rerun tool from the current project's history.
+type runHistory struct {
+	db      *storage.SQLiteDB
+	project string
+}
+
+// PastRun implements exec.RunHistory.
+func (h runHistory) PastRun(id int64) (*exec.PastRun, error) {
+	run, err := h.db.GetCommandRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Project != h.project {
+		return nil, errors.Newf(errors.ErrCodeUser, "run #%d belongs to another project", id)
+	}
+	return &exec.PastRun{
+		ID:         run.ID,
+		Command:    run.Command,
+		WorkingDir: run.WorkingDir,
+		Shell:      run.Shell,
+	}, nil
+}
+
+// projectDir returns the directory the run history is kept for.
+func projectDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
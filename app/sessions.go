@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/models"
+)
+
+// maxAttachSize bounds a file attached to the conversation from the UI.
+// Images are bounded by models.MaxAttachmentSize instead.
+const maxAttachSize = 256 * 1024
+
+// imageExtensions are the files AttachFile sends as images.
+var imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
+// Sessions returns the stored sessions, most recently updated first.
+func (app *Application) Sessions() ([]*storage.Session, error) {
+	return app.DB.ListSessions()
+}
+
+// ResumeSession replaces the conversation context with the messages of a
+// stored session.
+func (app *Application) ResumeSession(ctx context.Context, id string) error {
+	messages, err := app.SessionManager.GetMessages(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, errors.ErrCodeDatabase, "failed to resume session %s", id)
+	}
+	app.Context.Reset(messages...)
+	app.Logger.Info("Session resumed", "session_id", id, "messages", len(messages))
+	return nil
+}
+
+// ProjectFiles returns the project's files relative to its root, newest
+// first, leaving out ignored files.
+func (app *Application) ProjectFiles(ctx context.Context) ([]string, error) {
+	tool, err := app.ToolRegistry.Get("glob")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeToolNotFound, "glob tool not available")
+	}
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"pattern": "**/*", "path": app.Project})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeToolExecution, "failed to list project files")
+	}
+	if !result.Success {
+		return nil, errors.Wrap(result.Error, errors.ErrCodeToolExecution, "failed to list project files")
+	}
+
+	matches, _ := result.Output.([]string)
+	files := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if rel, err := filepath.Rel(app.Project, match); err == nil {
+			match = rel
+		}
+		files = append(files, match)
+	}
+	return files, nil
+}
+
+// AttachFile adds a project file to the conversation context on the user's
+// behalf. Images are attached as images, for models with vision; other
+// files as text.
+func (app *Application) AttachFile(path string) error {
+	full := path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(app.Project, path)
+	}
+
+	if imageExtensions[strings.ToLower(filepath.Ext(path))] {
+		image, err := models.LoadAttachment(full)
+		if err != nil {
+			return errors.Wrapf(err, errors.ErrCodeValidation, "failed to attach %s", path)
+		}
+		image.Path = path
+		app.Context.Append(models.Message{
+			Role:        "user",
+			Content:     fmt.Sprintf("Image %s:", path),
+			Attachments: []models.Attachment{image},
+		})
+		return nil
+	}
+
+	info, err := os.Stat(full)
+	if err != nil {
+		return errors.Wrapf(err, errors.ErrCodeFileNotFound, "failed to attach %s", path)
+	}
+	if info.Size() > maxAttachSize {
+		return errors.Newf(errors.ErrCodeValidation, "%s is too large to attach (%d KB, limit %d KB)",
+			path, info.Size()/1024, maxAttachSize/1024)
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return errors.Wrapf(err, errors.ErrCodeFileNotFound, "failed to attach %s", path)
+	}
+
+	app.Context.Append(models.Message{
+		Role:    "user",
+		Content: fmt.Sprintf("Contents of %s:\n\n```\n%s\n```", path, data),
+	})
+	return nil
+}
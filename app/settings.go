@@ -0,0 +1,46 @@
+package app
+
+import "github.com/abrksh22/bplus/internal/config"
+
+// keyEnv lists the variables loadConfig reads provider API keys from.
+var keyEnv = map[string]string{
+	"providers.anthropic.api_key":   "ANTHROPIC_API_KEY",
+	"providers.anthropic.api_keys":  "ANTHROPIC_API_KEYS",
+	"providers.openai.api_key":      "OPENAI_API_KEY",
+	"providers.openai.api_keys":     "OPENAI_API_KEYS",
+	"providers.gemini.api_key":      "GEMINI_API_KEY",
+	"providers.gemini.api_keys":     "GEMINI_API_KEYS",
+	"providers.openrouter.api_key":  "OPENROUTER_API_KEY",
+	"providers.openrouter.api_keys": "OPENROUTER_API_KEYS",
+	"providers.deepseek.api_key":    "DEEPSEEK_API_KEY",
+	"providers.deepseek.api_keys":   "DEEPSEEK_API_KEYS",
+	"providers.cohere.api_key":      "COHERE_API_KEY",
+	"providers.cohere.api_keys":     "COHERE_API_KEYS",
+	"providers.vllm.api_key":        "VLLM_API_KEY",
+}
+
+// configOrigins describes where the values loadConfig merges come from: the
+// API key variables it reads and the flags given.
+func configOrigins(opts *Options) config.Origins {
+	origins := config.Origins{
+		Env:     keyEnv,
+		Flags:   make(map[string]string),
+		Runtime: make(map[string]string),
+	}
+	if opts.Thorough {
+		origins.Flags["mode"] = "--thorough"
+	}
+	if opts.Model != "" {
+		origins.Flags["models.default"] = "--model"
+	}
+	if opts.Offline {
+		origins.Flags["models.default"] = "--offline"
+	}
+	return origins
+}
+
+// ConfigSettings returns every value of the effective configuration with
+// the source that set it, secrets masked.
+func (app *Application) ConfigSettings() []config.Setting {
+	return config.Inspect(app.Config, app.origins)
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/tools/exec"
+)
+
+// ShellSessions returns the open shell sessions of core.shell.
+func (app *Application) ShellSessions() []exec.ShellSession {
+	return app.Shells.List()
+}
+
+// KillShellSession kills a shell session and everything running in it.
+func (app *Application) KillShellSession(id string) error {
+	if err := app.Shells.Kill(id); err != nil {
+		return errors.Wrap(err, errors.ErrCodeUser, "failed to kill shell session")
+	}
+	app.Logger.Info("Shell session killed", "session", id)
+	return nil
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// unloadTimeout bounds unloading the local models when suspending.
+const unloadTimeout = 10 * time.Second
+
+// suspension tracks whether the session is suspended and what to take back
+// up when it resumes.
+type suspension struct {
+	mu        sync.Mutex
+	suspended bool
+	warm      bool // The model was kept loaded and is loaded again on resume
+}
+
+// IdleSuspendAfter returns how long the session may sit idle before it is
+// suspended (session.idle_suspend), or 0 if it never is.
+func (app *Application) IdleSuspendAfter() time.Duration {
+	return app.Config.Session.IdleSuspend
+}
+
+// Suspend releases what an idle session holds on to, so b+ can be left
+// running overnight: the keep-alive pings stop, the local models are
+// unloaded, idle connections are closed, language servers are stopped, the
+// database is flushed and freed memory is returned to the OS. Resume takes
+// it back up.
+func (app *Application) Suspend() {
+	s := &app.suspension
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.suspended {
+		return
+	}
+	s.suspended = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), unloadTimeout)
+	defer cancel()
+
+	s.warm = app.warmUp != nil
+	if s.warm {
+		app.stopWarmUp()
+		app.warmUp = nil
+	}
+	app.unload(ctx, app.Provider, app.Config.Models.Default)
+	if quick := app.Config.Models.Quick.Local; quick != "" && quick != app.Config.Models.Default {
+		if providerName, _, err := models.ParseModelName(quick); err == nil {
+			if p, ok := app.Router.Providers()[providerName]; ok {
+				app.unload(ctx, p, quick)
+			}
+		}
+	}
+
+	transport.Shared().CloseIdleConnections()
+	if app.LSP != nil {
+		app.LSP.Stop() // Started again by the next edit or lookup
+	}
+	if app.DB != nil {
+		if err := app.DB.Flush(); err != nil {
+			app.Logger.Warn("Failed to flush database", "error", err.Error())
+		}
+	}
+	debug.FreeOSMemory()
+
+	app.Logger.Info("Session suspended", "idle", app.IdleSuspendAfter().String())
+}
+
+// Resume undoes Suspend, loading the model again if it was kept loaded.
+func (app *Application) Resume() {
+	s := &app.suspension
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.suspended {
+		return
+	}
+	s.suspended = false
+
+	if s.warm {
+		app.startWarmUp(app.Provider, app.Config.Models.Default, app.Config.Providers[app.Provider.Name()].KeepAlive)
+	}
+	app.Logger.Info("Session resumed")
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/layers/validation"
+	"github.com/abrksh22/bplus/tools/exec"
+)
+
+// afterTool runs after each tool call. Test runs are recorded for the
+// validation layer; edits are checked with the language servers; files
+// read or written are watched, and those changed outside b+ reported.
+func (app *Application) afterTool(ctx context.Context, call execution.ToolExecution) string {
+	app.watchFiles(call)
+	var note string
+	if strings.TrimPrefix(call.ToolName, "core.") == "test" {
+		note = app.recordTests(ctx, call)
+	} else if app.LSP != nil {
+		note = app.checkEdits(ctx, call)
+	}
+	if changes := app.externalChanges(); changes != "" {
+		if note != "" {
+			note += "\n\n"
+		}
+		note += changes
+	}
+	return note
+}
+
+// recordTests feeds the results of a core.test call to the test tracker,
+// so validation tells regressions from flaky tests, and returns the
+// tracker's advice on whether the failures are worth another iteration.
+func (app *Application) recordTests(ctx context.Context, call execution.ToolExecution) string {
+	if call.Result == nil || !call.Result.Success || call.Result.Metadata == nil {
+		return ""
+	}
+	cases, _ := call.Result.Metadata["tests"].([]exec.TestCase)
+	results := make([]validation.TestResult, 0, len(cases))
+	for _, tc := range cases {
+		if tc.Status == exec.TestSkipped {
+			continue
+		}
+		results = append(results, validation.TestResult{Name: tc.Name, Passed: tc.Status == exec.TestPassed, Path: tc.Path})
+	}
+	if len(results) == 0 {
+		return ""
+	}
+	dir, _ := call.Result.Metadata["working_dir"].(string)
+	app.Tests.Record(app.codeState(ctx, dir), results)
+	return app.Tests.Advice()
+}
+
+// codeState identifies the code a test run tested: a hash of the working
+// tree's changes, or the time outside a git repository so every run
+// counts as testing new code.
+func (app *Application) codeState(ctx context.Context, dir string) string {
+	if dir == "" {
+		dir = app.Project
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	git := func(args ...string) ([]byte, error) {
+		cmd := osexec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		return cmd.Output()
+	}
+
+	diff, err := git("diff", "HEAD", "--binary")
+	if err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	h := sha256.New()
+	h.Write(diff)
+	// Untracked files count by size and modification time
+	if untracked, err := git("ls-files", "--others", "--exclude-standard", "-z"); err == nil {
+		for _, name := range strings.Split(string(untracked), "\x00") {
+			if name == "" {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				fmt.Fprintf(h, "%s\x00%d\x00%d\x00", name, info.Size(), info.ModTime().UnixNano())
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"time"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/tools/todo"
+)
+
+// todoTool returns the task list tool, keeping its lists in db and
+// publishing every change on bus for the UI to show.
+func todoTool(db *storage.SQLiteDB, bus *events.Bus) *todo.Tool {
+	return todo.NewTodoTool(todoStore{db: db}, todo.WithListener(func(session string, items []todo.Item) {
+		todos := make([]events.Todo, len(items))
+		for i, item := range items {
+			todos[i] = events.Todo{ID: item.ID, Content: item.Content, Status: item.Status}
+		}
+		bus.Publish(events.TodosUpdated{Session: session, Todos: todos, Time: time.Now()})
+	}))
+}
+
+// todoStore keeps the task lists of core.todo in the todos table.
+type todoStore struct {
+	db *storage.SQLiteDB
+}
+
+// List implements todo.Store.
+func (s todoStore) List(session string) ([]todo.Item, error) {
+	todos, err := s.db.ListTodos(session)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]todo.Item, len(todos))
+	for i, t := range todos {
+		items[i] = todo.Item{ID: t.ID, Content: t.Content, Status: t.Status}
+	}
+	return items, nil
+}
+
+// Create implements todo.Store.
+func (s todoStore) Create(session string, item todo.Item) (todo.Item, error) {
+	t := &storage.Todo{SessionID: session, Content: item.Content, Status: item.Status}
+	if err := s.db.CreateTodo(t); err != nil {
+		return item, err
+	}
+	item.ID = t.ID
+	return item, nil
+}
+
+// Update implements todo.Store.
+func (s todoStore) Update(session string, item todo.Item) error {
+	return s.db.UpdateTodo(&storage.Todo{ID: item.ID, SessionID: session, Content: item.Content, Status: item.Status})
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/tools"
+	toolplugin "github.com/abrksh22/bplus/tools/plugin"
+)
+
+// toolPluginDir is where WebAssembly tool plugins are discovered, under
+// the config directory.
+const toolPluginDir = "plugins"
+
+// loadToolPlugins registers the tools of the WebAssembly modules in the
+// plugin directory. Modules that can't be loaded are logged and skipped.
+func loadToolPlugins(registry *tools.Registry, logger *logging.Logger) *toolplugin.Plugins {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return &toolplugin.Plugins{}
+	}
+	dir := filepath.Join(configDir, toolPluginDir)
+	plugins, err := toolplugin.Load(context.Background(), dir)
+	if err != nil {
+		logger.Warn("Some tool plugins were not loaded", "dir", dir, "error", err.Error())
+	}
+	for _, tool := range plugins.Tools() {
+		if err := registry.Register(tool); err != nil {
+			logger.Warn("Failed to register tool plugin", "path", tool.Path(), "error", err.Error())
+			continue
+		}
+		logger.Info("Tool plugin loaded", "tool", "plugin."+tool.Name(), "version", tool.Version())
+	}
+	return plugins
+}
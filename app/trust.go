@@ -0,0 +1,72 @@
+package app
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/security"
+)
+
+// workspaceTrust is what the user decided about the project directory.
+// Until the project is trusted, its files are read only and commands and
+// MCP tools cannot run.
+type workspaceTrust struct {
+	store *security.TrustStore // Nil if decisions cannot be saved
+
+	mu    sync.Mutex
+	level security.TrustLevel
+}
+
+// loadWorkspaceTrust looks up the decision made for project. Decisions
+// that cannot be read leave the project undecided.
+func loadWorkspaceTrust(project string, logger *logging.Logger) *workspaceTrust {
+	t := &workspaceTrust{}
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		logger.Warn("Workspace trust decisions cannot be saved", "error", err.Error())
+		return t
+	}
+	t.store, err = security.LoadTrustStore(filepath.Join(dir, security.TrustFileName))
+	if err != nil {
+		logger.Warn("Failed to load workspace trust decisions", "error", err.Error())
+	}
+	t.level = t.store.Level(project)
+	return t
+}
+
+// WorkspaceTrust returns the trust level of the project directory and
+// whether the user still has to decide it.
+func (app *Application) WorkspaceTrust() (security.TrustLevel, bool) {
+	app.trust.mu.Lock()
+	defer app.trust.mu.Unlock()
+	return app.trust.level, app.trust.level == security.TrustUnknown
+}
+
+// TrustWorkspace records whether the user trusts the project directory and
+// applies the matching permission profile. The decision is remembered for
+// the directory and everything inside it.
+func (app *Application) TrustWorkspace(trusted bool) error {
+	level := security.TrustRestricted
+	if trusted {
+		level = security.TrustTrusted
+	}
+	if err := security.ApplyTrust(level, app.PermManager, app.roots); err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to apply workspace trust")
+	}
+
+	app.trust.mu.Lock()
+	app.trust.level = level
+	app.trust.mu.Unlock()
+	app.Logger.Info("Workspace trust set", "project", app.Project, "trust", string(level))
+
+	if app.trust.store == nil {
+		return errors.New(errors.ErrCodeInternal, "workspace trust applies to this session only: the config directory is unavailable")
+	}
+	if err := app.trust.store.Set(app.Project, level); err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to save workspace trust")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/models"
+)
+
+// defaultWarmUpInterval is how often a model is pinged when no keep-alive is
+// configured. Ollama unloads idle models after five minutes by default.
+const defaultWarmUpInterval = 2 * time.Minute
+
+// warmUp keeps a local provider's model loaded so prompts don't wait for it.
+type warmUp struct {
+	cancel context.CancelFunc
+	models chan string // Model to load next, replacing the current one
+}
+
+// startWarmUp loads model in the background and pings it every half
+// keep-alive period until Close. A failed load is retried at the next ping.
+// When the model is switched, the previous one is unloaded if the provider
+// can unload models. A model that isn't installed is offered to be pulled
+// instead of loaded. Providers without a preloader are not warmed up.
+func (app *Application) startWarmUp(p models.Provider, model string, keepAlive time.Duration) {
+	if _, ok := models.AsPreloader(p); !ok {
+		return
+	}
+	interval := keepAlive / 2
+	if interval <= 0 {
+		interval = defaultWarmUpInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &warmUp{cancel: cancel, models: make(chan string, 1)}
+	app.warmUp = w
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		loaded := ""
+		missing := "" // Model offered to be pulled, so it isn't offered at every ping
+		for {
+			if model != loaded && loaded != "" {
+				app.unload(ctx, p, loaded)
+				loaded = ""
+			}
+
+			if loaded == "" && !modelInstalled(ctx, p, model) {
+				if ctx.Err() != nil {
+					return
+				}
+				if model != missing {
+					missing = model
+					app.offerPull(model)
+				}
+			} else {
+				missing = ""
+				if model != loaded {
+					app.publishModelLoad(model, events.ModelLoading, nil)
+				}
+
+				start := time.Now()
+				err := preload(ctx, p, model, app.loadProgress(model))
+				if ctx.Err() != nil {
+					return
+				}
+				switch {
+				case err != nil:
+					loaded = ""
+					app.Logger.Warn("Failed to preload model", "model", model, "error", err.Error())
+					app.publishModelLoad(model, events.ModelFailed, err)
+				case model != loaded:
+					loaded = model
+					app.Logger.Info("Model preloaded", "model", model, "duration", time.Since(start).String())
+					app.publishModelLoad(model, events.ModelReady, nil)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case model = <-w.models:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// warmModel switches the warm-up to another model, if one is running, and
+// otherwise only offers to pull the model if it isn't installed.
+func (app *Application) warmModel(model string) {
+	if app.warmUp == nil {
+		app.checkModel(model)
+		return
+	}
+	// Only the latest model matters, so replace any pending one
+	select {
+	case <-app.warmUp.models:
+	default:
+	}
+	app.warmUp.models <- model
+}
+
+// stopWarmUp stops the keep-alive pings.
+func (app *Application) stopWarmUp() {
+	if app.warmUp != nil {
+		app.warmUp.cancel()
+	}
+}
+
+// loadProgress returns a callback publishing the load progress of model, at
+// most once per percent.
+func (app *Application) loadProgress(model string) func(float64) {
+	last := -1
+	return func(fraction float64) {
+		percent := int(fraction * 100)
+		if percent <= last || percent >= 100 {
+			return
+		}
+		last = percent
+		app.Events.Publish(events.ModelLoadChanged{Model: model, State: events.ModelLoading, Progress: fraction, Time: time.Now()})
+	}
+}
+
+// unload frees the memory of a model that is no longer used, if p can.
+func (app *Application) unload(ctx context.Context, p models.Provider, model string) {
+	unloader, ok := models.AsUnloader(p)
+	if !ok {
+		return
+	}
+	_, modelID, err := models.ParseModelName(model)
+	if err != nil {
+		return
+	}
+	if err := unloader.Unload(ctx, modelID); err != nil {
+		app.Logger.Debug("Failed to unload model", "model", model, "error", err.Error())
+		return
+	}
+	app.Logger.Info("Model unloaded", "model", model)
+}
+
+// publishModelLoad reports a model load state change on the event bus.
+func (app *Application) publishModelLoad(model, state string, err error) {
+	e := events.ModelLoadChanged{Model: model, State: state, Time: time.Now()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	app.Events.Publish(e)
+}
+
+// preload loads a model given by its full "provider/model" name, reporting
+// progress if the provider does.
+func preload(ctx context.Context, p models.Provider, model string, progress func(float64)) error {
+	_, modelID, err := models.ParseModelName(model)
+	if err != nil {
+		return err
+	}
+	if pp, ok := models.AsProgressPreloader(p); ok {
+		return pp.PreloadProgress(ctx, modelID, progress)
+	}
+	preloader, _ := models.AsPreloader(p)
+	return preloader.Preload(ctx, modelID)
+}
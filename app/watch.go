@@ -0,0 +1,66 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/tools/file"
+)
+
+// fileWatcher starts the watcher of the files the agent works with,
+// publishing every change made outside b+ on bus. Without one, changes go
+// unnoticed and core.watch is not offered.
+func fileWatcher(bus *events.Bus, logger *logging.Logger) *file.Watcher {
+	w, err := file.NewWatcher(file.WithChangeListener(func(c file.Change) {
+		bus.Publish(events.FileChanged{Path: c.Path, Kind: c.Kind, Time: c.Time})
+	}))
+	if err != nil {
+		logger.Warn("Files changed outside b+ will not be noticed", "error", err.Error())
+		return nil
+	}
+	return w
+}
+
+// watchFiles watches the files a successful call read or wrote, taking
+// their state after the call as the one the agent knows.
+func (app *Application) watchFiles(call execution.ToolExecution) {
+	if app.Watcher == nil || call.Result == nil || !call.Result.Success {
+		return
+	}
+	paths := editedPaths(call)
+	if strings.TrimPrefix(call.ToolName, "core.") == "read" && call.Result.Metadata != nil {
+		if path, _ := call.Result.Metadata["path"].(string); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		if err := app.Watcher.Watch(path); err != nil {
+			app.Logger.Debug("Failed to watch file", "path", path, "error", err.Error())
+		}
+	}
+}
+
+// externalChanges describes the files changed outside b+ since the agent
+// was last told, or returns "" if none were.
+func (app *Application) externalChanges() string {
+	if app.Watcher == nil {
+		return ""
+	}
+	return file.DescribeChanges(app.Watcher.Changes(), app.Project)
+}
+
+// addExternalChanges adds the files changed outside b+ between turns to
+// the context of the next one.
+func (app *Application) addExternalChanges(req *execution.AgentRequest) {
+	note := app.externalChanges()
+	if note == "" {
+		return
+	}
+	if req.Context != "" {
+		req.Context += "\n\n"
+	}
+	req.Context += note
+	app.Logger.Debug("Files changed outside b+ added to context")
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/worklog"
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/router"
+)
+
+// worklogTimeout bounds summarizing a task for the worklog.
+const worklogTimeout = 30 * time.Second
+
+// worklogMaxTokens is enough for the one-sentence summary asked for.
+const worklogMaxTokens = 300
+
+// logWork appends an entry for a turn that changed files to the project's
+// worklog, if session.worklog is on. It runs in the background, so the turn
+// isn't held up by the summary.
+func (app *Application) logWork(req *execution.AgentRequest, resp *execution.AgentResponse) {
+	if !app.Config.Session.Worklog {
+		return
+	}
+	files := app.changedFiles(resp.ToolCalls)
+	if len(files) == 0 {
+		return
+	}
+	entry := worklog.Entry{
+		Time:    time.Now(),
+		Session: req.SessionID,
+		Model:   app.CurrentModel(),
+		Files:   files,
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), worklogTimeout)
+		defer cancel()
+
+		entry.What, entry.Why = app.summarizeWork(ctx, req.UserMessage, resp.Content, files)
+		path := filepath.Join(app.Project, worklog.DefaultPath)
+		if err := worklog.Append(path, entry); err != nil {
+			app.Logger.Warn("Failed to write worklog", "path", path, "error", err.Error())
+			return
+		}
+		app.Logger.Debug("Worklog entry added", "path", path, "files", len(files))
+	}()
+}
+
+// summarizeWork asks the quick task model what changed and why, describing
+// the task from the request and answer themselves if it can't.
+func (app *Application) summarizeWork(ctx context.Context, request, answer string, files []string) (what, why string) {
+	model := app.QuickModel(ctx, router.TaskSummary)
+	providerName, _, err := models.ParseModelName(model)
+	if err != nil {
+		return worklog.Fallback(request, answer)
+	}
+	provider, ok := app.Router.Providers()[providerName]
+	if !ok {
+		return worklog.Fallback(request, answer)
+	}
+
+	resp, err := provider.CreateCompletion(ctx, &models.CompletionRequest{
+		Model:     model,
+		Messages:  []models.Message{{Role: "user", Content: worklog.Prompt(request, answer, files)}},
+		MaxTokens: worklogMaxTokens,
+	})
+	if err != nil {
+		app.Logger.Debug("Failed to summarize work", "model", model, "error", err.Error())
+		return worklog.Fallback(request, answer)
+	}
+	if what, why, ok := worklog.ParseSummary(resp.Content); ok {
+		return what, why
+	}
+	return worklog.Fallback(request, answer)
+}
+
+// changedFiles returns the files written or edited during a turn, relative
+// to the project where they are inside it, sorted.
+func (app *Application) changedFiles(calls []execution.ToolExecution) []string {
+	seen := make(map[string]bool)
+	for _, call := range calls {
+		for _, path := range editedPaths(call) {
+			if rel, err := filepath.Rel(app.Project, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			seen[filepath.ToSlash(path)] = true
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for f := range seen {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// editedPaths returns the files a successful call to an editing tool
+// wrote, as the tool reported them. Partial writes are left out until the
+// content is complete.
+func editedPaths(call execution.ToolExecution) []string {
+	switch strings.TrimPrefix(call.ToolName, "core.") {
+	case "write", "edit", "write_files", "patch", "lsp_rename", "format":
+	default:
+		return nil
+	}
+	if call.Result == nil || !call.Result.Success || call.Result.Metadata == nil {
+		return nil
+	}
+	if partial, _ := call.Result.Metadata["partial"].(bool); partial {
+		return nil
+	}
+	paths, _ := call.Result.Metadata["paths"].([]string)
+	if path, _ := call.Result.Metadata["path"].(string); path != "" {
+		paths = append(paths, path)
+	}
+	return paths
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/flags"
+	"github.com/abrksh22/bplus/internal/worktree"
+)
+
+// defaultRemote is the remote worktree branches are pushed to when
+// session.worktree.remote is unset.
+const defaultRemote = "origin"
+
+// Worktree returns the linked git worktree the session runs in and the work
+// done in it, for /finish to merge, push or discard.
+func (app *Application) Worktree(ctx context.Context) (*worktree.Worktree, *worktree.Changes, error) {
+	if !app.Flags.Enabled(flags.WorktreeFinish) {
+		return nil, nil, errors.Newf(errors.ErrCodeUser, "/finish is experimental; turn it on in /flags or with %s=1", flags.EnvName(flags.WorktreeFinish))
+	}
+	w, err := worktree.Detect(ctx, app.Project, app.Config.Session.Worktree.Base)
+	if stderrors.Is(err, worktree.ErrNotWorktree) {
+		return nil, nil, errors.Newf(errors.ErrCodeUser, "%s is not a linked git worktree", app.Project)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrCodeUser, "cannot finish worktree")
+	}
+	changes, err := w.Changes(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to diff worktree")
+	}
+	return w, changes, nil
+}
+
+// TestCommand returns the command the merge result is tested with:
+// session.worktree.test_command, or the usual one for the project.
+func (app *Application) TestCommand(w *worktree.Worktree) string {
+	if cmd := app.Config.Session.Worktree.TestCommand; cmd != "" {
+		return cmd
+	}
+	return worktree.DetectTestCommand(w.Dir)
+}
+
+// TestMerge runs the tests on the base branch with the changes applied,
+// without touching either worktree.
+func (app *Application) TestMerge(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) (*worktree.TestResult, error) {
+	command := app.TestCommand(w)
+	if command == "" {
+		return nil, errors.New(errors.ErrCodeConfig, "no test command; set session.worktree.test_command")
+	}
+	app.Logger.Info("Testing merge result", "branch", w.Branch, "base", w.Base, "command", command)
+	result, err := w.Test(ctx, c, command)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeToolExecution, "failed to test merge result")
+	}
+	return result, nil
+}
+
+// SquashMerge commits the changes to the base branch as one commit.
+func (app *Application) SquashMerge(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) error {
+	if err := w.SquashMerge(ctx, c, w.Message(ctx, c)); err != nil {
+		return errors.Wrapf(err, errors.ErrCodeToolExecution, "failed to merge %s into %s", w.Branch, w.Base)
+	}
+	app.Logger.Info("Squash-merged worktree", "branch", w.Branch, "base", w.Base, "files", c.Files)
+	return nil
+}
+
+// PushWorktree pushes the worktree's branch to session.worktree.remote.
+func (app *Application) PushWorktree(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) error {
+	remote := app.Config.Session.Worktree.Remote
+	if remote == "" {
+		remote = defaultRemote
+	}
+	if err := w.Push(ctx, c, remote); err != nil {
+		return errors.Wrap(err, errors.ErrCodeNetwork, "failed to push worktree branch")
+	}
+	app.Logger.Info("Pushed worktree branch", "branch", w.Branch, "remote", remote)
+	return nil
+}
+
+// DiscardWorktree removes the worktree and its branch. The session can't
+// go on afterwards, as its project directory is gone.
+func (app *Application) DiscardWorktree(ctx context.Context, w *worktree.Worktree) error {
+	if err := w.Discard(ctx); err != nil {
+		return errors.Wrap(err, errors.ErrCodeToolExecution, "failed to discard worktree")
+	}
+	app.Logger.Info("Discarded worktree", "branch", w.Branch, "dir", w.Dir)
+	return nil
+}
@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 
 	"github.com/abrksh22/bplus/app"
+	"github.com/abrksh22/bplus/internal/assets"
 	"github.com/abrksh22/bplus/ui"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -21,6 +26,23 @@ var (
 )
 
 func main() {
+	// Subcommands run without the UI
+	if len(os.Args) > 1 && os.Args[1] == "context" {
+		os.Exit(runContext(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "models" {
+		os.Exit(runModels(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		os.Exit(runBackup(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "assets" {
+		os.Exit(runAssets(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mcp-serve" {
+		os.Exit(runMCPServe(os.Args[2:]))
+	}
+
 	// Define command-line flags
 	var (
 		showVersion  = flag.Bool("version", false, "Show version information")
@@ -29,8 +51,18 @@ func main() {
 		fastMode     = flag.Bool("fast", false, "Run in Fast Mode (Layer 4 only)")
 		thoroughMode = flag.Bool("thorough", false, "Run in Thorough Mode (all 7 layers)")
 		configFile   = flag.String("config", "", "Path to config file")
+		modelName    = flag.String("model", "", "Default model as provider/model-id or an alias such as sonnet")
+		offlineMode  = flag.Bool("offline", false, "Disable remote providers and web tools (local models only)")
+		noLSP        = flag.Bool("no-lsp", false, "Do not start language servers or offer the lsp tools")
+		recordFile   = flag.String("record", "", "Record provider requests and responses to a file, secrets redacted")
+		replayFile   = flag.String("replay", "", "Replay provider responses from a recorded file instead of calling providers")
+		importPath   = flag.String("import", "", "Import session history from a file or directory and exit")
+		importFrom   = flag.String("import-from", "", "History format for --import: claude-code, codex, aider (default: auto-detect)")
 	)
 
+	var roots rootFlags
+	flag.Var(&roots, "add-dir", "Attach another directory to the session as [name=]path[:ro] (repeatable)")
+
 	// Short flags
 	flag.BoolVar(showVersion, "v", false, "Show version information (shorthand)")
 	flag.BoolVar(showHelp, "h", false, "Show help message (shorthand)")
@@ -58,6 +90,17 @@ func main() {
 		DebugMode:  *debugMode,
 		FastMode:   *fastMode,
 		Thorough:   *thoroughMode,
+		Offline:    *offlineMode,
+		NoLSP:      *noLSP,
+		Model:      *modelName,
+		Record:     *recordFile,
+		Replay:     *replayFile,
+		Roots:      roots,
+	}
+
+	// Handle --import before starting the UI
+	if *importPath != "" {
+		os.Exit(runImport(opts, *importFrom, *importPath))
 	}
 
 	application, err := app.New(opts)
@@ -65,7 +108,6 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to initialize b+: %v\n", err)
 		os.Exit(1)
 	}
-	defer application.Close()
 
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -91,21 +133,297 @@ func main() {
 	)
 
 	// Start the program
+	code := 0
 	finalModel, err := program.Run()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running b+: %v\n", err)
-		os.Exit(1)
+		code = 1
+	} else if m, ok := finalModel.(*ui.Model); ok && m.Error() != nil {
+		// Check if there was an error in the final model
+		fmt.Fprintf(os.Stderr, "Error: %v\n", m.Error())
+		code = 1
 	}
 
-	// Check if there was an error in the final model
-	if m, ok := finalModel.(*ui.Model); ok {
-		if m.Error() != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", m.Error())
-			os.Exit(1)
+	// In CI, validation findings annotate the diff under review
+	if err := application.ReportFindings(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// os.Exit skips deferred calls
+	application.Close()
+	os.Exit(code)
+}
+
+// runImport imports history from another tool and returns the exit code.
+func runImport(opts *app.Options, source, path string) int {
+	results, err := app.ImportSessions(opts, source, path)
+	imported, skipped := 0, 0
+	for _, r := range results {
+		if r.Skipped {
+			skipped++
+			continue
 		}
+		imported++
+		fmt.Printf("Imported %q (%d messages, %d files) as %s\n", r.Title, r.Messages, r.Files, r.SessionID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
 	}
 
-	os.Exit(0)
+	fmt.Printf("%d session(s) imported, %d already present\n", imported, skipped)
+	return 0
+}
+
+// runContext runs "bplus context <command>" and returns the exit code.
+func runContext(args []string) int {
+	if len(args) == 0 || args[0] != "dump" {
+		fmt.Fprintln(os.Stderr, "Usage: bplus context dump <session> [--format json|parquet] [--output <file>]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("context dump", flag.ContinueOnError)
+	format := fs.String("format", "json", "Output format: json, parquet")
+	output := fs.String("output", "", "Write to a file instead of stdout")
+	configFile := fs.String("config", "", "Path to config file")
+
+	// Accept the session before or after the flags
+	rest := args[1:]
+	var sessionID string
+	if len(rest) > 0 && rest[0] != "" && rest[0][0] != '-' {
+		sessionID, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+	if sessionID == "" && fs.NArg() > 0 {
+		sessionID = fs.Arg(0)
+	}
+	if sessionID == "" {
+		fmt.Fprintln(os.Stderr, "Usage: bplus context dump <session> [--format json|parquet] [--output <file>]")
+		return 2
+	}
+	if *format != "json" && *format != "parquet" {
+		fmt.Fprintf(os.Stderr, "Unknown format %q (want json or parquet)\n", *format)
+		return 2
+	}
+
+	dump, err := app.DumpContext(&app.Options{Version: Version, ConfigPath: *configFile}, sessionID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Context dump failed: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Context dump failed: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "parquet" {
+		err = dump.WriteParquet(w)
+	} else {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(dump)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Context dump failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runModels runs "bplus models <command>" and returns the exit code.
+func runModels(args []string) int {
+	if len(args) == 0 || args[0] != "refresh-pricing" {
+		fmt.Fprintln(os.Stderr, "Usage: bplus models refresh-pricing [--url <url>]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("models refresh-pricing", flag.ContinueOnError)
+	url := fs.String("url", "", "Fetch the pricing table from this URL instead of the published one")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	table, path, err := app.RefreshPricing(context.Background(), *url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Pricing refresh failed: %v\n", err)
+		return 1
+	}
+
+	entries := 0
+	for _, prices := range table.Providers {
+		entries += len(prices)
+	}
+	fmt.Printf("Pricing updated %s: %d provider(s), %d price(s), saved to %s\n", table.Updated, len(table.Providers), entries, path)
+	return 0
+}
+
+// runBackup runs "bplus backup <command>" and returns the exit code.
+func runBackup(args []string) int {
+	const usage = "Usage: bplus backup now|list|restore <file> [--config <path>]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("backup "+args[0], flag.ContinueOnError)
+	configFile := fs.String("config", "", "Path to config file")
+	rest := args[1:]
+	var file string
+	if len(rest) > 0 && rest[0] != "" && rest[0][0] != '-' {
+		file, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+	if file == "" && fs.NArg() > 0 {
+		file = fs.Arg(0)
+	}
+	opts := &app.Options{Version: Version, ConfigPath: *configFile}
+
+	switch args[0] {
+	case "now":
+		path, err := app.BackupNow(opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Backup failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Database backed up to %s\n", path)
+	case "list":
+		backups, err := app.ListBackups(opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Listing backups failed: %v\n", err)
+			return 1
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups yet")
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %s  %d KB\n", b.Time.Local().Format("2006-01-02 15:04:05"), b.Path, b.Size/1024)
+		}
+	case "restore":
+		if file == "" {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		saved, err := app.RestoreBackup(opts, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Restore failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Database restored from %s; the previous database was backed up to %s\n", file, saved)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// runMCPServe runs "bplus mcp-serve", serving tools over MCP on stdio
+// until the client disconnects, and returns the exit code.
+func runMCPServe(args []string) int {
+	fs := flag.NewFlagSet("mcp-serve", flag.ContinueOnError)
+	configFile := fs.String("config", "", "Path to config file")
+	toolList := fs.String("tools", strings.Join(app.MCPTools, ","), "Comma-separated tools to serve")
+	offlineMode := fs.Bool("offline", false, "Disable network access for the served tools")
+	var roots rootFlags
+	fs.Var(&roots, "add-dir", "Attach another directory as [name=]path[:ro] (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var names []string
+	for _, name := range strings.Split(*toolList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: bplus mcp-serve [--tools read,write,...] [--add-dir <spec>] [--config <path>]")
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	// Language servers are only started for the lsp tools
+	noLSP := !slices.ContainsFunc(names, func(name string) bool { return strings.HasPrefix(name, "lsp_") })
+	opts := &app.Options{Version: Version, ConfigPath: *configFile, Offline: *offlineMode, NoLSP: noLSP, Roots: roots}
+	if err := app.ServeMCP(ctx, opts, names, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "MCP server failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runAssets runs "bplus assets <command>" and returns the exit code.
+func runAssets(args []string) int {
+	const usage = "Usage: bplus assets list|export [dir] [--force]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("assets "+args[0], flag.ContinueOnError)
+	force := fs.Bool("force", false, "Overwrite files already in the directory")
+	rest := args[1:]
+	var dir string
+	if len(rest) > 0 && rest[0] != "" && rest[0][0] != '-' {
+		dir, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+	if dir == "" && fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	switch args[0] {
+	case "list":
+		fmt.Printf("Search path: %s\n", strings.Join(assets.SearchPath(), string(os.PathListSeparator)))
+		for _, group := range []string{assets.Migrations, assets.Prompts, assets.Themes, assets.Tokenizers} {
+			for _, name := range assets.List(group) {
+				fmt.Printf("%s/%s  %s\n", group, name, assets.Source(group+"/"+name))
+			}
+		}
+	case "export":
+		if path := assets.SearchPath(); dir == "" && len(path) > 0 {
+			dir = path[len(path)-1]
+		}
+		if dir == "" {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		written, err := assets.Export(dir, *force)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Exported %d asset(s) to %s\n", len(written), dir)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// rootFlags collects the values of the repeatable --add-dir flag.
+type rootFlags []string
+
+func (r *rootFlags) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *rootFlags) Set(value string) error {
+	*r = append(*r, value)
+	return nil
 }
 
 func printHelp() {
@@ -113,6 +431,11 @@ func printHelp() {
 
 Usage:
   bplus [flags]
+  bplus context dump <session> [--format json|parquet] [--output <file>]
+  bplus models refresh-pricing [--url <url>]
+  bplus backup now|list|restore <file>
+  bplus assets list|export [dir]
+  bplus mcp-serve [--tools <list>]
 
 Core Flags:
   -h, --help              Show this help message
@@ -122,14 +445,63 @@ Core Flags:
 Execution Modes:
       --fast              Run in Fast Mode (Layer 4 only) - default
       --thorough          Run in Thorough Mode (all 7 layers active)
+      --offline           Local models only; remote providers and web tools disabled
 
 Configuration:
       --config <path>     Path to config file (default: ~/.config/bplus/config.yaml)
+      --model <name>      Default model as provider/model-id or an alias (sonnet, opus,
+                          haiku, gpt, cheap, local or one set in models.aliases)
+
+Workspace:
+      --add-dir <spec>    Attach another directory as [name=]path[:ro]; repeatable.
+                          Files in it are referred to as name:path
+      --no-lsp            Don't start language servers (gopls, tsserver, pyright)
+
+Recording:
+      --record <file>     Record provider traffic to a file (secrets redacted)
+      --replay <file>     Answer provider requests from a recording, offline
+
+Session Import:
+      --import <path>     Import history from a file or directory and exit
+      --import-from <src> Format: claude-code, codex, aider (default: auto-detect)
+
+Context Export:
+  context dump <session>  Export context items with tiers, relevance and token counts
+      --format <fmt>      Output format: json, parquet (default: json)
+      --output <file>     Write to a file instead of stdout
+
+Pricing:
+  models refresh-pricing  Fetch the latest token prices into the config directory
+      --url <url>         Fetch from this URL instead of the published table
+
+Backups:
+  backup now              Back up the session database and rotate old backups
+  backup list             List backups, newest first
+  backup restore <file>   Verify a backup, save the current database, then restore
+                          it; quit bplus first
+
+Assets:
+  assets list             List built-in assets and the overrides in use
+  assets export [dir]     Copy the prompts, themes and tokenizer files out for editing
+                          (default: assets/ in the config directory)
+      --force             Overwrite files already there
+
+MCP Server:
+  mcp-serve               Serve tools to an editor or agent over MCP on stdio,
+                          with b+'s permission checks and sandboxing
+      --tools <list>      Tools to serve (default: read,write,edit,grep,bash)
+      --add-dir <spec>    Attach another directory, as for the UI
 
 Examples:
   bplus                   # Start in Fast Mode with default settings
   bplus --thorough        # Start in Thorough Mode for complex tasks
   bplus --debug           # Start with debug logging enabled
+  bplus --offline         # Run fully offline against Ollama/LM Studio
+  bplus --model opus      # Start with the model the "opus" alias stands for
+  bplus --add-dir infra=../infra:ro   # Work across this repo and a read-only one
+  bplus --replay bug.jsonl  # Reproduce a recorded session without providers
+  bplus --import ~/.claude/projects/myapp   # Import Claude Code history
+  bplus context dump <id> --format parquet --output ctx.parquet
   bplus --version         # Show version information
 
 For more information, visit: https://github.com/abrksh22/bplus
@@ -0,0 +1,208 @@
+// Package assets holds the files bplus needs at runtime, built into the
+// binary so it runs standalone: SQL migrations, default prompts, UI themes
+// and tokenizer vocabularies.
+//
+// Prompts, themes and tokenizers can be overridden without rebuilding:
+// a file of the same name in a directory of SearchPath() takes precedence
+// over the built-in one, and new themes or vocabularies placed there are
+// picked up alongside the built-in ones. Migrations always come from the
+// binary, as the database schema must match the code reading it.
+package assets
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/config"
+)
+
+//go:embed migrations prompts themes tokenizers
+var embedded embed.FS
+
+// EnvPath is the environment variable listing override directories,
+// separated like PATH.
+const EnvPath = "BPLUS_ASSETS_PATH"
+
+// Asset directories
+const (
+	Migrations = "migrations"
+	Prompts    = "prompts"
+	Themes     = "themes"
+	Tokenizers = "tokenizers"
+)
+
+// overridable are the directories whose files can be overridden.
+var overridable = map[string]bool{Prompts: true, Themes: true, Tokenizers: true}
+
+// SearchPath returns the directories searched for overrides, in order:
+// those of $BPLUS_ASSETS_PATH, then assets/ in the config directory.
+func SearchPath() []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv(EnvPath)) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if configDir, err := config.GetConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configDir, "assets"))
+	}
+	return dirs
+}
+
+// Read returns the contents of an asset, named by its slash-separated path
+// such as "themes/dark.yaml", from the first override directory that has it,
+// or the built-in copy.
+func Read(name string) ([]byte, error) {
+	if overridable[topDir(name)] {
+		for _, dir := range SearchPath() {
+			data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err == nil {
+				return data, nil
+			}
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	return ReadBuiltin(name)
+}
+
+// ReadBuiltin returns the contents of the built-in copy of an asset.
+func ReadBuiltin(name string) ([]byte, error) {
+	data, err := embedded.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("asset %s not found", name)
+	}
+	return data, nil
+}
+
+// Source returns where Read finds an asset: the path of its override, or
+// "builtin", or "" if there is none.
+func Source(name string) string {
+	if overridable[topDir(name)] {
+		for _, dir := range SearchPath() {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	if _, err := fs.Stat(embedded, name); err == nil {
+		return "builtin"
+	}
+	return ""
+}
+
+// List returns the sorted names of the files in an asset directory, built
+// in or in an override directory, without the directory prefix.
+func List(dir string) []string {
+	seen := make(map[string]bool)
+	if entries, err := embedded.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() {
+				seen[e.Name()] = true
+			}
+		}
+	}
+	if overridable[dir] {
+		for _, base := range SearchPath() {
+			entries, err := os.ReadDir(filepath.Join(base, dir))
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+					seen[e.Name()] = true
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export writes the built-in assets into dir, keeping their layout, as a
+// starting point for overrides. Existing files are left alone unless
+// overwrite is set. It returns the paths written.
+func Export(dir string, overwrite bool) ([]string, error) {
+	var written []string
+	err := fs.WalkDir(embedded, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !overridable[topDir(name)] {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(target); err == nil && !overwrite {
+			return nil
+		}
+		data, err := embedded.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		written = append(written, target)
+		return nil
+	})
+	return written, err
+}
+
+// Migration is one step of the database schema.
+type Migration struct {
+	Version int    // Schema version the step brings the database to
+	Name    string // File name, such as 001_schema.sql
+	SQL     string
+}
+
+// LoadMigrations returns the built-in migrations in version order. Their
+// files are named NNN_description.sql.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := embedded.ReadDir(Migrations)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no version prefix", e.Name())
+		}
+		data, err := embedded.ReadFile(path.Join(Migrations, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: e.Name(), SQL: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migrations[i-1].Name, migrations[i].Name, migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// topDir returns the first element of a slash-separated asset name.
+func topDir(name string) string {
+	dir, _, _ := strings.Cut(name, "/")
+	return dir
+}
@@ -0,0 +1,98 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isolate points the search path at a fresh directory only.
+func isolate(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv(EnvPath, dir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	return dir
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, m.Name)
+		assert.NotEmpty(t, strings.TrimSpace(m.SQL), m.Name)
+	}
+	assert.Contains(t, migrations[0].SQL, "CREATE TABLE IF NOT EXISTS schema_version")
+}
+
+func TestReadOverride(t *testing.T) {
+	dir := isolate(t)
+
+	builtin, err := Read("themes/dark.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "builtin", Source("themes/dark.yaml"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, Themes), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Themes, "dark.yaml"), []byte("primary: \"#000000\"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Themes, "mine.yaml"), []byte("primary: \"#FFFFFF\"\n"), 0644))
+
+	data, err := Read("themes/dark.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "primary: \"#000000\"\n", string(data))
+	assert.Equal(t, filepath.Join(dir, Themes, "dark.yaml"), Source("themes/dark.yaml"))
+
+	data, err = ReadBuiltin("themes/dark.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, builtin, data)
+
+	names := List(Themes)
+	assert.Contains(t, names, "dark.yaml")
+	assert.Contains(t, names, "mine.yaml")
+	assert.Contains(t, names, "nord.yaml")
+
+	_, err = Read("themes/missing.yaml")
+	assert.Error(t, err)
+	assert.Empty(t, Source("themes/missing.yaml"))
+}
+
+func TestMigrationsNotOverridable(t *testing.T) {
+	dir := isolate(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, Migrations), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Migrations, "001_schema.sql"), []byte("DROP TABLE sessions;"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Migrations, "004_extra.sql"), []byte("SELECT 1;"), 0644))
+
+	data, err := Read("migrations/001_schema.sql")
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "DROP TABLE")
+	assert.NotContains(t, List(Migrations), "004_extra.sql")
+	assert.Equal(t, "builtin", Source("migrations/001_schema.sql"))
+}
+
+func TestExport(t *testing.T) {
+	isolate(t)
+	dir := t.TempDir()
+
+	written, err := Export(dir, false)
+	require.NoError(t, err)
+	assert.Contains(t, written, filepath.Join(dir, Prompts, "layer4.md"))
+	assert.NoFileExists(t, filepath.Join(dir, Migrations, "001_schema.sql"))
+
+	path := filepath.Join(dir, Themes, "dark.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("edited"), 0644))
+	written, err = Export(dir, false)
+	require.NoError(t, err)
+	assert.Empty(t, written)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "edited", string(data))
+
+	_, err = Export(dir, true)
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotEqual(t, "edited", string(data))
+}
@@ -0,0 +1,105 @@
+-- Schema version tracking
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sessions table
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	context_snapshot TEXT,
+	metadata TEXT -- JSON
+);
+
+-- Messages table
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	role TEXT NOT NULL, -- 'user', 'assistant', 'system', 'tool'
+	content TEXT NOT NULL,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	tokens_input INTEGER DEFAULT 0,
+	tokens_output INTEGER DEFAULT 0,
+	cost REAL DEFAULT 0.0,
+	metadata TEXT, -- JSON
+	FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Files table (tracks files in session context)
+CREATE TABLE IF NOT EXISTS files (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	path TEXT NOT NULL,
+	content_hash TEXT,
+	modified_at TIMESTAMP,
+	size_bytes INTEGER,
+	FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+	UNIQUE(session_id, path)
+);
+
+-- Checkpoints table
+CREATE TABLE IF NOT EXISTS checkpoints (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	name TEXT,
+	state_snapshot TEXT NOT NULL, -- JSON
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Operations table (for undo/redo)
+CREATE TABLE IF NOT EXISTS operations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	type TEXT NOT NULL, -- 'file_write', 'file_delete', 'command', etc.
+	details TEXT, -- JSON
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	reversible BOOLEAN DEFAULT 1,
+	FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Metrics table
+CREATE TABLE IF NOT EXISTS metrics (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT,
+	metric_type TEXT NOT NULL, -- 'cost', 'tokens', 'duration', etc.
+	metric_name TEXT,
+	value REAL NOT NULL,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	metadata TEXT -- JSON
+);
+
+-- Create indexes for common queries
+CREATE INDEX IF NOT EXISTS idx_messages_session ON messages(session_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_files_session ON files(session_id);
+CREATE INDEX IF NOT EXISTS idx_operations_session ON operations(session_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_metrics_session ON metrics(session_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_metrics_type ON metrics(metric_type, timestamp);
+
+-- Create FTS5 virtual table for full-text search
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+	session_id UNINDEXED,
+	role UNINDEXED,
+	content,
+	content=messages,
+	content_rowid=id
+);
+
+-- Triggers to keep FTS table in sync
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+	INSERT INTO messages_fts(rowid, session_id, role, content)
+	VALUES (new.id, new.session_id, new.role, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+	DELETE FROM messages_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE ON messages BEGIN
+	DELETE FROM messages_fts WHERE rowid = old.id;
+	INSERT INTO messages_fts(rowid, session_id, role, content)
+	VALUES (new.id, new.session_id, new.role, new.content);
+END;
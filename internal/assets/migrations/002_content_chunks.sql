@@ -0,0 +1,10 @@
+-- Out-of-line storage for oversized message content. The content_encoding
+-- and content_size columns of messages are added beforehand, as SQLite has
+-- no ADD COLUMN IF NOT EXISTS.
+CREATE TABLE IF NOT EXISTS message_chunks (
+	message_id INTEGER NOT NULL,
+	seq INTEGER NOT NULL,
+	data BLOB NOT NULL, -- zstd-compressed content, split across rows
+	PRIMARY KEY (message_id, seq),
+	FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
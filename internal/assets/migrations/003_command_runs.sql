@@ -0,0 +1,14 @@
+-- Per-project command history
+CREATE TABLE IF NOT EXISTS command_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	project TEXT NOT NULL,
+	session_id TEXT,
+	command TEXT NOT NULL,
+	working_dir TEXT,
+	shell TEXT,
+	exit_code INTEGER NOT NULL,
+	duration_ms INTEGER DEFAULT 0,
+	favorite BOOLEAN DEFAULT 0,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_command_runs_project ON command_runs(project, timestamp);
@@ -0,0 +1,11 @@
+-- Task lists the agent keeps with core.todo, per session
+CREATE TABLE IF NOT EXISTS todos (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_todos_session ON todos(session_id, position);
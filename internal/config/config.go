@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -22,24 +24,96 @@ type Config struct {
 	Cost        CostConfig        `mapstructure:"cost" yaml:"cost" json:"cost"`                      // Cost management
 	Performance PerformanceConfig `mapstructure:"performance" yaml:"performance" json:"performance"` // Performance settings
 	Logging     LoggingConfig     `mapstructure:"logging" yaml:"logging" json:"logging"`             // Logging configuration
+
+	// Subsystems shipped dark, turned on or off by flag name; see package flags
+	Experimental map[string]bool `mapstructure:"experimental" yaml:"experimental" json:"experimental"`
 }
 
 // ModelConfig defines model selection for all layers
 type ModelConfig struct {
 	Default string            `mapstructure:"default" yaml:"default" json:"default"` // Default model for all layers
 	Layers  map[string]string `mapstructure:"layers" yaml:"layers" json:"layers"`    // Per-layer model overrides
+	Offline string            `mapstructure:"offline" yaml:"offline" json:"offline"` // Local model used with --offline
+	Aliases map[string]string `mapstructure:"aliases" yaml:"aliases" json:"aliases"` // Short names for full model names, e.g. sonnet
+	Quick   QuickModelConfig  `mapstructure:"quick" yaml:"quick" json:"quick"`       // Local fallback for quick tasks
+}
+
+// QuickModelConfig moves quick tasks such as session titles, summaries and
+// relevance scoring to a local model while the remote provider is slow.
+type QuickModelConfig struct {
+	Local      string        `mapstructure:"local" yaml:"local" json:"local"`                   // Local model; empty turns this off
+	MaxLatency time.Duration `mapstructure:"max_latency" yaml:"max_latency" json:"max_latency"` // Remote latency above which Local is used
+}
+
+// DefaultModelAliases returns the model aliases available without
+// configuration.
+func DefaultModelAliases() map[string]string {
+	return map[string]string{
+		"sonnet": "anthropic/claude-sonnet-4-5",
+		"opus":   "anthropic/claude-opus-4-1",
+		"haiku":  "anthropic/claude-haiku-4-0",
+		"gpt":    "openai/gpt-4o",
+		"cheap":  "openrouter/meta-llama/llama-3.1-70b-instruct",
+		"local":  "ollama/qwen2.5-coder:7b",
+	}
 }
 
 // ProviderConfigs contains all provider configurations
 type ProviderConfigs map[string]ProviderConfig
 
+// ProviderTypeOpenAICompatible is the provider type of servers speaking the
+// OpenAI chat completions API that b+ has no dedicated provider for.
+const ProviderTypeOpenAICompatible = "openai-compatible"
+
 // ProviderConfig defines configuration for a single provider
 type ProviderConfig struct {
-	APIKey     string            `mapstructure:"api_key" yaml:"api_key" json:"api_key"`
-	BaseURL    string            `mapstructure:"base_url" yaml:"base_url" json:"base_url"`
-	Timeout    time.Duration     `mapstructure:"timeout" yaml:"timeout" json:"timeout"`
-	MaxRetries int               `mapstructure:"max_retries" yaml:"max_retries" json:"max_retries"`
-	Extra      map[string]string `mapstructure:"extra" yaml:"extra" json:"extra"` // Provider-specific settings
+	Type               string            `mapstructure:"type" yaml:"type" json:"type"` // Empty for built-in providers, or "openai-compatible"
+	APIKey             string            `mapstructure:"api_key" yaml:"api_key" json:"api_key"`
+	APIKeys            []string          `mapstructure:"api_keys" yaml:"api_keys" json:"api_keys"`             // Additional keys to spread load across
+	KeyStrategy        string            `mapstructure:"key_strategy" yaml:"key_strategy" json:"key_strategy"` // "round_robin" (default) or "least_throttled"
+	BaseURL            string            `mapstructure:"base_url" yaml:"base_url" json:"base_url"`
+	Timeout            time.Duration     `mapstructure:"timeout" yaml:"timeout" json:"timeout"`
+	MaxRetries         int               `mapstructure:"max_retries" yaml:"max_retries" json:"max_retries"`
+	Preload            bool              `mapstructure:"preload" yaml:"preload" json:"preload"`                                        // Local providers: load the default model at startup
+	KeepAlive          time.Duration     `mapstructure:"keep_alive" yaml:"keep_alive" json:"keep_alive"`                               // Local providers: how long the model stays loaded while idle
+	ProxyURL           string            `mapstructure:"proxy_url" yaml:"proxy_url" json:"proxy_url"`                                  // HTTP(S) or SOCKS5 proxy; empty uses HTTP(S)_PROXY
+	CACertPath         string            `mapstructure:"ca_cert_path" yaml:"ca_cert_path" json:"ca_cert_path"`                         // PEM bundle trusted besides the system roots
+	InsecureSkipVerify bool              `mapstructure:"insecure_skip_verify" yaml:"insecure_skip_verify" json:"insecure_skip_verify"` // Skip TLS certificate verification
+	Extra              map[string]string `mapstructure:"extra" yaml:"extra" json:"extra"`                                              // Provider-specific settings
+	OIDC               OIDCConfig        `mapstructure:"oidc" yaml:"oidc" json:"oidc"`                                                 // Gateway authentication with an identity provider
+}
+
+// OIDCConfig configures authentication to a gateway in front of a provider,
+// such as an Azure AD-protected endpoint, with access tokens from an OpenID
+// Connect identity provider. Refresh tokens are kept in the OS keychain.
+type OIDCConfig struct {
+	Flow          string   `mapstructure:"flow" yaml:"flow" json:"flow"`       // "client_credentials" (default) or "device_code"
+	Issuer        string   `mapstructure:"issuer" yaml:"issuer" json:"issuer"` // Endpoints are discovered from it unless set below
+	TokenURL      string   `mapstructure:"token_url" yaml:"token_url" json:"token_url"`
+	DeviceAuthURL string   `mapstructure:"device_auth_url" yaml:"device_auth_url" json:"device_auth_url"`
+	ClientID      string   `mapstructure:"client_id" yaml:"client_id" json:"client_id"`
+	ClientSecret  string   `mapstructure:"client_secret" yaml:"client_secret" json:"client_secret"` // Client credentials flow only
+	Scopes        []string `mapstructure:"scopes" yaml:"scopes" json:"scopes"`
+	Audience      string   `mapstructure:"audience" yaml:"audience" json:"audience"`
+}
+
+// Enabled reports whether gateway authentication is configured.
+func (o OIDCConfig) Enabled() bool {
+	return o.ClientID != ""
+}
+
+// Keys returns all configured API keys, APIKey first, without duplicates or blanks.
+func (p ProviderConfig) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, k := range append([]string{p.APIKey}, p.APIKeys...) {
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	return keys
 }
 
 // LayerConfig defines layer-specific settings
@@ -50,47 +124,101 @@ type LayerConfig struct {
 	MainAgent           MainAgentLayerConfig  `mapstructure:"main_agent" yaml:"main_agent" json:"main_agent"`
 	Validation          ValidationLayerConfig `mapstructure:"validation" yaml:"validation" json:"validation"`
 	ContextManagement   ContextLayerConfig    `mapstructure:"context_management" yaml:"context_management" json:"context_management"`
+
+	// External processes implementing layers, keyed by layer name
+	Plugins map[string]LayerPluginConfig `mapstructure:"plugins" yaml:"plugins" json:"plugins"`
+
+	// Substitute models tried in order when a Thorough Mode layer's model
+	// fails its health check, keyed by layer name or "default". An order
+	// ending in "skip" skips the layer with a warning instead of failing.
+	Degradation map[string][]string `mapstructure:"degradation" yaml:"degradation" json:"degradation"`
+
+	// Sampling profiles layers can name, besides the built-in creative,
+	// balanced, precise and deterministic ones; a profile defined here
+	// replaces the built-in one of the same name
+	Profiles map[string]SamplingConfig `mapstructure:"profiles" yaml:"profiles" json:"profiles"`
+}
+
+// DegradationOrder returns the substitutes for a layer, falling back to the
+// "default" order.
+func (l LayerConfig) DegradationOrder(layer string) []string {
+	if order, ok := l.Degradation[layer]; ok {
+		return order
+	}
+	return l.Degradation["default"]
+}
+
+// LayerPluginConfig registers an external process that implements a layer
+// over stdio JSON-RPC
+type LayerPluginConfig struct {
+	Command string            `mapstructure:"command" yaml:"command" json:"command"`
+	Args    []string          `mapstructure:"args" yaml:"args" json:"args"`
+	Env     map[string]string `mapstructure:"env" yaml:"env" json:"env"`
+	Dir     string            `mapstructure:"dir" yaml:"dir" json:"dir"`             // Working directory
+	Timeout time.Duration     `mapstructure:"timeout" yaml:"timeout" json:"timeout"` // Per call (default 60s)
 }
 
 // IntentLayerConfig for Layer 1
 type IntentLayerConfig struct {
-	Enabled  bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
-	Model    string `mapstructure:"model" yaml:"model" json:"model"`
-	MaxTurns int    `mapstructure:"max_turns" yaml:"max_turns" json:"max_turns"`
+	Enabled  bool           `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Model    string         `mapstructure:"model" yaml:"model" json:"model"`
+	MaxTurns int            `mapstructure:"max_turns" yaml:"max_turns" json:"max_turns"`
+	Sampling SamplingConfig `mapstructure:"sampling" yaml:"sampling" json:"sampling"`
 }
 
 // PlanningLayerConfig for Layer 2
 type PlanningLayerConfig struct {
-	Enabled  bool     `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
-	NumPlans int      `mapstructure:"num_plans" yaml:"num_plans" json:"num_plans"`
-	Models   []string `mapstructure:"models" yaml:"models" json:"models"`
+	Enabled  bool           `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	NumPlans int            `mapstructure:"num_plans" yaml:"num_plans" json:"num_plans"`
+	Models   []string       `mapstructure:"models" yaml:"models" json:"models"`
+	Sampling SamplingConfig `mapstructure:"sampling" yaml:"sampling" json:"sampling"`
 }
 
 // SynthesisLayerConfig for Layer 3
 type SynthesisLayerConfig struct {
-	Enabled bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
-	Model   string `mapstructure:"model" yaml:"model" json:"model"`
+	Enabled  bool           `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Model    string         `mapstructure:"model" yaml:"model" json:"model"`
+	Sampling SamplingConfig `mapstructure:"sampling" yaml:"sampling" json:"sampling"`
 }
 
 // MainAgentLayerConfig for Layer 4
 type MainAgentLayerConfig struct {
-	Enabled bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"` // Always true, but kept for consistency
-	Model   string `mapstructure:"model" yaml:"model" json:"model"`
+	Enabled         bool           `mapstructure:"enabled" yaml:"enabled" json:"enabled"` // Always true, but kept for consistency
+	Model           string         `mapstructure:"model" yaml:"model" json:"model"`
+	ReasoningBudget int            `mapstructure:"reasoning_budget" yaml:"reasoning_budget" json:"reasoning_budget"` // Thinking tokens per answer; 0 = provider default
+	Sampling        SamplingConfig `mapstructure:"sampling" yaml:"sampling" json:"sampling"`
+
+	// Times an answer cut off at the token limit is resumed; 0 = default (3), -1 = never
+	MaxContinuations int `mapstructure:"max_continuations" yaml:"max_continuations" json:"max_continuations"`
+
+	// Cheap model streaming a draft answer to questions while Model works on
+	// the one that replaces it; empty = no drafts
+	DraftModel string `mapstructure:"draft_model" yaml:"draft_model" json:"draft_model"`
+
+	// How the agent talks to the user: default, terse, teacher or reviewer
+	Persona string `mapstructure:"persona" yaml:"persona" json:"persona"`
 }
 
 // ValidationLayerConfig for Layer 5
 type ValidationLayerConfig struct {
-	Enabled       bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
-	Model         string `mapstructure:"model" yaml:"model" json:"model"`
-	MaxIterations int    `mapstructure:"max_iterations" yaml:"max_iterations" json:"max_iterations"`
-	StrictMode    bool   `mapstructure:"strict_mode" yaml:"strict_mode" json:"strict_mode"`
+	Enabled       bool           `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Model         string         `mapstructure:"model" yaml:"model" json:"model"`
+	MaxIterations int            `mapstructure:"max_iterations" yaml:"max_iterations" json:"max_iterations"`
+	StrictMode    bool           `mapstructure:"strict_mode" yaml:"strict_mode" json:"strict_mode"`
+	Sampling      SamplingConfig `mapstructure:"sampling" yaml:"sampling" json:"sampling"`
 }
 
 // ContextLayerConfig for Layer 6
 type ContextLayerConfig struct {
-	Enabled          bool   `mapstructure:"enabled" yaml:"enabled" json:"enabled"` // Always true
-	Model            string `mapstructure:"model" yaml:"model" json:"model"`
-	MaxContextTokens int    `mapstructure:"max_context_tokens" yaml:"max_context_tokens" json:"max_context_tokens"`
+	Enabled          bool           `mapstructure:"enabled" yaml:"enabled" json:"enabled"` // Always true
+	Model            string         `mapstructure:"model" yaml:"model" json:"model"`
+	MaxContextTokens int            `mapstructure:"max_context_tokens" yaml:"max_context_tokens" json:"max_context_tokens"`
+	Sampling         SamplingConfig `mapstructure:"sampling" yaml:"sampling" json:"sampling"`
+
+	// How tool outputs are rated against the current task to decide which
+	// are pruned first: "terms" counts shared words, "model" asks the quick
+	// task model, charging the session budget (default: terms)
+	Relevance string `mapstructure:"relevance" yaml:"relevance" json:"relevance"`
 }
 
 // ToolConfig defines tool settings
@@ -99,6 +227,70 @@ type ToolConfig struct {
 	DisabledTools []string                   `mapstructure:"disabled_tools" yaml:"disabled_tools" json:"disabled_tools"`
 	AutoApprove   []string                   `mapstructure:"auto_approve" yaml:"auto_approve" json:"auto_approve"`
 	MCPServers    map[string]MCPServerConfig `mapstructure:"mcp_servers" yaml:"mcp_servers" json:"mcp_servers"`
+
+	// Command prefixes marked as favorites in the run history
+	FavoriteCommands []string `mapstructure:"favorite_commands" yaml:"favorite_commands" json:"favorite_commands"`
+
+	// Environment shell commands run in
+	Shell ShellConfig `mapstructure:"shell" yaml:"shell" json:"shell"`
+
+	// Language servers for code navigation and post-edit diagnostics
+	LSP LSPConfig `mapstructure:"lsp" yaml:"lsp" json:"lsp"`
+
+	// Linters and formatters run by core.lint and core.format
+	Lint LintConfig `mapstructure:"lint" yaml:"lint" json:"lint"`
+
+	// Databases the core.db_* tools connect to, by name
+	Databases map[string]DatabaseConfig `mapstructure:"databases" yaml:"databases" json:"databases"`
+}
+
+// DatabaseConfig defines a database connection
+type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver" yaml:"driver" json:"driver"`       // "sqlite", "postgres" or "mysql"
+	DSN      string `mapstructure:"dsn" yaml:"dsn" json:"dsn"`                // File path for SQLite, connection URL otherwise; $VARS are expanded
+	Writable bool   `mapstructure:"writable" yaml:"writable" json:"writable"` // Allow statements that change the database, each confirmed
+}
+
+// LintConfig chooses the linters and formatters run for the project
+type LintConfig struct {
+	Linters    []string                  `mapstructure:"linters" yaml:"linters" json:"linters"`          // Run these instead of detecting them, e.g. golangci-lint, ruff
+	Formatters []string                  `mapstructure:"formatters" yaml:"formatters" json:"formatters"` // Run these instead of detecting them, e.g. gofmt, prettier, ruff-format
+	Tools      map[string]LintToolConfig `mapstructure:"tools" yaml:"tools" json:"tools"`                // Define tools or override the built-in ones by name
+}
+
+// LintToolConfig defines a linter or formatter. The paths checked are
+// appended to its commands.
+type LintToolConfig struct {
+	Kind       string   `mapstructure:"kind" yaml:"kind" json:"kind"`                   // "lint" or "format"; built-in tools keep theirs
+	Command    string   `mapstructure:"command" yaml:"command" json:"command"`          // Lint: prints path:line[:column]: message. Format: rewrites the files
+	Fix        string   `mapstructure:"fix" yaml:"fix" json:"fix"`                      // Lint: fixes what it can and prints the rest
+	Check      string   `mapstructure:"check" yaml:"check" json:"check"`                // Format: prints the files needing formatting, changing nothing
+	Extensions []string `mapstructure:"extensions" yaml:"extensions" json:"extensions"` // With the dot
+	Markers    []string `mapstructure:"markers" yaml:"markers" json:"markers"`          // Globs of project files that show the tool is used
+}
+
+// LSPConfig defines the language servers run for the project
+type LSPConfig struct {
+	Enabled         bool                       `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Servers         map[string]LSPServerConfig `mapstructure:"servers" yaml:"servers" json:"servers"`                            // By language; replace or add to gopls, typescript-language-server and pyright
+	DiagnosticsWait time.Duration              `mapstructure:"diagnostics_wait" yaml:"diagnostics_wait" json:"diagnostics_wait"` // Longest wait for diagnostics after an edit (default 3s)
+}
+
+// LSPServerConfig defines how a language server is launched
+type LSPServerConfig struct {
+	Command    string            `mapstructure:"command" yaml:"command" json:"command"`
+	Args       []string          `mapstructure:"args" yaml:"args" json:"args"`
+	Env        map[string]string `mapstructure:"env" yaml:"env" json:"env"`
+	Extensions []string          `mapstructure:"extensions" yaml:"extensions" json:"extensions"` // With the dot; empty keeps those of the built-in server
+}
+
+// ShellConfig defines the shell profile applied to command executions
+type ShellConfig struct {
+	Shell           string            `mapstructure:"shell" yaml:"shell" json:"shell"`                                  // "bash" (default), "zsh", "sh" or "pwsh"
+	InitScripts     []string          `mapstructure:"init_scripts" yaml:"init_scripts" json:"init_scripts"`             // Sourced before each command
+	Path            []string          `mapstructure:"path" yaml:"path" json:"path"`                                     // Prepended to PATH
+	Env             map[string]string `mapstructure:"env" yaml:"env" json:"env"`                                        // Set for each command
+	VersionManagers []string          `mapstructure:"version_managers" yaml:"version_managers" json:"version_managers"` // nvm, fnm, pyenv, rbenv, asdf, mise, venv, or "auto"
 }
 
 // MCPServerConfig defines MCP server configuration
@@ -119,15 +311,47 @@ type UIConfig struct {
 	ShowCost   bool   `mapstructure:"show_cost" yaml:"show_cost" json:"show_cost"`
 	ShowTokens bool   `mapstructure:"show_tokens" yaml:"show_tokens" json:"show_tokens"`
 	ShowLayers bool   `mapstructure:"show_layers" yaml:"show_layers" json:"show_layers"`
+
+	// How file:line references are hyperlinked: "auto" (default), "off", an
+	// editor (vscode, cursor, zed, idea, sublime, file) or a URI template
+	// with {path}, {line} and {column}
+	EditorLinks string `mapstructure:"editor_links" yaml:"editor_links" json:"editor_links"`
+
+	// Abbreviations expanded in the input when followed by a space (e.g. ";;t")
+	Abbreviations map[string]string `mapstructure:"abbreviations" yaml:"abbreviations" json:"abbreviations"`
 }
 
 // SessionConfig defines session management settings
 type SessionConfig struct {
-	AutoSave           bool          `mapstructure:"auto_save" yaml:"auto_save" json:"auto_save"`
-	SaveInterval       time.Duration `mapstructure:"save_interval" yaml:"save_interval" json:"save_interval"`
-	CheckpointEnabled  bool          `mapstructure:"checkpoint_enabled" yaml:"checkpoint_enabled" json:"checkpoint_enabled"`
-	CheckpointInterval time.Duration `mapstructure:"checkpoint_interval" yaml:"checkpoint_interval" json:"checkpoint_interval"`
-	MaxHistorySize     int           `mapstructure:"max_history_size" yaml:"max_history_size" json:"max_history_size"`
+	AutoSave           bool           `mapstructure:"auto_save" yaml:"auto_save" json:"auto_save"`
+	SaveInterval       time.Duration  `mapstructure:"save_interval" yaml:"save_interval" json:"save_interval"`
+	CheckpointEnabled  bool           `mapstructure:"checkpoint_enabled" yaml:"checkpoint_enabled" json:"checkpoint_enabled"`
+	CheckpointInterval time.Duration  `mapstructure:"checkpoint_interval" yaml:"checkpoint_interval" json:"checkpoint_interval"`
+	MaxHistorySize     int            `mapstructure:"max_history_size" yaml:"max_history_size" json:"max_history_size"`
+	CompressThreshold  int            `mapstructure:"compress_threshold" yaml:"compress_threshold" json:"compress_threshold"` // Bytes; larger messages are stored zstd-compressed
+	MaxMessageSize     int            `mapstructure:"max_message_size" yaml:"max_message_size" json:"max_message_size"`       // Bytes; larger messages are truncated
+	Worktree           WorktreeConfig `mapstructure:"worktree" yaml:"worktree" json:"worktree"`                               // Finishing sessions run in a linked git worktree
+	IdleSuspend        time.Duration  `mapstructure:"idle_suspend" yaml:"idle_suspend" json:"idle_suspend"`                   // Idle time before heavyweight resources are released; 0 never
+	Worklog            bool           `mapstructure:"worklog" yaml:"worklog" json:"worklog"`                                  // Append a summary of each task that changed files to .b+/worklog.md
+	Backup             BackupConfig   `mapstructure:"backup" yaml:"backup" json:"backup"`                                     // Scheduled copies of the session database
+}
+
+// BackupConfig schedules backups of the session database. Backups are taken
+// while sessions keep running and are rotated, newest first.
+type BackupConfig struct {
+	Enabled  bool          `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	Interval time.Duration `mapstructure:"interval" yaml:"interval" json:"interval"` // Time between backups
+	Keep     int           `mapstructure:"keep" yaml:"keep" json:"keep"`             // Backups kept; 0 keeps all
+	MaxAge   time.Duration `mapstructure:"max_age" yaml:"max_age" json:"max_age"`    // Older backups are removed, except the newest; 0 never
+	Dir      string        `mapstructure:"dir" yaml:"dir" json:"dir"`                // Empty uses backups/ next to the database
+}
+
+// WorktreeConfig configures /finish, which merges the work of a session run
+// in a linked git worktree into its base branch.
+type WorktreeConfig struct {
+	Base        string `mapstructure:"base" yaml:"base" json:"base"`                         // Branch merged into; empty uses the main worktree's branch
+	TestCommand string `mapstructure:"test_command" yaml:"test_command" json:"test_command"` // Run on the merge result; empty detects it from the project
+	Remote      string `mapstructure:"remote" yaml:"remote" json:"remote"`                   // Remote the branch is pushed to; "origin" if empty
 }
 
 // SecurityConfig defines security settings
@@ -148,6 +372,31 @@ type CostConfig struct {
 	MonthlyBudget  float64 `mapstructure:"monthly_budget" yaml:"monthly_budget" json:"monthly_budget"`    // In USD
 	AlertThreshold float64 `mapstructure:"alert_threshold" yaml:"alert_threshold" json:"alert_threshold"` // Percentage (0-100)
 	FreeOnly       bool    `mapstructure:"free_only" yaml:"free_only" json:"free_only"`                   // Use only free models
+
+	Attribution AttributionConfig `mapstructure:"attribution" yaml:"attribution" json:"attribution"` // Spend attribution tags
+}
+
+// AttributionConfig defines identifiers forwarded to providers so API spend
+// can be attributed centrally (OpenAI "user", Anthropic "metadata.user_id",
+// hashed as Anthropic requires an opaque ID, and OpenRouter "user").
+// Requests without them are tagged with their session instead.
+type AttributionConfig struct {
+	Organization string `mapstructure:"organization" yaml:"organization" json:"organization"`
+	Team         string `mapstructure:"team" yaml:"team" json:"team"`
+	Project      string `mapstructure:"project" yaml:"project" json:"project"`
+	User         string `mapstructure:"user" yaml:"user" json:"user"`
+}
+
+// Identifier returns the attribution tags joined into a single stable
+// identifier (e.g. "acme/payments/checkout/alice"), or "" if none are set
+func (a AttributionConfig) Identifier() string {
+	var parts []string
+	for _, part := range []string{a.Organization, a.Team, a.Project, a.User} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
 }
 
 // PerformanceConfig defines performance settings
@@ -156,6 +405,16 @@ type PerformanceConfig struct {
 	CacheEnabled   bool          `mapstructure:"cache_enabled" yaml:"cache_enabled" json:"cache_enabled"`
 	DefaultTimeout time.Duration `mapstructure:"default_timeout" yaml:"default_timeout" json:"default_timeout"`
 	MaxContextSize int           `mapstructure:"max_context_size" yaml:"max_context_size" json:"max_context_size"`
+	HTTP           HTTPConfig    `mapstructure:"http" yaml:"http" json:"http"` // Shared provider HTTP transport
+}
+
+// HTTPConfig defines the shared HTTP transport used by all providers
+type HTTPConfig struct {
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout" yaml:"idle_conn_timeout" json:"idle_conn_timeout"`
+	KeepAlive           time.Duration `mapstructure:"keep_alive" yaml:"keep_alive" json:"keep_alive"`
+	DNSCacheTTL         time.Duration `mapstructure:"dns_cache_ttl" yaml:"dns_cache_ttl" json:"dns_cache_ttl"`
+	DisableHTTP2        bool          `mapstructure:"disable_http2" yaml:"disable_http2" json:"disable_http2"`
 }
 
 // LoggingConfig defines logging settings
@@ -180,15 +439,42 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("default model must be specified")
 	}
 
+	for alias, name := range c.Models.Aliases {
+		if alias == "" || strings.Contains(alias, "/") {
+			return fmt.Errorf("invalid model alias %q (aliases cannot be empty or contain '/')", alias)
+		}
+		if provider, model, ok := strings.Cut(name, "/"); !ok || provider == "" || model == "" {
+			return fmt.Errorf("model alias %s must name a model as provider/model-id, got %q", alias, name)
+		}
+	}
+
+	if c.Models.Quick.MaxLatency < 0 {
+		return fmt.Errorf("models.quick.max_latency cannot be negative")
+	}
+
 	// Validate layer configuration
 	if !c.Layers.MainAgent.Enabled {
 		return fmt.Errorf("main agent layer (Layer 4) cannot be disabled")
 	}
 
+	if c.Layers.MainAgent.ReasoningBudget < 0 {
+		return fmt.Errorf("main agent reasoning_budget cannot be negative")
+	}
+
+	if c.Layers.MainAgent.MaxContinuations < -1 {
+		return fmt.Errorf("main agent max_continuations must be -1 (disabled) or more")
+	}
+
 	if !c.Layers.ContextManagement.Enabled {
 		return fmt.Errorf("context management layer (Layer 6) cannot be disabled")
 	}
 
+	switch c.Layers.ContextManagement.Relevance {
+	case "", "model", "terms":
+	default:
+		return fmt.Errorf("invalid layers.context_management.relevance: %s (must be model or terms)", c.Layers.ContextManagement.Relevance)
+	}
+
 	// Validate parallel planning configuration
 	if c.Layers.ParallelPlanning.Enabled {
 		if c.Layers.ParallelPlanning.NumPlans < 2 || c.Layers.ParallelPlanning.NumPlans > 8 {
@@ -205,6 +491,133 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("validation max_iterations must be between 1 and 5")
 	}
 
+	if err := c.Layers.validateSampling(); err != nil {
+		return err
+	}
+
+	// Validate layer plugins
+	validLayers := map[string]bool{
+		"intent_clarification": true, "parallel_planning": true, "synthesis": true,
+		"main_agent": true, "validation": true, "context_management": true,
+	}
+	for layer, plugin := range c.Layers.Plugins {
+		if !validLayers[layer] {
+			return fmt.Errorf("plugin registered for unknown layer: %s", layer)
+		}
+		if plugin.Command == "" {
+			return fmt.Errorf("plugin for layer %s must specify a command", layer)
+		}
+	}
+
+	// Validate degradation orders; layers 4 and 6 always run, so they can't be skipped
+	for layer, order := range c.Layers.Degradation {
+		if layer != "default" && !validLayers[layer] {
+			return fmt.Errorf("degradation order for unknown layer: %s", layer)
+		}
+		for i, model := range order {
+			switch {
+			case model == "skip":
+				if layer == "main_agent" || layer == "context_management" {
+					return fmt.Errorf("layer %s cannot be skipped", layer)
+				}
+				if i != len(order)-1 {
+					return fmt.Errorf("degradation order for %s: skip must be the last entry", layer)
+				}
+			case !strings.Contains(model, "/"):
+				return fmt.Errorf("degradation order for %s: invalid model %q (want provider/model)", layer, model)
+			}
+		}
+	}
+
+	// Validate provider types and network settings
+	for name, provider := range c.Providers {
+		switch provider.Type {
+		case "":
+		case ProviderTypeOpenAICompatible:
+			if provider.BaseURL == "" {
+				return fmt.Errorf("provider %s: %s providers must set base_url", name, ProviderTypeOpenAICompatible)
+			}
+			if strings.Contains(name, "/") {
+				return fmt.Errorf("provider %s: names cannot contain '/'", name)
+			}
+		default:
+			return fmt.Errorf("provider %s: unknown type %q (must be empty or %s)", name, provider.Type, ProviderTypeOpenAICompatible)
+		}
+		if provider.ProxyURL == "" {
+			continue
+		}
+		proxy, err := url.Parse(provider.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return fmt.Errorf("provider %s: invalid proxy_url %q", name, provider.ProxyURL)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("provider %s: proxy_url scheme must be http, https, or socks5", name)
+		}
+	}
+
+	// Validate shell profile
+	validShells := map[string]bool{"": true, "bash": true, "zsh": true, "sh": true, "pwsh": true}
+	if !validShells[c.Tools.Shell.Shell] {
+		return fmt.Errorf("invalid shell: %s (must be bash, zsh, sh, or pwsh)", c.Tools.Shell.Shell)
+	}
+
+	// Validate language servers
+	for name, server := range c.Tools.LSP.Servers {
+		if server.Command == "" {
+			return fmt.Errorf("tools.lsp.servers.%s: command is required", name)
+		}
+		for _, ext := range server.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				return fmt.Errorf("tools.lsp.servers.%s: extension %q must start with a dot", name, ext)
+			}
+		}
+	}
+	if c.Tools.LSP.DiagnosticsWait < 0 {
+		return fmt.Errorf("tools.lsp.diagnostics_wait cannot be negative")
+	}
+
+	// Validate linters and formatters
+	for name, tool := range c.Tools.Lint.Tools {
+		switch tool.Kind {
+		case "", "lint", "format":
+		default:
+			return fmt.Errorf("tools.lint.tools.%s: invalid kind: %s (must be lint or format)", name, tool.Kind)
+		}
+		for _, ext := range tool.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				return fmt.Errorf("tools.lint.tools.%s: extension %q must start with a dot", name, ext)
+			}
+		}
+	}
+
+	// Validate databases
+	for name, db := range c.Tools.Databases {
+		switch db.Driver {
+		case "sqlite", "postgres", "mysql":
+		default:
+			return fmt.Errorf("tools.databases.%s: invalid driver: %s (must be sqlite, postgres or mysql)", name, db.Driver)
+		}
+		if db.DSN == "" {
+			return fmt.Errorf("tools.databases.%s: dsn is required", name)
+		}
+	}
+
+	// Validate editor links
+	validEditors := map[string]bool{"": true, "auto": true, "off": true, "vscode": true, "cursor": true, "zed": true, "idea": true, "sublime": true, "file": true}
+	if !validEditors[c.UI.EditorLinks] && !strings.Contains(c.UI.EditorLinks, "{path}") {
+		return fmt.Errorf("invalid ui.editor_links: %s (must be auto, off, vscode, cursor, zed, idea, sublime, file or a template with {path})", c.UI.EditorLinks)
+	}
+
+	// Validate backup schedule
+	if c.Session.Backup.Enabled && c.Session.Backup.Interval < time.Minute {
+		return fmt.Errorf("session.backup.interval must be at least 1m")
+	}
+	if c.Session.Backup.Keep < 0 || c.Session.Backup.MaxAge < 0 {
+		return fmt.Errorf("session.backup keep and max_age cannot be negative")
+	}
+
 	// Validate logging level
 	validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
 	if !validLevels[c.Logging.Level] {
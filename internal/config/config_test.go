@@ -157,6 +157,142 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "main agent layer (Layer 4) cannot be disabled",
 		},
+		{
+			name: "invalid proxy scheme",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Providers: ProviderConfigs{
+					"anthropic": ProviderConfig{ProxyURL: "ftp://proxy.corp:21"},
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "provider anthropic: proxy_url scheme must be http, https, or socks5",
+		},
+		{
+			name: "openai-compatible provider without base_url",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Providers: ProviderConfigs{
+					"together": ProviderConfig{Type: ProviderTypeOpenAICompatible},
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "provider together: openai-compatible providers must set base_url",
+		},
+		{
+			name: "unknown provider type",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Providers: ProviderConfigs{
+					"together": ProviderConfig{Type: "grpc", BaseURL: "https://api.together.xyz/v1"},
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "provider together: unknown type \"grpc\" (must be empty or openai-compatible)",
+		},
+		{
+			name: "negative reasoning budget",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled:         true,
+						ReasoningBudget: -1,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "main agent reasoning_budget cannot be negative",
+		},
+		{
+			name: "invalid max continuations",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled:          true,
+						MaxContinuations: -2,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "main agent max_continuations must be -1 (disabled) or more",
+		},
 		{
 			name: "context management disabled",
 			config: &Config{
@@ -292,6 +428,347 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid logging level",
 		},
+		{
+			name: "plugin for unknown layer",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+					Plugins: map[string]LayerPluginConfig{
+						"planning": {Command: "acme-planner"},
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "unknown layer",
+		},
+		{
+			name: "plugin without command",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+					Plugins: map[string]LayerPluginConfig{
+						"parallel_planning": {},
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "must specify a command",
+		},
+		{
+			name: "main agent cannot be skipped",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+					Degradation: map[string][]string{
+						"main_agent": {"ollama/qwen2.5-coder:7b", "skip"},
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "cannot be skipped",
+		},
+		{
+			name: "invalid shell",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Tools: ToolConfig{
+					Shell: ShellConfig{Shell: "fish"},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid shell",
+		},
+		{
+			name: "language server without command",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Tools: ToolConfig{
+					LSP: LSPConfig{Enabled: true, Servers: map[string]LSPServerConfig{
+						"rust": {Extensions: []string{".rs"}},
+					}},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "command is required",
+		},
+		{
+			name: "invalid lint tool kind",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Tools: ToolConfig{
+					Lint: LintConfig{Tools: map[string]LintToolConfig{
+						"eslint": {Kind: "check", Command: "npx eslint"},
+					}},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid kind",
+		},
+		{
+			name: "invalid database driver",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Tools: ToolConfig{
+					Databases: map[string]DatabaseConfig{
+						"app": {Driver: "oracle", DSN: "oracle://localhost/app"},
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid driver",
+		},
+		{
+			name: "backup interval too short",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+				},
+				Session: SessionConfig{
+					Backup: BackupConfig{Enabled: true, Interval: time.Second},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "session.backup.interval",
+		},
+		{
+			name: "unknown context relevance",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent:         MainAgentLayerConfig{Enabled: true},
+					ContextManagement: ContextLayerConfig{Enabled: true, Relevance: "embeddings"},
+					Validation:        ValidationLayerConfig{MaxIterations: 3},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "layers.context_management.relevance",
+		},
+		{
+			name: "unknown editor links",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent:         MainAgentLayerConfig{Enabled: true},
+					ContextManagement: ContextLayerConfig{Enabled: true},
+					Validation:        ValidationLayerConfig{MaxIterations: 3},
+				},
+				UI: UIConfig{EditorLinks: "notepad"},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "ui.editor_links",
+		},
+		{
+			name: "model alias without provider",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "sonnet",
+					Aliases: map[string]string{"sonnet": "claude-sonnet-4-5"},
+				},
+				Layers: LayerConfig{
+					MainAgent:         MainAgentLayerConfig{Enabled: true},
+					ContextManagement: ContextLayerConfig{Enabled: true},
+					Validation:        ValidationLayerConfig{MaxIterations: 3},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			wantErr: true,
+			errMsg:  "model alias sonnet must name a model",
+		},
+		{
+			name: "unknown sampling profile",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+						Sampling:      SamplingConfig{Profile: "strict"},
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  `unknown profile "strict"`,
+		},
+		{
+			name: "temperature out of range",
+			config: &Config{
+				Mode: "fast",
+				Models: ModelConfig{
+					Default: "anthropic/claude-sonnet-4-5",
+				},
+				Layers: LayerConfig{
+					MainAgent: MainAgentLayerConfig{
+						Enabled: true,
+					},
+					ContextManagement: ContextLayerConfig{
+						Enabled: true,
+					},
+					Validation: ValidationLayerConfig{
+						MaxIterations: 3,
+					},
+					Profiles: map[string]SamplingConfig{
+						"wild": {Temperature: floatPtr(3)},
+					},
+				},
+				Logging: LoggingConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "temperature must be between 0 and 2",
+		},
 	}
 
 	for _, tt := range tests {
@@ -307,6 +784,43 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestLayerConfig_Sampling(t *testing.T) {
+	topK := 40
+	layers := LayerConfig{
+		ParallelPlanning: PlanningLayerConfig{
+			Sampling: SamplingConfig{Temperature: floatPtr(0.9)},
+		},
+		Synthesis: SynthesisLayerConfig{
+			Sampling: SamplingConfig{Profile: "focused"},
+		},
+		Profiles: map[string]SamplingConfig{
+			"focused": {Temperature: floatPtr(0.3), TopK: &topK},
+		},
+	}
+
+	// Defaults per layer
+	validation := layers.Sampling("validation")
+	assert.Equal(t, ProfileDeterministic, validation.Profile)
+	require.NotNil(t, validation.Temperature)
+	assert.Equal(t, 0.0, *validation.Temperature)
+	assert.Equal(t, 0.7, *layers.Sampling("main_agent").Temperature)
+
+	// Own settings override the profile's
+	planning := layers.Sampling("parallel_planning")
+	assert.Equal(t, ProfileCreative, planning.Profile)
+	assert.Equal(t, 0.9, *planning.Temperature)
+	assert.Equal(t, 0.95, *planning.TopP)
+
+	// Configured profiles
+	synthesis := layers.Sampling("synthesis")
+	assert.Equal(t, 0.3, *synthesis.Temperature)
+	assert.Nil(t, synthesis.TopP)
+	assert.Equal(t, 40, *synthesis.TopK)
+
+	// Unknown layers use the provider defaults
+	assert.Equal(t, SamplingConfig{}, layers.Sampling("unknown"))
+}
+
 func TestGetConfigDir(t *testing.T) {
 	// Save original env vars
 	originalXDG := os.Getenv("XDG_CONFIG_HOME")
@@ -478,6 +992,26 @@ func TestLoader_LoadWithDefaults(t *testing.T) {
 	assert.Equal(t, 4, config.Layers.ParallelPlanning.NumPlans)
 	assert.True(t, config.Layers.MainAgent.Enabled)
 	assert.True(t, config.Layers.ContextManagement.Enabled)
+	assert.Equal(t, "terms", config.Layers.ContextManagement.Relevance)
+	assert.True(t, config.Tools.LSP.Enabled)
+	assert.Equal(t, 3*time.Second, config.Tools.LSP.DiagnosticsWait)
+}
+
+func TestLoader_UntrustedProject(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".b+"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".b+", "config.yaml"), []byte("mode: thorough\n"), 0o644))
+	t.Chdir(dir)
+
+	config, err := NewLoader().Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "thorough", config.Mode)
+
+	loader := NewLoader()
+	loader.SetProjectTrusted(false)
+	config, err = loader.Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "fast", config.Mode)
 }
 
 func TestProviderConfig_Timeout(t *testing.T) {
@@ -488,3 +1022,97 @@ func TestProviderConfig_Timeout(t *testing.T) {
 	assert.Equal(t, 300*time.Second, provider.Timeout)
 	assert.Equal(t, 5*time.Minute, provider.Timeout)
 }
+
+func TestProviderConfig_Keys(t *testing.T) {
+	provider := ProviderConfig{
+		APIKey:  "primary",
+		APIKeys: []string{"second", "", "primary", "third"},
+	}
+	assert.Equal(t, []string{"primary", "second", "third"}, provider.Keys())
+
+	assert.Equal(t, []string{"only"}, ProviderConfig{APIKeys: []string{"only"}}.Keys())
+	assert.Empty(t, ProviderConfig{}.Keys())
+}
+
+func TestAttributionConfig_Identifier(t *testing.T) {
+	tests := []struct {
+		name string
+		attr AttributionConfig
+		want string
+	}{
+		{"empty", AttributionConfig{}, ""},
+		{"project only", AttributionConfig{Project: "checkout"}, "checkout"},
+		{"all fields", AttributionConfig{Organization: "acme", Team: "payments", Project: "checkout", User: "alice"}, "acme/payments/checkout/alice"},
+		{"skips blanks", AttributionConfig{Organization: "acme", Team: "  ", User: "alice"}, "acme/alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.attr.Identifier())
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	xdg := t.TempDir()
+	userDir := filepath.Join(xdg, "bplus")
+	require.NoError(t, os.MkdirAll(userDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "config.yaml"), []byte(`
+logging:
+  level: debug
+ui:
+  theme: light
+`), 0644))
+
+	projectPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(projectPath, []byte(`
+ui:
+  theme: dark
+providers:
+  anthropic:
+    base_url: "https://proxy.example.com"
+`), 0644))
+
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-secret-value-1234")
+
+	loader := NewLoader()
+	cfg, err := loader.Load(projectPath)
+	require.NoError(t, err)
+
+	origins := loader.Origins()
+	origins.Flags = map[string]string{"mode": "--thorough"}
+	settings := make(map[string]Setting)
+	for _, s := range Inspect(cfg, origins) {
+		settings[s.Key] = s
+	}
+
+	assert.Equal(t, SourceFlag, settings["mode"].Source)
+	assert.Equal(t, "--thorough", settings["mode"].Origin)
+
+	key := settings["providers.anthropic.api_key"]
+	assert.Equal(t, SourceEnv, key.Source)
+	assert.Equal(t, "ANTHROPIC_API_KEY", key.Origin)
+	assert.True(t, key.Secret)
+	assert.NotContains(t, key.Value, "secret-value")
+	assert.Equal(t, "sk-a", key.Value[:4])
+
+	baseURL := settings["providers.anthropic.base_url"]
+	assert.Equal(t, SourceProjectFile, baseURL.Source)
+	assert.Equal(t, projectPath, baseURL.Origin)
+	assert.Equal(t, 6, baseURL.Line)
+	assert.Equal(t, "https://proxy.example.com", baseURL.Value)
+
+	// The project file overrides the user file
+	assert.Equal(t, SourceProjectFile, settings["ui.theme"].Source)
+	assert.Equal(t, "dark", settings["ui.theme"].Value)
+
+	level := settings["logging.level"]
+	assert.Equal(t, SourceUserFile, level.Source)
+	assert.Equal(t, filepath.Join(userDir, "config.yaml"), level.Origin)
+	assert.Equal(t, 3, level.Line)
+
+	assert.Equal(t, SourceDefault, settings["logging.max_backups"].Source)
+	assert.Equal(t, "3", settings["logging.max_backups"].Value)
+	assert.False(t, settings["providers.anthropic.key_strategy"].Secret)
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Source identifies where an effective configuration value came from.
+type Source string
+
+// Configuration sources, lowest priority first
+const (
+	SourceDefault     Source = "default"
+	SourceUserFile    Source = "user file"
+	SourceProjectFile Source = "project file"
+	SourceEnv         Source = "env"
+	SourceFlag        Source = "flag"
+	SourceRuntime     Source = "runtime"
+)
+
+// Origins describes the layers an effective configuration was merged from.
+type Origins struct {
+	UserFile    string            // User config file that was loaded, if any
+	ProjectFile string            // Project config file that was loaded, if any
+	Env         map[string]string // Environment variable that can set each key
+	EnvPrefix   string            // Prefix of the variables set by key, e.g. BPLUS for BPLUS_MODE
+	Flags       map[string]string // Flag that set each key
+	Runtime     map[string]string // What changed each key while running, e.g. "/models"
+}
+
+// Setting is one value of the effective configuration.
+type Setting struct {
+	Key    string // Dotted key, e.g. "providers.anthropic.base_url"
+	Value  string // Formatted value, masked when Secret
+	Secret bool
+	Source Source
+	Origin string // File, environment variable, flag or change that set the value
+	Line   int    // Line of the key in a file Origin, or 0
+}
+
+// secretKey matches the keys whose values are masked.
+var secretKey = regexp.MustCompile(`(?i)(api_?keys?|token|secret|password|credentials?)$`)
+
+// Inspect lists every value of cfg, sorted by key, with the source that set
+// it. Sources are resolved in the order the loader merges them: flags, then
+// environment variables, the project file, the user file and defaults;
+// changes made while running override them all.
+func Inspect(cfg *Config, origins Origins) []Setting {
+	var settings []Setting
+	flatten("", reflect.ValueOf(*cfg), func(key string, value reflect.Value) {
+		s := Setting{Key: key, Secret: secretKey.MatchString(key[strings.LastIndex(key, ".")+1:])}
+		s.Value = formatValue(value, s.Secret)
+		settings = append(settings, s)
+	})
+
+	userLines := keyLines(origins.UserFile)
+	projectLines := keyLines(origins.ProjectFile)
+	for i := range settings {
+		s := &settings[i]
+		s.Source = SourceDefault
+		if line, ok := lookupLine(userLines, s.Key); ok {
+			s.Source, s.Origin, s.Line = SourceUserFile, origins.UserFile, line
+		}
+		if line, ok := lookupLine(projectLines, s.Key); ok {
+			s.Source, s.Origin, s.Line = SourceProjectFile, origins.ProjectFile, line
+		}
+		if env := envFor(origins, s.Key); env != "" {
+			s.Source, s.Origin, s.Line = SourceEnv, env, 0
+		}
+		if flag, ok := origins.Flags[s.Key]; ok {
+			s.Source, s.Origin, s.Line = SourceFlag, flag, 0
+		}
+		if change, ok := origins.Runtime[s.Key]; ok {
+			s.Source, s.Origin, s.Line = SourceRuntime, change, 0
+		}
+	}
+
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// flatten calls leaf for each scalar or list reachable from v, keyed by the
+// dotted path of yaml field names and map keys.
+func flatten(prefix string, v reflect.Value, leaf func(key string, value reflect.Value)) {
+	join := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		leaf(prefix, v)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			flatten(join(name), v.Field(i), leaf)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			flatten(join(fmt.Sprint(k)), v.MapIndex(k), leaf)
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			flatten(prefix, v.Elem(), leaf)
+		}
+	default:
+		leaf(prefix, v)
+	}
+}
+
+// formatValue renders a leaf value, masking secrets.
+func formatValue(v reflect.Value, secret bool) string {
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i), secret)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+
+	s := fmt.Sprint(v.Interface())
+	if secret && s != "" {
+		return util.MaskSecret(s, 4)
+	}
+	return s
+}
+
+// envFor returns the environment variable currently setting key, if any.
+func envFor(origins Origins, key string) string {
+	if env, ok := origins.Env[key]; ok && os.Getenv(env) != "" {
+		return env
+	}
+	if origins.EnvPrefix != "" {
+		env := origins.EnvPrefix + "_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+		if os.Getenv(env) != "" {
+			return env
+		}
+	}
+	return ""
+}
+
+// keyLines maps each dotted key present in a YAML config file to its line.
+// Missing or unreadable files yield no keys.
+func keyLines(path string) map[string]int {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return nil
+	}
+
+	lines := make(map[string]int)
+	var walk func(prefix string, node *yaml.Node)
+	walk = func(prefix string, node *yaml.Node) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			lines[key] = node.Content[i].Line
+			walk(key, node.Content[i+1])
+		}
+	}
+	walk("", doc.Content[0])
+	return lines
+}
+
+// lookupLine returns the line key is set on. YAML keys are case-insensitive
+// to the loader, so they are matched the same way.
+func lookupLine(lines map[string]int, key string) (int, bool) {
+	if line, ok := lines[key]; ok {
+		return line, true
+	}
+	for k, line := range lines {
+		if strings.EqualFold(k, key) {
+			return line, true
+		}
+	}
+	return 0, false
+}
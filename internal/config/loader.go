@@ -11,7 +11,10 @@ import (
 
 // Loader handles configuration loading and merging
 type Loader struct {
-	v *viper.Viper
+	v       *viper.Viper
+	origins Origins
+
+	untrusted bool // Project config in .b+/ is ignored
 }
 
 // NewLoader creates a new configuration loader
@@ -21,6 +24,14 @@ func NewLoader() *Loader {
 	}
 }
 
+// SetProjectTrusted sets whether the project config found in .b+/ of the
+// current directory is honored. It is not for a workspace the user has not
+// trusted, since opening a repository should not let it reconfigure b+. A
+// project config path given explicitly to Load is always honored.
+func (l *Loader) SetProjectTrusted(trusted bool) {
+	l.untrusted = !trusted
+}
+
 // Load loads configuration from all sources and merges them
 // Priority (highest to lowest):
 // 1. CLI flags (handled externally by cobra)
@@ -54,6 +65,10 @@ func (l *Loader) Load(projectConfigPath string) (*Config, error) {
 		} else if err := l.loadProjectConfig(projectConfigPath); err != nil {
 			return nil, fmt.Errorf("failed to load project config: %w", err)
 		}
+	} else if l.untrusted {
+		if _, err := os.Stat(".b+/config.yaml"); err == nil {
+			fmt.Fprintf(os.Stderr, "Info: ignoring .b+/config.yaml in an untrusted workspace\n")
+		}
 	} else {
 		// Try to load from .b+/config.yaml in current directory
 		if err := l.loadProjectConfig(".b+/config.yaml"); err != nil {
@@ -103,6 +118,7 @@ func (l *Loader) loadUserConfig() error {
 	if err := l.v.ReadInConfig(); err != nil {
 		return fmt.Errorf("error reading user config: %w", err)
 	}
+	l.origins.UserFile = configPath
 
 	return nil
 }
@@ -117,26 +133,44 @@ func (l *Loader) loadProjectConfig(path string) error {
 	if err := l.v.MergeInConfig(); err != nil {
 		return fmt.Errorf("error reading project config: %w", err)
 	}
+	l.origins.ProjectFile = path
 
 	return nil
 }
 
+// envBindings maps the common environment variables to the keys they set
+var envBindings = map[string]string{
+	"ANTHROPIC_API_KEY":  "providers.anthropic.api_key",
+	"OPENAI_API_KEY":     "providers.openai.api_key",
+	"GOOGLE_API_KEY":     "providers.gemini.api_key",
+	"OPENROUTER_API_KEY": "providers.openrouter.api_key",
+	"DEEPSEEK_API_KEY":   "providers.deepseek.api_key",
+	"COHERE_API_KEY":     "providers.cohere.api_key",
+	"VLLM_API_KEY":       "providers.vllm.api_key",
+	"BPLUS_MODE":         "mode",
+	"BPLUS_MODEL":        "models.default",
+	"BPLUS_LOG_LEVEL":    "logging.level",
+	"BPLUS_TEAM":         "cost.attribution.team",
+	"BPLUS_PROJECT":      "cost.attribution.project",
+}
+
 // bindEnvVars binds environment variables to config keys
 func (l *Loader) bindEnvVars() {
-	// Bind common environment variables
-	envBindings := map[string]string{
-		"ANTHROPIC_API_KEY":  "providers.anthropic.api_key",
-		"OPENAI_API_KEY":     "providers.openai.api_key",
-		"GOOGLE_API_KEY":     "providers.gemini.api_key",
-		"OPENROUTER_API_KEY": "providers.openrouter.api_key",
-		"BPLUS_MODE":         "mode",
-		"BPLUS_MODEL":        "models.default",
-		"BPLUS_LOG_LEVEL":    "logging.level",
+	for env, key := range envBindings {
+		l.v.BindEnv(key, env)
 	}
+}
 
+// Origins returns the layers the last Load merged, for Inspect. Flags are
+// handled by the caller, which adds the keys it overrode.
+func (l *Loader) Origins() Origins {
+	origins := l.origins
+	origins.EnvPrefix = "BPLUS"
+	origins.Env = make(map[string]string, len(envBindings))
 	for env, key := range envBindings {
-		l.v.BindEnv(key, env)
+		origins.Env[key] = env
 	}
+	return origins
 }
 
 // substituteEnvVars performs ${VAR} substitution in string fields
@@ -144,6 +178,9 @@ func (l *Loader) substituteEnvVars(config *Config) error {
 	// Substitute in provider API keys
 	for name, provider := range config.Providers {
 		provider.APIKey = os.ExpandEnv(provider.APIKey)
+		for i, key := range provider.APIKeys {
+			provider.APIKeys[i] = os.ExpandEnv(key)
+		}
 		config.Providers[name] = provider
 	}
 
@@ -155,6 +192,22 @@ func (l *Loader) substituteEnvVars(config *Config) error {
 		config.Tools.MCPServers[name] = server
 	}
 
+	// Substitute in language server environment variables
+	for name, server := range config.Tools.LSP.Servers {
+		for key, value := range server.Env {
+			server.Env[key] = os.ExpandEnv(value)
+		}
+		config.Tools.LSP.Servers[name] = server
+	}
+
+	// Substitute in layer plugin environment variables
+	for layer, plugin := range config.Layers.Plugins {
+		for key, value := range plugin.Env {
+			plugin.Env[key] = os.ExpandEnv(value)
+		}
+		config.Layers.Plugins[layer] = plugin
+	}
+
 	return nil
 }
 
@@ -166,6 +219,9 @@ func (l *Loader) setDefaults() {
 
 	// Model defaults
 	l.v.SetDefault("models.default", "anthropic/claude-sonnet-4-5")
+	l.v.SetDefault("models.offline", "ollama/qwen2.5-coder:7b")
+	l.v.SetDefault("models.aliases", DefaultModelAliases())
+	l.v.SetDefault("models.quick.max_latency", "800ms")
 
 	// Provider defaults
 	l.v.SetDefault("providers.anthropic.base_url", "https://api.anthropic.com")
@@ -180,13 +236,29 @@ func (l *Loader) setDefaults() {
 	l.v.SetDefault("providers.gemini.timeout", "300s")
 	l.v.SetDefault("providers.gemini.max_retries", 3)
 
+	l.v.SetDefault("providers.deepseek.base_url", "https://api.deepseek.com")
+	l.v.SetDefault("providers.deepseek.timeout", "300s")
+	l.v.SetDefault("providers.deepseek.max_retries", 3)
+
+	l.v.SetDefault("providers.cohere.base_url", "https://api.cohere.com")
+	l.v.SetDefault("providers.cohere.timeout", "300s")
+	l.v.SetDefault("providers.cohere.max_retries", 3)
+
 	l.v.SetDefault("providers.ollama.base_url", "http://localhost:11434")
 	l.v.SetDefault("providers.ollama.timeout", "300s")
 	l.v.SetDefault("providers.ollama.max_retries", 3)
+	l.v.SetDefault("providers.ollama.preload", true)
+	l.v.SetDefault("providers.ollama.keep_alive", "10m")
 
 	l.v.SetDefault("providers.lmstudio.base_url", "http://localhost:1234")
 	l.v.SetDefault("providers.lmstudio.timeout", "300s")
 	l.v.SetDefault("providers.lmstudio.max_retries", 3)
+	l.v.SetDefault("providers.lmstudio.preload", true)
+	l.v.SetDefault("providers.lmstudio.keep_alive", "10m")
+
+	l.v.SetDefault("providers.vllm.base_url", "http://localhost:8000/v1")
+	l.v.SetDefault("providers.vllm.timeout", "300s")
+	l.v.SetDefault("providers.vllm.max_retries", 3)
 
 	// Layer defaults
 	l.v.SetDefault("layers.intent_clarification.enabled", true)
@@ -207,6 +279,8 @@ func (l *Loader) setDefaults() {
 
 	l.v.SetDefault("layers.main_agent.enabled", true)
 	l.v.SetDefault("layers.main_agent.model", "anthropic/claude-sonnet-4-5")
+	l.v.SetDefault("layers.main_agent.max_continuations", 0)
+	l.v.SetDefault("layers.main_agent.persona", "default")
 
 	l.v.SetDefault("layers.validation.enabled", true)
 	l.v.SetDefault("layers.validation.model", "openai/gpt-4-turbo")
@@ -216,11 +290,16 @@ func (l *Loader) setDefaults() {
 	l.v.SetDefault("layers.context_management.enabled", true)
 	l.v.SetDefault("layers.context_management.model", "openai/gpt-4-turbo")
 	l.v.SetDefault("layers.context_management.max_context_tokens", 200000)
+	l.v.SetDefault("layers.context_management.relevance", "terms")
+	l.v.SetDefault("layers.degradation.default", []string{"skip"})
 
 	// Tool defaults
 	l.v.SetDefault("tools.enabled_tools", []string{}) // Empty means all enabled
 	l.v.SetDefault("tools.disabled_tools", []string{})
 	l.v.SetDefault("tools.auto_approve", []string{})
+	l.v.SetDefault("tools.shell.version_managers", []string{"auto"})
+	l.v.SetDefault("tools.lsp.enabled", true)
+	l.v.SetDefault("tools.lsp.diagnostics_wait", "3s")
 
 	// UI defaults
 	l.v.SetDefault("ui.theme", "dark")
@@ -230,6 +309,7 @@ func (l *Loader) setDefaults() {
 	l.v.SetDefault("ui.show_cost", true)
 	l.v.SetDefault("ui.show_tokens", true)
 	l.v.SetDefault("ui.show_layers", true)
+	l.v.SetDefault("ui.editor_links", "auto")
 
 	// Session defaults
 	l.v.SetDefault("session.auto_save", true)
@@ -237,6 +317,15 @@ func (l *Loader) setDefaults() {
 	l.v.SetDefault("session.checkpoint_enabled", false)
 	l.v.SetDefault("session.checkpoint_interval", "5m")
 	l.v.SetDefault("session.max_history_size", 1000)
+	l.v.SetDefault("session.compress_threshold", 65536)
+	l.v.SetDefault("session.max_message_size", 33554432)
+	l.v.SetDefault("session.idle_suspend", "30m")
+	l.v.SetDefault("session.worklog", false)
+	l.v.SetDefault("session.backup.enabled", true)
+	l.v.SetDefault("session.backup.interval", "24h")
+	l.v.SetDefault("session.backup.keep", 7)
+	l.v.SetDefault("session.backup.max_age", "720h")
+	l.v.SetDefault("session.backup.dir", "")
 
 	// Security defaults
 	l.v.SetDefault("security.sandbox", false)
@@ -258,12 +347,21 @@ func (l *Loader) setDefaults() {
 	l.v.SetDefault("cost.monthly_budget", 0.0)
 	l.v.SetDefault("cost.alert_threshold", 80.0)
 	l.v.SetDefault("cost.free_only", false)
+	l.v.SetDefault("cost.attribution.organization", "")
+	l.v.SetDefault("cost.attribution.team", "")
+	l.v.SetDefault("cost.attribution.project", "")
+	l.v.SetDefault("cost.attribution.user", "")
 
 	// Performance defaults
 	l.v.SetDefault("performance.max_parallel", 4)
 	l.v.SetDefault("performance.cache_enabled", true)
 	l.v.SetDefault("performance.default_timeout", "5m")
 	l.v.SetDefault("performance.max_context_size", 200000)
+	l.v.SetDefault("performance.http.max_idle_conns_per_host", 10)
+	l.v.SetDefault("performance.http.idle_conn_timeout", "90s")
+	l.v.SetDefault("performance.http.keep_alive", "30s")
+	l.v.SetDefault("performance.http.dns_cache_ttl", "5m")
+	l.v.SetDefault("performance.http.disable_http2", false)
 
 	// Logging defaults
 	l.v.SetDefault("logging.level", "info")
@@ -0,0 +1,127 @@
+package config
+
+import "fmt"
+
+// SamplingConfig sets the sampling parameters of a layer's model calls.
+// Fields left unset fall back to the profile, then to the provider default.
+type SamplingConfig struct {
+	Profile     string   `mapstructure:"profile" yaml:"profile,omitempty" json:"profile,omitempty"` // Named profile, e.g. "creative"
+	Temperature *float64 `mapstructure:"temperature" yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP        *float64 `mapstructure:"top_p" yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	TopK        *int     `mapstructure:"top_k" yaml:"top_k,omitempty" json:"top_k,omitempty"`
+}
+
+// Sampling profiles
+const (
+	ProfileCreative      = "creative"      // Diverse output, for exploring alternatives
+	ProfileBalanced      = "balanced"      // General-purpose default
+	ProfilePrecise       = "precise"       // Focused output, for analysis and summaries
+	ProfileDeterministic = "deterministic" // Repeatable output, for checks
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+// builtinProfiles are the profiles available without configuration.
+var builtinProfiles = map[string]SamplingConfig{
+	ProfileCreative:      {Temperature: floatPtr(1.0), TopP: floatPtr(0.95)},
+	ProfileBalanced:      {Temperature: floatPtr(0.7)},
+	ProfilePrecise:       {Temperature: floatPtr(0.2)},
+	ProfileDeterministic: {Temperature: floatPtr(0)},
+}
+
+// defaultLayerProfiles is the profile each layer uses when it names none.
+// Planning explores several approaches, so it samples widely; validation
+// must judge the same change the same way every time.
+var defaultLayerProfiles = map[string]string{
+	"intent_clarification": ProfilePrecise,
+	"parallel_planning":    ProfileCreative,
+	"synthesis":            ProfileBalanced,
+	"main_agent":           ProfileBalanced,
+	"validation":           ProfileDeterministic,
+	"context_management":   ProfilePrecise,
+}
+
+// Sampling returns the sampling parameters of a layer: its own settings,
+// with unset fields taken from its profile.
+func (l LayerConfig) Sampling(layer string) SamplingConfig {
+	s := l.layerSampling(layer)
+	if s.Profile == "" {
+		s.Profile = defaultLayerProfiles[layer]
+	}
+
+	profile, ok := l.Profiles[s.Profile]
+	if !ok {
+		profile = builtinProfiles[s.Profile]
+	}
+	if s.Temperature == nil {
+		s.Temperature = profile.Temperature
+	}
+	if s.TopP == nil {
+		s.TopP = profile.TopP
+	}
+	if s.TopK == nil {
+		s.TopK = profile.TopK
+	}
+	return s
+}
+
+// layerSampling returns the sampling settings configured on a layer.
+func (l LayerConfig) layerSampling(layer string) SamplingConfig {
+	switch layer {
+	case "intent_clarification":
+		return l.IntentClarification.Sampling
+	case "parallel_planning":
+		return l.ParallelPlanning.Sampling
+	case "synthesis":
+		return l.Synthesis.Sampling
+	case "main_agent":
+		return l.MainAgent.Sampling
+	case "validation":
+		return l.Validation.Sampling
+	case "context_management":
+		return l.ContextManagement.Sampling
+	}
+	return SamplingConfig{}
+}
+
+// validateSampling checks the profiles and the sampling settings of every
+// layer.
+func (l LayerConfig) validateSampling() error {
+	for name, p := range l.Profiles {
+		if p.Profile != "" {
+			return fmt.Errorf("sampling profile %s cannot be based on another profile", name)
+		}
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("sampling profile %s: %w", name, err)
+		}
+	}
+
+	for layer := range defaultLayerProfiles {
+		s := l.layerSampling(layer)
+		if s.Profile != "" {
+			if _, ok := l.Profiles[s.Profile]; !ok {
+				if _, ok := builtinProfiles[s.Profile]; !ok {
+					return fmt.Errorf("%s sampling: unknown profile %q", layer, s.Profile)
+				}
+			}
+		}
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("%s sampling: %w", layer, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that the set parameters are in range.
+func (s SamplingConfig) validate() error {
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if s.TopP != nil && (*s.TopP <= 0 || *s.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+	if s.TopK != nil && *s.TopK < 1 {
+		return fmt.Errorf("top_k must be at least 1")
+	}
+	return nil
+}
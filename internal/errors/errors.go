@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ErrorCode represents a categorized error type
@@ -20,10 +21,13 @@ const (
 	ErrCodeDatabaseConflict ErrorCode = "DATABASE_CONFLICT"
 
 	// Provider errors
-	ErrCodeProvider        ErrorCode = "PROVIDER_ERROR"
-	ErrCodeProviderTimeout ErrorCode = "PROVIDER_TIMEOUT"
-	ErrCodeProviderAuth    ErrorCode = "PROVIDER_AUTH_ERROR"
-	ErrCodeProviderQuota   ErrorCode = "PROVIDER_QUOTA_EXCEEDED"
+	ErrCodeProvider           ErrorCode = "PROVIDER_ERROR"
+	ErrCodeProviderTimeout    ErrorCode = "PROVIDER_TIMEOUT"
+	ErrCodeProviderAuth       ErrorCode = "PROVIDER_AUTH_ERROR"
+	ErrCodeProviderQuota      ErrorCode = "PROVIDER_QUOTA_EXCEEDED"
+	ErrCodeProviderRateLimit  ErrorCode = "PROVIDER_RATE_LIMITED"
+	ErrCodeProviderOverloaded ErrorCode = "PROVIDER_OVERLOADED"
+	ErrCodeContextOverflow    ErrorCode = "CONTEXT_OVERFLOW"
 
 	// Tool errors
 	ErrCodeTool           ErrorCode = "TOOL_ERROR"
@@ -40,6 +44,9 @@ const (
 	ErrCodeNetwork        ErrorCode = "NETWORK_ERROR"
 	ErrCodeNetworkTimeout ErrorCode = "NETWORK_TIMEOUT"
 
+	// Cost errors
+	ErrCodeBudgetExceeded ErrorCode = "BUDGET_EXCEEDED"
+
 	// Validation errors
 	ErrCodeValidation ErrorCode = "VALIDATION_ERROR"
 
@@ -111,6 +118,15 @@ func Newf(code ErrorCode, format string, args ...interface{}) *Error {
 	}
 }
 
+// Typed is implemented by errors from other packages that classify
+// themselves, such as provider errors parsed from an API response.
+type Typed interface {
+	error
+	ErrorCode() ErrorCode
+	IsRetryable() bool
+	UserMessage() string
+}
+
 // Wrap wraps an existing error with a b+ error
 func Wrap(err error, code ErrorCode, message string) *Error {
 	if err == nil {
@@ -124,6 +140,22 @@ func Wrap(err error, code ErrorCode, message string) *Error {
 		return bpErr
 	}
 
+	// A typed error keeps its classification unless it is a generic one
+	var typed Typed
+	if errors.As(err, &typed) {
+		if typedCode := typed.ErrorCode(); typedCode != ErrCodeProvider && typedCode != "" {
+			code = typedCode
+		}
+		return &Error{
+			Code:        code,
+			Message:     message,
+			UserMsg:     typed.UserMessage(),
+			Err:         err,
+			Retryable:   typed.IsRetryable(),
+			Recoverable: true,
+		}
+	}
+
 	return &Error{
 		Code:        code,
 		Message:     message,
@@ -144,6 +176,10 @@ func Is(err error, code ErrorCode) bool {
 	if errors.As(err, &bpErr) {
 		return bpErr.Code == code
 	}
+	var typed Typed
+	if errors.As(err, &typed) {
+		return typed.ErrorCode() == code
+	}
 	return false
 }
 
@@ -153,9 +189,23 @@ func IsRetryable(err error) bool {
 	if errors.As(err, &bpErr) {
 		return bpErr.Retryable
 	}
+	var typed Typed
+	if errors.As(err, &typed) {
+		return typed.IsRetryable()
+	}
 	return false
 }
 
+// RetryAfter returns how long the source of err asked callers to wait
+// before retrying, e.g. from a Retry-After header, or 0 if it did not say.
+func RetryAfter(err error) time.Duration {
+	var delayed interface{ RetryDelay() time.Duration }
+	if errors.As(err, &delayed) {
+		return delayed.RetryDelay()
+	}
+	return 0
+}
+
 // IsRecoverable checks if an error is recoverable
 func IsRecoverable(err error) bool {
 	var bpErr *Error
@@ -167,16 +217,32 @@ func IsRecoverable(err error) bool {
 
 // GetUserMessage returns a user-friendly error message
 func GetUserMessage(err error) string {
+	if msg, ok := UserMessage(err); ok {
+		return msg
+	}
 	var bpErr *Error
 	if errors.As(err, &bpErr) {
-		if bpErr.UserMsg != "" {
-			return bpErr.UserMsg
-		}
 		return bpErr.Message
 	}
 	return err.Error()
 }
 
+// UserMessage returns the user-friendly message err carries, if any: a b+
+// error's UserMsg or the message of a typed error.
+func UserMessage(err error) (string, bool) {
+	var bpErr *Error
+	if errors.As(err, &bpErr) && bpErr.UserMsg != "" {
+		return bpErr.UserMsg, true
+	}
+	var typed Typed
+	if errors.As(err, &typed) {
+		if msg := typed.UserMessage(); msg != "" {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
 // Common error constructors
 
 // NewConfigError creates a configuration error
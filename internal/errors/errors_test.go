@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -301,6 +302,53 @@ func TestErrorChaining(t *testing.T) {
 	assert.True(t, Is(err3, ErrCodeDatabase))
 }
 
+// typedError is a self-classifying error as other packages define them.
+type typedError struct {
+	code      ErrorCode
+	retryable bool
+	delay     time.Duration
+}
+
+func (e *typedError) Error() string             { return "typed: " + string(e.code) }
+func (e *typedError) ErrorCode() ErrorCode      { return e.code }
+func (e *typedError) IsRetryable() bool         { return e.retryable }
+func (e *typedError) UserMessage() string       { return "user: " + string(e.code) }
+func (e *typedError) RetryDelay() time.Duration { return e.delay }
+
+func TestTypedErrors(t *testing.T) {
+	t.Run("wrap adopts classification", func(t *testing.T) {
+		base := &typedError{code: ErrCodeProviderOverloaded, retryable: true, delay: 3 * time.Second}
+		wrapped := Wrap(base, ErrCodeProvider, "LLM call failed")
+
+		assert.Equal(t, ErrCodeProviderOverloaded, wrapped.Code)
+		assert.True(t, wrapped.Retryable)
+		assert.Equal(t, "user: PROVIDER_OVERLOADED", GetUserMessage(wrapped))
+		assert.Equal(t, 3*time.Second, RetryAfter(wrapped))
+		assert.True(t, errors.Is(wrapped, base))
+	})
+
+	t.Run("generic code keeps the caller's", func(t *testing.T) {
+		wrapped := Wrap(&typedError{code: ErrCodeProvider}, ErrCodeInternal, "failed")
+		assert.Equal(t, ErrCodeInternal, wrapped.Code)
+		assert.False(t, wrapped.Retryable)
+	})
+
+	t.Run("unwrapped", func(t *testing.T) {
+		err := &typedError{code: ErrCodeProviderQuota}
+		assert.True(t, Is(err, ErrCodeProviderQuota))
+		assert.False(t, IsRetryable(err))
+		msg, ok := UserMessage(err)
+		assert.True(t, ok)
+		assert.Equal(t, "user: PROVIDER_QUOTA_EXCEEDED", msg)
+	})
+
+	t.Run("plain errors", func(t *testing.T) {
+		_, ok := UserMessage(errors.New("plain"))
+		assert.False(t, ok)
+		assert.Zero(t, RetryAfter(errors.New("plain")))
+	})
+}
+
 func TestErrorContext(t *testing.T) {
 	err := New(ErrCodeTool, "tool error")
 	err.WithContext("tool", "bash").
@@ -0,0 +1,355 @@
+// Package events provides a typed, in-process publish/subscribe bus.
+// The bus decouples producers (agent, layers) from consumers (UI, headless
+// output, integrations) so they can all share a single event source.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of an event.
+type Type string
+
+const (
+	TypeToolStarted         Type = "tool_started"
+	TypeToolFinished        Type = "tool_finished"
+	TypeToolProgress        Type = "tool_progress"
+	TypePermissionRequested Type = "permission_requested"
+	TypeCostUpdated         Type = "cost_updated"
+	TypeLayerChanged        Type = "layer_changed"
+	TypeRateLimitUpdated    Type = "rate_limit_updated"
+	TypeModelLoadChanged    Type = "model_load_changed"
+	TypeLayerDegraded       Type = "layer_degraded"
+	TypeProvidersChecked    Type = "providers_checked"
+	TypeRepositoryScanned   Type = "repository_scanned"
+	TypeSignInRequested     Type = "sign_in_requested"
+	TypeDraftStreamed       Type = "draft_streamed"
+	TypeDraftReplaced       Type = "draft_replaced"
+	TypeTodosUpdated        Type = "todos_updated"
+	TypeFileChanged         Type = "file_changed"
+	TypeAnswerTruncated     Type = "answer_truncated"
+)
+
+// Event is implemented by every event published on the bus.
+type Event interface {
+	Type() Type
+}
+
+// ToolStarted is published before a tool is executed.
+type ToolStarted struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Time      time.Time              `json:"time"`
+}
+
+// ToolFinished is published after a tool has executed (or failed to).
+type ToolFinished struct {
+	Tool     string        `json:"tool"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Time     time.Time     `json:"time"`
+}
+
+// ToolProgress is published while a long-running tool call works, such as
+// a large or multi-file write or a command in a shell session. Zero totals
+// are unknown.
+type ToolProgress struct {
+	Tool       string    `json:"tool"`
+	Path       string    `json:"path,omitempty"` // File being worked on
+	Bytes      int64     `json:"bytes,omitempty"`
+	TotalBytes int64     `json:"total_bytes,omitempty"`
+	Files      int       `json:"files,omitempty"` // Files completed
+	TotalFiles int       `json:"total_files,omitempty"`
+	Output     string    `json:"output,omitempty"` // Tail of the command output so far
+	Time       time.Time `json:"time"`
+}
+
+// PermissionRequested is published when a tool asks for a permission.
+type PermissionRequested struct {
+	Tool       string    `json:"tool"`
+	Permission string    `json:"permission"`
+	Resource   string    `json:"resource,omitempty"`
+	Preview    string    `json:"preview,omitempty"`  // Change the call would make, such as a diff
+	Elevated   bool      `json:"elevated,omitempty"` // The call would expose credentials
+	Reason     string    `json:"reason,omitempty"`   // Why the call is elevated
+	Time       time.Time `json:"time"`
+}
+
+// CostUpdated is published when token usage and cost change.
+type CostUpdated struct {
+	Model           string    `json:"model"`
+	InputTokens     int       `json:"input_tokens"`
+	OutputTokens    int       `json:"output_tokens"`
+	Cost            float64   `json:"cost"`       // Cost of the latest call
+	TotalCost       float64   `json:"total_cost"` // Session total
+	TotalTokens     int       `json:"total_tokens"`
+	ReasoningTokens int       `json:"reasoning_tokens,omitempty"` // Session total, included in TotalTokens
+	ReasoningCost   float64   `json:"reasoning_cost,omitempty"`   // Session total, included in TotalCost
+	Time            time.Time `json:"time"`
+}
+
+// LayerChanged is published when execution moves to another layer.
+type LayerChanged struct {
+	From int       `json:"from"`
+	To   int       `json:"to"`
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// RateLimitUpdated is published when a provider API reports a new rate limit
+// or requests start or stop waiting for one. Counts are -1 when unknown.
+type RateLimitUpdated struct {
+	Host              string    `json:"host"`
+	RequestsLimit     int       `json:"requests_limit"`
+	RequestsRemaining int       `json:"requests_remaining"`
+	TokensRemaining   int       `json:"tokens_remaining"`
+	BlockedUntil      time.Time `json:"blocked_until,omitempty"` // Zero if not limited
+	Queued            int       `json:"queued"`
+	Time              time.Time `json:"time"`
+}
+
+// Model load states
+const (
+	ModelLoading = "loading"
+	ModelReady   = "ready"
+	ModelFailed  = "failed"
+	ModelMissing = "missing" // Not installed, but the provider can pull it
+	ModelPulling = "pulling"
+)
+
+// ModelLoadChanged is published when a local model starts or finishes
+// loading into memory ahead of the first prompt, and while a model that
+// isn't installed yet is downloaded.
+type ModelLoadChanged struct {
+	Model    string    `json:"model"`
+	State    string    `json:"state"`              // ModelLoading, ModelReady, ModelFailed, ModelMissing or ModelPulling
+	Progress float64   `json:"progress,omitempty"` // Fraction loaded while ModelLoading or downloaded while ModelPulling, if known
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// LayerDegraded is published when a Thorough Mode layer's model fails its
+// health check and the layer runs on a substitute, is skipped, or cannot run.
+type LayerDegraded struct {
+	Layer      string    `json:"layer"`
+	Configured string    `json:"configured"`
+	Model      string    `json:"model,omitempty"` // Substitute; empty when skipped or failed
+	Skipped    bool      `json:"skipped"`
+	Reason     string    `json:"reason"`
+	Time       time.Time `json:"time"`
+}
+
+// ProvidersChecked is published when a round of provider connection tests
+// has finished.
+type ProvidersChecked struct {
+	Healthy int       `json:"healthy"`
+	Total   int       `json:"total"`
+	Time    time.Time `json:"time"`
+}
+
+// RepositoryScanned is published when the pre-flight scan of the project
+// finds a repository large enough to be expensive to work on.
+type RepositoryScanned struct {
+	Files       int       `json:"files"`
+	Tokens      int       `json:"tokens"`       // Estimated size of the source, and of an index of it
+	TurnTokens  int       `json:"turn_tokens"`  // Estimated context of a typical exploring turn
+	TurnCost    float64   `json:"turn_cost"`    // Price of TurnTokens with the current model, 0 if free or unknown
+	Model       string    `json:"model"`        // Model the cost is estimated for
+	LargestDirs []string  `json:"largest_dirs"` // Top-level directories holding most of the source, largest first
+	LocalModel  string    `json:"local_model"`  // Configured local model to explore with, if any
+	Truncated   bool      `json:"truncated"`    // The scan stopped early; the repository is even larger
+	Time        time.Time `json:"time"`
+}
+
+// SignInRequested is published when a provider gateway needs the user to
+// sign in with a device code before requests can be sent.
+type SignInRequested struct {
+	Provider        string    `json:"provider"`
+	UserCode        string    `json:"user_code"`
+	VerificationURI string    `json:"verification_uri"` // Where to enter UserCode
+	ExpiresAt       time.Time `json:"expires_at"`
+	Time            time.Time `json:"time"`
+}
+
+// DraftStreamed is published as a cheap model's draft answer grows, while
+// the configured model works on the answer that replaces it. Content is the
+// draft so far.
+type DraftStreamed struct {
+	Model   string    `json:"model"`
+	Content string    `json:"content"`
+	Done    bool      `json:"done"` // The draft is complete; the real answer is still coming
+	Time    time.Time `json:"time"`
+}
+
+// DraftReplaced is published when a draft answer is withdrawn: replaced by
+// the configured model's answer, or discarded because the draft failed or
+// the turn went on to use tools.
+type DraftReplaced struct {
+	Model     string    `json:"model"`
+	Discarded bool      `json:"discarded"`
+	Time      time.Time `json:"time"`
+}
+
+// Todo is an item of the agent's task list.
+type Todo struct {
+	ID      int64  `json:"id"`
+	Content string `json:"content"`
+	Status  string `json:"status"` // pending, in_progress or completed
+}
+
+// TodosUpdated is published when the agent changes its task list for a
+// session. Todos is the whole list, in order.
+type TodosUpdated struct {
+	Session string    `json:"session,omitempty"`
+	Todos   []Todo    `json:"todos"`
+	Time    time.Time `json:"time"`
+}
+
+// FileChanged is published when a file the agent is working with changes
+// outside b+, such as in the user's editor.
+type FileChanged struct {
+	Path string    `json:"path"`
+	Kind string    `json:"kind"` // modified, created or deleted
+	Time time.Time `json:"time"`
+}
+
+// AnswerTruncated is published when an answer still ends at the token
+// limit after it was resumed Continuations times.
+type AnswerTruncated struct {
+	Model         string    `json:"model"`
+	Continuations int       `json:"continuations"`
+	Time          time.Time `json:"time"`
+}
+
+func (ToolStarted) Type() Type         { return TypeToolStarted }
+func (ToolFinished) Type() Type        { return TypeToolFinished }
+func (ToolProgress) Type() Type        { return TypeToolProgress }
+func (PermissionRequested) Type() Type { return TypePermissionRequested }
+func (CostUpdated) Type() Type         { return TypeCostUpdated }
+func (LayerChanged) Type() Type        { return TypeLayerChanged }
+func (RateLimitUpdated) Type() Type    { return TypeRateLimitUpdated }
+func (ModelLoadChanged) Type() Type    { return TypeModelLoadChanged }
+func (LayerDegraded) Type() Type       { return TypeLayerDegraded }
+func (ProvidersChecked) Type() Type    { return TypeProvidersChecked }
+func (RepositoryScanned) Type() Type   { return TypeRepositoryScanned }
+func (SignInRequested) Type() Type     { return TypeSignInRequested }
+func (DraftStreamed) Type() Type       { return TypeDraftStreamed }
+func (DraftReplaced) Type() Type       { return TypeDraftReplaced }
+func (TodosUpdated) Type() Type        { return TypeTodosUpdated }
+func (FileChanged) Type() Type         { return TypeFileChanged }
+func (AnswerTruncated) Type() Type     { return TypeAnswerTruncated }
+
+// Handler receives published events.
+type Handler func(Event)
+
+type subscription struct {
+	types   map[Type]bool // nil means all types
+	handler Handler
+}
+
+// Bus is a synchronous, thread-safe event bus.
+// A nil *Bus is valid and discards all events.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]subscription
+}
+
+// NewBus creates a new event bus.
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int]subscription),
+	}
+}
+
+// Subscribe registers a handler for the given event types, or for all
+// events if none are given. It returns a function that removes the handler.
+func (b *Bus) Subscribe(handler Handler, types ...Type) func() {
+	sub := subscription{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Publish delivers an event to every matching subscriber, in the caller's
+// goroutine. Handlers must not block.
+func (b *Bus) Publish(event Event) {
+	if b == nil || event == nil {
+		return
+	}
+
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.subs))
+	for _, sub := range b.subs {
+		if sub.types == nil || sub.types[event.Type()] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// Channel subscribes a buffered channel to the given event types. Events are
+// dropped rather than blocking the publisher when the buffer is full. The
+// returned function unsubscribes and closes the channel.
+func (b *Bus) Channel(buffer int, types ...Type) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	var mu sync.Mutex
+	closed := false
+
+	unsubscribe := b.Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}, types...)
+
+	return ch, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+}
+
+// Subscribers returns the number of active subscriptions.
+func (b *Bus) Subscribers() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_SubscribeAll(t *testing.T) {
+	bus := NewBus()
+
+	var got []Type
+	unsubscribe := bus.Subscribe(func(e Event) {
+		got = append(got, e.Type())
+	})
+
+	bus.Publish(ToolStarted{Tool: "read"})
+	bus.Publish(CostUpdated{Cost: 0.01})
+	assert.Equal(t, []Type{TypeToolStarted, TypeCostUpdated}, got)
+
+	unsubscribe()
+	unsubscribe() // idempotent
+	bus.Publish(LayerChanged{To: 4})
+	assert.Len(t, got, 2)
+	assert.Equal(t, 0, bus.Subscribers())
+}
+
+func TestBus_SubscribeFiltered(t *testing.T) {
+	bus := NewBus()
+
+	var costs []float64
+	bus.Subscribe(func(e Event) {
+		costs = append(costs, e.(CostUpdated).TotalCost)
+	}, TypeCostUpdated)
+
+	bus.Publish(ToolStarted{Tool: "read"})
+	bus.Publish(CostUpdated{TotalCost: 0.5})
+	bus.Publish(PermissionRequested{Tool: "bash"})
+
+	assert.Equal(t, []float64{0.5}, costs)
+}
+
+func TestBus_NilSafe(t *testing.T) {
+	var bus *Bus
+	assert.NotPanics(t, func() {
+		bus.Publish(ToolStarted{Tool: "read"})
+	})
+	assert.Equal(t, 0, bus.Subscribers())
+}
+
+func TestBus_Channel(t *testing.T) {
+	bus := NewBus()
+
+	ch, cancel := bus.Channel(1, TypeToolStarted)
+	bus.Publish(ToolStarted{Tool: "read"})
+	bus.Publish(ToolStarted{Tool: "write"}) // dropped, buffer full
+
+	e := <-ch
+	require.IsType(t, ToolStarted{}, e)
+	assert.Equal(t, "read", e.(ToolStarted).Tool)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok, "channel should be closed")
+
+	// Publishing after cancel must not panic
+	assert.NotPanics(t, func() {
+		bus.Publish(ToolStarted{Tool: "read"})
+	})
+}
+
+func TestBus_ConcurrentPublish(t *testing.T) {
+	bus := NewBus()
+
+	var mu sync.Mutex
+	count := 0
+	bus.Subscribe(func(Event) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.Publish(CostUpdated{})
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, count)
+}
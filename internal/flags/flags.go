@@ -0,0 +1,166 @@
+// Package flags gates subsystems that aren't ready to be on for everyone.
+// A flag is set in the experimental section of the config file, overridden
+// per user by a BPLUS_EXPERIMENTAL_<NAME> environment variable, and can be
+// toggled for the session in /flags. Flags move through stages: they ship
+// experimental and off, and once their subsystem is on for everyone they
+// are deprecated, still honored but warned about, before being removed.
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EnvPrefix starts the environment variables that override flags, e.g.
+// BPLUS_EXPERIMENTAL_WORKTREE_FINISH=1.
+const EnvPrefix = "BPLUS_EXPERIMENTAL_"
+
+// Flag names
+const (
+	WorktreeFinish = "worktree_finish"
+)
+
+// Registry holds every flag b+ knows.
+var Registry = []Flag{
+	{
+		Name:        WorktreeFinish,
+		Description: "/finish: test, squash-merge, push or discard the work of a linked git worktree",
+		Stage:       StageExperimental,
+	},
+}
+
+// Stage is how far along a flag is.
+type Stage string
+
+// Flag stages
+const (
+	StageExperimental Stage = "experimental" // Off unless turned on; may change or go away
+	StageDeprecated   Stage = "deprecated"   // Still honored, but due for removal
+)
+
+// Flag describes a gated subsystem.
+type Flag struct {
+	Name        string
+	Description string
+	Stage       Stage
+	Default     bool
+	Deprecation string // Why a deprecated flag is going away and what replaces it
+}
+
+// Source says where a flag's value came from.
+type Source string
+
+// Value sources, from lowest to highest precedence
+const (
+	SourceDefault Source = "default"
+	SourceConfig  Source = "config"
+	SourceEnv     Source = "env"
+	SourceSession Source = "session" // Toggled in /flags; not saved
+)
+
+// State is a flag and its current value.
+type State struct {
+	Flag
+	Enabled bool
+	Source  Source
+}
+
+// Set holds the values of the flags. It is safe for concurrent use.
+type Set struct {
+	mu     sync.RWMutex
+	states map[string]*State
+}
+
+// New resolves the values of flags from the experimental section of the
+// config and the environment, given as os.Environ returns it. Unknown and
+// deprecated flags that are set, and unreadable values, are reported as
+// warnings rather than errors, so a stale config doesn't stop b+ starting.
+func New(flags []Flag, config map[string]bool, environ []string) (*Set, []string) {
+	s := &Set{states: make(map[string]*State, len(flags))}
+	for _, f := range flags {
+		s.states[f.Name] = &State{Flag: f, Enabled: f.Default, Source: SourceDefault}
+	}
+
+	var warnings []string
+	set := func(name string, enabled bool, source Source) {
+		state, ok := s.states[name]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown experimental flag %s (set in %s)", name, source))
+			return
+		}
+		if state.Stage == StageDeprecated {
+			warnings = append(warnings, fmt.Sprintf("experimental flag %s is deprecated: %s", name, state.Deprecation))
+		}
+		state.Enabled = enabled
+		state.Source = source
+	}
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		set(name, config[name], SourceConfig)
+	}
+
+	sort.Strings(environ)
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, EnvPrefix))
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s=%s is not a boolean; ignored", key, value))
+			continue
+		}
+		set(name, enabled, SourceEnv)
+	}
+	return s, warnings
+}
+
+// EnvName returns the environment variable that overrides a flag.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(name)
+}
+
+// Enabled reports whether a flag is on. Unknown flags are off.
+func (s *Set) Enabled(name string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, ok := s.states[name]
+	return ok && state.Enabled
+}
+
+// Toggle turns a flag on or off for the rest of the session.
+func (s *Set) Toggle(name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[name]
+	if !ok {
+		return fmt.Errorf("unknown experimental flag %s", name)
+	}
+	state.Enabled = enabled
+	state.Source = SourceSession
+	return nil
+}
+
+// States returns every flag and its value, by name.
+func (s *Set) States() []State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	states := make([]State, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
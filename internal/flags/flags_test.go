@@ -0,0 +1,77 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFlags = []Flag{
+	{Name: "parallel_agents", Stage: StageExperimental},
+	{Name: "fast_diff", Stage: StageExperimental, Default: true},
+	{Name: "old_router", Stage: StageDeprecated, Deprecation: "the new router is always on"},
+}
+
+func TestNew(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		s, warnings := New(testFlags, nil, nil)
+		assert.Empty(t, warnings)
+		assert.False(t, s.Enabled("parallel_agents"))
+		assert.True(t, s.Enabled("fast_diff"))
+		assert.False(t, s.Enabled("missing"))
+	})
+
+	t.Run("environment overrides config", func(t *testing.T) {
+		s, warnings := New(testFlags,
+			map[string]bool{"parallel_agents": true, "fast_diff": false},
+			[]string{"HOME=/home/dev", "BPLUS_EXPERIMENTAL_PARALLEL_AGENTS=0"})
+		assert.Empty(t, warnings)
+		assert.False(t, s.Enabled("parallel_agents"))
+		assert.False(t, s.Enabled("fast_diff"))
+
+		states := s.States()
+		require.Len(t, states, 3)
+		assert.Equal(t, "fast_diff", states[0].Name)
+		assert.Equal(t, SourceConfig, states[0].Source)
+		assert.Equal(t, SourceDefault, states[1].Source)
+		assert.Equal(t, SourceEnv, states[2].Source)
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		s, warnings := New(testFlags,
+			map[string]bool{"old_router": true, "graduated": true},
+			[]string{"BPLUS_EXPERIMENTAL_FAST_DIFF=maybe"})
+		assert.True(t, s.Enabled("old_router"), "deprecated flags are still honored")
+		assert.True(t, s.Enabled("fast_diff"))
+		assert.Equal(t, []string{
+			"unknown experimental flag graduated (set in config)",
+			"experimental flag old_router is deprecated: the new router is always on",
+			"BPLUS_EXPERIMENTAL_FAST_DIFF=maybe is not a boolean; ignored",
+		}, warnings)
+	})
+}
+
+func TestToggle(t *testing.T) {
+	s, _ := New(testFlags, nil, nil)
+	require.NoError(t, s.Toggle("parallel_agents", true))
+	assert.True(t, s.Enabled("parallel_agents"))
+	assert.Equal(t, SourceSession, s.States()[2].Source)
+	assert.Error(t, s.Toggle("missing", true))
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "BPLUS_EXPERIMENTAL_WORKTREE_FINISH", EnvName(WorktreeFinish))
+}
+
+func TestRegistry(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, f := range Registry {
+		assert.False(t, seen[f.Name], "duplicate flag %s", f.Name)
+		seen[f.Name] = true
+		assert.NotEmpty(t, f.Description, f.Name)
+		if f.Stage == StageDeprecated {
+			assert.NotEmpty(t, f.Deprecation, f.Name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AiderImporter imports Aider chat history (.aider.chat.history.md).
+// Each "# aider chat started at" heading begins a new conversation; user
+// turns are prefixed with "#### " and tool output with "> ".
+type AiderImporter struct{}
+
+const aiderHistoryFile = ".aider.chat.history.md"
+
+var (
+	aiderStartRe = regexp.MustCompile(`^# aider chat started at (.+)$`)
+	aiderFileRe  = regexp.MustCompile(`^(?:Added|Applied edit to|Creating empty file) (.+?)(?: to the chat)?\.?$`)
+)
+
+// Name returns the importer name.
+func (i *AiderImporter) Name() string {
+	return "aider"
+}
+
+// Detect reports whether the file is an Aider chat history.
+func (i *AiderImporter) Detect(path string) bool {
+	if filepath.Base(path) == aiderHistoryFile {
+		return true
+	}
+	if filepath.Ext(path) != ".md" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 0; n < 5 && scanner.Scan(); n++ {
+		if aiderStartRe.MatchString(strings.TrimSpace(scanner.Text())) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads every chat session in the history file.
+func (i *AiderImporter) Parse(path string) ([]*Conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var (
+		conversations []*Conversation
+		conv          *Conversation
+		role          string
+		buf           []string
+		files         []string
+	)
+
+	flush := func() {
+		if conv == nil || role == "" {
+			buf, files, role = nil, nil, ""
+			return
+		}
+		content := strings.TrimSpace(strings.Join(buf, "\n"))
+		if content != "" || len(files) > 0 {
+			conv.Messages = append(conv.Messages, Message{Role: role, Content: content, Timestamp: conv.StartedAt, Files: files})
+		}
+		buf, files, role = nil, nil, ""
+	}
+
+	startConversation := func(started string) {
+		flush()
+		t, _ := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSpace(started), time.Local)
+		conv = &Conversation{
+			Source:     i.Name(),
+			SourceID:   fmt.Sprintf("%s#%d", path, len(conversations)),
+			SourcePath: path,
+			ProjectDir: filepath.Dir(path),
+			StartedAt:  t,
+		}
+		conversations = append(conversations, conv)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := aiderStartRe.FindStringSubmatch(line); m != nil {
+			startConversation(m[1])
+			continue
+		}
+		if conv == nil {
+			startConversation("")
+		}
+
+		var lineRole, text string
+		switch {
+		case strings.HasPrefix(line, "#### "):
+			lineRole, text = "user", strings.TrimPrefix(line, "#### ")
+		case line == "####":
+			lineRole = "user"
+		case strings.HasPrefix(line, "> ") || line == ">":
+			lineRole, text = "tool", strings.TrimPrefix(strings.TrimPrefix(line, ">"), " ")
+			if m := aiderFileRe.FindStringSubmatch(text); m != nil {
+				files = append(files, m[1])
+			}
+		default:
+			if strings.TrimSpace(line) == "" && role != "assistant" {
+				// Blank lines separate turns; keep them only inside replies
+				if role != "" {
+					buf = append(buf, "")
+				}
+				continue
+			}
+			lineRole, text = "assistant", line
+		}
+
+		if lineRole != role {
+			flush()
+			role = lineRole
+		}
+		buf = append(buf, text)
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	result := make([]*Conversation, 0, len(conversations))
+	for _, c := range conversations {
+		if len(c.Messages) == 0 {
+			continue
+		}
+		c.Title = titleFrom(c.Messages)
+		result = append(result, c)
+	}
+	return result, nil
+}
@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ClaudeCodeImporter imports Claude Code transcripts
+// (~/.claude/projects/<project>/<session>.jsonl).
+type ClaudeCodeImporter struct{}
+
+// claudeLine is one entry of a Claude Code JSONL transcript.
+type claudeLine struct {
+	Type      string          `json:"type"`
+	SessionID string          `json:"sessionId"`
+	Timestamp string          `json:"timestamp"`
+	Cwd       string          `json:"cwd"`
+	Summary   string          `json:"summary"`
+	Message   json.RawMessage `json:"message"`
+}
+
+type claudeMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type claudeBlock struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text"`
+	Name    string                 `json:"name"`
+	Input   map[string]interface{} `json:"input"`
+	Content json.RawMessage        `json:"content"`
+}
+
+// Name returns the importer name.
+func (i *ClaudeCodeImporter) Name() string {
+	return "claude-code"
+}
+
+// Detect reports whether the file is a Claude Code transcript.
+func (i *ClaudeCodeImporter) Detect(path string) bool {
+	if filepath.Ext(path) != ".jsonl" {
+		return false
+	}
+	found := false
+	_ = scanJSONL(path, 5, func(raw []byte) error {
+		var line claudeLine
+		if json.Unmarshal(raw, &line) == nil && line.SessionID != "" {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+// Parse reads the conversations in a transcript, one per session ID.
+func (i *ClaudeCodeImporter) Parse(path string) ([]*Conversation, error) {
+	bySession := make(map[string]*Conversation)
+	var order []string
+	var summary string
+
+	err := scanJSONL(path, 0, func(raw []byte) error {
+		var line claudeLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil // Skip malformed lines rather than failing the whole file
+		}
+		if line.Type == "summary" {
+			if summary == "" {
+				summary = line.Summary
+			}
+			return nil
+		}
+		if (line.Type != "user" && line.Type != "assistant") || len(line.Message) == 0 {
+			return nil
+		}
+
+		var msg claudeMessage
+		if err := json.Unmarshal(line.Message, &msg); err != nil {
+			return nil
+		}
+		converted, ok := convertClaudeMessage(msg)
+		if !ok {
+			return nil
+		}
+		converted.Timestamp = parseTime(line.Timestamp)
+
+		conv, exists := bySession[line.SessionID]
+		if !exists {
+			conv = &Conversation{
+				Source:     i.Name(),
+				SourceID:   line.SessionID,
+				SourcePath: path,
+				ProjectDir: line.Cwd,
+			}
+			bySession[line.SessionID] = conv
+			order = append(order, line.SessionID)
+		}
+		conv.Messages = append(conv.Messages, converted)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make([]*Conversation, 0, len(order))
+	for _, id := range order {
+		conv := bySession[id]
+		conv.Title = summary
+		if conv.Title == "" {
+			conv.Title = titleFrom(conv.Messages)
+		}
+		conv.StartedAt = startedAt(conv.Messages)
+		conversations = append(conversations, conv)
+	}
+	return conversations, nil
+}
+
+// convertClaudeMessage flattens content blocks into text and file references.
+// Tool results are recorded as "tool" messages.
+func convertClaudeMessage(msg claudeMessage) (Message, bool) {
+	out := Message{Role: msg.Role}
+
+	var text string
+	if err := json.Unmarshal(msg.Content, &text); err == nil {
+		out.Content = text
+		return out, strings.TrimSpace(text) != ""
+	}
+
+	var blocks []claudeBlock
+	if err := json.Unmarshal(msg.Content, &blocks); err != nil {
+		return out, false
+	}
+
+	var parts []string
+	toolResult := false
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			parts = append(parts, b.Text)
+		case "tool_use":
+			parts = append(parts, fmt.Sprintf("[tool: %s]", b.Name))
+			out.Files = append(out.Files, filesFromArgs(b.Input)...)
+		case "tool_result":
+			toolResult = true
+			parts = append(parts, blockText(b.Content))
+		}
+	}
+	if toolResult {
+		out.Role = "tool"
+	}
+
+	out.Content = strings.TrimSpace(strings.Join(parts, "\n"))
+	return out, out.Content != ""
+}
+
+// blockText extracts text from a tool_result content field, which is either
+// a string or a list of text blocks.
+func blockText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var blocks []claudeBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// scanJSONL calls fn for each non-empty line of a JSONL file, stopping after
+// limit lines when limit > 0.
+func scanJSONL(path string, limit int, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	count := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+			if fnErr := fn([]byte(trimmed)); fnErr != nil {
+				return fnErr
+			}
+			count++
+			if limit > 0 && count >= limit {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
+
+// parseTime parses an RFC 3339 timestamp, returning the zero time on failure.
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// CodexImporter imports Codex CLI rollouts
+// (~/.codex/sessions/YYYY/MM/DD/rollout-*.jsonl).
+type CodexImporter struct{}
+
+// codexLine is one entry of a rollout file. Newer rollouts wrap items in a
+// typed envelope with a payload; older ones store items at the top level.
+type codexLine struct {
+	Timestamp string          `json:"timestamp"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	SessionID string          `json:"sessionId"`
+}
+
+type codexItem struct {
+	ID        string       `json:"id"`
+	Type      string       `json:"type"`
+	Role      string       `json:"role"`
+	Content   []codexBlock `json:"content"`
+	Name      string       `json:"name"`
+	Arguments string       `json:"arguments"`
+	Output    string       `json:"output"`
+	Cwd       string       `json:"cwd"`
+	Timestamp string       `json:"timestamp"`
+}
+
+type codexBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Name returns the importer name.
+func (i *CodexImporter) Name() string {
+	return "codex"
+}
+
+// Detect reports whether the file is a Codex CLI rollout.
+func (i *CodexImporter) Detect(path string) bool {
+	if filepath.Ext(path) != ".jsonl" {
+		return false
+	}
+	found := false
+	_ = scanJSONL(path, 5, func(raw []byte) error {
+		var line codexLine
+		if json.Unmarshal(raw, &line) != nil || line.SessionID != "" {
+			return nil
+		}
+		switch line.Type {
+		case "session_meta", "response_item":
+			found = true
+		case "message":
+			var item codexItem
+			if json.Unmarshal(raw, &item) == nil && item.Role != "" {
+				found = true
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+// Parse reads the single conversation stored in a rollout file.
+func (i *CodexImporter) Parse(path string) ([]*Conversation, error) {
+	conv := &Conversation{
+		Source:     i.Name(),
+		SourceID:   path,
+		SourcePath: path,
+	}
+
+	err := scanJSONL(path, 0, func(raw []byte) error {
+		var line codexLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil
+		}
+
+		itemRaw := raw
+		if len(line.Payload) > 0 {
+			itemRaw = line.Payload
+		}
+		var item codexItem
+		if err := json.Unmarshal(itemRaw, &item); err != nil {
+			return nil
+		}
+
+		if line.Type == "session_meta" {
+			if item.ID != "" {
+				conv.SourceID = item.ID
+			}
+			conv.ProjectDir = item.Cwd
+			return nil
+		}
+
+		ts := parseTime(line.Timestamp)
+		switch item.Type {
+		case "message":
+			text := codexText(item.Content)
+			if text == "" || isCodexContext(text) {
+				return nil
+			}
+			conv.Messages = append(conv.Messages, Message{Role: item.Role, Content: text, Timestamp: ts})
+		case "function_call":
+			var args map[string]interface{}
+			_ = json.Unmarshal([]byte(item.Arguments), &args)
+			conv.Messages = append(conv.Messages, Message{
+				Role:      "assistant",
+				Content:   fmt.Sprintf("[tool: %s]", item.Name),
+				Timestamp: ts,
+				Files:     filesFromArgs(args),
+			})
+		case "function_call_output":
+			if item.Output != "" {
+				conv.Messages = append(conv.Messages, Message{Role: "tool", Content: item.Output, Timestamp: ts})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conv.Messages) == 0 {
+		return nil, nil
+	}
+	conv.Title = titleFrom(conv.Messages)
+	conv.StartedAt = startedAt(conv.Messages)
+	return []*Conversation{conv}, nil
+}
+
+// codexText joins the text blocks of a message.
+func codexText(blocks []codexBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// isCodexContext reports whether a user message is injected context rather
+// than something the user typed.
+func isCodexContext(text string) bool {
+	return strings.HasPrefix(text, "<environment_context>") || strings.HasPrefix(text, "<user_instructions>")
+}
@@ -0,0 +1,299 @@
+// Package importer converts conversation history from other coding
+// assistants (Claude Code, Codex CLI, Aider) into b+ sessions, so history
+// stays searchable after migrating.
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/storage"
+)
+
+// Conversation is a tool-agnostic conversation parsed from a history file.
+type Conversation struct {
+	Source     string    // Importer name, e.g. "claude-code"
+	SourceID   string    // Stable identifier within the source (session ID or file path)
+	SourcePath string    // File the conversation was read from
+	Title      string    // Human-readable title
+	ProjectDir string    // Working directory of the original session, if known
+	StartedAt  time.Time // Time of the first message, if known
+	Messages   []Message
+}
+
+// Message is a single conversation turn.
+type Message struct {
+	Role      string    // user, assistant, system, tool
+	Content   string    // Plain-text content
+	Timestamp time.Time // Original timestamp, if known
+	Files     []string  // Files referenced by this turn
+}
+
+// Files returns the sorted, de-duplicated set of files referenced by the conversation.
+func (c *Conversation) Files() []string {
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+	for _, msg := range c.Messages {
+		for _, f := range msg.Files {
+			if f != "" && !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Importer parses history files written by another tool.
+type Importer interface {
+	// Name returns the importer name used on the command line.
+	Name() string
+
+	// Detect reports whether the file looks like this importer's format.
+	Detect(path string) bool
+
+	// Parse reads all conversations from the file.
+	Parse(path string) ([]*Conversation, error)
+}
+
+// Importers returns all built-in importers.
+func Importers() []Importer {
+	return []Importer{
+		&ClaudeCodeImporter{},
+		&CodexImporter{},
+		&AiderImporter{},
+	}
+}
+
+// Get returns the importer with the given name.
+func Get(name string) (Importer, error) {
+	for _, imp := range Importers() {
+		if imp.Name() == name {
+			return imp, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown import source: %s", name)
+}
+
+// Detect returns the first importer that recognizes the file.
+func Detect(path string) (Importer, error) {
+	for _, imp := range Importers() {
+		if imp.Detect(path) {
+			return imp, nil
+		}
+	}
+	return nil, fmt.Errorf("unrecognized history format: %s", path)
+}
+
+// ParsePath parses a history file, or every recognized file under a
+// directory. If source is empty the format is detected per file.
+func ParsePath(source, path string) ([]*Conversation, error) {
+	var imp Importer
+	if source != "" {
+		var err error
+		if imp, err = Get(source); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err := filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+		}
+	}
+
+	var conversations []*Conversation
+	for _, f := range files {
+		fileImp := imp
+		if fileImp == nil {
+			if fileImp, err = Detect(f); err != nil {
+				if info.IsDir() {
+					continue // Skip unrelated files when scanning a directory
+				}
+				return nil, err
+			}
+		} else if info.IsDir() && !fileImp.Detect(f) {
+			continue
+		}
+
+		convs, err := fileImp.Parse(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", f, err)
+		}
+		conversations = append(conversations, convs...)
+	}
+
+	return conversations, nil
+}
+
+// Result summarizes an import into the session store.
+type Result struct {
+	SessionID string
+	Title     string
+	Messages  int
+	Files     int
+	Skipped   bool // Already imported earlier
+}
+
+// Save stores a conversation as a b+ session. Importing the same
+// conversation twice is a no-op.
+func Save(db *storage.SQLiteDB, conv *Conversation) (*Result, error) {
+	sessionID := SessionID(conv)
+	result := &Result{SessionID: sessionID, Title: conv.Title}
+
+	if _, err := db.GetSession(sessionID); err == nil {
+		result.Skipped = true
+		return result, nil
+	}
+
+	title := conv.Title
+	if title == "" {
+		title = fmt.Sprintf("Imported from %s", conv.Source)
+	}
+	if err := db.CreateSession(sessionID, title); err != nil {
+		return nil, err
+	}
+
+	// A partial session would be reported as skipped by every later import,
+	// so it is removed again, with its messages and files
+	if err := saveContents(db, sessionID, conv, result); err != nil {
+		if derr := db.DeleteSession(sessionID); derr != nil {
+			return nil, fmt.Errorf("%w (and failed to remove the partial session: %v)", err, derr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// saveContents stores the metadata, messages and files of a conversation
+// in its session, counting them in result.
+func saveContents(db *storage.SQLiteDB, sessionID string, conv *Conversation, result *Result) error {
+	meta := map[string]interface{}{
+		"imported_from": conv.Source,
+		"source_id":     conv.SourceID,
+		"source_path":   conv.SourcePath,
+	}
+	if conv.ProjectDir != "" {
+		meta["project_dir"] = conv.ProjectDir
+	}
+	if !conv.StartedAt.IsZero() {
+		meta["started_at"] = conv.StartedAt.Format(time.RFC3339)
+	}
+	session, err := db.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.Metadata = jsonString(meta)
+	if err := db.UpdateSession(session); err != nil {
+		return err
+	}
+
+	for _, msg := range conv.Messages {
+		msgMeta := map[string]interface{}{"imported_from": conv.Source}
+		if !msg.Timestamp.IsZero() {
+			msgMeta["original_timestamp"] = msg.Timestamp.Format(time.RFC3339)
+		}
+		if len(msg.Files) > 0 {
+			msgMeta["files"] = msg.Files
+		}
+		if err := db.AddMessage(&storage.Message{
+			SessionID: sessionID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Metadata:  jsonString(msgMeta),
+		}); err != nil {
+			return err
+		}
+		result.Messages++
+	}
+
+	for _, path := range conv.Files() {
+		if err := db.AddFile(&storage.File{SessionID: sessionID, Path: path}); err != nil {
+			return err
+		}
+		result.Files++
+	}
+	return nil
+}
+
+// SessionID returns the deterministic b+ session ID for a conversation.
+func SessionID(conv *Conversation) string {
+	sum := sha256.Sum256([]byte(conv.Source + "\x00" + conv.SourceID))
+	return "import_" + hex.EncodeToString(sum[:8])
+}
+
+// jsonString marshals v into a string pointer for storage metadata columns.
+func jsonString(v interface{}) *string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	s := string(data)
+	return &s
+}
+
+// titleFrom derives a short title from the first user message.
+func titleFrom(messages []Message) string {
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(msg.Content), "\n", 2)[0])
+		if line == "" {
+			continue
+		}
+		if len([]rune(line)) > 60 {
+			line = string([]rune(line)[:57]) + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+// startedAt returns the first known message timestamp.
+func startedAt(messages []Message) time.Time {
+	for _, msg := range messages {
+		if !msg.Timestamp.IsZero() {
+			return msg.Timestamp
+		}
+	}
+	return time.Time{}
+}
+
+// fileArgKeys are tool argument names that commonly hold file paths.
+var fileArgKeys = []string{"file_path", "path", "notebook_path", "filename"}
+
+// filesFromArgs extracts file paths from tool-call arguments.
+func filesFromArgs(args map[string]interface{}) []string {
+	var files []string
+	for _, key := range fileArgKeys {
+		if s, ok := args[key].(string); ok && s != "" {
+			files = append(files, s)
+		}
+	}
+	return files
+}
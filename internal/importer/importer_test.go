@@ -0,0 +1,215 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const claudeTranscript = `{"type":"summary","summary":"Fix login bug","leafUuid":"x"}
+{"type":"user","sessionId":"s1","cwd":"/work/app","timestamp":"2025-01-02T10:00:00Z","message":{"role":"user","content":"Fix the login bug in auth.go"}}
+{"type":"assistant","sessionId":"s1","timestamp":"2025-01-02T10:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"Let me look."},{"type":"tool_use","name":"Read","input":{"file_path":"/work/app/auth.go"}}]}}
+{"type":"user","sessionId":"s1","timestamp":"2025-01-02T10:00:06Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"package auth"}]}}
+not json
+{"type":"assistant","sessionId":"s1","timestamp":"2025-01-02T10:00:09Z","message":{"role":"assistant","content":[{"type":"text","text":"Fixed."}]}}
+`
+
+const codexRollout = `{"timestamp":"2025-02-01T09:00:00Z","type":"session_meta","payload":{"id":"codex-1","cwd":"/work/api"}}
+{"timestamp":"2025-02-01T09:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"<environment_context>cwd</environment_context>"}]}}
+{"timestamp":"2025-02-01T09:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Add a health endpoint"}]}}
+{"timestamp":"2025-02-01T09:00:03Z","type":"response_item","payload":{"type":"function_call","name":"apply_patch","arguments":"{\"path\":\"server.go\"}"}}
+{"timestamp":"2025-02-01T09:00:04Z","type":"response_item","payload":{"type":"function_call_output","output":"ok"}}
+{"timestamp":"2025-02-01T09:00:05Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Done."}]}}
+`
+
+const aiderHistory = `
+# aider chat started at 2025-03-01 08:00:00
+
+> Aider v0.50.0
+> Added parser.go to the chat.
+
+#### refactor the parser
+#### keep the API stable
+
+Sure, here is the change.
+
+> Applied edit to parser.go
+
+# aider chat started at 2025-03-02 08:00:00
+
+#### write a README
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestClaudeCodeImporter(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "s1.jsonl", claudeTranscript)
+
+	imp := &ClaudeCodeImporter{}
+	assert.True(t, imp.Detect(path))
+	assert.False(t, (&CodexImporter{}).Detect(path))
+
+	convs, err := imp.Parse(path)
+	require.NoError(t, err)
+	require.Len(t, convs, 1)
+
+	conv := convs[0]
+	assert.Equal(t, "s1", conv.SourceID)
+	assert.Equal(t, "Fix login bug", conv.Title)
+	assert.Equal(t, "/work/app", conv.ProjectDir)
+	require.Len(t, conv.Messages, 4)
+	assert.Equal(t, "user", conv.Messages[0].Role)
+	assert.Equal(t, "tool", conv.Messages[2].Role)
+	assert.Equal(t, "package auth", conv.Messages[2].Content)
+	assert.Equal(t, []string{"/work/app/auth.go"}, conv.Files())
+	assert.False(t, conv.StartedAt.IsZero())
+}
+
+func TestCodexImporter(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "rollout-1.jsonl", codexRollout)
+
+	imp := &CodexImporter{}
+	assert.True(t, imp.Detect(path))
+	assert.False(t, (&ClaudeCodeImporter{}).Detect(path))
+
+	convs, err := imp.Parse(path)
+	require.NoError(t, err)
+	require.Len(t, convs, 1)
+
+	conv := convs[0]
+	assert.Equal(t, "codex-1", conv.SourceID)
+	assert.Equal(t, "/work/api", conv.ProjectDir)
+	assert.Equal(t, "Add a health endpoint", conv.Title)
+	require.Len(t, conv.Messages, 4, "environment context should be skipped")
+	assert.Equal(t, []string{"server.go"}, conv.Files())
+}
+
+func TestAiderImporter(t *testing.T) {
+	path := writeFile(t, t.TempDir(), ".aider.chat.history.md", aiderHistory)
+
+	imp := &AiderImporter{}
+	assert.True(t, imp.Detect(path))
+
+	convs, err := imp.Parse(path)
+	require.NoError(t, err)
+	require.Len(t, convs, 2)
+
+	first := convs[0]
+	assert.Equal(t, "refactor the parser", first.Title)
+	assert.Equal(t, []string{"parser.go"}, first.Files())
+	assert.Equal(t, 2025, first.StartedAt.Year())
+
+	var roles []string
+	for _, msg := range first.Messages {
+		roles = append(roles, msg.Role)
+	}
+	assert.Equal(t, []string{"tool", "user", "assistant", "tool"}, roles)
+	assert.Equal(t, "refactor the parser\nkeep the API stable", first.Messages[1].Content)
+
+	assert.Equal(t, "write a README", convs[1].Title)
+}
+
+func TestParsePath_Directory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "claude/s1.jsonl", claudeTranscript)
+	writeFile(t, dir, "codex/rollout-1.jsonl", codexRollout)
+	writeFile(t, dir, "notes.txt", "unrelated")
+
+	convs, err := ParsePath("", dir)
+	require.NoError(t, err)
+	assert.Len(t, convs, 2)
+
+	convs, err = ParsePath("codex", dir)
+	require.NoError(t, err)
+	require.Len(t, convs, 1)
+	assert.Equal(t, "codex", convs[0].Source)
+
+	_, err = ParsePath("nope", dir)
+	assert.Error(t, err)
+
+	_, err = ParsePath("", filepath.Join(dir, "notes.txt"))
+	assert.Error(t, err)
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewSQLiteDB(filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	path := writeFile(t, dir, "s1.jsonl", claudeTranscript)
+	convs, err := ParsePath("claude-code", path)
+	require.NoError(t, err)
+	require.Len(t, convs, 1)
+
+	result, err := Save(db, convs[0])
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, 4, result.Messages)
+	assert.Equal(t, 1, result.Files)
+
+	session, err := db.GetSession(result.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "Fix login bug", session.Name)
+	require.NotNil(t, session.Metadata)
+	assert.Contains(t, *session.Metadata, `"imported_from":"claude-code"`)
+
+	// Imported history is searchable
+	found, err := db.SearchMessages("login")
+	require.NoError(t, err)
+	assert.NotEmpty(t, found)
+
+	files, err := db.GetFiles(result.SessionID)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// Importing again is a no-op
+	again, err := Save(db, convs[0])
+	require.NoError(t, err)
+	assert.True(t, again.Skipped)
+	assert.Equal(t, result.SessionID, again.SessionID)
+}
+
+func TestSave_PartialImportRemoved(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewSQLiteDB(filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	path := writeFile(t, dir, "s1.jsonl", claudeTranscript)
+	convs, err := ParsePath("claude-code", path)
+	require.NoError(t, err)
+	require.Len(t, convs, 1)
+
+	// Fail after the messages are stored
+	_, err = db.DB().Exec("CREATE TRIGGER fail_files BEFORE INSERT ON files BEGIN SELECT RAISE(ABORT, 'disk full'); END")
+	require.NoError(t, err)
+	_, err = Save(db, convs[0])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+
+	_, err = db.GetSession(SessionID(convs[0]))
+	assert.Error(t, err, "the partial session is removed")
+	messages, err := db.GetSessionMessages(SessionID(convs[0]))
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	// So a later import is not skipped
+	_, err = db.DB().Exec("DROP TRIGGER fail_files")
+	require.NoError(t, err)
+	result, err := Save(db, convs[0])
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, 4, result.Messages)
+	assert.Equal(t, 1, result.Files)
+}
@@ -0,0 +1,484 @@
+// Package mcp serves b+ tools over the Model Context Protocol, so editors
+// and other agents can call them. The server speaks JSON-RPC 2.0 over
+// stdio, one message per line, and runs each call through an Executor,
+// which is where permission checks and sandboxing happen.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// ProtocolVersions are the MCP revisions the server speaks, latest first.
+var ProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// Protocol methods
+const (
+	MethodInitialize  = "initialize"
+	MethodInitialized = "notifications/initialized"
+	MethodCancelled   = "notifications/cancelled"
+	MethodPing        = "ping"
+	MethodToolsList   = "tools/list"
+	MethodToolsCall   = "tools/call"
+	MethodElicit      = "elicitation/create" // Sent to the client
+)
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// maxMessageSize is the largest line accepted from a client.
+const maxMessageSize = 16 * 1024 * 1024
+
+// Executor runs a call of a served tool.
+type Executor func(ctx context.Context, name string, arguments map[string]interface{}) (*tools.Result, error)
+
+// RPCError is a JSON-RPC error, sent to or received from a client.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements error.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithInfo sets the name and version the server reports to clients.
+func WithInfo(name, version string) Option {
+	return func(s *Server) {
+		s.name, s.version = name, version
+	}
+}
+
+// WithInstructions sets the usage hints the server gives clients.
+func WithInstructions(text string) Option {
+	return func(s *Server) {
+		s.instructions = text
+	}
+}
+
+// Server serves a set of tools to one client.
+type Server struct {
+	name         string
+	version      string
+	instructions string
+	tools        []tools.Tool
+	exec         Executor
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu        sync.Mutex
+	nextID    int64
+	pending   map[int64]chan message        // Requests sent to the client
+	calls     map[string]context.CancelFunc // Calls in flight, by request ID
+	canElicit bool
+	done      chan struct{} // Closed when the client disconnects
+}
+
+// NewServer creates a server for the given tools, running their calls
+// with exec.
+func NewServer(served []tools.Tool, exec Executor, opts ...Option) *Server {
+	s := &Server{
+		name:    "bplus",
+		version: "dev",
+		tools:   served,
+		exec:    exec,
+		pending: make(map[int64]chan message),
+		calls:   make(map[string]context.CancelFunc),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Serve reads requests from in and writes responses to out until in
+// closes or ctx is done. Tool calls run concurrently; those still running
+// when the client goes away are cancelled and waited for.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.enc = json.NewEncoder(out)
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		close(s.done)
+		cancel()
+		wg.Wait()
+	}()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			select {
+			case lines <- slices.Clone(scanner.Bytes()):
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if err != nil {
+				return fmt.Errorf("failed to read from client: %w", err)
+			}
+			return nil
+		case line = <-lines:
+		}
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			s.reply(nil, nil, &RPCError{Code: CodeParseError, Message: "invalid JSON: " + err.Error()})
+			continue
+		}
+		switch {
+		case msg.Method == "" && msg.ID != nil:
+			s.deliver(msg)
+		case msg.Method == "":
+			s.reply(nil, nil, &RPCError{Code: CodeInvalidRequest, Message: "message has no method"})
+		case msg.Method == MethodToolsCall && msg.ID != nil:
+			callCtx, stop := context.WithCancel(ctx)
+			s.mu.Lock()
+			s.calls[string(msg.ID)] = stop
+			s.mu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, err := s.callTool(callCtx, msg.Params)
+				s.mu.Lock()
+				delete(s.calls, string(msg.ID))
+				s.mu.Unlock()
+				stop()
+				s.reply(msg.ID, result, err)
+			}()
+		default:
+			result, err := s.handle(msg)
+			if msg.ID != nil {
+				s.reply(msg.ID, result, err)
+			}
+		}
+	}
+}
+
+// handle answers a request other than a tool call. Notifications get a
+// nil result and error.
+func (s *Server) handle(msg message) (interface{}, *RPCError) {
+	switch msg.Method {
+	case MethodInitialize:
+		return s.initialize(msg.Params)
+	case MethodInitialized:
+		return nil, nil
+	case MethodCancelled:
+		var params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err == nil {
+			s.mu.Lock()
+			if stop, ok := s.calls[string(params.RequestID)]; ok {
+				stop()
+			}
+			s.mu.Unlock()
+		}
+		return nil, nil
+	case MethodPing:
+		return struct{}{}, nil
+	case MethodToolsList:
+		return s.listTools(), nil
+	default:
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// initialize negotiates the protocol version and records what the client
+// can do.
+func (s *Server) initialize(raw json.RawMessage) (interface{}, *RPCError) {
+	var params struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		Capabilities    struct {
+			Elicitation *struct{} `json:"elicitation"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: "invalid initialize params: " + err.Error()}
+	}
+	version := ProtocolVersions[0]
+	if slices.Contains(ProtocolVersions, params.ProtocolVersion) {
+		version = params.ProtocolVersion
+	}
+	s.mu.Lock()
+	s.canElicit = params.Capabilities.Elicitation != nil
+	s.mu.Unlock()
+
+	result := map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    map[string]interface{}{"tools": map[string]interface{}{"listChanged": false}},
+		"serverInfo":      map[string]interface{}{"name": s.name, "version": s.version},
+	}
+	if s.instructions != "" {
+		result["instructions"] = s.instructions
+	}
+	return result, nil
+}
+
+// listTools describes the served tools.
+func (s *Server) listTools() interface{} {
+	list := make([]map[string]interface{}, len(s.tools))
+	for i, tool := range s.tools {
+		list[i] = map[string]interface{}{
+			"name":        tool.Name(),
+			"description": tool.Description(),
+			"inputSchema": InputSchema(tool.Parameters()),
+		}
+	}
+	return map[string]interface{}{"tools": list}
+}
+
+// callTool runs a tool call. Failures of the tool itself, including
+// denied permission, are results with isError set so the client's model
+// can see them; only malformed calls are protocol errors.
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (interface{}, *RPCError) {
+	var params struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: "invalid tools/call params: " + err.Error()}
+	}
+	if !slices.ContainsFunc(s.tools, func(t tools.Tool) bool { return t.Name() == params.Name }) {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+	if params.Arguments == nil {
+		params.Arguments = make(map[string]interface{})
+	}
+
+	result, err := s.exec(ctx, params.Name, params.Arguments)
+	text, isError := resultText(params.Name, result, err)
+	return map[string]interface{}{
+		"content": []map[string]interface{}{{"type": "text", "text": text}},
+		"isError": isError,
+	}, nil
+}
+
+// resultText renders the outcome of a call for the client, and whether it
+// failed.
+func resultText(name string, result *tools.Result, err error) (string, bool) {
+	switch {
+	case err != nil:
+		return err.Error(), true
+	case result == nil:
+		return fmt.Sprintf("Tool %s returned no result", name), true
+	case !result.Success:
+		if result.Error != nil {
+			return result.Error.Error(), true
+		}
+		return fmt.Sprintf("Tool %s failed", name), true
+	}
+	switch output := result.Output.(type) {
+	case string:
+		return output, false
+	case nil:
+		return fmt.Sprintf("Tool %s completed successfully", name), false
+	default:
+		b, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Sprint(output), false
+		}
+		return string(b), false
+	}
+}
+
+// CanElicit reports whether the client accepts elicitation requests, so
+// the server can ask its user questions.
+func (s *Server) CanElicit() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.canElicit
+}
+
+// Confirm asks the client's user to accept or decline what message
+// describes, and reports whether they accepted.
+func (s *Server) Confirm(ctx context.Context, message string) (bool, error) {
+	if !s.CanElicit() {
+		return false, fmt.Errorf("client does not support elicitation")
+	}
+	params := map[string]interface{}{
+		"message":         message,
+		"requestedSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+	}
+	var result struct {
+		Action string `json:"action"`
+	}
+	if err := s.call(ctx, MethodElicit, params, &result); err != nil {
+		return false, err
+	}
+	return result.Action == "accept", nil
+}
+
+// call sends a request to the client and decodes its result into result.
+func (s *Server) call(ctx context.Context, method string, params, result interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s params: %w", method, err)
+	}
+
+	ch := make(chan message, 1)
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.pending[id] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	if err := s.send(message{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(id)), Method: method, Params: raw}); err != nil {
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+	case <-s.done:
+		return fmt.Errorf("client disconnected during %s", method)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver hands a response from the client to the request awaiting it.
+func (s *Server) deliver(msg message) {
+	var id int64
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
+	}
+	s.mu.Lock()
+	ch, ok := s.pending[id]
+	s.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+// reply sends the response to a request; a nil id is sent as null.
+func (s *Server) reply(id json.RawMessage, result interface{}, rpcErr *RPCError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := message{JSONRPC: "2.0", ID: id}
+	if rpcErr != nil {
+		resp.Error = rpcErr
+	} else {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &RPCError{Code: CodeInternalError, Message: "failed to encode result: " + err.Error()}
+		} else {
+			resp.Result = raw
+		}
+	}
+	_ = s.send(resp)
+}
+
+// send writes one message.
+func (s *Server) send(msg message) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.enc.Encode(msg); err != nil {
+		return fmt.Errorf("failed to write to client: %w", err)
+	}
+	return nil
+}
+
+// InputSchema builds the JSON schema of a tool's parameters.
+func InputSchema(params []tools.Parameter) map[string]interface{} {
+	properties := make(map[string]interface{}, len(params))
+	required := make([]string, 0, len(params))
+	for _, param := range params {
+		prop := map[string]interface{}{"description": param.Description}
+		if typ := schemaType(param.Type); typ != "" {
+			prop["type"] = typ
+		}
+		if param.Default != nil {
+			prop["default"] = param.Default
+		}
+		if param.Validation != nil && len(param.Validation.Enum) > 0 {
+			prop["enum"] = param.Validation.Enum
+		}
+		properties[param.Name] = prop
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schemaType maps a tool parameter type to a JSON schema type; "" means any.
+func schemaType(typ tools.ParameterType) string {
+	switch typ {
+	case tools.TypeInt:
+		return "integer"
+	case tools.TypeFloat:
+		return "number"
+	case tools.TypeBool:
+		return "boolean"
+	case tools.TypeString, tools.TypeArray, tools.TypeObject:
+		return string(typ)
+	default:
+		return ""
+	}
+}
+
+// message is any JSON-RPC message: a request or notification when Method
+// is set, otherwise a response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
@@ -0,0 +1,215 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoTool returns its text parameter.
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echoes text" }
+func (echoTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{Name: "text", Type: tools.TypeString, Required: true, Description: "Text to echo"},
+		{Name: "times", Type: tools.TypeInt, Description: "Repeats", Default: 1},
+	}
+}
+func (echoTool) Category() string         { return "test" }
+func (echoTool) Version() string          { return "1.0.0" }
+func (echoTool) IsExternal() bool         { return false }
+func (echoTool) RequiresPermission() bool { return false }
+func (echoTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	return &tools.Result{Success: true, Output: params["text"]}, nil
+}
+
+// client drives a server over pipes.
+type client struct {
+	t   *testing.T
+	in  *io.PipeWriter
+	out *bufio.Scanner
+}
+
+func startServer(t *testing.T, exec Executor) (*Server, *client) {
+	t.Helper()
+	if exec == nil {
+		exec = func(ctx context.Context, name string, args map[string]interface{}) (*tools.Result, error) {
+			return echoTool{}.Execute(ctx, args)
+		}
+	}
+	s := NewServer([]tools.Tool{echoTool{}}, exec, WithInfo("bplus", "1.2.3"))
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Error("server did not stop")
+		}
+	})
+	return s, &client{t: t, in: inW, out: bufio.NewScanner(outR)}
+}
+
+func (c *client) send(msg string) {
+	c.t.Helper()
+	_, err := fmt.Fprintln(c.in, msg)
+	require.NoError(c.t, err)
+}
+
+func (c *client) receive() message {
+	c.t.Helper()
+	require.True(c.t, c.out.Scan(), "no message from server")
+	var msg message
+	require.NoError(c.t, json.Unmarshal(c.out.Bytes(), &msg))
+	return msg
+}
+
+func (c *client) result(msg message) map[string]interface{} {
+	c.t.Helper()
+	require.Nil(c.t, msg.Error)
+	var result map[string]interface{}
+	require.NoError(c.t, json.Unmarshal(msg.Result, &result))
+	return result
+}
+
+func TestServer_Initialize(t *testing.T) {
+	_, c := startServer(t, nil)
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test"}}}`)
+	msg := c.receive()
+	assert.JSONEq(t, "1", string(msg.ID))
+	result := c.result(msg)
+	assert.Equal(t, "2025-03-26", result["protocolVersion"])
+	assert.Equal(t, map[string]interface{}{"name": "bplus", "version": "1.2.3"}, result["serverInfo"])
+
+	// Unknown versions get the latest
+	c.send(`{"jsonrpc":"2.0","id":"a","method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	assert.Equal(t, ProtocolVersions[0], c.result(c.receive())["protocolVersion"])
+
+	c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	c.send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	msg = c.receive()
+	assert.JSONEq(t, "2", string(msg.ID))
+	assert.Empty(t, c.result(msg))
+
+	c.send(`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`)
+	msg = c.receive()
+	require.NotNil(t, msg.Error)
+	assert.Equal(t, CodeMethodNotFound, msg.Error.Code)
+
+	c.send(`{not json`)
+	msg = c.receive()
+	require.NotNil(t, msg.Error)
+	assert.Equal(t, CodeParseError, msg.Error.Code)
+	assert.JSONEq(t, "null", string(msg.ID))
+}
+
+func TestServer_Tools(t *testing.T) {
+	_, c := startServer(t, func(ctx context.Context, name string, args map[string]interface{}) (*tools.Result, error) {
+		if args["text"] == "deny" {
+			return nil, fmt.Errorf("permission denied for tool %s", name)
+		}
+		if args["text"] == "" {
+			return &tools.Result{Success: false, Error: fmt.Errorf("text is required")}, nil
+		}
+		return echoTool{}.Execute(ctx, args)
+	})
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	list := c.result(c.receive())["tools"].([]interface{})
+	require.Len(t, list, 1)
+	tool := list[0].(map[string]interface{})
+	assert.Equal(t, "echo", tool["name"])
+	assert.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text":  map[string]interface{}{"type": "string", "description": "Text to echo"},
+			"times": map[string]interface{}{"type": "integer", "description": "Repeats", "default": float64(1)},
+		},
+		"required": []interface{}{"text"},
+	}, tool["inputSchema"])
+
+	for text, want := range map[string]map[string]interface{}{
+		"hello": {"text": "hello", "isError": false},
+		"":      {"text": "text is required", "isError": true},
+		"deny":  {"text": "permission denied for tool echo", "isError": true},
+	} {
+		c.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":%q}}}`, text))
+		result := c.result(c.receive())
+		content := result["content"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "text", content["type"])
+		assert.Equal(t, want["text"], content["text"], text)
+		assert.Equal(t, want["isError"], result["isError"], text)
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"bash","arguments":{}}}`)
+	msg := c.receive()
+	require.NotNil(t, msg.Error)
+	assert.Equal(t, CodeInvalidParams, msg.Error.Code)
+}
+
+func TestServer_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	_, c := startServer(t, func(ctx context.Context, name string, args map[string]interface{}) (*tools.Result, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	c.send(`{"jsonrpc":"2.0","id":"slow","method":"tools/call","params":{"name":"echo","arguments":{"text":"x"}}}`)
+	<-started
+	c.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow"}}`)
+	msg := c.receive()
+	assert.JSONEq(t, `"slow"`, string(msg.ID))
+	assert.Equal(t, true, c.result(msg)["isError"])
+}
+
+func TestServer_Confirm(t *testing.T) {
+	var s *Server
+	s, c := startServer(t, func(ctx context.Context, name string, args map[string]interface{}) (*tools.Result, error) {
+		ok, err := s.Confirm(ctx, "Allow echo?")
+		if err != nil || !ok {
+			return nil, fmt.Errorf("permission denied for tool %s", name)
+		}
+		return echoTool{}.Execute(ctx, args)
+	})
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"elicitation":{}}}}`)
+	c.receive()
+	assert.True(t, s.CanElicit())
+
+	for action, want := range map[string]bool{"accept": false, "decline": true} {
+		c.send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`)
+		req := c.receive()
+		require.Equal(t, MethodElicit, req.Method)
+		var params map[string]interface{}
+		require.NoError(t, json.Unmarshal(req.Params, &params))
+		assert.Equal(t, "Allow echo?", params["message"])
+		c.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"action":%q}}`, req.ID, action))
+		assert.Equal(t, want, c.result(c.receive())["isError"], action)
+	}
+}
+
+func TestServer_ConfirmWithoutElicitation(t *testing.T) {
+	s := NewServer(nil, nil)
+	assert.False(t, s.CanElicit())
+	_, err := s.Confirm(context.Background(), "Allow?")
+	assert.Error(t, err)
+}
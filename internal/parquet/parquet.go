@@ -0,0 +1,304 @@
+// Package parquet writes flat tables as Apache Parquet files: one row group,
+// required columns, PLAIN encoding and no compression. That is enough for
+// exports read by analysis tools such as DuckDB, pandas or Spark.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Type is a column type.
+type Type int
+
+// Column types
+const (
+	Int64  Type = iota // int or int64 values
+	Double             // float64 values
+	String             // string values, stored as UTF-8 byte arrays
+)
+
+// Column describes one column of a table.
+type Column struct {
+	Name string
+	Type Type
+}
+
+const magic = "PAR1"
+
+// Parquet physical and converted types, encodings and page types
+const (
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+	convertedUTF8     = 0
+	repetitionReq     = 0
+	encodingPlain     = 0
+	encodingRLE       = 3
+	codecUncompressed = 0
+	pageData          = 0
+)
+
+// Write writes rows as a Parquet file. Each row holds one value per column,
+// of the column's type.
+func Write(w io.Writer, columns []Column, rows [][]interface{}) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("parquet: no columns")
+	}
+
+	var file bytes.Buffer
+	file.WriteString(magic)
+
+	chunks := make([]chunk, len(columns))
+	for c, col := range columns {
+		data, err := encodeColumn(col, c, rows)
+		if err != nil {
+			return err
+		}
+
+		var header thrift
+		header.i32(1, pageData)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.beginStruct(5) // data_page_header
+		header.i32(1, int32(len(rows)))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		header.stop()
+
+		chunks[c] = chunk{
+			offset: int64(file.Len()),
+			size:   int64(header.buf.Len() + len(data)),
+		}
+		file.Write(header.buf.Bytes())
+		file.Write(data)
+	}
+
+	footer := fileMetaData(columns, chunks, int64(len(rows)))
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.WriteString(magic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// chunk locates one column chunk in the file.
+type chunk struct {
+	offset int64
+	size   int64
+}
+
+// encodeColumn PLAIN-encodes column c of rows.
+func encodeColumn(col Column, c int, rows [][]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for r, row := range rows {
+		if c >= len(row) {
+			return nil, fmt.Errorf("parquet: row %d has no value for column %s", r, col.Name)
+		}
+		switch col.Type {
+		case Int64:
+			var v int64
+			switch n := row[c].(type) {
+			case int:
+				v = int64(n)
+			case int64:
+				v = n
+			default:
+				return nil, fmt.Errorf("parquet: row %d column %s: want integer, got %T", r, col.Name, row[c])
+			}
+			binary.Write(&buf, binary.LittleEndian, v)
+		case Double:
+			v, ok := row[c].(float64)
+			if !ok {
+				return nil, fmt.Errorf("parquet: row %d column %s: want float64, got %T", r, col.Name, row[c])
+			}
+			binary.Write(&buf, binary.LittleEndian, math.Float64bits(v))
+		case String:
+			v, ok := row[c].(string)
+			if !ok {
+				return nil, fmt.Errorf("parquet: row %d column %s: want string, got %T", r, col.Name, row[c])
+			}
+			binary.Write(&buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+		default:
+			return nil, fmt.Errorf("parquet: column %s has unknown type %d", col.Name, col.Type)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// fileMetaData encodes the footer describing the schema and column chunks.
+func fileMetaData(columns []Column, chunks []chunk, numRows int64) []byte {
+	var t thrift
+	t.i32(1, 1) // version
+
+	// Schema: a root group followed by one leaf per column
+	t.beginList(2, len(columns)+1)
+	t.beginListStruct()
+	t.str(4, "schema")
+	t.i32(5, int32(len(columns)))
+	t.endStruct()
+	for _, col := range columns {
+		t.beginListStruct()
+		t.i32(1, physicalType(col.Type))
+		t.i32(3, repetitionReq)
+		t.str(4, col.Name)
+		if col.Type == String {
+			t.i32(6, convertedUTF8)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, numRows)
+
+	// One row group holding every column
+	var total int64
+	for _, ch := range chunks {
+		total += ch.size
+	}
+	t.beginList(4, 1)
+	t.beginListStruct()
+	t.beginList(1, len(columns))
+	for i, col := range columns {
+		t.beginListStruct()
+		t.i64(2, chunks[i].offset) // file_offset
+		t.beginStruct(3)           // meta_data
+		t.i32(1, physicalType(col.Type))
+		t.i32List(2, []int32{encodingPlain})
+		t.strList(3, []string{col.Name})
+		t.i32(4, codecUncompressed)
+		t.i64(5, numRows)
+		t.i64(6, chunks[i].size)
+		t.i64(7, chunks[i].size)
+		t.i64(9, chunks[i].offset)
+		t.endStruct()
+		t.endStruct()
+	}
+	t.i64(2, total)
+	t.i64(3, numRows)
+	t.endStruct()
+
+	t.str(6, "bplus")
+	t.stop()
+	return t.buf.Bytes()
+}
+
+func physicalType(t Type) int32 {
+	switch t {
+	case Int64:
+		return physicalInt64
+	case Double:
+		return physicalDouble
+	default:
+		return physicalByteArray
+	}
+}
+
+// Thrift compact protocol types
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// thrift writes Thrift compact protocol structs. Fields must be written in
+// increasing id order within each struct.
+type thrift struct {
+	buf   bytes.Buffer
+	last  int   // Last field id of the current struct
+	stack []int // Last field ids of enclosing structs
+}
+
+func (t *thrift) field(id int, kind byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta<<4) | kind)
+	} else {
+		t.buf.WriteByte(kind)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thrift) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thrift) i32(id int, v int32) {
+	t.field(id, compactI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thrift) i64(id int, v int64) {
+	t.field(id, compactI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thrift) str(id int, s string) {
+	t.field(id, compactBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thrift) listHeader(size int, elem byte) {
+	if size < 15 {
+		t.buf.WriteByte(byte(size<<4) | elem)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elem)
+	t.varint(uint64(size))
+}
+
+func (t *thrift) i32List(id int, values []int32) {
+	t.field(id, compactList)
+	t.listHeader(len(values), compactI32)
+	for _, v := range values {
+		t.varint(zigzag(int64(v)))
+	}
+}
+
+func (t *thrift) strList(id int, values []string) {
+	t.field(id, compactList)
+	t.listHeader(len(values), compactBinary)
+	for _, v := range values {
+		t.varint(uint64(len(v)))
+		t.buf.WriteString(v)
+	}
+}
+
+// beginList starts a list of size structs, each begun with beginListStruct.
+func (t *thrift) beginList(id, size int) {
+	t.field(id, compactList)
+	t.listHeader(size, compactStruct)
+}
+
+func (t *thrift) beginListStruct() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thrift) beginStruct(id int) {
+	t.field(id, compactStruct)
+	t.beginListStruct()
+}
+
+func (t *thrift) endStruct() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thrift) stop() {
+	t.buf.WriteByte(0)
+}
@@ -0,0 +1,134 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decoder reads Thrift compact protocol structs as maps of field id to value.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) byte() byte {
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	d.pos += n
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *decoder) value(kind byte) interface{} {
+	switch kind {
+	case compactI32, compactI64:
+		return d.varint()
+	case compactBinary:
+		n := int(d.uvarint())
+		s := string(d.data[d.pos : d.pos+n])
+		d.pos += n
+		return s
+	case compactList:
+		header := d.byte()
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(d.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = d.value(elem)
+		}
+		return list
+	case compactStruct:
+		fields := make(map[int]interface{})
+		last := 0
+		for {
+			header := d.byte()
+			if header == 0 {
+				return fields
+			}
+			kind := header & 0x0f
+			if delta := int(header >> 4); delta != 0 {
+				last += delta
+			} else {
+				last = int(d.varint())
+			}
+			fields[last] = d.value(kind)
+		}
+	}
+	panic("unsupported type")
+}
+
+func TestWrite(t *testing.T) {
+	columns := []Column{
+		{Name: "index", Type: Int64},
+		{Name: "role", Type: String},
+		{Name: "relevance", Type: Double},
+	}
+	rows := [][]interface{}{
+		{0, "system", 0.0},
+		{int64(1), "user", 0.75},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, columns, rows))
+	data := buf.Bytes()
+
+	assert.Equal(t, magic, string(data[:4]))
+	assert.Equal(t, magic, string(data[len(data)-4:]))
+
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &decoder{data: data[len(data)-8-size : len(data)-8]}
+	meta := footer.value(compactStruct).(map[int]interface{})
+
+	assert.Equal(t, int64(1), meta[1])
+	assert.Equal(t, int64(2), meta[3])
+
+	schema := meta[2].([]interface{})
+	require.Len(t, schema, 4)
+	assert.Equal(t, int64(3), schema[0].(map[int]interface{})[5])
+	for i, col := range columns {
+		assert.Equal(t, col.Name, schema[i+1].(map[int]interface{})[4])
+	}
+
+	group := meta[4].([]interface{})[0].(map[int]interface{})
+	chunks := group[1].([]interface{})
+	require.Len(t, chunks, 3)
+	assert.Equal(t, int64(2), group[3])
+
+	// Read each column's page back from its offset
+	values := make([][]byte, len(chunks))
+	for i, c := range chunks {
+		colMeta := c.(map[int]interface{})[3].(map[int]interface{})
+		assert.Equal(t, []interface{}{columns[i].Name}, colMeta[3])
+		page := &decoder{data: data, pos: int(colMeta[9].(int64))}
+		header := page.value(compactStruct).(map[int]interface{})
+		assert.Equal(t, int64(2), header[5].(map[int]interface{})[1])
+		values[i] = data[page.pos : page.pos+int(header[3].(int64))]
+	}
+
+	assert.Equal(t, uint64(1), binary.LittleEndian.Uint64(values[0][8:]))
+	assert.Equal(t, "\x06\x00\x00\x00system\x04\x00\x00\x00user", string(values[1]))
+	assert.Equal(t, 0.75, math.Float64frombits(binary.LittleEndian.Uint64(values[2][8:])))
+}
+
+func TestWrite_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, Write(&buf, nil, nil))
+	assert.Error(t, Write(&buf, []Column{{Name: "n", Type: Int64}}, [][]interface{}{{"one"}}))
+	assert.Error(t, Write(&buf, []Column{{Name: "a", Type: String}, {Name: "b", Type: String}}, [][]interface{}{{"x"}}))
+}
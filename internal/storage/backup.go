@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupPrefix     = "bplus-"
+	backupExt        = ".db"
+	backupTimeFormat = "20060102-150405"
+)
+
+// BackupFile is a backup of the database in a backup directory.
+type BackupFile struct {
+	Path string
+	Time time.Time
+	Size int64
+}
+
+// BackupName returns the file name of a backup taken at t. Names sort in
+// the order the backups were taken.
+func BackupName(t time.Time) string {
+	return backupPrefix + t.UTC().Format(backupTimeFormat) + backupExt
+}
+
+// ListBackups returns the backups in dir, newest first. Files not named by
+// BackupName are ignored, and a missing dir has no backups.
+func ListBackups(dir string) ([]BackupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []BackupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupExt) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupExt))
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupFile{Path: filepath.Join(dir, name), Time: t, Size: info.Size()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// RotateBackups removes the backups in dir beyond the newest keep, and
+// those taken more than maxAge before now. A zero keep or maxAge leaves
+// that limit off. The newest backup is never removed, so ageing out can't
+// leave none. It returns the paths removed.
+func RotateBackups(dir string, keep int, maxAge time.Duration, now time.Time) ([]string, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for i, b := range backups {
+		if i == 0 {
+			continue
+		}
+		if (keep > 0 && i >= keep) || (maxAge > 0 && now.Sub(b.Time) > maxAge) {
+			if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("failed to remove old backup: %w", err)
+			}
+			removed = append(removed, b.Path)
+		}
+	}
+	return removed, nil
+}
+
+// VerifyBackup checks that path holds an intact bplus database: SQLite's
+// integrity check passes and the session tables are there.
+func VerifyBackup(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("backup %s is a directory", path)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("backup %s is not a readable database: %w", path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup %s failed the integrity check: %s", path, result)
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('sessions', 'messages')`).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to read backup schema: %w", err)
+	}
+	if tables != 2 {
+		return fmt.Errorf("backup %s is not a bplus database", path)
+	}
+	return nil
+}
+
+// RestoreFile replaces the database file at dbPath with a copy of the
+// backup at sourcePath. The copy is written beside the database and renamed
+// over it, so an interrupted restore leaves the old database in place. The
+// database must not be open.
+func RestoreFile(sourcePath, dbPath string) error {
+	src, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+	partial := dbPath + ".restore"
+	dst, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	_, err = io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	// A write-ahead log left from the old database would be replayed over
+	// the restored one
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			_ = os.Remove(partial)
+			return fmt.Errorf("failed to remove %s: %w", dbPath+suffix, err)
+		}
+	}
+	if err := os.Rename(partial, dbPath); err != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	for _, age := range []time.Duration{0, time.Hour, 2 * time.Hour, 48 * time.Hour, 72 * time.Hour} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, BackupName(now.Add(-age))), []byte("db"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me"), 0644))
+
+	backups, err := ListBackups(dir)
+	require.NoError(t, err)
+	require.Len(t, backups, 5)
+	assert.Equal(t, now, backups[0].Time, "newest first")
+
+	removed, err := RotateBackups(dir, 4, 60*time.Hour, now)
+	require.NoError(t, err)
+	assert.Len(t, removed, 1, "the 72h old backup is both beyond keep and too old")
+
+	removed, err = RotateBackups(dir, 2, 0, now)
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+
+	backups, err = ListBackups(dir)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, now.Add(-time.Hour), backups[1].Time)
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+
+	// The newest backup stays however old it is
+	removed, err = RotateBackups(dir, 0, time.Minute, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, removed, 1)
+	backups, err = ListBackups(dir)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, now, backups[0].Time)
+
+	backups, err = ListBackups(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestVerifyBackupAndRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	backupPath := filepath.Join(tmpDir, "backups", BackupName(time.Now()))
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.CreateSession("kept", "Kept"))
+	require.NoError(t, db.Backup(backupPath))
+	assert.NoFileExists(t, backupPath+".partial")
+	require.NoError(t, VerifyBackup(backupPath))
+	require.NoError(t, db.CreateSession("lost", "Lost"))
+
+	// Neither garbage nor a foreign database passes
+	garbage := filepath.Join(tmpDir, "garbage.db")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a database at all, just some text"), 0644))
+	assert.Error(t, VerifyBackup(garbage))
+	assert.Error(t, db.Restore(garbage))
+	assert.Error(t, VerifyBackup(filepath.Join(tmpDir, "missing.db")))
+
+	truncated := filepath.Join(tmpDir, "truncated.db")
+	data, err := os.ReadFile(backupPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(truncated, data[:len(data)/2], 0644))
+	assert.Error(t, VerifyBackup(truncated))
+
+	require.NoError(t, db.Restore(backupPath))
+	_, err = db.GetSession("kept")
+	assert.NoError(t, err)
+	_, err = db.GetSession("lost")
+	assert.Error(t, err, "sessions created after the backup are gone")
+	require.NoError(t, db.CreateSession("after", "After"))
+}
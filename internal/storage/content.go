@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings stored in messages.content_encoding
+const (
+	encodingPlain = ""
+	encodingZstd  = "zstd"
+)
+
+// ContentLimits controls how oversized message content is stored. Content
+// above CompressThreshold is zstd-compressed and stored out of line in
+// chunks; only a preview stays in the messages table (and the FTS index).
+type ContentLimits struct {
+	CompressThreshold int // Bytes above which content is compressed (0 disables)
+	ChunkSize         int // Maximum bytes per stored compressed chunk
+	PreviewSize       int // Bytes of compressed content kept inline for search
+	MaxSize           int // Bytes above which content is truncated (0 = unlimited)
+}
+
+// DefaultContentLimits returns the default content limits.
+func DefaultContentLimits() ContentLimits {
+	return ContentLimits{
+		CompressThreshold: 64 * 1024,
+		ChunkSize:         512 * 1024,
+		PreviewSize:       4 * 1024,
+		MaxSize:           32 * 1024 * 1024,
+	}
+}
+
+// SetContentLimits changes the content limits for subsequently added messages.
+// Existing messages are read back regardless of the limits they were written with.
+func (s *SQLiteDB) SetContentLimits(limits ContentLimits) {
+	s.limits = limits
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// zstdCodec returns the shared zstd encoder and decoder.
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// encodedContent is message content prepared for insertion.
+type encodedContent struct {
+	inline   string   // Value stored in messages.content
+	encoding string   // Value stored in messages.content_encoding
+	size     int      // Original (possibly truncated) content size in bytes
+	chunks   [][]byte // Compressed chunks stored in message_chunks
+}
+
+// encodeContent applies the size limit and, above the threshold, compresses
+// the content into chunks.
+func (s *SQLiteDB) encodeContent(content string) (*encodedContent, error) {
+	limits := s.limits
+
+	if limits.MaxSize > 0 && len(content) > limits.MaxSize {
+		dropped := len(content) - limits.MaxSize
+		content = truncateUTF8(content, limits.MaxSize) +
+			fmt.Sprintf("\n[truncated %d bytes]", dropped)
+	}
+
+	enc := &encodedContent{inline: content, encoding: encodingPlain, size: len(content)}
+	if limits.CompressThreshold <= 0 || len(content) <= limits.CompressThreshold {
+		return enc, nil
+	}
+
+	encoder, _, err := zstdCodec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize zstd: %w", err)
+	}
+	compressed := encoder.EncodeAll([]byte(content), nil)
+
+	chunkSize := limits.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = len(compressed)
+	}
+	for start := 0; start < len(compressed); start += chunkSize {
+		end := start + chunkSize
+		if end > len(compressed) {
+			end = len(compressed)
+		}
+		enc.chunks = append(enc.chunks, compressed[start:end])
+	}
+
+	enc.inline = truncateUTF8(content, limits.PreviewSize)
+	enc.encoding = encodingZstd
+	return enc, nil
+}
+
+// insertChunks stores compressed chunks for a message.
+func insertChunks(tx *sql.Tx, messageID int64, chunks [][]byte) error {
+	for i, chunk := range chunks {
+		if _, err := tx.Exec(
+			"INSERT INTO message_chunks (message_id, seq, data) VALUES (?, ?, ?)",
+			messageID, i, chunk,
+		); err != nil {
+			return fmt.Errorf("failed to store content chunk: %w", err)
+		}
+	}
+	return nil
+}
+
+// expandContent replaces a message's inline preview with its full content.
+// It must not be called while another query's rows are open, since the
+// database uses a single connection.
+func (s *SQLiteDB) expandContent(msg *Message) error {
+	if msg.encoding != encodingZstd {
+		return nil
+	}
+
+	rows, err := s.db.Query(
+		"SELECT data FROM message_chunks WHERE message_id = ? ORDER BY seq ASC",
+		msg.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load content chunks: %w", err)
+	}
+	var compressed []byte
+	for rows.Next() {
+		var chunk []byte
+		if err := rows.Scan(&chunk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan content chunk: %w", err)
+		}
+		compressed = append(compressed, chunk...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load content chunks: %w", err)
+	}
+
+	_, decoder, err := zstdCodec()
+	if err != nil {
+		return fmt.Errorf("failed to initialize zstd: %w", err)
+	}
+	content, err := decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decompress message %d: %w", msg.ID, err)
+	}
+
+	msg.Content = string(content)
+	msg.encoding = encodingPlain
+	return nil
+}
+
+// expandAll expands every compressed message in the slice.
+func (s *SQLiteDB) expandAll(messages []*Message) error {
+	for _, msg := range messages {
+		if err := s.expandContent(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
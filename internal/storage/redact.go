@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+)
+
+// GetMessage retrieves a message by ID.
+func (s *SQLiteDB) GetMessage(id int64) (*Message, error) {
+	messages, err := s.queryMessages("SELECT "+messageColumns+" FROM messages WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("message %d not found", id)
+	}
+	return messages[0], nil
+}
+
+// CountMatchingMessages counts the stored messages whose content matches re,
+// in every session.
+func (s *SQLiteDB) CountMatchingMessages(re *regexp.Regexp) (int, error) {
+	messages, err := s.queryMessages("SELECT " + messageColumns + " FROM messages")
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, msg := range messages {
+		if re.MatchString(msg.Content) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RedactMessages replaces every match of re in stored messages and session
+// context snapshots, in every session, with placeholder. The full-text index
+// is rebuilt so no trace of the matches stays searchable. It returns the
+// number of messages changed.
+func (s *SQLiteDB) RedactMessages(re *regexp.Regexp, placeholder string) (int, error) {
+	messages, err := s.queryMessages("SELECT " + messageColumns + " FROM messages")
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	n := 0
+	for _, msg := range messages {
+		if !re.MatchString(msg.Content) {
+			continue
+		}
+		if err := s.replaceContent(tx, msg.ID, re.ReplaceAllLiteralString(msg.Content, placeholder)); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err := redactSnapshots(tx, "", re, placeholder); err != nil {
+		return 0, err
+	}
+	if err := rebuildSearchIndex(tx); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit redaction: %w", err)
+	}
+	return n, nil
+}
+
+// RedactMessage replaces the whole content of message id with placeholder,
+// also where it appears in the context snapshot of the message's session,
+// and returns the message as it was.
+func (s *SQLiteDB) RedactMessage(id int64, placeholder string) (*Message, error) {
+	msg, err := s.GetMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.replaceContent(tx, id, placeholder); err != nil {
+		return nil, err
+	}
+	if msg.Content != "" {
+		re := regexp.MustCompile(regexp.QuoteMeta(msg.Content))
+		if err := redactSnapshots(tx, msg.SessionID, re, placeholder); err != nil {
+			return nil, err
+		}
+	}
+	if err := rebuildSearchIndex(tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit redaction: %w", err)
+	}
+	return msg, nil
+}
+
+// replaceContent stores new content for a message, re-encoding it and
+// dropping the chunks of the old content.
+func (s *SQLiteDB) replaceContent(tx *sql.Tx, id int64, content string) error {
+	enc, err := s.encodeContent(content)
+	if err != nil {
+		return fmt.Errorf("failed to redact message %d: %w", id, err)
+	}
+	if _, err := tx.Exec("DELETE FROM message_chunks WHERE message_id = ?", id); err != nil {
+		return fmt.Errorf("failed to redact message %d: %w", id, err)
+	}
+	if _, err := tx.Exec(
+		"UPDATE messages SET content = ?, content_encoding = ?, content_size = ? WHERE id = ?",
+		enc.inline, enc.encoding, enc.size, id,
+	); err != nil {
+		return fmt.Errorf("failed to redact message %d: %w", id, err)
+	}
+	return insertChunks(tx, id, enc.chunks)
+}
+
+// redactSnapshots replaces matches of re in the saved context of session,
+// or of every session if it is empty.
+func redactSnapshots(tx *sql.Tx, session string, re *regexp.Regexp, placeholder string) error {
+	query, args := "SELECT id, context_snapshot FROM sessions WHERE context_snapshot IS NOT NULL", []interface{}{}
+	if session != "" {
+		query, args = query+" AND id = ?", append(args, session)
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read session snapshots: %w", err)
+	}
+	redacted := make(map[string]string)
+	for rows.Next() {
+		var id, snapshot string
+		if err := rows.Scan(&id, &snapshot); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan session snapshot: %w", err)
+		}
+		if re.MatchString(snapshot) {
+			redacted[id] = re.ReplaceAllLiteralString(snapshot, placeholder)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read session snapshots: %w", err)
+	}
+
+	for id, snapshot := range redacted {
+		if _, err := tx.Exec("UPDATE sessions SET context_snapshot = ? WHERE id = ?", snapshot, id); err != nil {
+			return fmt.Errorf("failed to redact session %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// rebuildSearchIndex rebuilds the full-text index from the messages table.
+// Updates to the index remove old rows by reading the content table, which
+// already holds the new content, so terms of replaced content could linger
+// otherwise.
+func rebuildSearchIndex(tx *sql.Tx) error {
+	if _, err := tx.Exec("INSERT INTO messages_fts(messages_fts) VALUES('rebuild')"); err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+	return nil
+}
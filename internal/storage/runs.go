@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// maxCommandRuns is how many non-favorite runs are kept per project.
+const maxCommandRuns = 1000
+
+// Command run operations
+
+// RecordCommandRun stores a command run, dropping the project's oldest
+// non-favorite runs beyond the history limit
+func (s *SQLiteDB) RecordCommandRun(run *CommandRun) error {
+	timestamp := run.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	result, err := s.db.Exec(
+		"INSERT INTO command_runs (project, session_id, command, working_dir, shell, exit_code, duration_ms, favorite, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		run.Project, run.SessionID, run.Command, run.WorkingDir, run.Shell, run.ExitCode, run.Duration.Milliseconds(), run.Favorite, timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record command run: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get command run ID: %w", err)
+	}
+	run.ID = id
+	run.Timestamp = timestamp
+
+	if _, err := s.db.Exec(
+		`DELETE FROM command_runs WHERE project = ? AND favorite = 0 AND id NOT IN (
+			SELECT id FROM command_runs WHERE project = ? AND favorite = 0 ORDER BY id DESC LIMIT ?
+		)`,
+		run.Project, run.Project, maxCommandRuns,
+	); err != nil {
+		return fmt.Errorf("failed to prune command runs: %w", err)
+	}
+
+	return nil
+}
+
+// GetCommandRun retrieves a command run by ID
+func (s *SQLiteDB) GetCommandRun(id int64) (*CommandRun, error) {
+	rows, err := s.db.Query(
+		"SELECT id, project, session_id, command, working_dir, shell, exit_code, duration_ms, favorite, timestamp FROM command_runs WHERE id = ?",
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get command run: %w", err)
+	}
+	runs, err := scanCommandRuns(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("command run not found: #%d", id)
+	}
+	return runs[0], nil
+}
+
+// ListCommandRuns retrieves a project's command runs, favorites first and
+// then newest first
+func (s *SQLiteDB) ListCommandRuns(project string, limit int) ([]*CommandRun, error) {
+	rows, err := s.db.Query(
+		"SELECT id, project, session_id, command, working_dir, shell, exit_code, duration_ms, favorite, timestamp FROM command_runs WHERE project = ? ORDER BY favorite DESC, id DESC LIMIT ?",
+		project, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list command runs: %w", err)
+	}
+	return scanCommandRuns(rows)
+}
+
+// SetCommandRunFavorite marks or unmarks a command run as a favorite
+func (s *SQLiteDB) SetCommandRunFavorite(id int64, favorite bool) error {
+	result, err := s.db.Exec("UPDATE command_runs SET favorite = ? WHERE id = ?", favorite, id)
+	if err != nil {
+		return fmt.Errorf("failed to update command run: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("command run not found: #%d", id)
+	}
+	return nil
+}
+
+// scanCommandRuns reads command runs and closes rows
+func scanCommandRuns(rows *sql.Rows) ([]*CommandRun, error) {
+	defer rows.Close()
+
+	var runs []*CommandRun
+	for rows.Next() {
+		var (
+			run        CommandRun
+			workingDir sql.NullString
+			shell      sql.NullString
+			durationMs int64
+		)
+		if err := rows.Scan(&run.ID, &run.Project, &run.SessionID, &run.Command, &workingDir, &shell,
+			&run.ExitCode, &durationMs, &run.Favorite, &run.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan command run: %w", err)
+		}
+		run.WorkingDir = workingDir.String
+		run.Shell = shell.String
+		run.Duration = time.Duration(durationMs) * time.Millisecond
+		runs = append(runs, &run)
+	}
+
+	return runs, rows.Err()
+}
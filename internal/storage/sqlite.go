@@ -5,15 +5,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/abrksh22/bplus/internal/assets"
 	_ "modernc.org/sqlite" // SQLite driver
 )
 
 // SQLiteDB wraps a SQLite database connection
 type SQLiteDB struct {
-	db   *sql.DB
-	path string
+	db     *sql.DB
+	path   string
+	limits ContentLimits
 }
 
 // NewSQLiteDB creates a new SQLite database connection
@@ -24,7 +27,29 @@ func NewSQLiteDB(path string) (*SQLiteDB, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Open database
+	db, err := openDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlite := &SQLiteDB{
+		db:     db,
+		path:   path,
+		limits: DefaultContentLimits(),
+	}
+
+	// Initialize schema
+	if err := sqlite.initSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return sqlite, nil
+}
+
+// openDB opens the database at path with the connection settings bplus
+// relies on.
+func openDB(path string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -47,136 +72,74 @@ func NewSQLiteDB(path string) (*SQLiteDB, error) {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
-	sqlite := &SQLiteDB{
-		db:   db,
-		path: path,
-	}
+	return db, nil
+}
 
-	// Initialize schema
-	if err := sqlite.initSchema(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+// migrationSetup holds the steps a migration needs run before its SQL,
+// by schema version.
+var migrationSetup = map[int]func(*SQLiteDB) error{
+	2: (*SQLiteDB).addContentColumns,
+}
+
+// initSchema creates all required tables by applying the built-in
+// migrations in order. Each is idempotent, so opening an up-to-date
+// database applies them again harmlessly.
+func (s *SQLiteDB) initSchema() error {
+	migrations, err := assets.LoadMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
 	}
 
-	return sqlite, nil
+	for _, m := range migrations {
+		if setup, ok := migrationSetup[m.Version]; ok {
+			if err := setup(s); err != nil {
+				return err
+			}
+		}
+		if _, err := s.db.Exec(m.SQL); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.Name, err)
+		}
+		if err := s.updateSchemaVersion(m.Version); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// initSchema creates all required tables
-func (s *SQLiteDB) initSchema() error {
-	schema := `
-	-- Schema version tracking
-	CREATE TABLE IF NOT EXISTS schema_version (
-		version INTEGER PRIMARY KEY,
-		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Sessions table
-	CREATE TABLE IF NOT EXISTS sessions (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		context_snapshot TEXT,
-		metadata TEXT -- JSON
-	);
-
-	-- Messages table
-	CREATE TABLE IF NOT EXISTS messages (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT NOT NULL,
-		role TEXT NOT NULL, -- 'user', 'assistant', 'system', 'tool'
-		content TEXT NOT NULL,
-		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		tokens_input INTEGER DEFAULT 0,
-		tokens_output INTEGER DEFAULT 0,
-		cost REAL DEFAULT 0.0,
-		metadata TEXT, -- JSON
-		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
-	);
-
-	-- Files table (tracks files in session context)
-	CREATE TABLE IF NOT EXISTS files (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT NOT NULL,
-		path TEXT NOT NULL,
-		content_hash TEXT,
-		modified_at TIMESTAMP,
-		size_bytes INTEGER,
-		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
-		UNIQUE(session_id, path)
-	);
-
-	-- Checkpoints table
-	CREATE TABLE IF NOT EXISTS checkpoints (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT NOT NULL,
-		name TEXT,
-		state_snapshot TEXT NOT NULL, -- JSON
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
-	);
-
-	-- Operations table (for undo/redo)
-	CREATE TABLE IF NOT EXISTS operations (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT NOT NULL,
-		type TEXT NOT NULL, -- 'file_write', 'file_delete', 'command', etc.
-		details TEXT, -- JSON
-		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		reversible BOOLEAN DEFAULT 1,
-		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
-	);
-
-	-- Metrics table
-	CREATE TABLE IF NOT EXISTS metrics (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT,
-		metric_type TEXT NOT NULL, -- 'cost', 'tokens', 'duration', etc.
-		metric_name TEXT,
-		value REAL NOT NULL,
-		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		metadata TEXT -- JSON
-	);
-
-	-- Create indexes for common queries
-	CREATE INDEX IF NOT EXISTS idx_messages_session ON messages(session_id, timestamp);
-	CREATE INDEX IF NOT EXISTS idx_files_session ON files(session_id);
-	CREATE INDEX IF NOT EXISTS idx_operations_session ON operations(session_id, timestamp);
-	CREATE INDEX IF NOT EXISTS idx_metrics_session ON metrics(session_id, timestamp);
-	CREATE INDEX IF NOT EXISTS idx_metrics_type ON metrics(metric_type, timestamp);
-
-	-- Create FTS5 virtual table for full-text search
-	CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
-		session_id UNINDEXED,
-		role UNINDEXED,
-		content,
-		content=messages,
-		content_rowid=id
-	);
-
-	-- Triggers to keep FTS table in sync
-	CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
-		INSERT INTO messages_fts(rowid, session_id, role, content)
-		VALUES (new.id, new.session_id, new.role, new.content);
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
-		DELETE FROM messages_fts WHERE rowid = old.id;
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE ON messages BEGIN
-		DELETE FROM messages_fts WHERE rowid = old.id;
-		INSERT INTO messages_fts(rowid, session_id, role, content)
-		VALUES (new.id, new.session_id, new.role, new.content);
-	END;
-	`
-
-	if _, err := s.db.Exec(schema); err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
-	}
-
-	// Check and update schema version
-	return s.updateSchemaVersion(1)
+// addContentColumns adds the columns recording how message content is
+// stored (schema v2), unless the table already has them.
+func (s *SQLiteDB) addContentColumns() error {
+	hasColumn := false
+	rows, err := s.db.Query("PRAGMA table_info(messages)")
+	if err != nil {
+		return fmt.Errorf("failed to inspect messages table: %w", err)
+	}
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     *string
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect messages table: %w", err)
+		}
+		if name == "content_encoding" {
+			hasColumn = true
+		}
+	}
+	rows.Close()
+
+	if hasColumn {
+		return nil
+	}
+	if _, err := s.db.Exec(`
+		ALTER TABLE messages ADD COLUMN content_encoding TEXT DEFAULT '';
+		ALTER TABLE messages ADD COLUMN content_size INTEGER DEFAULT 0;
+	`); err != nil {
+		return fmt.Errorf("failed to add content columns: %w", err)
+	}
+	return nil
 }
 
 // updateSchemaVersion records the schema version
@@ -266,11 +229,23 @@ func (s *SQLiteDB) ListSessions() ([]*Session, error) {
 
 // Message operations
 
-// AddMessage adds a message to a session
+// AddMessage adds a message to a session. Content over the configured
+// ContentLimits is truncated and/or compressed transparently.
 func (s *SQLiteDB) AddMessage(msg *Message) error {
-	result, err := s.db.Exec(
-		"INSERT INTO messages (session_id, role, content, tokens_input, tokens_output, cost, metadata) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		msg.SessionID, msg.Role, msg.Content, msg.TokensInput, msg.TokensOutput, msg.Cost, msg.Metadata,
+	enc, err := s.encodeContent(msg.Content)
+	if err != nil {
+		return fmt.Errorf("failed to add message: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO messages (session_id, role, content, tokens_input, tokens_output, cost, metadata, content_encoding, content_size) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		msg.SessionID, msg.Role, enc.inline, msg.TokensInput, msg.TokensOutput, msg.Cost, msg.Metadata, enc.encoding, enc.size,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to add message: %w", err)
@@ -280,41 +255,81 @@ func (s *SQLiteDB) AddMessage(msg *Message) error {
 	if err != nil {
 		return fmt.Errorf("failed to get message ID: %w", err)
 	}
-	msg.ID = id
 
+	if err := insertChunks(tx, id, enc.chunks); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit message: %w", err)
+	}
+
+	msg.ID = id
 	return nil
 }
 
 // GetMessages retrieves all messages for a session
 func (s *SQLiteDB) GetMessages(sessionID string, limit int) ([]*Message, error) {
-	query := "SELECT id, session_id, role, content, timestamp, tokens_input, tokens_output, cost, metadata FROM messages WHERE session_id = ? ORDER BY timestamp DESC"
+	query := "SELECT " + messageColumns + " FROM messages WHERE session_id = ? ORDER BY timestamp DESC"
 	if limit > 0 {
 		query += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	rows, err := s.db.Query(query, sessionID)
+	return s.queryMessages(query, sessionID)
+}
+
+// GetSessionMessages retrieves all messages for a session in insertion order
+func (s *SQLiteDB) GetSessionMessages(sessionID string) ([]*Message, error) {
+	return s.queryMessages("SELECT "+messageColumns+" FROM messages WHERE session_id = ? ORDER BY id ASC", sessionID)
+}
+
+// messageColumns are the columns scanned by scanMessage
+const messageColumns = "id, session_id, role, content, timestamp, tokens_input, tokens_output, cost, metadata, content_encoding"
+
+// queryMessages runs a message query and expands compressed content
+func (s *SQLiteDB) queryMessages(query string, args ...interface{}) ([]*Message, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages: %w", err)
 	}
-	defer rows.Close()
 
 	var messages []*Message
 	for rows.Next() {
-		var msg Message
-		if err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content,
-			&msg.Timestamp, &msg.TokensInput, &msg.TokensOutput, &msg.Cost, &msg.Metadata); err != nil {
-			return nil, fmt.Errorf("failed to scan message: %w", err)
+		msg, err := scanMessage(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
 		}
-		messages = append(messages, &msg)
+		messages = append(messages, msg)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	// Rows must be closed first: chunk loading needs the single connection
+	if err := s.expandAll(messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
 
-	return messages, rows.Err()
+// scanMessage scans a row selected with messageColumns
+func scanMessage(rows *sql.Rows) (*Message, error) {
+	var msg Message
+	var encoding sql.NullString
+	if err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content,
+		&msg.Timestamp, &msg.TokensInput, &msg.TokensOutput, &msg.Cost, &msg.Metadata, &encoding); err != nil {
+		return nil, fmt.Errorf("failed to scan message: %w", err)
+	}
+	msg.encoding = encoding.String
+	return &msg, nil
 }
 
 // SearchMessages performs full-text search on messages
 func (s *SQLiteDB) SearchMessages(query string) ([]*Message, error) {
-	rows, err := s.db.Query(`
-		SELECT m.id, m.session_id, m.role, m.content, m.timestamp, m.tokens_input, m.tokens_output, m.cost, m.metadata
+	// Only the inline preview of compressed messages is indexed
+	messages, err := s.queryMessages(`
+		SELECT m.id, m.session_id, m.role, m.content, m.timestamp, m.tokens_input, m.tokens_output, m.cost, m.metadata, m.content_encoding
 		FROM messages m
 		JOIN messages_fts fts ON m.id = fts.rowid
 		WHERE messages_fts MATCH ?
@@ -324,19 +339,50 @@ func (s *SQLiteDB) SearchMessages(query string) ([]*Message, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to search messages: %w", err)
 	}
+	return messages, nil
+}
+
+// File operations
+
+// AddFile records a file referenced by a session. Re-adding a path updates its metadata.
+func (s *SQLiteDB) AddFile(file *File) error {
+	modifiedAt := file.ModifiedAt
+	if modifiedAt.IsZero() {
+		modifiedAt = time.Now()
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO files (session_id, path, content_hash, modified_at, size_bytes) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(session_id, path) DO UPDATE SET content_hash = excluded.content_hash,
+			modified_at = excluded.modified_at, size_bytes = excluded.size_bytes`,
+		file.SessionID, file.Path, file.ContentHash, modifiedAt, file.SizeBytes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add file: %w", err)
+	}
+	return nil
+}
+
+// GetFiles retrieves all files recorded for a session
+func (s *SQLiteDB) GetFiles(sessionID string) ([]*File, error) {
+	rows, err := s.db.Query(
+		"SELECT id, session_id, path, content_hash, modified_at, size_bytes FROM files WHERE session_id = ? ORDER BY path",
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get files: %w", err)
+	}
 	defer rows.Close()
 
-	var messages []*Message
+	var files []*File
 	for rows.Next() {
-		var msg Message
-		if err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content,
-			&msg.Timestamp, &msg.TokensInput, &msg.TokensOutput, &msg.Cost, &msg.Metadata); err != nil {
-			return nil, fmt.Errorf("failed to scan message: %w", err)
+		var f File
+		if err := rows.Scan(&f.ID, &f.SessionID, &f.Path, &f.ContentHash, &f.ModifiedAt, &f.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
 		}
-		messages = append(messages, &msg)
+		files = append(files, &f)
 	}
 
-	return messages, rows.Err()
+	return files, rows.Err()
 }
 
 // Checkpoint operations
@@ -383,6 +429,55 @@ func (s *SQLiteDB) GetCheckpoints(sessionID string) ([]*Checkpoint, error) {
 	return checkpoints, rows.Err()
 }
 
+// Metric operations
+
+// RecordMetric stores a metric sample
+func (s *SQLiteDB) RecordMetric(metric *Metric) error {
+	timestamp := metric.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	result, err := s.db.Exec(
+		"INSERT INTO metrics (session_id, metric_type, metric_name, value, timestamp, metadata) VALUES (?, ?, ?, ?, ?, ?)",
+		metric.SessionID, metric.MetricType, metric.MetricName, metric.Value, timestamp, metric.Metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record metric: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get metric ID: %w", err)
+	}
+	metric.ID = id
+	metric.Timestamp = timestamp
+
+	return nil
+}
+
+// GetRecentMetrics retrieves the most recent metrics of a type, newest first
+func (s *SQLiteDB) GetRecentMetrics(metricType string, limit int) ([]*Metric, error) {
+	rows, err := s.db.Query(
+		"SELECT id, session_id, metric_type, metric_name, value, timestamp, metadata FROM metrics WHERE metric_type = ? ORDER BY timestamp DESC, id DESC LIMIT ?",
+		metricType, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var metrics []*Metric
+	for rows.Next() {
+		var m Metric
+		if err := rows.Scan(&m.ID, &m.SessionID, &m.MetricType, &m.MetricName, &m.Value, &m.Timestamp, &m.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to scan metric: %w", err)
+		}
+		metrics = append(metrics, &m)
+	}
+
+	return metrics, rows.Err()
+}
+
 // Close closes the database connection
 func (s *SQLiteDB) Close() error {
 	if s.db != nil {
@@ -391,6 +486,18 @@ func (s *SQLiteDB) Close() error {
 	return nil
 }
 
+// Flush writes the write-ahead log into the database file and releases the
+// memory SQLite caches pages in, for a session that will be idle a while.
+func (s *SQLiteDB) Flush() error {
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	if _, err := s.db.Exec("PRAGMA shrink_memory"); err != nil {
+		return fmt.Errorf("failed to release cache: %w", err)
+	}
+	return nil
+}
+
 // DB returns the underlying sql.DB for advanced queries
 func (s *SQLiteDB) DB() *sql.DB {
 	return s.db
@@ -404,34 +511,48 @@ func (s *SQLiteDB) Backup(destPath string) error {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	// Use VACUUM INTO for backup (SQLite 3.27.0+)
-	_, err := s.db.Exec(fmt.Sprintf("VACUUM INTO '%s'", destPath))
+	// VACUUM INTO (SQLite 3.27.0+) runs on a connection of its own, reading a
+	// snapshot, so the session isn't held up while a large database is
+	// copied. The copy is written beside the destination and renamed into
+	// place, so a backup is never seen half-written.
+	conn, err := sql.Open("sqlite", s.path)
 	if err != nil {
+		return fmt.Errorf("failed to open database for backup: %w", err)
+	}
+	defer conn.Close()
+
+	partial := destPath + ".partial"
+	_ = os.Remove(partial)
+	if _, err := conn.Exec(fmt.Sprintf("VACUUM INTO '%s'", strings.ReplaceAll(partial, "'", "''"))); err != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("failed to backup database: %w", err)
+	}
+	if err := os.Rename(partial, destPath); err != nil {
+		_ = os.Remove(partial)
 		return fmt.Errorf("failed to backup database: %w", err)
 	}
 
 	return nil
 }
 
-// Restore restores the database from a backup
+// Restore replaces the database with a backup, once the backup passes
+// VerifyBackup
 func (s *SQLiteDB) Restore(sourcePath string) error {
+	if err := VerifyBackup(sourcePath); err != nil {
+		return err
+	}
+
 	// Close current connection
 	if err := s.Close(); err != nil {
 		return fmt.Errorf("failed to close database: %w", err)
 	}
 
-	// Copy backup to current database path
-	sourceData, err := os.ReadFile(sourcePath)
-	if err != nil {
-		return fmt.Errorf("failed to read backup: %w", err)
-	}
-
-	if err := os.WriteFile(s.path, sourceData, 0644); err != nil {
-		return fmt.Errorf("failed to restore database: %w", err)
+	if err := RestoreFile(sourcePath, s.path); err != nil {
+		return err
 	}
 
 	// Reopen database
-	db, err := sql.Open("sqlite", s.path)
+	db, err := openDB(s.path)
 	if err != nil {
 		return fmt.Errorf("failed to reopen database: %w", err)
 	}
@@ -1,8 +1,13 @@
 package storage
 
 import (
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -205,6 +210,279 @@ func TestSQLiteDB_CheckpointOperations(t *testing.T) {
 	})
 }
 
+func TestSQLiteDB_FileOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.CreateSession("file-session", "File Session"))
+
+	require.NoError(t, db.AddFile(&File{SessionID: "file-session", Path: "main.go", SizeBytes: 10}))
+	require.NoError(t, db.AddFile(&File{SessionID: "file-session", Path: "app/app.go"}))
+	// Re-adding a path updates it instead of failing on the unique constraint
+	require.NoError(t, db.AddFile(&File{SessionID: "file-session", Path: "main.go", SizeBytes: 20}))
+
+	files, err := db.GetFiles("file-session")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "app/app.go", files[0].Path)
+	assert.Equal(t, "main.go", files[1].Path)
+	assert.Equal(t, int64(20), files[1].SizeBytes)
+}
+
+func TestSQLiteDB_MetricOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	name := "ollama/llama3"
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, db.RecordMetric(&Metric{MetricType: "ttft_ms", MetricName: &name, Value: float64(i * 100)}))
+	}
+	require.NoError(t, db.RecordMetric(&Metric{MetricType: "cost", Value: 0.5}))
+
+	metrics, err := db.GetRecentMetrics("ttft_ms", 2)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, 300.0, metrics[0].Value, "newest first")
+	assert.Equal(t, name, *metrics[0].MetricName)
+	assert.Nil(t, metrics[0].SessionID)
+}
+
+func TestSQLiteDB_CommandRunOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	build := &CommandRun{Project: "/src/app", Command: "go build ./...", ExitCode: 0, Duration: 1500 * time.Millisecond}
+	test := &CommandRun{Project: "/src/app", Command: "go test ./...", ExitCode: 1, Shell: "bash"}
+	other := &CommandRun{Project: "/src/other", Command: "make"}
+	for _, run := range []*CommandRun{build, test, other} {
+		require.NoError(t, db.RecordCommandRun(run))
+		assert.NotZero(t, run.ID)
+	}
+
+	runs, err := db.ListCommandRuns("/src/app", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "go test ./...", runs[0].Command, "newest first")
+	assert.Equal(t, 1, runs[0].ExitCode)
+	assert.Equal(t, "bash", runs[0].Shell)
+
+	require.NoError(t, db.SetCommandRunFavorite(build.ID, true))
+	runs, err = db.ListCommandRuns("/src/app", 10)
+	require.NoError(t, err)
+	assert.Equal(t, build.ID, runs[0].ID, "favorites first")
+	assert.True(t, runs[0].Favorite)
+
+	got, err := db.GetCommandRun(build.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "go build ./...", got.Command)
+	assert.Equal(t, 1500*time.Millisecond, got.Duration)
+
+	_, err = db.GetCommandRun(999)
+	assert.Error(t, err)
+	assert.Error(t, db.SetCommandRunFavorite(999, true))
+}
+
+func TestSQLiteDB_TodoOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	plan := &Todo{SessionID: "s1", Content: "Read the parser"}
+	fix := &Todo{SessionID: "s1", Content: "Fix the off-by-one", Status: "in_progress"}
+	other := &Todo{SessionID: "s2", Content: "Unrelated"}
+	for _, todo := range []*Todo{plan, fix, other} {
+		require.NoError(t, db.CreateTodo(todo))
+		assert.NotZero(t, todo.ID)
+	}
+	assert.Equal(t, "pending", plan.Status)
+	assert.Equal(t, 1, plan.Position)
+	assert.Equal(t, 2, fix.Position)
+	assert.Equal(t, 1, other.Position, "positions are per session")
+
+	plan.Status = "completed"
+	require.NoError(t, db.UpdateTodo(plan))
+	todos, err := db.ListTodos("s1")
+	require.NoError(t, err)
+	require.Len(t, todos, 2)
+	assert.Equal(t, "Read the parser", todos[0].Content)
+	assert.Equal(t, "completed", todos[0].Status)
+	assert.Equal(t, "in_progress", todos[1].Status)
+
+	got, err := db.GetTodo("s1", fix.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Fix the off-by-one", got.Content)
+
+	// Todos of another session can't be reached
+	_, err = db.GetTodo("s1", other.ID)
+	assert.Error(t, err)
+	other.SessionID = "s1"
+	assert.Error(t, db.UpdateTodo(other))
+}
+
+func TestSQLiteDB_OversizedMessages(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	db.SetContentLimits(ContentLimits{
+		CompressThreshold: 1024,
+		ChunkSize:         64,
+		PreviewSize:       128,
+		MaxSize:           64 * 1024,
+	})
+	require.NoError(t, db.CreateSession("big-session", "Big Session"))
+
+	t.Run("compressed round trip", func(t *testing.T) {
+		content := "needle " + strings.Repeat("tool output line\n", 1000)
+		msg := &Message{SessionID: "big-session", Role: "tool", Content: content}
+		require.NoError(t, db.AddMessage(msg))
+
+		// Only a preview is stored inline
+		var inline, encoding string
+		require.NoError(t, db.DB().QueryRow(
+			"SELECT content, content_encoding FROM messages WHERE id = ?", msg.ID,
+		).Scan(&inline, &encoding))
+		assert.Equal(t, "zstd", encoding)
+		assert.LessOrEqual(t, len(inline), 128)
+
+		var chunks int
+		require.NoError(t, db.DB().QueryRow(
+			"SELECT COUNT(*) FROM message_chunks WHERE message_id = ?", msg.ID,
+		).Scan(&chunks))
+		assert.Greater(t, chunks, 0)
+
+		messages, err := db.GetSessionMessages("big-session")
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, content, messages[0].Content)
+
+		// The preview is still searchable and returns full content
+		found, err := db.SearchMessages("needle")
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, content, found[0].Content)
+	})
+
+	t.Run("small messages stay inline", func(t *testing.T) {
+		msg := &Message{SessionID: "big-session", Role: "user", Content: "short"}
+		require.NoError(t, db.AddMessage(msg))
+
+		messages, err := db.GetMessages("big-session", 0)
+		require.NoError(t, err)
+		assert.Len(t, messages, 2)
+	})
+
+	t.Run("truncates above max size", func(t *testing.T) {
+		content := strings.Repeat("é", 40*1024) // 80 KiB of two-byte runes
+		msg := &Message{SessionID: "big-session", Role: "tool", Content: content}
+		require.NoError(t, db.AddMessage(msg))
+
+		messages, err := db.GetSessionMessages("big-session")
+		require.NoError(t, err)
+		got := messages[len(messages)-1].Content
+		assert.True(t, utf8.ValidString(got))
+		assert.Contains(t, got, "[truncated")
+		assert.Less(t, len(got), len(content))
+	})
+
+	t.Run("chunks deleted with session", func(t *testing.T) {
+		require.NoError(t, db.DeleteSession("big-session"))
+
+		var chunks int
+		require.NoError(t, db.DB().QueryRow("SELECT COUNT(*) FROM message_chunks").Scan(&chunks))
+		assert.Equal(t, 0, chunks)
+	})
+}
+
+func TestSQLiteDB_Redaction(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	db.SetContentLimits(ContentLimits{CompressThreshold: 1024, ChunkSize: 64, PreviewSize: 128})
+	require.NoError(t, db.CreateSession("s1", "Session 1"))
+	require.NoError(t, db.CreateSession("s2", "Session 2"))
+
+	leaked := &Message{SessionID: "s1", Role: "user", Content: "my key is sk-live-abc123, use it"}
+	big := &Message{SessionID: "s2", Role: "tool", Content: "sk-live-abc123 " + strings.Repeat("env dump\n", 500)}
+	clean := &Message{SessionID: "s2", Role: "assistant", Content: "done"}
+	for _, msg := range []*Message{leaked, big, clean} {
+		require.NoError(t, db.AddMessage(msg))
+	}
+	_, err = db.DB().Exec("UPDATE sessions SET context_snapshot = ? WHERE id = ?", `{"system":"sk-live-abc123"}`, "s1")
+	require.NoError(t, err)
+
+	re := regexp.MustCompile(`sk-live-[a-z0-9]+`)
+	n, err := db.CountMatchingMessages(re)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = db.RedactMessages(re, "[redacted]")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	got, err := db.GetMessage(leaked.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "my key is [redacted], use it", got.Content)
+	got, err = db.GetMessage(big.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(got.Content, "[redacted] env dump"))
+
+	var snapshot string
+	require.NoError(t, db.DB().QueryRow("SELECT context_snapshot FROM sessions WHERE id = ?", "s1").Scan(&snapshot))
+	assert.NotContains(t, snapshot, "sk-live")
+
+	// Nothing is left in the search index
+	found, err := db.SearchMessages(`"sk-live-abc123"`)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	found, err = db.SearchMessages("redacted")
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	// A whole message by ID, also in its session's snapshot but not in
+	// other sessions'
+	for id, snapshot := range map[string]string{"s1": `{"messages":["done"]}`, "s2": `{"messages":["all done"]}`} {
+		_, err = db.DB().Exec("UPDATE sessions SET context_snapshot = ? WHERE id = ?", snapshot, id)
+		require.NoError(t, err)
+	}
+	original, err := db.RedactMessage(clean.ID, "[redacted]")
+	require.NoError(t, err)
+	assert.Equal(t, "done", original.Content)
+	assert.Equal(t, "assistant", original.Role)
+	found, err = db.SearchMessages("done")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	require.NoError(t, db.DB().QueryRow("SELECT context_snapshot FROM sessions WHERE id = ?", "s2").Scan(&snapshot))
+	assert.Equal(t, `{"messages":["all [redacted]"]}`, snapshot)
+	require.NoError(t, db.DB().QueryRow("SELECT context_snapshot FROM sessions WHERE id = ?", "s1").Scan(&snapshot))
+	assert.Equal(t, `{"messages":["done"]}`, snapshot)
+
+	_, err = db.RedactMessage(9999, "[redacted]")
+	assert.Error(t, err)
+}
+
 func TestSQLiteDB_Backup(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -234,6 +512,24 @@ func TestSQLiteDB_Backup(t *testing.T) {
 	assert.Equal(t, "Backup Session", session.Name)
 }
 
+func TestSQLiteDB_Flush(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.CreateSession("idle-session", "Idle Session"))
+	require.NoError(t, db.Flush())
+
+	info, err := os.Stat(dbPath + "-wal")
+	if err == nil {
+		assert.Zero(t, info.Size(), "the WAL is written into the database")
+	}
+	session, err := db.GetSession("idle-session")
+	require.NoError(t, err)
+	assert.Equal(t, "Idle Session", session.Name)
+}
+
 func TestSQLiteDB_ForeignKeyConstraints(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
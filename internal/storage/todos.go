@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Todo operations
+
+// CreateTodo appends a todo to the end of its session's list
+func (s *SQLiteDB) CreateTodo(todo *Todo) error {
+	now := time.Now()
+	if todo.Status == "" {
+		todo.Status = "pending"
+	}
+	result, err := s.db.Exec(
+		`INSERT INTO todos (session_id, content, status, position, created_at, updated_at)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM todos WHERE session_id = ?), ?, ?)`,
+		todo.SessionID, todo.Content, todo.Status, todo.SessionID, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create todo: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get todo ID: %w", err)
+	}
+	if err := s.db.QueryRow("SELECT position FROM todos WHERE id = ?", id).Scan(&todo.Position); err != nil {
+		return fmt.Errorf("failed to get todo position: %w", err)
+	}
+	todo.ID = id
+	todo.CreatedAt = now
+	todo.UpdatedAt = now
+	return nil
+}
+
+// UpdateTodo saves a todo's content and status. The todo must belong to
+// the session it names
+func (s *SQLiteDB) UpdateTodo(todo *Todo) error {
+	now := time.Now()
+	result, err := s.db.Exec(
+		"UPDATE todos SET content = ?, status = ?, updated_at = ? WHERE id = ? AND session_id = ?",
+		todo.Content, todo.Status, now, todo.ID, todo.SessionID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update todo: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("todo not found: #%d", todo.ID)
+	}
+	todo.UpdatedAt = now
+	return nil
+}
+
+// GetTodo retrieves a todo of a session by ID
+func (s *SQLiteDB) GetTodo(sessionID string, id int64) (*Todo, error) {
+	var todo Todo
+	err := s.db.QueryRow(
+		"SELECT id, session_id, content, status, position, created_at, updated_at FROM todos WHERE id = ? AND session_id = ?",
+		id, sessionID,
+	).Scan(&todo.ID, &todo.SessionID, &todo.Content, &todo.Status, &todo.Position, &todo.CreatedAt, &todo.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("todo not found: #%d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo: %w", err)
+	}
+	return &todo, nil
+}
+
+// ListTodos retrieves a session's todos in list order
+func (s *SQLiteDB) ListTodos(sessionID string) ([]*Todo, error) {
+	rows, err := s.db.Query(
+		"SELECT id, session_id, content, status, position, created_at, updated_at FROM todos WHERE session_id = ? ORDER BY position, id",
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todos: %w", err)
+	}
+	defer rows.Close()
+
+	var todos []*Todo
+	for rows.Next() {
+		var todo Todo
+		if err := rows.Scan(&todo.ID, &todo.SessionID, &todo.Content, &todo.Status, &todo.Position, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+		todos = append(todos, &todo)
+	}
+	return todos, rows.Err()
+}
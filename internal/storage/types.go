@@ -23,6 +23,8 @@ type Message struct {
 	TokensOutput int       `json:"tokens_output"`
 	Cost         float64   `json:"cost"`
 	Metadata     *string   `json:"metadata,omitempty"`
+
+	encoding string // Content encoding as stored; empty once content is expanded
 }
 
 // File represents a file tracked in a session
@@ -64,3 +66,28 @@ type Metric struct {
 	Timestamp  time.Time `json:"timestamp"`
 	Metadata   *string   `json:"metadata,omitempty"`
 }
+
+// CommandRun is a shell command the agent (or the user) executed in a project
+type CommandRun struct {
+	ID         int64         `json:"id"`
+	Project    string        `json:"project"` // Directory b+ was started in
+	SessionID  *string       `json:"session_id,omitempty"`
+	Command    string        `json:"command"`
+	WorkingDir string        `json:"working_dir,omitempty"`
+	Shell      string        `json:"shell,omitempty"`
+	ExitCode   int           `json:"exit_code"` // -1 if the command did not run to completion
+	Duration   time.Duration `json:"duration"`
+	Favorite   bool          `json:"favorite"`
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// Todo is an item of the task list the agent keeps for a session
+type Todo struct {
+	ID        int64     `json:"id"`
+	SessionID string    `json:"session_id"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"`   // pending, in_progress or completed
+	Position  int       `json:"position"` // Order in the list
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,151 @@
+// Package worklog keeps a human-readable trail of the work the agent did in a
+// project: after each task that changed files, a short entry saying what
+// changed, why, and which files, is appended to a Markdown file. It is kept
+// apart from git history, which records how the code changed but not what
+// the agent was asked to do.
+package worklog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultPath is where the worklog is kept, relative to the project.
+const DefaultPath = ".b+/worklog.md"
+
+// header starts a new worklog file.
+const header = "# Worklog\n\nWork done by b+ in this project, newest last.\n"
+
+// maxExcerpt bounds the request and answer passed to the summarizer, in
+// bytes.
+const maxExcerpt = 4000
+
+// Entry is one task in the worklog.
+type Entry struct {
+	Time    time.Time
+	Session string
+	Model   string   // Model that did the work
+	What    string   // What changed
+	Why     string   // Why it was changed
+	Files   []string // Files written or edited, relative to the project
+}
+
+// Markdown renders the entry as the worklog stores it.
+func (e Entry) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## %s", e.Time.Format("2006-01-02 15:04"))
+	if e.Session != "" {
+		fmt.Fprintf(&b, " · session %s", e.Session)
+	}
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "- **What:** %s\n", oneLine(e.What))
+	if e.Why != "" {
+		fmt.Fprintf(&b, "- **Why:** %s\n", oneLine(e.Why))
+	}
+	files := make([]string, len(e.Files))
+	for i, f := range e.Files {
+		files[i] = "`" + f + "`"
+	}
+	fmt.Fprintf(&b, "- **Files:** %s\n", strings.Join(files, ", "))
+	if e.Model != "" {
+		fmt.Fprintf(&b, "- **Model:** %s\n", e.Model)
+	}
+	return b.String()
+}
+
+// Append adds an entry to the worklog at path, creating it and its
+// directory if needed.
+func Append(path string, e Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create worklog directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open worklog: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat worklog: %w", err)
+	}
+	text := e.Markdown()
+	if info.Size() == 0 {
+		text = header + text
+	}
+	if _, err := f.WriteString(text); err != nil {
+		return fmt.Errorf("failed to write worklog: %w", err)
+	}
+	return nil
+}
+
+// Prompt asks a model to summarize a task for the worklog, answering in the
+// JSON ParseSummary reads.
+func Prompt(request, answer string, files []string) string {
+	var b strings.Builder
+	b.WriteString("Summarize this coding task for a project worklog read by the team. ")
+	b.WriteString(`Reply with only a JSON object: {"what": "...", "why": "..."}. `)
+	b.WriteString(`"what" says in one sentence what changed in the code; "why" says in one sentence what the change was for, from the request. `)
+	b.WriteString("Use plain past tense and no Markdown.\n\n")
+	fmt.Fprintf(&b, "Request:\n%s\n\n", excerpt(request))
+	fmt.Fprintf(&b, "Final answer:\n%s\n\n", excerpt(answer))
+	fmt.Fprintf(&b, "Files changed: %s\n", strings.Join(files, ", "))
+	return b.String()
+}
+
+// ParseSummary reads the what and why from a model's reply to Prompt. The
+// JSON may be wrapped in prose or a code fence.
+func ParseSummary(reply string) (what, why string, ok bool) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return "", "", false
+	}
+	var summary struct {
+		What string `json:"what"`
+		Why  string `json:"why"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &summary); err != nil {
+		return "", "", false
+	}
+	what = strings.TrimSpace(summary.What)
+	return what, strings.TrimSpace(summary.Why), what != ""
+}
+
+// Fallback describes a task without a model: the first line of the answer
+// for what changed and of the request for why.
+func Fallback(request, answer string) (what, why string) {
+	return firstLine(answer), firstLine(request)
+}
+
+// firstLine returns the first non-empty line of s, shortened to a sentence.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if len(line) > 200 {
+			line = strings.TrimSpace(strings.ToValidUTF8(line[:200], "")) + "…"
+		}
+		return line
+	}
+	return ""
+}
+
+// oneLine joins the lines of s, so an entry's fields stay on one line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// excerpt shortens s to maxExcerpt bytes, keeping its start.
+func excerpt(s string) string {
+	if len(s) <= maxExcerpt {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxExcerpt], "") + "\n[...]"
+}
@@ -0,0 +1,65 @@
+package worklog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".b+", "worklog.md")
+	at := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+
+	require.NoError(t, Append(path, Entry{
+		Time:    at,
+		Session: "a1b2c3",
+		Model:   "anthropic/claude-sonnet-4-5",
+		What:    "Added retries to the\nmirror fetcher.",
+		Why:     "Builds failed on flaky mirrors.",
+		Files:   []string{"fetch/fetch.go", "fetch/fetch_test.go"},
+	}))
+	require.NoError(t, Append(path, Entry{Time: at.Add(time.Hour), What: "Renamed Config.Dir.", Files: []string{"config.go"}}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	text := string(data)
+	assert.Equal(t, 1, strings.Count(text, "# Worklog\n"), "the header is written once")
+	assert.Contains(t, text, "## 2026-10-14 09:30 · session a1b2c3\n\n"+
+		"- **What:** Added retries to the mirror fetcher.\n"+
+		"- **Why:** Builds failed on flaky mirrors.\n"+
+		"- **Files:** `fetch/fetch.go`, `fetch/fetch_test.go`\n"+
+		"- **Model:** anthropic/claude-sonnet-4-5\n")
+	assert.Contains(t, text, "## 2026-10-14 10:30\n\n- **What:** Renamed Config.Dir.\n- **Files:** `config.go`\n")
+	assert.Less(t, strings.Index(text, "09:30"), strings.Index(text, "10:30"), "newest last")
+}
+
+func TestParseSummary(t *testing.T) {
+	what, why, ok := ParseSummary("Here it is:\n```json\n{\"what\": \"Added a --dry-run flag.\", \"why\": \"To preview deploys.\"}\n```")
+	require.True(t, ok)
+	assert.Equal(t, "Added a --dry-run flag.", what)
+	assert.Equal(t, "To preview deploys.", why)
+
+	_, _, ok = ParseSummary("I changed some files.")
+	assert.False(t, ok)
+	_, _, ok = ParseSummary(`{"what": "", "why": "no reason"}`)
+	assert.False(t, ok)
+}
+
+func TestPrompt(t *testing.T) {
+	prompt := Prompt("Add a --dry-run flag", strings.Repeat("x", 2*maxExcerpt), []string{"main.go"})
+	assert.Contains(t, prompt, "Add a --dry-run flag")
+	assert.Contains(t, prompt, "Files changed: main.go")
+	assert.Contains(t, prompt, "[...]")
+	assert.Less(t, len(prompt), 2*maxExcerpt)
+}
+
+func TestFallback(t *testing.T) {
+	what, why := Fallback("\nFix the flaky test\nin router", "Done. I fixed the race.\n\nDetails...")
+	assert.Equal(t, "Done. I fixed the race.", what)
+	assert.Equal(t, "Fix the flaky test", why)
+}
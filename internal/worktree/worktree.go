@@ -0,0 +1,333 @@
+// Package worktree finishes work done in a linked git worktree: it shows
+// the changes against the base branch, tests them merged into it, and
+// squash-merges, pushes or discards them. Uncommitted changes count as part
+// of the work, so nothing needs committing first.
+package worktree
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotWorktree is returned by Detect for a directory that isn't in a
+// linked worktree.
+var ErrNotWorktree = stderrors.New("not in a linked git worktree")
+
+// testOutputLimit bounds the test output kept in a TestResult.
+const testOutputLimit = 16 * 1024
+
+// Worktree is a linked git worktree and the branch its work merges into.
+type Worktree struct {
+	Dir     string // Root of the linked worktree
+	Branch  string // Branch checked out in it
+	MainDir string // Root of the main worktree
+	Base    string // Branch the work is merged into
+}
+
+// Changes is the work done in a worktree: everything that differs from
+// where its branch left the base, committed or not.
+type Changes struct {
+	MergeBase  string // Commit the branch left the base at
+	Tree       string // Snapshot of the worktree, untracked files included
+	Files      int
+	Insertions int
+	Deletions  int
+	Stat       string // Per file summary, as git diff --stat shows it
+	Diff       string
+}
+
+// Empty reports whether there is no work to merge.
+func (c *Changes) Empty() bool {
+	return c.Files == 0
+}
+
+// TestResult is the outcome of running the tests on the merge result.
+type TestResult struct {
+	Command  string
+	Passed   bool
+	Conflict bool   // The changes don't apply to the base; the tests didn't run
+	Output   string // Tail of the combined output
+	Duration time.Duration
+}
+
+// Detect returns the linked worktree dir is in. base names the branch the
+// work merges into; if empty it is the branch checked out in the main
+// worktree.
+func Detect(ctx context.Context, dir, base string) (*Worktree, error) {
+	top, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotWorktree
+	}
+	gitDir, err := git(ctx, dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, err
+	}
+	commonDir, err := git(ctx, dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Clean(gitDir) == filepath.Clean(commonDir) {
+		return nil, ErrNotWorktree
+	}
+
+	branch, err := git(ctx, top, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("worktree %s has no branch checked out", top)
+	}
+	w := &Worktree{Dir: top, Branch: branch, MainDir: filepath.Dir(commonDir), Base: base}
+	if w.Base == "" {
+		if w.Base, err = git(ctx, w.MainDir, "symbolic-ref", "--short", "HEAD"); err != nil {
+			return nil, fmt.Errorf("main worktree %s has no branch checked out; name the base branch", w.MainDir)
+		}
+	}
+	if w.Base == w.Branch {
+		return nil, fmt.Errorf("worktree %s is on the base branch %s", top, w.Base)
+	}
+	return w, nil
+}
+
+// Changes returns the work done in the worktree.
+func (w *Worktree) Changes(ctx context.Context) (*Changes, error) {
+	mergeBase, err := git(ctx, w.Dir, "merge-base", w.Base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("branch %s shares no history with %s: %w", w.Branch, w.Base, err)
+	}
+	tree, err := w.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Changes{MergeBase: mergeBase, Tree: tree}
+	numstat, err := git(ctx, w.Dir, "diff", "--numstat", mergeBase, tree)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(numstat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		c.Files++
+		// Binary files count "-" lines
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		c.Insertions += added
+		c.Deletions += deleted
+	}
+	if c.Stat, err = git(ctx, w.Dir, "diff", "--stat", mergeBase, tree); err != nil {
+		return nil, err
+	}
+	if c.Diff, err = git(ctx, w.Dir, "diff", mergeBase, tree); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Test applies the changes to the base branch in a scratch worktree and
+// runs command there with sh, so the tests see the code as it would be
+// once merged.
+func (w *Worktree) Test(ctx context.Context, c *Changes, command string) (*TestResult, error) {
+	result := &TestResult{Command: command}
+	scratch, err := os.MkdirTemp("", "bplus-merge-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch worktree: %w", err)
+	}
+	os.Remove(scratch) // git worktree add wants to create it
+	if _, err := git(ctx, w.MainDir, "worktree", "add", "--detach", scratch, w.Base); err != nil {
+		return nil, fmt.Errorf("failed to create scratch worktree: %w", err)
+	}
+	defer func() {
+		_, _ = git(context.Background(), w.MainDir, "worktree", "remove", "--force", scratch)
+	}()
+
+	if err := w.apply(ctx, scratch, c); err != nil {
+		result.Conflict = true
+		result.Output = err.Error()
+		return result, nil
+	}
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = scratch
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err = cmd.Run()
+	result.Duration = time.Since(start)
+	result.Output = tail(output.String(), testOutputLimit)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !stderrors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to run %s: %w", command, err)
+	}
+	result.Passed = err == nil
+	return result, nil
+}
+
+// SquashMerge commits the changes to the base branch in the main worktree
+// as one commit. The main worktree must have the base checked out and no
+// uncommitted changes; if the changes don't apply it is left as it was.
+func (w *Worktree) SquashMerge(ctx context.Context, c *Changes, message string) error {
+	current, err := git(ctx, w.MainDir, "symbolic-ref", "--short", "HEAD")
+	if err != nil || current != w.Base {
+		return fmt.Errorf("main worktree %s must have %s checked out to merge into it", w.MainDir, w.Base)
+	}
+	if status, err := git(ctx, w.MainDir, "status", "--porcelain"); err != nil {
+		return err
+	} else if status != "" {
+		return fmt.Errorf("main worktree %s has uncommitted changes", w.MainDir)
+	}
+
+	if err := w.apply(ctx, w.MainDir, c); err != nil {
+		_, _ = git(context.Background(), w.MainDir, "reset", "--hard", "HEAD")
+		return err
+	}
+	if _, err := git(ctx, w.MainDir, "commit", "--quiet", "-m", message); err != nil {
+		_, _ = git(context.Background(), w.MainDir, "reset", "--hard", "HEAD")
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
+
+// Message returns the commit message of a squash merge: the branch name,
+// then the subjects of the commits on it, oldest first.
+func (w *Worktree) Message(ctx context.Context, c *Changes) string {
+	message := fmt.Sprintf("Merge branch '%s'", w.Branch)
+	subjects, err := git(ctx, w.Dir, "log", "--reverse", "--format=* %s", c.MergeBase+"..HEAD")
+	if err != nil || subjects == "" {
+		return message
+	}
+	return message + "\n\n" + subjects
+}
+
+// Push pushes the worktree's branch to remote, setting it as upstream.
+// Only commits are pushed, so uncommitted changes are refused.
+func (w *Worktree) Push(ctx context.Context, c *Changes, remote string) error {
+	head, err := git(ctx, w.Dir, "rev-parse", "HEAD^{tree}")
+	if err != nil {
+		return err
+	}
+	if head != c.Tree {
+		return fmt.Errorf("worktree %s has uncommitted changes; commit them before pushing", w.Dir)
+	}
+	if _, err := git(ctx, w.Dir, "push", "--quiet", "--set-upstream", remote, w.Branch); err != nil {
+		return fmt.Errorf("failed to push %s to %s: %w", w.Branch, remote, err)
+	}
+	return nil
+}
+
+// Discard removes the worktree and deletes its branch, throwing the work
+// away.
+func (w *Worktree) Discard(ctx context.Context) error {
+	if _, err := git(ctx, w.MainDir, "worktree", "remove", "--force", w.Dir); err != nil {
+		return fmt.Errorf("failed to remove worktree %s: %w", w.Dir, err)
+	}
+	if _, err := git(ctx, w.MainDir, "branch", "-D", w.Branch); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", w.Branch, err)
+	}
+	return nil
+}
+
+// testCommands are the usual test commands of a project, by the file that
+// marks its kind, in the order they are looked for.
+var testCommands = []struct{ marker, command string }{
+	{"go.mod", "go test ./..."},
+	{"Cargo.toml", "cargo test"},
+	{"package.json", "npm test"},
+	{"pyproject.toml", "python -m pytest"},
+	{"setup.py", "python -m pytest"},
+	{"Makefile", "make test"},
+}
+
+// DetectTestCommand returns the usual test command of the project in dir,
+// or "" if its kind isn't recognized.
+func DetectTestCommand(dir string) string {
+	for _, tc := range testCommands {
+		if _, err := os.Stat(filepath.Join(dir, tc.marker)); err == nil {
+			return tc.command
+		}
+	}
+	return ""
+}
+
+// snapshot writes the worktree's files, untracked ones included, as a tree
+// object, using a scratch index so the real one is left alone.
+func (w *Worktree) snapshot(ctx context.Context) (string, error) {
+	index, err := os.CreateTemp("", "bplus-index-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot worktree: %w", err)
+	}
+	index.Close()
+	os.Remove(index.Name()) // git wants to create it
+	defer os.Remove(index.Name())
+
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+	if _, err := gitEnv(ctx, w.Dir, env, "add", "--all"); err != nil {
+		return "", fmt.Errorf("failed to snapshot worktree: %w", err)
+	}
+	tree, err := gitEnv(ctx, w.Dir, env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot worktree: %w", err)
+	}
+	return tree, nil
+}
+
+// apply applies the changes to the checkout in dir, staging them.
+func (w *Worktree) apply(ctx context.Context, dir string, c *Changes) error {
+	patch, err := git(ctx, w.Dir, "diff", "--binary", "--full-index", c.MergeBase, c.Tree)
+	if err != nil {
+		return err
+	}
+	if patch == "" {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "apply", "--3way", "--index", "--whitespace=nowarn")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(patch + "\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("changes don't apply to %s: %s", w.Base, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	return gitEnv(ctx, dir, nil, args...)
+}
+
+// gitEnv runs a git command with extra environment variables.
+func gitEnv(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// tail returns the last limit bytes of s.
+func tail(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return "…" + s[len(s)-limit:]
+}
@@ -0,0 +1,206 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setup creates a repository on main with one commit and a linked worktree
+// on the branch "feature", returning both roots.
+func setup(t *testing.T) (mainDir, featureDir string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	mainDir = filepath.Join(root, "main")
+	featureDir = filepath.Join(root, "feature")
+	require.NoError(t, os.Mkdir(mainDir, 0755))
+
+	run(t, mainDir, "init", "--quiet", "--initial-branch=main")
+	run(t, mainDir, "config", "user.email", "dev@example.com")
+	run(t, mainDir, "config", "user.name", "Dev")
+	write(t, mainDir, "app.txt", "one\n")
+	run(t, mainDir, "add", "app.txt")
+	run(t, mainDir, "commit", "--quiet", "-m", "initial")
+	run(t, mainDir, "worktree", "add", "--quiet", "-b", "feature", featureDir)
+	return mainDir, featureDir
+}
+
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git(context.Background(), dir, args...)
+	require.NoError(t, err, "git %s", strings.Join(args, " "))
+	return out
+}
+
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func TestDetect(t *testing.T) {
+	ctx := context.Background()
+	mainDir, featureDir := setup(t)
+
+	_, err := Detect(ctx, mainDir, "")
+	assert.ErrorIs(t, err, ErrNotWorktree)
+	_, err = Detect(ctx, t.TempDir(), "")
+	assert.ErrorIs(t, err, ErrNotWorktree)
+
+	w, err := Detect(ctx, featureDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, "feature", w.Branch)
+	assert.Equal(t, "main", w.Base)
+	resolved, _ := filepath.EvalSymlinks(mainDir)
+	actual, _ := filepath.EvalSymlinks(w.MainDir)
+	assert.Equal(t, resolved, actual)
+
+	_, err = Detect(ctx, featureDir, "feature")
+	assert.Error(t, err)
+}
+
+func TestChanges(t *testing.T) {
+	ctx := context.Background()
+	_, featureDir := setup(t)
+	w, err := Detect(ctx, featureDir, "")
+	require.NoError(t, err)
+
+	c, err := w.Changes(ctx)
+	require.NoError(t, err)
+	assert.True(t, c.Empty())
+
+	// A committed change, an uncommitted one and an untracked file
+	write(t, featureDir, "app.txt", "one\ntwo\n")
+	run(t, featureDir, "commit", "--quiet", "-am", "two")
+	write(t, featureDir, "app.txt", "one\ntwo\nthree\n")
+	write(t, featureDir, "new.txt", "new\n")
+
+	c, err = w.Changes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Files)
+	assert.Equal(t, 3, c.Insertions)
+	assert.Equal(t, 0, c.Deletions)
+	assert.Contains(t, c.Stat, "new.txt")
+	assert.Contains(t, c.Diff, "+three")
+
+	// The real index is left alone
+	assert.Equal(t, "?? new.txt", run(t, featureDir, "status", "--porcelain", "--untracked-files=all", "new.txt"))
+}
+
+func TestTest(t *testing.T) {
+	ctx := context.Background()
+	mainDir, featureDir := setup(t)
+	w, err := Detect(ctx, featureDir, "")
+	require.NoError(t, err)
+
+	write(t, featureDir, "app.txt", "one\ntwo\n")
+	c, err := w.Changes(ctx)
+	require.NoError(t, err)
+
+	t.Run("passes on the merge result", func(t *testing.T) {
+		result, err := w.Test(ctx, c, "grep -q two app.txt")
+		require.NoError(t, err)
+		assert.True(t, result.Passed)
+		assert.False(t, result.Conflict)
+	})
+
+	t.Run("fails", func(t *testing.T) {
+		result, err := w.Test(ctx, c, "echo broken; exit 1")
+		require.NoError(t, err)
+		assert.False(t, result.Passed)
+		assert.Contains(t, result.Output, "broken")
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		write(t, mainDir, "app.txt", "uno\n")
+		run(t, mainDir, "commit", "--quiet", "-am", "uno")
+		defer run(t, mainDir, "reset", "--quiet", "--hard", "HEAD~1")
+
+		result, err := w.Test(ctx, c, "true")
+		require.NoError(t, err)
+		assert.True(t, result.Conflict)
+		assert.False(t, result.Passed)
+	})
+
+	// Scratch worktrees are cleaned up
+	assert.Equal(t, 2, strings.Count(run(t, mainDir, "worktree", "list", "--porcelain"), "worktree "))
+}
+
+func TestSquashMerge(t *testing.T) {
+	ctx := context.Background()
+	mainDir, featureDir := setup(t)
+	w, err := Detect(ctx, featureDir, "")
+	require.NoError(t, err)
+
+	write(t, featureDir, "app.txt", "one\ntwo\n")
+	run(t, featureDir, "commit", "--quiet", "-am", "two")
+	write(t, featureDir, "new.txt", "new\n")
+	c, err := w.Changes(ctx)
+	require.NoError(t, err)
+
+	t.Run("refuses a dirty main worktree", func(t *testing.T) {
+		write(t, mainDir, "scratch.txt", "x\n")
+		defer os.Remove(filepath.Join(mainDir, "scratch.txt"))
+		assert.Error(t, w.SquashMerge(ctx, c, "Add two"))
+	})
+
+	message := w.Message(ctx, c)
+	assert.Equal(t, "Merge branch 'feature'\n\n* two", message)
+	require.NoError(t, w.SquashMerge(ctx, c, message))
+	assert.Equal(t, "Merge branch 'feature'", run(t, mainDir, "log", "-1", "--format=%s"))
+	assert.Equal(t, "2", run(t, mainDir, "rev-list", "--count", "HEAD"))
+	assert.Equal(t, "", run(t, mainDir, "status", "--porcelain"))
+	data, err := os.ReadFile(filepath.Join(mainDir, "new.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+}
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+	mainDir, featureDir := setup(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	run(t, mainDir, "init", "--quiet", "--bare", remote)
+	run(t, mainDir, "remote", "add", "origin", remote)
+	w, err := Detect(ctx, featureDir, "")
+	require.NoError(t, err)
+
+	write(t, featureDir, "app.txt", "one\ntwo\n")
+	c, err := w.Changes(ctx)
+	require.NoError(t, err)
+	assert.Error(t, w.Push(ctx, c, "origin"), "uncommitted changes aren't pushed")
+
+	run(t, featureDir, "commit", "--quiet", "-am", "two")
+	c, err = w.Changes(ctx)
+	require.NoError(t, err)
+	require.NoError(t, w.Push(ctx, c, "origin"))
+	assert.Equal(t, run(t, featureDir, "rev-parse", "HEAD"), run(t, remote, "rev-parse", "feature"))
+}
+
+func TestDiscard(t *testing.T) {
+	ctx := context.Background()
+	mainDir, featureDir := setup(t)
+	w, err := Detect(ctx, featureDir, "")
+	require.NoError(t, err)
+	write(t, featureDir, "app.txt", "changed\n")
+
+	require.NoError(t, w.Discard(ctx))
+	assert.NoDirExists(t, featureDir)
+	assert.Equal(t, "", run(t, mainDir, "branch", "--list", "feature"))
+}
+
+func TestDetectTestCommand(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, "", DetectTestCommand(dir))
+	write(t, dir, "Makefile", "test:\n")
+	assert.Equal(t, "make test", DetectTestCommand(dir))
+	write(t, dir, "go.mod", "module x\n")
+	assert.Equal(t, "go test ./...", DetectTestCommand(dir))
+}
@@ -0,0 +1,336 @@
+package contextmgr
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/models"
+)
+
+// Scheduling defaults
+const (
+	// defaultTriggerRatio is the share of the token budget at which
+	// optimization is requested automatically.
+	defaultTriggerRatio = 0.8
+
+	// defaultMinInterval is the minimum time between automatic optimizations.
+	defaultMinInterval = 30 * time.Second
+)
+
+// Manager owns the conversation history and schedules its optimization.
+//
+// Optimization never runs while an agent turn is being assembled: requests
+// made during a turn are coalesced and run once the turn ends. Automatic
+// optimizations are also throttled to at most one per minimum interval.
+type Manager struct {
+	mu         sync.Mutex
+	history    []models.Message
+	provenance []Provenance // Parallel to history
+	categoryOf CategoryFunc
+	model      string // Model whose tokenizer counts the history
+
+	maxTokens     int
+	triggerRatio  float64
+	minInterval   time.Duration
+	keepRecent    int
+	maxToolOutput int
+
+	scorer Scorer
+	intent string             // User messages of the current task, latest first
+	scores map[uint64]float64 // Scorer ratings against intent, by content key
+
+	inTurn  bool
+	pending bool // Optimization requested and not yet run
+	lastRun time.Time
+	now     func() time.Time
+}
+
+// Option is a functional option for configuring the manager.
+type Option func(*Manager)
+
+// WithMinInterval sets the minimum time between automatic optimizations.
+func WithMinInterval(d time.Duration) Option {
+	return func(m *Manager) {
+		m.minInterval = d
+	}
+}
+
+// WithKeepRecent sets how many trailing messages are never pruned.
+func WithKeepRecent(n int) Option {
+	return func(m *Manager) {
+		m.keepRecent = n
+	}
+}
+
+// WithMaxToolOutput sets the size in bytes above which older tool outputs are pruned.
+func WithMaxToolOutput(n int) Option {
+	return func(m *Manager) {
+		m.maxToolOutput = n
+	}
+}
+
+// WithToolCategories sets how tool categories are looked up to classify
+// tool output.
+func WithToolCategories(fn CategoryFunc) Option {
+	return func(m *Manager) {
+		m.categoryOf = fn
+	}
+}
+
+// WithModel sets the model whose tokenizer counts the history.
+func WithModel(model string) Option {
+	return func(m *Manager) {
+		m.model = model
+	}
+}
+
+// NewManager creates a manager for a context budget of maxTokens. A
+// non-positive budget disables automatic optimization.
+func NewManager(maxTokens int, opts ...Option) *Manager {
+	m := &Manager{
+		maxTokens:     maxTokens,
+		triggerRatio:  defaultTriggerRatio,
+		minInterval:   defaultMinInterval,
+		keepRecent:    defaultKeepRecent,
+		maxToolOutput: defaultMaxToolOutput,
+		now:           time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Append adds messages to the history, tagging each with its provenance.
+func (m *Manager) Append(messages ...models.Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range messages {
+		m.history = append(m.history, msg)
+		m.provenance = append(m.provenance, MessageProvenance(msg, m.categoryOf))
+	}
+}
+
+// Reset replaces the history with messages, as when resuming a session.
+func (m *Manager) Reset(messages ...models.Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = nil
+	m.provenance = nil
+	m.pending = false
+	m.intent = ""
+	m.scores = nil
+	for _, msg := range messages {
+		m.history = append(m.history, msg)
+		m.provenance = append(m.provenance, MessageProvenance(msg, m.categoryOf))
+	}
+}
+
+// Redact scrubs the patterns of r from the history and returns how many
+// messages changed.
+func (m *Manager) Redact(r *models.Redactor) int {
+	return m.RedactWhere(r, nil)
+}
+
+// RedactWhere scrubs the patterns of r from the history messages keep
+// accepts, or from all of them if keep is nil, and returns how many
+// messages changed.
+func (m *Manager) RedactWhere(r *models.Redactor, keep func(models.Message) bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for i, msg := range m.history {
+		if keep != nil && !keep(msg) {
+			continue
+		}
+		if redacted, changed := r.RedactMessage(msg); changed {
+			m.history[i] = redacted
+			n++
+		}
+	}
+	return n
+}
+
+// Items describes the history with the provenance, tier and relevance to
+// the current intent of each message.
+func (m *Manager) Items() []Item {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rel := m.relevanceOf(m.history)
+	tiers := tierMessages(m.model, m.history, m.keepRecent, m.maxToolOutput, rel)
+	items := make([]Item, len(m.history))
+	for i, msg := range m.history {
+		items[i] = Item{
+			Index:      i,
+			Role:       msg.Role,
+			Name:       msg.Name,
+			Tokens:     models.CountTokens(m.model, msg.Content),
+			Provenance: m.provenance[i],
+			Tier:       tiers[i],
+			Relevance:  rel(msg),
+		}
+	}
+	return items
+}
+
+// Thresholds returns the pruning thresholds: how many trailing messages are
+// kept and the tool output size in bytes above which older outputs are pruned.
+func (m *Manager) Thresholds() (keepRecent, maxToolOutput int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.keepRecent, m.maxToolOutput
+}
+
+// MaxTokens returns the context budget.
+func (m *Manager) MaxTokens() int {
+	return m.maxTokens
+}
+
+// Untrusted reports whether the history holds any low-trust content.
+func (m *Manager) Untrusted() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.provenance {
+		if p.Untrusted() {
+			return true
+		}
+	}
+	return false
+}
+
+// History returns a copy of the history.
+func (m *Manager) History() []models.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]models.Message, len(m.history))
+	copy(out, m.history)
+	return out
+}
+
+// Tokens returns the size of the history in tokens.
+func (m *Manager) Tokens() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return CountTokens(m.model, m.history)
+}
+
+// SetModel changes the model whose tokenizer counts the history.
+func (m *Manager) SetModel(model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.model = model
+}
+
+// BeginTurn marks the start of an agent turn. The history is left untouched
+// until EndTurn.
+func (m *Manager) BeginTurn() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inTurn = true
+}
+
+// EndTurn marks the end of an agent turn and runs a pending or automatic
+// optimization if the throttle allows. It returns the plan that was applied,
+// if any.
+func (m *Manager) EndTurn() (Plan, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inTurn = false
+	if m.overBudget() {
+		m.pending = true
+	}
+	return m.runPending()
+}
+
+// Trigger requests an optimization. Outside a turn it runs immediately if the
+// throttle allows; otherwise it is coalesced with other requests and runs at
+// the next turn boundary.
+func (m *Manager) Trigger() (Plan, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending = true
+	if m.inTurn {
+		return Plan{}, false
+	}
+	return m.runPending()
+}
+
+// Compactor returns a models.Compactor that prunes the tool outputs of a
+// request that overflows the model's context window with the manager's
+// thresholds, least relevant to the current intent first (see Compact).
+// The history is optimized too, at the next turn boundary, so later
+// requests start out smaller.
+func (m *Manager) Compactor() models.Compactor {
+	return func(req *models.CompletionRequest, excess int) (*models.CompletionRequest, bool) {
+		m.mu.Lock()
+		keepRecent, maxToolOutput := m.keepRecent, m.maxToolOutput
+		rel := m.relevanceOf(req.Messages)
+		m.mu.Unlock()
+		messages, plan := Compact(req.Model, req.Messages, excess, keepRecent, maxToolOutput, rel)
+		if plan.Empty() {
+			return req, false
+		}
+		m.Trigger()
+
+		compacted := *req
+		compacted.Messages = messages
+		return &compacted, true
+	}
+}
+
+// Preview returns what an optimization would prune now, without applying it.
+func (m *Manager) Preview() Plan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return planPrunes(m.model, m.history, m.keepRecent, m.maxToolOutput, m.relevanceOf(m.history))
+}
+
+// Optimize prunes the history now, bypassing the throttle. It fails while
+// a turn is in progress.
+func (m *Manager) Optimize() (Plan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.inTurn {
+		return Plan{}, errors.New(errors.ErrCodeUser, "cannot optimize context while a turn is in progress")
+	}
+	return m.run(), nil
+}
+
+// overBudget reports whether the history has reached the trigger threshold.
+// It must be called with mu held.
+func (m *Manager) overBudget() bool {
+	if m.maxTokens <= 0 {
+		return false
+	}
+	return float64(CountTokens(m.model, m.history)) >= float64(m.maxTokens)*m.triggerRatio
+}
+
+// runPending runs a pending optimization unless one ran within the minimum
+// interval, in which case it stays pending. It must be called with mu held.
+func (m *Manager) runPending() (Plan, bool) {
+	if !m.pending {
+		return Plan{}, false
+	}
+	if !m.lastRun.IsZero() && m.now().Sub(m.lastRun) < m.minInterval {
+		return Plan{}, false
+	}
+	plan := m.run()
+	return plan, !plan.Empty()
+}
+
+// run applies an optimization pass. It must be called with mu held.
+func (m *Manager) run() Plan {
+	plan := planPrunes(m.model, m.history, m.keepRecent, m.maxToolOutput, m.relevanceOf(m.history))
+	if !plan.Empty() {
+		m.history = applyPlan(m.history, plan)
+	}
+	m.pending = false
+	m.lastRun = m.now()
+	return plan
+}
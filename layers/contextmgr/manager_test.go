@@ -0,0 +1,162 @@
+package contextmgr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prunable returns an exchange whose tool output is pruned once it is
+// older than the keep-recent window.
+func prunable() []models.Message {
+	return []models.Message{
+		{Role: "user", Content: "show me the log"},
+		{Role: "tool", Name: "core.read", Content: strings.Repeat("log line\n", defaultMaxToolOutput/9+10)},
+	}
+}
+
+// recent returns n short messages filling the keep-recent window.
+func recent(n int) []models.Message {
+	messages := make([]models.Message, n)
+	for i := range messages {
+		messages[i] = models.Message{Role: "user", Content: "ok"}
+	}
+	return messages
+}
+
+// testClock is a manually advanced clock.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestManager(maxTokens int) (*Manager, *testClock) {
+	clock := &testClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	m := NewManager(maxTokens)
+	m.now = clock.Now
+	return m, clock
+}
+
+func TestManager_TriggerOutsideTurn(t *testing.T) {
+	m, _ := newTestManager(0)
+	m.Append(prunable()...)
+	m.Append(recent(defaultKeepRecent)...)
+
+	plan, ran := m.Trigger()
+	require.True(t, ran)
+	assert.Len(t, plan.Prunes, 1)
+	assert.Contains(t, m.History()[1].Content, "[pruned")
+}
+
+func TestManager_CoalescesDuringTurn(t *testing.T) {
+	m, _ := newTestManager(0)
+	m.Append(prunable()...)
+	m.Append(recent(defaultKeepRecent)...)
+	before := m.History()
+
+	m.BeginTurn()
+	for i := 0; i < 3; i++ {
+		_, ran := m.Trigger()
+		assert.False(t, ran, "nothing runs while a turn is assembled")
+	}
+	assert.Equal(t, before, m.History())
+
+	// The requests run once, at the end of the turn
+	plan, ran := m.EndTurn()
+	require.True(t, ran)
+	assert.Len(t, plan.Prunes, 1)
+	_, ran = m.EndTurn()
+	assert.False(t, ran, "the coalesced requests are done")
+}
+
+func TestManager_OptimizeRefusedDuringTurn(t *testing.T) {
+	m, _ := newTestManager(0)
+	m.Append(prunable()...)
+	m.Append(recent(defaultKeepRecent)...)
+
+	m.BeginTurn()
+	_, err := m.Optimize()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.ErrCodeUser))
+	assert.NotContains(t, m.History()[1].Content, "[pruned")
+
+	// A refused request is not left pending
+	_, ran := m.EndTurn()
+	assert.False(t, ran)
+
+	plan, err := m.Optimize()
+	require.NoError(t, err)
+	assert.Len(t, plan.Prunes, 1)
+}
+
+func TestManager_Throttle(t *testing.T) {
+	m, clock := newTestManager(0)
+	m.Append(prunable()...)
+	m.Append(recent(defaultKeepRecent)...)
+	_, ran := m.Trigger()
+	require.True(t, ran)
+
+	// Another request within the interval waits
+	m.Append(prunable()...)
+	m.Append(recent(defaultKeepRecent)...)
+	clock.now = clock.now.Add(defaultMinInterval - time.Second)
+	_, ran = m.Trigger()
+	assert.False(t, ran)
+	_, ran = m.EndTurn()
+	assert.False(t, ran, "still within the interval")
+
+	// It stays pending and runs at the first turn boundary after it
+	clock.now = clock.now.Add(time.Second)
+	m.BeginTurn()
+	plan, ran := m.EndTurn()
+	require.True(t, ran)
+	assert.Len(t, plan.Prunes, 1)
+}
+
+func TestManager_TriggerRatio(t *testing.T) {
+	history := append(prunable(), recent(defaultKeepRecent)...)
+	tokens := CountTokens("", history)
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		ran       bool
+	}{
+		{"at the trigger ratio", int(float64(tokens) / defaultTriggerRatio), true},
+		{"below the trigger ratio", int(float64(tokens)/defaultTriggerRatio) + 10, false},
+		{"no budget", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(tt.maxTokens)
+			m.Reset(history...)
+			m.BeginTurn()
+			_, ran := m.EndTurn()
+			assert.Equal(t, tt.ran, ran)
+		})
+	}
+}
+
+func TestManager_WithMinInterval(t *testing.T) {
+	m, clock := newTestManager(0)
+	WithMinInterval(time.Minute)(m)
+	m.Append(prunable()...)
+	m.Append(recent(defaultKeepRecent)...)
+	m.Trigger()
+
+	m.Append(prunable()...)
+	m.Append(recent(defaultKeepRecent)...)
+	clock.now = clock.now.Add(defaultMinInterval)
+	_, ran := m.Trigger()
+	assert.False(t, ran)
+	clock.now = clock.now.Add(time.Minute)
+	_, ran = m.Trigger()
+	assert.True(t, ran)
+}
@@ -0,0 +1,186 @@
+// Package contextmgr implements Layer 6 (Context Management) of the 7-layer architecture.
+// It keeps the conversation history and prunes it between agent turns so the
+// context stays within the model's budget.
+package contextmgr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// Pruning defaults
+const (
+	// defaultKeepRecent is the number of trailing messages never pruned, so the
+	// exchange the model is working on stays intact.
+	defaultKeepRecent = 6
+
+	// defaultMaxToolOutput is the size in bytes above which older tool
+	// outputs are pruned.
+	defaultMaxToolOutput = 2000
+
+	// prunedHead is how much of a pruned output is kept as a hint of what it was.
+	prunedHead = 200
+)
+
+// Prune describes one message the optimizer shortens.
+type Prune struct {
+	Index        int    // Position in the history
+	Role         string // Message role (always "tool" for now)
+	Name         string // Tool name
+	TokensBefore int
+	TokensAfter  int
+}
+
+// Plan is the set of prunes one optimization pass makes.
+type Plan struct {
+	Prunes       []Prune
+	TokensBefore int // Estimated history size before pruning
+	TokensAfter  int // Estimated history size after pruning
+}
+
+// Empty reports whether the plan prunes nothing.
+func (p Plan) Empty() bool {
+	return len(p.Prunes) == 0
+}
+
+// Saved returns the estimated number of tokens the plan frees.
+func (p Plan) Saved() int {
+	return p.TokensBefore - p.TokensAfter
+}
+
+// CountTokens returns the token count of messages for model.
+func CountTokens(model string, messages []models.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += models.CountTokens(model, msg.Content)
+	}
+	return total
+}
+
+// planPrunes selects older, oversized tool outputs for pruning. The last
+// keepRecent messages are left alone, as are outputs rel rates as relevant
+// to what the user is doing now and outputs a later result refers to (see
+// UnchangedOutput); rel may be nil.
+func planPrunes(model string, messages []models.Message, keepRecent, maxToolOutput int, rel relevanceFunc) Plan {
+	plan := Plan{TokensBefore: CountTokens(model, messages)}
+	plan.TokensAfter = plan.TokensBefore
+
+	referenced := referencedOutputs(messages)
+	for i := 0; i < len(messages)-keepRecent; i++ {
+		msg := messages[i]
+		if msg.Role != "tool" || len(msg.Content) <= maxToolOutput || referenced[i] {
+			continue
+		}
+		if rel != nil && rel(msg) >= retainRelevance {
+			continue
+		}
+		before := models.CountTokens(model, msg.Content)
+		after := models.CountTokens(model, prunedContent(msg))
+		plan.Prunes = append(plan.Prunes, Prune{
+			Index:        i,
+			Role:         msg.Role,
+			Name:         msg.Name,
+			TokensBefore: before,
+			TokensAfter:  after,
+		})
+		plan.TokensAfter += after - before
+	}
+	return plan
+}
+
+// Compact prunes tool outputs in messages until about excess tokens are
+// freed, so a request that overflows the context window fits. Outputs
+// larger than maxToolOutput outside the last keepRecent messages go first;
+// if that is not enough, recent outputs are pruned too. Within each pass
+// the outputs rel rates least relevant go first, then the oldest; with a
+// nil rel, oldest first. Messages other than tool outputs, and outputs a
+// later result refers to, are never changed.
+func Compact(model string, messages []models.Message, excess, keepRecent, maxToolOutput int, rel relevanceFunc) ([]models.Message, Plan) {
+	plan := Plan{TokensBefore: CountTokens(model, messages)}
+	plan.TokensAfter = plan.TokensBefore
+
+	referenced := referencedOutputs(messages)
+	pruned := make(map[int]bool)
+	passes := []struct{ keepRecent, minSize int }{
+		{keepRecent, maxToolOutput},
+		{0, prunedHead},
+	}
+	for _, pass := range passes {
+		for _, i := range pruneOrder(messages, len(messages)-pass.keepRecent, rel) {
+			if plan.Saved() >= excess {
+				break
+			}
+			msg := messages[i]
+			if msg.Role != "tool" || pruned[i] || referenced[i] || len(msg.Content) <= pass.minSize {
+				continue
+			}
+			before := models.CountTokens(model, msg.Content)
+			after := models.CountTokens(model, prunedContent(msg))
+			if after >= before {
+				continue
+			}
+			pruned[i] = true
+			plan.Prunes = append(plan.Prunes, Prune{
+				Index:        i,
+				Role:         msg.Role,
+				Name:         msg.Name,
+				TokensBefore: before,
+				TokensAfter:  after,
+			})
+			plan.TokensAfter += after - before
+		}
+	}
+	if plan.Empty() {
+		return messages, plan
+	}
+	return applyPlan(messages, plan), plan
+}
+
+// pruneOrder returns the indexes of the first n messages in the order they
+// are pruned: least relevant first, oldest first among equals.
+func pruneOrder(messages []models.Message, n int, rel relevanceFunc) []int {
+	order := make([]int, 0, max(n, 0))
+	scores := make([]float64, max(n, 0))
+	for i := 0; i < n; i++ {
+		order = append(order, i)
+		if rel != nil && messages[i].Role == "tool" {
+			scores[i] = rel(messages[i])
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] < scores[order[b]]
+	})
+	return order
+}
+
+// applyPlan returns a copy of messages with the plan's prunes applied.
+func applyPlan(messages []models.Message, plan Plan) []models.Message {
+	out := make([]models.Message, len(messages))
+	copy(out, messages)
+	for _, p := range plan.Prunes {
+		if p.Index < len(out) {
+			out[p.Index].Content = prunedContent(out[p.Index])
+		}
+	}
+	return out
+}
+
+// prunedContent keeps the start of a tool output and notes what was dropped.
+// Output fenced by Guard keeps its fence, so what is left of it is still
+// marked untrusted.
+func prunedContent(msg models.Message) string {
+	if open, inner, close, ok := splitGuard(msg.Content); ok {
+		return open + prunedText(inner, msg.Name) + close
+	}
+	return prunedText(msg.Content, msg.Name)
+}
+
+func prunedText(content, tool string) string {
+	head := content
+	if len(head) > prunedHead {
+		head = head[:prunedHead]
+	}
+	return fmt.Sprintf("%s\n[pruned %d bytes of %s output]", head, len(content)-len(head), tool)
+}
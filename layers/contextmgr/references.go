@@ -0,0 +1,59 @@
+package contextmgr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// UnchangedOutput is the content of a tool result replaced by a reference
+// to an identical earlier result: the one at position n of the messages,
+// answering call callID of tool, size bytes long. The results such
+// references point to are never pruned, so the model can still refer to
+// them.
+func UnchangedOutput(n int, callID, tool string, size int) string {
+	ref := fmt.Sprintf("message #%d", n)
+	if callID != "" {
+		ref += fmt.Sprintf(" (tool call %s)", callID)
+	}
+	return fmt.Sprintf("Output unchanged since %s: %s returned the same %d bytes. Refer to that result instead of running it again.",
+		ref, tool, size)
+}
+
+// unchangedOutput matches the content UnchangedOutput returns.
+var unchangedOutput = regexp.MustCompile(`^Output unchanged since message #\d+(?: \(tool call (\S+)\))?: (\S+) returned the same (\d+) bytes\. `)
+
+// referencedOutputs returns the positions of the tool results in messages
+// that a later UnchangedOutput refers to. Positions shift as histories are
+// trimmed and requests assembled, so a reference is matched to the latest
+// earlier result of the same tool and call with the size it gives.
+func referencedOutputs(messages []models.Message) map[int]bool {
+	var referenced map[int]bool
+	for i, msg := range messages {
+		if msg.Role != "tool" {
+			continue
+		}
+		m := unchangedOutput.FindStringSubmatch(msg.Content)
+		if m == nil {
+			continue
+		}
+		callID, tool := m[1], m[2]
+		size, err := strconv.Atoi(m[3])
+		if err != nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			prior := messages[j]
+			if prior.Role == "tool" && prior.Name == tool && prior.ToolCallID == callID && len(prior.Content) == size {
+				if referenced == nil {
+					referenced = make(map[int]bool)
+				}
+				referenced[j] = true
+				break
+			}
+		}
+	}
+	return referenced
+}
@@ -0,0 +1,82 @@
+package contextmgr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferencedOutputs(t *testing.T) {
+	output := strings.Repeat("x", 3000)
+	result := func(tool, callID, content string) models.Message {
+		return models.Message{Role: "tool", Name: tool, ToolCallID: callID, Content: content}
+	}
+
+	tests := []struct {
+		name     string
+		messages []models.Message
+		want     map[int]bool
+	}{
+		{
+			name: "reference to an earlier result",
+			messages: []models.Message{
+				result("core.read", "call_1_1", output),
+				result("core.read", "call_2_1", UnchangedOutput(1, "call_1_1", "core.read", len(output))),
+			},
+			want: map[int]bool{0: true},
+		},
+		{
+			name: "call IDs repeat, so the latest match is the one",
+			messages: []models.Message{
+				result("core.read", "call_1_1", output),
+				result("core.read", "call_1_1", output),
+				result("core.read", "call_2_1", UnchangedOutput(2, "call_1_1", "core.read", len(output))),
+			},
+			want: map[int]bool{1: true},
+		},
+		{
+			name: "result without a call ID",
+			messages: []models.Message{
+				result("core.bash", "", output),
+				result("core.bash", "", UnchangedOutput(1, "", "core.bash", len(output))),
+			},
+			want: map[int]bool{0: true},
+		},
+		{
+			name: "other tool, call or size",
+			messages: []models.Message{
+				result("core.bash", "call_1_1", output),
+				result("core.read", "call_1_2", output),
+				result("core.read", "call_1_1", output[:100]),
+				result("core.read", "call_2_1", UnchangedOutput(1, "call_1_1", "core.read", len(output))),
+			},
+		},
+		{
+			name: "references are only to earlier results",
+			messages: []models.Message{
+				result("core.read", "call_2_1", UnchangedOutput(2, "call_1_1", "core.read", len(output))),
+				result("core.read", "call_1_1", output),
+			},
+		},
+		{
+			name: "text quoting a reference outside a tool result",
+			messages: []models.Message{
+				result("core.read", "call_1_1", output),
+				{Role: "user", Content: UnchangedOutput(1, "call_1_1", "core.read", len(output))},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := referencedOutputs(tt.messages)
+			if tt.want == nil {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
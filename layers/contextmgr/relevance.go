@@ -0,0 +1,228 @@
+package contextmgr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// Relevance scoring defaults
+const (
+	// retainRelevance is the relevance to the current intent from which an
+	// oversized tool output survives routine pruning; it is only pruned when
+	// a request would overflow the context window.
+	retainRelevance = 0.7
+
+	// minTaskTerms is how many terms a user message needs to start a new
+	// task; shorter ones ("yes", "go on") continue the current one.
+	minTaskTerms = 3
+
+	// taskOverlap is the share of a message's terms the intent must have for
+	// the message to continue the same task.
+	taskOverlap = 0.3
+
+	// maxIntentLen bounds the intent as follow-ups are added to it; the
+	// latest part is kept.
+	maxIntentLen = 2000
+
+	// minScoredLen is the size in bytes from which tool outputs are rated by
+	// the scorer; smaller ones are never pruned, so term overlap will do.
+	minScoredLen = prunedHead
+
+	// ratingExcerpt is how much of each item a rating prompt shows.
+	ratingExcerpt = 400
+
+	// maxRescored is how many tool outputs one Rescore rates, newest first;
+	// older ones keep their term overlap until a later call.
+	maxRescored = 60
+
+	// maxCachedScores is how many ratings are kept before the cache starts
+	// over.
+	maxCachedScores = 1024
+)
+
+// Scorer rates how relevant context items are to what the user is doing
+// now, from 0 (unrelated) to 1 (needed for the task).
+type Scorer interface {
+	Score(ctx context.Context, intent string, contents []string) ([]float64, error)
+}
+
+// ScorerFunc adapts a function to Scorer.
+type ScorerFunc func(ctx context.Context, intent string, contents []string) ([]float64, error)
+
+// Score calls f.
+func (f ScorerFunc) Score(ctx context.Context, intent string, contents []string) ([]float64, error) {
+	return f(ctx, intent, contents)
+}
+
+// SetScorer sets how tool outputs are rated against the user's intent.
+// Without one, or until it has rated an output, relevance is the term
+// overlap of the output with the intent.
+func (m *Manager) SetScorer(s Scorer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scorer = s
+}
+
+// relevanceFunc returns the relevance of a message to the current intent.
+type relevanceFunc func(msg models.Message) float64
+
+// SetIntent records the user message a turn starts with. A message that
+// shares too little with the current intent starts a new task: it becomes
+// the intent, and every rating is dropped so Rescore rates the context
+// against it. Follow-ups are added to the intent instead. It reports
+// whether the task changed.
+func (m *Manager) SetIntent(message string) bool {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.intent != "" && sameTask(m.intent, message) {
+		m.intent = message + "\n" + m.intent
+		if len(m.intent) > maxIntentLen {
+			m.intent = m.intent[:maxIntentLen]
+		}
+		return false
+	}
+	m.intent = message
+	m.scores = nil
+	return true
+}
+
+// Intent returns what relevance is scored against: the user messages of
+// the current task, latest first.
+func (m *Manager) Intent() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.intent
+}
+
+// Rescore rates the tool outputs not yet rated against the current intent
+// with the scorer, newest first. It does nothing without a scorer or an intent. Ratings
+// made for an intent that has since changed are dropped.
+func (m *Manager) Rescore(ctx context.Context) error {
+	m.mu.Lock()
+	scorer, intent := m.scorer, m.intent
+	var contents []string
+	var keys []uint64
+	seen := make(map[uint64]bool)
+	for i := len(m.history) - 1; i >= 0 && len(contents) < maxRescored; i-- {
+		msg := m.history[i]
+		if msg.Role != "tool" || len(msg.Content) <= minScoredLen {
+			continue
+		}
+		key := contentKey(msg.Content)
+		if _, ok := m.scores[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		contents = append(contents, msg.Content)
+		keys = append(keys, key)
+	}
+	m.mu.Unlock()
+
+	if scorer == nil || intent == "" || len(contents) == 0 {
+		return nil
+	}
+	scores, err := scorer.Score(ctx, intent, contents)
+	if err != nil {
+		return err
+	}
+	if len(scores) != len(contents) {
+		return fmt.Errorf("scorer rated %d of %d items", len(scores), len(contents))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.intent != intent {
+		return nil
+	}
+	if m.scores == nil || len(m.scores)+len(keys) > maxCachedScores {
+		m.scores = make(map[uint64]float64)
+	}
+	for i, key := range keys {
+		m.scores[key] = min(max(scores[i], 0), 1)
+	}
+	return nil
+}
+
+// relevanceOf returns how messages are scored now: the scorer's rating if
+// it has rated them, else term overlap with the intent, or with the latest
+// user message before there is one. It must be called with mu held.
+func (m *Manager) relevanceOf(history []models.Message) relevanceFunc {
+	query := terms(m.intent)
+	if m.intent == "" {
+		query = queryTerms(history)
+	}
+	scores := m.scores
+	return func(msg models.Message) float64 {
+		if score, ok := scores[contentKey(msg.Content)]; ok {
+			return score
+		}
+		return relevance(query, msg.Content)
+	}
+}
+
+// sameTask reports whether message continues the task of intent: it is
+// too short to name a task of its own, or shares enough of its terms.
+func sameTask(intent, message string) bool {
+	words := terms(message)
+	if len(words) < minTaskTerms {
+		return true
+	}
+	return relevance(words, intent) >= taskOverlap
+}
+
+// contentKey identifies a message's content in the rating cache.
+func contentKey(content string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(content))
+	return h.Sum64()
+}
+
+// RatingPrompt asks a model to rate each of contents, 0 to 10, by how much
+// the user's current task needs it. Each item is shown in part.
+func RatingPrompt(intent string, contents []string) string {
+	var b strings.Builder
+	b.WriteString("You decide which parts of a coding assistant's context are worth keeping. ")
+	b.WriteString("Rate each numbered item from 0 (unrelated to the user's current task) to 10 (needed to do it). ")
+	fmt.Fprintf(&b, "Reply with only a JSON array of %d integers, one per item, in order.\n\n", len(contents))
+	b.WriteString("Current task, latest message first:\n")
+	b.WriteString(intent)
+	b.WriteString("\n")
+	for i, content := range contents {
+		excerpt := content
+		if len(excerpt) > ratingExcerpt {
+			excerpt = excerpt[:ratingExcerpt] + "…"
+		}
+		fmt.Fprintf(&b, "\nItem %d:\n%s\n", i+1, excerpt)
+	}
+	return b.String()
+}
+
+// ParseRatings reads the n ratings of a reply to RatingPrompt as relevances
+// from 0 to 1.
+func ParseRatings(reply string, n int) ([]float64, error) {
+	start, end := strings.IndexByte(reply, '['), strings.LastIndexByte(reply, ']')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no ratings in reply")
+	}
+	var ratings []float64
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &ratings); err != nil {
+		return nil, fmt.Errorf("invalid ratings: %w", err)
+	}
+	if len(ratings) != n {
+		return nil, fmt.Errorf("got %d ratings for %d items", len(ratings), n)
+	}
+	for i, r := range ratings {
+		ratings[i] = min(max(r, 0), 10) / 10
+	}
+	return ratings, nil
+}
@@ -0,0 +1,220 @@
+package contextmgr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRatings(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		n       int
+		want    []float64
+		wantErr string
+	}{
+		{name: "bare array", reply: "[10, 0, 5]", n: 3, want: []float64{1, 0, 0.5}},
+		{name: "prose around the array", reply: "Here are the ratings:\n```json\n[7, 3]\n```", n: 2, want: []float64{0.7, 0.3}},
+		{name: "out of range ratings are clamped", reply: "[-4, 15]", n: 2, want: []float64{0, 1}},
+		{name: "short reply", reply: "[8, 2]", n: 3, wantErr: "got 2 ratings for 3 items"},
+		{name: "long reply", reply: "[8, 2, 1]", n: 2, wantErr: "got 3 ratings for 2 items"},
+		{name: "no array", reply: "All of them matter.", n: 2, wantErr: "no ratings in reply"},
+		{name: "brackets reversed", reply: "] then [", n: 1, wantErr: "no ratings in reply"},
+		{name: "unterminated array", reply: "[4, 5", n: 2, wantErr: "no ratings in reply"},
+		{name: "non-numeric rating", reply: `[4, "high"]`, n: 2, wantErr: "invalid ratings"},
+		{name: "two arrays", reply: "[1, 2] or maybe [3]", n: 2, wantErr: "invalid ratings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRatings(tt.reply, tt.n)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDeltaSlice(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestRatingPrompt(t *testing.T) {
+	long := strings.Repeat("x", ratingExcerpt+50)
+	prompt := RatingPrompt("fix the login handler", []string{"short output", long})
+
+	assert.Contains(t, prompt, "JSON array of 2 integers")
+	assert.Contains(t, prompt, "fix the login handler")
+	assert.Contains(t, prompt, "Item 1:\nshort output\n")
+	assert.Contains(t, prompt, "Item 2:\n"+long[:ratingExcerpt]+"…\n")
+	assert.NotContains(t, prompt, long)
+}
+
+func TestSameTask(t *testing.T) {
+	intent := "fix the login handler in the auth package"
+
+	tests := []struct {
+		name    string
+		message string
+		same    bool
+	}{
+		{"too short to name a task", "go on", true},
+		{"fewer terms than a task needs", "yes do that", true},
+		{"follow-up on the same code", "now update the login handler tests", true},
+		{"overlap at the threshold", "login handler auth alpha bravo charlie delta echo foxtrot golf", true},
+		{"overlap below the threshold", "login handler alpha bravo charlie delta echo foxtrot golf hotel", false},
+		{"unrelated task", "write release notes for version two", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.same, sameTask(intent, tt.message))
+		})
+	}
+}
+
+func TestManager_SetIntent(t *testing.T) {
+	m := NewManager(0)
+	assert.False(t, m.SetIntent("  "))
+	assert.Empty(t, m.Intent())
+
+	assert.True(t, m.SetIntent("fix the login handler in the auth package"))
+
+	// Follow-ups are added to the task, latest first, and keep the ratings
+	m.scores = map[uint64]float64{1: 0.9}
+	assert.False(t, m.SetIntent("go on"))
+	assert.Equal(t, "go on\nfix the login handler in the auth package", m.Intent())
+	assert.Len(t, m.scores, 1)
+
+	// A new task replaces the intent and drops the ratings
+	assert.True(t, m.SetIntent("write release notes for version two"))
+	assert.Equal(t, "write release notes for version two", m.Intent())
+	assert.Nil(t, m.scores)
+
+	// The intent is bounded, keeping the latest messages
+	for i := 0; i < 200; i++ {
+		m.SetIntent("release notes for version two, part " + fmt.Sprint(i))
+	}
+	assert.Len(t, m.Intent(), maxIntentLen)
+	assert.True(t, strings.HasPrefix(m.Intent(), "release notes for version two, part 199\n"))
+}
+
+// toolOutput is a tool output large enough to be rated.
+func toolOutput(i int) models.Message {
+	return models.Message{Role: "tool", Name: "core.read", Content: fmt.Sprintf("output %d: %s", i, strings.Repeat("data ", minScoredLen/5+1))}
+}
+
+// recordingScorer rates everything score and remembers what it was asked.
+type recordingScorer struct {
+	score float64
+	calls [][]string
+	then  func() // Run after rating, as if the user moved on meanwhile
+}
+
+func (s *recordingScorer) Score(ctx context.Context, intent string, contents []string) ([]float64, error) {
+	s.calls = append(s.calls, contents)
+	if s.then != nil {
+		s.then()
+	}
+	scores := make([]float64, len(contents))
+	for i := range scores {
+		scores[i] = s.score
+	}
+	return scores, nil
+}
+
+func TestManager_Rescore(t *testing.T) {
+	m := NewManager(0)
+	scorer := &recordingScorer{score: 0.9}
+	m.SetScorer(scorer)
+
+	history := []models.Message{{Role: "user", Content: "read every file"}}
+	for i := 0; i < maxRescored+10; i++ {
+		history = append(history, toolOutput(i))
+	}
+	// Small outputs and repeats aren't rated
+	history = append(history, models.Message{Role: "tool", Content: "ok"}, toolOutput(maxRescored+9))
+	m.Reset(history...)
+
+	// Nothing is rated without an intent
+	require.NoError(t, m.Rescore(context.Background()))
+	assert.Empty(t, scorer.calls)
+
+	m.SetIntent("read every file in the project")
+	require.NoError(t, m.Rescore(context.Background()))
+	require.Len(t, scorer.calls, 1)
+	require.Len(t, scorer.calls[0], maxRescored, "one call rates at most maxRescored outputs")
+	assert.Equal(t, toolOutput(maxRescored+9).Content, scorer.calls[0][0], "newest first")
+	assert.Equal(t, toolOutput(10).Content, scorer.calls[0][maxRescored-1])
+
+	// A later call rates the rest
+	require.NoError(t, m.Rescore(context.Background()))
+	require.Len(t, scorer.calls, 2)
+	assert.Len(t, scorer.calls[1], 10)
+	assert.Equal(t, toolOutput(9).Content, scorer.calls[1][0])
+
+	require.NoError(t, m.Rescore(context.Background()))
+	assert.Len(t, scorer.calls, 2, "everything is rated")
+
+	for _, item := range m.Items() {
+		if len(history[item.Index].Content) > minScoredLen {
+			assert.Equal(t, 0.9, item.Relevance, "item %d", item.Index)
+		}
+	}
+}
+
+func TestManager_RescoreClampsAndChecks(t *testing.T) {
+	m := NewManager(0)
+	m.Reset(toolOutput(1))
+	m.SetIntent("read every file in the project")
+
+	m.SetScorer(&recordingScorer{score: 4})
+	require.NoError(t, m.Rescore(context.Background()))
+	assert.Equal(t, 1.0, m.Items()[0].Relevance)
+
+	m.SetIntent("write release notes for version two")
+	m.SetScorer(ScorerFunc(func(ctx context.Context, intent string, contents []string) ([]float64, error) {
+		return []float64{}, nil
+	}))
+	assert.EqualError(t, m.Rescore(context.Background()), "scorer rated 0 of 1 items")
+}
+
+func TestManager_RescoreDropsStaleRatings(t *testing.T) {
+	m := NewManager(0)
+	m.Reset(toolOutput(1))
+	m.SetIntent("read every file in the project")
+	m.SetScorer(&recordingScorer{score: 0.9, then: func() {
+		m.SetIntent("write release notes for version two")
+	}})
+
+	require.NoError(t, m.Rescore(context.Background()))
+	assert.Empty(t, m.scores, "ratings for the old task are dropped")
+}
+
+func TestManager_RelevanceOf(t *testing.T) {
+	m := NewManager(0)
+	history := []models.Message{
+		{Role: "user", Content: "where is the login handler"},
+		{Role: "tool", Content: "the login handler is in auth.go"},
+	}
+	m.Reset(history...)
+
+	// Before an intent, the latest user message is the query
+	rel := m.relevanceOf(history)
+	assert.InDelta(t, 0.75, rel(history[1]), 1e-9) // where, the, login, handler: 3 of 4
+
+	m.SetIntent("release notes")
+	rel = m.relevanceOf(history)
+	assert.Equal(t, 0.0, rel(history[1]))
+
+	// Ratings take precedence over overlap
+	m.scores = map[uint64]float64{contentKey(history[1].Content): 0.8}
+	rel = m.relevanceOf(history)
+	assert.Equal(t, 0.8, rel(history[1]))
+}
@@ -0,0 +1,76 @@
+package contextmgr
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// Tier classifies a message by how the optimizer treats it.
+type Tier string
+
+// Tiers
+const (
+	TierRecent   Tier = "recent"   // Inside the keep-recent window, never pruned
+	TierPrunable Tier = "prunable" // Pruned by the next optimization
+	TierRetained Tier = "retained" // Older, but below the pruning threshold, relevant to the current task or referred to by a later result
+)
+
+// tierMessages returns the tier of each message under the given thresholds
+// and relevance.
+func tierMessages(model string, messages []models.Message, keepRecent, maxToolOutput int, rel relevanceFunc) []Tier {
+	tiers := make([]Tier, len(messages))
+	for i := range tiers {
+		if i >= len(messages)-keepRecent {
+			tiers[i] = TierRecent
+		} else {
+			tiers[i] = TierRetained
+		}
+	}
+	for _, p := range planPrunes(model, messages, keepRecent, maxToolOutput, rel).Prunes {
+		tiers[p.Index] = TierPrunable
+	}
+	return tiers
+}
+
+// minTermLen is the shortest word counted as a relevance term.
+const minTermLen = 3
+
+// queryTerms returns the terms of the latest user message.
+func queryTerms(messages []models.Message) map[string]bool {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return terms(messages[i].Content)
+		}
+	}
+	return nil
+}
+
+// relevance returns the share of query terms that appear in content.
+func relevance(query map[string]bool, content string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	found := 0
+	for term := range terms(content) {
+		if query[term] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(query))
+}
+
+// terms returns the distinct lowercased words of text.
+func terms(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		if len(w) >= minTermLen {
+			set[w] = true
+		}
+	}
+	return set
+}
@@ -5,10 +5,14 @@ package execution
 import (
 	"context"
 	"fmt"
+	"maps"
+	"sync"
 	"time"
 
 	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/events"
 	"github.com/abrksh22/bplus/internal/logging"
+	"github.com/abrksh22/bplus/layers/contextmgr"
 	"github.com/abrksh22/bplus/models"
 	"github.com/abrksh22/bplus/security"
 	"github.com/abrksh22/bplus/tools"
@@ -22,16 +26,45 @@ type Agent struct {
 	permMgr     *security.PermissionManager
 	logger      *logging.Logger
 	costTracker *CostTracker
+	events      *events.Bus
+	roots       *security.Roots
+	drafter     models.Provider // Serves config.DraftModel; nil if provider does
+	afterTool   AfterToolHook   // Nil unless results are checked after each call
 }
 
+// AfterToolHook runs after a tool call succeeds, such as to check the files
+// it edited, and returns a note for the model appended to the call's
+// output, or "".
+type AfterToolHook func(ctx context.Context, execution ToolExecution) string
+
+// layerNumber is the position of the main agent in the 7-layer architecture.
+const layerNumber = 4
+
 // AgentConfig holds configuration for the agent.
 type AgentConfig struct {
-	ModelName     string  // Model to use (e.g., "anthropic/claude-sonnet-4-5")
-	SystemPrompt  string  // System prompt for the agent
-	MaxIterations int     // Maximum agent loop iterations
-	Temperature   float64 // Temperature for generation
-	MaxTokens     int     // Maximum tokens per generation
-	Streaming     bool    // Enable streaming responses
+	ModelName     string // Model to use (e.g., "anthropic/claude-sonnet-4-5")
+	SystemPrompt  string // System prompt for the agent
+	MaxIterations int    // Maximum agent loop iterations
+	MaxTokens     int    // Maximum tokens per generation
+	Streaming     bool   // Enable streaming responses
+
+	// Sampling parameters of the layer the agent runs; unset ones use the
+	// provider default
+	Sampling models.Sampling
+
+	// Tokens the model may spend reasoning before each answer (0 = provider default)
+	ReasoningBudget int
+
+	// Times an answer cut off at the token limit is resumed (0 = default, -1 = never)
+	MaxContinuations int
+
+	// Tool calls from one turn run at once (0 or 1 = one at a time). Above
+	// 1 the model is also asked for parallel tool calls.
+	MaxParallelTools int
+
+	// Cheap model streaming a draft answer to questions while ModelName
+	// works on the real one, which replaces it (empty = no drafts)
+	DraftModel string
 }
 
 // NewAgent creates a new agent with the given configuration.
@@ -59,6 +92,66 @@ func NewAgent(provider models.Provider, config *AgentConfig, toolReg *tools.Regi
 	}, nil
 }
 
+// NewSubAgent creates an agent for a delegated task, sharing the
+// provider, tools, permissions and event bus. It may spend up to budget,
+// which is taken out of this agent's budget (limits that are zero get
+// whatever is left), and its usage counts towards this agent's totals. A nil
+// config uses this agent's. Call Release when the sub-agent is done to
+// return its unspent budget.
+func (a *Agent) NewSubAgent(config *AgentConfig, budget Budget) (*Agent, error) {
+	if config == nil {
+		c := *a.config
+		config = &c
+	}
+	if config.DraftModel != "" {
+		// Nobody watches a sub-agent's answer come in
+		c := *config
+		c.DraftModel = ""
+		config = &c
+	}
+
+	tracker, err := a.costTracker.Allocate(budget)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeBudgetExceeded, "cannot allocate sub-agent budget")
+	}
+
+	return &Agent{
+		provider:    a.provider,
+		config:      config,
+		toolReg:     a.toolReg,
+		permMgr:     a.permMgr,
+		logger:      a.logger.WithComponent("subagent"),
+		costTracker: tracker,
+		events:      a.events,
+		roots:       a.roots,
+		afterTool:   a.afterTool,
+	}, nil
+}
+
+// Release returns a sub-agent's unspent budget to its parent.
+func (a *Agent) Release() {
+	a.costTracker.Release()
+}
+
+// SetEventBus sets the bus the agent publishes tool, permission, cost and
+// layer events on. A nil bus disables publishing.
+func (a *Agent) SetEventBus(bus *events.Bus) {
+	a.events = bus
+}
+
+// SetAfterToolHook sets the hook run after each successful tool call. A
+// nil hook disables it.
+func (a *Agent) SetAfterToolHook(hook AfterToolHook) {
+	a.afterTool = hook
+}
+
+// SetRoots sets the directories attached to the session. Path arguments
+// of tool calls may then name a root ("infra:main.tf") and are resolved
+// against it, and the model is told which roots it can work in.
+func (a *Agent) SetRoots(roots *security.Roots) {
+	a.roots = roots
+}
+
 // AgentRequest represents a request to the agent.
 type AgentRequest struct {
 	// User's message
@@ -70,8 +163,15 @@ type AgentRequest struct {
 	// Session ID for context
 	SessionID string
 
+	// End user the turn is run for, forwarded to providers for attribution
+	// (optional)
+	User string
+
 	// Context from Layer 6 (optional)
 	Context string
+
+	// Images attached to the user's message (optional)
+	Attachments []models.Attachment
 }
 
 // AgentResponse represents the agent's response.
@@ -91,8 +191,15 @@ type AgentResponse struct {
 	// Whether the task is complete
 	Complete bool
 
+	// Whether the answer still ends at the token limit, after any continuations
+	Truncated bool
+
 	// Any errors encountered
 	Error error
+
+	// Messages added to the conversation during this request: the user
+	// message, assistant turns and tool results
+	Messages []models.Message
 }
 
 // ToolExecution represents a single tool execution in the agent loop.
@@ -112,85 +219,128 @@ func (a *Agent) Execute(ctx context.Context, req *AgentRequest) (*AgentResponse,
 
 	a.logger.Info("Starting agent execution", "session_id", req.SessionID, "model", a.config.ModelName)
 
+	a.events.Publish(events.LayerChanged{To: layerNumber, Name: "main_agent", Time: time.Now()})
+
 	response := &AgentResponse{
 		ToolCalls: make([]ToolExecution, 0),
 	}
 
 	// Build conversation messages
 	messages := append(req.History, models.Message{
-		Role:    "user",
-		Content: req.UserMessage,
+		Role:        "user",
+		Content:     req.UserMessage,
+		Attachments: req.Attachments,
 	})
 
 	// Get available tools
 	availableTools := a.getAvailableTools()
 
+	// Context from other layers extends the system prompt
+	system := a.config.SystemPrompt
+	if roots := a.roots.Describe(); roots != "" {
+		system += "\n\n" + roots
+	}
+	if req.Context != "" {
+		system += "\n\n" + req.Context
+	}
+
+	// Once low-trust content is in the context, tool calls may have been
+	// steered by it and get stricter permission handling
+	untrusted := a.untrustedHistory(req.History)
+
+	// Fail before the first call if the model is known not to support the turn
+	if err := models.CheckCapabilities(a.config.ModelName, a.requirements(messages, availableTools)); err != nil {
+		a.logger.Error("Model cannot run this request", err, "model", a.config.ModelName)
+		return nil, errors.Wrap(err, errors.ErrCodeValidation, "model cannot run this request")
+	}
+
+	// Stream a draft to the user while the strong model works on the answer
+	d := a.startDraft(ctx, req, messages, system)
+	defer func() { a.endDraft(d, response, false) }()
+
 	// Agent loop
 	for iteration := 0; iteration < a.config.MaxIterations; iteration++ {
 		response.Iterations = iteration + 1
 
 		a.logger.Debug("Agent iteration", "iteration", iteration+1, "max", a.config.MaxIterations)
 
+		if !a.costTracker.WithinSessionBudget() {
+			a.logger.Warn("Agent stopped at its budget", "budget", a.costTracker.SessionBudget())
+			response.Messages = turnMessages(messages, len(req.History), "")
+			return response, errors.New(errors.ErrCodeBudgetExceeded, "agent budget exhausted")
+		}
+
 		// Call LLM
 		completionReq := &models.CompletionRequest{
 			Model:     a.config.ModelName,
 			Messages:  messages,
-			System:    a.config.SystemPrompt,
+			System:    system,
 			Tools:     availableTools,
 			MaxTokens: a.config.MaxTokens,
-		}
 
-		if a.config.Temperature > 0 {
-			completionReq.Temperature = &a.config.Temperature
+			ParallelToolCalls: a.config.MaxParallelTools > 1,
+			ReasoningBudget:   a.config.ReasoningBudget,
+			Metadata:          requestMetadata(req),
 		}
 
-		var completionResp *models.CompletionResponse
-		var err error
-
-		if a.config.Streaming && a.provider.SupportsStreaming() {
-			completionResp, err = a.executeStreaming(ctx, completionReq)
-		} else {
-			completionResp, err = a.provider.CreateCompletion(ctx, completionReq)
-		}
+		a.config.Sampling.Apply(completionReq)
 
+		completionResp, err := a.complete(ctx, completionReq)
 		if err != nil {
 			a.logger.Error("LLM call failed", err, "iteration", iteration+1)
 			return nil, errors.Wrap(err, errors.ErrCodeProvider, "LLM call failed")
 		}
-
-		// Track usage and cost
-		a.costTracker.AddUsage(completionResp.Usage)
-		response.Usage.InputTokens += completionResp.Usage.InputTokens
-		response.Usage.OutputTokens += completionResp.Usage.OutputTokens
-		response.Usage.TotalTokens += completionResp.Usage.TotalTokens
-		response.Usage.Cost += completionResp.Usage.Cost
+		a.recordUsage(response, completionResp.Usage)
+
+		// Resume answers cut off by the token limit instead of returning them truncated
+		if isTruncated(completionResp) {
+			completionResp, err = a.continueTruncated(ctx, completionReq, completionResp, response)
+			if err != nil {
+				a.logger.Error("LLM continuation failed", err, "iteration", iteration+1)
+				return nil, errors.Wrap(err, errors.ErrCodeProvider, "LLM continuation failed")
+			}
+		}
+		response.Truncated = isTruncated(completionResp)
+		if response.Truncated {
+			a.events.Publish(events.AnswerTruncated{Model: a.config.ModelName, Continuations: a.maxContinuations(), Time: time.Now()})
+		}
 
 		// Check stop reason
 		if completionResp.StopReason == "end_turn" || completionResp.StopReason == "stop_sequence" {
 			// Task complete
+			a.endDraft(d, response, true)
+			d = nil
 			response.Content = completionResp.Content
 			response.Complete = true
+			response.Messages = turnMessages(messages, len(req.History), completionResp.Content)
 			a.logger.Info("Agent execution complete", "iterations", iteration+1, "cost", response.Usage.Cost)
 			return response, nil
 		}
 
 		if completionResp.StopReason == "tool_use" && len(completionResp.ToolCalls) > 0 {
+			// The answer needs tools, which the draft couldn't use
+			a.endDraft(d, response, false)
+			d = nil
+
 			// Execute tool calls
 			toolResults := make([]models.Message, 0, len(completionResp.ToolCalls))
 
-			for _, toolCall := range completionResp.ToolCalls {
-				execution := ToolExecution{
-					ToolName:  toolCall.Name,
-					Arguments: toolCall.Arguments,
-					Timestamp: time.Now(),
+			// Results are matched to calls by ID; not every provider assigns one
+			for i := range completionResp.ToolCalls {
+				if completionResp.ToolCalls[i].ID == "" {
+					completionResp.ToolCalls[i].ID = fmt.Sprintf("call_%d_%d", iteration+1, i+1)
 				}
+			}
 
-				// Execute tool with permission check
-				result, err := a.executeTool(ctx, toolCall.Name, toolCall.Arguments)
-				execution.Result = result
-				execution.Permission = (err == nil) // Permission was granted if no error
+			// The calls were all chosen before any of their results were
+			// seen, so the whole batch runs with the trust of the context
+			// it was requested in
+			outcomes := a.runToolCalls(tools.WithSession(ctx, req.SessionID), completionResp.ToolCalls, untrusted)
 
-				response.ToolCalls = append(response.ToolCalls, execution)
+			for i, toolCall := range completionResp.ToolCalls {
+				outcome := outcomes[i]
+				result, err := outcome.execution.Result, outcome.err
+				response.ToolCalls = append(response.ToolCalls, outcome.execution)
 
 				// Format tool result as message
 				var resultContent string
@@ -201,21 +351,35 @@ func (a *Agent) Execute(ctx context.Context, req *AgentRequest) (*AgentResponse,
 				} else {
 					resultContent = fmt.Sprintf("Tool failed: %v", result.Error)
 				}
+				provenance := contextmgr.ToolProvenance(toolCall.Name, a.toolCategory(toolCall.Name))
+				resultContent = contextmgr.Guard(resultContent, provenance)
+				if provenance.Untrusted() {
+					untrusted = true
+				}
+				if err == nil && result.Success {
+					var deduped bool
+					if resultContent, deduped = dedupResult(messages, toolCall, resultContent); deduped {
+						a.logger.Debug("Replaced unchanged tool result with a reference", "tool", toolCall.Name)
+					}
+				}
 
 				toolResults = append(toolResults, models.Message{
-					Role:    "tool",
-					Content: resultContent,
-					Name:    toolCall.Name,
+					Role:       "tool",
+					Content:    resultContent,
+					Name:       toolCall.Name,
+					ToolCallID: toolCall.ID,
 				})
 			}
 
 			// Add assistant message with tool calls
-			if completionResp.Content != "" {
-				messages = append(messages, models.Message{
-					Role:    "assistant",
-					Content: completionResp.Content,
-				})
-			}
+			messages = append(messages, models.Message{
+				Role:      "assistant",
+				Content:   completionResp.Content,
+				ToolCalls: completionResp.ToolCalls,
+
+				Reasoning:          completionResp.Reasoning,
+				ReasoningSignature: completionResp.ReasoningSignature,
+			})
 
 			// Add tool results to conversation
 			messages = append(messages, toolResults...)
@@ -227,6 +391,7 @@ func (a *Agent) Execute(ctx context.Context, req *AgentRequest) (*AgentResponse,
 		// If we get here, something unexpected happened
 		response.Content = completionResp.Content
 		response.Complete = false
+		response.Messages = turnMessages(messages, len(req.History), completionResp.Content)
 		a.logger.Warn("Agent stopped with unexpected reason", "stop_reason", completionResp.StopReason)
 		return response, nil
 	}
@@ -234,11 +399,208 @@ func (a *Agent) Execute(ctx context.Context, req *AgentRequest) (*AgentResponse,
 	// Max iterations reached
 	a.logger.Warn("Agent reached max iterations", "max", a.config.MaxIterations)
 	response.Complete = false
+	response.Messages = turnMessages(messages, len(req.History), "")
 	return response, errors.New(errors.ErrCodeInternal, "agent reached maximum iterations without completing task")
 }
 
-// executeTool executes a single tool with permission checking.
-func (a *Agent) executeTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*tools.Result, error) {
+// requestMetadata returns the attribution metadata sent with the turn's
+// completions, or nil if the turn has none.
+func requestMetadata(req *AgentRequest) map[string]string {
+	if req.SessionID == "" && req.User == "" {
+		return nil
+	}
+	metadata := make(map[string]string, 2)
+	if req.SessionID != "" {
+		metadata[models.MetadataSession] = req.SessionID
+	}
+	if req.User != "" {
+		metadata[models.MetadataUser] = req.User
+	}
+	return metadata
+}
+
+// turnMessages returns the messages added after the history, plus the final
+// assistant answer if there is one.
+func turnMessages(messages []models.Message, historyLen int, answer string) []models.Message {
+	added := make([]models.Message, 0, len(messages)-historyLen+1)
+	added = append(added, messages[historyLen:]...)
+	if answer != "" {
+		added = append(added, models.Message{Role: "assistant", Content: answer})
+	}
+	return added
+}
+
+// complete makes a single LLM call, streaming when enabled and supported.
+func (a *Agent) complete(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	if a.config.Streaming && a.provider.SupportsStreaming() {
+		return a.executeStreaming(ctx, req)
+	}
+	return a.provider.CreateCompletion(ctx, req)
+}
+
+// recordUsage tracks the usage of one LLM call and publishes the new totals.
+func (a *Agent) recordUsage(response *AgentResponse, usage models.Usage) {
+	a.costTracker.AddUsage(usage)
+	if usage.CostEstimated && usage.GenerationID != "" {
+		a.reconcileCost(usage.GenerationID)
+	}
+	response.Usage.InputTokens += usage.InputTokens
+	response.Usage.OutputTokens += usage.OutputTokens
+	response.Usage.TotalTokens += usage.TotalTokens
+	response.Usage.Cost += usage.Cost
+
+	// Sub-agent usage is aggregated into the session's totals
+	totalIn, totalOut, totalCost := a.costTracker.root().GetTotals()
+	reasoningTokens, reasoningCost := a.costTracker.root().GetReasoningTotals()
+	a.events.Publish(events.CostUpdated{
+		Model:           a.config.ModelName,
+		InputTokens:     usage.InputTokens,
+		OutputTokens:    usage.OutputTokens,
+		Cost:            usage.Cost,
+		TotalCost:       totalCost,
+		TotalTokens:     totalIn + totalOut,
+		ReasoningTokens: reasoningTokens,
+		ReasoningCost:   reasoningCost,
+		Time:            time.Now(),
+	})
+}
+
+// toolOutcome is the execution of one tool call in a turn.
+type toolOutcome struct {
+	execution ToolExecution
+	err       error
+}
+
+// runToolCalls executes the tool calls returned in one turn and returns
+// their outcomes in call order. Consecutive calls that only read run
+// concurrently, up to MaxParallelTools at a time; a call that writes or
+// executes runs on its own once the calls before it are done, so changes
+// happen in the order the model asked for them.
+func (a *Agent) runToolCalls(ctx context.Context, calls []models.ToolCall, untrusted bool) []toolOutcome {
+	outcomes := make([]toolOutcome, len(calls))
+	limit := max(a.config.MaxParallelTools, 1)
+
+	for start := 0; start < len(calls); {
+		end := start + 1
+		if a.concurrentSafe(calls[start].Name) {
+			for end < len(calls) && a.concurrentSafe(calls[end].Name) {
+				end++
+			}
+		}
+
+		sem := make(chan struct{}, limit)
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				outcomes[i] = a.runToolCall(ctx, calls[i], untrusted)
+			}()
+		}
+		wg.Wait()
+		start = end
+	}
+	return outcomes
+}
+
+// progressInterval is the least time between progress events published
+// for the bytes of one file or the output of a command.
+const progressInterval = 100 * time.Millisecond
+
+// runToolCall executes one tool call, publishing its start and finish.
+func (a *Agent) runToolCall(ctx context.Context, call models.ToolCall, untrusted bool) toolOutcome {
+	execution := ToolExecution{
+		ToolName:  call.Name,
+		Arguments: call.Arguments,
+		Timestamp: time.Now(),
+	}
+
+	a.events.Publish(events.ToolStarted{
+		Tool:      call.Name,
+		Arguments: call.Arguments,
+		Time:      execution.Timestamp,
+	})
+
+	// Execute tool with permission check, publishing any progress it
+	// reports. Byte counts within a file and streamed output are throttled
+	// so they don't crowd other events out of subscribers' buffers.
+	var lastProgress time.Time
+	lastFiles := -1
+	ctx = tools.WithProgress(ctx, func(p tools.Progress) {
+		now := time.Now()
+		if (p.Output != "" || p.Files == lastFiles && p.Bytes < p.TotalBytes) && now.Sub(lastProgress) < progressInterval {
+			return
+		}
+		lastProgress, lastFiles = now, p.Files
+		a.events.Publish(events.ToolProgress{
+			Tool:       call.Name,
+			Path:       p.Path,
+			Bytes:      p.Bytes,
+			TotalBytes: p.TotalBytes,
+			Files:      p.Files,
+			TotalFiles: p.TotalFiles,
+			Output:     p.Output,
+			Time:       now,
+		})
+	})
+	result, err := a.executeTool(ctx, call.Name, call.Arguments, untrusted)
+	execution.Result = result
+	execution.Permission = (err == nil) // Permission was granted if no error
+	if err == nil && result != nil && result.Success && a.afterTool != nil {
+		if note := a.afterTool(ctx, execution); note != "" {
+			execution.Result = withNote(result, note)
+			result = execution.Result
+		}
+	}
+
+	a.events.Publish(toolFinishedEvent(call.Name, result, err, execution.Timestamp))
+
+	return toolOutcome{execution: execution, err: err}
+}
+
+// ExecuteTool runs one tool call made outside a conversation, such as by
+// an MCP client, with the same permission checks, events and after-tool
+// hook as the model's calls.
+func (a *Agent) ExecuteTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*tools.Result, error) {
+	outcome := a.runToolCall(ctx, models.ToolCall{Name: toolName, Arguments: arguments}, false)
+	return outcome.execution.Result, outcome.err
+}
+
+// withNote returns a copy of a result with a note appended to its output.
+// Structured output is left alone, so the note goes to the metadata.
+func withNote(result *tools.Result, note string) *tools.Result {
+	noted := *result
+	if output, ok := result.Output.(string); ok {
+		noted.Output = output + "\n\n" + note
+		return &noted
+	}
+	noted.Metadata = make(map[string]interface{}, len(result.Metadata)+1)
+	maps.Copy(noted.Metadata, result.Metadata)
+	noted.Metadata["note"] = note
+	return &noted
+}
+
+// concurrentSafe reports whether calls to a tool may run alongside other
+// calls: it only reads files or the network. Unknown tools fail without
+// side effects and count as safe.
+func (a *Agent) concurrentSafe(toolName string) bool {
+	tool, err := a.toolReg.Get(toolName)
+	if err != nil {
+		return true
+	}
+	switch determinePermission(tool) {
+	case security.PermissionRead, security.PermissionNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// executeTool executes a single tool with permission checking. untrusted
+// marks permission requests made with low-trust content in the context.
+func (a *Agent) executeTool(ctx context.Context, toolName string, arguments map[string]interface{}, untrusted bool) (*tools.Result, error) {
 	a.logger.Debug("Executing tool", "tool", toolName, "args", arguments)
 
 	// Get tool from registry
@@ -247,10 +609,30 @@ func (a *Agent) executeTool(ctx context.Context, toolName string, arguments map[
 		return nil, errors.Newf(errors.ErrCodeToolNotFound, "tool %s not found", toolName)
 	}
 
+	if !a.toolReg.IsEnabled(toolName) {
+		return nil, errors.Newf(errors.ErrCodeToolPermission, "tool %s is disabled for this session", toolName)
+	}
+
+	arguments, path, err := a.resolvePaths(arguments)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ErrCodeToolPermission, "invalid path for tool %s", toolName)
+	}
+
 	// Check permissions
 	if tool.RequiresPermission() {
 		permission := determinePermission(tool)
 		resource := determineResource(arguments)
+		if d, ok := tool.(tools.ResourceDescriber); ok {
+			resource = d.DescribeResource(arguments)
+		}
+		preview := ""
+		if p, ok := tool.(tools.Previewer); ok {
+			// A call that can't be previewed would fail, so it isn't put
+			// to the user
+			if preview, err = p.Preview(ctx, arguments); err != nil {
+				return &tools.Result{Success: false, Error: err}, nil
+			}
+		}
 
 		req := &security.PermissionRequest{
 			Permission: permission,
@@ -258,13 +640,43 @@ func (a *Agent) executeTool(ctx context.Context, toolName string, arguments map[
 			Operation:  fmt.Sprintf("execute %s", toolName),
 			Reason:     "Tool execution requested by agent",
 			ToolName:   toolName,
+			Untrusted:  untrusted,
+			Path:       path,
+			Preview:    preview,
+		}
+		if c, ok := tool.(tools.Confirmer); ok {
+			req.Confirm = c.RequiresConfirmation(arguments)
 		}
+		if c, ok := tool.(tools.SensitiveChecker); ok {
+			if reason := c.SensitiveReason(arguments); reason != "" {
+				req.Reason = reason
+				req.Risk = security.RiskHigh
+				req.Elevated = true
+			}
+		}
+
+		e := events.PermissionRequested{
+			Tool:       toolName,
+			Permission: string(permission),
+			Resource:   resource,
+			Preview:    preview,
+			Elevated:   req.Elevated,
+			Time:       time.Now(),
+		}
+		if req.Elevated {
+			e.Reason = req.Reason
+		}
+		a.events.Publish(e)
 
 		granted, err := a.permMgr.Check(ctx, req)
 		if err != nil {
 			return nil, errors.Wrapf(err, errors.ErrCodeToolPermission, "failed to request permission for tool %s", toolName)
 		}
 		if !granted {
+			// The model is told why, so it can explain rather than retry
+			if req.Elevated {
+				return nil, errors.Newf(errors.ErrCodeToolPermission, "permission denied for tool %s: %s", toolName, req.Reason)
+			}
 			return nil, errors.Newf(errors.ErrCodeToolPermission, "permission denied for tool %s", toolName)
 		}
 	}
@@ -279,6 +691,25 @@ func (a *Agent) executeTool(ctx context.Context, toolName string, arguments map[
 	return result, nil
 }
 
+// toolFinishedEvent builds the event published after a tool call.
+func toolFinishedEvent(toolName string, result *tools.Result, err error, started time.Time) events.ToolFinished {
+	e := events.ToolFinished{
+		Tool:     toolName,
+		Duration: time.Since(started),
+		Time:     time.Now(),
+	}
+	switch {
+	case err != nil:
+		e.Error = err.Error()
+	case result != nil:
+		e.Success = result.Success
+		if result.Error != nil {
+			e.Error = result.Error.Error()
+		}
+	}
+	return e
+}
+
 // executeStreaming handles streaming completions.
 func (a *Agent) executeStreaming(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
 	tokenChan, err := a.provider.StreamCompletion(ctx, req)
@@ -287,6 +718,8 @@ func (a *Agent) executeStreaming(ctx context.Context, req *models.CompletionRequ
 	}
 
 	var content string
+	var reasoning string
+	var signature string
 	var toolCalls []models.ToolCall
 	var stopReason string
 	var usage models.Usage
@@ -301,6 +734,11 @@ func (a *Agent) executeStreaming(ctx context.Context, req *models.CompletionRequ
 			// TODO: Stream to UI in Phase 6.2
 		}
 
+		reasoning += token.Reasoning
+		if token.ReasoningSignature != "" {
+			signature = token.ReasoningSignature
+		}
+
 		if token.ToolCall != nil {
 			toolCalls = append(toolCalls, *token.ToolCall)
 		}
@@ -309,7 +747,10 @@ func (a *Agent) executeStreaming(ctx context.Context, req *models.CompletionRequ
 			if token.Usage != nil {
 				usage = *token.Usage
 			}
-			stopReason = "end_turn"
+			stopReason = models.NormalizeStopReason(token.StopReason)
+			if stopReason == "" {
+				stopReason = "end_turn"
+			}
 			if len(toolCalls) > 0 {
 				stopReason = "tool_use"
 			}
@@ -318,17 +759,58 @@ func (a *Agent) executeStreaming(ctx context.Context, req *models.CompletionRequ
 	}
 
 	return &models.CompletionResponse{
-		Content:    content,
-		ToolCalls:  toolCalls,
-		StopReason: stopReason,
-		Usage:      usage,
-		Model:      req.Model,
+		Content:            content,
+		Reasoning:          reasoning,
+		ReasoningSignature: signature,
+		ToolCalls:          toolCalls,
+		StopReason:         stopReason,
+		Usage:              usage,
+		Model:              req.Model,
 	}, nil
 }
 
+// costReconcileTimeout bounds the background lookup of a generation's billed cost.
+const costReconcileTimeout = 30 * time.Second
+
+// reconcileCost replaces an estimated cost with the provider's billed cost
+// once it is available. The lookup runs in the background so the agent loop
+// isn't held up by the provider's reporting delay.
+func (a *Agent) reconcileCost(generationID string) {
+	reconciler, ok := models.AsCostReconciler(a.provider)
+	if !ok {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), costReconcileTimeout)
+		defer cancel()
+
+		cost, err := reconciler.ReconcileCost(ctx, generationID)
+		if err != nil {
+			a.logger.Warn("Cost reconciliation failed", "generation", generationID, "error", err.Error())
+			return
+		}
+		if !a.costTracker.ReconcileCost(generationID, cost) {
+			return
+		}
+
+		totalIn, totalOut, totalCost := a.costTracker.root().GetTotals()
+		reasoningTokens, reasoningCost := a.costTracker.root().GetReasoningTotals()
+		a.events.Publish(events.CostUpdated{
+			Model:           a.config.ModelName,
+			Cost:            cost,
+			TotalCost:       totalCost,
+			TotalTokens:     totalIn + totalOut,
+			ReasoningTokens: reasoningTokens,
+			ReasoningCost:   reasoningCost,
+			Time:            time.Now(),
+		})
+	}()
+}
+
 // getAvailableTools returns tools in the format expected by the LLM provider.
 func (a *Agent) getAvailableTools() []models.Tool {
-	registeredTools := a.toolReg.AllTools()
+	registeredTools := a.toolReg.EnabledTools()
 	llmTools := make([]models.Tool, 0, len(registeredTools))
 
 	for _, tool := range registeredTools {
@@ -358,23 +840,81 @@ func (a *Agent) getAvailableTools() []models.Tool {
 	return llmTools
 }
 
+// toolCategory returns the category of a registered tool, or "" if unknown.
+func (a *Agent) toolCategory(name string) string {
+	tool, err := a.toolReg.Get(name)
+	if err != nil {
+		return ""
+	}
+	return tool.Category()
+}
+
+// requirements returns what a turn needs from the model: tool calling when
+// tools are offered and image input for attachments. Room for the prompt
+// is checked for every call by models.WithContextGuard, which can compact
+// the request first.
+func (a *Agent) requirements(messages []models.Message, tools []models.Tool) models.Requirements {
+	req := models.Requirements{
+		Tools: len(tools) > 0,
+	}
+	for _, msg := range messages {
+		if len(msg.Attachments) > 0 {
+			req.Vision = true
+			break
+		}
+	}
+	return req
+}
+
+// untrustedHistory reports whether history holds low-trust content.
+func (a *Agent) untrustedHistory(history []models.Message) bool {
+	for _, msg := range history {
+		if contextmgr.MessageProvenance(msg, a.toolCategory).Untrusted() {
+			return true
+		}
+	}
+	return false
+}
+
 // determinePermission maps tool categories to permission types.
 func determinePermission(tool tools.Tool) security.Permission {
 	switch tool.Category() {
 	case "file":
 		// Determine read vs write based on tool name
 		switch tool.Name() {
-		case "core.read", "core.glob", "core.grep":
+		case "read", "glob", "grep", "watch":
 			return security.PermissionRead
-		case "core.write", "core.edit":
+		case "write", "edit":
 			return security.PermissionWrite
 		default:
 			return security.PermissionWrite
 		}
-	case "exec":
+	case "git":
+		// Status, diff and log only read the repository
+		if !tool.RequiresPermission() {
+			return security.PermissionRead
+		}
+		return security.PermissionWrite
+	case "exec", "docker":
 		return security.PermissionExecute
 	case "web":
 		return security.PermissionNetwork
+	case "lsp":
+		// Only renames change files
+		if tool.Name() == "lsp_rename" {
+			return security.PermissionWrite
+		}
+		return security.PermissionRead
+	case "db":
+		// Queries run read-only; only db_exec changes the database
+		if tool.Name() == "db_exec" {
+			return security.PermissionWrite
+		}
+		return security.PermissionRead
+	case "clipboard":
+		// Writes replace what the user copied; reads are confirmed by the
+		// tool on every call
+		return security.PermissionWrite
 	case "mcp":
 		return security.PermissionMCP
 	default:
@@ -382,6 +922,61 @@ func determinePermission(tool tools.Tool) security.Permission {
 	}
 }
 
+// pathArguments are the tool parameters that name a file or directory.
+var pathArguments = []string{"file_path", "path", "working_dir"}
+
+// resolvePaths returns arguments with root references in path parameters
+// resolved to absolute paths, and the first path accessed. The caller's
+// map is left unchanged.
+func (a *Agent) resolvePaths(arguments map[string]interface{}) (map[string]interface{}, string, error) {
+	if len(a.roots.List()) == 0 {
+		return arguments, "", nil
+	}
+
+	resolved, cloned := arguments, false
+	first := ""
+	for _, key := range pathArguments {
+		ref, ok := arguments[key].(string)
+		if !ok || ref == "" {
+			continue
+		}
+		path, err := a.roots.Resolve(ref)
+		if err != nil {
+			return nil, "", err
+		}
+		if first == "" {
+			first = path
+		}
+		if path == ref {
+			continue
+		}
+		if !cloned {
+			resolved, cloned = maps.Clone(arguments), true
+		}
+		resolved[key] = path
+	}
+
+	// Batch tools name their files as the keys of an object
+	if files, ok := arguments["files"].(map[string]interface{}); ok {
+		paths := make(map[string]interface{}, len(files))
+		for ref, content := range files {
+			path, err := a.roots.Resolve(ref)
+			if err != nil {
+				return nil, "", err
+			}
+			if first == "" || path < first {
+				first = path
+			}
+			paths[path] = content
+		}
+		if !cloned {
+			resolved = maps.Clone(arguments)
+		}
+		resolved["files"] = paths
+	}
+	return resolved, first, nil
+}
+
 // determineResource extracts the resource being accessed from tool arguments.
 func determineResource(arguments map[string]interface{}) string {
 	// Common resource parameter names
@@ -398,6 +993,11 @@ func (a *Agent) GetCostTracker() *CostTracker {
 	return a.costTracker
 }
 
+// GetConfig returns the agent's current configuration.
+func (a *Agent) GetConfig() *AgentConfig {
+	return a.config
+}
+
 // UpdateConfig updates the agent's configuration.
 func (a *Agent) UpdateConfig(config *AgentConfig) {
 	if config != nil {
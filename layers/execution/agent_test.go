@@ -0,0 +1,96 @@
+package execution
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/security"
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sensitiveTool needs elevated permission for every call.
+type sensitiveTool struct {
+	calls int
+}
+
+func (t *sensitiveTool) Name() string                  { return "secrets" }
+func (t *sensitiveTool) Description() string           { return "Prints the .env file" }
+func (t *sensitiveTool) Parameters() []tools.Parameter { return nil }
+func (t *sensitiveTool) RequiresPermission() bool      { return true }
+func (t *sensitiveTool) Category() string              { return "exec" }
+func (t *sensitiveTool) Version() string               { return "1.0.0" }
+func (t *sensitiveTool) IsExternal() bool              { return false }
+
+func (t *sensitiveTool) SensitiveReason(params map[string]interface{}) string {
+	return "command prints .env, which may hold credentials"
+}
+
+func (t *sensitiveTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	t.calls++
+	return &tools.Result{Success: true, Output: "API_KEY=..."}, nil
+}
+
+func TestExecuteTool_ElevatedDenialReason(t *testing.T) {
+	call := &models.CompletionResponse{
+		StopReason: "tool_use",
+		ToolCalls:  []models.ToolCall{{ID: "call_1", Name: "core.secrets", Arguments: map[string]interface{}{}}},
+	}
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{call}}
+	var asked *security.PermissionRequest
+	perms := security.NewPermissionManager(security.ModeYOLO, func(ctx context.Context, req *security.PermissionRequest) (bool, error) {
+		asked = req
+		return false, nil
+	})
+	registry := tools.NewRegistry()
+	tool := &sensitiveTool{}
+	require.NoError(t, registry.Register(tool))
+	agent, err := NewAgent(provider, &AgentConfig{ModelName: "test/model", MaxIterations: 5}, registry, perms)
+	require.NoError(t, err)
+	bus := events.NewBus()
+	agent.SetEventBus(bus)
+	ch, cancel := bus.Channel(8, events.TypePermissionRequested)
+	defer cancel()
+
+	_, err = agent.Execute(context.Background(), &AgentRequest{UserMessage: "show me the keys"})
+	require.NoError(t, err)
+	require.NotNil(t, asked, "elevated requests are put to the handler even in YOLO mode")
+	assert.True(t, asked.Elevated)
+	assert.Zero(t, tool.calls)
+
+	// The model learns why the call was denied
+	require.Len(t, provider.requests, 2)
+	messages := provider.requests[1].Messages
+	result := messages[len(messages)-1]
+	assert.Equal(t, "tool", result.Role)
+	assert.Contains(t, result.Content, "permission denied for tool core.secrets: command prints .env, which may hold credentials")
+
+	// And so does the user
+	e := (<-ch).(events.PermissionRequested)
+	assert.True(t, e.Elevated)
+	assert.Equal(t, "command prints .env, which may hold credentials", e.Reason)
+}
+
+func TestRecordUsage_PublishesReasoningTotals(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{{
+		Content:    "42",
+		StopReason: "end_turn",
+		Usage:      models.Usage{InputTokens: 100, OutputTokens: 500, Cost: 0.01, ReasoningTokens: 400, ReasoningCost: 0.008},
+	}}}
+	agent, _ := newTestAgent(t, provider)
+	bus := events.NewBus()
+	agent.SetEventBus(bus)
+	ch, cancel := bus.Channel(8, events.TypeCostUpdated)
+	defer cancel()
+
+	_, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "think it through"})
+	require.NoError(t, err)
+
+	e := (<-ch).(events.CostUpdated)
+	assert.Equal(t, 400, e.ReasoningTokens)
+	assert.InDelta(t, 0.008, e.ReasoningCost, 1e-9)
+	assert.Equal(t, 600, e.TotalTokens, "reasoning is part of the totals")
+}
@@ -0,0 +1,138 @@
+package execution
+
+import (
+	"context"
+	"strings"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+const (
+	// defaultMaxContinuations bounds how often one truncated answer is
+	// resumed unless configured otherwise.
+	defaultMaxContinuations = 3
+
+	// maxTokensGrowth caps how far the token limit is raised when the model
+	// spent it all without producing content (e.g. on reasoning).
+	maxTokensGrowth = 4
+
+	// Repeated text between minOverlap and maxOverlap bytes is trimmed when
+	// stitching parts; shorter matches are likely coincidental.
+	minOverlap = 8
+	maxOverlap = 200
+
+	continuePrompt = "Your previous response was cut off. Continue exactly where it stopped, " +
+		"without repeating any text or adding commentary."
+)
+
+// isTruncated reports whether a completion stopped at the token limit
+// without calling tools.
+func isTruncated(resp *models.CompletionResponse) bool {
+	return models.NormalizeStopReason(resp.StopReason) == "max_tokens" && len(resp.ToolCalls) == 0
+}
+
+// continueTruncated resumes a completion that hit the token limit and
+// stitches the parts into one response. Providers that support prefill
+// continue the partial answer in place; others are asked to continue in a
+// new turn. Empty truncated answers are retried with a larger token limit.
+// Usage of each extra call is recorded on response.
+func (a *Agent) continueTruncated(ctx context.Context, req *models.CompletionRequest, resp *models.CompletionResponse, response *AgentResponse) (*models.CompletionResponse, error) {
+	limit := a.maxContinuations()
+	prefill := models.SupportsPrefill(a.provider)
+	content := resp.Content
+	maxTokens := req.MaxTokens
+
+	for attempt := 1; attempt <= limit && isTruncated(resp); attempt++ {
+		next := *req
+
+		if strings.TrimSpace(content) == "" {
+			// Nothing to continue from; give the model more room instead
+			if req.MaxTokens <= 0 || maxTokens >= req.MaxTokens*maxTokensGrowth {
+				break
+			}
+			maxTokens *= 2
+			next.MaxTokens = maxTokens
+			a.logger.Info("Retrying empty truncated completion", "attempt", attempt, "max_tokens", maxTokens)
+		} else {
+			a.logger.Info("Continuing truncated completion", "attempt", attempt, "prefill", prefill, "chars", len(content))
+			next.Messages = continuationMessages(req.Messages, content, prefill)
+		}
+
+		part, err := a.complete(ctx, &next)
+		if err != nil {
+			return nil, err
+		}
+		a.recordUsage(response, part.Usage)
+
+		if strings.TrimSpace(content) == "" {
+			content = part.Content
+		} else {
+			content = stitch(content, part.Content, prefill)
+		}
+		resp = &models.CompletionResponse{
+			Content:            content,
+			Reasoning:          part.Reasoning,
+			ReasoningSignature: part.ReasoningSignature,
+			ToolCalls:          part.ToolCalls,
+			StopReason:         part.StopReason,
+			Usage:              part.Usage,
+			Model:              part.Model,
+			Metadata:           part.Metadata,
+		}
+	}
+
+	if isTruncated(resp) {
+		a.logger.Warn("Completion still truncated after continuations", "attempts", limit)
+	}
+	return resp, nil
+}
+
+// maxContinuations returns how often a truncated answer is resumed.
+func (a *Agent) maxContinuations() int {
+	switch n := a.config.MaxContinuations; {
+	case n < 0:
+		return 0
+	case n == 0:
+		return defaultMaxContinuations
+	default:
+		return n
+	}
+}
+
+// continuationMessages builds the conversation for resuming a partial answer.
+func continuationMessages(messages []models.Message, partial string, prefill bool) []models.Message {
+	out := make([]models.Message, 0, len(messages)+2)
+	out = append(out, messages...)
+
+	if prefill {
+		// Prefilled assistant turns may not end in whitespace
+		return append(out, models.Message{Role: "assistant", Content: strings.TrimRight(partial, " \t\n")})
+	}
+	return append(out,
+		models.Message{Role: "assistant", Content: partial},
+		models.Message{Role: "user", Content: continuePrompt},
+	)
+}
+
+// stitch joins a partial answer and its continuation. Prefill continuations
+// follow on directly from the trimmed partial; new-turn continuations often
+// repeat the tail of the partial, which is dropped.
+func stitch(partial, continuation string, prefill bool) string {
+	if prefill {
+		return strings.TrimRight(partial, " \t\n") + continuation
+	}
+
+	limit := maxOverlap
+	if len(partial) < limit {
+		limit = len(partial)
+	}
+	if len(continuation) < limit {
+		limit = len(continuation)
+	}
+	for n := limit; n >= minOverlap; n-- {
+		if strings.HasSuffix(partial, continuation[:n]) {
+			return partial + continuation[n:]
+		}
+	}
+	return partial + continuation
+}
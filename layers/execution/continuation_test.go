@@ -0,0 +1,197 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefillProvider is a scriptedProvider that continues assistant messages
+// in place.
+type prefillProvider struct {
+	*scriptedProvider
+}
+
+func (p prefillProvider) SupportsPrefill() bool { return true }
+
+// truncated is a response cut off at the token limit.
+func truncated(content string) *models.CompletionResponse {
+	return &models.CompletionResponse{Content: content, StopReason: "max_tokens", Usage: models.Usage{OutputTokens: 100}}
+}
+
+// finished is a response that ends the turn.
+func finished(content string) *models.CompletionResponse {
+	return &models.CompletionResponse{Content: content, StopReason: "end_turn", Usage: models.Usage{OutputTokens: 100}}
+}
+
+func TestContinueTruncated_NewTurn(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{
+		truncated("The handler validates the session token and"),
+		finished("validates the session token and then loads the user."),
+	}}
+	agent, _ := newTestAgent(t, provider)
+
+	resp, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "what does the handler do?"})
+	require.NoError(t, err)
+	assert.Equal(t, "The handler validates the session token and then loads the user.", resp.Content)
+	assert.False(t, resp.Truncated)
+	assert.Equal(t, 200, resp.Usage.OutputTokens, "the continuation is charged to the turn")
+
+	// The model is asked to continue in a new turn
+	require.Len(t, provider.requests, 2)
+	messages := provider.requests[1].Messages
+	require.GreaterOrEqual(t, len(messages), 2)
+	assert.Equal(t, models.Message{Role: "assistant", Content: "The handler validates the session token and"}, messages[len(messages)-2])
+	assert.Equal(t, models.Message{Role: "user", Content: continuePrompt}, messages[len(messages)-1])
+}
+
+func TestContinueTruncated_Prefill(t *testing.T) {
+	scripted := &scriptedProvider{responses: []*models.CompletionResponse{
+		truncated("Step one: read the config.\n"),
+		truncated(" Step two: open the database."),
+		finished(" Step three: serve."),
+	}}
+	agent, _ := newTestAgent(t, prefillProvider{scripted})
+
+	resp, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "how does it start?"})
+	require.NoError(t, err)
+	assert.Equal(t, "Step one: read the config. Step two: open the database. Step three: serve.", resp.Content)
+	assert.False(t, resp.Truncated)
+
+	// The partial answer is prefilled, without trailing whitespace or a prompt
+	require.Len(t, scripted.requests, 3)
+	last := scripted.requests[2].Messages[len(scripted.requests[2].Messages)-1]
+	assert.Equal(t, models.Message{Role: "assistant", Content: "Step one: read the config. Step two: open the database."}, last)
+	for _, req := range scripted.requests[1:] {
+		for _, msg := range req.Messages {
+			assert.NotEqual(t, continuePrompt, msg.Content)
+		}
+	}
+}
+
+func TestContinueTruncated_EmptyRaisesLimit(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{
+		truncated(""),
+		truncated("  "),
+		finished("The answer."),
+	}}
+	agent, _ := newTestAgent(t, provider)
+	agent.config.MaxTokens = 1000
+
+	resp, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "think hard"})
+	require.NoError(t, err)
+	assert.Equal(t, "The answer.", resp.Content)
+
+	// The history is not changed; only the token limit is raised
+	require.Len(t, provider.requests, 3)
+	for i, want := range []int{1000, 2000, 4000} {
+		assert.Equal(t, want, provider.requests[i].MaxTokens)
+		assert.Equal(t, provider.requests[0].Messages, provider.requests[i].Messages)
+	}
+}
+
+func TestContinueTruncated_EmptyAtGrowthCap(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{
+		truncated(""), truncated(""), truncated(""), truncated(""),
+	}}
+	agent, _ := newTestAgent(t, provider)
+	agent.config.MaxTokens = 1000
+
+	resp, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "think hard"})
+	require.NoError(t, err)
+	assert.True(t, resp.Truncated)
+
+	// The limit grows to maxTokensGrowth times the configured one, no further
+	require.Len(t, provider.requests, 3)
+	assert.Equal(t, 1000*maxTokensGrowth, provider.requests[2].MaxTokens)
+}
+
+func TestContinueTruncated_PublishesTruncation(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{
+		truncated("one"), truncated(" two"), truncated(" three"), truncated(" four"),
+	}}
+	agent, _ := newTestAgent(t, provider)
+	bus := events.NewBus()
+	agent.SetEventBus(bus)
+	ch, cancel := bus.Channel(8, events.TypeAnswerTruncated)
+	defer cancel()
+
+	resp, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "count"})
+	require.NoError(t, err)
+	assert.True(t, resp.Truncated)
+	assert.Equal(t, "one two three four", resp.Content)
+
+	select {
+	case e := <-ch:
+		assert.Equal(t, events.AnswerTruncated{Model: "test/model", Continuations: defaultMaxContinuations, Time: e.(events.AnswerTruncated).Time}, e)
+	default:
+		t.Fatal("no truncation event")
+	}
+}
+
+func TestContinueTruncated_EmptyWithoutLimit(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{truncated("")}}
+	agent, _ := newTestAgent(t, provider)
+
+	resp, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "think hard"})
+	require.NoError(t, err)
+	assert.True(t, resp.Truncated, "there is no limit to raise")
+	assert.Len(t, provider.requests, 1)
+}
+
+func TestContinueTruncated_Limit(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxContinuations int
+		requests         int
+	}{
+		{"disabled", -1, 1},
+		{"default", 0, 1 + defaultMaxContinuations},
+		{"one", 1, 2},
+		{"five", 5, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := make([]*models.CompletionResponse, 10)
+			for i := range responses {
+				responses[i] = truncated(fmt.Sprintf(" part %d", i+1))
+			}
+			provider := &scriptedProvider{responses: responses}
+			agent, _ := newTestAgent(t, provider)
+			agent.config.MaxContinuations = tt.maxContinuations
+
+			resp, err := agent.Execute(context.Background(), &AgentRequest{UserMessage: "write it all"})
+			require.NoError(t, err)
+			assert.True(t, resp.Truncated)
+			assert.Len(t, provider.requests, tt.requests)
+			assert.Equal(t, 100*tt.requests, resp.Usage.OutputTokens)
+		})
+	}
+}
+
+func TestStitch(t *testing.T) {
+	tests := []struct {
+		name         string
+		partial      string
+		continuation string
+		prefill      bool
+		want         string
+	}{
+		{"prefill follows on", "The first part.\n", " The second part.", true, "The first part. The second part."},
+		{"repeated tail is dropped", "It reads the file and", "reads the file and parses it.", false, "It reads the file and parses it."},
+		{"short overlap is kept", "a cat", "cat food", false, "a catcat food"},
+		{"no overlap", "First.", " Second.", false, "First. Second."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, stitch(tt.partial, tt.continuation, tt.prefill))
+		})
+	}
+}
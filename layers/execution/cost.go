@@ -14,6 +14,8 @@ type CostTracker struct {
 	totalInput      int
 	totalOutput     int
 	totalCost       float64
+	totalReasoning  int
+	reasoningCost   float64
 	entries         []CostEntry
 	sessionStart    time.Time
 	lastReset       time.Time
@@ -21,16 +23,35 @@ type CostTracker struct {
 	dailySpent      float64
 	budgetWarning   float64
 	warningCallback func(float64, float64) // (spent, budget)
+
+	sessionBudget Budget         // Zero fields are unlimited
+	parent        *CostTracker   // Tracker usage is aggregated into; nil for the session
+	children      []*CostTracker // Sub-agent trackers holding part of the budget
+}
+
+// Budget caps what an agent may spend. Zero fields are unlimited.
+type Budget struct {
+	Tokens int     // Input plus output tokens
+	Cost   float64 // In USD
+}
+
+// IsZero reports whether b is unlimited.
+func (b Budget) IsZero() bool {
+	return b == Budget{}
 }
 
 // CostEntry represents a single cost record.
 type CostEntry struct {
-	Timestamp    time.Time
-	InputTokens  int
-	OutputTokens int
-	Cost         float64
-	ModelName    string
-	Operation    string // e.g., "completion", "streaming"
+	Timestamp       time.Time
+	InputTokens     int
+	OutputTokens    int
+	ReasoningTokens int // Subset of OutputTokens
+	Cost            float64
+	ReasoningCost   float64 // Subset of Cost
+	ModelName       string
+	Operation       string // e.g., "completion", "streaming"
+	GenerationID    string // Provider generation ID, if reported
+	Estimated       bool   // Cost is an estimate awaiting reconciliation
 }
 
 // NewCostTracker creates a new cost tracker.
@@ -43,26 +64,83 @@ func NewCostTracker() *CostTracker {
 	}
 }
 
-// AddUsage records token usage and cost.
+// AddUsage records token usage and cost. A sub-agent's usage is added to
+// its parent's as well.
 func (ct *CostTracker) AddUsage(usage models.Usage) {
+	ct.addUsage(usage, "completion")
+	for p := ct.parent; p != nil; p = p.parent {
+		p.addUsage(usage, "subagent")
+	}
+}
+
+func (ct *CostTracker) addUsage(usage models.Usage, operation string) {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 
 	ct.totalInput += usage.InputTokens
 	ct.totalOutput += usage.OutputTokens
 	ct.totalCost += usage.Cost
+	ct.totalReasoning += usage.ReasoningTokens
+	ct.reasoningCost += usage.ReasoningCost
 	ct.dailySpent += usage.Cost
 
 	entry := CostEntry{
-		Timestamp:    time.Now(),
-		InputTokens:  usage.InputTokens,
-		OutputTokens: usage.OutputTokens,
-		Cost:         usage.Cost,
-		Operation:    "completion",
+		Timestamp:       time.Now(),
+		InputTokens:     usage.InputTokens,
+		OutputTokens:    usage.OutputTokens,
+		ReasoningTokens: usage.ReasoningTokens,
+		Cost:            usage.Cost,
+		ReasoningCost:   usage.ReasoningCost,
+		Operation:       operation,
+		GenerationID:    usage.GenerationID,
+		Estimated:       usage.CostEstimated,
 	}
 	ct.entries = append(ct.entries, entry)
 
-	// Check budget warning
+	ct.checkWarning()
+}
+
+// ReconcileCost replaces the estimated cost of the entry for a generation
+// with its billed cost, adjusting the totals, in parents too. It returns
+// false if no estimated entry exists for the generation.
+func (ct *CostTracker) ReconcileCost(generationID string, cost float64) bool {
+	if !ct.reconcileCost(generationID, cost) {
+		return false
+	}
+	for p := ct.parent; p != nil; p = p.parent {
+		p.reconcileCost(generationID, cost)
+	}
+	return true
+}
+
+func (ct *CostTracker) reconcileCost(generationID string, cost float64) bool {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for i := len(ct.entries) - 1; i >= 0; i-- {
+		entry := &ct.entries[i]
+		if entry.GenerationID != generationID || !entry.Estimated {
+			continue
+		}
+
+		delta := cost - entry.Cost
+		ct.totalCost += delta
+		// Entries from before the last daily reset no longer count toward today
+		if !entry.Timestamp.Before(ct.lastReset) {
+			ct.dailySpent += delta
+		}
+		entry.Cost = cost
+		entry.Estimated = false
+
+		ct.checkWarning()
+		return true
+	}
+	return false
+}
+
+// checkWarning fires the budget warning callback once spending crosses the
+// warning threshold. Callers must hold ct.mu.
+func (ct *CostTracker) checkWarning() {
 	if ct.dailyBudget > 0 && ct.budgetWarning > 0 {
 		if ct.dailySpent >= ct.budgetWarning && ct.warningCallback != nil {
 			go ct.warningCallback(ct.dailySpent, ct.dailyBudget)
@@ -77,6 +155,14 @@ func (ct *CostTracker) GetTotals() (inputTokens, outputTokens int, cost float64)
 	return ct.totalInput, ct.totalOutput, ct.totalCost
 }
 
+// GetReasoningTotals returns total reasoning tokens and their cost, which are
+// already included in the output token and cost totals.
+func (ct *CostTracker) GetReasoningTotals() (tokens int, cost float64) {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.totalReasoning, ct.reasoningCost
+}
+
 // GetDailySpent returns the amount spent today.
 func (ct *CostTracker) GetDailySpent() float64 {
 	ct.mu.RLock()
@@ -132,6 +218,145 @@ func (ct *CostTracker) RemainingBudget() float64 {
 	return ct.dailyBudget - ct.dailySpent
 }
 
+// SetSessionBudget caps what the session, or the sub-agent this tracker
+// belongs to, may spend.
+func (ct *CostTracker) SetSessionBudget(budget Budget) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.sessionBudget = budget
+}
+
+// SessionBudget returns the tracker's budget.
+func (ct *CostTracker) SessionBudget() Budget {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.sessionBudget
+}
+
+// WithinSessionBudget returns true while something is left of every limit
+// of the tracker's budget, after deducting sub-agent allocations.
+func (ct *CostTracker) WithinSessionBudget() bool {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	avail := ct.available()
+	if ct.sessionBudget.Tokens > 0 && avail.Tokens == 0 {
+		return false
+	}
+	if ct.sessionBudget.Cost > 0 && avail.Cost == 0 {
+		return false
+	}
+	return true
+}
+
+// AvailableBudget returns what is left of the budget once spending and the
+// unspent part of sub-agent allocations are deducted. Fields the budget
+// leaves unlimited are zero.
+func (ct *CostTracker) AvailableBudget() Budget {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.available()
+}
+
+// available computes AvailableBudget. Callers must hold ct.mu.
+func (ct *CostTracker) available() Budget {
+	b := ct.sessionBudget
+	var avail Budget
+	if b.Tokens > 0 {
+		avail.Tokens = max(b.Tokens-ct.totalInput-ct.totalOutput, 0)
+	}
+	if b.Cost > 0 {
+		avail.Cost = max(b.Cost-ct.totalCost, 0)
+	}
+
+	for _, child := range ct.children {
+		reserved := child.unspent()
+		if b.Tokens > 0 {
+			avail.Tokens = max(avail.Tokens-reserved.Tokens, 0)
+		}
+		if b.Cost > 0 {
+			avail.Cost = max(avail.Cost-reserved.Cost, 0)
+		}
+	}
+	return avail
+}
+
+// unspent returns the part of the tracker's budget not yet spent.
+func (ct *CostTracker) unspent() Budget {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	var left Budget
+	if ct.sessionBudget.Tokens > 0 {
+		left.Tokens = max(ct.sessionBudget.Tokens-ct.totalInput-ct.totalOutput, 0)
+	}
+	if ct.sessionBudget.Cost > 0 {
+		left.Cost = max(ct.sessionBudget.Cost-ct.totalCost, 0)
+	}
+	return left
+}
+
+// Allocate creates the tracker of a sub-agent, whose usage is aggregated
+// into this one. The sub-agent may spend up to budget, which is reserved
+// out of this tracker's available budget until Release is called. Limits
+// that are zero or exceed what is available are capped at the available
+// amount; limits this tracker does not have stay as requested. It fails
+// when the budget is already used up.
+func (ct *CostTracker) Allocate(budget Budget) (*CostTracker, error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	avail := ct.available()
+	if ct.sessionBudget.Tokens > 0 {
+		if avail.Tokens == 0 {
+			return nil, fmt.Errorf("session token budget exhausted")
+		}
+		if budget.Tokens <= 0 || budget.Tokens > avail.Tokens {
+			budget.Tokens = avail.Tokens
+		}
+	}
+	if ct.sessionBudget.Cost > 0 {
+		if avail.Cost == 0 {
+			return nil, fmt.Errorf("session cost budget exhausted")
+		}
+		if budget.Cost <= 0 || budget.Cost > avail.Cost {
+			budget.Cost = avail.Cost
+		}
+	}
+
+	child := NewCostTracker()
+	child.sessionBudget = budget
+	child.parent = ct
+	ct.children = append(ct.children, child)
+	return child, nil
+}
+
+// Release returns the unspent part of a sub-agent's allocation to its
+// parent. Usage already recorded stays in the parent's totals.
+func (ct *CostTracker) Release() {
+	p := ct.parent
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, child := range p.children {
+		if child == ct {
+			p.children = append(p.children[:i], p.children[i+1:]...)
+			break
+		}
+	}
+}
+
+// root returns the session's tracker.
+func (ct *CostTracker) root() *CostTracker {
+	for ct.parent != nil {
+		ct = ct.parent
+	}
+	return ct
+}
+
 // ResetDaily resets daily spending counters.
 func (ct *CostTracker) ResetDaily() {
 	ct.mu.Lock()
@@ -147,6 +372,8 @@ func (ct *CostTracker) Reset() {
 	ct.totalInput = 0
 	ct.totalOutput = 0
 	ct.totalCost = 0
+	ct.totalReasoning = 0
+	ct.reasoningCost = 0
 	ct.dailySpent = 0
 	ct.entries = make([]CostEntry, 0, 100)
 	ct.sessionStart = time.Now()
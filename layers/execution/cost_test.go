@@ -0,0 +1,109 @@
+package execution
+
+import (
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostTracker_Allocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		session Budget
+		spent   int
+		request Budget
+		want    Budget
+	}{
+		{"within the remainder", Budget{Tokens: 1000, Cost: 1}, 200, Budget{Tokens: 300, Cost: 0.25}, Budget{Tokens: 300, Cost: 0.25}},
+		{"beyond the remainder", Budget{Tokens: 1000, Cost: 1}, 200, Budget{Tokens: 5000, Cost: 2}, Budget{Tokens: 800, Cost: 1}},
+		{"unset limits get the remainder", Budget{Tokens: 1000}, 400, Budget{}, Budget{Tokens: 600}},
+		{"limits the session lacks stay", Budget{Cost: 1}, 0, Budget{Tokens: 500}, Budget{Tokens: 500, Cost: 1}},
+		{"unlimited session", Budget{}, 100, Budget{Tokens: 500}, Budget{Tokens: 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewCostTracker()
+			root.SetSessionBudget(tt.session)
+			root.AddUsage(models.Usage{InputTokens: tt.spent})
+
+			child, err := root.Allocate(tt.request)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, child.SessionBudget())
+		})
+	}
+}
+
+func TestCostTracker_AllocateReservesBudget(t *testing.T) {
+	root := NewCostTracker()
+	root.SetSessionBudget(Budget{Tokens: 1000})
+
+	first, err := root.Allocate(Budget{Tokens: 600})
+	require.NoError(t, err)
+	assert.Equal(t, Budget{Tokens: 400}, root.AvailableBudget())
+
+	// A second child only gets what the first left
+	second, err := root.Allocate(Budget{Tokens: 600})
+	require.NoError(t, err)
+	assert.Equal(t, Budget{Tokens: 400}, second.SessionBudget())
+	assert.False(t, root.WithinSessionBudget())
+
+	_, err = root.Allocate(Budget{Tokens: 1})
+	assert.EqualError(t, err, "session token budget exhausted")
+
+	// Spending within an allocation doesn't take more of the parent's
+	first.AddUsage(models.Usage{InputTokens: 100, OutputTokens: 100})
+	assert.Equal(t, Budget{}, root.AvailableBudget())
+
+	// Releasing returns only the unspent part
+	first.Release()
+	assert.Equal(t, Budget{Tokens: 400}, root.AvailableBudget())
+	second.Release()
+	assert.Equal(t, Budget{Tokens: 800}, root.AvailableBudget())
+}
+
+func TestCostTracker_ChildUsageAddsUpToRoot(t *testing.T) {
+	root := NewCostTracker()
+	root.SetSessionBudget(Budget{Tokens: 10000, Cost: 10})
+	root.AddUsage(models.Usage{InputTokens: 100, OutputTokens: 50, Cost: 0.1})
+
+	child, err := root.Allocate(Budget{Tokens: 5000})
+	require.NoError(t, err)
+	grandchild, err := child.Allocate(Budget{Tokens: 1000})
+	require.NoError(t, err)
+	assert.Same(t, root, grandchild.root())
+
+	child.AddUsage(models.Usage{InputTokens: 200, OutputTokens: 100, Cost: 0.2})
+	grandchild.AddUsage(models.Usage{InputTokens: 300, OutputTokens: 150, Cost: 0.3, GenerationID: "gen-1", CostEstimated: true})
+
+	in, out, cost := grandchild.GetTotals()
+	assert.Equal(t, []interface{}{300, 150, 0.3}, []interface{}{in, out, cost})
+	in, out, cost = child.GetTotals()
+	assert.Equal(t, 500, in)
+	assert.Equal(t, 250, out)
+	assert.InDelta(t, 0.5, cost, 1e-9)
+	in, out, cost = root.GetTotals()
+	assert.Equal(t, 600, in)
+	assert.Equal(t, 300, out)
+	assert.InDelta(t, 0.6, cost, 1e-9)
+
+	// Reconciled costs are corrected up the chain
+	require.True(t, grandchild.ReconcileCost("gen-1", 0.5))
+	_, _, cost = root.GetTotals()
+	assert.InDelta(t, 0.8, cost, 1e-9)
+
+	// Usage stays counted after the children are released
+	grandchild.Release()
+	child.Release()
+	in, out, _ = root.GetTotals()
+	assert.Equal(t, 900, in+out)
+	assert.Equal(t, Budget{Tokens: 9100, Cost: 9.2}, roundBudget(root.AvailableBudget()))
+}
+
+// roundBudget rounds a budget's cost to cents, for comparisons.
+func roundBudget(b Budget) Budget {
+	b.Cost = float64(int(b.Cost*100+0.5)) / 100
+	return b
+}
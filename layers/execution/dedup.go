@@ -0,0 +1,73 @@
+package execution
+
+import (
+	"encoding/json"
+
+	"github.com/abrksh22/bplus/layers/contextmgr"
+	"github.com/abrksh22/bplus/models"
+)
+
+// minDedupSize is the smallest tool result replaced by a reference; shorter
+// results cost little more than the reference itself.
+const minDedupSize = 256
+
+// dedupResult returns a short reference to an earlier, identical result of
+// the same call in messages, such as a re-read of an unchanged file or a
+// re-run of a command with the same output. Calls match on the tool and its
+// arguments, so files that happen to have the same content, like copies of
+// a license, are each shown. The optimizer keeps results referred to
+// whole. Otherwise content is returned unchanged and ok is false.
+func dedupResult(messages []models.Message, call models.ToolCall, content string) (string, bool) {
+	if len(content) < minDedupSize {
+		return content, false
+	}
+	key, ok := callKey(call)
+	if !ok {
+		return content, false
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role != "tool" || msg.Name != call.Name || len(msg.Content) != len(content) || msg.Content != content {
+			continue
+		}
+		prior, found := findCall(messages[:i], msg.ToolCallID)
+		if !found {
+			continue
+		}
+		if priorKey, ok := callKey(prior); !ok || priorKey != key {
+			continue
+		}
+		return contextmgr.UnchangedOutput(i+1, msg.ToolCallID, call.Name, len(content)), true
+	}
+	return content, false
+}
+
+// callKey identifies a call by its tool and arguments. Arguments are
+// encoded with sorted keys, so the same arguments in any order match.
+func callKey(call models.ToolCall) (string, bool) {
+	args, err := json.Marshal(call.Arguments)
+	if err != nil {
+		return "", false
+	}
+	return call.Name + "\x00" + string(args), true
+}
+
+// findCall returns the call a tool result with the given ID answers: the
+// latest with that ID in the assistant turns of messages, as IDs assigned
+// by the agent repeat across turns.
+func findCall(messages []models.Message, id string) (models.ToolCall, bool) {
+	if id == "" {
+		return models.ToolCall{}, false
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "assistant" {
+			continue
+		}
+		for _, call := range messages[i].ToolCalls {
+			if call.ID == id {
+				return call, true
+			}
+		}
+	}
+	return models.ToolCall{}, false
+}
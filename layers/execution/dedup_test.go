@@ -0,0 +1,116 @@
+package execution
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/layers/contextmgr"
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callTurn returns the assistant turn making call and the tool message
+// answering it with content.
+func callTurn(call models.ToolCall, content string) []models.Message {
+	return []models.Message{
+		{Role: "assistant", ToolCalls: []models.ToolCall{call}},
+		{Role: "tool", Name: call.Name, ToolCallID: call.ID, Content: content},
+	}
+}
+
+func readCall(id, path string) models.ToolCall {
+	return models.ToolCall{ID: id, Name: "core.read", Arguments: map[string]interface{}{"file_path": path}}
+}
+
+func TestDedupResult(t *testing.T) {
+	license := strings.Repeat("Permission is hereby granted, free of charge. ", 20)
+	short := license[:minDedupSize-1]
+
+	tests := []struct {
+		name    string
+		prior   string // Content of the earlier read of LICENSE
+		call    models.ToolCall
+		content string
+		deduped bool
+	}{
+		{"same call", license, readCall("call_2_1", "LICENSE"), license, true},
+		{"different path", license, readCall("call_2_1", "vendor/lib/LICENSE"), license, false},
+		{"extra argument", license, models.ToolCall{ID: "call_2_1", Name: "core.read", Arguments: map[string]interface{}{"file_path": "LICENSE", "offset": 10.0}}, license, false},
+		{"different tool", license, models.ToolCall{ID: "call_2_1", Name: "core.bash", Arguments: map[string]interface{}{"file_path": "LICENSE"}}, license, false},
+		{"different content", license, readCall("call_2_1", "LICENSE"), license + "!", false},
+		{"below minDedupSize", short, readCall("call_2_1", "LICENSE"), short, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := append([]models.Message{{Role: "user", Content: "check the licenses"}},
+				callTurn(readCall("call_1_1", "LICENSE"), tt.prior)...)
+			got, deduped := dedupResult(messages, tt.call, tt.content)
+			assert.Equal(t, tt.deduped, deduped)
+			if deduped {
+				assert.Equal(t, "Output unchanged since message #3 (tool call call_1_1): core.read returned the same 920 bytes. Refer to that result instead of running it again.", got)
+			} else {
+				assert.Equal(t, tt.content, got)
+			}
+		})
+	}
+}
+
+func TestDedupResult_ArgumentOrder(t *testing.T) {
+	content := strings.Repeat("x", minDedupSize)
+	first := models.ToolCall{ID: "a", Name: "core.grep", Arguments: map[string]interface{}{"pattern": "foo", "path": "src"}}
+	again := models.ToolCall{ID: "b", Name: "core.grep", Arguments: map[string]interface{}{"path": "src", "pattern": "foo"}}
+
+	_, deduped := dedupResult(callTurn(first, content), again, content)
+	assert.True(t, deduped)
+}
+
+func TestDedupResult_RepeatedCallIDs(t *testing.T) {
+	content := strings.Repeat("y", minDedupSize)
+
+	// Agent-assigned IDs restart each turn; a result is matched to the call
+	// of its own turn
+	messages := append(callTurn(readCall("call_1_1", "a.txt"), "other"),
+		callTurn(readCall("call_1_1", "b.txt"), content)...)
+	_, deduped := dedupResult(messages, readCall("call_2_1", "a.txt"), content)
+	assert.False(t, deduped)
+	_, deduped = dedupResult(messages, readCall("call_2_1", "b.txt"), content)
+	assert.True(t, deduped)
+
+	// Results whose call can't be found aren't referred to
+	orphan := []models.Message{{Role: "tool", Name: "core.read", Content: content}}
+	_, deduped = dedupResult(orphan, readCall("call_2_1", "b.txt"), content)
+	assert.False(t, deduped)
+}
+
+func TestDedupResult_OriginalKeptByOptimizer(t *testing.T) {
+	license := strings.Repeat("Permission is hereby granted, free of charge. ", 60)
+	buildLog := strings.Repeat("ok  \tgithub.com/acme/app\n", 150)
+
+	messages := []models.Message{{Role: "user", Content: "check the licenses"}}
+	messages = append(messages, callTurn(readCall("call_1_1", "LICENSE"), license)...)
+	messages = append(messages, callTurn(readCall("call_1_2", "build.log"), buildLog)...)
+	stub, deduped := dedupResult(messages, readCall("call_2_1", "LICENSE"), license)
+	require.True(t, deduped)
+	messages = append(messages, callTurn(readCall("call_2_1", "LICENSE"), stub)...)
+	for i := 0; i < 6; i++ {
+		messages = append(messages, models.Message{Role: "user", Content: "go on"})
+	}
+
+	// Both outputs are old and oversized, but the license is referred to
+	m := contextmgr.NewManager(0)
+	m.Reset(messages...)
+	plan, err := m.Optimize()
+	require.NoError(t, err)
+	require.Len(t, plan.Prunes, 1)
+	assert.Equal(t, 4, plan.Prunes[0].Index)
+	assert.Equal(t, license, m.History()[2].Content)
+
+	// Requests that overflow keep it too
+	compacted, ok := m.Compactor()(&models.CompletionRequest{Model: "test/model", Messages: messages}, 1_000_000)
+	require.True(t, ok)
+	assert.Equal(t, license, compacted.Messages[2].Content)
+	assert.Contains(t, compacted.Messages[4].Content, "[pruned")
+	assert.Equal(t, stub, compacted.Messages[6].Content)
+}
@@ -0,0 +1,154 @@
+package execution
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/models"
+)
+
+// draftMaxTokens caps a draft answer; it only has to tide the user over.
+const draftMaxTokens = 1024
+
+// draftPublishInterval is how often the growing draft is published, so a
+// fast draft model doesn't flood the event bus.
+const draftPublishInterval = 100 * time.Millisecond
+
+// questionWords start prompts that ask for an answer rather than for work.
+var questionWords = []string{
+	"what", "why", "how", "when", "where", "which", "who", "is", "are", "can", "could",
+	"does", "do", "did", "should", "would", "explain", "describe",
+}
+
+// draft is a draft answer streaming while the strong model works.
+type draft struct {
+	model  string
+	cancel context.CancelFunc
+	done   chan struct{}
+	usage  models.Usage // Set once done is closed
+}
+
+// SetDraftProvider sets the provider serving AgentConfig.DraftModel, when it
+// isn't the agent's own.
+func (a *Agent) SetDraftProvider(p models.Provider) {
+	a.drafter = p
+}
+
+// isQuestion reports whether a prompt reads as a question that can be
+// answered without tools, the only kind of turn worth drafting.
+func isQuestion(req *AgentRequest) bool {
+	if len(req.Attachments) > 0 {
+		return false
+	}
+	text := strings.ToLower(strings.TrimSpace(req.UserMessage))
+	if text == "" {
+		return false
+	}
+	if strings.HasSuffix(text, "?") {
+		return true
+	}
+	first, _, _ := strings.Cut(text, " ")
+	for _, w := range questionWords {
+		if first == w {
+			return true
+		}
+	}
+	return false
+}
+
+// startDraft streams an answer from the draft model to the UI, if one is
+// configured and the turn is a question, while the strong model answers
+// the same messages. It returns nil when no draft is made.
+func (a *Agent) startDraft(ctx context.Context, req *AgentRequest, messages []models.Message, system string) *draft {
+	model := a.config.DraftModel
+	if model == "" || model == a.config.ModelName || !a.config.Streaming || !isQuestion(req) {
+		return nil
+	}
+	provider := a.drafter
+	if provider == nil {
+		provider = a.provider
+	}
+	if !provider.SupportsStreaming() {
+		return nil
+	}
+
+	draftReq := &models.CompletionRequest{
+		Model:     model,
+		Messages:  messages,
+		System:    system,
+		MaxTokens: draftMaxTokens,
+		Metadata:  requestMetadata(req),
+	}
+	a.config.Sampling.Apply(draftReq)
+
+	ctx, cancel := context.WithCancel(ctx)
+	d := &draft{model: model, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+		d.usage = a.streamDraft(ctx, provider, draftReq)
+	}()
+	return d
+}
+
+// streamDraft streams the draft, publishing it as it grows, and returns its
+// usage. A failed draft is discarded; the strong answer follows anyway.
+func (a *Agent) streamDraft(ctx context.Context, provider models.Provider, req *models.CompletionRequest) models.Usage {
+	tokens, err := provider.StreamCompletion(ctx, req)
+	if err != nil {
+		a.discardDraft(ctx, req.Model, err)
+		return models.Usage{}
+	}
+
+	var content strings.Builder
+	var usage models.Usage
+	var published time.Time
+	for token := range tokens {
+		if token.Error != nil {
+			a.discardDraft(ctx, req.Model, token.Error)
+			return usage
+		}
+		content.WriteString(token.Content)
+		if token.Usage != nil {
+			usage = *token.Usage
+		}
+		if token.Content != "" && time.Since(published) >= draftPublishInterval {
+			published = time.Now()
+			a.events.Publish(events.DraftStreamed{Model: req.Model, Content: content.String(), Time: published})
+		}
+		if token.Done {
+			break
+		}
+	}
+	if ctx.Err() == nil {
+		a.events.Publish(events.DraftStreamed{Model: req.Model, Content: content.String(), Done: true, Time: time.Now()})
+	}
+	return usage
+}
+
+// discardDraft withdraws a draft that failed, unless the strong answer
+// already replaced it.
+func (a *Agent) discardDraft(ctx context.Context, model string, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	a.logger.Debug("Draft failed", "model", model, "error", err.Error())
+	a.events.Publish(events.DraftReplaced{Model: model, Discarded: true, Time: time.Now()})
+}
+
+// endDraft stops the draft, if any, once the strong model has answered or
+// the turn has taken another course, and counts its usage towards the turn.
+// A draft the strong answer replaces is reported as replaced, any other as
+// discarded.
+func (a *Agent) endDraft(d *draft, response *AgentResponse, replaced bool) {
+	if d == nil {
+		return
+	}
+	d.cancel()
+	<-d.done
+	a.events.Publish(events.DraftReplaced{Model: d.model, Discarded: !replaced, Time: time.Now()})
+	if d.usage.TotalTokens > 0 || d.usage.Cost > 0 {
+		a.recordUsage(response, d.usage)
+	}
+}
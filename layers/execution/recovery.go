@@ -189,6 +189,10 @@ func RetryWithPolicy(ctx context.Context, policy *RetryPolicy, fn func(context.C
 		if policy.Jitter {
 			currentDelay = addJitter(delay, 0.2)
 		}
+		// Wait at least as long as the provider asked for
+		if wait := errors.RetryAfter(err); wait > currentDelay {
+			currentDelay = wait
+		}
 
 		logger.Info("Retrying after error", "attempt", attempt, "delay", currentDelay.String(), "error", err.Error())
 
@@ -215,6 +219,12 @@ func isRetryable(err error) bool {
 		return false
 	}
 
+	// Auth and quota failures do not pass by themselves, whatever the
+	// provider's message says
+	if errors.Is(err, errors.ErrCodeProviderAuth) || errors.Is(err, errors.ErrCodeProviderQuota) {
+		return false
+	}
+
 	// Check for known retryable error types
 	if errors.IsRetryable(err) {
 		return true
@@ -128,12 +128,8 @@ func (sm *SessionManager) GetSession(ctx context.Context, sessionID string) (*Se
 }
 
 // SaveMessage saves a message to a session.
+// Oversized content (e.g. huge tool outputs) is compressed by the storage layer.
 func (sm *SessionManager) SaveMessage(ctx context.Context, sessionID string, message models.Message, tokensInput, tokensOutput int, cost float64) error {
-	query := `
-		INSERT INTO messages (session_id, role, content, tokens_input, tokens_output, cost, metadata)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
-
 	// Store additional metadata
 	metadata := make(map[string]interface{})
 	if message.Name != "" {
@@ -144,8 +140,17 @@ func (sm *SessionManager) SaveMessage(ctx context.Context, sessionID string, mes
 	if err != nil {
 		return errors.Wrap(err, errors.ErrCodeInternal, "failed to marshal message metadata")
 	}
-
-	_, err = sm.db.DB().Exec(query, sessionID, message.Role, message.Content, tokensInput, tokensOutput, cost, string(metadataJSON))
+	metadataStr := string(metadataJSON)
+
+	err = sm.db.AddMessage(&storage.Message{
+		SessionID:    sessionID,
+		Role:         message.Role,
+		Content:      message.Content,
+		TokensInput:  tokensInput,
+		TokensOutput: tokensOutput,
+		Cost:         cost,
+		Metadata:     &metadataStr,
+	})
 	if err != nil {
 		return errors.Wrap(err, errors.ErrCodeInternal, "failed to save message")
 	}
@@ -162,32 +167,22 @@ func (sm *SessionManager) SaveMessage(ctx context.Context, sessionID string, mes
 
 // GetMessages retrieves all messages for a session.
 func (sm *SessionManager) GetMessages(ctx context.Context, sessionID string) ([]models.Message, error) {
-	query := `
-		SELECT role, content, metadata
-		FROM messages
-		WHERE session_id = ?
-		ORDER BY id ASC
-	`
-
-	rows, err := sm.db.DB().Query(query, sessionID)
+	stored, err := sm.db.GetSessionMessages(sessionID)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to query messages")
 	}
-	defer rows.Close()
-
-	messages := make([]models.Message, 0)
-	for rows.Next() {
-		var msg models.Message
-		var metadataJSON *string
 
-		if err := rows.Scan(&msg.Role, &msg.Content, &metadataJSON); err != nil {
-			return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to scan message row")
+	messages := make([]models.Message, 0, len(stored))
+	for _, row := range stored {
+		msg := models.Message{
+			Role:    row.Role,
+			Content: row.Content,
 		}
 
 		// Parse metadata
-		if metadataJSON != nil {
+		if row.Metadata != nil {
 			var metadata map[string]interface{}
-			if err := json.Unmarshal([]byte(*metadataJSON), &metadata); err == nil {
+			if err := json.Unmarshal([]byte(*row.Metadata), &metadata); err == nil {
 				if name, ok := metadata["name"].(string); ok {
 					msg.Name = name
 				}
@@ -197,10 +192,6 @@ func (sm *SessionManager) GetMessages(ctx context.Context, sessionID string) ([]
 		messages = append(messages, msg)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "error iterating message rows")
-	}
-
 	return messages, nil
 }
 
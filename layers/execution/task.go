@@ -0,0 +1,156 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// subAgentKey marks the context of a sub-agent's turn.
+type subAgentKey struct{}
+
+// TaskTool delegates a self-contained task to a sub-agent with a budget of
+// its own, taken out of the delegating agent's. The sub-agent shares the
+// agent's tools and permissions, and its usage counts towards the session's.
+type TaskTool struct {
+	agent *Agent
+}
+
+// NewTaskTool creates a task tool. It runs no tasks until SetAgent is
+// called, as the agent is created after its tools are registered.
+func NewTaskTool() *TaskTool {
+	return &TaskTool{}
+}
+
+// SetAgent sets the agent whose sub-agents run the tasks.
+func (t *TaskTool) SetAgent(agent *Agent) {
+	t.agent = agent
+}
+
+// Name returns the tool name.
+func (t *TaskTool) Name() string {
+	return "task"
+}
+
+// Description returns the tool description.
+func (t *TaskTool) Description() string {
+	return "Delegates a self-contained task, such as researching a question across the codebase, to a sub-agent with the same tools, and returns its final answer. " +
+		"The sub-agent doesn't see this conversation, so the prompt must say everything it needs. " +
+		"Give it a token or cost budget; its spending counts towards the session's budget"
+}
+
+// Parameters returns the tool parameters.
+func (t *TaskTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "prompt",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "The task, with all the context the sub-agent needs",
+		},
+		{
+			Name:        "max_tokens",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Input plus output tokens the sub-agent may use (default: what is left of the session's budget)",
+		},
+		{
+			Name:        "max_cost",
+			Type:        tools.TypeFloat,
+			Required:    false,
+			Description: "USD the sub-agent may spend (default: what is left of the session's budget)",
+		},
+	}
+}
+
+// Category returns the tool category.
+func (t *TaskTool) Category() string {
+	return "agent"
+}
+
+// Version returns the tool version.
+func (t *TaskTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *TaskTool) IsExternal() bool {
+	return false
+}
+
+// RequiresPermission returns false: the sub-agent's own tool calls are
+// checked.
+func (t *TaskTool) RequiresPermission() bool {
+	return false
+}
+
+// Execute runs the task in a sub-agent and releases its unspent budget
+// when it is done.
+func (t *TaskTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	prompt, _ := params["prompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return taskFailure(fmt.Errorf("prompt is required"), startTime), nil
+	}
+	if t.agent == nil {
+		return taskFailure(fmt.Errorf("no agent to delegate tasks to"), startTime), nil
+	}
+	if ctx.Value(subAgentKey{}) != nil {
+		return taskFailure(fmt.Errorf("sub-agents cannot delegate tasks"), startTime), nil
+	}
+
+	var budget Budget
+	if n, ok := params["max_tokens"].(float64); ok {
+		budget.Tokens = int(n)
+	} else if n, ok := params["max_tokens"].(int); ok {
+		budget.Tokens = n
+	}
+	if c, ok := params["max_cost"].(float64); ok {
+		budget.Cost = c
+	}
+	if budget.Tokens < 0 || budget.Cost < 0 {
+		return taskFailure(fmt.Errorf("max_tokens and max_cost cannot be negative"), startTime), nil
+	}
+
+	sub, err := t.agent.NewSubAgent(nil, budget)
+	if err != nil {
+		return taskFailure(err, startTime), nil
+	}
+	defer sub.Release()
+
+	resp, err := sub.Execute(context.WithValue(ctx, subAgentKey{}, true), &AgentRequest{
+		UserMessage: prompt,
+		SessionID:   tools.SessionID(ctx),
+	})
+	if resp == nil {
+		return taskFailure(err, startTime), nil
+	}
+
+	// A sub-agent stopped at its budget reports what it spent
+	result := &tools.Result{
+		Success: err == nil,
+		Output:  resp.Content,
+		Error:   err,
+		Metadata: map[string]interface{}{
+			"input_tokens":  resp.Usage.InputTokens,
+			"output_tokens": resp.Usage.OutputTokens,
+			"cost":          resp.Usage.Cost,
+			"iterations":    resp.Iterations,
+			"tool_calls":    len(resp.ToolCalls),
+		},
+		Duration: time.Since(startTime),
+	}
+	return result, nil
+}
+
+// taskFailure is the result of a task that could not run.
+func taskFailure(err error, startTime time.Time) *tools.Result {
+	return &tools.Result{
+		Success:  false,
+		Error:    err,
+		Duration: time.Since(startTime),
+	}
+}
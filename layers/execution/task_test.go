@@ -0,0 +1,156 @@
+package execution
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/security"
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedProvider returns its responses in order, then ends the turn.
+type scriptedProvider struct {
+	mu        sync.Mutex
+	responses []*models.CompletionResponse
+	requests  []*models.CompletionRequest
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) ListModels(ctx context.Context) ([]models.Model, error) { return nil, nil }
+
+func (p *scriptedProvider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	if len(p.responses) == 0 {
+		return &models.CompletionResponse{Content: "done", StopReason: "end_turn"}, nil
+	}
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func (p *scriptedProvider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
+	return nil, errors.New(errors.ErrCodeProvider, "streaming not supported")
+}
+
+func (p *scriptedProvider) TestConnection(ctx context.Context) error { return nil }
+
+func (p *scriptedProvider) GetModelInfo(ctx context.Context, modelID string) (*models.ModelInfo, error) {
+	return nil, nil
+}
+
+func (p *scriptedProvider) SupportsStreaming() bool { return false }
+
+func (p *scriptedProvider) SupportsTools() bool { return true }
+
+// toolTurn is a response calling a tool that isn't registered, which the
+// agent answers with an error and goes on.
+func toolTurn(tokens int) *models.CompletionResponse {
+	return &models.CompletionResponse{
+		StopReason: "tool_use",
+		ToolCalls:  []models.ToolCall{{Name: "core.missing", Arguments: map[string]interface{}{}}},
+		Usage:      models.Usage{InputTokens: tokens, OutputTokens: tokens, Cost: float64(tokens) / 1000},
+	}
+}
+
+func newTestAgent(t *testing.T, provider models.Provider) (*Agent, *tools.Registry) {
+	t.Helper()
+	registry := tools.NewRegistry()
+	agent, err := NewAgent(provider, &AgentConfig{ModelName: "test/model", MaxIterations: 5}, registry, security.NewPermissionManager(security.ModeYOLO, nil))
+	require.NoError(t, err)
+	return agent, registry
+}
+
+func TestNewSubAgent_BudgetExceeded(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{toolTurn(100), toolTurn(100)}}
+	agent, _ := newTestAgent(t, provider)
+	agent.GetCostTracker().SetSessionBudget(Budget{Tokens: 1000})
+
+	sub, err := agent.NewSubAgent(nil, Budget{Tokens: 150})
+	require.NoError(t, err)
+	defer sub.Release()
+
+	// One call spends 200 tokens, so the sub-agent stops before the next
+	resp, err := sub.Execute(context.Background(), &AgentRequest{UserMessage: "look around"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.ErrCodeBudgetExceeded))
+	require.NotNil(t, resp)
+	assert.Equal(t, 200, resp.Usage.InputTokens+resp.Usage.OutputTokens)
+	assert.Len(t, provider.requests, 1)
+
+	// The parent has what neither the spending nor the allocation took
+	in, out, _ := agent.GetCostTracker().GetTotals()
+	assert.Equal(t, 200, in+out)
+	assert.Equal(t, Budget{Tokens: 800}, agent.GetCostTracker().AvailableBudget())
+}
+
+func TestNewSubAgent_ParentExhausted(t *testing.T) {
+	agent, _ := newTestAgent(t, &scriptedProvider{})
+	agent.GetCostTracker().SetSessionBudget(Budget{Cost: 0.5})
+	agent.GetCostTracker().AddUsage(models.Usage{Cost: 0.5})
+
+	_, err := agent.NewSubAgent(nil, Budget{Cost: 0.1})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.ErrCodeBudgetExceeded))
+}
+
+func TestTaskTool_Execute(t *testing.T) {
+	provider := &scriptedProvider{responses: []*models.CompletionResponse{
+		toolTurn(50),
+		{Content: "The config is loaded in app/app.go", StopReason: "end_turn", Usage: models.Usage{InputTokens: 40, OutputTokens: 10}},
+	}}
+	agent, registry := newTestAgent(t, provider)
+	agent.GetCostTracker().SetSessionBudget(Budget{Tokens: 1000})
+	task := NewTaskTool()
+	task.SetAgent(agent)
+	require.NoError(t, registry.Register(task))
+
+	result, err := task.Execute(context.Background(), map[string]interface{}{"prompt": "Where is the config loaded?", "max_tokens": float64(5000)})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "The config is loaded in app/app.go", result.Output)
+	assert.Equal(t, 90, result.Metadata["input_tokens"])
+	assert.Equal(t, 60, result.Metadata["output_tokens"])
+	assert.Equal(t, 2, result.Metadata["iterations"])
+
+	// The sub-agent's spending is the session's, and its allocation is back
+	in, out, _ := agent.GetCostTracker().GetTotals()
+	assert.Equal(t, 150, in+out)
+	assert.Equal(t, Budget{Tokens: 850}, agent.GetCostTracker().AvailableBudget())
+	assert.Empty(t, agent.GetCostTracker().children)
+}
+
+func TestTaskTool_Failures(t *testing.T) {
+	agent, _ := newTestAgent(t, &scriptedProvider{responses: []*models.CompletionResponse{toolTurn(100)}})
+	agent.GetCostTracker().SetSessionBudget(Budget{Tokens: 1000})
+	task := NewTaskTool()
+	ctx := context.Background()
+
+	result, err := task.Execute(ctx, map[string]interface{}{"prompt": "anything"})
+	require.NoError(t, err)
+	assert.EqualError(t, result.Error, "no agent to delegate tasks to")
+
+	task.SetAgent(agent)
+	result, err = task.Execute(ctx, map[string]interface{}{"prompt": " "})
+	require.NoError(t, err)
+	assert.EqualError(t, result.Error, "prompt is required")
+
+	result, err = task.Execute(context.WithValue(ctx, subAgentKey{}, true), map[string]interface{}{"prompt": "nested"})
+	require.NoError(t, err)
+	assert.EqualError(t, result.Error, "sub-agents cannot delegate tasks")
+
+	// A sub-agent out of budget fails the call with what it spent
+	result, err = task.Execute(ctx, map[string]interface{}{"prompt": "look around", "max_tokens": float64(100)})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.True(t, errors.Is(result.Error, errors.ErrCodeBudgetExceeded))
+	assert.Equal(t, 100, result.Metadata["input_tokens"])
+	assert.Equal(t, Budget{Tokens: 800}, agent.GetCostTracker().AvailableBudget())
+}
@@ -0,0 +1,354 @@
+// Package plugin lets external processes implement layers of the 7-layer
+// architecture. A plugin is started from config and speaks JSON-RPC 2.0 over
+// stdio, one message per line: b+ sends layer inputs and receives structured
+// outputs, so a layer can be customized (e.g. by a company-internal planning
+// service) without recompiling b+.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion is the plugin protocol version b+ speaks.
+const ProtocolVersion = 1
+
+// Protocol methods
+const (
+	MethodInitialize = "initialize" // Handshake, sent once after start
+	MethodRun        = "layer/run"  // Run the layer on one input
+	MethodShutdown   = "shutdown"   // Sent before stdin is closed
+)
+
+// Process defaults
+const (
+	// defaultTimeout bounds a single call when the config sets none.
+	defaultTimeout = 60 * time.Second
+
+	// shutdownGrace is how long a plugin may take to exit before it is killed.
+	shutdownGrace = 2 * time.Second
+
+	// stderrTail is how much of a plugin's stderr is kept for error reports.
+	stderrTail = 4096
+
+	// maxMessageSize is the largest line accepted from a plugin.
+	maxMessageSize = 16 * 1024 * 1024
+)
+
+// Config describes how to start a plugin process.
+type Config struct {
+	Command string            // Executable, looked up in PATH
+	Args    []string          // Command-line arguments
+	Env     map[string]string // Added to b+'s environment
+	Dir     string            // Working directory; empty for the current one
+	Timeout time.Duration     // Per call; defaultTimeout if zero
+}
+
+// Info is what a plugin reports about itself during the handshake.
+type Info struct {
+	Name            string   `json:"name"`
+	Version         string   `json:"version,omitempty"`
+	ProtocolVersion int      `json:"protocol_version"`
+	Layers          []string `json:"layers,omitempty"` // Layers implemented; empty means any
+}
+
+// RPCError is an error returned by a plugin.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements error.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// Client is a connection to one running plugin process.
+type Client struct {
+	layer   string
+	timeout time.Duration
+	info    Info
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan response
+	done    chan struct{} // Closed when the process exits
+	exitErr error
+}
+
+// Start launches the plugin for layer and performs the handshake. ctx only
+// bounds the handshake; the process runs until Close.
+func Start(ctx context.Context, layer string, cfg Config) (*Client, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("no command configured for %s plugin", layer)
+	}
+
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stdout: %w", err)
+	}
+	stderr := &tailBuffer{max: stderrTail}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s plugin: %w", layer, err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	c := &Client{
+		layer:   layer,
+		timeout: timeout,
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  stderr,
+		enc:     json.NewEncoder(stdin),
+		pending: make(map[int64]chan response),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+
+	params := initializeParams{ProtocolVersion: ProtocolVersion, Layer: layer, Client: "b+"}
+	if err := c.Call(ctx, MethodInitialize, params, &c.info); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("%s plugin handshake failed: %w", layer, err)
+	}
+	if c.info.ProtocolVersion != ProtocolVersion {
+		c.Close()
+		return nil, fmt.Errorf("%s plugin speaks protocol version %d, b+ speaks %d",
+			layer, c.info.ProtocolVersion, ProtocolVersion)
+	}
+	if !c.implements(layer) {
+		c.Close()
+		return nil, fmt.Errorf("plugin %s does not implement layer %s", c.info.Name, layer)
+	}
+
+	return c, nil
+}
+
+// Info returns what the plugin reported during the handshake.
+func (c *Client) Info() Info {
+	return c.info
+}
+
+// Alive reports whether the plugin process is still running.
+func (c *Client) Alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Call invokes method with params and decodes the result into result (which
+// may be nil). The call is bounded by ctx and the configured timeout.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s params: %w", method, err)
+	}
+
+	ch := make(chan response, 1)
+	c.mu.Lock()
+	if !c.Alive() {
+		c.mu.Unlock()
+		return c.exitError()
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(request{JSONRPC: "2.0", ID: &id, Method: method, Params: raw}); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+	case <-c.done:
+		return c.exitError()
+	case <-ctx.Done():
+		return fmt.Errorf("%s plugin %s call: %w", c.layer, method, ctx.Err())
+	}
+}
+
+// Close asks the plugin to shut down, then kills it if it has not exited
+// within the grace period.
+func (c *Client) Close() error {
+	if c.Alive() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		_ = c.send(request{JSONRPC: "2.0", Method: MethodShutdown})
+		c.stdin.Close()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			_ = c.cmd.Process.Kill()
+			<-c.done
+		}
+		cancel()
+	}
+	return nil
+}
+
+// send writes one message.
+func (c *Client) send(req request) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.enc.Encode(req); err != nil {
+		return fmt.Errorf("failed to write to %s plugin: %w", c.layer, err)
+	}
+	return nil
+}
+
+// readLoop dispatches responses until stdout closes, then reaps the process.
+// Messages without an ID (plugin notifications) are ignored.
+func (c *Client) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		var resp response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || resp.ID == nil {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[*resp.ID]
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+
+	// A plugin whose output can no longer be read is of no further use
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		_ = c.cmd.Process.Kill()
+	}
+
+	err := c.cmd.Wait()
+	if scanErr != nil {
+		err = fmt.Errorf("failed to read plugin output: %w", scanErr)
+	}
+	c.mu.Lock()
+	c.exitErr = err
+	close(c.done)
+	c.mu.Unlock()
+}
+
+// exitError describes why the plugin is no longer running.
+func (c *Client) exitError() error {
+	msg := fmt.Sprintf("%s plugin exited", c.layer)
+	if c.exitErr != nil {
+		msg += ": " + c.exitErr.Error()
+	}
+	if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+		msg += "\n" + tail
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// implements reports whether the plugin declared layer (or declared none).
+func (c *Client) implements(layer string) bool {
+	if len(c.info.Layers) == 0 {
+		return true
+	}
+	for _, l := range c.info.Layers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// JSON-RPC message types
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+type initializeParams struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Layer           string `json:"layer"`
+	Client          string `json:"client"`
+}
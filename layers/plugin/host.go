@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// Layer names plugins can be registered for, matching the layer config keys.
+const (
+	LayerIntent     = "intent_clarification"
+	LayerPlanning   = "parallel_planning"
+	LayerSynthesis  = "synthesis"
+	LayerMainAgent  = "main_agent"
+	LayerValidation = "validation"
+	LayerContext    = "context_management"
+)
+
+// Layers lists every layer name in pipeline order.
+var Layers = []string{LayerIntent, LayerPlanning, LayerSynthesis, LayerMainAgent, LayerValidation, LayerContext}
+
+// IsLayer reports whether name is a known layer.
+func IsLayer(name string) bool {
+	for _, l := range Layers {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Input is the params of a layer/run call.
+type Input struct {
+	Layer     string           `json:"layer"`
+	SessionID string           `json:"session_id,omitempty"`
+	Prompt    string           `json:"prompt"`
+	History   []models.Message `json:"history,omitempty"`
+	Context   string           `json:"context,omitempty"`  // Context gathered by earlier layers
+	Response  string           `json:"response,omitempty"` // Agent answer, for layers after the main agent
+	Sampling  *models.Sampling `json:"sampling,omitempty"` // Sampling parameters configured for the layer
+}
+
+// Output is the result of a layer/run call. Empty fields leave the turn
+// unchanged.
+type Output struct {
+	Prompt  string          `json:"prompt,omitempty"`  // Replacement user message (e.g. clarified intent)
+	Context string          `json:"context,omitempty"` // Added to the agent's context (e.g. a plan)
+	Data    json.RawMessage `json:"data,omitempty"`    // Layer-specific structured output
+}
+
+// Host starts registered plugins on first use and routes layer calls to them.
+type Host struct {
+	mu      sync.Mutex
+	configs map[string]Config
+	clients map[string]*Client
+}
+
+// NewHost creates a host for plugins keyed by layer name.
+func NewHost(configs map[string]Config) *Host {
+	h := &Host{
+		configs: make(map[string]Config, len(configs)),
+		clients: make(map[string]*Client),
+	}
+	for layer, cfg := range configs {
+		h.configs[layer] = cfg
+	}
+	return h
+}
+
+// Has reports whether a plugin is registered for layer.
+func (h *Host) Has(layer string) bool {
+	if h == nil {
+		return false
+	}
+	_, ok := h.configs[layer]
+	return ok
+}
+
+// Registered returns the layers with a registered plugin, sorted.
+func (h *Host) Registered() []string {
+	if h == nil {
+		return nil
+	}
+	layers := make([]string, 0, len(h.configs))
+	for layer := range h.configs {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+	return layers
+}
+
+// Run sends in to the plugin for layer, starting it on first use. A plugin
+// that has exited is started again.
+func (h *Host) Run(ctx context.Context, layer string, in Input) (*Output, error) {
+	client, err := h.client(ctx, layer)
+	if err != nil {
+		return nil, err
+	}
+
+	in.Layer = layer
+	var out Output
+	if err := client.Call(ctx, MethodRun, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Close shuts down every running plugin.
+func (h *Host) Close() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for layer, c := range h.clients {
+		c.Close()
+		delete(h.clients, layer)
+	}
+	return nil
+}
+
+// client returns the running plugin for layer, starting it if needed.
+func (h *Host) client(ctx context.Context, layer string) (*Client, error) {
+	if !h.Has(layer) {
+		return nil, fmt.Errorf("no plugin registered for layer %s", layer)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c, ok := h.clients[layer]; ok {
+		if c.Alive() {
+			return c, nil
+		}
+		delete(h.clients, layer)
+	}
+
+	c, err := Start(ctx, layer, h.configs[layer])
+	if err != nil {
+		return nil, err
+	}
+	h.clients[layer] = c
+	return c, nil
+}
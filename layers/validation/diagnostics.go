@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"sort"
+	"sync"
+)
+
+// Diagnostics holds the latest findings language servers report for each
+// file, updated after every edit, so validation sees problems the agent
+// introduced without running a build of its own.
+type Diagnostics struct {
+	mu     sync.RWMutex
+	byPath map[string][]Finding
+}
+
+// NewDiagnostics creates an empty diagnostics store.
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{byPath: make(map[string][]Finding)}
+}
+
+// Update replaces the findings of a file; none clears them.
+func (d *Diagnostics) Update(path string, findings []Finding) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(findings) == 0 {
+		delete(d.byPath, path)
+		return
+	}
+	d.byPath[path] = append([]Finding(nil), findings...)
+}
+
+// Findings returns the current findings ordered by path and line.
+func (d *Diagnostics) Findings() []Finding {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var findings []Finding
+	for _, f := range d.byPath {
+		findings = append(findings, f...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// Errors returns how many current findings are errors.
+func (d *Diagnostics) Errors() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	n := 0
+	for _, findings := range d.byPath {
+		for _, f := range findings {
+			if f.Severity == SeverityError {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Report publishes the current findings to r.
+func (d *Diagnostics) Report(r Reporter) error {
+	return r.Report(d.Findings())
+}
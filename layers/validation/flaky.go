@@ -0,0 +1,233 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TestResult is the outcome of one test in one run of the test suite.
+type TestResult struct {
+	Name   string // Fully qualified, e.g. "pkg/ui.TestSnapshot/80x24"
+	Passed bool
+	Path   string // File the test is defined in, if known
+}
+
+// TestStatus classifies a test from its results across validation runs.
+type TestStatus string
+
+const (
+	TestPassing    TestStatus = "passing"    // Passed in the latest run
+	TestFailing    TestStatus = "failing"    // Has failed in every run
+	TestRegression TestStatus = "regression" // Passed before, fails consistently now
+	TestFlaky      TestStatus = "flaky"      // Passes and fails nondeterministically
+)
+
+// flipThreshold is how many pass/fail flips across code changes mark a test
+// as likely flaky even if it never flipped on the same code.
+const flipThreshold = 3
+
+// testHistory is what the tracker knows about one test.
+type testHistory struct {
+	path    string
+	runs    []bool                   // Outcomes in run order
+	byState map[string]map[bool]bool // Outcomes seen per code state
+	states  map[string]int           // Runs per code state
+	flipped bool                     // Passed and failed on the same code
+	last    string                   // Code state of the latest run
+}
+
+// TestTracker records test results across validation iterations and tells
+// regressions apart from flaky tests. A test that both passes and fails on
+// the same code is flaky; one that keeps flipping as the code changes is
+// likely flaky too. Validation should spend iterations only on the tests
+// Failures returns, and rerun the ones Unconfirmed returns first; Advice
+// puts this to the agent after each run.
+type TestTracker struct {
+	mu    sync.Mutex
+	tests map[string]*testHistory
+	state string // Code state of the latest run
+}
+
+// NewTestTracker creates an empty tracker.
+func NewTestTracker() *TestTracker {
+	return &TestTracker{tests: make(map[string]*testHistory)}
+}
+
+// Record adds a run of the test suite. state identifies the code the run
+// tested, e.g. a hash of the working tree diff; runs with the same state
+// tested the same code.
+func (t *TestTracker) Record(state string, results []TestResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state = state
+	for _, r := range results {
+		h := t.tests[r.Name]
+		if h == nil {
+			h = &testHistory{byState: make(map[string]map[bool]bool), states: make(map[string]int)}
+			t.tests[r.Name] = h
+		}
+		if r.Path != "" {
+			h.path = r.Path
+		}
+		h.runs = append(h.runs, r.Passed)
+		if h.byState[state] == nil {
+			h.byState[state] = make(map[bool]bool)
+		}
+		h.byState[state][r.Passed] = true
+		h.states[state]++
+		if h.byState[state][true] && h.byState[state][false] {
+			h.flipped = true
+		}
+		h.last = state
+	}
+}
+
+// Status classifies the named test. Tests never recorded are passing.
+func (t *TestTracker) Status(name string) TestStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status(t.tests[name])
+}
+
+// status classifies h. Callers must hold t.mu.
+func (t *TestTracker) status(h *testHistory) TestStatus {
+	if h == nil || len(h.runs) == 0 {
+		return TestPassing
+	}
+	if h.flipped || flips(h.runs) >= flipThreshold {
+		return TestFlaky
+	}
+	if h.runs[len(h.runs)-1] {
+		return TestPassing
+	}
+	for _, passed := range h.runs {
+		if passed {
+			return TestRegression
+		}
+	}
+	return TestFailing
+}
+
+// flips counts changes between passing and failing in runs.
+func flips(runs []bool) int {
+	n := 0
+	for i := 1; i < len(runs); i++ {
+		if runs[i] != runs[i-1] {
+			n++
+		}
+	}
+	return n
+}
+
+// Failures returns the tests that failed in the latest run and are not
+// flaky, sorted by name: the ones worth changing code for.
+func (t *TestTracker) Failures() []string {
+	return t.matching(func(name string, h *testHistory, status TestStatus) bool {
+		return h.last == t.state && (status == TestFailing || status == TestRegression)
+	})
+}
+
+// Flaky returns the tests found to be flaky, sorted by name.
+func (t *TestTracker) Flaky() []string {
+	return t.matching(func(name string, h *testHistory, status TestStatus) bool {
+		return status == TestFlaky
+	})
+}
+
+// Unconfirmed returns the failures that have run only once on the current
+// code. Rerunning them before trying a fix shows whether they are flaky.
+func (t *TestTracker) Unconfirmed() []string {
+	return t.matching(func(name string, h *testHistory, status TestStatus) bool {
+		return h.last == t.state && (status == TestFailing || status == TestRegression) && h.states[t.state] < 2
+	})
+}
+
+// NeedsFix reports whether the latest run has failures that are not flaky.
+func (t *TestTracker) NeedsFix() bool {
+	return len(t.Failures()) > 0
+}
+
+// Advice tells the agent what to do about the latest run: when only flaky
+// tests failed there is nothing to fix, so it should not spend another
+// iteration on them; failures seen once should be rerun before being fixed.
+// It is empty when the latest run needs no comment.
+func (t *TestTracker) Advice() string {
+	var notes []string
+	if flaky := t.Flaky(); len(flaky) > 0 {
+		notes = append(notes, fmt.Sprintf("Likely flaky, don't change code for them: %s", strings.Join(flaky, ", ")))
+	}
+	if !t.NeedsFix() {
+		if failed := t.flakyFailures(); len(failed) > 0 {
+			notes = append(notes, fmt.Sprintf("Only flaky tests failed (%s), so the code needs no fix: don't iterate on them.", strings.Join(failed, ", ")))
+		}
+	} else if unconfirmed := t.Unconfirmed(); len(unconfirmed) > 0 {
+		notes = append(notes, fmt.Sprintf("Failed once on this code; rerun them to rule out flakiness before fixing: %s", strings.Join(unconfirmed, ", ")))
+	}
+	return strings.Join(notes, "\n")
+}
+
+// flakyFailures returns the flaky tests that failed in the latest run,
+// sorted by name.
+func (t *TestTracker) flakyFailures() []string {
+	return t.matching(func(name string, h *testHistory, status TestStatus) bool {
+		return h.last == t.state && !h.runs[len(h.runs)-1] && status == TestFlaky
+	})
+}
+
+// matching returns the names of the tests keep accepts, sorted.
+func (t *TestTracker) matching(keep func(name string, h *testHistory, status TestStatus) bool) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var names []string
+	for name, h := range t.tests {
+		if keep(name, h, t.status(h)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Findings reports failing tests as errors and flaky tests as warnings, so
+// flaky tests are surfaced without failing validation.
+func (t *TestTracker) Findings() []Finding {
+	var findings []Finding
+	for _, name := range t.Failures() {
+		check, msg := "test_failure", fmt.Sprintf("%s fails", name)
+		if t.Status(name) == TestRegression {
+			check, msg = "test_regression", fmt.Sprintf("%s passed before and fails now", name)
+		}
+		findings = append(findings, Finding{Check: check, Severity: SeverityError, Message: msg, Path: t.path(name)})
+	}
+	for _, name := range t.Flaky() {
+		msg := fmt.Sprintf("%s is likely flaky: it keeps flipping between passing and failing", name)
+		if t.flippedOnSameCode(name) {
+			msg = fmt.Sprintf("%s is flaky: it both passed and failed on the same code", name)
+		}
+		findings = append(findings, Finding{Check: "flaky_test", Severity: SeverityWarning, Message: msg, Path: t.path(name)})
+	}
+	return findings
+}
+
+// flippedOnSameCode reports whether the named test both passed and failed
+// on the same code.
+func (t *TestTracker) flippedOnSameCode(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.tests[name]
+	return h != nil && h.flipped
+}
+
+// path returns the file the named test is defined in, if known.
+func (t *TestTracker) path(name string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if h := t.tests[name]; h != nil {
+		return h.path
+	}
+	return ""
+}
@@ -0,0 +1,204 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// run is one run of a single test.
+type run struct {
+	state  string
+	passed bool
+}
+
+func TestTestTracker_Status(t *testing.T) {
+	tests := []struct {
+		name        string
+		runs        []run
+		status      TestStatus
+		failures    []string
+		flaky       []string
+		unconfirmed []string
+	}{
+		{
+			name:   "pass, fail, pass on the same code is flaky",
+			runs:   []run{{"a", true}, {"a", false}, {"a", true}},
+			status: TestFlaky,
+			flaky:  []string{"TestX"},
+		},
+		{
+			name:   "fail then pass on the same code is flaky",
+			runs:   []run{{"a", false}, {"a", true}},
+			status: TestFlaky,
+			flaky:  []string{"TestX"},
+		},
+		{
+			name:   "flipping with every change is likely flaky",
+			runs:   []run{{"a", true}, {"b", false}, {"c", true}, {"d", false}},
+			status: TestFlaky,
+			flaky:  []string{"TestX"},
+		},
+		{
+			name:   "broken and fixed by edits is passing",
+			runs:   []run{{"a", true}, {"b", false}, {"c", true}},
+			status: TestPassing,
+		},
+		{
+			name:     "consistent failure after passing is a regression",
+			runs:     []run{{"a", true}, {"b", false}, {"b", false}},
+			status:   TestRegression,
+			failures: []string{"TestX"},
+		},
+		{
+			name:     "consistent failure without a pass is failing",
+			runs:     []run{{"a", false}, {"a", false}},
+			status:   TestFailing,
+			failures: []string{"TestX"},
+		},
+		{
+			name:        "a single failure is unconfirmed",
+			runs:        []run{{"a", false}},
+			status:      TestFailing,
+			failures:    []string{"TestX"},
+			unconfirmed: []string{"TestX"},
+		},
+		{
+			name:        "a new failure after passing is unconfirmed",
+			runs:        []run{{"a", true}, {"b", false}},
+			status:      TestRegression,
+			failures:    []string{"TestX"},
+			unconfirmed: []string{"TestX"},
+		},
+		{
+			name:   "passing",
+			runs:   []run{{"a", true}, {"b", true}},
+			status: TestPassing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTestTracker()
+			for _, r := range tt.runs {
+				tracker.Record(r.state, []TestResult{{Name: "TestX", Passed: r.passed}})
+			}
+			assert.Equal(t, tt.status, tracker.Status("TestX"))
+			assert.Equal(t, tt.failures, tracker.Failures())
+			assert.Equal(t, tt.flaky, tracker.Flaky())
+			assert.Equal(t, tt.unconfirmed, tracker.Unconfirmed())
+			assert.Equal(t, len(tt.failures) > 0, tracker.NeedsFix())
+		})
+	}
+}
+
+func TestTestTracker_Unrecorded(t *testing.T) {
+	tracker := NewTestTracker()
+	assert.Equal(t, TestPassing, tracker.Status("TestNever"))
+	assert.Empty(t, tracker.Findings())
+	assert.False(t, tracker.NeedsFix())
+}
+
+func TestTestTracker_FailuresOfEarlierCode(t *testing.T) {
+	tracker := NewTestTracker()
+	tracker.Record("a", []TestResult{{Name: "TestOld", Passed: false}, {Name: "TestNew", Passed: true}})
+	// Targeted runs of other tests on new code don't clear TestOld's failure
+	// from history, but it isn't a failure of the current code
+	tracker.Record("b", []TestResult{{Name: "TestNew", Passed: false}})
+
+	assert.Equal(t, []string{"TestNew"}, tracker.Failures())
+	assert.Equal(t, TestFailing, tracker.Status("TestOld"))
+}
+
+func TestTestTracker_Findings(t *testing.T) {
+	tracker := NewTestTracker()
+	tracker.Record("a", []TestResult{
+		{Name: "pkg.TestRegressed", Passed: true, Path: "pkg/a_test.go"},
+		{Name: "pkg.TestFlaky", Passed: true, Path: "pkg/b_test.go"},
+		{Name: "pkg.TestDrifting", Passed: true},
+	})
+	tracker.Record("b", []TestResult{
+		{Name: "pkg.TestRegressed", Passed: false},
+		{Name: "pkg.TestFlaky", Passed: false},
+		{Name: "pkg.TestDrifting", Passed: false},
+		{Name: "pkg.TestBroken", Passed: false, Path: "pkg/c_test.go"},
+	})
+	tracker.Record("b", []TestResult{{Name: "pkg.TestFlaky", Passed: true}})
+	tracker.Record("c", []TestResult{{Name: "pkg.TestDrifting", Passed: true}})
+	tracker.Record("d", []TestResult{
+		{Name: "pkg.TestDrifting", Passed: false},
+		{Name: "pkg.TestRegressed", Passed: false},
+		{Name: "pkg.TestBroken", Passed: false},
+	})
+
+	assert.Equal(t, []Finding{
+		{Check: "test_failure", Severity: SeverityError, Message: "pkg.TestBroken fails", Path: "pkg/c_test.go"},
+		{Check: "test_regression", Severity: SeverityError, Message: "pkg.TestRegressed passed before and fails now", Path: "pkg/a_test.go"},
+		{Check: "flaky_test", Severity: SeverityWarning, Message: "pkg.TestDrifting is likely flaky: it keeps flipping between passing and failing"},
+		{Check: "flaky_test", Severity: SeverityWarning, Message: "pkg.TestFlaky is flaky: it both passed and failed on the same code", Path: "pkg/b_test.go"},
+	}, tracker.Findings())
+}
+
+func TestTestTracker_Advice(t *testing.T) {
+	tests := []struct {
+		name     string
+		runs     [][]TestResult // Recorded in order, each on the next code state
+		same     bool           // Record every run on the same code
+		needsFix bool
+		want     string
+	}{
+		{
+			name: "all passing",
+			runs: [][]TestResult{{{Name: "TestA", Passed: true}}},
+			want: "",
+		},
+		{
+			name:     "new failure is rerun first",
+			runs:     [][]TestResult{{{Name: "TestA", Passed: false}}},
+			needsFix: true,
+			want:     "Failed once on this code; rerun them to rule out flakiness before fixing: TestA",
+		},
+		{
+			name:     "confirmed failure needs a fix",
+			runs:     [][]TestResult{{{Name: "TestA", Passed: false}}, {{Name: "TestA", Passed: false}}},
+			same:     true,
+			needsFix: true,
+			want:     "",
+		},
+		{
+			name: "only flaky failures stop iterating",
+			runs: [][]TestResult{{{Name: "TestA", Passed: true}}, {{Name: "TestA", Passed: false}}},
+			same: true,
+			want: "Likely flaky, don't change code for them: TestA\n" +
+				"Only flaky tests failed (TestA), so the code needs no fix: don't iterate on them.",
+		},
+		{
+			name:     "flaky and real failures",
+			runs:     [][]TestResult{{{Name: "TestA", Passed: true}, {Name: "TestB", Passed: false}}, {{Name: "TestA", Passed: false}, {Name: "TestB", Passed: false}}},
+			same:     true,
+			needsFix: true,
+			want:     "Likely flaky, don't change code for them: TestA",
+		},
+		{
+			name: "flaky test passing again",
+			runs: [][]TestResult{{{Name: "TestA", Passed: true}}, {{Name: "TestA", Passed: false}}, {{Name: "TestA", Passed: true}}},
+			same: true,
+			want: "Likely flaky, don't change code for them: TestA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTestTracker()
+			for i, results := range tt.runs {
+				state := string(rune('a' + i))
+				if tt.same {
+					state = "a"
+				}
+				tracker.Record(state, results)
+			}
+			assert.Equal(t, tt.needsFix, tracker.NeedsFix())
+			assert.Equal(t, tt.want, tracker.Advice())
+		})
+	}
+}
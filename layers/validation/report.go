@@ -0,0 +1,219 @@
+// Package validation implements Layer 5 (Validation) of the 7-layer architecture.
+// Findings are reported through pluggable reporters; in CI they are emitted
+// in the format the CI system uses to annotate the diff under review.
+package validation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Severity levels for findings.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNotice  = "notice"
+)
+
+// Finding is one issue found while validating the agent's work.
+type Finding struct {
+	Check    string // Checklist item or tool that produced the finding (e.g. "code_quality")
+	Severity string // SeverityError, SeverityWarning or SeverityNotice
+	Message  string
+	Path     string // File relative to the repository root; empty for run-level findings
+	Line     int    // 1-based; 0 if unknown
+	Column   int    // 1-based; 0 if unknown
+}
+
+// Reporter publishes findings.
+type Reporter interface {
+	Report(findings []Finding) error
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(findings []Finding) error
+
+// Report calls f.
+func (f ReporterFunc) Report(findings []Finding) error {
+	return f(findings)
+}
+
+// MultiReporter reports to every reporter, returning the first error.
+func MultiReporter(reporters ...Reporter) Reporter {
+	return ReporterFunc(func(findings []Finding) error {
+		var firstErr error
+		for _, r := range reporters {
+			if err := r.Report(findings); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	})
+}
+
+// GitHubReporter writes findings as GitHub Actions workflow commands
+// ("::error file=...,line=...::message"), which the runner turns into
+// annotations on the pull request diff.
+type GitHubReporter struct {
+	w io.Writer
+}
+
+// NewGitHubReporter creates a reporter writing workflow commands to w
+// (normally stdout).
+func NewGitHubReporter(w io.Writer) *GitHubReporter {
+	return &GitHubReporter{w: w}
+}
+
+// Report writes one workflow command per finding.
+func (r *GitHubReporter) Report(findings []Finding) error {
+	for _, f := range findings {
+		level := f.Severity
+		if level != SeverityError && level != SeverityWarning {
+			level = SeverityNotice
+		}
+
+		var props []string
+		if f.Path != "" {
+			props = append(props, "file="+escapeGitHubProperty(f.Path))
+			if f.Line > 0 {
+				props = append(props, fmt.Sprintf("line=%d", f.Line))
+			}
+			if f.Column > 0 {
+				props = append(props, fmt.Sprintf("col=%d", f.Column))
+			}
+		}
+		if f.Check != "" {
+			props = append(props, "title="+escapeGitHubProperty("b+ "+f.Check))
+		}
+
+		cmd := "::" + level
+		if len(props) > 0 {
+			cmd += " " + strings.Join(props, ",")
+		}
+		if _, err := fmt.Fprintf(r.w, "%s::%s\n", cmd, escapeGitHubData(f.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeGitHubData escapes workflow command message data.
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes workflow command property values.
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+// GitLabReporter writes findings as a GitLab Code Quality report, which
+// merge requests show inline when the job publishes it as a
+// codequality artifact.
+type GitLabReporter struct {
+	path string
+}
+
+// NewGitLabReporter creates a reporter writing the JSON report to path.
+func NewGitLabReporter(path string) *GitLabReporter {
+	return &GitLabReporter{path: path}
+}
+
+// Report writes the code quality report. Run-level findings without a file
+// are omitted, since GitLab requires a location.
+func (r *GitLabReporter) Report(findings []Finding) error {
+	issues := make([]codeQualityIssue, 0, len(findings))
+	for _, f := range findings {
+		if f.Path == "" {
+			continue
+		}
+		line := f.Line
+		if line <= 0 {
+			line = 1
+		}
+
+		issue := codeQualityIssue{
+			Description: f.Message,
+			CheckName:   f.Check,
+			Fingerprint: fingerprint(f),
+			Severity:    gitLabSeverity(f.Severity),
+		}
+		issue.Location.Path = f.Path
+		issue.Location.Lines.Begin = line
+		issues = append(issues, issue)
+	}
+
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode code quality report: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write code quality report: %w", err)
+	}
+	return nil
+}
+
+// gitLabSeverity maps a finding severity to a Code Quality severity.
+func gitLabSeverity(severity string) string {
+	switch severity {
+	case SeverityError:
+		return "major"
+	case SeverityWarning:
+		return "minor"
+	default:
+		return "info"
+	}
+}
+
+// fingerprint identifies a finding across runs so GitLab can tell new
+// issues from ones already present on the target branch. The line is left
+// out so unrelated edits above a finding don't make it look new.
+func fingerprint(f Finding) string {
+	sum := sha256.Sum256([]byte(f.Check + "\x00" + f.Path + "\x00" + f.Message))
+	return hex.EncodeToString(sum[:16])
+}
+
+// DefaultCodeQualityPath is where the GitLab report is written unless
+// BPLUS_CODE_QUALITY_REPORT is set.
+const DefaultCodeQualityPath = "gl-code-quality-report.json"
+
+// CIReporter returns a reporter for the CI system detected from the
+// environment, or nil when not running in a supported CI system.
+func CIReporter(getenv func(string) string) Reporter {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		return NewGitHubReporter(os.Stdout)
+	case getenv("GITLAB_CI") == "true":
+		path := getenv("BPLUS_CODE_QUALITY_REPORT")
+		if path == "" {
+			path = DefaultCodeQualityPath
+		}
+		return NewGitLabReporter(path)
+	default:
+		return nil
+	}
+}
+
+// Code Quality report types
+
+type codeQualityIssue struct {
+	Description string `json:"description"`
+	CheckName   string `json:"check_name"`
+	Fingerprint string `json:"fingerprint"`
+	Severity    string `json:"severity"`
+	Location    struct {
+		Path  string `json:"path"`
+		Lines struct {
+			Begin int `json:"begin"`
+		} `json:"lines"`
+	} `json:"location"`
+}
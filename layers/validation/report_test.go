@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubReporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		finding Finding
+		want    string
+	}{
+		{
+			name:    "error with location",
+			finding: Finding{Check: "lsp", Severity: SeverityError, Message: "undefined: foo", Path: "app/app.go", Line: 12, Column: 3},
+			want:    "::error file=app/app.go,line=12,col=3,title=b+ lsp::undefined: foo\n",
+		},
+		{
+			name:    "message data is escaped",
+			finding: Finding{Severity: SeverityWarning, Message: "100% done\r\nnext: a, b"},
+			want:    "::warning::100%25 done%0D%0Anext: a, b\n",
+		},
+		{
+			name:    "properties are escaped",
+			finding: Finding{Check: "x:y", Severity: SeverityError, Message: "m", Path: "dir,1/50%:a\nb.go", Line: 1},
+			want:    "::error file=dir%2C1/50%25%3Aa%0Ab.go,line=1,title=b+ x%3Ay::m\n",
+		},
+		{
+			name:    "other severities are notices",
+			finding: Finding{Severity: "info", Message: "fyi"},
+			want:    "::notice::fyi\n",
+		},
+		{
+			name:    "line without a file is dropped",
+			finding: Finding{Severity: SeverityError, Message: "run failed", Line: 4},
+			want:    "::error::run failed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, NewGitHubReporter(&buf).Report([]Finding{tt.finding}))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestGitLabReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	findings := []Finding{
+		{Check: "test_failure", Severity: SeverityError, Message: "TestA fails", Path: "a_test.go", Line: 7},
+		{Check: "flaky_test", Severity: SeverityWarning, Message: "TestB is flaky", Path: "b_test.go"},
+		{Check: "lsp", Severity: SeverityNotice, Message: "unused", Path: "c.go", Line: 2},
+		{Check: "run", Severity: SeverityError, Message: "no location"},
+	}
+	require.NoError(t, NewGitLabReporter(path).Report(findings))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var issues []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &issues))
+	require.Len(t, issues, 3, "findings without a file are left out")
+
+	assert.Equal(t, map[string]interface{}{
+		"description": "TestA fails",
+		"check_name":  "test_failure",
+		"fingerprint": fingerprint(findings[0]),
+		"severity":    "major",
+		"location": map[string]interface{}{
+			"path":  "a_test.go",
+			"lines": map[string]interface{}{"begin": float64(7)},
+		},
+	}, issues[0])
+	assert.Equal(t, "minor", issues[1]["severity"])
+	assert.Equal(t, map[string]interface{}{"begin": float64(1)}, issues[1]["location"].(map[string]interface{})["lines"])
+	assert.Equal(t, "info", issues[2]["severity"])
+
+	// An empty run writes an empty list, which GitLab reads as no issues
+	require.NoError(t, NewGitLabReporter(path).Report(nil))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(data))
+}
+
+func TestFingerprint(t *testing.T) {
+	f := Finding{Check: "lsp", Severity: SeverityError, Message: "undefined: foo", Path: "app/app.go", Line: 12}
+	assert.Len(t, fingerprint(f), 32)
+	assert.Equal(t, fingerprint(f), fingerprint(f))
+
+	// Moving the finding keeps it the same issue
+	moved := f
+	moved.Line, moved.Column = 40, 5
+	assert.Equal(t, fingerprint(f), fingerprint(moved))
+
+	for _, other := range []Finding{
+		{Check: "lsp", Message: "undefined: bar", Path: "app/app.go"},
+		{Check: "lsp", Message: "undefined: foo", Path: "app/other.go"},
+		{Check: "vet", Message: "undefined: foo", Path: "app/app.go"},
+		// Fields are separated, so they can't run into each other
+		{Check: "ls", Message: "undefined: foo", Path: "papp/app.go"},
+	} {
+		assert.NotEqual(t, fingerprint(f), fingerprint(other), "%+v", other)
+	}
+}
+
+func TestCIReporter(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	assert.Nil(t, CIReporter(env(nil)))
+	assert.Nil(t, CIReporter(env(map[string]string{"GITHUB_ACTIONS": "false"})))
+	assert.IsType(t, &GitHubReporter{}, CIReporter(env(map[string]string{"GITHUB_ACTIONS": "true"})))
+
+	r := CIReporter(env(map[string]string{"GITLAB_CI": "true"}))
+	require.IsType(t, &GitLabReporter{}, r)
+	assert.Equal(t, DefaultCodeQualityPath, r.(*GitLabReporter).path)
+
+	r = CIReporter(env(map[string]string{"GITLAB_CI": "true", "BPLUS_CODE_QUALITY_REPORT": "out/cq.json"}))
+	assert.Equal(t, "out/cq.json", r.(*GitLabReporter).path)
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxAttachmentSize is the largest image accepted as an attachment. It is
+// the smallest per-image limit of the supported providers.
+const MaxAttachmentSize = 5 * 1024 * 1024
+
+// imageTypes are the image MIME types every supported provider accepts.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Attachment is an image sent along with a message.
+type Attachment struct {
+	Path     string // File the image was read from, if any
+	Data     []byte // Image bytes
+	MimeType string // e.g. "image/png"
+}
+
+// LoadAttachment reads an image file for attaching to a message.
+func LoadAttachment(path string) (Attachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return NewAttachment(path, data)
+}
+
+// NewAttachment creates an attachment from image bytes (e.g. a pasted
+// screenshot), detecting the MIME type from the data. path is informational
+// and may be empty.
+func NewAttachment(path string, data []byte) (Attachment, error) {
+	if len(data) == 0 {
+		return Attachment{}, fmt.Errorf("attachment %s is empty", attachmentName(path))
+	}
+	if len(data) > MaxAttachmentSize {
+		return Attachment{}, fmt.Errorf("attachment %s is %d bytes, over the %d byte limit",
+			attachmentName(path), len(data), MaxAttachmentSize)
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !imageTypes[mimeType] {
+		return Attachment{}, fmt.Errorf("attachment %s is %s; only PNG, JPEG, GIF and WebP images are supported",
+			attachmentName(path), mimeType)
+	}
+
+	return Attachment{Path: path, Data: data, MimeType: mimeType}, nil
+}
+
+// Base64 returns the image data base64-encoded.
+func (a Attachment) Base64() string {
+	return base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// DataURL returns the image as a data: URL.
+func (a Attachment) DataURL() string {
+	return "data:" + a.MimeType + ";base64," + a.Base64()
+}
+
+func attachmentName(path string) string {
+	if path == "" {
+		return "(pasted)"
+	}
+	return strings.TrimSpace(filepath.Base(path))
+}
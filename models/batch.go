@@ -0,0 +1,182 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BatchDiscount is the share of the regular price batched requests cost
+// at providers with a batch API.
+const BatchDiscount = 0.5
+
+// DefaultBatchPollInterval is how often RunBatch checks on a batch when the
+// caller doesn't say.
+const DefaultBatchPollInterval = 30 * time.Second
+
+// Batcher is implemented by providers that accept many completion requests
+// at once, answered in the background within a day at a lower price. It
+// suits work nobody waits on, such as Thorough Mode planning, where a
+// batch is cheaper than the same calls made one by one.
+type Batcher interface {
+	// CreateBatch submits requests, each with an ID unique in the batch,
+	// and returns the batch as accepted.
+	CreateBatch(ctx context.Context, reqs []BatchRequest) (*Batch, error)
+
+	// GetBatch returns the progress of a batch.
+	GetBatch(ctx context.Context, id string) (*Batch, error)
+
+	// BatchResults returns the results of a batch that is done, in no
+	// particular order.
+	BatchResults(ctx context.Context, id string) ([]BatchResult, error)
+
+	// CancelBatch stops a batch. Requests already answered keep their
+	// results.
+	CancelBatch(ctx context.Context, id string) error
+}
+
+// BatchRequest is one request of a batch.
+type BatchRequest struct {
+	ID      string // Unique in the batch; results carry it back
+	Request *CompletionRequest
+}
+
+// BatchStatus is the state of a batch.
+type BatchStatus string
+
+// Batch states
+const (
+	BatchInProgress BatchStatus = "in_progress" // Being validated or answered
+	BatchEnded      BatchStatus = "ended"       // Every request has a result
+	BatchCanceled   BatchStatus = "canceled"    // Stopped before it ended
+	BatchExpired    BatchStatus = "expired"     // Not answered in time
+	BatchFailed     BatchStatus = "failed"      // Rejected as a whole
+)
+
+// Batch is a submitted batch.
+type Batch struct {
+	ID        string
+	Status    BatchStatus
+	Requests  int // Requests in the batch
+	Succeeded int // Requests answered so far
+	Failed    int // Requests that failed so far
+	CreatedAt time.Time
+	ExpiresAt time.Time // When unanswered requests expire, if known
+}
+
+// Done reports whether the batch has stopped changing.
+func (b *Batch) Done() bool {
+	return b.Status != BatchInProgress
+}
+
+// BatchResult is the outcome of one request of a batch.
+type BatchResult struct {
+	ID       string
+	Response *CompletionResponse // Nil if the request failed
+	Error    error
+}
+
+// AsBatcher returns p as a Batcher, looking through wrapping providers.
+// Requests are batched as the wrappers would send them: redacted and fitted
+// to the context window.
+func AsBatcher(p Provider) (Batcher, bool) {
+	var prepare []func(*CompletionRequest) (*CompletionRequest, error)
+	for p != nil {
+		if b, ok := p.(Batcher); ok {
+			if len(prepare) == 0 {
+				return b, true
+			}
+			return &preparedBatcher{Batcher: b, prepare: prepare}, true
+		}
+		if rp, ok := p.(requestPreparer); ok {
+			prepare = append(prepare, rp.prepare)
+		}
+		u, ok := p.(interface{ Unwrap() Provider })
+		if !ok {
+			break
+		}
+		p = u.Unwrap()
+	}
+	return nil, false
+}
+
+// requestPreparer is implemented by wrapping providers that change requests
+// before passing them on.
+type requestPreparer interface {
+	prepare(req *CompletionRequest) (*CompletionRequest, error)
+}
+
+// preparedBatcher prepares batched requests as the wrappers around the
+// batcher would.
+type preparedBatcher struct {
+	Batcher
+	prepare []func(*CompletionRequest) (*CompletionRequest, error) // Outermost first
+}
+
+// CreateBatch submits the prepared requests. A request that can't be
+// prepared fails the batch before it is submitted.
+func (b *preparedBatcher) CreateBatch(ctx context.Context, reqs []BatchRequest) (*Batch, error) {
+	prepared := make([]BatchRequest, len(reqs))
+	for i, r := range reqs {
+		req := r.Request
+		for _, prepare := range b.prepare {
+			var err error
+			if req, err = prepare(req); err != nil {
+				return nil, fmt.Errorf("batch request %s: %w", r.ID, err)
+			}
+		}
+		prepared[i] = BatchRequest{ID: r.ID, Request: req}
+	}
+	return b.Batcher.CreateBatch(ctx, prepared)
+}
+
+// RunBatch submits requests as one batch and waits for it to be done,
+// checking every poll (DefaultBatchPollInterval if 0). Results are returned
+// in the order of reqs; requests the batch didn't answer, such as those of
+// an expired batch, get an error. If ctx ends first the batch is canceled.
+func RunBatch(ctx context.Context, b Batcher, reqs []BatchRequest, poll time.Duration) ([]BatchResult, error) {
+	if poll <= 0 {
+		poll = DefaultBatchPollInterval
+	}
+	batch, err := b.CreateBatch(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for !batch.Done() {
+		select {
+		case <-ctx.Done():
+			cancelCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			_ = b.CancelBatch(cancelCtx, batch.ID)
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if batch, err = b.GetBatch(ctx, batch.ID); err != nil {
+			return nil, err
+		}
+	}
+	if batch.Status == BatchFailed {
+		return nil, fmt.Errorf("batch %s failed", batch.ID)
+	}
+
+	results, err := b.BatchResults(ctx, batch.ID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]BatchResult, len(results))
+	for _, r := range results {
+		byID[r.ID] = r
+	}
+	ordered := make([]BatchResult, len(reqs))
+	for i, r := range reqs {
+		result, ok := byID[r.ID]
+		if !ok {
+			result = BatchResult{ID: r.ID, Error: fmt.Errorf("batch %s %s without answering request %s", batch.ID, batch.Status, r.ID)}
+		}
+		ordered[i] = result
+	}
+	return ordered, nil
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Capabilities describes what a model supports.
+type Capabilities struct {
+	ContextWindow     int  // Maximum context window in tokens
+	Vision            bool // Accepts image attachments
+	Tools             bool // Supports tool/function calling
+	JSONMode          bool // Can be constrained to emit JSON
+	ParallelToolCalls bool // Can request several tool calls in one turn
+}
+
+// Requirements describes what a run needs from a model. Zero fields are not
+// required.
+type Requirements struct {
+	ContextTokens     int // Tokens the prompt needs
+	Vision            bool
+	Tools             bool
+	JSONMode          bool
+	ParallelToolCalls bool
+}
+
+// Missing lists the requirements caps does not meet.
+func (r Requirements) Missing(caps Capabilities) []string {
+	var missing []string
+	if r.Tools && !caps.Tools {
+		missing = append(missing, "tool calling")
+	}
+	if r.ParallelToolCalls && !caps.ParallelToolCalls {
+		missing = append(missing, "parallel tool calls")
+	}
+	if r.Vision && !caps.Vision {
+		missing = append(missing, "image input")
+	}
+	if r.JSONMode && !caps.JSONMode {
+		missing = append(missing, "JSON mode")
+	}
+	if r.ContextTokens > 0 && caps.ContextWindow > 0 && r.ContextTokens > caps.ContextWindow {
+		missing = append(missing, fmt.Sprintf("a %d token context (has %d)", r.ContextTokens, caps.ContextWindow))
+	}
+	return missing
+}
+
+// CapabilityError reports a model that cannot run what was asked of it.
+type CapabilityError struct {
+	Model   string
+	Missing []string
+}
+
+// Error implements error.
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("model %s does not support %s", e.Model, strings.Join(e.Missing, ", "))
+}
+
+// capabilityEntry gives the capabilities of the models whose ID starts with
+// prefix.
+type capabilityEntry struct {
+	prefix string
+	caps   Capabilities
+}
+
+// knownCapabilities lists the capabilities of well-known model families by
+// model ID prefix; the longest matching prefix wins. Models are matched
+// regardless of the provider serving them, so e.g. an Anthropic model
+// reached through OpenRouter is found too.
+var knownCapabilities = []capabilityEntry{
+	// Anthropic
+	{"claude-", Capabilities{ContextWindow: 200000, Vision: true, Tools: true, ParallelToolCalls: true}},
+	{"claude-2", Capabilities{ContextWindow: 100000}},
+	{"claude-instant", Capabilities{ContextWindow: 100000}},
+
+	// OpenAI
+	{"gpt-3.5-turbo", Capabilities{ContextWindow: 16385, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+	{"gpt-4", Capabilities{ContextWindow: 8192, Tools: true}},
+	{"gpt-4-turbo", Capabilities{ContextWindow: 128000, Vision: true, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+	{"gpt-4o", Capabilities{ContextWindow: 128000, Vision: true, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+	{"gpt-4.1", Capabilities{ContextWindow: 1047576, Vision: true, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+	{"gpt-5", Capabilities{ContextWindow: 400000, Vision: true, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+	{"o1", Capabilities{ContextWindow: 200000, Vision: true, Tools: true, JSONMode: true}},
+	{"o1-mini", Capabilities{ContextWindow: 128000}},
+	{"o1-preview", Capabilities{ContextWindow: 128000}},
+	{"o3", Capabilities{ContextWindow: 200000, Vision: true, Tools: true, JSONMode: true}},
+	{"o3-mini", Capabilities{ContextWindow: 200000, Tools: true, JSONMode: true}},
+	{"o4-mini", Capabilities{ContextWindow: 200000, Vision: true, Tools: true, JSONMode: true}},
+
+	// Google
+	{"gemini-1.5-pro", Capabilities{ContextWindow: 2097152, Vision: true, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+	{"gemini-1.5-flash", Capabilities{ContextWindow: 1048576, Vision: true, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+	{"gemini-2", Capabilities{ContextWindow: 1048576, Vision: true, Tools: true, JSONMode: true, ParallelToolCalls: true}},
+
+	// Local model families (Ollama and LM Studio tags)
+	{"llama3.1", Capabilities{ContextWindow: 131072, Tools: true, JSONMode: true}},
+	{"llama3.2", Capabilities{ContextWindow: 131072, Tools: true, JSONMode: true}},
+	{"llama3.2-vision", Capabilities{ContextWindow: 131072, Vision: true, JSONMode: true}},
+	{"llama3", Capabilities{ContextWindow: 8192, JSONMode: true}},
+	{"qwen2.5", Capabilities{ContextWindow: 32768, Tools: true, JSONMode: true}},
+	{"qwen3", Capabilities{ContextWindow: 40960, Tools: true, JSONMode: true}},
+	{"mistral", Capabilities{ContextWindow: 32768, Tools: true, JSONMode: true}},
+	{"codellama", Capabilities{ContextWindow: 16384, JSONMode: true}},
+	{"deepseek-r1", Capabilities{ContextWindow: 131072, JSONMode: true}},
+	{"llava", Capabilities{ContextWindow: 4096, Vision: true, JSONMode: true}},
+}
+
+var (
+	capabilitiesMu     sync.RWMutex
+	customCapabilities = map[string]Capabilities{} // Keyed by "provider/model-id"
+)
+
+// RegisterCapabilities records the capabilities of a model given as
+// "provider/model-id", overriding the built-in table. Providers call it for
+// models whose capabilities they discover, and users can describe models
+// the table does not know.
+func RegisterCapabilities(model string, caps Capabilities) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	customCapabilities[model] = caps
+}
+
+// LookupCapabilities returns the capabilities of a model given as
+// "provider/model-id" or a bare model ID. ok is false for unknown models.
+func LookupCapabilities(model string) (caps Capabilities, ok bool) {
+	capabilitiesMu.RLock()
+	caps, ok = customCapabilities[model]
+	capabilitiesMu.RUnlock()
+	if ok {
+		return caps, true
+	}
+
+	// Strip the provider and any routing prefix, e.g. "openrouter/openai/gpt-4o"
+	id := strings.ToLower(model)
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+
+	best := -1
+	for _, entry := range knownCapabilities {
+		if strings.HasPrefix(id, entry.prefix) && len(entry.prefix) > best {
+			caps, best = entry.caps, len(entry.prefix)
+		}
+	}
+	return caps, best >= 0
+}
+
+// CheckCapabilities returns a *CapabilityError if model is known not to meet
+// req. Unknown models are assumed capable, so new models are never rejected.
+func CheckCapabilities(model string, req Requirements) error {
+	caps, ok := LookupCapabilities(model)
+	if !ok {
+		return nil
+	}
+	if missing := req.Missing(caps); len(missing) > 0 {
+		return &CapabilityError{Model: model, Missing: missing}
+	}
+	return nil
+}
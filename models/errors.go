@@ -0,0 +1,239 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/errors"
+)
+
+// maxErrorMessage bounds the raw response body used as an error message when
+// the body is not a recognised error document.
+const maxErrorMessage = 500
+
+// NewHTTPError builds the error for a failed provider response from its
+// status, headers and body. The error JSON of the Anthropic, OpenAI
+// (and compatible), Gemini, Cohere and Ollama APIs is recognised, so the
+// error carries the provider's own message and type and is classified as an
+// auth, quota, rate limit or overload failure.
+func NewHTTPError(provider string, resp *http.Response, body []byte) *ProviderError {
+	e := &ProviderError{
+		Provider: provider,
+		Code:     fmt.Sprintf("HTTP_%d", resp.StatusCode),
+		Status:   resp.StatusCode,
+	}
+
+	var retryDelay time.Duration
+	e.Type, e.Message, retryDelay = parseErrorBody(body)
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+		if len(e.Message) > maxErrorMessage {
+			e.Message = e.Message[:maxErrorMessage] + "..."
+		}
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+
+	e.RetryAfter = parseRetryAfter(resp.Header)
+	if e.RetryAfter == 0 {
+		e.RetryAfter = retryDelay
+	}
+
+	e.Kind = classifyError(resp.StatusCode, e.Type, e.Message)
+	e.Retryable = retryableKind(e.Kind) || e.Kind == errors.ErrCodeProvider && resp.StatusCode >= 500
+	return e
+}
+
+// NewAPIError builds the error for a failure a provider reports outside the
+// HTTP status, such as an error event in a response stream, classifying it
+// by its type or code.
+func NewAPIError(provider, errType, message string) *ProviderError {
+	e := &ProviderError{
+		Provider: provider,
+		Code:     errType,
+		Type:     errType,
+		Message:  message,
+	}
+	e.Kind = classifyError(0, errType, message)
+	e.Retryable = retryableKind(e.Kind)
+	return e
+}
+
+// retryableKind reports whether failures of a kind pass by themselves.
+func retryableKind(kind errors.ErrorCode) bool {
+	switch kind {
+	case errors.ErrCodeProviderRateLimit, errors.ErrCodeProviderOverloaded, errors.ErrCodeProviderTimeout:
+		return true
+	}
+	return false
+}
+
+// errorBody covers the error documents of the supported APIs:
+//
+//	Anthropic: {"type": "error", "error": {"type": "overloaded_error", "message": "..."}}
+//	OpenAI:    {"error": {"message": "...", "type": "insufficient_quota", "code": "insufficient_quota"}}
+//	Gemini:    {"error": {"code": 429, "message": "...", "status": "RESOURCE_EXHAUSTED", "details": [...]}}
+//	Cohere:    {"message": "..."}
+//	Ollama:    {"error": "..."}
+type errorBody struct {
+	Error   json.RawMessage `json:"error"`
+	Message string          `json:"message"`
+}
+
+type errorDetail struct {
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code"`
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Details []struct {
+		Type       string `json:"@type"`
+		RetryDelay string `json:"retryDelay"`
+	} `json:"details"`
+}
+
+// parseErrorBody extracts the error type, message and any retry delay from
+// a provider error document. Unrecognised bodies yield empty values.
+func parseErrorBody(body []byte) (errType, message string, retryDelay time.Duration) {
+	var doc errorBody
+	if json.Unmarshal(body, &doc) != nil {
+		return "", "", 0
+	}
+	if len(doc.Error) == 0 {
+		return "", doc.Message, 0
+	}
+
+	var text string
+	if json.Unmarshal(doc.Error, &text) == nil {
+		return "", text, 0
+	}
+
+	var detail errorDetail
+	if json.Unmarshal(doc.Error, &detail) != nil {
+		return "", doc.Message, 0
+	}
+	errType = detail.Type
+	if errType == "" {
+		errType = detail.Status
+	}
+	if errType == "" {
+		// OpenAI-compatible servers sometimes only send a string code
+		var code string
+		if json.Unmarshal(detail.Code, &code) == nil {
+			errType = code
+		}
+	}
+	for _, d := range detail.Details {
+		if strings.HasSuffix(d.Type, "RetryInfo") {
+			retryDelay, _ = time.ParseDuration(d.RetryDelay)
+		}
+	}
+	return errType, detail.Message, retryDelay
+}
+
+// parseRetryAfter reads the Retry-After header, given in seconds or as an
+// HTTP date, and the millisecond variant some APIs send.
+func parseRetryAfter(h http.Header) time.Duration {
+	if ms, err := strconv.Atoi(h.Get("Retry-After-Ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && time.Until(t) > 0 {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// Error types and message fragments that mark exhausted credits or quota
+// rather than a passing rate limit; retrying does not help with these.
+var (
+	quotaTypes = []string{"insufficient_quota", "billing_hard_limit_reached", "billing_not_active", "billing_error"}
+	quotaHints = []string{"credit balance", "billing", "quota", "insufficient credits", "payment"}
+)
+
+// classifyError maps a provider response to an error code.
+func classifyError(status int, errType, message string) errors.ErrorCode {
+	t := strings.ToLower(errType)
+	msg := strings.ToLower(message)
+
+	for _, q := range quotaTypes {
+		if t == q {
+			return errors.ErrCodeProviderQuota
+		}
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden ||
+		t == "authentication_error" || t == "permission_error" || t == "invalid_api_key" ||
+		t == "unauthenticated" || t == "permission_denied":
+		return errors.ErrCodeProviderAuth
+	case status == http.StatusPaymentRequired:
+		return errors.ErrCodeProviderQuota
+	case status == http.StatusTooManyRequests || t == "rate_limit_error" || t == "rate_limit_exceeded" ||
+		t == "resource_exhausted":
+		for _, hint := range quotaHints {
+			// A 429 for an exhausted daily or monthly quota is not a passing limit
+			if strings.Contains(msg, hint) && !strings.Contains(msg, "per minute") {
+				return errors.ErrCodeProviderQuota
+			}
+		}
+		return errors.ErrCodeProviderRateLimit
+	case status == 529 || status == http.StatusServiceUnavailable || t == "overloaded_error" || t == "unavailable":
+		return errors.ErrCodeProviderOverloaded
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout || t == "deadline_exceeded":
+		return errors.ErrCodeProviderTimeout
+	case t == "context_length_exceeded" || strings.Contains(msg, "prompt is too long") ||
+		strings.Contains(msg, "maximum context length"):
+		// The guard in WithContextGuard missed it, e.g. for an unknown model
+		return errors.ErrCodeContextOverflow
+	case status == http.StatusBadRequest && strings.Contains(msg, "credit balance"):
+		// Anthropic reports an empty credit balance as an invalid request
+		return errors.ErrCodeProviderQuota
+	}
+	return errors.ErrCodeProvider
+}
+
+// ErrorCode returns the error's classification, implementing errors.Typed.
+func (e *ProviderError) ErrorCode() errors.ErrorCode {
+	if e.Kind == "" {
+		return errors.ErrCodeProvider
+	}
+	return e.Kind
+}
+
+// RetryDelay returns how long the provider asked callers to wait before
+// retrying.
+func (e *ProviderError) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// UserMessage returns what to tell the user about the error, implementing
+// errors.Typed.
+func (e *ProviderError) UserMessage() string {
+	switch e.Kind {
+	case errors.ErrCodeProviderAuth:
+		return fmt.Sprintf("Authentication failed for %s. Please check your API key.", e.Provider)
+	case errors.ErrCodeProviderQuota:
+		return fmt.Sprintf("%s rejected the request for quota or billing reasons (%s). Please check your plan and credits.", e.Provider, e.Message)
+	case errors.ErrCodeProviderRateLimit:
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("%s is rate limiting requests. Try again in %s.", e.Provider, e.RetryAfter.Round(time.Second))
+		}
+		return fmt.Sprintf("%s is rate limiting requests. Please try again shortly.", e.Provider)
+	case errors.ErrCodeProviderOverloaded:
+		return fmt.Sprintf("%s is overloaded right now. Please try again shortly.", e.Provider)
+	case errors.ErrCodeProviderTimeout:
+		return fmt.Sprintf("The AI provider (%s) is not responding. Please try again.", e.Provider)
+	}
+	return ""
+}
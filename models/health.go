@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/errors"
+)
+
+// AuthState is what a connection test revealed about a provider's credentials.
+type AuthState string
+
+const (
+	AuthOK      AuthState = "ok"      // The provider accepted the connection test
+	AuthFailed  AuthState = "failed"  // Credentials are missing or were rejected
+	AuthUnknown AuthState = "unknown" // The test failed for another reason
+)
+
+// ProviderHealth is the outcome of testing one provider's connection.
+type ProviderHealth struct {
+	Provider  string
+	Latency   time.Duration // How long the connection test took
+	Auth      AuthState
+	Err       error       // Nil when the provider is healthy
+	Load      *ServerLoad // Nil unless the provider's server reports its load
+	CheckedAt time.Time
+}
+
+// ServerLoad is how busy a self-hosted model server is.
+type ServerLoad struct {
+	RequestsRunning int     // Requests currently being processed
+	RequestsWaiting int     // Requests queued for processing
+	KVCacheUsage    float64 // Fraction of the KV cache in use (0-1)
+}
+
+// String formats the load compactly, e.g. "3 running, 7 waiting, KV 42%".
+func (l ServerLoad) String() string {
+	return fmt.Sprintf("%d running, %d waiting, KV %.0f%%", l.RequestsRunning, l.RequestsWaiting, l.KVCacheUsage*100)
+}
+
+// LoadReporter is implemented by providers whose server reports its load,
+// such as vLLM.
+type LoadReporter interface {
+	Metrics(ctx context.Context) (*ServerLoad, error)
+}
+
+// Healthy reports whether the connection test passed.
+func (h ProviderHealth) Healthy() bool {
+	return h.Err == nil
+}
+
+// CheckProvider tests p's connection, timing it and classifying any failure.
+// The load of healthy servers that report it is read too; a server whose
+// metrics cannot be read is reported without load.
+func CheckProvider(ctx context.Context, p Provider) ProviderHealth {
+	start := time.Now()
+	err := p.TestConnection(ctx)
+	h := ProviderHealth{
+		Provider: p.Name(),
+		Latency:  time.Since(start),
+		Auth:     authState(err),
+		Err:      err,
+	}
+	if reporter, ok := unwrapAs[LoadReporter](p); ok && err == nil {
+		h.Load, _ = reporter.Metrics(ctx)
+	}
+	h.CheckedAt = time.Now()
+	return h
+}
+
+// authState classifies a connection test error. Providers without a key to
+// send fail before any request, with an error naming the missing key.
+func authState(err error) AuthState {
+	switch {
+	case err == nil:
+		return AuthOK
+	case errors.Is(err, errors.ErrCodeProviderAuth), strings.Contains(strings.ToLower(err.Error()), "api key"):
+		return AuthFailed
+	default:
+		return AuthUnknown
+	}
+}
+
+// CheckAll tests every provider concurrently and returns the results sorted
+// by provider name.
+func (r *Registry) CheckAll(ctx context.Context) []ProviderHealth {
+	providers := r.ListAll()
+
+	results := make([]ProviderHealth, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = CheckProvider(ctx, provider)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Provider < results[j].Provider })
+	return results
+}
@@ -2,8 +2,15 @@ package models
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/abrksh22/bplus/internal/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -131,6 +138,15 @@ func TestGetProviderFromModel(t *testing.T) {
 }
 
 // TestGetModelIDFromModel tests model ID extraction.
+func TestIsLocalProvider(t *testing.T) {
+	assert.True(t, IsLocalProvider("ollama"))
+	assert.True(t, IsLocalProvider("lmstudio"))
+	assert.True(t, IsLocalProvider(" Ollama "))
+	assert.False(t, IsLocalProvider("anthropic"))
+	assert.False(t, IsLocalProvider("openrouter"))
+	assert.False(t, IsLocalProvider(""))
+}
+
 func TestGetModelIDFromModel(t *testing.T) {
 	modelID, err := GetModelIDFromModel("anthropic/claude-sonnet-4-5")
 	require.NoError(t, err)
@@ -212,6 +228,28 @@ var ErrModelNotFound = &ProviderError{
 	Message:  "model not found",
 }
 
+// TestModelAliases tests resolving model aliases.
+func TestModelAliases(t *testing.T) {
+	SetAliases(map[string]string{
+		"sonnet": "anthropic/claude-sonnet-4-5",
+		"cheap":  "openrouter/meta-llama/llama-3.1-70b-instruct",
+		"claude": "anthropic/claude-sonnet-4-5",
+	})
+	defer SetAliases(nil)
+
+	provider, modelID, err := ParseModelName("cheap")
+	require.NoError(t, err)
+	assert.Equal(t, "openrouter", provider)
+	assert.Equal(t, "meta-llama/llama-3.1-70b-instruct", modelID)
+
+	assert.Equal(t, "anthropic/claude-sonnet-4-5", ResolveAlias(" sonnet "))
+	assert.Equal(t, "openai/gpt-4o", ResolveAlias("openai/gpt-4o"))
+	assert.Equal(t, []string{"claude", "sonnet"}, AliasesFor("anthropic/claude-sonnet-4-5"))
+
+	_, _, err = ParseModelName("unknown")
+	assert.Error(t, err)
+}
+
 // TestRegistry tests the provider registry.
 func TestRegistry(t *testing.T) {
 	t.Run("Register and Get", func(t *testing.T) {
@@ -281,6 +319,57 @@ func TestRegistry(t *testing.T) {
 		assert.NoError(t, results["provider1"])
 		assert.Error(t, results["provider2"])
 	})
+
+	t.Run("CheckAll", func(t *testing.T) {
+		registry := NewRegistry()
+
+		_ = registry.Register(&mockProvider{name: "openai", testConnectError: &ProviderError{Provider: "openai", Message: "invalid key", Kind: errors.ErrCodeProviderAuth}})
+		_ = registry.Register(&mockProvider{name: "anthropic"})
+		_ = registry.Register(&mockProvider{name: "gemini", testConnectError: fmt.Errorf("API key not set")})
+		_ = registry.Register(&mockProvider{name: "ollama", testConnectError: assert.AnError})
+
+		results := registry.CheckAll(context.Background())
+		require.Len(t, results, 4)
+		got := make(map[string]AuthState)
+		for _, h := range results {
+			got[h.Provider] = h.Auth
+			assert.False(t, h.CheckedAt.IsZero())
+		}
+		assert.Equal(t, map[string]AuthState{"anthropic": AuthOK, "gemini": AuthFailed, "ollama": AuthUnknown, "openai": AuthFailed}, got)
+		assert.Equal(t, "anthropic", results[0].Provider)
+		assert.True(t, results[0].Healthy())
+		assert.False(t, results[3].Healthy())
+	})
+
+	t.Run("CheckProvider reads server load", func(t *testing.T) {
+		load := &ServerLoad{RequestsRunning: 2, RequestsWaiting: 1, KVCacheUsage: 0.5}
+		healthy := &loadProvider{mockProvider: mockProvider{name: "vllm"}, load: load}
+		h := CheckProvider(context.Background(), healthy)
+		assert.Equal(t, load, h.Load)
+		assert.Equal(t, "2 running, 1 waiting, KV 50%", h.Load.String())
+
+		// Load is neither read from servers that are down nor required
+		down := &loadProvider{mockProvider: mockProvider{name: "vllm", testConnectError: assert.AnError}, load: load}
+		assert.Nil(t, CheckProvider(context.Background(), down).Load)
+		assert.Zero(t, down.reads)
+		failing := &loadProvider{mockProvider: mockProvider{name: "vllm"}, err: assert.AnError}
+		h = CheckProvider(context.Background(), failing)
+		assert.True(t, h.Healthy())
+		assert.Nil(t, h.Load)
+	})
+}
+
+// loadProvider is a provider whose server reports its load.
+type loadProvider struct {
+	mockProvider
+	load  *ServerLoad
+	err   error
+	reads int
+}
+
+func (p *loadProvider) Metrics(ctx context.Context) (*ServerLoad, error) {
+	p.reads++
+	return p.load, p.err
 }
 
 // TestProviderError tests the ProviderError type.
@@ -299,6 +388,129 @@ func TestProviderError(t *testing.T) {
 	assert.False(t, err.IsRetryable())
 }
 
+// TestNewHTTPError tests parsing provider error responses into typed errors.
+func TestNewHTTPError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		header      http.Header
+		body        string
+		wantKind    errors.ErrorCode
+		wantType    string
+		wantMessage string
+		wantRetry   bool
+		wantAfter   time.Duration
+	}{
+		{
+			name:        "anthropic overloaded",
+			status:      529,
+			body:        `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			wantKind:    errors.ErrCodeProviderOverloaded,
+			wantType:    "overloaded_error",
+			wantMessage: "Overloaded",
+			wantRetry:   true,
+		},
+		{
+			name:        "anthropic credit balance",
+			status:      400,
+			body:        `{"type":"error","error":{"type":"invalid_request_error","message":"Your credit balance is too low to access the Anthropic API."}}`,
+			wantKind:    errors.ErrCodeProviderQuota,
+			wantType:    "invalid_request_error",
+			wantMessage: "Your credit balance is too low to access the Anthropic API.",
+		},
+		{
+			name:        "openai auth",
+			status:      401,
+			body:        `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			wantKind:    errors.ErrCodeProviderAuth,
+			wantType:    "invalid_request_error",
+			wantMessage: "Incorrect API key provided",
+		},
+		{
+			name:        "openai quota",
+			status:      429,
+			body:        `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			wantKind:    errors.ErrCodeProviderQuota,
+			wantType:    "insufficient_quota",
+			wantMessage: "You exceeded your current quota",
+		},
+		{
+			name:        "openai rate limit",
+			status:      429,
+			header:      http.Header{"Retry-After": []string{"12"}},
+			body:        `{"error":{"message":"Rate limit reached for requests per minute","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantKind:    errors.ErrCodeProviderRateLimit,
+			wantType:    "requests",
+			wantMessage: "Rate limit reached for requests per minute",
+			wantRetry:   true,
+			wantAfter:   12 * time.Second,
+		},
+		{
+			name:        "gemini resource exhausted",
+			status:      429,
+			body:        `{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED","details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"36s"}]}}`,
+			wantKind:    errors.ErrCodeProviderRateLimit,
+			wantType:    "RESOURCE_EXHAUSTED",
+			wantMessage: "Resource has been exhausted",
+			wantRetry:   true,
+			wantAfter:   36 * time.Second,
+		},
+		{
+			name:        "openai context length",
+			status:      400,
+			body:        `{"error":{"message":"This model's maximum context length is 128000 tokens.","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			wantKind:    errors.ErrCodeContextOverflow,
+			wantType:    "invalid_request_error",
+			wantMessage: "This model's maximum context length is 128000 tokens.",
+		},
+		{
+			name:        "ollama string error",
+			status:      404,
+			body:        `{"error":"model 'llama9' not found"}`,
+			wantKind:    errors.ErrCodeProvider,
+			wantMessage: "model 'llama9' not found",
+		},
+		{
+			name:        "plain server error",
+			status:      502,
+			body:        "bad gateway",
+			wantKind:    errors.ErrCodeProvider,
+			wantMessage: "bad gateway",
+			wantRetry:   true,
+		},
+		{
+			name:        "empty body",
+			status:      503,
+			wantKind:    errors.ErrCodeProviderOverloaded,
+			wantMessage: "Service Unavailable",
+			wantRetry:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			err := NewHTTPError("test", &http.Response{StatusCode: tt.status, Header: header}, []byte(tt.body))
+
+			assert.Equal(t, tt.wantKind, err.ErrorCode())
+			assert.Equal(t, tt.wantType, err.Type)
+			assert.Equal(t, tt.wantMessage, err.Message)
+			assert.Equal(t, tt.wantRetry, err.IsRetryable())
+			assert.Equal(t, tt.wantAfter, err.RetryAfter)
+			assert.Equal(t, tt.status, err.Status)
+			assert.Equal(t, "HTTP_"+strconv.Itoa(tt.status), err.Code)
+
+			// Wrapping keeps the classification
+			wrapped := errors.Wrap(err, errors.ErrCodeProvider, "LLM call failed")
+			assert.True(t, errors.Is(wrapped, tt.wantKind))
+			assert.Equal(t, tt.wantRetry, errors.IsRetryable(wrapped))
+		})
+	}
+}
+
 // TestCompletionRequest tests the CompletionRequest type.
 func TestCompletionRequest(t *testing.T) {
 	temp := 0.7
@@ -422,3 +634,344 @@ func BenchmarkFormatModelName(b *testing.B) {
 		_ = FormatModelName("anthropic", "claude-sonnet-4-5")
 	}
 }
+
+// TestWithStreamMetrics tests stream timing through the metering wrapper.
+func TestWithStreamMetrics(t *testing.T) {
+	recorded := make(chan StreamMetrics, 1)
+	provider := WithStreamMetrics(&mockProvider{name: "test"}, func(m StreamMetrics) {
+		recorded <- m
+	})
+	assert.Equal(t, "test", provider.Name())
+
+	tokens, err := provider.StreamCompletion(context.Background(), &CompletionRequest{Model: "test-1"})
+	require.NoError(t, err)
+
+	var count int
+	for range tokens {
+		count++
+	}
+	assert.Equal(t, 2, count, "tokens are forwarded unchanged")
+
+	var got StreamMetrics
+	select {
+	case got = <-recorded:
+	case <-time.After(time.Second):
+		t.Fatal("stream metrics were not recorded")
+	}
+	assert.Equal(t, "test/test-1", got.Model)
+	assert.Equal(t, 1, got.OutputTokens, "falls back to chunk count without usage")
+	assert.False(t, got.Error)
+	assert.GreaterOrEqual(t, got.Duration, got.TimeToFirstToken)
+}
+
+func TestWithContextGuard(t *testing.T) {
+	RegisterCapabilities("test/small", Capabilities{ContextWindow: 1000})
+	big := strings.Repeat("word ", 2000)
+	req := &CompletionRequest{
+		Model:     "small",
+		Messages:  []Message{{Role: "tool", Content: big}, {Role: "user", Content: "next"}},
+		MaxTokens: 200,
+	}
+
+	// Without a compactor the call fails with how much to trim
+	provider := WithContextGuard(&mockProvider{name: "test"}, nil)
+	_, err := provider.CreateCompletion(context.Background(), req)
+	var overflow *ContextOverflowError
+	require.ErrorAs(t, err, &overflow)
+	assert.ErrorIs(t, err, ErrContextOverflow)
+	assert.Equal(t, "test/small", overflow.Model)
+	assert.Equal(t, overflow.PromptTokens+200-1000, overflow.Excess())
+	assert.True(t, errors.Is(errors.Wrap(err, errors.ErrCodeProvider, "LLM call failed"), errors.ErrCodeContextOverflow))
+	_, err = provider.StreamCompletion(context.Background(), req)
+	assert.ErrorIs(t, err, ErrContextOverflow)
+
+	// A compactor that frees enough lets the call through
+	var asked int
+	provider = WithContextGuard(&mockProvider{name: "test"}, func(r *CompletionRequest, excess int) (*CompletionRequest, bool) {
+		asked = excess
+		trimmed := *r
+		trimmed.Messages = r.Messages[1:]
+		return &trimmed, true
+	})
+	resp, err := provider.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "Test response", resp.Content)
+	assert.Equal(t, overflow.Excess(), asked)
+
+	// Requests that fit, and models of unknown window, are sent unchanged
+	req.Messages = req.Messages[1:]
+	_, err = WithContextGuard(&mockProvider{name: "test"}, nil).CreateCompletion(context.Background(), req)
+	assert.NoError(t, err)
+	_, err = WithContextGuard(&mockProvider{name: "test"}, nil).CreateCompletion(context.Background(), &CompletionRequest{Model: "unknown", Messages: []Message{{Content: big}}})
+	assert.NoError(t, err)
+}
+
+// countingProvider counts prompt tokens as a provider API would.
+type countingProvider struct {
+	mockProvider
+	tokens int
+	err    error
+	calls  int
+}
+
+func (p *countingProvider) CountPromptTokens(ctx context.Context, req *CompletionRequest) (int, error) {
+	p.calls++
+	return p.tokens, p.err
+}
+
+// TestContextGuardProviderCounts tests that prompts near the context window
+// are sized by the provider, with counts cached and the estimate used when
+// counting fails.
+func TestContextGuardProviderCounts(t *testing.T) {
+	RegisterCapabilities("test/counted", Capabilities{ContextWindow: 1000})
+	near := &CompletionRequest{Model: "counted", Messages: []Message{{Role: "user", Content: strings.Repeat("word ", 700)}}, MaxTokens: 100}
+	estimate := PromptTokens(near)
+	require.Greater(t, estimate+100, 750, "prompt must be near the window")
+	require.Less(t, estimate+100, 1000, "prompt must fit by estimate")
+
+	// The provider's count is authoritative: larger than estimated, it overflows
+	counter := &countingProvider{mockProvider: mockProvider{name: "test"}, tokens: 950}
+	provider := WithContextGuard(WithStreamMetrics(counter, nil), nil)
+	_, err := provider.CreateCompletion(context.Background(), near)
+	var overflow *ContextOverflowError
+	require.ErrorAs(t, err, &overflow)
+	assert.True(t, overflow.Counted)
+	assert.Equal(t, 50, overflow.Excess())
+	assert.NotContains(t, overflow.Error(), "~")
+
+	// The same prompt again is not counted again
+	_, err = provider.CreateCompletion(context.Background(), near)
+	assert.Error(t, err)
+	assert.Equal(t, 1, counter.calls)
+
+	// Small prompts are only estimated
+	_, err = provider.CreateCompletion(context.Background(), &CompletionRequest{Model: "counted", Messages: []Message{{Role: "user", Content: "hi"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counter.calls)
+
+	// A missing endpoint falls back to the estimate and is not asked again
+	counter = &countingProvider{mockProvider: mockProvider{name: "test"}, err: &ProviderError{Provider: "test", Status: 404}}
+	provider = WithContextGuard(counter, nil)
+	_, err = provider.CreateCompletion(context.Background(), near)
+	assert.NoError(t, err)
+	other := *near
+	other.System = "Be brief"
+	_, err = provider.CreateCompletion(context.Background(), &other)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counter.calls)
+}
+
+// TestPerfTracker tests rolling performance averages.
+func TestPerfTracker(t *testing.T) {
+	tracker := NewPerfTracker(2)
+	assert.Zero(t, tracker.Stats("test/a").Samples)
+
+	tracker.Record(StreamMetrics{Model: "test/a", TimeToFirstToken: 100 * time.Millisecond, TokensPerSecond: 10})
+	tracker.Record(StreamMetrics{Model: "test/a", TimeToFirstToken: 300 * time.Millisecond, TokensPerSecond: 30})
+	tracker.Record(StreamMetrics{Model: "test/a", TimeToFirstToken: 500 * time.Millisecond, TokensPerSecond: 50})
+	tracker.Record(StreamMetrics{Model: "test/a", Error: true})
+
+	stats := tracker.Stats("test/a")
+	assert.Equal(t, 2, stats.Samples, "window keeps the most recent samples")
+	assert.Equal(t, 400*time.Millisecond, stats.AvgTimeToFirstToken)
+	assert.InDelta(t, 40.0, stats.AvgTokensPerSecond, 1e-9)
+}
+
+// TestNormalizeStopReason tests mapping provider stop reasons.
+func TestNormalizeStopReason(t *testing.T) {
+	assert.Equal(t, "max_tokens", NormalizeStopReason("length"))
+	assert.Equal(t, "max_tokens", NormalizeStopReason("MAX_TOKENS"))
+	assert.Equal(t, "end_turn", NormalizeStopReason("stop"))
+	assert.Equal(t, "tool_use", NormalizeStopReason("tool_calls"))
+	assert.Equal(t, "end_turn", NormalizeStopReason("end_turn"))
+	assert.Equal(t, "", NormalizeStopReason(""))
+}
+
+// TestNewAttachment tests image attachment validation.
+func TestNewAttachment(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	a, err := NewAttachment("trace.png", png)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", a.MimeType)
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgoAAAANSUhEUg==", a.DataURL())
+
+	_, err = NewAttachment("notes.txt", []byte("not an image"))
+	assert.ErrorContains(t, err, "only PNG, JPEG, GIF and WebP")
+
+	_, err = NewAttachment("", nil)
+	assert.ErrorContains(t, err, "(pasted) is empty")
+
+	_, err = NewAttachment("huge.png", append(png, make([]byte, MaxAttachmentSize)...))
+	assert.ErrorContains(t, err, "over the")
+
+	_, err = LoadAttachment("does-not-exist.png")
+	assert.Error(t, err)
+}
+
+func TestLookupCapabilities(t *testing.T) {
+	caps, ok := LookupCapabilities("openai/gpt-4o-mini")
+	require.True(t, ok)
+	assert.True(t, caps.Tools)
+	assert.Equal(t, 128000, caps.ContextWindow)
+
+	// The longest prefix wins and routing prefixes are ignored
+	caps, ok = LookupCapabilities("openrouter/openai/o1-mini")
+	require.True(t, ok)
+	assert.False(t, caps.Tools)
+
+	_, ok = LookupCapabilities("ollama/brand-new-model")
+	assert.False(t, ok)
+
+	RegisterCapabilities("ollama/brand-new-model", Capabilities{ContextWindow: 4096})
+	caps, ok = LookupCapabilities("ollama/brand-new-model")
+	require.True(t, ok)
+	assert.Equal(t, 4096, caps.ContextWindow)
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	err := CheckCapabilities("openai/o1-mini", Requirements{Tools: true, Vision: true})
+	var capErr *CapabilityError
+	require.ErrorAs(t, err, &capErr)
+	assert.Equal(t, []string{"tool calling", "image input"}, capErr.Missing)
+
+	err = CheckCapabilities("anthropic/claude-sonnet-4-5", Requirements{ContextTokens: 250000})
+	assert.ErrorContains(t, err, "a 250000 token context (has 200000)")
+
+	assert.NoError(t, CheckCapabilities("anthropic/claude-sonnet-4-5", Requirements{Tools: true, Vision: true, ParallelToolCalls: true}))
+	assert.NoError(t, CheckCapabilities("lmstudio/unknown", Requirements{Tools: true}))
+}
+
+// recordingProvider keeps the last request it was sent.
+type recordingProvider struct {
+	mockProvider
+	last *CompletionRequest
+}
+
+func (p *recordingProvider) CreateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	p.last = req
+	return p.mockProvider.CreateCompletion(ctx, req)
+}
+
+func TestWithRedaction(t *testing.T) {
+	redactor := NewRedactor()
+	inner := &recordingProvider{mockProvider: mockProvider{name: "test"}}
+	provider := WithRedaction(inner, redactor)
+
+	req := &CompletionRequest{
+		Model:  "m",
+		System: "token sk-live-abc123",
+		Messages: []Message{
+			{Role: "user", Content: "my key is sk-live-abc123"},
+			{Role: "assistant", Reasoning: "they pasted sk-live-abc123", ReasoningSignature: "sig", ToolCalls: []ToolCall{
+				{ID: "1", Name: "bash", Arguments: map[string]interface{}{"command": "curl -H 'Authorization: sk-live-abc123'", "env": []interface{}{"KEY=sk-live-abc123"}, "timeout": 5.0}},
+			}},
+		},
+	}
+
+	// Without patterns requests are sent as they are
+	_, err := provider.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "my key is sk-live-abc123", inner.last.Messages[0].Content)
+
+	redactor.Add(regexp.MustCompile(`sk-live-[a-z0-9]+`))
+	_, err = provider.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+	sent := inner.last
+	assert.Equal(t, "token [redacted]", sent.System)
+	assert.Equal(t, "my key is [redacted]", sent.Messages[0].Content)
+	assert.Equal(t, "they pasted [redacted]", sent.Messages[1].Reasoning)
+	assert.Empty(t, sent.Messages[1].ReasoningSignature)
+	args := sent.Messages[1].ToolCalls[0].Arguments
+	assert.Equal(t, "curl -H 'Authorization: [redacted]'", args["command"])
+	assert.Equal(t, []interface{}{"KEY=[redacted]"}, args["env"])
+	assert.Equal(t, 5.0, args["timeout"])
+
+	// The caller's request is left as it is
+	assert.Equal(t, "my key is sk-live-abc123", req.Messages[0].Content)
+	assert.Equal(t, "sig", req.Messages[1].ReasoningSignature)
+	assert.Contains(t, req.Messages[1].ToolCalls[0].Arguments["command"], "sk-live-abc123")
+}
+
+func TestEndUser(t *testing.T) {
+	req := &CompletionRequest{}
+	assert.Empty(t, EndUser(req, ""))
+	assert.Equal(t, "acme", EndUser(req, "acme"))
+
+	req.Metadata = map[string]string{MetadataSession: "sess-1"}
+	assert.Equal(t, "acme", EndUser(req, "acme"))
+	assert.Equal(t, "sess-1", EndUser(req, ""))
+
+	req.Metadata[MetadataUser] = "alice"
+	assert.Equal(t, "alice", EndUser(req, "acme"))
+}
+
+// batchProvider answers batches in memory, ending them after a poll.
+type batchProvider struct {
+	mockProvider
+	submitted []BatchRequest
+	polls     int
+	canceled  bool
+}
+
+func (p *batchProvider) CreateBatch(ctx context.Context, reqs []BatchRequest) (*Batch, error) {
+	p.submitted = reqs
+	return &Batch{ID: "batch-1", Status: BatchInProgress, Requests: len(reqs)}, nil
+}
+
+func (p *batchProvider) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	p.polls++
+	return &Batch{ID: id, Status: BatchEnded, Requests: len(p.submitted)}, nil
+}
+
+func (p *batchProvider) BatchResults(ctx context.Context, id string) ([]BatchResult, error) {
+	// The last request went unanswered; the rest come back in reverse
+	var results []BatchResult
+	for i := len(p.submitted) - 2; i >= 0; i-- {
+		results = append(results, BatchResult{ID: p.submitted[i].ID, Response: &CompletionResponse{Content: p.submitted[i].Request.System}})
+	}
+	return results, nil
+}
+
+func (p *batchProvider) CancelBatch(ctx context.Context, id string) error {
+	p.canceled = true
+	return nil
+}
+
+func TestRunBatch(t *testing.T) {
+	inner := &batchProvider{mockProvider: mockProvider{name: "test"}}
+	redactor := NewRedactor()
+	redactor.Add(regexp.MustCompile(`sk-live-[a-z0-9]+`))
+	provider := WithRedaction(inner, redactor)
+
+	batcher, ok := AsBatcher(provider)
+	require.True(t, ok)
+	_, ok = AsBatcher(&mockProvider{})
+	assert.False(t, ok)
+
+	reqs := []BatchRequest{
+		{ID: "a", Request: &CompletionRequest{Model: "m", System: "plan a with sk-live-abc123"}},
+		{ID: "b", Request: &CompletionRequest{Model: "m", System: "plan b"}},
+		{ID: "c", Request: &CompletionRequest{Model: "m", System: "plan c"}},
+	}
+	results, err := RunBatch(context.Background(), batcher, reqs, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.polls)
+
+	// Batched requests are redacted like any other
+	assert.Equal(t, "plan a with [redacted]", inner.submitted[0].Request.System)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "a", results[0].ID)
+	assert.Equal(t, "plan a with [redacted]", results[0].Response.Content)
+	assert.Equal(t, "plan b", results[1].Response.Content)
+	assert.Nil(t, results[2].Response)
+	assert.ErrorContains(t, results[2].Error, "without answering request c")
+
+	// A batch given up on is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = RunBatch(ctx, batcher, reqs, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, inner.canceled)
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/errors"
+)
+
+// ErrContextOverflow matches every *ContextOverflowError with errors.Is.
+var ErrContextOverflow = stderrors.New("prompt exceeds the model's context window")
+
+// ContextOverflowError reports a request whose prompt, with room for the
+// requested output, does not fit the model's context window.
+type ContextOverflowError struct {
+	Model         string
+	PromptTokens  int // Tokens of the system prompt, messages and tools
+	OutputTokens  int // Tokens reserved for the answer (the request's MaxTokens)
+	ContextWindow int
+	Counted       bool // PromptTokens was counted by the provider, not estimated
+}
+
+// Excess returns how many prompt tokens must be trimmed for the request to
+// fit.
+func (e *ContextOverflowError) Excess() int {
+	return e.PromptTokens + e.OutputTokens - e.ContextWindow
+}
+
+// Error implements error.
+func (e *ContextOverflowError) Error() string {
+	approx := "~"
+	if e.Counted {
+		approx = ""
+	}
+	return fmt.Sprintf("prompt of %s%d tokens plus %d output tokens exceeds the %d token context window of %s by %s%d tokens",
+		approx, e.PromptTokens, e.OutputTokens, e.ContextWindow, e.Model, approx, e.Excess())
+}
+
+// Is makes errors.Is(err, ErrContextOverflow) hold.
+func (e *ContextOverflowError) Is(target error) bool {
+	return target == ErrContextOverflow
+}
+
+// ErrorCode implements errors.Typed.
+func (e *ContextOverflowError) ErrorCode() errors.ErrorCode {
+	return errors.ErrCodeContextOverflow
+}
+
+// IsRetryable implements errors.Typed. Sending the same prompt again fails
+// the same way.
+func (e *ContextOverflowError) IsRetryable() bool {
+	return false
+}
+
+// UserMessage implements errors.Typed.
+func (e *ContextOverflowError) UserMessage() string {
+	return fmt.Sprintf("The conversation no longer fits the context window of %s: ~%d tokens must be trimmed. Run /optimize or start a new session.", e.Model, e.Excess())
+}
+
+// PromptTokens estimates the tokens req sends: the system prompt, every
+// message with its tool calls, and the tool definitions.
+func PromptTokens(req *CompletionRequest) int {
+	var b strings.Builder
+	b.WriteString(req.System)
+	for _, msg := range req.Messages {
+		b.WriteString("\n")
+		b.WriteString(msg.Content)
+		b.WriteString(msg.Reasoning)
+		for _, call := range msg.ToolCalls {
+			args, _ := json.Marshal(call.Arguments)
+			b.WriteString(call.Name)
+			b.Write(args)
+		}
+	}
+	if len(req.Tools) > 0 {
+		tools, _ := json.Marshal(req.Tools)
+		b.Write(tools)
+	}
+	return CountTokens(req.Model, b.String())
+}
+
+// checkPrompt returns a *ContextOverflowError if a prompt of prompt tokens
+// and req's output tokens do not fit window.
+func checkPrompt(model string, req *CompletionRequest, window, prompt int, counted bool) error {
+	e := &ContextOverflowError{
+		Model:         model,
+		PromptTokens:  prompt,
+		OutputTokens:  req.MaxTokens,
+		ContextWindow: window,
+		Counted:       counted,
+	}
+	if e.Excess() > 0 {
+		return e
+	}
+	return nil
+}
+
+// Compactor shortens the messages of req by at least excess tokens, e.g. by
+// pruning old tool outputs. It returns the shortened request, or false if
+// nothing could be trimmed.
+type Compactor func(req *CompletionRequest, excess int) (*CompletionRequest, bool)
+
+// WithContextGuard wraps a provider so requests are checked against the
+// model's context window before they are sent. A request that does not fit
+// is passed to compact, if given, and sent once it fits; otherwise the call
+// fails with a *ContextOverflowError saying how many tokens must be trimmed,
+// instead of being rejected by the API. Models of unknown context window
+// are not checked. Prompts near the limit are counted by the provider if it
+// is a TokenCounter, falling back to the estimate if counting fails.
+func WithContextGuard(p Provider, compact Compactor) Provider {
+	return &guardedProvider{Provider: p, compact: compact}
+}
+
+type guardedProvider struct {
+	Provider
+	compact Compactor
+	counts  tokenCounts
+}
+
+// Unwrap returns the wrapped provider.
+func (p *guardedProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// CreateCompletion sends req once it fits the context window.
+func (p *guardedProvider) CreateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	req, err := p.fit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return p.Provider.CreateCompletion(ctx, req)
+}
+
+// StreamCompletion streams req once it fits the context window.
+func (p *guardedProvider) StreamCompletion(ctx context.Context, req *CompletionRequest) (<-chan StreamToken, error) {
+	req, err := p.fit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return p.Provider.StreamCompletion(ctx, req)
+}
+
+// prepare fits a batched request to the context window.
+func (p *guardedProvider) prepare(req *CompletionRequest) (*CompletionRequest, error) {
+	return p.fit(context.Background(), req)
+}
+
+// fit returns req, compacted if it overflows the context window.
+func (p *guardedProvider) fit(ctx context.Context, req *CompletionRequest) (*CompletionRequest, error) {
+	model := req.Model
+	if !strings.Contains(model, "/") {
+		model = FormatModelName(p.Provider.Name(), model)
+	}
+	caps, ok := LookupCapabilities(model)
+	if !ok {
+		return req, nil
+	}
+
+	err := p.check(ctx, model, req, caps.ContextWindow)
+	var overflow *ContextOverflowError
+	if !stderrors.As(err, &overflow) || p.compact == nil {
+		return req, err
+	}
+	compacted, ok := p.compact(req, overflow.Excess())
+	if !ok {
+		return req, err
+	}
+	if err := p.check(ctx, model, compacted, caps.ContextWindow); err != nil {
+		return req, err
+	}
+	return compacted, nil
+}
+
+// check returns a *ContextOverflowError if req does not fit a context
+// window of window tokens, counting prompts near the limit with the provider
+// where it can. A non-positive window is not checked.
+func (p *guardedProvider) check(ctx context.Context, model string, req *CompletionRequest, window int) error {
+	if window <= 0 {
+		return nil
+	}
+	estimate := PromptTokens(req)
+	if float64(estimate+req.MaxTokens) < remoteCountRatio*float64(window) {
+		return checkPrompt(model, req, window, estimate, false)
+	}
+	counter, ok := unwrapAs[TokenCounter](p.Provider)
+	if !ok {
+		return checkPrompt(model, req, window, estimate, false)
+	}
+	counted, err := p.counts.count(ctx, counter, req)
+	if err != nil {
+		return checkPrompt(model, req, window, estimate, false)
+	}
+	return checkPrompt(model, req, window, counted, true)
+}
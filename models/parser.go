@@ -2,12 +2,57 @@ package models
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 )
 
-// ParseModelName parses a model name in the format "provider/model-id".
-// Returns the provider name and model ID.
+// Model aliases, short names such as "sonnet" that stand for a full model
+// name such as "anthropic/claude-sonnet-4-5".
+var (
+	aliasMu sync.RWMutex
+	aliases map[string]string
+)
+
+// SetAliases replaces the model aliases.
+func SetAliases(m map[string]string) {
+	aliasMu.Lock()
+	defer aliasMu.Unlock()
+	aliases = make(map[string]string, len(m))
+	for alias, name := range m {
+		aliases[strings.TrimSpace(alias)] = strings.TrimSpace(name)
+	}
+}
+
+// ResolveAlias returns the full model name name is an alias for, or name
+// itself if it is not an alias.
+func ResolveAlias(name string) string {
+	aliasMu.RLock()
+	defer aliasMu.RUnlock()
+	if full, ok := aliases[strings.TrimSpace(name)]; ok {
+		return full
+	}
+	return name
+}
+
+// AliasesFor returns the aliases of a full model name, sorted.
+func AliasesFor(fullName string) []string {
+	aliasMu.RLock()
+	defer aliasMu.RUnlock()
+	var names []string
+	for alias, name := range aliases {
+		if name == fullName {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseModelName parses a model name in the format "provider/model-id",
+// or an alias for one. Returns the provider name and model ID.
 func ParseModelName(fullName string) (provider, modelID string, err error) {
+	fullName = ResolveAlias(fullName)
 	parts := strings.SplitN(fullName, "/", 2)
 
 	if len(parts) != 2 {
@@ -45,6 +90,18 @@ func GetProviderFromModel(fullName string) (string, error) {
 	return provider, err
 }
 
+// localProviders lists providers that run entirely on the user's machine.
+var localProviders = map[string]bool{
+	"ollama":   true,
+	"lmstudio": true,
+}
+
+// IsLocalProvider reports whether a provider runs locally and therefore
+// never sends prompts over the network. Used to enforce offline mode.
+func IsLocalProvider(provider string) bool {
+	return localProviders[strings.ToLower(strings.TrimSpace(provider))]
+}
+
 // GetModelIDFromModel extracts the model ID from a full model name.
 func GetModelIDFromModel(fullName string) (string, error) {
 	_, modelID, err := ParseModelName(fullName)
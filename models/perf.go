@@ -0,0 +1,166 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StreamMetrics captures the observed performance of one streaming call.
+type StreamMetrics struct {
+	Provider         string
+	Model            string        // Full model name ("provider/model-id")
+	TimeToFirstToken time.Duration // From request start to the first content, reasoning, or tool token
+	Duration         time.Duration // Total stream duration
+	OutputTokens     int           // From reported usage, or the number of chunks if not reported
+	TokensPerSecond  float64       // Output tokens over the generation phase (after the first token)
+	Error            bool          // Stream ended with an error
+}
+
+// WithStreamMetrics wraps a provider so every streaming call reports its
+// time-to-first-token and throughput to record. Other methods pass through.
+func WithStreamMetrics(p Provider, record func(StreamMetrics)) Provider {
+	return &meteredProvider{Provider: p, record: record}
+}
+
+type meteredProvider struct {
+	Provider
+	record func(StreamMetrics)
+}
+
+// Unwrap returns the wrapped provider.
+func (p *meteredProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// StreamCompletion forwards the wrapped stream while timing it.
+func (p *meteredProvider) StreamCompletion(ctx context.Context, req *CompletionRequest) (<-chan StreamToken, error) {
+	start := time.Now()
+	upstream, err := p.Provider.StreamCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamToken, cap(upstream))
+	go func() {
+		m := StreamMetrics{
+			Provider: p.Provider.Name(),
+			Model:    FormatModelName(p.Provider.Name(), req.Model),
+		}
+		var firstToken time.Time
+		chunks := 0
+
+		for tok := range upstream {
+			if firstToken.IsZero() && (tok.Content != "" || tok.Reasoning != "" || tok.ToolCall != nil) {
+				firstToken = time.Now()
+			}
+			if tok.Content != "" || tok.Reasoning != "" {
+				chunks++
+			}
+			if tok.Usage != nil && tok.Usage.OutputTokens > 0 {
+				m.OutputTokens = tok.Usage.OutputTokens
+			}
+			if tok.Error != nil {
+				m.Error = true
+			}
+			out <- tok
+		}
+		// Close before recording so consumers are not held up by the recorder
+		close(out)
+
+		end := time.Now()
+		m.Duration = end.Sub(start)
+		if firstToken.IsZero() {
+			// Nothing was generated; there is no first token to report
+			if p.record != nil && m.Error {
+				p.record(m)
+			}
+			return
+		}
+
+		m.TimeToFirstToken = firstToken.Sub(start)
+		if m.OutputTokens == 0 {
+			m.OutputTokens = chunks
+		}
+		if gen := end.Sub(firstToken).Seconds(); gen > 0 {
+			m.TokensPerSecond = float64(m.OutputTokens) / gen
+		}
+		if p.record != nil {
+			p.record(m)
+		}
+	}()
+
+	return out, nil
+}
+
+// ModelPerformance pairs a model with its observed performance.
+type ModelPerformance struct {
+	Model   string   // Full model name ("provider/model-id")
+	Aliases []string // Short names for the model
+	Current bool     // Model currently in use
+	PerformanceStats
+}
+
+// PerformanceStats are rolling averages of recent streaming calls for a model.
+type PerformanceStats struct {
+	Samples             int
+	AvgTimeToFirstToken time.Duration
+	AvgTokensPerSecond  float64
+}
+
+// PerfTracker keeps a rolling window of stream metrics per model.
+type PerfTracker struct {
+	mu      sync.RWMutex
+	window  int
+	samples map[string][]StreamMetrics
+}
+
+// NewPerfTracker creates a tracker averaging over the last window samples per model.
+func NewPerfTracker(window int) *PerfTracker {
+	if window <= 0 {
+		window = 20
+	}
+	return &PerfTracker{
+		window:  window,
+		samples: make(map[string][]StreamMetrics),
+	}
+}
+
+// Record adds a sample. Failed streams are ignored for averaging.
+func (t *PerfTracker) Record(m StreamMetrics) {
+	if m.Error {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := append(t.samples[m.Model], m)
+	if len(s) > t.window {
+		s = s[len(s)-t.window:]
+	}
+	t.samples[m.Model] = s
+}
+
+// Stats returns rolling averages for a model (full "provider/model-id" name).
+// Samples is zero if nothing has been recorded.
+func (t *PerfTracker) Stats(model string) PerformanceStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s := t.samples[model]
+	stats := PerformanceStats{Samples: len(s)}
+	if len(s) == 0 {
+		return stats
+	}
+
+	var ttft time.Duration
+	var tps float64
+	for _, m := range s {
+		ttft += m.TimeToFirstToken
+		tps += m.TokensPerSecond
+	}
+	stats.AvgTimeToFirstToken = ttft / time.Duration(len(s))
+	stats.AvgTokensPerSecond = tps / float64(len(s))
+	return stats
+}
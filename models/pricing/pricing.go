@@ -0,0 +1,209 @@
+// Package pricing holds the token prices providers compute call costs with.
+// The built-in table ships as prices.yaml; a pricing.yaml in the config
+// directory overrides or extends it, and Refresh replaces that file with the
+// latest published table.
+package pricing
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the version of the table format this build reads.
+const SchemaVersion = 1
+
+// FileName is the name of the override file in the config directory.
+const FileName = "pricing.yaml"
+
+// DefaultURL is where Refresh fetches the published table from.
+const DefaultURL = "https://raw.githubusercontent.com/abrksh22/bplus/main/models/pricing/prices.yaml"
+
+// maxTableSize bounds a fetched table.
+const maxTableSize = 1 << 20
+
+//go:embed prices.yaml
+var builtin []byte
+
+// Price is the price of the models whose ID contains Match.
+type Price struct {
+	Match     string  `yaml:"match" json:"match"`                               // Substring of the model ID; "*" matches any
+	Input     float64 `yaml:"input" json:"input"`                               // USD per million input tokens
+	Output    float64 `yaml:"output" json:"output"`                             // USD per million output tokens
+	CacheRead float64 `yaml:"cache_read,omitempty" json:"cache_read,omitempty"` // USD per million cached input tokens
+}
+
+// InputRate returns the price of one input token.
+func (p Price) InputRate() float64 {
+	return p.Input / 1000000
+}
+
+// OutputRate returns the price of one output token.
+func (p Price) OutputRate() float64 {
+	return p.Output / 1000000
+}
+
+// CacheReadRate returns the price of one cached input token.
+func (p Price) CacheReadRate() float64 {
+	return p.CacheRead / 1000000
+}
+
+// Cost returns the cost of a call with the given token counts.
+func (p Price) Cost(inputTokens, outputTokens int) float64 {
+	return float64(inputTokens)*p.InputRate() + float64(outputTokens)*p.OutputRate()
+}
+
+// Table lists the prices of each provider's models, most specific first.
+type Table struct {
+	Version   int                `yaml:"version" json:"version"`
+	Updated   string             `yaml:"updated" json:"updated"` // Date the prices were last checked
+	Providers map[string][]Price `yaml:"providers" json:"providers"`
+}
+
+// Parse reads a pricing table, rejecting tables newer than this build reads.
+func Parse(data []byte) (*Table, error) {
+	var t Table
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("invalid pricing table: %w", err)
+	}
+	if t.Version < 1 || t.Version > SchemaVersion {
+		return nil, fmt.Errorf("unsupported pricing table version %d (this build reads version %d)", t.Version, SchemaVersion)
+	}
+	for provider, prices := range t.Providers {
+		for _, p := range prices {
+			if p.Match == "" {
+				return nil, fmt.Errorf("pricing for %s has an entry without match", provider)
+			}
+			if p.Input < 0 || p.Output < 0 || p.CacheRead < 0 {
+				return nil, fmt.Errorf("pricing for %s/%s is negative", provider, p.Match)
+			}
+		}
+	}
+	return &t, nil
+}
+
+// Lookup returns the first price of provider matching model.
+func (t *Table) Lookup(provider, model string) (Price, bool) {
+	for _, p := range t.Providers[provider] {
+		if p.Match == "*" || strings.Contains(model, p.Match) {
+			return p, true
+		}
+	}
+	return Price{}, false
+}
+
+// merge returns base with the entries of override taking precedence.
+func merge(base, override *Table) *Table {
+	merged := &Table{
+		Version:   base.Version,
+		Updated:   base.Updated,
+		Providers: make(map[string][]Price, len(base.Providers)),
+	}
+	if override.Updated > merged.Updated {
+		merged.Updated = override.Updated
+	}
+	for provider, prices := range base.Providers {
+		merged.Providers[provider] = prices
+	}
+	for provider, prices := range override.Providers {
+		merged.Providers[provider] = append(append([]Price(nil), prices...), merged.Providers[provider]...)
+	}
+	return merged
+}
+
+// Builtin returns the table shipped with this build.
+func Builtin() *Table {
+	t, err := Parse(builtin)
+	if err != nil {
+		panic("pricing: built-in table: " + err.Error())
+	}
+	return t
+}
+
+// Load returns the built-in table overridden by dir/pricing.yaml, if present.
+func Load(dir string) (*Table, error) {
+	t := Builtin()
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return t, fmt.Errorf("failed to read pricing overrides: %w", err)
+	}
+	override, err := Parse(data)
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", filepath.Join(dir, FileName), err)
+	}
+	return merge(t, override), nil
+}
+
+var (
+	currentMu sync.RWMutex
+	current   = Builtin()
+)
+
+// Set makes t the table Lookup uses.
+func Set(t *Table) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = t
+}
+
+// Current returns the table Lookup uses.
+func Current() *Table {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// Lookup returns the price of a provider's model from the current table, or
+// a zero price if the provider has none.
+func Lookup(provider, model string) Price {
+	p, _ := Current().Lookup(provider, model)
+	return p
+}
+
+// Refresh fetches the published table from url, saves it as dir/pricing.yaml
+// and makes it current. Entries the saved file held are replaced.
+func Refresh(ctx context.Context, client *http.Client, url, dir string) (*Table, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pricing: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch pricing: HTTP %d from %s", resp.StatusCode, url)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTableSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pricing: %w", err)
+	}
+	fetched, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to save pricing: %w", err)
+	}
+
+	t := merge(Builtin(), fetched)
+	Set(t)
+	return t, nil
+}
@@ -0,0 +1,117 @@
+package pricing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltin(t *testing.T) {
+	table := Builtin()
+	assert.Equal(t, SchemaVersion, table.Version)
+
+	tests := []struct {
+		provider, model string
+		input, output   float64
+	}{
+		{"anthropic", "claude-opus-4-1", 15, 75},
+		{"anthropic", "claude-sonnet-4-5-20250929", 3, 15},
+		{"anthropic", "claude-next", 3, 15},
+		{"openai", "gpt-4o-mini", 0.15, 0.60},
+		{"openai", "gpt-4o-2024-08-06", 5, 15},
+		{"gemini", "gemini-1.5-flash-8b", 0.0375, 0.15},
+		{"deepseek", "deepseek-reasoner", 0.55, 2.19},
+	}
+	for _, tt := range tests {
+		p, ok := table.Lookup(tt.provider, tt.model)
+		require.True(t, ok, tt.model)
+		assert.Equal(t, tt.input, p.Input, tt.model)
+		assert.Equal(t, tt.output, p.Output, tt.model)
+	}
+
+	_, ok := table.Lookup("ollama", "llama3")
+	assert.False(t, ok, "local models are free")
+	assert.InDelta(t, 3.0+15.0, Price{Input: 3, Output: 15}.Cost(1000000, 1000000), 1e-9)
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte("version: 2\nproviders: {}\n"))
+	assert.ErrorContains(t, err, "unsupported pricing table version 2")
+
+	_, err = Parse([]byte("version: 1\nproviders:\n  openai:\n    - {input: 1, output: 2}\n"))
+	assert.ErrorContains(t, err, "without match")
+
+	_, err = Parse([]byte("version: 1\nproviders:\n  openai:\n    - {match: x, input: -1, output: 2}\n"))
+	assert.ErrorContains(t, err, "negative")
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	table, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, Builtin().Updated, table.Updated)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, FileName), []byte(`
+version: 1
+updated: "2099-01-01"
+providers:
+  anthropic:
+    - {match: sonnet, input: 2.00, output: 10.00}
+  mistral:
+    - {match: "*", input: 1.00, output: 3.00}
+`), 0o644))
+
+	table, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "2099-01-01", table.Updated)
+
+	sonnet, _ := table.Lookup("anthropic", "claude-sonnet-4-5")
+	assert.Equal(t, 2.0, sonnet.Input, "overrides take precedence")
+	opus, _ := table.Lookup("anthropic", "claude-opus-4-1")
+	assert.Equal(t, 15.0, opus.Input, "built-in entries still apply")
+	mistral, ok := table.Lookup("mistral", "mistral-large")
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, mistral.Output)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, FileName), []byte("version: [\n"), 0o644))
+	table, err = Load(dir)
+	assert.Error(t, err)
+	assert.NotNil(t, table, "the built-in table is returned on error")
+}
+
+func TestRefresh(t *testing.T) {
+	defer Set(Builtin())
+
+	published := "version: 1\nupdated: \"2099-02-01\"\nproviders:\n  openai:\n    - {match: gpt-5, input: 1.25, output: 10.00}\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.Write([]byte("version: 9\n"))
+			return
+		}
+		w.Write([]byte(published))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	table, err := Refresh(context.Background(), srv.Client(), srv.URL+"/prices.yaml", dir)
+	require.NoError(t, err)
+	assert.Equal(t, "2099-02-01", table.Updated)
+	assert.Equal(t, 1.25, Lookup("openai", "gpt-5-mini").Input)
+
+	saved, err := os.ReadFile(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	assert.Equal(t, published, string(saved))
+
+	// An unreadable table leaves the saved one alone
+	_, err = Refresh(context.Background(), srv.Client(), srv.URL+"/bad", dir)
+	assert.Error(t, err)
+	saved, _ = os.ReadFile(filepath.Join(dir, FileName))
+	assert.Equal(t, published, string(saved))
+}
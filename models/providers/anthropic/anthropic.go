@@ -2,22 +2,29 @@
 package anthropic
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
 )
 
 const (
 	defaultBaseURL = "https://api.anthropic.com/v1"
 	apiVersion     = "2023-06-01"
+
+	// minThinkingBudget is the smallest extended thinking budget the API accepts.
+	minThinkingBudget = 1024
 )
 
 // Provider implements the Anthropic Claude API provider.
@@ -25,6 +32,11 @@ type Provider struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	userID  string // Optional metadata.user_id for spend attribution
+
+	modelsMu      sync.Mutex
+	modelList     []models.Model // Discovered models, refreshed after modelsTTL
+	modelsFetched time.Time
 }
 
 // New creates a new Anthropic provider.
@@ -32,9 +44,7 @@ func New(apiKey string, opts ...Option) *Provider {
 	p := &Provider{
 		apiKey:  apiKey,
 		baseURL: defaultBaseURL,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		client:  transport.NewClient(60 * time.Second),
 	}
 
 	for _, opt := range opts {
@@ -61,54 +71,28 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithUserID sets the identifier sent as metadata.user_id with every
+// request, for abuse detection and usage attribution. Anthropic expects an
+// opaque identifier (no names or emails), so it is sent hashed.
+func WithUserID(userID string) Option {
+	return func(p *Provider) {
+		p.userID = userID
+	}
+}
+
+// opaqueUserID hashes an identifier for metadata.user_id, so names in
+// attribution tags such as "acme/payments/alice" aren't sent while the same
+// identifier still maps to the same user.
+func opaqueUserID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16])
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return "anthropic"
 }
 
-// ListModels returns available Claude models.
-func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
-	// Anthropic doesn't have a models endpoint, so we return known models
-	return []models.Model{
-		{
-			ID:            "claude-opus-4-1",
-			Name:          "Claude Opus 4.1",
-			Provider:      "anthropic",
-			ContextWindow: 200000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  15.00 / 1000000, // $15 per million tokens
-				OutputTokens: 75.00 / 1000000, // $75 per million tokens
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
-		},
-		{
-			ID:            "claude-sonnet-4-5",
-			Name:          "Claude Sonnet 4.5",
-			Provider:      "anthropic",
-			ContextWindow: 200000,
-			MaxOutput:     8192,
-			Pricing: models.Pricing{
-				InputTokens:  3.00 / 1000000,  // $3 per million tokens
-				OutputTokens: 15.00 / 1000000, // $15 per million tokens
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
-		},
-		{
-			ID:            "claude-haiku-4-0",
-			Name:          "Claude Haiku 4.0",
-			Provider:      "anthropic",
-			ContextWindow: 200000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  0.80 / 1000000, // $0.80 per million tokens
-				OutputTokens: 4.00 / 1000000, // $4 per million tokens
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
-		},
-	}, nil
-}
-
 // CreateCompletion creates a non-streaming completion.
 func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
 	// Convert to Anthropic API format
@@ -134,12 +118,7 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, &models.ProviderError{
-			Provider:  "anthropic",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("anthropic", resp, body)
 	}
 
 	var apiResp messageResponse
@@ -150,6 +129,46 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 	return p.convertResponse(&apiResp), nil
 }
 
+// CountPromptTokens counts the input tokens of req with the count_tokens
+// endpoint, which is free and reflects the model's own tokenizer.
+func (p *Provider) CountPromptTokens(ctx context.Context, req *models.CompletionRequest) (int, error) {
+	apiReq := p.convertRequest(req, false)
+	body, err := json.Marshal(countTokensRequest{
+		Model:    apiReq.Model,
+		Messages: apiReq.Messages,
+		System:   apiReq.System,
+		Tools:    apiReq.Tools,
+		Thinking: apiReq.Thinking,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/messages/count_tokens", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, models.NewHTTPError("anthropic", resp, body)
+	}
+
+	var apiResp countTokensResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return apiResp.InputTokens, nil
+}
+
 // StreamCompletion creates a streaming completion.
 func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
 	// Convert to Anthropic API format
@@ -176,12 +195,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, &models.ProviderError{
-			Provider:  "anthropic",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("anthropic", resp, body)
 	}
 
 	tokens := make(chan models.StreamToken, 10)
@@ -190,8 +204,12 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 		defer close(tokens)
 		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := transport.NewLineReader(resp.Body)
 		var usage *models.Usage
+		var stopReason string
+
+		// Tool inputs arrive as JSON fragments, per content block index
+		toolUses := make(map[int]*toolUseBuffer)
 
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -212,14 +230,53 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 			}
 
 			switch event.Type {
+			case "content_block_start":
+				if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+					toolUses[event.Index] = &toolUseBuffer{id: event.ContentBlock.ID, name: event.ContentBlock.Name}
+				}
+
 			case "content_block_delta":
-				if event.Delta != nil && event.Delta.Text != "" {
+				if event.Delta == nil {
+					continue
+				}
+				switch event.Delta.Type {
+				case "input_json_delta":
+					if tu, ok := toolUses[event.Index]; ok {
+						tu.input.WriteString(event.Delta.PartialJSON)
+					}
+					continue
+				case "thinking_delta":
+					if event.Delta.Thinking != "" {
+						tokens <- models.StreamToken{Reasoning: event.Delta.Thinking}
+					}
+					continue
+				case "signature_delta":
+					tokens <- models.StreamToken{ReasoningSignature: event.Delta.Signature}
+					continue
+				}
+				if event.Delta.Text != "" {
 					tokens <- models.StreamToken{
 						Content: event.Delta.Text,
 					}
 				}
 
+			case "content_block_stop":
+				tu, ok := toolUses[event.Index]
+				if !ok {
+					continue
+				}
+				delete(toolUses, event.Index)
+				call, err := tu.toolCall()
+				if err != nil {
+					tokens <- models.StreamToken{Error: err}
+					return
+				}
+				tokens <- models.StreamToken{ToolCall: call}
+
 			case "message_delta":
+				if event.Delta != nil && event.Delta.StopReason != "" {
+					stopReason = event.Delta.StopReason
+				}
 				if event.Usage != nil {
 					usage = &models.Usage{
 						InputTokens:  0, // Will be set in message_start
@@ -243,8 +300,9 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 					usage.Cost = calculateCost(req.Model, usage.InputTokens, usage.OutputTokens)
 				}
 				tokens <- models.StreamToken{
-					Done:  true,
-					Usage: usage,
+					Done:       true,
+					StopReason: stopReason,
+					Usage:      usage,
 				}
 				return
 			}
@@ -297,6 +355,12 @@ func (p *Provider) SupportsTools() bool {
 	return true
 }
 
+// SupportsPrefill returns true: a trailing assistant message is continued
+// in place, which is how truncated answers are resumed.
+func (p *Provider) SupportsPrefill() bool {
+	return true
+}
+
 // Helper methods
 
 func (p *Provider) setHeaders(req *http.Request) {
@@ -310,29 +374,47 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *m
 		Model:     req.Model,
 		MaxTokens: req.MaxTokens,
 		Stream:    stream,
-		Messages:  make([]message, len(req.Messages)),
+		Messages:  convertMessages(req.Messages),
 	}
 
 	if req.System != "" {
 		apiReq.System = req.System
 	}
 
-	for i, msg := range req.Messages {
-		apiReq.Messages[i] = message{
-			Role:    msg.Role,
-			Content: msg.Content,
+	if userID := models.EndUser(req, p.userID); userID != "" {
+		apiReq.Metadata = &requestMetadata{UserID: opaqueUserID(userID)}
+	}
+
+	if len(req.Tools) > 0 {
+		apiReq.Tools = make([]toolDef, len(req.Tools))
+		for i, t := range req.Tools {
+			apiReq.Tools[i] = toolDef{
+				Name:        t.Name,
+				Description: t.Description,
+				InputSchema: inputSchema(t),
+			}
 		}
 	}
 
-	if req.Temperature != nil {
-		apiReq.Temperature = *req.Temperature
+	if req.ReasoningBudget > 0 {
+		budget := max(req.ReasoningBudget, minThinkingBudget)
+		apiReq.Thinking = &thinkingConfig{Type: "enabled", BudgetTokens: budget}
+		// The budget counts towards max_tokens, which must leave room to answer
+		if apiReq.MaxTokens <= budget {
+			apiReq.MaxTokens += budget
+		}
+	}
+
+	// Extended thinking requires the default temperature and no top_k
+	if req.Temperature != nil && apiReq.Thinking == nil {
+		apiReq.Temperature = req.Temperature
 	}
 
 	if req.TopP != nil {
 		apiReq.TopP = *req.TopP
 	}
 
-	if req.TopK != nil {
+	if req.TopK != nil && apiReq.Thinking == nil {
 		apiReq.TopK = *req.TopK
 	}
 
@@ -349,12 +431,26 @@ func (p *Provider) convertResponse(apiResp *messageResponse) *models.CompletionR
 		StopReason: apiResp.StopReason,
 	}
 
-	// Extract text content
+	// Extract text content and tool calls
 	if len(apiResp.Content) > 0 {
 		var parts []string
 		for _, content := range apiResp.Content {
-			if content.Type == "text" {
+			switch content.Type {
+			case "text":
 				parts = append(parts, content.Text)
+			case "thinking":
+				resp.Reasoning += content.Thinking
+				resp.ReasoningSignature = content.Signature
+			case "tool_use":
+				var args map[string]interface{}
+				if len(content.Input) > 0 {
+					json.Unmarshal(content.Input, &args)
+				}
+				resp.ToolCalls = append(resp.ToolCalls, models.ToolCall{
+					ID:        content.ID,
+					Name:      content.Name,
+					Arguments: args,
+				})
 			}
 		}
 		resp.Content = strings.Join(parts, "")
@@ -373,45 +469,186 @@ func (p *Provider) convertResponse(apiResp *messageResponse) *models.CompletionR
 	return resp
 }
 
-func calculateCost(model string, inputTokens, outputTokens int) float64 {
-	// Simplified cost calculation - in production, this should use actual pricing
-	var inputCost, outputCost float64
-
-	switch {
-	case strings.Contains(model, "opus"):
-		inputCost = 15.00 / 1000000
-		outputCost = 75.00 / 1000000
-	case strings.Contains(model, "sonnet"):
-		inputCost = 3.00 / 1000000
-		outputCost = 15.00 / 1000000
-	case strings.Contains(model, "haiku"):
-		inputCost = 0.80 / 1000000
-		outputCost = 4.00 / 1000000
+// convertMessages converts conversation messages to Anthropic messages. Tool
+// calls become tool_use blocks on the assistant message, and tool results
+// become tool_result blocks on a user message; consecutive results share one
+// message, as the API requires.
+func convertMessages(msgs []models.Message) []message {
+	out := make([]message, 0, len(msgs))
+	for _, msg := range msgs {
+		switch {
+		case msg.Role == "tool" && msg.ToolCallID != "":
+			block := contentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}
+			if n := len(out); n > 0 && isToolResults(out[n-1]) {
+				out[n-1].Content = append(out[n-1].Content.([]contentBlock), block)
+				continue
+			}
+			out = append(out, message{Role: "user", Content: []contentBlock{block}})
+
+		case msg.Role == "tool":
+			// A result without a call ID can't be tied to a tool_use block
+			out = append(out, message{Role: "user", Content: fmt.Sprintf("Result of tool %s:\n%s", msg.Name, msg.Content)})
+
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			blocks := make([]contentBlock, 0, len(msg.ToolCalls)+2)
+			// With extended thinking, a tool-using turn must replay its
+			// signed thinking block first
+			if msg.Reasoning != "" && msg.ReasoningSignature != "" {
+				blocks = append(blocks, contentBlock{Type: "thinking", Thinking: msg.Reasoning, Signature: msg.ReasoningSignature})
+			}
+			if msg.Content != "" {
+				blocks = append(blocks, contentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input, err := json.Marshal(call.Arguments)
+				if err != nil || call.Arguments == nil {
+					input = []byte("{}")
+				}
+				blocks = append(blocks, contentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			out = append(out, message{Role: "assistant", Content: blocks})
+
+		case len(msg.Attachments) > 0:
+			// Images go before the text that refers to them
+			blocks := make([]contentBlock, 0, len(msg.Attachments)+1)
+			for _, a := range msg.Attachments {
+				blocks = append(blocks, contentBlock{
+					Type:   "image",
+					Source: &imageSource{Type: "base64", MediaType: a.MimeType, Data: a.Base64()},
+				})
+			}
+			if msg.Content != "" {
+				blocks = append(blocks, contentBlock{Type: "text", Text: msg.Content})
+			}
+			out = append(out, message{Role: msg.Role, Content: blocks})
+
+		default:
+			out = append(out, message{Role: msg.Role, Content: msg.Content})
+		}
+	}
+	return out
+}
+
+// isToolResults reports whether msg is a user message of tool results.
+func isToolResults(msg message) bool {
+	blocks, ok := msg.Content.([]contentBlock)
+	return ok && msg.Role == "user" && len(blocks) > 0 && blocks[0].Type == "tool_result"
+}
+
+// inputSchema builds the JSON schema of a tool's input.
+func inputSchema(t models.Tool) map[string]interface{} {
+	properties := make(map[string]interface{}, len(t.Parameters))
+	required := make([]string, 0, len(t.Parameters))
+	for _, param := range t.Parameters {
+		prop := map[string]interface{}{"description": param.Description}
+		if typ := schemaType(param.Type); typ != "" {
+			prop["type"] = typ
+		}
+		if len(param.Enum) > 0 {
+			prop["enum"] = param.Enum
+		}
+		properties[param.Name] = prop
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schemaType maps a tool parameter type to a JSON schema type; "" means any.
+func schemaType(typ string) string {
+	switch typ {
+	case "int", "integer":
+		return "integer"
+	case "float", "number":
+		return "number"
+	case "bool", "boolean":
+		return "boolean"
+	case "string", "array", "object":
+		return typ
 	default:
-		inputCost = 3.00 / 1000000
-		outputCost = 15.00 / 1000000
+		return ""
 	}
+}
 
-	return (float64(inputTokens) * inputCost) + (float64(outputTokens) * outputCost)
+// toolUseBuffer accumulates a streamed tool_use block.
+type toolUseBuffer struct {
+	id    string
+	name  string
+	input strings.Builder
+}
+
+// toolCall decodes the accumulated input.
+func (b *toolUseBuffer) toolCall() (*models.ToolCall, error) {
+	call := &models.ToolCall{ID: b.id, Name: b.name}
+	if b.input.Len() > 0 {
+		if err := json.Unmarshal([]byte(b.input.String()), &call.Arguments); err != nil {
+			return nil, fmt.Errorf("failed to decode input of tool call %s: %w", b.name, err)
+		}
+	}
+	return call, nil
+}
+
+// calculateCost prices a call from the anthropic pricing table.
+func calculateCost(model string, inputTokens, outputTokens int) float64 {
+	return pricing.Lookup("anthropic", model).Cost(inputTokens, outputTokens)
 }
 
 // API types
 
 type messageRequest struct {
-	Model         string    `json:"model"`
-	Messages      []message `json:"messages"`
-	System        string    `json:"system,omitempty"`
-	MaxTokens     int       `json:"max_tokens"`
-	Temperature   float64   `json:"temperature,omitempty"`
-	TopP          float64   `json:"top_p,omitempty"`
-	TopK          int       `json:"top_k,omitempty"`
-	StopSequences []string  `json:"stop_sequences,omitempty"`
-	Stream        bool      `json:"stream,omitempty"`
+	Model         string           `json:"model"`
+	Messages      []message        `json:"messages"`
+	System        string           `json:"system,omitempty"`
+	MaxTokens     int              `json:"max_tokens"`
+	Temperature   *float64         `json:"temperature,omitempty"`
+	TopP          float64          `json:"top_p,omitempty"`
+	TopK          int              `json:"top_k,omitempty"`
+	StopSequences []string         `json:"stop_sequences,omitempty"`
+	Stream        bool             `json:"stream,omitempty"`
+	Tools         []toolDef        `json:"tools,omitempty"`
+	Metadata      *requestMetadata `json:"metadata,omitempty"`
+	Thinking      *thinkingConfig  `json:"thinking,omitempty"`
+}
+
+type countTokensRequest struct {
+	Model    string          `json:"model"`
+	Messages []message       `json:"messages"`
+	System   string          `json:"system,omitempty"`
+	Tools    []toolDef       `json:"tools,omitempty"`
+	Thinking *thinkingConfig `json:"thinking,omitempty"`
+}
+
+type countTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+type thinkingConfig struct {
+	Type         string `json:"type"` // "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type toolDef struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type requestMetadata struct {
+	UserID string `json:"user_id,omitempty"`
 }
 
 type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // A string or []contentBlock
 }
 
 type messageResponse struct {
@@ -427,6 +664,28 @@ type messageResponse struct {
 type contentBlock struct {
 	Type string `json:"type"`
 	Text string `json:"text,omitempty"`
+
+	// thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+
+	// image
+	Source *imageSource `json:"source,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"` // "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
 type usageInfo struct {
@@ -435,13 +694,19 @@ type usageInfo struct {
 }
 
 type streamEvent struct {
-	Type    string           `json:"type"`
-	Delta   *contentDelta    `json:"delta,omitempty"`
-	Usage   *usageInfo       `json:"usage,omitempty"`
-	Message *messageResponse `json:"message,omitempty"`
+	Type         string           `json:"type"`
+	Index        int              `json:"index"`                   // Content block index
+	ContentBlock *contentBlock    `json:"content_block,omitempty"` // Set on content_block_start
+	Delta        *contentDelta    `json:"delta,omitempty"`
+	Usage        *usageInfo       `json:"usage,omitempty"`
+	Message      *messageResponse `json:"message,omitempty"`
 }
 
 type contentDelta struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	PartialJSON string `json:"partial_json,omitempty"` // Set on input_json_delta
+	Thinking    string `json:"thinking,omitempty"`     // Set on thinking_delta
+	Signature   string `json:"signature,omitempty"`    // Set on signature_delta
+	StopReason  string `json:"stop_reason,omitempty"`  // Set on message_delta
 }
@@ -49,8 +49,18 @@ func TestProvider_SupportsTools(t *testing.T) {
 	assert.True(t, p.SupportsTools())
 }
 
+// unavailableServer fails every request, so model discovery falls back to
+// the local pricing table.
+func unavailableServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
 func TestProvider_ListModels(t *testing.T) {
-	p := New("test-key")
+	p := New("test-key", WithBaseURL(unavailableServer(t).URL))
 	modelslist, err := p.ListModels(context.Background())
 
 	require.NoError(t, err)
@@ -80,7 +90,7 @@ func TestProvider_ListModels(t *testing.T) {
 }
 
 func TestProvider_GetModelInfo(t *testing.T) {
-	p := New("test-key")
+	p := New("test-key", WithBaseURL(unavailableServer(t).URL))
 
 	t.Run("Valid model ID", func(t *testing.T) {
 		info, err := p.GetModelInfo(context.Background(), "claude-sonnet-4-5")
@@ -96,6 +106,61 @@ func TestProvider_GetModelInfo(t *testing.T) {
 	})
 }
 
+func TestProvider_ListModels_Discovery(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		requests++
+
+		if r.URL.Query().Get("after_id") == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"id": "claude-sonnet-4-5-20250929", "display_name": "Claude Sonnet 4.5", "created_at": "2025-09-29T00:00:00Z"},
+				},
+				"has_more": true,
+				"last_id":  "claude-sonnet-4-5-20250929",
+			})
+			return
+		}
+		assert.Equal(t, "claude-sonnet-4-5-20250929", r.URL.Query().Get("after_id"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "claude-opus-5-20260101", "display_name": "Claude Opus 5", "created_at": "2026-01-01T00:00:00Z"},
+			},
+			"has_more": false,
+		})
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	list, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+
+	ids := make([]string, len(list))
+	for i, m := range list {
+		ids[i] = m.ID
+	}
+	// The alias of a served model is kept; retired ones are dropped
+	assert.Equal(t, []string{"claude-sonnet-4-5", "claude-sonnet-4-5-20250929", "claude-opus-5-20260101"}, ids)
+
+	dated := list[1]
+	assert.Equal(t, "Claude Sonnet 4.5", dated.Name)
+	assert.Equal(t, 3.00/1000000, dated.Pricing.InputTokens)
+	assert.Equal(t, 8192, dated.MaxOutput)
+
+	// New models are priced by family until the pricing table catches up
+	opus := list[2]
+	assert.Equal(t, "Claude Opus 5", opus.Name)
+	assert.Equal(t, 15.00/1000000, opus.Pricing.InputTokens)
+	assert.Equal(t, defaultContextWindow, opus.ContextWindow)
+
+	// The list is reused until it expires
+	_, err = p.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
+
 func TestProvider_CreateCompletion(t *testing.T) {
 	// Create mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -273,3 +338,415 @@ func TestProvider_TestConnection(t *testing.T) {
 	err := p.TestConnection(context.Background())
 	assert.NoError(t, err)
 }
+
+func TestProvider_CreateCompletion_WithUserID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Metadata *struct {
+				UserID string `json:"user_id"`
+			} `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		require.NotNil(t, req.Metadata)
+		assert.Equal(t, opaqueUserID("acme/payments"), req.Metadata.UserID)
+		assert.Len(t, req.Metadata.UserID, 32)
+		assert.NotContains(t, req.Metadata.UserID, "acme")
+
+		response := map[string]interface{}{
+			"id":      "msg_123",
+			"type":    "message",
+			"role":    "assistant",
+			"content": []map[string]interface{}{{"type": "text", "text": "ok"}},
+			"usage":   map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	p := New("test-api-key", WithBaseURL(server.URL), WithUserID("acme/payments"))
+
+	req := &models.CompletionRequest{
+		Model:     "claude-sonnet-4-5",
+		Messages:  []models.Message{{Role: "user", Content: "Hello"}},
+		MaxTokens: 100,
+	}
+
+	_, err := p.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+}
+
+func TestProvider_CreateCompletion_SessionUserID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Metadata *struct {
+				UserID string `json:"user_id"`
+			} `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		require.NotNil(t, req.Metadata)
+		assert.Equal(t, opaqueUserID("sess-1"), req.Metadata.UserID)
+
+		response := map[string]interface{}{
+			"id":      "msg_123",
+			"type":    "message",
+			"role":    "assistant",
+			"content": []map[string]interface{}{{"type": "text", "text": "ok"}},
+			"usage":   map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	p := New("test-api-key", WithBaseURL(server.URL))
+
+	req := &models.CompletionRequest{
+		Model:     "claude-sonnet-4-5",
+		Messages:  []models.Message{{Role: "user", Content: "Hello"}},
+		MaxTokens: 100,
+		Metadata:  map[string]string{models.MetadataSession: "sess-1"},
+	}
+
+	_, err := p.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+}
+
+func TestProvider_CreateCompletion_ToolUse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Tools []struct {
+				Name        string                 `json:"name"`
+				InputSchema map[string]interface{} `json:"input_schema"`
+			} `json:"tools"`
+			Messages []struct {
+				Role    string          `json:"role"`
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		require.Len(t, req.Tools, 1)
+		assert.Equal(t, "bash", req.Tools[0].Name)
+		props := req.Tools[0].InputSchema["properties"].(map[string]interface{})
+		assert.Equal(t, "integer", props["timeout"].(map[string]interface{})["type"])
+		assert.Equal(t, []interface{}{"command"}, req.Tools[0].InputSchema["required"])
+
+		// Calls and results are content blocks; both results share one user message
+		require.Len(t, req.Messages, 3)
+		assert.JSONEq(t, `"List files"`, string(req.Messages[0].Content))
+		assert.JSONEq(t, `[
+			{"type":"text","text":"Listing."},
+			{"type":"tool_use","id":"toolu_1","name":"bash","input":{"command":"ls"}},
+			{"type":"tool_use","id":"toolu_2","name":"bash","input":{"command":"pwd"}}
+		]`, string(req.Messages[1].Content))
+		assert.Equal(t, "user", req.Messages[2].Role)
+		assert.JSONEq(t, `[
+			{"type":"tool_result","tool_use_id":"toolu_1","content":"a.go"},
+			{"type":"tool_result","tool_use_id":"toolu_2","content":"/src"}
+		]`, string(req.Messages[2].Content))
+
+		response := map[string]interface{}{
+			"id":   "msg_123",
+			"type": "message",
+			"role": "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Reading it."},
+				{"type": "tool_use", "id": "toolu_3", "name": "read", "input": map[string]interface{}{"file_path": "a.go"}},
+			},
+			"stop_reason": "tool_use",
+			"usage":       map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	p := New("test-api-key", WithBaseURL(server.URL))
+
+	req := &models.CompletionRequest{
+		Model: "claude-sonnet-4-5",
+		Messages: []models.Message{
+			{Role: "user", Content: "List files"},
+			{Role: "assistant", Content: "Listing.", ToolCalls: []models.ToolCall{
+				{ID: "toolu_1", Name: "bash", Arguments: map[string]interface{}{"command": "ls"}},
+				{ID: "toolu_2", Name: "bash", Arguments: map[string]interface{}{"command": "pwd"}},
+			}},
+			{Role: "tool", Name: "bash", Content: "a.go", ToolCallID: "toolu_1"},
+			{Role: "tool", Name: "bash", Content: "/src", ToolCallID: "toolu_2"},
+		},
+		Tools: []models.Tool{{
+			Name:        "bash",
+			Description: "Runs a command",
+			Parameters: []models.Parameter{
+				{Name: "command", Type: "string", Required: true},
+				{Name: "timeout", Type: "int"},
+			},
+		}},
+		MaxTokens: 100,
+	}
+
+	resp, err := p.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "tool_use", resp.StopReason)
+	assert.Equal(t, "Reading it.", resp.Content)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, models.ToolCall{ID: "toolu_3", Name: "read", Arguments: map[string]interface{}{"file_path": "a.go"}}, resp.ToolCalls[0])
+}
+
+func TestProvider_StreamCompletion_ToolUse(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":12}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_9","name":"bash","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"comm"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"and\": \"go test\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			w.Write([]byte("data: " + e + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	p := New("test-api-key", WithBaseURL(server.URL))
+	tokens, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:     "claude-sonnet-4-5",
+		Messages:  []models.Message{{Role: "user", Content: "Run the tests"}},
+		MaxTokens: 100,
+	})
+	require.NoError(t, err)
+
+	var content string
+	var calls []models.ToolCall
+	var last models.StreamToken
+	for token := range tokens {
+		require.NoError(t, token.Error)
+		content += token.Content
+		if token.ToolCall != nil {
+			calls = append(calls, *token.ToolCall)
+		}
+		last = token
+	}
+
+	assert.Equal(t, "Checking.", content)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "toolu_9", calls[0].ID)
+	assert.Equal(t, "bash", calls[0].Name)
+	assert.Equal(t, map[string]interface{}{"command": "go test"}, calls[0].Arguments)
+	assert.True(t, last.Done)
+	assert.Equal(t, "tool_use", last.StopReason)
+}
+
+func TestProvider_StreamCompletion_Thinking(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":12}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"The tests live in ./..."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":" so run go test."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-1"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Running them."}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	}
+	var apiReq map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&apiReq))
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			w.Write([]byte("data: " + e + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	temperature := 0.7
+	p := New("test-api-key", WithBaseURL(server.URL))
+	tokens, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:           "claude-sonnet-4-5",
+		Messages:        []models.Message{{Role: "user", Content: "Run the tests"}},
+		MaxTokens:       1000,
+		Temperature:     &temperature,
+		ReasoningBudget: 2000,
+	})
+	require.NoError(t, err)
+
+	var content, reasoning, signature string
+	for token := range tokens {
+		require.NoError(t, token.Error)
+		content += token.Content
+		reasoning += token.Reasoning
+		if token.ReasoningSignature != "" {
+			signature = token.ReasoningSignature
+		}
+	}
+
+	assert.Equal(t, "Running them.", content)
+	assert.Equal(t, "The tests live in ./... so run go test.", reasoning)
+	assert.Equal(t, "sig-1", signature)
+
+	// The budget fits within max_tokens and temperature is left at its default
+	assert.Equal(t, map[string]interface{}{"type": "enabled", "budget_tokens": float64(2000)}, apiReq["thinking"])
+	assert.Equal(t, float64(3000), apiReq["max_tokens"])
+	assert.NotContains(t, apiReq, "temperature")
+}
+
+func TestConvertMessages_ThinkingReplay(t *testing.T) {
+	msgs := convertMessages([]models.Message{{
+		Role:               "assistant",
+		Reasoning:          "Need the test output.",
+		ReasoningSignature: "sig-1",
+		ToolCalls:          []models.ToolCall{{ID: "toolu_1", Name: "bash", Arguments: map[string]interface{}{"command": "go test"}}},
+	}})
+
+	body, err := json.Marshal(msgs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"role":"assistant","content":[
+		{"type":"thinking","thinking":"Need the test output.","signature":"sig-1"},
+		{"type":"tool_use","id":"toolu_1","name":"bash","input":{"command":"go test"}}
+	]}]`, string(body))
+}
+
+func TestConvertMessages_Images(t *testing.T) {
+	msgs := convertMessages([]models.Message{{
+		Role:        "user",
+		Content:     "Why does this panic?",
+		Attachments: []models.Attachment{{Data: []byte("img"), MimeType: "image/png"}},
+	}})
+
+	body, err := json.Marshal(msgs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"role":"user","content":[
+		{"type":"image","source":{"type":"base64","media_type":"image/png","data":"aW1n"}},
+		{"type":"text","text":"Why does this panic?"}
+	]}]`, string(body))
+}
+
+func TestProvider_StreamCompletion_LargeChunks(t *testing.T) {
+	// A multi-megabyte tool input arrives as one SSE data line
+	content := strings.Repeat("line of a generated file\n", 200000)
+	input, err := json.Marshal(map[string]string{"content": content})
+	require.NoError(t, err)
+	partial, err := json.Marshal(string(input))
+	require.NoError(t, err)
+
+	events := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":12}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"write","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":` + string(partial) + `}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			w.Write([]byte("data: " + e + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	p := New("test-api-key", WithBaseURL(server.URL))
+	tokens, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:     "claude-sonnet-4-5",
+		Messages:  []models.Message{{Role: "user", Content: "Write the file"}},
+		MaxTokens: 100,
+	})
+	require.NoError(t, err)
+
+	var calls []models.ToolCall
+	var last models.StreamToken
+	for token := range tokens {
+		require.NoError(t, token.Error)
+		if token.ToolCall != nil {
+			calls = append(calls, *token.ToolCall)
+		}
+		last = token
+	}
+
+	require.Len(t, calls, 1)
+	assert.Equal(t, content, calls[0].Arguments["content"])
+	assert.True(t, last.Done)
+}
+
+func TestProvider_Batch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-api-key", r.Header.Get("x-api-key"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /messages/batches":
+			var req createBatchRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Len(t, req.Requests, 2)
+			assert.Equal(t, "plan-1", req.Requests[0].CustomID)
+			assert.Equal(t, "claude-sonnet-4-5", req.Requests[0].Params.Model)
+			assert.False(t, req.Requests[0].Params.Stream)
+			w.Write([]byte(`{"id":"msgbatch_1","processing_status":"in_progress","request_counts":{"processing":2}}`))
+		case "GET /messages/batches/msgbatch_1":
+			w.Write([]byte(`{"id":"msgbatch_1","processing_status":"ended","request_counts":{"succeeded":1,"errored":1}}`))
+		case "GET /messages/batches/msgbatch_1/results":
+			w.Write([]byte(`{"custom_id":"plan-1","result":{"type":"succeeded","message":{"id":"msg_1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Plan"}],"usage":{"input_tokens":1000,"output_tokens":1000}}}}
+{"custom_id":"plan-2","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}}}
+`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p := New("test-api-key", WithBaseURL(server.URL))
+	batch, err := p.CreateBatch(context.Background(), []models.BatchRequest{
+		{ID: "plan-1", Request: &models.CompletionRequest{Model: "claude-sonnet-4-5", Messages: []models.Message{{Role: "user", Content: "Plan A"}}, MaxTokens: 100}},
+		{ID: "plan-2", Request: &models.CompletionRequest{Model: "claude-sonnet-4-5", Messages: []models.Message{{Role: "user", Content: "Plan B"}}, MaxTokens: 100}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchInProgress, batch.Status)
+	assert.Equal(t, 2, batch.Requests)
+
+	batch, err = p.GetBatch(context.Background(), "msgbatch_1")
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchEnded, batch.Status)
+
+	results, err := p.BatchResults(context.Background(), "msgbatch_1")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Plan", results[0].Response.Content)
+	assert.InDelta(t, calculateCost("claude-sonnet-4-5", 1000, 1000)*models.BatchDiscount, results[0].Response.Usage.Cost, 1e-9)
+	assert.ErrorContains(t, results[1].Error, "bad")
+}
+
+func TestProvider_CountPromptTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages/count_tokens", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "claude-sonnet-4-5", req["model"])
+		assert.Equal(t, "Be brief", req["system"])
+		assert.NotContains(t, req, "max_tokens", "count_tokens takes no generation settings")
+		json.NewEncoder(w).Encode(map[string]interface{}{"input_tokens": 1234})
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	var _ models.TokenCounter = p
+	n, err := p.CountPromptTokens(context.Background(), &models.CompletionRequest{
+		Model:     "claude-sonnet-4-5",
+		System:    "Be brief",
+		Messages:  []models.Message{{Role: "user", Content: "Hello"}},
+		MaxTokens: 100,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1234, n)
+}
@@ -0,0 +1,201 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// CreateBatch submits the requests to the Message Batches API, to be
+// answered within 24 hours at half the price. IDs may only hold letters,
+// digits, "-" and "_", up to 64 of them.
+func (p *Provider) CreateBatch(ctx context.Context, reqs []models.BatchRequest) (*models.Batch, error) {
+	apiReq := &createBatchRequest{Requests: make([]batchRequest, len(reqs))}
+	for i, r := range reqs {
+		apiReq.Requests[i] = batchRequest{CustomID: r.ID, Params: p.convertRequest(r.Request, false)}
+	}
+
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var batch messageBatch
+	if err := p.batchCall(ctx, "POST", "/messages/batches", bytes.NewReader(body), &batch); err != nil {
+		return nil, err
+	}
+	return batch.convert(), nil
+}
+
+// GetBatch returns the progress of a batch.
+func (p *Provider) GetBatch(ctx context.Context, id string) (*models.Batch, error) {
+	var batch messageBatch
+	if err := p.batchCall(ctx, "GET", "/messages/batches/"+id, nil, &batch); err != nil {
+		return nil, err
+	}
+	return batch.convert(), nil
+}
+
+// CancelBatch stops a batch.
+func (p *Provider) CancelBatch(ctx context.Context, id string) error {
+	return p.batchCall(ctx, "POST", "/messages/batches/"+id+"/cancel", nil, nil)
+}
+
+// BatchResults downloads the results of a batch that has ended. Costs are
+// those of the batch price.
+func (p *Provider) BatchResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/messages/batches/"+id+"/results", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, models.NewHTTPError("anthropic", resp, body)
+	}
+
+	var results []models.BatchResult
+	scanner := transport.NewLineReader(resp.Body)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line batchResultLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("failed to decode batch result: %w", err)
+		}
+		results = append(results, p.convertBatchResult(&line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch results: %w", err)
+	}
+	return results, nil
+}
+
+// convertBatchResult converts one line of the results of a batch.
+func (p *Provider) convertBatchResult(line *batchResultLine) models.BatchResult {
+	result := models.BatchResult{ID: line.CustomID}
+	switch line.Result.Type {
+	case "succeeded":
+		if line.Result.Message == nil {
+			result.Error = fmt.Errorf("batch request %s has no message", line.CustomID)
+			break
+		}
+		result.Response = p.convertResponse(line.Result.Message)
+		result.Response.Usage.Cost *= models.BatchDiscount
+	case "errored":
+		if line.Result.Error != nil && line.Result.Error.Error != nil {
+			result.Error = models.NewAPIError("anthropic", line.Result.Error.Error.Type, line.Result.Error.Error.Message)
+		} else {
+			result.Error = models.NewAPIError("anthropic", "errored", "batch request failed")
+		}
+	default: // canceled, expired
+		result.Error = fmt.Errorf("batch request %s %s", line.CustomID, line.Result.Type)
+	}
+	return result
+}
+
+// batchCall sends a Message Batches API request and decodes the response
+// into out, if not nil.
+func (p *Provider) batchCall(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return models.NewHTTPError("anthropic", resp, body)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Message Batches API types
+
+type createBatchRequest struct {
+	Requests []batchRequest `json:"requests"`
+}
+
+type batchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Params   *messageRequest `json:"params"`
+}
+
+type messageBatch struct {
+	ID               string    `json:"id"`
+	ProcessingStatus string    `json:"processing_status"`
+	CreatedAt        time.Time `json:"created_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CancelInitiated  *string   `json:"cancel_initiated_at"`
+	RequestCounts    struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+// convert maps the batch onto the provider-neutral states.
+func (b *messageBatch) convert() *models.Batch {
+	counts := b.RequestCounts
+	batch := &models.Batch{
+		ID:        b.ID,
+		Requests:  counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired,
+		Succeeded: counts.Succeeded,
+		Failed:    counts.Errored,
+		CreatedAt: b.CreatedAt,
+		ExpiresAt: b.ExpiresAt,
+	}
+	switch {
+	case b.ProcessingStatus != "ended":
+		batch.Status = models.BatchInProgress
+	case b.CancelInitiated != nil:
+		batch.Status = models.BatchCanceled
+	case counts.Expired > 0 && counts.Succeeded+counts.Errored == 0:
+		batch.Status = models.BatchExpired
+	default:
+		batch.Status = models.BatchEnded
+	}
+	return batch
+}
+
+type batchResultLine struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string           `json:"type"` // succeeded, errored, canceled or expired
+		Message *messageResponse `json:"message"`
+		Error   *struct {
+			Error *struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"error"`
+	} `json:"result"`
+}
@@ -0,0 +1,212 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// modelsTTL is how long a discovered model list is reused before it is
+// fetched again.
+const modelsTTL = time.Hour
+
+// Defaults for discovered models missing from the pricing table
+const (
+	defaultContextWindow = 200000
+	defaultMaxOutput     = 8192
+)
+
+// modelsPageSize is the page size requested from the models endpoint.
+const modelsPageSize = 1000
+
+// ListModels returns the Claude models available to the API key, discovered
+// from the models endpoint and merged with the local pricing table. Aliases
+// from the pricing table ("claude-sonnet-4-5") are kept alongside the dated
+// IDs they resolve to. If discovery fails, the pricing table itself is returned.
+func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
+	p.modelsMu.Lock()
+	defer p.modelsMu.Unlock()
+
+	if p.modelList != nil && time.Since(p.modelsFetched) < modelsTTL {
+		return p.modelList, nil
+	}
+
+	infos, err := p.fetchModels(ctx)
+	if err != nil {
+		return knownModels, nil
+	}
+
+	p.modelList = mergeModels(infos)
+	p.modelsFetched = time.Now()
+	return p.modelList, nil
+}
+
+// fetchModels pages through the models endpoint.
+func (p *Provider) fetchModels(ctx context.Context) ([]modelInfo, error) {
+	var all []modelInfo
+	afterID := ""
+
+	for {
+		query := url.Values{"limit": {fmt.Sprint(modelsPageSize)}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		p.setHeaders(httpReq)
+
+		resp, err := p.client.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, models.NewHTTPError("anthropic", resp, body)
+		}
+
+		var page modelListResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode model list: %w", err)
+		}
+
+		all = append(all, page.Data...)
+		if !page.HasMore || page.LastID == "" {
+			return all, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+// mergeModels builds the model list from discovered models. Each model
+// inherits pricing and limits from the pricing table entry its ID extends
+// ("claude-sonnet-4-5-20250929" from "claude-sonnet-4-5"); unmatched models
+// are priced by family.
+func mergeModels(infos []modelInfo) []models.Model {
+	var list []models.Model
+	seen := make(map[string]bool)
+
+	// Aliases first, for every known model that is still served
+	for _, known := range knownModels {
+		for _, info := range infos {
+			if info.ID == known.ID || strings.HasPrefix(info.ID, known.ID+"-") {
+				list = append(list, known)
+				seen[known.ID] = true
+				break
+			}
+		}
+	}
+
+	for _, info := range infos {
+		if seen[info.ID] {
+			continue
+		}
+		m, ok := lookupKnown(info.ID)
+		if !ok {
+			m = models.Model{
+				Provider:      "anthropic",
+				ContextWindow: defaultContextWindow,
+				MaxOutput:     defaultMaxOutput,
+				Pricing: models.Pricing{
+					InputTokens:  calculateCost(info.ID, 1, 0),
+					OutputTokens: calculateCost(info.ID, 0, 1),
+				},
+				Capabilities: []string{"streaming", "tools", "vision"},
+			}
+		}
+		m.ID = info.ID
+		m.Name = info.DisplayName
+		if m.Name == "" {
+			m.Name = info.ID
+		}
+		if t, err := time.Parse(time.RFC3339, info.CreatedAt); err == nil {
+			m.CreatedAt = t
+		}
+		list = append(list, m)
+		seen[info.ID] = true
+	}
+	return list
+}
+
+// lookupKnown returns the pricing table entry that id is a dated variant of.
+func lookupKnown(id string) (models.Model, bool) {
+	var best models.Model
+	found := false
+	for _, m := range knownModels {
+		if id != m.ID && !strings.HasPrefix(id, m.ID+"-") {
+			continue
+		}
+		if !found || len(m.ID) > len(best.ID) {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
+// knownModels is the local pricing table, also used when discovery fails.
+var knownModels = []models.Model{
+	{
+		ID:            "claude-opus-4-1",
+		Name:          "Claude Opus 4.1",
+		Provider:      "anthropic",
+		ContextWindow: 200000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  15.00 / 1000000, // $15 per million tokens
+			OutputTokens: 75.00 / 1000000, // $75 per million tokens
+		},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "claude-sonnet-4-5",
+		Name:          "Claude Sonnet 4.5",
+		Provider:      "anthropic",
+		ContextWindow: 200000,
+		MaxOutput:     8192,
+		Pricing: models.Pricing{
+			InputTokens:  3.00 / 1000000,  // $3 per million tokens
+			OutputTokens: 15.00 / 1000000, // $15 per million tokens
+		},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "claude-haiku-4-0",
+		Name:          "Claude Haiku 4.0",
+		Provider:      "anthropic",
+		ContextWindow: 200000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  0.80 / 1000000, // $0.80 per million tokens
+			OutputTokens: 4.00 / 1000000, // $4 per million tokens
+		},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+}
+
+// Models API types
+
+type modelListResponse struct {
+	Data    []modelInfo `json:"data"`
+	HasMore bool        `json:"has_more"`
+	LastID  string      `json:"last_id"`
+}
+
+type modelInfo struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	CreatedAt   string `json:"created_at"`
+}
@@ -0,0 +1,589 @@
+// Package cohere provides Cohere API integration using the v2 chat API.
+// Cohere's grounded-generation extras (citations and the tool plan the model
+// writes before calling tools) are surfaced in CompletionResponse.Metadata.
+package cohere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+const defaultBaseURL = "https://api.cohere.com"
+
+// Metadata keys set on responses
+const (
+	MetadataToolPlan  = "tool_plan" // Model's plan before calling tools
+	MetadataCitations = "citations" // JSON array of citations
+)
+
+// Provider implements the Cohere API provider.
+type Provider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// New creates a new Cohere provider.
+func New(apiKey string, opts ...Option) *Provider {
+	p := &Provider{
+		apiKey:  apiKey,
+		baseURL: defaultBaseURL,
+		client:  transport.NewClient(300 * time.Second),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Option is a functional option for configuring the provider.
+type Option func(*Provider)
+
+// WithBaseURL sets a custom base URL.
+func WithBaseURL(baseURL string) Option {
+	return func(p *Provider) {
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "cohere"
+}
+
+// ListModels returns available Cohere models.
+func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
+	return []models.Model{
+		{
+			ID:            "command-r-plus-08-2024",
+			Name:          "Command R+",
+			Provider:      "cohere",
+			ContextWindow: 128000,
+			MaxOutput:     4096,
+			Pricing: models.Pricing{
+				InputTokens:  2.50 / 1000000, // $2.50 per million
+				OutputTokens: 10.0 / 1000000, // $10 per million
+			},
+			Capabilities: []string{"streaming", "tools", "citations"},
+		},
+		{
+			ID:            "command-r-08-2024",
+			Name:          "Command R",
+			Provider:      "cohere",
+			ContextWindow: 128000,
+			MaxOutput:     4096,
+			Pricing: models.Pricing{
+				InputTokens:  0.15 / 1000000, // $0.15 per million
+				OutputTokens: 0.60 / 1000000, // $0.60 per million
+			},
+			Capabilities: []string{"streaming", "tools", "citations"},
+		},
+		{
+			ID:            "command-r7b-12-2024",
+			Name:          "Command R7B",
+			Provider:      "cohere",
+			ContextWindow: 128000,
+			MaxOutput:     4096,
+			Pricing: models.Pricing{
+				InputTokens:  0.0375 / 1000000, // $0.0375 per million
+				OutputTokens: 0.15 / 1000000,   // $0.15 per million
+			},
+			Capabilities: []string{"streaming", "tools", "citations"},
+		},
+	}, nil
+}
+
+// CreateCompletion creates a non-streaming completion.
+func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	apiReq := p.convertRequest(req, false)
+
+	resp, err := p.doRequest(ctx, apiReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var apiResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return p.convertResponse(req.Model, &apiResp), nil
+}
+
+// StreamCompletion creates a streaming completion. The tool plan and
+// citations are attached to the final token's Metadata.
+func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
+	apiReq := p.convertRequest(req, true)
+
+	resp, err := p.doRequest(ctx, apiReq)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(chan models.StreamToken, 10)
+
+	go func() {
+		defer close(tokens)
+		defer resp.Body.Close()
+
+		scanner := transport.NewLineReader(resp.Body)
+
+		var toolPlan strings.Builder
+		var citations []citation
+		pending := make(map[int]*toolCall) // Tool calls accumulate across events by index
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			var event streamEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				tokens <- models.StreamToken{Error: err}
+				return
+			}
+
+			delta := event.Delta.Message
+			switch event.Type {
+			case "content-delta":
+				if delta.Content.Text != "" {
+					tokens <- models.StreamToken{Content: delta.Content.Text}
+				}
+
+			case "tool-plan-delta":
+				toolPlan.WriteString(delta.ToolPlan)
+
+			case "tool-call-start":
+				pending[event.Index] = &toolCall{
+					ID:       delta.ToolCalls.ID,
+					Type:     delta.ToolCalls.Type,
+					Function: delta.ToolCalls.Function,
+				}
+
+			case "tool-call-delta":
+				if call, ok := pending[event.Index]; ok {
+					call.Function.Arguments += delta.ToolCalls.Function.Arguments
+				}
+
+			case "citation-start":
+				citations = append(citations, delta.Citations)
+
+			case "message-end":
+				flushToolCalls(tokens, pending)
+
+				final := models.StreamToken{
+					Done:     true,
+					Metadata: buildMetadata(toolPlan.String(), citations),
+				}
+				if event.Delta.FinishReason != "" {
+					final.StopReason = convertStopReason(event.Delta.FinishReason)
+				}
+				if event.Delta.Usage != nil {
+					u := convertUsage(req.Model, event.Delta.Usage)
+					final.Usage = &u
+				}
+				tokens <- final
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			tokens <- models.StreamToken{Error: err}
+		}
+	}()
+
+	return tokens, nil
+}
+
+// TestConnection tests the API connection.
+func (p *Provider) TestConnection(ctx context.Context) error {
+	if p.apiKey == "" {
+		return fmt.Errorf("API key not set")
+	}
+	return nil
+}
+
+// GetModelInfo returns information about a specific model.
+func (p *Provider) GetModelInfo(ctx context.Context, modelID string) (*models.ModelInfo, error) {
+	allModels, err := p.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range allModels {
+		if model.ID == modelID {
+			return &models.ModelInfo{
+				Model:       model,
+				Description: "Cohere Command model",
+				Available:   true,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("model %s not found", modelID)
+}
+
+// SupportsStreaming returns true.
+func (p *Provider) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsTools returns true.
+func (p *Provider) SupportsTools() bool {
+	return true
+}
+
+// Helper methods
+
+func (p *Provider) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("X-Client-Name", "bplus")
+}
+
+// doRequest sends a chat request and returns the response on HTTP 200.
+func (p *Provider) doRequest(ctx context.Context, apiReq *chatRequest) (*http.Response, error) {
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/v2/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, models.NewHTTPError("cohere", resp, body)
+	}
+
+	return resp, nil
+}
+
+func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *chatRequest {
+	apiReq := &chatRequest{
+		Model:            req.Model,
+		Stream:           stream,
+		Messages:         make([]chatMessage, 0, len(req.Messages)+1),
+		Temperature:      req.Temperature,
+		P:                req.TopP,
+		K:                req.TopK,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+	}
+
+	if req.System != "" {
+		apiReq.Messages = append(apiReq.Messages, chatMessage{
+			Role:    "system",
+			Content: req.System,
+		})
+	}
+
+	for _, msg := range req.Messages {
+		// Cohere's tool role requires the originating tool_call_id, which
+		// conversation messages don't carry; send results as user turns.
+		if msg.Role == "tool" {
+			apiReq.Messages = append(apiReq.Messages, chatMessage{
+				Role:    "user",
+				Content: fmt.Sprintf("Result of tool %s:\n%s", msg.Name, msg.Content),
+			})
+			continue
+		}
+		apiReq.Messages = append(apiReq.Messages, chatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+
+	if req.MaxTokens > 0 {
+		apiReq.MaxTokens = req.MaxTokens
+	}
+
+	if len(req.StopSequences) > 0 {
+		apiReq.StopSequences = req.StopSequences
+	}
+
+	if len(req.Tools) > 0 {
+		apiReq.Tools = make([]tool, len(req.Tools))
+		for i, t := range req.Tools {
+			apiReq.Tools[i] = tool{
+				Type: "function",
+				Function: functionDef{
+					Name:        t.Name,
+					Description: t.Description,
+					Parameters:  convertToolParams(t.Parameters),
+				},
+			}
+		}
+	}
+
+	return apiReq
+}
+
+func (p *Provider) convertResponse(model string, apiResp *chatResponse) *models.CompletionResponse {
+	resp := &models.CompletionResponse{
+		Model:      model,
+		StopReason: convertStopReason(apiResp.FinishReason),
+		Metadata:   buildMetadata(apiResp.Message.ToolPlan, apiResp.Message.Citations),
+	}
+
+	var content strings.Builder
+	for _, block := range apiResp.Message.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	resp.Content = content.String()
+
+	if len(apiResp.Message.ToolCalls) > 0 {
+		resp.ToolCalls = make([]models.ToolCall, len(apiResp.Message.ToolCalls))
+		for i, tc := range apiResp.Message.ToolCalls {
+			resp.ToolCalls[i] = convertToolCall(tc)
+		}
+		resp.StopReason = "tool_use"
+	}
+
+	if apiResp.Usage != nil {
+		resp.Usage = convertUsage(model, apiResp.Usage)
+	}
+
+	return resp
+}
+
+// buildMetadata encodes the tool plan and citations, returning nil if there are neither.
+func buildMetadata(toolPlan string, citations []citation) map[string]string {
+	if toolPlan == "" && len(citations) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string)
+	if toolPlan != "" {
+		metadata[MetadataToolPlan] = toolPlan
+	}
+	if len(citations) > 0 {
+		if data, err := json.Marshal(citations); err == nil {
+			metadata[MetadataCitations] = string(data)
+		}
+	}
+	return metadata
+}
+
+// flushToolCalls emits accumulated streaming tool calls in index order.
+func flushToolCalls(tokens chan<- models.StreamToken, pending map[int]*toolCall) {
+	indexes := make([]int, 0, len(pending))
+	for i := range pending {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		call := convertToolCall(*pending[i])
+		tokens <- models.StreamToken{ToolCall: &call}
+		delete(pending, i)
+	}
+}
+
+func convertToolCall(tc toolCall) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return models.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Function.Name,
+		Arguments: args,
+	}
+}
+
+func convertStopReason(reason string) string {
+	switch reason {
+	case "COMPLETE":
+		return "end_turn"
+	case "MAX_TOKENS":
+		return "max_tokens"
+	case "STOP_SEQUENCE":
+		return "stop_sequence"
+	case "TOOL_CALL":
+		return "tool_use"
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+func convertToolParams(params []models.Parameter) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for _, p := range params {
+		properties[p.Name] = map[string]interface{}{
+			"type":        p.Type,
+			"description": p.Description,
+		}
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// convertUsage converts API usage. Cost is based on billed units, which can
+// differ from the raw token counts.
+func convertUsage(model string, u *usage) models.Usage {
+	result := models.Usage{
+		InputTokens:  int(u.Tokens.InputTokens),
+		OutputTokens: int(u.Tokens.OutputTokens),
+	}
+	if result.InputTokens == 0 && result.OutputTokens == 0 {
+		result.InputTokens = int(u.BilledUnits.InputTokens)
+		result.OutputTokens = int(u.BilledUnits.OutputTokens)
+	}
+	result.TotalTokens = result.InputTokens + result.OutputTokens
+	result.Cost = calculateCost(model, int(u.BilledUnits.InputTokens), int(u.BilledUnits.OutputTokens))
+	return result
+}
+
+// calculateCost prices a call from the cohere pricing table.
+func calculateCost(model string, inputTokens, outputTokens int) float64 {
+	return pricing.Lookup("cohere", model).Cost(inputTokens, outputTokens)
+}
+
+// API types
+
+type chatRequest struct {
+	Model            string        `json:"model"`
+	Messages         []chatMessage `json:"messages"`
+	Tools            []tool        `json:"tools,omitempty"`
+	Stream           bool          `json:"stream,omitempty"`
+	MaxTokens        int           `json:"max_tokens,omitempty"`
+	Temperature      *float64      `json:"temperature,omitempty"`
+	P                *float64      `json:"p,omitempty"`
+	K                *int          `json:"k,omitempty"`
+	StopSequences    []string      `json:"stop_sequences,omitempty"`
+	FrequencyPenalty *float64      `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64      `json:"presence_penalty,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type tool struct {
+	Type     string      `json:"type"`
+	Function functionDef `json:"function"`
+}
+
+type functionDef struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type toolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function toolCallFunc `json:"function"`
+}
+
+type toolCallFunc struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatResponse struct {
+	ID           string          `json:"id"`
+	FinishReason string          `json:"finish_reason"`
+	Message      responseMessage `json:"message"`
+	Usage        *usage          `json:"usage"`
+}
+
+type responseMessage struct {
+	Role      string         `json:"role"`
+	Content   []contentBlock `json:"content"`
+	ToolPlan  string         `json:"tool_plan,omitempty"`
+	ToolCalls []toolCall     `json:"tool_calls,omitempty"`
+	Citations []citation     `json:"citations,omitempty"`
+}
+
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type citation struct {
+	Start   int              `json:"start"`
+	End     int              `json:"end"`
+	Text    string           `json:"text"`
+	Sources []citationSource `json:"sources,omitempty"`
+}
+
+type citationSource struct {
+	Type     string                 `json:"type"`
+	ID       string                 `json:"id,omitempty"`
+	Document map[string]interface{} `json:"document,omitempty"`
+}
+
+type usage struct {
+	BilledUnits tokenCounts `json:"billed_units"`
+	Tokens      tokenCounts `json:"tokens"`
+}
+
+// tokenCounts values are numbers that Cohere may encode as floats.
+type tokenCounts struct {
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+}
+
+type streamEvent struct {
+	Type  string      `json:"type"`
+	Index int         `json:"index"`
+	Delta streamDelta `json:"delta"`
+}
+
+type streamDelta struct {
+	Message      streamMessage `json:"message"`
+	FinishReason string        `json:"finish_reason,omitempty"`
+	Usage        *usage        `json:"usage,omitempty"`
+}
+
+type streamMessage struct {
+	Content   contentBlock `json:"content"`
+	ToolPlan  string       `json:"tool_plan,omitempty"`
+	ToolCalls toolCall     `json:"tool_calls"`
+	Citations citation     `json:"citations"`
+}
@@ -0,0 +1,169 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	p := New("test-key")
+	assert.Equal(t, "cohere", p.Name())
+	assert.Equal(t, defaultBaseURL, p.baseURL)
+	assert.NotNil(t, p.client)
+
+	p = New("test-key", WithBaseURL("http://custom/"))
+	assert.Equal(t, "http://custom", p.baseURL)
+}
+
+func TestProvider_CreateCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/chat", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var req chatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "command-r-08-2024", req.Model)
+		require.Len(t, req.Messages, 3)
+		assert.Equal(t, "system", req.Messages[0].Role)
+		assert.Equal(t, "user", req.Messages[2].Role, "tool results are sent as user turns")
+		assert.Contains(t, req.Messages[2].Content, "Result of tool read")
+		require.Len(t, req.Tools, 1)
+		assert.Equal(t, "read", req.Tools[0].Function.Name)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":            "resp-1",
+			"finish_reason": "TOOL_CALL",
+			"message": map[string]interface{}{
+				"role":      "assistant",
+				"content":   []map[string]interface{}{{"type": "text", "text": "Reading the file."}},
+				"tool_plan": "I will read main.go first.",
+				"tool_calls": []map[string]interface{}{{
+					"id":   "call_1",
+					"type": "function",
+					"function": map[string]interface{}{
+						"name":      "read",
+						"arguments": `{"path":"main.go"}`,
+					},
+				}},
+				"citations": []map[string]interface{}{{
+					"start": 0, "end": 7, "text": "Reading",
+					"sources": []map[string]interface{}{{"type": "tool", "id": "call_0"}},
+				}},
+			},
+			"usage": map[string]interface{}{
+				"billed_units": map[string]interface{}{"input_tokens": 100.0, "output_tokens": 20.0},
+				"tokens":       map[string]interface{}{"input_tokens": 150, "output_tokens": 20},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	resp, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{
+		Model:  "command-r-08-2024",
+		System: "Be brief.",
+		Messages: []models.Message{
+			{Role: "user", Content: "Read main.go"},
+			{Role: "tool", Name: "read", Content: "package main"},
+		},
+		Tools: []models.Tool{{Name: "read"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Reading the file.", resp.Content)
+	assert.Equal(t, "tool_use", resp.StopReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "main.go", resp.ToolCalls[0].Arguments["path"])
+
+	assert.Equal(t, "I will read main.go first.", resp.Metadata[MetadataToolPlan])
+	var citations []citation
+	require.NoError(t, json.Unmarshal([]byte(resp.Metadata[MetadataCitations]), &citations))
+	require.Len(t, citations, 1)
+	assert.Equal(t, "call_0", citations[0].Sources[0].ID)
+
+	assert.Equal(t, 150, resp.Usage.InputTokens)
+	assert.InDelta(t, 100*0.15/1e6+20*0.60/1e6, resp.Usage.Cost, 1e-12, "cost uses billed units")
+}
+
+func TestProvider_StreamCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message-start","id":"resp-1"}`,
+			`{"type":"tool-plan-delta","delta":{"message":{"tool_plan":"Check "}}}`,
+			`{"type":"tool-plan-delta","delta":{"message":{"tool_plan":"the file."}}}`,
+			`{"type":"tool-call-start","index":0,"delta":{"message":{"tool_calls":{"id":"call_1","type":"function","function":{"name":"read","arguments":""}}}}}`,
+			`{"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"{\"path\":"}}}}}`,
+			`{"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"\"a.go\"}"}}}}}`,
+			`{"type":"tool-call-end","index":0}`,
+			`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"Hello"}}}}`,
+			`{"type":"citation-start","index":0,"delta":{"message":{"citations":{"start":0,"end":5,"text":"Hello"}}}}`,
+			`{"type":"message-end","delta":{"finish_reason":"COMPLETE","usage":{"billed_units":{"input_tokens":10,"output_tokens":5},"tokens":{"input_tokens":12,"output_tokens":5}}}}`,
+		}
+		for _, e := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", e)
+		}
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "command-r-plus-08-2024",
+		Messages: []models.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var content string
+	var calls []*models.ToolCall
+	var final *models.StreamToken
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		content += tok.Content
+		if tok.ToolCall != nil {
+			calls = append(calls, tok.ToolCall)
+		}
+		if tok.Done {
+			tok := tok
+			final = &tok
+		}
+	}
+
+	assert.Equal(t, "Hello", content)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "a.go", calls[0].Arguments["path"])
+
+	require.NotNil(t, final)
+	assert.Equal(t, "Check the file.", final.Metadata[MetadataToolPlan])
+	assert.Contains(t, final.Metadata[MetadataCitations], `"text":"Hello"`)
+	require.NotNil(t, final.Usage)
+	assert.Equal(t, 17, final.Usage.TotalTokens)
+}
+
+func TestProvider_ErrorHandling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"message":"overloaded"}`))
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	_, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{Model: "command-r-08-2024"})
+	require.Error(t, err)
+
+	var provErr *models.ProviderError
+	require.ErrorAs(t, err, &provErr)
+	assert.True(t, provErr.IsRetryable())
+	assert.Equal(t, "HTTP_503", provErr.Code)
+}
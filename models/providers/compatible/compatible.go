@@ -0,0 +1,597 @@
+// Package compatible provides a generic provider for servers that speak the
+// OpenAI chat completions API, such as Together, Groq, Fireworks, llama.cpp
+// or a company gateway, so they can be used from config alone with
+// providers.<name>.type: openai-compatible. Servers with a dedicated
+// provider (vLLM, LM Studio, DeepSeek, OpenRouter) should use it instead,
+// as it knows their extensions.
+package compatible
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// defaultContextWindow is assumed for models the server doesn't give a
+// context length for.
+const defaultContextWindow = 8192
+
+// Provider implements a provider for an OpenAI-compatible server.
+type Provider struct {
+	name          string
+	baseURL       string
+	apiKey        string // Optional; sent as a bearer token when set
+	headers       map[string]string
+	contextWindow int
+	client        *http.Client
+}
+
+// New creates a provider named name for the server at baseURL, which
+// includes the version suffix, e.g. https://api.together.xyz/v1. Models are
+// addressed as name/model-id.
+func New(name, baseURL string, opts ...Option) *Provider {
+	p := &Provider{
+		name:          name,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		contextWindow: defaultContextWindow,
+		client:        transport.NewClient(300 * time.Second),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Option is a functional option for configuring the provider.
+type Option func(*Provider)
+
+// WithAPIKey sets the API key sent as a bearer token.
+func WithAPIKey(apiKey string) Option {
+	return func(p *Provider) {
+		p.apiKey = apiKey
+	}
+}
+
+// WithHeaders sets headers sent with every request, such as an
+// organization or routing header a gateway wants.
+func WithHeaders(headers map[string]string) Option {
+	return func(p *Provider) {
+		p.headers = headers
+	}
+}
+
+// WithContextWindow sets the context length assumed for models the server
+// doesn't report one for.
+func WithContextWindow(tokens int) Option {
+	return func(p *Provider) {
+		if tokens > 0 {
+			p.contextWindow = tokens
+		}
+	}
+}
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// Name returns the name the provider was configured under.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// ListModels returns the models the server lists at /models.
+func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, models.NewHTTPError(p.name, resp, body)
+	}
+
+	var apiResp listModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result := make([]models.Model, 0, len(apiResp.Data))
+	for _, m := range apiResp.Data {
+		// Servers disagree on where the context length goes
+		contextWindow := m.ContextLength
+		if contextWindow == 0 {
+			contextWindow = m.MaxModelLen
+		}
+		if contextWindow == 0 {
+			contextWindow = p.contextWindow
+		}
+
+		price := pricing.Lookup(p.name, m.ID)
+		model := models.Model{
+			ID:            m.ID,
+			Name:          m.ID,
+			Provider:      p.name,
+			ContextWindow: contextWindow,
+			MaxOutput:     contextWindow,
+			Pricing: models.Pricing{
+				InputTokens:  price.InputRate(),
+				OutputTokens: price.OutputRate(),
+			},
+			Capabilities: []string{"streaming", "tools"},
+		}
+		if m.Created > 0 {
+			model.CreatedAt = time.Unix(m.Created, 0)
+		}
+		result = append(result, model)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result, nil
+}
+
+// CreateCompletion creates a non-streaming completion.
+func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	resp, err := p.doRequest(ctx, p.convertRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var apiResp chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return p.convertResponse(&apiResp, req.Model), nil
+}
+
+// StreamCompletion creates a streaming completion.
+func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
+	resp, err := p.doRequest(ctx, p.convertRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(chan models.StreamToken, 10)
+
+	go func() {
+		defer close(tokens)
+		defer resp.Body.Close()
+
+		scanner := transport.NewLineReader(resp.Body)
+
+		var totalUsage *models.Usage
+		var stopReason string
+		pending := make(map[int]*toolCall) // Tool calls accumulate across chunks by index
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				flushToolCalls(tokens, pending)
+				tokens <- models.StreamToken{
+					Done:       true,
+					StopReason: stopReason,
+					Usage:      totalUsage,
+				}
+				return
+			}
+
+			var chunk chatCompletionChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				tokens <- models.StreamToken{Error: err}
+				return
+			}
+
+			if len(chunk.Choices) > 0 {
+				delta := chunk.Choices[0].Delta
+				if reasoning := delta.reasoning(); reasoning != "" {
+					tokens <- models.StreamToken{Reasoning: reasoning}
+				}
+				if delta.Content != "" {
+					tokens <- models.StreamToken{Content: delta.Content}
+				}
+
+				for _, tc := range delta.ToolCalls {
+					call, ok := pending[tc.Index]
+					if !ok {
+						call = &toolCall{ID: tc.ID, Type: tc.Type}
+						pending[tc.Index] = call
+					}
+					if tc.Function.Name != "" {
+						call.Function.Name = tc.Function.Name
+					}
+					call.Function.Arguments += tc.Function.Arguments
+				}
+
+				if chunk.Choices[0].FinishReason != "" {
+					stopReason = models.NormalizeStopReason(chunk.Choices[0].FinishReason)
+					flushToolCalls(tokens, pending)
+				}
+			}
+
+			if chunk.Usage != nil {
+				totalUsage = p.convertUsage(chunk.Usage, req.Model)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			tokens <- models.StreamToken{Error: err}
+			return
+		}
+
+		// Some servers close the stream without [DONE]
+		flushToolCalls(tokens, pending)
+		tokens <- models.StreamToken{Done: true, StopReason: stopReason, Usage: totalUsage}
+	}()
+
+	return tokens, nil
+}
+
+// TestConnection checks that the server answers and accepts the API key by
+// listing its models.
+func (p *Provider) TestConnection(ctx context.Context) error {
+	if _, err := p.ListModels(ctx); err != nil {
+		return fmt.Errorf("%s not accessible at %s: %w", p.name, p.baseURL, err)
+	}
+	return nil
+}
+
+// GetModelInfo returns information about a specific model.
+func (p *Provider) GetModelInfo(ctx context.Context, modelID string) (*models.ModelInfo, error) {
+	allModels, err := p.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range allModels {
+		if model.ID == modelID {
+			return &models.ModelInfo{
+				Model:       model,
+				Description: "Model served by " + p.name,
+				Available:   true,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("model %s not found", modelID)
+}
+
+// SupportsStreaming returns true.
+func (p *Provider) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsTools returns true. Servers that don't support tools reject
+// requests that send them.
+func (p *Provider) SupportsTools() bool {
+	return true
+}
+
+// Helper methods
+
+func (p *Provider) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// doRequest sends a chat completion request and returns the response on HTTP 200.
+func (p *Provider) doRequest(ctx context.Context, apiReq *chatCompletionRequest) (*http.Response, error) {
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, models.NewHTTPError(p.name, resp, body)
+	}
+
+	return resp, nil
+}
+
+func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *chatCompletionRequest {
+	apiReq := &chatCompletionRequest{
+		Model:            req.Model,
+		Stream:           stream,
+		Messages:         make([]chatMessage, 0, len(req.Messages)+1),
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		Stop:             req.StopSequences,
+		User:             models.EndUser(req, ""),
+	}
+	if stream {
+		apiReq.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	if req.System != "" {
+		apiReq.Messages = append(apiReq.Messages, chatMessage{
+			Role:    "system",
+			Content: req.System,
+		})
+	}
+
+	for _, msg := range req.Messages {
+		apiMsg := chatMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, tc := range msg.ToolCalls {
+			args, _ := json.Marshal(tc.Arguments)
+			apiMsg.ToolCalls = append(apiMsg.ToolCalls, toolCall{
+				ID:       tc.ID,
+				Type:     "function",
+				Function: toolCallFunc{Name: tc.Name, Arguments: string(args)},
+			})
+		}
+		apiReq.Messages = append(apiReq.Messages, apiMsg)
+	}
+
+	if len(req.Tools) > 0 {
+		apiReq.Parallel = &req.ParallelToolCalls
+		apiReq.Tools = make([]tool, len(req.Tools))
+		for i, t := range req.Tools {
+			apiReq.Tools[i] = tool{
+				Type: "function",
+				Function: functionDef{
+					Name:        t.Name,
+					Description: t.Description,
+					Parameters:  convertToolParams(t.Parameters),
+				},
+			}
+		}
+	}
+
+	return apiReq
+}
+
+// convertResponse converts a completion. Servers that don't echo the model
+// keep the one requested, so the price can still be looked up.
+func (p *Provider) convertResponse(apiResp *chatCompletionResponse, requested string) *models.CompletionResponse {
+	resp := &models.CompletionResponse{
+		Model: apiResp.Model,
+	}
+	if resp.Model == "" {
+		resp.Model = requested
+	}
+
+	if len(apiResp.Choices) > 0 {
+		choice := apiResp.Choices[0]
+		resp.Content = choice.Message.Content
+		resp.Reasoning = choice.Message.reasoning()
+		resp.StopReason = models.NormalizeStopReason(choice.FinishReason)
+
+		if len(choice.Message.ToolCalls) > 0 {
+			resp.ToolCalls = make([]models.ToolCall, len(choice.Message.ToolCalls))
+			for i, tc := range choice.Message.ToolCalls {
+				resp.ToolCalls[i] = convertToolCall(tc)
+			}
+			resp.StopReason = "tool_use"
+		}
+	}
+
+	if apiResp.Usage != nil {
+		resp.Usage = *p.convertUsage(apiResp.Usage, resp.Model)
+	}
+
+	return resp
+}
+
+// convertUsage converts token usage, priced from the pricing table under
+// the provider's name; servers without an entry cost nothing.
+func (p *Provider) convertUsage(u *usage, model string) *models.Usage {
+	_, modelID, err := models.ParseModelName(model)
+	if err != nil {
+		modelID = model
+	}
+	return &models.Usage{
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+		TotalTokens:  u.TotalTokens,
+		Cost:         pricing.Lookup(p.name, modelID).Cost(u.PromptTokens, u.CompletionTokens),
+	}
+}
+
+// flushToolCalls emits accumulated streaming tool calls in index order.
+func flushToolCalls(tokens chan<- models.StreamToken, pending map[int]*toolCall) {
+	indexes := make([]int, 0, len(pending))
+	for i := range pending {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		call := convertToolCall(*pending[i])
+		tokens <- models.StreamToken{ToolCall: &call}
+		delete(pending, i)
+	}
+}
+
+func convertToolCall(tc toolCall) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return models.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Function.Name,
+		Arguments: args,
+	}
+}
+
+func convertToolParams(params []models.Parameter) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for _, p := range params {
+		properties[p.Name] = map[string]interface{}{
+			"type":        p.Type,
+			"description": p.Description,
+		}
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// API types
+
+type chatCompletionRequest struct {
+	Model            string         `json:"model"`
+	Messages         []chatMessage  `json:"messages"`
+	MaxTokens        int            `json:"max_tokens,omitempty"`
+	Temperature      *float64       `json:"temperature,omitempty"`
+	TopP             *float64       `json:"top_p,omitempty"`
+	FrequencyPenalty *float64       `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64       `json:"presence_penalty,omitempty"`
+	Stop             []string       `json:"stop,omitempty"`
+	Stream           bool           `json:"stream,omitempty"`
+	StreamOptions    *streamOptions `json:"stream_options,omitempty"`
+	Tools            []tool         `json:"tools,omitempty"`
+	Parallel         *bool          `json:"parallel_tool_calls,omitempty"` // Sent with Tools
+	User             string         `json:"user,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+
+	// Reasoning, under the name the server uses
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	Reasoning        string `json:"reasoning,omitempty"`
+}
+
+// reasoning returns the reasoning sent with a message, if any.
+func (m chatMessage) reasoning() string {
+	if m.ReasoningContent != "" {
+		return m.ReasoningContent
+	}
+	return m.Reasoning
+}
+
+type tool struct {
+	Type     string      `json:"type"`
+	Function functionDef `json:"function"`
+}
+
+type functionDef struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type toolCall struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function toolCallFunc `json:"function"`
+}
+
+type toolCallFunc struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatCompletionResponse struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   *usage   `json:"usage"`
+}
+
+type choice struct {
+	Index        int         `json:"index"`
+	Message      chatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type chatCompletionChunk struct {
+	ID      string        `json:"id"`
+	Model   string        `json:"model"`
+	Choices []choiceDelta `json:"choices"`
+	Usage   *usage        `json:"usage"`
+}
+
+type choiceDelta struct {
+	Index        int         `json:"index"`
+	Delta        chatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type listModelsResponse struct {
+	Data []modelData `json:"data"`
+}
+
+type modelData struct {
+	ID            string `json:"id"`
+	Created       int64  `json:"created"`
+	OwnedBy       string `json:"owned_by"`
+	ContextLength int    `json:"context_length"` // Together, OpenRouter style
+	MaxModelLen   int    `json:"max_model_len"`  // vLLM style
+}
@@ -0,0 +1,157 @@
+package compatible
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	p := New("together", "https://api.together.xyz/v1/", WithAPIKey("secret"), WithContextWindow(32768))
+	assert.Equal(t, "together", p.Name())
+	assert.Equal(t, "https://api.together.xyz/v1", p.baseURL)
+	assert.Equal(t, 32768, p.contextWindow)
+
+	p = New("llamacpp", "http://localhost:8080/v1", WithContextWindow(0))
+	assert.Equal(t, defaultContextWindow, p.contextWindow)
+}
+
+func TestProvider_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "team-a", r.Header.Get("X-Team"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "mistral-small", "context_length": 32768},
+				{"id": "llama-3.1-8b", "max_model_len": 131072},
+				{"id": "phi-3"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p := New("gateway", server.URL+"/v1", WithAPIKey("secret"), WithHeaders(map[string]string{"X-Team": "team-a"}))
+	list, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, "llama-3.1-8b", list[0].ID)
+	assert.Equal(t, 131072, list[0].ContextWindow)
+	assert.Equal(t, 32768, list[1].ContextWindow)
+	assert.Equal(t, defaultContextWindow, list[2].ContextWindow)
+	assert.Equal(t, "gateway", list[2].Provider)
+
+	require.NoError(t, p.TestConnection(context.Background()))
+}
+
+func TestProvider_CreateCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"), "no key, no header")
+
+		var req chatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Messages, 4)
+		assert.Equal(t, "system", req.Messages[0].Role)
+		assert.Equal(t, "read_file", req.Messages[2].ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"path":"main.go"}`, req.Messages[2].ToolCalls[0].Function.Arguments)
+		assert.Equal(t, "call_1", req.Messages[3].ToolCallID)
+		require.Len(t, req.Tools, 1)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message": map[string]interface{}{
+					"role":       "assistant",
+					"content":    "",
+					"reasoning":  "Need to look again",
+					"tool_calls": []map[string]interface{}{{"id": "call_2", "type": "function", "function": map[string]interface{}{"name": "read_file", "arguments": `{"path":"go.mod"}`}}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 20, "completion_tokens": 5, "total_tokens": 25},
+		})
+	}))
+	defer server.Close()
+
+	p := New("llamacpp", server.URL+"/v1")
+	resp, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{
+		Model:  "qwen2.5-coder",
+		System: "You are helpful",
+		Messages: []models.Message{
+			{Role: "user", Content: "What does main.go do?"},
+			{Role: "assistant", ToolCalls: []models.ToolCall{{ID: "call_1", Name: "read_file", Arguments: map[string]interface{}{"path": "main.go"}}}},
+			{Role: "tool", ToolCallID: "call_1", Name: "read_file", Content: "package main"},
+		},
+		Tools: []models.Tool{{Name: "read_file", Parameters: []models.Parameter{{Name: "path", Type: "string", Required: true}}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "qwen2.5-coder", resp.Model, "the requested model stands in for one not echoed")
+	assert.Equal(t, "Need to look again", resp.Reasoning)
+	assert.Equal(t, "tool_use", resp.StopReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "go.mod", resp.ToolCalls[0].Arguments["path"])
+	assert.Equal(t, 25, resp.Usage.TotalTokens)
+	assert.Zero(t, resp.Usage.Cost, "unpriced servers cost nothing")
+}
+
+func TestProvider_StreamCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		// No [DONE]: the stream just ends
+		chunks := []string{
+			`{"choices":[{"delta":{"reasoning_content":"Hmm"}}]}`,
+			`{"choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data:%s\n\n", c)
+		}
+	}))
+	defer server.Close()
+
+	p := New("llamacpp", server.URL+"/v1")
+	tokens, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "qwen2.5-coder",
+		Messages: []models.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var content, reasoning string
+	var last models.StreamToken
+	for tok := range tokens {
+		require.NoError(t, tok.Error)
+		content += tok.Content
+		reasoning += tok.Reasoning
+		last = tok
+	}
+	assert.Equal(t, "Hello", content)
+	assert.Equal(t, "Hmm", reasoning)
+	assert.True(t, last.Done)
+	assert.Equal(t, "end_turn", last.StopReason)
+	require.NotNil(t, last.Usage)
+	assert.Equal(t, 5, last.Usage.TotalTokens)
+}
+
+func TestProvider_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"invalid api key","type":"auth"}}`))
+	}))
+	defer server.Close()
+
+	p := New("gateway", server.URL+"/v1", WithAPIKey("wrong"))
+	_, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{Model: "m"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid api key")
+}
@@ -0,0 +1,567 @@
+// Package deepseek provides DeepSeek API integration.
+// DeepSeek exposes an OpenAI-compatible chat API; deepseek-reasoner
+// additionally returns its chain of thought as reasoning_content.
+package deepseek
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+const (
+	defaultBaseURL = "https://api.deepseek.com"
+
+	modelChat     = "deepseek-chat"
+	modelReasoner = "deepseek-reasoner"
+)
+
+// Provider implements the DeepSeek API provider.
+type Provider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+	user    string // Optional end-user identifier for spend attribution
+}
+
+// New creates a new DeepSeek provider.
+func New(apiKey string, opts ...Option) *Provider {
+	p := &Provider{
+		apiKey:  apiKey,
+		baseURL: defaultBaseURL,
+		client:  transport.NewClient(300 * time.Second), // Reasoning can take minutes
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Option is a functional option for configuring the provider.
+type Option func(*Provider)
+
+// WithBaseURL sets a custom base URL.
+func WithBaseURL(baseURL string) Option {
+	return func(p *Provider) {
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithUser sets the end-user identifier sent in the "user" field of every request.
+func WithUser(user string) Option {
+	return func(p *Provider) {
+		p.user = user
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "deepseek"
+}
+
+// ListModels returns available DeepSeek models.
+func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
+	return []models.Model{
+		{
+			ID:            modelChat,
+			Name:          "DeepSeek Chat",
+			Provider:      "deepseek",
+			ContextWindow: 64000,
+			MaxOutput:     8192,
+			Pricing: models.Pricing{
+				InputTokens:  0.27 / 1000000, // $0.27 per million (cache miss)
+				OutputTokens: 1.10 / 1000000, // $1.10 per million
+			},
+			Capabilities: []string{"streaming", "tools"},
+		},
+		{
+			ID:            modelReasoner,
+			Name:          "DeepSeek Reasoner",
+			Provider:      "deepseek",
+			ContextWindow: 64000,
+			MaxOutput:     32768,
+			Pricing: models.Pricing{
+				InputTokens:  0.55 / 1000000, // $0.55 per million (cache miss)
+				OutputTokens: 2.19 / 1000000, // $2.19 per million, reasoning included
+			},
+			Capabilities: []string{"streaming", "reasoning"},
+		},
+	}, nil
+}
+
+// CreateCompletion creates a non-streaming completion.
+func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	apiReq := p.convertRequest(req, false)
+
+	resp, err := p.doRequest(ctx, apiReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var apiResp chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return p.convertResponse(&apiResp), nil
+}
+
+// StreamCompletion creates a streaming completion. Reasoning deltas are
+// delivered in StreamToken.Reasoning, separately from answer content.
+func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
+	apiReq := p.convertRequest(req, true)
+
+	resp, err := p.doRequest(ctx, apiReq)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(chan models.StreamToken, 10)
+
+	go func() {
+		defer close(tokens)
+		defer resp.Body.Close()
+
+		scanner := transport.NewLineReader(resp.Body)
+
+		var totalUsage *models.Usage
+		var stopReason string
+		pending := make(map[int]*toolCall) // Tool calls accumulate across chunks by index
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			data := strings.TrimPrefix(line, "data: ")
+			if data == "[DONE]" {
+				flushToolCalls(tokens, pending)
+				tokens <- models.StreamToken{
+					Done:       true,
+					StopReason: stopReason,
+					Usage:      totalUsage,
+				}
+				return
+			}
+
+			var chunk chatCompletionChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				tokens <- models.StreamToken{Error: err}
+				return
+			}
+
+			if len(chunk.Choices) > 0 {
+				delta := chunk.Choices[0].Delta
+				if delta.ReasoningContent != "" {
+					tokens <- models.StreamToken{Reasoning: delta.ReasoningContent}
+				}
+				if delta.Content != "" {
+					tokens <- models.StreamToken{Content: delta.Content}
+				}
+
+				for _, tc := range delta.ToolCalls {
+					call, ok := pending[tc.Index]
+					if !ok {
+						call = &toolCall{ID: tc.ID, Type: tc.Type}
+						pending[tc.Index] = call
+					}
+					if tc.Function.Name != "" {
+						call.Function.Name = tc.Function.Name
+					}
+					call.Function.Arguments += tc.Function.Arguments
+				}
+
+				if chunk.Choices[0].FinishReason != "" {
+					stopReason = convertStopReason(chunk.Choices[0].FinishReason)
+					flushToolCalls(tokens, pending)
+				}
+			}
+
+			if chunk.Usage != nil {
+				u := convertUsage(req.Model, chunk.Usage)
+				totalUsage = &u
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			tokens <- models.StreamToken{Error: err}
+		}
+	}()
+
+	return tokens, nil
+}
+
+// TestConnection tests the API connection.
+func (p *Provider) TestConnection(ctx context.Context) error {
+	if p.apiKey == "" {
+		return fmt.Errorf("API key not set")
+	}
+	return nil
+}
+
+// GetModelInfo returns information about a specific model.
+func (p *Provider) GetModelInfo(ctx context.Context, modelID string) (*models.ModelInfo, error) {
+	allModels, err := p.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range allModels {
+		if model.ID == modelID {
+			return &models.ModelInfo{
+				Model:       model,
+				Description: "DeepSeek model",
+				Available:   true,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("model %s not found", modelID)
+}
+
+// SupportsStreaming returns true.
+func (p *Provider) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsTools returns true. Tools are dropped for deepseek-reasoner,
+// which does not accept function calling.
+func (p *Provider) SupportsTools() bool {
+	return true
+}
+
+// Helper methods
+
+func (p *Provider) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+}
+
+// doRequest sends a chat completion request and returns the response on HTTP 200.
+func (p *Provider) doRequest(ctx context.Context, apiReq *chatCompletionRequest) (*http.Response, error) {
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, models.NewHTTPError("deepseek", resp, body)
+	}
+
+	return resp, nil
+}
+
+func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *chatCompletionRequest {
+	reasoner := isReasoner(req.Model)
+
+	apiReq := &chatCompletionRequest{
+		Model:    req.Model,
+		Stream:   stream,
+		Messages: make([]chatMessage, 0, len(req.Messages)+1),
+		User:     models.EndUser(req, p.user),
+	}
+	if stream {
+		apiReq.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	if req.System != "" {
+		apiReq.Messages = append(apiReq.Messages, chatMessage{
+			Role:    "system",
+			Content: req.System,
+		})
+	}
+
+	for _, msg := range req.Messages {
+		apiReq.Messages = append(apiReq.Messages, chatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+
+	if req.MaxTokens > 0 {
+		apiReq.MaxTokens = req.MaxTokens
+	}
+
+	// deepseek-reasoner ignores sampling parameters; don't send them
+	if !reasoner {
+		apiReq.Temperature = req.Temperature
+		apiReq.TopP = req.TopP
+	}
+
+	if len(req.StopSequences) > 0 {
+		apiReq.Stop = req.StopSequences
+	}
+
+	if len(req.Tools) > 0 && !reasoner {
+		apiReq.Parallel = &req.ParallelToolCalls
+		apiReq.Tools = make([]tool, len(req.Tools))
+		for i, t := range req.Tools {
+			apiReq.Tools[i] = tool{
+				Type: "function",
+				Function: functionDef{
+					Name:        t.Name,
+					Description: t.Description,
+					Parameters:  convertToolParams(t.Parameters),
+				},
+			}
+		}
+	}
+
+	return apiReq
+}
+
+func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.CompletionResponse {
+	resp := &models.CompletionResponse{
+		Model: apiResp.Model,
+	}
+
+	if len(apiResp.Choices) > 0 {
+		choice := apiResp.Choices[0]
+		resp.Content = choice.Message.Content
+		resp.Reasoning = choice.Message.ReasoningContent
+		resp.StopReason = convertStopReason(choice.FinishReason)
+
+		if len(choice.Message.ToolCalls) > 0 {
+			resp.ToolCalls = make([]models.ToolCall, len(choice.Message.ToolCalls))
+			for i, tc := range choice.Message.ToolCalls {
+				resp.ToolCalls[i] = convertToolCall(tc)
+			}
+			resp.StopReason = "tool_use"
+		}
+	}
+
+	if apiResp.Usage != nil {
+		resp.Usage = convertUsage(apiResp.Model, apiResp.Usage)
+	}
+
+	return resp
+}
+
+// flushToolCalls emits accumulated streaming tool calls in index order.
+func flushToolCalls(tokens chan<- models.StreamToken, pending map[int]*toolCall) {
+	indexes := make([]int, 0, len(pending))
+	for i := range pending {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		call := convertToolCall(*pending[i])
+		tokens <- models.StreamToken{ToolCall: &call}
+		delete(pending, i)
+	}
+}
+
+func convertToolCall(tc toolCall) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return models.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Function.Name,
+		Arguments: args,
+	}
+}
+
+func convertStopReason(reason string) string {
+	switch reason {
+	case "stop":
+		return "end_turn"
+	case "length":
+		return "max_tokens"
+	case "tool_calls":
+		return "tool_use"
+	default:
+		return reason
+	}
+}
+
+func convertToolParams(params []models.Parameter) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for _, p := range params {
+		properties[p.Name] = map[string]interface{}{
+			"type":        p.Type,
+			"description": p.Description,
+		}
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// isReasoner reports whether the model is a reasoning (R1-style) model.
+func isReasoner(model string) bool {
+	return strings.Contains(model, "reasoner") || strings.Contains(model, "r1")
+}
+
+// convertUsage converts API usage, splitting out reasoning tokens and pricing
+// cached prompt tokens at the cache-hit rate.
+func convertUsage(model string, u *usage) models.Usage {
+	result := models.Usage{
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+		TotalTokens:  u.TotalTokens,
+	}
+	if u.CompletionTokensDetails != nil {
+		result.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+
+	cacheHit := u.PromptCacheHitTokens
+	cacheMiss := u.PromptTokens - cacheHit
+	if u.PromptCacheMissTokens > 0 {
+		cacheMiss = u.PromptCacheMissTokens
+	}
+
+	result.Cost, result.ReasoningCost = calculateCost(model, cacheHit, cacheMiss, u.CompletionTokens, result.ReasoningTokens)
+	return result
+}
+
+// calculateCost returns the total cost and the reasoning portion of it.
+// Reasoning tokens are billed at the output rate.
+func calculateCost(model string, cacheHitTokens, cacheMissTokens, completionTokens, reasoningTokens int) (cost, reasoningCost float64) {
+	price := pricing.Lookup("deepseek", model)
+	cost = float64(cacheHitTokens)*price.CacheReadRate() +
+		float64(cacheMissTokens)*price.InputRate() +
+		float64(completionTokens)*price.OutputRate()
+	reasoningCost = float64(reasoningTokens) * price.OutputRate()
+	return cost, reasoningCost
+}
+
+// API types
+
+type chatCompletionRequest struct {
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	TopP          *float64       `json:"top_p,omitempty"`
+	Stop          []string       `json:"stop,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+	Tools         []tool         `json:"tools,omitempty"`
+	Parallel      *bool          `json:"parallel_tool_calls,omitempty"` // Sent with Tools
+	User          string         `json:"user,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role             string     `json:"role"`
+	Content          string     `json:"content"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []toolCall `json:"tool_calls,omitempty"`
+}
+
+type tool struct {
+	Type     string      `json:"type"`
+	Function functionDef `json:"function"`
+}
+
+type functionDef struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type toolCall struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function toolCallFunc `json:"function"`
+}
+
+type toolCallFunc struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatCompletionResponse struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   *usage   `json:"usage"`
+}
+
+type choice struct {
+	Index        int         `json:"index"`
+	Message      chatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type usage struct {
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptCacheHitTokens    int                      `json:"prompt_cache_hit_tokens"`
+	PromptCacheMissTokens   int                      `json:"prompt_cache_miss_tokens"`
+	CompletionTokensDetails *completionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+type completionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+type chatCompletionChunk struct {
+	ID      string        `json:"id"`
+	Model   string        `json:"model"`
+	Choices []choiceDelta `json:"choices"`
+	Usage   *usage        `json:"usage"`
+}
+
+type choiceDelta struct {
+	Index        int          `json:"index"`
+	Delta        messageDelta `json:"delta"`
+	FinishReason string       `json:"finish_reason"`
+}
+
+type messageDelta struct {
+	Role             string     `json:"role,omitempty"`
+	Content          string     `json:"content,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []toolCall `json:"tool_calls,omitempty"`
+}
@@ -0,0 +1,155 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	p := New("test-key")
+	assert.Equal(t, "deepseek", p.Name())
+	assert.Equal(t, defaultBaseURL, p.baseURL)
+	assert.NotNil(t, p.client)
+
+	p = New("test-key", WithBaseURL("http://custom/"), WithUser("team"))
+	assert.Equal(t, "http://custom", p.baseURL)
+	assert.Equal(t, "team", p.user)
+}
+
+func TestProvider_CreateCompletion_Reasoner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var req chatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, modelReasoner, req.Model)
+		assert.Nil(t, req.Temperature, "reasoner ignores sampling parameters")
+		assert.Empty(t, req.Tools, "reasoner does not accept tools")
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": modelReasoner,
+			"choices": []map[string]interface{}{{
+				"message": map[string]interface{}{
+					"role":              "assistant",
+					"content":           "42",
+					"reasoning_content": "Let me think...",
+				},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]interface{}{
+				"prompt_tokens":            1000,
+				"completion_tokens":        500,
+				"total_tokens":             1500,
+				"prompt_cache_hit_tokens":  400,
+				"prompt_cache_miss_tokens": 600,
+				"completion_tokens_details": map[string]interface{}{
+					"reasoning_tokens": 300,
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	temp := 0.7
+	p := New("test-key", WithBaseURL(server.URL))
+	resp, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{
+		Model:       modelReasoner,
+		Messages:    []models.Message{{Role: "user", Content: "What is the answer?"}},
+		Temperature: &temp,
+		Tools:       []models.Tool{{Name: "read"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "42", resp.Content)
+	assert.Equal(t, "Let me think...", resp.Reasoning)
+	assert.Equal(t, "end_turn", resp.StopReason)
+	assert.Equal(t, 300, resp.Usage.ReasoningTokens)
+
+	wantCost := 400*0.14/1e6 + 600*0.55/1e6 + 500*2.19/1e6
+	assert.InDelta(t, wantCost, resp.Usage.Cost, 1e-12)
+	assert.InDelta(t, 300*2.19/1e6, resp.Usage.ReasoningCost, 1e-12)
+}
+
+func TestProvider_StreamCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotNil(t, req.StreamOptions)
+		assert.True(t, req.StreamOptions.IncludeUsage)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"reasoning_content":"Thinking"}}]}`,
+			`{"choices":[{"delta":{"content":"Hello"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read","arguments":"{\"path\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a.go\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":20,"total_tokens":30,"completion_tokens_details":{"reasoning_tokens":5}}}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    modelChat,
+		Messages: []models.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var reasoning, content string
+	var calls []*models.ToolCall
+	var final *models.StreamToken
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		reasoning += tok.Reasoning
+		content += tok.Content
+		if tok.ToolCall != nil {
+			calls = append(calls, tok.ToolCall)
+		}
+		if tok.Done {
+			tok := tok
+			final = &tok
+		}
+	}
+
+	assert.Equal(t, "Thinking", reasoning)
+	assert.Equal(t, "Hello", content)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "read", calls[0].Name)
+	assert.Equal(t, "a.go", calls[0].Arguments["path"])
+
+	require.NotNil(t, final)
+	require.NotNil(t, final.Usage)
+	assert.Equal(t, 5, final.Usage.ReasoningTokens)
+	assert.Greater(t, final.Usage.ReasoningCost, 0.0)
+}
+
+func TestProvider_ErrorHandling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"rate limited"}`))
+	}))
+	defer server.Close()
+
+	// A plain client, so the shared rate limiter does not wait out the 429
+	p := New("test-key", WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	_, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{Model: modelChat})
+	require.Error(t, err)
+
+	var provErr *models.ProviderError
+	require.ErrorAs(t, err, &provErr)
+	assert.True(t, provErr.IsRetryable())
+	assert.Equal(t, "HTTP_429", provErr.Code)
+}
@@ -2,7 +2,6 @@
 package gemini
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -13,6 +12,8 @@ import (
 	"time"
 
 	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
 )
 
 const (
@@ -31,9 +32,7 @@ func New(apiKey string, opts ...Option) *Provider {
 	p := &Provider{
 		apiKey:  apiKey,
 		baseURL: defaultBaseURL,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		client:  transport.NewClient(60 * time.Second),
 	}
 
 	for _, opt := range opts {
@@ -144,12 +143,7 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, &models.ProviderError{
-			Provider:  "gemini",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("gemini", resp, body)
 	}
 
 	var apiResp generateContentResponse
@@ -160,6 +154,44 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 	return p.convertResponse(&apiResp, req.Model), nil
 }
 
+// CountPromptTokens counts the input tokens of req with the countTokens
+// endpoint, which is free and reflects the model's own tokenizer.
+func (p *Provider) CountPromptTokens(ctx context.Context, req *models.CompletionRequest) (int, error) {
+	apiReq := p.convertRequest(req, false)
+	apiReq.GenerationConfig = nil
+	body, err := json.Marshal(countTokensRequest{
+		GenerateContentRequest: countedRequest{Model: "models/" + req.Model, generateContentRequest: apiReq},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:countTokens?key=%s", p.baseURL, req.Model, p.apiKey)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, models.NewHTTPError("gemini", resp, body)
+	}
+
+	var apiResp countTokensResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return apiResp.TotalTokens, nil
+}
+
 // StreamCompletion creates a streaming completion.
 func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
 	apiReq := p.convertRequest(req, true)
@@ -185,12 +217,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, &models.ProviderError{
-			Provider:  "gemini",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("gemini", resp, body)
 	}
 
 	tokens := make(chan models.StreamToken, 10)
@@ -199,8 +226,9 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 		defer close(tokens)
 		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := transport.NewLineReader(resp.Body)
 		var totalUsage *models.Usage
+		sawToolCall := false
 
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -232,6 +260,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 
 						// Handle function calls
 						if part.FunctionCall != nil {
+							sawToolCall = true
 							tokens <- models.StreamToken{
 								ToolCall: &models.ToolCall{
 									ID:        fmt.Sprintf("call_%d", time.Now().UnixNano()),
@@ -255,11 +284,14 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 						}
 					}
 
-					if totalUsage != nil {
-						tokens <- models.StreamToken{
-							Done:  true,
-							Usage: totalUsage,
-						}
+					stopReason := models.NormalizeStopReason(candidate.FinishReason)
+					if sawToolCall {
+						stopReason = "tool_use"
+					}
+					tokens <- models.StreamToken{
+						Done:       true,
+						StopReason: stopReason,
+						Usage:      totalUsage,
 					}
 					return
 				}
@@ -338,7 +370,7 @@ func (p *Provider) setHeaders(req *http.Request) {
 
 func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *generateContentRequest {
 	apiReq := &generateContentRequest{
-		Contents: make([]content, 0),
+		Contents: convertMessages(req.Messages),
 	}
 
 	// Add system instruction if present
@@ -348,19 +380,6 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *g
 		}
 	}
 
-	// Add conversation messages
-	for _, msg := range req.Messages {
-		role := "user"
-		if msg.Role == "assistant" {
-			role = "model"
-		}
-
-		apiReq.Contents = append(apiReq.Contents, content{
-			Role:  role,
-			Parts: []part{{Text: msg.Content}},
-		})
-	}
-
 	// Generation config
 	apiReq.GenerationConfig = &generationConfig{}
 
@@ -401,6 +420,74 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *g
 	return apiReq
 }
 
+// convertMessages converts the conversation into Gemini contents. Tool calls
+// become functionCall parts of the model's turn, and the results that follow
+// them are sent back together as functionResponse parts of one user turn.
+func convertMessages(messages []models.Message) []content {
+	contents := make([]content, 0, len(messages))
+	callNames := make(map[string]string) // Tool call ID to function name
+
+	for _, msg := range messages {
+		if msg.Role == "tool" {
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			resp := part{FunctionResponse: &functionResponse{Name: name, Response: toolResponse(msg.Content)}}
+
+			// Results of parallel calls share one turn
+			if n := len(contents); n > 0 && contents[n-1].Role == "user" && contents[n-1].Parts[0].FunctionResponse != nil {
+				contents[n-1].Parts = append(contents[n-1].Parts, resp)
+				continue
+			}
+			contents = append(contents, content{Role: "user", Parts: []part{resp}})
+			continue
+		}
+
+		// Gemini rejects empty text parts, so messages with nothing to
+		// send are left out
+		if msg.Content == "" && len(msg.Attachments) == 0 && len(msg.ToolCalls) == 0 {
+			continue
+		}
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "model"
+		}
+
+		parts := make([]part, 0, len(msg.Attachments)+len(msg.ToolCalls)+1)
+		for _, a := range msg.Attachments {
+			parts = append(parts, part{InlineData: &inlineData{MimeType: a.MimeType, Data: a.Base64()}})
+		}
+		if msg.Content != "" {
+			parts = append(parts, part{Text: msg.Content})
+		}
+		for _, call := range msg.ToolCalls {
+			callNames[call.ID] = call.Name
+			args := call.Arguments
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			parts = append(parts, part{FunctionCall: &functionCall{Name: call.Name, Args: args}})
+		}
+
+		contents = append(contents, content{
+			Role:  role,
+			Parts: parts,
+		})
+	}
+	return contents
+}
+
+// toolResponse wraps a tool result for a functionResponse part, which must be
+// a JSON object. Results that already are one are sent as they are.
+func toolResponse(result string) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]interface{}{"content": result}
+}
+
 func (p *Provider) convertResponse(apiResp *generateContentResponse, model string) *models.CompletionResponse {
 	resp := &models.CompletionResponse{
 		Model: model,
@@ -430,18 +517,15 @@ func (p *Provider) convertResponse(apiResp *generateContentResponse, model strin
 		resp.Content = strings.Join(contentParts, "")
 		resp.ToolCalls = toolCalls
 
-		// Map finish reason
-		switch candidate.FinishReason {
-		case "STOP":
-			resp.StopReason = "end_turn"
-		case "MAX_TOKENS":
+		// Map finish reason. Gemini finishes with STOP after function calls,
+		// which still need their results sent back.
+		switch {
+		case len(toolCalls) > 0:
+			resp.StopReason = "tool_use"
+		case candidate.FinishReason == "MAX_TOKENS":
 			resp.StopReason = "max_tokens"
 		default:
-			if len(toolCalls) > 0 {
-				resp.StopReason = "tool_use"
-			} else {
-				resp.StopReason = "end_turn"
-			}
+			resp.StopReason = "end_turn"
 		}
 	}
 
@@ -479,28 +563,9 @@ func convertToolParams(params []models.Parameter) map[string]interface{} {
 	}
 }
 
-func calculateCost(model string, promptTokens, completionTokens int) float64 {
-	var inputCost, outputCost float64
-
-	switch {
-	case strings.Contains(model, "gemini-2.0-flash-exp"):
-		inputCost = 0.00 // Free during preview
-		outputCost = 0.00
-	case strings.Contains(model, "gemini-1.5-pro"):
-		inputCost = 1.25 / 1000000
-		outputCost = 5.00 / 1000000
-	case strings.Contains(model, "gemini-1.5-flash-8b"):
-		inputCost = 0.0375 / 1000000
-		outputCost = 0.15 / 1000000
-	case strings.Contains(model, "gemini-1.5-flash"):
-		inputCost = 0.075 / 1000000
-		outputCost = 0.30 / 1000000
-	default:
-		inputCost = 0.075 / 1000000
-		outputCost = 0.30 / 1000000
-	}
-
-	return (float64(promptTokens) * inputCost) + (float64(completionTokens) * outputCost)
+// calculateCost prices a call from the gemini pricing table.
+func calculateCost(model string, inputTokens, outputTokens int) float64 {
+	return pricing.Lookup("gemini", model).Cost(inputTokens, outputTokens)
 }
 
 // API types
@@ -512,14 +577,36 @@ type generateContentRequest struct {
 	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
 }
 
+type countTokensRequest struct {
+	GenerateContentRequest countedRequest `json:"generateContentRequest"`
+}
+
+// countedRequest is a generateContentRequest naming its model, as
+// countTokens wants it.
+type countedRequest struct {
+	Model string `json:"model"`
+	*generateContentRequest
+}
+
+type countTokensResponse struct {
+	TotalTokens int `json:"totalTokens"`
+}
+
 type content struct {
 	Role  string `json:"role,omitempty"`
 	Parts []part `json:"parts"`
 }
 
 type part struct {
-	Text         string        `json:"text,omitempty"`
-	FunctionCall *functionCall `json:"functionCall,omitempty"`
+	Text             string            `json:"text,omitempty"`
+	InlineData       *inlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+}
+
+type inlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // Base64
 }
 
 type functionCall struct {
@@ -527,6 +614,11 @@ type functionCall struct {
 	Args map[string]interface{} `json:"args"`
 }
 
+type functionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
 type tool struct {
 	FunctionDeclarations []functionDeclaration `json:"functionDeclarations"`
 }
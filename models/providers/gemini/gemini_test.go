@@ -0,0 +1,148 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMessages_FunctionResponses(t *testing.T) {
+	contents := convertMessages([]models.Message{
+		{Role: "user", Content: "What's the weather in Paris and Rome?"},
+		{Role: "assistant", ToolCalls: []models.ToolCall{
+			{ID: "call_1", Name: "weather", Arguments: map[string]interface{}{"city": "Paris"}},
+			{ID: "call_2", Name: "weather", Arguments: map[string]interface{}{"city": "Rome"}},
+		}},
+		{Role: "tool", ToolCallID: "call_1", Name: "weather", Content: `{"temp":18}`},
+		{Role: "tool", ToolCallID: "call_2", Content: "sunny, 24C"},
+	})
+
+	require.Len(t, contents, 3)
+
+	model := contents[1]
+	assert.Equal(t, "model", model.Role)
+	require.Len(t, model.Parts, 2)
+	assert.Equal(t, "weather", model.Parts[0].FunctionCall.Name)
+	assert.Equal(t, "Rome", model.Parts[1].FunctionCall.Args["city"])
+
+	// Both results go back in one user turn, in call order
+	results := contents[2]
+	assert.Equal(t, "user", results.Role)
+	require.Len(t, results.Parts, 2)
+	assert.Equal(t, "weather", results.Parts[0].FunctionResponse.Name)
+	assert.Equal(t, float64(18), results.Parts[0].FunctionResponse.Response["temp"])
+	// The name is recovered from the call when the result lacks it
+	assert.Equal(t, "weather", results.Parts[1].FunctionResponse.Name)
+	assert.Equal(t, "sunny, 24C", results.Parts[1].FunctionResponse.Response["content"])
+}
+
+func TestProvider_ToolLoop(t *testing.T) {
+	var turns []generateContentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req generateContentRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		turns = append(turns, req)
+
+		if len(turns) == 1 {
+			// Gemini reports STOP even when it wants a function called
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"read","args":{"file_path":"/tmp/a.txt"}}}]},"finishReason":"STOP"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"The file says hello."}]},"finishReason":"STOP"}]}`)
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	req := &models.CompletionRequest{
+		Model:    "gemini-1.5-flash",
+		Messages: []models.Message{{Role: "user", Content: "Read /tmp/a.txt"}},
+		Tools:    []models.Tool{{Name: "read", Description: "Reads a file"}},
+	}
+
+	resp, err := p.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "tool_use", resp.StopReason)
+	require.Len(t, resp.ToolCalls, 1)
+
+	call := resp.ToolCalls[0]
+	req.Messages = append(req.Messages,
+		models.Message{Role: "assistant", ToolCalls: resp.ToolCalls},
+		models.Message{Role: "tool", ToolCallID: call.ID, Name: call.Name, Content: "hello"},
+	)
+
+	resp, err = p.CreateCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "end_turn", resp.StopReason)
+	assert.Equal(t, "The file says hello.", resp.Content)
+
+	require.Len(t, turns, 2)
+	second := turns[1].Contents
+	require.Len(t, second, 3)
+	assert.Equal(t, "read", second[1].Parts[0].FunctionCall.Name)
+	assert.Equal(t, "/tmp/a.txt", second[1].Parts[0].FunctionCall.Args["file_path"])
+	assert.Equal(t, "read", second[2].Parts[0].FunctionResponse.Name)
+	assert.Equal(t, "hello", second[2].Parts[0].FunctionResponse.Response["content"])
+}
+
+func TestProvider_StreamCompletion_ToolUse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sse", r.URL.Query().Get("alt"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"glob","args":{"pattern":"*.go"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}`+"\n\n")
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	tokens, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "gemini-1.5-flash",
+		Messages: []models.Message{{Role: "user", Content: "List Go files"}},
+	})
+	require.NoError(t, err)
+
+	var calls []*models.ToolCall
+	var last models.StreamToken
+	for token := range tokens {
+		require.NoError(t, token.Error)
+		if token.ToolCall != nil {
+			calls = append(calls, token.ToolCall)
+		}
+		last = token
+	}
+	require.Len(t, calls, 1)
+	assert.Equal(t, "glob", calls[0].Name)
+	assert.True(t, last.Done)
+	assert.Equal(t, "tool_use", last.StopReason)
+}
+
+func TestProvider_CountPromptTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-1.5-flash:countTokens", r.URL.Path)
+
+		var req struct {
+			GenerateContentRequest map[string]interface{} `json:"generateContentRequest"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "models/gemini-1.5-flash", req.GenerateContentRequest["model"])
+		assert.Contains(t, req.GenerateContentRequest, "contents")
+		assert.Contains(t, req.GenerateContentRequest, "systemInstruction")
+		fmt.Fprint(w, `{"totalTokens": 321}`)
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	var _ models.TokenCounter = p
+	n, err := p.CountPromptTokens(context.Background(), &models.CompletionRequest{
+		Model:    "gemini-1.5-flash",
+		System:   "Be brief",
+		Messages: []models.Message{{Role: "user", Content: "Hello"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 321, n)
+}
@@ -3,17 +3,18 @@
 package lmstudio
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
 )
 
 const (
@@ -22,17 +23,16 @@ const (
 
 // Provider implements the LM Studio API provider.
 type Provider struct {
-	baseURL string
-	client  *http.Client
+	baseURL   string
+	client    *http.Client
+	keepAlive time.Duration // Idle time before LM Studio unloads a model it loaded on demand; 0 uses the server default
 }
 
 // New creates a new LM Studio provider.
 func New(opts ...Option) *Provider {
 	p := &Provider{
 		baseURL: defaultBaseURL,
-		client: &http.Client{
-			Timeout: 300 * time.Second, // Long timeout for local models
-		},
+		client:  transport.NewClient(300 * time.Second), // Long timeout for local models
 	}
 
 	for _, opt := range opts {
@@ -59,13 +59,40 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithKeepAlive sets how long LM Studio keeps a model it loaded on demand
+// after the last request.
+func WithKeepAlive(d time.Duration) Option {
+	return func(p *Provider) {
+		p.keepAlive = d
+	}
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return "lmstudio"
 }
 
-// ListModels returns available models from LM Studio.
+// ListModels returns available models from LM Studio, with the context
+// windows the native API reports. Servers without it are listed through the
+// OpenAI-compatible API, with context windows guessed from model names.
 func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
+	native, err := p.nativeModels(ctx)
+	if err == nil {
+		result := make([]models.Model, 0, len(native))
+		for _, m := range native {
+			if m.Type == "embeddings" {
+				continue
+			}
+			registerCapabilities(m)
+			model := newModel(m.ID, m.contextWindow())
+			if m.Type == "vlm" {
+				model.Capabilities = append(model.Capabilities, "vision")
+			}
+			result = append(result, model)
+		}
+		return result, nil
+	}
+
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -91,23 +118,28 @@ func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
 
 	result := make([]models.Model, 0, len(apiResp.Data))
 	for _, m := range apiResp.Data {
-		result = append(result, models.Model{
-			ID:            m.ID,
-			Name:          m.ID, // LM Studio uses ID as name
-			Provider:      "lmstudio",
-			ContextWindow: determineContextWindow(m.ID),
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  0, // Local models are free
-				OutputTokens: 0,
-			},
-			Capabilities: []string{"streaming"},
-		})
+		result = append(result, newModel(m.ID, determineContextWindow(m.ID)))
 	}
 
 	return result, nil
 }
 
+// newModel describes a model LM Studio serves.
+func newModel(id string, contextWindow int) models.Model {
+	return models.Model{
+		ID:            id,
+		Name:          id, // LM Studio uses ID as name
+		Provider:      "lmstudio",
+		ContextWindow: contextWindow,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  0, // Local models are free
+			OutputTokens: 0,
+		},
+		Capabilities: []string{"streaming"},
+	}
+}
+
 // CreateCompletion creates a non-streaming completion.
 func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
 	apiReq := p.convertRequest(req, false)
@@ -132,12 +164,7 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, &models.ProviderError{
-			Provider:  "lmstudio",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500,
-		}
+		return nil, models.NewHTTPError("lmstudio", resp, body)
 	}
 
 	var apiResp chatCompletionResponse
@@ -172,12 +199,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, &models.ProviderError{
-			Provider:  "lmstudio",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500,
-		}
+		return nil, models.NewHTTPError("lmstudio", resp, body)
 	}
 
 	tokens := make(chan models.StreamToken, 10)
@@ -186,8 +208,10 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 		defer close(tokens)
 		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := transport.NewLineReader(resp.Body)
 		var totalUsage *models.Usage
+		var stopReason string
+		pending := make(map[int]*toolCall) // Tool calls accumulate across chunks by index
 		var totalTokens int
 
 		for scanner.Scan() {
@@ -199,6 +223,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 
 			data := strings.TrimPrefix(line, "data: ")
 			if data == "[DONE]" {
+				flushToolCalls(tokens, pending)
 				if totalUsage == nil {
 					// Create usage if not provided
 					totalUsage = &models.Usage{
@@ -208,8 +233,9 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 					}
 				}
 				tokens <- models.StreamToken{
-					Done:  true,
-					Usage: totalUsage,
+					Done:       true,
+					StopReason: stopReason,
+					Usage:      totalUsage,
 				}
 				return
 			}
@@ -222,29 +248,30 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 
 			if len(chunk.Choices) > 0 {
 				delta := chunk.Choices[0].Delta
+				if chunk.Choices[0].FinishReason != "" {
+					stopReason = models.NormalizeStopReason(chunk.Choices[0].FinishReason)
+				}
 				if delta.Content != "" {
 					tokens <- models.StreamToken{
 						Content: delta.Content,
 					}
-					// Rough token estimation (1 token ≈ 4 characters)
-					totalTokens += len(delta.Content) / 4
+					totalTokens += models.CountTokens(req.Model, delta.Content)
 				}
 
-				// Handle tool calls if present (limited support)
-				if len(delta.ToolCalls) > 0 {
-					for _, tc := range delta.ToolCalls {
-						if tc.Function.Name != "" {
-							var args map[string]interface{}
-							json.Unmarshal([]byte(tc.Function.Arguments), &args)
-							tokens <- models.StreamToken{
-								ToolCall: &models.ToolCall{
-									ID:        tc.ID,
-									Name:      tc.Function.Name,
-									Arguments: args,
-								},
-							}
-						}
+				// Tool call arguments stream as JSON fragments, accumulated by index
+				for _, tc := range delta.ToolCalls {
+					call, ok := pending[tc.Index]
+					if !ok {
+						call = &toolCall{ID: tc.ID, Type: tc.Type}
+						pending[tc.Index] = call
+					}
+					if tc.Function.Name != "" {
+						call.Function.Name = tc.Function.Name
 					}
+					call.Function.Arguments += tc.Function.Arguments
+				}
+				if chunk.Choices[0].FinishReason != "" {
+					flushToolCalls(tokens, pending)
 				}
 			}
 
@@ -308,6 +335,23 @@ func (p *Provider) GetModelInfo(ctx context.Context, modelID string) (*models.Mo
 	return nil, fmt.Errorf("model %s not found in LM Studio", modelID)
 }
 
+// Preload loads a model so the next request doesn't wait for the load. A
+// repeated call resets the model's idle timer.
+func (p *Provider) Preload(ctx context.Context, modelID string) error {
+	return p.PreloadProgress(ctx, modelID, nil)
+}
+
+// ping requests a single token from a model. LM Studio loads models on
+// demand, so this loads one that is not loaded.
+func (p *Provider) ping(ctx context.Context, modelID string) error {
+	_, err := p.CreateCompletion(ctx, &models.CompletionRequest{
+		Model:     modelID,
+		Messages:  []models.Message{{Role: "user", Content: "hi"}},
+		MaxTokens: 1,
+	})
+	return err
+}
+
 // SupportsStreaming returns true.
 func (p *Provider) SupportsStreaming() bool {
 	return true
@@ -329,6 +373,7 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 		Model:    req.Model,
 		Stream:   stream,
 		Messages: make([]chatMessage, 0, len(req.Messages)+1),
+		TTL:      int(p.keepAlive / time.Second),
 	}
 
 	// Add system message if present
@@ -392,13 +437,7 @@ func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.Comp
 		if len(choice.Message.ToolCalls) > 0 {
 			resp.ToolCalls = make([]models.ToolCall, len(choice.Message.ToolCalls))
 			for i, tc := range choice.Message.ToolCalls {
-				var args map[string]interface{}
-				json.Unmarshal([]byte(tc.Function.Arguments), &args)
-				resp.ToolCalls[i] = models.ToolCall{
-					ID:        tc.ID,
-					Name:      tc.Function.Name,
-					Arguments: args,
-				}
+				resp.ToolCalls[i] = convertToolCall(tc)
 			}
 			resp.StopReason = "tool_use"
 		}
@@ -417,7 +456,33 @@ func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.Comp
 	return resp
 }
 
-// determineContextWindow estimates context window based on model name.
+// flushToolCalls emits accumulated streaming tool calls in index order.
+func flushToolCalls(tokens chan<- models.StreamToken, pending map[int]*toolCall) {
+	indexes := make([]int, 0, len(pending))
+	for i := range pending {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		call := convertToolCall(*pending[i])
+		tokens <- models.StreamToken{ToolCall: &call}
+		delete(pending, i)
+	}
+}
+
+func convertToolCall(tc toolCall) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return models.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Function.Name,
+		Arguments: args,
+	}
+}
+
+// determineContextWindow estimates context window based on model name, for
+// servers without the native API.
 func determineContextWindow(modelID string) int {
 	modelLower := strings.ToLower(modelID)
 
@@ -461,6 +526,7 @@ type chatCompletionRequest struct {
 	TopP        *float64      `json:"top_p,omitempty"`
 	Stop        []string      `json:"stop,omitempty"`
 	Stream      bool          `json:"stream,omitempty"`
+	TTL         int           `json:"ttl,omitempty"` // Seconds before an on-demand model is unloaded
 }
 
 type chatMessage struct {
@@ -470,6 +536,7 @@ type chatMessage struct {
 }
 
 type toolCall struct {
+	Index    int          `json:"index"`
 	ID       string       `json:"id"`
 	Type     string       `json:"type"`
 	Function toolCallFunc `json:"function"`
@@ -0,0 +1,250 @@
+package lmstudio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// errNoNativeAPI reports a server without LM Studio's native REST API,
+// such as an older LM Studio or another OpenAI-compatible server. The
+// OpenAI-compatible endpoints are used instead.
+var errNoNativeAPI = errors.New("LM Studio native API not available")
+
+// stateLoaded is the native API's state of a model in memory.
+const stateLoaded = "loaded"
+
+// nativeModel is a model as the native API describes it.
+type nativeModel struct {
+	ID                  string `json:"id"`
+	Type                string `json:"type"` // "llm", "vlm" or "embeddings"
+	Publisher           string `json:"publisher"`
+	Arch                string `json:"arch"`
+	Quantization        string `json:"quantization"`
+	State               string `json:"state"` // stateLoaded or "not-loaded"
+	MaxContextLength    int    `json:"max_context_length"`
+	LoadedContextLength int    `json:"loaded_context_length,omitempty"` // Context the model was loaded with
+}
+
+// contextWindow returns the context the model runs with: the one it was
+// loaded with, or the most it supports.
+func (m nativeModel) contextWindow() int {
+	if m.LoadedContextLength > 0 {
+		return m.LoadedContextLength
+	}
+	return m.MaxContextLength
+}
+
+// nativeURL returns the base URL of the native REST API, served next to
+// the OpenAI-compatible one.
+func (p *Provider) nativeURL() string {
+	return strings.TrimSuffix(p.baseURL, "/v1") + "/api/v0"
+}
+
+// nativeModels lists the downloaded models with their state and context
+// length.
+func (p *Provider) nativeModels(ctx context.Context) ([]nativeModel, error) {
+	var list struct {
+		Data []nativeModel `json:"data"`
+	}
+	if err := p.nativeGet(ctx, "/models", &list); err != nil {
+		return nil, err
+	}
+	return list.Data, nil
+}
+
+// nativeModel describes one downloaded model.
+func (p *Provider) nativeModel(ctx context.Context, modelID string) (*nativeModel, error) {
+	var m nativeModel
+	if err := p.nativeGet(ctx, "/models/"+url.PathEscape(modelID), &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// nativeGet decodes the response to a GET of path on the native API.
+func (p *Provider) nativeGet(ctx context.Context, path string, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.nativeURL()+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Older servers answer 404 for the whole API, LM Studio for an unknown
+	// model; either way the OpenAI-compatible API is the one to use
+	if resp.StatusCode == http.StatusNotFound {
+		return errNoNativeAPI
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return models.NewHTTPError("lmstudio", resp, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// registerCapabilities records what the server reports about m, so its real
+// context window is used instead of a guess from its name.
+func registerCapabilities(m nativeModel) {
+	if m.contextWindow() <= 0 {
+		return
+	}
+	caps, _ := models.LookupCapabilities(m.ID)
+	caps.ContextWindow = m.contextWindow()
+	caps.Vision = caps.Vision || m.Type == "vlm"
+	models.RegisterCapabilities(models.FormatModelName("lmstudio", m.ID), caps)
+}
+
+// ContextLength returns the context window of a downloaded model as LM
+// Studio reports it: the length it is loaded with, or the most it supports.
+func (p *Provider) ContextLength(ctx context.Context, modelID string) (int, error) {
+	m, err := p.nativeModel(ctx, modelID)
+	if err != nil {
+		return 0, err
+	}
+	registerCapabilities(*m)
+	return m.contextWindow(), nil
+}
+
+// PreloadProgress loads a model through the native API, reporting load
+// progress, with the configured TTL. A model that is already loaded is
+// pinged instead, which resets its idle timer. Without the native API the
+// model is loaded just in time by requesting a single token from it.
+func (p *Provider) PreloadProgress(ctx context.Context, modelID string, progress func(fraction float64)) error {
+	m, err := p.nativeModel(ctx, modelID)
+	switch {
+	case errors.Is(err, errNoNativeAPI):
+		return p.ping(ctx, modelID)
+	case err != nil:
+		return err
+	case m.State == stateLoaded:
+		return p.ping(ctx, modelID)
+	}
+
+	err = p.load(ctx, modelID, progress)
+	if errors.Is(err, errNoNativeAPI) {
+		return p.ping(ctx, modelID)
+	}
+	if err != nil {
+		return err
+	}
+	if loaded, err := p.nativeModel(ctx, modelID); err == nil {
+		registerCapabilities(*loaded)
+	}
+	return nil
+}
+
+// loadEvent is one line of the native load response.
+type loadEvent struct {
+	Status   string  `json:"status"` // "loading", "loaded" or "error"
+	Progress float64 `json:"progress"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// load asks LM Studio to load a model and follows its progress until the
+// model is loaded.
+func (p *Provider) load(ctx context.Context, modelID string, progress func(float64)) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": modelID,
+		"ttl":   int(p.keepAlive / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.nativeURL()+"/models/load", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return errNoNativeAPI
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return models.NewHTTPError("lmstudio", resp, body)
+	}
+
+	// Progress arrives as one JSON event per line, with or without an SSE
+	// "data: " prefix; a server that does not stream sends just the last one
+	scanner := transport.NewLineReader(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "data: "))
+		if line == "" {
+			continue
+		}
+		var e loadEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return fmt.Errorf("failed to decode load progress: %w", err)
+		}
+		switch e.Status {
+		case "error":
+			return fmt.Errorf("LM Studio failed to load %s: %s", modelID, e.Error)
+		case "loaded":
+			if progress != nil {
+				progress(1)
+			}
+			return nil
+		}
+		if progress != nil && e.Progress > 0 {
+			progress(e.Progress)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read load progress: %w", err)
+	}
+	return nil
+}
+
+// Unload frees the memory a loaded model holds.
+func (p *Provider) Unload(ctx context.Context, modelID string) error {
+	body, err := json.Marshal(map[string]string{"model": modelID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.nativeURL()+"/models/unload", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return errNoNativeAPI
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return models.NewHTTPError("lmstudio", resp, body)
+	}
+	return nil
+}
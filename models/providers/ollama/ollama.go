@@ -2,7 +2,6 @@
 package ollama
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -13,6 +12,7 @@ import (
 	"time"
 
 	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
 )
 
 const (
@@ -21,17 +21,16 @@ const (
 
 // Provider implements the Ollama provider for local models.
 type Provider struct {
-	baseURL string
-	client  *http.Client
+	baseURL   string
+	client    *http.Client
+	keepAlive time.Duration // How long Ollama keeps the model loaded; 0 uses the server default
 }
 
 // New creates a new Ollama provider.
 func New(opts ...Option) *Provider {
 	p := &Provider{
 		baseURL: defaultBaseURL,
-		client: &http.Client{
-			Timeout: 5 * time.Minute, // Longer timeout for local model loading
-		},
+		client:  transport.NewClient(5 * time.Minute), // Longer timeout for local model loading
 	}
 
 	for _, opt := range opts {
@@ -58,6 +57,13 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithKeepAlive sets how long Ollama keeps a model loaded after a request.
+func WithKeepAlive(d time.Duration) Option {
+	return func(p *Provider) {
+		p.keepAlive = d
+	}
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return "ollama"
@@ -78,12 +84,7 @@ func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, &models.ProviderError{
-			Provider:  "ollama",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: false,
-		}
+		return nil, models.NewHTTPError("ollama", resp, body)
 	}
 
 	var tagsResp tagsResponse
@@ -134,12 +135,7 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, &models.ProviderError{
-			Provider:  "ollama",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: false,
-		}
+		return nil, models.NewHTTPError("ollama", resp, body)
 	}
 
 	var apiResp chatResponse
@@ -174,12 +170,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, &models.ProviderError{
-			Provider:  "ollama",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: false,
-		}
+		return nil, models.NewHTTPError("ollama", resp, body)
 	}
 
 	tokens := make(chan models.StreamToken, 10)
@@ -188,7 +179,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 		defer close(tokens)
 		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := transport.NewLineReader(resp.Body)
 		var totalTokens int
 
 		for scanner.Scan() {
@@ -207,7 +198,8 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 			if event.Done {
 				totalTokens = event.PromptEvalCount + event.EvalCount
 				tokens <- models.StreamToken{
-					Done: true,
+					Done:       true,
+					StopReason: models.NormalizeStopReason(event.DoneReason),
 					Usage: &models.Usage{
 						InputTokens:  event.PromptEvalCount,
 						OutputTokens: event.EvalCount,
@@ -289,6 +281,51 @@ func (p *Provider) GetModelInfo(ctx context.Context, modelID string) (*models.Mo
 	}, nil
 }
 
+// Preload loads a model into memory without generating anything, so the
+// next request doesn't wait for the load. Calling it again while the model is
+// loaded resets its keep-alive timer.
+func (p *Provider) Preload(ctx context.Context, modelID string) error {
+	return p.load(ctx, modelID, p.keepAliveParam())
+}
+
+// Unload frees the memory of a loaded model by asking Ollama to keep it
+// loaded for no time at all.
+func (p *Provider) Unload(ctx context.Context, modelID string) error {
+	return p.load(ctx, modelID, "0")
+}
+
+// load sends a request without a prompt, which only sets how long the model
+// stays loaded.
+func (p *Provider) load(ctx context.Context, modelID, keepAlive string) error {
+	body, err := json.Marshal(&generateRequest{
+		Model:     modelID,
+		KeepAlive: keepAlive,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("ollama not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return models.NewHTTPError("ollama", resp, body)
+	}
+
+	return nil
+}
+
 // SupportsStreaming returns true.
 func (p *Provider) SupportsStreaming() bool {
 	return true
@@ -301,12 +338,22 @@ func (p *Provider) SupportsTools() bool {
 
 // Helper methods
 
+// keepAliveParam returns the keep_alive request value, or "" for the server
+// default.
+func (p *Provider) keepAliveParam() string {
+	if p.keepAlive <= 0 {
+		return ""
+	}
+	return p.keepAlive.String()
+}
+
 func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *chatRequest {
 	apiReq := &chatRequest{
-		Model:    req.Model,
-		Messages: make([]message, len(req.Messages)),
-		Stream:   stream,
-		Options:  &options{},
+		Model:     req.Model,
+		Messages:  make([]message, len(req.Messages)),
+		Stream:    stream,
+		Options:   &options{},
+		KeepAlive: p.keepAliveParam(),
 	}
 
 	// Add system message if present
@@ -325,7 +372,7 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 	}
 
 	if req.Temperature != nil {
-		apiReq.Options.Temperature = *req.Temperature
+		apiReq.Options.Temperature = req.Temperature
 	}
 
 	if req.TopP != nil {
@@ -344,10 +391,15 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 }
 
 func (p *Provider) convertResponse(apiResp *chatResponse) *models.CompletionResponse {
+	stopReason := "end_turn"
+	if apiResp.DoneReason == "length" {
+		stopReason = "max_tokens"
+	}
+
 	return &models.CompletionResponse{
 		Content:    apiResp.Message.Content,
 		Model:      apiResp.Model,
-		StopReason: "end_turn",
+		StopReason: stopReason,
 		Usage: models.Usage{
 			InputTokens:  apiResp.PromptEvalCount,
 			OutputTokens: apiResp.EvalCount,
@@ -368,10 +420,17 @@ type modelInfo struct {
 }
 
 type chatRequest struct {
-	Model    string    `json:"model"`
-	Messages []message `json:"messages"`
-	Stream   bool      `json:"stream"`
-	Options  *options  `json:"options,omitempty"`
+	Model     string    `json:"model"`
+	Messages  []message `json:"messages"`
+	Stream    bool      `json:"stream"`
+	Options   *options  `json:"options,omitempty"`
+	KeepAlive string    `json:"keep_alive,omitempty"`
+}
+
+// generateRequest without a prompt only loads the model.
+type generateRequest struct {
+	Model     string `json:"model"`
+	KeepAlive string `json:"keep_alive,omitempty"`
 }
 
 type message struct {
@@ -380,7 +439,7 @@ type message struct {
 }
 
 type options struct {
-	Temperature float64  `json:"temperature,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
 	TopP        float64  `json:"top_p,omitempty"`
 	TopK        int      `json:"top_k,omitempty"`
 	Stop        []string `json:"stop,omitempty"`
@@ -390,6 +449,7 @@ type chatResponse struct {
 	Model           string  `json:"model"`
 	Message         message `json:"message"`
 	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason,omitempty"` // "stop" or "length"
 	PromptEvalCount int     `json:"prompt_eval_count"`
 	EvalCount       int     `json:"eval_count"`
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/abrksh22/bplus/models"
 	"github.com/stretchr/testify/assert"
@@ -137,6 +138,127 @@ func TestProvider_TestConnection(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestProvider_Preload(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/generate", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		json.NewEncoder(w).Encode(map[string]interface{}{"model": got["model"], "done": true, "done_reason": "load"})
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL), WithKeepAlive(30*time.Minute))
+	require.NoError(t, p.Preload(context.Background(), "llama3:latest"))
+	assert.Equal(t, "llama3:latest", got["model"])
+	assert.Equal(t, "30m0s", got["keep_alive"])
+	_, hasPrompt := got["prompt"]
+	assert.False(t, hasPrompt, "a prompt would generate instead of only loading")
+
+	// Chat requests keep the model loaded just as long
+	apiReq := p.convertRequest(&models.CompletionRequest{Model: "llama3:latest"}, false)
+	assert.Equal(t, "30m0s", apiReq.KeepAlive)
+
+	t.Run("Unknown model", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"model 'missing' not found"}`))
+		}))
+		defer server.Close()
+
+		err := New(WithBaseURL(server.URL)).Preload(context.Background(), "missing")
+		var perr *models.ProviderError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, "HTTP_404", perr.Code)
+		assert.False(t, perr.Retryable)
+	})
+}
+
+func TestProvider_Unload(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/generate", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		json.NewEncoder(w).Encode(map[string]interface{}{"model": got["model"], "done": true, "done_reason": "unload"})
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL), WithKeepAlive(30*time.Minute))
+	require.NoError(t, p.Unload(context.Background(), "llama3:latest"))
+	assert.Equal(t, "llama3:latest", got["model"])
+	assert.Equal(t, "0", got["keep_alive"])
+
+	_, ok := models.AsUnloader(p)
+	assert.True(t, ok)
+}
+
+func TestProvider_HasModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/show", r.URL.Path)
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req["model"] != "llama3:latest" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"model not found"}`))
+			return
+		}
+		w.Write([]byte(`{"modelinfo":""}`))
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL))
+	has, err := p.HasModel(context.Background(), "llama3:latest")
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	has, err = p.HasModel(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestProvider_PullModel(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/pull", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got["model"] == "missing" {
+			w.Write([]byte(`{"status":"pulling manifest"}` + "\n" + `{"error":"pull model manifest: file does not exist"}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"status":"pulling manifest"}
+{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":400,"completed":100}
+{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":400,"completed":400}
+{"status":"verifying sha256 digest"}
+{"status":"success"}
+`))
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL))
+	progress := make(chan models.PullProgress, 10)
+	require.NoError(t, p.PullModel(context.Background(), "llama3", progress))
+	assert.Equal(t, "llama3", got["model"])
+	assert.Equal(t, true, got["stream"])
+
+	close(progress)
+	var fractions []float64
+	var last models.PullProgress
+	for update := range progress {
+		fractions = append(fractions, update.Fraction())
+		last = update
+	}
+	assert.Equal(t, []float64{0, 0.25, 1, 0, 0}, fractions)
+	assert.Equal(t, "success", last.Status)
+
+	t.Run("Unknown model", func(t *testing.T) {
+		err := p.PullModel(context.Background(), "missing", nil)
+		var perr *models.ProviderError
+		require.ErrorAs(t, err, &perr)
+		assert.Contains(t, perr.Message, "file does not exist")
+	})
+}
+
 func TestProvider_ErrorHandling(t *testing.T) {
 	t.Run("Server error", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
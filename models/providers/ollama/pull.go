@@ -0,0 +1,128 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// HasModel reports whether a model is installed in Ollama.
+func (p *Provider) HasModel(ctx context.Context, modelID string) (bool, error) {
+	body, err := json.Marshal(&modelRequest{Model: modelID})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("ollama not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return false, models.NewHTTPError("ollama", resp, body)
+	}
+}
+
+// PullModel downloads a model from the Ollama library, sending progress to
+// progress, if not nil, as each layer downloads. Pulling a model that is
+// already installed only checks it is up to date.
+func (p *Provider) PullModel(ctx context.Context, modelID string, progress chan<- models.PullProgress) error {
+	body, err := json.Marshal(&pullRequest{Model: modelID, Stream: true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Downloads take as long as they take; ctx bounds them instead
+	client := *p.client
+	client.Timeout = 0
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("ollama not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return models.NewHTTPError("ollama", resp, body)
+	}
+
+	scanner := transport.NewLineReader(resp.Body)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var event pullResponse
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if event.Error != "" {
+			return models.NewAPIError("ollama", "pull_failed", event.Error)
+		}
+		if progress != nil {
+			select {
+			case progress <- models.PullProgress{
+				Status:    event.Status,
+				Digest:    event.Digest,
+				Total:     event.Total,
+				Completed: event.Completed,
+			}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if event.Status == "success" {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pull interrupted: %w", err)
+	}
+	return fmt.Errorf("pull of %s ended before it succeeded", modelID)
+}
+
+type modelRequest struct {
+	Model string `json:"model"`
+}
+
+type pullRequest struct {
+	Model  string `json:"model"`
+	Stream bool   `json:"stream"`
+}
+
+// pullResponse is one line of the progress /api/pull streams.
+type pullResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
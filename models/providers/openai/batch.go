@@ -0,0 +1,302 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// batchEndpoint is the endpoint batched requests are sent to.
+const batchEndpoint = "/v1/chat/completions"
+
+// CreateBatch uploads the requests as a batch input file and submits it to
+// the Batch API, to be answered within 24 hours at half the price.
+// Requests for models that need the Responses API can't be batched.
+func (p *Provider) CreateBatch(ctx context.Context, reqs []models.BatchRequest) (*models.Batch, error) {
+	var input bytes.Buffer
+	enc := json.NewEncoder(&input)
+	for _, r := range reqs {
+		if usesResponses(r.Request) {
+			return nil, fmt.Errorf("batch request %s: %s can't be batched", r.ID, r.Request.Model)
+		}
+		line := batchInputLine{
+			CustomID: r.ID,
+			Method:   "POST",
+			URL:      batchEndpoint,
+			Body:     p.convertRequest(r.Request, false),
+		}
+		if err := enc.Encode(&line); err != nil {
+			return nil, fmt.Errorf("failed to marshal batch request %s: %w", r.ID, err)
+		}
+	}
+
+	fileID, err := p.uploadBatchInput(ctx, input.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(&createBatchRequest{
+		InputFileID:      fileID,
+		Endpoint:         batchEndpoint,
+		CompletionWindow: "24h",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var batch batchObject
+	if err := p.batchCall(ctx, "POST", "/batches", bytes.NewReader(body), &batch); err != nil {
+		return nil, err
+	}
+	return batch.convert(), nil
+}
+
+// GetBatch returns the progress of a batch.
+func (p *Provider) GetBatch(ctx context.Context, id string) (*models.Batch, error) {
+	var batch batchObject
+	if err := p.batchCall(ctx, "GET", "/batches/"+id, nil, &batch); err != nil {
+		return nil, err
+	}
+	return batch.convert(), nil
+}
+
+// CancelBatch stops a batch.
+func (p *Provider) CancelBatch(ctx context.Context, id string) error {
+	return p.batchCall(ctx, "POST", "/batches/"+id+"/cancel", nil, nil)
+}
+
+// BatchResults downloads the output and error files of a batch that is
+// done. Costs are those of the batch price.
+func (p *Provider) BatchResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	var batch batchObject
+	if err := p.batchCall(ctx, "GET", "/batches/"+id, nil, &batch); err != nil {
+		return nil, err
+	}
+	if !batch.convert().Done() {
+		return nil, fmt.Errorf("batch %s is still %s", id, batch.Status)
+	}
+
+	var results []models.BatchResult
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		fileResults, err := p.batchFileResults(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, fileResults...)
+	}
+	return results, nil
+}
+
+// uploadBatchInput uploads a batch input file and returns its ID.
+func (p *Provider) uploadBatchInput(ctx context.Context, input []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("purpose", "batch"); err != nil {
+		return "", fmt.Errorf("failed to write upload: %w", err)
+	}
+	part, err := form.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", fmt.Errorf("failed to write upload: %w", err)
+	}
+	if _, err := part.Write(input); err != nil {
+		return "", fmt.Errorf("failed to write upload: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("failed to write upload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/files", &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+	httpReq.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", models.NewHTTPError("openai", resp, body)
+	}
+
+	var file fileObject
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return file.ID, nil
+}
+
+// batchFileResults reads the results in a batch output or error file.
+func (p *Provider) batchFileResults(ctx context.Context, fileID string) ([]models.BatchResult, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/files/"+fileID+"/content", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, models.NewHTTPError("openai", resp, body)
+	}
+
+	var results []models.BatchResult
+	scanner := transport.NewLineReader(resp.Body)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line batchOutputLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("failed to decode batch result: %w", err)
+		}
+		results = append(results, p.convertBatchResult(&line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch results: %w", err)
+	}
+	return results, nil
+}
+
+// convertBatchResult converts one line of a batch output or error file.
+func (p *Provider) convertBatchResult(line *batchOutputLine) models.BatchResult {
+	result := models.BatchResult{ID: line.CustomID}
+	switch {
+	case line.Error != nil:
+		result.Error = models.NewAPIError("openai", line.Error.Code, line.Error.Message)
+	case line.Response == nil:
+		result.Error = fmt.Errorf("batch request %s has no response", line.CustomID)
+	case line.Response.StatusCode != http.StatusOK:
+		result.Error = models.NewAPIError("openai", fmt.Sprintf("HTTP_%d", line.Response.StatusCode), string(line.Response.Body))
+	default:
+		var apiResp chatCompletionResponse
+		if err := json.Unmarshal(line.Response.Body, &apiResp); err != nil {
+			result.Error = fmt.Errorf("failed to decode batch response %s: %w", line.CustomID, err)
+			break
+		}
+		result.Response = p.convertResponse(&apiResp)
+		result.Response.Usage.Cost *= models.BatchDiscount
+	}
+	return result
+}
+
+// batchCall sends a Batch API request and decodes the response into out,
+// if not nil.
+func (p *Provider) batchCall(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return models.NewHTTPError("openai", resp, body)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Batch API types
+
+type batchInputLine struct {
+	CustomID string                 `json:"custom_id"`
+	Method   string                 `json:"method"`
+	URL      string                 `json:"url"`
+	Body     *chatCompletionRequest `json:"body"`
+}
+
+type createBatchRequest struct {
+	InputFileID      string `json:"input_file_id"`
+	Endpoint         string `json:"endpoint"`
+	CompletionWindow string `json:"completion_window"`
+}
+
+type fileObject struct {
+	ID string `json:"id"`
+}
+
+type batchObject struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	CreatedAt     int64  `json:"created_at"`
+	ExpiresAt     int64  `json:"expires_at"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+// convert maps the batch onto the provider-neutral states.
+func (b *batchObject) convert() *models.Batch {
+	batch := &models.Batch{
+		ID:        b.ID,
+		Requests:  b.RequestCounts.Total,
+		Succeeded: b.RequestCounts.Completed,
+		Failed:    b.RequestCounts.Failed,
+	}
+	if b.CreatedAt > 0 {
+		batch.CreatedAt = time.Unix(b.CreatedAt, 0)
+	}
+	if b.ExpiresAt > 0 {
+		batch.ExpiresAt = time.Unix(b.ExpiresAt, 0)
+	}
+	switch b.Status {
+	case "completed":
+		batch.Status = models.BatchEnded
+	case "cancelled":
+		batch.Status = models.BatchCanceled
+	case "expired":
+		batch.Status = models.BatchExpired
+	case "failed":
+		batch.Status = models.BatchFailed
+	default: // validating, in_progress, finalizing, cancelling
+		batch.Status = models.BatchInProgress
+	}
+	return batch
+}
+
+type batchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
@@ -0,0 +1,221 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// modelsTTL is how long a discovered model list is reused before it is
+// fetched again.
+const modelsTTL = time.Hour
+
+// Defaults for discovered models missing from the pricing table
+const (
+	defaultContextWindow = 128000
+	defaultMaxOutput     = 4096
+)
+
+// nonChatMarkers identify /models entries that cannot be used for chat completions.
+var nonChatMarkers = []string{
+	"embedding", "whisper", "tts", "dall-e", "davinci", "babbage",
+	"moderation", "audio", "realtime", "transcribe", "image", "search",
+}
+
+// ListModels returns the chat models available to the API key, discovered
+// from /models and merged with the local pricing table. If discovery fails,
+// the pricing table itself is returned.
+func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
+	p.modelsMu.Lock()
+	defer p.modelsMu.Unlock()
+
+	if p.modelList != nil && time.Since(p.modelsFetched) < modelsTTL {
+		return p.modelList, nil
+	}
+
+	objects, err := p.fetchModels(ctx)
+	if err != nil {
+		return knownModels, nil
+	}
+
+	p.modelList = mergeModels(objects)
+	p.modelsFetched = time.Now()
+	return p.modelList, nil
+}
+
+// fetchModels lists the chat-capable models from /models.
+func (p *Provider) fetchModels(ctx context.Context) ([]modelObject, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, models.NewHTTPError("openai", resp, body)
+	}
+
+	var list modelListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+
+	chat := list.Data[:0]
+	for _, m := range list.Data {
+		if isChatModel(m.ID) {
+			chat = append(chat, m)
+		}
+	}
+	return chat, nil
+}
+
+// mergeModels builds the model list from discovered IDs. Each ID inherits
+// pricing and limits from the longest matching entry in the pricing table
+// ("gpt-4o-2024-08-06" from "gpt-4o"); unmatched IDs are priced by family.
+func mergeModels(objects []modelObject) []models.Model {
+	list := make([]models.Model, 0, len(objects))
+	for _, obj := range objects {
+		m, ok := lookupKnown(obj.ID)
+		if !ok {
+			m = models.Model{
+				Name:          obj.ID,
+				Provider:      "openai",
+				ContextWindow: defaultContextWindow,
+				MaxOutput:     defaultMaxOutput,
+				Pricing: models.Pricing{
+					InputTokens:  calculateCost(obj.ID, 1, 0),
+					OutputTokens: calculateCost(obj.ID, 0, 1),
+				},
+				Capabilities: []string{"streaming", "tools"},
+			}
+		} else if m.ID != obj.ID {
+			m.Name += " (" + strings.TrimPrefix(obj.ID, m.ID+"-") + ")"
+		}
+		m.ID = obj.ID
+		if obj.Created > 0 {
+			m.CreatedAt = time.Unix(obj.Created, 0)
+		}
+		list = append(list, m)
+	}
+	return list
+}
+
+// lookupKnown returns the pricing table entry for id, matching dated or
+// suffixed variants of a known model.
+func lookupKnown(id string) (models.Model, bool) {
+	var best models.Model
+	found := false
+	for _, m := range knownModels {
+		if id != m.ID && !strings.HasPrefix(id, m.ID+"-") {
+			continue
+		}
+		if !found || len(m.ID) > len(best.ID) {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
+// isChatModel reports whether a /models ID is a chat completion model.
+func isChatModel(id string) bool {
+	if !strings.HasPrefix(id, "gpt-") && !strings.HasPrefix(id, "chatgpt-") &&
+		!(len(id) > 1 && id[0] == 'o' && id[1] >= '0' && id[1] <= '9') {
+		return false
+	}
+	for _, marker := range nonChatMarkers {
+		if strings.Contains(id, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// knownModels is the local pricing table, also used when discovery fails.
+var knownModels = []models.Model{
+	{
+		ID:            "gpt-4-turbo",
+		Name:          "GPT-4 Turbo",
+		Provider:      "openai",
+		ContextWindow: 128000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  10.00 / 1000000, // $10 per million
+			OutputTokens: 30.00 / 1000000, // $30 per million
+		},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "gpt-4o",
+		Name:          "GPT-4o",
+		Provider:      "openai",
+		ContextWindow: 128000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  5.00 / 1000000,  // $5 per million
+			OutputTokens: 15.00 / 1000000, // $15 per million
+		},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "gpt-4o-mini",
+		Name:          "GPT-4o Mini",
+		Provider:      "openai",
+		ContextWindow: 128000,
+		MaxOutput:     16384,
+		Pricing: models.Pricing{
+			InputTokens:  0.15 / 1000000, // $0.15 per million
+			OutputTokens: 0.60 / 1000000, // $0.60 per million
+		},
+		Capabilities: []string{"streaming", "tools"},
+	},
+	{
+		ID:            "o1",
+		Name:          "O1",
+		Provider:      "openai",
+		ContextWindow: 200000,
+		MaxOutput:     100000,
+		Pricing: models.Pricing{
+			InputTokens:  15.00 / 1000000, // $15 per million
+			OutputTokens: 60.00 / 1000000, // $60 per million
+		},
+		Capabilities: []string{"streaming"},
+	},
+	{
+		ID:            "o1-mini",
+		Name:          "O1 Mini",
+		Provider:      "openai",
+		ContextWindow: 128000,
+		MaxOutput:     65536,
+		Pricing: models.Pricing{
+			InputTokens:  3.00 / 1000000,  // $3 per million
+			OutputTokens: 12.00 / 1000000, // $12 per million
+		},
+		Capabilities: []string{"streaming"},
+	},
+}
+
+// Models API types
+
+type modelListResponse struct {
+	Data []modelObject `json:"data"`
+}
+
+type modelObject struct {
+	ID      string `json:"id"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
@@ -2,17 +2,20 @@
 package openai
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
 )
 
 const (
@@ -24,6 +27,11 @@ type Provider struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	user    string // Optional end-user identifier for spend attribution
+
+	modelsMu      sync.Mutex
+	modelList     []models.Model // Discovered models, refreshed after modelsTTL
+	modelsFetched time.Time
 }
 
 // New creates a new OpenAI provider.
@@ -31,9 +39,7 @@ func New(apiKey string, opts ...Option) *Provider {
 	p := &Provider{
 		apiKey:  apiKey,
 		baseURL: defaultBaseURL,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		client:  transport.NewClient(60 * time.Second),
 	}
 
 	for _, opt := range opts {
@@ -60,80 +66,25 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithUser sets the end-user identifier sent in the "user" field of every
+// request, used by OpenAI for abuse monitoring and usage attribution.
+func WithUser(user string) Option {
+	return func(p *Provider) {
+		p.user = user
+	}
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return "openai"
 }
 
-// ListModels returns available OpenAI models.
-func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
-	// Return commonly used models
-	return []models.Model{
-		{
-			ID:            "gpt-4-turbo",
-			Name:          "GPT-4 Turbo",
-			Provider:      "openai",
-			ContextWindow: 128000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  10.00 / 1000000, // $10 per million
-				OutputTokens: 30.00 / 1000000, // $30 per million
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
-		},
-		{
-			ID:            "gpt-4o",
-			Name:          "GPT-4o",
-			Provider:      "openai",
-			ContextWindow: 128000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  5.00 / 1000000,  // $5 per million
-				OutputTokens: 15.00 / 1000000, // $15 per million
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
-		},
-		{
-			ID:            "gpt-4o-mini",
-			Name:          "GPT-4o Mini",
-			Provider:      "openai",
-			ContextWindow: 128000,
-			MaxOutput:     16384,
-			Pricing: models.Pricing{
-				InputTokens:  0.15 / 1000000, // $0.15 per million
-				OutputTokens: 0.60 / 1000000, // $0.60 per million
-			},
-			Capabilities: []string{"streaming", "tools"},
-		},
-		{
-			ID:            "o1",
-			Name:          "O1",
-			Provider:      "openai",
-			ContextWindow: 200000,
-			MaxOutput:     100000,
-			Pricing: models.Pricing{
-				InputTokens:  15.00 / 1000000, // $15 per million
-				OutputTokens: 60.00 / 1000000, // $60 per million
-			},
-			Capabilities: []string{"streaming"},
-		},
-		{
-			ID:            "o1-mini",
-			Name:          "O1 Mini",
-			Provider:      "openai",
-			ContextWindow: 128000,
-			MaxOutput:     65536,
-			Pricing: models.Pricing{
-				InputTokens:  3.00 / 1000000,  // $3 per million
-				OutputTokens: 12.00 / 1000000, // $12 per million
-			},
-			Capabilities: []string{"streaming"},
-		},
-	}, nil
-}
-
 // CreateCompletion creates a non-streaming completion.
 func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	if usesResponses(req) {
+		return p.createResponse(ctx, req)
+	}
+
 	apiReq := p.convertRequest(req, false)
 
 	body, err := json.Marshal(apiReq)
@@ -156,12 +107,7 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, &models.ProviderError{
-			Provider:  "openai",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("openai", resp, body)
 	}
 
 	var apiResp chatCompletionResponse
@@ -172,8 +118,13 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 	return p.convertResponse(&apiResp), nil
 }
 
-// StreamCompletion creates a streaming completion.
+// StreamCompletion creates a streaming completion. With a reasoning budget,
+// reasoning models stream their reasoning summary in StreamToken.Reasoning.
 func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
+	if usesResponses(req) {
+		return p.streamResponse(ctx, req)
+	}
+
 	apiReq := p.convertRequest(req, true)
 
 	body, err := json.Marshal(apiReq)
@@ -196,12 +147,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, &models.ProviderError{
-			Provider:  "openai",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("openai", resp, body)
 	}
 
 	tokens := make(chan models.StreamToken, 10)
@@ -210,8 +156,10 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 		defer close(tokens)
 		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := transport.NewLineReader(resp.Body)
 		var totalUsage *models.Usage
+		var stopReason string
+		pending := make(map[int]*toolCall) // Tool calls accumulate across chunks by index
 
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -222,11 +170,11 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 
 			data := strings.TrimPrefix(line, "data: ")
 			if data == "[DONE]" {
-				if totalUsage != nil {
-					tokens <- models.StreamToken{
-						Done:  true,
-						Usage: totalUsage,
-					}
+				flushToolCalls(tokens, pending)
+				tokens <- models.StreamToken{
+					Done:       true,
+					StopReason: stopReason,
+					Usage:      totalUsage,
 				}
 				return
 			}
@@ -239,27 +187,29 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 
 			if len(chunk.Choices) > 0 {
 				delta := chunk.Choices[0].Delta
+				if chunk.Choices[0].FinishReason != "" {
+					stopReason = models.NormalizeStopReason(chunk.Choices[0].FinishReason)
+				}
 				if delta.Content != "" {
 					tokens <- models.StreamToken{
 						Content: delta.Content,
 					}
 				}
 
-				// Handle tool calls if present
-				if len(delta.ToolCalls) > 0 {
-					for _, tc := range delta.ToolCalls {
-						if tc.Function.Name != "" {
-							var args map[string]interface{}
-							json.Unmarshal([]byte(tc.Function.Arguments), &args)
-							tokens <- models.StreamToken{
-								ToolCall: &models.ToolCall{
-									ID:        tc.ID,
-									Name:      tc.Function.Name,
-									Arguments: args,
-								},
-							}
-						}
+				// Tool call arguments stream as JSON fragments, accumulated by index
+				for _, tc := range delta.ToolCalls {
+					call, ok := pending[tc.Index]
+					if !ok {
+						call = &toolCall{ID: tc.ID, Type: tc.Type}
+						pending[tc.Index] = call
+					}
+					if tc.Function.Name != "" {
+						call.Function.Name = tc.Function.Name
 					}
+					call.Function.Arguments += tc.Function.Arguments
+				}
+				if chunk.Choices[0].FinishReason != "" {
+					flushToolCalls(tokens, pending)
 				}
 			}
 
@@ -332,6 +282,7 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 		Model:    req.Model,
 		Stream:   stream,
 		Messages: make([]chatMessage, 0, len(req.Messages)+1),
+		User:     models.EndUser(req, p.user),
 	}
 
 	// Add system message if present
@@ -347,6 +298,7 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 		apiReq.Messages = append(apiReq.Messages, chatMessage{
 			Role:    msg.Role,
 			Content: msg.Content,
+			Parts:   contentParts(msg),
 		})
 	}
 
@@ -368,6 +320,7 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 
 	// Add tools if present
 	if len(req.Tools) > 0 {
+		apiReq.Parallel = &req.ParallelToolCalls
 		apiReq.Tools = make([]tool, len(req.Tools))
 		for i, t := range req.Tools {
 			apiReq.Tools[i] = tool{
@@ -398,13 +351,7 @@ func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.Comp
 		if len(choice.Message.ToolCalls) > 0 {
 			resp.ToolCalls = make([]models.ToolCall, len(choice.Message.ToolCalls))
 			for i, tc := range choice.Message.ToolCalls {
-				var args map[string]interface{}
-				json.Unmarshal([]byte(tc.Function.Arguments), &args)
-				resp.ToolCalls[i] = models.ToolCall{
-					ID:        tc.ID,
-					Name:      tc.Function.Name,
-					Arguments: args,
-				}
+				resp.ToolCalls[i] = convertToolCall(tc)
 			}
 			resp.StopReason = "tool_use"
 		}
@@ -423,6 +370,31 @@ func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.Comp
 	return resp
 }
 
+// flushToolCalls emits accumulated streaming tool calls in index order.
+func flushToolCalls(tokens chan<- models.StreamToken, pending map[int]*toolCall) {
+	indexes := make([]int, 0, len(pending))
+	for i := range pending {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		call := convertToolCall(*pending[i])
+		tokens <- models.StreamToken{ToolCall: &call}
+		delete(pending, i)
+	}
+}
+
+func convertToolCall(tc toolCall) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return models.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Function.Name,
+		Arguments: args,
+	}
+}
+
 func convertToolParams(params []models.Parameter) map[string]interface{} {
 	properties := make(map[string]interface{})
 	required := []string{}
@@ -444,31 +416,9 @@ func convertToolParams(params []models.Parameter) map[string]interface{} {
 	}
 }
 
-func calculateCost(model string, promptTokens, completionTokens int) float64 {
-	var inputCost, outputCost float64
-
-	switch {
-	case strings.Contains(model, "gpt-4-turbo"):
-		inputCost = 10.00 / 1000000
-		outputCost = 30.00 / 1000000
-	case strings.Contains(model, "gpt-4o-mini"):
-		inputCost = 0.15 / 1000000
-		outputCost = 0.60 / 1000000
-	case strings.Contains(model, "gpt-4o"):
-		inputCost = 5.00 / 1000000
-		outputCost = 15.00 / 1000000
-	case strings.Contains(model, "o1-mini"):
-		inputCost = 3.00 / 1000000
-		outputCost = 12.00 / 1000000
-	case strings.Contains(model, "o1"):
-		inputCost = 15.00 / 1000000
-		outputCost = 60.00 / 1000000
-	default:
-		inputCost = 5.00 / 1000000
-		outputCost = 15.00 / 1000000
-	}
-
-	return (float64(promptTokens) * inputCost) + (float64(completionTokens) * outputCost)
+// calculateCost prices a call from the openai pricing table.
+func calculateCost(model string, inputTokens, outputTokens int) float64 {
+	return pricing.Lookup("openai", model).Cost(inputTokens, outputTokens)
 }
 
 // API types
@@ -482,12 +432,55 @@ type chatCompletionRequest struct {
 	Stop        []string      `json:"stop,omitempty"`
 	Stream      bool          `json:"stream,omitempty"`
 	Tools       []tool        `json:"tools,omitempty"`
+	Parallel    *bool         `json:"parallel_tool_calls,omitempty"` // Sent with Tools
+	User        string        `json:"user,omitempty"`
 }
 
 type chatMessage struct {
 	Role      string     `json:"role"`
 	Content   string     `json:"content"`
 	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+
+	// Parts replaces Content when sending a message with images
+	Parts []contentPart `json:"-"`
+}
+
+// MarshalJSON sends Parts as the content when set.
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type plain chatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+type contentPart struct {
+	Type     string    `json:"type"` // "text" or "image_url"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// contentParts returns the content parts of a message with images, or nil
+// for a text-only message.
+func contentParts(msg models.Message) []contentPart {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+	parts := make([]contentPart, 0, len(msg.Attachments)+1)
+	if msg.Content != "" {
+		parts = append(parts, contentPart{Type: "text", Text: msg.Content})
+	}
+	for _, a := range msg.Attachments {
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: a.DataURL()}})
+	}
+	return parts
 }
 
 type tool struct {
@@ -502,6 +495,7 @@ type functionDef struct {
 }
 
 type toolCall struct {
+	Index    int          `json:"index"`
 	ID       string       `json:"id"`
 	Type     string       `json:"type"`
 	Function toolCallFunc `json:"function"`
@@ -0,0 +1,313 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_ListModels_Discovery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "gpt-4o-mini-2024-07-18", "created": 1721172741},
+				{"id": "gpt-4o"},
+				{"id": "gpt-5"},
+				{"id": "o3-mini"},
+				{"id": "text-embedding-3-small"},
+				{"id": "gpt-4o-realtime-preview"},
+				{"id": "whisper-1"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	list, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+
+	ids := make([]string, len(list))
+	for i, m := range list {
+		ids[i] = m.ID
+	}
+	assert.Equal(t, []string{"gpt-4o-mini-2024-07-18", "gpt-4o", "gpt-5", "o3-mini"}, ids)
+
+	// Dated variants inherit from the longest matching known model
+	mini := list[0]
+	assert.Equal(t, "GPT-4o Mini (2024-07-18)", mini.Name)
+	assert.Equal(t, 0.15/1000000, mini.Pricing.InputTokens)
+	assert.Equal(t, 16384, mini.MaxOutput)
+	assert.False(t, mini.CreatedAt.IsZero())
+
+	// Unknown models get defaults and family pricing
+	gpt5 := list[2]
+	assert.Equal(t, defaultContextWindow, gpt5.ContextWindow)
+	assert.Greater(t, gpt5.Pricing.OutputTokens, 0.0)
+
+	info, err := p.GetModelInfo(context.Background(), "gpt-5")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-5", info.ID)
+}
+
+func TestProvider_ListModels_Fallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p := New("bad-key", WithBaseURL(server.URL))
+	list, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, knownModels, list)
+}
+
+func TestConvertRequest_ImageParts(t *testing.T) {
+	p := New("test-key")
+	req := p.convertRequest(&models.CompletionRequest{
+		Model: "gpt-4o",
+		Messages: []models.Message{
+			{Role: "user", Content: "What is this error?", Attachments: []models.Attachment{
+				{Data: []byte("img"), MimeType: "image/png"},
+			}},
+			{Role: "assistant", Content: "A nil pointer dereference."},
+		},
+	}, false)
+
+	body, err := json.Marshal(req.Messages)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"role":"user","content":[
+			{"type":"text","text":"What is this error?"},
+			{"type":"image_url","image_url":{"url":"data:image/png;base64,aW1n"}}
+		]},
+		{"role":"assistant","content":"A nil pointer dereference."}
+	]`, string(body))
+}
+
+func TestConvertRequest_ParallelToolCalls(t *testing.T) {
+	p := New("test-key")
+	tools := []models.Tool{{Name: "read", Description: "Read a file"}}
+
+	req := p.convertRequest(&models.CompletionRequest{Model: "gpt-4o", Tools: tools, ParallelToolCalls: true}, false)
+	require.NotNil(t, req.Parallel)
+	assert.True(t, *req.Parallel)
+
+	req = p.convertRequest(&models.CompletionRequest{Model: "gpt-4o", Tools: tools}, false)
+	require.NotNil(t, req.Parallel)
+	assert.False(t, *req.Parallel)
+
+	// Without tools the setting is not sent
+	req = p.convertRequest(&models.CompletionRequest{Model: "gpt-4o", ParallelToolCalls: true}, false)
+	assert.Nil(t, req.Parallel)
+}
+
+func TestProvider_StreamCompletion_ToolCallFragments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"pa"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"glob","arguments":"{\"pattern\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"a.go\"}"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":1,"function":{"arguments":"\"*.go\"}"}}]}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []models.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var calls []*models.ToolCall
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		if tok.ToolCall != nil {
+			calls = append(calls, tok.ToolCall)
+		}
+	}
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.Equal(t, "read", calls[0].Name)
+	assert.Equal(t, "a.go", calls[0].Arguments["path"])
+	assert.Equal(t, "call_2", calls[1].ID)
+	assert.Equal(t, "glob", calls[1].Name)
+	assert.Equal(t, "*.go", calls[1].Arguments["pattern"])
+}
+
+func TestProvider_StreamCompletion_ReasoningSummary(t *testing.T) {
+	var apiReq map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/responses", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&apiReq))
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"response.reasoning_summary_part.added","summary_index":0}`,
+			`{"type":"response.reasoning_summary_text.delta","delta":"**Finding the tests**"}`,
+			`{"type":"response.reasoning_summary_part.added","summary_index":1}`,
+			`{"type":"response.reasoning_summary_text.delta","delta":"Run go test."}`,
+			`{"type":"response.output_text.delta","delta":"Running them."}`,
+			`{"type":"response.output_item.done","item":{"type":"function_call","call_id":"call_1","name":"bash","arguments":"{\"command\":\"go test\"}"}}`,
+			`{"type":"response.completed","response":{"model":"o4-mini","output":[{"type":"function_call","call_id":"call_1","name":"bash","arguments":"{}"}],` +
+				`"usage":{"input_tokens":20,"output_tokens":300,"total_tokens":320,"output_tokens_details":{"reasoning_tokens":256}}}}`,
+		}
+		for _, e := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", e)
+		}
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:           "o4-mini",
+		System:          "Be brief.",
+		Messages:        []models.Message{{Role: "user", Content: "Run the tests"}},
+		MaxTokens:       1000,
+		ReasoningBudget: 8000,
+	})
+	require.NoError(t, err)
+
+	var content, reasoning string
+	var calls []*models.ToolCall
+	var last models.StreamToken
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		content += tok.Content
+		reasoning += tok.Reasoning
+		if tok.ToolCall != nil {
+			calls = append(calls, tok.ToolCall)
+		}
+		last = tok
+	}
+
+	assert.Equal(t, "Running them.", content)
+	assert.Equal(t, "**Finding the tests**\n\nRun go test.", reasoning)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "go test", calls[0].Arguments["command"])
+	assert.True(t, last.Done)
+	assert.Equal(t, "tool_use", last.StopReason)
+	assert.Equal(t, 256, last.Usage.ReasoningTokens)
+
+	assert.Equal(t, map[string]interface{}{"effort": "medium", "summary": "auto"}, apiReq["reasoning"])
+	assert.Equal(t, "Be brief.", apiReq["instructions"])
+	assert.Equal(t, float64(9000), apiReq["max_output_tokens"])
+}
+
+func TestProvider_StreamCompletion_LargeChunks(t *testing.T) {
+	// Multi-megabyte text and tool argument chunks, each on one SSE data line
+	text := strings.Repeat("a", 3*1024*1024)
+	content := strings.Repeat("b", 6*1024*1024)
+	args, err := json.Marshal(map[string]string{"content": content})
+	require.NoError(t, err)
+	argsJSON, err := json.Marshal(string(args))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"content":"` + text + `"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"write","arguments":` + string(argsJSON) + `}}]}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []models.Message{{Role: "user", Content: "Write the file"}},
+	})
+	require.NoError(t, err)
+
+	var got string
+	var calls []*models.ToolCall
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		got += tok.Content
+		if tok.ToolCall != nil {
+			calls = append(calls, tok.ToolCall)
+		}
+	}
+
+	assert.Equal(t, text, got)
+	require.Len(t, calls, 1)
+	assert.Equal(t, content, calls[0].Arguments["content"])
+}
+
+func TestProvider_Batch(t *testing.T) {
+	var input string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /files":
+			assert.Equal(t, "batch", r.FormValue("purpose"))
+			file, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			data, err := io.ReadAll(file)
+			require.NoError(t, err)
+			input = string(data)
+			fmt.Fprint(w, `{"id":"file-in"}`)
+		case "POST /batches":
+			var req createBatchRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, createBatchRequest{InputFileID: "file-in", Endpoint: "/v1/chat/completions", CompletionWindow: "24h"}, req)
+			fmt.Fprint(w, `{"id":"batch_1","status":"validating","request_counts":{"total":2}}`)
+		case "GET /batches/batch_1":
+			fmt.Fprint(w, `{"id":"batch_1","status":"completed","output_file_id":"file-out","error_file_id":"file-err","request_counts":{"total":2,"completed":1,"failed":1}}`)
+		case "GET /files/file-out/content":
+			fmt.Fprintln(w, `{"custom_id":"plan-1","response":{"status_code":200,"body":{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"Plan"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1000,"completion_tokens":1000,"total_tokens":2000}}}}`)
+		case "GET /files/file-err/content":
+			fmt.Fprintln(w, `{"custom_id":"plan-2","response":{"status_code":400,"body":{"error":{"message":"bad"}}}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	batch, err := p.CreateBatch(context.Background(), []models.BatchRequest{
+		{ID: "plan-1", Request: &models.CompletionRequest{Model: "gpt-4o", Messages: []models.Message{{Role: "user", Content: "Plan A"}}}},
+		{ID: "plan-2", Request: &models.CompletionRequest{Model: "gpt-4o", Messages: []models.Message{{Role: "user", Content: "Plan B"}}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchInProgress, batch.Status)
+	assert.Contains(t, input, `"custom_id":"plan-1"`)
+	assert.Contains(t, input, `"url":"/v1/chat/completions"`)
+	assert.Len(t, strings.Split(strings.TrimSpace(input), "\n"), 2)
+
+	batch, err = p.GetBatch(context.Background(), "batch_1")
+	require.NoError(t, err)
+	assert.True(t, batch.Done())
+	assert.Equal(t, 1, batch.Failed)
+
+	results, err := p.BatchResults(context.Background(), "batch_1")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Plan", results[0].Response.Content)
+	assert.InDelta(t, calculateCost("gpt-4o", 1000, 1000)*models.BatchDiscount, results[0].Response.Usage.Cost, 1e-9)
+	assert.Equal(t, "plan-2", results[1].ID)
+	assert.Error(t, results[1].Error)
+}
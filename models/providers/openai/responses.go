@@ -0,0 +1,370 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+// Reasoning summaries of o-series and GPT-5 models are only returned by the
+// Responses API, so requests with a reasoning budget for those models are
+// sent there instead of to /chat/completions.
+
+// usesResponses reports whether req is sent to the Responses API.
+func usesResponses(req *models.CompletionRequest) bool {
+	return req.ReasoningBudget > 0 && isReasoningModel(req.Model)
+}
+
+// isReasoningModel reports whether model reasons before answering.
+func isReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// reasoningEffort maps a reasoning budget onto the API's effort levels.
+func reasoningEffort(budget int) string {
+	switch {
+	case budget < 4096:
+		return "low"
+	case budget < 16384:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// createResponse creates a non-streaming completion with the Responses API.
+func (p *Provider) createResponse(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	resp, err := p.postResponse(ctx, p.convertResponsesRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var apiResp responseObject
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return convertResponseObject(&apiResp), nil
+}
+
+// streamResponse creates a streaming completion with the Responses API.
+// Reasoning summary deltas are delivered in StreamToken.Reasoning.
+func (p *Provider) streamResponse(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
+	resp, err := p.postResponse(ctx, p.convertResponsesRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(chan models.StreamToken, 10)
+
+	go func() {
+		defer close(tokens)
+		defer resp.Body.Close()
+
+		scanner := transport.NewLineReader(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			var event responseEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				tokens <- models.StreamToken{Error: err}
+				return
+			}
+
+			switch event.Type {
+			case "response.reasoning_summary_part.added":
+				// Summaries come in parts; keep them apart as paragraphs
+				if event.SummaryIndex > 0 {
+					tokens <- models.StreamToken{Reasoning: "\n\n"}
+				}
+			case "response.reasoning_summary_text.delta":
+				tokens <- models.StreamToken{Reasoning: event.Delta}
+			case "response.output_text.delta":
+				tokens <- models.StreamToken{Content: event.Delta}
+			case "response.output_item.done":
+				// Function calls arrive complete here, arguments and all
+				if event.Item != nil && event.Item.Type == "function_call" {
+					call := convertFunctionCall(*event.Item)
+					tokens <- models.StreamToken{ToolCall: &call}
+				}
+			case "response.completed", "response.incomplete":
+				if event.Response == nil {
+					event.Response = &responseObject{}
+				}
+				final := convertResponseObject(event.Response)
+				tokens <- models.StreamToken{
+					Done:       true,
+					StopReason: final.StopReason,
+					Usage:      &final.Usage,
+				}
+				return
+			case "response.failed", "error":
+				tokens <- models.StreamToken{Error: event.err()}
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			tokens <- models.StreamToken{Error: err}
+		}
+	}()
+
+	return tokens, nil
+}
+
+// postResponse sends a Responses API request, returning the response on
+// HTTP 200.
+func (p *Provider) postResponse(ctx context.Context, apiReq *responsesRequest) (*http.Response, error) {
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/responses", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, models.NewHTTPError("openai", resp, body)
+	}
+	return resp, nil
+}
+
+func (p *Provider) convertResponsesRequest(req *models.CompletionRequest, stream bool) *responsesRequest {
+	apiReq := &responsesRequest{
+		Model:        req.Model,
+		Instructions: req.System,
+		Input:        make([]inputItem, 0, len(req.Messages)),
+		Stream:       stream,
+		User:         p.user,
+		Reasoning:    &reasoningConfig{Effort: reasoningEffort(req.ReasoningBudget), Summary: "auto"},
+	}
+
+	// Reasoning tokens count towards max_output_tokens
+	if req.MaxTokens > 0 {
+		apiReq.MaxOutputTokens = req.MaxTokens + req.ReasoningBudget
+	}
+
+	for _, msg := range req.Messages {
+		switch {
+		case msg.Role == "tool" && msg.ToolCallID != "":
+			apiReq.Input = append(apiReq.Input, inputItem{Type: "function_call_output", CallID: msg.ToolCallID, Output: msg.Content})
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			if msg.Content != "" {
+				apiReq.Input = append(apiReq.Input, inputItem{Role: "assistant", Content: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				args, err := json.Marshal(call.Arguments)
+				if err != nil || call.Arguments == nil {
+					args = []byte("{}")
+				}
+				apiReq.Input = append(apiReq.Input, inputItem{Type: "function_call", CallID: call.ID, Name: call.Name, Arguments: string(args)})
+			}
+		default:
+			apiReq.Input = append(apiReq.Input, inputItem{Role: msg.Role, Content: msg.Content})
+		}
+	}
+
+	for _, t := range req.Tools {
+		apiReq.Tools = append(apiReq.Tools, responsesTool{
+			Type:        "function",
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  convertToolParams(t.Parameters),
+		})
+	}
+	if len(req.Tools) > 0 {
+		apiReq.Parallel = &req.ParallelToolCalls
+	}
+
+	return apiReq
+}
+
+func convertResponseObject(apiResp *responseObject) *models.CompletionResponse {
+	resp := &models.CompletionResponse{
+		Model:      apiResp.Model,
+		StopReason: "end_turn",
+	}
+
+	var content, reasoning []string
+	for _, item := range apiResp.Output {
+		switch item.Type {
+		case "reasoning":
+			for _, part := range item.Summary {
+				reasoning = append(reasoning, part.Text)
+			}
+		case "message":
+			for _, part := range item.Content {
+				if part.Type == "output_text" {
+					content = append(content, part.Text)
+				}
+			}
+		case "function_call":
+			resp.ToolCalls = append(resp.ToolCalls, convertFunctionCall(item))
+		}
+	}
+	resp.Content = strings.Join(content, "")
+	resp.Reasoning = strings.Join(reasoning, "\n\n")
+
+	switch {
+	case len(resp.ToolCalls) > 0:
+		resp.StopReason = "tool_use"
+	case apiResp.IncompleteDetails != nil && apiResp.IncompleteDetails.Reason == "max_output_tokens":
+		resp.StopReason = "max_tokens"
+	}
+
+	if u := apiResp.Usage; u != nil {
+		resp.Usage = models.Usage{
+			InputTokens:     u.InputTokens,
+			OutputTokens:    u.OutputTokens,
+			TotalTokens:     u.TotalTokens,
+			ReasoningTokens: u.OutputTokensDetails.ReasoningTokens,
+			Cost:            calculateCost(apiResp.Model, u.InputTokens, u.OutputTokens),
+		}
+		resp.Usage.ReasoningCost = calculateCost(apiResp.Model, 0, resp.Usage.ReasoningTokens)
+	}
+
+	return resp
+}
+
+func convertFunctionCall(item outputItem) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(item.Arguments), &args)
+	return models.ToolCall{
+		ID:        item.CallID,
+		Name:      item.Name,
+		Arguments: args,
+	}
+}
+
+// Responses API types
+
+type responsesRequest struct {
+	Model           string           `json:"model"`
+	Instructions    string           `json:"instructions,omitempty"`
+	Input           []inputItem      `json:"input"`
+	Tools           []responsesTool  `json:"tools,omitempty"`
+	Parallel        *bool            `json:"parallel_tool_calls,omitempty"` // Sent with Tools
+	MaxOutputTokens int              `json:"max_output_tokens,omitempty"`
+	Reasoning       *reasoningConfig `json:"reasoning,omitempty"`
+	Stream          bool             `json:"stream,omitempty"`
+	User            string           `json:"user,omitempty"`
+}
+
+type reasoningConfig struct {
+	Effort  string `json:"effort"`  // "low", "medium" or "high"
+	Summary string `json:"summary"` // "auto", "concise" or "detailed"
+}
+
+// inputItem is a message (Role and Content) or, with Type set, a function
+// call or its output.
+type inputItem struct {
+	Type      string `json:"type,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Content   string `json:"content,omitempty"`
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+}
+
+type responsesTool struct {
+	Type        string      `json:"type"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type responseObject struct {
+	ID                string             `json:"id"`
+	Model             string             `json:"model"`
+	Status            string             `json:"status"`
+	Output            []outputItem       `json:"output"`
+	Usage             *responsesUsage    `json:"usage"`
+	IncompleteDetails *incompleteDetails `json:"incomplete_details"`
+	Error             *responseError     `json:"error"`
+}
+
+type outputItem struct {
+	Type string `json:"type"` // "reasoning", "message" or "function_call"
+
+	// reasoning
+	Summary []outputPart `json:"summary,omitempty"`
+
+	// message
+	Content []outputPart `json:"content,omitempty"`
+
+	// function_call
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type outputPart struct {
+	Type string `json:"type"` // "summary_text" or "output_text"
+	Text string `json:"text"`
+}
+
+type responsesUsage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+type incompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+type responseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type responseEvent struct {
+	Type         string          `json:"type"`
+	Delta        string          `json:"delta,omitempty"`
+	SummaryIndex int             `json:"summary_index,omitempty"`
+	Item         *outputItem     `json:"item,omitempty"`
+	Response     *responseObject `json:"response,omitempty"`
+
+	// error events
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// err returns the error reported by a failed or error event.
+func (e responseEvent) err() error {
+	if e.Response != nil && e.Response.Error != nil {
+		return models.NewAPIError("openai", e.Response.Error.Code, e.Response.Error.Message)
+	}
+	return models.NewAPIError("openai", e.Code, e.Message)
+}
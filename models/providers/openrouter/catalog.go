@@ -0,0 +1,226 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// defaultCatalogTTL is how long a fetched model catalog is considered fresh.
+const defaultCatalogTTL = 24 * time.Hour
+
+// WithCatalogCache caches the model catalog at path for ttl. Without a cache
+// path the catalog is only kept in memory for the provider's lifetime.
+func WithCatalogCache(path string, ttl time.Duration) Option {
+	return func(p *Provider) {
+		p.catalogPath = path
+		if ttl > 0 {
+			p.catalogTTL = ttl
+		}
+	}
+}
+
+// catalogCache is the on-disk form of the model catalog.
+type catalogCache struct {
+	FetchedAt time.Time    `json:"fetched_at"`
+	Models    []modelEntry `json:"models"`
+}
+
+// catalog returns the model catalog, preferring a fresh cached copy, then the
+// live /models endpoint, then a stale cached copy if the fetch fails.
+func (p *Provider) catalog(ctx context.Context) ([]models.Model, error) {
+	p.catalogMu.Lock()
+	defer p.catalogMu.Unlock()
+
+	if p.catalogModels != nil && time.Since(p.catalogFetched) < p.catalogTTL {
+		return p.catalogModels, nil
+	}
+
+	cached, cacheErr := p.readCatalogCache()
+	if cacheErr == nil && time.Since(cached.FetchedAt) < p.catalogTTL {
+		p.setCatalog(cached)
+		return p.catalogModels, nil
+	}
+
+	entries, err := p.fetchCatalog(ctx)
+	if err != nil {
+		if cacheErr == nil {
+			// Stale, but better than nothing while the API is unreachable
+			p.setCatalog(cached)
+			return p.catalogModels, nil
+		}
+		return nil, err
+	}
+
+	fresh := &catalogCache{FetchedAt: time.Now(), Models: entries}
+	p.setCatalog(fresh)
+	p.writeCatalogCache(fresh)
+	return p.catalogModels, nil
+}
+
+// setCatalog converts and stores a catalog in memory. It must be called with
+// catalogMu held.
+func (p *Provider) setCatalog(c *catalogCache) {
+	list := make([]models.Model, 0, len(c.Models))
+	for _, e := range c.Models {
+		list = append(list, e.toModel())
+	}
+	p.catalogModels = list
+	p.catalogFetched = c.FetchedAt
+	p.catalogDescriptions = make(map[string]string, len(c.Models))
+	for _, e := range c.Models {
+		if e.Description != "" {
+			p.catalogDescriptions[e.ID] = e.Description
+		}
+	}
+}
+
+// catalogDescription returns the catalog description of a model, if known.
+func (p *Provider) catalogDescription(modelID string) string {
+	p.catalogMu.Lock()
+	defer p.catalogMu.Unlock()
+	return p.catalogDescriptions[modelID]
+}
+
+// fetchCatalog retrieves the full model list from the API.
+func (p *Provider) fetchCatalog(ctx context.Context) ([]modelEntry, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, models.NewHTTPError("openrouter", resp, body)
+	}
+
+	var list modelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode model catalog: %w", err)
+	}
+	return list.Data, nil
+}
+
+func (p *Provider) readCatalogCache() (*catalogCache, error) {
+	if p.catalogPath == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(p.catalogPath)
+	if err != nil {
+		return nil, err
+	}
+	var c catalogCache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// writeCatalogCache persists the catalog. Failures are ignored; the next
+// call simply fetches again.
+func (p *Provider) writeCatalogCache(c *catalogCache) {
+	if p.catalogPath == "" {
+		return
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(p.catalogPath), 0755); err != nil {
+		return
+	}
+	tmp := p.catalogPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, p.catalogPath)
+}
+
+// toModel converts a catalog entry. Prices are reported per token as decimal strings.
+func (e modelEntry) toModel() models.Model {
+	m := models.Model{
+		ID:            e.ID,
+		Name:          e.Name,
+		Provider:      "openrouter",
+		ContextWindow: e.ContextLength,
+		Pricing: models.Pricing{
+			InputTokens:  parsePrice(e.Pricing.Prompt),
+			OutputTokens: parsePrice(e.Pricing.Completion),
+			MinimumCost:  parsePrice(e.Pricing.Request),
+		},
+		Capabilities: []string{"streaming"},
+	}
+	if e.Created > 0 {
+		m.CreatedAt = time.Unix(e.Created, 0)
+	}
+	if e.TopProvider.ContextLength > 0 && m.ContextWindow == 0 {
+		m.ContextWindow = e.TopProvider.ContextLength
+	}
+	m.MaxOutput = e.TopProvider.MaxCompletionTokens
+
+	for _, param := range e.SupportedParameters {
+		if param == "tools" {
+			m.Capabilities = append(m.Capabilities, "tools")
+			break
+		}
+	}
+	for _, modality := range e.Architecture.InputModalities {
+		if modality == "image" {
+			m.Capabilities = append(m.Capabilities, "vision")
+			break
+		}
+	}
+	return m
+}
+
+// parsePrice parses a per-token price. Unparseable or negative values
+// (OpenRouter uses "-1" for variable-priced routers) are treated as unknown.
+func parsePrice(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
+// Catalog API types
+
+type modelsResponse struct {
+	Data []modelEntry `json:"data"`
+}
+
+type modelEntry struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	Created       int64  `json:"created,omitempty"`
+	ContextLength int    `json:"context_length"`
+	Pricing       struct {
+		Prompt     string `json:"prompt"`
+		Completion string `json:"completion"`
+		Request    string `json:"request,omitempty"`
+	} `json:"pricing"`
+	TopProvider struct {
+		ContextLength       int `json:"context_length,omitempty"`
+		MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+	} `json:"top_provider"`
+	Architecture struct {
+		InputModalities []string `json:"input_modalities,omitempty"`
+	} `json:"architecture"`
+	SupportedParameters []string `json:"supported_parameters,omitempty"`
+}
@@ -3,17 +3,22 @@
 package openrouter
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/pricing"
+	"github.com/abrksh22/bplus/models/transport"
 )
 
 const (
@@ -27,6 +32,17 @@ type Provider struct {
 	client  *http.Client
 	appName string // Optional app name for OpenRouter
 	appURL  string // Optional app URL for OpenRouter
+	user    string // Optional end-user identifier for spend attribution
+
+	reconcileDelay    time.Duration // Initial wait before looking up generation stats
+	reconcileAttempts int           // Lookups before giving up on a generation
+
+	catalogPath         string        // On-disk model catalog cache ("" = memory only)
+	catalogTTL          time.Duration // How long a fetched catalog stays fresh
+	catalogMu           sync.Mutex
+	catalogModels       []models.Model
+	catalogFetched      time.Time
+	catalogDescriptions map[string]string
 }
 
 // New creates a new OpenRouter provider.
@@ -34,10 +50,12 @@ func New(apiKey string, opts ...Option) *Provider {
 	p := &Provider{
 		apiKey:  apiKey,
 		baseURL: defaultBaseURL,
-		client: &http.Client{
-			Timeout: 120 * time.Second, // Longer timeout for model routing
-		},
+		client:  transport.NewClient(120 * time.Second), // Longer timeout for model routing
 		appName: "bplus",
+
+		reconcileDelay:    500 * time.Millisecond,
+		reconcileAttempts: 5,
+		catalogTTL:        defaultCatalogTTL,
 	}
 
 	for _, opt := range opts {
@@ -72,113 +90,128 @@ func WithAppInfo(name, url string) Option {
 	}
 }
 
+// WithUser sets the end-user identifier sent in the "user" field of every
+// request, which OpenRouter surfaces in its activity and usage reports.
+func WithUser(user string) Option {
+	return func(p *Provider) {
+		p.user = user
+	}
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return "openrouter"
 }
 
-// ListModels returns popular models available via OpenRouter.
+// ListModels returns the models available via OpenRouter, with pricing and
+// context windows from the /models endpoint. The catalog is cached (see
+// WithCatalogCache); if it cannot be fetched, a curated list is returned.
 func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
-	// Return a curated list of popular models
-	// Full list can be fetched from /api/v1/models endpoint
-	return []models.Model{
-		{
-			ID:            "anthropic/claude-3.5-sonnet",
-			Name:          "Claude 3.5 Sonnet",
-			Provider:      "openrouter",
-			ContextWindow: 200000,
-			MaxOutput:     8192,
-			Pricing: models.Pricing{
-				InputTokens:  3.00 / 1000000,
-				OutputTokens: 15.00 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
+	list, err := p.catalog(ctx)
+	if err != nil || len(list) == 0 {
+		return fallbackModels, nil
+	}
+	return list, nil
+}
+
+// fallbackModels is used when the catalog is unavailable.
+var fallbackModels = []models.Model{
+	{
+		ID:            "anthropic/claude-3.5-sonnet",
+		Name:          "Claude 3.5 Sonnet",
+		Provider:      "openrouter",
+		ContextWindow: 200000,
+		MaxOutput:     8192,
+		Pricing: models.Pricing{
+			InputTokens:  3.00 / 1000000,
+			OutputTokens: 15.00 / 1000000,
 		},
-		{
-			ID:            "anthropic/claude-3-opus",
-			Name:          "Claude 3 Opus",
-			Provider:      "openrouter",
-			ContextWindow: 200000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  15.00 / 1000000,
-				OutputTokens: 75.00 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "anthropic/claude-3-opus",
+		Name:          "Claude 3 Opus",
+		Provider:      "openrouter",
+		ContextWindow: 200000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  15.00 / 1000000,
+			OutputTokens: 75.00 / 1000000,
 		},
-		{
-			ID:            "openai/gpt-4-turbo",
-			Name:          "GPT-4 Turbo",
-			Provider:      "openrouter",
-			ContextWindow: 128000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  10.00 / 1000000,
-				OutputTokens: 30.00 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "openai/gpt-4-turbo",
+		Name:          "GPT-4 Turbo",
+		Provider:      "openrouter",
+		ContextWindow: 128000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  10.00 / 1000000,
+			OutputTokens: 30.00 / 1000000,
 		},
-		{
-			ID:            "openai/gpt-4o",
-			Name:          "GPT-4o",
-			Provider:      "openrouter",
-			ContextWindow: 128000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  5.00 / 1000000,
-				OutputTokens: 15.00 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "openai/gpt-4o",
+		Name:          "GPT-4o",
+		Provider:      "openrouter",
+		ContextWindow: 128000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  5.00 / 1000000,
+			OutputTokens: 15.00 / 1000000,
 		},
-		{
-			ID:            "google/gemini-pro-1.5",
-			Name:          "Gemini Pro 1.5",
-			Provider:      "openrouter",
-			ContextWindow: 2097152,
-			MaxOutput:     8192,
-			Pricing: models.Pricing{
-				InputTokens:  1.25 / 1000000,
-				OutputTokens: 5.00 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools", "vision"},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "google/gemini-pro-1.5",
+		Name:          "Gemini Pro 1.5",
+		Provider:      "openrouter",
+		ContextWindow: 2097152,
+		MaxOutput:     8192,
+		Pricing: models.Pricing{
+			InputTokens:  1.25 / 1000000,
+			OutputTokens: 5.00 / 1000000,
 		},
-		{
-			ID:            "meta-llama/llama-3.1-405b-instruct",
-			Name:          "Llama 3.1 405B Instruct",
-			Provider:      "openrouter",
-			ContextWindow: 131072,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  3.00 / 1000000,
-				OutputTokens: 3.00 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools"},
+		Capabilities: []string{"streaming", "tools", "vision"},
+	},
+	{
+		ID:            "meta-llama/llama-3.1-405b-instruct",
+		Name:          "Llama 3.1 405B Instruct",
+		Provider:      "openrouter",
+		ContextWindow: 131072,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  3.00 / 1000000,
+			OutputTokens: 3.00 / 1000000,
 		},
-		{
-			ID:            "meta-llama/llama-3.1-70b-instruct",
-			Name:          "Llama 3.1 70B Instruct",
-			Provider:      "openrouter",
-			ContextWindow: 131072,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  0.52 / 1000000,
-				OutputTokens: 0.75 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools"},
+		Capabilities: []string{"streaming", "tools"},
+	},
+	{
+		ID:            "meta-llama/llama-3.1-70b-instruct",
+		Name:          "Llama 3.1 70B Instruct",
+		Provider:      "openrouter",
+		ContextWindow: 131072,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  0.52 / 1000000,
+			OutputTokens: 0.75 / 1000000,
 		},
-		{
-			ID:            "mistralai/mistral-large",
-			Name:          "Mistral Large",
-			Provider:      "openrouter",
-			ContextWindow: 128000,
-			MaxOutput:     4096,
-			Pricing: models.Pricing{
-				InputTokens:  2.00 / 1000000,
-				OutputTokens: 6.00 / 1000000,
-			},
-			Capabilities: []string{"streaming", "tools"},
+		Capabilities: []string{"streaming", "tools"},
+	},
+	{
+		ID:            "mistralai/mistral-large",
+		Name:          "Mistral Large",
+		Provider:      "openrouter",
+		ContextWindow: 128000,
+		MaxOutput:     4096,
+		Pricing: models.Pricing{
+			InputTokens:  2.00 / 1000000,
+			OutputTokens: 6.00 / 1000000,
 		},
-	}, nil
+		Capabilities: []string{"streaming", "tools"},
+	},
 }
 
 // CreateCompletion creates a non-streaming completion.
@@ -196,6 +229,7 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 	}
 
 	p.setHeaders(httpReq)
+	setSession(httpReq, req)
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -205,12 +239,7 @@ func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionR
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, &models.ProviderError{
-			Provider:  "openrouter",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("openrouter", resp, body)
 	}
 
 	var apiResp chatCompletionResponse
@@ -236,6 +265,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 	}
 
 	p.setHeaders(httpReq)
+	setSession(httpReq, req)
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -245,12 +275,7 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, &models.ProviderError{
-			Provider:  "openrouter",
-			Code:      fmt.Sprintf("HTTP_%d", resp.StatusCode),
-			Message:   string(body),
-			Retryable: resp.StatusCode >= 500 || resp.StatusCode == 429,
-		}
+		return nil, models.NewHTTPError("openrouter", resp, body)
 	}
 
 	tokens := make(chan models.StreamToken, 10)
@@ -259,8 +284,11 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 		defer close(tokens)
 		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := transport.NewLineReader(resp.Body)
 		var totalUsage *models.Usage
+		var stopReason string
+		pending := make(map[int]*toolCall) // Tool calls accumulate across chunks by index
+		var generationID string
 
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -271,11 +299,15 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 
 			data := strings.TrimPrefix(line, "data: ")
 			if data == "[DONE]" {
-				if totalUsage != nil {
-					tokens <- models.StreamToken{
-						Done:  true,
-						Usage: totalUsage,
-					}
+				flushToolCalls(tokens, pending)
+				// Without usage the cost is unknown until reconciled
+				if totalUsage == nil && generationID != "" {
+					totalUsage = &models.Usage{GenerationID: generationID, CostEstimated: true}
+				}
+				tokens <- models.StreamToken{
+					Done:       true,
+					StopReason: stopReason,
+					Usage:      totalUsage,
 				}
 				return
 			}
@@ -285,41 +317,42 @@ func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionR
 				tokens <- models.StreamToken{Error: err}
 				return
 			}
+			if chunk.ID != "" {
+				generationID = chunk.ID
+			}
 
 			if len(chunk.Choices) > 0 {
 				delta := chunk.Choices[0].Delta
+				if chunk.Choices[0].FinishReason != "" {
+					stopReason = models.NormalizeStopReason(chunk.Choices[0].FinishReason)
+				}
 				if delta.Content != "" {
 					tokens <- models.StreamToken{
 						Content: delta.Content,
 					}
 				}
 
-				// Handle tool calls if present
-				if len(delta.ToolCalls) > 0 {
-					for _, tc := range delta.ToolCalls {
-						if tc.Function.Name != "" {
-							var args map[string]interface{}
-							json.Unmarshal([]byte(tc.Function.Arguments), &args)
-							tokens <- models.StreamToken{
-								ToolCall: &models.ToolCall{
-									ID:        tc.ID,
-									Name:      tc.Function.Name,
-									Arguments: args,
-								},
-							}
-						}
+				// Tool call arguments stream as JSON fragments, accumulated by index
+				for _, tc := range delta.ToolCalls {
+					call, ok := pending[tc.Index]
+					if !ok {
+						call = &toolCall{ID: tc.ID, Type: tc.Type}
+						pending[tc.Index] = call
+					}
+					if tc.Function.Name != "" {
+						call.Function.Name = tc.Function.Name
 					}
+					call.Function.Arguments += tc.Function.Arguments
+				}
+				if chunk.Choices[0].FinishReason != "" {
+					flushToolCalls(tokens, pending)
 				}
 			}
 
 			// Track usage (OpenRouter provides this in streaming)
 			if chunk.Usage != nil {
-				totalUsage = &models.Usage{
-					InputTokens:  chunk.Usage.PromptTokens,
-					OutputTokens: chunk.Usage.CompletionTokens,
-					TotalTokens:  chunk.Usage.TotalTokens,
-					Cost:         calculateCostFromUsage(chunk.Usage),
-				}
+				u := convertUsage(generationID, chunk.Usage)
+				totalUsage = &u
 			}
 		}
 
@@ -367,15 +400,19 @@ func (p *Provider) GetModelInfo(ctx context.Context, modelID string) (*models.Mo
 
 	for _, model := range allModels {
 		if model.ID == modelID {
+			description := p.catalogDescription(modelID)
+			if description == "" {
+				description = "Model available via OpenRouter"
+			}
 			return &models.ModelInfo{
 				Model:       model,
-				Description: "Model available via OpenRouter",
+				Description: description,
 				Available:   true,
 			}, nil
 		}
 	}
 
-	// Model not in our curated list, but might still be available
+	// Model not in the catalog, but might still be available
 	return &models.ModelInfo{
 		Model: models.Model{
 			ID:       modelID,
@@ -411,11 +448,21 @@ func (p *Provider) setHeaders(req *http.Request) {
 	}
 }
 
+// setSession tags a completion with the session it belongs to, so the
+// session's generations can be grouped in OpenRouter's activity view.
+func setSession(httpReq *http.Request, req *models.CompletionRequest) {
+	if session := req.Metadata[models.MetadataSession]; session != "" {
+		httpReq.Header.Set("X-Session-Id", session)
+	}
+}
+
 func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *chatCompletionRequest {
 	apiReq := &chatCompletionRequest{
 		Model:    req.Model,
 		Stream:   stream,
 		Messages: make([]chatMessage, 0, len(req.Messages)+1),
+		User:     models.EndUser(req, p.user),
+		Usage:    &usageOptions{Include: true}, // Ask for the billed cost in usage
 	}
 
 	// Add system message if present
@@ -431,6 +478,7 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 		apiReq.Messages = append(apiReq.Messages, chatMessage{
 			Role:    msg.Role,
 			Content: msg.Content,
+			Parts:   contentParts(msg),
 		})
 	}
 
@@ -452,6 +500,7 @@ func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) *c
 
 	// Add tools if present
 	if len(req.Tools) > 0 {
+		apiReq.Parallel = &req.ParallelToolCalls
 		apiReq.Tools = make([]tool, len(req.Tools))
 		for i, t := range req.Tools {
 			apiReq.Tools[i] = tool{
@@ -482,13 +531,7 @@ func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.Comp
 		if len(choice.Message.ToolCalls) > 0 {
 			resp.ToolCalls = make([]models.ToolCall, len(choice.Message.ToolCalls))
 			for i, tc := range choice.Message.ToolCalls {
-				var args map[string]interface{}
-				json.Unmarshal([]byte(tc.Function.Arguments), &args)
-				resp.ToolCalls[i] = models.ToolCall{
-					ID:        tc.ID,
-					Name:      tc.Function.Name,
-					Arguments: args,
-				}
+				resp.ToolCalls[i] = convertToolCall(tc)
 			}
 			resp.StopReason = "tool_use"
 		}
@@ -496,17 +539,39 @@ func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.Comp
 
 	// Usage
 	if apiResp.Usage != nil {
-		resp.Usage = models.Usage{
-			InputTokens:  apiResp.Usage.PromptTokens,
-			OutputTokens: apiResp.Usage.CompletionTokens,
-			TotalTokens:  apiResp.Usage.TotalTokens,
-			Cost:         calculateCostFromUsage(apiResp.Usage),
-		}
+		resp.Usage = convertUsage(apiResp.ID, apiResp.Usage)
+	} else if apiResp.ID != "" {
+		resp.Usage = models.Usage{GenerationID: apiResp.ID, CostEstimated: true}
 	}
 
 	return resp
 }
 
+// flushToolCalls emits accumulated streaming tool calls in index order.
+func flushToolCalls(tokens chan<- models.StreamToken, pending map[int]*toolCall) {
+	indexes := make([]int, 0, len(pending))
+	for i := range pending {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		call := convertToolCall(*pending[i])
+		tokens <- models.StreamToken{ToolCall: &call}
+		delete(pending, i)
+	}
+}
+
+func convertToolCall(tc toolCall) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return models.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Function.Name,
+		Arguments: args,
+	}
+}
+
 func convertToolParams(params []models.Parameter) map[string]interface{} {
 	properties := make(map[string]interface{})
 	required := []string{}
@@ -528,18 +593,91 @@ func convertToolParams(params []models.Parameter) map[string]interface{} {
 	}
 }
 
-func calculateCostFromUsage(usage *usage) float64 {
-	// OpenRouter provides generation_cost in the usage metadata
-	// If not available, we'll calculate based on tokens
+// convertUsage converts API usage, marking the cost as estimated when
+// OpenRouter did not report it.
+func convertUsage(generationID string, u *usage) models.Usage {
+	cost, estimated := calculateCostFromUsage(u)
+	return models.Usage{
+		InputTokens:   u.PromptTokens,
+		OutputTokens:  u.CompletionTokens,
+		TotalTokens:   u.TotalTokens,
+		Cost:          cost,
+		CostEstimated: estimated,
+		GenerationID:  generationID,
+	}
+}
+
+// calculateCostFromUsage returns the reported cost, or an estimate based on
+// average pricing (and estimated=true) when the response carries none.
+func calculateCostFromUsage(usage *usage) (cost float64, estimated bool) {
+	// OpenRouter reports the billed cost when usage accounting is requested;
+	// older responses use generation_cost
+	if usage.Cost > 0 {
+		return usage.Cost, false
+	}
 	if usage.GenerationCost > 0 {
-		return usage.GenerationCost
+		return usage.GenerationCost, false
 	}
 
 	// Fallback: estimate based on average pricing
-	inputCost := 2.00 / 1000000  // Average input cost
-	outputCost := 6.00 / 1000000 // Average output cost
+	return pricing.Lookup("openrouter", "").Cost(usage.PromptTokens, usage.CompletionTokens), true
+}
+
+// ReconcileCost looks up the billed cost of a generation. Generation stats
+// become available shortly after a call completes, so lookups that find
+// nothing are retried with backoff.
+func (p *Provider) ReconcileCost(ctx context.Context, generationID string) (float64, error) {
+	delay := p.reconcileDelay
+	var lastErr error
+
+	for attempt := 0; attempt < p.reconcileAttempts; attempt++ {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
 
-	return (float64(usage.PromptTokens) * inputCost) + (float64(usage.CompletionTokens) * outputCost)
+		gen, err := p.getGeneration(ctx, generationID)
+		if err == nil {
+			return gen.TotalCost, nil
+		}
+		lastErr = err
+
+		var provErr *models.ProviderError
+		if errors.As(err, &provErr) && provErr.Code != "HTTP_404" && !provErr.IsRetryable() {
+			return 0, err
+		}
+	}
+
+	return 0, fmt.Errorf("generation %s not available: %w", generationID, lastErr)
+}
+
+// getGeneration fetches the stats of a completed generation.
+func (p *Provider) getGeneration(ctx context.Context, generationID string) (*generation, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/generation?id="+url.QueryEscape(generationID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, models.NewHTTPError("openrouter", resp, body)
+	}
+
+	var genResp generationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
+		return nil, fmt.Errorf("failed to decode generation: %w", err)
+	}
+	return &genResp.Data, nil
 }
 
 // API types
@@ -553,12 +691,60 @@ type chatCompletionRequest struct {
 	Stop        []string      `json:"stop,omitempty"`
 	Stream      bool          `json:"stream,omitempty"`
 	Tools       []tool        `json:"tools,omitempty"`
+	Parallel    *bool         `json:"parallel_tool_calls,omitempty"` // Sent with Tools
+	User        string        `json:"user,omitempty"`
+	Usage       *usageOptions `json:"usage,omitempty"`
+}
+
+type usageOptions struct {
+	Include bool `json:"include"`
 }
 
 type chatMessage struct {
 	Role      string     `json:"role"`
 	Content   string     `json:"content"`
 	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+
+	// Parts replaces Content when sending a message with images
+	Parts []contentPart `json:"-"`
+}
+
+// MarshalJSON sends Parts as the content when set.
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type plain chatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+type contentPart struct {
+	Type     string    `json:"type"` // "text" or "image_url"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// contentParts returns the content parts of a message with images, or nil
+// for a text-only message.
+func contentParts(msg models.Message) []contentPart {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+	parts := make([]contentPart, 0, len(msg.Attachments)+1)
+	if msg.Content != "" {
+		parts = append(parts, contentPart{Type: "text", Text: msg.Content})
+	}
+	for _, a := range msg.Attachments {
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: a.DataURL()}})
+	}
+	return parts
 }
 
 type tool struct {
@@ -573,6 +759,7 @@ type functionDef struct {
 }
 
 type toolCall struct {
+	Index    int          `json:"index"`
 	ID       string       `json:"id"`
 	Type     string       `json:"type"`
 	Function toolCallFunc `json:"function"`
@@ -600,9 +787,22 @@ type usage struct {
 	PromptTokens     int     `json:"prompt_tokens"`
 	CompletionTokens int     `json:"completion_tokens"`
 	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"`            // Billed cost with usage accounting
 	GenerationCost   float64 `json:"generation_cost,omitempty"` // OpenRouter-specific
 }
 
+type generationResponse struct {
+	Data generation `json:"data"`
+}
+
+type generation struct {
+	ID                     string  `json:"id"`
+	Model                  string  `json:"model"`
+	TotalCost              float64 `json:"total_cost"`
+	NativeTokensPrompt     int     `json:"native_tokens_prompt"`
+	NativeTokensCompletion int     `json:"native_tokens_completion"`
+}
+
 type chatCompletionChunk struct {
 	ID      string        `json:"id"`
 	Model   string        `json:"model"`
@@ -0,0 +1,264 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_StreamCompletion_EstimatedCost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotNil(t, req.Usage)
+		assert.True(t, req.Usage.Include)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"gen-1\",\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "anthropic/claude-sonnet-4.5",
+		Messages: []models.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var final *models.StreamToken
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		if tok.Done {
+			tok := tok
+			final = &tok
+		}
+	}
+
+	require.NotNil(t, final, "stream should end with a done token even without usage")
+	require.NotNil(t, final.Usage)
+	assert.True(t, final.Usage.CostEstimated)
+	assert.Equal(t, "gen-1", final.Usage.GenerationID)
+}
+
+func TestConvertUsage(t *testing.T) {
+	u := convertUsage("gen-1", &usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110, Cost: 0.0042})
+	assert.False(t, u.CostEstimated)
+	assert.Equal(t, 0.0042, u.Cost)
+
+	u = convertUsage("gen-2", &usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110})
+	assert.True(t, u.CostEstimated, "average-price fallback is an estimate")
+	assert.Greater(t, u.Cost, 0.0)
+}
+
+func TestProvider_ReconcileCost(t *testing.T) {
+	var lookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/generation", r.URL.Path)
+		assert.Equal(t, "gen-1", r.URL.Query().Get("id"))
+
+		// Stats are not available on the first lookup
+		if lookups.Add(1) == 1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"id": "gen-1", "total_cost": 0.0123},
+		})
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	p.reconcileDelay = time.Millisecond
+
+	reconciler, ok := models.AsCostReconciler(models.WithStreamMetrics(p, nil))
+	require.True(t, ok, "reconciler is found through wrapping providers")
+
+	cost, err := reconciler.ReconcileCost(context.Background(), "gen-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0123, cost)
+	assert.Equal(t, int32(2), lookups.Load())
+}
+
+func TestProvider_ReconcileCost_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	p.reconcileDelay = time.Millisecond
+
+	_, err := p.ReconcileCost(context.Background(), "gen-1")
+	var provErr *models.ProviderError
+	require.ErrorAs(t, err, &provErr)
+	assert.Equal(t, "HTTP_401", provErr.Code)
+}
+
+func TestProvider_ListModels_Catalog(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		atomic.AddInt32(&fetches, 1)
+		fmt.Fprint(w, `{"data":[{
+			"id":"acme/new-model",
+			"name":"Acme: New Model",
+			"description":"A brand new model",
+			"created":1700000000,
+			"context_length":65536,
+			"pricing":{"prompt":"0.000001","completion":"0.000004","request":"0"},
+			"top_provider":{"context_length":65536,"max_completion_tokens":8192},
+			"architecture":{"input_modalities":["text","image"]},
+			"supported_parameters":["temperature","tools"]
+		},{
+			"id":"openrouter/auto",
+			"name":"Auto Router",
+			"context_length":2000000,
+			"pricing":{"prompt":"-1","completion":"-1"}
+		}]}`)
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "models.json")
+	p := New("test-key", WithBaseURL(server.URL), WithCatalogCache(cachePath, time.Hour))
+
+	list, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	m := list[0]
+	assert.Equal(t, "acme/new-model", m.ID)
+	assert.Equal(t, 65536, m.ContextWindow)
+	assert.Equal(t, 8192, m.MaxOutput)
+	assert.InDelta(t, 1e-6, m.Pricing.InputTokens, 1e-15)
+	assert.InDelta(t, 4e-6, m.Pricing.OutputTokens, 1e-15)
+	assert.ElementsMatch(t, []string{"streaming", "tools", "vision"}, m.Capabilities)
+	assert.Zero(t, list[1].Pricing.InputTokens, "variable pricing is unknown")
+
+	info, err := p.GetModelInfo(context.Background(), "acme/new-model")
+	require.NoError(t, err)
+	assert.Equal(t, "A brand new model", info.Description)
+
+	// A second provider reads the fresh on-disk cache instead of fetching
+	p2 := New("test-key", WithBaseURL(server.URL), WithCatalogCache(cachePath, time.Hour))
+	list, err = p2.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestProvider_ListModels_Fallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	list, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, fallbackModels, list)
+
+	// A stale cache is preferred over the curated list
+	cachePath := filepath.Join(t.TempDir(), "models.json")
+	stale := `{"fetched_at":"2020-01-01T00:00:00Z","models":[{"id":"acme/old","name":"Old","context_length":4096,"pricing":{"prompt":"0","completion":"0"}}]}`
+	require.NoError(t, os.WriteFile(cachePath, []byte(stale), 0644))
+
+	p = New("test-key", WithBaseURL(server.URL), WithCatalogCache(cachePath, time.Hour))
+	list, err = p.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "acme/old", list[0].ID)
+}
+
+func TestConvertRequest_ImageParts(t *testing.T) {
+	p := New("test-key")
+	req := p.convertRequest(&models.CompletionRequest{
+		Model: "anthropic/claude-sonnet-4-5",
+		Messages: []models.Message{{Role: "user", Content: "Explain", Attachments: []models.Attachment{
+			{Data: []byte("img"), MimeType: "image/jpeg"},
+		}}},
+	}, false)
+
+	body, err := json.Marshal(req.Messages)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"role":"user","content":[
+		{"type":"text","text":"Explain"},
+		{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,aW1n"}}
+	]}]`, string(body))
+}
+
+func TestProvider_StreamCompletion_ToolCallFragments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"pa"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"glob","arguments":"{\"pattern\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"a.go\"}"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":1,"function":{"arguments":"\"*.go\"}"}}]}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "anthropic/claude-sonnet-4.5",
+		Messages: []models.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var calls []*models.ToolCall
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		if tok.ToolCall != nil {
+			calls = append(calls, tok.ToolCall)
+		}
+	}
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.Equal(t, "read", calls[0].Name)
+	assert.Equal(t, "a.go", calls[0].Arguments["path"])
+	assert.Equal(t, "call_2", calls[1].ID)
+	assert.Equal(t, "glob", calls[1].Name)
+	assert.Equal(t, "*.go", calls[1].Arguments["pattern"])
+}
+
+func TestProvider_CreateCompletion_Attribution(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sess-1", r.Header.Get("X-Session-Id"))
+		var req struct {
+			User string `json:"user"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "alice", req.User)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"gen-1","model":"openai/gpt-4o","choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	p := New("test-key", WithBaseURL(server.URL), WithUser("acme"))
+	_, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "openai/gpt-4o",
+		Messages: []models.Message{{Role: "user", Content: "Hi"}},
+		Metadata: map[string]string{models.MetadataUser: "alice", models.MetadataSession: "sess-1"},
+	})
+	require.NoError(t, err)
+}
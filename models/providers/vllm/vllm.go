@@ -0,0 +1,687 @@
+// Package vllm provides vLLM server integration.
+// vLLM serves an OpenAI-compatible API; on top of that this provider
+// discovers the served models (including LoRA adapters), passes guided
+// decoding parameters, and reads the server's Prometheus metrics.
+package vllm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/transport"
+)
+
+const (
+	defaultBaseURL = "http://localhost:8000/v1"
+)
+
+// Guided decoding parameters are passed per request in
+// CompletionRequest.Metadata under these keys.
+const (
+	MetadataGuidedJSON    = "vllm.guided_json"    // JSON schema the output must match
+	MetadataGuidedRegex   = "vllm.guided_regex"   // Regular expression the output must match
+	MetadataGuidedChoice  = "vllm.guided_choice"  // JSON array of allowed outputs
+	MetadataGuidedGrammar = "vllm.guided_grammar" // Context-free grammar (EBNF)
+	MetadataGuidedBackend = "vllm.guided_backend" // Decoding backend, e.g. "xgrammar" or "outlines"
+)
+
+// Provider implements the vLLM API provider.
+type Provider struct {
+	apiKey  string // Optional; set when the server runs with --api-key
+	baseURL string
+	client  *http.Client
+}
+
+// New creates a new vLLM provider.
+func New(opts ...Option) *Provider {
+	p := &Provider{
+		baseURL: defaultBaseURL,
+		client:  transport.NewClient(300 * time.Second), // Long timeout for self-hosted models
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Option is a functional option for configuring the provider.
+type Option func(*Provider)
+
+// WithBaseURL sets a custom base URL, including the /v1 suffix.
+func WithBaseURL(baseURL string) Option {
+	return func(p *Provider) {
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithAPIKey sets the API key the server was started with.
+func WithAPIKey(apiKey string) Option {
+	return func(p *Provider) {
+		p.apiKey = apiKey
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "vllm"
+}
+
+// ListModels returns the models served by the vLLM server, including
+// LoRA adapters, with their configured context length.
+func (p *Provider) ListModels(ctx context.Context) ([]models.Model, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list models: %s", string(body))
+	}
+
+	var apiResp listModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result := make([]models.Model, 0, len(apiResp.Data))
+	for _, m := range apiResp.Data {
+		contextWindow := m.MaxModelLen
+		if contextWindow == 0 {
+			contextWindow = 4096 // Conservative default for older servers
+		}
+
+		name := m.ID
+		if m.Parent != "" {
+			name = fmt.Sprintf("%s (LoRA on %s)", m.ID, m.Parent)
+		}
+
+		result = append(result, models.Model{
+			ID:            m.ID,
+			Name:          name,
+			Provider:      "vllm",
+			ContextWindow: contextWindow,
+			MaxOutput:     contextWindow,
+			Pricing: models.Pricing{
+				InputTokens:  0, // Self-hosted models are free
+				OutputTokens: 0,
+			},
+			Capabilities: []string{"streaming", "tools", "guided_decoding"},
+			CreatedAt:    time.Unix(m.Created, 0),
+		})
+	}
+
+	return result, nil
+}
+
+// CreateCompletion creates a non-streaming completion.
+func (p *Provider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	apiReq, err := p.convertRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.doRequest(ctx, apiReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var apiResp chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return p.convertResponse(&apiResp), nil
+}
+
+// StreamCompletion creates a streaming completion.
+func (p *Provider) StreamCompletion(ctx context.Context, req *models.CompletionRequest) (<-chan models.StreamToken, error) {
+	apiReq, err := p.convertRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.doRequest(ctx, apiReq)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(chan models.StreamToken, 10)
+
+	go func() {
+		defer close(tokens)
+		defer resp.Body.Close()
+
+		scanner := transport.NewLineReader(resp.Body)
+
+		var totalUsage *models.Usage
+		var stopReason string
+		pending := make(map[int]*toolCall) // Tool calls accumulate across chunks by index
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			data := strings.TrimPrefix(line, "data: ")
+			if data == "[DONE]" {
+				flushToolCalls(tokens, pending)
+				tokens <- models.StreamToken{
+					Done:       true,
+					StopReason: stopReason,
+					Usage:      totalUsage,
+				}
+				return
+			}
+
+			var chunk chatCompletionChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				tokens <- models.StreamToken{Error: err}
+				return
+			}
+
+			if len(chunk.Choices) > 0 {
+				delta := chunk.Choices[0].Delta
+				if delta.ReasoningContent != "" {
+					tokens <- models.StreamToken{Reasoning: delta.ReasoningContent}
+				}
+				if delta.Content != "" {
+					tokens <- models.StreamToken{Content: delta.Content}
+				}
+
+				for _, tc := range delta.ToolCalls {
+					call, ok := pending[tc.Index]
+					if !ok {
+						call = &toolCall{ID: tc.ID, Type: tc.Type}
+						pending[tc.Index] = call
+					}
+					if tc.Function.Name != "" {
+						call.Function.Name = tc.Function.Name
+					}
+					call.Function.Arguments += tc.Function.Arguments
+				}
+
+				if chunk.Choices[0].FinishReason != "" {
+					stopReason = models.NormalizeStopReason(chunk.Choices[0].FinishReason)
+					flushToolCalls(tokens, pending)
+				}
+			}
+
+			if chunk.Usage != nil {
+				totalUsage = &models.Usage{
+					InputTokens:  chunk.Usage.PromptTokens,
+					OutputTokens: chunk.Usage.CompletionTokens,
+					TotalTokens:  chunk.Usage.TotalTokens,
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			tokens <- models.StreamToken{Error: err}
+		}
+	}()
+
+	return tokens, nil
+}
+
+// TestConnection checks that the server is up using its health endpoint.
+func (p *Provider) TestConnection(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.serverURL()+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("vLLM not running or not accessible at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vLLM returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GetModelInfo returns information about a specific model.
+func (p *Provider) GetModelInfo(ctx context.Context, modelID string) (*models.ModelInfo, error) {
+	allModels, err := p.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range allModels {
+		if model.ID == modelID {
+			return &models.ModelInfo{
+				Model:       model,
+				Description: "Model served by vLLM",
+				Available:   true,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("model %s not found", modelID)
+}
+
+// SupportsStreaming returns true.
+func (p *Provider) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsTools returns true. The server must be started with
+// --enable-auto-tool-choice and a tool call parser for tools to work.
+func (p *Provider) SupportsTools() bool {
+	return true
+}
+
+// Metrics reads the server's Prometheus metrics endpoint. Values are summed
+// across all served models. Connection tests report them in /providers.
+func (p *Provider) Metrics(ctx context.Context) (*models.ServerLoad, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.serverURL()+"/metrics", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vLLM metrics returned status %d", resp.StatusCode)
+	}
+
+	values, err := parsePrometheus(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	metrics := &models.ServerLoad{
+		RequestsRunning: int(values["vllm:num_requests_running"]),
+		RequestsWaiting: int(values["vllm:num_requests_waiting"]),
+		KVCacheUsage:    values["vllm:kv_cache_usage_perc"],
+	}
+	// Older vLLM versions report KV cache usage under the GPU-specific name
+	if _, ok := values["vllm:kv_cache_usage_perc"]; !ok {
+		metrics.KVCacheUsage = values["vllm:gpu_cache_usage_perc"]
+	}
+	return metrics, nil
+}
+
+// Helper methods
+
+func (p *Provider) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+}
+
+// serverURL returns the server root, where /health and /metrics are served.
+func (p *Provider) serverURL() string {
+	return strings.TrimSuffix(p.baseURL, "/v1")
+}
+
+// doRequest sends a chat completion request and returns the response on HTTP 200.
+func (p *Provider) doRequest(ctx context.Context, apiReq *chatCompletionRequest) (*http.Response, error) {
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, models.NewHTTPError("vllm", resp, body)
+	}
+
+	return resp, nil
+}
+
+func (p *Provider) convertRequest(req *models.CompletionRequest, stream bool) (*chatCompletionRequest, error) {
+	apiReq := &chatCompletionRequest{
+		Model:            req.Model,
+		Stream:           stream,
+		Messages:         make([]chatMessage, 0, len(req.Messages)+1),
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		TopK:             req.TopK,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		Stop:             req.StopSequences,
+	}
+	if stream {
+		apiReq.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	if req.System != "" {
+		apiReq.Messages = append(apiReq.Messages, chatMessage{
+			Role:    "system",
+			Content: req.System,
+		})
+	}
+
+	for _, msg := range req.Messages {
+		apiReq.Messages = append(apiReq.Messages, chatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+
+	if len(req.Tools) > 0 {
+		apiReq.Parallel = &req.ParallelToolCalls
+		apiReq.Tools = make([]tool, len(req.Tools))
+		for i, t := range req.Tools {
+			apiReq.Tools[i] = tool{
+				Type: "function",
+				Function: functionDef{
+					Name:        t.Name,
+					Description: t.Description,
+					Parameters:  convertToolParams(t.Parameters),
+				},
+			}
+		}
+	}
+
+	if err := applyGuidedDecoding(apiReq, req.Metadata); err != nil {
+		return nil, err
+	}
+
+	return apiReq, nil
+}
+
+// applyGuidedDecoding copies guided decoding parameters from request metadata.
+func applyGuidedDecoding(apiReq *chatCompletionRequest, metadata map[string]string) error {
+	if schema := metadata[MetadataGuidedJSON]; schema != "" {
+		if !json.Valid([]byte(schema)) {
+			return fmt.Errorf("invalid %s: not valid JSON", MetadataGuidedJSON)
+		}
+		apiReq.GuidedJSON = json.RawMessage(schema)
+	}
+	if choice := metadata[MetadataGuidedChoice]; choice != "" {
+		if err := json.Unmarshal([]byte(choice), &apiReq.GuidedChoice); err != nil {
+			return fmt.Errorf("invalid %s: %w", MetadataGuidedChoice, err)
+		}
+	}
+	apiReq.GuidedRegex = metadata[MetadataGuidedRegex]
+	apiReq.GuidedGrammar = metadata[MetadataGuidedGrammar]
+	apiReq.GuidedDecodingBackend = metadata[MetadataGuidedBackend]
+	return nil
+}
+
+func (p *Provider) convertResponse(apiResp *chatCompletionResponse) *models.CompletionResponse {
+	resp := &models.CompletionResponse{
+		Model: apiResp.Model,
+	}
+
+	if len(apiResp.Choices) > 0 {
+		choice := apiResp.Choices[0]
+		resp.Content = choice.Message.Content
+		resp.Reasoning = choice.Message.ReasoningContent
+		resp.StopReason = models.NormalizeStopReason(choice.FinishReason)
+
+		if len(choice.Message.ToolCalls) > 0 {
+			resp.ToolCalls = make([]models.ToolCall, len(choice.Message.ToolCalls))
+			for i, tc := range choice.Message.ToolCalls {
+				resp.ToolCalls[i] = convertToolCall(tc)
+			}
+			resp.StopReason = "tool_use"
+		}
+	}
+
+	if apiResp.Usage != nil {
+		resp.Usage = models.Usage{
+			InputTokens:  apiResp.Usage.PromptTokens,
+			OutputTokens: apiResp.Usage.CompletionTokens,
+			TotalTokens:  apiResp.Usage.TotalTokens,
+		}
+	}
+
+	return resp
+}
+
+// flushToolCalls emits accumulated streaming tool calls in index order.
+func flushToolCalls(tokens chan<- models.StreamToken, pending map[int]*toolCall) {
+	indexes := make([]int, 0, len(pending))
+	for i := range pending {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		call := convertToolCall(*pending[i])
+		tokens <- models.StreamToken{ToolCall: &call}
+		delete(pending, i)
+	}
+}
+
+func convertToolCall(tc toolCall) models.ToolCall {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return models.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Function.Name,
+		Arguments: args,
+	}
+}
+
+func convertToolParams(params []models.Parameter) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for _, p := range params {
+		properties[p.Name] = map[string]interface{}{
+			"type":        p.Type,
+			"description": p.Description,
+		}
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// parsePrometheus parses Prometheus text exposition, summing samples of the
+// same metric across label sets.
+func parsePrometheus(r io.Reader) (map[string]float64, error) {
+	values := make(map[string]float64)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// name{labels} value [timestamp]
+		name := line
+		rest := ""
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name = line[:i]
+			rest = line[i:]
+		}
+		if strings.HasPrefix(rest, "{") {
+			end := strings.LastIndex(rest, "}")
+			if end < 0 {
+				continue
+			}
+			rest = rest[end+1:]
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		values[name] += value
+	}
+
+	return values, scanner.Err()
+}
+
+// API types
+
+type chatCompletionRequest struct {
+	Model            string         `json:"model"`
+	Messages         []chatMessage  `json:"messages"`
+	MaxTokens        int            `json:"max_tokens,omitempty"`
+	Temperature      *float64       `json:"temperature,omitempty"`
+	TopP             *float64       `json:"top_p,omitempty"`
+	TopK             *int           `json:"top_k,omitempty"`
+	FrequencyPenalty *float64       `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64       `json:"presence_penalty,omitempty"`
+	Stop             []string       `json:"stop,omitempty"`
+	Stream           bool           `json:"stream,omitempty"`
+	StreamOptions    *streamOptions `json:"stream_options,omitempty"`
+	Tools            []tool         `json:"tools,omitempty"`
+	Parallel         *bool          `json:"parallel_tool_calls,omitempty"` // Sent with Tools
+
+	// vLLM guided decoding extensions
+	GuidedJSON            json.RawMessage `json:"guided_json,omitempty"`
+	GuidedRegex           string          `json:"guided_regex,omitempty"`
+	GuidedChoice          []string        `json:"guided_choice,omitempty"`
+	GuidedGrammar         string          `json:"guided_grammar,omitempty"`
+	GuidedDecodingBackend string          `json:"guided_decoding_backend,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role             string     `json:"role"`
+	Content          string     `json:"content"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []toolCall `json:"tool_calls,omitempty"`
+}
+
+type tool struct {
+	Type     string      `json:"type"`
+	Function functionDef `json:"function"`
+}
+
+type functionDef struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type toolCall struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function toolCallFunc `json:"function"`
+}
+
+type toolCallFunc struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatCompletionResponse struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   *usage   `json:"usage"`
+}
+
+type choice struct {
+	Index        int         `json:"index"`
+	Message      chatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type chatCompletionChunk struct {
+	ID      string        `json:"id"`
+	Model   string        `json:"model"`
+	Choices []choiceDelta `json:"choices"`
+	Usage   *usage        `json:"usage"`
+}
+
+type choiceDelta struct {
+	Index        int          `json:"index"`
+	Delta        messageDelta `json:"delta"`
+	FinishReason string       `json:"finish_reason"`
+}
+
+type messageDelta struct {
+	Role             string     `json:"role,omitempty"`
+	Content          string     `json:"content,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []toolCall `json:"tool_calls,omitempty"`
+}
+
+type listModelsResponse struct {
+	Data []modelData `json:"data"`
+}
+
+type modelData struct {
+	ID          string `json:"id"`
+	Object      string `json:"object"`
+	Created     int64  `json:"created"`
+	OwnedBy     string `json:"owned_by"`
+	Root        string `json:"root"`
+	Parent      string `json:"parent,omitempty"`
+	MaxModelLen int    `json:"max_model_len"`
+}
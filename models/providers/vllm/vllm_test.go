@@ -0,0 +1,142 @@
+package vllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	p := New()
+	assert.Equal(t, "vllm", p.Name())
+	assert.Equal(t, defaultBaseURL, p.baseURL)
+	assert.Equal(t, "http://localhost:8000", p.serverURL())
+
+	p = New(WithBaseURL("http://gpu-box:9000/v1/"), WithAPIKey("secret"))
+	assert.Equal(t, "http://gpu-box:9000/v1", p.baseURL)
+	assert.Equal(t, "secret", p.apiKey)
+}
+
+func TestProvider_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "Qwen/Qwen2.5-Coder-32B-Instruct", "owned_by": "vllm", "max_model_len": 32768},
+				{"id": "sql-lora", "owned_by": "vllm", "parent": "Qwen/Qwen2.5-Coder-32B-Instruct"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL+"/v1"), WithAPIKey("secret"))
+	list, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, 32768, list[0].ContextWindow)
+	assert.Equal(t, 4096, list[1].ContextWindow)
+	assert.Contains(t, list[1].Name, "LoRA")
+}
+
+func TestProvider_GuidedDecoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{"type": "object"}, req["guided_json"])
+		assert.Equal(t, []interface{}{"yes", "no"}, req["guided_choice"])
+		assert.Equal(t, "xgrammar", req["guided_decoding_backend"])
+		assert.Nil(t, req["guided_regex"])
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   "qwen",
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "yes"}, "finish_reason": "stop"}},
+			"usage":   map[string]interface{}{"prompt_tokens": 5, "completion_tokens": 1, "total_tokens": 6},
+		})
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL + "/v1"))
+	resp, err := p.CreateCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "qwen",
+		Messages: []models.Message{{Role: "user", Content: "Is Go compiled?"}},
+		Metadata: map[string]string{
+			MetadataGuidedJSON:    `{"type":"object"}`,
+			MetadataGuidedChoice:  `["yes","no"]`,
+			MetadataGuidedBackend: "xgrammar",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "yes", resp.Content)
+	assert.Equal(t, "end_turn", resp.StopReason)
+	assert.Equal(t, 6, resp.Usage.TotalTokens)
+
+	_, err = p.CreateCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "qwen",
+		Metadata: map[string]string{MetadataGuidedJSON: "{not json"},
+	})
+	assert.Error(t, err)
+}
+
+func TestProvider_StreamCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"},"finish_reason":"length"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL + "/v1"))
+	stream, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{Model: "qwen"})
+	require.NoError(t, err)
+
+	var content string
+	var final models.StreamToken
+	for tok := range stream {
+		require.NoError(t, tok.Error)
+		content += tok.Content
+		if tok.Done {
+			final = tok
+		}
+	}
+	assert.Equal(t, "Hello", content)
+	assert.Equal(t, "max_tokens", final.StopReason)
+	require.NotNil(t, final.Usage)
+	assert.Equal(t, 5, final.Usage.TotalTokens)
+}
+
+func TestProvider_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metrics", r.URL.Path)
+		fmt.Fprint(w, `# HELP vllm:num_requests_running Number of requests running.
+# TYPE vllm:num_requests_running gauge
+vllm:num_requests_running{model_name="qwen"} 3.0
+vllm:num_requests_running{model_name="sql-lora"} 1.0
+vllm:num_requests_waiting{model_name="qwen"} 7.0
+vllm:gpu_cache_usage_perc{model_name="qwen"} 0.42
+`)
+	}))
+	defer server.Close()
+
+	p := New(WithBaseURL(server.URL + "/v1"))
+	var _ models.LoadReporter = p
+	metrics, err := p.Metrics(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, metrics.RequestsRunning)
+	assert.Equal(t, 7, metrics.RequestsWaiting)
+	assert.InDelta(t, 0.42, metrics.KVCacheUsage, 1e-9, "falls back to the older metric name")
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"regexp"
+	"sync"
+)
+
+// RedactionPlaceholder replaces redacted content.
+const RedactionPlaceholder = "[redacted]"
+
+// Redactor holds the patterns scrubbed from every prompt, such as a secret
+// that was pasted into the conversation by mistake. A nil *Redactor
+// redacts nothing.
+type Redactor struct {
+	mu       sync.RWMutex
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor without patterns.
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// Add scrubs matches of re from every later prompt.
+func (r *Redactor) Add(re *regexp.Regexp) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, re)
+}
+
+// Redact replaces every match of the patterns in s with RedactionPlaceholder
+// and reports whether anything was replaced.
+func (r *Redactor) Redact(s string) (string, bool) {
+	if r == nil {
+		return s, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	changed := false
+	for _, re := range r.patterns {
+		if re.MatchString(s) {
+			s = re.ReplaceAllLiteralString(s, RedactionPlaceholder)
+			changed = true
+		}
+	}
+	return s, changed
+}
+
+// RedactRequest returns req with the patterns scrubbed from its system
+// prompt, messages and tool call arguments. req itself is left as it is.
+func (r *Redactor) RedactRequest(req *CompletionRequest) *CompletionRequest {
+	if r == nil {
+		return req
+	}
+	out := *req
+	out.System, _ = r.Redact(req.System)
+	out.Messages = make([]Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		out.Messages[i], _ = r.RedactMessage(msg)
+	}
+	return &out
+}
+
+// RedactMessage returns msg with the patterns scrubbed from its content,
+// reasoning and tool call arguments, and whether anything was scrubbed.
+// Reasoning that changes loses its signature, which no longer matches it.
+func (r *Redactor) RedactMessage(msg Message) (Message, bool) {
+	content, changed := r.Redact(msg.Content)
+	msg.Content = content
+	if reasoning, ok := r.Redact(msg.Reasoning); ok {
+		msg.Reasoning = reasoning
+		msg.ReasoningSignature = ""
+		changed = true
+	}
+	if len(msg.ToolCalls) > 0 {
+		calls := make([]ToolCall, len(msg.ToolCalls))
+		for i, call := range msg.ToolCalls {
+			args, ok := r.redactValue(call.Arguments)
+			call.Arguments = args.(map[string]interface{})
+			changed = changed || ok
+			calls[i] = call
+		}
+		msg.ToolCalls = calls
+	}
+	return msg, changed
+}
+
+// redactValue scrubs the strings in a decoded JSON value.
+func (r *Redactor) redactValue(v interface{}) (interface{}, bool) {
+	changed := false
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case map[string]interface{}:
+		if v == nil {
+			return v, false
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			var ok bool
+			out[k], ok = r.redactValue(e)
+			changed = changed || ok
+		}
+		return out, changed
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			var ok bool
+			out[i], ok = r.redactValue(e)
+			changed = changed || ok
+		}
+		return out, changed
+	default:
+		return v, false
+	}
+}
+
+// WithRedaction wraps a provider so the patterns of r are scrubbed from
+// every request before it is sent.
+func WithRedaction(p Provider, r *Redactor) Provider {
+	return &redactingProvider{Provider: p, redactor: r}
+}
+
+type redactingProvider struct {
+	Provider
+	redactor *Redactor
+}
+
+// Unwrap returns the wrapped provider.
+func (p *redactingProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// CreateCompletion sends req with the patterns scrubbed.
+func (p *redactingProvider) CreateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	return p.Provider.CreateCompletion(ctx, p.redactor.RedactRequest(req))
+}
+
+// StreamCompletion streams req with the patterns scrubbed.
+func (p *redactingProvider) StreamCompletion(ctx context.Context, req *CompletionRequest) (<-chan StreamToken, error) {
+	return p.Provider.StreamCompletion(ctx, p.redactor.RedactRequest(req))
+}
+
+// prepare scrubs the patterns from a batched request.
+func (p *redactingProvider) prepare(req *CompletionRequest) (*CompletionRequest, error) {
+	return p.redactor.RedactRequest(req), nil
+}
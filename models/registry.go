@@ -74,14 +74,12 @@ func (r *Registry) ListAll() []Provider {
 	return providers
 }
 
-// TestAll tests connectivity for all providers.
+// TestAll tests connectivity for all providers concurrently. See CheckAll
+// for latency and authentication details.
 func (r *Registry) TestAll(ctx context.Context) map[string]error {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	results := make(map[string]error)
-	for name, provider := range r.providers {
-		results[name] = provider.TestConnection(ctx)
+	for _, health := range r.CheckAll(ctx) {
+		results[health.Provider] = health.Err
 	}
 	return results
 }
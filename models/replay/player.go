@@ -0,0 +1,141 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// ErrNotRecorded is returned for requests the cassette has no response for.
+var ErrNotRecorded = errors.New("no recorded interaction")
+
+// Player answers requests from a recorded cassette without network access.
+//
+// A request gets the first unplayed interaction with the same method, URL
+// and body, redacted as when recording. Failing that, it gets the first
+// unplayed interaction with the same method and URL, so a conversation whose
+// prompts differ slightly (e.g. by the date in the system prompt) still
+// replays, in recorded order. Each interaction is played once.
+type Player struct {
+	opts *options
+
+	mu           sync.Mutex
+	interactions []Interaction
+	played       []bool
+}
+
+// NewPlayer creates a player for the cassette at path.
+func NewPlayer(path string, opts ...Option) (*Player, error) {
+	interactions, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlayerFrom(interactions, opts...), nil
+}
+
+// NewPlayerFrom creates a player for the given interactions.
+func NewPlayerFrom(interactions []Interaction, opts ...Option) *Player {
+	return &Player{
+		opts:         newOptions(opts),
+		interactions: interactions,
+		played:       make([]bool, len(interactions)),
+	}
+}
+
+// Wrap returns the player itself: replayed requests never reach next.
+func (p *Player) Wrap(next http.RoundTripper) http.RoundTripper {
+	return p
+}
+
+// Remaining returns how many interactions have not been played.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for _, played := range p.played {
+		if !played {
+			n++
+		}
+	}
+	return n
+}
+
+// RoundTrip implements http.RoundTripper.
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	reqURL := p.opts.redactURL(req.URL)
+	in, ok := p.next(req.Method, reqURL, p.opts.redact(string(body)))
+	if !ok {
+		return nil, fmt.Errorf("%w for %s %s", ErrNotRecorded, req.Method, reqURL)
+	}
+
+	header := in.Headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(in.Body)))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(in.Body))),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}, nil
+}
+
+// next marks and returns the interaction that answers a request.
+func (p *Player) next(method, reqURL, body string) (Interaction, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fallback := -1
+	for i, in := range p.interactions {
+		if p.played[i] || in.Method != method || !sameURL(in.URL, reqURL) {
+			continue
+		}
+		if in.RequestBody == body {
+			p.played[i] = true
+			return in, true
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	if fallback < 0 {
+		return Interaction{}, false
+	}
+	p.played[fallback] = true
+	return p.interactions[fallback], true
+}
+
+// sameURL reports whether two redacted URLs name the same endpoint, ignoring
+// the order of query parameters.
+func sameURL(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host && ua.Path == ub.Path &&
+		ua.Query().Encode() == ub.Query().Encode()
+}
@@ -0,0 +1,139 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Recorder appends provider traffic to a cassette file.
+type Recorder struct {
+	opts *options
+
+	mu   sync.Mutex
+	file *os.File // Nil once closed
+	err  error    // First write error, reported by Close
+}
+
+// NewRecorder creates a recorder writing to a new cassette at path,
+// replacing any existing one.
+func NewRecorder(path string, opts ...Option) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %w", err)
+	}
+	return &Recorder{opts: newOptions(opts), file: f}, nil
+}
+
+// Wrap returns a round tripper that sends requests over next and records
+// them with their responses. Response bodies are recorded as the caller
+// reads them, so streamed responses still arrive incrementally.
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &recording{recorder: r, next: next}
+}
+
+// Close closes the cassette, returning the first error hit while recording.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return r.err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	r.file = nil
+	return r.err
+}
+
+// write appends in to the cassette. Requests still streaming when the
+// recorder is closed are dropped.
+func (r *Recorder) write(in *Interaction) {
+	line, err := json.Marshal(in)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	if _, err := r.file.Write(append(line, '\n')); err != nil && r.err == nil {
+		r.err = fmt.Errorf("failed to record interaction: %w", err)
+	}
+}
+
+type recording struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *recording) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	o := rt.recorder.opts
+	in := &Interaction{
+		Method:         req.Method,
+		URL:            o.redactURL(req.URL),
+		RequestHeaders: o.redactHeader(req.Header),
+		RequestBody:    o.redact(string(body)),
+		Status:         resp.StatusCode,
+		Headers:        o.redactHeader(resp.Header),
+		RecordedAt:     o.now(),
+	}
+	resp.Body = &teeBody{body: resp.Body, done: func(b []byte) {
+		in.Body = o.redact(string(b))
+		rt.recorder.write(in)
+	}}
+	return resp, nil
+}
+
+// readRequestBody reads req's body and puts an unread copy back.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// teeBody keeps a copy of what is read from a response body and hands it to
+// done once the body is closed.
+type teeBody struct {
+	body io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *teeBody) Close() error {
+	err := b.body.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+	return err
+}
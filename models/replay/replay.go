@@ -0,0 +1,145 @@
+// Package replay records provider HTTP traffic to disk and plays it back.
+//
+// A Recorder wraps a provider's transport and appends every request and
+// response to a cassette, a JSON Lines file, with API keys and other secrets
+// redacted. A Player serves those responses again without touching the
+// network, so sessions can be demoed offline, bugs reproduced from a user's
+// recording, and integration tests run without provider credentials.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Redacted replaces secrets in recorded interactions.
+const Redacted = "REDACTED"
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeaders http.Header `json:"request_headers,omitempty"`
+	RequestBody    string      `json:"request_body,omitempty"`
+	Status         int         `json:"status"`
+	Headers        http.Header `json:"headers,omitempty"`
+	Body           string      `json:"body"`
+	RecordedAt     time.Time   `json:"recorded_at"`
+}
+
+// secretHeaders carry credentials; their values are never written to disk.
+var secretHeaders = []string{
+	"Authorization", "Proxy-Authorization", "X-Api-Key", "Api-Key",
+	"X-Goog-Api-Key", "Cookie", "Set-Cookie",
+}
+
+// secretParams are query parameters that carry credentials.
+var secretParams = []string{"key", "api_key", "apikey", "token", "access_token"}
+
+// secretPattern matches the shapes of well-known API keys and tokens.
+var secretPattern = regexp.MustCompile(`\b(sk-(ant-|proj-)?[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{30,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35})`)
+
+// Option is a functional option for configuring recorders and players.
+type Option func(*options)
+
+type options struct {
+	secrets []string
+	now     func() time.Time
+}
+
+// WithSecrets redacts the given values, such as the configured API keys,
+// wherever they appear in a request or response.
+func WithSecrets(secrets ...string) Option {
+	return func(o *options) {
+		for _, s := range secrets {
+			if s != "" {
+				o.secrets = append(o.secrets, s)
+			}
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// redact replaces secrets in s.
+func (o *options) redact(s string) string {
+	for _, secret := range o.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return secretPattern.ReplaceAllString(s, Redacted)
+}
+
+// redactURL replaces secrets in u, including credential query parameters.
+func (o *options) redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	q := c.Query()
+	for _, name := range secretParams {
+		if q.Has(name) {
+			q.Set(name, Redacted)
+		}
+	}
+	c.RawQuery = q.Encode()
+	return o.redact(c.String())
+}
+
+// redactHeader returns a copy of h with credential values replaced.
+func (o *options) redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	c := make(http.Header, len(h))
+	for name, values := range h {
+		redacted := make([]string, len(values))
+		for i, v := range values {
+			redacted[i] = o.redact(v)
+		}
+		c[name] = redacted
+	}
+	for _, name := range secretHeaders {
+		if c.Get(name) != "" {
+			c.Set(name, Redacted)
+		}
+	}
+	return c
+}
+
+// Load reads the interactions of the cassette at path, in recorded order.
+func Load(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer f.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var in Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("invalid interaction on line %d of %s: %w", line, path, err)
+		}
+		interactions = append(interactions, in)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	return interactions, nil
+}
@@ -0,0 +1,149 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/providers/anthropic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "sk-ant-REDACTED"
+
+// sseServer streams a short Anthropic response, failing requests without
+// the test key.
+func sseServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != testKey {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Set-Cookie", "session=abc")
+		events := []string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":10}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			fmt.Fprintf(w, "data: %s\n\n", e)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func streamText(t *testing.T, p *anthropic.Provider) string {
+	t.Helper()
+	tokens, err := p.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:     "claude-sonnet-4-5",
+		Messages:  []models.Message{{Role: "user", Content: "Say hello"}},
+		MaxTokens: 100,
+	})
+	require.NoError(t, err)
+
+	var text strings.Builder
+	for token := range tokens {
+		require.NoError(t, token.Error)
+		text.WriteString(token.Content)
+	}
+	return text.String()
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := sseServer(t)
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+	recorded := anthropic.New(testKey,
+		anthropic.WithBaseURL(srv.URL),
+		anthropic.WithHTTPClient(&http.Client{Transport: recorder.Wrap(http.DefaultTransport)}))
+	assert.Equal(t, "Hello world", streamText(t, recorded))
+	require.NoError(t, recorder.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), testKey)
+	assert.NotContains(t, string(data), "session=abc")
+
+	interactions, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, interactions, 1)
+	assert.Equal(t, http.StatusOK, interactions[0].Status)
+	assert.Equal(t, Redacted, interactions[0].RequestHeaders.Get("X-Api-Key"))
+	assert.Contains(t, interactions[0].Body, "Hello")
+
+	// Replay needs neither the server nor the key
+	srv.Close()
+	player, err := NewPlayer(path)
+	require.NoError(t, err)
+	replayed := anthropic.New("placeholder",
+		anthropic.WithBaseURL(srv.URL),
+		anthropic.WithHTTPClient(&http.Client{Transport: player}))
+	assert.Equal(t, "Hello world", streamText(t, replayed))
+	assert.Equal(t, 0, player.Remaining())
+
+	// Each interaction plays once
+	_, err = replayed.StreamCompletion(context.Background(), &models.CompletionRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []models.Message{{Role: "user", Content: "Say hello"}},
+	})
+	assert.ErrorIs(t, err, ErrNotRecorded)
+}
+
+func TestPlayer_Matching(t *testing.T) {
+	player := NewPlayerFrom([]Interaction{
+		{Method: "POST", URL: "https://api.example.com/v1/chat", RequestBody: `{"n":1}`, Status: 200, Body: "first"},
+		{Method: "POST", URL: "https://api.example.com/v1/chat", RequestBody: `{"n":2}`, Status: 200, Body: "second"},
+		{Method: "GET", URL: "https://api.example.com/v1/models?key=REDACTED", Status: 200, Body: "models"},
+	})
+	client := &http.Client{Transport: player}
+
+	do := func(method, url, body string) string {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	// Exact body matches are preferred over recorded order
+	assert.Equal(t, "second", do("POST", "https://api.example.com/v1/chat", `{"n":2}`))
+	// Otherwise the next unplayed interaction for the endpoint is used
+	assert.Equal(t, "first", do("POST", "https://api.example.com/v1/chat", `{"n":3}`))
+	// Credential query parameters are redacted before matching
+	assert.Equal(t, "models", do("GET", "https://api.example.com/v1/models?key=AIzaSecret", ""))
+
+	_, err := client.Get("https://api.example.com/v1/other")
+	assert.ErrorIs(t, err, ErrNotRecorded)
+}
+
+func TestRedaction(t *testing.T) {
+	o := newOptions([]Option{WithSecrets("hunter2")})
+
+	assert.Equal(t, "password="+Redacted, o.redact("password=hunter2"))
+	assert.Equal(t, "Bearer "+Redacted, o.redact("Bearer sk-proj-abcdefghijklmnopqrstuvwx"))
+
+	h := o.redactHeader(http.Header{
+		"Authorization": {"Bearer token"},
+		"Content-Type":  {"application/json"},
+	})
+	assert.Equal(t, Redacted, h.Get("Authorization"))
+	assert.Equal(t, "application/json", h.Get("Content-Type"))
+}
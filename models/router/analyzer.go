@@ -109,10 +109,7 @@ func (t TaskAnalysis) String() string {
 // Helper functions (placeholders for future implementation)
 
 func estimateTokens(req *models.CompletionRequest) int {
-	// Placeholder - will use proper tokenization in full implementation
-	// Rough estimate: 1 token ≈ 4 characters
-	messageText := extractMessageText(req)
-	return len(messageText) / 4
+	return models.CountTokens(req.Model, extractMessageText(req))
 }
 
 func extractMessageText(req *models.CompletionRequest) string {
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// BatchPolicy says how requests nobody waits on, such as Thorough Mode
+// planning across several models, are sent.
+type BatchPolicy struct {
+	Enabled bool          // Submit to the batch API of providers that have one
+	Poll    time.Duration // How often batches are checked; models.DefaultBatchPollInterval if 0
+}
+
+// CompleteAll answers requests for models of any configured provider,
+// returning their results in the order of reqs. With batching enabled, the
+// requests for each provider with a batch API are submitted to it as one
+// batch, at the batch price; the others are sent as regular completions,
+// all at once.
+func (r *Router) CompleteAll(ctx context.Context, reqs []models.BatchRequest, policy BatchPolicy) []models.BatchResult {
+	results := make([]models.BatchResult, len(reqs))
+	groups := make(map[string][]int) // Indexes into reqs per provider
+	for i, req := range reqs {
+		results[i].ID = req.ID
+		providerName, _, err := models.ParseModelName(req.Request.Model)
+		if err == nil {
+			err = r.CheckModel(req.Request.Model)
+		}
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+		groups[providerName] = append(groups[providerName], i)
+	}
+
+	r.mu.Lock()
+	providers := make(map[string]models.Provider, len(groups))
+	for name := range groups {
+		providers[name] = r.providers[name]
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for name, indexes := range groups {
+		provider := providers[name]
+		if provider == nil {
+			for _, i := range indexes {
+				results[i].Error = fmt.Errorf("provider %s is not configured", name)
+			}
+			continue
+		}
+
+		if batcher, ok := models.AsBatcher(provider); ok && policy.Enabled {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				group := make([]models.BatchRequest, len(indexes))
+				for j, i := range indexes {
+					group[j] = reqs[i]
+				}
+				groupResults, err := models.RunBatch(ctx, batcher, group, policy.Poll)
+				for j, i := range indexes {
+					if err != nil {
+						results[i].Error = err
+						continue
+					}
+					results[i] = groupResults[j]
+				}
+			}()
+			continue
+		}
+
+		for _, i := range indexes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i].Response, results[i].Error = provider.CreateCompletion(ctx, reqs[i].Request)
+			}()
+		}
+	}
+	wg.Wait()
+	return results
+}
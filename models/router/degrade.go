@@ -0,0 +1,128 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// Skip in a degradation order skips the layer instead of failing it.
+const Skip = "skip"
+
+// healthTTL is how long a provider health check result is reused.
+const healthTTL = 30 * time.Second
+
+// healthCheckTimeout bounds one provider health check.
+const healthCheckTimeout = 5 * time.Second
+
+// ErrLayerUnavailable is returned when no model of a layer passes its health
+// check and its degradation order does not allow skipping it.
+var ErrLayerUnavailable = errors.New("no healthy model for layer")
+
+// Resolution is the model chosen to run a layer.
+type Resolution struct {
+	Layer      string
+	Configured string   // Model set in the layer configuration
+	Model      string   // Model to run the layer with; empty when skipped
+	Skipped    bool     // No model was healthy and the order ends in "skip"
+	Failures   []string // Why earlier candidates were passed over
+}
+
+// Degraded reports whether the layer runs with a substitute or not at all.
+func (r Resolution) Degraded() bool {
+	return r.Skipped || r.Model != r.Configured
+}
+
+// String describes the resolution for logs and notices.
+func (r Resolution) String() string {
+	switch {
+	case r.Skipped:
+		return fmt.Sprintf("%s skipped: %s", r.Layer, strings.Join(r.Failures, "; "))
+	case r.Model != r.Configured:
+		return fmt.Sprintf("%s using %s: %s", r.Layer, r.Model, strings.Join(r.Failures, "; "))
+	default:
+		return fmt.Sprintf("%s using %s", r.Layer, r.Model)
+	}
+}
+
+// healthResult is a cached provider health check.
+type healthResult struct {
+	err     error
+	checked time.Time
+}
+
+// AddProvider makes another provider available for routing and health checks.
+func (r *Router) AddProvider(p models.Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[p.Name()] = p
+}
+
+// CheckHealth reports whether model can be used now: it must be allowed
+// under the routing constraints and its provider must be registered and pass
+// a connection test. Provider results are cached for a short while so that
+// resolving several layers does not repeat the same request.
+func (r *Router) CheckHealth(ctx context.Context, model string) error {
+	if err := r.CheckModel(model); err != nil {
+		return err
+	}
+	providerName, _, err := models.ParseModelName(model)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	provider, ok := r.providers[providerName]
+	cached, seen := r.health[providerName]
+	r.mu.Unlock()
+	if !ok || provider == nil {
+		return fmt.Errorf("provider %s is not configured", providerName)
+	}
+	if seen && time.Since(cached.checked) < healthTTL {
+		return cached.err
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	err = provider.TestConnection(checkCtx)
+
+	r.mu.Lock()
+	r.health[providerName] = healthResult{err: err, checked: time.Now()}
+	r.mu.Unlock()
+	return err
+}
+
+// ResolveLayer picks the model to run a layer with: the configured model if
+// it is healthy, otherwise the first healthy model of order. Models known to
+// lack the layer's requirements are passed over without a health check. An order ending
+// in Skip skips the layer when nothing is healthy; without it
+// ErrLayerUnavailable is returned.
+func (r *Router) ResolveLayer(ctx context.Context, layer, configured string, order []string) (Resolution, error) {
+	res := Resolution{Layer: layer, Configured: configured}
+
+	candidates := append([]string{configured}, order...)
+	for _, model := range candidates {
+		if model == Skip {
+			res.Skipped = true
+			return res, nil
+		}
+		if model == "" {
+			continue
+		}
+		if err := models.CheckCapabilities(model, r.Requirements(layer)); err != nil {
+			res.Failures = append(res.Failures, err.Error())
+			continue
+		}
+		err := r.CheckHealth(ctx, model)
+		if err == nil {
+			res.Model = model
+			return res, nil
+		}
+		res.Failures = append(res.Failures, fmt.Sprintf("%s unavailable (%v)", model, err))
+	}
+	return res, fmt.Errorf("%w %s: %s", ErrLayerUnavailable, layer, strings.Join(res.Failures, "; "))
+}
@@ -0,0 +1,147 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// QuickTask is a small request the user waits on, such as naming a
+// session, which any capable model can answer.
+type QuickTask string
+
+// Quick tasks
+const (
+	TaskTitle     QuickTask = "title"
+	TaskSummary   QuickTask = "summary"
+	TaskRelevance QuickTask = "relevance"
+)
+
+// DefaultMaxLatency is the remote latency above which quick tasks move to
+// the local model when the policy doesn't set one.
+const DefaultMaxLatency = 800 * time.Millisecond
+
+// latencyTTL is how long a measured provider latency is trusted before the
+// provider is probed again.
+const latencyTTL = time.Minute
+
+// QuickPolicy moves quick tasks to a local model while the remote provider
+// is slow to respond, so they keep the UI responsive on a bad network.
+type QuickPolicy struct {
+	Local      string        // Local model for quick tasks; empty turns the policy off
+	MaxLatency time.Duration // Remote latency above which Local is preferred; DefaultMaxLatency if 0
+}
+
+// QuickSelection is the model chosen for a quick task.
+type QuickSelection struct {
+	Task    QuickTask
+	Model   string        // Model to run the task with
+	Local   bool          // The policy moved the task to the local model
+	Latency time.Duration // Measured latency of the remote provider, if it was measured
+	Reason  string        // Why the local model was chosen
+}
+
+// latencyResult is a measured provider latency.
+type latencyResult struct {
+	latency  time.Duration
+	err      error
+	measured time.Time
+}
+
+// SetQuickPolicy sets how quick tasks choose between the remote and local
+// models.
+func (r *Router) SetQuickPolicy(p QuickPolicy) {
+	if p.MaxLatency <= 0 {
+		p.MaxLatency = DefaultMaxLatency
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quick = p
+}
+
+// ObserveLatency records the latency of a provider measured elsewhere, such
+// as by a connection test, sparing a probe.
+func (r *Router) ObserveLatency(provider string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latency[provider] = latencyResult{latency: latency, measured: time.Now()}
+}
+
+// ProviderLatency returns how long provider takes to answer a connection
+// test, probing it unless a recent measurement exists. A probe is given up
+// on after timeout, returning the time waited and the error.
+func (r *Router) ProviderLatency(ctx context.Context, provider string, timeout time.Duration) (time.Duration, error) {
+	r.mu.Lock()
+	p, ok := r.providers[provider]
+	cached, seen := r.latency[provider]
+	r.mu.Unlock()
+	if seen && time.Since(cached.measured) < latencyTTL {
+		return cached.latency, cached.err
+	}
+	if !ok || p == nil {
+		return 0, fmt.Errorf("provider %s is not configured", provider)
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := p.TestConnection(probeCtx)
+	latency := time.Since(start)
+	if ctx.Err() != nil {
+		// The caller gave up; that says nothing about the provider
+		return latency, ctx.Err()
+	}
+
+	r.mu.Lock()
+	r.latency[provider] = latencyResult{latency: latency, err: err, measured: time.Now()}
+	r.mu.Unlock()
+	return latency, err
+}
+
+// SelectQuickModel picks the model to run a quick task with. model, the
+// model the task would otherwise run on, is kept unless it is remote and its
+// provider is slower than the policy allows, unreachable, or not allowed
+// offline, and the local model is healthy. The remote provider is probed at
+// most for twice the allowed latency.
+func (r *Router) SelectQuickModel(ctx context.Context, task QuickTask, model string) QuickSelection {
+	sel := QuickSelection{Task: task, Model: model}
+
+	r.mu.Lock()
+	policy := r.quick
+	r.mu.Unlock()
+	if policy.Local == "" || policy.Local == model {
+		return sel
+	}
+	providerName, _, err := models.ParseModelName(model)
+	if err != nil || models.IsLocalProvider(providerName) {
+		return sel
+	}
+
+	if err := r.CheckModel(model); err != nil {
+		sel.Reason = err.Error()
+	} else {
+		latency, err := r.ProviderLatency(ctx, providerName, 2*policy.MaxLatency)
+		sel.Latency = latency
+		switch {
+		case ctx.Err() != nil:
+			return sel
+		case err != nil:
+			sel.Reason = fmt.Sprintf("%s unreachable (%v)", providerName, err)
+		case latency > policy.MaxLatency:
+			sel.Reason = fmt.Sprintf("%s responded in %s, over %s", providerName, latency.Round(time.Millisecond), policy.MaxLatency)
+		default:
+			return sel
+		}
+	}
+
+	// A local model that is down is no better than a slow remote one
+	if err := r.CheckHealth(ctx, policy.Local); err != nil {
+		sel.Reason = ""
+		return sel
+	}
+	sel.Model = policy.Local
+	sel.Local = true
+	return sel
+}
@@ -4,7 +4,9 @@ package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/abrksh22/bplus/models"
 )
@@ -12,13 +14,23 @@ import (
 // Router handles intelligent model selection based on task requirements.
 // This is a placeholder implementation that will be fully developed in future phases.
 type Router struct {
+	mu        sync.Mutex // Guards providers, health, latency and quick
 	providers map[string]models.Provider
+	health    map[string]healthResult  // Last health check per provider
+	latency   map[string]latencyResult // Last latency measurement per provider
+	quick     QuickPolicy
 	rules     []RoutingRule
 	fallbacks map[string][]string
 	budget    *CostTracker
 	enabled   bool // Router is inactive by default
+	offline   bool // Only local providers may be routed to
+
+	requirements map[string]models.Requirements // What each layer needs from its model
 }
 
+// ErrOffline is returned when a remote model is requested in offline mode.
+var ErrOffline = errors.New("offline mode: remote providers are disabled")
+
 // RoutingRule defines a rule for model selection.
 type RoutingRule struct {
 	Name      string
@@ -30,12 +42,19 @@ type RoutingRule struct {
 // NewRouter creates a new router instance.
 // The router is inactive by default and will be enabled in future phases.
 func NewRouter(providers map[string]models.Provider) *Router {
+	if providers == nil {
+		providers = make(map[string]models.Provider)
+	}
 	return &Router{
 		providers: providers,
+		health:    make(map[string]healthResult),
+		latency:   make(map[string]latencyResult),
 		rules:     make([]RoutingRule, 0),
 		fallbacks: make(map[string][]string),
 		budget:    NewCostTracker(BudgetLimits{}),
 		enabled:   false, // Inactive by default
+
+		requirements: make(map[string]models.Requirements),
 	}
 }
 
@@ -87,6 +106,74 @@ func (r *Router) IsEnabled() bool {
 	return r.enabled
 }
 
+// SetOffline enables or disables offline mode. While offline, remote
+// providers are hidden from routing entirely and only local providers
+// (Ollama, LM Studio) can be selected.
+func (r *Router) SetOffline(offline bool) {
+	r.offline = offline
+}
+
+// IsOffline returns whether the router is in offline mode.
+func (r *Router) IsOffline() bool {
+	return r.offline
+}
+
+// Providers returns the providers currently eligible for routing.
+// In offline mode, remote providers are excluded.
+func (r *Router) Providers() map[string]models.Provider {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	eligible := make(map[string]models.Provider, len(r.providers))
+	for name, provider := range r.providers {
+		if r.offline && !models.IsLocalProvider(name) {
+			continue
+		}
+		eligible[name] = provider
+	}
+	return eligible
+}
+
+// CheckModel verifies that a model ("provider/model-id") may be used under
+// the current routing constraints. It returns ErrOffline for remote models
+// while offline mode is active.
+func (r *Router) CheckModel(fullName string) error {
+	provider, _, err := models.ParseModelName(fullName)
+	if err != nil {
+		return err
+	}
+
+	if r.offline && !models.IsLocalProvider(provider) {
+		return fmt.Errorf("%w: %s", ErrOffline, fullName)
+	}
+
+	return nil
+}
+
+// SetRequirements sets what a layer needs from the models it runs on.
+// Models known to lack a requirement are never resolved for the layer.
+func (r *Router) SetRequirements(layer string, req models.Requirements) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requirements[layer] = req
+}
+
+// Requirements returns what a layer needs from the models it runs on.
+func (r *Router) Requirements(layer string) models.Requirements {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requirements[layer]
+}
+
+// CheckCapabilities verifies that a model may be used under the current
+// routing constraints and is not known to lack anything req asks for. It
+// returns a *models.CapabilityError for models that cannot run the task.
+func (r *Router) CheckCapabilities(fullName string, req models.Requirements) error {
+	if err := r.CheckModel(fullName); err != nil {
+		return err
+	}
+	return models.CheckCapabilities(fullName, req)
+}
+
 // RouteWithFallback attempts to route to a model with fallback support.
 // Placeholder for future implementation.
 func (r *Router) RouteWithFallback(ctx context.Context, req *models.CompletionRequest) (string, error) {
@@ -103,6 +190,7 @@ func (r *Router) RouteWithFallback(ctx context.Context, req *models.CompletionRe
 func (r *Router) GetStats() RouterStats {
 	return RouterStats{
 		Enabled:      r.enabled,
+		Offline:      r.offline,
 		TotalRoutes:  0,
 		FailedRoutes: 0,
 		RulesCount:   len(r.rules),
@@ -112,6 +200,7 @@ func (r *Router) GetStats() RouterStats {
 // RouterStats contains routing statistics.
 type RouterStats struct {
 	Enabled      bool
+	Offline      bool
 	TotalRoutes  int
 	FailedRoutes int
 	RulesCount   int
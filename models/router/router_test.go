@@ -1,7 +1,10 @@
 package router
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/abrksh22/bplus/models"
 	"github.com/stretchr/testify/assert"
@@ -40,6 +43,32 @@ func TestRouter_SelectModel_Disabled(t *testing.T) {
 	assert.Contains(t, err.Error(), "not yet enabled")
 }
 
+func TestRouter_Offline(t *testing.T) {
+	providers := map[string]models.Provider{
+		"anthropic": nil,
+		"ollama":    nil,
+		"lmstudio":  nil,
+	}
+	router := NewRouter(providers)
+	assert.False(t, router.IsOffline())
+	assert.Len(t, router.Providers(), 3)
+	assert.NoError(t, router.CheckModel("anthropic/claude-sonnet-4-5"))
+
+	router.SetOffline(true)
+	assert.True(t, router.IsOffline())
+	assert.True(t, router.GetStats().Offline)
+
+	eligible := router.Providers()
+	assert.Len(t, eligible, 2)
+	assert.NotContains(t, eligible, "anthropic")
+
+	err := router.CheckModel("anthropic/claude-sonnet-4-5")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrOffline)
+	assert.NoError(t, router.CheckModel("ollama/llama3:latest"))
+	assert.NoError(t, router.CheckModel("lmstudio/qwen2.5-coder"))
+}
+
 func TestRouter_AddRule(t *testing.T) {
 	router := NewRouter(nil)
 
@@ -278,7 +307,7 @@ func TestEstimateTokens(t *testing.T) {
 
 	tokens := estimateTokens(req)
 	assert.True(t, tokens > 0)
-	// Rough estimate: 1 token ≈ 4 characters
+	// "This is a test message with some content" is 8 tokens
 	assert.True(t, tokens > 5) // Should be at least 5 tokens
 }
 
@@ -309,3 +338,235 @@ func TestTaskAnalysis_String(t *testing.T) {
 	assert.Contains(t, str, "Requires: Tools")
 	require.Contains(t, str, "Language: go")
 }
+
+// healthProvider is a provider whose connection test fails with err after
+// delay.
+type healthProvider struct {
+	models.Provider
+	name  string
+	err   error
+	delay time.Duration
+	tests int
+}
+
+func (p *healthProvider) Name() string { return p.name }
+
+func (p *healthProvider) TestConnection(ctx context.Context) error {
+	p.tests++
+	select {
+	case <-time.After(p.delay):
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRouter_ResolveLayer(t *testing.T) {
+	openai := &healthProvider{name: "openai", err: errors.New("connection refused")}
+	ollama := &healthProvider{name: "ollama"}
+	router := NewRouter(nil)
+	router.AddProvider(openai)
+	router.AddProvider(ollama)
+	ctx := context.Background()
+
+	// Healthy configured model
+	res, err := router.ResolveLayer(ctx, "synthesis", "ollama/qwen2.5-coder:7b", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ollama/qwen2.5-coder:7b", res.Model)
+	assert.False(t, res.Degraded())
+
+	// Substitute from the degradation order
+	res, err = router.ResolveLayer(ctx, "validation", "openai/gpt-4-turbo", []string{"anthropic/claude-sonnet-4-5", "ollama/llama3:8b", Skip})
+	require.NoError(t, err)
+	assert.Equal(t, "ollama/llama3:8b", res.Model)
+	assert.True(t, res.Degraded())
+	require.Len(t, res.Failures, 2)
+	assert.Contains(t, res.Failures[0], "connection refused")
+	assert.Contains(t, res.Failures[1], "not configured")
+
+	// Skip when nothing is healthy
+	res, err = router.ResolveLayer(ctx, "intent_clarification", "openai/gpt-4-turbo", []string{Skip})
+	require.NoError(t, err)
+	assert.True(t, res.Skipped)
+	assert.Empty(t, res.Model)
+	assert.Contains(t, res.String(), "skipped")
+
+	// Fail without a skip
+	_, err = router.ResolveLayer(ctx, "synthesis", "openai/gpt-4-turbo", nil)
+	assert.ErrorIs(t, err, ErrLayerUnavailable)
+
+	// Health checks are cached per provider
+	assert.Equal(t, 1, openai.tests)
+	assert.Equal(t, 1, ollama.tests)
+
+	// Remote models are unavailable offline
+	router.SetOffline(true)
+	res, err = router.ResolveLayer(ctx, "synthesis", "openai/gpt-4-turbo", []string{"ollama/qwen2.5-coder:7b"})
+	require.NoError(t, err)
+	assert.Equal(t, "ollama/qwen2.5-coder:7b", res.Model)
+	assert.Contains(t, res.Failures[0], "offline")
+}
+
+func TestRouter_Capabilities(t *testing.T) {
+	router := NewRouter(nil)
+	router.AddProvider(&healthProvider{name: "openai"})
+	ctx := context.Background()
+
+	tools := models.Requirements{Tools: true}
+	var capErr *models.CapabilityError
+	err := router.CheckCapabilities("openai/o1-mini", tools)
+	require.ErrorAs(t, err, &capErr)
+	assert.Contains(t, err.Error(), "tool calling")
+	assert.NoError(t, router.CheckCapabilities("openai/gpt-4o", tools))
+	assert.NoError(t, router.CheckCapabilities("ollama/some-new-model", tools), "unknown models are assumed capable")
+
+	// Incapable models are passed over without a health check
+	router.SetRequirements("validation", tools)
+	res, err := router.ResolveLayer(ctx, "validation", "openai/o1-mini", []string{"openai/gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "openai/gpt-4o", res.Model)
+	require.Len(t, res.Failures, 1)
+	assert.Contains(t, res.Failures[0], "does not support tool calling")
+
+	router.SetOffline(true)
+	assert.ErrorIs(t, router.CheckCapabilities("openai/gpt-4o", tools), ErrOffline)
+}
+
+func TestRouter_SelectQuickModel(t *testing.T) {
+	anthropic := &healthProvider{name: "anthropic", delay: 50 * time.Millisecond}
+	ollama := &healthProvider{name: "ollama"}
+	router := NewRouter(nil)
+	router.AddProvider(anthropic)
+	router.AddProvider(ollama)
+	ctx := context.Background()
+
+	// Without a local model quick tasks stay where they are
+	sel := router.SelectQuickModel(ctx, TaskTitle, "anthropic/claude-haiku-4-0")
+	assert.Equal(t, "anthropic/claude-haiku-4-0", sel.Model)
+	assert.Zero(t, anthropic.tests)
+
+	// A fast remote provider is kept
+	router.SetQuickPolicy(QuickPolicy{Local: "ollama/llama3.2:3b", MaxLatency: 200 * time.Millisecond})
+	sel = router.SelectQuickModel(ctx, TaskTitle, "anthropic/claude-haiku-4-0")
+	assert.Equal(t, "anthropic/claude-haiku-4-0", sel.Model)
+	assert.False(t, sel.Local)
+	assert.Greater(t, sel.Latency, time.Duration(0))
+
+	// The measurement is reused
+	router.SelectQuickModel(ctx, TaskSummary, "anthropic/claude-haiku-4-0")
+	assert.Equal(t, 1, anthropic.tests)
+
+	// A slow one is replaced by the local model
+	router.ObserveLatency("anthropic", time.Second)
+	sel = router.SelectQuickModel(ctx, TaskRelevance, "anthropic/claude-haiku-4-0")
+	assert.Equal(t, "ollama/llama3.2:3b", sel.Model)
+	assert.True(t, sel.Local)
+	assert.Contains(t, sel.Reason, "over 200ms")
+
+	// Probes give up after twice the allowed latency
+	slow := &healthProvider{name: "openai", delay: time.Minute}
+	router.AddProvider(slow)
+	start := time.Now()
+	sel = router.SelectQuickModel(ctx, TaskTitle, "openai/gpt-4o-mini")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, "ollama/llama3.2:3b", sel.Model)
+	assert.Contains(t, sel.Reason, "unreachable")
+
+	// Local models need no help, and a local model that is down doesn't either
+	sel = router.SelectQuickModel(ctx, TaskTitle, "ollama/qwen2.5-coder:7b")
+	assert.False(t, sel.Local)
+	router.SetQuickPolicy(QuickPolicy{Local: "lmstudio/qwen2.5-7b-instruct"})
+	sel = router.SelectQuickModel(ctx, TaskTitle, "anthropic/claude-haiku-4-0")
+	assert.Equal(t, "anthropic/claude-haiku-4-0", sel.Model)
+	assert.Empty(t, sel.Reason)
+}
+
+// completionProvider answers every request with its model name.
+type completionProvider struct {
+	models.Provider
+	name string
+}
+
+func (p *completionProvider) Name() string { return p.name }
+
+func (p *completionProvider) CreateCompletion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	return &models.CompletionResponse{Content: "direct " + req.Model}, nil
+}
+
+// batchingProvider answers batches at once.
+type batchingProvider struct {
+	completionProvider
+	batches int
+}
+
+func (p *batchingProvider) CreateBatch(ctx context.Context, reqs []models.BatchRequest) (*models.Batch, error) {
+	p.batches++
+	return &models.Batch{ID: "b", Status: models.BatchEnded, Requests: len(reqs)}, nil
+}
+
+func (p *batchingProvider) GetBatch(ctx context.Context, id string) (*models.Batch, error) {
+	return &models.Batch{ID: id, Status: models.BatchEnded}, nil
+}
+
+func (p *batchingProvider) BatchResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	return []models.BatchResult{
+		{ID: "2", Response: &models.CompletionResponse{Content: "batched"}},
+		{ID: "1", Response: &models.CompletionResponse{Content: "batched"}},
+	}, nil
+}
+
+func (p *batchingProvider) CancelBatch(ctx context.Context, id string) error { return nil }
+
+func TestRouter_CompleteAll(t *testing.T) {
+	anthropic := &batchingProvider{completionProvider: completionProvider{name: "anthropic"}}
+	router := NewRouter(nil)
+	router.AddProvider(anthropic)
+	router.AddProvider(&completionProvider{name: "gemini"})
+
+	reqs := []models.BatchRequest{
+		{ID: "1", Request: &models.CompletionRequest{Model: "anthropic/claude-sonnet-4-5"}},
+		{ID: "2", Request: &models.CompletionRequest{Model: "anthropic/claude-opus-4-1"}},
+		{ID: "3", Request: &models.CompletionRequest{Model: "gemini/gemini-2.5-pro"}},
+		{ID: "4", Request: &models.CompletionRequest{Model: "openai/gpt-4o"}},
+	}
+
+	results := router.CompleteAll(context.Background(), reqs, BatchPolicy{Enabled: true})
+	require.Len(t, results, 4)
+	assert.Equal(t, 1, anthropic.batches)
+	assert.Equal(t, "batched", results[0].Response.Content)
+	assert.Equal(t, "batched", results[1].Response.Content)
+	assert.Equal(t, "direct gemini/gemini-2.5-pro", results[2].Response.Content)
+	assert.Equal(t, "4", results[3].ID)
+	assert.ErrorContains(t, results[3].Error, "openai is not configured")
+
+	// Without batching every request is sent directly
+	results = router.CompleteAll(context.Background(), reqs[:1], BatchPolicy{})
+	assert.Equal(t, "direct anthropic/claude-sonnet-4-5", results[0].Response.Content)
+	assert.Equal(t, 1, anthropic.batches)
+}
+
+func TestSubstitutions(t *testing.T) {
+	subs := NewSubstitutions()
+	start := time.Now()
+
+	subs.Record(Substitution{Kind: SubstitutionQuick, Purpose: "quick task summary", Configured: "anthropic/claude-sonnet-4-5", Used: "ollama/llama3.2", Reason: "short input", Last: start})
+	subs.Record(Substitution{Kind: SubstitutionDegraded, Purpose: "layer validation", Configured: "openai/gpt-4o", Reason: "no API key"})
+	subs.Record(Substitution{Kind: SubstitutionQuick, Purpose: "quick task summary", Configured: "anthropic/claude-sonnet-4-5", Used: "ollama/llama3.2", Reason: "short input again"})
+
+	list := subs.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, 2, list[0].Count)
+	assert.Equal(t, "short input again", list[0].Reason)
+	assert.Equal(t, start, list[0].First)
+	assert.True(t, list[0].Last.After(start) || list[0].Last.Equal(start))
+	assert.False(t, list[0].Skipped())
+	assert.True(t, list[1].Skipped())
+
+	// Callers get a copy
+	list[0].Count = 10
+	assert.Equal(t, 2, subs.List()[0].Count)
+
+	var none *Substitutions
+	none.Record(Substitution{Kind: SubstitutionOffline})
+	assert.Empty(t, none.List())
+}
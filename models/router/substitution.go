@@ -0,0 +1,81 @@
+package router
+
+import (
+	"sync"
+	"time"
+)
+
+// Substitution kinds
+const (
+	SubstitutionOffline  = "offline"  // Offline mode replaced a remote model with a local one
+	SubstitutionDegraded = "degraded" // A layer's model failed its health check
+	SubstitutionQuick    = "quick"    // A quick task ran on the local model
+)
+
+// Substitution is a model used instead of the one the user configured, kept
+// so differences in behavior can be explained.
+type Substitution struct {
+	Kind       string
+	Purpose    string // What the model was used for, such as a layer or a quick task
+	Configured string
+	Used       string // Empty when the work was skipped instead
+	Reason     string // Latest reason given
+	Count      int    // Times it happened
+	First      time.Time
+	Last       time.Time
+}
+
+// Skipped reports whether the work was skipped rather than run on another
+// model.
+func (s Substitution) Skipped() bool {
+	return s.Used == ""
+}
+
+// Substitutions records the model substitutions of a session. The same
+// substitution made again is counted rather than listed twice. A nil
+// *Substitutions is valid and records nothing.
+type Substitutions struct {
+	mu   sync.Mutex
+	list []Substitution
+}
+
+// NewSubstitutions creates an empty substitution record.
+func NewSubstitutions() *Substitutions {
+	return &Substitutions{}
+}
+
+// Record adds a substitution, stamped now if it has no time.
+func (s *Substitutions) Record(sub Substitution) {
+	if s == nil {
+		return
+	}
+	if sub.Last.IsZero() {
+		sub.Last = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.list {
+		prev := &s.list[i]
+		if prev.Kind == sub.Kind && prev.Purpose == sub.Purpose && prev.Configured == sub.Configured && prev.Used == sub.Used {
+			prev.Count++
+			prev.Last = sub.Last
+			if sub.Reason != "" {
+				prev.Reason = sub.Reason
+			}
+			return
+		}
+	}
+	sub.Count, sub.First = 1, sub.Last
+	s.list = append(s.list, sub)
+}
+
+// List returns the substitutions in the order they first happened.
+func (s *Substitutions) List() []Substitution {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Substitution(nil), s.list...)
+}
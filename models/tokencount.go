@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenCounter is implemented by providers whose API counts the tokens of
+// a prompt as the model will see it, which local estimates only
+// approximate.
+type TokenCounter interface {
+	// CountPromptTokens returns the input tokens req would be billed for
+	CountPromptTokens(ctx context.Context, req *CompletionRequest) (int, error)
+}
+
+const (
+	// remoteCountRatio is the share of the context window from which a
+	// prompt is counted by the provider: near the limit an estimate's error
+	// decides whether the request fits
+	remoteCountRatio = 0.75
+
+	// countTimeout bounds a provider count; the estimate is used instead if
+	// it takes longer
+	countTimeout = 5 * time.Second
+
+	// maxCachedCounts is how many counts are kept before the cache starts
+	// over
+	maxCachedCounts = 256
+)
+
+// tokenCounts caches the counts of a provider by prompt content, so a
+// prompt sized again, such as when a request is retried, costs no call.
+type tokenCounts struct {
+	mu          sync.Mutex
+	counts      map[[sha256.Size]byte]int
+	unsupported bool // The endpoint is missing, e.g. behind a gateway
+}
+
+// count returns the provider's count of req's prompt tokens.
+func (c *tokenCounts) count(ctx context.Context, counter TokenCounter, req *CompletionRequest) (int, error) {
+	key := promptKey(req)
+	c.mu.Lock()
+	n, ok := c.counts[key]
+	unsupported := c.unsupported
+	c.mu.Unlock()
+	if ok {
+		return n, nil
+	}
+	if unsupported {
+		return 0, stderrors.ErrUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, countTimeout)
+	defer cancel()
+	n, err := counter.CountPromptTokens(ctx, req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		var pe *ProviderError
+		if stderrors.As(err, &pe) && (pe.Status == http.StatusNotFound || pe.Status == http.StatusMethodNotAllowed || pe.Status == http.StatusNotImplemented) {
+			c.unsupported = true
+		}
+		return 0, err
+	}
+	if c.counts == nil || len(c.counts) >= maxCachedCounts {
+		c.counts = make(map[[sha256.Size]byte]int)
+	}
+	c.counts[key] = n
+	return n, nil
+}
+
+// promptKey hashes what a request's prompt tokens depend on.
+func promptKey(req *CompletionRequest) [sha256.Size]byte {
+	h := sha256.New()
+	_ = json.NewEncoder(h).Encode(struct {
+		Model    string
+		System   string
+		Messages []Message
+		Tools    []Tool
+	}{req.Model, req.System, req.Messages, req.Tools})
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
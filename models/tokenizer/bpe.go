@@ -0,0 +1,120 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BPE is a byte-level BPE tokenizer with tiktoken's merge ranks.
+type BPE struct {
+	name  string
+	ranks map[string]int
+	split func(string) []string
+}
+
+// LoadTiktoken reads merge ranks in tiktoken's format: one base64 token and
+// its rank per line. split is the encoding's pre-tokenizer.
+func LoadTiktoken(name string, r io.Reader, split func(string) []string) (*BPE, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s line %d: expected token and rank", name, line)
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		ranks[string(b)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("%s has no tokens", name)
+	}
+	return NewBPE(name, ranks, split), nil
+}
+
+// NewBPE creates a BPE tokenizer from merge ranks.
+func NewBPE(name string, ranks map[string]int, split func(string) []string) *BPE {
+	return &BPE{name: name, ranks: ranks, split: split}
+}
+
+// Name returns the encoding name.
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count returns the number of tokens in text.
+func (b *BPE) Count(text string) int {
+	n := 0
+	for _, piece := range b.split(text) {
+		if _, ok := b.ranks[piece]; ok {
+			n++
+			continue
+		}
+		n += len(b.merge(piece))
+	}
+	return n
+}
+
+// Encode returns the token IDs of text.
+func (b *BPE) Encode(text string) []int {
+	var ids []int
+	for _, piece := range b.split(text) {
+		if rank, ok := b.ranks[piece]; ok {
+			ids = append(ids, rank)
+			continue
+		}
+		for _, part := range b.merge(piece) {
+			ids = append(ids, b.ranks[part])
+		}
+	}
+	return ids
+}
+
+// merge applies merges to piece, lowest rank first, until none applies, and
+// returns the resulting tokens. Pieces start as single bytes, which all
+// byte-level vocabularies contain.
+func (b *BPE) merge(piece string) []string {
+	// bounds[i] is the start of part i; the last entry is len(piece)
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best, at := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < best {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		bounds = append(bounds[:at+1], bounds[at+2:]...)
+	}
+
+	parts := make([]string, len(bounds)-1)
+	for i := range parts {
+		parts[i] = piece[bounds[i]:bounds[i+1]]
+	}
+	return parts
+}
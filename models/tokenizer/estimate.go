@@ -0,0 +1,98 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator approximates a BPE tokenizer without its vocabulary. Text is
+// pre-tokenized like the real encoding, and each piece is costed by its
+// shape: common words are one token, long words and identifiers split at
+// case changes, symbols pair up and non-Latin text costs about a token per
+// character.
+type Estimator struct {
+	name  string
+	split func(string) []string
+	scale float64 // Tokens relative to cl100k_base for the same text
+}
+
+// NewEstimator creates an estimator over a pre-tokenizer. scale adjusts the
+// count for vocabularies that encode text in more or fewer tokens than
+// cl100k_base.
+func NewEstimator(name string, split func(string) []string, scale float64) *Estimator {
+	return &Estimator{name: name, split: split, scale: scale}
+}
+
+// Name returns the name of the approximated encoding.
+func (e *Estimator) Name() string {
+	return e.name
+}
+
+// Count returns the estimated number of tokens in text.
+func (e *Estimator) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	total := 0
+	for _, piece := range e.split(text) {
+		total += pieceCost(piece)
+	}
+	return int(math.Ceil(float64(total) * e.scale))
+}
+
+// wordTokenLen is the length of a word segment that usually fits one token.
+const wordTokenLen = 8
+
+// pieceCost estimates the tokens of one pre-tokenized piece.
+func pieceCost(piece string) int {
+	r, _ := utf8.DecodeRuneInString(piece)
+	switch {
+	case unicode.IsSpace(r) && isAllSpace(piece):
+		// Vocabularies have tokens for long runs of indentation
+		return 1 + utf8.RuneCountInString(piece)/16
+	case isNumber(r):
+		return 1
+	}
+
+	cost, segment, symbols, wide := 0, 0, 0, 0
+	prevLower := false
+	flush := func() {
+		if segment > 0 {
+			cost += (segment + wordTokenLen - 1) / wordTokenLen
+		}
+		segment = 0
+	}
+	for i, r := range piece {
+		switch {
+		case r >= utf8.RuneSelf && isLetter(r):
+			// Non-Latin scripts take about a token per character
+			flush()
+			wide++
+		case isLetter(r):
+			if unicode.IsUpper(r) && prevLower {
+				flush() // camelCase boundary
+			}
+			segment++
+			prevLower = unicode.IsLower(r)
+		case r == '\r' || r == '\n':
+			// Trailing newlines join the preceding symbols
+		case i == 0 && (r == ' ' || unicode.IsSpace(r)):
+			// Leading space is part of the following word
+		default:
+			flush()
+			symbols++
+		}
+	}
+	flush()
+	return max(1, cost+wide+(symbols+1)/2)
+}
+
+func isAllSpace(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
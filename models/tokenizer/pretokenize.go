@@ -0,0 +1,165 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pre-tokenizers split text into the pieces BPE merges within, as the
+// splitting regular expressions of tiktoken's encodings do. They are written
+// out by hand because the patterns use lookahead, which Go's regexp lacks.
+
+// splitCL100K splits text like the cl100k_base pattern, also used by Llama 3:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitCL100K(text string) []string {
+	return split(text, false)
+}
+
+// splitO200K splits text like the o200k_base pattern, which additionally
+// breaks words at case changes and keeps contractions with their word:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|...)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|...)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitO200K(text string) []string {
+	return split(text, true)
+}
+
+func split(text string, o200k bool) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		n := match(text[i:], o200k)
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+// match returns the length of the piece at the start of s, which is never
+// zero for non-empty s.
+func match(s string, o200k bool) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	if !o200k {
+		if n := contraction(s); n > 0 {
+			return n
+		}
+	}
+
+	// Words, with an optional leading space or symbol
+	start := 0
+	if !isLetter(r) && !isNumber(r) && r != '\r' && r != '\n' {
+		if next, _ := utf8.DecodeRuneInString(s[size:]); size < len(s) && isLetter(next) {
+			start = size
+		}
+	}
+	if first, _ := utf8.DecodeRuneInString(s[start:]); start < len(s) && isLetter(first) {
+		var end int
+		if o200k {
+			end = start + o200kWord(s[start:])
+			end += contraction(s[end:])
+		} else {
+			end = start + scan(s[start:], isLetter, -1)
+		}
+		if end > start {
+			return end
+		}
+	}
+
+	// Up to three digits
+	if isNumber(r) {
+		return scan(s, isNumber, 3)
+	}
+
+	// Symbols, with an optional leading space and trailing newlines
+	start = 0
+	if r == ' ' && size < len(s) {
+		if next, _ := utf8.DecodeRuneInString(s[size:]); isSymbol(next) {
+			start = size
+		}
+	}
+	if first, _ := utf8.DecodeRuneInString(s[start:]); isSymbol(first) {
+		end := start + scan(s[start:], isSymbol, -1)
+		trailing := func(r rune) bool { return r == '\r' || r == '\n' || (o200k && r == '/') }
+		return end + scan(s[end:], trailing, -1)
+	}
+
+	// Whitespace
+	if unicode.IsSpace(r) {
+		run := scan(s, unicode.IsSpace, -1)
+		// \s*[\r\n]+ ends after the last newline of the run
+		if last := strings.LastIndexAny(s[:run], "\r\n"); last >= 0 {
+			return last + 1
+		}
+		// \s+(?!\S) leaves the last space to the following word
+		if run < len(s) {
+			if _, lastSize := utf8.DecodeLastRuneInString(s[:run]); run > lastSize {
+				return run - lastSize
+			}
+		}
+		return run
+	}
+
+	return size
+}
+
+// o200kWord matches an o200k word: optional capitals followed by lower case
+// letters, or capitals alone. Modifier and other letters and marks count as
+// either case.
+func o200kWord(s string) int {
+	upper := scan(s, func(r rune) bool { return isUpperClass(r) || isBothClass(r) }, -1)
+	lower := scan(s[upper:], func(r rune) bool { return isLowerClass(r) || isBothClass(r) }, -1)
+	return upper + lower
+}
+
+// contraction matches 's, 't, 're, 've, 'm, 'll or 'd in any case.
+func contraction(s string) int {
+	if len(s) < 2 || s[0] != '\'' {
+		return 0
+	}
+	lower := strings.ToLower(s[:min(3, len(s))])
+	for _, c := range []string{"'re", "'ve", "'ll"} {
+		if strings.HasPrefix(lower, c) {
+			return 3
+		}
+	}
+	switch lower[1] {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	return 0
+}
+
+// scan returns the byte length of the leading runes of s matching f, up to
+// limit runes (no limit if negative).
+func scan(s string, f func(rune) bool, limit int) int {
+	n, count := 0, 0
+	for n < len(s) && (limit < 0 || count < limit) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !f(r) {
+			break
+		}
+		n += size
+		count++
+	}
+	return n
+}
+
+func isLetter(r rune) bool { return unicode.IsLetter(r) }
+func isNumber(r rune) bool { return unicode.IsNumber(r) }
+
+// isSymbol matches [^\s\p{L}\p{N}].
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !isLetter(r) && !isNumber(r)
+}
+
+func isUpperClass(r rune) bool { return unicode.IsUpper(r) || unicode.IsTitle(r) }
+func isLowerClass(r rune) bool { return unicode.IsLower(r) }
+
+// isBothClass matches letters and marks o200k accepts in either position.
+func isBothClass(r rune) bool {
+	return unicode.In(r, unicode.Lm, unicode.Lo, unicode.M)
+}
@@ -0,0 +1,218 @@
+package tokenizer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// SentencePiece piece types
+const (
+	pieceNormal      = 1
+	pieceUnknown     = 2
+	pieceControl     = 3
+	pieceUserDefined = 4
+	pieceUnused      = 5
+	pieceByte        = 6
+)
+
+// spaceSymbol replaces spaces in SentencePiece vocabularies.
+const spaceSymbol = "▁"
+
+// maxChunk bounds the runes merged at once, keeping merges fast on text
+// without spaces such as minified code.
+const maxChunk = 256
+
+// SentencePiece is a SentencePiece BPE tokenizer, as used by Llama 2,
+// Mistral and Gemma.
+type SentencePiece struct {
+	name        string
+	scores      map[string]float32
+	byteTokens  bool // Unknown characters fall back to one token per byte
+	dummyPrefix bool // A space is added before the text
+}
+
+// LoadSentencePiece parses a SentencePiece model (tokenizer.model).
+func LoadSentencePiece(name string, data []byte) (*SentencePiece, error) {
+	sp := &SentencePiece{name: name, scores: make(map[string]float32), dummyPrefix: true}
+
+	err := protoFields(data, func(field int, value []byte) error {
+		switch field {
+		case 1: // pieces
+			return sp.addPiece(value)
+		case 3: // normalizer_spec
+			return protoFields(value, func(field int, value []byte) error {
+				if field == 3 && len(value) > 0 { // add_dummy_prefix
+					sp.dummyPrefix = value[0] != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	if len(sp.scores) == 0 {
+		return nil, fmt.Errorf("%s has no pieces", name)
+	}
+	return sp, nil
+}
+
+// addPiece adds one SentencePiece message to the vocabulary.
+func (sp *SentencePiece) addPiece(data []byte) error {
+	var piece string
+	var score float32
+	kind := pieceNormal
+	err := protoFields(data, func(field int, value []byte) error {
+		switch field {
+		case 1:
+			piece = string(value)
+		case 2:
+			if len(value) == 4 {
+				score = math.Float32frombits(binary.LittleEndian.Uint32(value))
+			}
+		case 3:
+			if v, n := binary.Uvarint(value); n > 0 {
+				kind = int(v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case pieceNormal, pieceUserDefined:
+		sp.scores[piece] = score
+	case pieceByte:
+		sp.byteTokens = true
+	}
+	return nil
+}
+
+// Name returns the model name.
+func (sp *SentencePiece) Name() string {
+	return sp.name
+}
+
+// Count returns the number of tokens in text.
+func (sp *SentencePiece) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	text = strings.ReplaceAll(text, " ", spaceSymbol)
+	if sp.dummyPrefix {
+		text = spaceSymbol + text
+	}
+
+	n := 0
+	for _, chunk := range chunks(text) {
+		for _, symbol := range sp.merge(chunk) {
+			switch {
+			case sp.has(symbol):
+				n++
+			case sp.byteTokens:
+				n += len(symbol)
+			default:
+				n += utf8.RuneCountInString(symbol) // One unknown token each
+			}
+		}
+	}
+	return n
+}
+
+func (sp *SentencePiece) has(symbol string) bool {
+	_, ok := sp.scores[symbol]
+	return ok
+}
+
+// merge joins adjacent symbols of chunk, highest scoring pair first, until no
+// pair is in the vocabulary.
+func (sp *SentencePiece) merge(chunk string) []string {
+	var symbols []string
+	for _, r := range chunk {
+		symbols = append(symbols, string(r))
+	}
+
+	for len(symbols) > 1 {
+		best, at := float32(math.Inf(-1)), -1
+		for i := 0; i+1 < len(symbols); i++ {
+			if score, ok := sp.scores[symbols[i]+symbols[i+1]]; ok && score > best {
+				best, at = score, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		symbols[at] += symbols[at+1]
+		symbols = append(symbols[:at+1], symbols[at+2:]...)
+	}
+	return symbols
+}
+
+// chunks splits text before each word, keeping a run of spaces with the word
+// that follows it, as pieces don't span words.
+func chunks(text string) []string {
+	var out []string
+	start, runes := 0, 0
+	inWord := false
+	for i, r := range text {
+		isSpace := string(r) == spaceSymbol
+		if (isSpace && inWord) || runes == maxChunk {
+			out = append(out, text[start:i])
+			start, runes = i, 0
+		}
+		inWord = !isSpace
+		runes++
+	}
+	return append(out, text[start:])
+}
+
+// protoFields calls f with the number and raw value of each field of a
+// protobuf message. Varints are passed encoded, fixed32 values as 4 bytes.
+func protoFields(data []byte, f func(field int, value []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+
+		var value []byte
+		switch wire {
+		case 0: // varint
+			_, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint in field %d", field)
+			}
+			value, data = data[:n], data[n:]
+		case 1: // fixed64
+			if len(data) < 8 {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			value, data = data[:8], data[8:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			value, data = data[n:n+int(size)], data[n+int(size):]
+		case 5: // fixed32
+			if len(data) < 4 {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			value, data = data[:4], data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wire, field)
+		}
+
+		if err := f(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
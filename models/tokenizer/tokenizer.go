@@ -0,0 +1,199 @@
+// Package tokenizer counts tokens the way model vocabularies do: byte-level
+// BPE compatible with tiktoken for OpenAI and Llama 3 models, SentencePiece
+// for Llama 2 style models, and an approximation for Claude, whose tokenizer
+// is not published.
+//
+// Vocabularies are large, so the repository does not carry them. Files
+// placed in Dir() (cl100k_base.tiktoken, o200k_base.tiktoken,
+// llama3.tiktoken, llama.model) give exact counts, as do those under
+// tokenizers/ of the assets, where a build can embed them for air-gapped
+// hosts; without them each encoding falls back to an estimator that
+// pre-tokenizes text the same way.
+package tokenizer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/abrksh22/bplus/internal/assets"
+)
+
+// Tokenizer counts the tokens of text.
+type Tokenizer interface {
+	// Name returns the encoding name
+	Name() string
+	// Count returns the number of tokens in text
+	Count(text string) int
+}
+
+// Encodings
+const (
+	CL100K = "cl100k_base" // GPT-4, GPT-3.5 and embeddings
+	O200K  = "o200k_base"  // GPT-4o, GPT-4.1, GPT-5 and o-series
+	Claude = "claude"      // Anthropic models (approximate)
+	Llama3 = "llama3"      // Llama 3 and later
+	Llama  = "llama"       // Llama 2, Code Llama, Mistral and other SentencePiece models
+)
+
+// encoding describes how to build a tokenizer for one encoding.
+type encoding struct {
+	file  string                // Vocabulary file in Dir()
+	split func(string) []string // Pre-tokenizer
+	scale float64               // Estimator scale without the vocabulary
+}
+
+var encodings = map[string]encoding{
+	CL100K: {file: "cl100k_base.tiktoken", split: splitCL100K, scale: 1},
+	O200K:  {file: "o200k_base.tiktoken", split: splitO200K, scale: 0.95},
+	Claude: {split: splitCL100K, scale: 1.1},
+	Llama3: {file: "llama3.tiktoken", split: splitCL100K, scale: 0.95},
+	Llama:  {file: "llama.model", split: splitCL100K, scale: 1.2},
+}
+
+// modelEncodings maps model name prefixes to encodings, most specific first.
+var modelEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", O200K},
+	{"gpt-4.1", O200K},
+	{"gpt-4.5", O200K},
+	{"gpt-5", O200K},
+	{"o1", O200K},
+	{"o3", O200K},
+	{"o4", O200K},
+	{"chatgpt-4o", O200K},
+	{"gpt-4", CL100K},
+	{"gpt-3.5", CL100K},
+	{"text-embedding", CL100K},
+	{"claude", Claude},
+	{"anthropic", Claude},
+	{"llama3", Llama3},
+	{"llama-3", Llama3},
+	{"llama4", Llama3},
+	{"llama-4", Llama3},
+	{"meta-llama/llama-3", Llama3},
+	{"meta-llama/llama-4", Llama3},
+	{"llama", Llama},
+	{"codellama", Llama},
+	{"meta-llama", Llama},
+	{"mistral", Llama},
+	{"mixtral", Llama},
+	{"gemma", Llama},
+}
+
+// EncodingFor returns the encoding of a model, given as "provider/model" or
+// a bare model ID. Unknown models use cl100k_base.
+func EncodingFor(model string) string {
+	name := strings.ToLower(model)
+	_, id, found := strings.Cut(name, "/")
+	if !found {
+		id = name
+	}
+	for _, candidate := range []string{id, name} {
+		// Names like "qwen/llama-3-8b" or "llama3:8b" carry the base model
+		base := candidate[strings.LastIndex(candidate, "/")+1:]
+		for _, m := range modelEncodings {
+			if strings.HasPrefix(candidate, m.prefix) || strings.HasPrefix(base, m.prefix) {
+				return m.encoding
+			}
+		}
+	}
+	return CL100K
+}
+
+var (
+	mu     sync.Mutex
+	loaded = make(map[string]Tokenizer)
+	dir    string
+)
+
+// Dir returns the directory vocabulary files are loaded from:
+// $BPLUS_TOKENIZER_DIR, or ~/.cache/bplus/tokenizers.
+func Dir() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return vocabDir()
+}
+
+// SetDir changes the vocabulary directory and drops loaded tokenizers.
+func SetDir(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	dir = path
+	loaded = make(map[string]Tokenizer)
+}
+
+func vocabDir() string {
+	if dir != "" {
+		return dir
+	}
+	if env := os.Getenv("BPLUS_TOKENIZER_DIR"); env != "" {
+		return env
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cache, "bplus", "tokenizers")
+	}
+	return ""
+}
+
+// ForModel returns the tokenizer of a model.
+func ForModel(model string) Tokenizer {
+	return Get(EncodingFor(model))
+}
+
+// Get returns the tokenizer of an encoding: exact if its vocabulary file is
+// available, estimated otherwise. Unknown encodings use cl100k_base.
+func Get(name string) Tokenizer {
+	enc, ok := encodings[name]
+	if !ok {
+		name, enc = CL100K, encodings[CL100K]
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if t, ok := loaded[name]; ok {
+		return t
+	}
+	t := load(name, enc)
+	loaded[name] = t
+	return t
+}
+
+// load builds the tokenizer of an encoding, preferring its vocabulary from
+// Dir(), then from the assets.
+func load(name string, enc encoding) Tokenizer {
+	if enc.file != "" {
+		if data, ok := readVocab(enc.file); ok {
+			if strings.HasSuffix(enc.file, ".tiktoken") {
+				if bpe, err := LoadTiktoken(name, bytes.NewReader(data), enc.split); err == nil {
+					return bpe
+				}
+			} else if sp, err := LoadSentencePiece(name, data); err == nil {
+				return sp
+			}
+		}
+	}
+	return NewEstimator(name, enc.split, enc.scale)
+}
+
+// readVocab returns the contents of a vocabulary file.
+func readVocab(file string) ([]byte, bool) {
+	if vocabDir() != "" {
+		if data, err := os.ReadFile(filepath.Join(vocabDir(), file)); err == nil {
+			return data, true
+		}
+	}
+	if data, err := assets.Read(assets.Tokenizers + "/" + file); err == nil {
+		return data, true
+	}
+	return nil, false
+}
+
+// Count returns the number of tokens text has for model.
+func Count(model, text string) int {
+	return ForModel(model).Count(text)
+}
@@ -0,0 +1,218 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCL100K(t *testing.T) {
+	tests := map[string][]string{
+		"Hello, world!":        {"Hello", ",", " world", "!"},
+		"I'm here":             {"I", "'m", " here"},
+		"12345 apples":         {"123", "45", " apples"},
+		"func main() {\n}":     {"func", " main", "()", " {\n", "}"},
+		"a   b":                {"a", "  ", " b"},
+		"x\n\n  y":             {"x", "\n\n", " ", " y"},
+		"trailing   ":          {"trailing", "   "},
+		"getUserName(id)":      {"getUserName", "(id", ")"},
+		"\tindented":           {"\tindented"},
+		"naïve café":           {"naïve", " café"},
+		"path/to/file.go":      {"path", "/to", "/file", ".go"},
+		"price: $100":          {"price", ":", " $", "100"},
+		"mixed 日本語 text":       {"mixed", " 日本語", " text"},
+		"  \n":                 {"  \n"},
+		"DON'T":                {"DON", "'T"},
+		"":                     nil,
+		"https://example.com/": {"https", "://", "example", ".com", "/"},
+	}
+	for text, want := range tests {
+		assert.Equal(t, want, splitCL100K(text), "%q", text)
+		assert.Equal(t, text, strings.Join(splitCL100K(text), ""))
+	}
+}
+
+func TestSplitO200K(t *testing.T) {
+	tests := map[string][]string{
+		"HelloWorld":      {"Hello", "World"},
+		"I'm here":        {"I'm", " here"},
+		"HTTPServer":      {"HTTPServer"},
+		"getUserName":     {"get", "User", "Name"},
+		"path/to/":        {"path", "/to", "/"},
+		"a+/\nb":          {"a", "+/\n", "b"},
+		"XMLHttpRequest2": {"XMLHttp", "Request", "2"},
+	}
+	for text, want := range tests {
+		assert.Equal(t, want, splitO200K(text), "%q", text)
+	}
+}
+
+func TestBPE(t *testing.T) {
+	// Every byte, plus merges for "hello" and " world"
+	var lines []string
+	rank := 0
+	add := func(token string) {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(token)), rank))
+		rank++
+	}
+	for b := 0; b < 256; b++ {
+		add(string([]byte{byte(b)}))
+	}
+	for _, token := range []string{"he", "ll", "hell", "hello", " w", "or", " wor", "ld", " world"} {
+		add(token)
+	}
+
+	bpe, err := LoadTiktoken("test", strings.NewReader(strings.Join(lines, "\n")), splitCL100K)
+	require.NoError(t, err)
+	assert.Equal(t, "test", bpe.Name())
+
+	assert.Equal(t, 2, bpe.Count("hello world"))
+	assert.Equal(t, []int{259, 264}, bpe.Encode("hello world"))
+	assert.Equal(t, 3, bpe.Count("help")) // "he", "l", "p"
+	assert.Equal(t, 0, bpe.Count(""))
+
+	_, err = LoadTiktoken("bad", strings.NewReader("not-base64! 1"), splitCL100K)
+	assert.Error(t, err)
+}
+
+// spModel encodes a SentencePiece model with the given pieces.
+func spModel(pieces map[string]float32, byteFallback bool) []byte {
+	var model []byte
+	field := func(buf []byte, num int, wire uint64, value []byte) []byte {
+		buf = binary.AppendUvarint(buf, uint64(num)<<3|wire)
+		if wire == 2 {
+			buf = binary.AppendUvarint(buf, uint64(len(value)))
+		}
+		return append(buf, value...)
+	}
+	addPiece := func(text string, score float32, kind uint64) {
+		var piece []byte
+		piece = field(piece, 1, 2, []byte(text))
+		piece = field(piece, 2, 5, binary.LittleEndian.AppendUint32(nil, math.Float32bits(score)))
+		piece = field(piece, 3, 0, binary.AppendUvarint(nil, kind))
+		model = field(model, 1, 2, piece)
+	}
+	addPiece("<unk>", 0, pieceUnknown)
+	for text, score := range pieces {
+		addPiece(text, score, pieceNormal)
+	}
+	if byteFallback {
+		for b := 0; b < 256; b++ {
+			addPiece(fmt.Sprintf("<0x%02X>", b), 0, pieceByte)
+		}
+	}
+	return model
+}
+
+func TestSentencePiece(t *testing.T) {
+	pieces := map[string]float32{
+		"▁": -1, "h": -2, "e": -2, "l": -2, "o": -2, "w": -2, "r": -2, "d": -2,
+		"▁h": -3, "▁he": -4, "ll": -3, "▁hell": -5, "▁hello": -6,
+		"▁w": -3, "or": -3, "▁wor": -5, "ld": -3, "▁world": -6,
+	}
+	sp, err := LoadSentencePiece("test", spModel(pieces, true))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, sp.Count("hello world"))
+	assert.Equal(t, 0, sp.Count(""))
+	// "▁hello", "▁", then "é" as its two bytes
+	assert.Equal(t, 4, sp.Count("hello é"))
+
+	sp, err = LoadSentencePiece("no-bytes", spModel(pieces, false))
+	require.NoError(t, err)
+	assert.Equal(t, 3, sp.Count("hello é"))
+
+	_, err = LoadSentencePiece("bad", []byte{0xff})
+	assert.Error(t, err)
+}
+
+func TestEstimator(t *testing.T) {
+	e := NewEstimator(CL100K, splitCL100K, 1)
+
+	// Counts of cl100k_base for the same text
+	assert.Equal(t, 4, e.Count("Hello, world!"))
+	assert.Equal(t, 0, e.Count(""))
+	assert.InDelta(t, 9, e.Count("The quick brown fox jumps over the lazy dog."), 2)
+	assert.InDelta(t, 12, e.Count("func (p *Provider) Name() string {\n\treturn p.name\n}"), 4)
+
+	// Identifiers split at case changes, non-Latin text costs per character
+	assert.Greater(t, e.Count("getUserAccountName"), e.Count("name"))
+	assert.GreaterOrEqual(t, e.Count("日本語のテキスト"), 8)
+
+	// Each encoding is scaled relative to cl100k_base
+	long := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100)
+	claude := NewEstimator(Claude, splitCL100K, 1.1)
+	assert.Greater(t, claude.Count(long), e.Count(long))
+}
+
+func TestEncodingFor(t *testing.T) {
+	tests := map[string]string{
+		"openai/gpt-4o-mini":                   O200K,
+		"openai/gpt-4-turbo":                   CL100K,
+		"openai/o3-mini":                       O200K,
+		"gpt-5":                                O200K,
+		"anthropic/claude-sonnet-4-5":          Claude,
+		"openrouter/anthropic/claude-3.5":      Claude,
+		"ollama/llama3:8b":                     Llama3,
+		"openrouter/meta-llama/llama-3-70b":    Llama3,
+		"ollama/codellama:34b":                 Llama,
+		"lmstudio/mistral-7b-instruct":         Llama,
+		"ollama/qwen2.5-coder:7b":              CL100K,
+		"deepseek/deepseek-chat":               CL100K,
+		"":                                     CL100K,
+		"openrouter/meta-llama/llama-2-13b-ch": Llama,
+	}
+	for model, want := range tests {
+		assert.Equal(t, want, EncodingFor(model), model)
+	}
+}
+
+func TestForModel_LoadsVocabulary(t *testing.T) {
+	dir := t.TempDir()
+	SetDir(dir)
+	defer SetDir("")
+
+	_, ok := ForModel("openai/gpt-4").(*Estimator)
+	assert.True(t, ok, "estimates without a vocabulary")
+
+	var lines []string
+	for b := 0; b < 256; b++ {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cl100k_base.tiktoken"), []byte(strings.Join(lines, "\n")), 0o644))
+	SetDir(dir)
+
+	tok := ForModel("openai/gpt-4")
+	_, ok = tok.(*BPE)
+	require.True(t, ok, "loads the vocabulary")
+	assert.Equal(t, CL100K, tok.Name())
+	assert.Equal(t, 5, Count("openai/gpt-4", "hello")) // Bytes only, no merges
+}
+
+func TestForModel_LoadsVocabularyFromAssets(t *testing.T) {
+	assetDir := t.TempDir()
+	t.Setenv("BPLUS_ASSETS_PATH", assetDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	SetDir(t.TempDir())
+	defer SetDir("")
+
+	var lines []string
+	for b := 0; b < 256; b++ {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(assetDir, "tokenizers"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(assetDir, "tokenizers", "o200k_base.tiktoken"), []byte(strings.Join(lines, "\n")), 0o644))
+
+	tok := ForModel("openai/gpt-4o")
+	_, ok := tok.(*BPE)
+	require.True(t, ok, "loads the vocabulary from the assets when Dir() lacks it")
+	assert.Equal(t, O200K, tok.Name())
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/abrksh22/bplus/models/tokenizer"
+
+// CountTokens returns the number of tokens text has for model, given as
+// "provider/model" or a bare model ID. Counts are exact when the model's
+// vocabulary is installed (see tokenizer.Dir) and estimated otherwise.
+func CountTokens(model, text string) int {
+	return tokenizer.Count(model, text)
+}
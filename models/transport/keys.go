@@ -0,0 +1,288 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key selection strategies
+const (
+	// StrategyRoundRobin cycles through keys, skipping throttled ones.
+	StrategyRoundRobin = "round_robin"
+
+	// StrategyLeastThrottled prefers the key that was throttled least recently.
+	StrategyLeastThrottled = "least_throttled"
+)
+
+// defaultThrottleCooldown is how long a key is rested after a 429 without a
+// Retry-After header.
+const defaultThrottleCooldown = 30 * time.Second
+
+// authHeaders are the headers providers send API keys in.
+var authHeaders = []string{"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key"}
+
+// KeyStats reports usage of one key in a pool.
+type KeyStats struct {
+	Key            string // Masked key ("sk-...abcd")
+	Requests       int64
+	Throttles      int64     // Responses with HTTP 429
+	LastThrottled  time.Time // Zero if never throttled
+	ThrottledUntil time.Time // Zero if usable now
+}
+
+// KeyPool selects among several API keys for one provider and tracks which
+// of them are being rate limited.
+type KeyPool struct {
+	mu       sync.Mutex
+	keys     []*poolKey
+	strategy string
+	next     int
+	now      func() time.Time
+}
+
+type poolKey struct {
+	value          string
+	requests       int64
+	throttles      int64
+	lastThrottled  time.Time
+	throttledUntil time.Time
+}
+
+// NewKeyPool creates a pool over keys using the given strategy
+// (StrategyRoundRobin if empty or unknown). Duplicate and empty keys are dropped.
+func NewKeyPool(keys []string, strategy string) *KeyPool {
+	if strategy != StrategyLeastThrottled {
+		strategy = StrategyRoundRobin
+	}
+	p := &KeyPool{strategy: strategy, now: time.Now}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		p.keys = append(p.keys, &poolKey{value: k})
+	}
+	return p
+}
+
+// Len returns the number of keys in the pool.
+func (p *KeyPool) Len() int {
+	return len(p.keys)
+}
+
+// Next returns the key to use for the next request. Keys resting after a 429
+// are skipped; if every key is resting, the one available soonest is used.
+func (p *KeyPool) Next() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.keys) == 0 {
+		return ""
+	}
+	now := p.now()
+
+	var chosen *poolKey
+	switch p.strategy {
+	case StrategyLeastThrottled:
+		for _, k := range p.keys {
+			if !k.available(now) {
+				continue
+			}
+			if chosen == nil || k.lastThrottled.Before(chosen.lastThrottled) {
+				chosen = k
+			}
+		}
+	default:
+		for i := 0; i < len(p.keys); i++ {
+			k := p.keys[(p.next+i)%len(p.keys)]
+			if k.available(now) {
+				chosen = k
+				p.next = (p.next + i + 1) % len(p.keys)
+				break
+			}
+		}
+	}
+
+	if chosen == nil {
+		for _, k := range p.keys {
+			if chosen == nil || k.throttledUntil.Before(chosen.throttledUntil) {
+				chosen = k
+			}
+		}
+	}
+
+	chosen.requests++
+	return chosen.value
+}
+
+// MarkThrottled records a 429 for key, resting it for retryAfter (or the
+// default cooldown if zero).
+func (p *KeyPool) MarkThrottled(key string, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = defaultThrottleCooldown
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.value == key {
+			now := p.now()
+			k.throttles++
+			k.lastThrottled = now
+			k.throttledUntil = now.Add(retryAfter)
+			return
+		}
+	}
+}
+
+// Available reports whether any key is usable right now.
+func (p *KeyPool) Available() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for _, k := range p.keys {
+		if k.available(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns per-key usage, with keys masked.
+func (p *KeyPool) Stats() []KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	stats := make([]KeyStats, len(p.keys))
+	for i, k := range p.keys {
+		stats[i] = KeyStats{
+			Key:           maskKey(k.value),
+			Requests:      k.requests,
+			Throttles:     k.throttles,
+			LastThrottled: k.lastThrottled,
+		}
+		if !k.available(now) {
+			stats[i].ThrottledUntil = k.throttledUntil
+		}
+	}
+	return stats
+}
+
+func (k *poolKey) available(now time.Time) bool {
+	return !now.Before(k.throttledUntil)
+}
+
+// maskKey hides all but the ends of a key.
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:3] + "..." + key[len(key)-4:]
+}
+
+// KeyRotator is an http.RoundTripper that spreads requests across a key
+// pool. Providers are built with the pool's first key; the rotator swaps it
+// for the selected key in auth headers and the "key" query parameter, and
+// retries a throttled request with another key when one is available.
+type KeyRotator struct {
+	base    http.RoundTripper
+	primary string
+	pool    *KeyPool
+}
+
+// NewKeyRotator wraps base so requests authenticated with primary use keys
+// from pool instead.
+func NewKeyRotator(base http.RoundTripper, primary string, pool *KeyPool) *KeyRotator {
+	return &KeyRotator{base: base, primary: primary, pool: pool}
+}
+
+// NewKeyedClient returns an http.Client over the shared rate limiter that
+// rotates keys from pool in place of primary.
+func NewKeyedClient(timeout time.Duration, primary string, pool *KeyPool) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewKeyRotator(Limiter(), primary, pool),
+	}
+}
+
+// Pool returns the rotator's key pool.
+func (r *KeyRotator) Pool() *KeyPool {
+	return r.pool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *KeyRotator) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := r.pool.Len()
+	if attempts == 0 || r.primary == "" {
+		return r.base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		key := r.pool.Next()
+		keyed, err := r.withKey(req, key, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+		// While another key could take the request, a 429 is handled here
+		// rather than waited out by the rate limiter
+		if attempt+1 < attempts {
+			keyed = keyed.WithContext(withoutLimiterRetry(keyed.Context()))
+		}
+
+		resp, err := r.base.RoundTrip(keyed)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+
+		r.pool.MarkThrottled(key, retryAfter(resp.Header.Get("Retry-After")))
+
+		// Retry with another key only if one is usable and the body can be replayed
+		if attempt+1 >= attempts || !r.pool.Available() || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+// withKey returns a copy of req authenticated with key. A fresh body is
+// obtained for retries.
+func (r *KeyRotator) withKey(req *http.Request, key string, retry bool) (*http.Request, error) {
+	out := req.Clone(req.Context())
+	if retry && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+
+	for _, h := range authHeaders {
+		if v := out.Header.Get(h); v != "" && strings.Contains(v, r.primary) {
+			out.Header.Set(h, strings.ReplaceAll(v, r.primary, key))
+		}
+	}
+	if q := out.URL.Query(); q.Get("key") == r.primary {
+		q.Set("key", key)
+		out.URL.RawQuery = q.Encode()
+	}
+	return out, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
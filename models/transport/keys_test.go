@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyPool_RoundRobin(t *testing.T) {
+	pool := NewKeyPool([]string{"a", "b", "", "a", "c"}, "")
+	require.Equal(t, 3, pool.Len())
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, pool.Next())
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, got)
+}
+
+func TestKeyPool_SkipsThrottled(t *testing.T) {
+	now := time.Now()
+	pool := NewKeyPool([]string{"a", "b"}, StrategyRoundRobin)
+	pool.now = func() time.Time { return now }
+
+	pool.MarkThrottled("a", time.Minute)
+	assert.Equal(t, "b", pool.Next())
+	assert.Equal(t, "b", pool.Next())
+
+	// All keys resting: the one available soonest is used
+	pool.MarkThrottled("b", 2*time.Minute)
+	assert.False(t, pool.Available())
+	assert.Equal(t, "a", pool.Next())
+
+	now = now.Add(time.Minute)
+	assert.True(t, pool.Available())
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats[0].Throttles)
+	assert.True(t, stats[0].ThrottledUntil.IsZero())
+	assert.False(t, stats[1].ThrottledUntil.IsZero())
+}
+
+func TestKeyPool_LeastThrottled(t *testing.T) {
+	now := time.Now()
+	pool := NewKeyPool([]string{"a", "b", "c"}, StrategyLeastThrottled)
+	pool.now = func() time.Time { return now }
+
+	pool.MarkThrottled("a", time.Second)
+	now = now.Add(time.Second)
+	pool.MarkThrottled("b", time.Second)
+	now = now.Add(2 * time.Second)
+
+	// c was never throttled, then a was throttled longest ago
+	assert.Equal(t, "c", pool.Next())
+	pool.MarkThrottled("c", time.Second)
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, "a", pool.Next())
+}
+
+func TestKeyRotator_RetriesThrottledKey(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		seen = append(seen, key)
+		mu.Unlock()
+		if key == "key-one" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	pool := NewKeyPool([]string{"key-one", "key-two"}, StrategyRoundRobin)
+	client := &http.Client{Transport: NewKeyRotator(http.DefaultTransport, "key-one", pool)}
+
+	req, err := http.NewRequest("POST", server.URL, strings.NewReader(`{"prompt":"hi"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer key-one")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"key-one", "key-two"}, seen)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats[0].Throttles)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), stats[0].ThrottledUntil, 5*time.Second)
+}
+
+func TestKeyRotator_QueryKey(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Query().Get("key"))
+	}))
+	defer server.Close()
+
+	pool := NewKeyPool([]string{"key-one", "key-two"}, StrategyRoundRobin)
+	client := &http.Client{Transport: NewKeyRotator(http.DefaultTransport, "key-one", pool)}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "?key=key-one&alt=sse")
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, []string{"key-one", "key-two"}, got)
+}
+
+func TestMaskKey(t *testing.T) {
+	assert.Equal(t, "****", maskKey("short"))
+	assert.Equal(t, "sk-...wxyz", maskKey("sk-abcdefghijwxyz"))
+}
@@ -0,0 +1,116 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Network holds the proxy and TLS settings of one provider, for networks
+// that route API traffic through a proxy or inspect it with their own CA.
+type Network struct {
+	ProxyURL           string // Proxy for every request; empty uses HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	CACertPath         string // PEM bundle trusted in addition to the system roots
+	InsecureSkipVerify bool   // Skip server certificate verification
+}
+
+// IsZero reports whether n leaves the shared transport's settings as they are.
+func (n Network) IsZero() bool {
+	return n == Network{}
+}
+
+// NewNetworkTransport creates a transport from cfg that connects through
+// n's proxy and trusts n's CA bundle.
+func NewNetworkTransport(cfg Config, n Network) (*Transport, error) {
+	t := New(cfg)
+
+	if n.ProxyURL != "" {
+		proxy, err := url.Parse(n.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", n.ProxyURL)
+		}
+		t.base.Proxy = http.ProxyURL(proxy)
+	}
+
+	if n.CACertPath != "" || n.InsecureSkipVerify {
+		tlsCfg := &tls.Config{InsecureSkipVerify: n.InsecureSkipVerify}
+		if n.CACertPath != "" {
+			pool, err := certPool(n.CACertPath)
+			if err != nil {
+				return nil, err
+			}
+			tlsCfg.RootCAs = pool
+		}
+		t.base.TLSClientConfig = tlsCfg
+	}
+
+	return t, nil
+}
+
+// certPool returns the system roots plus the certificates in the PEM file at path.
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// NewNetworkClient returns an http.Client with the given timeout behind the
+// shared rate limiter whose requests leave over a transport with n's
+// settings, built from the shared transport's configuration.
+func NewNetworkClient(timeout time.Duration, n Network) (*http.Client, error) {
+	tr, err := NewNetworkTransport(SharedConfig(), n)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: Route(Limiter(), tr),
+	}, nil
+}
+
+// transportKey is the context key of a request's outgoing transport.
+type transportKey struct{}
+
+// Route returns a round tripper that passes requests to next, normally the
+// shared rate limiter, and has them sent over via instead of the shared
+// transport. Rate limits are still tracked with every other provider's.
+func Route(next, via http.RoundTripper) http.RoundTripper {
+	return &router{next: next, via: via}
+}
+
+type router struct {
+	next http.RoundTripper
+	via  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *router) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := context.WithValue(req.Context(), transportKey{}, r.via)
+	return r.next.RoundTrip(req.WithContext(ctx))
+}
+
+// routedTransport returns the transport a request was routed to, if any.
+func routedTransport(ctx context.Context) (http.RoundTripper, bool) {
+	rt, ok := ctx.Value(transportKey{}).(http.RoundTripper)
+	return rt, ok
+}
@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkClient_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("via proxy"))
+	}))
+	defer proxy.Close()
+
+	client, err := NewNetworkClient(5*time.Second, Network{ProxyURL: proxy.URL})
+	require.NoError(t, err)
+
+	resp, err := client.Get("http://api.example.invalid/v1/models")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, "via proxy", string(body))
+	assert.Equal(t, "http://api.example.invalid/v1/models", proxied)
+}
+
+func TestNetworkClient_CACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// The test server's self-signed certificate isn't trusted by default
+	client, err := NewNetworkClient(5*time.Second, Network{})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caPath, cert, 0o600))
+
+	client, err = NewNetworkClient(5*time.Second, Network{CACertPath: caPath})
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	client, err = NewNetworkClient(5*time.Second, Network{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestNewNetworkTransport_Errors(t *testing.T) {
+	_, err := NewNetworkTransport(DefaultConfig(), Network{ProxyURL: "ftp://proxy:21"})
+	assert.ErrorContains(t, err, "scheme")
+
+	_, err = NewNetworkTransport(DefaultConfig(), Network{CACertPath: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorContains(t, err, "failed to read CA certificates")
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0o600))
+	_, err = NewNetworkTransport(DefaultConfig(), Network{CACertPath: empty})
+	assert.ErrorContains(t, err, "no PEM certificates")
+}
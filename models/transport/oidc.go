@@ -0,0 +1,428 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDC flows a TokenSource can authenticate with
+const (
+	OIDCClientCredentials = "client_credentials" // A service identity with a client secret
+	OIDCDeviceCode        = "device_code"        // A user signing in on another device
+)
+
+// deviceCodeGrant is the grant type of the device authorization flow.
+const deviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+// tokenLeeway is how long before it expires an access token is replaced.
+const tokenLeeway = time.Minute
+
+// defaultDeviceInterval is how often the token endpoint is polled during the
+// device flow when the identity provider doesn't say.
+var defaultDeviceInterval = 5 * time.Second
+
+// TokenStore keeps refresh tokens between sessions, e.g. in the OS keychain.
+type TokenStore interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+}
+
+// DeviceCode is what a user needs to sign in during the device flow.
+type DeviceCode struct {
+	UserCode        string
+	VerificationURI string // Where to enter UserCode
+	CompleteURI     string // VerificationURI with the code filled in, if the provider gives one
+	ExpiresAt       time.Time
+}
+
+// DevicePrompt shows a device code to the user.
+type DevicePrompt func(name string, code DeviceCode)
+
+var (
+	devicePromptMu sync.RWMutex
+	devicePrompt   DevicePrompt = func(name string, code DeviceCode) {
+		fmt.Printf("To authenticate %s, visit %s and enter code %s\n", name, code.VerificationURI, code.UserCode)
+	}
+)
+
+// SetDevicePrompt sets how device codes are shown to the user. By default
+// they are printed to stdout.
+func SetDevicePrompt(prompt DevicePrompt) {
+	devicePromptMu.Lock()
+	defer devicePromptMu.Unlock()
+	devicePrompt = prompt
+}
+
+func showDeviceCode(name string, code DeviceCode) {
+	devicePromptMu.RLock()
+	prompt := devicePrompt
+	devicePromptMu.RUnlock()
+	if prompt != nil {
+		prompt(name, code)
+	}
+}
+
+// OIDCConfig describes how to get access tokens for a gateway protected by
+// an OpenID Connect identity provider, such as Azure AD.
+type OIDCConfig struct {
+	Name          string // Shown in device code prompts, e.g. the provider name
+	Flow          string // OIDCClientCredentials (default) or OIDCDeviceCode
+	Issuer        string // Endpoints are discovered from it unless given below
+	TokenURL      string
+	DeviceAuthURL string
+	ClientID      string
+	ClientSecret  string
+	Scopes        []string
+	Audience      string // Sent as the audience parameter, for providers that need one
+
+	Store    TokenStore // Keeps the refresh token; nil keeps it in memory only
+	StoreKey string     // Key of the refresh token in Store
+}
+
+// TokenSource gets access tokens with an OIDC flow, caches them until they
+// are about to expire and renews them with a refresh token when it has one.
+type TokenSource struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	resolved bool // Endpoints have been discovered
+	access   string
+	expiry   time.Time
+	refresh  string
+	loaded   bool // The refresh token has been read from the store
+}
+
+// NewTokenSource creates a token source for cfg whose requests to the
+// identity provider are sent with client.
+func NewTokenSource(cfg OIDCConfig, client *http.Client) (*TokenSource, error) {
+	if cfg.Flow == "" {
+		cfg.Flow = OIDCClientCredentials
+	}
+	switch {
+	case cfg.ClientID == "":
+		return nil, fmt.Errorf("OIDC client ID is required")
+	case cfg.Issuer == "" && cfg.TokenURL == "":
+		return nil, fmt.Errorf("OIDC issuer or token URL is required")
+	case cfg.Flow == OIDCClientCredentials && cfg.ClientSecret == "":
+		return nil, fmt.Errorf("OIDC client credentials flow requires a client secret")
+	case cfg.Flow != OIDCClientCredentials && cfg.Flow != OIDCDeviceCode:
+		return nil, fmt.Errorf("unknown OIDC flow %q: must be %s or %s", cfg.Flow, OIDCClientCredentials, OIDCDeviceCode)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &TokenSource{cfg: cfg, client: client}, nil
+}
+
+// Token returns a valid access token, getting a new one if needed. In the
+// device flow this waits until the user has signed in.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.access != "" && time.Now().Add(tokenLeeway).Before(s.expiry) {
+		return s.access, nil
+	}
+	if err := s.resolve(ctx); err != nil {
+		return "", err
+	}
+	if !s.loaded {
+		s.loaded = true
+		if s.cfg.Store != nil && s.refresh == "" {
+			s.refresh, _ = s.cfg.Store.Get(s.cfg.StoreKey)
+		}
+	}
+
+	if s.refresh != "" {
+		err := s.grant(ctx, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {s.refresh}})
+		if err == nil {
+			return s.access, nil
+		}
+		// A refresh token that was revoked or expired is dropped and the
+		// flow starts over
+		s.refresh = ""
+	}
+
+	var err error
+	switch s.cfg.Flow {
+	case OIDCDeviceCode:
+		err = s.deviceFlow(ctx)
+	default:
+		err = s.grant(ctx, url.Values{"grant_type": {"client_credentials"}})
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.access, nil
+}
+
+// Invalidate drops the cached access token, e.g. after the gateway rejected
+// it, so the next Token call gets a new one.
+func (s *TokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.access = ""
+}
+
+// resolve discovers the endpoints not configured from the issuer.
+func (s *TokenSource) resolve(ctx context.Context) error {
+	if s.resolved {
+		return nil
+	}
+	needDevice := s.cfg.Flow == OIDCDeviceCode && s.cfg.DeviceAuthURL == ""
+	if s.cfg.TokenURL != "" && !needDevice {
+		s.resolved = true
+		return nil
+	}
+	if s.cfg.Issuer == "" {
+		return fmt.Errorf("OIDC device authorization URL or issuer is required")
+	}
+
+	var doc struct {
+		TokenEndpoint               string `json:"token_endpoint"`
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	}
+	discovery := strings.TrimSuffix(s.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, "GET", discovery, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OIDC discovery failed: %s returned %s", discovery, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode OIDC discovery document: %w", err)
+	}
+
+	if s.cfg.TokenURL == "" {
+		s.cfg.TokenURL = doc.TokenEndpoint
+	}
+	if s.cfg.DeviceAuthURL == "" {
+		s.cfg.DeviceAuthURL = doc.DeviceAuthorizationEndpoint
+	}
+	if s.cfg.TokenURL == "" || (s.cfg.Flow == OIDCDeviceCode && s.cfg.DeviceAuthURL == "") {
+		return fmt.Errorf("identity provider %s does not advertise the endpoints of the %s flow", s.cfg.Issuer, s.cfg.Flow)
+	}
+	s.resolved = true
+	return nil
+}
+
+// tokenResponse is the token endpoint's answer, successful or not.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// oauthError is an error the token endpoint returned.
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+func (e *oauthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("token request failed: %s: %s", e.Code, e.Description)
+	}
+	return "token request failed: " + e.Code
+}
+
+// grant requests tokens from the token endpoint and keeps them.
+func (s *TokenSource) grant(ctx context.Context, form url.Values) error {
+	form.Set("client_id", s.cfg.ClientID)
+	if s.cfg.ClientSecret != "" {
+		form.Set("client_secret", s.cfg.ClientSecret)
+	}
+	if len(s.cfg.Scopes) > 0 && form.Get("grant_type") != deviceCodeGrant {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	if s.cfg.Audience != "" {
+		form.Set("audience", s.cfg.Audience)
+	}
+
+	var tok tokenResponse
+	if err := s.post(ctx, s.cfg.TokenURL, form, &tok); err != nil {
+		return err
+	}
+	if tok.Error != "" {
+		return &oauthError{Code: tok.Error, Description: tok.Description}
+	}
+	if tok.AccessToken == "" {
+		return fmt.Errorf("token response has no access token")
+	}
+
+	s.access = tok.AccessToken
+	s.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	if tok.ExpiresIn <= 0 {
+		s.expiry = time.Now().Add(time.Hour)
+	}
+	if tok.RefreshToken != "" && tok.RefreshToken != s.refresh {
+		s.refresh = tok.RefreshToken
+		if s.cfg.Store != nil {
+			if err := s.cfg.Store.Set(s.cfg.StoreKey, s.refresh); err != nil {
+				return fmt.Errorf("failed to store refresh token: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// deviceFlow has the user sign in on another device and waits until they
+// have.
+func (s *TokenSource) deviceFlow(ctx context.Context) error {
+	form := url.Values{"client_id": {s.cfg.ClientID}}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	var auth struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURL         string `json:"verification_url"` // Older Google-style name
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+		Error                   string `json:"error"`
+		Description             string `json:"error_description"`
+	}
+	if err := s.post(ctx, s.cfg.DeviceAuthURL, form, &auth); err != nil {
+		return err
+	}
+	if auth.Error != "" {
+		return &oauthError{Code: auth.Error, Description: auth.Description}
+	}
+	if auth.VerificationURI == "" {
+		auth.VerificationURI = auth.VerificationURL
+	}
+
+	expires := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	if auth.ExpiresIn <= 0 {
+		expires = time.Now().Add(15 * time.Minute)
+	}
+	showDeviceCode(s.cfg.Name, DeviceCode{
+		UserCode:        auth.UserCode,
+		VerificationURI: auth.VerificationURI,
+		CompleteURI:     auth.VerificationURIComplete,
+		ExpiresAt:       expires,
+	})
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDeviceInterval
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(expires) {
+			return fmt.Errorf("device code expired before sign-in completed")
+		}
+
+		err := s.grant(ctx, url.Values{"grant_type": {deviceCodeGrant}, "device_code": {auth.DeviceCode}})
+		var oerr *oauthError
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &oerr) && oerr.Code == "authorization_pending":
+		case errors.As(err, &oerr) && oerr.Code == "slow_down":
+			interval += 5 * time.Second
+		default:
+			return err
+		}
+	}
+}
+
+// post sends form to endpoint and decodes the JSON answer into out. OAuth
+// errors come back with a 400 status and a JSON body, so those are decoded
+// too.
+func (s *TokenSource) post(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read token response: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("token request failed: %s", resp.Status)
+		}
+		return fmt.Errorf("failed to decode token response: %w", err)
+	}
+	return nil
+}
+
+// NewBearerTransport returns a round tripper that authenticates requests to
+// a gateway with access tokens from src before passing them to next. A
+// request the gateway rejects with 401 is retried once with a new token.
+func NewBearerTransport(next http.RoundTripper, src *TokenSource) http.RoundTripper {
+	return &bearerTransport{next: next, src: src}
+}
+
+type bearerTransport struct {
+	next http.RoundTripper
+	src  *TokenSource
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	// The token may have been revoked before it expired
+	t.src.Invalidate()
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+	return t.send(retry)
+}
+
+// send sends req with a current access token.
+func (t *bearerTransport) send(req *http.Request) (*http.Response, error) {
+	token, err := t.src.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("gateway authentication failed: %w", err)
+	}
+	authed := req.Clone(req.Context())
+	authed.Header.Set("Authorization", "Bearer "+token)
+	return t.next.RoundTrip(authed)
+}
@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a TokenStore kept in memory.
+type memoryStore map[string]string
+
+func (s memoryStore) Get(key string) (string, error) { return s[key], nil }
+
+func (s memoryStore) Set(key, value string) error {
+	s[key] = value
+	return nil
+}
+
+// identityProvider is a fake OIDC identity provider.
+type identityProvider struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	issued   int
+	grants   []string
+	pending  int    // Device flow polls answered authorization_pending
+	revoked  string // Refresh token that is rejected
+	lifetime int
+}
+
+func newIdentityProvider(t *testing.T) *identityProvider {
+	idp := &identityProvider{lifetime: 3600}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"token_endpoint":                idp.URL + "/token",
+			"device_authorization_endpoint": idp.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "dev-123",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://login.example.com/device",
+			"expires_in":       600,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+
+		grant := r.Form.Get("grant_type")
+		idp.grants = append(idp.grants, grant)
+		fail := func(code string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": code})
+		}
+		switch grant {
+		case "client_credentials":
+			if r.Form.Get("client_secret") != "s3cret" {
+				fail("invalid_client")
+				return
+			}
+		case deviceCodeGrant:
+			if idp.pending > 0 {
+				idp.pending--
+				fail("authorization_pending")
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") == idp.revoked {
+				fail("invalid_grant")
+				return
+			}
+		}
+		idp.issued++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", idp.issued),
+			"refresh_token": fmt.Sprintf("refresh-%d", idp.issued),
+			"expires_in":    idp.lifetime,
+		})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func TestTokenSource_ClientCredentials(t *testing.T) {
+	idp := newIdentityProvider(t)
+	src, err := NewTokenSource(OIDCConfig{Issuer: idp.URL, ClientID: "b+", ClientSecret: "s3cret"}, nil)
+	require.NoError(t, err)
+
+	token, err := src.Token(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token)
+
+	// Cached until it is about to expire
+	token, err = src.Token(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token)
+
+	// Renewed with the refresh token once invalidated
+	src.Invalidate()
+	token, err = src.Token(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", token)
+	assert.Equal(t, []string{"client_credentials", "refresh_token"}, idp.grants)
+}
+
+func TestTokenSource_DeviceCode(t *testing.T) {
+	defer func(d time.Duration) { defaultDeviceInterval = d }(defaultDeviceInterval)
+	defaultDeviceInterval = time.Millisecond
+
+	var prompted DeviceCode
+	SetDevicePrompt(func(name string, code DeviceCode) { prompted = code })
+	defer SetDevicePrompt(nil)
+
+	idp := newIdentityProvider(t)
+	idp.pending = 2
+	store := memoryStore{}
+	src, err := NewTokenSource(OIDCConfig{Flow: OIDCDeviceCode, Issuer: idp.URL, ClientID: "b+", Store: store, StoreKey: "oidc/openai"}, nil)
+	require.NoError(t, err)
+
+	token, err := src.Token(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token)
+	assert.Equal(t, "ABCD-EFGH", prompted.UserCode)
+	assert.Equal(t, "https://login.example.com/device", prompted.VerificationURI)
+	assert.Equal(t, "refresh-1", store["oidc/openai"])
+
+	// A later session signs in with the stored refresh token
+	src, err = NewTokenSource(OIDCConfig{Flow: OIDCDeviceCode, Issuer: idp.URL, ClientID: "b+", Store: store, StoreKey: "oidc/openai"}, nil)
+	require.NoError(t, err)
+	prompted = DeviceCode{}
+	token, err = src.Token(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", token)
+	assert.Empty(t, prompted.UserCode)
+
+	// A revoked refresh token starts the device flow over
+	idp.revoked = "refresh-2"
+	src, err = NewTokenSource(OIDCConfig{Flow: OIDCDeviceCode, Issuer: idp.URL, ClientID: "b+", Store: store, StoreKey: "oidc/openai"}, nil)
+	require.NoError(t, err)
+	token, err = src.Token(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "access-3", token)
+	assert.Equal(t, "ABCD-EFGH", prompted.UserCode)
+}
+
+func TestNewTokenSource_Errors(t *testing.T) {
+	_, err := NewTokenSource(OIDCConfig{Issuer: "https://login.example.com"}, nil)
+	assert.ErrorContains(t, err, "client ID")
+
+	_, err = NewTokenSource(OIDCConfig{ClientID: "b+", ClientSecret: "s"}, nil)
+	assert.ErrorContains(t, err, "issuer or token URL")
+
+	_, err = NewTokenSource(OIDCConfig{Issuer: "https://login.example.com", ClientID: "b+"}, nil)
+	assert.ErrorContains(t, err, "client secret")
+
+	_, err = NewTokenSource(OIDCConfig{Issuer: "https://login.example.com", ClientID: "b+", Flow: "password"}, nil)
+	assert.ErrorContains(t, err, "unknown OIDC flow")
+}
+
+func TestBearerTransport_RetriesRejectedToken(t *testing.T) {
+	idp := newIdentityProvider(t)
+	var seen []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer access-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer gateway.Close()
+
+	src, err := NewTokenSource(OIDCConfig{TokenURL: idp.URL + "/token", ClientID: "b+", ClientSecret: "s3cret"}, nil)
+	require.NoError(t, err)
+	client := &http.Client{Transport: NewBearerTransport(http.DefaultTransport, src)}
+
+	resp, err := client.Post(gateway.URL, "application/json", strings.NewReader(`{"prompt":"hi"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"Bearer access-1", "Bearer access-2"}, seen)
+}
@@ -0,0 +1,402 @@
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limiter defaults
+const (
+	// defaultMaxWait is the longest a request is held for a rate limit to
+	// reset; beyond it the request is sent (or the 429 returned) as is.
+	defaultMaxWait = 2 * time.Minute
+
+	// defaultMaxRetries is how many times a throttled request is retried.
+	defaultMaxRetries = 3
+
+	// defaultBackoff is the first wait after a 429 without any reset hint.
+	defaultBackoff = time.Second
+)
+
+// LimitState is the rate limit last reported by one API host for one key.
+// Counts are -1 when the API does not report them.
+type LimitState struct {
+	Host              string
+	RequestsLimit     int
+	RequestsRemaining int
+	TokensLimit       int
+	TokensRemaining   int
+	ResetAt           time.Time // When the exhausted limit resets; zero if unknown
+	BlockedUntil      time.Time // Requests are held until then; zero if not limited
+	Queued            int       // Requests waiting for the limit to reset
+	Throttles         int64     // Responses with HTTP 429
+	Updated           time.Time
+}
+
+// Limited reports whether requests are being held at now.
+func (s LimitState) Limited(now time.Time) bool {
+	return s.Queued > 0 || now.Before(s.BlockedUntil)
+}
+
+// RateLimiter is an http.RoundTripper shared by all providers. It records the
+// rate limits APIs report (Retry-After and x-ratelimit-* headers), holds
+// requests while a limit is exhausted, and waits out a 429 and retries
+// instead of failing immediately.
+type RateLimiter struct {
+	base       http.RoundTripper // Nil means the shared transport
+	maxWait    time.Duration
+	maxRetries int
+	listener   func(LimitState)
+	now        func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*LimitState // Keyed by host and key fingerprint
+}
+
+// LimiterOption is a functional option for configuring the rate limiter.
+type LimiterOption func(*RateLimiter)
+
+// WithMaxWait sets the longest a request is held for a limit to reset.
+func WithMaxWait(d time.Duration) LimiterOption {
+	return func(l *RateLimiter) {
+		l.maxWait = d
+	}
+}
+
+// WithMaxRetries sets how many times a throttled request is retried.
+func WithMaxRetries(n int) LimiterOption {
+	return func(l *RateLimiter) {
+		l.maxRetries = n
+	}
+}
+
+// NewRateLimiter creates a rate limiter over base (the shared transport if nil).
+func NewRateLimiter(base http.RoundTripper, opts ...LimiterOption) *RateLimiter {
+	l := &RateLimiter{
+		base:       base,
+		maxWait:    defaultMaxWait,
+		maxRetries: defaultMaxRetries,
+		now:        time.Now,
+		states:     make(map[string]*LimitState),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// SetListener registers a function called with the new state whenever a
+// host's limit changes. It must not block.
+func (l *RateLimiter) SetListener(fn func(LimitState)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listener = fn
+}
+
+// States returns the known limits, sorted by host.
+func (l *RateLimiter) States() []LimitState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	states := make([]LimitState, 0, len(l.states))
+	for _, s := range l.states {
+		states = append(states, *s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Host < states[j].Host
+	})
+	return states
+}
+
+// RoundTrip implements http.RoundTripper.
+func (l *RateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := limitKey(req)
+	replayable := req.Body == nil || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if err := l.wait(req.Context(), key); err != nil {
+			return nil, err
+		}
+
+		out := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			out = req.Clone(req.Context())
+			out.Body = body
+		}
+
+		resp, err := l.transport(req).RoundTrip(out)
+		if err != nil {
+			return resp, err
+		}
+
+		wait := l.update(key, req.URL.Host, resp, attempt)
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		}
+
+		// Wait out the 429 and retry unless the caller handles it or it is too long
+		if attempt >= l.maxRetries || !replayable || wait > l.maxWait || limiterRetryDisabled(req.Context()) {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+// transport returns the next round tripper: the one req was routed to, if
+// any, and otherwise the limiter's base, behind the interceptor if set.
+func (l *RateLimiter) transport(req *http.Request) http.RoundTripper {
+	if rt, ok := routedTransport(req.Context()); ok {
+		return intercept(rt)
+	}
+	if l.base != nil {
+		return intercept(l.base)
+	}
+	return intercept(Shared())
+}
+
+// wait holds the request while the limit for key is exhausted, up to maxWait.
+func (l *RateLimiter) wait(ctx context.Context, key string) error {
+	l.mu.Lock()
+	state, ok := l.states[key]
+	if !ok {
+		l.mu.Unlock()
+		return nil
+	}
+	d := state.BlockedUntil.Sub(l.now())
+	if d <= 0 || d > l.maxWait {
+		l.mu.Unlock()
+		return nil
+	}
+	state.Queued++
+	l.notify(state)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	state.Queued--
+	l.notify(state)
+	l.mu.Unlock()
+	return err
+}
+
+// update records the limits reported by resp and returns how long to wait
+// before retrying if it is a 429.
+func (l *RateLimiter) update(key, host string, resp *http.Response, attempt int) time.Duration {
+	now := l.now()
+	limits, hasLimits := parseLimitHeaders(resp.Header, now)
+	throttled := resp.StatusCode == http.StatusTooManyRequests
+	if !hasLimits && !throttled {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.states[key]
+	if !ok {
+		state = &LimitState{Host: host, RequestsLimit: -1, RequestsRemaining: -1, TokensLimit: -1, TokensRemaining: -1}
+		l.states[key] = state
+	}
+	state.Updated = now
+	if limits.RequestsLimit >= 0 {
+		state.RequestsLimit = limits.RequestsLimit
+	}
+	if limits.RequestsRemaining >= 0 {
+		state.RequestsRemaining = limits.RequestsRemaining
+	}
+	if limits.TokensLimit >= 0 {
+		state.TokensLimit = limits.TokensLimit
+	}
+	if limits.TokensRemaining >= 0 {
+		state.TokensRemaining = limits.TokensRemaining
+	}
+	state.ResetAt = limits.ResetAt
+
+	var wait time.Duration
+	switch {
+	case throttled:
+		state.Throttles++
+		wait = retryAfter(resp.Header.Get("Retry-After"))
+		if ms, err := strconv.Atoi(resp.Header.Get("Retry-After-Ms")); err == nil && ms > 0 {
+			wait = time.Duration(ms) * time.Millisecond
+		}
+		if wait <= 0 && limits.ResetAt.After(now) {
+			wait = limits.ResetAt.Sub(now)
+		}
+		if wait <= 0 {
+			wait = defaultBackoff << attempt
+		}
+		state.BlockedUntil = now.Add(wait)
+	case (state.RequestsRemaining == 0 || state.TokensRemaining == 0) && limits.ResetAt.After(now):
+		// Exhausted but not yet throttled: hold later requests until the reset
+		state.BlockedUntil = limits.ResetAt
+	default:
+		state.BlockedUntil = time.Time{}
+	}
+
+	l.notify(state)
+	return wait
+}
+
+// notify calls the listener with a copy of state. It must be called with mu held.
+func (l *RateLimiter) notify(state *LimitState) {
+	if l.listener != nil {
+		l.listener(*state)
+	}
+}
+
+// parseLimitHeaders reads the rate limit headers used by OpenAI-compatible
+// APIs (x-ratelimit-*-requests), Anthropic (anthropic-ratelimit-*) and
+// OpenRouter (x-ratelimit-limit/remaining/reset). ResetAt is the latest reset
+// of an exhausted limit, or the earliest reset otherwise.
+func parseLimitHeaders(h http.Header, now time.Time) (LimitState, bool) {
+	s := LimitState{RequestsLimit: -1, RequestsRemaining: -1, TokensLimit: -1, TokensRemaining: -1}
+	found := false
+
+	read := func(names ...string) (int, bool) {
+		for _, name := range names {
+			if v := h.Get(name); v != "" {
+				if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+					found = true
+					return n, true
+				}
+			}
+		}
+		return -1, false
+	}
+	resetOf := func(names ...string) time.Time {
+		for _, name := range names {
+			if t := parseReset(h.Get(name), now); !t.IsZero() {
+				found = true
+				return t
+			}
+		}
+		return time.Time{}
+	}
+
+	s.RequestsLimit, _ = read("X-Ratelimit-Limit-Requests", "Anthropic-Ratelimit-Requests-Limit", "X-Ratelimit-Limit")
+	s.RequestsRemaining, _ = read("X-Ratelimit-Remaining-Requests", "Anthropic-Ratelimit-Requests-Remaining", "X-Ratelimit-Remaining")
+	s.TokensLimit, _ = read("X-Ratelimit-Limit-Tokens", "Anthropic-Ratelimit-Tokens-Limit")
+	s.TokensRemaining, _ = read("X-Ratelimit-Remaining-Tokens", "Anthropic-Ratelimit-Tokens-Remaining")
+	requestsReset := resetOf("X-Ratelimit-Reset-Requests", "Anthropic-Ratelimit-Requests-Reset", "X-Ratelimit-Reset")
+	tokensReset := resetOf("X-Ratelimit-Reset-Tokens", "Anthropic-Ratelimit-Tokens-Reset")
+
+	switch {
+	case s.RequestsRemaining == 0 && s.TokensRemaining == 0:
+		s.ResetAt = later(requestsReset, tokensReset)
+	case s.RequestsRemaining == 0:
+		s.ResetAt = requestsReset
+	case s.TokensRemaining == 0:
+		s.ResetAt = tokensReset
+	default:
+		s.ResetAt = earlier(requestsReset, tokensReset)
+	}
+	return s, found
+}
+
+// parseReset parses a reset given as a duration ("6m0s", "20ms"), an
+// RFC 3339 time, a Unix timestamp in seconds or milliseconds, or a number
+// of seconds from now.
+func parseReset(v string, now time.Time) time.Time {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}
+	}
+	if n, err := strconv.ParseFloat(v, 64); err == nil {
+		switch {
+		case n > 1e12:
+			return time.UnixMilli(int64(n))
+		case n > 1e9:
+			return time.Unix(int64(n), 0)
+		default:
+			return now.Add(time.Duration(n * float64(time.Second)))
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(d)
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// limitKey identifies the rate limit a request counts against: limits are
+// per API key, so requests are grouped by host and a fingerprint of the
+// credentials.
+func limitKey(req *http.Request) string {
+	var creds strings.Builder
+	for _, h := range authHeaders {
+		creds.WriteString(req.Header.Get(h))
+	}
+	creds.WriteString(req.URL.Query().Get("key"))
+	if creds.Len() == 0 {
+		return req.URL.Host
+	}
+	sum := sha256.Sum256([]byte(creds.String()))
+	return req.URL.Host + "#" + hex.EncodeToString(sum[:6])
+}
+
+type limiterRetryKey struct{}
+
+// withoutLimiterRetry marks ctx so the rate limiter returns a 429 to the
+// caller instead of waiting it out, for callers that can route the request
+// elsewhere.
+func withoutLimiterRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, limiterRetryKey{}, true)
+}
+
+func limiterRetryDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(limiterRetryKey{}).(bool)
+	return disabled
+}
+
+var (
+	sharedLimiterOnce sync.Once
+	sharedLimiter     *RateLimiter
+)
+
+// Limiter returns the process-wide rate limiter in front of the shared
+// transport.
+func Limiter() *RateLimiter {
+	sharedLimiterOnce.Do(func() {
+		sharedLimiter = NewRateLimiter(nil)
+	})
+	return sharedLimiter
+}
@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimitHeaders(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		wantReqs  int
+		wantLeft  int
+		wantToks  int
+		wantReset time.Time
+	}{
+		{
+			name: "openai",
+			headers: map[string]string{
+				"x-ratelimit-limit-requests":     "500",
+				"x-ratelimit-remaining-requests": "0",
+				"x-ratelimit-remaining-tokens":   "1200",
+				"x-ratelimit-reset-requests":     "6m0s",
+				"x-ratelimit-reset-tokens":       "20ms",
+			},
+			wantReqs:  500,
+			wantLeft:  0,
+			wantToks:  1200,
+			wantReset: now.Add(6 * time.Minute),
+		},
+		{
+			name: "anthropic",
+			headers: map[string]string{
+				"anthropic-ratelimit-requests-limit":     "50",
+				"anthropic-ratelimit-requests-remaining": "49",
+				"anthropic-ratelimit-tokens-remaining":   "0",
+				"anthropic-ratelimit-requests-reset":     "2026-01-01T12:00:05Z",
+				"anthropic-ratelimit-tokens-reset":       "2026-01-01T12:00:30Z",
+			},
+			wantReqs:  50,
+			wantLeft:  49,
+			wantToks:  0,
+			wantReset: now.Add(30 * time.Second),
+		},
+		{
+			name: "openrouter",
+			headers: map[string]string{
+				"X-RateLimit-Limit":     "20",
+				"X-RateLimit-Remaining": "3",
+				"X-RateLimit-Reset":     "1767268810000",
+			},
+			wantReqs:  20,
+			wantLeft:  3,
+			wantToks:  -1,
+			wantReset: time.UnixMilli(1767268810000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			s, ok := parseLimitHeaders(h, now)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantReqs, s.RequestsLimit)
+			assert.Equal(t, tt.wantLeft, s.RequestsRemaining)
+			assert.Equal(t, tt.wantToks, s.TokensRemaining)
+			assert.True(t, tt.wantReset.Equal(s.ResetAt), "reset %v, want %v", s.ResetAt, tt.wantReset)
+		})
+	}
+
+	_, ok := parseLimitHeaders(http.Header{}, now)
+	assert.False(t, ok)
+}
+
+func TestRateLimiter_RetriesAfter429(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After-Ms", "10")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var updates []LimitState
+	limiter := NewRateLimiter(http.DefaultTransport)
+	limiter.SetListener(func(s LimitState) { updates = append(updates, s) })
+
+	client := &http.Client{Transport: limiter}
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{}`))
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+
+	states := limiter.States()
+	require.Len(t, states, 1)
+	assert.Equal(t, int64(1), states[0].Throttles)
+	assert.NotEmpty(t, updates)
+}
+
+func TestRateLimiter_HoldsRequestsUntilReset(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("X-Ratelimit-Limit-Requests", "1")
+			w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+			w.Header().Set("X-Ratelimit-Reset-Requests", "50ms")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(http.DefaultTransport)
+	var queued atomic.Int32
+	limiter.SetListener(func(s LimitState) {
+		if s.Queued > 0 {
+			queued.Store(int32(s.Queued))
+		}
+	})
+	client := &http.Client{Transport: limiter}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.True(t, limiter.States()[0].Limited(time.Now()))
+
+	// The second request waits for the reset instead of hitting the limit
+	start := time.Now()
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	assert.Equal(t, int32(1), queued.Load())
+	assert.False(t, limiter.States()[0].Limited(time.Now()))
+}
+
+func TestRateLimiter_ReturnsLongOrOptedOut429(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// Longer than the limiter is willing to wait
+	limiter := NewRateLimiter(http.DefaultTransport, WithMaxWait(time.Second))
+	resp, err := (&http.Client{Transport: limiter}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// The caller handles the 429 itself
+	limiter = NewRateLimiter(http.DefaultTransport)
+	req, err := http.NewRequestWithContext(withoutLimiterRetry(context.Background()), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err = limiter.RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
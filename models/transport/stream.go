@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Stream line buffer sizes
+const (
+	// streamBufferSize is the read buffer of a LineReader and the line
+	// buffer it keeps between lines.
+	streamBufferSize = 64 * 1024
+
+	// MaxLineSize bounds a single stream line. SSE data lines carrying large
+	// tool-call arguments or JSON chunks run to several megabytes; anything
+	// larger is treated as a broken stream rather than buffered without end.
+	MaxLineSize = 64 * 1024 * 1024
+)
+
+// ErrLineTooLong is returned by LineReader.Err for a line over MaxLineSize.
+var ErrLineTooLong = errors.New("stream line too long")
+
+// LineReader reads the lines of a streaming response body. It has the
+// Scan/Text/Bytes/Err methods of bufio.Scanner, but its line buffer grows to
+// fit each line instead of failing past a fixed size, and shrinks back after
+// an unusually long line so one large chunk does not pin its memory for the
+// rest of the stream.
+type LineReader struct {
+	r    *bufio.Reader
+	line []byte
+	max  int
+	err  error
+}
+
+// NewLineReader returns a LineReader reading r.
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{r: bufio.NewReaderSize(r, streamBufferSize), max: MaxLineSize}
+}
+
+// Scan advances to the next line, which is then available through Bytes or
+// Text without its line ending. It returns false at the end of the stream or
+// on an error, which Err reports.
+func (l *LineReader) Scan() bool {
+	if l.err != nil {
+		return false
+	}
+	if cap(l.line) > 4*streamBufferSize && len(l.line) < streamBufferSize {
+		l.line = nil
+	}
+	l.line = l.line[:0]
+
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		if len(l.line)+len(chunk) > l.max {
+			l.err = fmt.Errorf("%w: over %d bytes", ErrLineTooLong, l.max)
+			return false
+		}
+		l.line = append(l.line, chunk...)
+
+		switch {
+		case err == nil:
+			l.line = dropLineEnding(l.line)
+			return true
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			l.err = io.EOF
+			if len(l.line) == 0 {
+				return false
+			}
+			l.line = dropLineEnding(l.line)
+			return true
+		default:
+			l.err = err
+			return false
+		}
+	}
+}
+
+// Bytes returns the current line. The slice is reused by the next Scan.
+func (l *LineReader) Bytes() []byte {
+	return l.line
+}
+
+// Text returns the current line as a string.
+func (l *LineReader) Text() string {
+	return string(l.line)
+}
+
+// Err returns the error that stopped Scan, or nil at the end of the stream.
+func (l *LineReader) Err() error {
+	if errors.Is(l.err, io.EOF) {
+		return nil
+	}
+	return l.err
+}
+
+// dropLineEnding strips a trailing "\n" or "\r\n".
+func dropLineEnding(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
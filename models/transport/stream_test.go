@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineReader(t *testing.T) {
+	r := NewLineReader(strings.NewReader("data: a\r\n\ndata: b\nlast"))
+
+	var lines []string
+	for r.Scan() {
+		lines = append(lines, r.Text())
+	}
+	require.NoError(t, r.Err())
+	assert.Equal(t, []string{"data: a", "", "data: b", "last"}, lines)
+}
+
+func TestLineReader_MultiMegabyteLines(t *testing.T) {
+	big := strings.Repeat("x", 5*1024*1024)
+	huge := strings.Repeat("y", 12*1024*1024)
+	input := "data: " + big + "\n\ndata: small\ndata: " + huge + "\ndata: [DONE]\n"
+
+	// Short reads split each line across many buffer fills
+	for name, reader := range map[string]*LineReader{
+		"whole":   NewLineReader(strings.NewReader(input)),
+		"trickle": NewLineReader(iotest.HalfReader(strings.NewReader(input))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var lines []string
+			for reader.Scan() {
+				lines = append(lines, reader.Text())
+			}
+			require.NoError(t, reader.Err())
+			require.Len(t, lines, 5)
+			assert.Equal(t, "data: "+big, lines[0])
+			assert.Equal(t, "data: small", lines[2])
+			assert.Equal(t, "data: "+huge, lines[3])
+			assert.Equal(t, "data: [DONE]", lines[4])
+		})
+	}
+}
+
+func TestLineReader_ShrinksAfterLongLine(t *testing.T) {
+	r := NewLineReader(strings.NewReader(strings.Repeat("x", 4*1024*1024) + "\na\nb\n"))
+
+	require.True(t, r.Scan())
+	assert.GreaterOrEqual(t, cap(r.line), 4*1024*1024)
+	require.True(t, r.Scan())
+	require.True(t, r.Scan())
+	assert.Equal(t, "b", r.Text())
+	assert.LessOrEqual(t, cap(r.line), streamBufferSize)
+}
+
+func TestLineReader_TooLong(t *testing.T) {
+	r := NewLineReader(strings.NewReader(strings.Repeat("x", 1024) + "\n"))
+	r.max = 512
+
+	assert.False(t, r.Scan())
+	assert.True(t, errors.Is(r.Err(), ErrLineTooLong))
+}
+
+func TestLineReader_ReadError(t *testing.T) {
+	r := NewLineReader(iotest.TimeoutReader(strings.NewReader(strings.Repeat("x", 2*streamBufferSize) + "\n")))
+
+	assert.False(t, r.Scan())
+	assert.ErrorIs(t, r.Err(), iotest.ErrTimeout)
+}
@@ -0,0 +1,299 @@
+// Package transport provides the shared HTTP transport used by all model
+// providers: connection pooling, HTTP/2, tuned keep-alives, DNS caching, and
+// connection reuse metrics. Sharing one transport lets streaming calls to the
+// same API reuse warm TLS connections instead of paying a handshake each time.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config configures the HTTP transport.
+type Config struct {
+	MaxIdleConns          int           // Idle connections kept across all hosts
+	MaxIdleConnsPerHost   int           // Idle connections kept per host
+	MaxConnsPerHost       int           // 0 means unlimited
+	IdleConnTimeout       time.Duration // How long idle connections stay in the pool
+	KeepAlive             time.Duration // TCP keep-alive interval
+	DialTimeout           time.Duration // TCP connect timeout
+	TLSHandshakeTimeout   time.Duration // TLS handshake timeout
+	ResponseHeaderTimeout time.Duration // 0 means no limit (required for slow first tokens)
+	DNSCacheTTL           time.Duration // 0 disables DNS caching
+	ForceHTTP2            bool          // Attempt HTTP/2 over TLS
+}
+
+// DefaultConfig returns settings tuned for long-lived streaming API calls.
+func DefaultConfig() Config {
+	return Config{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DNSCacheTTL:         5 * time.Minute,
+		ForceHTTP2:          true,
+	}
+}
+
+// Stats reports connection reuse metrics for a transport.
+type Stats struct {
+	Requests       int64 // Requests that obtained a connection
+	ReusedConns    int64 // Requests served on a pooled connection
+	NewConns       int64 // Requests that had to dial a new connection
+	DNSCacheHits   int64
+	DNSCacheMisses int64
+}
+
+// ReuseRatio returns the fraction of requests served on a reused connection.
+func (s Stats) ReuseRatio() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.ReusedConns) / float64(s.Requests)
+}
+
+// Transport is an http.RoundTripper that wraps a pooled http.Transport and
+// records connection reuse metrics.
+type Transport struct {
+	base *http.Transport
+	dns  *dnsCache
+
+	requests    atomic.Int64
+	reusedConns atomic.Int64
+	newConns    atomic.Int64
+}
+
+// New creates a transport from the given configuration.
+func New(cfg Config) *Transport {
+	t := &Transport{}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	dial := dialer.DialContext
+	if cfg.DNSCacheTTL > 0 {
+		t.dns = newDNSCache(cfg.DNSCacheTTL)
+		dial = t.dns.dialer(dialer)
+	}
+
+	t.base = &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     cfg.ForceHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.requests.Add(1)
+			if info.Reused {
+				t.reusedConns.Add(1)
+			} else {
+				t.newConns.Add(1)
+			}
+		},
+	}
+
+	ctx := httptrace.WithClientTrace(req.Context(), trace)
+	return t.base.RoundTrip(req.WithContext(ctx))
+}
+
+// Base returns the underlying http.Transport.
+func (t *Transport) Base() *http.Transport {
+	return t.base
+}
+
+// Stats returns a snapshot of the transport's connection metrics.
+func (t *Transport) Stats() Stats {
+	s := Stats{
+		Requests:    t.requests.Load(),
+		ReusedConns: t.reusedConns.Load(),
+		NewConns:    t.newConns.Load(),
+	}
+	if t.dns != nil {
+		s.DNSCacheHits = t.dns.hits.Load()
+		s.DNSCacheMisses = t.dns.misses.Load()
+	}
+	return s
+}
+
+// CloseIdleConnections closes pooled connections that are not in use.
+func (t *Transport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+}
+
+var (
+	sharedMu        sync.Mutex
+	sharedTransport *Transport
+	sharedConfig    = DefaultConfig()
+	interceptor     func(http.RoundTripper) http.RoundTripper
+)
+
+// Shared returns the process-wide transport used by all providers.
+func Shared() *Transport {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if sharedTransport == nil {
+		sharedTransport = New(sharedConfig)
+	}
+	return sharedTransport
+}
+
+// Configure replaces the shared transport with one built from cfg.
+// Clients created before the call keep using the previous transport, so
+// this should run at startup before providers are constructed.
+func Configure(cfg Config) {
+	sharedMu.Lock()
+	old := sharedTransport
+	sharedTransport = New(cfg)
+	sharedConfig = cfg
+	sharedMu.Unlock()
+
+	if old != nil {
+		old.CloseIdleConnections()
+	}
+}
+
+// SharedConfig returns the configuration of the shared transport.
+func SharedConfig() Config {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	return sharedConfig
+}
+
+// Intercept has every request sent through the rate limiter, by any
+// provider, go over wrap(rt) instead of rt, the shared or routed transport it
+// would otherwise leave over. Recording and replaying provider traffic
+// hooks in here. A nil wrap removes the interceptor.
+func Intercept(wrap func(http.RoundTripper) http.RoundTripper) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	interceptor = wrap
+}
+
+// intercept returns rt wrapped by the interceptor, if one is set.
+func intercept(rt http.RoundTripper) http.RoundTripper {
+	sharedMu.Lock()
+	wrap := interceptor
+	sharedMu.Unlock()
+
+	if wrap == nil {
+		return rt
+	}
+	return wrap(rt)
+}
+
+// NewClient returns an http.Client with the given timeout that uses the
+// shared transport behind the shared rate limiter. A zero timeout means no
+// overall limit.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: Limiter(),
+	}
+}
+
+// dnsCache caches resolved host addresses for a fixed TTL.
+type dnsCache struct {
+	ttl      time.Duration
+	resolver *net.Resolver
+	mu       sync.RWMutex
+	entries  map[string]dnsEntry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:      ttl,
+		resolver: net.DefaultResolver,
+		entries:  make(map[string]dnsEntry),
+	}
+}
+
+// lookup returns addresses for host, using the cache when fresh.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[host]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		c.hits.Add(1)
+		return entry.addrs, nil
+	}
+
+	c.misses.Add(1)
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return addrs, nil
+}
+
+// dialer wraps a net.Dialer so host names are resolved through the cache.
+// Each cached address is tried in order until one connects.
+func (c *dnsCache) dialer(d *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		// Literal IPs need no resolution
+		if net.ParseIP(host) != nil {
+			return d.DialContext(ctx, network, addr)
+		}
+
+		addrs, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no addresses found for %s", host)
+		}
+		return nil, lastErr
+	}
+}
@@ -0,0 +1,112 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport_ConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tr := New(DefaultConfig())
+	client := &http.Client{Transport: tr, Timeout: 5 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	stats := tr.Stats()
+	assert.Equal(t, int64(3), stats.Requests)
+	assert.Equal(t, int64(1), stats.NewConns)
+	assert.Equal(t, int64(2), stats.ReusedConns)
+	assert.InDelta(t, 2.0/3.0, stats.ReuseRatio(), 0.001)
+}
+
+func TestDNSCache(t *testing.T) {
+	cache := newDNSCache(time.Minute)
+
+	addrs, err := cache.lookup(context.Background(), "localhost")
+	require.NoError(t, err)
+	assert.NotEmpty(t, addrs)
+
+	_, err = cache.lookup(context.Background(), "localhost")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), cache.misses.Load())
+	assert.Equal(t, int64(1), cache.hits.Load())
+}
+
+func TestDNSCache_Expiry(t *testing.T) {
+	cache := newDNSCache(time.Nanosecond)
+
+	_, err := cache.lookup(context.Background(), "localhost")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = cache.lookup(context.Background(), "localhost")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), cache.misses.Load())
+	assert.Equal(t, int64(0), cache.hits.Load())
+}
+
+func TestNewClient_UsesSharedTransport(t *testing.T) {
+	client := NewClient(30 * time.Second)
+	assert.Equal(t, 30*time.Second, client.Timeout)
+	assert.Same(t, Limiter(), client.Transport)
+	assert.Same(t, Shared(), Limiter().transport(&http.Request{}))
+}
+
+func TestConfigure(t *testing.T) {
+	before := Shared()
+
+	cfg := DefaultConfig()
+	cfg.MaxIdleConnsPerHost = 3
+	cfg.DNSCacheTTL = 0
+	Configure(cfg)
+	defer Configure(DefaultConfig())
+
+	after := Shared()
+	assert.NotSame(t, before, after)
+	assert.Equal(t, 3, after.Base().MaxIdleConnsPerHost)
+	assert.Nil(t, after.dns)
+}
+
+func TestIntercept(t *testing.T) {
+	var wrapped http.RoundTripper
+	Intercept(func(rt http.RoundTripper) http.RoundTripper {
+		wrapped = rt
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusTeapot, Body: http.NoBody, Request: req}, nil
+		})
+	})
+	defer Intercept(nil)
+
+	resp, err := NewClient(time.Second).Get("http://intercepted.invalid/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+	assert.Same(t, Shared(), wrapped)
+}
+
+func TestStats_ReuseRatioEmpty(t *testing.T) {
+	assert.Equal(t, 0.0, Stats{}.ReuseRatio())
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
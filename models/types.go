@@ -4,6 +4,8 @@ package models
 import (
 	"context"
 	"time"
+
+	"github.com/abrksh22/bplus/internal/errors"
 )
 
 // Provider represents an LLM provider (e.g., Anthropic, OpenAI, Ollama).
@@ -74,6 +76,11 @@ type CompletionRequest struct {
 	// Tool definitions for function calling (optional)
 	Tools []Tool
 
+	// Whether the model may return several tool calls in one turn, which
+	// the caller then runs as a batch. Sent to providers that take the
+	// setting; the others decide for themselves.
+	ParallelToolCalls bool
+
 	// Sampling parameters
 	Temperature      *float64 // 0.0 to 1.0
 	TopP             *float64 // 0.0 to 1.0
@@ -83,15 +90,68 @@ type CompletionRequest struct {
 	FrequencyPenalty *float64 // -2.0 to 2.0
 	PresencePenalty  *float64 // -2.0 to 2.0
 
+	// Tokens a thinking model may spend reasoning before it answers; 0
+	// leaves reasoning at the provider default. Providers that only offer
+	// effort levels map the budget onto the nearest level.
+	ReasoningBudget int
+
 	// Metadata
 	Metadata map[string]string
 }
 
+// Attribution metadata providers forward with a request, where their API
+// has a place for it, so usage can be traced per user and per session in
+// the provider's dashboard.
+const (
+	MetadataUser    = "user"       // End user the request is made for
+	MetadataSession = "session_id" // Session the request belongs to
+)
+
+// EndUser returns the end-user identifier to send with req: the user in its
+// metadata, else fallback, the identifier the provider was configured with,
+// else the session, so requests can at least be told apart per session.
+func EndUser(req *CompletionRequest, fallback string) string {
+	if user := req.Metadata[MetadataUser]; user != "" {
+		return user
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return req.Metadata[MetadataSession]
+}
+
+// Sampling holds the sampling parameters a caller applies to its requests.
+// Nil fields leave the provider default.
+type Sampling struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"`
+}
+
+// Apply sets the parameters s holds on req.
+func (s Sampling) Apply(req *CompletionRequest) {
+	if s.Temperature != nil {
+		req.Temperature = s.Temperature
+	}
+	if s.TopP != nil {
+		req.TopP = s.TopP
+	}
+	if s.TopK != nil {
+		req.TopK = s.TopK
+	}
+}
+
 // CompletionResponse represents a completion response.
 type CompletionResponse struct {
 	// Generated content
 	Content string
 
+	// Reasoning content from thinking models (not part of Content)
+	Reasoning string
+
+	// Provider signature over Reasoning, sent back with it on later turns
+	ReasoningSignature string
+
 	// Tool calls made by the model (if any)
 	ToolCalls []ToolCall
 
@@ -110,19 +170,28 @@ type CompletionResponse struct {
 
 // StreamToken represents a single token in a streaming response.
 type StreamToken struct {
-	Content  string    // Token content
-	ToolCall *ToolCall // Tool call (if any)
-	Done     bool      // True if this is the last token
-	Usage    *Usage    // Usage info (sent with last token)
-	Error    error     // Error (if any)
-	Metadata map[string]string
+	Content            string    // Token content
+	Reasoning          string    // Reasoning token content (thinking models)
+	ReasoningSignature string    // Signature over the reasoning so far, sent once it is complete
+	ToolCall           *ToolCall // Tool call (if any)
+	Done               bool      // True if this is the last token
+	StopReason         string    // Stop reason, if known (sent with last token)
+	Usage              *Usage    // Usage info (sent with last token)
+	Error              error     // Error (if any)
+	Metadata           map[string]string
 }
 
 // Message represents a conversation message.
 type Message struct {
-	Role    string // "user", "assistant", "system"
+	Role    string // "user", "assistant", "system", "tool"
 	Content string // Message content
-	Name    string // Optional name for multi-party conversations
+	Name    string // Optional name for multi-party conversations; the tool name for "tool" messages
+
+	ToolCalls          []ToolCall   // Tools called by an assistant message
+	Reasoning          string       // Reasoning behind an assistant message, for providers that need it replayed
+	ReasoningSignature string       // Provider signature over Reasoning
+	ToolCallID         string       // Call answered by a "tool" message
+	Attachments        []Attachment // Images sent with a user message
 }
 
 // Tool represents a tool/function that the model can call.
@@ -151,10 +220,142 @@ type ToolCall struct {
 
 // Usage represents token usage and cost information.
 type Usage struct {
-	InputTokens  int     // Tokens in the prompt
-	OutputTokens int     // Tokens in the completion
-	TotalTokens  int     // Total tokens used
-	Cost         float64 // Estimated cost in USD
+	InputTokens     int     // Tokens in the prompt
+	OutputTokens    int     // Tokens in the completion (including reasoning)
+	TotalTokens     int     // Total tokens used
+	ReasoningTokens int     // Portion of OutputTokens spent on reasoning
+	Cost            float64 // Estimated cost in USD
+	ReasoningCost   float64 // Portion of Cost spent on reasoning tokens
+	CostEstimated   bool    // Cost is a fallback estimate; see CostReconciler
+	GenerationID    string  // Provider generation ID, for cost reconciliation
+}
+
+// CostReconciler is implemented by providers that can look up the
+// authoritative cost of a completed generation after the call returns.
+type CostReconciler interface {
+	// ReconcileCost returns the billed cost in USD for a generation ID
+	ReconcileCost(ctx context.Context, generationID string) (float64, error)
+}
+
+// PrefillProvider is implemented by providers that continue a trailing
+// assistant message in place rather than answering it with a new turn.
+type PrefillProvider interface {
+	SupportsPrefill() bool
+}
+
+// Preloader is implemented by local providers that can load a model into
+// memory ahead of the first request.
+type Preloader interface {
+	// Preload loads the model, or keeps it loaded if it already is
+	Preload(ctx context.Context, modelID string) error
+}
+
+// ProgressPreloader is implemented by preloaders that report how far a
+// model load has got.
+type ProgressPreloader interface {
+	// PreloadProgress loads the model like Preload, calling progress with
+	// the fraction loaded, from 0 to 1, as the load advances
+	PreloadProgress(ctx context.Context, modelID string, progress func(fraction float64)) error
+}
+
+// Unloader is implemented by local providers that can free the memory a
+// loaded model holds.
+type Unloader interface {
+	Unload(ctx context.Context, modelID string) error
+}
+
+// ModelPuller is implemented by local providers that can download models
+// they don't have yet.
+type ModelPuller interface {
+	// HasModel reports whether the model is installed
+	HasModel(ctx context.Context, modelID string) (bool, error)
+	// PullModel downloads the model, sending progress to progress, if not
+	// nil, until it returns. progress is not closed.
+	PullModel(ctx context.Context, modelID string, progress chan<- PullProgress) error
+}
+
+// PullProgress reports how far a model download has got.
+type PullProgress struct {
+	Status    string // Step of the download, e.g. "pulling manifest" or "verifying sha256 digest"
+	Digest    string // Layer being downloaded, if any
+	Total     int64  // Size of the layer in bytes, 0 if unknown
+	Completed int64  // Bytes of the layer downloaded so far
+}
+
+// Fraction returns the fraction of the layer downloaded, from 0 to 1, or 0
+// if it is unknown.
+func (p PullProgress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return min(float64(p.Completed)/float64(p.Total), 1)
+}
+
+// AsCostReconciler returns p as a CostReconciler, looking through
+// wrapping providers such as WithStreamMetrics.
+func AsCostReconciler(p Provider) (CostReconciler, bool) {
+	return unwrapAs[CostReconciler](p)
+}
+
+// AsPreloader returns p as a Preloader, looking through wrapping providers.
+func AsPreloader(p Provider) (Preloader, bool) {
+	return unwrapAs[Preloader](p)
+}
+
+// AsProgressPreloader returns p as a ProgressPreloader, looking through
+// wrapping providers.
+func AsProgressPreloader(p Provider) (ProgressPreloader, bool) {
+	return unwrapAs[ProgressPreloader](p)
+}
+
+// AsUnloader returns p as an Unloader, looking through wrapping providers.
+func AsUnloader(p Provider) (Unloader, bool) {
+	return unwrapAs[Unloader](p)
+}
+
+// AsModelPuller returns p as a ModelPuller, looking through wrapping
+// providers.
+func AsModelPuller(p Provider) (ModelPuller, bool) {
+	return unwrapAs[ModelPuller](p)
+}
+
+// SupportsPrefill reports whether p continues a trailing assistant message.
+func SupportsPrefill(p Provider) bool {
+	pp, ok := unwrapAs[PrefillProvider](p)
+	return ok && pp.SupportsPrefill()
+}
+
+// unwrapAs finds the first provider in a chain of wrappers implementing T.
+func unwrapAs[T any](p Provider) (T, bool) {
+	for p != nil {
+		if t, ok := p.(T); ok {
+			return t, true
+		}
+		u, ok := p.(interface{ Unwrap() Provider })
+		if !ok {
+			break
+		}
+		p = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// NormalizeStopReason maps provider-specific stop reasons onto the values
+// used in CompletionResponse.StopReason. Unknown reasons are returned as is.
+func NormalizeStopReason(reason string) string {
+	switch reason {
+	case "stop", "STOP", "COMPLETE":
+		return "end_turn"
+	case "length", "MAX_TOKENS":
+		return "max_tokens"
+	case "tool_calls", "function_call", "TOOL_CALL":
+		return "tool_use"
+	case "STOP_SEQUENCE":
+		return "stop_sequence"
+	default:
+		return reason
+	}
 }
 
 // ProviderConfig represents configuration for a provider.
@@ -173,6 +374,12 @@ type ProviderError struct {
 	Code      string
 	Message   string
 	Retryable bool
+
+	// Filled in from failed HTTP responses by NewHTTPError
+	Status     int              // HTTP status code
+	Type       string           // Provider error type or code, e.g. "overloaded_error"
+	Kind       errors.ErrorCode // Classification, e.g. errors.ErrCodeProviderAuth
+	RetryAfter time.Duration    // How long the provider asked callers to wait
 }
 
 func (e *ProviderError) Error() string {
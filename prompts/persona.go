@@ -0,0 +1,157 @@
+package prompts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultPersona is the persona used when none is configured.
+const DefaultPersona = "default"
+
+// Persona is a profile of how the main agent talks to the user. It adjusts
+// sections of the Layer 4 prompt rather than replacing it, so the tool and
+// safety guidance stays the same for every persona.
+type Persona struct {
+	Name        string
+	Description string
+
+	// Sections replaces the body of the named sections, such as
+	// "Tone and Style"
+	Sections map[string]string
+
+	// Omit drops the named sections, with their subsections
+	Omit []string
+
+	// Instructions are added to the end of the prompt
+	Instructions string
+
+	// MaxWords is the target length of a response, 0 for no target
+	MaxWords int
+}
+
+// toneBase is the part of "Tone and Style" every persona keeps.
+const toneBase = `- Only use emojis if the user explicitly requests it. Avoid using emojis in all communication unless asked.
+- Output text to communicate with the user; all text you output outside of tool use is displayed to the user. Only use tools to complete tasks. Never use tools like core.bash or code comments as means to communicate with the user during the session.
+- NEVER create files unless they're absolutely necessary for achieving your goal. ALWAYS prefer editing an existing file to creating a new one. This includes markdown files.`
+
+var personas = map[string]Persona{
+	DefaultPersona: {
+		Name:        DefaultPersona,
+		Description: "Balanced assistant that completes tasks and reports briefly",
+	},
+	"terse": {
+		Name:        "terse",
+		Description: "Terse executor: does the work and reports only the outcome",
+		Sections: map[string]string{
+			"Tone and Style": toneBase + `
+- Your output is displayed on a command line interface. Be as brief as possible: no preamble, no restating the request, no summary of steps the user already saw.
+- Report the outcome in a sentence or a few bullets. Explain only what was unexpected or needs the user's attention.`,
+		},
+		Omit:         []string{"Example Workflows"},
+		Instructions: `You are a terse executor. Act instead of discussing: make reasonable assumptions, state them in one line, and ask only when a wrong guess would be costly.`,
+		MaxWords:     80,
+	},
+	"teacher": {
+		Name:        "teacher",
+		Description: "Teaching assistant: explains the reasoning behind each change",
+		Sections: map[string]string{
+			"Tone and Style": toneBase + `
+- Your output is displayed on a command line interface. You can use Github-flavored markdown for formatting.
+- Explain why, not just what: name the concept or convention behind a change and point to where the codebase already follows it.`,
+		},
+		Instructions: `You are a teaching assistant. The user wants to understand the code as well as change it. Before a non-trivial change, say briefly what you will do and why. Afterwards, walk through the key lines with file_path:line_number references, mention alternatives you rejected and why, and suggest what to read next. Prefer small, explainable steps over large rewrites.`,
+		MaxWords:     400,
+	},
+	"reviewer": {
+		Name:        "reviewer",
+		Description: "Reviewer: reads and critiques code without changing it unless asked",
+		Sections: map[string]string{
+			"Tone and Style": toneBase + `
+- Your output is displayed on a command line interface. You can use Github-flavored markdown for formatting.
+- Be direct and specific. Every finding points to file_path:line_number and says what is wrong and how to fix it.`,
+		},
+		Omit:         []string{"Example Workflows"},
+		Instructions: `You are a code reviewer. Read the code in question and report findings; do NOT edit files, create commits or run commands that change the work tree unless the user asks you to. Group findings by severity (bugs, risks, style) with the most serious first, and say plainly when you found nothing worth changing. Run tests or read-only commands when they settle whether a finding is real.`,
+		MaxWords:     300,
+	},
+}
+
+// Personas returns the available personas sorted by name.
+func Personas() []Persona {
+	list := make([]Persona, 0, len(personas))
+	for _, p := range personas {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupPersona returns the named persona. An empty name is the default.
+func LookupPersona(name string) (Persona, bool) {
+	if name == "" {
+		name = DefaultPersona
+	}
+	p, ok := personas[strings.ToLower(name)]
+	return p, ok
+}
+
+// Layer4Prompt returns the Layer 4 prompt adjusted for a persona.
+func Layer4Prompt(p Persona) string {
+	if len(p.Sections) == 0 && len(p.Omit) == 0 && p.Instructions == "" && p.MaxWords == 0 {
+		return Layer4MainAgent
+	}
+
+	omit := make(map[string]bool, len(p.Omit))
+	for _, name := range p.Omit {
+		omit[name] = true
+	}
+
+	var b strings.Builder
+	skipLevel := 0 // Level of the omitted section being skipped, 0 if none
+	replaced := false
+	for _, line := range strings.Split(Layer4MainAgent, "\n") {
+		level, title := heading(line)
+		if level > 0 {
+			if skipLevel > 0 && level > skipLevel {
+				continue
+			}
+			skipLevel, replaced = 0, false
+			if omit[title] {
+				skipLevel = level
+				continue
+			}
+			if body, ok := p.Sections[title]; ok {
+				b.WriteString(line + "\n" + body + "\n\n")
+				replaced = true
+				continue
+			}
+		}
+		if skipLevel > 0 || replaced {
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+
+	prompt := strings.TrimRight(b.String(), "\n") + "\n\n# Persona\n"
+	if p.Instructions != "" {
+		prompt += "\n" + p.Instructions + "\n"
+	}
+	if p.MaxWords > 0 {
+		prompt += fmt.Sprintf("\nKeep responses under about %d words unless the user asks for more detail. Code, diffs and command output don't count toward this.\n", p.MaxWords)
+	}
+	return strings.TrimRight(prompt, "\n")
+}
+
+// heading returns the level and title of a markdown heading line, or 0 if
+// the line isn't one.
+func heading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level >= len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(line[level:])
+}
@@ -0,0 +1,103 @@
+package sdk
+
+import (
+	"time"
+
+	"github.com/abrksh22/bplus/internal/events"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// Events reported while a message is answered
+const (
+	EventToolStarted         EventType = "tool_started"
+	EventToolFinished        EventType = "tool_finished"
+	EventToolProgress        EventType = "tool_progress"
+	EventPermissionRequested EventType = "permission_requested"
+	EventCostUpdated         EventType = "cost_updated"
+	EventDraftStreamed       EventType = "draft_streamed"
+	EventDraftReplaced       EventType = "draft_replaced"
+)
+
+// Event is something that happened while a message was answered. Only the
+// fields of its type are set.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// Tool events and permission requests
+	Tool      string
+	Arguments map[string]interface{} // EventToolStarted
+	Success   bool                   // EventToolFinished
+	Error     string                 // EventToolFinished
+	Duration  time.Duration          // EventToolFinished
+
+	// EventToolProgress: how far a long-running tool call has got, zero
+	// totals being unknown
+	Path       string
+	Bytes      int64
+	TotalBytes int64
+	Files      int
+	TotalFiles int
+	Output     string // Tail of a command's output so far
+
+	// EventPermissionRequested
+	Permission string
+	Resource   string
+	Preview    string // Change the call would make, such as a diff, if known
+
+	// Cost and draft events
+	Model string
+
+	// EventCostUpdated
+	InputTokens  int
+	OutputTokens int
+	Cost         float64 // Cost of the latest model call
+	TotalCost    float64 // Cost of the engine's calls so far
+
+	// Draft events: a cheap model's answer shown until the real one arrives
+	Content   string // EventDraftStreamed: the draft so far
+	Done      bool   // EventDraftStreamed: the draft is complete
+	Discarded bool   // EventDraftReplaced: withdrawn rather than answered
+}
+
+// toEvent converts an engine event, reporting false for events that are
+// not part of the API.
+func toEvent(e events.Event) (Event, bool) {
+	switch e := e.(type) {
+	case events.ToolStarted:
+		return Event{Type: EventToolStarted, Time: e.Time, Tool: e.Tool, Arguments: e.Arguments}, true
+	case events.ToolFinished:
+		return Event{Type: EventToolFinished, Time: e.Time, Tool: e.Tool, Success: e.Success, Error: e.Error, Duration: e.Duration}, true
+	case events.ToolProgress:
+		return Event{
+			Type:       EventToolProgress,
+			Time:       e.Time,
+			Tool:       e.Tool,
+			Path:       e.Path,
+			Bytes:      e.Bytes,
+			TotalBytes: e.TotalBytes,
+			Files:      e.Files,
+			TotalFiles: e.TotalFiles,
+			Output:     e.Output,
+		}, true
+	case events.PermissionRequested:
+		return Event{Type: EventPermissionRequested, Time: e.Time, Tool: e.Tool, Permission: e.Permission, Resource: e.Resource, Preview: e.Preview}, true
+	case events.CostUpdated:
+		return Event{
+			Type:         EventCostUpdated,
+			Time:         e.Time,
+			Model:        e.Model,
+			InputTokens:  e.InputTokens,
+			OutputTokens: e.OutputTokens,
+			Cost:         e.Cost,
+			TotalCost:    e.TotalCost,
+		}, true
+	case events.DraftStreamed:
+		return Event{Type: EventDraftStreamed, Time: e.Time, Model: e.Model, Content: e.Content, Done: e.Done}, true
+	case events.DraftReplaced:
+		return Event{Type: EventDraftReplaced, Time: e.Time, Model: e.Model, Discarded: e.Discarded}, true
+	}
+	return Event{}, false
+}
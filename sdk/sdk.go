@@ -0,0 +1,178 @@
+// Package sdk embeds the b+ agent engine in other Go programs.
+//
+// A Client holds one engine: its configuration, provider, tools and session
+// store, set up exactly as the b+ command sets them up. Conversations are
+// run in Sessions, which are stored like the sessions of the command and
+// can be resumed by either.
+//
+//	client, err := sdk.New(sdk.WithModel("sonnet"), sdk.WithTools(myTool))
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	session, err := client.NewSession(ctx, "release notes")
+//	if err != nil {
+//		return err
+//	}
+//	reply, err := session.SendMessage(ctx, "Summarize the changes since v1.2",
+//		sdk.OnEvent(func(e sdk.Event) { log.Println(e.Type, e.Tool) }))
+//
+// The types of this package are its stable API; the engine packages it is
+// built on may change between releases.
+package sdk
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/abrksh22/bplus/app"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/security"
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Client is an embedded b+ engine. Messages of all its sessions are run one
+// at a time, since they share the engine's agent.
+type Client struct {
+	app *app.Application
+	mu  sync.Mutex // Held while a message is run
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	app         *app.Options
+	tools       []tools.Tool
+	permissions security.PromptHandler
+}
+
+// WithModel sets the model messages are sent to, as "provider/model" or an
+// alias such as "sonnet". The configured default is used otherwise.
+func WithModel(name string) Option {
+	return func(o *options) {
+		o.app.Model = name
+	}
+}
+
+// WithConfigFile reads the configuration from path instead of the default
+// location.
+func WithConfigFile(path string) Option {
+	return func(o *options) {
+		o.app.ConfigPath = path
+	}
+}
+
+// WithOffline keeps the engine to local models and disables web tools.
+func WithOffline() Option {
+	return func(o *options) {
+		o.app.Offline = true
+	}
+}
+
+// WithRoots attaches directories besides the working directory, each
+// given as "[name=]path[:ro]".
+func WithRoots(roots ...string) Option {
+	return func(o *options) {
+		o.app.Roots = append(o.app.Roots, roots...)
+	}
+}
+
+// WithVersion sets the version the engine reports itself as.
+func WithVersion(version string) Option {
+	return func(o *options) {
+		o.app.Version = version
+	}
+}
+
+// WithTools registers tools besides the built-in ones. Tools are namespaced
+// as in the registry: "plugin." for external tools, "core." otherwise.
+func WithTools(t ...tools.Tool) Option {
+	return func(o *options) {
+		o.tools = append(o.tools, t...)
+	}
+}
+
+// WithPermissionHandler sets the handler asked before a tool does something
+// that needs permission. Approvals are remembered for the rest of the
+// engine's life, except for elevated and untrusted requests. Without a
+// handler requests are approved, as in the b+ command, apart from elevated
+// ones, which are denied.
+func WithPermissionHandler(handler security.PromptHandler) Option {
+	return func(o *options) {
+		o.permissions = handler
+	}
+}
+
+// New starts an engine. Close releases it.
+func New(opts ...Option) (*Client, error) {
+	o := &options{app: app.DefaultOptions()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	a, err := app.New(o.app)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{app: a}
+	for _, t := range o.tools {
+		if err := c.RegisterTool(t); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+	if o.permissions != nil {
+		a.PermManager.SetPromptHandler(o.permissions)
+	}
+	return c, nil
+}
+
+// RegisterTool adds a tool the agent can call from the next message on.
+func (c *Client) RegisterTool(t tools.Tool) error {
+	if err := c.app.ToolRegistry.Register(t); err != nil {
+		return errors.Wrapf(err, errors.ErrCodeValidation, "failed to register tool %s", t.Name())
+	}
+	return nil
+}
+
+// UnregisterTool removes a tool, given by the name it was registered or
+// listed as.
+func (c *Client) UnregisterTool(name string) error {
+	if err := c.app.ToolRegistry.Unregister(name); err != nil {
+		return errors.Wrapf(err, errors.ErrCodeToolNotFound, "failed to unregister tool %s", name)
+	}
+	return nil
+}
+
+// Tools returns the names of the tools the agent can call, sorted.
+func (c *Client) Tools() []string {
+	var names []string
+	for _, name := range c.app.ToolRegistry.List() {
+		if c.app.ToolRegistry.IsEnabled(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Model returns the model messages are sent to.
+func (c *Client) Model() string {
+	return c.app.CurrentModel()
+}
+
+// SetModel switches the model messages are sent to from the next message on.
+func (c *Client) SetModel(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.app.SetModel(name)
+}
+
+// Close stops the engine. Sessions can't be used afterwards.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.app.Close()
+}
@@ -0,0 +1,56 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToEvent(t *testing.T) {
+	now := time.Now()
+
+	event, ok := toEvent(events.ToolFinished{Tool: "bash", Error: "exit 1", Duration: time.Second, Time: now})
+	assert.True(t, ok)
+	assert.Equal(t, Event{Type: EventToolFinished, Time: now, Tool: "bash", Error: "exit 1", Duration: time.Second}, event)
+
+	event, ok = toEvent(events.CostUpdated{Model: "anthropic/claude-sonnet-4-5", InputTokens: 10, OutputTokens: 5, Cost: 0.01, TotalCost: 0.03, Time: now})
+	assert.True(t, ok)
+	assert.Equal(t, EventCostUpdated, event.Type)
+	assert.Equal(t, 0.03, event.TotalCost)
+
+	event, ok = toEvent(events.DraftStreamed{Model: "ollama/llama3", Content: "Probably", Time: now})
+	assert.True(t, ok)
+	assert.Equal(t, "Probably", event.Content)
+
+	// Engine events outside the API are left out
+	_, ok = toEvent(events.ProvidersChecked{Time: now})
+	assert.False(t, ok)
+}
+
+func TestToReply(t *testing.T) {
+	reply := toReply(&execution.AgentResponse{
+		Content: "Done",
+		ToolCalls: []execution.ToolExecution{
+			{ToolName: "read", Arguments: map[string]interface{}{"path": "go.mod"}, Result: &tools.Result{Success: true, Output: "module x"}},
+			{ToolName: "bash", Result: &tools.Result{Error: errors.New("exit 1")}},
+			{ToolName: "write"},
+		},
+		Usage:     models.Usage{InputTokens: 100, OutputTokens: 20},
+		Truncated: true,
+	})
+
+	assert.Equal(t, "Done", reply.Content)
+	assert.True(t, reply.Truncated)
+	assert.Equal(t, 100, reply.Usage.InputTokens)
+	assert.Equal(t, []ToolCall{
+		{Name: "read", Arguments: map[string]interface{}{"path": "go.mod"}, Success: true, Output: "module x"},
+		{Name: "bash", Error: "exit 1"},
+		{Name: "write"},
+	}, reply.ToolCalls)
+}
@@ -0,0 +1,176 @@
+package sdk
+
+import (
+	"context"
+
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/layers/execution"
+	"github.com/abrksh22/bplus/models"
+)
+
+// Session is a stored conversation. Its messages are run one at a time.
+type Session struct {
+	client  *Client
+	id      string
+	name    string
+	history []models.Message
+}
+
+// Reply is the agent's answer to a message.
+type Reply struct {
+	Content   string
+	ToolCalls []ToolCall   // Tools called while answering, in order
+	Usage     models.Usage // Tokens and cost of the whole turn
+	Truncated bool         // The answer still ends at the token limit
+}
+
+// ToolCall is one tool call made while answering a message.
+type ToolCall struct {
+	Name      string
+	Arguments map[string]interface{}
+	Success   bool
+	Output    interface{} // Nil unless the call succeeded
+	Error     string      // Set if the call failed
+}
+
+// SendOption configures one message.
+type SendOption func(*sendOptions)
+
+type sendOptions struct {
+	onEvent     func(Event)
+	attachments []models.Attachment
+	user        string
+}
+
+// OnEvent calls handler with the events of the message as they happen, in
+// the goroutine that produced them. handler must not block.
+func OnEvent(handler func(Event)) SendOption {
+	return func(o *sendOptions) {
+		o.onEvent = handler
+	}
+}
+
+// WithAttachments attaches images to the message.
+func WithAttachments(attachments ...models.Attachment) SendOption {
+	return func(o *sendOptions) {
+		o.attachments = append(o.attachments, attachments...)
+	}
+}
+
+// WithUser names the end user the message is sent for, forwarded to
+// providers that attribute usage per user.
+func WithUser(user string) SendOption {
+	return func(o *sendOptions) {
+		o.user = user
+	}
+}
+
+// NewSession starts a stored conversation.
+func (c *Client) NewSession(ctx context.Context, name string) (*Session, error) {
+	session, err := c.app.SessionManager.CreateSession(ctx, name)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabase, "failed to create session")
+	}
+	return &Session{client: c, id: session.ID, name: session.Name, history: []models.Message{}}, nil
+}
+
+// ResumeSession continues a stored conversation, such as one held in the
+// b+ command.
+func (c *Client) ResumeSession(ctx context.Context, id string) (*Session, error) {
+	session, err := c.app.SessionManager.GetSession(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ErrCodeDatabaseNotFound, "failed to load session %s", id)
+	}
+	messages, err := c.app.SessionManager.GetMessages(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.ErrCodeDatabase, "failed to load messages of session %s", id)
+	}
+	if messages == nil {
+		messages = []models.Message{}
+	}
+	return &Session{client: c, id: session.ID, name: session.Name, history: messages}, nil
+}
+
+// ID returns the session's ID, which ResumeSession takes.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Name returns the session's name.
+func (s *Session) Name() string {
+	return s.name
+}
+
+// History returns the conversation so far.
+func (s *Session) History() []models.Message {
+	s.client.mu.Lock()
+	defer s.client.mu.Unlock()
+	return append([]models.Message(nil), s.history...)
+}
+
+// SendMessage sends a message to the agent and waits for its answer, which
+// may take several model calls and tool calls. The turn is stored with the
+// session once it succeeds; a failed turn leaves the session as it was.
+func (s *Session) SendMessage(ctx context.Context, text string, opts ...SendOption) (*Reply, error) {
+	o := &sendOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s.client.mu.Lock()
+	defer s.client.mu.Unlock()
+
+	if o.onEvent != nil {
+		unsubscribe := s.client.app.Events.Subscribe(func(e events.Event) {
+			if event, ok := toEvent(e); ok {
+				o.onEvent(event)
+			}
+		})
+		defer unsubscribe()
+	}
+
+	resp, err := s.client.app.Execute(ctx, &execution.AgentRequest{
+		UserMessage: text,
+		History:     s.history,
+		SessionID:   s.id,
+		User:        o.user,
+		Attachments: o.attachments,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, msg := range resp.Messages {
+		// The turn's usage is recorded with its last message
+		var input, output int
+		var cost float64
+		if i == len(resp.Messages)-1 {
+			input, output, cost = resp.Usage.InputTokens, resp.Usage.OutputTokens, resp.Usage.Cost
+		}
+		if err := s.client.app.SessionManager.SaveMessage(ctx, s.id, msg, input, output, cost); err != nil {
+			return nil, errors.Wrapf(err, errors.ErrCodeDatabase, "failed to store message of session %s", s.id)
+		}
+	}
+	s.history = append(s.history, resp.Messages...)
+
+	return toReply(resp), nil
+}
+
+// toReply converts the agent's response.
+func toReply(resp *execution.AgentResponse) *Reply {
+	reply := &Reply{Content: resp.Content, Usage: resp.Usage, Truncated: resp.Truncated}
+	for _, call := range resp.ToolCalls {
+		tc := ToolCall{Name: call.ToolName, Arguments: call.Arguments}
+		if call.Result != nil {
+			tc.Success = call.Result.Success
+			if call.Result.Success {
+				tc.Output = call.Result.Output
+			} else if call.Result.Error != nil {
+				tc.Error = call.Result.Error.Error()
+			}
+		}
+		reply.ToolCalls = append(reply.ToolCalls, tc)
+	}
+	return reply
+}
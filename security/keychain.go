@@ -0,0 +1,232 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// KeychainService is the service name secrets are kept under in the OS
+// keychain.
+const KeychainService = "bplus"
+
+// SecretsFileName is the file in the config directory secrets are kept in
+// when no OS keychain is available.
+const SecretsFileName = "secrets.json"
+
+// ErrSecretNotFound is returned for a secret that was never stored.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Keychain keeps secrets such as refresh tokens between sessions.
+type Keychain interface {
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// NewKeychain returns the OS keychain: the macOS login keychain through
+// security(1), or the Secret Service through secret-tool(1) on Linux. Where
+// neither is available, or the keychain refuses a secret, secrets are kept
+// in fallbackPath, readable by the user only.
+func NewKeychain(fallbackPath string) Keychain {
+	file := NewFileKeychain(fallbackPath)
+	var native Keychain
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			native = macKeychain{}
+		}
+	case "linux", "freebsd", "openbsd":
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			native = secretService{}
+		}
+	}
+	if native == nil {
+		return file
+	}
+	return &fallbackKeychain{primary: native, fallback: file}
+}
+
+// fallbackKeychain keeps secrets in primary, and in fallback when primary
+// fails, e.g. on a headless machine without a Secret Service daemon.
+type fallbackKeychain struct {
+	primary  Keychain
+	fallback Keychain
+}
+
+func (k *fallbackKeychain) Get(account string) (string, error) {
+	secret, err := k.primary.Get(account)
+	if err == nil {
+		return secret, nil
+	}
+	return k.fallback.Get(account)
+}
+
+func (k *fallbackKeychain) Set(account, secret string) error {
+	if err := k.primary.Set(account, secret); err != nil {
+		return k.fallback.Set(account, secret)
+	}
+	// Don't leave an older copy behind in the file
+	_ = k.fallback.Delete(account)
+	return nil
+}
+
+func (k *fallbackKeychain) Delete(account string) error {
+	err := k.primary.Delete(account)
+	if ferr := k.fallback.Delete(account); ferr != nil && err == nil {
+		return ferr
+	}
+	return nil
+}
+
+// macKeychain is the macOS login keychain.
+type macKeychain struct{}
+
+func (macKeychain) Get(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", KeychainService, "-a", account, "-w").Output()
+	if err != nil {
+		return "", ErrSecretNotFound
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (macKeychain) Set(account, secret string) error {
+	// -U updates an existing item instead of failing
+	return runKeychainCommand(nil, "security", "add-generic-password", "-U", "-s", KeychainService, "-a", account, "-w", secret)
+}
+
+func (macKeychain) Delete(account string) error {
+	return runKeychainCommand(nil, "security", "delete-generic-password", "-s", KeychainService, "-a", account)
+}
+
+// secretService is the freedesktop Secret Service, e.g. GNOME Keyring or
+// KWallet.
+type secretService struct{}
+
+func (secretService) Get(account string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", KeychainService, "account", account).Output()
+	if err != nil || len(out) == 0 {
+		return "", ErrSecretNotFound
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (secretService) Set(account, secret string) error {
+	// The secret is read from stdin so it doesn't show up in ps
+	return runKeychainCommand(strings.NewReader(secret), "secret-tool", "store", "--label", KeychainService+" "+account,
+		"service", KeychainService, "account", account)
+}
+
+func (secretService) Delete(account string) error {
+	return runKeychainCommand(nil, "secret-tool", "clear", "service", KeychainService, "account", account)
+}
+
+// runKeychainCommand runs a keychain tool, returning its error output on
+// failure.
+func runKeychainCommand(stdin *strings.Reader, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// FileKeychain keeps secrets in a JSON file readable by the user only.
+type FileKeychain struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileKeychain returns a keychain kept in the file at path.
+func NewFileKeychain(path string) *FileKeychain {
+	return &FileKeychain{path: path}
+}
+
+// Get returns the secret stored for account.
+func (k *FileKeychain) Get(account string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	secrets, err := k.read()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[account]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+// Set stores the secret for account, replacing any earlier one.
+func (k *FileKeychain) Set(account, secret string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	secrets, err := k.read()
+	if err != nil {
+		return err
+	}
+	secrets[account] = secret
+	return k.write(secrets)
+}
+
+// Delete forgets the secret stored for account.
+func (k *FileKeychain) Delete(account string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	secrets, err := k.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[account]; !ok {
+		return nil
+	}
+	delete(secrets, account)
+	return k.write(secrets)
+}
+
+// read returns the stored secrets. A missing file holds none.
+func (k *FileKeychain) read() (map[string]string, error) {
+	secrets := make(map[string]string)
+	data, err := os.ReadFile(k.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", k.path, err)
+	}
+	return secrets, nil
+}
+
+// write saves secrets atomically.
+func (k *FileKeychain) write(secrets map[string]string) error {
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmp := k.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	if err := os.Rename(tmp, k.path); err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKeychain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", SecretsFileName)
+	k := NewFileKeychain(path)
+
+	_, err := k.Get("oidc/openai")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	require.NoError(t, k.Set("oidc/openai", "refresh-1"))
+	require.NoError(t, k.Set("oidc/openai", "refresh-2"))
+	secret, err := k.Get("oidc/openai")
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-2", secret)
+
+	// Readable by the user only
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Kept between sessions
+	secret, err = NewFileKeychain(path).Get("oidc/openai")
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-2", secret)
+
+	require.NoError(t, k.Delete("oidc/openai"))
+	require.NoError(t, k.Delete("oidc/openai"))
+	_, err = k.Get("oidc/openai")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
@@ -23,10 +23,13 @@ const (
 
 // PermissionManager handles permission checking and granting.
 type PermissionManager struct {
-	grants        map[Permission]bool // Granted permissions
-	mode          PermissionMode      // Permission mode
-	promptHandler PromptHandler       // Handler for permission prompts
-	auditLog      []AuditEntry        // Audit log
+	grants        map[Permission]bool            // Granted permissions
+	rootGrants    map[string]map[Permission]bool // Permissions granted within a root, keyed by its path
+	blocked       map[Permission]bool            // Hard-denied permissions (override every mode)
+	roots         *Roots                         // Session roots, whose permissions bound every grant
+	mode          PermissionMode                 // Permission mode
+	promptHandler PromptHandler                  // Handler for permission prompts
+	auditLog      []AuditEntry                   // Audit log
 	mu            sync.RWMutex
 }
 
@@ -47,11 +50,31 @@ type PromptHandler func(ctx context.Context, req *PermissionRequest) (bool, erro
 type PermissionRequest struct {
 	Permission  Permission // Permission being requested
 	Resource    string     // Resource being accessed (file path, command, etc.)
+	Path        string     // Absolute file or directory accessed, if any
 	Operation   string     // Operation being performed
 	Reason      string     // Why this permission is needed
+	Preview     string     // Change the operation would make, such as a diff, if known
 	Risk        RiskLevel  // Risk assessment
 	ToolName    string     // Tool requesting permission
 	RequestedAt time.Time  // When permission was requested
+
+	// Untrusted is set when low-trust content (e.g. a fetched web page) is in
+	// the context the request was made from. Such requests are never approved
+	// by a standing grant or auto-approval, and approving one grants nothing
+	// for later requests.
+	Untrusted bool
+
+	// Elevated is set when the operation would expose credentials, such as
+	// a command printing a .env file. Elevated requests are always put to
+	// the prompt handler, even in YOLO mode, are denied when there is none,
+	// and approving one grants nothing for later requests.
+	Elevated bool
+
+	// Confirm is set for operations the user must approve one by one, such
+	// as starting containers. Like untrusted requests, they are never
+	// approved by a standing grant or auto-approval, and approving one
+	// grants nothing for later requests.
+	Confirm bool
 }
 
 // RiskLevel represents the risk level of an operation.
@@ -78,6 +101,8 @@ type AuditEntry struct {
 func NewPermissionManager(mode PermissionMode, handler PromptHandler) *PermissionManager {
 	return &PermissionManager{
 		grants:        make(map[Permission]bool),
+		rootGrants:    make(map[string]map[Permission]bool),
+		blocked:       make(map[Permission]bool),
 		mode:          mode,
 		promptHandler: handler,
 		auditLog:      make([]AuditEntry, 0),
@@ -89,6 +114,23 @@ func (pm *PermissionManager) Check(ctx context.Context, req *PermissionRequest)
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	// Blocked permissions are denied regardless of mode or prior grants
+	if pm.blocked[req.Permission] {
+		pm.logAudit(req, false)
+		return false, nil
+	}
+
+	// Paths in a root only get the permissions the root allows
+	root, inRoot := pm.roots.Find(req.Path)
+	if req.Path != "" && inRoot && !root.Allows(req.Permission) {
+		pm.logAudit(req, false)
+		return false, nil
+	}
+
+	if req.Elevated && pm.mode != ModeDeny {
+		return pm.promptElevated(ctx, req)
+	}
+
 	// Check mode-specific behavior
 	switch pm.mode {
 	case ModeYOLO:
@@ -101,7 +143,7 @@ func (pm *PermissionManager) Check(ctx context.Context, req *PermissionRequest)
 
 	case ModeAutoApprove:
 		// Auto-approve low-risk operations
-		if req.Risk == RiskLow {
+		if req.Risk == RiskLow && !req.Untrusted && !req.Confirm {
 			pm.logAudit(req, true)
 			return true, nil
 		}
@@ -109,8 +151,17 @@ func (pm *PermissionManager) Check(ctx context.Context, req *PermissionRequest)
 		fallthrough
 
 	case ModeInteractive:
-		// Check if already granted
-		if pm.grants[req.Permission] || pm.grants[PermissionAll] {
+		// Check if already granted. Approvals for a path in a root only
+		// grant the permission within that root.
+		scope := pm.grants
+		if req.Path != "" && inRoot {
+			if pm.rootGrants[root.Path] == nil {
+				pm.rootGrants[root.Path] = make(map[Permission]bool)
+			}
+			scope = pm.rootGrants[root.Path]
+		}
+		standing := !req.Untrusted && !req.Confirm
+		if standing && (pm.grants[req.Permission] || pm.grants[PermissionAll] || scope[req.Permission]) {
 			pm.logAudit(req, true)
 			return true, nil
 		}
@@ -122,8 +173,8 @@ func (pm *PermissionManager) Check(ctx context.Context, req *PermissionRequest)
 				return false, err
 			}
 
-			if granted {
-				pm.grants[req.Permission] = true
+			if granted && standing {
+				scope[req.Permission] = true
 			}
 
 			pm.logAudit(req, granted)
@@ -139,6 +190,31 @@ func (pm *PermissionManager) Check(ctx context.Context, req *PermissionRequest)
 	return false, nil
 }
 
+// promptElevated asks the prompt handler about an elevated request, without
+// consulting or recording grants. Callers must hold pm.mu.
+func (pm *PermissionManager) promptElevated(ctx context.Context, req *PermissionRequest) (bool, error) {
+	if pm.promptHandler == nil {
+		pm.logAudit(req, false)
+		return false, nil
+	}
+
+	granted, err := pm.promptHandler(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	pm.logAudit(req, granted)
+	return granted, nil
+}
+
+// SetPromptHandler replaces the handler asked for permissions that aren't
+// already granted. A nil handler denies them.
+func (pm *PermissionManager) SetPromptHandler(handler PromptHandler) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.promptHandler = handler
+}
+
 // Grant explicitly grants a permission.
 func (pm *PermissionManager) Grant(permission Permission) {
 	pm.mu.Lock()
@@ -153,6 +229,34 @@ func (pm *PermissionManager) Revoke(permission Permission) {
 	defer pm.mu.Unlock()
 
 	delete(pm.grants, permission)
+	for _, grants := range pm.rootGrants {
+		delete(grants, permission)
+	}
+}
+
+// Block hard-denies a permission. Blocked permissions are refused even in
+// YOLO mode and cannot be granted until unblocked (e.g. network in offline mode).
+func (pm *PermissionManager) Block(permission Permission) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.blocked[permission] = true
+}
+
+// Unblock removes a hard denial for a permission.
+func (pm *PermissionManager) Unblock(permission Permission) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	delete(pm.blocked, permission)
+}
+
+// IsBlocked returns whether a permission is hard-denied.
+func (pm *PermissionManager) IsBlocked(permission Permission) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	return pm.blocked[permission]
 }
 
 // GrantAll grants all permissions.
@@ -169,6 +273,17 @@ func (pm *PermissionManager) RevokeAll() {
 	defer pm.mu.Unlock()
 
 	pm.grants = make(map[Permission]bool)
+	pm.rootGrants = make(map[string]map[Permission]bool)
+}
+
+// SetRoots bounds permissions for paths in roots by what each root allows,
+// and scopes approvals for such paths to their root. Requests carry the
+// path they access in PermissionRequest.Path.
+func (pm *PermissionManager) SetRoots(roots *Roots) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.roots = roots
 }
 
 // GetAuditLog returns the audit log.
@@ -0,0 +1,261 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Root is a directory attached to a session. Files in it are referred to as
+// "name:relative/path", and only the permissions it allows are granted for
+// paths inside it.
+type Root struct {
+	Name        string
+	Path        string       // Absolute, cleaned directory
+	Permissions []Permission // Allowed in the root
+}
+
+// defaultRootPermissions are allowed in a root that names none.
+var defaultRootPermissions = []Permission{PermissionRead, PermissionWrite, PermissionExecute}
+
+// rootName matches valid root names. Single letters are excluded so that
+// Windows drive letters are never mistaken for a root prefix.
+var rootName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]+$`)
+
+// Allows reports whether p may be granted for paths in r.
+func (r Root) Allows(p Permission) bool {
+	for _, allowed := range r.Permissions {
+		if allowed == p || allowed == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOnly reports whether r only allows reading.
+func (r Root) ReadOnly() bool {
+	return r.Allows(PermissionRead) && !r.Allows(PermissionWrite) && !r.Allows(PermissionExecute)
+}
+
+// Contains reports whether path is r's directory or inside it.
+func (r Root) Contains(path string) bool {
+	rel, err := filepath.Rel(r.Path, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ParseRoot parses a root given as "[name=]path[:ro|:rw]". Without a name
+// the directory's base name is used; ":ro" attaches it read-only.
+func ParseRoot(spec string) (Root, error) {
+	var root Root
+	if name, path, ok := strings.Cut(spec, "="); ok && rootName.MatchString(name) {
+		root.Name, spec = name, path
+	}
+	switch {
+	case strings.HasSuffix(spec, ":ro"):
+		root.Permissions = []Permission{PermissionRead}
+		spec = strings.TrimSuffix(spec, ":ro")
+	case strings.HasSuffix(spec, ":rw"):
+		spec = strings.TrimSuffix(spec, ":rw")
+	}
+	if spec == "" {
+		return Root{}, fmt.Errorf("root %q has no path", root.Name)
+	}
+	root.Path = spec
+	return root, nil
+}
+
+// Roots is the set of directories attached to a session. The first root
+// added is the primary one: relative paths without a root prefix resolve
+// against it, and it cannot be detached. A nil *Roots has no roots and
+// resolves every path unchanged.
+type Roots struct {
+	mu    sync.RWMutex
+	roots []Root
+}
+
+// NewRoots creates an empty set of roots.
+func NewRoots() *Roots {
+	return &Roots{}
+}
+
+// Add attaches root, filling in its name and permissions if unset, and
+// returns it as attached.
+func (rs *Roots) Add(root Root) (Root, error) {
+	path, err := filepath.Abs(expandHome(root.Path))
+	if err != nil {
+		return Root{}, fmt.Errorf("invalid root path %s: %w", root.Path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Root{}, fmt.Errorf("cannot attach root: %w", err)
+	}
+	if !info.IsDir() {
+		return Root{}, fmt.Errorf("cannot attach root: %s is not a directory", path)
+	}
+	root.Path = path
+
+	if root.Name == "" {
+		root.Name = defaultRootName(path)
+	}
+	if !rootName.MatchString(root.Name) {
+		return Root{}, fmt.Errorf("invalid root name %q (use letters, digits, '.', '_' or '-', starting with a letter)", root.Name)
+	}
+	if len(root.Permissions) == 0 {
+		root.Permissions = defaultRootPermissions
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for _, existing := range rs.roots {
+		if existing.Name == root.Name {
+			return Root{}, fmt.Errorf("a root named %s is already attached (%s)", root.Name, existing.Path)
+		}
+		if existing.Path == root.Path {
+			return Root{}, fmt.Errorf("%s is already attached as %s", root.Path, existing.Name)
+		}
+	}
+	rs.roots = append(rs.roots, root)
+	return root, nil
+}
+
+// Remove detaches the root named name.
+func (rs *Roots) Remove(name string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for i, root := range rs.roots {
+		if root.Name != name {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("the primary root %s cannot be detached", name)
+		}
+		rs.roots = append(rs.roots[:i], rs.roots[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("no root named %s", name)
+}
+
+// SetPermissions changes what the root named name allows. Nil permissions
+// restore the default ones.
+func (rs *Roots) SetPermissions(name string, perms []Permission) error {
+	if len(perms) == 0 {
+		perms = defaultRootPermissions
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for i := range rs.roots {
+		if rs.roots[i].Name == name {
+			rs.roots[i].Permissions = perms
+			return nil
+		}
+	}
+	return fmt.Errorf("no root named %s", name)
+}
+
+// List returns the attached roots, primary first.
+func (rs *Roots) List() []Root {
+	if rs == nil {
+		return nil
+	}
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return append([]Root(nil), rs.roots...)
+}
+
+// Find returns the innermost root containing path.
+func (rs *Roots) Find(path string) (Root, bool) {
+	var found Root
+	ok := false
+	for _, root := range rs.List() {
+		if root.Contains(path) && len(root.Path) > len(found.Path) {
+			found, ok = root, true
+		}
+	}
+	return found, ok
+}
+
+// Resolve turns a file reference into an absolute path. "name:rel" is rel
+// inside the root called name and may not leave it; other relative paths
+// are relative to the primary root. Absolute paths, and prefixes that name
+// no root, are returned as given.
+func (rs *Roots) Resolve(ref string) (string, error) {
+	roots := rs.List()
+	if len(roots) == 0 || ref == "" {
+		return ref, nil
+	}
+
+	if name, rel, ok := strings.Cut(ref, ":"); ok && rootName.MatchString(name) {
+		for _, root := range roots {
+			if root.Name != name {
+				continue
+			}
+			path := filepath.Join(root.Path, filepath.FromSlash(rel))
+			if !root.Contains(path) {
+				return "", fmt.Errorf("%s is outside root %s", ref, name)
+			}
+			return path, nil
+		}
+	}
+
+	if filepath.IsAbs(ref) || strings.Contains(ref, ":") {
+		return ref, nil
+	}
+	return filepath.Join(roots[0].Path, ref), nil
+}
+
+// Describe lists the roots for the model when more than one is attached.
+func (rs *Roots) Describe() string {
+	roots := rs.List()
+	if len(roots) < 2 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Workspace roots. Refer to files in them as root:path (e.g. %s:README.md); relative paths are in %s.\n", roots[1].Name, roots[0].Name)
+	for _, root := range roots {
+		fmt.Fprintf(&b, "- %s: %s (%s)\n", root.Name, root.Path, DescribePermissions(root))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// DescribePermissions names what a root allows, e.g. "read only".
+func DescribePermissions(root Root) string {
+	if root.ReadOnly() {
+		return "read only"
+	}
+	names := make([]string, len(root.Permissions))
+	for i, p := range root.Permissions {
+		names[i] = string(p)
+	}
+	return strings.Join(names, ", ")
+}
+
+// defaultRootName derives a root name from a directory's base name.
+func defaultRootName(path string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return '-'
+	}, filepath.Base(path))
+	if !rootName.MatchString(name) {
+		name = "root-" + name
+	}
+	return name
+}
+
+// expandHome replaces a leading "~" with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
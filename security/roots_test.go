@@ -0,0 +1,154 @@
+package security
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRoots attaches a "backend" root and a read-only "infra" root.
+func newTestRoots(t *testing.T) (*Roots, string, string) {
+	base := t.TempDir()
+	backend := filepath.Join(base, "backend")
+	infra := filepath.Join(base, "infra")
+	require.NoError(t, os.MkdirAll(backend, 0o755))
+	require.NoError(t, os.MkdirAll(infra, 0o755))
+
+	roots := NewRoots()
+	_, err := roots.Add(Root{Path: backend})
+	require.NoError(t, err)
+	_, err = roots.Add(Root{Name: "infra", Path: infra, Permissions: []Permission{PermissionRead}})
+	require.NoError(t, err)
+	return roots, backend, infra
+}
+
+func TestParseRoot(t *testing.T) {
+	root, err := ParseRoot("infra=../infra:ro")
+	require.NoError(t, err)
+	assert.Equal(t, Root{Name: "infra", Path: "../infra", Permissions: []Permission{PermissionRead}}, root)
+
+	root, err = ParseRoot("/src/api:rw")
+	require.NoError(t, err)
+	assert.Equal(t, Root{Path: "/src/api"}, root)
+
+	// "=" in a path that does not start with a name is part of the path
+	root, err = ParseRoot("/tmp/a=b")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/a=b", root.Path)
+
+	_, err = ParseRoot("infra=")
+	assert.Error(t, err)
+}
+
+func TestRoots_Add(t *testing.T) {
+	roots, backend, infra := newTestRoots(t)
+
+	list := roots.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "backend", list[0].Name)
+	assert.Equal(t, defaultRootPermissions, list[0].Permissions)
+	assert.True(t, list[1].ReadOnly())
+
+	_, err := roots.Add(Root{Name: "backend", Path: infra})
+	assert.ErrorContains(t, err, "already attached")
+	_, err = roots.Add(Root{Name: "other", Path: backend})
+	assert.ErrorContains(t, err, "already attached as backend")
+	_, err = roots.Add(Root{Name: "x", Path: backend})
+	assert.ErrorContains(t, err, "invalid root name")
+	_, err = roots.Add(Root{Path: filepath.Join(backend, "missing")})
+	assert.Error(t, err)
+
+	assert.ErrorContains(t, roots.Remove("backend"), "primary root")
+	require.NoError(t, roots.Remove("infra"))
+	assert.Len(t, roots.List(), 1)
+	assert.Error(t, roots.Remove("infra"))
+}
+
+func TestRoots_Resolve(t *testing.T) {
+	roots, backend, infra := newTestRoots(t)
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"infra:modules/vpc.tf", filepath.Join(infra, "modules", "vpc.tf")},
+		{"backend:main.go", filepath.Join(backend, "main.go")},
+		{"infra:", infra},
+		{"cmd/server.go", filepath.Join(backend, "cmd", "server.go")},
+		{"/etc/hosts", "/etc/hosts"},
+		{"unknown:file.go", "unknown:file.go"},
+	}
+	for _, tt := range tests {
+		got, err := roots.Resolve(tt.ref)
+		require.NoError(t, err, tt.ref)
+		assert.Equal(t, tt.want, got, tt.ref)
+	}
+
+	_, err := roots.Resolve("infra:../backend/main.go")
+	assert.ErrorContains(t, err, "outside root infra")
+
+	// Without roots, references are left alone
+	var none *Roots
+	got, err := none.Resolve("infra:main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, "infra:main.tf", got)
+
+	root, ok := roots.Find(filepath.Join(infra, "main.tf"))
+	require.True(t, ok)
+	assert.Equal(t, "infra", root.Name)
+	_, ok = roots.Find(filepath.Dir(infra))
+	assert.False(t, ok)
+}
+
+func TestRoots_Describe(t *testing.T) {
+	roots, backend, infra := newTestRoots(t)
+
+	desc := roots.Describe()
+	assert.Contains(t, desc, "infra:README.md")
+	assert.Contains(t, desc, "- backend: "+backend+" (read, write, execute)")
+	assert.Contains(t, desc, "- infra: "+infra+" (read only)")
+
+	require.NoError(t, roots.Remove("infra"))
+	assert.Empty(t, roots.Describe())
+}
+
+func TestPermissionManager_Roots(t *testing.T) {
+	roots, backend, infra := newTestRoots(t)
+
+	prompts := 0
+	pm := NewPermissionManager(ModeInteractive, func(ctx context.Context, req *PermissionRequest) (bool, error) {
+		prompts++
+		return true, nil
+	})
+	pm.SetRoots(roots)
+
+	check := func(perm Permission, path string) bool {
+		granted, err := pm.Check(context.Background(), &PermissionRequest{Permission: perm, Resource: path, Path: path})
+		require.NoError(t, err)
+		return granted
+	}
+
+	// Read-only roots refuse writes without asking
+	assert.False(t, check(PermissionWrite, filepath.Join(infra, "main.tf")))
+	assert.Equal(t, 0, prompts)
+	assert.True(t, check(PermissionRead, filepath.Join(infra, "main.tf")))
+	assert.Equal(t, 1, prompts)
+
+	// Approving a write in one root grants it there only
+	assert.True(t, check(PermissionWrite, filepath.Join(backend, "a.go")))
+	assert.True(t, check(PermissionWrite, filepath.Join(backend, "b.go")))
+	assert.Equal(t, 2, prompts)
+	assert.True(t, check(PermissionRead, filepath.Join(backend, "a.go")))
+	assert.Equal(t, 3, prompts)
+
+	// Root permissions hold even in YOLO mode
+	yolo := NewPermissionManager(ModeYOLO, nil)
+	yolo.SetRoots(roots)
+	granted, err := yolo.Check(context.Background(), &PermissionRequest{Permission: PermissionExecute, Path: infra})
+	require.NoError(t, err)
+	assert.False(t, granted)
+}
@@ -0,0 +1,151 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TrustLevel is what the user decided about a workspace.
+type TrustLevel string
+
+const (
+	TrustUnknown    TrustLevel = ""           // Not decided yet
+	TrustTrusted    TrustLevel = "trusted"    // Files may be changed and project settings are honored
+	TrustRestricted TrustLevel = "restricted" // Files are read only and project settings are ignored
+)
+
+// TrustFileName is the file in the config directory trust decisions are
+// kept in.
+const TrustFileName = "trusted_folders.json"
+
+// TrustStore remembers which directories the user trusts. A decision made
+// for a directory applies to everything inside it unless one is made for a
+// subdirectory.
+type TrustStore struct {
+	path string
+
+	mu      sync.Mutex
+	folders map[string]TrustLevel
+}
+
+// trustFile is the on-disk form of a TrustStore.
+type trustFile struct {
+	Folders map[string]TrustLevel `json:"folders"`
+}
+
+// LoadTrustStore reads the trust decisions kept at path. A missing file
+// holds no decisions.
+func LoadTrustStore(path string) (*TrustStore, error) {
+	s := &TrustStore{path: path, folders: make(map[string]TrustLevel)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read trust decisions: %w", err)
+	}
+
+	var file trustFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return s, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for dir, level := range file.Folders {
+		if level == TrustTrusted || level == TrustRestricted {
+			s.folders[filepath.Clean(dir)] = level
+		}
+	}
+	return s, nil
+}
+
+// Level returns the decision that applies to dir: the one made for dir or
+// its closest parent.
+func (s *TrustStore) Level(dir string) TrustLevel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir = filepath.Clean(dir)
+	for {
+		if level, ok := s.folders[dir]; ok {
+			return level
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return TrustUnknown
+		}
+		dir = parent
+	}
+}
+
+// Set records the decision for dir and saves the store.
+func (s *TrustStore) Set(dir string, level TrustLevel) error {
+	if level != TrustTrusted && level != TrustRestricted {
+		return fmt.Errorf("invalid trust level %q", level)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.folders[filepath.Clean(dir)] = level
+
+	data, err := json.MarshalIndent(trustFile{Folders: s.folders}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode trust decisions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save trust decisions: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save trust decisions: %w", err)
+	}
+	return nil
+}
+
+// RestrictedProfile is the permission profile of a workspace that is not
+// trusted: files in it can be read but not changed, and commands and MCP
+// tools cannot run.
+var RestrictedProfile = []Permission{PermissionRead}
+
+// restrictedBlocked are blocked outright in a workspace that is not trusted,
+// since commands can change files wherever they run.
+var restrictedBlocked = []Permission{PermissionExecute, PermissionMCP}
+
+// ApplyTrust sets the permission profile for the primary root of roots:
+// the default one for a trusted workspace, RestrictedProfile otherwise.
+// Permissions that a workspace that is not trusted blocks are blocked or
+// unblocked to match.
+func ApplyTrust(level TrustLevel, pm *PermissionManager, roots *Roots) error {
+	list := roots.List()
+	if len(list) == 0 {
+		return fmt.Errorf("no workspace root is attached")
+	}
+
+	perms := RestrictedProfile
+	if level == TrustTrusted {
+		perms = nil
+	}
+	if err := roots.SetPermissions(list[0].Name, perms); err != nil {
+		return err
+	}
+	for _, p := range restrictedBlocked {
+		if level == TrustTrusted {
+			pm.Unblock(p)
+		} else {
+			pm.Block(p)
+		}
+	}
+	return nil
+}
+
+// DescribeTrust names a trust level for display.
+func DescribeTrust(level TrustLevel) string {
+	if level == TrustUnknown {
+		return "not decided (restricted)"
+	}
+	return string(level)
+}
@@ -0,0 +1,60 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", TrustFileName)
+	store, err := LoadTrustStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, TrustUnknown, store.Level("/src/api"))
+
+	require.NoError(t, store.Set("/src", TrustTrusted))
+	require.NoError(t, store.Set("/src/vendor/", TrustRestricted))
+	assert.Error(t, store.Set("/src", TrustUnknown))
+
+	// Decisions apply to subdirectories, the closest one winning
+	assert.Equal(t, TrustTrusted, store.Level("/src/api"))
+	assert.Equal(t, TrustRestricted, store.Level("/src/vendor/lib"))
+	assert.Equal(t, TrustUnknown, store.Level("/srcx"))
+
+	// Decisions are saved
+	reloaded, err := LoadTrustStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, TrustTrusted, reloaded.Level("/src/api"))
+	assert.Equal(t, TrustRestricted, reloaded.Level("/src/vendor"))
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = LoadTrustStore(path)
+	assert.Error(t, err)
+}
+
+func TestApplyTrust(t *testing.T) {
+	roots, backend, _ := newTestRoots(t)
+	pm := NewPermissionManager(ModeYOLO, nil)
+	pm.SetRoots(roots)
+
+	require.NoError(t, ApplyTrust(TrustUnknown, pm, roots))
+	assert.True(t, roots.List()[0].ReadOnly())
+	assert.True(t, pm.IsBlocked(PermissionExecute))
+	assert.True(t, pm.IsBlocked(PermissionMCP))
+	assert.Equal(t, "infra", roots.List()[1].Name, "other roots are left alone")
+	granted, err := pm.Check(t.Context(), &PermissionRequest{Permission: PermissionWrite, Path: filepath.Join(backend, "main.go")})
+	require.NoError(t, err)
+	assert.False(t, granted)
+
+	require.NoError(t, ApplyTrust(TrustTrusted, pm, roots))
+	assert.Equal(t, defaultRootPermissions, roots.List()[0].Permissions)
+	assert.False(t, pm.IsBlocked(PermissionExecute))
+	granted, err = pm.Check(t.Context(), &PermissionRequest{Permission: PermissionWrite, Path: filepath.Join(backend, "main.go")})
+	require.NoError(t, err)
+	assert.True(t, granted)
+
+	assert.Error(t, ApplyTrust(TrustTrusted, pm, NewRoots()))
+}
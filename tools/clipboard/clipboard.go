@@ -0,0 +1,250 @@
+// Package clipboard provides the tool reading and writing the system
+// clipboard, so the agent can pick up a stack trace the user copied or hand
+// them a snippet to paste elsewhere.
+//
+// The system clipboard is reached through the platform's own means:
+// wl-copy and wl-paste under Wayland, xclip or xsel under X11, pbcopy and
+// pbpaste on macOS and the clipboard API on Windows.
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/atotto/clipboard"
+)
+
+// defaultMaxRead bounds the clipboard text returned to the agent.
+const defaultMaxRead = 64 * 1024
+
+// previewLength bounds the text shown when asking to write the clipboard.
+const previewLength = 2000
+
+// Backend reads and writes a clipboard.
+type Backend interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// errUnsupported is returned when no clipboard command is installed.
+var errUnsupported = errors.New("no clipboard is available: install wl-clipboard, xclip or xsel")
+
+// system is the system clipboard.
+type system struct{}
+
+func (system) ReadAll() (string, error) {
+	if clipboard.Unsupported {
+		return "", errUnsupported
+	}
+	return clipboard.ReadAll()
+}
+
+func (system) WriteAll(text string) error {
+	if clipboard.Unsupported {
+		return errUnsupported
+	}
+	return clipboard.WriteAll(text)
+}
+
+// Option is a functional option for configuring the clipboard tool.
+type Option func(*Tool)
+
+// WithBackend uses backend instead of the system clipboard.
+func WithBackend(backend Backend) Option {
+	return func(t *Tool) {
+		t.backend = backend
+	}
+}
+
+// WithMaxRead sets how many bytes of clipboard text are returned to the
+// agent; longer text is cut.
+func WithMaxRead(n int) Option {
+	return func(t *Tool) {
+		t.maxRead = n
+	}
+}
+
+// Tool reads or writes the clipboard. Every read is put to the user, since
+// the clipboard often holds passwords and other text the agent shouldn't
+// see.
+type Tool struct {
+	backend Backend
+	maxRead int
+}
+
+// NewClipboardTool creates a clipboard tool using the system clipboard.
+func NewClipboardTool(opts ...Option) *Tool {
+	t := &Tool{backend: system{}, maxRead: defaultMaxRead}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "clipboard"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Reads or writes the user's system clipboard. Use read when the user says they copied something, such as a stack trace or log, " +
+		"and write to hand them text they asked for to paste elsewhere. Each read is approved by the user"
+}
+
+// Parameters returns the tool parameters.
+func (t *Tool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "action",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "read or write",
+		},
+		{
+			Name:        "text",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For write: the text to put on the clipboard, replacing what is there",
+		},
+	}
+}
+
+// Category returns the tool category.
+func (t *Tool) Category() string {
+	return "clipboard"
+}
+
+// Version returns the tool version.
+func (t *Tool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *Tool) IsExternal() bool {
+	return false
+}
+
+// RequiresPermission returns true as the clipboard belongs to the user.
+func (t *Tool) RequiresPermission() bool {
+	return true
+}
+
+// RequiresConfirmation returns true for reads, whatever was approved
+// before.
+func (t *Tool) RequiresConfirmation(params map[string]interface{}) bool {
+	return action(params) != "write"
+}
+
+// DescribeResource names what a call accesses.
+func (t *Tool) DescribeResource(params map[string]interface{}) string {
+	if action(params) == "write" {
+		text, _ := params["text"].(string)
+		return fmt.Sprintf("clipboard (write %d characters)", utf8.RuneCountInString(text))
+	}
+	return "clipboard (read)"
+}
+
+// Preview shows the text a write would put on the clipboard.
+func (t *Tool) Preview(ctx context.Context, params map[string]interface{}) (string, error) {
+	switch action(params) {
+	case "read":
+		return "", nil
+	case "write":
+		text, _ := params["text"].(string)
+		if text == "" {
+			return "", fmt.Errorf("text is required for write")
+		}
+		if len(text) > previewLength {
+			text = truncate(text, previewLength) + "\n…"
+		}
+		return text, nil
+	default:
+		return "", invalidAction(params)
+	}
+}
+
+// Execute runs the action.
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	switch action(params) {
+	case "read":
+		text, err := t.backend.ReadAll()
+		if err != nil {
+			return failure(fmt.Errorf("failed to read the clipboard: %w", err), startTime)
+		}
+		if text == "" {
+			return &tools.Result{
+				Success:  true,
+				Output:   "The clipboard is empty",
+				Metadata: map[string]interface{}{"length": 0},
+				Duration: time.Since(startTime),
+			}, nil
+		}
+		output, truncated := text, false
+		if len(text) > t.maxRead {
+			output = truncate(text, t.maxRead) + fmt.Sprintf("\n[clipboard text cut at %d of %d bytes]", t.maxRead, len(text))
+			truncated = true
+		}
+		return &tools.Result{
+			Success:  true,
+			Output:   output,
+			Metadata: map[string]interface{}{"length": len(text), "truncated": truncated},
+			Duration: time.Since(startTime),
+		}, nil
+
+	case "write":
+		text, _ := params["text"].(string)
+		if text == "" {
+			return failure(fmt.Errorf("text is required for write"), startTime)
+		}
+		if err := t.backend.WriteAll(text); err != nil {
+			return failure(fmt.Errorf("failed to write the clipboard: %w", err), startTime)
+		}
+		return &tools.Result{
+			Success:  true,
+			Output:   fmt.Sprintf("Copied %d characters to the clipboard", utf8.RuneCountInString(text)),
+			Metadata: map[string]interface{}{"length": len(text)},
+			Duration: time.Since(startTime),
+		}, nil
+
+	default:
+		return failure(invalidAction(params), startTime)
+	}
+}
+
+// action returns the call's action.
+func action(params map[string]interface{}) string {
+	a, _ := params["action"].(string)
+	return strings.TrimSpace(a)
+}
+
+// invalidAction is the error for a call with an unknown action.
+func invalidAction(params map[string]interface{}) error {
+	return fmt.Errorf("invalid action %q: must be read or write", action(params))
+}
+
+// truncate cuts text to at most n bytes without splitting a character.
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
+// failure is the result of a call that failed.
+func failure(err error, startTime time.Time) (*tools.Result, error) {
+	return &tools.Result{
+		Success:  false,
+		Error:    err,
+		Duration: time.Since(startTime),
+	}, nil
+}
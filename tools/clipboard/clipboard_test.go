@@ -0,0 +1,121 @@
+package clipboard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memory is a clipboard backend kept in memory.
+type memory struct {
+	text string
+	err  error
+}
+
+func (m *memory) ReadAll() (string, error) { return m.text, m.err }
+
+func (m *memory) WriteAll(text string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.text = text
+	return nil
+}
+
+func TestTool_Metadata(t *testing.T) {
+	tool := NewClipboardTool()
+	var _ tools.Tool = tool
+	var _ tools.Confirmer = tool
+	var _ tools.Previewer = tool
+	var _ tools.ResourceDescriber = tool
+
+	assert.Equal(t, "clipboard", tool.Name())
+	assert.Equal(t, "clipboard", tool.Category())
+	assert.False(t, tool.IsExternal())
+	assert.True(t, tool.RequiresPermission())
+
+	read := map[string]interface{}{"action": "read"}
+	write := map[string]interface{}{"action": "write", "text": "héllo"}
+	assert.True(t, tool.RequiresConfirmation(read))
+	assert.False(t, tool.RequiresConfirmation(write))
+	assert.Equal(t, "clipboard (read)", tool.DescribeResource(read))
+	assert.Equal(t, "clipboard (write 5 characters)", tool.DescribeResource(write))
+}
+
+func TestTool_Execute(t *testing.T) {
+	clip := &memory{text: "panic: runtime error\n\tmain.go:12"}
+	tool := NewClipboardTool(WithBackend(clip))
+	ctx := context.Background()
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"action": "read"})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "panic: runtime error\n\tmain.go:12", result.Output)
+	assert.Equal(t, false, result.Metadata["truncated"])
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "write", "text": "go test ./..."})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "go test ./...", clip.text)
+	assert.Equal(t, "Copied 13 characters to the clipboard", result.Output)
+
+	clip.text = ""
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "read"})
+	require.NoError(t, err)
+	assert.Equal(t, "The clipboard is empty", result.Output)
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "write"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.EqualError(t, result.Error, "text is required for write")
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "paste"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "must be read or write")
+
+	clip.err = fmt.Errorf("xclip: cannot open display")
+	result, err = tool.Execute(ctx, map[string]interface{}{"action": "read"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.EqualError(t, result.Error, "failed to read the clipboard: xclip: cannot open display")
+}
+
+func TestTool_Truncation(t *testing.T) {
+	clip := &memory{text: strings.Repeat("é", 10)}
+	tool := NewClipboardTool(WithBackend(clip), WithMaxRead(5))
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"action": "read"})
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.Equal(t, "éé\n[clipboard text cut at 5 of 20 bytes]", result.Output)
+	assert.Equal(t, true, result.Metadata["truncated"])
+	assert.Equal(t, 20, result.Metadata["length"])
+}
+
+func TestTool_Preview(t *testing.T) {
+	tool := NewClipboardTool(WithBackend(&memory{}))
+	ctx := context.Background()
+
+	preview, err := tool.Preview(ctx, map[string]interface{}{"action": "write", "text": "SELECT 1;"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", preview)
+
+	preview, err = tool.Preview(ctx, map[string]interface{}{"action": "write", "text": strings.Repeat("x", previewLength+10)})
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("x", previewLength)+"\n…", preview)
+
+	preview, err = tool.Preview(ctx, map[string]interface{}{"action": "read"})
+	require.NoError(t, err)
+	assert.Empty(t, preview)
+
+	_, err = tool.Preview(ctx, map[string]interface{}{"action": "write"})
+	assert.Error(t, err)
+	_, err = tool.Preview(ctx, map[string]interface{}{"action": "clear"})
+	assert.Error(t, err)
+}
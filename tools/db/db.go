@@ -0,0 +1,152 @@
+// Package db provides tools for inspecting and querying the databases
+// configured for a project: SQLite, Postgres and MySQL.
+//
+// Queries run in a read-only transaction, so they cannot change the
+// database whatever they contain. Statements that change it go through
+// db_exec, which is refused for databases not marked writable and needs
+// write permission confirmed for every statement.
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Query defaults
+const (
+	// queryTimeout bounds a query or statement unless the call sets its own.
+	queryTimeout = 30 * time.Second
+
+	// maxTimeout caps the timeout a call can set.
+	maxTimeout = 10 * time.Minute
+
+	// defaultRows and maxRows bound the rows a query returns.
+	defaultRows = 100
+	maxRows     = 1000
+
+	// maxCell bounds the characters shown of a single value.
+	maxCell = 200
+
+	// maxOutput bounds the schema and rows returned to the agent.
+	maxOutput = 20000
+)
+
+// Tools returns the database tools, connecting through m.
+func Tools(m *Manager) []tools.Tool {
+	return []tools.Tool{
+		NewSchemaTool(m),
+		NewQueryTool(m),
+		NewExecTool(m),
+	}
+}
+
+// base holds what all database tools have in common.
+type base struct {
+	manager *Manager
+}
+
+// Category returns the tool category.
+func (base) Category() string {
+	return "db"
+}
+
+// Version returns the tool version.
+func (base) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (base) IsExternal() bool {
+	return false
+}
+
+// RequiresPermission returns true: databases may hold private data.
+func (base) RequiresPermission() bool {
+	return true
+}
+
+// databaseParam returns the parameter naming the database, listing those
+// configured.
+func (b base) databaseParam() tools.Parameter {
+	names := b.manager.Names()
+	p := tools.Parameter{
+		Name:        "database",
+		Type:        tools.TypeString,
+		Required:    len(names) != 1,
+		Description: "Database to use, one of: " + strings.Join(names, ", "),
+	}
+	if len(names) == 1 {
+		p.Description = "Database to use (default: " + names[0] + ")"
+	}
+	return p
+}
+
+// database returns the database the parameters name, defaulting to the
+// only one configured.
+func (b base) database(params map[string]interface{}) (string, error) {
+	name := stringParam(params, "database")
+	if name != "" {
+		return name, nil
+	}
+	if names := b.manager.Names(); len(names) == 1 {
+		return names[0], nil
+	}
+	return "", fmt.Errorf("database is required: one of %s", strings.Join(b.manager.Names(), ", "))
+}
+
+// timeoutParam is the parameter bounding a call.
+var timeoutParam = tools.Parameter{
+	Name:        "timeout",
+	Type:        tools.TypeInt,
+	Required:    false,
+	Description: "Timeout in seconds (default: 30)",
+}
+
+// callTimeout returns the timeout parameter in seconds, or queryTimeout,
+// capped at maxTimeout.
+func callTimeout(params map[string]interface{}) time.Duration {
+	timeout := queryTimeout
+	if seconds := intParam(params, "timeout", 0); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	return min(timeout, maxTimeout)
+}
+
+// failure is the result of a database tool call that failed.
+func failure(err error, startTime time.Time) (*tools.Result, error) {
+	return &tools.Result{
+		Success:  false,
+		Error:    err,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// stringParam returns a string parameter, trimmed.
+func stringParam(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+// intParam returns an integer parameter, or def if it isn't given.
+func intParam(params map[string]interface{}, name string, def int) int {
+	switch v := params[name].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// truncate shortens s to n characters.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestManager returns a manager for a SQLite database holding teams and
+// users, under the name app.
+func newTestManager(t *testing.T, writable bool) (*Manager, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+CREATE TABLE teams (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	email TEXT NOT NULL,
+	team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+	role TEXT DEFAULT 'member'
+);
+CREATE VIEW admins AS SELECT * FROM users WHERE role = 'admin';
+INSERT INTO teams (name) VALUES ('core');
+INSERT INTO users (email, team_id, role) VALUES ('ada@example.com', 1, 'admin'), ('bob@example.com', 1, 'member');
+`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	m := NewManager(map[string]Database{"app": {Driver: DriverSQLite, DSN: path, Writable: writable}})
+	t.Cleanup(func() { m.Close() })
+	return m, path
+}
+
+func execute(t *testing.T, tool tools.Tool, params map[string]interface{}) *tools.Result {
+	t.Helper()
+	result, err := tool.Execute(context.Background(), params)
+	require.NoError(t, err)
+	return result
+}
+
+func TestSchemaTool(t *testing.T) {
+	m, _ := newTestManager(t, false)
+	result := execute(t, NewSchemaTool(m), map[string]interface{}{})
+	require.True(t, result.Success, "%v", result.Error)
+	tables := result.Metadata["tables"].([]Table)
+	require.Len(t, tables, 3)
+	assert.Equal(t, "admins", tables[0].Name)
+	assert.True(t, tables[0].View)
+
+	users := tables[2]
+	assert.Equal(t, "users", users.Name)
+	assert.Equal(t, []ForeignKey{{Column: "team_id", Table: "teams", Refers: "id", OnDelete: "CASCADE"}}, users.ForeignKeys)
+	assert.Contains(t, result.Output, "users\n  id INTEGER primary key\n  email TEXT not null\n  team_id INTEGER -> teams.id\n  role TEXT default 'member'")
+
+	result = execute(t, NewSchemaTool(m), map[string]interface{}{"database": "app", "table": "TEAMS"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "teams\n  id INTEGER primary key\n  name TEXT not null", result.Output)
+
+	result = execute(t, NewSchemaTool(m), map[string]interface{}{"table": "orders"})
+	assert.False(t, result.Success)
+}
+
+func TestQueryTool(t *testing.T) {
+	m, _ := newTestManager(t, true)
+	tool := NewQueryTool(m)
+	assert.Equal(t, "database app", tool.DescribeResource(map[string]interface{}{}))
+
+	result := execute(t, tool, map[string]interface{}{"query": "SELECT email, role, NULL AS note FROM users ORDER BY id"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "email | role | note\nada@example.com | admin | NULL\nbob@example.com | member | NULL\n(2 rows)", result.Output)
+	assert.Equal(t, [][]string{{"ada@example.com", "admin", "NULL"}, {"bob@example.com", "member", "NULL"}}, result.Metadata["rows"])
+
+	result = execute(t, tool, map[string]interface{}{"query": "SELECT id FROM users", "limit": 1})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.True(t, result.Metadata["truncated"].(bool))
+	assert.Contains(t, result.Output, "more not shown")
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		result := execute(t, tool, map[string]interface{}{"query": "DELETE FROM users"})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "use db_exec")
+
+		// Writes hidden in a query are refused by the database
+		result = execute(t, tool, map[string]interface{}{"query": "WITH x AS (SELECT 1) INSERT INTO teams (name) SELECT 'ops' FROM x"})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "readonly")
+		result = execute(t, tool, map[string]interface{}{"query": "SELECT 1; DROP TABLE users"})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "2 statements")
+
+		// The connection can still write afterwards
+		result = execute(t, NewExecTool(m), map[string]interface{}{"statement": "INSERT INTO teams (name) VALUES ('ops')"})
+		require.True(t, result.Success, "%v", result.Error)
+		result = execute(t, tool, map[string]interface{}{"query": "SELECT count(*) FROM teams"})
+		assert.Equal(t, [][]string{{"2"}}, result.Metadata["rows"])
+	})
+}
+
+func TestExecTool(t *testing.T) {
+	m, _ := newTestManager(t, true)
+	tool := NewExecTool(m)
+	assert.True(t, tool.RequiresConfirmation(nil))
+	assert.Equal(t, "database app: UPDATE users SET role = 'admin'", tool.DescribeResource(map[string]interface{}{"statement": "UPDATE users SET role = 'admin'"}))
+	assert.Equal(t, "Statement updates every row of a table", tool.SensitiveReason(map[string]interface{}{"statement": "UPDATE users SET role = 'admin'"}))
+	assert.Empty(t, tool.SensitiveReason(map[string]interface{}{"statement": "UPDATE users SET role = 'admin' WHERE id = 2"}))
+
+	result := execute(t, tool, map[string]interface{}{"statement": "UPDATE users SET role = 'admin' WHERE id = 2"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "Statement executed: 1 rows affected", result.Output)
+
+	result = execute(t, tool, map[string]interface{}{"statement": "INSERT INTO missing VALUES (1)"})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "no such table")
+
+	readOnly, _ := newTestManager(t, false)
+	result = execute(t, NewExecTool(readOnly), map[string]interface{}{"statement": "DELETE FROM users"})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "read-only")
+}
+
+func TestDatabaseSelection(t *testing.T) {
+	m := NewManager(map[string]Database{
+		"a":  {Driver: DriverSQLite, DSN: filepath.Join(t.TempDir(), "a.db")},
+		"pg": {Driver: DriverPostgres, DSN: "postgres://localhost/app"},
+	})
+	defer m.Close()
+	tool := NewQueryTool(m)
+	assert.True(t, tool.Parameters()[0].Required)
+
+	result := execute(t, tool, map[string]interface{}{"query": "SELECT 1"})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "database is required")
+	result = execute(t, tool, map[string]interface{}{"database": "b", "query": "SELECT 1"})
+	assert.Contains(t, result.Error.Error(), "unknown database")
+	result = execute(t, tool, map[string]interface{}{"database": "pg", "query": "SELECT 1"})
+	assert.Contains(t, result.Error.Error(), "no postgres driver")
+	result = execute(t, tool, map[string]interface{}{"database": "a", "query": "SELECT 1 AS one"})
+	require.True(t, result.Success, "%v", result.Error)
+}
+
+func TestParseStatements(t *testing.T) {
+	statements, err := parseStatements(`
+-- Leading comment; not a statement
+SELECT 'a;b', "weird;name", $$ body; $$, $1 FROM t /* ; */;
+update t set x = 'it''s' where id = $tag$;$tag$;
+;`)
+	require.NoError(t, err)
+	assert.Equal(t, []statement{
+		{"SELECT", "FROM", "T"},
+		{"UPDATE", "T", "SET", "X", "WHERE", "ID"},
+	}, statements)
+
+	_, err = parseStatements("SELECT 'open")
+	assert.Error(t, err)
+	_, err = parseStatements("SELECT 1 /* open")
+	assert.Error(t, err)
+
+	for sql, reason := range map[string]string{
+		"DROP TABLE users":                   "drops table",
+		"TRUNCATE users":                     "empties a table",
+		"ALTER TABLE users DROP COLUMN role": "drops part of a table",
+		"DELETE FROM users":                  "deletes every row of a table",
+		"DELETE FROM users WHERE id = 1":     "",
+		"INSERT INTO users VALUES (1)":       "",
+	} {
+		stmt, err := singleStatement(sql)
+		require.NoError(t, err)
+		assert.Equal(t, reason, stmt.destructive(), sql)
+	}
+}
+
+func TestToolMetadata(t *testing.T) {
+	for _, tool := range Tools(NewManager(nil)) {
+		t.Run(tool.Name(), func(t *testing.T) {
+			assert.Equal(t, "db", tool.Category())
+			assert.Equal(t, "1.0.0", tool.Version())
+			assert.False(t, tool.IsExternal())
+			assert.True(t, tool.RequiresPermission())
+			assert.NotEmpty(t, tool.Description())
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	_ "modernc.org/sqlite" // SQLite driver
+)
+
+// Supported database kinds
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// driverNames are the database/sql drivers each kind can use, in order of
+// preference. Only SQLite's is built in; Postgres and MySQL connections
+// need a build that links one of theirs.
+var driverNames = map[string][]string{
+	DriverSQLite:   {"sqlite", "sqlite3"},
+	DriverPostgres: {"pgx", "postgres"},
+	DriverMySQL:    {"mysql"},
+}
+
+// Database defines a connection.
+type Database struct {
+	Driver   string // sqlite, postgres or mysql
+	DSN      string // File path for SQLite, connection URL otherwise; $VARS are expanded
+	Writable bool   // Allow db_exec; each statement is still confirmed
+}
+
+// Manager holds the configured databases and opens each on first use.
+type Manager struct {
+	mu        sync.Mutex
+	databases map[string]Database
+	open      map[string]*sql.DB
+}
+
+// NewManager creates a manager for databases, by name.
+func NewManager(databases map[string]Database) *Manager {
+	return &Manager{
+		databases: databases,
+		open:      make(map[string]*sql.DB),
+	}
+}
+
+// Names returns the names of the configured databases, sorted.
+func (m *Manager) Names() []string {
+	names := make([]string, 0, len(m.databases))
+	for name := range m.databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Database returns the definition of a configured database.
+func (m *Manager) Database(name string) (Database, bool) {
+	d, ok := m.databases[name]
+	return d, ok
+}
+
+// conn returns the connection pool of a database, opening it if needed.
+func (m *Manager) conn(ctx context.Context, name string) (*sql.DB, Database, error) {
+	d, ok := m.databases[name]
+	if !ok {
+		return nil, d, fmt.Errorf("unknown database %q: configured are %v", name, m.Names())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if db, ok := m.open[name]; ok {
+		return db, d, nil
+	}
+	driver, err := driverFor(d.Driver)
+	if err != nil {
+		return nil, d, err
+	}
+	db, err := sql.Open(driver, os.ExpandEnv(d.DSN))
+	if err != nil {
+		return nil, d, fmt.Errorf("failed to open database %s: %w", name, err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, d, fmt.Errorf("failed to connect to database %s: %w", name, err)
+	}
+	m.open[name] = db
+	return db, d, nil
+}
+
+// driverFor returns the registered database/sql driver for a kind.
+func driverFor(kind string) (string, error) {
+	candidates, ok := driverNames[kind]
+	if !ok {
+		return "", fmt.Errorf("unsupported database driver %q (must be sqlite, postgres or mysql)", kind)
+	}
+	registered := make(map[string]bool)
+	for _, name := range sql.Drivers() {
+		registered[name] = true
+	}
+	for _, name := range candidates {
+		if registered[name] {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no %s driver is built into this binary", kind)
+}
+
+// Close closes the open connections.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var firstErr error
+	for name, db := range m.open {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close database %s: %w", name, err)
+		}
+		delete(m.open, name)
+	}
+	return firstErr
+}
@@ -0,0 +1,282 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// QueryTool runs a read-only query.
+type QueryTool struct{ base }
+
+// NewQueryTool creates a new db_query tool.
+func NewQueryTool(m *Manager) *QueryTool {
+	return &QueryTool{base{m}}
+}
+
+// Name returns the tool name.
+func (t *QueryTool) Name() string {
+	return "db_query"
+}
+
+// Description returns the tool description.
+func (t *QueryTool) Description() string {
+	return "Runs a read-only SQL query, such as a SELECT, on a configured database and returns the rows. " +
+		"The query runs in a read-only transaction; use db_exec for statements that change the database"
+}
+
+// Parameters returns the tool parameters.
+func (t *QueryTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		t.databaseParam(),
+		{
+			Name:        "query",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "A single SQL statement in the database's dialect",
+		},
+		{
+			Name:        "limit",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Most rows to return (default: 100, at most 1000)",
+			Default:     defaultRows,
+		},
+		timeoutParam,
+	}
+}
+
+// DescribeResource names the database for permission prompts.
+func (t *QueryTool) DescribeResource(params map[string]interface{}) string {
+	name, _ := t.database(params)
+	return "database " + name
+}
+
+// Execute runs the query.
+func (t *QueryTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	name, err := t.database(params)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	query := stringParam(params, "query")
+	stmt, err := singleStatement(query)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	if !stmt.reads() {
+		return failure(fmt.Errorf("db_query only runs queries that read; use db_exec for %s statements", stmt[0]), startTime)
+	}
+	limit := min(max(intParam(params, "limit", defaultRows), 1), maxRows)
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout(params))
+	defer cancel()
+	db, d, err := t.manager.conn(ctx, name)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	var rows *resultRows
+	err = readOnly(ctx, db, d.Driver, func(tx *sql.Tx) error {
+		rows, err = queryRows(ctx, tx, query, limit)
+		return err
+	})
+	if err != nil {
+		return failure(fmt.Errorf("query failed: %w", err), startTime)
+	}
+
+	return &tools.Result{
+		Success: true,
+		Output:  rows.String(),
+		Metadata: map[string]interface{}{
+			"database":  name,
+			"columns":   rows.columns,
+			"rows":      rows.values,
+			"truncated": rows.truncated,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// resultRows are the rows a query returned, as text.
+type resultRows struct {
+	columns   []string
+	values    [][]string
+	truncated bool // More rows than the limit were returned
+}
+
+// queryRows runs a query and reads up to limit rows.
+func queryRows(ctx context.Context, tx *sql.Tx, query string, limit int) (*resultRows, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &resultRows{columns: columns}
+	for rows.Next() {
+		if len(r.values) == limit {
+			r.truncated = true
+			break
+		}
+		raw := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range raw {
+			ptrs[i] = &raw[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		values := make([]string, len(raw))
+		for i, v := range raw {
+			values[i] = formatValue(v)
+		}
+		r.values = append(r.values, values)
+	}
+	return r, rows.Err()
+}
+
+// formatValue renders a scanned value.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// String renders the rows as a table, one row per line with values
+// separated by " | ".
+func (r *resultRows) String() string {
+	if len(r.values) == 0 {
+		return "No rows (columns: " + strings.Join(r.columns, ", ") + ")"
+	}
+	var b strings.Builder
+	b.WriteString(strings.Join(r.columns, " | "))
+	for _, row := range r.values {
+		b.WriteByte('\n')
+		for i, v := range row {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString(strings.ReplaceAll(truncate(v, maxCell), "\n", `\n`))
+		}
+		if b.Len() > maxOutput {
+			b.WriteString("\n... (output truncated)")
+			return b.String()
+		}
+	}
+	fmt.Fprintf(&b, "\n(%d rows", len(r.values))
+	if r.truncated {
+		b.WriteString(", more not shown: narrow the query or raise limit")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// ExecTool runs a statement that changes a database.
+type ExecTool struct{ base }
+
+// NewExecTool creates a new db_exec tool.
+func NewExecTool(m *Manager) *ExecTool {
+	return &ExecTool{base{m}}
+}
+
+// Name returns the tool name.
+func (t *ExecTool) Name() string {
+	return "db_exec"
+}
+
+// Description returns the tool description.
+func (t *ExecTool) Description() string {
+	return "Runs a SQL statement that changes a database, such as an INSERT, UPDATE or CREATE TABLE, and returns the rows it affected. " +
+		"Only databases configured as writable accept it, and every statement is put to the user"
+}
+
+// Parameters returns the tool parameters.
+func (t *ExecTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		t.databaseParam(),
+		{
+			Name:        "statement",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "A single SQL statement in the database's dialect",
+		},
+		timeoutParam,
+	}
+}
+
+// RequiresConfirmation returns true: every statement is confirmed.
+func (t *ExecTool) RequiresConfirmation(params map[string]interface{}) bool {
+	return true
+}
+
+// DescribeResource describes the statement for permission prompts.
+func (t *ExecTool) DescribeResource(params map[string]interface{}) string {
+	name, _ := t.database(params)
+	return "database " + name + ": " + truncate(stringParam(params, "statement"), maxCell)
+}
+
+// SensitiveReason flags statements that drop or empty data.
+func (t *ExecTool) SensitiveReason(params map[string]interface{}) string {
+	stmt, err := singleStatement(stringParam(params, "statement"))
+	if err != nil {
+		return ""
+	}
+	if reason := stmt.destructive(); reason != "" {
+		return "Statement " + reason
+	}
+	return ""
+}
+
+// Execute runs the statement.
+func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	name, err := t.database(params)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	statement := stringParam(params, "statement")
+	if _, err := singleStatement(statement); err != nil {
+		return failure(err, startTime)
+	}
+	if d, ok := t.manager.Database(name); ok && !d.Writable {
+		return failure(fmt.Errorf("database %s is read-only: set writable in its config to allow db_exec", name), startTime)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout(params))
+	defer cancel()
+	db, _, err := t.manager.conn(ctx, name)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	res, err := db.ExecContext(ctx, statement)
+	if err != nil {
+		return failure(fmt.Errorf("statement failed: %w", err), startTime)
+	}
+
+	output := "Statement executed"
+	metadata := map[string]interface{}{"database": name}
+	if n, err := res.RowsAffected(); err == nil {
+		output = fmt.Sprintf("Statement executed: %d rows affected", n)
+		metadata["rows_affected"] = n
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   output,
+		Metadata: metadata,
+		Duration: time.Since(startTime),
+	}, nil
+}
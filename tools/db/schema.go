@@ -0,0 +1,282 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Table is a table or view and its columns.
+type Table struct {
+	Name        string       `json:"name"` // Qualified by its schema outside the default one
+	View        bool         `json:"view,omitempty"`
+	Columns     []Column     `json:"columns"`
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+}
+
+// Column is a column of a table.
+type Column struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Nullable   bool   `json:"nullable"`
+	Default    string `json:"default,omitempty"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+}
+
+// ForeignKey is a column referencing another table's.
+type ForeignKey struct {
+	Column   string `json:"column"`
+	Table    string `json:"table"`
+	Refers   string `json:"refers"`
+	OnDelete string `json:"on_delete,omitempty"`
+}
+
+// String renders the table with a column per line.
+func (t Table) String() string {
+	var b strings.Builder
+	b.WriteString(t.Name)
+	if t.View {
+		b.WriteString(" (view)")
+	}
+	refs := make(map[string]ForeignKey, len(t.ForeignKeys))
+	for _, fk := range t.ForeignKeys {
+		refs[fk.Column] = fk
+	}
+	for _, c := range t.Columns {
+		fmt.Fprintf(&b, "\n  %s %s", c.Name, c.Type)
+		if c.PrimaryKey {
+			b.WriteString(" primary key")
+		} else if !c.Nullable {
+			b.WriteString(" not null")
+		}
+		if c.Default != "" {
+			b.WriteString(" default " + c.Default)
+		}
+		if fk, ok := refs[c.Name]; ok {
+			fmt.Fprintf(&b, " -> %s.%s", fk.Table, fk.Refers)
+		}
+	}
+	return b.String()
+}
+
+// SchemaTool describes the tables of a database.
+type SchemaTool struct{ base }
+
+// NewSchemaTool creates a new db_schema tool.
+func NewSchemaTool(m *Manager) *SchemaTool {
+	return &SchemaTool{base{m}}
+}
+
+// Name returns the tool name.
+func (t *SchemaTool) Name() string {
+	return "db_schema"
+}
+
+// Description returns the tool description.
+func (t *SchemaTool) Description() string {
+	return "Lists the tables and views of a configured database with their columns, types, primary keys and, for SQLite, foreign keys"
+}
+
+// Parameters returns the tool parameters.
+func (t *SchemaTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		t.databaseParam(),
+		{
+			Name:        "table",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Only describe this table (default: all)",
+		},
+		timeoutParam,
+	}
+}
+
+// DescribeResource names the database for permission prompts.
+func (t *SchemaTool) DescribeResource(params map[string]interface{}) string {
+	name, _ := t.database(params)
+	return "database " + name
+}
+
+// Execute describes the schema.
+func (t *SchemaTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	name, err := t.database(params)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	only := stringParam(params, "table")
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout(params))
+	defer cancel()
+	db, d, err := t.manager.conn(ctx, name)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	var tables []Table
+	err = readOnly(ctx, db, d.Driver, func(tx *sql.Tx) error {
+		if d.Driver == DriverSQLite {
+			tables, err = sqliteSchema(ctx, tx)
+		} else {
+			tables, err = informationSchema(ctx, tx, d.Driver)
+		}
+		return err
+	})
+	if err != nil {
+		return failure(fmt.Errorf("failed to read schema: %w", err), startTime)
+	}
+	if only != "" {
+		var found []Table
+		for _, table := range tables {
+			if strings.EqualFold(table.Name, only) || strings.HasSuffix(strings.ToLower(table.Name), "."+strings.ToLower(only)) {
+				found = append(found, table)
+			}
+		}
+		if len(found) == 0 {
+			return failure(fmt.Errorf("no table %s in database %s", only, name), startTime)
+		}
+		tables = found
+	}
+
+	var b strings.Builder
+	if len(tables) == 0 {
+		b.WriteString("No tables")
+	}
+	for i, table := range tables {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(table.String())
+		if b.Len() > maxOutput {
+			fmt.Fprintf(&b, "\n\n... %d more tables: describe one at a time with table", len(tables)-i-1)
+			break
+		}
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   b.String(),
+		Metadata: map[string]interface{}{"database": name, "tables": tables},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// sqliteSchema reads a SQLite database's tables from its catalog.
+func sqliteSchema(ctx context.Context, tx *sql.Tx) ([]Table, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT name, type FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var tables []Table
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, Table{Name: name, View: kind == "view"})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range tables {
+		columns, err := tx.QueryContext(ctx,
+			`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?)`, tables[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		for columns.Next() {
+			var c Column
+			var notNull bool
+			var def sql.NullString
+			var pk int
+			if err := columns.Scan(&c.Name, &c.Type, &notNull, &def, &pk); err != nil {
+				columns.Close()
+				return nil, err
+			}
+			c.Nullable, c.Default, c.PrimaryKey = !notNull && pk == 0, def.String, pk > 0
+			tables[i].Columns = append(tables[i].Columns, c)
+		}
+		columns.Close()
+
+		keys, err := tx.QueryContext(ctx,
+			`SELECT "from", "table", "to", on_delete FROM pragma_foreign_key_list(?)`, tables[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		for keys.Next() {
+			var fk ForeignKey
+			var to sql.NullString
+			if err := keys.Scan(&fk.Column, &fk.Table, &to, &fk.OnDelete); err != nil {
+				keys.Close()
+				return nil, err
+			}
+			fk.Refers = to.String
+			if fk.OnDelete == "NO ACTION" {
+				fk.OnDelete = ""
+			}
+			tables[i].ForeignKeys = append(tables[i].ForeignKeys, fk)
+		}
+		keys.Close()
+	}
+	return tables, nil
+}
+
+// schemaFilters select the user's schemas in information_schema, and
+// currentSchemas name the one tables are shown unqualified in.
+var (
+	schemaFilters = map[string]string{
+		DriverPostgres: "c.table_schema NOT IN ('pg_catalog', 'information_schema')",
+		DriverMySQL:    "c.table_schema = DATABASE()",
+	}
+	currentSchemas = map[string]string{
+		DriverPostgres: "current_schema()",
+		DriverMySQL:    "DATABASE()",
+	}
+)
+
+// informationSchema reads a Postgres or MySQL database's tables from
+// information_schema.
+func informationSchema(ctx context.Context, tx *sql.Tx, kind string) ([]Table, error) {
+	query := fmt.Sprintf(`SELECT c.table_schema, c.table_schema = %s, c.table_name, t.table_type,
+	c.column_name, c.data_type, c.is_nullable, c.column_default,
+	EXISTS (SELECT 1 FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage k
+			ON k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema AND k.table_name = tc.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+			AND tc.table_name = c.table_name AND k.column_name = c.column_name)
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE %s
+ORDER BY c.table_schema, c.table_name, c.ordinal_position`, currentSchemas[kind], schemaFilters[kind])
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []Table
+	for rows.Next() {
+		var schema, table, tableType, nullable string
+		var current, pk bool
+		var def sql.NullString
+		var c Column
+		if err := rows.Scan(&schema, &current, &table, &tableType, &c.Name, &c.Type, &nullable, &def, &pk); err != nil {
+			return nil, err
+		}
+		c.Nullable, c.Default, c.PrimaryKey = nullable == "YES", def.String, pk
+		if !current {
+			table = schema + "." + table
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Name != table {
+			tables = append(tables, Table{Name: table, View: tableType == "VIEW"})
+		}
+		tables[len(tables)-1].Columns = append(tables[len(tables)-1].Columns, c)
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// statement is the words of a SQL statement, upper-cased, with literals,
+// quoted identifiers and comments left out.
+type statement []string
+
+// has reports whether the statement contains a word.
+func (s statement) has(word string) bool {
+	for _, w := range s {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// readKeywords are the first words of statements that only read.
+var readKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"EXPLAIN":  true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"PRAGMA":   true,
+}
+
+// reads reports whether the statement looks like it only reads. Queries
+// still run in a read-only transaction, which is what enforces it: a
+// WITH can wrap an INSERT.
+func (s statement) reads() bool {
+	return len(s) > 0 && readKeywords[s[0]]
+}
+
+// destructive returns why a statement drops or empties data, if it does.
+func (s statement) destructive() string {
+	switch {
+	case len(s) == 0:
+		return ""
+	case s[0] == "DROP":
+		return "drops " + strings.ToLower(strings.Join(s[1:min(len(s), 2)], " "))
+	case s[0] == "TRUNCATE":
+		return "empties a table"
+	case s[0] == "ALTER" && s.has("DROP"):
+		return "drops part of a table"
+	case (s[0] == "DELETE" || s[0] == "UPDATE") && !s.has("WHERE"):
+		return strings.ToLower(s[0]) + "s every row of a table"
+	}
+	return ""
+}
+
+// parseStatements splits SQL into statements at the semicolons outside
+// literals and comments. Empty statements are dropped.
+func parseStatements(query string) ([]statement, error) {
+	var statements []statement
+	var cur statement
+	end := func() {
+		if len(cur) > 0 {
+			statements = append(statements, cur)
+		}
+		cur = nil
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			i += j
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += j + 4
+		case c == '\'' || c == '"' || c == '`':
+			// Quotes are escaped by doubling them
+			j := i + 1
+			for {
+				k := strings.IndexByte(query[j:], c)
+				if k < 0 {
+					return nil, fmt.Errorf("unterminated %c quote", c)
+				}
+				j += k + 1
+				if j >= len(query) || query[j] != c {
+					break
+				}
+				j++
+			}
+			i = j
+		case c == '$' && dollarTag(query[i:]) != "":
+			tag := dollarTag(query[i:])
+			j := strings.Index(query[i+len(tag):], tag)
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated %s quote", tag)
+			}
+			i += 2*len(tag) + j
+		case c == ';':
+			end()
+			i++
+		case isWordStart(c):
+			j := i + 1
+			for j < len(query) && (isWordStart(query[j]) || query[j] >= '0' && query[j] <= '9' || query[j] == '$') {
+				j++
+			}
+			cur = append(cur, strings.ToUpper(query[i:j]))
+			i = j
+		default:
+			i++
+		}
+	}
+	end()
+	return statements, nil
+}
+
+// dollarTag returns the Postgres dollar quote s starts with, such as $$
+// or $body$, or "" if it starts with none.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case isWordStart(c) || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// isWordStart reports whether c can start a keyword or identifier.
+func isWordStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// singleStatement parses SQL that must hold exactly one statement.
+func singleStatement(query string) (statement, error) {
+	statements, err := parseStatements(query)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse SQL: %w", err)
+	}
+	switch len(statements) {
+	case 0:
+		return nil, fmt.Errorf("no SQL statement given")
+	case 1:
+		return statements[0], nil
+	}
+	return nil, fmt.Errorf("%d statements given: run one per call", len(statements))
+}
+
+// readOnly runs fn in a transaction that cannot change the database, and
+// rolls it back. SQLite ignores read-only transactions, so its connection
+// is switched to query_only for the duration instead.
+func readOnly(ctx context.Context, db *sql.DB, kind string, fn func(*sql.Tx) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if kind == DriverSQLite {
+		if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+			return err
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), "PRAGMA query_only = OFF"); err != nil {
+				// Don't hand a read-only connection back to the pool
+				_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
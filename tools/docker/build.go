@@ -0,0 +1,184 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// buildLogLines is how many lines of a successful build's log are returned.
+const buildLogLines = 20
+
+// BuildTool builds an image.
+type BuildTool struct{ base }
+
+// NewBuildTool creates a new docker_build tool.
+func NewBuildTool(s *Session) *BuildTool {
+	return &BuildTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *BuildTool) Name() string {
+	return "docker_build"
+}
+
+// Description returns the tool description.
+func (t *BuildTool) Description() string {
+	return "Builds a docker image from a Dockerfile and returns its image ID. A failed build returns the end of the build log"
+}
+
+// Parameters returns the tool parameters.
+func (t *BuildTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "context",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Build context directory (default: .)",
+			Default:     ".",
+		},
+		{
+			Name:        "file",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Dockerfile to build (default: Dockerfile in the context)",
+		},
+		{
+			Name:        "tag",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Name and tag for the image, e.g. myapp:dev",
+		},
+		{
+			Name:        "build_args",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Build arguments, one KEY=VALUE per line",
+		},
+		{
+			Name:        "target",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Stage of a multi-stage build to stop at",
+		},
+		{
+			Name:        "no_cache",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Build without the layer cache",
+			Default:     false,
+		},
+		workingDirParam,
+		{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Timeout in seconds (default: 1800)",
+		},
+	}
+}
+
+// RequiresConfirmation returns true: every build is confirmed.
+func (t *BuildTool) RequiresConfirmation(params map[string]interface{}) bool {
+	return true
+}
+
+// DescribeResource describes the build for permission prompts.
+func (t *BuildTool) DescribeResource(params map[string]interface{}) string {
+	desc := "docker build " + firstNonEmpty(stringParam(params, "context"), ".")
+	if tag := stringParam(params, "tag"); tag != "" {
+		desc += " -t " + tag
+	}
+	return desc
+}
+
+// Execute builds the image.
+func (t *BuildTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	dir := stringParam(params, "working_dir")
+	buildContext := firstNonEmpty(stringParam(params, "context"), ".")
+	tag := stringParam(params, "tag")
+
+	tmp, err := os.MkdirTemp("", "bplus-docker-*")
+	if err != nil {
+		return failure(err, startTime)
+	}
+	defer os.RemoveAll(tmp)
+	iidFile := filepath.Join(tmp, "iid")
+
+	args := []string{"build", "--iidfile", iidFile}
+	if tag != "" {
+		if err := checkName("tag", tag); err != nil {
+			return failure(err, startTime)
+		}
+		args = append(args, "-t", tag)
+	}
+	if file := stringParam(params, "file"); file != "" {
+		args = append(args, "-f", file)
+	}
+	for _, arg := range linesParam(params, "build_args") {
+		if !strings.Contains(arg, "=") {
+			return failure(fmt.Errorf("invalid build argument %q: must be KEY=VALUE", arg), startTime)
+		}
+		args = append(args, "--build-arg", arg)
+	}
+	if target := stringParam(params, "target"); target != "" {
+		args = append(args, "--target", target)
+	}
+	if boolParam(params, "no_cache", false) {
+		args = append(args, "--no-cache")
+	}
+	if err := checkName("context", buildContext); err != nil {
+		return failure(err, startTime)
+	}
+	args = append(args, buildContext)
+
+	r, err := t.session.run(ctx, dir, timeoutParam(params, buildTimeout), args...)
+	if err != nil {
+		return failure(fmt.Errorf("build failed: %s", tail(r.output(), maxOutput)), startTime)
+	}
+	data, _ := os.ReadFile(iidFile)
+	imageID := strings.TrimSpace(string(data))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Built image %s", firstNonEmpty(imageID, "(unknown ID)"))
+	if tag != "" {
+		fmt.Fprintf(&b, " tagged %s", tag)
+	}
+	if log := lastLines(r.output(), buildLogLines); log != "" {
+		b.WriteString("\n\n" + log)
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  b.String(),
+		Metadata: map[string]interface{}{
+			"image_id": imageID,
+			"tag":      tag,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// firstNonEmpty returns the first of values that isn't empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// lastLines returns the last n lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,297 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// composeParams are the parameters selecting a compose project.
+var composeParams = []tools.Parameter{
+	{
+		Name:        "working_dir",
+		Type:        tools.TypeString,
+		Required:    false,
+		Description: "Directory of the compose project (default: current directory)",
+	},
+	{
+		Name:        "files",
+		Type:        tools.TypeString,
+		Required:    false,
+		Description: "Compose files, one per line (default: compose.yaml or docker-compose.yml in the directory)",
+	},
+	{
+		Name:        "project",
+		Type:        tools.TypeString,
+		Required:    false,
+		Description: "Project name (default: the directory name)",
+	},
+}
+
+// composeProject reads the project the parameters select.
+func composeProject(params map[string]interface{}) (ComposeProject, error) {
+	dir := stringParam(params, "working_dir")
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ComposeProject{}, err
+	}
+	p := ComposeProject{Dir: abs, Files: linesParam(params, "files"), Project: stringParam(params, "project")}
+	if err := checkName("project", p.Project); err != nil {
+		return p, err
+	}
+	for _, f := range p.Files {
+		if err := checkName("compose file", f); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// ComposeUpTool brings up a compose project.
+type ComposeUpTool struct{ base }
+
+// NewComposeUpTool creates a new compose_up tool.
+func NewComposeUpTool(s *Session) *ComposeUpTool {
+	return &ComposeUpTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *ComposeUpTool) Name() string {
+	return "compose_up"
+}
+
+// Description returns the tool description.
+func (t *ComposeUpTool) Description() string {
+	return "Brings up a docker compose project, or some of its services, in the background and lists the state of its services. " +
+		"The project is taken down when the session ends"
+}
+
+// Parameters returns the tool parameters.
+func (t *ComposeUpTool) Parameters() []tools.Parameter {
+	return append(append([]tools.Parameter(nil), composeParams...),
+		tools.Parameter{
+			Name:        "services",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Services to bring up, one per line (default: all)",
+		},
+		tools.Parameter{
+			Name:        "build",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Build images before starting",
+			Default:     false,
+		},
+		tools.Parameter{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Timeout in seconds (default: 1800)",
+		},
+	)
+}
+
+// RequiresConfirmation returns true: every project brought up is confirmed.
+func (t *ComposeUpTool) RequiresConfirmation(params map[string]interface{}) bool {
+	return true
+}
+
+// DescribeResource describes the project for permission prompts.
+func (t *ComposeUpTool) DescribeResource(params map[string]interface{}) string {
+	desc := "docker compose up"
+	if services := linesParam(params, "services"); len(services) > 0 {
+		desc += " " + strings.Join(services, " ")
+	}
+	if dir := stringParam(params, "working_dir"); dir != "" {
+		desc += " in " + dir
+	}
+	return desc
+}
+
+// ComposeService is the state of a compose service's container.
+type ComposeService struct {
+	Service string `json:"service"`
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Status  string `json:"status,omitempty"`
+	Health  string `json:"health,omitempty"`
+	Ports   string `json:"ports,omitempty"`
+}
+
+// composePsEntry is a container as docker compose ps --format json lists it.
+type composePsEntry struct {
+	Service string
+	Name    string
+	State   string
+	Status  string
+	Health  string
+	Ports   string
+}
+
+// Execute brings up the project.
+func (t *ComposeUpTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	p, err := composeProject(params)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	p.Services = linesParam(params, "services")
+	for _, service := range p.Services {
+		if err := checkName("service", service); err != nil {
+			return failure(err, startTime)
+		}
+	}
+	p.Started = time.Now()
+
+	args := append([]string{"compose"}, p.args()...)
+	args = append(args, "up", "--detach")
+	if boolParam(params, "build", false) {
+		args = append(args, "--build")
+	}
+	args = append(args, p.Services...)
+	r, err := t.session.run(ctx, p.Dir, timeoutParam(params, buildTimeout), args...)
+	if err != nil {
+		// Services that started before the failure are still torn down
+		t.session.trackProject(p)
+		return failure(fmt.Errorf("compose up failed: %s", tail(r.output(), maxOutput)), startTime)
+	}
+	t.session.trackProject(p)
+
+	services, err := t.services(ctx, p)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Brought up %s", filepath.Base(p.Dir))
+	for _, s := range services {
+		fmt.Fprintf(&b, "\n%s (%s): %s", s.Service, s.Name, firstNonEmpty(s.Status, s.State))
+		if s.Ports != "" {
+			fmt.Fprintf(&b, " [%s]", s.Ports)
+		}
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   b.String(),
+		Metadata: map[string]interface{}{"services": services, "dir": p.Dir},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// services lists the containers of the project's services.
+func (t *ComposeUpTool) services(ctx context.Context, p ComposeProject) ([]ComposeService, error) {
+	args := append([]string{"compose"}, p.args()...)
+	args = append(args, "ps", "--all", "--format", "json")
+	r, err := t.session.run(ctx, p.Dir, commandTimeout, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older compose versions print an array, newer ones a line per container
+	var entries []composePsEntry
+	out := strings.TrimSpace(r.stdout)
+	if strings.HasPrefix(out, "[") {
+		if err := json.Unmarshal([]byte(out), &entries); err != nil {
+			return nil, fmt.Errorf("unexpected compose ps output: %w", err)
+		}
+	} else {
+		for _, line := range strings.Split(out, "\n") {
+			var e composePsEntry
+			if line = strings.TrimSpace(line); line != "" && json.Unmarshal([]byte(line), &e) == nil {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	services := make([]ComposeService, 0, len(entries))
+	for _, e := range entries {
+		services = append(services, ComposeService(e))
+	}
+	return services, nil
+}
+
+// ComposeDownTool takes down a compose project.
+type ComposeDownTool struct{ base }
+
+// NewComposeDownTool creates a new compose_down tool.
+func NewComposeDownTool(s *Session) *ComposeDownTool {
+	return &ComposeDownTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *ComposeDownTool) Name() string {
+	return "compose_down"
+}
+
+// Description returns the tool description.
+func (t *ComposeDownTool) Description() string {
+	return "Stops and removes the containers and networks of a docker compose project, and its volumes if asked"
+}
+
+// Parameters returns the tool parameters.
+func (t *ComposeDownTool) Parameters() []tools.Parameter {
+	return append(append([]tools.Parameter(nil), composeParams...),
+		tools.Parameter{
+			Name:        "volumes",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Also remove the project's named volumes, deleting their data",
+			Default:     false,
+		},
+	)
+}
+
+// RequiresConfirmation returns true: every project taken down is confirmed.
+func (t *ComposeDownTool) RequiresConfirmation(params map[string]interface{}) bool {
+	return true
+}
+
+// DescribeResource describes the project for permission prompts.
+func (t *ComposeDownTool) DescribeResource(params map[string]interface{}) string {
+	desc := "docker compose down"
+	if boolParam(params, "volumes", false) {
+		desc += " --volumes"
+	}
+	if dir := stringParam(params, "working_dir"); dir != "" {
+		desc += " in " + dir
+	}
+	return desc
+}
+
+// Execute takes down the project.
+func (t *ComposeDownTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	p, err := composeProject(params)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	volumes := boolParam(params, "volumes", false)
+
+	args := append([]string{"compose"}, p.args()...)
+	args = append(args, "down", "--remove-orphans")
+	if volumes {
+		args = append(args, "--volumes")
+	}
+	if _, err := t.session.run(ctx, p.Dir, commandTimeout, args...); err != nil {
+		return failure(err, startTime)
+	}
+	t.session.untrackProject(p)
+
+	output := "Took down " + filepath.Base(p.Dir)
+	if volumes {
+		output += " and removed its volumes"
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   output,
+		Metadata: map[string]interface{}{"dir": p.Dir, "volumes": volumes},
+		Duration: time.Since(startTime),
+	}, nil
+}
@@ -0,0 +1,296 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// defaultLogLines is how many lines of logs are returned by default.
+const defaultLogLines = 200
+
+// LogsTool returns a container's logs.
+type LogsTool struct{ base }
+
+// NewLogsTool creates a new docker_logs tool.
+func NewLogsTool(s *Session) *LogsTool {
+	return &LogsTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *LogsTool) Name() string {
+	return "docker_logs"
+}
+
+// Description returns the tool description.
+func (t *LogsTool) Description() string {
+	return "Returns the latest output of a container"
+}
+
+// Parameters returns the tool parameters.
+func (t *LogsTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "container",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Container name or ID",
+		},
+		{
+			Name:        "tail",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Number of lines from the end (default: 200)",
+			Default:     defaultLogLines,
+		},
+		{
+			Name:        "since",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Only output since this time, e.g. 10m or 2024-01-02T15:04:05",
+		},
+	}
+}
+
+// Execute returns the logs.
+func (t *LogsTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	container := stringParam(params, "container")
+	if container == "" {
+		return failure(fmt.Errorf("container is required"), startTime)
+	}
+	if err := checkName("container", container); err != nil {
+		return failure(err, startTime)
+	}
+
+	args := []string{"logs", "--tail", fmt.Sprint(max(intParam(params, "tail", defaultLogLines), 1))}
+	if since := stringParam(params, "since"); since != "" {
+		args = append(args, "--since", since)
+	}
+	args = append(args, container)
+	r, err := t.session.run(ctx, "", commandTimeout, args...)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   tail(r.output(), maxOutput),
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// PsTool lists containers.
+type PsTool struct{ base }
+
+// NewPsTool creates a new docker_ps tool.
+func NewPsTool(s *Session) *PsTool {
+	return &PsTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *PsTool) Name() string {
+	return "docker_ps"
+}
+
+// Description returns the tool description.
+func (t *PsTool) Description() string {
+	return "Lists the containers started this session, including those of compose projects brought up, with their state and ports; " +
+		"with all, every container on the host"
+}
+
+// Parameters returns the tool parameters.
+func (t *PsTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "all",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "List every container, not only this session's",
+			Default:     false,
+		},
+	}
+}
+
+// ContainerState is a container as listed by docker_ps.
+type ContainerState struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	State   string `json:"state"`  // e.g. running or exited
+	Status  string `json:"status"` // e.g. "Up 5 minutes (healthy)"
+	Ports   string `json:"ports,omitempty"`
+	Project string `json:"project,omitempty"` // Compose project
+	Service string `json:"service,omitempty"` // Compose service
+}
+
+// psEntry is a line of docker ps --format '{{json .}}'.
+type psEntry struct {
+	ID     string
+	Names  string
+	Image  string
+	State  string
+	Status string
+	Ports  string
+	Labels string
+}
+
+// Execute lists the containers.
+func (t *PsTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	all := boolParam(params, "all", false)
+
+	r, err := t.session.run(ctx, "", commandTimeout, "ps", "--all", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return failure(err, startTime)
+	}
+	projectDirs := make(map[string]bool)
+	for _, p := range t.session.Projects() {
+		if abs, err := filepath.Abs(p.Dir); err == nil {
+			projectDirs[abs] = true
+		}
+	}
+
+	var containers []ContainerState
+	for _, line := range strings.Split(r.stdout, "\n") {
+		var e psEntry
+		if line = strings.TrimSpace(line); line == "" || json.Unmarshal([]byte(line), &e) != nil {
+			continue
+		}
+		labels := parseLabels(e.Labels)
+		mine := labels[sessionLabel] == t.session.ID() || t.session.started(e.ID) ||
+			projectDirs[labels["com.docker.compose.project.working_dir"]]
+		if !all && !mine {
+			continue
+		}
+		containers = append(containers, ContainerState{
+			ID:      e.ID,
+			Name:    e.Names,
+			Image:   e.Image,
+			State:   e.State,
+			Status:  e.Status,
+			Ports:   e.Ports,
+			Project: labels["com.docker.compose.project"],
+			Service: labels["com.docker.compose.service"],
+		})
+	}
+
+	var b strings.Builder
+	if len(containers) == 0 {
+		b.WriteString("No containers")
+	}
+	for i, c := range containers {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s %s (%s): %s", shortID(c.ID), c.Name, c.Image, c.Status)
+		if c.Ports != "" {
+			fmt.Fprintf(&b, " [%s]", c.Ports)
+		}
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  b.String(),
+		Metadata: map[string]interface{}{
+			"containers": containers,
+			"projects":   t.session.Projects(),
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// parseLabels reads the labels docker ps lists as key=value,key=value.
+func parseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// StopTool stops a container.
+type StopTool struct{ base }
+
+// NewStopTool creates a new docker_stop tool.
+func NewStopTool(s *Session) *StopTool {
+	return &StopTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *StopTool) Name() string {
+	return "docker_stop"
+}
+
+// Description returns the tool description.
+func (t *StopTool) Description() string {
+	return "Stops a container and, unless remove is false, removes it"
+}
+
+// Parameters returns the tool parameters.
+func (t *StopTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "container",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Container name or ID",
+		},
+		{
+			Name:        "remove",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Remove the container once stopped (default: true)",
+			Default:     true,
+		},
+	}
+}
+
+// RequiresConfirmation returns true: every container stopped is confirmed.
+func (t *StopTool) RequiresConfirmation(params map[string]interface{}) bool {
+	return true
+}
+
+// DescribeResource describes the container for permission prompts.
+func (t *StopTool) DescribeResource(params map[string]interface{}) string {
+	if boolParam(params, "remove", true) {
+		return "docker stop and remove " + stringParam(params, "container")
+	}
+	return "docker stop " + stringParam(params, "container")
+}
+
+// Execute stops the container.
+func (t *StopTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	container := stringParam(params, "container")
+	if container == "" {
+		return failure(fmt.Errorf("container is required"), startTime)
+	}
+	if err := checkName("container", container); err != nil {
+		return failure(err, startTime)
+	}
+	remove := boolParam(params, "remove", true)
+
+	if _, err := t.session.run(ctx, "", commandTimeout, "stop", container); err != nil {
+		return failure(err, startTime)
+	}
+	output := "Stopped " + container
+	if remove {
+		if _, err := t.session.run(ctx, "", commandTimeout, "rm", container); err != nil {
+			return failure(err, startTime)
+		}
+		t.session.untrack(container)
+		output = "Stopped and removed " + container
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   output,
+		Metadata: map[string]interface{}{"container": container, "removed": remove},
+		Duration: time.Since(startTime),
+	}, nil
+}
@@ -0,0 +1,260 @@
+// Package docker provides structured tools for building images and running
+// containers with the docker CLI and docker compose.
+//
+// Containers and compose projects started through the tools are tracked
+// per session and torn down when it ends. Every call needs execute
+// permission, and those that build, start, change or remove anything must
+// be confirmed each time: a standing grant doesn't cover them.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Docker defaults
+const (
+	// commandTimeout bounds quick commands, such as listing containers.
+	commandTimeout = 60 * time.Second
+
+	// buildTimeout bounds image builds and compose up, which may pull and
+	// build images.
+	buildTimeout = 30 * time.Minute
+
+	// maxOutput bounds the build logs and container output returned to the
+	// agent; the end is kept.
+	maxOutput = 20000
+)
+
+// Tools returns all docker tools, tracking what they start in s.
+func Tools(s *Session) []tools.Tool {
+	return []tools.Tool{
+		NewBuildTool(s),
+		NewRunTool(s),
+		NewExecTool(s),
+		NewLogsTool(s),
+		NewPsTool(s),
+		NewStopTool(s),
+		NewComposeUpTool(s),
+		NewComposeDownTool(s),
+	}
+}
+
+// workingDirParam is the parameter naming the directory docker runs in.
+var workingDirParam = tools.Parameter{
+	Name:        "working_dir",
+	Type:        tools.TypeString,
+	Required:    false,
+	Description: "Directory to run docker in (default: current directory)",
+}
+
+// base holds what all docker tools have in common.
+type base struct {
+	session *Session
+}
+
+// Category returns the tool category.
+func (base) Category() string {
+	return "docker"
+}
+
+// Version returns the tool version.
+func (base) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (base) IsExternal() bool {
+	return false
+}
+
+// RequiresPermission returns true: every docker call executes commands.
+func (base) RequiresPermission() bool {
+	return true
+}
+
+// result is the outcome of a docker command.
+type result struct {
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// output returns what the command printed, standard error last.
+func (r result) output() string {
+	return strings.TrimSpace(strings.TrimSpace(r.stdout) + "\n" + strings.TrimSpace(r.stderr))
+}
+
+// run runs the docker CLI in dir. The error is set if the command could
+// not run or exited non-zero, and holds what it printed on standard error.
+func (s *Session) run(ctx context.Context, dir string, timeout time.Duration, args ...string) (result, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := osexec.CommandContext(ctx, s.command, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "DOCKER_CLI_HINTS=false", "BUILDKIT_PROGRESS=plain")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	r := result{stdout: stdout.String(), stderr: stderr.String(), exitCode: -1}
+	if cmd.ProcessState != nil {
+		r.exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return r, fmt.Errorf("%s %s timed out after %s", s.command, args[0], timeout)
+	case errors.Is(err, osexec.ErrNotFound):
+		return r, fmt.Errorf("%s is not installed or not on PATH", s.command)
+	case err != nil && r.exitCode < 0:
+		return r, fmt.Errorf("%s %s: %w", s.command, args[0], err)
+	case err != nil:
+		msg := tail(strings.TrimSpace(r.stderr), maxOutput)
+		if msg == "" {
+			msg = tail(strings.TrimSpace(r.stdout), maxOutput)
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return r, fmt.Errorf("%s %s: %s", s.command, args[0], msg)
+	}
+	return r, nil
+}
+
+// failure is the result of a docker tool call that failed.
+func failure(err error, startTime time.Time) (*tools.Result, error) {
+	return &tools.Result{
+		Success:  false,
+		Error:    err,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// stringParam returns a string parameter, trimmed.
+func stringParam(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+// boolParam returns a boolean parameter, or def if it isn't given.
+func boolParam(params map[string]interface{}, name string, def bool) bool {
+	if b, ok := params[name].(bool); ok {
+		return b
+	}
+	return def
+}
+
+// intParam returns an integer parameter, or def if it isn't given.
+func intParam(params map[string]interface{}, name string, def int) int {
+	switch v := params[name].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// linesParam returns the values of a parameter given one per line.
+func linesParam(params map[string]interface{}, name string) []string {
+	var values []string
+	for _, line := range strings.Split(stringParam(params, name), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return values
+}
+
+// timeoutParam returns the timeout parameter in seconds, or def, capped
+// at buildTimeout.
+func timeoutParam(params map[string]interface{}, def time.Duration) time.Duration {
+	timeout := def
+	if seconds := intParam(params, "timeout", 0); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	return min(timeout, buildTimeout)
+}
+
+// checkName rejects a container, image or service name that docker would
+// read as an option.
+func checkName(kind, name string) error {
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid %s %q", kind, name)
+	}
+	return nil
+}
+
+// splitArgs splits a command line into arguments the way a POSIX shell
+// would for quoting: single and double quotes group words and backslashes
+// escape outside single quotes. Nothing is expanded.
+func splitArgs(command string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in command: %s", command)
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// tail returns the last n bytes of s, starting at a line.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return "...\n" + s
+}
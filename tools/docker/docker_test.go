@@ -0,0 +1,227 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDocker answers like the docker CLI and logs each call's arguments,
+// one call per line.
+const fakeDocker = `#!/bin/sh
+echo "$*" >> "$FAKE_DOCKER_LOG"
+case "$1" in
+build)
+	echo "#1 [internal] load build definition from Dockerfile"
+	echo "sha256:0123456789abcdef" > "$3"
+	;;
+run)
+	case "$*" in
+	*--detach*) echo "4f2c9e0d1b7a6c3e5f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6" ;;
+	*false) echo "boom" >&2; exit 3 ;;
+	*missing:latest*) echo "Unable to find image 'missing:latest' locally" >&2; exit 125 ;;
+	*) echo "hello from the container" ;;
+	esac
+	;;
+ps)
+	echo '{"ID":"4f2c9e0d1b7a6c3e5f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6","Names":"db","Image":"postgres:16","State":"running","Status":"Up 2 seconds","Ports":"0.0.0.0:5432->5432/tcp","Labels":"dev.bplus.session='"$FAKE_DOCKER_SESSION"'"}'
+	echo '{"ID":"9999999999999999","Names":"other","Image":"redis","State":"running","Status":"Up 3 days","Ports":"","Labels":""}'
+	echo '{"ID":"7777777777777777","Names":"app-web-1","Image":"nginx","State":"running","Status":"Up 1 second","Ports":"","Labels":"com.docker.compose.project=app,com.docker.compose.service=web,com.docker.compose.project.working_dir='"$FAKE_DOCKER_PROJECT"'"}'
+	;;
+compose)
+	case "$*" in
+	*" ps "*) echo '{"Service":"web","Name":"app-web-1","State":"running","Status":"Up 1 second","Health":"","Ports":"0.0.0.0:8080->80/tcp"}' ;;
+	esac
+	;;
+logs)
+	echo "ready to accept connections"
+	;;
+esac
+`
+
+// newFakeSession returns a session running the fake docker CLI, and a
+// function returning the calls made so far.
+func newFakeSession(t *testing.T) (*Session, string, func() []string) {
+	t.Helper()
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "docker")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeDocker), 0755))
+	log := filepath.Join(dir, "calls.log")
+	project := filepath.Join(dir, "app")
+	require.NoError(t, os.Mkdir(project, 0755))
+
+	s := NewSession(WithCommand(bin))
+	t.Setenv("FAKE_DOCKER_LOG", log)
+	t.Setenv("FAKE_DOCKER_SESSION", s.ID())
+	t.Setenv("FAKE_DOCKER_PROJECT", project)
+	calls := func() []string {
+		data, _ := os.ReadFile(log)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	return s, project, calls
+}
+
+func execute(t *testing.T, tool tools.Tool, params map[string]interface{}) *tools.Result {
+	t.Helper()
+	result, err := tool.Execute(context.Background(), params)
+	require.NoError(t, err)
+	return result
+}
+
+func TestBuildTool(t *testing.T) {
+	s, _, calls := newFakeSession(t)
+	result := execute(t, NewBuildTool(s), map[string]interface{}{
+		"tag":        "myapp:dev",
+		"build_args": "VERSION=1\nDEBUG=true",
+	})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "sha256:0123456789abcdef", result.Metadata["image_id"])
+	assert.Contains(t, result.Output, "Built image sha256:0123456789abcdef tagged myapp:dev")
+	assert.Regexp(t, `^build --iidfile \S+ -t myapp:dev --build-arg VERSION=1 --build-arg DEBUG=true \.$`, calls()[0])
+
+	result = execute(t, NewBuildTool(s), map[string]interface{}{"build_args": "VERSION"})
+	assert.False(t, result.Success)
+	result = execute(t, NewBuildTool(s), map[string]interface{}{"tag": "--push"})
+	assert.False(t, result.Success)
+}
+
+func TestRunTool(t *testing.T) {
+	s, _, calls := newFakeSession(t)
+	tool := NewRunTool(s)
+	assert.True(t, tool.RequiresConfirmation(nil))
+	assert.Equal(t, "docker run postgres:16 -p 5432:5432", tool.DescribeResource(map[string]interface{}{"image": "postgres:16", "ports": "5432:5432"}))
+
+	result := execute(t, tool, map[string]interface{}{
+		"image": "postgres:16",
+		"name":  "db",
+		"ports": "5432:5432",
+		"env":   "POSTGRES_PASSWORD=dev",
+	})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "run --label dev.bplus.session="+s.ID()+" --detach --name db --publish 5432:5432 --env POSTGRES_PASSWORD=dev postgres:16", calls()[0])
+	assert.Contains(t, result.Output, "Started container db (4f2c9e0d1b7a)")
+	containers := s.Containers()
+	require.Len(t, containers, 1)
+	assert.Equal(t, "db", containers[0].Name)
+
+	t.Run("Foreground", func(t *testing.T) {
+		result := execute(t, tool, map[string]interface{}{"image": "alpine", "command": `echo "hello from" 'the container'`, "detach": false})
+		require.True(t, result.Success, "%v", result.Error)
+		assert.Equal(t, "hello from the container", result.Output)
+		assert.Equal(t, "run --label dev.bplus.session="+s.ID()+" --rm alpine echo hello from the container", calls()[1])
+
+		result = execute(t, tool, map[string]interface{}{"image": "alpine", "command": "false", "detach": false})
+		assert.False(t, result.Success)
+		assert.Equal(t, 3, result.Metadata["exit_code"])
+		assert.Contains(t, result.Error.Error(), "boom")
+
+		result = execute(t, tool, map[string]interface{}{"image": "missing:latest", "detach": false})
+		assert.False(t, result.Success)
+		assert.Nil(t, result.Metadata)
+		assert.Contains(t, result.Error.Error(), "Unable to find image")
+	})
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	s, project, calls := newFakeSession(t)
+	result := execute(t, NewRunTool(s), map[string]interface{}{"image": "postgres:16", "name": "db"})
+	require.True(t, result.Success, "%v", result.Error)
+	result = execute(t, NewComposeUpTool(s), map[string]interface{}{"working_dir": project, "services": "web", "build": true})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, result.Output, "web (app-web-1): Up 1 second [0.0.0.0:8080->80/tcp]")
+	assert.Equal(t, "compose up --detach --build web", calls()[1])
+	require.Len(t, s.Projects(), 1)
+
+	// Only this session's containers are listed unless all are asked for
+	result = execute(t, NewPsTool(s), map[string]interface{}{})
+	require.True(t, result.Success, "%v", result.Error)
+	containers := result.Metadata["containers"].([]ContainerState)
+	require.Len(t, containers, 2)
+	assert.Equal(t, "db", containers[0].Name)
+	assert.Equal(t, "web", containers[1].Service)
+	result = execute(t, NewPsTool(s), map[string]interface{}{"all": true})
+	assert.Len(t, result.Metadata["containers"], 3)
+
+	result = execute(t, NewLogsTool(s), map[string]interface{}{"container": "db", "tail": 5})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "ready to accept connections", result.Output)
+	assert.Contains(t, calls(), "logs --tail 5 db")
+
+	result = execute(t, NewExecTool(s), map[string]interface{}{"container": "db", "command": "psql -c 'select 1'", "user": "postgres"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, calls(), "exec --user postgres db psql -c select 1")
+
+	// Closing the session removes what is still running
+	require.NoError(t, s.Close())
+	all := calls()
+	assert.Equal(t, []string{
+		"rm --force 4f2c9e0d1b7a6c3e5f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6",
+		"compose down --remove-orphans",
+	}, all[len(all)-2:])
+	assert.Empty(t, s.Containers())
+	assert.Empty(t, s.Projects())
+}
+
+func TestStopTool(t *testing.T) {
+	s, _, calls := newFakeSession(t)
+	require.True(t, execute(t, NewRunTool(s), map[string]interface{}{"image": "postgres:16", "name": "db"}).Success)
+
+	result := execute(t, NewStopTool(s), map[string]interface{}{"container": "db"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, []string{"stop db", "rm db"}, calls()[1:])
+	assert.Empty(t, s.Containers())
+
+	// Nothing is left to remove
+	require.NoError(t, s.Close())
+	assert.Len(t, calls(), 3)
+}
+
+func TestComposeDownTool(t *testing.T) {
+	s, project, calls := newFakeSession(t)
+	params := map[string]interface{}{"working_dir": project, "files": "compose.yaml\ncompose.dev.yaml"}
+	require.True(t, execute(t, NewComposeUpTool(s), params).Success)
+
+	params["volumes"] = true
+	result := execute(t, NewComposeDownTool(s), params)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, calls(), "compose -f compose.yaml -f compose.dev.yaml down --remove-orphans --volumes")
+	assert.Empty(t, s.Projects())
+}
+
+func TestMissingDocker(t *testing.T) {
+	s := NewSession(WithCommand("bplus-no-such-docker"))
+	result := execute(t, NewPsTool(s), map[string]interface{}{})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "not installed")
+}
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`sh -c 'echo "$HOME" | wc -c' a\ b "x\"y"`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c", `echo "$HOME" | wc -c`, "a b", `x"y`}, args)
+
+	args, err = splitArgs("")
+	require.NoError(t, err)
+	assert.Empty(t, args)
+
+	_, err = splitArgs(`echo 'open`)
+	assert.Error(t, err)
+}
+
+func TestToolMetadata(t *testing.T) {
+	for _, tool := range Tools(NewSession()) {
+		t.Run(tool.Name(), func(t *testing.T) {
+			assert.Equal(t, "docker", tool.Category())
+			assert.Equal(t, "1.0.0", tool.Version())
+			assert.False(t, tool.IsExternal())
+			assert.True(t, tool.RequiresPermission())
+			assert.NotEmpty(t, tool.Description())
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Timeouts of commands run in containers
+const (
+	runTimeout  = 10 * time.Minute
+	execTimeout = 2 * time.Minute
+)
+
+// dockerFailed is the exit code docker run and exec fail with when docker
+// itself fails rather than the command in the container.
+const dockerFailed = 125
+
+// RunTool starts a container.
+type RunTool struct{ base }
+
+// NewRunTool creates a new docker_run tool.
+func NewRunTool(s *Session) *RunTool {
+	return &RunTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *RunTool) Name() string {
+	return "docker_run"
+}
+
+// Description returns the tool description.
+func (t *RunTool) Description() string {
+	return "Starts a container from an image. By default it runs detached and its ID is returned; it is tracked and removed when the " +
+		"session ends. With detach false the container runs to completion and is removed, and its output and exit code are returned"
+}
+
+// Parameters returns the tool parameters.
+func (t *RunTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "image",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Image to run, e.g. postgres:16",
+		},
+		{
+			Name:        "command",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Command and arguments replacing the image's default, quoted as in a shell (not run by one)",
+		},
+		{
+			Name:        "name",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Container name",
+		},
+		{
+			Name:        "ports",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Ports to publish, one HOST:CONTAINER per line, e.g. 5432:5432",
+		},
+		{
+			Name:        "env",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Environment variables, one KEY=VALUE per line",
+		},
+		{
+			Name:        "volumes",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Volumes or bind mounts, one SOURCE:TARGET[:ro] per line",
+		},
+		{
+			Name:        "network",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Network to connect the container to",
+		},
+		{
+			Name:        "workdir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Working directory inside the container",
+		},
+		{
+			Name:        "detach",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Run in the background (default: true)",
+			Default:     true,
+		},
+		workingDirParam,
+		{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Timeout in seconds for a container run to completion (default: 600)",
+		},
+	}
+}
+
+// RequiresConfirmation returns true: every container started is confirmed.
+func (t *RunTool) RequiresConfirmation(params map[string]interface{}) bool {
+	return true
+}
+
+// DescribeResource describes the container for permission prompts.
+func (t *RunTool) DescribeResource(params map[string]interface{}) string {
+	desc := "docker run " + stringParam(params, "image")
+	if command := stringParam(params, "command"); command != "" {
+		desc += " " + command
+	}
+	for _, port := range linesParam(params, "ports") {
+		desc += " -p " + port
+	}
+	for _, volume := range linesParam(params, "volumes") {
+		desc += " -v " + volume
+	}
+	return desc
+}
+
+// Execute starts the container.
+func (t *RunTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	image := stringParam(params, "image")
+	if image == "" {
+		return failure(fmt.Errorf("image is required"), startTime)
+	}
+	if err := checkName("image", image); err != nil {
+		return failure(err, startTime)
+	}
+	command, err := splitArgs(stringParam(params, "command"))
+	if err != nil {
+		return failure(err, startTime)
+	}
+	name := stringParam(params, "name")
+	detach := boolParam(params, "detach", true)
+
+	args := []string{"run", "--label", sessionLabel + "=" + t.session.ID()}
+	if detach {
+		args = append(args, "--detach")
+	} else {
+		args = append(args, "--rm")
+	}
+	if name != "" {
+		if err := checkName("container name", name); err != nil {
+			return failure(err, startTime)
+		}
+		args = append(args, "--name", name)
+	}
+	for _, port := range linesParam(params, "ports") {
+		args = append(args, "--publish", port)
+	}
+	for _, env := range linesParam(params, "env") {
+		if !strings.Contains(env, "=") {
+			return failure(fmt.Errorf("invalid environment variable %q: must be KEY=VALUE", env), startTime)
+		}
+		args = append(args, "--env", env)
+	}
+	for _, volume := range linesParam(params, "volumes") {
+		args = append(args, "--volume", volume)
+	}
+	if network := stringParam(params, "network"); network != "" {
+		args = append(args, "--network", network)
+	}
+	if workdir := stringParam(params, "workdir"); workdir != "" {
+		args = append(args, "--workdir", workdir)
+	}
+	args = append(args, image)
+	args = append(args, command...)
+
+	dir := stringParam(params, "working_dir")
+	if !detach {
+		r, err := t.session.run(ctx, dir, timeoutParam(params, runTimeout), args...)
+		return commandResult(r, err, startTime)
+	}
+
+	r, err := t.session.run(ctx, dir, buildTimeout, args...) // May pull the image
+	if err != nil {
+		return failure(err, startTime)
+	}
+	lines := strings.Split(strings.TrimSpace(r.stdout), "\n")
+	id := strings.TrimSpace(lines[len(lines)-1])
+	t.session.track(Container{ID: id, Name: name, Image: image, Started: time.Now()})
+
+	output := fmt.Sprintf("Started container %s from %s", shortID(id), image)
+	if name != "" {
+		output = fmt.Sprintf("Started container %s (%s) from %s", name, shortID(id), image)
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  output + "; check on it with docker_logs and stop it with docker_stop",
+		Metadata: map[string]interface{}{
+			"id":    id,
+			"name":  name,
+			"image": image,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// ExecTool runs a command in a running container.
+type ExecTool struct{ base }
+
+// NewExecTool creates a new docker_exec tool.
+func NewExecTool(s *Session) *ExecTool {
+	return &ExecTool{base{s}}
+}
+
+// Name returns the tool name.
+func (t *ExecTool) Name() string {
+	return "docker_exec"
+}
+
+// Description returns the tool description.
+func (t *ExecTool) Description() string {
+	return "Runs a command in a running container and returns its output and exit code. The command is not run by a shell; " +
+		"use sh -c '...' for pipes or redirection"
+}
+
+// Parameters returns the tool parameters.
+func (t *ExecTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "container",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Container name or ID",
+		},
+		{
+			Name:        "command",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Command and arguments, quoted as in a shell",
+		},
+		{
+			Name:        "workdir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Working directory inside the container",
+		},
+		{
+			Name:        "env",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Environment variables, one KEY=VALUE per line",
+		},
+		{
+			Name:        "user",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "User to run as, e.g. root or 1000:1000",
+		},
+		{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Timeout in seconds (default: 120)",
+		},
+	}
+}
+
+// RequiresConfirmation returns true: every command run in a container is
+// confirmed.
+func (t *ExecTool) RequiresConfirmation(params map[string]interface{}) bool {
+	return true
+}
+
+// DescribeResource describes the command for permission prompts.
+func (t *ExecTool) DescribeResource(params map[string]interface{}) string {
+	return "docker exec " + stringParam(params, "container") + " " + stringParam(params, "command")
+}
+
+// Execute runs the command.
+func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	container := stringParam(params, "container")
+	if container == "" {
+		return failure(fmt.Errorf("container is required"), startTime)
+	}
+	if err := checkName("container", container); err != nil {
+		return failure(err, startTime)
+	}
+	command, err := splitArgs(stringParam(params, "command"))
+	if err != nil {
+		return failure(err, startTime)
+	}
+	if len(command) == 0 {
+		return failure(fmt.Errorf("command is required"), startTime)
+	}
+
+	args := []string{"exec"}
+	if workdir := stringParam(params, "workdir"); workdir != "" {
+		args = append(args, "--workdir", workdir)
+	}
+	for _, env := range linesParam(params, "env") {
+		if !strings.Contains(env, "=") {
+			return failure(fmt.Errorf("invalid environment variable %q: must be KEY=VALUE", env), startTime)
+		}
+		args = append(args, "--env", env)
+	}
+	if user := stringParam(params, "user"); user != "" {
+		args = append(args, "--user", user)
+	}
+	args = append(args, container)
+	args = append(args, command...)
+
+	r, err := t.session.run(ctx, "", timeoutParam(params, execTimeout), args...)
+	return commandResult(r, err, startTime)
+}
+
+// commandResult turns the outcome of a command run in a container into a
+// tool result. The command failing is reported with its output; docker
+// failing is reported as the error.
+func commandResult(r result, err error, startTime time.Time) (*tools.Result, error) {
+	if err != nil && (r.exitCode <= 0 || r.exitCode == dockerFailed) {
+		return failure(err, startTime)
+	}
+	output := tail(r.output(), maxOutput)
+	metadata := map[string]interface{}{"exit_code": r.exitCode}
+	if r.exitCode != 0 {
+		return &tools.Result{
+			Success:  false,
+			Error:    fmt.Errorf("command exited with code %d:\n%s", r.exitCode, output),
+			Metadata: metadata,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   output,
+		Metadata: metadata,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// shortID returns the 12-character form docker shows of a container ID.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,226 @@
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionLabel is the label marking the containers a session started.
+const sessionLabel = "dev.bplus.session"
+
+// Container is a container started this session.
+type Container struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name,omitempty"`
+	Image   string    `json:"image"`
+	Started time.Time `json:"started"`
+}
+
+// ComposeProject is a compose project brought up this session.
+type ComposeProject struct {
+	Dir      string    `json:"dir"`
+	Files    []string  `json:"files,omitempty"`
+	Project  string    `json:"project,omitempty"` // -p, if given
+	Services []string  `json:"services,omitempty"`
+	Started  time.Time `json:"started"`
+}
+
+// args returns the compose arguments selecting the project.
+func (p ComposeProject) args() []string {
+	var args []string
+	for _, f := range p.Files {
+		args = append(args, "-f", f)
+	}
+	if p.Project != "" {
+		args = append(args, "-p", p.Project)
+	}
+	return args
+}
+
+// key identifies the project.
+func (p ComposeProject) key() string {
+	return p.Dir + "\x00" + strings.Join(p.Files, "\x00") + "\x00" + p.Project
+}
+
+// Session tracks the containers and compose projects the docker tools
+// started, so they can be listed and torn down when the session ends.
+type Session struct {
+	mu         sync.Mutex
+	id         string
+	command    string
+	containers map[string]Container      // By ID
+	projects   map[string]ComposeProject // By key
+}
+
+// Option configures a Session.
+type Option func(*Session)
+
+// WithCommand sets the docker CLI run, e.g. podman (default: docker).
+func WithCommand(command string) Option {
+	return func(s *Session) {
+		if command != "" {
+			s.command = command
+		}
+	}
+}
+
+// NewSession creates a session with nothing started.
+func NewSession(opts ...Option) *Session {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	s := &Session{
+		id:         hex.EncodeToString(b),
+		command:    "docker",
+		containers: make(map[string]Container),
+		projects:   make(map[string]ComposeProject),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ID returns the value of the label on the session's containers.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Containers returns the containers started this session, oldest first.
+func (s *Session) Containers() []Container {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	containers := make([]Container, 0, len(s.containers))
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Started.Before(containers[j].Started) })
+	return containers
+}
+
+// Projects returns the compose projects brought up this session, oldest
+// first.
+func (s *Session) Projects() []ComposeProject {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	projects := make([]ComposeProject, 0, len(s.projects))
+	for _, p := range s.projects {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Started.Before(projects[j].Started) })
+	return projects
+}
+
+// track records a container the session started.
+func (s *Session) track(c Container) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.containers[c.ID] = c
+}
+
+// untrack forgets the container with the ID or name given, returning it.
+func (s *Session) untrack(ref string) (Container, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.lookup(ref); ok {
+		delete(s.containers, c.ID)
+		return c, true
+	}
+	return Container{}, false
+}
+
+// lookup finds a tracked container by name, ID or ID prefix. Callers must
+// hold s.mu.
+func (s *Session) lookup(ref string) (Container, bool) {
+	for _, c := range s.containers {
+		if c.Name == ref || c.ID == ref || (len(ref) >= 4 && strings.HasPrefix(c.ID, ref)) {
+			return c, true
+		}
+	}
+	return Container{}, false
+}
+
+// started reports whether the session started the container.
+func (s *Session) started(ref string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.lookup(ref)
+	return ok
+}
+
+// trackProject records a compose project brought up, adding services to
+// those recorded before.
+func (s *Session) trackProject(p ComposeProject) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.projects[p.key()]; ok {
+		p.Started = prev.Started
+		if len(p.Services) > 0 && len(prev.Services) > 0 {
+			p.Services = union(prev.Services, p.Services)
+		} else {
+			p.Services = nil // The whole project is up
+		}
+	}
+	s.projects[p.key()] = p
+}
+
+// untrackProject forgets a compose project taken down.
+func (s *Session) untrackProject(p ComposeProject) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.projects, p.key())
+}
+
+// Close stops and removes the containers the session started and takes
+// down its compose projects.
+func (s *Session) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var errs []error
+	containers := s.Containers()
+	if len(containers) > 0 {
+		args := []string{"rm", "--force"}
+		for _, c := range containers {
+			args = append(args, c.ID)
+		}
+		if _, err := s.run(ctx, "", commandTimeout, args...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, p := range s.Projects() {
+		args := append([]string{"compose"}, p.args()...)
+		args = append(args, "down", "--remove-orphans")
+		if _, err := s.run(ctx, p.Dir, commandTimeout, args...); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(p.Dir), err))
+		}
+	}
+
+	s.mu.Lock()
+	s.containers = make(map[string]Container)
+	s.projects = make(map[string]ComposeProject)
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// union returns the values of a then those of b not in a.
+func union(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	out := append([]string(nil), a...)
+	for _, v := range a {
+		seen[v] = true
+	}
+	for _, v := range b {
+		if !seen[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
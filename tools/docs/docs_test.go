@@ -0,0 +1,125 @@
+package docs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestDetectDependencies(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":           "module example.com/app\n\ngo 1.25\n\nrequire github.com/spf13/viper v1.18.2\n\nrequire (\n\tgithub.com/google/uuid v1.6.0 // indirect\n)\n",
+		"package.json":     `{"dependencies":{"react":"^18.0.0"},"devDependencies":{"@types/node":"^20"}}`,
+		"requirements.txt": "# pinned\nrequests==2.31.0\n-r dev.txt\nFlask>=3\n",
+		"pyproject.toml":   "[project]\nname = \"app\"\ndependencies = [\n  \"pydantic>=2\",\n  \"typing_extensions\",\n]\n",
+	})
+
+	deps := DetectDependencies(dir)
+	assert.ElementsMatch(t, []string{"github.com/spf13/viper", "github.com/google/uuid"}, deps[EcosystemGo])
+	assert.ElementsMatch(t, []string{"react", "@types/node"}, deps[EcosystemNPM])
+	assert.ElementsMatch(t, []string{"requests", "Flask", "pydantic", "typing_extensions"}, deps[EcosystemPyPI])
+
+	assert.True(t, deps.Has(EcosystemGo, "github.com/spf13/viper/remote"))
+	assert.True(t, deps.Has(EcosystemGo, "net/http"))
+	assert.True(t, deps.Has(EcosystemPyPI, "typing-extensions"))
+	assert.False(t, deps.Has(EcosystemNPM, "lodash"))
+
+	assert.Equal(t, EcosystemNPM, deps.Ecosystem("react"))
+	assert.Equal(t, EcosystemPyPI, deps.Ecosystem("flask"))
+	assert.Equal(t, EcosystemGo, deps.Ecosystem("encoding/json"))
+	assert.Equal(t, "", deps.Ecosystem("example.com/unknown"))
+}
+
+func TestLookupTool_NPM(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Equal(t, "/@types%2Fnode", r.URL.EscapedPath())
+		w.Write([]byte(`{"readme":"# node types\n\nIntro.\n\n## fs\n\nreadFile reads a file.\n\n## http\n\ncreateServer starts a server.\n"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"package.json": `{"devDependencies":{"@types/node":"^20"}}`})
+
+	cacheDir := t.TempDir()
+	tool := NewLookupTool(cacheDir, WithProjectDir(dir), WithHTTPClient(srv.Client()))
+	tool.npmURL = srv.URL
+
+	params := map[string]interface{}{"package": "@types/node", "query": "createServer"}
+	result, err := tool.Execute(context.Background(), params)
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, result.Output, "createServer starts a server")
+	assert.NotContains(t, result.Output, "readFile")
+	assert.Equal(t, EcosystemNPM, result.Metadata["ecosystem"])
+	assert.Equal(t, false, result.Metadata["cached"])
+
+	// Served from the cache the second time
+	result, err = tool.Execute(context.Background(), params)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.Equal(t, true, result.Metadata["cached"])
+	assert.Equal(t, int32(1), requests.Load())
+	assert.FileExists(t, filepath.Join(cacheDir, "docs", "npm", "_at_types__node.txt"))
+}
+
+func TestLookupTool_PyPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/requests/json", r.URL.Path)
+		w.Write([]byte(`{"info":{"summary":"Python HTTP for Humans.","description":"Requests\n========\n\nUsage text."}}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"requirements.txt": "requests\n"})
+
+	tool := NewLookupTool(t.TempDir(), WithProjectDir(dir), WithHTTPClient(srv.Client()))
+	tool.pypiURL = srv.URL
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"package": "requests"})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.True(t, strings.HasPrefix(result.Output.(string), "Python HTTP for Humans."))
+}
+
+func TestLookupTool_OnlyDependencies(t *testing.T) {
+	tool := NewLookupTool(t.TempDir(), WithProjectDir(t.TempDir()))
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"package": "left-pad", "ecosystem": "npm"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "not a dependency")
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"package": "../../etc/passwd"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+}
+
+func TestExcerpt(t *testing.T) {
+	doc := "package http\n\nOverview text.\n\nfunc Get(url string) (*Response, error)\n    Get issues a GET.\n\nfunc Post(url string) (*Response, error)\n    Post issues a POST.\n\ntype Client struct{}\n    A Client is an HTTP client.\n"
+
+	out := excerpt(doc, "Post", 1000)
+	assert.Contains(t, out, "func Post")
+	assert.NotContains(t, out, "func Get")
+
+	assert.Equal(t, doc, excerpt(doc, "", 1000))
+	assert.Contains(t, excerpt(doc, "", 10), "(truncated)")
+	assert.Contains(t, excerpt(doc, "nothing", 1000), "No section mentions")
+}
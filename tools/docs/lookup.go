@@ -0,0 +1,353 @@
+// Package docs provides the documentation lookup tool.
+package docs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models/transport"
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Lookup defaults
+const (
+	// defaultTTL is how long cached docs are served before being refetched.
+	defaultTTL = 7 * 24 * time.Hour
+
+	// defaultMaxExcerpt bounds the text returned to the agent.
+	defaultMaxExcerpt = 8000
+
+	// fetchTimeout bounds fetching one package's docs.
+	fetchTimeout = 30 * time.Second
+)
+
+// LookupTool serves excerpts of the official docs of a project's
+// dependencies: go doc output for Go packages, READMEs from the npm registry
+// and project descriptions from PyPI. Docs are cached on disk, and only
+// packages the project declares can be looked up, so the agent gets real
+// APIs without general web access.
+type LookupTool struct {
+	cacheDir   string
+	projectDir string
+	ttl        time.Duration
+	maxExcerpt int
+	client     *http.Client
+	npmURL     string
+	pypiURL    string
+}
+
+// Option is a functional option for configuring the lookup tool.
+type Option func(*LookupTool)
+
+// WithProjectDir sets the project whose dependencies can be looked up
+// (default: the working directory).
+func WithProjectDir(dir string) Option {
+	return func(t *LookupTool) {
+		t.projectDir = dir
+	}
+}
+
+// WithTTL sets how long cached docs are served before being refetched.
+func WithTTL(ttl time.Duration) Option {
+	return func(t *LookupTool) {
+		t.ttl = ttl
+	}
+}
+
+// WithHTTPClient sets the HTTP client registries are queried with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *LookupTool) {
+		t.client = client
+	}
+}
+
+// NewLookupTool creates a docs_lookup tool caching docs under cacheDir.
+func NewLookupTool(cacheDir string, opts ...Option) *LookupTool {
+	t := &LookupTool{
+		cacheDir:   cacheDir,
+		ttl:        defaultTTL,
+		maxExcerpt: defaultMaxExcerpt,
+		client:     transport.NewClient(fetchTimeout),
+		npmURL:     defaultNPMRegistry,
+		pypiURL:    defaultPyPI,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Name returns the tool name.
+func (t *LookupTool) Name() string {
+	return "docs_lookup"
+}
+
+// Description returns the tool description.
+func (t *LookupTool) Description() string {
+	return "Looks up the official documentation of a project dependency (Go package, npm or PyPI package) and returns the excerpts relevant to a query. Use it to check an API before calling it"
+}
+
+// Parameters returns the tool parameters.
+func (t *LookupTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "package",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Package to look up (e.g. net/http, github.com/spf13/viper, react, requests)",
+		},
+		{
+			Name:        "query",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Function, type or topic to find in the docs; empty returns the overview",
+			Default:     "",
+		},
+		{
+			Name:        "ecosystem",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Ecosystem: " + strings.Join(Ecosystems(), ", ") + " (default: detected from the project's manifests)",
+			Default:     "",
+			Validation:  &tools.Validation{Enum: Ecosystems()},
+		},
+		{
+			Name:        "refresh",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Fetch the docs again instead of using the cached copy",
+			Default:     false,
+		},
+	}
+}
+
+// RequiresPermission returns true as fetching docs contacts package registries.
+func (t *LookupTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the package in the permission prompt.
+func (t *LookupTool) DescribeResource(params map[string]interface{}) string {
+	pkg, _ := params["package"].(string)
+	return fmt.Sprintf("look up docs for %s", pkg)
+}
+
+// Execute looks up the docs.
+func (t *LookupTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	pkg, _ := params["package"].(string)
+	query, _ := params["query"].(string)
+	ecosystem, _ := params["ecosystem"].(string)
+	refresh, _ := params["refresh"].(bool)
+
+	pkg = strings.TrimSpace(pkg)
+	if pkg == "" || strings.ContainsAny(pkg, " \t\n\\") || strings.Contains(pkg, "..") {
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("invalid package name: %q", pkg),
+		}, nil
+	}
+
+	switch ecosystem {
+	case "", EcosystemGo, EcosystemNPM, EcosystemPyPI:
+	default:
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("unsupported ecosystem: %s", ecosystem),
+		}, nil
+	}
+
+	dir := t.projectDir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	deps := DetectDependencies(dir)
+	if ecosystem == "" {
+		ecosystem = deps.Ecosystem(pkg)
+	}
+	if ecosystem == "" || !deps.Has(ecosystem, pkg) {
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("%s is not a dependency declared by the project; only dependencies can be looked up", pkg),
+		}, nil
+	}
+
+	doc, cached, err := t.load(ctx, ecosystem, pkg, dir, refresh)
+	if err != nil {
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("failed to look up docs for %s: %w", pkg, err),
+		}, nil
+	}
+
+	return &tools.Result{
+		Success: true,
+		Output:  excerpt(doc, query, t.maxExcerpt),
+		Metadata: map[string]interface{}{
+			"package":   pkg,
+			"ecosystem": ecosystem,
+			"query":     query,
+			"cached":    cached,
+			"doc_size":  len(doc),
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// load returns the docs of pkg from the cache, fetching them when missing,
+// stale or refresh is set. A stale copy is served if fetching fails.
+func (t *LookupTool) load(ctx context.Context, ecosystem, pkg, dir string, refresh bool) (doc string, cached bool, err error) {
+	if t.cacheDir == "" {
+		doc, err = t.fetch(ctx, ecosystem, pkg, dir)
+		return doc, false, err
+	}
+
+	path := t.cachePath(ecosystem, pkg)
+	info, statErr := os.Stat(path)
+	fresh := statErr == nil && time.Since(info.ModTime()) < t.ttl
+	if fresh && !refresh {
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data), true, nil
+		}
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	doc, err = t.fetch(fetchCtx, ecosystem, pkg, dir)
+	if err == nil && strings.TrimSpace(doc) == "" {
+		err = fmt.Errorf("no documentation published")
+	}
+	if err != nil {
+		if data, readErr := os.ReadFile(path); readErr == nil {
+			return string(data), true, nil
+		}
+		return "", false, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		_ = os.WriteFile(path, []byte(doc), 0o644)
+	}
+	return doc, false, nil
+}
+
+// cachePath returns where the docs of pkg are cached.
+func (t *LookupTool) cachePath(ecosystem, pkg string) string {
+	name := strings.NewReplacer("/", "__", "@", "_at_").Replace(pkg)
+	return filepath.Join(t.cacheDir, "docs", ecosystem, name+".txt")
+}
+
+// Category returns the tool category. Docs are third-party content and are
+// treated like other fetched web content.
+func (t *LookupTool) Category() string {
+	return "web"
+}
+
+// Version returns the tool version.
+func (t *LookupTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *LookupTool) IsExternal() bool {
+	return false
+}
+
+// sectionStart matches the lines a doc section starts at: markdown and
+// reStructuredText headings, and Go declarations in go doc output.
+var sectionStart = regexp.MustCompile(`^(#{1,6} |func |type |const |var |[A-Za-z].*\n[=~^-]{3,}$)`)
+
+var queryTerm = regexp.MustCompile(`[A-Za-z0-9_]+`)
+
+// excerpt returns the sections of doc most relevant to query, in document
+// order, within max bytes. Without a query the start of doc is returned.
+func excerpt(doc, query string, max int) string {
+	if len(doc) <= max && query == "" {
+		return doc
+	}
+
+	sections := splitSections(doc)
+	terms := queryTerm.FindAllString(strings.ToLower(query), -1)
+	if len(terms) == 0 {
+		return truncate(doc, max)
+	}
+
+	type scored struct {
+		index int
+		score int
+	}
+	var ranked []scored
+	for i, section := range sections {
+		lower := strings.ToLower(section)
+		score := 0
+		for _, term := range terms {
+			score += strings.Count(lower, term)
+			// A match in the heading or declaration counts most
+			if first, _, _ := strings.Cut(lower, "\n"); strings.Contains(first, term) {
+				score += 10
+			}
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{i, score})
+		}
+	}
+	if len(ranked) == 0 {
+		return fmt.Sprintf("No section mentions %q; the docs start with:\n\n%s", query, truncate(doc, max))
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	var picked []int
+	size := 0
+	for _, r := range ranked {
+		if size > 0 && size+len(sections[r.index]) > max {
+			continue
+		}
+		picked = append(picked, r.index)
+		size += len(sections[r.index])
+	}
+	sort.Ints(picked)
+
+	parts := make([]string, 0, len(picked))
+	for _, i := range picked {
+		parts = append(parts, strings.TrimSpace(sections[i]))
+	}
+	return truncate(strings.Join(parts, "\n\n...\n\n"), max)
+}
+
+// splitSections splits doc at the lines sections start at.
+func splitSections(doc string) []string {
+	lines := strings.Split(doc, "\n")
+	var sections []string
+	var current strings.Builder
+	for i, line := range lines {
+		start := sectionStart.MatchString(line)
+		if !start && i+1 < len(lines) {
+			start = sectionStart.MatchString(line + "\n" + lines[i+1])
+		}
+		if start && current.Len() > 0 {
+			sections = append(sections, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	if current.Len() > 0 {
+		sections = append(sections, current.String())
+	}
+	return sections
+}
+
+// truncate cuts s to at most max bytes, marking the cut.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "\n... (truncated)"
+}
@@ -0,0 +1,234 @@
+package docs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Ecosystems docs can be looked up for
+const (
+	EcosystemGo   = "go"
+	EcosystemNPM  = "npm"
+	EcosystemPyPI = "pypi"
+)
+
+// Ecosystems returns the ecosystems docs_lookup supports.
+func Ecosystems() []string {
+	return []string{EcosystemGo, EcosystemNPM, EcosystemPyPI}
+}
+
+// Registry endpoints docs are fetched from; nothing else is contacted.
+const (
+	defaultNPMRegistry = "https://registry.npmjs.org"
+	defaultPyPI        = "https://pypi.org/pypi"
+)
+
+// maxDocBytes bounds a fetched document.
+const maxDocBytes = 2 << 20
+
+// Dependencies lists the packages a project declares, per ecosystem.
+type Dependencies map[string][]string
+
+// Has reports whether pkg is a dependency in ecosystem. Go packages match
+// the module that contains them, and the standard library is always allowed.
+func (d Dependencies) Has(ecosystem, pkg string) bool {
+	return (ecosystem == EcosystemGo && isGoStdlib(pkg)) || d.declares(ecosystem, pkg)
+}
+
+// Ecosystem returns the ecosystem pkg is a dependency in, or "" if none.
+func (d Dependencies) Ecosystem(pkg string) string {
+	for _, ecosystem := range []string{EcosystemGo, EcosystemNPM, EcosystemPyPI} {
+		if d.declares(ecosystem, pkg) {
+			return ecosystem
+		}
+	}
+	if isGoStdlib(pkg) {
+		return EcosystemGo
+	}
+	return ""
+}
+
+// declares reports whether the project declares pkg in ecosystem.
+func (d Dependencies) declares(ecosystem, pkg string) bool {
+	for _, dep := range d[ecosystem] {
+		switch ecosystem {
+		case EcosystemGo:
+			if pkg == dep || strings.HasPrefix(pkg, dep+"/") {
+				return true
+			}
+		case EcosystemPyPI:
+			if normalizePyPI(pkg) == normalizePyPI(dep) {
+				return true
+			}
+		default:
+			if pkg == dep {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isGoStdlib reports whether pkg looks like a standard library import path,
+// whose first element has no dot.
+func isGoStdlib(pkg string) bool {
+	first, _, _ := strings.Cut(pkg, "/")
+	return first != "" && !strings.Contains(first, ".") && !strings.Contains(pkg, "@")
+}
+
+// normalizePyPI normalizes a Python distribution name per PEP 503.
+func normalizePyPI(name string) string {
+	return strings.ToLower(pySeparators.ReplaceAllString(name, "-"))
+}
+
+var (
+	pySeparators   = regexp.MustCompile(`[-_.]+`)
+	goRequire      = regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+\.[^\s()]+)\s+v\S+`)
+	pyRequirement  = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)`)
+	pyprojectEntry = regexp.MustCompile(`^\s*"([A-Za-z0-9][A-Za-z0-9._-]*)`)
+)
+
+// DetectDependencies reads the dependencies declared in dir's go.mod,
+// package.json, requirements.txt and pyproject.toml.
+func DetectDependencies(dir string) Dependencies {
+	deps := make(Dependencies)
+
+	scanLines(filepath.Join(dir, "go.mod"), func(line string) {
+		if m := goRequire.FindStringSubmatch(line); m != nil {
+			deps[EcosystemGo] = append(deps[EcosystemGo], m[1])
+		}
+	})
+
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Dependencies    map[string]string `json:"dependencies"`
+			DevDependencies map[string]string `json:"devDependencies"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			for name := range pkg.Dependencies {
+				deps[EcosystemNPM] = append(deps[EcosystemNPM], name)
+			}
+			for name := range pkg.DevDependencies {
+				deps[EcosystemNPM] = append(deps[EcosystemNPM], name)
+			}
+		}
+	}
+
+	scanLines(filepath.Join(dir, "requirements.txt"), func(line string) {
+		if strings.HasPrefix(strings.TrimSpace(line), "-") {
+			return
+		}
+		if m := pyRequirement.FindStringSubmatch(line); m != nil {
+			deps[EcosystemPyPI] = append(deps[EcosystemPyPI], m[1])
+		}
+	})
+
+	inDeps := false
+	scanLines(filepath.Join(dir, "pyproject.toml"), func(line string) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "dependencies") && strings.Contains(trimmed, "["):
+			inDeps = !strings.Contains(trimmed, "]")
+			trimmed = trimmed[strings.Index(trimmed, "[")+1:]
+		case !inDeps:
+			return
+		case strings.HasPrefix(trimmed, "]"):
+			inDeps = false
+			return
+		}
+		for _, entry := range strings.Split(trimmed, ",") {
+			if m := pyprojectEntry.FindStringSubmatch(entry); m != nil {
+				deps[EcosystemPyPI] = append(deps[EcosystemPyPI], m[1])
+			}
+		}
+	})
+
+	return deps
+}
+
+// scanLines calls fn for each line of the file at path, if it exists.
+func scanLines(path string, fn func(string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+}
+
+// fetch retrieves the docs of pkg in ecosystem.
+func (t *LookupTool) fetch(ctx context.Context, ecosystem, pkg, dir string) (string, error) {
+	switch ecosystem {
+	case EcosystemGo:
+		return goDoc(ctx, pkg, dir)
+	case EcosystemNPM:
+		var meta struct {
+			Readme string `json:"readme"`
+		}
+		if err := t.getJSON(ctx, t.npmURL+"/"+url.PathEscape(pkg), &meta); err != nil {
+			return "", err
+		}
+		return meta.Readme, nil
+	case EcosystemPyPI:
+		var meta struct {
+			Info struct {
+				Summary     string `json:"summary"`
+				Description string `json:"description"`
+			} `json:"info"`
+		}
+		if err := t.getJSON(ctx, t.pypiURL+"/"+url.PathEscape(pkg)+"/json", &meta); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(meta.Info.Summary + "\n\n" + meta.Info.Description), nil
+	}
+	return "", fmt.Errorf("unsupported ecosystem: %s", ecosystem)
+}
+
+// getJSON decodes the JSON document at u into v.
+func (t *LookupTool) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry returned HTTP %d for %s", resp.StatusCode, u)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocBytes)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	return nil
+}
+
+// goDoc renders a Go package's documentation with the local toolchain, from
+// the module cache, so no network access is needed.
+func goDoc(ctx context.Context, pkg, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "doc", "-all", pkg)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("go doc %s failed: %w: %s", pkg, err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,255 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// defaultStartWait is how long bash_background watches a new process, so
+// a command failing at once is reported as failed rather than started.
+const defaultStartWait = 2 * time.Second
+
+// BashBackgroundTool starts commands that keep running, such as dev
+// servers and watchers, as background processes whose output is read with
+// process_output.
+type BashBackgroundTool struct {
+	processes *ProcessManager
+	profile   *ShellProfile
+}
+
+// NewBashBackgroundTool creates a new BashBackground tool whose processes
+// are kept in processes, so they can be listed, read and stopped, and are
+// all stopped when the session closes.
+func NewBashBackgroundTool(processes *ProcessManager, opts ...BashOption) *BashBackgroundTool {
+	return &BashBackgroundTool{processes: processes, profile: NewBashTool(opts...).profile}
+}
+
+// Name returns the tool name.
+func (t *BashBackgroundTool) Name() string {
+	return "bash_background"
+}
+
+// Description returns the tool description.
+func (t *BashBackgroundTool) Description() string {
+	return "Starts a long-running command, such as a dev server or file watcher, in the background and returns its first output. " +
+		"Poll it with process_output, see all with process_list and stop it with process_kill; background processes are stopped when the session ends."
+}
+
+// Parameters returns the tool parameters.
+func (t *BashBackgroundTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "command",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "The command to run in the background",
+		},
+		{
+			Name:        "name",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "ID to refer to the process by, such as dev-server (default: bg-N)",
+		},
+		{
+			Name:        "working_dir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Working directory for the command",
+			Default:     "",
+		},
+		{
+			Name:        "wait_for",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Regex for an output line that shows the process is ready, such as 'listening on'; the call returns once it appears, the process exits, or the wait runs out",
+		},
+		{
+			Name:        "wait",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Milliseconds to watch the output before returning (default: 2000, or 60000 with wait_for; max: 300000)",
+		},
+		{
+			Name:        "shell",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Shell to use (bash, zsh, sh, pwsh)",
+			Default:     t.profile.shell(),
+		},
+	}
+}
+
+// RequiresPermission returns true as command execution requires permission.
+func (t *BashBackgroundTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the command in the permission prompt.
+func (t *BashBackgroundTool) DescribeResource(params map[string]interface{}) string {
+	command, _ := params["command"].(string)
+	return fmt.Sprintf("run %s in the background", command)
+}
+
+// SensitiveReason reports commands that would print or upload credentials,
+// or break the git safety protocol, as for the bash tool.
+func (t *BashBackgroundTool) SensitiveReason(params map[string]interface{}) string {
+	command, _ := params["command"].(string)
+	return sensitiveCommand(command)
+}
+
+// Execute starts the process.
+func (t *BashBackgroundTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	command, _ := params["command"].(string)
+	name, _ := params["name"].(string)
+	workingDir, _ := params["working_dir"].(string)
+	waitFor, _ := params["wait_for"].(string)
+	shell, _ := params["shell"].(string)
+	if shell == "" {
+		shell = t.profile.shell()
+	}
+
+	fail := func(err error) (*tools.Result, error) {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Metadata: map[string]interface{}{"command": command},
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	if strings.TrimSpace(command) == "" {
+		return fail(fmt.Errorf("command is required"))
+	}
+	if isDangerousCommand(command) {
+		return fail(fmt.Errorf("command blocked: potentially dangerous operation detected"))
+	}
+	var ready *regexp.Regexp
+	if waitFor != "" {
+		var err error
+		if ready, err = regexp.Compile(waitFor); err != nil {
+			return fail(fmt.Errorf("invalid wait_for regex: %w", err))
+		}
+	}
+
+	wait := defaultStartWait
+	if ready != nil {
+		wait = 60 * time.Second
+	}
+	switch v := params["wait"].(type) {
+	case int:
+		wait = time.Duration(v) * time.Millisecond
+	case float64:
+		wait = time.Duration(v) * time.Millisecond
+	}
+	wait = min(max(wait, 0), 300*time.Second)
+
+	// A name whose process has exited is free to run again
+	if name != "" {
+		t.processes.forget(name)
+	}
+	proc, err := t.processes.start(name, shell, command, workingDir, t.profile)
+	if err != nil {
+		return fail(err)
+	}
+
+	matched := t.watch(ctx, proc, ready, wait)
+
+	info := proc.info()
+	stdout, stderr, _, _ := t.processes.ReadNew(proc.ID)
+	const maxOutputLen = 30000
+	stdout, stderr = tailOutput(stdout, maxOutputLen), tailOutput(stderr, maxOutputLen)
+
+	result := &tools.Result{
+		Success: true,
+		Output: map[string]interface{}{
+			"process_id": info.ID,
+			"pid":        info.PID,
+			"running":    !info.Done,
+			"stdout":     stdout,
+			"stderr":     stderr,
+		},
+		Metadata: map[string]interface{}{
+			"process_id":  info.ID,
+			"command":     command,
+			"shell":       shell,
+			"working_dir": workingDir,
+			"running":     !info.Done,
+		},
+		Duration: time.Since(startTime),
+	}
+	output := result.Output.(map[string]interface{})
+	switch {
+	case info.Done:
+		output["exit_code"] = info.ExitCode
+		result.Metadata["exit_code"] = info.ExitCode
+		if info.ExitCode != 0 {
+			result.Success = false
+			result.Error = fmt.Errorf("process exited with status %d", info.ExitCode)
+			if dep := DetectMissingDependency(stderr+"\n"+stdout, workingDir); dep != nil {
+				result.Metadata["missing_dependency"] = dep
+				result.Error = fmt.Errorf("process exited with status %d (%s)", info.ExitCode, dep.Hint())
+			}
+		}
+	case ready != nil && !matched:
+		output["note"] = fmt.Sprintf("still running, but no output matched %q within %s", waitFor, wait.Round(time.Millisecond))
+	}
+	if ready != nil {
+		result.Metadata["ready"] = matched
+	}
+	return result, nil
+}
+
+// watch waits until a line of the process's output matches ready, the
+// process exits, wait passes or ctx ends, reporting whether ready matched.
+// Cancelling the call doesn't stop the process; it runs in the background.
+func (t *BashBackgroundTool) watch(ctx context.Context, proc *BackgroundProcess, ready *regexp.Regexp, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if ready != nil && (matchesLine(ready, proc.stdout.String()) || matchesLine(ready, proc.stderr.String())) {
+			return true
+		}
+		select {
+		case <-proc.exited:
+			return ready != nil && (matchesLine(ready, proc.stdout.String()) || matchesLine(ready, proc.stderr.String()))
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// matchesLine reports whether a line of output matches re.
+func matchesLine(re *regexp.Regexp, output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Category returns the tool category.
+func (t *BashBackgroundTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *BashBackgroundTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *BashBackgroundTool) IsExternal() bool {
+	return false
+}
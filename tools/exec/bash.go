@@ -13,11 +13,27 @@ import (
 )
 
 // BashTool implements the command execution tool.
-type BashTool struct{}
+type BashTool struct {
+	profile *ShellProfile
+}
+
+// BashOption is a functional option for configuring the Bash tool.
+type BashOption func(*BashTool)
+
+// WithProfile runs commands in the environment described by profile.
+func WithProfile(profile *ShellProfile) BashOption {
+	return func(t *BashTool) {
+		t.profile = profile
+	}
+}
 
 // NewBashTool creates a new Bash tool.
-func NewBashTool() *BashTool {
-	return &BashTool{}
+func NewBashTool(opts ...BashOption) *BashTool {
+	t := &BashTool{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // Name returns the tool name.
@@ -58,7 +74,7 @@ func (t *BashTool) Parameters() []tools.Parameter {
 			Type:        tools.TypeString,
 			Required:    false,
 			Description: "Shell to use (bash, zsh, sh, pwsh)",
-			Default:     "bash",
+			Default:     t.profile.shell(),
 		},
 	}
 }
@@ -68,6 +84,14 @@ func (t *BashTool) RequiresPermission() bool {
 	return true
 }
 
+// SensitiveReason reports commands that would print or upload credentials,
+// such as cat .env or curl with a raw API key in a header, and git commands
+// that break the git safety protocol, such as git push --force.
+func (t *BashTool) SensitiveReason(params map[string]interface{}) string {
+	command, _ := params["command"].(string)
+	return sensitiveCommand(command)
+}
+
 // Execute executes the bash command.
 func (t *BashTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
 	startTime := time.Now()
@@ -76,7 +100,7 @@ func (t *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 	command := params["command"].(string)
 	workingDir := ""
 	timeoutMs := 120000
-	shell := "bash"
+	shell := t.profile.shell()
 
 	if val, ok := params["working_dir"]; ok {
 		workingDir = val.(string)
@@ -118,17 +142,18 @@ func (t *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 	cmdCtx, cancel := context.WithTimeout(ctx, timeoutDuration)
 	defer cancel()
 
-	// Determine shell command
+	// Determine shell command, run in the profile's environment
+	script := t.profile.wrap(shell, command, workingDir)
 	var cmd *exec.Cmd
 	switch shell {
 	case "bash":
-		cmd = exec.CommandContext(cmdCtx, "bash", "-c", command)
+		cmd = exec.CommandContext(cmdCtx, "bash", "-c", script)
 	case "zsh":
-		cmd = exec.CommandContext(cmdCtx, "zsh", "-c", command)
+		cmd = exec.CommandContext(cmdCtx, "zsh", "-c", script)
 	case "sh":
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+		cmd = exec.CommandContext(cmdCtx, "sh", "-c", script)
 	case "pwsh", "powershell":
-		cmd = exec.CommandContext(cmdCtx, "pwsh", "-Command", command)
+		cmd = exec.CommandContext(cmdCtx, "pwsh", "-Command", script)
 	default:
 		return &tools.Result{
 			Success: false,
@@ -140,6 +165,7 @@ func (t *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 	if workingDir != "" {
 		cmd.Dir = workingDir
 	}
+	cmd.Env = t.profile.environ()
 
 	// Capture output
 	var stdout, stderr bytes.Buffer
@@ -177,6 +203,11 @@ func (t *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 
 	if err != nil {
 		result.Error = fmt.Errorf("command failed: %w", err)
+		// Point the agent at an approved install instead of a free-form one
+		if dep := DetectMissingDependency(stderrStr+"\n"+stdoutStr, workingDir); dep != nil {
+			result.Metadata["missing_dependency"] = dep
+			result.Error = fmt.Errorf("command failed: %w (%s)", err, dep.Hint())
+		}
 		result.Output = map[string]interface{}{
 			"stdout":    stdoutStr,
 			"stderr":    stderrStr,
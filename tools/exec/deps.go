@@ -0,0 +1,333 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Kinds of missing dependency
+const (
+	DependencyModule  = "module"  // A library imported by the code
+	DependencyCommand = "command" // An executable run by the command
+)
+
+// MissingDependency is a dependency a failed command could not find, with
+// the package that provides it.
+type MissingDependency struct {
+	Kind    string // DependencyModule or DependencyCommand
+	Name    string // Module or command as reported in the output
+	Package string // Package to install; empty if unknown
+	Manager string // Package manager to install it with; empty if unknown
+}
+
+// Installable reports whether the dependency can be installed with the
+// install_dependency tool.
+func (d *MissingDependency) Installable() bool {
+	return d.Package != "" && d.Manager != ""
+}
+
+// Hint describes the dependency and how to install it, for the agent.
+func (d *MissingDependency) Hint() string {
+	msg := fmt.Sprintf("missing %s %q", d.Kind, d.Name)
+	if !d.Installable() {
+		return msg
+	}
+	return fmt.Sprintf("%s; install %s via %s with the install_dependency tool (manager=%s, package=%s) instead of installing it from the shell",
+		msg, d.Package, d.Manager, d.Manager, d.Package)
+}
+
+// installers maps a package manager to the arguments that install one
+// package (appended last), and to those for a development dependency if the
+// manager distinguishes them.
+var installers = map[string]struct {
+	args    []string
+	devArgs []string
+}{
+	"go":    {args: []string{"go", "get"}},
+	"npm":   {args: []string{"npm", "install"}, devArgs: []string{"npm", "install", "--save-dev"}},
+	"pnpm":  {args: []string{"pnpm", "add"}, devArgs: []string{"pnpm", "add", "--save-dev"}},
+	"yarn":  {args: []string{"yarn", "add"}, devArgs: []string{"yarn", "add", "--dev"}},
+	"pip":   {args: []string{"python3", "-m", "pip", "install"}},
+	"cargo": {args: []string{"cargo", "add"}, devArgs: []string{"cargo", "add", "--dev"}},
+	"brew":  {args: []string{"brew", "install"}},
+}
+
+// Managers returns the package managers install_dependency supports.
+func Managers() []string {
+	names := make([]string, 0, len(installers))
+	for name := range installers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validPackage matches package names that are safe to pass to an installer:
+// no options, whitespace or shell syntax.
+var validPackage = regexp.MustCompile(`^[A-Za-z0-9@][A-Za-z0-9@._/+=:~-]*$`)
+
+// Signatures of missing-dependency errors in command output
+var (
+	goMissingPackage    = regexp.MustCompile(`no required module provides package ([^\s;:]+)`)
+	goMissingSum        = regexp.MustCompile(`missing go\.sum entry for module providing package ([^\s;:(]+)`)
+	nodeMissingModule   = regexp.MustCompile(`Cannot find module '([^']+)'`)
+	pythonMissingModule = regexp.MustCompile(`ModuleNotFoundError: No module named '([^']+)'`)
+	rustMissingCrate    = regexp.MustCompile("can't find crate for `([^`]+)`")
+	commandNotFound     = []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^(?:/bin/)?(?:bash|sh|dash): (?:line \d+: |\d+: )?([^\s:]+): (?:command )?not found`),
+		regexp.MustCompile(`(?m)zsh: command not found: (\S+)`),
+		regexp.MustCompile(`(?m)exec: "([^"]+)": executable file not found in \$PATH`),
+	}
+)
+
+// knownCommands maps common development commands to the package providing
+// them. Commands not listed fall back to the system package manager.
+var knownCommands = map[string]MissingDependency{
+	"tsc":           {Package: "typescript", Manager: "npm"},
+	"jest":          {Package: "jest", Manager: "npm"},
+	"eslint":        {Package: "eslint", Manager: "npm"},
+	"prettier":      {Package: "prettier", Manager: "npm"},
+	"vitest":        {Package: "vitest", Manager: "npm"},
+	"pytest":        {Package: "pytest", Manager: "pip"},
+	"black":         {Package: "black", Manager: "pip"},
+	"ruff":          {Package: "ruff", Manager: "pip"},
+	"mypy":          {Package: "mypy", Manager: "pip"},
+	"goimports":     {Package: "golang.org/x/tools/cmd/goimports@latest", Manager: "go"},
+	"staticcheck":   {Package: "honnef.co/go/tools/cmd/staticcheck@latest", Manager: "go"},
+	"golangci-lint": {Package: "github.com/golangci/golangci-lint/cmd/golangci-lint@latest", Manager: "go"},
+}
+
+// DetectMissingDependency looks for the signature of a missing module or
+// command in the output of a failed command run in workingDir. It returns
+// nil if there is none.
+func DetectMissingDependency(output, workingDir string) *MissingDependency {
+	if m := goMissingPackage.FindStringSubmatch(output); m != nil {
+		return &MissingDependency{Kind: DependencyModule, Name: m[1], Package: m[1], Manager: "go"}
+	}
+	if m := goMissingSum.FindStringSubmatch(output); m != nil {
+		return &MissingDependency{Kind: DependencyModule, Name: m[1], Package: m[1], Manager: "go"}
+	}
+	if m := nodeMissingModule.FindStringSubmatch(output); m != nil && !isRelativeModule(m[1]) {
+		return &MissingDependency{Kind: DependencyModule, Name: m[1], Package: nodePackage(m[1]), Manager: nodeManager(workingDir)}
+	}
+	if m := pythonMissingModule.FindStringSubmatch(output); m != nil {
+		// The distribution usually shares the top-level package name
+		pkg := strings.SplitN(m[1], ".", 2)[0]
+		return &MissingDependency{Kind: DependencyModule, Name: m[1], Package: pkg, Manager: "pip"}
+	}
+	if m := rustMissingCrate.FindStringSubmatch(output); m != nil {
+		return &MissingDependency{Kind: DependencyModule, Name: m[1], Package: strings.ReplaceAll(m[1], "_", "-"), Manager: "cargo"}
+	}
+
+	for _, re := range commandNotFound {
+		m := re.FindStringSubmatch(output)
+		if m == nil {
+			continue
+		}
+		dep := MissingDependency{Kind: DependencyCommand, Name: filepath.Base(m[1])}
+		if known, ok := knownCommands[dep.Name]; ok {
+			dep.Package, dep.Manager = known.Package, known.Manager
+			if dep.Manager == "npm" {
+				dep.Manager = nodeManager(workingDir)
+			}
+		} else if runtime.GOOS == "darwin" {
+			if _, err := exec.LookPath("brew"); err == nil {
+				dep.Package, dep.Manager = dep.Name, "brew"
+			}
+		}
+		return &dep
+	}
+
+	return nil
+}
+
+// isRelativeModule reports whether a Node module reference is a path
+// rather than a package.
+func isRelativeModule(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "/")
+}
+
+// nodePackage returns the package name of a module path, keeping the scope
+// of scoped packages ("@scope/pkg/sub" is "@scope/pkg").
+func nodePackage(module string) string {
+	parts := strings.Split(module, "/")
+	if strings.HasPrefix(module, "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// nodeManager picks the Node package manager from the project's lockfile.
+func nodeManager(workingDir string) string {
+	dir := workingDir
+	if dir == "" {
+		dir = "."
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pnpm-lock.yaml")); err == nil {
+		return "pnpm"
+	}
+	if _, err := os.Stat(filepath.Join(dir, "yarn.lock")); err == nil {
+		return "yarn"
+	}
+	return "npm"
+}
+
+// InstallDependencyTool installs one package with a known package manager.
+// It replaces free-form install commands: the agent names the package and
+// manager, the tool builds the command, and the user approves "install X via
+// Y" before it runs.
+type InstallDependencyTool struct{}
+
+// NewInstallDependencyTool creates a new install_dependency tool.
+func NewInstallDependencyTool() *InstallDependencyTool {
+	return &InstallDependencyTool{}
+}
+
+// Name returns the tool name.
+func (t *InstallDependencyTool) Name() string {
+	return "install_dependency"
+}
+
+// Description returns the tool description.
+func (t *InstallDependencyTool) Description() string {
+	return "Installs a missing dependency reported by a failed command with a package manager, after the user approves it"
+}
+
+// Parameters returns the tool parameters.
+func (t *InstallDependencyTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "package",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Package to install (e.g. typescript, github.com/google/uuid, requests)",
+		},
+		{
+			Name:        "manager",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Package manager: " + strings.Join(Managers(), ", "),
+			Validation:  &tools.Validation{Enum: Managers()},
+		},
+		{
+			Name:        "dev",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Install as a development dependency where the manager distinguishes them",
+			Default:     false,
+		},
+		{
+			Name:        "working_dir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Project directory to install into",
+			Default:     "",
+		},
+	}
+}
+
+// RequiresPermission returns true as installing packages requires permission.
+func (t *InstallDependencyTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the package and manager in the permission prompt.
+func (t *InstallDependencyTool) DescribeResource(params map[string]interface{}) string {
+	pkg, _ := params["package"].(string)
+	manager, _ := params["manager"].(string)
+	return fmt.Sprintf("install %s via %s", pkg, manager)
+}
+
+// Execute installs the package.
+func (t *InstallDependencyTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	pkg, _ := params["package"].(string)
+	manager, _ := params["manager"].(string)
+	dev, _ := params["dev"].(bool)
+	workingDir, _ := params["working_dir"].(string)
+
+	installer, ok := installers[manager]
+	if !ok {
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("unsupported package manager: %s", manager),
+		}, nil
+	}
+	if !validPackage.MatchString(pkg) {
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("invalid package name: %q", pkg),
+		}, nil
+	}
+
+	args := installer.args
+	if dev && installer.devArgs != nil {
+		args = installer.devArgs
+	}
+	args = append(append([]string{}, args...), pkg)
+
+	cmdCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, args[0], args[1:]...)
+	cmd.Dir = workingDir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	outputStr := output.String()
+	const maxOutputLen = 30000
+	if len(outputStr) > maxOutputLen {
+		outputStr = outputStr[len(outputStr)-maxOutputLen:]
+	}
+
+	result := &tools.Result{
+		Success: err == nil,
+		Output:  outputStr,
+		Metadata: map[string]interface{}{
+			"package":     pkg,
+			"manager":     manager,
+			"command":     strings.Join(args, " "),
+			"working_dir": workingDir,
+			"exit_code":   exitCode,
+		},
+		Duration: time.Since(startTime),
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("failed to install %s via %s: %w", pkg, manager, err)
+	}
+	return result, nil
+}
+
+// Category returns the tool category.
+func (t *InstallDependencyTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *InstallDependencyTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *InstallDependencyTool) IsExternal() bool {
+	return false
+}
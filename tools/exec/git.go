@@ -0,0 +1,112 @@
+package exec
+
+import (
+	"path"
+	"strings"
+)
+
+// The git tools carry out the git safety protocol by construction, but git
+// can still be run through bash. Commands that would break the protocol
+// are flagged so they need elevated permission and the user sees why.
+
+// gitOptionsWithValue are git's global options that take the next word as
+// their value.
+var gitOptionsWithValue = map[string]bool{
+	"-C": true, "-c": true, "--git-dir": true, "--work-tree": true, "--namespace": true,
+	"--exec-path": true, "--config-env": true,
+}
+
+// unsafeGitCommand returns why the words of a simple command would break the
+// git safety protocol, or "" if they would not or don't run git.
+func unsafeGitCommand(fields []string) string {
+	i := 0
+	for i < len(fields) && !strings.EqualFold(path.Base(fields[i]), "git") {
+		i++
+	}
+	if i == len(fields) {
+		return ""
+	}
+
+	// Skip global options to reach the subcommand
+	i++
+	for i < len(fields) && strings.HasPrefix(fields[i], "-") {
+		if gitOptionsWithValue[fields[i]] {
+			i++
+		}
+		i++
+	}
+	if i >= len(fields) {
+		return ""
+	}
+	sub, args := fields[i], fields[i+1:]
+
+	has := func(flags ...string) bool {
+		for _, arg := range args {
+			for _, flag := range flags {
+				if arg == flag || strings.HasPrefix(arg, flag+"=") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	// shortFlag matches a short flag alone or combined, as in -fu
+	shortFlag := func(flag byte) bool {
+		for _, arg := range args {
+			if len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.IndexByte(arg[1:], flag) >= 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	if has("--no-verify") || (sub == "commit" && shortFlag('n')) {
+		return "git command skips the repository's hooks"
+	}
+	switch sub {
+	case "push":
+		if has("--force", "--force-with-lease", "--force-if-includes", "--mirror") || shortFlag('f') {
+			return "git push --force rewrites history on the remote"
+		}
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "+") {
+				return "git push with a +refspec rewrites history on the remote"
+			}
+		}
+	case "commit":
+		if has("--amend") {
+			return "git commit --amend rewrites the last commit, which may not be yours or may be pushed"
+		}
+	case "reset":
+		// --keep refuses to reset files with local changes, so it is safe
+		if has("--hard") {
+			return "git reset --hard discards uncommitted changes"
+		}
+		if has("--merge") {
+			return "git reset --merge discards staged changes"
+		}
+	case "clean":
+		if has("--force") || shortFlag('f') {
+			return "git clean deletes untracked files"
+		}
+	case "branch":
+		if has("--force") || shortFlag('D') || (has("--delete") && shortFlag('f')) {
+			return "git branch -D deletes a branch even if it isn't merged"
+		}
+	case "config":
+		// Reading takes a key at most; setting takes a key and a value
+		values := 0
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				values++
+			}
+		}
+		if has("--get", "--get-all", "--get-regexp") {
+			break
+		}
+		if values >= 2 || has("--unset", "--unset-all", "--add", "--replace-all", "--edit", "--rename-section", "--remove-section") || shortFlag('e') {
+			return "git config changes git configuration"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,431 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+const (
+	// lintTimeout is how long one linter or formatter may run.
+	lintTimeout = 5 * time.Minute
+
+	// maxReportedViolations caps the violations listed in a lint report;
+	// all are in the metadata.
+	maxReportedViolations = 100
+)
+
+// LintTool runs the project's linters and reports their violations.
+type LintTool struct {
+	linters *Linters
+	profile *ShellProfile
+}
+
+// NewLintTool creates a new Lint tool running linters; nil uses the
+// built-in ones.
+func NewLintTool(linters *Linters, opts ...BashOption) *LintTool {
+	bash := NewBashTool(opts...)
+	return &LintTool{linters: linters, profile: bash.profile}
+}
+
+// Name returns the tool name.
+func (t *LintTool) Name() string {
+	return "lint"
+}
+
+// Description returns the tool description.
+func (t *LintTool) Description() string {
+	return "Runs the project's linters (golangci-lint, ruff, or those configured) on paths or the whole project and reports each " +
+		"violation as path:line:column with its rule, optionally applying the fixes the linters can make"
+}
+
+// Parameters returns the tool parameters.
+func (t *LintTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "paths",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Files or directories to lint, one per line (default: the whole project)",
+			Default:     "",
+		},
+		{
+			Name:        "linter",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Run only this linter (default: those configured, or detected from the project)",
+			Default:     "",
+		},
+		{
+			Name:        "fix",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Apply the fixes the linters can make and report what remains",
+			Default:     false,
+		},
+		{
+			Name:        "working_dir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Project directory to lint in (default: current directory)",
+			Default:     "",
+		},
+	}
+}
+
+// RequiresPermission returns true as linters are project commands and
+// fixes rewrite files.
+func (t *LintTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute runs the linters.
+func (t *LintTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	fail := func(err error, metadata map[string]interface{}) (*tools.Result, error) {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Metadata: metadata,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	workingDir, _ := params["working_dir"].(string)
+	name, _ := params["linter"].(string)
+	fix, _ := params["fix"].(bool)
+	paths := linterPaths(params)
+
+	linters, requested, err := t.linters.Select(LinterLint, dirOrDot(workingDir), name)
+	if err != nil {
+		return fail(err, nil)
+	}
+	if len(linters) == 0 {
+		return fail(fmt.Errorf("no linter detected in %s; configure tools.lint.linters or pass linter", dirOrDot(workingDir)), nil)
+	}
+
+	var violations []Violation
+	var ran, skipped []string
+	for _, l := range linters {
+		targets := l.targets(workingDir, paths)
+		if len(targets) == 0 {
+			continue
+		}
+		command := l.Command
+		if fix && l.Fix != "" {
+			command = l.Fix
+		}
+		out, exitCode, err := runLinter(ctx, t.profile, command, targets, workingDir)
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", l.Name, err), nil)
+		}
+		if notInstalled(exitCode, out, workingDir) {
+			if requested {
+				return fail(fmt.Errorf("%s is not installed: %s", l.Name, tail(strings.TrimSpace(out), maxFailureOutput)), nil)
+			}
+			skipped = append(skipped, l.Name)
+			continue
+		}
+		found := parseViolations(l.Name, workingDir, out)
+		if exitCode != 0 && len(found) == 0 {
+			return fail(fmt.Errorf("%s failed with exit code %d:\n%s", l.Name, exitCode, tail(strings.TrimSpace(out), maxFailureOutput)), nil)
+		}
+		ran = append(ran, l.Name)
+		violations = append(violations, found...)
+	}
+	if len(ran) == 0 && len(skipped) > 0 {
+		return fail(fmt.Errorf("none of the detected linters are installed: %s", strings.Join(skipped, ", ")), nil)
+	}
+
+	var b strings.Builder
+	switch {
+	case len(ran) == 0:
+		b.WriteString("No linter handles these paths")
+	case len(violations) == 0:
+		fmt.Fprintf(&b, "%s: no violations", strings.Join(ran, ", "))
+	default:
+		fmt.Fprintf(&b, "%s: %d violation(s)", strings.Join(ran, ", "), len(violations))
+		for i, v := range violations {
+			if i == maxReportedViolations {
+				fmt.Fprintf(&b, "\n... and %d more", len(violations)-i)
+				break
+			}
+			b.WriteString("\n" + v.String())
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&b, "\nSkipped, not installed: %s", strings.Join(skipped, ", "))
+	}
+
+	return &tools.Result{
+		Success: true,
+		Output:  b.String(),
+		Metadata: map[string]interface{}{
+			"violations": violations,
+			"count":      len(violations),
+			"linters":    ran,
+			"skipped":    skipped,
+			"fixed":      fix,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *LintTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *LintTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *LintTool) IsExternal() bool {
+	return false
+}
+
+// FormatTool runs the project's formatters.
+type FormatTool struct {
+	linters *Linters
+	profile *ShellProfile
+}
+
+// NewFormatTool creates a new Format tool running the formatters of
+// linters; nil uses the built-in ones.
+func NewFormatTool(linters *Linters, opts ...BashOption) *FormatTool {
+	bash := NewBashTool(opts...)
+	return &FormatTool{linters: linters, profile: bash.profile}
+}
+
+// Name returns the tool name.
+func (t *FormatTool) Name() string {
+	return "format"
+}
+
+// Description returns the tool description.
+func (t *FormatTool) Description() string {
+	return "Formats files with the project's formatters (gofmt, prettier, ruff format, or those configured) and lists the files " +
+		"it changed, or with check only lists the files needing formatting"
+}
+
+// Parameters returns the tool parameters.
+func (t *FormatTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "paths",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Files or directories to format, one per line (default: the whole project)",
+			Default:     "",
+		},
+		{
+			Name:        "formatter",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Run only this formatter (default: those configured, or detected from the project)",
+			Default:     "",
+		},
+		{
+			Name:        "check",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Only list the files needing formatting, changing nothing",
+			Default:     false,
+		},
+		{
+			Name:        "working_dir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Project directory to format in (default: current directory)",
+			Default:     "",
+		},
+	}
+}
+
+// RequiresPermission returns true as formatting rewrites files.
+func (t *FormatTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute runs the formatters: each lists the files it would change, then
+// rewrites them unless only checking.
+func (t *FormatTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	fail := func(err error, metadata map[string]interface{}) (*tools.Result, error) {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Metadata: metadata,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	workingDir, _ := params["working_dir"].(string)
+	name, _ := params["formatter"].(string)
+	check, _ := params["check"].(bool)
+	paths := linterPaths(params)
+
+	formatters, requested, err := t.linters.Select(LinterFormat, dirOrDot(workingDir), name)
+	if err != nil {
+		return fail(err, nil)
+	}
+	if len(formatters) == 0 {
+		return fail(fmt.Errorf("no formatter detected in %s; configure tools.lint.formatters or pass formatter", dirOrDot(workingDir)), nil)
+	}
+
+	var files, ran, skipped []string
+	var formatted []string // Paths of the files rewritten, for callers tracking edits
+	for _, f := range formatters {
+		targets := f.targets(workingDir, paths)
+		if len(targets) == 0 {
+			continue
+		}
+		out, exitCode, err := runLinter(ctx, t.profile, f.Check, targets, workingDir)
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", f.Name, err), nil)
+		}
+		if notInstalled(exitCode, out, workingDir) {
+			if requested {
+				return fail(fmt.Errorf("%s is not installed: %s", f.Name, tail(strings.TrimSpace(out), maxFailureOutput)), nil)
+			}
+			skipped = append(skipped, f.Name)
+			continue
+		}
+		pending := parseFileList(workingDir, out)
+		if exitCode != 0 && len(pending) == 0 {
+			return fail(fmt.Errorf("%s failed with exit code %d:\n%s", f.Name, exitCode, tail(strings.TrimSpace(out), maxFailureOutput)), nil)
+		}
+		ran = append(ran, f.Name)
+		if check || len(pending) == 0 {
+			files = append(files, pending...)
+			continue
+		}
+
+		out, exitCode, err = runLinter(ctx, t.profile, f.Command, pending, workingDir)
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("exit code %d:\n%s", exitCode, tail(strings.TrimSpace(out), maxFailureOutput))
+		}
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", f.Name, err), map[string]interface{}{"paths": formatted})
+		}
+		files = append(files, pending...)
+		for _, p := range pending {
+			formatted = append(formatted, filepath.Join(workingDir, p))
+		}
+	}
+	if len(ran) == 0 && len(skipped) > 0 {
+		return fail(fmt.Errorf("none of the detected formatters are installed: %s", strings.Join(skipped, ", ")), nil)
+	}
+
+	var b strings.Builder
+	switch {
+	case len(ran) == 0:
+		b.WriteString("No formatter handles these paths")
+	case len(files) == 0:
+		fmt.Fprintf(&b, "%s: all files are formatted", strings.Join(ran, ", "))
+	case check:
+		fmt.Fprintf(&b, "%s: %d file(s) need formatting:\n%s", strings.Join(ran, ", "), len(files), strings.Join(files, "\n"))
+	default:
+		fmt.Fprintf(&b, "%s: formatted %d file(s):\n%s", strings.Join(ran, ", "), len(files), strings.Join(files, "\n"))
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&b, "\nSkipped, not installed: %s", strings.Join(skipped, ", "))
+	}
+
+	metadata := map[string]interface{}{
+		"files":      files,
+		"formatters": ran,
+		"skipped":    skipped,
+		"check":      check,
+	}
+	if !check {
+		metadata["paths"] = formatted
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   b.String(),
+		Metadata: metadata,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *FormatTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *FormatTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *FormatTool) IsExternal() bool {
+	return false
+}
+
+// linterPaths reads the paths parameter, one path per line.
+func linterPaths(params map[string]interface{}) []string {
+	s, _ := params["paths"].(string)
+	var paths []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// dirOrDot returns dir, or "." for the current directory.
+func dirOrDot(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// runLinter runs command with targets appended in dir and returns its
+// combined output and exit code. The error is set only if the command
+// could not run to completion.
+func runLinter(ctx context.Context, profile *ShellProfile, command string, targets []string, dir string) (string, int, error) {
+	args := make([]string, 0, len(targets)+1)
+	args = append(args, command)
+	for _, t := range targets {
+		args = append(args, shellQuote(t))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lintTimeout)
+	defer cancel()
+	cmd := profile.command(ctx, strings.Join(args, " "), dir)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return out.String(), -1, fmt.Errorf("timed out after %s", lintTimeout)
+	}
+	if cmd.ProcessState == nil {
+		return out.String(), -1, err
+	}
+	return out.String(), cmd.ProcessState.ExitCode(), nil
+}
+
+// notInstalled reports whether a linter failed because its executable is
+// missing.
+func notInstalled(exitCode int, output, dir string) bool {
+	if exitCode == 0 {
+		return false
+	}
+	if exitCode == 127 || strings.Contains(output, "could not determine executable to run") {
+		return true
+	}
+	dep := DetectMissingDependency(output, dir)
+	return dep != nil && dep.Kind == DependencyCommand
+}
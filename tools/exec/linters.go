@@ -0,0 +1,335 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of linter.
+const (
+	LinterLint   = "lint"   // Reports violations
+	LinterFormat = "format" // Rewrites files into a canonical layout
+)
+
+// Linter is a linter or formatter core.lint and core.format can run. The
+// paths to check are appended to its commands.
+type Linter struct {
+	Name       string
+	Kind       string   // LinterLint or LinterFormat
+	Command    string   // Lint: prints violations as path:line[:column]: message. Format: rewrites the files
+	Fix        string   // Lint: fixes what it can and prints the rest
+	Check      string   // Format: prints the files needing formatting, changing nothing
+	Extensions []string // Files it handles, with the dot
+	Markers    []string // Globs of project files that show the project uses it
+	Dirs       bool     // Takes package directories rather than files
+	All        string   // Target checking the whole project
+}
+
+// DefaultLinters returns the built-in linters and formatters by name.
+func DefaultLinters() map[string]Linter {
+	goMarkers := []string{"go.mod"}
+	pyMarkers := []string{"ruff.toml", ".ruff.toml", "pyproject.toml", "setup.py", "setup.cfg", "requirements.txt"}
+	return map[string]Linter{
+		"gofmt": {
+			Kind:       LinterFormat,
+			Command:    "gofmt -w",
+			Check:      "gofmt -l",
+			Extensions: []string{".go"},
+			Markers:    goMarkers,
+			All:        ".",
+		},
+		"golangci-lint": {
+			Kind:       LinterLint,
+			Command:    "golangci-lint run",
+			Fix:        "golangci-lint run --fix",
+			Extensions: []string{".go"},
+			Markers:    goMarkers,
+			Dirs:       true,
+			All:        "./...",
+		},
+		"prettier": {
+			Kind:    LinterFormat,
+			Command: "npx --no-install prettier --write",
+			Check:   "npx --no-install prettier --list-different",
+			Extensions: []string{
+				".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".vue", ".css", ".scss", ".less",
+				".html", ".json", ".md", ".yaml", ".yml", ".graphql",
+			},
+			Markers: []string{".prettierrc*", "prettier.config.*", "node_modules/.bin/prettier"},
+			All:     ".",
+		},
+		"ruff": {
+			Kind:       LinterLint,
+			Command:    "ruff check --output-format=concise --no-fix",
+			Fix:        "ruff check --output-format=concise --fix",
+			Extensions: []string{".py", ".pyi"},
+			Markers:    pyMarkers,
+			All:        ".",
+		},
+		"ruff-format": {
+			Kind:       LinterFormat,
+			Command:    "ruff format",
+			Check:      "ruff format --check",
+			Extensions: []string{".py", ".pyi"},
+			Markers:    pyMarkers,
+			All:        ".",
+		},
+	}
+}
+
+// Linters are the linters and formatters available to a project, and the
+// ones its configuration picks.
+type Linters struct {
+	known      map[string]Linter
+	linters    []string // Chosen by config; empty detects them
+	formatters []string
+}
+
+// NewLinters returns the built-in linters with custom ones added. A custom
+// linter named like a built-in one overrides the fields it sets. linters
+// and formatters name the ones to run; empty lists detect them from the
+// project's files.
+func NewLinters(custom map[string]Linter, linters, formatters []string) *Linters {
+	known := DefaultLinters()
+	for name, c := range custom {
+		l, ok := known[name]
+		if !ok {
+			l = Linter{All: "."}
+		}
+		if c.Kind != "" {
+			l.Kind = c.Kind
+		}
+		if c.Command != "" {
+			l.Command = c.Command
+		}
+		if c.Fix != "" {
+			l.Fix = c.Fix
+		}
+		if c.Check != "" {
+			l.Check = c.Check
+		}
+		if len(c.Extensions) > 0 {
+			l.Extensions = c.Extensions
+		}
+		if len(c.Markers) > 0 {
+			l.Markers = c.Markers
+		}
+		known[name] = l
+	}
+	for name, l := range known {
+		l.Name = name
+		known[name] = l
+	}
+	return &Linters{known: known, linters: linters, formatters: formatters}
+}
+
+// Select returns the linters of kind to run in dir: the one named, those
+// the configuration names, or those the project's files call for. It
+// reports whether the linters were asked for rather than detected.
+func (ls *Linters) Select(kind, dir, name string) ([]Linter, bool, error) {
+	if ls == nil {
+		ls = NewLinters(nil, nil, nil)
+	}
+	names := ls.linters
+	if kind == LinterFormat {
+		names = ls.formatters
+	}
+	if name != "" {
+		names = []string{name}
+	}
+
+	if len(names) > 0 {
+		selected := make([]Linter, 0, len(names))
+		for _, n := range names {
+			l, ok := ls.known[n]
+			if !ok || l.Kind != kind {
+				return nil, true, fmt.Errorf("unknown %s tool: %s (available: %s)", kind, n, strings.Join(ls.names(kind), ", "))
+			}
+			if l.Command == "" || (kind == LinterFormat && l.Check == "") {
+				return nil, true, fmt.Errorf("%s has no command configured", n)
+			}
+			selected = append(selected, l)
+		}
+		return selected, true, nil
+	}
+
+	var selected []Linter
+	for _, n := range ls.names(kind) {
+		l := ls.known[n]
+		if l.Command == "" || (kind == LinterFormat && l.Check == "") {
+			continue
+		}
+		for _, marker := range l.Markers {
+			if matches, _ := filepath.Glob(filepath.Join(dir, marker)); len(matches) > 0 {
+				selected = append(selected, l)
+				break
+			}
+		}
+	}
+	return selected, false, nil
+}
+
+// names returns the names of the linters of kind, sorted.
+func (ls *Linters) names(kind string) []string {
+	var names []string
+	for name, l := range ls.known {
+		if l.Kind == kind {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// targets returns the arguments checking paths with l, or nil if l handles
+// none of them. Directories are passed to every linter; no paths checks
+// the whole project.
+func (l Linter) targets(dir string, paths []string) []string {
+	if len(paths) == 0 {
+		return []string{l.All}
+	}
+	var targets []string
+	seen := make(map[string]bool)
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	for _, p := range paths {
+		full := p
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(dir, p)
+		}
+		if info, err := os.Stat(full); err == nil && info.IsDir() {
+			if l.Dirs {
+				add(filepath.ToSlash(filepath.Join(p, "...")))
+			} else {
+				add(p)
+			}
+			continue
+		}
+		if !l.handles(p) {
+			continue
+		}
+		if l.Dirs {
+			d := filepath.Dir(p)
+			if !filepath.IsAbs(d) && d != "." {
+				d = "." + string(filepath.Separator) + d
+			}
+			add(d)
+		} else {
+			add(p)
+		}
+	}
+	return targets
+}
+
+// handles reports whether l checks the file at path.
+func (l Linter) handles(path string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range l.Extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation is a problem a linter found.
+type Violation struct {
+	Linter  string `json:"linter"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Rule    string `json:"rule,omitempty"` // e.g. F401 or errcheck
+	Message string `json:"message"`
+}
+
+// String formats v as path:line:column: message.
+func (v Violation) String() string {
+	loc := fmt.Sprintf("%s:%d", v.Path, v.Line)
+	if v.Column > 0 {
+		loc += fmt.Sprintf(":%d", v.Column)
+	}
+	rule := v.Linter
+	if v.Rule != "" {
+		rule += " " + v.Rule
+	}
+	return fmt.Sprintf("%s: %s (%s)", loc, v.Message, rule)
+}
+
+// Lines of linter output
+var (
+	violationLine = regexp.MustCompile(`^(\S[^:]*):(\d+):(?:(\d+):)?\s+(.+)$`)
+	leadingRule   = regexp.MustCompile(`^([A-Z]+[0-9]+)\s+(?:\[\*\]\s+)?(.+)$`)         // ruff: "F401 [*] `os` imported but unused"
+	trailingRule  = regexp.MustCompile(`^(.+?)\s+\(([A-Za-z0-9_-]+)\)$`)                // golangci-lint: "... is not checked (errcheck)"
+	reformatLine  = regexp.MustCompile(`^(?:Would reformat:\s*|\[warn\]\s*)?(.+?)\s*$`) // ruff format --check, prettier --check
+)
+
+// parseViolations reads the violations a linter printed, with paths
+// relative to dir where possible.
+func parseViolations(linter, dir, output string) []Violation {
+	var violations []Violation
+	for _, line := range strings.Split(output, "\n") {
+		m := violationLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		v := Violation{Linter: linter, Path: relPath(dir, m[1]), Message: m[4]}
+		v.Line, _ = strconv.Atoi(m[2])
+		v.Column, _ = strconv.Atoi(m[3])
+		if r := leadingRule.FindStringSubmatch(v.Message); r != nil {
+			v.Rule, v.Message = r[1], r[2]
+		} else if r := trailingRule.FindStringSubmatch(v.Message); r != nil {
+			v.Message, v.Rule = r[1], r[2]
+		}
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// parseFileList reads the files a formatter's check printed, keeping only
+// lines naming files that exist.
+func parseFileList(dir, output string) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		m := reformatLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil || m[1] == "" {
+			continue
+		}
+		path := m[1]
+		full := path
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(dir, path)
+		}
+		if info, err := os.Stat(full); err != nil || info.IsDir() {
+			continue
+		}
+		if path = relPath(dir, path); !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// relPath returns path relative to dir if it is inside it, with slashes.
+func relPath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		base := dir
+		if abs, err := filepath.Abs(dir); err == nil {
+			base = abs
+		}
+		if rel, err := filepath.Rel(base, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(path), "./")
+}
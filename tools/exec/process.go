@@ -7,15 +7,31 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/abrksh22/bplus/tools"
 )
 
-// ProcessManager manages background processes.
+const (
+	// maxBackgroundProcesses is how many processes may run at once
+	maxBackgroundProcesses = 16
+
+	// maxProcessOutput is how much of each output stream a process keeps;
+	// older output is dropped
+	maxProcessOutput = 1 << 20
+
+	// killGrace is how long a process asked to exit has before it is killed
+	killGrace = 3 * time.Second
+)
+
+// ProcessManager manages background processes, such as dev servers and
+// watchers started by the bash_background tool.
 type ProcessManager struct {
 	processes map[string]*BackgroundProcess
+	started   int // Processes started, for naming unnamed ones
 	mu        sync.RWMutex
 }
 
@@ -23,86 +39,128 @@ type ProcessManager struct {
 type BackgroundProcess struct {
 	ID        string
 	Command   string
+	Dir       string
 	Cmd       *exec.Cmd
 	StartTime time.Time
-	Stdout    *bytes.Buffer
-	Stderr    *bytes.Buffer
 	Done      bool
 	ExitCode  int
 	Error     error
+	stdout    *processOutput
+	stderr    *processOutput
+	exited    chan struct{} // Closed when the process has exited
 	mu        sync.Mutex
 }
 
-var globalProcessManager = &ProcessManager{
-	processes: make(map[string]*BackgroundProcess),
+// ProcessInfo describes a background process.
+type ProcessInfo struct {
+	ID       string
+	Command  string
+	Dir      string
+	PID      int
+	Started  time.Time
+	Done     bool
+	ExitCode int
+}
+
+// NewProcessManager creates a manager with no processes.
+func NewProcessManager() *ProcessManager {
+	return &ProcessManager{processes: make(map[string]*BackgroundProcess)}
 }
 
-// StartBackgroundProcess starts a process in the background.
+// StartProcess starts a process in the background.
 func (pm *ProcessManager) StartProcess(id, command, workingDir string) error {
+	_, err := pm.start(id, "bash", command, workingDir, nil)
+	return err
+}
+
+// start runs command with shell in the profile's environment. An empty id
+// names the process after how many have been started.
+func (pm *ProcessManager) start(id, shell, command, workingDir string, profile *ShellProfile) (*BackgroundProcess, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	if id == "" {
+		id = fmt.Sprintf("bg-%d", pm.started+1)
+	}
 	if _, exists := pm.processes[id]; exists {
-		return fmt.Errorf("process %s already exists", id)
+		return nil, fmt.Errorf("process %s already exists", id)
+	}
+	running := 0
+	for _, proc := range pm.processes {
+		if !proc.finished() {
+			running++
+		}
+	}
+	if running >= maxBackgroundProcesses {
+		return nil, fmt.Errorf("%d background processes are already running; kill one first", running)
 	}
 
-	cmd := exec.Command("bash", "-c", command)
+	script := profile.wrap(shell, command, workingDir)
+	var cmd *exec.Cmd
+	switch shell {
+	case "bash", "zsh", "sh":
+		cmd = exec.Command(shell, "-c", script)
+	case "pwsh", "powershell":
+		cmd = exec.Command("pwsh", "-Command", script)
+	default:
+		return nil, fmt.Errorf("unsupported shell: %s", shell)
+	}
 	if workingDir != "" {
 		cmd.Dir = workingDir
 	}
-
-	stdout := &bytes.Buffer{}
-	stderr := &bytes.Buffer{}
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start process: %w", err)
-	}
+	cmd.Env = profile.environ()
 
 	proc := &BackgroundProcess{
 		ID:        id,
 		Command:   command,
+		Dir:       workingDir,
 		Cmd:       cmd,
 		StartTime: time.Now(),
-		Stdout:    stdout,
-		Stderr:    stderr,
-		Done:      false,
+		stdout:    &processOutput{},
+		stderr:    &processOutput{},
+		exited:    make(chan struct{}),
 	}
+	cmd.Stdout = proc.stdout
+	cmd.Stderr = proc.stderr
+	// Its own process group, so it can be stopped along with its children
+	// and ^C in the terminal doesn't reach it
+	setSession(cmd, false)
+	// Children left running, such as a daemonized server, would otherwise
+	// hold the output open and keep Wait from returning
+	cmd.WaitDelay = time.Second
 
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start process: %w", err)
+	}
+
+	pm.started++
 	pm.processes[id] = proc
 
 	// Monitor process in background
 	go func() {
 		err := cmd.Wait()
 		proc.mu.Lock()
-		defer proc.mu.Unlock()
-
 		proc.Done = true
 		proc.Error = err
 		if cmd.ProcessState != nil {
 			proc.ExitCode = cmd.ProcessState.ExitCode()
 		}
+		proc.mu.Unlock()
+		close(proc.exited)
 	}()
 
-	return nil
+	return proc, nil
 }
 
 // GetProcessOutput gets the output of a background process.
 func (pm *ProcessManager) GetProcessOutput(id string, filter *regexp.Regexp) (string, string, bool, error) {
-	pm.mu.RLock()
-	proc, exists := pm.processes[id]
-	pm.mu.RUnlock()
-
-	if !exists {
-		return "", "", false, fmt.Errorf("process %s not found", id)
+	proc, err := pm.get(id)
+	if err != nil {
+		return "", "", false, err
 	}
 
-	proc.mu.Lock()
-	defer proc.mu.Unlock()
-
-	stdout := proc.Stdout.String()
-	stderr := proc.Stderr.String()
+	stdout := proc.stdout.String()
+	stderr := proc.stderr.String()
 
 	// Apply filter if provided
 	if filter != nil {
@@ -110,48 +168,198 @@ func (pm *ProcessManager) GetProcessOutput(id string, filter *regexp.Regexp) (st
 		stderr = filterOutput(stderr, filter)
 	}
 
-	return stdout, stderr, proc.Done, nil
+	return stdout, stderr, proc.finished(), nil
+}
+
+// ReadNew returns the output of a background process since it was last
+// read, and whether older output had to be dropped before it was.
+func (pm *ProcessManager) ReadNew(id string) (string, string, bool, error) {
+	proc, err := pm.get(id)
+	if err != nil {
+		return "", "", false, err
+	}
+	stdout, droppedOut := proc.stdout.unread()
+	stderr, droppedErr := proc.stderr.unread()
+	return stdout, stderr, droppedOut || droppedErr, nil
 }
 
-// KillProcess kills a background process.
+// KillProcess stops a background process and everything it started: it is
+// asked to exit, then killed if it hasn't within a few seconds. A process
+// that has already exited is only forgotten.
 func (pm *ProcessManager) KillProcess(id string) error {
 	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
 	proc, exists := pm.processes[id]
+	delete(pm.processes, id)
+	pm.mu.Unlock()
+
 	if !exists {
 		return fmt.Errorf("process %s not found", id)
 	}
+	return stop([]*BackgroundProcess{proc})
+}
+
+// ListProcesses lists all background processes.
+func (pm *ProcessManager) ListProcesses() []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	ids := make([]string, 0, len(pm.processes))
+	for id := range pm.processes {
+		ids = append(ids, id)
+	}
+	return ids
+}
 
-	proc.mu.Lock()
-	defer proc.mu.Unlock()
+// Processes describes the background processes, oldest first.
+func (pm *ProcessManager) Processes() []ProcessInfo {
+	pm.mu.RLock()
+	list := make([]ProcessInfo, 0, len(pm.processes))
+	for _, proc := range pm.processes {
+		list = append(list, proc.info())
+	}
+	pm.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Started.Before(list[j].Started) })
+	return list
+}
 
-	if proc.Done {
-		return fmt.Errorf("process %s already finished", id)
+// Info describes a background process.
+func (pm *ProcessManager) Info(id string) (ProcessInfo, error) {
+	proc, err := pm.get(id)
+	if err != nil {
+		return ProcessInfo{}, err
 	}
+	return proc.info(), nil
+}
 
-	if proc.Cmd.Process != nil {
-		if err := proc.Cmd.Process.Kill(); err != nil {
-			return fmt.Errorf("failed to kill process: %w", err)
-		}
+// Close stops every background process, for the end of a session.
+func (pm *ProcessManager) Close() error {
+	pm.mu.Lock()
+	procs := make([]*BackgroundProcess, 0, len(pm.processes))
+	for _, proc := range pm.processes {
+		procs = append(procs, proc)
 	}
+	pm.processes = make(map[string]*BackgroundProcess)
+	pm.mu.Unlock()
 
-	proc.Done = true
-	delete(pm.processes, id)
+	return stop(procs)
+}
 
-	return nil
+// forget drops a process that has exited, so its name can be reused.
+func (pm *ProcessManager) forget(id string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if proc, ok := pm.processes[id]; ok && proc.finished() {
+		delete(pm.processes, id)
+	}
 }
 
-// ListProcesses lists all background processes.
-func (pm *ProcessManager) ListProcesses() []string {
+// get returns a background process.
+func (pm *ProcessManager) get(id string) (*BackgroundProcess, error) {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
+	proc, exists := pm.processes[id]
+	if !exists {
+		return nil, fmt.Errorf("process %s not found", id)
+	}
+	return proc, nil
+}
 
-	ids := make([]string, 0, len(pm.processes))
-	for id := range pm.processes {
-		ids = append(ids, id)
+// stop asks procs to exit and kills those still running after killGrace.
+func stop(procs []*BackgroundProcess) error {
+	for _, proc := range procs {
+		if !proc.finished() {
+			_ = terminateGroup(proc.Cmd)
+		}
 	}
-	return ids
+
+	var errs []string
+	deadline := time.After(killGrace)
+	for _, proc := range procs {
+		select {
+		case <-proc.exited:
+			continue
+		case <-deadline:
+		}
+		if err := killGroup(proc.Cmd); err != nil {
+			errs = append(errs, fmt.Sprintf("process %s: %v", proc.ID, err))
+			continue
+		}
+		select {
+		case <-proc.exited:
+		case <-time.After(2 * time.Second):
+			errs = append(errs, fmt.Sprintf("process %s did not exit", proc.ID))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to kill process: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// finished reports whether the process has exited.
+func (p *BackgroundProcess) finished() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Done
+}
+
+// info describes the process.
+func (p *BackgroundProcess) info() ProcessInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := ProcessInfo{
+		ID:       p.ID,
+		Command:  p.Command,
+		Dir:      p.Dir,
+		Started:  p.StartTime,
+		Done:     p.Done,
+		ExitCode: p.ExitCode,
+	}
+	if p.Cmd.Process != nil {
+		info.PID = p.Cmd.Process.Pid
+	}
+	return info
+}
+
+// processOutput collects an output stream of a background process,
+// keeping the latest maxProcessOutput bytes.
+type processOutput struct {
+	mu      sync.Mutex
+	buf     []byte
+	written int64 // Bytes written in all, including those dropped
+	read    int64 // Offset in written up to which output has been read
+}
+
+// Write appends p, dropping the oldest output beyond the limit.
+func (o *processOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, p...)
+	if len(o.buf) > maxProcessOutput {
+		o.buf = append([]byte(nil), o.buf[len(o.buf)-maxProcessOutput:]...)
+	}
+	o.written += int64(len(p))
+	return len(p), nil
+}
+
+// String returns the output kept.
+func (o *processOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return string(o.buf)
+}
+
+// unread returns the output written since the last call, and whether some
+// of it was dropped before it could be read.
+func (o *processOutput) unread() (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.written - int64(len(o.buf)) // Offset of buf[0]
+	dropped := o.read < kept
+	from := max(o.read, kept) - kept
+	o.read = o.written
+	return string(o.buf[from:]), dropped
 }
 
 // filterOutput filters output lines by regex.
@@ -170,12 +378,23 @@ func filterOutput(output string, filter *regexp.Regexp) string {
 	return result.String()
 }
 
+// tailOutput keeps the last maxLen bytes of output.
+func tailOutput(output string, maxLen int) string {
+	if len(output) <= maxLen {
+		return output
+	}
+	return "... (earlier output truncated)\n" + output[len(output)-maxLen:]
+}
+
 // ProcessOutputTool implements getting process output.
-type ProcessOutputTool struct{}
+type ProcessOutputTool struct {
+	processes *ProcessManager
+}
 
-// NewProcessOutputTool creates a new ProcessOutput tool.
-func NewProcessOutputTool() *ProcessOutputTool {
-	return &ProcessOutputTool{}
+// NewProcessOutputTool creates a new ProcessOutput tool reading the
+// processes of processes.
+func NewProcessOutputTool(processes *ProcessManager) *ProcessOutputTool {
+	return &ProcessOutputTool{processes: processes}
 }
 
 // Name returns the tool name.
@@ -185,7 +404,7 @@ func (t *ProcessOutputTool) Name() string {
 
 // Description returns the tool description.
 func (t *ProcessOutputTool) Description() string {
-	return "Gets output from a background process"
+	return "Gets the output a background process started with bash_background printed since it was last read, and whether it is still running"
 }
 
 // Parameters returns the tool parameters.
@@ -204,6 +423,13 @@ func (t *ProcessOutputTool) Parameters() []tools.Parameter {
 			Description: "Regex filter for output lines",
 			Default:     "",
 		},
+		{
+			Name:        "all",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Return all the output kept (the last 1MB of each stream) rather than only what is new since the last read",
+			Default:     false,
+		},
 	}
 }
 
@@ -214,12 +440,10 @@ func (t *ProcessOutputTool) RequiresPermission() bool {
 
 // Execute gets process output.
 func (t *ProcessOutputTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
-	processID := params["process_id"].(string)
-	filterStr := ""
-
-	if val, ok := params["filter"]; ok {
-		filterStr = val.(string)
-	}
+	startTime := time.Now()
+	processID, _ := params["process_id"].(string)
+	filterStr, _ := params["filter"].(string)
+	all, _ := params["all"].(bool)
 
 	var filter *regexp.Regexp
 	var err error
@@ -227,31 +451,57 @@ func (t *ProcessOutputTool) Execute(ctx context.Context, params map[string]inter
 		filter, err = regexp.Compile(filterStr)
 		if err != nil {
 			return &tools.Result{
-				Success: false,
-				Error:   fmt.Errorf("invalid filter regex: %w", err),
+				Success:  false,
+				Error:    fmt.Errorf("invalid filter regex: %w", err),
+				Duration: time.Since(startTime),
 			}, nil
 		}
 	}
 
-	stdout, stderr, done, err := globalProcessManager.GetProcessOutput(processID, filter)
+	// Read the state first, so output printed before an exit isn't missed
+	info, err := t.processes.Info(processID)
+	var stdout, stderr string
+	dropped := false
+	if err == nil {
+		if all {
+			stdout, stderr, _, err = t.processes.GetProcessOutput(processID, nil)
+		} else {
+			stdout, stderr, dropped, err = t.processes.ReadNew(processID)
+		}
+	}
 	if err != nil {
 		return &tools.Result{
-			Success: false,
-			Error:   err,
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
 		}, nil
 	}
+	if filter != nil {
+		stdout, stderr = filterOutput(stdout, filter), filterOutput(stderr, filter)
+	}
+
+	const maxOutputLen = 30000
+	output := map[string]interface{}{
+		"stdout":  tailOutput(stdout, maxOutputLen),
+		"stderr":  tailOutput(stderr, maxOutputLen),
+		"done":    info.Done,
+		"running": !info.Done,
+	}
+	if info.Done {
+		output["exit_code"] = info.ExitCode
+	}
+	if dropped {
+		output["note"] = "some output was dropped before it was read; read more often to see all of it"
+	}
 
 	return &tools.Result{
 		Success: true,
-		Output: map[string]interface{}{
-			"stdout": stdout,
-			"stderr": stderr,
-			"done":   done,
-		},
+		Output:  output,
 		Metadata: map[string]interface{}{
 			"process_id": processID,
-			"done":       done,
+			"done":       info.Done,
 		},
+		Duration: time.Since(startTime),
 	}, nil
 }
 
@@ -270,26 +520,29 @@ func (t *ProcessOutputTool) IsExternal() bool {
 	return false
 }
 
-// KillProcessTool implements killing a process.
-type KillProcessTool struct{}
+// ProcessKillTool implements killing a process.
+type ProcessKillTool struct {
+	processes *ProcessManager
+}
 
-// NewKillProcessTool creates a new KillProcess tool.
-func NewKillProcessTool() *KillProcessTool {
-	return &KillProcessTool{}
+// NewProcessKillTool creates a new ProcessKill tool stopping the processes
+// of processes.
+func NewProcessKillTool(processes *ProcessManager) *ProcessKillTool {
+	return &ProcessKillTool{processes: processes}
 }
 
 // Name returns the tool name.
-func (t *KillProcessTool) Name() string {
-	return "kill_process"
+func (t *ProcessKillTool) Name() string {
+	return "process_kill"
 }
 
 // Description returns the tool description.
-func (t *KillProcessTool) Description() string {
-	return "Kills a background process"
+func (t *ProcessKillTool) Description() string {
+	return "Stops a background process and the processes it started, or forgets one that has already exited"
 }
 
 // Parameters returns the tool parameters.
-func (t *KillProcessTool) Parameters() []tools.Parameter {
+func (t *ProcessKillTool) Parameters() []tools.Parameter {
 	return []tools.Parameter{
 		{
 			Name:        "process_id",
@@ -301,42 +554,138 @@ func (t *KillProcessTool) Parameters() []tools.Parameter {
 }
 
 // RequiresPermission returns true.
-func (t *KillProcessTool) RequiresPermission() bool {
+func (t *ProcessKillTool) RequiresPermission() bool {
 	return true
 }
 
+// DescribeResource names the process in the permission prompt.
+func (t *ProcessKillTool) DescribeResource(params map[string]interface{}) string {
+	processID, _ := params["process_id"].(string)
+	if info, err := t.processes.Info(processID); err == nil {
+		return fmt.Sprintf("kill background process %s (%s)", processID, info.Command)
+	}
+	return "kill background process " + processID
+}
+
 // Execute kills the process.
-func (t *KillProcessTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
-	processID := params["process_id"].(string)
+func (t *ProcessKillTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	processID, _ := params["process_id"].(string)
 
-	err := globalProcessManager.KillProcess(processID)
+	info, err := t.processes.Info(processID)
+	if err == nil {
+		err = t.processes.KillProcess(processID)
+	}
 	if err != nil {
 		return &tools.Result{
-			Success: false,
-			Error:   err,
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
 		}, nil
 	}
 
+	output := fmt.Sprintf("Process %s killed successfully", processID)
+	if info.Done {
+		output = fmt.Sprintf("Process %s had already exited with status %d and was removed", processID, info.ExitCode)
+	}
 	return &tools.Result{
 		Success: true,
-		Output:  fmt.Sprintf("Process %s killed successfully", processID),
+		Output:  output,
 		Metadata: map[string]interface{}{
 			"process_id": processID,
 		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *ProcessKillTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *ProcessKillTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false.
+func (t *ProcessKillTool) IsExternal() bool {
+	return false
+}
+
+// ProcessListTool lists the background processes.
+type ProcessListTool struct {
+	processes *ProcessManager
+}
+
+// NewProcessListTool creates a new ProcessList tool listing the processes
+// of processes.
+func NewProcessListTool(processes *ProcessManager) *ProcessListTool {
+	return &ProcessListTool{processes: processes}
+}
+
+// Name returns the tool name.
+func (t *ProcessListTool) Name() string {
+	return "process_list"
+}
+
+// Description returns the tool description.
+func (t *ProcessListTool) Description() string {
+	return "Lists the background processes started with bash_background, whether each is still running, and its command"
+}
+
+// Parameters returns the tool parameters.
+func (t *ProcessListTool) Parameters() []tools.Parameter {
+	return nil
+}
+
+// RequiresPermission returns true, as for the other process tools.
+func (t *ProcessListTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute lists the processes.
+func (t *ProcessListTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	procs := t.processes.Processes()
+
+	var b strings.Builder
+	if len(procs) == 0 {
+		b.WriteString("No background processes")
+	}
+	running := 0
+	for _, p := range procs {
+		state := fmt.Sprintf("running %s", time.Since(p.Started).Round(time.Second))
+		if p.Done {
+			state = fmt.Sprintf("exited %d", p.ExitCode)
+		} else {
+			running++
+		}
+		fmt.Fprintf(&b, "%s\tpid %d\t%s\t%s\n", p.ID, p.PID, state, p.Command)
+	}
+
+	return &tools.Result{
+		Success: true,
+		Output:  strings.TrimRight(b.String(), "\n"),
+		Metadata: map[string]interface{}{
+			"processes": len(procs),
+			"running":   running,
+		},
+		Duration: time.Since(startTime),
 	}, nil
 }
 
 // Category returns the tool category.
-func (t *KillProcessTool) Category() string {
+func (t *ProcessListTool) Category() string {
 	return "exec"
 }
 
 // Version returns the tool version.
-func (t *KillProcessTool) Version() string {
+func (t *ProcessListTool) Version() string {
 	return "1.0.0"
 }
 
 // IsExternal returns false.
-func (t *KillProcessTool) IsExternal() bool {
+func (t *ProcessListTool) IsExternal() bool {
 	return false
 }
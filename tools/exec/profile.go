@@ -0,0 +1,223 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VersionManagerAuto activates the version managers a project's files call
+// for.
+const VersionManagerAuto = "auto"
+
+// ShellProfile is the environment commands run in: the developer's shell,
+// the scripts their interactive shell would source, and the version managers
+// that pick the project's node or python.
+type ShellProfile struct {
+	Shell           string            // Shell used when a call doesn't name one; empty means bash
+	InitScripts     []string          // Sourced before each command
+	Path            []string          // Prepended to PATH, first entry first
+	Env             map[string]string // Set for each command
+	VersionManagers []string          // Activated before each command; VersionManagerAuto detects them
+}
+
+// activations are the shell snippets that activate each version manager. A
+// manager that isn't installed is skipped silently. {shell} is the shell name.
+var activations = map[string]string{
+	"nvm":   `export NVM_DIR="${NVM_DIR:-$HOME/.nvm}"; if [ -s "$NVM_DIR/nvm.sh" ]; then . "$NVM_DIR/nvm.sh"; if [ -f .nvmrc ]; then nvm use; fi; fi`,
+	"fnm":   `if command -v fnm; then eval "$(fnm env)"; fnm use; fi`,
+	"pyenv": `if command -v pyenv; then eval "$(pyenv init -)"; fi`,
+	"rbenv": `if command -v rbenv; then eval "$(rbenv init -)"; fi`,
+	"asdf":  `if [ -f "${ASDF_DIR:-$HOME/.asdf}/asdf.sh" ]; then . "${ASDF_DIR:-$HOME/.asdf}/asdf.sh"; fi`,
+	"mise":  `if command -v mise; then eval "$(mise activate {shell} --shims)"; fi`,
+	"venv":  `if [ -f .venv/bin/activate ]; then . .venv/bin/activate; elif [ -f venv/bin/activate ]; then . venv/bin/activate; fi`,
+}
+
+// VersionManagers returns the version managers a profile can activate.
+func VersionManagers() []string {
+	names := make([]string, 0, len(activations))
+	for name := range activations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// versionFiles maps project files to the version managers that read them,
+// in order of preference.
+var versionFiles = []struct {
+	file     string
+	managers []string
+}{
+	{".nvmrc", []string{"fnm", "nvm"}},
+	{".node-version", []string{"fnm", "nvm"}},
+	{".python-version", []string{"pyenv"}},
+	{".ruby-version", []string{"rbenv"}},
+	{"mise.toml", []string{"mise"}},
+	{".mise.toml", []string{"mise"}},
+	{".tool-versions", []string{"mise", "asdf"}},
+	{".venv", []string{"venv"}},
+	{"venv", []string{"venv"}},
+}
+
+// DetectVersionManagers returns the version managers the project in dir uses,
+// judged by its version files. Where several managers read a file, the first
+// one installed is picked.
+func DetectVersionManagers(dir string) []string {
+	if dir == "" {
+		dir = "."
+	}
+	var found []string
+	seen := make(map[string]bool)
+	for _, vf := range versionFiles {
+		if _, err := os.Stat(filepath.Join(dir, vf.file)); err != nil {
+			continue
+		}
+		manager := pickManager(vf.managers)
+		if manager != "" && !seen[manager] {
+			seen[manager] = true
+			found = append(found, manager)
+		}
+	}
+	return found
+}
+
+// pickManager returns the first installed manager of candidates, or the last
+// candidate if none is found on PATH (it may be a shell function, like nvm).
+func pickManager(candidates []string) string {
+	for _, name := range candidates {
+		if name == "venv" {
+			return name
+		}
+		if _, err := exec.LookPath(name); err == nil {
+			return name
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// shell returns the profile's shell, or bash.
+func (p *ShellProfile) shell() string {
+	if p == nil || p.Shell == "" {
+		return "bash"
+	}
+	return p.Shell
+}
+
+// wrap prefixes command with the profile's init scripts and version manager
+// activation for shell, run in dir. Setup output is discarded and setup
+// failures are ignored, so the command's own output and exit code are what
+// the caller sees. PowerShell commands are returned unchanged.
+func (p *ShellProfile) wrap(shell, command, dir string) string {
+	if p == nil || shell == "pwsh" || shell == "powershell" {
+		return command
+	}
+
+	var setup []string
+	for _, script := range p.InitScripts {
+		setup = append(setup, fmt.Sprintf(`if [ -f %[1]s ]; then . %[1]s; fi`, shellQuote(expandPath(script))))
+	}
+	managers := p.VersionManagers
+	if len(managers) == 1 && managers[0] == VersionManagerAuto {
+		managers = DetectVersionManagers(dir)
+	}
+	for _, name := range managers {
+		if snippet, ok := activations[name]; ok {
+			setup = append(setup, strings.ReplaceAll(snippet, "{shell}", activationShell(shell)))
+		}
+	}
+	if len(setup) == 0 {
+		return command
+	}
+
+	var b strings.Builder
+	for _, line := range setup {
+		fmt.Fprintf(&b, "{ %s; } >/dev/null 2>&1\n", line)
+	}
+	b.WriteString(command)
+	return b.String()
+}
+
+// command returns a POSIX shell command run with the profile in dir. Tools
+// that build their own commands use it; a PowerShell profile falls back to
+// bash.
+func (p *ShellProfile) command(ctx context.Context, command, dir string) *exec.Cmd {
+	shell := p.shell()
+	switch shell {
+	case "bash", "zsh", "sh":
+	default:
+		shell = "bash"
+	}
+	cmd := exec.CommandContext(ctx, shell, "-c", p.wrap(shell, command, dir))
+	cmd.Dir = dir
+	cmd.Env = p.environ()
+	return cmd
+}
+
+// environ returns the command environment: the process environment with the
+// profile's variables set and its PATH entries prepended. It returns nil,
+// meaning the unchanged process environment, if the profile sets neither.
+func (p *ShellProfile) environ() []string {
+	if p == nil || (len(p.Env) == 0 && len(p.Path) == 0) {
+		return nil
+	}
+
+	env := os.Environ()
+	set := func(key, value string) {
+		for i, kv := range env {
+			if strings.HasPrefix(kv, key+"=") {
+				env[i] = key + "=" + value
+				return
+			}
+		}
+		env = append(env, key+"="+value)
+	}
+
+	keys := make([]string, 0, len(p.Env))
+	for key := range p.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		set(key, os.ExpandEnv(p.Env[key]))
+	}
+
+	if len(p.Path) > 0 {
+		dirs := make([]string, 0, len(p.Path)+1)
+		for _, dir := range p.Path {
+			dirs = append(dirs, expandPath(dir))
+		}
+		if path := os.Getenv("PATH"); path != "" {
+			dirs = append(dirs, path)
+		}
+		set("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+	}
+	return env
+}
+
+// activationShell is the shell name version managers generate code for.
+func activationShell(shell string) string {
+	if shell == "zsh" {
+		return "zsh"
+	}
+	return "bash"
+}
+
+// expandPath expands a leading ~ and environment variables in path.
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	return os.ExpandEnv(path)
+}
+
+// shellQuote quotes s as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,32 @@
+//go:build !linux
+
+package exec
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// errNoPTY is returned where pseudo-terminals aren't supported; sessions
+// then run on pipes, which suits commands but not programs that need a
+// terminal.
+var errNoPTY = errors.New("pseudo-terminals are not supported on this platform")
+
+func openPTY() (*os.File, *os.File, error) {
+	return nil, nil, errNoPTY
+}
+
+func setSession(cmd *exec.Cmd, hasTTY bool) {}
+
+func killGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// terminateGroup kills cmd; processes can't be asked to exit everywhere.
+func terminateGroup(cmd *exec.Cmd) error {
+	return killGroup(cmd)
+}
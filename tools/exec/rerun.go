@@ -0,0 +1,129 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// PastRun is a command from the project's run history.
+type PastRun struct {
+	ID         int64
+	Command    string
+	WorkingDir string
+	Shell      string
+}
+
+// RunHistory looks up past runs by their history number.
+type RunHistory interface {
+	PastRun(id int64) (*PastRun, error)
+}
+
+// RerunTool re-executes a command from the run history, so the user can
+// say "re-run #12" without repeating the command.
+type RerunTool struct {
+	history RunHistory
+	bash    *BashTool
+}
+
+// NewRerunTool creates a rerun tool over history. Commands run in the
+// environment the Bash tool options describe.
+func NewRerunTool(history RunHistory, opts ...BashOption) *RerunTool {
+	return &RerunTool{history: history, bash: NewBashTool(opts...)}
+}
+
+// Name returns the tool name.
+func (t *RerunTool) Name() string {
+	return "rerun"
+}
+
+// Description returns the tool description.
+func (t *RerunTool) Description() string {
+	return "Re-executes a command from the run history by its number (e.g. when asked to \"re-run #12\")"
+}
+
+// Parameters returns the tool parameters.
+func (t *RerunTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "id",
+			Type:        tools.TypeInt,
+			Required:    true,
+			Description: "Run number from the history (without the #)",
+		},
+		{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Timeout in milliseconds (default: 120000, max: 600000)",
+			Default:     120000,
+		},
+	}
+}
+
+// RequiresPermission returns true as command execution requires permission.
+func (t *RerunTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute looks up the run and executes its command again.
+func (t *RerunTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	var id int64
+	switch v := params["id"].(type) {
+	case int:
+		id = int64(v)
+	case int64:
+		id = v
+	case float64:
+		id = int64(v)
+	default:
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("id must be a run number"),
+		}, nil
+	}
+
+	run, err := t.history.PastRun(id)
+	if err != nil {
+		return &tools.Result{
+			Success: false,
+			Error:   err,
+		}, nil
+	}
+
+	bashParams := map[string]interface{}{
+		"command":     run.Command,
+		"working_dir": run.WorkingDir,
+	}
+	if run.Shell != "" {
+		bashParams["shell"] = run.Shell
+	}
+	if timeout, ok := params["timeout"]; ok {
+		bashParams["timeout"] = timeout
+	}
+
+	result, err := t.bash.Execute(ctx, bashParams)
+	if result != nil {
+		if result.Metadata == nil {
+			result.Metadata = map[string]interface{}{}
+		}
+		result.Metadata["rerun_of"] = run.ID
+	}
+	return result, err
+}
+
+// Category returns the tool category.
+func (t *RerunTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *RerunTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *RerunTool) IsExternal() bool {
+	return false
+}
@@ -0,0 +1,139 @@
+package exec
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// The read-path ignore patterns keep the file tools away from secrets, but a
+// command can still print or upload them. Commands that would are flagged so
+// they need elevated permission instead of an ordinary execute grant.
+
+// exposingPrograms print, copy off the machine or upload the files they are
+// given.
+var exposingPrograms = map[string]bool{
+	"cat": true, "tac": true, "less": true, "more": true, "head": true, "tail": true,
+	"bat": true, "nl": true, "strings": true, "xxd": true, "od": true, "hexdump": true,
+	"base64": true, "grep": true, "rg": true, "awk": true, "sed": true, "cut": true,
+	"sort": true, "uniq": true, "diff": true, "jq": true, "yq": true,
+	"curl": true, "wget": true, "http": true, "scp": true, "rsync": true, "nc": true,
+	"type": true, "get-content": true, "gc": true,
+}
+
+// sensitiveFileNames are base names of files that hold credentials.
+var sensitiveFileNames = map[string]bool{
+	".netrc": true, "_netrc": true, ".npmrc": true, ".pypirc": true,
+	".pgpass": true, ".git-credentials": true, ".htpasswd": true,
+	"id_rsa": true, "id_dsa": true, "id_ecdsa": true, "id_ed25519": true,
+}
+
+// sensitiveFileSuffixes are path endings of credential files.
+var sensitiveFileSuffixes = []string{
+	".pem", ".key", ".p12", ".pfx", ".keystore", ".jks",
+	".aws/credentials", ".docker/config.json", ".kube/config",
+	".config/gcloud/credentials.db", ".config/gh/hosts.yml",
+}
+
+// envTemplateSuffixes mark .env files that hold placeholders, not secrets.
+var envTemplateSuffixes = []string{".example", ".sample", ".template", ".dist"}
+
+var (
+	// rawAuthHeader matches an auth header whose value is written out
+	// rather than taken from a variable.
+	rawAuthHeader = regexp.MustCompile(`(?i)\b(authorization:\s*(bearer|basic|token)\s+|(x-)?api-key:\s*|private-token:\s*)[^\s'"$]`)
+
+	// rawKey matches the shapes of well-known API keys and tokens.
+	rawKey = regexp.MustCompile(`\b(sk-(ant-|proj-)?[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{30,}|github_pat_[A-Za-z0-9_]{30,}|AKIA[0-9A-Z]{16}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`)
+
+	// commandSeparator splits a command line into simple commands.
+	commandSeparator = regexp.MustCompile(`\|\||&&|[|;&\n]`)
+)
+
+// sensitiveCommand returns why command would expose credentials or break
+// the git safety protocol, or "" if it would not.
+func sensitiveCommand(command string) string {
+	if rawAuthHeader.MatchString(command) {
+		return "command sends a raw credential in a request header"
+	}
+	if rawKey.MatchString(command) {
+		return "command contains a raw API key or token"
+	}
+
+	for _, segment := range commandSeparator.Split(command, -1) {
+		fields := strings.Fields(segment)
+		if reason := unsafeGitCommand(fields); reason != "" {
+			return reason
+		}
+		program := programName(fields)
+		if program == "" || !exposingPrograms[program] {
+			continue
+		}
+		for _, arg := range fields[1:] {
+			if file := sensitiveArg(arg); file != "" {
+				return fmt.Sprintf("%s would expose credential file %s", program, file)
+			}
+		}
+	}
+	return ""
+}
+
+// programName returns the program a simple command runs, skipping variable
+// assignments and privilege wrappers.
+func programName(fields []string) string {
+	for _, f := range fields {
+		switch {
+		case strings.Contains(f, "=") && !strings.HasPrefix(f, "-"):
+			continue
+		case f == "sudo" || f == "doas" || f == "command" || f == "exec" || f == "env":
+			continue
+		}
+		return strings.ToLower(path.Base(f))
+	}
+	return ""
+}
+
+// sensitiveArg returns the credential file an argument refers to: the
+// argument itself, an input redirection, or a curl-style @file upload.
+func sensitiveArg(arg string) string {
+	arg = strings.Trim(arg, `'"`)
+	if i := strings.LastIndexAny(arg, "@<"); i >= 0 {
+		arg = arg[i+1:]
+	}
+	arg = strings.Trim(arg, `'"`)
+	if arg == "" || strings.HasPrefix(arg, "-") {
+		return ""
+	}
+	if IsSensitiveFile(arg) {
+		return arg
+	}
+	return ""
+}
+
+// IsSensitiveFile reports whether p names a file that holds credentials.
+func IsSensitiveFile(p string) bool {
+	p = strings.ToLower(strings.ReplaceAll(p, `\`, "/"))
+	base := path.Base(p)
+
+	if strings.HasPrefix(base, ".env") {
+		if base == ".env" || strings.HasPrefix(base, ".env.") {
+			for _, suffix := range envTemplateSuffixes {
+				if strings.HasSuffix(base, suffix) {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+	if sensitiveFileNames[base] {
+		return true
+	}
+	for _, suffix := range sensitiveFileSuffixes {
+		if strings.HasSuffix(p, suffix) {
+			return true
+		}
+	}
+	return false
+}
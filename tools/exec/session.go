@@ -0,0 +1,565 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxShellSessions is how many sessions may be open at once
+	maxShellSessions = 8
+
+	// maxSessionBuffer is how much unread output a session keeps; older
+	// output is dropped
+	maxSessionBuffer = 1 << 20
+
+	// startupTimeout bounds the profile's setup when a session starts
+	startupTimeout = 15 * time.Second
+)
+
+// ShellSession describes a shell session.
+type ShellSession struct {
+	ID       string
+	Shell    string
+	Dir      string // Working directory after the last command
+	PID      int
+	PTY      bool   // False where sessions run on pipes
+	Command  string // Command running, empty when the shell is idle
+	Started  time.Time
+	LastUsed time.Time
+}
+
+// ShellSessions keeps the persistent shells of the shell tool. Each runs on
+// a pseudo-terminal, so the working directory, environment and any
+// interactive program it runs carry over from call to call.
+type ShellSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*shellSession
+}
+
+// NewShellSessions creates an empty set of sessions.
+func NewShellSessions() *ShellSessions {
+	return &ShellSessions{sessions: make(map[string]*shellSession)}
+}
+
+// List returns the open sessions sorted by ID.
+func (ss *ShellSessions) List() []ShellSession {
+	ss.mu.Lock()
+	sessions := make([]*shellSession, 0, len(ss.sessions))
+	for _, s := range ss.sessions {
+		sessions = append(sessions, s)
+	}
+	ss.mu.Unlock()
+
+	list := make([]ShellSession, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s.info())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Kill kills a session's shell and everything running in it.
+func (ss *ShellSessions) Kill(id string) error {
+	ss.mu.Lock()
+	s, ok := ss.sessions[id]
+	delete(ss.sessions, id)
+	ss.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("shell session %s not found", id)
+	}
+	return s.kill()
+}
+
+// Close kills every session.
+func (ss *ShellSessions) Close() error {
+	var errs []string
+	for _, s := range ss.List() {
+		if err := ss.Kill(s.ID); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to kill shell sessions: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// get returns an open session.
+func (ss *ShellSessions) get(id string) (*shellSession, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	s, ok := ss.sessions[id]
+	return s, ok
+}
+
+// open returns the session with id, starting it in dir if it isn't open.
+// It reports whether the session was started.
+func (ss *ShellSessions) open(ctx context.Context, id, shell, dir string, profile *ShellProfile) (*shellSession, bool, error) {
+	ss.mu.Lock()
+	if s, ok := ss.sessions[id]; ok {
+		ss.mu.Unlock()
+		return s, false, nil
+	}
+	if len(ss.sessions) >= maxShellSessions {
+		ss.mu.Unlock()
+		return nil, false, fmt.Errorf("%d shell sessions are open; kill one first", maxShellSessions)
+	}
+	ss.mu.Unlock()
+
+	s, err := startShell(ctx, id, shell, dir, profile)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if existing, ok := ss.sessions[id]; ok {
+		// Started concurrently by another call
+		_ = s.kill()
+		return existing, false, nil
+	}
+	ss.sessions[id] = s
+	go func() {
+		<-s.done
+		ss.mu.Lock()
+		if ss.sessions[id] == s {
+			delete(ss.sessions, id)
+		}
+		ss.mu.Unlock()
+		s.cleanup()
+	}()
+	return s, true, nil
+}
+
+// shellSession is a running shell. Commands are written to a script run
+// with ".", so they run in the shell itself, and end with a marker line
+// that reports their exit status and the working directory.
+type shellSession struct {
+	id      string
+	shell   string
+	cmd     *exec.Cmd
+	input   io.WriteCloser
+	pty     bool
+	hooked  bool   // The prompt prints the marker
+	scripts string // Directory of the command scripts
+	started time.Time
+	notify  chan struct{} // Signalled when output arrives
+	drained chan struct{} // Closed when all output has been read
+	done    chan struct{} // Closed when the shell has exited
+
+	mu       sync.Mutex
+	buf      []byte // Output not yet returned
+	dir      string
+	nonce    string // Marker of the running command, empty when idle
+	command  string
+	runs     int
+	lastUsed time.Time
+}
+
+// reply is what a session printed while a call waited.
+type reply struct {
+	output   string
+	finished bool // The command finished, with exitCode
+	exitCode int
+	exited   bool // The shell itself exited
+}
+
+// shellArgs start each shell without its startup files, which the profile
+// sources instead, and without line editing, which would echo input.
+var shellArgs = map[string][]string{
+	"bash": {"--noprofile", "--norc", "--noediting"},
+	"zsh":  {"-f", "+o", "zle"},
+	"sh":   nil,
+}
+
+// reportStatus prints the marker of the command in __bplus_nonce.
+const reportStatus = `__bplus_status=$?; if [ -n "$__bplus_nonce" ]; then printf '\036bplus:%s:%s:%s\036\n' "$__bplus_nonce" "$__bplus_status" "$PWD"; __bplus_nonce=; fi`
+
+// promptHooks print the marker from the prompt where the shell has a hook
+// for it. An interrupted command abandons the rest of its input line but
+// still returns to the prompt, so its marker isn't lost. Other shells get
+// the marker appended to the command line.
+var promptHooks = map[string]string{
+	"bash": "PROMPT_COMMAND=" + shellQuote(reportStatus),
+	"zsh":  "precmd() { " + reportStatus + "; }",
+}
+
+// startShell starts a session and runs the profile's setup in it.
+func startShell(ctx context.Context, id, shell, dir string, profile *ShellProfile) (*shellSession, error) {
+	args, ok := shellArgs[shell]
+	if !ok {
+		return nil, fmt.Errorf("unsupported shell for sessions: %s (use bash, zsh or sh)", shell)
+	}
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		dir = wd
+	}
+	scripts, err := os.MkdirTemp("", "bplus-shell-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	cmd := exec.Command(shell, args...)
+	cmd.Dir = dir
+	env := profile.environ()
+	if env == nil {
+		env = os.Environ()
+	}
+	// Pagers would wait for a key; a dumb terminal keeps colors and cursor
+	// movement out of the output
+	cmd.Env = append(env, "TERM=dumb", "PAGER=cat", "GIT_PAGER=cat", "HISTFILE=/dev/null", "PS1=", "PS2=")
+
+	s := &shellSession{
+		id:       id,
+		shell:    shell,
+		cmd:      cmd,
+		scripts:  scripts,
+		started:  time.Now(),
+		notify:   make(chan struct{}, 1),
+		drained:  make(chan struct{}),
+		done:     make(chan struct{}),
+		dir:      dir,
+		lastUsed: time.Now(),
+	}
+
+	var output io.ReadCloser
+	var childEnd *os.File // Closed once the shell has it
+	master, tty, err := openPTY()
+	if err == nil {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+		s.input, output, childEnd, s.pty = master, master, tty, true
+	} else {
+		r, w, err := os.Pipe()
+		if err != nil {
+			os.RemoveAll(scripts)
+			return nil, fmt.Errorf("failed to create output pipe: %w", err)
+		}
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			r.Close()
+			w.Close()
+			os.RemoveAll(scripts)
+			return nil, fmt.Errorf("failed to create input pipe: %w", err)
+		}
+		cmd.Stdout, cmd.Stderr = w, w
+		s.input, output, childEnd = stdin, r, w
+	}
+	setSession(cmd, s.pty)
+
+	if err := cmd.Start(); err != nil {
+		childEnd.Close()
+		output.Close()
+		s.input.Close()
+		os.RemoveAll(scripts)
+		return nil, fmt.Errorf("failed to start %s: %w", shell, err)
+	}
+	childEnd.Close()
+
+	go s.read(output)
+	go func() {
+		_ = cmd.Wait()
+		// Output still in flight is kept, unless a process the shell left
+		// behind holds the terminal open
+		select {
+		case <-s.drained:
+		case <-time.After(100 * time.Millisecond):
+		}
+		close(s.done)
+	}()
+
+	setup := profile.wrap(shell, "stty -echo 2>/dev/null; PS1=''; PS2=''; PROMPT=''; RPROMPT=''\n"+promptHooks[shell], dir)
+	ctx, cancel := context.WithTimeout(ctx, startupTimeout)
+	defer cancel()
+	r, err := s.run(ctx, setup, startupTimeout, nil)
+	if err == nil && !r.finished {
+		err = fmt.Errorf("shell setup did not finish within %s", startupTimeout)
+	}
+	if err != nil {
+		_ = s.kill()
+		s.cleanup()
+		return nil, fmt.Errorf("failed to start shell session: %w", err)
+	}
+	s.hooked = promptHooks[shell] != ""
+	return s, nil
+}
+
+// read collects the shell's output until it closes.
+func (s *shellSession) read(r io.ReadCloser) {
+	defer close(s.drained)
+	defer r.Close()
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			s.mu.Lock()
+			s.buf = append(s.buf, chunk[:n]...)
+			if len(s.buf) > maxSessionBuffer {
+				s.buf = append([]byte(nil), s.buf[len(s.buf)-maxSessionBuffer:]...)
+			}
+			s.mu.Unlock()
+			select {
+			case s.notify <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// run runs command in the shell and waits for it to finish, for timeout
+// or for ctx.
+func (s *shellSession) run(ctx context.Context, command string, timeout time.Duration, stream func([]byte)) (reply, error) {
+	s.mu.Lock()
+	if s.nonce != "" {
+		running := s.command
+		s.mu.Unlock()
+		return reply{}, fmt.Errorf("session %s is still running %q; read its output, send it input, or interrupt it first", s.id, running)
+	}
+	s.runs++
+	script := filepath.Join(s.scripts, fmt.Sprintf("%d.sh", s.runs))
+	s.mu.Unlock()
+
+	if err := os.WriteFile(script, []byte(command+"\n"), 0o600); err != nil {
+		return reply{}, fmt.Errorf("failed to write command: %w", err)
+	}
+	nonce := newNonce()
+	line := fmt.Sprintf("__bplus_nonce=%s; . %s\n", nonce, shellQuote(script))
+	if !s.hooked {
+		line = fmt.Sprintf("__bplus_nonce=%s; . %s; %s\n", nonce, shellQuote(script), reportStatus)
+	}
+
+	s.mu.Lock()
+	s.nonce, s.command, s.lastUsed = nonce, command, time.Now()
+	s.mu.Unlock()
+	if _, err := io.WriteString(s.input, line); err != nil {
+		s.mu.Lock()
+		s.nonce, s.command = "", ""
+		s.mu.Unlock()
+		return reply{}, fmt.Errorf("failed to write to session %s: %w", s.id, err)
+	}
+	return s.wait(ctx, timeout, 0, stream)
+}
+
+// send types input into the command running in the session and waits for
+// its output to settle.
+func (s *shellSession) send(ctx context.Context, input string, timeout, settle time.Duration, stream func([]byte)) (reply, error) {
+	if !s.running() {
+		return reply{}, fmt.Errorf("nothing is running in session %s to send input to; use action run", s.id)
+	}
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+	if _, err := io.WriteString(s.input, input); err != nil {
+		return reply{}, fmt.Errorf("failed to write to session %s: %w", s.id, err)
+	}
+	return s.wait(ctx, timeout, settle, stream)
+}
+
+// interrupt sends ^C to the command running in the session.
+func (s *shellSession) interrupt(ctx context.Context, timeout, settle time.Duration) (reply, error) {
+	if !s.pty {
+		return reply{}, fmt.Errorf("interrupting needs a terminal, which this platform lacks; kill the session instead")
+	}
+	if !s.running() {
+		return reply{}, fmt.Errorf("nothing is running in session %s", s.id)
+	}
+	if _, err := io.WriteString(s.input, "\x03"); err != nil {
+		return reply{}, fmt.Errorf("failed to write to session %s: %w", s.id, err)
+	}
+	return s.wait(ctx, timeout, settle, nil)
+}
+
+// wait collects output until the running command finishes, the shell
+// exits, timeout passes or ctx is done. With settle set it also returns
+// once output has been quiet that long, for programs waiting on input.
+// stream, if set, receives the output collected so far as it arrives.
+func (s *shellSession) wait(ctx context.Context, timeout, settle time.Duration, stream func([]byte)) (reply, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var quiet <-chan time.Time
+	resetQuiet := func() {
+		if settle > 0 {
+			quiet = time.After(settle)
+		}
+	}
+	resetQuiet()
+
+	var collected []byte
+	done := s.done
+	exited := false
+	for {
+		s.mu.Lock()
+		fresh := len(s.buf)
+		collected = append(collected, s.buf...)
+		s.buf = s.buf[:0]
+		nonce := s.nonce
+		s.mu.Unlock()
+
+		if nonce != "" {
+			if r, rest, ok := finished(collected, nonce); ok {
+				s.mu.Lock()
+				s.buf = append(rest, s.buf...)
+				if s.nonce == nonce {
+					s.nonce, s.command = "", ""
+				}
+				if r.dir != "" {
+					s.dir = r.dir
+				}
+				s.mu.Unlock()
+				return r.reply, nil
+			}
+		}
+		if fresh > 0 {
+			resetQuiet()
+			if stream != nil {
+				stream(collected)
+			}
+		}
+		if exited {
+			return reply{output: cleanOutput(collected), exited: true, exitCode: s.cmd.ProcessState.ExitCode()}, nil
+		}
+
+		select {
+		case <-s.notify:
+		case <-done:
+			exited, done = true, nil
+		case <-quiet:
+			return reply{output: cleanOutput(collected)}, nil
+		case <-deadline.C:
+			return reply{output: cleanOutput(collected)}, nil
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.buf = append(collected, s.buf...)
+			s.mu.Unlock()
+			return reply{}, ctx.Err()
+		}
+	}
+}
+
+// markedReply is a finished command's reply and the directory it left the
+// shell in.
+type markedReply struct {
+	reply
+	dir string
+}
+
+// finished looks for the marker a command ends with in output, returning
+// the command's reply and the output after the marker.
+func finished(output []byte, nonce string) (markedReply, []byte, bool) {
+	prefix := []byte("\x1ebplus:" + nonce + ":")
+	start := bytes.Index(output, prefix)
+	if start < 0 {
+		return markedReply{}, nil, false
+	}
+	end := bytes.IndexByte(output[start+len(prefix):], '\x1e')
+	if end < 0 {
+		return markedReply{}, nil, false
+	}
+	fields := string(output[start+len(prefix) : start+len(prefix)+end])
+	rest := output[start+len(prefix)+end+1:]
+	rest = bytes.TrimPrefix(bytes.TrimPrefix(rest, []byte("\r")), []byte("\n"))
+
+	status, dir, _ := strings.Cut(fields, ":")
+	code, _ := strconv.Atoi(status)
+	return markedReply{
+		reply: reply{output: cleanOutput(output[:start]), finished: true, exitCode: code},
+		dir:   dir,
+	}, append([]byte(nil), rest...), true
+}
+
+// running reports whether a command is running in the session.
+func (s *shellSession) running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nonce != ""
+}
+
+// info describes the session.
+func (s *shellSession) info() ShellSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := ShellSession{
+		ID:       s.id,
+		Shell:    s.shell,
+		Dir:      s.dir,
+		PTY:      s.pty,
+		Command:  s.command,
+		Started:  s.started,
+		LastUsed: s.lastUsed,
+	}
+	if s.cmd.Process != nil {
+		info.PID = s.cmd.Process.Pid
+	}
+	return info
+}
+
+// kill kills the shell and what runs in it, and waits for it to exit.
+func (s *shellSession) kill() error {
+	err := killGroup(s.cmd)
+	s.input.Close()
+	select {
+	case <-s.done:
+	case <-time.After(2 * time.Second):
+		return fmt.Errorf("shell session %s did not exit", s.id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to kill shell session %s: %w", s.id, err)
+	}
+	return nil
+}
+
+// cleanup removes the session's command scripts.
+func (s *shellSession) cleanup() {
+	os.RemoveAll(s.scripts)
+}
+
+// newNonce returns a random marker for a command.
+func newNonce() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// terminalEscapes matches the color, cursor and title sequences programs
+// write even to dumb terminals.
+var terminalEscapes = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()][0-9A-Za-z]|\x1b[=>78]`)
+
+// cleanOutput turns terminal output into text: escape sequences are
+// removed, and a line redrawn after a carriage return, as progress bars
+// are, keeps only what was drawn last.
+func cleanOutput(output []byte) string {
+	text := terminalEscapes.ReplaceAllString(string(output), "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if j := strings.LastIndexByte(strings.TrimRight(line, "\r"), '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = strings.Map(func(r rune) rune {
+			if r < ' ' && r != '\t' {
+				return -1
+			}
+			return r
+		}, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,353 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Shell actions
+const (
+	shellRun       = "run"
+	shellSend      = "send"
+	shellRead      = "read"
+	shellInterrupt = "interrupt"
+	shellKill      = "kill"
+	shellList      = "list"
+)
+
+const (
+	// defaultSession is the session calls use when they don't name one
+	defaultSession = "default"
+
+	// settleTime is how long output must be quiet before send, read and
+	// interrupt return while a program keeps running, such as a REPL
+	// waiting at its prompt
+	settleTime = 500 * time.Millisecond
+
+	// streamBytes is how much of the latest output is streamed as progress
+	streamBytes = 4096
+)
+
+// ShellTool runs commands in persistent shell sessions. Unlike the bash
+// tool, cd and exports carry over between calls, and interactive programs
+// such as REPLs and prompts can be driven by sending them input.
+type ShellTool struct {
+	sessions *ShellSessions
+	profile  *ShellProfile
+}
+
+// NewShellTool creates a new Shell tool whose sessions are kept in
+// sessions, so they can be listed and killed outside of tool calls.
+func NewShellTool(sessions *ShellSessions, opts ...BashOption) *ShellTool {
+	return &ShellTool{sessions: sessions, profile: NewBashTool(opts...).profile}
+}
+
+// Name returns the tool name.
+func (t *ShellTool) Name() string {
+	return "shell"
+}
+
+// Description returns the tool description.
+func (t *ShellTool) Description() string {
+	return "Runs commands in a persistent terminal session that keeps its working directory and environment between calls. " +
+		"A command still running when the timeout passes keeps running: read its output later, send it input (for REPLs and prompts), or interrupt it. " +
+		"Kill sessions that are no longer needed."
+}
+
+// Parameters returns the tool parameters.
+func (t *ShellTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "action",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "What to do: run a command, send input to the running program, read new output, interrupt (^C) the running program, kill the session, or list sessions (default: run)",
+			Default:     shellRun,
+			Validation:  &tools.Validation{Enum: []string{shellRun, shellSend, shellRead, shellInterrupt, shellKill, shellList}},
+		},
+		{
+			Name:        "session",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Session to use; it is started on first use (default: default)",
+			Default:     defaultSession,
+		},
+		{
+			Name:        "command",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Command to run, for action run",
+		},
+		{
+			Name:        "input",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Text to type into the running program, for action send; end it with \\n to press enter",
+		},
+		{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Milliseconds to wait for output (default: 120000 for run, 10000 otherwise; max: 600000)",
+		},
+		{
+			Name:        "working_dir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Directory a new session starts in; later calls use the session's own directory",
+		},
+		{
+			Name:        "shell",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Shell a new session runs (bash, zsh, sh)",
+			Default:     t.profile.shell(),
+		},
+	}
+}
+
+// RequiresPermission returns true as command execution requires permission.
+func (t *ShellTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the session and command in the permission prompt.
+func (t *ShellTool) DescribeResource(params map[string]interface{}) string {
+	action, id := shellAction(params), shellSessionID(params)
+	switch action {
+	case shellRun:
+		command, _ := params["command"].(string)
+		return fmt.Sprintf("run %s in shell session %s", command, id)
+	case shellList:
+		return "list shell sessions"
+	default:
+		return fmt.Sprintf("%s shell session %s", action, id)
+	}
+}
+
+// SensitiveReason reports commands and input that would print or upload
+// credentials, or break the git safety protocol, as for the bash tool.
+func (t *ShellTool) SensitiveReason(params map[string]interface{}) string {
+	text, _ := params["command"].(string)
+	if shellAction(params) == shellSend {
+		text, _ = params["input"].(string)
+	}
+	return sensitiveCommand(text)
+}
+
+// Execute carries out the action.
+func (t *ShellTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	action, id := shellAction(params), shellSessionID(params)
+
+	fail := func(err error) (*tools.Result, error) {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Metadata: map[string]interface{}{"session": id, "action": action},
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	switch action {
+	case shellList:
+		return t.list(startTime), nil
+	case shellKill:
+		if err := t.sessions.Kill(id); err != nil {
+			return fail(err)
+		}
+		return &tools.Result{
+			Success:  true,
+			Output:   fmt.Sprintf("Shell session %s killed", id),
+			Metadata: map[string]interface{}{"session": id, "action": action},
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	timeout := 10 * time.Second
+	if action == shellRun {
+		timeout = 120 * time.Second
+	}
+	switch v := params["timeout"].(type) {
+	case int:
+		timeout = time.Duration(v) * time.Millisecond
+	case float64:
+		timeout = time.Duration(v) * time.Millisecond
+	}
+	timeout = min(max(timeout, time.Second), 600*time.Second)
+
+	// Stream the tail of the output to whoever is watching the call
+	stream := func(output []byte) {
+		if len(output) > streamBytes {
+			output = output[len(output)-streamBytes:]
+		}
+		tools.ReportProgress(ctx, tools.Progress{Output: cleanOutput(output)})
+	}
+
+	var s *shellSession
+	created := false
+	var r reply
+	var err error
+	switch action {
+	case shellRun:
+		command, _ := params["command"].(string)
+		if strings.TrimSpace(command) == "" {
+			return fail(fmt.Errorf("command is required to run"))
+		}
+		if isDangerousCommand(command) {
+			return fail(fmt.Errorf("command blocked: potentially dangerous operation detected"))
+		}
+		shell, _ := params["shell"].(string)
+		if shell == "" {
+			shell = t.profile.shell()
+		}
+		dir, _ := params["working_dir"].(string)
+		s, created, err = t.sessions.open(ctx, id, shell, dir, t.profile)
+		if err != nil {
+			return fail(err)
+		}
+		r, err = s.run(ctx, command, timeout, stream)
+	case shellSend, shellRead, shellInterrupt:
+		var ok bool
+		if s, ok = t.sessions.get(id); !ok {
+			return fail(fmt.Errorf("shell session %s not found; start it with action run", id))
+		}
+		switch action {
+		case shellSend:
+			input, _ := params["input"].(string)
+			if input == "" {
+				return fail(fmt.Errorf("input is required to send"))
+			}
+			if isDangerousCommand(input) {
+				return fail(fmt.Errorf("input blocked: potentially dangerous operation detected"))
+			}
+			r, err = s.send(ctx, input, timeout, settleTime, stream)
+		case shellRead:
+			r, err = s.wait(ctx, timeout, settleTime, stream)
+		case shellInterrupt:
+			r, err = s.interrupt(ctx, timeout, settleTime)
+		}
+	default:
+		return fail(fmt.Errorf("unknown action: %s", action))
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// Don't leave the command running after the call it belonged to
+			if s != nil && s.pty && s.running() {
+				_, _ = s.input.Write([]byte("\x03"))
+			}
+			return fail(fmt.Errorf("cancelled; the running command was interrupted"))
+		}
+		return fail(err)
+	}
+
+	return t.result(s, action, created, r, timeout, startTime), nil
+}
+
+// result reports what a session printed.
+func (t *ShellTool) result(s *shellSession, action string, created bool, r reply, timeout time.Duration, startTime time.Time) *tools.Result {
+	info := s.info()
+	output := r.output
+	const maxOutputLen = 30000
+	if len(output) > maxOutputLen {
+		output = "... (earlier output truncated)\n" + output[len(output)-maxOutputLen:]
+	}
+
+	result := &tools.Result{
+		Success: true,
+		Metadata: map[string]interface{}{
+			"session":     info.ID,
+			"action":      action,
+			"created":     created,
+			"shell":       info.Shell,
+			"working_dir": info.Dir,
+			"pty":         info.PTY,
+			"running":     !r.finished && !r.exited,
+		},
+		Duration: time.Since(startTime),
+	}
+	switch {
+	case r.exited:
+		result.Metadata["exit_code"] = r.exitCode
+		output += fmt.Sprintf("\n(shell exited with status %d; the session is closed)", r.exitCode)
+	case r.finished:
+		result.Metadata["exit_code"] = r.exitCode
+		// An interrupted command's status is the expected outcome
+		if r.exitCode != 0 && action != shellInterrupt {
+			result.Success = false
+			result.Error = fmt.Errorf("command exited with status %d", r.exitCode)
+			if dep := DetectMissingDependency(r.output, info.Dir); dep != nil {
+				result.Metadata["missing_dependency"] = dep
+				result.Error = fmt.Errorf("command exited with status %d (%s)", r.exitCode, dep.Hint())
+			}
+		}
+	case info.Command != "" && (action == shellRun || time.Since(startTime) >= timeout):
+		output += fmt.Sprintf("\n(still running after %s; read to wait for more output, send to type input, or interrupt to stop it)", timeout.Round(time.Second))
+	case info.Command != "":
+		output += "\n(still running, waiting for input or output)"
+	}
+	result.Output = output
+	return result
+}
+
+// list reports the open sessions.
+func (t *ShellTool) list(startTime time.Time) *tools.Result {
+	sessions := t.sessions.List()
+	var b strings.Builder
+	if len(sessions) == 0 {
+		b.WriteString("No shell sessions are open")
+	}
+	for _, s := range sessions {
+		state := "idle"
+		if s.Command != "" {
+			state = "running " + s.Command
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\n", s.ID, s.Shell, s.Dir, state)
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  strings.TrimRight(b.String(), "\n"),
+		Metadata: map[string]interface{}{
+			"action":   shellList,
+			"sessions": len(sessions),
+		},
+		Duration: time.Since(startTime),
+	}
+}
+
+// shellAction returns a call's action, run by default.
+func shellAction(params map[string]interface{}) string {
+	if action, _ := params["action"].(string); action != "" {
+		return action
+	}
+	return shellRun
+}
+
+// shellSessionID returns a call's session, the default one if it names
+// none.
+func shellSessionID(params map[string]interface{}) string {
+	if id, _ := params["session"].(string); id != "" {
+		return id
+	}
+	return defaultSession
+}
+
+// Category returns the tool category.
+func (t *ShellTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *ShellTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *ShellTool) IsExternal() bool {
+	return false
+}
@@ -0,0 +1,209 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+const (
+	// defaultTestTimeout is how long a test run may take unless the call
+	// says otherwise.
+	defaultTestTimeout = 10 * time.Minute
+
+	// maxTestTimeout caps the timeout a call may ask for.
+	maxTestTimeout = 30 * time.Minute
+)
+
+// TestTool runs a project's tests with the framework it uses and reports
+// each test's outcome.
+type TestTool struct {
+	profile *ShellProfile
+}
+
+// NewTestTool creates a new Test tool.
+func NewTestTool(opts ...BashOption) *TestTool {
+	bash := NewBashTool(opts...)
+	return &TestTool{profile: bash.profile}
+}
+
+// Name returns the tool name.
+func (t *TestTool) Name() string {
+	return "test"
+}
+
+// Description returns the tool description.
+func (t *TestTool) Description() string {
+	return "Runs the project's tests with its framework (go test, pytest, jest or cargo test, detected from the project), optionally only some " +
+		"packages, files or test names, and reports which tests passed and failed with the output of each failure, and coverage if asked"
+}
+
+// Parameters returns the tool parameters.
+func (t *TestTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "target",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "What to test, space-separated: Go packages (./pkg/...), pytest files or node IDs, jest path patterns, or cargo packages (default: everything)",
+			Default:     "",
+		},
+		{
+			Name:        "run",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Only tests whose names match: a regex for go test -run, an expression for pytest -k, a pattern for jest -t, a filter for cargo",
+			Default:     "",
+		},
+		{
+			Name:        "framework",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "go, pytest, jest or cargo (default: detected from the working directory)",
+			Default:     "",
+		},
+		{
+			Name:        "coverage",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Measure coverage (pytest needs pytest-cov; not supported for cargo)",
+			Default:     false,
+		},
+		{
+			Name:        "working_dir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Project directory to run the tests in (default: current directory)",
+			Default:     "",
+		},
+		{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Timeout in seconds (default: 600, max: 1800)",
+			Default:     int(defaultTestTimeout / time.Second),
+		},
+	}
+}
+
+// RequiresPermission returns true as running tests executes project code.
+func (t *TestTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute runs the tests.
+func (t *TestTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	fail := func(err error, metadata map[string]interface{}) (*tools.Result, error) {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Metadata: metadata,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	workingDir, _ := params["working_dir"].(string)
+	dir := workingDir
+	if dir == "" {
+		dir = "."
+	}
+	framework, _ := params["framework"].(string)
+	if framework == "" {
+		if framework = DetectTestFramework(dir); framework == "" {
+			return fail(fmt.Errorf("no test framework detected in %s (looked for go.mod, Cargo.toml, jest in package.json, and pytest configuration); pass framework", dir), nil)
+		}
+	}
+	target, _ := params["target"].(string)
+	run, _ := params["run"].(string)
+	coverage, _ := params["coverage"].(bool)
+
+	timeout := defaultTestTimeout
+	switch v := params["timeout"].(type) {
+	case int:
+		timeout = time.Duration(v) * time.Second
+	case float64:
+		timeout = time.Duration(v) * time.Second
+	}
+	timeout = min(max(timeout, time.Second), maxTestTimeout)
+
+	tmp, err := os.MkdirTemp("", "bplus-test-*")
+	if err != nil {
+		return fail(err, nil)
+	}
+	defer os.RemoveAll(tmp)
+
+	tc, err := buildTestCommand(framework, strings.Fields(target), run, coverage, tmp)
+	if err != nil {
+		return fail(err, nil)
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := t.profile.command(cmdCtx, tc.command, workingDir)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	exitCode := cmd.ProcessState.ExitCode()
+
+	metadata := map[string]interface{}{
+		"framework":   framework,
+		"command":     tc.command,
+		"working_dir": workingDir,
+		"exit_code":   exitCode,
+	}
+	if cmdCtx.Err() == context.DeadlineExceeded {
+		return fail(fmt.Errorf("tests timed out after %s", timeout), metadata)
+	}
+
+	result := parseTestRun(framework, tc, stdout.String(), stderr.String())
+	passed, failed, skipped := result.Counts()
+	metadata["tests"] = result.Tests
+	metadata["passed"] = passed
+	metadata["failed"] = failed
+	metadata["skipped"] = skipped
+	if result.Coverage >= 0 {
+		metadata["coverage"] = result.Coverage
+	}
+
+	if len(result.Tests) == 0 && runErr != nil {
+		// Nothing ran: the build failed or the framework isn't installed
+		err := fmt.Errorf("tests did not run: %w", runErr)
+		if dep := DetectMissingDependency(stderr.String()+"\n"+stdout.String(), workingDir); dep != nil {
+			metadata["missing_dependency"] = dep
+			err = fmt.Errorf("tests did not run: %w (%s)", runErr, dep.Hint())
+		}
+		if result.Errors != "" {
+			err = fmt.Errorf("%w\n%s", err, tail(result.Errors, maxFailureOutput))
+		}
+		return fail(err, metadata)
+	}
+
+	return &tools.Result{
+		Success:  true,
+		Output:   formatTestRun(framework, tc.command, result, time.Since(startTime)),
+		Metadata: metadata,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *TestTool) Category() string {
+	return "exec"
+}
+
+// Version returns the tool version.
+func (t *TestTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *TestTool) IsExternal() bool {
+	return false
+}
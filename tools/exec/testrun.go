@@ -0,0 +1,645 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Test frameworks core.test knows how to run and read.
+const (
+	FrameworkGo     = "go"
+	FrameworkPytest = "pytest"
+	FrameworkJest   = "jest"
+	FrameworkCargo  = "cargo"
+)
+
+// Test outcomes.
+const (
+	TestPassed  = "passed"
+	TestFailed  = "failed"
+	TestSkipped = "skipped"
+)
+
+// TestCase is the outcome of one test.
+type TestCase struct {
+	Name     string        `json:"name"`           // Fully qualified, e.g. "pkg/ui.TestSnapshot/80x24"
+	Status   string        `json:"status"`         // TestPassed, TestFailed or TestSkipped
+	Path     string        `json:"path,omitempty"` // File the test is defined in, if known
+	Line     int           `json:"line,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Output   string        `json:"output,omitempty"` // What a failing test printed
+}
+
+// TestRun is the parsed outcome of a test command.
+type TestRun struct {
+	Tests    []TestCase
+	Coverage float64 // Percent of statements or lines covered; -1 if not measured
+	Errors   string  // Output explaining why tests could not run, such as build errors
+}
+
+// Counts returns how many tests passed, failed and were skipped.
+func (r *TestRun) Counts() (passed, failed, skipped int) {
+	for _, t := range r.Tests {
+		switch t.Status {
+		case TestPassed:
+			passed++
+		case TestFailed:
+			failed++
+		default:
+			skipped++
+		}
+	}
+	return passed, failed, skipped
+}
+
+// DetectTestFramework returns the test framework of the project in dir,
+// or "" if none is recognized.
+func DetectTestFramework(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return FrameworkGo
+	case exists("Cargo.toml"):
+		return FrameworkCargo
+	case usesJest(dir):
+		return FrameworkJest
+	case exists("pytest.ini") || exists("conftest.py") || exists("pyproject.toml") || exists("setup.py") ||
+		exists("setup.cfg") || exists("tox.ini"):
+		return FrameworkPytest
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "test_*.py")); len(matches) > 0 {
+		return FrameworkPytest
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "tests", "test_*.py")); len(matches) > 0 {
+		return FrameworkPytest
+	}
+	return ""
+}
+
+// usesJest reports whether the package in dir is tested with jest.
+func usesJest(dir string) bool {
+	if matches, _ := filepath.Glob(filepath.Join(dir, "jest.config.*")); len(matches) > 0 {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+		Jest            json.RawMessage   `json:"jest"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return false
+	}
+	_, dep := pkg.Dependencies["jest"]
+	_, devDep := pkg.DevDependencies["jest"]
+	return dep || devDep || len(pkg.Jest) > 0 || strings.Contains(pkg.Scripts["test"], "jest")
+}
+
+// testCommand is how a framework is run.
+type testCommand struct {
+	command  string
+	report   string // File the framework writes its results to, if any
+	coverage string // File or directory coverage is written to, if measured
+}
+
+// buildTestCommand returns the command running the tests of targets whose
+// names match run, measuring coverage if asked, with scratch files in tmp.
+func buildTestCommand(framework string, targets []string, run string, coverage bool, tmp string) (testCommand, error) {
+	quoted := make([]string, len(targets))
+	for i, t := range targets {
+		quoted[i] = shellQuote(t)
+	}
+	args := []string{}
+	tc := testCommand{}
+
+	switch framework {
+	case FrameworkGo:
+		args = append(args, "go", "test", "-json")
+		if run != "" {
+			args = append(args, "-run", shellQuote(run))
+		}
+		if coverage {
+			tc.coverage = filepath.Join(tmp, "cover.out")
+			args = append(args, "-coverprofile="+shellQuote(tc.coverage))
+		}
+		if len(quoted) == 0 {
+			quoted = []string{"./..."}
+		}
+		args = append(args, quoted...)
+	case FrameworkPytest:
+		args = append(args, "python", "-m", "pytest", "-rA", "--tb=short", "--no-header", "-q")
+		if run != "" {
+			args = append(args, "-k", shellQuote(run))
+		}
+		if coverage {
+			args = append(args, "--cov", "--cov-report=term")
+		}
+		args = append(args, quoted...)
+	case FrameworkJest:
+		tc.report = filepath.Join(tmp, "jest.json")
+		args = append(args, "npx", "--no-install", "jest", "--json", "--testLocationInResults", "--outputFile="+shellQuote(tc.report))
+		if run != "" {
+			args = append(args, "-t", shellQuote(run))
+		}
+		if coverage {
+			tc.coverage = filepath.Join(tmp, "coverage")
+			args = append(args, "--coverage", "--coverageReporters=json-summary", "--coverageDirectory="+shellQuote(tc.coverage))
+		}
+		args = append(args, quoted...)
+	case FrameworkCargo:
+		args = append(args, "cargo", "test", "--no-fail-fast")
+		for _, t := range quoted {
+			args = append(args, "-p", t)
+		}
+		if run != "" {
+			args = append(args, shellQuote(run))
+		}
+	default:
+		return tc, fmt.Errorf("unsupported test framework: %s (must be go, pytest, jest or cargo)", framework)
+	}
+	tc.command = strings.Join(args, " ")
+	return tc, nil
+}
+
+// parseTestRun reads the results of a framework's run from its output and
+// the files it wrote.
+func parseTestRun(framework string, tc testCommand, stdout, stderr string) *TestRun {
+	var run *TestRun
+	switch framework {
+	case FrameworkGo:
+		run = parseGoTest(stdout, stderr)
+		if tc.coverage != "" {
+			run.Coverage = goCoverage(tc.coverage)
+		}
+	case FrameworkPytest:
+		run = parsePytest(stdout + "\n" + stderr)
+	case FrameworkJest:
+		data, _ := os.ReadFile(tc.report)
+		run = parseJest(data, stderr)
+		if tc.coverage != "" {
+			run.Coverage = jestCoverage(filepath.Join(tc.coverage, "coverage-summary.json"))
+		}
+	case FrameworkCargo:
+		run = parseCargoTest(stdout, stderr)
+	}
+	return run
+}
+
+// goTestEvent is a line of go test -json output.
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// goTestLocation finds where a failing Go test reported a failure.
+var goTestLocation = regexp.MustCompile(`(?m)^\s*(\S+_test\.go):(\d+):`)
+
+// parseGoTest reads go test -json output.
+func parseGoTest(stdout, stderr string) *TestRun {
+	run := &TestRun{Coverage: -1}
+	outputs := make(map[string]*strings.Builder)
+	pkgOutput := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool) // Packages with a failing test
+	var failedPkgs []string
+	var stray strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev goTestEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &ev) != nil {
+			stray.Write(line)
+			stray.WriteByte('\n')
+			continue
+		}
+		if ev.Test == "" {
+			switch ev.Action {
+			case "output":
+				if pkgOutput[ev.Package] == nil {
+					pkgOutput[ev.Package] = &strings.Builder{}
+				}
+				pkgOutput[ev.Package].WriteString(ev.Output)
+			case "build-output":
+				stray.WriteString(ev.Output)
+			case "fail":
+				failedPkgs = append(failedPkgs, ev.Package)
+			}
+			continue
+		}
+
+		name := ev.Package + "." + ev.Test
+		switch ev.Action {
+		case "output":
+			if outputs[name] == nil {
+				outputs[name] = &strings.Builder{}
+			}
+			outputs[name].WriteString(ev.Output)
+		case "pass", "fail", "skip":
+			tc := TestCase{Name: name, Duration: time.Duration(ev.Elapsed * float64(time.Second))}
+			switch ev.Action {
+			case "pass":
+				tc.Status = TestPassed
+			case "skip":
+				tc.Status = TestSkipped
+			default:
+				tc.Status = TestFailed
+				failedTests[ev.Package] = true
+				if out := outputs[name]; out != nil {
+					tc.Output = out.String()
+					if m := goTestLocation.FindStringSubmatch(tc.Output); m != nil {
+						tc.Path = m[1]
+						tc.Line, _ = strconv.Atoi(m[2])
+					}
+				}
+			}
+			run.Tests = append(run.Tests, tc)
+		}
+	}
+
+	// A package that failed without a failing test did not build or
+	// panicked outside a test
+	var errs []string
+	for _, pkg := range failedPkgs {
+		if failedTests[pkg] {
+			continue
+		}
+		if out := pkgOutput[pkg]; out != nil {
+			errs = append(errs, strings.TrimSpace(out.String()))
+		} else {
+			errs = append(errs, "FAIL "+pkg)
+		}
+	}
+	if s := strings.TrimSpace(stray.String() + stderr); s != "" {
+		errs = append([]string{s}, errs...)
+	}
+	if len(failedPkgs) > len(failedTests) || len(run.Tests) == 0 {
+		run.Errors = strings.Join(errs, "\n")
+	}
+	return run
+}
+
+// goCoverage returns the percentage of statements a Go cover profile
+// covers, or -1 if it can't be read.
+func goCoverage(profile string) float64 {
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		return -1
+	}
+	// Blocks may be listed once per package that covers them
+	type block struct{ stmts, hit int }
+	blocks := make(map[string]*block)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		stmts, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		b := blocks[fields[0]]
+		if b == nil {
+			b = &block{stmts: stmts}
+			blocks[fields[0]] = b
+		}
+		b.hit += count
+	}
+	total, covered := 0, 0
+	for _, b := range blocks {
+		total += b.stmts
+		if b.hit > 0 {
+			covered += b.stmts
+		}
+	}
+	if total == 0 {
+		return -1
+	}
+	return roundPercent(float64(covered) * 100 / float64(total))
+}
+
+// Lines of pytest output
+var (
+	pytestSummary  = regexp.MustCompile(`^(PASSED|FAILED|ERROR|XPASS|XFAIL) (\S+)(?: - (.*))?$`)
+	pytestSkipped  = regexp.MustCompile(`^SKIPPED \[\d+\] (\S+?):(\d+): (.*)$`)
+	pytestSection  = regexp.MustCompile(`^_{3,} (.+?) _{3,}$`)
+	pytestBanner   = regexp.MustCompile(`^={3,} (.+?) ={3,}$`)
+	pytestCoverage = regexp.MustCompile(`(?m)^TOTAL\s.*?(\d+(?:\.\d+)?)%\s*$`)
+)
+
+// parsePytest reads pytest -rA output.
+func parsePytest(output string) *TestRun {
+	run := &TestRun{Coverage: -1}
+	details := make(map[string]string) // Failure report by section title
+	var section string
+	var body strings.Builder
+	inSummary := false
+	flush := func() {
+		if section != "" {
+			details[section] = strings.TrimSpace(body.String())
+		}
+		section = ""
+		body.Reset()
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := pytestBanner.FindStringSubmatch(line); m != nil {
+			flush()
+			inSummary = strings.Contains(m[1], "short test summary")
+			continue
+		}
+		if m := pytestSection.FindStringSubmatch(line); m != nil && !inSummary {
+			flush()
+			section = m[1]
+			continue
+		}
+		if section != "" {
+			body.WriteString(line)
+			body.WriteByte('\n')
+			continue
+		}
+		if !inSummary {
+			continue
+		}
+		if m := pytestSkipped.FindStringSubmatch(line); m != nil {
+			lineNo, _ := strconv.Atoi(m[2])
+			run.Tests = append(run.Tests, TestCase{Name: m[1] + ":" + m[2], Status: TestSkipped, Path: m[1], Line: lineNo, Output: m[3]})
+			continue
+		}
+		m := pytestSummary.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		nodeID := m[2]
+		tc := TestCase{Name: nodeID, Path: strings.SplitN(nodeID, "::", 2)[0]}
+		switch m[1] {
+		case "PASSED", "XFAIL":
+			tc.Status = TestPassed
+		case "XPASS":
+			tc.Status = TestSkipped
+		default:
+			tc.Status = TestFailed
+			tc.Output = m[3]
+		}
+		run.Tests = append(run.Tests, tc)
+	}
+	flush()
+
+	// Failure sections are titled by the test, e.g. "TestCart.test_total"
+	for i, tc := range run.Tests {
+		if tc.Status != TestFailed {
+			continue
+		}
+		parts := strings.SplitN(tc.Name, "::", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		title := strings.ReplaceAll(parts[1], "::", ".")
+		for _, key := range []string{title, "ERROR at setup of " + title, "ERROR collecting " + parts[0]} {
+			if d, ok := details[key]; ok {
+				run.Tests[i].Output = d
+				break
+			}
+		}
+	}
+
+	if m := pytestCoverage.FindStringSubmatch(output); m != nil {
+		run.Coverage, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if len(run.Tests) == 0 {
+		run.Errors = strings.TrimSpace(output)
+	}
+	return run
+}
+
+// jestReport is the part of jest --json output read.
+type jestReport struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			Duration        *float64 `json:"duration"`
+			FailureMessages []string `json:"failureMessages"`
+			Location        *struct {
+				Line int `json:"line"`
+			} `json:"location"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+// parseJest reads the report jest --json wrote.
+func parseJest(report []byte, stderr string) *TestRun {
+	run := &TestRun{Coverage: -1}
+	var r jestReport
+	if len(report) == 0 || json.Unmarshal(report, &r) != nil {
+		run.Errors = strings.TrimSpace(stderr)
+		return run
+	}
+	var errs []string
+	for _, file := range r.TestResults {
+		if len(file.AssertionResults) == 0 && file.Status == "failed" {
+			// The file failed to load, so none of its tests ran
+			run.Tests = append(run.Tests, TestCase{Name: file.Name, Status: TestFailed, Path: file.Name, Output: file.Message})
+			errs = append(errs, file.Message)
+			continue
+		}
+		for _, a := range file.AssertionResults {
+			tc := TestCase{Name: file.Name + " > " + a.FullName, Path: file.Name}
+			if a.Location != nil {
+				tc.Line = a.Location.Line
+			}
+			if a.Duration != nil {
+				tc.Duration = time.Duration(*a.Duration * float64(time.Millisecond))
+			}
+			switch a.Status {
+			case "passed":
+				tc.Status = TestPassed
+			case "failed":
+				tc.Status = TestFailed
+				tc.Output = strings.Join(a.FailureMessages, "\n")
+			default: // pending, skipped, todo, disabled
+				tc.Status = TestSkipped
+			}
+			run.Tests = append(run.Tests, tc)
+		}
+	}
+	if len(run.Tests) == 0 {
+		errs = append(errs, strings.TrimSpace(stderr))
+		run.Errors = strings.TrimSpace(strings.Join(errs, "\n"))
+	}
+	return run
+}
+
+// jestCoverage returns the line coverage of a jest json-summary report, or
+// -1 if it can't be read.
+func jestCoverage(summary string) float64 {
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		return -1
+	}
+	var s struct {
+		Total struct {
+			Lines struct {
+				Pct float64 `json:"pct"`
+			} `json:"lines"`
+		} `json:"total"`
+	}
+	if json.Unmarshal(data, &s) != nil {
+		return -1
+	}
+	return s.Total.Lines.Pct
+}
+
+// Lines of cargo test output
+var (
+	cargoResult = regexp.MustCompile(`^test (\S+) \.\.\. (ok|FAILED|ignored)`)
+	cargoOutput = regexp.MustCompile(`^---- (\S+) stdout ----$`)
+)
+
+// parseCargoTest reads cargo test output. Tests print to stdout and cargo
+// reports compile errors on stderr.
+func parseCargoTest(stdout, stderr string) *TestRun {
+	run := &TestRun{Coverage: -1}
+	details := make(map[string]string)
+	var current string
+	var body strings.Builder
+	flush := func() {
+		if current != "" {
+			details[current] = strings.TrimSpace(body.String())
+		}
+		current = ""
+		body.Reset()
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := cargoOutput.FindStringSubmatch(line); m != nil {
+			flush()
+			current = m[1]
+			continue
+		}
+		if current != "" {
+			if line == "failures:" || strings.HasPrefix(line, "test result:") {
+				flush()
+			} else {
+				body.WriteString(line)
+				body.WriteByte('\n')
+			}
+			continue
+		}
+		if m := cargoResult.FindStringSubmatch(line); m != nil {
+			tc := TestCase{Name: m[1]}
+			switch m[2] {
+			case "ok":
+				tc.Status = TestPassed
+			case "ignored":
+				tc.Status = TestSkipped
+			default:
+				tc.Status = TestFailed
+			}
+			run.Tests = append(run.Tests, tc)
+		}
+	}
+	flush()
+
+	for i, tc := range run.Tests {
+		if tc.Status == TestFailed {
+			run.Tests[i].Output = details[tc.Name]
+		}
+	}
+	if strings.Contains(stderr, "error[") || strings.Contains(stderr, "error: could not compile") || len(run.Tests) == 0 {
+		run.Errors = strings.TrimSpace(stderr)
+	}
+	return run
+}
+
+// Limits of the report core.test returns
+const (
+	maxReportedFailures = 20
+	maxFailureOutput    = 4000
+)
+
+// formatTestRun renders a run for the model: counts and coverage, then
+// the failures with what they printed, then any errors.
+func formatTestRun(framework, command string, run *TestRun, elapsed time.Duration) string {
+	var b bytes.Buffer
+	passed, failed, skipped := run.Counts()
+	fmt.Fprintf(&b, "%s: %d passed, %d failed, %d skipped in %s", framework, passed, failed, skipped, elapsed.Round(10*time.Millisecond))
+	if run.Coverage >= 0 {
+		fmt.Fprintf(&b, "; coverage %.1f%%", run.Coverage)
+	}
+	fmt.Fprintf(&b, "\nCommand: %s\n", command)
+
+	var failures []TestCase
+	for _, t := range run.Tests {
+		if t.Status == TestFailed {
+			failures = append(failures, t)
+		}
+	}
+	sort.SliceStable(failures, func(i, j int) bool { return failures[i].Name < failures[j].Name })
+	for i, t := range failures {
+		if i == maxReportedFailures {
+			fmt.Fprintf(&b, "\n... and %d more failures\n", len(failures)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\nFAIL %s", t.Name)
+		if t.Path != "" && t.Line > 0 {
+			fmt.Fprintf(&b, " (%s:%d)", t.Path, t.Line)
+		}
+		b.WriteByte('\n')
+		if out := strings.TrimSpace(t.Output); out != "" {
+			b.WriteString(tail(out, maxFailureOutput))
+			b.WriteByte('\n')
+		}
+	}
+	if run.Errors != "" {
+		b.WriteString("\nErrors:\n")
+		b.WriteString(tail(run.Errors, maxFailureOutput))
+		b.WriteByte('\n')
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// tail returns the last n bytes of s, starting at a line.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return "...\n" + s
+}
+
+// roundPercent rounds a percentage to one decimal.
+func roundPercent(p float64) float64 {
+	return float64(int(p*10+0.5)) / 10
+}
@@ -0,0 +1,370 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// defaultASTMatches is how many matches a structural search returns when
+// head_limit is not given.
+const defaultASTMatches = 100
+
+// astKinds are the kinds the kind parameter accepts, with the node kinds
+// each covers.
+var astKinds = map[string][]string{
+	"function":  {"func", "method"},
+	"method":    {"method"},
+	"type":      {"struct", "interface", "class", "type", "enum"},
+	"struct":    {"struct", "class"},
+	"class":     {"class", "struct"},
+	"interface": {"interface"},
+	"import":    {"import"},
+	"call":      nil, // Call sites rather than declarations
+}
+
+// ASTGrepTool implements structural code search: declarations and calls
+// selected by what they are rather than how their text looks.
+type ASTGrepTool struct{}
+
+// NewASTGrepTool creates a new ASTGrep tool.
+func NewASTGrepTool() *ASTGrepTool {
+	return &ASTGrepTool{}
+}
+
+// Name returns the tool name.
+func (t *ASTGrepTool) Name() string {
+	return "astgrep"
+}
+
+// Description returns the tool description.
+func (t *ASTGrepTool) Description() string {
+	return "Searches Go, TypeScript/JavaScript and Python code by structure: functions and methods (optionally those calling X or on receiver R), " +
+		"types (optionally those with a field Y), imports, or call sites of X (optionally inside function F); more precise than grep for code questions"
+}
+
+// Parameters returns the tool parameters.
+func (t *ASTGrepTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "kind",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "What to find: function, method, type, struct, class, interface, import or call",
+		},
+		{
+			Name:        "name",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Regex the name must match: of the declaration, the import path, or for calls the callee as written (e.g. os.Open, self.save)",
+			Default:     "",
+		},
+		{
+			Name:        "calls",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For functions and methods, only those whose body makes a call whose callee matches this regex",
+			Default:     "",
+		},
+		{
+			Name:        "field",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For types, only those with a field or member whose name or declaration (e.g. \"Timeout time.Duration\") matches this regex",
+			Default:     "",
+		},
+		{
+			Name:        "receiver",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For methods, only those of a type or class whose name matches this regex",
+			Default:     "",
+		},
+		{
+			Name:        "inside",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For calls, only those made inside a function or method whose name (Type.method for methods) matches this regex",
+			Default:     "",
+		},
+		{
+			Name:        "exported",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Only exported or public declarations (default: false)",
+			Default:     false,
+		},
+		{
+			Name:        "path",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "File or directory to search (defaults to current directory)",
+			Default:     ".",
+		},
+		{
+			Name:        "type",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Languages to search, comma-separated: go, ts, js, py (default: all three)",
+			Default:     "",
+		},
+		{
+			Name:        "head_limit",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: fmt.Sprintf("Return at most this many matches (default: %d)", defaultASTMatches),
+			Default:     defaultASTMatches,
+		},
+	}
+}
+
+// RequiresPermission returns true as reading the workspace requires permission.
+func (t *ASTGrepTool) RequiresPermission() bool {
+	return true
+}
+
+// astQuery is a parsed structural search.
+type astQuery struct {
+	kind     string
+	kinds    map[string]bool
+	name     *regexp.Regexp
+	calls    *regexp.Regexp
+	field    *regexp.Regexp
+	receiver *regexp.Regexp
+	inside   *regexp.Regexp
+	exported bool
+}
+
+// Execute executes the structural search.
+func (t *ASTGrepTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	fail := func(err error) (*tools.Result, error) {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	q := astQuery{}
+	q.kind, _ = params["kind"].(string)
+	covered, ok := astKinds[q.kind]
+	if !ok {
+		return fail(fmt.Errorf("invalid kind: %s", q.kind))
+	}
+	q.kinds = make(map[string]bool, len(covered))
+	for _, k := range covered {
+		q.kinds[k] = true
+	}
+	q.exported, _ = params["exported"].(bool)
+
+	for _, p := range []struct {
+		param string
+		re    **regexp.Regexp
+		kinds []string // Kinds the filter applies to, nil for any
+	}{
+		{"name", &q.name, nil},
+		{"calls", &q.calls, []string{"function", "method"}},
+		{"field", &q.field, []string{"type", "struct", "class", "interface"}},
+		{"receiver", &q.receiver, []string{"function", "method"}},
+		{"inside", &q.inside, []string{"call"}},
+	} {
+		expr, _ := params[p.param].(string)
+		if expr == "" {
+			continue
+		}
+		if p.kinds != nil && !contains(p.kinds, q.kind) {
+			return fail(fmt.Errorf("%s does not apply to kind %s (only %s)", p.param, q.kind, strings.Join(p.kinds, ", ")))
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fail(fmt.Errorf("invalid %s regex: %w", p.param, err))
+		}
+		*p.re = re
+	}
+	if q.receiver != nil {
+		delete(q.kinds, "func")
+	}
+
+	searchPath := "."
+	if val, ok := params["path"].(string); ok && val != "" {
+		searchPath = val
+	}
+	limit := intParam(params, "head_limit")
+	if limit <= 0 {
+		limit = defaultASTMatches
+	}
+	opts := grepOptions{respectIgnore: true}
+	typeNames, _ := params["type"].(string)
+	for _, name := range strings.Split(typeNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		globs, ok := fileTypes[name]
+		if !ok {
+			return fail(fmt.Errorf("unknown file type: %s", name))
+		}
+		opts.types = append(opts.types, globs...)
+	}
+
+	files, err := grepFiles(searchPath, opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	var matches []map[string]interface{}
+	searched := 0
+	truncated := false
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		if !hasSyntax(path) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > maxGrepFileSize {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0 {
+			continue
+		}
+		searched++
+		found := q.match(path, content, parseSyntax(path, content))
+		if len(matches)+len(found) > limit {
+			matches = append(matches, found[:limit-len(matches)]...)
+			truncated = true
+			break
+		}
+		matches = append(matches, found...)
+	}
+
+	return &tools.Result{
+		Success: true,
+		Output:  matches,
+		Metadata: map[string]interface{}{
+			"kind":           q.kind,
+			"path":           searchPath,
+			"match_count":    len(matches),
+			"files_searched": searched,
+			"truncated":      truncated,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *ASTGrepTool) Category() string {
+	return "file"
+}
+
+// Version returns the tool version.
+func (t *ASTGrepTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *ASTGrepTool) IsExternal() bool {
+	return false
+}
+
+// match returns the entries of a file that the query selects.
+func (q *astQuery) match(path string, content []byte, tree *syntaxTree) []map[string]interface{} {
+	var matches []map[string]interface{}
+	if q.kind == "call" {
+		var lines []string
+		for _, c := range tree.calls {
+			if q.name != nil && !q.name.MatchString(c.Callee) {
+				continue
+			}
+			if q.inside != nil && (c.In == "" || !q.inside.MatchString(c.In)) {
+				continue
+			}
+			if lines == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+			match := map[string]interface{}{
+				"file": path,
+				"line": c.Line,
+				"kind": "call",
+				"name": c.Callee,
+				"text": strings.TrimSpace(strings.TrimSuffix(lines[c.Line-1], "\r")),
+			}
+			if c.In != "" {
+				match["in"] = c.In
+			}
+			matches = append(matches, match)
+		}
+		return matches
+	}
+
+	for _, n := range tree.nodes {
+		if !q.kinds[n.Kind] || (q.exported && !n.Exported) {
+			continue
+		}
+		if q.name != nil && !q.name.MatchString(n.Name) && !(n.Receiver != "" && q.name.MatchString(qualifiedName(n))) {
+			continue
+		}
+		if q.receiver != nil && !q.receiver.MatchString(n.Receiver) {
+			continue
+		}
+		var matched []string
+		if q.calls != nil {
+			for _, c := range n.Calls {
+				if q.calls.MatchString(c.Callee) {
+					matched = append(matched, fmt.Sprintf("%d: %s", c.Line, c.Callee))
+				}
+			}
+			if matched == nil {
+				continue
+			}
+		}
+		if q.field != nil {
+			for _, f := range n.Fields {
+				if q.field.MatchString(f.Name) || q.field.MatchString(f.Decl) {
+					matched = append(matched, fmt.Sprintf("%d: %s", f.Line, f.Decl))
+				}
+			}
+			if matched == nil {
+				continue
+			}
+		}
+
+		match := map[string]interface{}{
+			"file":      path,
+			"line":      n.Line,
+			"kind":      n.Kind,
+			"name":      n.Name,
+			"signature": n.Signature,
+		}
+		if n.EndLine > n.Line {
+			match["end_line"] = n.EndLine
+		}
+		if n.Receiver != "" {
+			match["receiver"] = n.Receiver
+		}
+		if matched != nil {
+			match["matched"] = matched
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// contains reports whether list has s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// BatchWriteTool writes several files as one operation: either all of them
+// are written, or none are. Progress is reported file by file, and a call
+// cancelled between files, or failing on one, puts back the files already
+// written.
+type BatchWriteTool struct{}
+
+// NewBatchWriteTool creates a new write_files tool.
+func NewBatchWriteTool() *BatchWriteTool {
+	return &BatchWriteTool{}
+}
+
+// Name returns the tool name.
+func (t *BatchWriteTool) Name() string {
+	return "write_files"
+}
+
+// Description returns the tool description.
+func (t *BatchWriteTool) Description() string {
+	return "Writes several files at once, all or nothing: if one fails or the call is cancelled, " +
+		"files already written are restored. Use it for changes spanning many files, such as a rename or new package"
+}
+
+// Parameters returns the tool parameters.
+func (t *BatchWriteTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "files",
+			Type:        tools.TypeObject,
+			Required:    true,
+			Description: "Files to write: an object mapping each absolute file path to its full new content",
+		},
+		{
+			Name:        "create_dirs",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Create parent directories if they don't exist (default: true)",
+			Default:     true,
+		},
+	}
+}
+
+// RequiresPermission returns true as file writing requires permission.
+func (t *BatchWriteTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the files in the permission prompt.
+func (t *BatchWriteTool) DescribeResource(params map[string]interface{}) string {
+	files, _ := params["files"].(map[string]interface{})
+	paths := sortedPaths(files)
+	switch len(paths) {
+	case 0:
+		return "write no files"
+	case 1:
+		return fmt.Sprintf("write %s", paths[0])
+	default:
+		return fmt.Sprintf("write %d files: %s and %d more", len(paths), paths[0], len(paths)-1)
+	}
+}
+
+// original is what a file held before the batch wrote it, to roll back to.
+type original struct {
+	path    string
+	existed bool
+	content []byte
+	mode    os.FileMode
+	dirs    []string // Directories created for the file, innermost first
+}
+
+// Execute writes the files.
+func (t *BatchWriteTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	files, _ := params["files"].(map[string]interface{})
+	createDirs := true
+	if val, ok := params["create_dirs"].(bool); ok {
+		createDirs = val
+	}
+
+	paths := sortedPaths(files)
+	if len(paths) == 0 {
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("no files to write"),
+		}, nil
+	}
+	contents := make(map[string]string, len(paths))
+	for _, path := range paths {
+		content, ok := files[path].(string)
+		if !ok {
+			return &tools.Result{
+				Success: false,
+				Error:   fmt.Errorf("content of %s must be a string", path),
+			}, nil
+		}
+		clean := filepath.Clean(path)
+		if !filepath.IsAbs(clean) {
+			return &tools.Result{
+				Success: false,
+				Error:   fmt.Errorf("file path %s must be absolute", path),
+			}, nil
+		}
+		if _, dup := contents[clean]; dup {
+			return &tools.Result{
+				Success: false,
+				Error:   fmt.Errorf("%s is given more than once", clean),
+			}, nil
+		}
+		contents[clean] = content
+	}
+	paths = sortedPaths(contents)
+
+	var written []original
+	var total int
+	for i, path := range paths {
+		err := ctx.Err()
+		if err == nil {
+			var orig original
+			orig, err = writeOne(ctx, path, contents[path], createDirs, i, len(paths))
+			if err == nil {
+				written = append(written, orig)
+				total += len(contents[path])
+				tools.ReportProgress(ctx, tools.Progress{Path: path, Files: i + 1, TotalFiles: len(paths)})
+				continue
+			}
+		}
+
+		rollbackErr := rollback(written)
+		reason := fmt.Errorf("failed to write %s: %w", path, err)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			reason = fmt.Errorf("cancelled after %d of %d files", len(written), len(paths))
+		}
+		if rollbackErr != nil {
+			reason = fmt.Errorf("%w; rollback incomplete: %v", reason, rollbackErr)
+		} else if len(written) > 0 {
+			reason = fmt.Errorf("%w; the %d files written were restored", reason, len(written))
+		}
+		return &tools.Result{
+			Success: false,
+			Error:   reason,
+			Metadata: map[string]interface{}{
+				"paths":       []string{},
+				"rolled_back": len(written),
+			},
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	return &tools.Result{
+		Success: true,
+		Output:  fmt.Sprintf("Successfully wrote %d files (%d bytes)", len(paths), total),
+		Metadata: map[string]interface{}{
+			"paths": paths,
+			"files": len(paths),
+			"size":  total,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// writeOne writes the i-th of n files, reporting its bytes as progress, and
+// returns what it held before.
+func writeOne(ctx context.Context, path, content string, createDirs bool, i, n int) (original, error) {
+	orig := original{path: path, mode: 0644}
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return orig, fmt.Errorf("%s is a directory", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return orig, err
+		}
+		orig.existed, orig.content, orig.mode = true, data, info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return orig, err
+	}
+
+	if createDirs {
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+				break
+			}
+			orig.dirs = append(orig.dirs, dir)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return orig, fmt.Errorf("failed to create directories: %w", err)
+		}
+	}
+
+	fileCtx := tools.WithProgress(ctx, func(p tools.Progress) {
+		p.Files, p.TotalFiles = i, n
+		tools.ReportProgress(ctx, p)
+	})
+	if err := writeFileAtomic(fileCtx, path, content); err != nil {
+		removeDirs(orig.dirs)
+		return orig, err
+	}
+	if orig.existed && orig.mode != 0644 {
+		_ = os.Chmod(path, orig.mode)
+	}
+	return orig, nil
+}
+
+// rollback puts back the files a batch wrote, newest first.
+func rollback(written []original) error {
+	var errs []error
+	for i := len(written) - 1; i >= 0; i-- {
+		orig := written[i]
+		if !orig.existed {
+			if err := os.Remove(orig.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			removeDirs(orig.dirs)
+			continue
+		}
+		current, err := os.ReadFile(orig.path)
+		if err == nil && bytes.Equal(current, orig.content) {
+			continue
+		}
+		if err := writeFileAtomic(context.Background(), orig.path, string(orig.content)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", orig.path, err))
+			continue
+		}
+		_ = os.Chmod(orig.path, orig.mode)
+	}
+	return errors.Join(errs...)
+}
+
+// removeDirs removes directories created for a file, innermost first, as
+// long as they are empty.
+func removeDirs(dirs []string) {
+	for _, dir := range dirs {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// sortedPaths returns the keys of files, sorted.
+func sortedPaths[V any](files map[string]V) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Category returns the tool category.
+func (t *BatchWriteTool) Category() string {
+	return "file"
+}
+
+// Version returns the tool version.
+func (t *BatchWriteTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *BatchWriteTool) IsExternal() bool {
+	return false
+}
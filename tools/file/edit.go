@@ -145,7 +145,7 @@ func (t *EditTool) Execute(ctx context.Context, params map[string]interface{}) (
 	}
 
 	// Write new content atomically
-	if err := writeFileAtomic(filePath, newContent); err != nil {
+	if err := writeFileAtomic(ctx, filePath, newContent); err != nil {
 		// Restore from backup
 		_ = os.WriteFile(filePath, content, 0644)
 
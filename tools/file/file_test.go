@@ -2,10 +2,16 @@ package file
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/abrksh22/bplus/tools"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -126,6 +132,71 @@ func TestWriteTool(t *testing.T) {
 		_, err = os.Stat(testFile)
 		assert.NoError(t, err)
 	})
+
+	t.Run("Multi-part write", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "large.txt")
+
+		result, err := tool.Execute(context.Background(), map[string]interface{}{
+			"file_path": testFile,
+			"content":   "part one, ",
+			"final":     false,
+		})
+		require.NoError(t, err)
+		require.True(t, result.Success)
+		assert.Equal(t, 10, result.Metadata["next_offset"])
+
+		// Target is untouched until the final part arrives
+		_, err = os.Stat(testFile)
+		assert.True(t, os.IsNotExist(err))
+
+		result, err = tool.Execute(context.Background(), map[string]interface{}{
+			"file_path": testFile,
+			"content":   "part two",
+			"offset":    float64(10),
+			"final":     true,
+		})
+		require.NoError(t, err)
+		require.True(t, result.Success)
+
+		written, err := os.ReadFile(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, "part one, part two", string(written))
+
+		_, err = os.Stat(testFile + ".partial")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Multi-part offset mismatch", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "mismatch.txt")
+
+		result, err := tool.Execute(context.Background(), map[string]interface{}{
+			"file_path": testFile,
+			"content":   "12345",
+			"final":     false,
+		})
+		require.NoError(t, err)
+		require.True(t, result.Success)
+
+		result, err = tool.Execute(context.Background(), map[string]interface{}{
+			"file_path": testFile,
+			"content":   "6789",
+			"offset":    3,
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "offset mismatch")
+	})
+
+	t.Run("Continuation without partial", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{
+			"file_path": filepath.Join(tmpDir, "orphan.txt"),
+			"content":   "tail",
+			"offset":    100,
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "no partial write")
+	})
 }
 
 // TestEditTool tests the Edit tool.
@@ -206,6 +277,239 @@ func TestEditTool(t *testing.T) {
 	})
 }
 
+// TestBatchWriteTool tests the write_files tool.
+func TestBatchWriteTool(t *testing.T) {
+	tool := NewBatchWriteTool()
+
+	t.Run("Write files with progress", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		small := filepath.Join(tmpDir, "a.txt")
+		large := filepath.Join(tmpDir, "sub", "b.txt")
+		content := strings.Repeat("x", 3*writeChunk)
+
+		var progress []tools.Progress
+		ctx := tools.WithProgress(context.Background(), func(p tools.Progress) {
+			progress = append(progress, p)
+		})
+		result, err := tool.Execute(ctx, map[string]interface{}{
+			"files": map[string]interface{}{small: "small", large: content},
+		})
+		require.NoError(t, err)
+		require.True(t, result.Success, "%v", result.Error)
+		assert.Equal(t, []string{small, large}, result.Metadata["paths"])
+
+		written, err := os.ReadFile(large)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(written))
+
+		// a.txt done, then b.txt chunk by chunk, then b.txt done
+		require.Len(t, progress, 5)
+		assert.Equal(t, tools.Progress{Path: small, Files: 1, TotalFiles: 2}, progress[0])
+		assert.Equal(t, tools.Progress{Path: large, Bytes: writeChunk, TotalBytes: int64(len(content)), Files: 1, TotalFiles: 2}, progress[1])
+		assert.Equal(t, int64(len(content)), progress[3].Bytes)
+		assert.Equal(t, 2, progress[4].Files)
+	})
+
+	t.Run("Failure rolls back", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		existing := filepath.Join(tmpDir, "a.txt")
+		require.NoError(t, os.WriteFile(existing, []byte("original"), 0644))
+		created := filepath.Join(tmpDir, "b", "new.txt")
+		blocked := filepath.Join(tmpDir, "c")
+		require.NoError(t, os.Mkdir(blocked, 0755))
+
+		result, err := tool.Execute(context.Background(), map[string]interface{}{
+			"files": map[string]interface{}{existing: "changed", created: "new", blocked: "oops"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "is a directory")
+		assert.Contains(t, result.Error.Error(), "2 files written were restored")
+
+		restored, err := os.ReadFile(existing)
+		require.NoError(t, err)
+		assert.Equal(t, "original", string(restored))
+		_, err = os.Stat(filepath.Dir(created))
+		assert.True(t, os.IsNotExist(err), "created directories should be removed")
+	})
+
+	t.Run("Cancel between files", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		first := filepath.Join(tmpDir, "a.txt")
+		second := filepath.Join(tmpDir, "b.txt")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = tools.WithProgress(ctx, func(p tools.Progress) {
+			if p.Files == 1 {
+				cancel()
+			}
+		})
+		result, err := tool.Execute(ctx, map[string]interface{}{
+			"files": map[string]interface{}{first: "one", second: "two"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "cancelled after 1 of 2 files")
+		assert.Equal(t, 1, result.Metadata["rolled_back"])
+
+		for _, path := range []string{first, second} {
+			_, err := os.Stat(path)
+			assert.True(t, os.IsNotExist(err), "%s should not exist", path)
+		}
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{
+			"files": map[string]interface{}{"relative.txt": "x"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "must be absolute")
+
+		result, err = tool.Execute(context.Background(), map[string]interface{}{
+			"files": map[string]interface{}{},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+	})
+}
+
+// TestPatchTool tests applying unified diffs.
+func TestPatchTool(t *testing.T) {
+	tool := NewPatchTool()
+	source := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+
+	t.Run("Apply git diff", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		path := filepath.Join(tmpDir, "main.go")
+		require.NoError(t, os.WriteFile(path, []byte(source), 0644))
+
+		patch := "diff --git a/main.go b/main.go\n" +
+			"index 1111111..2222222 100644\n" +
+			"--- a/main.go\n" +
+			"+++ b/main.go\n" +
+			"@@ -5,3 +5,3 @@ import \"fmt\"\n" +
+			" func main() {\n" +
+			"-\tfmt.Println(\"hello\")\n" +
+			"+\tfmt.Println(\"goodbye\")\n" +
+			" }\n" +
+			"--- /dev/null\n" +
+			"+++ b/docs/notes.txt\n" +
+			"@@ -0,0 +1,2 @@\n" +
+			"+first\n" +
+			"+second\n"
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch, "working_dir": tmpDir})
+		require.NoError(t, err)
+		require.True(t, result.Success, "%v", result.Error)
+		assert.Equal(t, "Applied 2 hunk(s) to 2 file(s)", result.Output)
+		assert.Equal(t, []string{filepath.Join(tmpDir, "docs", "notes.txt"), path}, result.Metadata["paths"])
+
+		patched, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Replace(source, "hello", "goodbye", 1), string(patched))
+		created, err := os.ReadFile(filepath.Join(tmpDir, "docs", "notes.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "first\nsecond\n", string(created))
+	})
+
+	t.Run("Fuzzy matching", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		path := filepath.Join(tmpDir, "main.go")
+		// Two lines added above the hunk since the diff was made
+		require.NoError(t, os.WriteFile(path, []byte("// Command main.\n// It greets.\n"+source), 0644))
+
+		// Wrong line numbers and counts, a blank context line without its
+		// space, and context that no longer matches at the ends
+		patch := "--- main.go\n" +
+			"+++ main.go\n" +
+			"@@ -3,7 +3,7 @@\n" +
+			" import \"os\"\n" +
+			"\n" +
+			" func main() {\n" +
+			"-    fmt.Println(\"hello\")\n" +
+			"+\tfmt.Println(\"goodbye\")\n" +
+			" } // main\n"
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch, "working_dir": tmpDir, "dry_run": true})
+		require.NoError(t, err)
+		require.True(t, result.Success, "%v", result.Error)
+		assert.Contains(t, result.Output, "main.go: hunk 1 applied with offset +2 lines, fuzz 1, whitespace ignored")
+		assert.Contains(t, result.Output, "@@ -6,3 +6,3 @@\n \n func main() {\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"goodbye\")")
+
+		// A dry run changes nothing
+		unchanged, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(unchanged), "hello")
+		assert.Nil(t, result.Metadata["paths"])
+	})
+
+	t.Run("Failing hunk changes nothing", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		first := filepath.Join(tmpDir, "a.txt")
+		second := filepath.Join(tmpDir, "b.txt")
+		require.NoError(t, os.WriteFile(first, []byte("one\ntwo\n"), 0644))
+		require.NoError(t, os.WriteFile(second, []byte("three\nfour\n"), 0644))
+
+		patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+2\n" +
+			"--- a/b.txt\n+++ b/b.txt\n@@ -1,2 +1,2 @@\n five\n-six\n+6\n"
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch, "working_dir": tmpDir})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "b.txt: hunk 1 does not match")
+
+		_, err = tool.Preview(context.Background(), map[string]interface{}{"patch": patch, "working_dir": tmpDir})
+		assert.Error(t, err)
+		content, err := os.ReadFile(first)
+		require.NoError(t, err)
+		assert.Equal(t, "one\ntwo\n", string(content))
+	})
+
+	t.Run("Delete and rename", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		old := filepath.Join(tmpDir, "old.txt")
+		gone := filepath.Join(tmpDir, "gone.txt")
+		require.NoError(t, os.WriteFile(old, []byte("keep\nme\n"), 0644))
+		require.NoError(t, os.WriteFile(gone, []byte("bye\n"), 0644))
+
+		patch := "--- a/old.txt\n+++ b/new.txt\n@@ -1,2 +1,2 @@\n keep\n-me\n+you\n" +
+			"--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch, "working_dir": tmpDir})
+		require.NoError(t, err)
+		require.True(t, result.Success, "%v", result.Error)
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "new.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "keep\nyou\n", string(content))
+		for _, path := range []string{old, gone} {
+			_, err := os.Stat(path)
+			assert.True(t, os.IsNotExist(err), "%s should be removed", path)
+		}
+	})
+
+	t.Run("Paths outside the working directory", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		patch := "--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n"
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch, "working_dir": tmpDir})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "is outside")
+	})
+
+	t.Run("No newline at end of file", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		path := filepath.Join(tmpDir, "a.txt")
+		require.NoError(t, os.WriteFile(path, []byte("one\ntwo"), 0644))
+
+		patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+2\n"
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch, "working_dir": tmpDir})
+		require.NoError(t, err)
+		require.True(t, result.Success, "%v", result.Error)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "one\n2\n", string(content))
+	})
+}
+
 // TestGlobTool tests the Glob tool.
 func TestGlobTool(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -320,6 +624,395 @@ func TestGrepTool(t *testing.T) {
 	})
 }
 
+// TestGrepToolFilters tests ignore files, file types, multiline patterns,
+// head_limit and binary files.
+func TestGrepToolFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewGrepTool()
+
+	files := map[string]string{
+		".gitignore":          "build/\n*.log\n!keep.log\n/vendor\n",
+		".bplusignore":        "secret.txt\n",
+		"main.go":             "package main\n\nfunc main() {\n\tneedle()\n}\n",
+		"lib/util.go":         "package lib\n// needle\n",
+		"lib/.gitignore":      "generated.go\n",
+		"lib/generated.go":    "needle\n",
+		"lib/vendor/dep.go":   "needle\n",
+		"vendor/dep.go":       "needle\n",
+		"build/out.go":        "needle\n",
+		"debug.log":           "needle\n",
+		"keep.log":            "needle\n",
+		"secret.txt":          "needle\n",
+		"notes.md":            "needle\n",
+		"Makefile":            "needle:\n",
+		".git/config":         "needle\n",
+		"node_modules/x/a.js": "needle\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "blob.bin"), []byte("needle\x00\x01"), 0644))
+
+	search := func(params map[string]interface{}) *tools.Result {
+		params["path"] = tmpDir
+		result, err := tool.Execute(context.Background(), params)
+		require.NoError(t, err)
+		require.True(t, result.Success, "%v", result.Error)
+		return result
+	}
+	rel := func(paths []string) []string {
+		var out []string
+		for _, p := range paths {
+			r, _ := filepath.Rel(tmpDir, p)
+			out = append(out, filepath.ToSlash(r))
+		}
+		return out
+	}
+
+	t.Run("Ignore files", func(t *testing.T) {
+		result := search(map[string]interface{}{"pattern": "needle"})
+		assert.Equal(t, []string{"Makefile", "keep.log", "lib/util.go", "lib/vendor/dep.go", "main.go", "notes.md"}, rel(result.Output.([]string)))
+	})
+
+	t.Run("Ignore files off", func(t *testing.T) {
+		result := search(map[string]interface{}{"pattern": "needle", "respect_gitignore": false})
+		got := rel(result.Output.([]string))
+		assert.Contains(t, got, "build/out.go")
+		assert.Contains(t, got, "secret.txt")
+		assert.NotContains(t, got, ".git/config")
+		assert.NotContains(t, got, "blob.bin")
+	})
+
+	t.Run("File type", func(t *testing.T) {
+		result := search(map[string]interface{}{"pattern": "needle", "type": "go,make"})
+		assert.Equal(t, []string{"Makefile", "lib/util.go", "lib/vendor/dep.go", "main.go"}, rel(result.Output.([]string)))
+
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"pattern": "needle", "path": tmpDir, "type": "cobol"})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+	})
+
+	t.Run("Multiline", func(t *testing.T) {
+		result := search(map[string]interface{}{"pattern": `func main\(\) \{.*?needle`, "output_mode": "content"})
+		assert.Empty(t, result.Output)
+
+		result = search(map[string]interface{}{"pattern": `func main\(\) \{.*?needle`, "output_mode": "content", "multiline": true})
+		matches := result.Output.([]map[string]interface{})
+		require.Len(t, matches, 1)
+		assert.Equal(t, 3, matches[0]["line"])
+		assert.Equal(t, 4, matches[0]["end_line"])
+		assert.Equal(t, "func main() {\n\tneedle()", matches[0]["text"])
+	})
+
+	t.Run("Head limit", func(t *testing.T) {
+		result := search(map[string]interface{}{"pattern": "needle", "head_limit": 2})
+		assert.Equal(t, []string{"Makefile", "keep.log"}, rel(result.Output.([]string)))
+		assert.Equal(t, true, result.Metadata["truncated"])
+
+		result = search(map[string]interface{}{"pattern": "needle", "output_mode": "content", "head_limit": 3})
+		assert.Len(t, result.Output, 3)
+
+		result = search(map[string]interface{}{"pattern": "needle", "head_limit": 100})
+		assert.Equal(t, false, result.Metadata["truncated"])
+	})
+
+	t.Run("Subdirectory keeps parent ignores", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".git"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "lib", "build"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "lib", "build", "x.go"), []byte("needle\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "lib", "app.log"), []byte("needle\n"), 0644))
+
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"pattern": "needle", "path": filepath.Join(tmpDir, "lib")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"lib/util.go", "lib/vendor/dep.go"}, rel(result.Output.([]string)))
+	})
+}
+
+// TestOutlineFile tests symbol extraction for Go and pattern-matched languages.
+func TestOutlineFile(t *testing.T) {
+	names := func(symbols []Symbol) []string {
+		var out []string
+		for _, s := range symbols {
+			out = append(out, fmt.Sprintf("%s %s %d %v", s.Kind, s.Name, s.Line, s.Exported))
+		}
+		return out
+	}
+
+	goSrc := "package p\n\n// Server serves.\ntype Server struct {\n\tAddr string\n}\n\ntype handler interface{ Serve() }\n\nconst Version = \"1\"\n\nfunc (s *Server) Start(port int) error {\n\treturn nil\n}\n\nfunc helper() {}\n"
+	symbols := outlineFile("p.go", []byte(goSrc))
+	assert.Equal(t, []string{"struct Server 4 true", "interface handler 8 false", "const Version 10 true", "method Start 12 true", "func helper 16 false"}, names(symbols))
+	assert.Equal(t, "func (s *Server) Start(port int) error", symbols[3].Signature)
+
+	pySrc := "import os\n\nclass Store:\n    \"\"\"\n    def not_a_method(self):\n    \"\"\"\n    def get(self, key):\n        pass\n\ndef _private():\n    pass\n"
+	assert.Equal(t, []string{"class Store 3 true", "method get 7 true", "func _private 10 false"}, names(outlineFile("store.py", []byte(pySrc))))
+
+	tsSrc := "export interface Props {}\nexport const Button = (p: Props) => null\nfunction local() {}\nexport default class App {}\n"
+	assert.Equal(t, []string{"interface Props 1 true", "func Button 2 true", "func local 3 false", "class App 4 true"}, names(outlineFile("app.tsx", []byte(tsSrc))))
+
+	assert.Nil(t, outlineFile("notes.txt", []byte("func main() {}")))
+	assert.Nil(t, outlineFile("broken.go", []byte("package")))
+}
+
+// TestRepoMapTool tests outlining a tree within a token budget.
+func TestRepoMapTool(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewRepoMapTool()
+
+	files := map[string]string{
+		".gitignore":       "gen/\n",
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"main_test.go":     "package main\n\nfunc TestMain() {}\n",
+		"pkg/store.go":     "package pkg\n\ntype Store struct{}\n\nfunc (s *Store) Get() {}\n\nfunc hidden() {}\n",
+		"web/app.ts":       "export function render() {}\n",
+		"gen/generated.go": "package gen\n\nfunc Generated() {}\n",
+		"README.md":        "# readme\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("Full map", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"path": tmpDir})
+		require.NoError(t, err)
+		require.True(t, result.Success)
+		out := result.Output.(string)
+		assert.Contains(t, out, "main.go:\n  3: func main()\n")
+		assert.Contains(t, out, "pkg/store.go:\n  3: type Store struct\n  5: func (s *Store) Get()\n  7: func hidden()\n")
+		assert.Contains(t, out, "web/app.ts:\n  1: export function render()\n")
+		assert.NotContains(t, out, "main_test.go")
+		assert.NotContains(t, out, "gen/")
+		assert.NotContains(t, out, "README")
+		assert.Less(t, strings.Index(out, "main.go"), strings.Index(out, "pkg/store.go"))
+		assert.Equal(t, false, result.Metadata["truncated"])
+	})
+
+	t.Run("Type and tests", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"path": tmpDir, "type": "go", "tests": true})
+		require.NoError(t, err)
+		out := result.Output.(string)
+		assert.Contains(t, out, "main_test.go")
+		assert.NotContains(t, out, "app.ts")
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"path": tmpDir, "max_tokens": 12})
+		require.NoError(t, err)
+		require.True(t, result.Success)
+		assert.Equal(t, true, result.Metadata["truncated"])
+		assert.LessOrEqual(t, result.Metadata["tokens"], 14)
+	})
+
+	t.Run("Not a directory", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"path": filepath.Join(tmpDir, "main.go")})
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+	})
+}
+
+// TestASTGrepTool tests structural search over Go, Python and TypeScript.
+func TestASTGrepTool(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewASTGrepTool()
+
+	files := map[string]string{
+		"store.go": "package store\n\nimport \"os\"\n\ntype Store struct {\n\tPath    string\n\tTimeout time.Duration\n}\n\n" +
+			"func (s *Store) Load() ([]byte, error) {\n\treturn os.ReadFile(s.Path)\n}\n\n" +
+			"func describe() string {\n\t// os.ReadFile(x) in a comment\n\treturn \"os.ReadFile(y)\"\n}\n",
+		"cache.py": "import json\n\nclass Cache:\n    limit: int = 10\n\n    def __init__(self, path):\n        self.path = path\n\n" +
+			"    def save(self, data):\n        text = json.dumps(data)  # json.loads(x) noted\n        self._write(text)\n\n" +
+			"def helper():\n    return \"json.dumps(z)\"\n",
+		"api.ts": "import { get } from './http';\n\nexport interface User {\n  id: string;\n  email?: string;\n}\n\n" +
+			"export class Client {\n  private base = '/api';\n\n  async fetchUser(id: string): Promise<User> {\n    return get<User>(`${this.base}/users/${id}`);\n  }\n}\n\n" +
+			"export const format = (u: User) => u.email ?? render(u.id);\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+
+	search := func(t *testing.T, params map[string]interface{}) []map[string]interface{} {
+		params["path"] = tmpDir
+		result, err := tool.Execute(context.Background(), params)
+		require.NoError(t, err)
+		require.True(t, result.Success, "%v", result.Error)
+		return result.Output.([]map[string]interface{})
+	}
+	names := func(matches []map[string]interface{}) []string {
+		var out []string
+		for _, m := range matches {
+			out = append(out, fmt.Sprintf("%s %s", filepath.Base(m["file"].(string)), m["name"]))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	t.Run("Functions calling", func(t *testing.T) {
+		matches := search(t, map[string]interface{}{"kind": "function", "calls": `^os\.ReadFile$`})
+		require.Len(t, matches, 1, "calls quoted in strings and comments don't count")
+		assert.Equal(t, "Load", matches[0]["name"])
+		assert.Equal(t, "Store", matches[0]["receiver"])
+		assert.Equal(t, []string{"11: os.ReadFile"}, matches[0]["matched"])
+		assert.Equal(t, 12, matches[0]["end_line"])
+
+		assert.Equal(t, []string{"api.ts fetchUser", "cache.py save"}, names(search(t, map[string]interface{}{"kind": "method", "calls": `json\.dumps|^get$`})))
+		assert.Equal(t, []string{"api.ts format"}, names(search(t, map[string]interface{}{"kind": "function", "calls": "render"})))
+	})
+
+	t.Run("Types with field", func(t *testing.T) {
+		assert.Equal(t, []string{"store.go Store"}, names(search(t, map[string]interface{}{"kind": "struct", "field": `time\.Duration`})))
+		assert.Equal(t, []string{"cache.py Cache"}, names(search(t, map[string]interface{}{"kind": "class", "field": "^path$"})))
+		assert.Equal(t, []string{"cache.py Cache"}, names(search(t, map[string]interface{}{"kind": "class", "field": "^limit$"})))
+		assert.Equal(t, []string{"api.ts User"}, names(search(t, map[string]interface{}{"kind": "type", "field": "^email$"})))
+		assert.Equal(t, []string{"api.ts Client"}, names(search(t, map[string]interface{}{"kind": "class", "field": "^base$"})))
+	})
+
+	t.Run("Calls inside", func(t *testing.T) {
+		matches := search(t, map[string]interface{}{"kind": "call", "name": "_write"})
+		require.Len(t, matches, 1)
+		assert.Equal(t, "Cache.save", matches[0]["in"])
+		assert.Equal(t, 11, matches[0]["line"])
+		assert.Equal(t, "self._write(text)", matches[0]["text"])
+
+		assert.Len(t, search(t, map[string]interface{}{"kind": "call", "name": "ReadFile", "inside": "^describe$"}), 0)
+	})
+
+	t.Run("Receiver, imports and exported", func(t *testing.T) {
+		assert.Equal(t, []string{"cache.py __init__", "cache.py save"}, names(search(t, map[string]interface{}{"kind": "function", "receiver": "^Cache$"})))
+		assert.Equal(t, []string{"api.ts ./http", "cache.py json", "store.go os"}, names(search(t, map[string]interface{}{"kind": "import"})))
+		assert.Equal(t, []string{"cache.py helper", "store.go describe"}, names(search(t, map[string]interface{}{"kind": "function", "name": "^(helper|describe)$"})))
+		assert.Empty(t, search(t, map[string]interface{}{"kind": "function", "name": "^(helper|describe)$", "exported": true, "type": "go"}))
+	})
+
+	t.Run("Head limit", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"kind": "function", "path": tmpDir, "head_limit": 2})
+		require.NoError(t, err)
+		assert.Len(t, result.Output, 2)
+		assert.Equal(t, true, result.Metadata["truncated"])
+	})
+
+	t.Run("Invalid queries", func(t *testing.T) {
+		for _, params := range []map[string]interface{}{
+			{"kind": "module"},
+			{"kind": "call", "field": "x"},
+			{"kind": "function", "calls": "("},
+		} {
+			params["path"] = tmpDir
+			result, err := tool.Execute(context.Background(), params)
+			require.NoError(t, err)
+			assert.False(t, result.Success, "%v", params)
+		}
+	})
+}
+
+// TestWatcher tests noticing files changed outside b+.
+func TestWatcher(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+
+	var heard []Change
+	var mu sync.Mutex
+	w, err := NewWatcher(WithDebounce(10*time.Millisecond), WithChangeListener(func(c Change) {
+		mu.Lock()
+		heard = append(heard, c)
+		mu.Unlock()
+	}))
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, w.Watch(file))
+
+	// changes waits for the watcher to report n changes.
+	changes := func(n int) []Change {
+		t.Helper()
+		var all []Change
+		require.Eventually(t, func() bool {
+			all = append(all, w.Changes()...)
+			return len(all) >= n
+		}, 5*time.Second, 10*time.Millisecond)
+		return all
+	}
+
+	t.Run("External edit", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc main() {}\n"), 0644))
+		got := changes(1)
+		require.Len(t, got, 1)
+		assert.Equal(t, file, got[0].Path)
+		assert.Equal(t, ChangeModified, got[0].Kind)
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(heard) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Own write", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte("package app\n"), 0644))
+		require.NoError(t, w.Watch(file))
+		time.Sleep(100 * time.Millisecond)
+		assert.Empty(t, w.Changes())
+	})
+
+	t.Run("Directory", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "gen")
+		require.NoError(t, os.Mkdir(dir, 0755))
+		require.NoError(t, w.Watch(dir))
+		assert.Equal(t, []string{dir + string(filepath.Separator), file}, w.Watched())
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt"), []byte("x"), 0644))
+		require.NoError(t, os.Remove(file))
+		got := changes(2)
+		kinds := map[string]string{}
+		for _, c := range got {
+			kinds[filepath.Base(c.Path)] = c.Kind
+		}
+		assert.Equal(t, map[string]string{"out.txt": ChangeCreated, "main.go": ChangeDeleted}, kinds)
+
+		require.NoError(t, w.Unwatch(dir))
+		assert.Error(t, w.Unwatch(dir))
+	})
+
+	t.Run("Describe", func(t *testing.T) {
+		text := DescribeChanges([]Change{
+			{Path: file, Kind: ChangeModified},
+			{Path: "/elsewhere/x.go", Kind: ChangeCreated},
+			{Path: file, Kind: ChangeDeleted},
+		}, tmpDir)
+		assert.Contains(t, text, "read them again before editing")
+		assert.True(t, strings.HasSuffix(text, ":\n- main.go (deleted)\n- /elsewhere/x.go (created)"), text)
+		assert.Empty(t, DescribeChanges(nil, tmpDir))
+	})
+}
+
+// TestWatchTool tests adding and removing watched paths.
+func TestWatchTool(t *testing.T) {
+	tmpDir := t.TempDir()
+	w, err := NewWatcher()
+	require.NoError(t, err)
+	defer w.Close()
+	tool := NewWatchTool(w)
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"action": "add", "path": tmpDir})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "Watching 1 paths:\n"+tmpDir+string(filepath.Separator), result.Output)
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"action": "add", "path": filepath.Join(tmpDir, "missing")})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"action": "remove", "path": tmpDir})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "Nothing is watched", result.Output)
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"action": "watch"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+}
+
 // TestToolMetadata tests tool metadata methods.
 func TestToolMetadata(t *testing.T) {
 	tools := []struct {
@@ -337,8 +1030,13 @@ func TestToolMetadata(t *testing.T) {
 		{NewReadTool(), "read", "file"},
 		{NewWriteTool(), "write", "file"},
 		{NewEditTool(), "edit", "file"},
+		{NewBatchWriteTool(), "write_files", "file"},
+		{NewPatchTool(), "patch", "file"},
 		{NewGlobTool(), "glob", "file"},
 		{NewGrepTool(), "grep", "file"},
+		{NewRepoMapTool(), "repomap", "file"},
+		{NewASTGrepTool(), "astgrep", "file"},
+		{NewWatchTool(nil), "watch", "file"},
 	}
 
 	for _, tt := range tools {
@@ -1,18 +1,74 @@
 package file
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/abrksh22/bplus/tools"
 )
 
+// Search limits
+const (
+	// binarySniffLen is how much of a file is checked for NUL bytes, which
+	// mark it binary and unsearched, as ripgrep does.
+	binarySniffLen = 8000
+
+	// maxGrepFileSize is the size above which files are skipped; files that
+	// large are data or build output rather than code.
+	maxGrepFileSize = 20 << 20
+)
+
+// fileTypes are the names the type parameter accepts, after ripgrep's -t,
+// with the file names they cover.
+var fileTypes = map[string][]string{
+	"c":          {"*.c", "*.h"},
+	"cpp":        {"*.cpp", "*.cc", "*.cxx", "*.hpp", "*.hh", "*.hxx", "*.h"},
+	"cs":         {"*.cs"},
+	"css":        {"*.css", "*.scss", "*.sass", "*.less"},
+	"dart":       {"*.dart"},
+	"docker":     {"Dockerfile", "*.dockerfile"},
+	"elixir":     {"*.ex", "*.exs"},
+	"go":         {"*.go"},
+	"haskell":    {"*.hs"},
+	"html":       {"*.html", "*.htm"},
+	"java":       {"*.java"},
+	"javascript": {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+	"js":         {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+	"json":       {"*.json"},
+	"kotlin":     {"*.kt", "*.kts"},
+	"lua":        {"*.lua"},
+	"make":       {"Makefile", "makefile", "GNUmakefile", "*.mk"},
+	"markdown":   {"*.md", "*.markdown"},
+	"md":         {"*.md", "*.markdown"},
+	"php":        {"*.php"},
+	"proto":      {"*.proto"},
+	"py":         {"*.py", "*.pyi"},
+	"python":     {"*.py", "*.pyi"},
+	"rb":         {"*.rb"},
+	"ruby":       {"*.rb"},
+	"rust":       {"*.rs"},
+	"scala":      {"*.scala"},
+	"sh":         {"*.sh", "*.bash", "*.zsh"},
+	"sql":        {"*.sql"},
+	"svelte":     {"*.svelte"},
+	"swift":      {"*.swift"},
+	"terraform":  {"*.tf", "*.tfvars"},
+	"toml":       {"*.toml"},
+	"ts":         {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"typescript": {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"vue":        {"*.vue"},
+	"xml":        {"*.xml"},
+	"yaml":       {"*.yaml", "*.yml"},
+	"zig":        {"*.zig"},
+}
+
 // GrepTool implements the content search tool.
 type GrepTool struct{}
 
@@ -28,7 +84,8 @@ func (t *GrepTool) Name() string {
 
 // Description returns the tool description.
 func (t *GrepTool) Description() string {
-	return "Searches for pattern in files with ripgrep-style functionality"
+	return "Searches for pattern in files with ripgrep-style functionality: files excluded by .gitignore and .bplusignore, " +
+		"binary files and .git are skipped, results can be filtered by file type and capped with head_limit"
 }
 
 // Parameters returns the tool parameters.
@@ -89,6 +146,34 @@ func (t *GrepTool) Parameters() []tools.Parameter {
 			Description: "Glob pattern to filter files (e.g., '*.go')",
 			Default:     "",
 		},
+		{
+			Name:        "type",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "File types to search, comma-separated, as ripgrep's -t names them (go, js, ts, py, rust, java, md, yaml, ...)",
+			Default:     "",
+		},
+		{
+			Name:        "multiline",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Let the pattern span lines, with . matching newlines (-U --multiline-dotall)",
+			Default:     false,
+		},
+		{
+			Name:        "head_limit",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Return only the first N files, counts or matching lines (default: all)",
+			Default:     0,
+		},
+		{
+			Name:        "respect_gitignore",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Respect .gitignore and .bplusignore files (default: true)",
+			Default:     true,
+		},
 	}
 }
 
@@ -97,6 +182,21 @@ func (t *GrepTool) RequiresPermission() bool {
 	return true
 }
 
+// grepOptions are the parameters of one search.
+type grepOptions struct {
+	re              *regexp.Regexp
+	prefilter       *regexp.Regexp // re over whole files, with ^ and $ at line boundaries
+	outputMode      string
+	fileGlob        string
+	types           []string // File name globs of the type parameter
+	contextBefore   int
+	contextAfter    int
+	showLineNumbers bool
+	multiline       bool
+	headLimit       int
+	respectIgnore   bool
+}
+
 // Execute executes the grep operation.
 func (t *GrepTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
 	startTime := time.Now()
@@ -104,77 +204,102 @@ func (t *GrepTool) Execute(ctx context.Context, params map[string]interface{}) (
 	// Extract parameters
 	pattern := params["pattern"].(string)
 	searchPath := "."
-	outputMode := "files_with_matches"
 	caseInsensitive := false
-	contextBefore := 0
-	contextAfter := 0
-	showLineNumbers := false
-	fileGlob := ""
+	fileTypeNames := ""
+	opts := grepOptions{outputMode: "files_with_matches", respectIgnore: true}
 
 	if val, ok := params["path"]; ok {
 		searchPath = val.(string)
 	}
 	if val, ok := params["output_mode"]; ok {
-		outputMode = val.(string)
+		opts.outputMode = val.(string)
 	}
 	if val, ok := params["case_insensitive"]; ok {
 		caseInsensitive = val.(bool)
 	}
-	if val, ok := params["context_before"]; ok {
-		switch v := val.(type) {
-		case int:
-			contextBefore = v
-		case float64:
-			contextBefore = int(v)
-		}
-	}
-	if val, ok := params["context_after"]; ok {
-		switch v := val.(type) {
-		case int:
-			contextAfter = v
-		case float64:
-			contextAfter = int(v)
-		}
-	}
+	opts.contextBefore = intParam(params, "context_before")
+	opts.contextAfter = intParam(params, "context_after")
+	opts.headLimit = intParam(params, "head_limit")
 	if val, ok := params["show_line_numbers"]; ok {
-		showLineNumbers = val.(bool)
+		opts.showLineNumbers = val.(bool)
 	}
 	if val, ok := params["file_glob"]; ok {
-		fileGlob = val.(string)
+		opts.fileGlob = val.(string)
+	}
+	if val, ok := params["type"]; ok {
+		fileTypeNames = val.(string)
+	}
+	if val, ok := params["multiline"]; ok {
+		opts.multiline = val.(bool)
+	}
+	if val, ok := params["respect_gitignore"]; ok {
+		opts.respectIgnore = val.(bool)
+	}
+
+	fail := func(err error) (*tools.Result, error) {
+		return &tools.Result{
+			Success: false,
+			Error:   err,
+		}, nil
+	}
+
+	switch opts.outputMode {
+	case "files_with_matches", "count", "content":
+	default:
+		return fail(fmt.Errorf("invalid output_mode: %s", opts.outputMode))
+	}
+
+	for _, name := range strings.Split(fileTypeNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		globs, ok := fileTypes[name]
+		if !ok {
+			return fail(fmt.Errorf("unknown file type: %s", name))
+		}
+		opts.types = append(opts.types, globs...)
 	}
 
 	// Compile regex
-	var re *regexp.Regexp
-	var err error
+	flags := ""
 	if caseInsensitive {
-		re, err = regexp.Compile("(?i)" + pattern)
-	} else {
-		re, err = regexp.Compile(pattern)
+		flags += "i"
+	}
+	if opts.multiline {
+		flags += "s"
 	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	var err error
+	opts.re, err = regexp.Compile(pattern)
 	if err != nil {
-		return &tools.Result{
-			Success: false,
-			Error:   fmt.Errorf("invalid regex pattern: %w", err),
-		}, nil
+		return fail(fmt.Errorf("invalid regex pattern: %w", err))
 	}
+	opts.prefilter = regexp.MustCompile("(?m)" + pattern)
 
 	// Perform search
-	results, err := grepSearch(searchPath, re, outputMode, fileGlob, contextBefore, contextAfter, showLineNumbers)
+	files, err := grepFiles(searchPath, opts)
 	if err != nil {
-		return &tools.Result{
-			Success: false,
-			Error:   err,
-		}, nil
+		return fail(err)
+	}
+	matches, unsearched, err := searchFiles(ctx, files, opts)
+	if err != nil {
+		return fail(err)
 	}
+	results, truncated := grepResults(matches, opts)
+	truncated = truncated || unsearched
 
 	return &tools.Result{
 		Success: true,
 		Output:  results,
 		Metadata: map[string]interface{}{
-			"pattern":     pattern,
-			"path":        searchPath,
-			"output_mode": outputMode,
-			"match_count": countMatches(results),
+			"pattern":        params["pattern"],
+			"path":           searchPath,
+			"output_mode":    opts.outputMode,
+			"match_count":    countMatches(results),
+			"files_searched": len(files),
+			"truncated":      truncated,
 		},
 		Duration: time.Since(startTime),
 	}, nil
@@ -195,174 +320,315 @@ func (t *GrepTool) IsExternal() bool {
 	return false
 }
 
-// grepSearch performs the search operation.
-func grepSearch(searchPath string, re *regexp.Regexp, outputMode, fileGlob string, contextBefore, contextAfter int, showLineNumbers bool) (interface{}, error) {
+// intParam returns an integer parameter, which arrives as a float64 from
+// JSON, or 0.
+func intParam(params map[string]interface{}, name string) int {
+	switch v := params[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// grepFiles returns the files to search under searchPath, in path order:
+// the path itself if it is a file, else the files of the directory tree
+// that pass the filters, without those the ignore files exclude if
+// opts.respectIgnore is set. .git is always skipped, and so is
+// node_modules when ignore files are respected.
+func grepFiles(searchPath string, opts grepOptions) ([]string, error) {
 	info, err := os.Stat(searchPath)
 	if err != nil {
 		return nil, err
 	}
+	if !info.IsDir() {
+		return []string{searchPath}, nil
+	}
 
-	var files []string
-	if info.IsDir() {
-		// Walk directory
-		err := filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil // Skip errors
-			}
+	var rules ignoreRules
+	if opts.respectIgnore {
+		rules = parentIgnoreRules(searchPath)
+	}
 
-			if info.IsDir() {
-				return nil
+	var files []string
+	var walk func(dir, rel string, rules ignoreRules)
+	walk = func(dir, rel string, rules ignoreRules) {
+		if opts.respectIgnore {
+			rules = rules.withDir(dir, rel)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return // Skip unreadable directories
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			path := filepath.Join(dir, name)
+			entryRel := name
+			if rel != "" {
+				entryRel = rel + "/" + name
 			}
 
-			// Check file glob
-			if fileGlob != "" {
-				matched, _ := filepath.Match(fileGlob, filepath.Base(path))
-				if !matched {
-					return nil
+			if entry.IsDir() {
+				if name == ".git" || (opts.respectIgnore && (name == "node_modules" || rules.ignored(entryRel, true))) {
+					continue
 				}
+				walk(path, entryRel, rules)
+				continue
 			}
-
-			// Skip binary files (simple check)
-			if !isTextFile(path) {
-				return nil
+			if !entry.Type().IsRegular() {
+				continue // Symlinks, devices and sockets
+			}
+			if opts.respectIgnore && rules.ignored(entryRel, false) {
+				continue
+			}
+			if opts.fileGlob != "" && !matchesGlob(opts.fileGlob, name, entryRel) {
+				continue
+			}
+			if len(opts.types) > 0 && !matchesAny(opts.types, name) {
+				continue
 			}
-
 			files = append(files, path)
-			return nil
-		})
-
-		if err != nil {
-			return nil, err
 		}
-	} else {
-		files = []string{searchPath}
 	}
+	walk(searchPath, "", rules)
+	return files, nil
+}
 
-	// Search in files based on output mode
-	switch outputMode {
-	case "files_with_matches":
-		return filesWithMatches(files, re)
+// matchesGlob reports whether a file matches a file_glob: by name, or by
+// its path from the search root when the glob has a slash.
+func matchesGlob(glob, name, rel string) bool {
+	if strings.Contains(glob, "/") {
+		matched, _ := regexp.MatchString("^"+globRegexp(strings.TrimPrefix(glob, "/"))+"$", rel)
+		return matched
+	}
+	matched, _ := filepath.Match(glob, name)
+	return matched
+}
 
-	case "count":
-		return matchCounts(files, re)
+// matchesAny reports whether name matches one of globs.
+func matchesAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if matched, _ := filepath.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
 
-	case "content":
-		return contentMatches(files, re, contextBefore, contextAfter, showLineNumbers)
+// fileMatches holds what one file's search found.
+type fileMatches struct {
+	file  string
+	count int                      // Number of matches
+	lines []map[string]interface{} // Matches in content mode
+}
 
+// entries returns how many entries the file adds to the output, for
+// head_limit.
+func (f *fileMatches) entries(outputMode string) int {
+	switch {
+	case f.count == 0:
+		return 0
+	case outputMode == "content":
+		return len(f.lines)
 	default:
-		return nil, fmt.Errorf("invalid output_mode: %s", outputMode)
+		return 1
 	}
 }
 
-// filesWithMatches returns list of files containing matches.
-func filesWithMatches(files []string, re *regexp.Regexp) ([]string, error) {
-	var matches []string
+// searchFiles searches files in parallel, returning what each found in
+// file order. Once the files searched in order hold head_limit entries, no
+// more are started; it reports whether any were left unsearched.
+func searchFiles(ctx context.Context, files []string, opts grepOptions) ([]*fileMatches, bool, error) {
+	type searched struct {
+		index   int
+		matches *fileMatches
+	}
+	results := make([]*fileMatches, len(files))
+	jobs := make(chan int)
+	done := make(chan searched)
+	for w := 0; w < min(runtime.NumCPU(), len(files)); w++ {
+		go func() {
+			for i := range jobs {
+				done <- searched{i, searchFile(files[i], opts)}
+			}
+		}()
+	}
 
-	for _, file := range files {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			continue // Skip unreadable files
+	next, inOrder, entries, running := 0, 0, 0, 0
+	stop := false
+	for {
+		var send chan int
+		if next < len(files) && !stop {
+			send = jobs
 		}
-
-		if re.Match(content) {
-			matches = append(matches, file)
+		if send == nil && running == 0 {
+			break
+		}
+		select {
+		case send <- next:
+			next++
+			running++
+		case r := <-done:
+			running--
+			results[r.index] = r.matches
+			for inOrder < next && results[inOrder] != nil {
+				entries += results[inOrder].entries(opts.outputMode)
+				inOrder++
+			}
+			if (opts.headLimit > 0 && entries >= opts.headLimit) || ctx.Err() != nil {
+				stop = true
+			}
 		}
 	}
+	close(jobs)
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
 
-	return matches, nil
+	var out []*fileMatches
+	for _, r := range results {
+		if r != nil && r.count > 0 {
+			out = append(out, r)
+		}
+	}
+	return out, next < len(files), nil
 }
 
-// matchCounts returns match counts per file.
-func matchCounts(files []string, re *regexp.Regexp) (map[string]int, error) {
-	counts := make(map[string]int)
+// searchFile searches one file. Unreadable, binary and oversized files
+// have no matches.
+func searchFile(path string, opts grepOptions) *fileMatches {
+	result := &fileMatches{file: path}
+	if info, err := os.Stat(path); err != nil || info.Size() > maxGrepFileSize {
+		return result
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0 {
+		return result
+	}
+
+	if opts.multiline {
+		spans := opts.re.FindAllIndex(content, -1)
+		result.count = len(spans)
+		if opts.outputMode == "content" && len(spans) > 0 {
+			result.lines = multilineMatches(path, content, spans, opts)
+		}
+		return result
+	}
 
-	for _, file := range files {
-		content, err := os.ReadFile(file)
-		if err != nil {
+	// The whole file is checked first, as most files don't match
+	if !opts.prefilter.Match(content) {
+		return result
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		n := len(opts.re.FindAllStringIndex(line, -1))
+		if n == 0 {
 			continue
 		}
-
-		matches := re.FindAll(content, -1)
-		if len(matches) > 0 {
-			counts[file] = len(matches)
+		result.count += n
+		if opts.outputMode != "content" {
+			continue
+		}
+		match := map[string]interface{}{
+			"file": path,
+			"line": i + 1,
+			"text": line,
 		}
+		if opts.contextBefore > 0 || opts.contextAfter > 0 {
+			match["context"] = contextLines(lines, i, i, opts)
+		}
+		result.lines = append(result.lines, match)
 	}
-
-	return counts, nil
+	return result
 }
 
-// contentMatches returns matching lines with context.
-func contentMatches(files []string, re *regexp.Regexp, contextBefore, contextAfter int, showLineNumbers bool) ([]map[string]interface{}, error) {
-	var results []map[string]interface{}
-
-	for _, file := range files {
-		f, err := os.Open(file)
-		if err != nil {
-			continue
+// multilineMatches returns a content entry for each match span, with the
+// text of the lines it covers.
+func multilineMatches(path string, content []byte, spans [][]int, opts grepOptions) []map[string]interface{} {
+	text := string(content)
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	var matches []map[string]interface{}
+	for _, span := range spans {
+		first := strings.Count(text[:span[0]], "\n")
+		last := first + strings.Count(strings.TrimSuffix(text[span[0]:span[1]], "\n"), "\n")
+		last = min(last, len(lines)-1)
+		match := map[string]interface{}{
+			"file": path,
+			"line": first + 1,
+			"text": strings.Join(lines[first:last+1], "\n"),
 		}
-
-		scanner := bufio.NewScanner(f)
-		lineNum := 0
-		var lines []string
-
-		// Read all lines for context support
-		for scanner.Scan() {
-			lines = append(lines, scanner.Text())
+		if last > first {
+			match["end_line"] = last + 1
 		}
-		f.Close()
-
-		// Find matches
-		for i, line := range lines {
-			lineNum = i + 1
-
-			if re.MatchString(line) {
-				match := map[string]interface{}{
-					"file": file,
-					"line": lineNum,
-					"text": line,
-				}
-
-				// Add context lines
-				if contextBefore > 0 || contextAfter > 0 {
-					context := make([]string, 0)
+		if opts.contextBefore > 0 || opts.contextAfter > 0 {
+			match["context"] = contextLines(lines, first, last, opts)
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
 
-					// Before context
-					for j := max(0, i-contextBefore); j < i; j++ {
-						context = append(context, lines[j])
-					}
+// contextLines returns lines first to last with the requested context
+// around them.
+func contextLines(lines []string, first, last int, opts grepOptions) []string {
+	from := max(0, first-opts.contextBefore)
+	to := min(len(lines), last+opts.contextAfter+1)
+	context := make([]string, 0, to-from)
+	for _, line := range lines[from:to] {
+		context = append(context, strings.TrimSuffix(line, "\r"))
+	}
+	return context
+}
 
-					// Match line
-					context = append(context, line)
+// grepResults builds the output for the mode from what the files matched,
+// keeping the first head_limit entries. It reports whether any were
+// dropped.
+func grepResults(matches []*fileMatches, opts grepOptions) (interface{}, bool) {
+	limit := opts.headLimit
+	truncated := false
+	full := func(n int) bool {
+		if limit > 0 && n >= limit {
+			truncated = true
+			return true
+		}
+		return false
+	}
 
-					// After context
-					for j := i + 1; j < min(len(lines), i+contextAfter+1); j++ {
-						context = append(context, lines[j])
-					}
+	switch opts.outputMode {
+	case "count":
+		counts := make(map[string]int)
+		for _, m := range matches {
+			if full(len(counts)) {
+				break
+			}
+			counts[m.file] = m.count
+		}
+		return counts, truncated
 
-					match["context"] = context
+	case "content":
+		var results []map[string]interface{}
+		for _, m := range matches {
+			for _, line := range m.lines {
+				if full(len(results)) {
+					return results, truncated
 				}
-
-				results = append(results, match)
+				results = append(results, line)
 			}
 		}
-	}
-
-	return results, nil
-}
+		return results, truncated
 
-// isTextFile performs a simple check if file is text.
-func isTextFile(path string) bool {
-	// Check extension
-	ext := strings.ToLower(filepath.Ext(path))
-	textExts := map[string]bool{
-		".go": true, ".txt": true, ".md": true, ".js": true, ".ts": true,
-		".jsx": true, ".tsx": true, ".json": true, ".yaml": true, ".yml": true,
-		".toml": true, ".xml": true, ".html": true, ".css": true, ".scss": true,
-		".py": true, ".rb": true, ".java": true, ".c": true, ".cpp": true,
-		".h": true, ".hpp": true, ".rs": true, ".sh": true, ".bash": true,
+	default:
+		var files []string
+		for _, m := range matches {
+			if full(len(files)) {
+				break
+			}
+			files = append(files, m.file)
+		}
+		return files, truncated
 	}
-
-	return textExts[ext]
 }
 
 // countMatches counts the total number of matches in results.
@@ -381,17 +647,3 @@ func countMatches(results interface{}) int {
 	}
 	return 0
 }
-
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
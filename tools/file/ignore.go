@@ -0,0 +1,200 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFiles are the files whose patterns exclude paths from searches, in
+// each directory: git's, then bplus's own for files git tracks but the
+// agent should not see.
+var ignoreFiles = []string{".gitignore", ".bplusignore"}
+
+// ignoreRule is one pattern of an ignore file.
+type ignoreRule struct {
+	re       *regexp.Regexp
+	base     string // Slash-separated directory of the ignore file, relative to the search root
+	negate   bool   // !pattern re-includes what earlier rules excluded
+	dirOnly  bool   // pattern/ matches directories only
+	anchored bool   // Matched against the path from base rather than the name
+	above    string // For rules of directories above the search root, the path from there to it
+}
+
+// ignoreRules holds the rules in force in a directory: those of its ignore
+// files and of every directory above it, in order, the last match winning.
+type ignoreRules []ignoreRule
+
+// ignored reports whether rel, a slash-separated path relative to the
+// search root, is excluded by the rules.
+func (r ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	name := rel[strings.LastIndexByte(rel, '/')+1:]
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		subject := name
+		if rule.anchored {
+			var ok bool
+			if subject, ok = relativeTo(rule.base, rel); !ok {
+				continue
+			}
+			if rule.above != "" {
+				subject = rule.above + "/" + subject
+			}
+		}
+		if rule.re.MatchString(subject) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// relativeTo returns rel relative to base, if it is inside it.
+func relativeTo(base, rel string) (string, bool) {
+	if base == "" {
+		return rel, true
+	}
+	rest, ok := strings.CutPrefix(rel, base+"/")
+	return rest, ok
+}
+
+// withDir returns the rules with those of dir's ignore files added. base is
+// dir relative to the search root.
+func (r ignoreRules) withDir(dir, base string) ignoreRules {
+	out := r
+	for _, name := range ignoreFiles {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if len(out) == len(r) {
+			out = append(ignoreRules(nil), r...)
+		}
+		out = append(out, parseIgnore(string(content), base)...)
+	}
+	return out
+}
+
+// parentIgnoreRules returns the rules that apply to root from the ignore
+// files of the directories above it, up to the repository root, so
+// searching a subdirectory excludes what searching the repository would.
+func parentIgnoreRules(root string) ignoreRules {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(abs, ".git")); err == nil {
+		return nil // root is the repository
+	}
+	var dirs []string
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			return nil // Not in a repository
+		}
+	}
+
+	var rules ignoreRules
+	for i := len(dirs) - 1; i >= 0; i-- {
+		above, err := filepath.Rel(dirs[i], abs)
+		if err != nil {
+			continue
+		}
+		for _, rule := range ignoreRules(nil).withDir(dirs[i], "") {
+			rule.above = filepath.ToSlash(above)
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnore parses the patterns of an ignore file in base with git's
+// syntax: # comments, ! negation, a trailing / for directories only, and
+// *, ?, [...] and ** globs, anchored when the pattern contains a slash
+// other than at its end.
+func parseIgnore(content, base string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			rule.negate = true
+			line = rest
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if rest, ok := strings.CutSuffix(line, "/"); ok {
+			rule.dirOnly = true
+			line = rest
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		re, err := regexp.Compile("^" + globRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globRegexp translates a gitignore glob into a regular expression over
+// slash-separated paths.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,317 @@
+package file
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxSignatureLen bounds a symbol's signature in an outline.
+const maxSignatureLen = 120
+
+// Symbol is a top-level declaration of a source file.
+type Symbol struct {
+	Kind      string // func, method, type, class, interface, ...
+	Name      string
+	Signature string // The declaration, without its body
+	Line      int
+	Exported  bool // Visible outside its file or package
+}
+
+// outlinePattern matches a declaration line of one kind; its name group
+// is the symbol's name.
+type outlinePattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// pattern compiles an outline pattern.
+func pattern(kind, expr string) outlinePattern {
+	return outlinePattern{kind: kind, re: regexp.MustCompile(expr)}
+}
+
+var (
+	pythonOutline = []outlinePattern{
+		pattern("class", `^class\s+(?P<name>\w+)`),
+		pattern("func", `^(?:async\s+)?def\s+(?P<name>\w+)`),
+		pattern("method", `^    (?:async\s+)?def\s+(?P<name>\w+)`),
+	}
+	scriptOutline = []outlinePattern{
+		pattern("class", `^(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(?P<name>\w+)`),
+		pattern("func", `^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\*?\s+(?P<name>\w+)`),
+		pattern("interface", `^(?:export\s+)?interface\s+(?P<name>\w+)`),
+		pattern("type", `^(?:export\s+)?type\s+(?P<name>\w+)`),
+		pattern("enum", `^(?:export\s+)?(?:const\s+)?enum\s+(?P<name>\w+)`),
+		pattern("func", `^(?:export\s+)?(?:const|let|var)\s+(?P<name>\w+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function|\([^)]*\)\s*(?::[^=]+)?=>|\w+\s*=>)`),
+		pattern("const", `^export\s+(?:const|let|var)\s+(?P<name>\w+)`),
+	}
+	rustOutline = []outlinePattern{
+		pattern("func", `^(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"\w+"\s+)?fn\s+(?P<name>\w+)`),
+		pattern("method", `^    (?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(?P<name>\w+)`),
+		pattern("struct", `^(?:pub(?:\([^)]*\))?\s+)?struct\s+(?P<name>\w+)`),
+		pattern("enum", `^(?:pub(?:\([^)]*\))?\s+)?enum\s+(?P<name>\w+)`),
+		pattern("trait", `^(?:pub(?:\([^)]*\))?\s+)?(?:unsafe\s+)?trait\s+(?P<name>\w+)`),
+		pattern("type", `^(?:pub(?:\([^)]*\))?\s+)?type\s+(?P<name>\w+)`),
+		pattern("impl", `^(?:unsafe\s+)?impl(?:<[^>]*>)?\s+(?P<name>[\w:<>, ]+?)\s*(?:\{|where|$)`),
+		pattern("mod", `^(?:pub(?:\([^)]*\))?\s+)?mod\s+(?P<name>\w+)`),
+	}
+	classOutline = []outlinePattern{
+		pattern("class", `^(?:\s{0,4})(?:(?:public|protected|private|internal|static|abstract|final|sealed|open|data|partial)\s+)*(?:class|record|object)\s+(?P<name>\w+)`),
+		pattern("interface", `^(?:\s{0,4})(?:(?:public|protected|private|internal|sealed)\s+)*interface\s+(?P<name>\w+)`),
+		pattern("enum", `^(?:\s{0,4})(?:(?:public|protected|private|internal)\s+)*enum\s+(?:class\s+)?(?P<name>\w+)`),
+		pattern("method", `^ {4}(?:(?:public|protected|private|internal|static|final|abstract|override|suspend|async|virtual|synchronized)\s+)+[\w<>\[\], ?]*?\s*(?:fun\s+)?(?P<name>\w+)\s*\([^;]*$`),
+		pattern("func", `^fun\s+(?:<[^>]*>\s*)?(?P<name>[\w.]+)\s*\(`),
+	}
+	rubyOutline = []outlinePattern{
+		pattern("class", `^\s*class\s+(?P<name>[\w:]+)`),
+		pattern("module", `^\s*module\s+(?P<name>[\w:]+)`),
+		pattern("method", `^\s*def\s+(?P<name>[\w.?!=]+)`),
+	}
+	phpOutline = []outlinePattern{
+		pattern("class", `^(?:(?:abstract|final)\s+)?class\s+(?P<name>\w+)`),
+		pattern("interface", `^interface\s+(?P<name>\w+)`),
+		pattern("trait", `^trait\s+(?P<name>\w+)`),
+		pattern("func", `^function\s+(?P<name>\w+)`),
+		pattern("method", `^    (?:(?:public|protected|private|static|abstract|final)\s+)*function\s+(?P<name>\w+)`),
+	}
+	cOutline = []outlinePattern{
+		pattern("struct", `^(?:typedef\s+)?(?:struct|union)\s+(?P<name>\w+)\s*\{?\s*$`),
+		pattern("enum", `^(?:typedef\s+)?enum\s+(?:class\s+)?(?P<name>\w+)`),
+		pattern("class", `^(?:template\s*<[^>]*>\s*)?class\s+(?P<name>\w+)`),
+		pattern("namespace", `^namespace\s+(?P<name>[\w:]+)`),
+		pattern("func", `^(?:static\s+|inline\s+|extern\s+|virtual\s+|const\s+|unsigned\s+|signed\s+)*[A-Za-z_][\w:<>,\s\*&]*?[\s\*&]+(?P<name>[A-Za-z_][\w:~]*)\s*\([^;]*$`),
+	}
+	shellOutline = []outlinePattern{
+		pattern("func", `^(?:function\s+)?(?P<name>[\w-]+)\s*\(\)\s*\{?`),
+	}
+
+	// outlineLanguages are the outline patterns of each file extension
+	// without a parser of its own. A line gets the first pattern it matches.
+	outlineLanguages = map[string][]outlinePattern{
+		".py": pythonOutline, ".pyi": pythonOutline,
+		".js": scriptOutline, ".jsx": scriptOutline, ".mjs": scriptOutline, ".cjs": scriptOutline,
+		".ts": scriptOutline, ".tsx": scriptOutline, ".mts": scriptOutline, ".cts": scriptOutline,
+		".rs":   rustOutline,
+		".java": classOutline, ".kt": classOutline, ".kts": classOutline, ".cs": classOutline, ".scala": classOutline,
+		".swift": classOutline, ".dart": classOutline,
+		".rb":  rubyOutline,
+		".php": phpOutline,
+		".c":   cOutline, ".h": cOutline, ".cc": cOutline, ".cpp": cOutline, ".cxx": cOutline, ".hpp": cOutline, ".hh": cOutline,
+		".sh": shellOutline, ".bash": shellOutline, ".zsh": shellOutline,
+	}
+
+	// notFunctions are C keywords the function pattern would take for names.
+	notFunctions = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "return": true, "sizeof": true, "else": true}
+)
+
+// hasOutline reports whether outlineFile knows the language of path.
+func hasOutline(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	_, ok := outlineLanguages[ext]
+	return ok || ext == ".go"
+}
+
+// outlineFile returns the top-level symbols of a source file: parsed for
+// Go, matched line by line against declaration patterns for the other
+// languages hasOutline knows, which finds declarations at the top level and
+// the methods of top-level classes as conventionally indented.
+func outlineFile(path string, content []byte) []Symbol {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".go" {
+		return outlineGo(path, content)
+	}
+	patterns, ok := outlineLanguages[ext]
+	if !ok {
+		return nil
+	}
+
+	var symbols []Symbol
+	inComment := false
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		// Skip block comments and docstrings, which quote code
+		if inComment {
+			if strings.Contains(trimmed, "*/") || strings.HasSuffix(trimmed, `"""`) {
+				inComment = false
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "/*") && !strings.Contains(trimmed, "*/") {
+			inComment = true
+			continue
+		}
+		if strings.HasPrefix(trimmed, `"""`) && (len(trimmed) == 3 || !strings.HasSuffix(trimmed[3:], `"""`)) {
+			inComment = true
+			continue
+		}
+
+		for _, p := range patterns {
+			m := p.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := strings.TrimSpace(m[p.re.SubexpIndex("name")])
+			if notFunctions[name] {
+				break
+			}
+			symbols = append(symbols, Symbol{
+				Kind:      p.kind,
+				Name:      name,
+				Signature: signature(trimmed),
+				Line:      i + 1,
+				Exported:  exportedName(ext, name, trimmed),
+			})
+			break
+		}
+	}
+	return symbols
+}
+
+// exportedName reports whether a symbol is public, by the conventions of
+// its language.
+func exportedName(ext, name, line string) bool {
+	switch ext {
+	case ".py", ".pyi", ".rb":
+		return !strings.HasPrefix(name, "_")
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+		return strings.HasPrefix(line, "export")
+	case ".rs":
+		return strings.HasPrefix(line, "pub") || strings.HasPrefix(line, "impl")
+	case ".java", ".kt", ".kts", ".cs", ".scala", ".swift", ".dart", ".php":
+		return !strings.Contains(line, "private ")
+	case ".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".hh":
+		return !strings.HasPrefix(line, "static ")
+	}
+	return true
+}
+
+// signature shortens a declaration line to its signature, dropping a body
+// that starts on it.
+func signature(line string) string {
+	if i := strings.Index(line, ") {"); i >= 0 {
+		line = line[:i+1]
+	} else if i := strings.Index(line, " {"); i >= 0 && !strings.Contains(line[:i], "(") {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(strings.TrimRight(line, "{:= "))
+	if len(line) > maxSignatureLen {
+		line = line[:maxSignatureLen] + "…"
+	}
+	return line
+}
+
+// outlineGo returns the top-level declarations of a Go file. Files that
+// don't parse have none.
+func outlineGo(path string, content []byte) []Symbol {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var symbols []Symbol
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			kind := "func"
+			if d.Recv != nil {
+				kind = "method"
+			}
+			sig := *d
+			sig.Body, sig.Doc = nil, nil
+			symbols = append(symbols, Symbol{
+				Kind:      kind,
+				Name:      d.Name.Name,
+				Signature: signature(goSource(fset, &sig)),
+				Line:      fset.Position(d.Pos()).Line,
+				Exported:  d.Name.IsExported() && (d.Recv == nil || exportedReceiver(d.Recv)),
+			})
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					kind := "type"
+					switch s.Type.(type) {
+					case *ast.StructType:
+						kind = "struct"
+					case *ast.InterfaceType:
+						kind = "interface"
+					}
+					symbols = append(symbols, Symbol{
+						Kind:      kind,
+						Name:      s.Name.Name,
+						Signature: signature("type " + s.Name.Name + " " + goTypeSummary(fset, s)),
+						Line:      fset.Position(s.Pos()).Line,
+						Exported:  s.Name.IsExported(),
+					})
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Name == "_" {
+							continue
+						}
+						symbols = append(symbols, Symbol{
+							Kind:      d.Tok.String(),
+							Name:      name.Name,
+							Signature: d.Tok.String() + " " + name.Name,
+							Line:      fset.Position(name.Pos()).Line,
+							Exported:  name.IsExported(),
+						})
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// exportedReceiver reports whether a method's receiver type is exported.
+func exportedReceiver(recv *ast.FieldList) bool {
+	if len(recv.List) == 0 {
+		return false
+	}
+	t := recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// goTypeSummary describes a type declaration without its fields or methods.
+func goTypeSummary(fset *token.FileSet, s *ast.TypeSpec) string {
+	switch s.Type.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	}
+	src := goSource(fset, s.Type)
+	if i := strings.IndexByte(src, '\n'); i >= 0 {
+		src = src[:i]
+	}
+	return src
+}
+
+// goSource prints a node as Go source.
+func goSource(fset *token.FileSet, node any) string {
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, fset, node); err != nil {
+		return ""
+	}
+	return b.String()
+}
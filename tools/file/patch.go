@@ -0,0 +1,420 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// PatchTool applies unified diffs, for models that write changes as diffs
+// rather than as old and new strings.
+type PatchTool struct{}
+
+// NewPatchTool creates a new Patch tool.
+func NewPatchTool() *PatchTool {
+	return &PatchTool{}
+}
+
+// Name returns the tool name.
+func (t *PatchTool) Name() string {
+	return "patch"
+}
+
+// Description returns the tool description.
+func (t *PatchTool) Description() string {
+	return "Applies a unified diff to one or more files, creating, deleting or renaming files as the diff says. " +
+		"Hunks that have moved or whose context differs slightly are still applied, and reported. " +
+		"Nothing is changed unless every hunk applies."
+}
+
+// Parameters returns the tool parameters.
+func (t *PatchTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "patch",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Unified diff as written by diff -u or git diff, with ---/+++ headers and @@ hunks",
+		},
+		{
+			Name:        "working_dir",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Absolute directory the paths in the diff are relative to (default: current directory)",
+		},
+		{
+			Name:        "dry_run",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Check that the diff applies and show the changes it would make without writing them",
+			Default:     false,
+		},
+	}
+}
+
+// RequiresPermission returns true as patching changes files.
+func (t *PatchTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the files in the permission prompt.
+func (t *PatchTool) DescribeResource(params map[string]interface{}) string {
+	verb := "patch"
+	if dryRun, _ := params["dry_run"].(bool); dryRun {
+		verb = "check patch of"
+	}
+	changes, err := t.plan(params)
+	if err != nil {
+		return "patch files"
+	}
+	switch len(changes) {
+	case 1:
+		return fmt.Sprintf("%s %s", verb, changes[0].path)
+	default:
+		return fmt.Sprintf("%s %d files: %s and %d more", verb, len(changes), changes[0].path, len(changes)-1)
+	}
+}
+
+// Preview returns the diff as it will apply, with the files' own context
+// lines and where each hunk matched.
+func (t *PatchTool) Preview(ctx context.Context, params map[string]interface{}) (string, error) {
+	changes, err := t.plan(params)
+	if err != nil {
+		return "", err
+	}
+	return combinedDiff(changes), nil
+}
+
+// fileChange is what a patch does to one file.
+type fileChange struct {
+	path    string // File written, or removed if remove is set
+	from    string // File renamed to path, if any
+	remove  bool
+	content string
+	hunks   []appliedHunk
+	diff    string
+}
+
+// Execute applies the patch.
+func (t *PatchTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	changes, err := t.plan(params)
+	if err != nil {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	hunks := 0
+	var notes []string
+	for _, c := range changes {
+		for i, h := range c.hunks {
+			hunks++
+			if note := adjustment(h); note != "" {
+				notes = append(notes, fmt.Sprintf("%s: hunk %d applied with %s", filepath.Base(c.path), i+1, note))
+			}
+		}
+	}
+	diff := combinedDiff(changes)
+
+	if dryRun, _ := params["dry_run"].(bool); dryRun {
+		output := fmt.Sprintf("Patch applies to %d file(s), %d hunk(s)", len(changes), hunks)
+		if len(notes) > 0 {
+			output += ":\n" + strings.Join(notes, "\n")
+		}
+		return &tools.Result{
+			Success: true,
+			Output:  output + "\n\n" + diff,
+			Metadata: map[string]interface{}{
+				"dry_run":  true,
+				"files":    len(changes),
+				"hunks":    hunks,
+				"adjusted": len(notes),
+				"diff":     diff,
+			},
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	var done []original
+	var paths []string
+	for i, c := range changes {
+		err := ctx.Err()
+		if err == nil {
+			var origs []original
+			origs, err = applyChange(ctx, c, i, len(changes))
+			done = append(done, origs...)
+			if err == nil {
+				paths = append(paths, c.path)
+				if c.from != "" {
+					paths = append(paths, c.from)
+				}
+				tools.ReportProgress(ctx, tools.Progress{Path: c.path, Files: i + 1, TotalFiles: len(changes)})
+				continue
+			}
+		}
+
+		rollbackErr := rollback(done)
+		reason := fmt.Errorf("failed to patch %s: %w", c.path, err)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			reason = fmt.Errorf("cancelled after %d of %d files", i, len(changes))
+		}
+		if rollbackErr != nil {
+			reason = fmt.Errorf("%w; rollback incomplete: %v", reason, rollbackErr)
+		} else if len(done) > 0 {
+			reason = fmt.Errorf("%w; the files changed were restored", reason)
+		}
+		return &tools.Result{
+			Success: false,
+			Error:   reason,
+			Metadata: map[string]interface{}{
+				"paths":       []string{},
+				"rolled_back": len(done),
+			},
+			Duration: time.Since(startTime),
+		}, nil
+	}
+	sort.Strings(paths)
+
+	output := fmt.Sprintf("Applied %d hunk(s) to %d file(s)", hunks, len(changes))
+	if len(notes) > 0 {
+		output += ":\n" + strings.Join(notes, "\n")
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  output,
+		Metadata: map[string]interface{}{
+			"paths":    paths,
+			"files":    len(changes),
+			"hunks":    hunks,
+			"adjusted": len(notes),
+			"diff":     diff,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// plan works out what the patch does to each file without changing any.
+func (t *PatchTool) plan(params map[string]interface{}) ([]fileChange, error) {
+	text, _ := params["patch"].(string)
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("patch is empty")
+	}
+	dir, _ := params["working_dir"].(string)
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		dir = wd
+	}
+	dir = filepath.Clean(dir)
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("working_dir must be absolute")
+	}
+
+	patches, err := parsePatch(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	var changes []fileChange
+	pending := make(map[string]string) // Content of files changed earlier in the patch
+	for _, p := range patches {
+		source, target, err := patchPaths(dir, p)
+		if err != nil {
+			return nil, err
+		}
+
+		var base string
+		switch content, ok := pending[source]; {
+		case source == "":
+			if _, err := os.Stat(target); err == nil {
+				return nil, fmt.Errorf("%s: the patch creates it, but it already exists", relName(dir, target))
+			}
+		case ok:
+			base = content
+		default:
+			data, err := os.ReadFile(source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", relName(dir, source), err)
+			}
+			base = string(data)
+		}
+
+		content, applied, err := applyHunks(base, p.hunks)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", relName(dir, firstNonEmpty(source, target)), err)
+		}
+
+		c := fileChange{path: target, content: content, hunks: applied}
+		oldName, newName := "/dev/null", "/dev/null"
+		if source != "" {
+			oldName = "a/" + relName(dir, source)
+		}
+		if target != "" {
+			newName = "b/" + relName(dir, target)
+		}
+		switch {
+		case target == "":
+			if content != "" {
+				return nil, fmt.Errorf("%s: the patch deletes it, but %d line(s) would be left", relName(dir, source), len(strings.Split(strings.TrimSuffix(content, "\n"), "\n")))
+			}
+			c.path, c.remove = source, true
+		case source != "" && source != target:
+			c.from = source
+		}
+		c.diff = formatDiff(oldName, newName, applied)
+		pending[c.path] = content
+		if c.from != "" {
+			delete(pending, c.from)
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// patchPaths returns the absolute source and target of a file patch, ""
+// for /dev/null. git's a/ and b/ prefixes are dropped unless only the
+// prefixed path exists. Paths must be inside dir.
+func patchPaths(dir string, p filePatch) (string, string, error) {
+	oldPath, newPath := p.oldPath, p.newPath
+	if (oldPath == "" || strings.HasPrefix(oldPath, "a/")) && (newPath == "" || strings.HasPrefix(newPath, "b/")) {
+		name := firstNonEmpty(oldPath, newPath)
+		if !exists(filepath.Join(dir, name)) || exists(filepath.Join(dir, name[2:])) {
+			oldPath, newPath = strings.TrimPrefix(oldPath, "a/"), strings.TrimPrefix(newPath, "b/")
+		}
+	}
+
+	resolve := func(name string) (string, error) {
+		if name == "" {
+			return "", nil
+		}
+		path := filepath.Clean(name)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("%s is outside %s", name, dir)
+		}
+		return path, nil
+	}
+	source, err := resolve(oldPath)
+	if err != nil {
+		return "", "", err
+	}
+	target, err := resolve(newPath)
+	if err != nil {
+		return "", "", err
+	}
+	return source, target, nil
+}
+
+// applyChange writes, removes or renames the i-th of n files, returning
+// what it changed so it can be rolled back.
+func applyChange(ctx context.Context, c fileChange, i, n int) ([]original, error) {
+	if c.remove {
+		return removeFile(c.path)
+	}
+	orig, err := writeOne(ctx, c.path, c.content, true, i, n)
+	if err != nil {
+		return nil, err
+	}
+	done := []original{orig}
+	if c.from != "" {
+		removed, err := removeFile(c.from)
+		done = append(done, removed...)
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// removeFile removes a file, returning what it held.
+func removeFile(path string) ([]original, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	return []original{{path: path, existed: true, content: data, mode: info.Mode().Perm()}}, nil
+}
+
+// adjustment describes how a hunk had to be adapted to apply, or is empty
+// if it applied as given.
+func adjustment(h appliedHunk) string {
+	var parts []string
+	if h.offset != 0 {
+		parts = append(parts, fmt.Sprintf("offset %+d lines", h.offset))
+	}
+	if h.fuzz > 0 {
+		parts = append(parts, fmt.Sprintf("fuzz %d", h.fuzz))
+	}
+	if h.loose {
+		parts = append(parts, "whitespace ignored")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// combinedDiff joins the diffs of each file.
+func combinedDiff(changes []fileChange) string {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.diff)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// relName returns path relative to dir, for messages and diff headers.
+func relName(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Category returns the tool category.
+func (t *PatchTool) Category() string {
+	return "file"
+}
+
+// Version returns the tool version.
+func (t *PatchTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *PatchTool) IsExternal() bool {
+	return false
+}
@@ -0,0 +1,339 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models/tokenizer"
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Repository map limits
+const (
+	// defaultMapTokens is the outline budget when none is given.
+	defaultMapTokens = 4000
+
+	// maxMapTokens bounds the budget the model can ask for.
+	maxMapTokens = 32000
+
+	// maxOutlineFileSize is the size above which files are listed without
+	// their symbols; files that large are usually generated.
+	maxOutlineFileSize = 1 << 20
+)
+
+// RepoMapTool outlines a repository: its source files with their
+// top-level symbols, fitted within a token budget.
+type RepoMapTool struct{}
+
+// NewRepoMapTool creates a new RepoMap tool.
+func NewRepoMapTool() *RepoMapTool {
+	return &RepoMapTool{}
+}
+
+// Name returns the tool name.
+func (t *RepoMapTool) Name() string {
+	return "repomap"
+}
+
+// Description returns the tool description.
+func (t *RepoMapTool) Description() string {
+	return "Outlines the source files of a directory tree with their top-level types, functions and classes and the line each starts on, " +
+		"within a token budget; use it first to orient in an unfamiliar codebase"
+}
+
+// Parameters returns the tool parameters.
+func (t *RepoMapTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "path",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Directory to outline (defaults to current directory)",
+			Default:     ".",
+		},
+		{
+			Name:        "max_tokens",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: fmt.Sprintf("Token budget of the outline (default: %d, max: %d)", defaultMapTokens, maxMapTokens),
+			Default:     defaultMapTokens,
+		},
+		{
+			Name:        "type",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "File types to outline, comma-separated, as for grep (go, ts, py, ...)",
+			Default:     "",
+		},
+		{
+			Name:        "tests",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Include test files (default: false)",
+			Default:     false,
+		},
+		{
+			Name:        "private",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Include unexported and private symbols (default: only when the budget allows after public ones)",
+			Default:     false,
+		},
+	}
+}
+
+// RequiresPermission returns true as reading the workspace requires permission.
+func (t *RepoMapTool) RequiresPermission() bool {
+	return true
+}
+
+// fileOutline is one file of the map.
+type fileOutline struct {
+	path    string // Slash-separated, relative to the mapped directory
+	symbols []Symbol
+}
+
+// Execute outlines the directory.
+func (t *RepoMapTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	root := "."
+	if val, ok := params["path"].(string); ok && val != "" {
+		root = val
+	}
+	budget := intParam(params, "max_tokens")
+	if budget <= 0 {
+		budget = defaultMapTokens
+	}
+	budget = min(budget, maxMapTokens)
+	private, _ := params["private"].(bool)
+	tests, _ := params["tests"].(bool)
+	typeNames, _ := params["type"].(string)
+
+	fail := func(err error) (*tools.Result, error) {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	opts := grepOptions{respectIgnore: true}
+	for _, name := range strings.Split(typeNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		globs, ok := fileTypes[name]
+		if !ok {
+			return fail(fmt.Errorf("unknown file type: %s", name))
+		}
+		opts.types = append(opts.types, globs...)
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return fail(err)
+	}
+	if !info.IsDir() {
+		return fail(fmt.Errorf("%s is not a directory", root))
+	}
+	paths, err := grepFiles(root, opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	var outlines []fileOutline
+	symbolCount := 0
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		if !hasOutline(path) || (!tests && isTestFile(rel)) {
+			continue
+		}
+		outline := fileOutline{path: filepath.ToSlash(rel)}
+		if fi, err := os.Stat(path); err == nil && fi.Size() <= maxOutlineFileSize {
+			if content, err := os.ReadFile(path); err == nil {
+				outline.symbols = outlineFile(path, content)
+			}
+		}
+		symbolCount += len(outline.symbols)
+		outlines = append(outlines, outline)
+	}
+	sortOutlines(outlines)
+
+	text, shown, omitted := renderRepoMap(outlines, budget, private)
+	return &tools.Result{
+		Success: true,
+		Output:  text,
+		Metadata: map[string]interface{}{
+			"path":          root,
+			"files":         len(outlines),
+			"symbols":       symbolCount,
+			"symbols_shown": shown,
+			"omitted_files": omitted,
+			"tokens":        tokenizer.Count("", text),
+			"truncated":     omitted > 0 || shown < symbolCount,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *RepoMapTool) Category() string {
+	return "file"
+}
+
+// Version returns the tool version.
+func (t *RepoMapTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *RepoMapTool) IsExternal() bool {
+	return false
+}
+
+// testFileSuffixes and testFilePrefixes name test files by the conventions
+// of the languages outlined.
+var (
+	testFileSuffixes = []string{"_test.go", "_test.py", "_test.rb", "_spec.rb", "Test.java", "Tests.java", "Test.kt", "Tests.cs"}
+	testFilePrefixes = []string{"test_"}
+)
+
+// isTestFile reports whether path, relative to the mapped directory, is a
+// test file, by its name or by sitting in a tests directory.
+func isTestFile(path string) bool {
+	name := filepath.Base(path)
+	for _, suffix := range testFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	for _, prefix := range testFilePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	if strings.Contains(name, ".test.") || strings.Contains(name, ".spec.") {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if dir == "__tests__" || dir == "tests" || dir == "test" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortOutlines orders files for the map: shallower files first, as entry
+// points and package roots sit near the top of a tree, then by path.
+func sortOutlines(outlines []fileOutline) {
+	sort.SliceStable(outlines, func(i, j int) bool {
+		di, dj := strings.Count(outlines[i].path, "/"), strings.Count(outlines[j].path, "/")
+		if di != dj {
+			return di < dj
+		}
+		return outlines[i].path < outlines[j].path
+	})
+}
+
+// renderRepoMap renders the outlines within budget tokens. Every file gets
+// its public symbols while they fit; once they don't, files are listed by
+// name with a count of their symbols, and once names don't fit either the
+// rest are summarized. Private symbols are added only when private is set
+// or everything public fitted. It returns the map, how many symbols it
+// shows and how many files it leaves out.
+func renderRepoMap(outlines []fileOutline, budget int, private bool) (string, int, int) {
+	if len(outlines) == 0 {
+		return "No source files found.", 0, 0
+	}
+
+	render := func(includePrivate bool) (string, int, int) {
+		var b strings.Builder
+		used, shown := 0, 0
+		namesOnly := false
+		for i, f := range outlines {
+			var block strings.Builder
+			var symbols []Symbol
+			for _, s := range f.symbols {
+				if s.Exported || includePrivate {
+					symbols = append(symbols, s)
+				}
+			}
+			if !namesOnly {
+				if len(symbols) == 0 {
+					block.WriteString(f.path + "\n")
+				} else {
+					block.WriteString(f.path + ":\n")
+				}
+				for _, s := range symbols {
+					fmt.Fprintf(&block, "  %d: %s\n", s.Line, s.Signature)
+				}
+				cost := tokenizer.Count("", block.String())
+				if used+cost <= budget {
+					b.WriteString(block.String())
+					used += cost
+					shown += len(symbols)
+					continue
+				}
+				namesOnly = true
+			}
+
+			line := f.path + "\n"
+			if len(symbols) > 0 {
+				line = fmt.Sprintf("%s (%d symbols)\n", f.path, len(symbols))
+			}
+			cost := tokenizer.Count("", line)
+			if used+cost > budget {
+				fmt.Fprintf(&b, "… %d more files\n", len(outlines)-i)
+				return b.String(), shown, len(outlines) - i
+			}
+			b.WriteString(line)
+			used += cost
+		}
+		return b.String(), shown, 0
+	}
+
+	text, shown, omitted := render(private)
+	if private {
+		return text, shown, omitted
+	}
+	if total := countPublic(outlines); omitted == 0 && shown == total {
+		if full, fullShown, fullOmitted := render(true); fullOmitted == 0 && fullShown == countAll(outlines) {
+			return full, fullShown, 0
+		}
+	}
+	return text, shown, omitted
+}
+
+// countPublic returns how many exported symbols the outlines have.
+func countPublic(outlines []fileOutline) int {
+	n := 0
+	for _, f := range outlines {
+		for _, s := range f.symbols {
+			if s.Exported {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// countAll returns how many symbols the outlines have.
+func countAll(outlines []fileOutline) int {
+	n := 0
+	for _, f := range outlines {
+		n += len(f.symbols)
+	}
+	return n
+}
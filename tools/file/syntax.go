@@ -0,0 +1,632 @@
+package file
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Node is a declaration found by a structural search: a function, method,
+// type or import, with the calls its body makes and the fields it declares.
+type Node struct {
+	Kind      string // func, method, struct, interface, class, type, enum or import
+	Name      string
+	Receiver  string // Type a method belongs to
+	Signature string
+	Line      int
+	EndLine   int
+	Exported  bool
+	Fields    []Field
+	Calls     []Call
+}
+
+// Field is a field of a struct or class, or a member of an interface.
+type Field struct {
+	Name string
+	Decl string // The field's declaration, such as "Timeout time.Duration"
+	Line int
+}
+
+// Call is a call site.
+type Call struct {
+	Callee string // The called expression, such as "os.Open" or "s.db.Exec"
+	Line   int
+	In     string // Function or method making the call, "" at the top level
+}
+
+// syntaxTree is what a structural search knows of a file: its declarations
+// and every call site.
+type syntaxTree struct {
+	nodes []*Node
+	calls []Call
+}
+
+// hasSyntax reports whether parseSyntax knows the language of path.
+func hasSyntax(path string) bool {
+	switch syntaxLanguage(path) {
+	case "go", "py", "ts":
+		return true
+	}
+	return false
+}
+
+// syntaxLanguage returns the structural language of path.
+func syntaxLanguage(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return "go"
+	case ".py", ".pyi":
+		return "py"
+	case ".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs":
+		return "ts"
+	}
+	return ""
+}
+
+// parseSyntax returns the structure of a source file: parsed for Go and
+// scanned for Python and TypeScript/JavaScript, with strings and comments
+// masked so that code quoted in them is not taken for code. Files that
+// don't parse have none.
+func parseSyntax(path string, content []byte) *syntaxTree {
+	switch syntaxLanguage(path) {
+	case "go":
+		return parseGoSyntax(path, content)
+	case "py":
+		return scanPython(string(content))
+	case "ts":
+		return scanScript(string(content))
+	}
+	return &syntaxTree{}
+}
+
+// parseGoSyntax returns the structure of a Go file.
+func parseGoSyntax(path string, content []byte) *syntaxTree {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return &syntaxTree{}
+	}
+	tree := &syntaxTree{}
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+
+	// collectCalls records the calls under n as made by owner.
+	collectCalls := func(n ast.Node, owner *Node) {
+		in := ""
+		if owner != nil {
+			in = qualifiedName(owner)
+		}
+		ast.Inspect(n, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			callee := goCallee(call.Fun)
+			if callee == "" {
+				return true
+			}
+			c := Call{Callee: callee, Line: line(call.Pos()), In: in}
+			tree.calls = append(tree.calls, c)
+			if owner != nil {
+				owner.Calls = append(owner.Calls, c)
+			}
+			return true
+		})
+	}
+
+	for _, spec := range f.Imports {
+		name := strings.Trim(spec.Path.Value, `"`)
+		tree.nodes = append(tree.nodes, &Node{
+			Kind:      "import",
+			Name:      name,
+			Signature: "import " + goSource(fset, spec),
+			Line:      line(spec.Pos()),
+			EndLine:   line(spec.End()),
+			Exported:  true,
+		})
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			node := &Node{
+				Kind:     "func",
+				Name:     d.Name.Name,
+				Line:     line(d.Pos()),
+				EndLine:  line(d.End()),
+				Exported: d.Name.IsExported(),
+			}
+			if d.Recv != nil {
+				node.Kind = "method"
+				node.Receiver = goReceiver(d.Recv)
+				node.Exported = node.Exported && exportedReceiver(d.Recv)
+			}
+			sig := *d
+			sig.Body, sig.Doc = nil, nil
+			node.Signature = signature(goSource(fset, &sig))
+			tree.nodes = append(tree.nodes, node)
+			if d.Body != nil {
+				collectCalls(d.Body, node)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					node := &Node{
+						Kind:      "type",
+						Name:      s.Name.Name,
+						Signature: signature("type " + s.Name.Name + " " + goTypeSummary(fset, s)),
+						Line:      line(s.Pos()),
+						EndLine:   line(s.End()),
+						Exported:  s.Name.IsExported(),
+					}
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						node.Kind = "struct"
+						node.Fields = goFields(fset, t.Fields)
+					case *ast.InterfaceType:
+						node.Kind = "interface"
+						node.Fields = goFields(fset, t.Methods)
+					}
+					tree.nodes = append(tree.nodes, node)
+					collectCalls(s.Type, nil)
+				case *ast.ValueSpec:
+					for _, value := range s.Values {
+						collectCalls(value, nil)
+					}
+				}
+			}
+		}
+	}
+	return tree
+}
+
+// goCallee returns the called expression of a call as written, or "" for
+// calls of function literals and other computed values.
+func goCallee(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name
+	case *ast.SelectorExpr:
+		if x := goCallee(f.X); x != "" {
+			return x + "." + f.Sel.Name
+		}
+		return f.Sel.Name
+	case *ast.IndexExpr:
+		return goCallee(f.X)
+	case *ast.IndexListExpr:
+		return goCallee(f.X)
+	case *ast.ParenExpr:
+		return goCallee(f.X)
+	case *ast.StarExpr:
+		return goCallee(f.X)
+	case *ast.CallExpr:
+		// f()() and s.Get().Do(): the chain up to the last call
+		if x := goCallee(f.Fun); x != "" {
+			return x + "()"
+		}
+	}
+	return ""
+}
+
+// goReceiver returns the name of a method's receiver type.
+func goReceiver(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	t := recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// goFields returns the fields of a struct or the methods and embedded
+// interfaces of an interface.
+func goFields(fset *token.FileSet, list *ast.FieldList) []Field {
+	if list == nil {
+		return nil
+	}
+	var fields []Field
+	for _, f := range list.List {
+		typ := goSource(fset, f.Type)
+		line := fset.Position(f.Pos()).Line
+		if len(f.Names) == 0 {
+			// Embedded: named by its type
+			name := strings.TrimPrefix(typ, "*")
+			name = name[strings.LastIndexByte(name, '.')+1:]
+			fields = append(fields, Field{Name: name, Decl: typ, Line: line})
+			continue
+		}
+		for _, name := range f.Names {
+			decl := name.Name + " " + typ
+			if _, ok := f.Type.(*ast.FuncType); ok {
+				decl = name.Name + strings.TrimPrefix(typ, "func")
+			}
+			fields = append(fields, Field{Name: name.Name, Decl: decl, Line: line})
+		}
+	}
+	return fields
+}
+
+// callPattern matches a call in masked source: a dotted name followed by
+// an opening parenthesis, with TypeScript type arguments between.
+var callPattern = regexp.MustCompile(`(#?[A-Za-z_$][\w$]*(?:\s*\??\.\s*#?[A-Za-z_$][\w$]*)*)\s*(?:<[\w\s,.\[\]|&]*>)?\s*\(`)
+
+// notCallees are keywords callPattern would take for calls.
+var notCallees = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true, "function": true,
+	"elif": true, "and": true, "or": true, "not": true, "in": true, "is": true, "with": true, "assert": true,
+	"lambda": true, "yield": true, "await": true, "del": true, "except": true, "typeof": true,
+	"def": true, "class": true, "async": true, "from": true, "import": true, "raise": true, "new": true, "void": true, "delete": true, "instanceof": true, "case": true, "do": true, "else": true,
+}
+
+// lineCalls returns the callees called on a masked line. skip is a callee
+// to leave out, the name a declaration on the line introduces.
+func lineCalls(line, skip string) []string {
+	var callees []string
+	for _, m := range callPattern.FindAllStringSubmatchIndex(line, -1) {
+		callee := line[m[2]:m[3]]
+		callee = strings.NewReplacer(" ", "", "\t", "", "?.", ".").Replace(callee)
+		if notCallees[callee] || callee == skip {
+			continue
+		}
+		// A declaration's own name: def f(, function f(, class C(
+		before := strings.TrimRight(line[:m[2]], " \t")
+		if strings.HasSuffix(before, "def") || strings.HasSuffix(before, "function") || strings.HasSuffix(before, "class") {
+			continue
+		}
+		callees = append(callees, callee)
+	}
+	return callees
+}
+
+// maskSource blanks the contents of string literals and comments, keeping
+// line breaks and the position of everything else. hashComments selects #
+// comments and triple-quoted strings, as in Python, over // and /* */ and
+// template literals, as in JavaScript.
+func maskSource(src string, hashComments bool) string {
+	out := []byte(src)
+	blank := func(from, to int) {
+		for i := from; i < to && i < len(out); i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case hashComments && c == '#',
+			!hashComments && c == '/' && i+1 < len(src) && src[i+1] == '/':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			blank(i, i+end)
+			i += end - 1
+		case !hashComments && c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			blank(i, i+end+4)
+			i += end + 3
+		case hashComments && (strings.HasPrefix(src[i:], `"""`) || strings.HasPrefix(src[i:], `'''`)):
+			quote := src[i : i+3]
+			end := strings.Index(src[i+3:], quote)
+			if end < 0 {
+				end = len(src) - i - 3
+			}
+			blank(i+3, i+3+end)
+			i += end + 5
+		case c == '"' || c == '\'' || (!hashComments && c == '`'):
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				} else if src[j] == '\n' && c != '`' {
+					break // Unterminated
+				}
+				j++
+			}
+			blank(i+1, j)
+			i = j
+		}
+	}
+	return string(out)
+}
+
+// indentOf returns the width of a line's leading whitespace, tabs counting
+// as four.
+func indentOf(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+var (
+	pyDef      = regexp.MustCompile(`^(\s*)(?:async\s+)?def\s+(\w+)\s*\(`)
+	pyClass    = regexp.MustCompile(`^(\s*)class\s+(\w+)`)
+	pyImport   = regexp.MustCompile(`^import\s+([\w.]+)`)
+	pyFrom     = regexp.MustCompile(`^from\s+([\w.]+)\s+import\b`)
+	pyAttr     = regexp.MustCompile(`\bself\.(\w+)\s*(:[^=]+)?=[^=]`)
+	pyClassVar = regexp.MustCompile(`^(\w+)\s*(:[^=]+)?(=[^=].*)?$`)
+)
+
+// scanPython returns the structure of a Python file, following blocks by
+// indentation. Class fields are the names assigned in the class body and
+// the self attributes its methods assign.
+func scanPython(src string) *syntaxTree {
+	tree := &syntaxTree{}
+	lines := strings.Split(maskSource(src, true), "\n")
+	original := strings.Split(src, "\n")
+
+	type block struct {
+		indent int
+		node   *Node
+	}
+	var stack []block
+	lastCode := 0
+	closeBlocks := func(indent int) {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack[len(stack)-1].node.EndLine = lastCode
+			stack = stack[:len(stack)-1]
+		}
+	}
+	enclosing := func(kinds ...string) *Node {
+		for i := len(stack) - 1; i >= 0; i-- {
+			for _, k := range kinds {
+				if stack[i].node.Kind == k {
+					return stack[i].node
+				}
+			}
+		}
+		return nil
+	}
+	addField := func(class *Node, name, decl string, line int) {
+		for _, f := range class.Fields {
+			if f.Name == name {
+				return
+			}
+		}
+		class.Fields = append(class.Fields, Field{Name: name, Decl: decl, Line: line})
+	}
+
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		number := i + 1
+		indent := indentOf(line)
+		closeBlocks(indent)
+		lastCode = number
+		text := strings.TrimSpace(strings.TrimRight(original[i], "\r"))
+
+		if indent == 0 {
+			if m := pyImport.FindStringSubmatch(trimmed); m != nil {
+				tree.nodes = append(tree.nodes, &Node{Kind: "import", Name: m[1], Signature: text, Line: number, EndLine: number, Exported: true})
+			} else if m := pyFrom.FindStringSubmatch(trimmed); m != nil {
+				tree.nodes = append(tree.nodes, &Node{Kind: "import", Name: m[1], Signature: text, Line: number, EndLine: number, Exported: true})
+			}
+		}
+
+		// Calls on a def line, in default values, run in the enclosing function
+		fn := enclosing("func", "method")
+		declared := ""
+		if m := pyDef.FindStringSubmatch(line); m != nil {
+			node := &Node{Kind: "func", Name: m[2], Signature: signature(text), Line: number, EndLine: number, Exported: !strings.HasPrefix(m[2], "_")}
+			if len(stack) > 0 && stack[len(stack)-1].node.Kind == "class" {
+				node.Kind = "method"
+				node.Receiver = stack[len(stack)-1].node.Name
+			}
+			tree.nodes = append(tree.nodes, node)
+			stack = append(stack, block{indent: indent, node: node})
+			declared = m[2]
+		} else if m := pyClass.FindStringSubmatch(line); m != nil {
+			node := &Node{Kind: "class", Name: m[2], Signature: signature(text), Line: number, EndLine: number, Exported: !strings.HasPrefix(m[2], "_")}
+			tree.nodes = append(tree.nodes, node)
+			stack = append(stack, block{indent: indent, node: node})
+			declared = m[2]
+		} else if len(stack) > 0 && stack[len(stack)-1].node.Kind == "class" {
+			if m := pyClassVar.FindStringSubmatch(trimmed); m != nil && (m[2] != "" || m[3] != "") {
+				addField(stack[len(stack)-1].node, m[1], text, number)
+			}
+		}
+
+		if fn != nil && fn.Kind == "method" {
+			if class := enclosing("class"); class != nil && class.Name == fn.Receiver {
+				for _, m := range pyAttr.FindAllStringSubmatch(line, -1) {
+					addField(class, m[1], "self."+strings.TrimSpace(m[1]+m[2]), number)
+				}
+			}
+		}
+
+		for _, callee := range lineCalls(line, declared) {
+			c := Call{Callee: callee, Line: number}
+			if fn != nil {
+				c.In = qualifiedName(fn)
+				fn.Calls = append(fn.Calls, c)
+			}
+			tree.calls = append(tree.calls, c)
+		}
+	}
+	closeBlocks(0)
+	return tree
+}
+
+// qualifiedName names a node as calls record it: Type.method for methods.
+func qualifiedName(n *Node) string {
+	if n.Receiver != "" {
+		return n.Receiver + "." + n.Name
+	}
+	return n.Name
+}
+
+var (
+	tsClass     = regexp.MustCompile(`^(export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?class\s+([\w$]+)`)
+	tsInterface = regexp.MustCompile(`^(export\s+)?(?:declare\s+)?interface\s+([\w$]+)`)
+	tsTypeBody  = regexp.MustCompile(`^(export\s+)?(?:declare\s+)?type\s+([\w$]+)(?:<[^=]*>)?\s*=\s*\{`)
+	tsEnum      = regexp.MustCompile(`^(export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+([\w$]+)`)
+	tsFunction  = regexp.MustCompile(`^(export\s+)?(?:default\s+)?(?:declare\s+)?(?:async\s+)?function\s*\*?\s*([\w$]+)`)
+	tsArrow     = regexp.MustCompile(`^(export\s+)?(?:const|let|var)\s+([\w$]+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|(?:\([^)]*\)|[\w$]+)\s*(?::[^=]+?)?=>)`)
+	tsImport    = regexp.MustCompile(`^import\s+(?:type\s+)?(?:[^'"]*\s+from\s+)?['"]([^'"]+)['"]`)
+	tsMethod    = regexp.MustCompile(`^(?:(?:public|private|protected|static|readonly|async|abstract|override|get|set|declare)\s+)*\*?\s*(#?[\w$]+)\s*\??\s*(?:<[^>]*>)?\s*\(`)
+	tsMember    = regexp.MustCompile(`^(?:(?:public|private|protected|static|readonly|declare|override)\s+)*(#?[\w$]+)\s*[?!]?\s*[:=]`)
+)
+
+// scanScript returns the structure of a TypeScript or JavaScript file,
+// following blocks by braces. Declarations are recognized at the start of
+// a line; class members and the members of interfaces and object types
+// directly inside their bodies.
+func scanScript(src string) *syntaxTree {
+	tree := &syntaxTree{}
+	masked := maskSource(src, false)
+	lines := strings.Split(masked, "\n")
+	original := strings.Split(src, "\n")
+
+	type scope struct {
+		node  *Node // nil for blocks that are not declarations
+		depth int   // Brace depth inside the scope
+	}
+	var stack []scope
+	depth := 0
+	var pending *Node // Declaration waiting for its opening brace
+
+	// current returns the innermost declaration scope.
+	current := func() *Node {
+		if len(stack) > 0 && stack[len(stack)-1].depth == depth {
+			return stack[len(stack)-1].node
+		}
+		return nil
+	}
+	enclosingFunc := func() *Node {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if n := stack[i].node; n != nil && (n.Kind == "func" || n.Kind == "method") {
+				return n
+			}
+		}
+		return nil
+	}
+	exported := func(m []string) bool { return m[1] != "" }
+
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		number := i + 1
+		text := strings.TrimSpace(strings.TrimRight(original[i], "\r"))
+		if trimmed == "" {
+			continue
+		}
+
+		var node *Node
+		declared := ""
+		if depth == 0 {
+			if m := tsImport.FindStringSubmatch(text); m != nil {
+				tree.nodes = append(tree.nodes, &Node{Kind: "import", Name: m[1], Signature: text, Line: number, EndLine: number, Exported: true})
+			}
+		}
+		parent := current()
+		switch {
+		case parent != nil && parent.Kind == "class":
+			if m := tsMethod.FindStringSubmatch(trimmed); m != nil && !notCallees[m[1]] {
+				node = &Node{Kind: "method", Name: m[1], Receiver: parent.Name, Exported: !strings.HasPrefix(m[1], "#") && !strings.Contains(trimmed, "private ")}
+			} else if m := tsMember.FindStringSubmatch(trimmed); m != nil {
+				parent.Fields = append(parent.Fields, Field{Name: m[1], Decl: strings.TrimRight(text, ";,"), Line: number})
+			}
+		case parent != nil && (parent.Kind == "interface" || parent.Kind == "type"):
+			if m := tsMember.FindStringSubmatch(trimmed); m != nil {
+				parent.Fields = append(parent.Fields, Field{Name: m[1], Decl: strings.TrimRight(text, ";,"), Line: number})
+			} else if m := tsMethod.FindStringSubmatch(trimmed); m != nil {
+				parent.Fields = append(parent.Fields, Field{Name: m[1], Decl: strings.TrimRight(text, ";,"), Line: number})
+			}
+		default:
+			if m := tsClass.FindStringSubmatch(trimmed); m != nil {
+				node = &Node{Kind: "class", Name: m[2], Exported: exported(m)}
+			} else if m := tsInterface.FindStringSubmatch(trimmed); m != nil {
+				node = &Node{Kind: "interface", Name: m[2], Exported: exported(m)}
+			} else if m := tsTypeBody.FindStringSubmatch(trimmed); m != nil {
+				node = &Node{Kind: "type", Name: m[2], Exported: exported(m)}
+			} else if m := tsEnum.FindStringSubmatch(trimmed); m != nil {
+				node = &Node{Kind: "enum", Name: m[2], Exported: exported(m)}
+			} else if m := tsFunction.FindStringSubmatch(trimmed); m != nil {
+				node = &Node{Kind: "func", Name: m[2], Exported: exported(m)}
+			} else if m := tsArrow.FindStringSubmatch(trimmed); m != nil {
+				node = &Node{Kind: "func", Name: m[2], Exported: exported(m)}
+			}
+		}
+		if node != nil {
+			node.Signature = signature(text)
+			node.Line, node.EndLine = number, number
+			tree.nodes = append(tree.nodes, node)
+			declared = node.Name
+			pending = node
+			// Arrow functions with an expression body end on their line
+			if node.Kind == "func" && strings.Contains(trimmed, "=>") {
+				rest := strings.TrimSpace(trimmed[strings.LastIndex(trimmed, "=>")+2:])
+				if rest != "" && !strings.HasPrefix(rest, "{") {
+					pending = nil
+				}
+			}
+		}
+
+		// Calls before braces are made in the enclosing function; those of a
+		// one-line declaration in the declaration itself
+		fn := enclosingFunc()
+		if node != nil && (node.Kind == "func" || node.Kind == "method") {
+			fn = node
+		}
+		for _, callee := range lineCalls(line, declared) {
+			c := Call{Callee: callee, Line: number}
+			if fn != nil {
+				c.In = qualifiedName(fn)
+				fn.Calls = append(fn.Calls, c)
+			}
+			tree.calls = append(tree.calls, c)
+		}
+
+		for _, c := range line {
+			switch c {
+			case '{':
+				depth++
+				if pending != nil {
+					stack = append(stack, scope{node: pending, depth: depth})
+					pending = nil
+				}
+			case '}':
+				if len(stack) > 0 && stack[len(stack)-1].depth == depth {
+					stack[len(stack)-1].node.EndLine = number
+					stack = stack[:len(stack)-1]
+				}
+				depth = max(0, depth-1)
+			case ';':
+				// Declarations without bodies: overloads, abstract methods
+				pending = nil
+			}
+		}
+	}
+	return tree
+}
@@ -0,0 +1,338 @@
+package file
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxFuzz is how many context lines at either end of a hunk may be ignored
+// when it doesn't match as given, as with patch's --fuzz.
+const maxFuzz = 2
+
+// filePatch is the change a unified diff makes to one file.
+type filePatch struct {
+	oldPath string // Empty for /dev/null, i.e. a new file
+	newPath string // Empty for /dev/null, i.e. a deleted file
+	hunks   []hunk
+}
+
+// hunk is one "@@" section of a file patch.
+type hunk struct {
+	oldStart int // 1-based, 0 when the header gives no position
+	oldLines int // -1 when the header gives no counts
+	newLines int
+	lines    []hunkLine
+
+	// "\ No newline at end of file" followed the old or new side's last line
+	noNewlineOld bool
+	noNewlineNew bool
+}
+
+// hunkLine is a context (' '), removed ('-') or added ('+') line.
+type hunkLine struct {
+	op   byte
+	text string
+}
+
+// appliedHunk is a hunk as it matched the file, with the file's own
+// context lines, for previews and reports.
+type appliedHunk struct {
+	oldStart int // 1-based
+	newStart int
+	lines    []hunkLine
+	offset   int  // Lines away from where the header put it
+	fuzz     int  // Context lines ignored at each end
+	loose    bool // Matched only with whitespace ignored
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch parses a unified diff as written by diff -u or git diff. It is
+// lenient where models tend to slip: wrong or missing hunk counts, blank
+// context lines without their space, and CRLF line endings.
+func parsePatch(text string) ([]filePatch, error) {
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	isFileHeader := func(i int) bool {
+		return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+	}
+
+	var patches []filePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case isFileHeader(i):
+			patches = append(patches, filePatch{oldPath: headerPath(line[4:]), newPath: headerPath(lines[i+1][4:])})
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if len(patches) == 0 {
+				return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+			}
+			h := parseHunkHeader(line)
+			oldSeen, newSeen := 0, 0
+			for i+1 < len(lines) {
+				next := lines[i+1]
+				counted := h.oldLines >= 0 && oldSeen >= h.oldLines && newSeen >= h.newLines
+				if strings.HasPrefix(next, "@@") || strings.HasPrefix(next, "diff ") || isFileHeader(i+1) || (counted && next == "-- ") {
+					break
+				}
+				if next == "" {
+					// A blank context line that lost its space, unless the
+					// hunk is already complete
+					if counted {
+						break
+					}
+					next = " "
+				}
+				op := next[0]
+				if op == '\\' {
+					switch {
+					case len(h.lines) == 0:
+					case h.lines[len(h.lines)-1].op == '+':
+						h.noNewlineNew = true
+					case h.lines[len(h.lines)-1].op == '-':
+						h.noNewlineOld = true
+					default:
+						h.noNewlineOld, h.noNewlineNew = true, true
+					}
+					i++
+					continue
+				}
+				if op != ' ' && op != '-' && op != '+' {
+					break
+				}
+				h.lines = append(h.lines, hunkLine{op: op, text: next[1:]})
+				if op != '+' {
+					oldSeen++
+				}
+				if op != '-' {
+					newSeen++
+				}
+				i++
+			}
+			// Blank lines between hunks are not context
+			for len(h.lines) > 0 && h.oldLines < 0 && h.lines[len(h.lines)-1] == (hunkLine{op: ' '}) {
+				h.lines = h.lines[:len(h.lines)-1]
+			}
+			p := &patches[len(patches)-1]
+			p.hunks = append(p.hunks, h)
+		}
+	}
+
+	var files []filePatch
+	for _, p := range patches {
+		if p.oldPath == "" && p.newPath == "" {
+			return nil, fmt.Errorf("file header names /dev/null on both sides")
+		}
+		if len(p.hunks) == 0 && p.oldPath == p.newPath {
+			continue
+		}
+		files = append(files, p)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found; expected ---/+++ file headers followed by @@ hunks")
+	}
+	return files, nil
+}
+
+// headerPath returns the path of a ---/+++ header, without its timestamp,
+// or "" for /dev/null.
+func headerPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	return s
+}
+
+// parseHunkHeader parses "@@ -l,s +l,s @@". Headers without numbers are
+// accepted, the hunk then being found by its content alone.
+func parseHunkHeader(line string) hunk {
+	m := hunkHeader.FindStringSubmatch(line)
+	if m == nil {
+		return hunk{oldLines: -1, newLines: -1}
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	start, _ := strconv.Atoi(m[1])
+	return hunk{oldStart: start, oldLines: count(m[2]), newLines: count(m[4])}
+}
+
+// applyHunks applies hunks to content. A hunk that doesn't match where its
+// header says is looked for nearby, then with whitespace differences
+// ignored, then with up to maxFuzz context lines ignored at each end.
+func applyHunks(content string, hunks []hunk) (string, []appliedHunk, error) {
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+	}
+	finalNewline := content == "" || strings.HasSuffix(content, eol)
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, eol), eol)
+	}
+
+	var out []string
+	var applied []appliedHunk
+	pos, offset := 0, 0 // Next unconsumed line; how far the last hunk was off
+	for n, h := range hunks {
+		expected := pos
+		if h.oldStart > 0 {
+			expected = h.oldStart - 1 + offset
+			if h.oldLines == 0 {
+				// "-l,0" names the line after which to insert
+				expected = h.oldStart + offset
+			}
+		}
+		at, head, tail, loose, ok := locate(lines, pos, expected, h.lines)
+		if !ok {
+			return "", nil, fmt.Errorf("hunk %d does not match the file near line %d", n+1, max(expected, 0)+1)
+		}
+
+		out = append(out, lines[pos:at]...)
+		a := appliedHunk{oldStart: at + 1, newStart: len(out) + 1, fuzz: max(head, tail), loose: loose}
+		if h.oldStart > 0 {
+			a.offset = at - head - expected
+			offset += a.offset
+		}
+		k := at
+		for _, l := range h.lines[head : len(h.lines)-tail] {
+			switch l.op {
+			case ' ':
+				out = append(out, lines[k])
+				a.lines = append(a.lines, hunkLine{op: ' ', text: lines[k]})
+				k++
+			case '-':
+				a.lines = append(a.lines, hunkLine{op: '-', text: lines[k]})
+				k++
+			case '+':
+				out = append(out, l.text)
+				a.lines = append(a.lines, l)
+			}
+		}
+		pos = k
+		if pos == len(lines) && (h.noNewlineOld || h.noNewlineNew) {
+			finalNewline = !h.noNewlineNew
+		}
+		applied = append(applied, a)
+	}
+	out = append(out, lines[pos:]...)
+
+	result := strings.Join(out, eol)
+	if finalNewline && len(out) > 0 {
+		result += eol
+	}
+	return result, applied, nil
+}
+
+// locate finds where the old side of a hunk is in lines, at or after pos
+// and as near expected as possible. It returns the match and the context
+// lines ignored at the head and tail of the hunk.
+func locate(lines []string, pos, expected int, hunkLines []hunkLine) (at, head, tail int, loose, ok bool) {
+	leading, trailing := 0, 0
+	for leading < len(hunkLines) && hunkLines[leading].op == ' ' {
+		leading++
+	}
+	for trailing < len(hunkLines)-leading && hunkLines[len(hunkLines)-1-trailing].op == ' ' {
+		trailing++
+	}
+
+	for fuzz := 0; fuzz <= maxFuzz; fuzz++ {
+		head, tail := min(fuzz, leading), min(fuzz, trailing)
+		if fuzz > 0 && head+tail == 0 {
+			break
+		}
+		var old []string
+		for _, l := range hunkLines[head : len(hunkLines)-tail] {
+			if l.op != '+' {
+				old = append(old, l.text)
+			}
+		}
+		if len(old) == 0 && fuzz > 0 {
+			break
+		}
+		for _, loose := range []bool{false, true} {
+			if at, ok := search(lines, pos, expected+head, old, loose); ok {
+				return at, head, tail, loose, true
+			}
+		}
+	}
+	return 0, 0, 0, false, false
+}
+
+// search returns the start of old in lines at or after pos, trying the
+// positions nearest expected first.
+func search(lines []string, pos, expected int, old []string, loose bool) (int, bool) {
+	last := len(lines) - len(old)
+	if last < pos {
+		return 0, false
+	}
+	expected = min(max(expected, pos), last)
+	if len(old) == 0 {
+		return expected, true
+	}
+	matches := func(at int) bool {
+		for i, want := range old {
+			got := lines[at+i]
+			if loose {
+				got, want = strings.Join(strings.Fields(got), " "), strings.Join(strings.Fields(want), " ")
+			}
+			if got != want {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; expected-d >= pos || expected+d <= last; d++ {
+		if at := expected - d; at >= pos && matches(at) {
+			return at, true
+		}
+		if at := expected + d; d > 0 && at <= last && matches(at) {
+			return at, true
+		}
+	}
+	return 0, false
+}
+
+// formatDiff renders the hunks as they apply to a file as a unified diff.
+func formatDiff(oldName, newName string, hunks []appliedHunk) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		oldLines, newLines := 0, 0
+		for _, l := range h.lines {
+			if l.op != '+' {
+				oldLines++
+			}
+			if l.op != '-' {
+				newLines++
+			}
+		}
+		oldStart, newStart := h.oldStart, h.newStart
+		if oldLines == 0 {
+			oldStart--
+		}
+		if newLines == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLines, newStart, newLines)
+		for _, l := range h.lines {
+			b.WriteByte(l.op)
+			b.WriteString(l.text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
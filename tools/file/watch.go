@@ -0,0 +1,467 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Kinds of change reported by a Watcher
+const (
+	ChangeModified = "modified"
+	ChangeCreated  = "created"
+	ChangeDeleted  = "deleted"
+)
+
+// defaultDebounce is how long a Watcher waits for a burst of events, such
+// as an editor's save, to settle before reporting it.
+const defaultDebounce = 300 * time.Millisecond
+
+// Change is a change made to a watched file by something other than b+.
+type Change struct {
+	Path string    `json:"path"`
+	Kind string    `json:"kind"` // modified, created or deleted
+	Time time.Time `json:"time"`
+}
+
+// fileState is what a Watcher last knew of a file.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// statFile returns the current state of a file.
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// WatcherOption configures a Watcher.
+type WatcherOption func(*Watcher)
+
+// WithChangeListener sets a function told about every change as it is
+// found, such as to show it to the user.
+func WithChangeListener(fn func(Change)) WatcherOption {
+	return func(w *Watcher) {
+		w.listener = fn
+	}
+}
+
+// WithDebounce sets how long events settle before changes are reported.
+func WithDebounce(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// Watcher notices files changed outside b+, such as by the user's editor,
+// so the agent doesn't edit a copy it read before the change. Files are
+// compared with the state b+ last saw them in, so its own writes are not
+// reported once Watch has been called again after them.
+//
+// Directories are watched rather than files, since editors often save by
+// replacing a file; a watched directory reports changes to its entries but
+// not to those of its subdirectories.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	listener func(Change)
+	debounce time.Duration
+
+	mu      sync.Mutex
+	files   map[string]fileState // Watched files, on their own or in a watched directory
+	own     map[string]bool      // Files watched on their own
+	dirs    map[string]bool      // Directories watched for all their entries
+	parents map[string]int       // Directories added to fs and how many paths need each
+	dirty   map[string]bool      // Paths with events not yet looked at
+	pending []Change             // Changes not yet taken by Changes
+	timer   *time.Timer
+	closed  bool
+}
+
+// NewWatcher starts a watcher with nothing watched.
+func NewWatcher(opts ...WatcherOption) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	w := &Watcher{
+		fs:       fsw,
+		debounce: defaultDebounce,
+		files:    make(map[string]fileState),
+		own:      make(map[string]bool),
+		dirs:     make(map[string]bool),
+		parents:  make(map[string]int),
+		dirty:    make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	go w.run()
+	return w, nil
+}
+
+// Watch watches a file or directory, taking its current state as the one
+// b+ knows. Watching a file again, after b+ read or wrote it, updates the
+// state it is compared with.
+func (w *Watcher) Watch(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cannot watch %s: %w", path, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return fmt.Errorf("watcher is closed")
+	}
+	if !info.IsDir() {
+		if !w.own[path] {
+			if err := w.addParent(filepath.Dir(path)); err != nil {
+				return err
+			}
+			w.own[path] = true
+		}
+		w.files[path] = statFile(path)
+		delete(w.dirty, path)
+		return nil
+	}
+
+	if w.dirs[path] {
+		return nil
+	}
+	if err := w.addParent(path); err != nil {
+		return err
+	}
+	w.dirs[path] = true
+	entries, _ := os.ReadDir(path)
+	for _, e := range entries {
+		if !e.IsDir() {
+			entry := filepath.Join(path, e.Name())
+			if _, ok := w.files[entry]; !ok {
+				w.files[entry] = statFile(entry)
+			}
+		}
+	}
+	return nil
+}
+
+// Unwatch stops watching a file or directory.
+func (w *Watcher) Unwatch(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case w.dirs[path]:
+		delete(w.dirs, path)
+		for file := range w.files {
+			if filepath.Dir(file) == path && !w.own[file] {
+				delete(w.files, file)
+			}
+		}
+		w.removeParent(path)
+	case w.own[path]:
+		delete(w.own, path)
+		if !w.dirs[filepath.Dir(path)] {
+			delete(w.files, path)
+		}
+		w.removeParent(filepath.Dir(path))
+	default:
+		return fmt.Errorf("%s is not watched", path)
+	}
+	return nil
+}
+
+// addParent adds a directory to the fsnotify watch. Callers must hold w.mu.
+func (w *Watcher) addParent(dir string) error {
+	if w.parents[dir] == 0 {
+		if err := w.fs.Add(dir); err != nil {
+			return fmt.Errorf("cannot watch %s: %w", dir, err)
+		}
+	}
+	w.parents[dir]++
+	return nil
+}
+
+// removeParent drops a path's need for a directory. Callers must hold w.mu.
+func (w *Watcher) removeParent(dir string) {
+	w.parents[dir]--
+	if w.parents[dir] <= 0 {
+		delete(w.parents, dir)
+		_ = w.fs.Remove(dir)
+	}
+}
+
+// Watched returns the watched directories and the files watched on their
+// own, sorted.
+func (w *Watcher) Watched() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var paths []string
+	for dir := range w.dirs {
+		paths = append(paths, dir+string(filepath.Separator))
+	}
+	for file := range w.own {
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Changes returns the changes found since it was last called, and stops
+// reporting them.
+func (w *Watcher) Changes() []Change {
+	w.mu.Lock()
+	found := w.flush()
+	changes := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+	w.notify(found)
+	return changes
+}
+
+// Close stops the watcher.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	return w.fs.Close()
+}
+
+// run marks the paths events are about until the watcher closes.
+func (w *Watcher) run() {
+	for {
+		select {
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.mark(filepath.Clean(event.Name))
+		case _, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// mark records an event about path and schedules a flush.
+func (w *Watcher) mark(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	if _, ok := w.files[path]; !ok && !w.dirs[filepath.Dir(path)] {
+		return
+	}
+	w.dirty[path] = true
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.debounce, func() {
+			w.mu.Lock()
+			w.timer = nil
+			found := w.flush()
+			w.mu.Unlock()
+			w.notify(found)
+		})
+	}
+}
+
+// flush compares the paths with events with their known state, queues the
+// changes found and returns them. Callers must hold w.mu.
+func (w *Watcher) flush() []Change {
+	var found []Change
+	now := time.Now()
+	for path := range w.dirty {
+		delete(w.dirty, path)
+		known, watched := w.files[path]
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() || err != nil && !watched {
+			continue // Subdirectories and short-lived files aren't followed
+		}
+		current := statFile(path)
+
+		var kind string
+		switch {
+		case current == known && watched:
+		case !current.exists && known.exists:
+			kind = ChangeDeleted
+		case current.exists && !known.exists:
+			kind = ChangeCreated
+		case current.exists:
+			kind = ChangeModified
+		}
+		w.files[path] = current
+		if kind != "" {
+			found = append(found, Change{Path: path, Kind: kind, Time: now})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	w.pending = append(w.pending, found...)
+	return found
+}
+
+// notify tells the listener about changes. It is called without w.mu held.
+func (w *Watcher) notify(changes []Change) {
+	if w.listener == nil {
+		return
+	}
+	for _, c := range changes {
+		w.listener(c)
+	}
+}
+
+// DescribeChanges renders changes for the agent, with paths relative to
+// root where they are inside it.
+func DescribeChanges(changes []Change, root string) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	seen := make(map[string]int, len(changes))
+	var lines []string
+	for _, c := range changes {
+		rel := c.Path
+		if r, err := filepath.Rel(root, c.Path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = filepath.ToSlash(r)
+		}
+		line := fmt.Sprintf("- %s (%s)", rel, c.Kind)
+		if i, ok := seen[c.Path]; ok {
+			lines[i] = line // Only the latest change to a file matters
+			continue
+		}
+		seen[c.Path] = len(lines)
+		lines = append(lines, line)
+	}
+	return "Files changed outside b+, such as in the user's editor, since you last read them; " +
+		"read them again before editing them:\n" + strings.Join(lines, "\n")
+}
+
+// WatchTool adds paths to the watcher or removes them.
+type WatchTool struct {
+	watcher *Watcher
+}
+
+// NewWatchTool creates a new watch tool.
+func NewWatchTool(w *Watcher) *WatchTool {
+	return &WatchTool{watcher: w}
+}
+
+// Name returns the tool name.
+func (t *WatchTool) Name() string {
+	return "watch"
+}
+
+// Description returns the tool description.
+func (t *WatchTool) Description() string {
+	return "Watches files or directories for changes made outside b+, such as by the user's editor or a build, and reports them to you as they happen. " +
+		"Files you read or edit are watched already; add directories or generated files you need to follow"
+}
+
+// Parameters returns the tool parameters.
+func (t *WatchTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "action",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "add, remove or list",
+		},
+		{
+			Name:        "path",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For add and remove: the file or directory; a directory's entries are watched but not its subdirectories",
+		},
+	}
+}
+
+// Category returns the tool category.
+func (t *WatchTool) Category() string {
+	return "file"
+}
+
+// Version returns the tool version.
+func (t *WatchTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *WatchTool) IsExternal() bool {
+	return false
+}
+
+// RequiresPermission returns true as watching reveals changes to files.
+func (t *WatchTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute runs the action.
+func (t *WatchTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	action, _ := params["action"].(string)
+	path, _ := params["path"].(string)
+	path = strings.TrimSpace(path)
+
+	var err error
+	switch strings.TrimSpace(action) {
+	case "add":
+		if path == "" {
+			err = fmt.Errorf("path is required for add")
+		} else {
+			err = t.watcher.Watch(path)
+		}
+	case "remove":
+		if path == "" {
+			err = fmt.Errorf("path is required for remove")
+		} else {
+			err = t.watcher.Unwatch(path)
+		}
+	case "list":
+	default:
+		err = fmt.Errorf("invalid action %q: must be add, remove or list", action)
+	}
+	if err != nil {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	watched := t.watcher.Watched()
+	output := "Nothing is watched"
+	if len(watched) > 0 {
+		output = fmt.Sprintf("Watching %d paths:\n%s", len(watched), strings.Join(watched, "\n"))
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   output,
+		Metadata: map[string]interface{}{"watched": watched},
+		Duration: time.Since(startTime),
+	}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+	"unicode/utf8"
 
 	"github.com/abrksh22/bplus/tools"
 )
@@ -25,7 +26,9 @@ func (t *WriteTool) Name() string {
 
 // Description returns the tool description.
 func (t *WriteTool) Description() string {
-	return "Writes content to a file with atomic writes and automatic backups"
+	return "Writes content to a file with atomic writes and automatic backups. " +
+		"Large files can be written in parts: send each part with final=false and " +
+		"offset set to the byte offset it continues from, then send the last part with final=true"
 }
 
 // Parameters returns the tool parameters.
@@ -57,6 +60,20 @@ func (t *WriteTool) Parameters() []tools.Parameter {
 			Description: "Create parent directories if they don't exist (default: true)",
 			Default:     true,
 		},
+		{
+			Name:        "offset",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Byte offset this part continues from in a multi-part write (default: 0)",
+			Default:     0,
+		},
+		{
+			Name:        "final",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Whether this is the last part of the file (default: true)",
+			Default:     true,
+		},
 	}
 }
 
@@ -83,6 +100,21 @@ func (t *WriteTool) Execute(ctx context.Context, params map[string]interface{})
 		createDirs = val.(bool)
 	}
 
+	offset := 0
+	if val, ok := params["offset"]; ok {
+		switch v := val.(type) {
+		case int:
+			offset = v
+		case float64:
+			offset = int(v)
+		}
+	}
+
+	final := true
+	if val, ok := params["final"]; ok {
+		final = val.(bool)
+	}
+
 	// Validate path
 	filePath = filepath.Clean(filePath)
 	if !filepath.IsAbs(filePath) {
@@ -103,6 +135,42 @@ func (t *WriteTool) Execute(ctx context.Context, params map[string]interface{})
 		}
 	}
 
+	// Multi-part writes are staged next to the target until the final part
+	partialPath := filePath + ".partial"
+	if offset > 0 || !final {
+		staged, err := stageChunk(partialPath, offset, content)
+		if err != nil {
+			return &tools.Result{
+				Success: false,
+				Error:   err,
+			}, nil
+		}
+
+		if !final {
+			return &tools.Result{
+				Success: true,
+				Output: fmt.Sprintf("Staged %d bytes for %s (%d bytes total); continue from byte offset %d",
+					len(content), filePath, len(staged), len(staged)),
+				Metadata: map[string]interface{}{
+					"path":        filePath,
+					"partial":     true,
+					"next_offset": len(staged),
+				},
+				Duration: time.Since(startTime),
+			}, nil
+		}
+
+		// Final part: validate the stitched content before committing it
+		if !utf8.Valid(staged) {
+			_ = os.Remove(partialPath)
+			return &tools.Result{
+				Success: false,
+				Error:   fmt.Errorf("stitched content for %s is not valid UTF-8; parts were likely split incorrectly", filePath),
+			}, nil
+		}
+		content = string(staged)
+	}
+
 	// Create backup if file exists
 	var backupPath string
 	if createBackup {
@@ -118,7 +186,8 @@ func (t *WriteTool) Execute(ctx context.Context, params map[string]interface{})
 	}
 
 	// Write file atomically
-	if err := writeFileAtomic(filePath, content); err != nil {
+	_ = os.Remove(partialPath)
+	if err := writeFileAtomic(ctx, filePath, content); err != nil {
 		// Restore from backup if write failed
 		if backupPath != "" {
 			_ = copyFile(backupPath, filePath)
@@ -157,11 +226,41 @@ func (t *WriteTool) IsExternal() bool {
 	return false
 }
 
+// writeChunk is how much of a large file is written between progress
+// reports and cancellation checks.
+const writeChunk = 256 << 10
+
 // writeFileAtomic writes a file atomically by writing to a temp file first.
-func writeFileAtomic(filePath, content string) error {
+// Contents larger than writeChunk are written in chunks, reporting the bytes
+// written to ctx's progress listener; if ctx is cancelled in between, the
+// file is left as it was.
+func writeFileAtomic(ctx context.Context, filePath, content string) error {
 	// Write to temp file
 	tempPath := filePath + ".tmp"
-	if err := os.WriteFile(tempPath, []byte(content), 0644); err != nil {
+	f, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	total := int64(len(content))
+	for written := 0; written < len(content); {
+		if err := ctx.Err(); err != nil {
+			f.Close()
+			_ = os.Remove(tempPath)
+			return err
+		}
+		end := min(written+writeChunk, len(content))
+		if _, err := f.WriteString(content[written:end]); err != nil {
+			f.Close()
+			_ = os.Remove(tempPath)
+			return err
+		}
+		written = end
+		if total > writeChunk {
+			tools.ReportProgress(ctx, tools.Progress{Path: filePath, Bytes: int64(written), TotalBytes: total})
+		}
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tempPath)
 		return err
 	}
 
@@ -174,6 +273,34 @@ func writeFileAtomic(filePath, content string) error {
 	return nil
 }
 
+// stageChunk appends a part of a multi-part write to the staging file and
+// returns the full staged content. The offset must match the number of
+// bytes already staged, so dropped or repeated parts are detected instead of
+// silently corrupting the file. An offset of 0 starts a new staging file.
+func stageChunk(partialPath string, offset int, chunk string) ([]byte, error) {
+	var staged []byte
+	if offset > 0 {
+		existing, err := os.ReadFile(partialPath)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no partial write in progress; start with offset 0")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read partial write: %w", err)
+		}
+		if len(existing) != offset {
+			return nil, fmt.Errorf("offset mismatch: %d bytes staged but part continues from offset %d", len(existing), offset)
+		}
+		staged = existing
+	}
+
+	staged = append(staged, chunk...)
+	if err := os.WriteFile(partialPath, staged, 0644); err != nil {
+		return nil, fmt.Errorf("failed to stage partial write: %w", err)
+	}
+
+	return staged, nil
+}
+
 // copyFile copies a file from src to dst.
 func copyFile(src, dst string) error {
 	content, err := os.ReadFile(src)
@@ -0,0 +1,201 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Branch actions
+const (
+	branchList   = "list"
+	branchCreate = "create"
+	branchSwitch = "switch"
+	branchDelete = "delete"
+)
+
+// BranchTool lists, creates, switches and deletes branches. Deleting is
+// only allowed for merged branches other than main, master and the current
+// one.
+type BranchTool struct {
+	base
+}
+
+// NewBranchTool creates a new git_branch tool.
+func NewBranchTool() *BranchTool {
+	return &BranchTool{}
+}
+
+// Name returns the tool name.
+func (t *BranchTool) Name() string {
+	return "git_branch"
+}
+
+// Description returns the tool description.
+func (t *BranchTool) Description() string {
+	return "Lists branches, creates a branch and switches to it, switches to an existing branch, or deletes a merged branch"
+}
+
+// Parameters returns the tool parameters.
+func (t *BranchTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "action",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "What to do: list, create, switch or delete (default: list)",
+			Default:     branchList,
+			Validation:  &tools.Validation{Enum: []string{branchList, branchCreate, branchSwitch, branchDelete}},
+		},
+		{
+			Name:        "name",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Branch to create, switch to or delete",
+		},
+		{
+			Name:        "start_point",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Commit or branch a new branch starts at (default: HEAD)",
+		},
+		workingDirParam,
+	}
+}
+
+// RequiresPermission returns true as branches can be changed.
+func (t *BranchTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the branch in the permission prompt.
+func (t *BranchTool) DescribeResource(params map[string]interface{}) string {
+	action := stringParam(params, "action")
+	if action == "" || action == branchList {
+		return "list branches"
+	}
+	return fmt.Sprintf("%s branch %s", action, stringParam(params, "name"))
+}
+
+// Execute carries out the action.
+func (t *BranchTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	dir := stringParam(params, "working_dir")
+	action := stringParam(params, "action")
+	if action == "" {
+		action = branchList
+	}
+	if action == branchList {
+		return t.list(ctx, dir, startTime)
+	}
+
+	name := stringParam(params, "name")
+	if name == "" {
+		return failure(fmt.Errorf("name is required to %s a branch", action), startTime)
+	}
+	if err := checkRef("branch name", name); err != nil {
+		return failure(err, startTime)
+	}
+
+	var args []string
+	switch action {
+	case branchCreate:
+		if _, err := run(ctx, dir, "check-ref-format", "--branch", name); err != nil {
+			return failure(fmt.Errorf("invalid branch name %q", name), startTime)
+		}
+		args = []string{"switch", "-c", name}
+		if start := stringParam(params, "start_point"); start != "" {
+			if err := checkRef("start point", start); err != nil {
+				return failure(err, startTime)
+			}
+			args = append(args, start)
+		}
+	case branchSwitch:
+		args = []string{"switch", name}
+	case branchDelete:
+		if protectedBranches[name] {
+			return failure(fmt.Errorf("refusing to delete %s", name), startTime)
+		}
+		current, _ := run(ctx, dir, "branch", "--show-current")
+		if strings.TrimSpace(current) == name {
+			return failure(fmt.Errorf("refusing to delete %s: it is the current branch", name), startTime)
+		}
+		// -d, never -D: git refuses to delete a branch that isn't merged
+		args = []string{"branch", "-d", name}
+	default:
+		return failure(fmt.Errorf("unknown action %q", action), startTime)
+	}
+
+	out, err := run(ctx, dir, args...)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	msg := map[string]string{
+		branchCreate: "Created and switched to branch %s",
+		branchSwitch: "Switched to branch %s",
+		branchDelete: "Deleted branch %s",
+	}[action]
+	output := fmt.Sprintf(msg, name)
+	if out = strings.TrimSpace(out); out != "" && action != branchDelete {
+		output += "\n" + out
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  output,
+		Metadata: map[string]interface{}{
+			"action": action,
+			"branch": name,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// list lists the local branches with their upstreams.
+func (t *BranchTool) list(ctx context.Context, dir string, startTime time.Time) (*tools.Result, error) {
+	out, err := run(ctx, dir, "branch", "--no-color", "--format=%(HEAD)%1f%(refname:short)%1f%(upstream:short)%1f%(upstream:track)")
+	if err != nil {
+		return failure(err, startTime)
+	}
+
+	var branches []string
+	current := ""
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		marker := "  "
+		if fields[0] == "*" {
+			marker = "* "
+			current = fields[1]
+		}
+		branches = append(branches, fields[1])
+		b.WriteString(marker + fields[1])
+		if fields[2] != "" {
+			fmt.Fprintf(&b, " [%s", fields[2])
+			if track := strings.Trim(fields[3], "[]"); track != "" {
+				b.WriteString(": " + track)
+			}
+			b.WriteString("]")
+		}
+		b.WriteString("\n")
+	}
+	output := strings.TrimRight(b.String(), "\n")
+	if output == "" {
+		output = "No branches yet"
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  output,
+		Metadata: map[string]interface{}{
+			"branches": branches,
+			"current":  current,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
@@ -0,0 +1,152 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// CommitTool commits the staged changes. Hooks always run, credential files
+// are never committed, and a commit is only amended when the user authored
+// it and it hasn't been pushed.
+type CommitTool struct {
+	base
+}
+
+// NewCommitTool creates a new git_commit tool.
+func NewCommitTool() *CommitTool {
+	return &CommitTool{}
+}
+
+// Name returns the tool name.
+func (t *CommitTool) Name() string {
+	return "git_commit"
+}
+
+// Description returns the tool description.
+func (t *CommitTool) Description() string {
+	return "Commits the staged changes with a message. Only commit when the user asks. Amending is refused for commits by someone else or already pushed"
+}
+
+// Parameters returns the tool parameters.
+func (t *CommitTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "message",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Commit message: a short subject line, then optionally a blank line and a body",
+		},
+		{
+			Name:        "amend",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Replace the last commit instead; only when the user asked to amend, or to add changes a pre-commit hook made",
+			Default:     false,
+		},
+		workingDirParam,
+	}
+}
+
+// RequiresPermission returns true as committing changes the repository.
+func (t *CommitTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the commit in the permission prompt.
+func (t *CommitTool) DescribeResource(params map[string]interface{}) string {
+	subject, _, _ := strings.Cut(stringParam(params, "message"), "\n")
+	if boolParam(params, "amend") {
+		return "amend last commit: " + subject
+	}
+	return "commit: " + subject
+}
+
+// Execute makes the commit.
+func (t *CommitTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	dir := stringParam(params, "working_dir")
+	message := stringParam(params, "message")
+	amend := boolParam(params, "amend")
+	if message == "" {
+		return failure(fmt.Errorf("message is required"), startTime)
+	}
+
+	out, err := run(ctx, dir, "diff", "--cached", "--name-only", "-z")
+	if err != nil {
+		return failure(err, startTime)
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 && !amend {
+		return failure(fmt.Errorf("nothing staged to commit; stage changes with git_stage first"), startTime)
+	}
+	if found := sensitive(files); len(found) > 0 {
+		return failure(fmt.Errorf("refusing to commit credential files: %s; unstage them with git_stage", strings.Join(found, ", ")), startTime)
+	}
+	if amend {
+		if err := checkAmend(ctx, dir); err != nil {
+			return failure(err, startTime)
+		}
+	}
+
+	args := []string{"commit", "--quiet", "-m", message}
+	if amend {
+		args = append(args, "--amend")
+	}
+	if _, err := run(ctx, dir, args...); err != nil {
+		return failure(err, startTime)
+	}
+
+	head, err := run(ctx, dir, "log", "-1", "--format=%h%x1f%s")
+	if err != nil {
+		return failure(err, startTime)
+	}
+	hash, subject, _ := strings.Cut(strings.TrimSpace(head), "\x1f")
+	verb := "Committed"
+	if amend {
+		verb = "Amended"
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  fmt.Sprintf("%s %s: %s (%d files)", verb, hash, subject, len(files)),
+		Metadata: map[string]interface{}{
+			"hash":    hash,
+			"files":   files,
+			"amended": amend,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// checkAmend returns why the last commit must not be amended: it was
+// authored by someone else, or it has been pushed, which would take a force
+// push to replace.
+func checkAmend(ctx context.Context, dir string) error {
+	author, err := run(ctx, dir, "log", "-1", "--format=%an <%ae>%x1f%ae")
+	if err != nil {
+		return fmt.Errorf("there is no commit to amend")
+	}
+	name, email, _ := strings.Cut(strings.TrimSpace(author), "\x1f")
+	user, _ := run(ctx, dir, "config", "--get", "user.email")
+	if user = strings.TrimSpace(user); user == "" || !strings.EqualFold(user, email) {
+		return fmt.Errorf("refusing to amend a commit by %s; make a new commit instead", name)
+	}
+
+	remotes, err := run(ctx, dir, "branch", "--remotes", "--contains", "HEAD")
+	if err != nil {
+		return err
+	}
+	if pushed := strings.Fields(remotes); len(pushed) > 0 {
+		return fmt.Errorf("refusing to amend a commit already pushed to %s; make a new commit instead", pushed[0])
+	}
+	return nil
+}
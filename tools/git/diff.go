@@ -0,0 +1,105 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// DiffTool shows changes in a repository.
+type DiffTool struct {
+	base
+}
+
+// NewDiffTool creates a new git_diff tool.
+func NewDiffTool() *DiffTool {
+	return &DiffTool{}
+}
+
+// Name returns the tool name.
+func (t *DiffTool) Name() string {
+	return "git_diff"
+}
+
+// Description returns the tool description.
+func (t *DiffTool) Description() string {
+	return "Shows changes as a unified diff: unstaged changes by default, staged changes with staged, or changes since a commit or branch with ref"
+}
+
+// Parameters returns the tool parameters.
+func (t *DiffTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "staged",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Show changes staged for the next commit instead of unstaged ones",
+			Default:     false,
+		},
+		{
+			Name:        "ref",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Commit, branch or range (e.g. main...HEAD) to compare against",
+		},
+		{
+			Name:        "paths",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Limit the diff to these paths, one per line",
+		},
+		{
+			Name:        "stat",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Show only a summary of changed files and lines",
+			Default:     false,
+		},
+		workingDirParam,
+	}
+}
+
+// RequiresPermission returns false as diff only reads the repository.
+func (t *DiffTool) RequiresPermission() bool {
+	return false
+}
+
+// Execute shows the diff.
+func (t *DiffTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if boolParam(params, "staged") {
+		args = append(args, "--cached")
+	}
+	if boolParam(params, "stat") {
+		args = append(args, "--stat")
+	}
+	if ref := stringParam(params, "ref"); ref != "" {
+		if err := checkRef("ref", ref); err != nil {
+			return failure(err, startTime)
+		}
+		args = append(args, ref)
+	}
+	args = append(args, "--")
+	args = append(args, pathsParam(params, "paths")...)
+
+	out, err := run(ctx, stringParam(params, "working_dir"), args...)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	if strings.TrimSpace(out) == "" {
+		out = "No changes"
+	}
+	out, truncated := truncate(out)
+	return &tools.Result{
+		Success: true,
+		Output:  out,
+		Metadata: map[string]interface{}{
+			"truncated": truncated,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
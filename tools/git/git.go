@@ -0,0 +1,176 @@
+// Package git provides structured tools for working with a git repository.
+//
+// The tools carry out the Layer 4 git safety protocol in code rather than
+// leaving it to the prompt: there is no push, no force, no skipping of
+// hooks and no changing of git configuration; a commit is only amended when
+// it is the user's own and hasn't been pushed; branches are only deleted
+// once merged and never main or master; and credential files are never
+// staged or committed.
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/abrksh22/bplus/tools/exec"
+)
+
+// Git defaults
+const (
+	// gitTimeout bounds one git command.
+	gitTimeout = 60 * time.Second
+
+	// maxOutput bounds the diffs and logs returned to the agent.
+	maxOutput = 50000
+)
+
+// protectedBranches are never deleted.
+var protectedBranches = map[string]bool{"main": true, "master": true}
+
+// Tools returns all git tools.
+func Tools() []tools.Tool {
+	return []tools.Tool{
+		NewStatusTool(),
+		NewDiffTool(),
+		NewLogTool(),
+		NewBranchTool(),
+		NewStageTool(),
+		NewCommitTool(),
+		NewStashTool(),
+	}
+}
+
+// workingDirParam is the parameter every git tool takes to name the
+// repository.
+var workingDirParam = tools.Parameter{
+	Name:        "working_dir",
+	Type:        tools.TypeString,
+	Required:    false,
+	Description: "Directory inside the repository (default: current directory)",
+}
+
+// base holds what all git tools have in common.
+type base struct{}
+
+// Category returns the tool category.
+func (base) Category() string {
+	return "git"
+}
+
+// Version returns the tool version.
+func (base) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (base) IsExternal() bool {
+	return false
+}
+
+// run runs git in dir and returns its standard output. A failing command's
+// error holds what git printed on standard error.
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	cmd := osexec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_PAGER=cat",
+		"GIT_EDITOR=true",
+		"LC_ALL=C",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+// failure is the result of a git tool call that failed.
+func failure(err error, startTime time.Time) (*tools.Result, error) {
+	return &tools.Result{
+		Success:  false,
+		Error:    err,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// stringParam returns a string parameter, trimmed.
+func stringParam(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return strings.TrimSpace(s)
+}
+
+// boolParam returns a boolean parameter.
+func boolParam(params map[string]interface{}, name string) bool {
+	b, _ := params[name].(bool)
+	return b
+}
+
+// intParam returns an integer parameter, or def if it isn't given.
+func intParam(params map[string]interface{}, name string, def int) int {
+	switch v := params[name].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// pathsParam returns the paths given one per line.
+func pathsParam(params map[string]interface{}, name string) []string {
+	var paths []string
+	for _, line := range strings.Split(stringParam(params, name), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// checkRef rejects a ref or branch name that git would read as an option.
+func checkRef(kind, ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid %s %q", kind, ref)
+	}
+	return nil
+}
+
+// sensitive returns the credential files among paths.
+func sensitive(paths []string) []string {
+	var found []string
+	for _, p := range paths {
+		if exec.IsSensitiveFile(p) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// truncate bounds output returned to the agent.
+func truncate(s string) (string, bool) {
+	if len(s) <= maxOutput {
+		return s, false
+	}
+	return strings.ToValidUTF8(s[:maxOutput], "") + "\n... (truncated)", true
+}
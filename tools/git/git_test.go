@@ -0,0 +1,293 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// newRepo creates a repository on branch main with one commit by the
+// configured user.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	gitRun(t, dir, "config", "user.name", "Me")
+	gitRun(t, dir, "config", "user.email", "me@example.com")
+	writeFile(t, dir, "README.md", "hello\n")
+	gitRun(t, dir, "add", "README.md")
+	gitRun(t, dir, "commit", "-q", "-m", "Initial commit")
+	return dir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := run(context.Background(), dir, args...)
+	require.NoError(t, err)
+	return out
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func execute(t *testing.T, tool tools.Tool, dir string, params map[string]interface{}) *tools.Result {
+	t.Helper()
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	params["working_dir"] = dir
+	result, err := tool.Execute(context.Background(), params)
+	require.NoError(t, err)
+	return result
+}
+
+// TestParseStatus tests parsing porcelain v2 status.
+func TestParseStatus(t *testing.T) {
+	out := "# branch.oid 1234567890abcdef\x00# branch.head feature\x00# branch.upstream origin/feature\x00# branch.ab +2 -1\x00" +
+		"1 M. N... 100644 100644 100644 aaa bbb staged.go\x00" +
+		"1 .M N... 100644 100644 100644 aaa bbb dir/unstaged file.go\x00" +
+		"2 R. N... 100644 100644 100644 aaa bbb R100 new.go\x00old.go\x00" +
+		"u UU N... 100644 100644 100644 100644 aaa bbb ccc conflict.go\x00" +
+		"? untracked.txt\x00"
+
+	st := parseStatus(out)
+	assert.Equal(t, "feature", st.Branch)
+	assert.Equal(t, "1234567", st.Commit)
+	assert.Equal(t, "origin/feature", st.Upstream)
+	assert.Equal(t, 2, st.Ahead)
+	assert.Equal(t, 1, st.Behind)
+	require.Len(t, st.Changes, 3)
+	assert.Equal(t, "dir/unstaged file.go", st.Changes[1].Path)
+	assert.Equal(t, fileChange{Path: "new.go", OrigPath: "old.go", Staged: 'R', Unstaged: '.'}, st.Changes[2])
+	assert.Equal(t, []string{"conflict.go"}, st.Conflicted)
+	assert.Equal(t, []string{"untracked.txt"}, st.Untracked)
+
+	text := st.String()
+	assert.Contains(t, text, "On branch feature, tracking origin/feature (ahead 2, behind 1)")
+	assert.Contains(t, text, "renamed: old.go -> new.go")
+	assert.Contains(t, text, "Untracked files:\n  untracked.txt")
+}
+
+// TestStatusTool tests reporting the status of a repository.
+func TestStatusTool(t *testing.T) {
+	dir := newRepo(t)
+	tool := NewStatusTool()
+
+	result := execute(t, tool, dir, nil)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, result.Output, "On branch main")
+	assert.Contains(t, result.Output, "working tree clean")
+
+	writeFile(t, dir, "README.md", "changed\n")
+	writeFile(t, dir, "new.go", "package main\n")
+	result = execute(t, tool, dir, nil)
+	require.True(t, result.Success)
+	assert.Equal(t, []string{"README.md"}, result.Metadata["unstaged"])
+	assert.Equal(t, []string{"new.go"}, result.Metadata["untracked"])
+}
+
+// TestStageTool tests staging and unstaging files.
+func TestStageTool(t *testing.T) {
+	dir := newRepo(t)
+	tool := NewStageTool()
+
+	writeFile(t, dir, "a.go", "package a\n")
+	writeFile(t, dir, "b.go", "package b\n")
+	result := execute(t, tool, dir, map[string]interface{}{"paths": "a.go\nb.go"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, []string{"a.go", "b.go"}, result.Metadata["paths"])
+
+	result = execute(t, tool, dir, map[string]interface{}{"paths": "b.go", "unstage": true})
+	require.True(t, result.Success, "%v", result.Error)
+	staged := gitRun(t, dir, "diff", "--cached", "--name-only")
+	assert.Equal(t, "a.go\n", staged)
+
+	t.Run("Credential files are refused", func(t *testing.T) {
+		writeFile(t, dir, ".env", "API_KEY=secret\n")
+		result := execute(t, tool, dir, map[string]interface{}{"all": true})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), ".env")
+		assert.Equal(t, "a.go\n", gitRun(t, dir, "diff", "--cached", "--name-only"), "nothing should be staged")
+	})
+
+	t.Run("Paths are required", func(t *testing.T) {
+		result := execute(t, tool, dir, nil)
+		assert.False(t, result.Success)
+	})
+}
+
+// TestCommitTool tests committing and the amend checks.
+func TestCommitTool(t *testing.T) {
+	tool := NewCommitTool()
+
+	t.Run("Commit staged changes", func(t *testing.T) {
+		dir := newRepo(t)
+		result := execute(t, tool, dir, map[string]interface{}{"message": "Nothing here"})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "nothing staged")
+
+		writeFile(t, dir, "a.go", "package a\n")
+		gitRun(t, dir, "add", "a.go")
+		result = execute(t, tool, dir, map[string]interface{}{"message": "Add a\n\nWith a body."})
+		require.True(t, result.Success, "%v", result.Error)
+		assert.Contains(t, result.Output, "Add a (1 files)")
+		assert.Equal(t, []string{"a.go"}, result.Metadata["files"])
+	})
+
+	t.Run("Amend own unpushed commit", func(t *testing.T) {
+		dir := newRepo(t)
+		writeFile(t, dir, "a.go", "package a\n")
+		gitRun(t, dir, "add", "a.go")
+		result := execute(t, tool, dir, map[string]interface{}{"message": "Initial commit, with a", "amend": true})
+		require.True(t, result.Success, "%v", result.Error)
+		assert.Equal(t, "1\n", gitRun(t, dir, "rev-list", "--count", "HEAD"))
+	})
+
+	t.Run("Amending another author's commit is refused", func(t *testing.T) {
+		dir := newRepo(t)
+		gitRun(t, dir, "commit", "-q", "--allow-empty", "-m", "Theirs", "--author", "Someone <someone@example.com>")
+		result := execute(t, tool, dir, map[string]interface{}{"message": "Mine now", "amend": true})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "commit by Someone")
+	})
+
+	t.Run("Amending a pushed commit is refused", func(t *testing.T) {
+		dir := newRepo(t)
+		remote := t.TempDir()
+		gitRun(t, remote, "init", "-q", "--bare")
+		gitRun(t, dir, "remote", "add", "origin", remote)
+		gitRun(t, dir, "push", "-q", "origin", "main")
+		result := execute(t, tool, dir, map[string]interface{}{"message": "Rewritten", "amend": true})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), "already pushed to origin/main")
+	})
+
+	t.Run("Credential files are refused", func(t *testing.T) {
+		dir := newRepo(t)
+		writeFile(t, dir, "config/.env.production", "TOKEN=secret\n")
+		gitRun(t, dir, "add", "config/.env.production")
+		result := execute(t, tool, dir, map[string]interface{}{"message": "Add config"})
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error.Error(), ".env.production")
+	})
+}
+
+// TestBranchTool tests the branch actions.
+func TestBranchTool(t *testing.T) {
+	dir := newRepo(t)
+	tool := NewBranchTool()
+
+	result := execute(t, tool, dir, map[string]interface{}{"action": "create", "name": "feature"})
+	require.True(t, result.Success, "%v", result.Error)
+	result = execute(t, tool, dir, nil)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, []string{"feature", "main"}, result.Metadata["branches"])
+	assert.Equal(t, "feature", result.Metadata["current"])
+	assert.Contains(t, result.Output, "* feature")
+
+	result = execute(t, tool, dir, map[string]interface{}{"action": "delete", "name": "feature"})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "current branch")
+
+	// An unmerged branch is kept
+	gitRun(t, dir, "commit", "-q", "--allow-empty", "-m", "Feature work")
+	result = execute(t, tool, dir, map[string]interface{}{"action": "switch", "name": "main"})
+	require.True(t, result.Success, "%v", result.Error)
+	result = execute(t, tool, dir, map[string]interface{}{"action": "delete", "name": "feature"})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "not fully merged")
+
+	gitRun(t, dir, "merge", "-q", "feature")
+	result = execute(t, tool, dir, map[string]interface{}{"action": "delete", "name": "feature"})
+	assert.True(t, result.Success, "%v", result.Error)
+
+	result = execute(t, tool, dir, map[string]interface{}{"action": "delete", "name": "main"})
+	assert.False(t, result.Success)
+
+	result = execute(t, tool, dir, map[string]interface{}{"action": "switch", "name": "--orphan"})
+	assert.False(t, result.Success)
+}
+
+// TestStashTool tests stashing and popping changes.
+func TestStashTool(t *testing.T) {
+	dir := newRepo(t)
+	tool := NewStashTool()
+
+	writeFile(t, dir, "README.md", "work in progress\n")
+	result := execute(t, tool, dir, map[string]interface{}{"message": "wip"})
+	require.True(t, result.Success, "%v", result.Error)
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+
+	result = execute(t, tool, dir, map[string]interface{}{"action": "list"})
+	require.True(t, result.Success)
+	assert.Contains(t, result.Output, "wip")
+
+	result = execute(t, tool, dir, map[string]interface{}{"action": "pop"})
+	require.True(t, result.Success, "%v", result.Error)
+	data, err = os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "work in progress\n", string(data))
+
+	result = execute(t, tool, dir, map[string]interface{}{"action": "drop"})
+	assert.False(t, result.Success)
+}
+
+// TestDiffAndLogTools tests reading diffs and history.
+func TestDiffAndLogTools(t *testing.T) {
+	dir := newRepo(t)
+
+	result := execute(t, NewDiffTool(), dir, nil)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "No changes", result.Output)
+
+	writeFile(t, dir, "README.md", "hello\nworld\n")
+	result = execute(t, NewDiffTool(), dir, map[string]interface{}{"paths": "README.md"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, result.Output, "+world")
+
+	result = execute(t, NewDiffTool(), dir, map[string]interface{}{"staged": true})
+	require.True(t, result.Success)
+	assert.Equal(t, "No changes", result.Output)
+
+	result = execute(t, NewLogTool(), dir, map[string]interface{}{"count": 5})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, result.Output, "Me: Initial commit")
+	commits := result.Metadata["commits"].([]commitInfo)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "me@example.com", commits[0].Email)
+
+	result = execute(t, NewLogTool(), dir, map[string]interface{}{"ref": "--all"})
+	assert.False(t, result.Success)
+}
+
+// TestToolMetadata tests the metadata of the git tools.
+func TestToolMetadata(t *testing.T) {
+	readOnly := map[string]bool{"git_status": true, "git_diff": true, "git_log": true}
+	for _, tool := range Tools() {
+		t.Run(tool.Name(), func(t *testing.T) {
+			assert.Equal(t, "git", tool.Category())
+			assert.Equal(t, "1.0.0", tool.Version())
+			assert.False(t, tool.IsExternal())
+			assert.Equal(t, !readOnly[tool.Name()], tool.RequiresPermission())
+			assert.NotEmpty(t, tool.Description())
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// LogTool shows the commit history of a repository.
+type LogTool struct {
+	base
+}
+
+// NewLogTool creates a new git_log tool.
+func NewLogTool() *LogTool {
+	return &LogTool{}
+}
+
+// Name returns the tool name.
+func (t *LogTool) Name() string {
+	return "git_log"
+}
+
+// Description returns the tool description.
+func (t *LogTool) Description() string {
+	return "Lists recent commits with their hash, date, author and subject, optionally for one branch or path"
+}
+
+// Parameters returns the tool parameters.
+func (t *LogTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "count",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Number of commits to show (default: 10, max: 100)",
+			Default:     10,
+		},
+		{
+			Name:        "ref",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Branch, commit or range to list (default: HEAD)",
+		},
+		{
+			Name:        "path",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Only list commits that changed this path",
+		},
+		workingDirParam,
+	}
+}
+
+// RequiresPermission returns false as log only reads the repository.
+func (t *LogTool) RequiresPermission() bool {
+	return false
+}
+
+// commitInfo is a commit listed by git_log.
+type commitInfo struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Email   string `json:"email"`
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+}
+
+// Execute lists the commits.
+func (t *LogTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	count := intParam(params, "count", 10)
+	if count < 1 {
+		count = 1
+	}
+	if count > 100 {
+		count = 100
+	}
+	args := []string{"log", "--no-color", fmt.Sprintf("-n%d", count), "--date=short", "--format=%h%x1f%an%x1f%ae%x1f%ad%x1f%s"}
+	if ref := stringParam(params, "ref"); ref != "" {
+		if err := checkRef("ref", ref); err != nil {
+			return failure(err, startTime)
+		}
+		args = append(args, ref)
+	}
+	args = append(args, "--")
+	if path := stringParam(params, "path"); path != "" {
+		args = append(args, path)
+	}
+
+	out, err := run(ctx, stringParam(params, "working_dir"), args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "does not have any commits yet") {
+			return &tools.Result{
+				Success:  true,
+				Output:   "No commits yet",
+				Duration: time.Since(startTime),
+			}, nil
+		}
+		return failure(err, startTime)
+	}
+
+	var commits []commitInfo
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		c := commitInfo{Hash: fields[0], Author: fields[1], Email: fields[2], Date: fields[3], Subject: fields[4]}
+		commits = append(commits, c)
+		fmt.Fprintf(&b, "%s %s %s: %s\n", c.Hash, c.Date, c.Author, c.Subject)
+	}
+	output := strings.TrimRight(b.String(), "\n")
+	if output == "" {
+		output = "No commits"
+	}
+	output, _ = truncate(output)
+	return &tools.Result{
+		Success: true,
+		Output:  output,
+		Metadata: map[string]interface{}{
+			"commits": commits,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
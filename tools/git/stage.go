@@ -0,0 +1,151 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// StageTool adds changes to the index ready to commit, or takes them out.
+// Credential files are never staged.
+type StageTool struct {
+	base
+}
+
+// NewStageTool creates a new git_stage tool.
+func NewStageTool() *StageTool {
+	return &StageTool{}
+}
+
+// Name returns the tool name.
+func (t *StageTool) Name() string {
+	return "git_stage"
+}
+
+// Description returns the tool description.
+func (t *StageTool) Description() string {
+	return "Stages files for the next commit, or unstages them. Credential files such as .env are refused"
+}
+
+// Parameters returns the tool parameters.
+func (t *StageTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "paths",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Files or directories to stage, one per line",
+		},
+		{
+			Name:        "all",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Stage every change, including new and deleted files",
+			Default:     false,
+		},
+		{
+			Name:        "unstage",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Take the paths out of the index instead, keeping the changes in the work tree",
+			Default:     false,
+		},
+		workingDirParam,
+	}
+}
+
+// RequiresPermission returns true as staging changes the index.
+func (t *StageTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the files in the permission prompt.
+func (t *StageTool) DescribeResource(params map[string]interface{}) string {
+	verb := "stage"
+	if boolParam(params, "unstage") {
+		verb = "unstage"
+	}
+	if boolParam(params, "all") {
+		return verb + " all changes"
+	}
+	return verb + " " + strings.Join(pathsParam(params, "paths"), ", ")
+}
+
+// Execute stages or unstages the paths.
+func (t *StageTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	dir := stringParam(params, "working_dir")
+	paths := pathsParam(params, "paths")
+	all := boolParam(params, "all")
+	if len(paths) == 0 && !all {
+		return failure(fmt.Errorf("give paths to stage, or set all"), startTime)
+	}
+	if all {
+		paths = []string{"."}
+	}
+
+	if boolParam(params, "unstage") {
+		args := append([]string{"restore", "--staged", "--"}, paths...)
+		if _, err := run(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+			// Nothing to restore from before the first commit
+			args = append([]string{"rm", "--cached", "-r", "--quiet", "--"}, paths...)
+		}
+		if _, err := run(ctx, dir, args...); err != nil {
+			return failure(err, startTime)
+		}
+		return &tools.Result{
+			Success:  true,
+			Output:   "Unstaged " + strings.Join(paths, ", "),
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	// See what would be staged before staging anything
+	args := append([]string{"add", "--dry-run", "--all", "--"}, paths...)
+	out, err := run(ctx, dir, args...)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	staged := parseDryRun(out)
+	if found := sensitive(staged); len(found) > 0 {
+		return failure(fmt.Errorf("refusing to stage credential files: %s", strings.Join(found, ", ")), startTime)
+	}
+	if len(staged) == 0 {
+		return &tools.Result{
+			Success:  true,
+			Output:   "Nothing to stage",
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	args = append([]string{"add", "--all", "--"}, paths...)
+	if _, err := run(ctx, dir, args...); err != nil {
+		return failure(err, startTime)
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  fmt.Sprintf("Staged %d files:\n  %s", len(staged), strings.Join(staged, "\n  ")),
+		Metadata: map[string]interface{}{
+			"paths": staged,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// parseDryRun returns the paths git add --dry-run lists, as in
+// "add 'main.go'" or "remove 'old.go'".
+func parseDryRun(out string) []string {
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		_, path, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		paths = append(paths, strings.Trim(path, "'"))
+	}
+	return paths
+}
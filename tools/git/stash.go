@@ -0,0 +1,147 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Stash actions
+const (
+	stashPush  = "push"
+	stashList  = "list"
+	stashShow  = "show"
+	stashApply = "apply"
+	stashPop   = "pop"
+)
+
+// StashTool sets changes aside and brings them back. Stashes are never
+// dropped or cleared, so none is ever lost.
+type StashTool struct {
+	base
+}
+
+// NewStashTool creates a new git_stash tool.
+func NewStashTool() *StashTool {
+	return &StashTool{}
+}
+
+// Name returns the tool name.
+func (t *StashTool) Name() string {
+	return "git_stash"
+}
+
+// Description returns the tool description.
+func (t *StashTool) Description() string {
+	return "Stashes uncommitted changes, lists or shows stashes, or applies or pops one back onto the work tree"
+}
+
+// Parameters returns the tool parameters.
+func (t *StashTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "action",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "What to do: push, list, show, apply or pop (default: push)",
+			Default:     stashPush,
+			Validation:  &tools.Validation{Enum: []string{stashPush, stashList, stashShow, stashApply, stashPop}},
+		},
+		{
+			Name:        "message",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "Description of the stash, for push",
+		},
+		{
+			Name:        "include_untracked",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Stash untracked files too, for push",
+			Default:     false,
+		},
+		{
+			Name:        "index",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Stash to show, apply or pop, 0 being the latest (default: 0)",
+			Default:     0,
+		},
+		workingDirParam,
+	}
+}
+
+// RequiresPermission returns true as stashing changes the work tree.
+func (t *StashTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the action in the permission prompt.
+func (t *StashTool) DescribeResource(params map[string]interface{}) string {
+	action := stringParam(params, "action")
+	if action == "" {
+		action = stashPush
+	}
+	return "stash " + action
+}
+
+// Execute carries out the action.
+func (t *StashTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	action := stringParam(params, "action")
+	if action == "" {
+		action = stashPush
+	}
+	index := intParam(params, "index", 0)
+	if index < 0 {
+		return failure(fmt.Errorf("invalid stash index %d", index), startTime)
+	}
+	ref := fmt.Sprintf("stash@{%d}", index)
+
+	var args []string
+	switch action {
+	case stashPush:
+		args = []string{"stash", "push"}
+		if boolParam(params, "include_untracked") {
+			args = append(args, "--include-untracked")
+		}
+		if message := stringParam(params, "message"); message != "" {
+			args = append(args, "-m", message)
+		}
+	case stashList:
+		args = []string{"stash", "list"}
+	case stashShow:
+		args = []string{"stash", "show", "--patch", "--no-color", ref}
+	case stashApply, stashPop:
+		args = []string{"stash", action, ref}
+	default:
+		return failure(fmt.Errorf("unknown action %q", action), startTime)
+	}
+
+	out, err := run(ctx, stringParam(params, "working_dir"), args...)
+	if err != nil {
+		return failure(err, startTime)
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		out = map[string]string{
+			stashList:  "No stashes",
+			stashApply: "Applied " + ref,
+			stashPop:   "Popped " + ref,
+		}[action]
+	}
+	out, truncated := truncate(out)
+	return &tools.Result{
+		Success: true,
+		Output:  out,
+		Metadata: map[string]interface{}{
+			"action":    action,
+			"truncated": truncated,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
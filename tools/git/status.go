@@ -0,0 +1,230 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// StatusTool reports the branch and the changed files of a repository.
+type StatusTool struct {
+	base
+}
+
+// NewStatusTool creates a new git_status tool.
+func NewStatusTool() *StatusTool {
+	return &StatusTool{}
+}
+
+// Name returns the tool name.
+func (t *StatusTool) Name() string {
+	return "git_status"
+}
+
+// Description returns the tool description.
+func (t *StatusTool) Description() string {
+	return "Shows the current branch, how far it is ahead of or behind its upstream, and the staged, unstaged, untracked and conflicted files"
+}
+
+// Parameters returns the tool parameters.
+func (t *StatusTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{workingDirParam}
+}
+
+// RequiresPermission returns false as status only reads the repository.
+func (t *StatusTool) RequiresPermission() bool {
+	return false
+}
+
+// fileChange is a changed file in the status of a repository.
+type fileChange struct {
+	Path     string
+	OrigPath string // Path before a rename or copy
+	Staged   byte   // Status in the index, '.' if unchanged
+	Unstaged byte   // Status in the work tree, '.' if unchanged
+}
+
+// status is the state of a repository.
+type status struct {
+	Branch     string // Empty when HEAD is detached
+	Commit     string // Abbreviated HEAD, empty before the first commit
+	Upstream   string
+	Ahead      int
+	Behind     int
+	Changes    []fileChange
+	Untracked  []string
+	Conflicted []string
+}
+
+// Execute reports the status.
+func (t *StatusTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	st, err := readStatus(ctx, stringParam(params, "working_dir"))
+	if err != nil {
+		return failure(err, startTime)
+	}
+
+	var staged, unstaged []string
+	for _, c := range st.Changes {
+		if c.Staged != '.' {
+			staged = append(staged, c.Path)
+		}
+		if c.Unstaged != '.' {
+			unstaged = append(unstaged, c.Path)
+		}
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  st.String(),
+		Metadata: map[string]interface{}{
+			"branch":     st.Branch,
+			"upstream":   st.Upstream,
+			"ahead":      st.Ahead,
+			"behind":     st.Behind,
+			"staged":     staged,
+			"unstaged":   unstaged,
+			"untracked":  st.Untracked,
+			"conflicted": st.Conflicted,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// readStatus reads the status of the repository dir is in.
+func readStatus(ctx context.Context, dir string) (*status, error) {
+	out, err := run(ctx, dir, "status", "--porcelain=v2", "--branch", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatus(out), nil
+}
+
+// parseStatus parses the output of git status --porcelain=v2 --branch -z.
+func parseStatus(out string) *status {
+	st := &status{}
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		if len(rec) < 2 {
+			continue
+		}
+		switch rec[0] {
+		case '#':
+			key, value, _ := strings.Cut(rec[2:], " ")
+			switch key {
+			case "branch.oid":
+				if value != "(initial)" && len(value) >= 7 {
+					st.Commit = value[:7]
+				}
+			case "branch.head":
+				if value != "(detached)" {
+					st.Branch = value
+				}
+			case "branch.upstream":
+				st.Upstream = value
+			case "branch.ab":
+				for _, f := range strings.Fields(value) {
+					n, _ := strconv.Atoi(f[1:])
+					if f[0] == '+' {
+						st.Ahead = n
+					} else {
+						st.Behind = n
+					}
+				}
+			}
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			if fields := strings.SplitN(rec, " ", 9); len(fields) == 9 {
+				st.Changes = append(st.Changes, fileChange{Path: fields[8], Staged: fields[1][0], Unstaged: fields[1][1]})
+			}
+		case '2':
+			// 2 XY sub mH mI mW hH hI Xscore path, then the original path
+			if fields := strings.SplitN(rec, " ", 10); len(fields) == 10 {
+				c := fileChange{Path: fields[9], Staged: fields[1][0], Unstaged: fields[1][1]}
+				if i+1 < len(records) {
+					i++
+					c.OrigPath = records[i]
+				}
+				st.Changes = append(st.Changes, c)
+			}
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			if fields := strings.SplitN(rec, " ", 11); len(fields) == 11 {
+				st.Conflicted = append(st.Conflicted, fields[10])
+			}
+		case '?':
+			st.Untracked = append(st.Untracked, rec[2:])
+		}
+	}
+	return st
+}
+
+// changeNames describe file status letters.
+var changeNames = map[byte]string{
+	'M': "modified", 'T': "type changed", 'A': "added", 'D': "deleted",
+	'R': "renamed", 'C': "copied",
+}
+
+// String describes the status the way git status does, briefly.
+func (st *status) String() string {
+	var b strings.Builder
+	switch {
+	case st.Branch != "":
+		fmt.Fprintf(&b, "On branch %s", st.Branch)
+	case st.Commit != "":
+		fmt.Fprintf(&b, "HEAD detached at %s", st.Commit)
+	default:
+		b.WriteString("HEAD detached")
+	}
+	if st.Upstream != "" {
+		fmt.Fprintf(&b, ", tracking %s", st.Upstream)
+		switch {
+		case st.Ahead > 0 && st.Behind > 0:
+			fmt.Fprintf(&b, " (ahead %d, behind %d)", st.Ahead, st.Behind)
+		case st.Ahead > 0:
+			fmt.Fprintf(&b, " (ahead %d)", st.Ahead)
+		case st.Behind > 0:
+			fmt.Fprintf(&b, " (behind %d)", st.Behind)
+		}
+	}
+	if st.Branch != "" && st.Commit == "" {
+		b.WriteString(", no commits yet")
+	}
+	b.WriteString("\n")
+
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	var staged, unstaged []string
+	for _, c := range st.Changes {
+		if name, ok := changeNames[c.Staged]; ok {
+			line := fmt.Sprintf("%s: %s", name, c.Path)
+			if c.OrigPath != "" {
+				line = fmt.Sprintf("%s: %s -> %s", name, c.OrigPath, c.Path)
+			}
+			staged = append(staged, line)
+		}
+		if name, ok := changeNames[c.Unstaged]; ok {
+			unstaged = append(unstaged, fmt.Sprintf("%s: %s", name, c.Path))
+		}
+	}
+	section("Conflicts", st.Conflicted)
+	section("Staged changes", staged)
+	section("Unstaged changes", unstaged)
+	section("Untracked files", st.Untracked)
+	if len(st.Changes)+len(st.Untracked)+len(st.Conflicted) == 0 {
+		b.WriteString("\nNothing to commit, working tree clean\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,530 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout is how long a server has to answer shutdown before it
+// is killed.
+const shutdownTimeout = 2 * time.Second
+
+// message is a JSON-RPC 2.0 request, notification or response.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+// rpcError is the error of a failed request.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// document is a file open in the server.
+type document struct {
+	version int
+	content string
+}
+
+// Client talks to one language server process over stdio.
+type Client struct {
+	name string
+	root string
+	cmd  *exec.Cmd
+	in   io.WriteCloser
+
+	writeMu sync.Mutex // Serializes messages written to the server
+
+	mu        sync.Mutex
+	nextID    int64
+	pending   map[int64]chan *message
+	docs      map[string]*document    // By URI
+	diags     map[string][]Diagnostic // Latest published per URI
+	published map[string]int          // Times diagnostics were published per URI
+	changed   chan struct{}           // Closed and replaced on each publish
+	methods   map[string]bool         // Requests the server announced support for
+	done      chan struct{}           // Closed when the server has exited
+	exitErr   error
+	stderr    *limitedWriter // Start of the server's stderr, for errors
+}
+
+// StartClient launches a language server for the project at root and
+// completes the initialize handshake.
+func StartClient(ctx context.Context, name string, server ServerConfig, root string) (*Client, error) {
+	cmd := exec.Command(server.Command, server.Args...)
+	cmd.Dir = root
+	cmd.Env = os.Environ()
+	for k, v := range server.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &limitedWriter{n: 64 << 10}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", server.Command, err)
+	}
+
+	c := &Client{
+		name:      name,
+		root:      root,
+		cmd:       cmd,
+		in:        in,
+		pending:   make(map[int64]chan *message),
+		docs:      make(map[string]*document),
+		diags:     make(map[string][]Diagnostic),
+		published: make(map[string]int),
+		changed:   make(chan struct{}),
+		methods:   make(map[string]bool),
+		done:      make(chan struct{}),
+		stderr:    stderr,
+	}
+	go c.readLoop(bufio.NewReader(out))
+
+	if err := c.initialize(ctx); err != nil {
+		c.kill()
+		return nil, fmt.Errorf("%s failed to initialize: %w", name, err)
+	}
+	return c, nil
+}
+
+// Name returns the name of the server's language.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// initialize sends initialize and initialized, recording what the server
+// supports.
+func (c *Client) initialize(ctx context.Context) error {
+	rootURI := FileURI(c.root)
+	params := map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   rootURI,
+		"rootPath":  c.root,
+		"clientInfo": map[string]string{
+			"name": "bplus",
+		},
+		"workspaceFolders": []map[string]string{
+			{"uri": rootURI, "name": c.root},
+		},
+		"capabilities": map[string]interface{}{
+			"workspace": map[string]interface{}{
+				"workspaceFolders": true,
+				"configuration":    true,
+				"workspaceEdit":    map[string]interface{}{"documentChanges": true},
+			},
+			"textDocument": map[string]interface{}{
+				"synchronization":    map[string]interface{}{"didSave": true},
+				"publishDiagnostics": map[string]interface{}{"relatedInformation": false},
+				"definition":         map[string]interface{}{"linkSupport": true},
+				"references":         map[string]interface{}{},
+				"rename":             map[string]interface{}{"prepareSupport": false},
+			},
+		},
+	}
+	var result struct {
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+	}
+	if err := c.Call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	for name, method := range map[string]string{
+		"definitionProvider": "textDocument/definition",
+		"referencesProvider": "textDocument/references",
+		"renameProvider":     "textDocument/rename",
+	} {
+		if raw, ok := result.Capabilities[name]; ok && string(raw) != "false" && string(raw) != "null" {
+			c.methods[method] = true
+		}
+	}
+	return c.Notify("initialized", map[string]interface{}{})
+}
+
+// Supports reports whether the server announced support for a request,
+// such as "textDocument/rename".
+func (c *Client) Supports(method string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.methods[method]
+}
+
+// Call sends a request and decodes its result into result, which may be
+// nil. Cancelling ctx cancels the request.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	reply := make(chan *message, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	raw := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.write(&message{ID: &raw, Method: method, Params: marshal(params)}); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-reply:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %w", method, msg.Error)
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	case <-ctx.Done():
+		_ = c.Notify("$/cancelRequest", map[string]int64{"id": id})
+		return ctx.Err()
+	case <-c.done:
+		return c.exited()
+	}
+}
+
+// Notify sends a notification.
+func (c *Client) Notify(method string, params interface{}) error {
+	return c.write(&message{Method: method, Params: marshal(params)})
+}
+
+// write sends a message with its Content-Length header.
+func (c *Client) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		select {
+		case <-c.done:
+			return c.exited()
+		default:
+			return fmt.Errorf("failed to write to %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// readLoop reads messages until the server exits, routing responses to
+// their callers and answering the server's own requests.
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer func() {
+		err := c.cmd.Wait()
+		c.mu.Lock()
+		c.exitErr = err
+		c.mu.Unlock()
+		close(c.done)
+	}()
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			c.answer(msg)
+		case msg.Method != "":
+			c.handleNotification(msg)
+		case msg.ID != nil:
+			id, err := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			reply := c.pending[id]
+			c.mu.Unlock()
+			if reply != nil {
+				reply <- msg
+			}
+		}
+	}
+}
+
+// readMessage reads one framed message.
+func readMessage(r *bufio.Reader) (*message, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %s", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// answer replies to a request from the server. Settings are left at the
+// server's defaults and progress and registrations are accepted.
+func (c *Client) answer(msg *message) {
+	var result interface{}
+	switch msg.Method {
+	case "workspace/configuration":
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		result = make([]interface{}, len(params.Items))
+	case "workspace/workspaceFolders":
+		result = []map[string]string{{"uri": FileURI(c.root), "name": c.root}}
+	}
+	_ = c.write(&message{ID: msg.ID, Result: marshal(result)})
+}
+
+// handleNotification records diagnostics the server publishes.
+func (c *Client) handleNotification(msg *message) {
+	if msg.Method != "textDocument/publishDiagnostics" {
+		return
+	}
+	var params publishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diags[params.URI] = params.Diagnostics
+	c.published[params.URI]++
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Sync tells the server the current content of a file, opening it first
+// if needed. It returns false if the server already had that content.
+func (c *Client) Sync(path, languageID string, content []byte) (bool, error) {
+	uri := FileURI(path)
+	text := string(content)
+
+	c.mu.Lock()
+	doc, open := c.docs[uri]
+	if open && doc.content == text {
+		c.mu.Unlock()
+		return false, nil
+	}
+	if !open {
+		doc = &document{}
+		c.docs[uri] = doc
+	}
+	doc.version++
+	doc.content = text
+	version := doc.version
+	c.mu.Unlock()
+
+	if !open {
+		return true, c.Notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"uri":        uri,
+				"languageId": languageID,
+				"version":    version,
+				"text":       text,
+			},
+		})
+	}
+	if err := c.Notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": version},
+		"contentChanges": []map[string]string{{"text": text}},
+	}); err != nil {
+		return true, err
+	}
+	return true, c.Notify("textDocument/didSave", map[string]interface{}{
+		"textDocument": textDocumentIdentifier{URI: uri},
+	})
+}
+
+// Published returns how many times diagnostics have been published for
+// each document, to tell later publishes from earlier ones.
+func (c *Client) Published() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int, len(c.published))
+	for uri, n := range c.published {
+		counts[uri] = n
+	}
+	return counts
+}
+
+// WaitDiagnostics waits until diagnostics have been published for every
+// URI in waitFor since the counts in since were taken, and then until the
+// server has gone quiet for settle, or ctx is done. It returns the latest
+// diagnostics of every document published to since then.
+func (c *Client) WaitDiagnostics(ctx context.Context, since map[string]int, waitFor []string, settle time.Duration) map[string][]Diagnostic {
+	for {
+		c.mu.Lock()
+		changed := c.changed
+		waiting := false
+		for _, uri := range waitFor {
+			if c.published[uri] <= since[uri] {
+				waiting = true
+				break
+			}
+		}
+		c.mu.Unlock()
+
+		var quiet <-chan time.Time
+		if !waiting {
+			quiet = time.After(settle)
+		}
+		select {
+		case <-changed:
+			continue
+		case <-quiet:
+		case <-ctx.Done():
+		case <-c.done:
+		}
+		break
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	diags := make(map[string][]Diagnostic)
+	for uri, n := range c.published {
+		if n > since[uri] {
+			diags[uri] = c.diags[uri]
+		}
+	}
+	return diags
+}
+
+// Diagnostics returns the latest diagnostics published for a file.
+func (c *Client) Diagnostics(path string) []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diags[FileURI(path)]
+}
+
+// Alive reports whether the server is still running.
+func (c *Client) Alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close shuts the server down, killing it if it does not exit in time.
+func (c *Client) Close() error {
+	if !c.Alive() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := c.Call(ctx, "shutdown", nil, nil); err == nil {
+		_ = c.Notify("exit", nil)
+	}
+	_ = c.in.Close()
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		c.kill()
+	}
+	return nil
+}
+
+// kill stops the server immediately and waits for it to exit.
+func (c *Client) kill() {
+	if c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+	<-c.done
+}
+
+// exited returns the error reported once the server has exited.
+func (c *Client) exited() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msg := fmt.Sprintf("%s exited", c.name)
+	if c.exitErr != nil {
+		msg += ": " + c.exitErr.Error()
+	}
+	if tail := lastLine(c.stderr.String()); tail != "" {
+		msg += ": " + tail
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// marshal encodes params, with nil as JSON null.
+func marshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// limitedWriter keeps the first n bytes written to it and drops the rest.
+type limitedWriter struct {
+	mu  sync.Mutex
+	buf strings.Builder
+	n   int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.n > 0 {
+		chunk := p[:min(len(p), l.n)]
+		l.n -= len(chunk)
+		l.buf.Write(chunk)
+	}
+	return len(p), nil
+}
+
+func (l *limitedWriter) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
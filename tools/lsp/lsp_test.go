@@ -0,0 +1,295 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary doubles as a language server when this is set.
+const fakeServerEnv = "BPLUS_FAKE_LSP"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer serves LSP over stdio: lines containing BROKEN are
+// errors, definitions point at the first line mentioning "func <word>",
+// references are every occurrence of the word, and renames replace them.
+func runFakeServer() {
+	r := bufio.NewReader(os.Stdin)
+	docs := make(map[string]string)
+	send := func(msg map[string]interface{}) {
+		msg["jsonrpc"] = "2.0"
+		body, _ := json.Marshal(msg)
+		fmt.Fprintf(os.Stdout, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	publish := func(uri string) {
+		diags := []Diagnostic{}
+		for i, line := range strings.Split(docs[uri], "\n") {
+			if col := strings.Index(line, "BROKEN"); col >= 0 {
+				diags = append(diags, Diagnostic{
+					Range:    Range{Start: Position{Line: i, Character: col}, End: Position{Line: i, Character: col + 6}},
+					Severity: SeverityError,
+					Source:   "fake",
+					Message:  "undefined: BROKEN",
+				})
+			}
+		}
+		send(map[string]interface{}{"method": "textDocument/publishDiagnostics", "params": publishDiagnosticsParams{URI: uri, Diagnostics: diags}})
+	}
+	occurrences := func(uri string, pos Position) (string, []Location) {
+		lines := strings.Split(docs[uri], "\n")
+		line := lines[pos.Line]
+		start, end := pos.Character, pos.Character
+		for start > 0 && isWord(line[start-1]) {
+			start--
+		}
+		for end < len(line) && isWord(line[end]) {
+			end++
+		}
+		word := line[start:end]
+		var locs []Location
+		for i, l := range lines {
+			for off := 0; ; {
+				j := strings.Index(l[off:], word)
+				if j < 0 {
+					break
+				}
+				off += j
+				locs = append(locs, Location{URI: uri, Range: Range{Start: Position{Line: i, Character: off}, End: Position{Line: i, Character: off + len(word)}}})
+				off += len(word)
+			}
+		}
+		return word, locs
+	}
+
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+			Position Position `json:"position"`
+			NewName  string   `json:"newName"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		uri := params.TextDocument.URI
+
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			// Ask for settings, as gopls does, before answering
+			send(map[string]interface{}{"id": "cfg", "method": "workspace/configuration", "params": map[string]interface{}{"items": []interface{}{map[string]string{}}}})
+			result = map[string]interface{}{"capabilities": map[string]interface{}{
+				"definitionProvider": true,
+				"referencesProvider": true,
+				"renameProvider":     map[string]bool{"prepareProvider": false},
+			}}
+		case "textDocument/didOpen":
+			docs[uri] = params.TextDocument.Text
+			publish(uri)
+			continue
+		case "textDocument/didChange":
+			docs[uri] = params.ContentChanges[len(params.ContentChanges)-1].Text
+			publish(uri)
+			continue
+		case "textDocument/definition":
+			word, _ := occurrences(uri, params.Position)
+			var locs []locationLink
+			for i, l := range strings.Split(docs[uri], "\n") {
+				if j := strings.Index(l, "func "+word); j >= 0 {
+					target := Range{Start: Position{Line: i, Character: j + 5}, End: Position{Line: i, Character: j + 5 + len(word)}}
+					locs = append(locs, locationLink{TargetURI: uri, TargetRange: target, TargetSelectionRange: target})
+					break
+				}
+			}
+			result = locs
+		case "textDocument/references":
+			_, result = occurrences(uri, params.Position)
+		case "textDocument/rename":
+			_, locs := occurrences(uri, params.Position)
+			var edits []TextEdit
+			for _, loc := range locs {
+				edits = append(edits, TextEdit{Range: loc.Range, NewText: params.NewName})
+			}
+			result = WorkspaceEdit{Changes: map[string][]TextEdit{uri: edits}}
+		case "shutdown":
+		case "exit":
+			return
+		default:
+			if msg.ID == nil {
+				continue // Notifications and replies to our requests
+			}
+		}
+		if msg.ID != nil {
+			send(map[string]interface{}{"id": msg.ID, "result": result})
+		}
+	}
+}
+
+func isWord(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// newFakeManager returns a manager whose .fake files are served by the
+// fake server, and a project directory for them.
+func newFakeManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	m := NewManager(dir, WithServers(map[string]ServerConfig{
+		"fake": {Command: exe, Env: map[string]string{fakeServerEnv: "1"}, Extensions: []string{".fake"}},
+	}), WithDiagnosticsWait(5*time.Second))
+	t.Cleanup(func() { _ = m.Close() })
+	return m, dir
+}
+
+const fakeSource = "func greet() {}\n\nfunc main() {\n\tgreet()\n\tgreet()\n}\n"
+
+func TestManager_Diagnose(t *testing.T) {
+	m, dir := newFakeManager(t)
+	path := filepath.Join(dir, "main.fake")
+	require.NoError(t, os.WriteFile(path, []byte(fakeSource), 0644))
+	ctx := context.Background()
+
+	diags, err := m.Diagnose(ctx, []string{path}, 0)
+	require.NoError(t, err)
+	require.Contains(t, diags, path)
+	assert.Empty(t, diags[path])
+	assert.Equal(t, []string{"fake"}, m.Running())
+
+	require.NoError(t, os.WriteFile(path, []byte(fakeSource+"BROKEN\n"), 0644))
+	diags, err = m.Diagnose(ctx, []string{path}, 0)
+	require.NoError(t, err)
+	require.Len(t, diags[path], 1)
+	assert.Equal(t, 7, diags[path][0].Range.Start.Line+1)
+	assert.Equal(t, 1, CountErrors(diags[path]))
+	assert.Equal(t, "main.fake:7:1: error: undefined: BROKEN (fake)", FormatDiagnostics(diags, dir))
+
+	// Unchanged files are answered from what the server last published
+	start := time.Now()
+	diags, err = m.Diagnose(ctx, []string{path}, 0)
+	require.NoError(t, err)
+	assert.Len(t, diags[path], 1)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Files no server is configured for are skipped
+	diags, err = m.Diagnose(ctx, []string{filepath.Join(dir, "notes.txt")}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, diags)
+}
+
+func TestManager_DefinitionAndReferences(t *testing.T) {
+	m, dir := newFakeManager(t)
+	path := filepath.Join(dir, "main.fake")
+	require.NoError(t, os.WriteFile(path, []byte(fakeSource), 0644))
+	ctx := context.Background()
+
+	def := NewDefinitionTool(m)
+	result, err := def.Execute(ctx, map[string]interface{}{"path": path, "line": 4, "symbol": "greet"})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	locs := result.Output.([]map[string]interface{})
+	require.Len(t, locs, 1)
+	assert.Equal(t, 1, locs[0]["line"])
+	assert.Equal(t, 6, locs[0]["column"])
+	assert.Equal(t, "func greet() {}", locs[0]["text"])
+
+	refs := NewReferencesTool(m)
+	result, err = refs.Execute(ctx, map[string]interface{}{"path": path, "line": 4, "column": float64(2)})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Len(t, result.Output.([]map[string]interface{}), 3)
+	assert.Equal(t, 3, result.Metadata["count"])
+
+	result, err = def.Execute(ctx, map[string]interface{}{"path": path, "line": 4, "symbol": "missing"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+}
+
+func TestRenameTool(t *testing.T) {
+	m, dir := newFakeManager(t)
+	path := filepath.Join(dir, "main.fake")
+	require.NoError(t, os.WriteFile(path, []byte(fakeSource), 0644))
+
+	result, err := NewRenameTool(m).Execute(context.Background(), map[string]interface{}{
+		"path":     path,
+		"line":     1,
+		"symbol":   "greet",
+		"new_name": "welcome",
+	})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, []string{path}, result.Metadata["paths"])
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strings.ReplaceAll(fakeSource, "greet", "welcome"), string(data))
+}
+
+func TestManager_MissingServer(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir, WithServers(map[string]ServerConfig{
+		"go": {Command: "bplus-no-such-language-server"},
+	}))
+	defer m.Close()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0644))
+
+	assert.False(t, m.Serves(path))
+	_, err := m.Diagnose(context.Background(), []string{path}, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not installed")
+
+	result, err := NewDiagnosticsTool(m).Execute(context.Background(), map[string]interface{}{"path": filepath.Join(dir, "notes.txt")})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.ErrorIs(t, result.Error, ErrNoServer)
+}
+
+func TestApplyEdits(t *testing.T) {
+	text := "héllo wörld\nsecond line\n"
+	edits := []TextEdit{
+		{Range: Range{Start: Position{Line: 1, Character: 0}, End: Position{Line: 1, Character: 6}}, NewText: "2nd"},
+		{Range: Range{Start: Position{Line: 0, Character: 6}, End: Position{Line: 0, Character: 11}}, NewText: "world"},
+	}
+	got, err := applyEdits(text, edits)
+	require.NoError(t, err)
+	assert.Equal(t, "héllo world\n2nd line\n", got)
+
+	_, err = applyEdits(text, append(edits, TextEdit{Range: Range{Start: Position{Line: 0, Character: 8}, End: Position{Line: 0, Character: 9}}}))
+	assert.Error(t, err)
+}
+
+func TestPositions(t *testing.T) {
+	line := "a😀b"
+	assert.Equal(t, 3, utf16Offset(line, 3))
+	assert.Equal(t, 3, characterColumn(line, 3))
+	assert.Equal(t, len("a😀"), byteIndex(line, 3))
+
+	path := filepath.Join(t.TempDir(), "a b.go")
+	assert.Equal(t, path, URIPath(FileURI(path)))
+	assert.True(t, strings.HasPrefix(FileURI(path), "file:///"))
+}
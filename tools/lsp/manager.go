@@ -0,0 +1,571 @@
+// Package lsp runs language servers (gopls, typescript-language-server,
+// pyright) for the project and exposes what they know about the code:
+// definitions, references, renames and the diagnostics of edited files.
+// Servers are started on first use, one per language, and only if their
+// command is installed.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDiagnosticsWait is how long Diagnose waits for a server to
+	// publish diagnostics of a changed file.
+	defaultDiagnosticsWait = 3 * time.Second
+
+	// diagnosticsSettle is how long a server must stay quiet after
+	// publishing before the diagnostics are taken as complete; servers
+	// often publish a file several times as analysis proceeds.
+	diagnosticsSettle = 250 * time.Millisecond
+
+	// initializeTimeout bounds the initialize handshake.
+	initializeTimeout = 30 * time.Second
+)
+
+// ErrNoServer is returned for files no language server is configured for.
+var ErrNoServer = errors.New("no language server for this file type")
+
+// ServerConfig describes how to launch a language server.
+type ServerConfig struct {
+	Command    string
+	Args       []string
+	Env        map[string]string
+	Extensions []string // File extensions served, with the dot (".go")
+}
+
+// DefaultServers returns the built-in servers by language.
+func DefaultServers() map[string]ServerConfig {
+	return map[string]ServerConfig{
+		"go": {
+			Command:    "gopls",
+			Extensions: []string{".go"},
+		},
+		"typescript": {
+			Command:    "typescript-language-server",
+			Args:       []string{"--stdio"},
+			Extensions: []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"},
+		},
+		"python": {
+			Command:    "pyright-langserver",
+			Args:       []string{"--stdio"},
+			Extensions: []string{".py", ".pyi"},
+		},
+	}
+}
+
+// languageIDs are the document language identifiers of file extensions
+// whose language differs from their server's name.
+var languageIDs = map[string]string{
+	".tsx": "typescriptreact",
+	".js":  "javascript",
+	".jsx": "javascriptreact",
+	".mjs": "javascript",
+	".cjs": "javascript",
+}
+
+// Manager starts and keeps the language servers of a project.
+type Manager struct {
+	root    string
+	servers map[string]ServerConfig
+	wait    time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*Client // By language
+	failed  map[string]error   // Servers that could not start, until Stop
+	closed  bool
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithServers adds servers by language, replacing the built-in server of
+// a language given. A server without extensions keeps those of the
+// built-in one.
+func WithServers(servers map[string]ServerConfig) Option {
+	return func(m *Manager) {
+		for name, server := range servers {
+			if len(server.Extensions) == 0 {
+				server.Extensions = m.servers[name].Extensions
+			}
+			m.servers[name] = server
+		}
+	}
+}
+
+// WithDiagnosticsWait sets how long Diagnose waits for diagnostics of a
+// changed file.
+func WithDiagnosticsWait(d time.Duration) Option {
+	return func(m *Manager) {
+		if d > 0 {
+			m.wait = d
+		}
+	}
+}
+
+// NewManager creates a manager for the project at root. No server is
+// started until a file it serves is used.
+func NewManager(root string, opts ...Option) *Manager {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	m := &Manager{
+		root:    root,
+		servers: DefaultServers(),
+		wait:    defaultDiagnosticsWait,
+		clients: make(map[string]*Client),
+		failed:  make(map[string]error),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// server returns the language serving a file and its server.
+func (m *Manager) server(path string) (string, ServerConfig, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, e := range m.servers[name].Extensions {
+			if strings.EqualFold(e, ext) {
+				return name, m.servers[name], true
+			}
+		}
+	}
+	return "", ServerConfig{}, false
+}
+
+// Serves reports whether a file has a configured language server whose
+// command is installed.
+func (m *Manager) Serves(path string) bool {
+	_, server, ok := m.server(path)
+	if !ok {
+		return false
+	}
+	_, err := exec.LookPath(server.Command)
+	return err == nil
+}
+
+// Client returns the running server of a file's language, starting it if
+// needed.
+func (m *Manager) Client(ctx context.Context, path string) (*Client, error) {
+	name, server, ok := m.server(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoServer, filepath.Base(path))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, fmt.Errorf("language servers are shut down")
+	}
+	if c := m.clients[name]; c != nil {
+		if c.Alive() {
+			return c, nil
+		}
+		delete(m.clients, name)
+		m.failed[name] = c.exited()
+	}
+	if err := m.failed[name]; err != nil {
+		return nil, err
+	}
+
+	if _, err := exec.LookPath(server.Command); err != nil {
+		m.failed[name] = fmt.Errorf("%s language server %s is not installed", name, server.Command)
+		return nil, m.failed[name]
+	}
+	startCtx, cancel := context.WithTimeout(ctx, initializeTimeout)
+	defer cancel()
+	c, err := StartClient(startCtx, name, server, m.root)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.failed[name] = err // Not retried on every edit
+		}
+		return nil, err
+	}
+	m.clients[name] = c
+	return c, nil
+}
+
+// open returns the server of a file with the file's current content
+// synced to it, and that content.
+func (m *Manager) open(ctx context.Context, path string) (*Client, string, error) {
+	c, err := m.Client(ctx, path)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := c.Sync(path, languageID(c.Name(), path), content); err != nil {
+		return nil, "", err
+	}
+	return c, string(content), nil
+}
+
+// Definition returns where the symbol at a 1-based line and character
+// column of a file is defined.
+func (m *Manager) Definition(ctx context.Context, path string, line, column int) ([]Location, error) {
+	c, content, err := m.open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Supports("textDocument/definition") {
+		return nil, fmt.Errorf("%s language server does not support definitions", c.Name())
+	}
+	params, err := positionParams(path, content, line, column)
+	if err != nil {
+		return nil, err
+	}
+	return locations(ctx, c, "textDocument/definition", params)
+}
+
+// References returns the uses of the symbol at a 1-based line and
+// character column of a file, with its declaration if includeDeclaration.
+func (m *Manager) References(ctx context.Context, path string, line, column int, includeDeclaration bool) ([]Location, error) {
+	c, content, err := m.open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Supports("textDocument/references") {
+		return nil, fmt.Errorf("%s language server does not support references", c.Name())
+	}
+	pos, err := positionParams(path, content, line, column)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"textDocument": pos.TextDocument,
+		"position":     pos.Position,
+		"context":      map[string]bool{"includeDeclaration": includeDeclaration},
+	}
+	return locations(ctx, c, "textDocument/references", params)
+}
+
+// Rename renames the symbol at a 1-based line and character column of a
+// file everywhere it is used, writing the changed files, and returns them
+// sorted.
+func (m *Manager) Rename(ctx context.Context, path string, line, column int, newName string) ([]string, error) {
+	c, content, err := m.open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Supports("textDocument/rename") {
+		return nil, fmt.Errorf("%s language server does not support renaming", c.Name())
+	}
+	pos, err := positionParams(path, content, line, column)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"textDocument": pos.TextDocument,
+		"position":     pos.Position,
+		"newName":      newName,
+	}
+	var edit *WorkspaceEdit
+	if err := c.Call(ctx, "textDocument/rename", params, &edit); err != nil {
+		return nil, err
+	}
+	if edit == nil {
+		return nil, fmt.Errorf("nothing to rename at %s:%d:%d", path, line, column)
+	}
+
+	changes, err := fileEdits(edit)
+	if err != nil {
+		return nil, err
+	}
+	// Every edit is computed before any file is written, so a rename the
+	// files can't take is not left half done
+	updated := make(map[string]string, len(changes))
+	for file, edits := range changes {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text, err := applyEdits(string(data), edits)
+		if err != nil {
+			return nil, fmt.Errorf("cannot apply rename to %s: %w", file, err)
+		}
+		updated[file] = text
+	}
+
+	files := make([]string, 0, len(updated))
+	for file, text := range updated {
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(file); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(file, []byte(text), mode); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+		if _, err := c.Sync(file, languageID(c.Name(), file), []byte(text)); err != nil {
+			return files, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Diagnose syncs files to their servers and returns the diagnostics they
+// publish, by path: for the files given and any other file the servers
+// report on meanwhile, such as callers an edit broke. It waits up to wait
+// (0 for the manager's default) for diagnostics of a changed file. Files
+// without a server are skipped; servers that fail to start are returned
+// as errors.
+func (m *Manager) Diagnose(ctx context.Context, paths []string, wait time.Duration) (map[string][]Diagnostic, error) {
+	if wait <= 0 {
+		wait = m.wait
+	}
+	byClient := make(map[*Client][]string)
+	var errs []error
+	for _, path := range paths {
+		c, err := m.Client(ctx, path)
+		if errors.Is(err, ErrNoServer) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		byClient[c] = append(byClient[c], path)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	diags := make(map[string][]Diagnostic)
+	for c, files := range byClient {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := m.diagnose(ctx, c, files, wait)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for path, d := range found {
+				diags[path] = d
+			}
+		}()
+	}
+	wg.Wait()
+	return diags, errors.Join(errs...)
+}
+
+// diagnose syncs files to one server and collects its diagnostics.
+func (m *Manager) diagnose(ctx context.Context, c *Client, files []string, wait time.Duration) (map[string][]Diagnostic, error) {
+	since := c.Published()
+	var waitFor []string
+	diags := make(map[string][]Diagnostic)
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				diags[path] = nil // Deleted, so nothing to report
+				continue
+			}
+			return nil, err
+		}
+		changed, err := c.Sync(path, languageID(c.Name(), path), content)
+		if err != nil {
+			return nil, err
+		}
+		if changed || since[FileURI(path)] == 0 {
+			waitFor = append(waitFor, FileURI(path))
+		} else {
+			diags[path] = c.Diagnostics(path)
+		}
+	}
+	if len(waitFor) == 0 {
+		return diags, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	for uri, d := range c.WaitDiagnostics(waitCtx, since, waitFor, diagnosticsSettle) {
+		diags[URIPath(uri)] = d
+	}
+	return diags, nil
+}
+
+// Running returns the languages whose servers are running, sorted.
+func (m *Manager) Running() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name, c := range m.clients {
+		if c.Alive() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stop shuts down the running servers. They are started again when next
+// needed, and servers that failed to start are tried again.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*Client)
+	m.failed = make(map[string]error)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = c.Close()
+		}()
+	}
+	wg.Wait()
+}
+
+// Close shuts down the running servers for good.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	m.Stop()
+	return nil
+}
+
+// languageID returns the document language of a file served by the
+// server of language.
+func languageID(language, path string) string {
+	if id, ok := languageIDs[strings.ToLower(filepath.Ext(path))]; ok {
+		return id
+	}
+	return language
+}
+
+// positionParams returns the request parameters of a 1-based line and
+// character column of a file with content.
+func positionParams(path, content string, line, column int) (textDocumentPosition, error) {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return textDocumentPosition{}, fmt.Errorf("line %d is out of range (%s has %d lines)", line, path, len(lines))
+	}
+	return textDocumentPosition{
+		TextDocument: textDocumentIdentifier{URI: FileURI(path)},
+		Position:     Position{Line: line - 1, Character: utf16Offset(lines[line-1], max(column, 1))},
+	}, nil
+}
+
+// locations sends a request answered with a Location, a list of them, or
+// a list of LocationLinks.
+func locations(ctx context.Context, c *Client, method string, params interface{}) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.Call(ctx, method, params, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		var loc Location
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			return nil, err
+		}
+		return []Location{loc}, nil
+	}
+
+	var items []struct {
+		Location
+		locationLink
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	locs := make([]Location, 0, len(items))
+	for _, item := range items {
+		if item.URI == "" && item.TargetURI != "" {
+			item.URI, item.Range = item.TargetURI, item.TargetSelectionRange
+		}
+		locs = append(locs, item.Location)
+	}
+	return locs, nil
+}
+
+// fileEdits returns the text edits of a workspace edit by file path.
+func fileEdits(edit *WorkspaceEdit) (map[string][]TextEdit, error) {
+	changes := make(map[string][]TextEdit)
+	for uri, edits := range edit.Changes {
+		changes[URIPath(uri)] = append(changes[URIPath(uri)], edits...)
+	}
+	for _, dc := range edit.DocumentChanges {
+		if dc.Kind != "" {
+			return nil, fmt.Errorf("the rename needs a file %s, which is not supported", dc.Kind)
+		}
+		path := URIPath(dc.TextDocument.URI)
+		changes[path] = append(changes[path], dc.Edits...)
+	}
+	return changes, nil
+}
+
+// applyEdits returns text with non-overlapping edits applied.
+func applyEdits(text string, edits []TextEdit) (string, error) {
+	lines := strings.SplitAfter(text, "\n")
+	offset := func(p Position) (int, error) {
+		if p.Line > len(lines) || p.Line == len(lines) && p.Character > 0 {
+			return 0, fmt.Errorf("position %d:%d is past the end of the file", p.Line+1, p.Character)
+		}
+		start := 0
+		for _, l := range lines[:p.Line] {
+			start += len(l)
+		}
+		if p.Line == len(lines) {
+			return start, nil
+		}
+		return start + byteIndex(strings.TrimRight(lines[p.Line], "\r\n"), p.Character), nil
+	}
+
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, err := offset(e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := offset(e.Range.End)
+		if err != nil {
+			return "", err
+		}
+		spans = append(spans, span{start, end, e.NewText})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last || s.end < s.start {
+			return "", fmt.Errorf("overlapping edits")
+		}
+		b.WriteString(text[last:s.start])
+		b.WriteString(s.text)
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
@@ -0,0 +1,166 @@
+package lsp
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a zero-based line and UTF-16 offset within it, as the
+// Language Server Protocol counts them.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span of a document, its end exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// locationLink is what servers may answer definition requests with
+// instead of a Location.
+type locationLink struct {
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+// Diagnostic severities.
+const (
+	SeverityError       = 1
+	SeverityWarning     = 2
+	SeverityInformation = 3
+	SeverityHint        = 4
+)
+
+// Diagnostic is a problem a language server found in a document.
+type Diagnostic struct {
+	Range    Range           `json:"range"`
+	Severity int             `json:"severity,omitempty"`
+	Code     json.RawMessage `json:"code,omitempty"` // A number or a string
+	Source   string          `json:"source,omitempty"`
+	Message  string          `json:"message"`
+}
+
+// TextEdit replaces a range of a document with new text.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit is a set of edits across documents, such as a rename
+// produces. Servers fill in Changes or DocumentChanges.
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []documentChange      `json:"documentChanges,omitempty"`
+}
+
+// documentChange is an edit of one document, or a file operation (create,
+// rename, delete) when Kind is set.
+type documentChange struct {
+	Kind         string `json:"kind,omitempty"`
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Edits []TextEdit `json:"edits"`
+}
+
+// publishDiagnosticsParams are the parameters of the
+// textDocument/publishDiagnostics notification.
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// textDocumentPosition identifies a position in a document, the
+// parameters of definition and references requests.
+type textDocumentPosition struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// FileURI returns the file:// URI of a path.
+func FileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letters
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// URIPath returns the path a file:// URI names.
+func URIPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // Windows drive letters
+	}
+	return filepath.FromSlash(path)
+}
+
+// utf16Offset returns the UTF-16 offset of the column'th character
+// (1-based) of line, the way positions are sent to servers.
+func utf16Offset(line string, column int) int {
+	offset := 0
+	for _, r := range line {
+		if column <= 1 {
+			break
+		}
+		offset += utf16Len(r)
+		column--
+	}
+	return offset
+}
+
+// characterColumn returns the 1-based character column of a UTF-16
+// offset into line, the way positions are shown.
+func characterColumn(line string, offset int) int {
+	column := 1
+	for _, r := range line {
+		if offset <= 0 {
+			break
+		}
+		offset -= utf16Len(r)
+		column++
+	}
+	return column
+}
+
+// byteIndex returns the byte offset of a UTF-16 offset into line.
+func byteIndex(line string, offset int) int {
+	for i, r := range line {
+		if offset <= 0 {
+			return i
+		}
+		offset -= utf16Len(r)
+	}
+	return len(line)
+}
+
+// utf16Len returns how many UTF-16 code units encode r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
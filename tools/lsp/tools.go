@@ -0,0 +1,540 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Tools returns the tools backed by a manager's language servers.
+func Tools(m *Manager) []tools.Tool {
+	return []tools.Tool{
+		NewDefinitionTool(m),
+		NewReferencesTool(m),
+		NewRenameTool(m),
+		NewDiagnosticsTool(m),
+	}
+}
+
+// positionParameters are the parameters locating a symbol.
+var positionParameters = []tools.Parameter{
+	{
+		Name:        "path",
+		Type:        tools.TypeString,
+		Required:    true,
+		Description: "File containing the symbol",
+	},
+	{
+		Name:        "line",
+		Type:        tools.TypeInt,
+		Required:    true,
+		Description: "Line of the symbol (1-based)",
+	},
+	{
+		Name:        "column",
+		Type:        tools.TypeInt,
+		Required:    false,
+		Description: "Column of the symbol (1-based, in characters); give this or symbol",
+		Default:     0,
+	},
+	{
+		Name:        "symbol",
+		Type:        tools.TypeString,
+		Required:    false,
+		Description: "Name of the symbol on the line, used to find its column when column is not given",
+		Default:     "",
+	},
+}
+
+// symbolPosition returns the file, line and column the position
+// parameters name.
+func symbolPosition(params map[string]interface{}) (string, int, int, error) {
+	path, _ := params["path"].(string)
+	if path == "" {
+		return "", 0, 0, fmt.Errorf("path is required")
+	}
+	line := intParam(params, "line")
+	if line < 1 {
+		return "", 0, 0, fmt.Errorf("line is required")
+	}
+	if column := intParam(params, "column"); column > 0 {
+		return path, line, column, nil
+	}
+	symbol, _ := params["symbol"].(string)
+	if symbol == "" {
+		return "", 0, 0, fmt.Errorf("column or symbol is required")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	lines := strings.Split(string(content), "\n")
+	if line > len(lines) {
+		return "", 0, 0, fmt.Errorf("line %d is out of range (%s has %d lines)", line, path, len(lines))
+	}
+	column, ok := symbolColumn(lines[line-1], symbol)
+	if !ok {
+		return "", 0, 0, fmt.Errorf("symbol %q not found on line %d of %s", symbol, line, path)
+	}
+	return path, line, column, nil
+}
+
+// symbolColumn returns the 1-based character column of the first
+// occurrence of symbol on line as a whole word, or else as a substring.
+func symbolColumn(line, symbol string) (int, bool) {
+	index := -1
+	if loc := regexp.MustCompile(`\b` + regexp.QuoteMeta(symbol) + `\b`).FindStringIndex(line); loc != nil {
+		index = loc[0]
+	} else {
+		index = strings.Index(line, symbol)
+	}
+	if index < 0 {
+		return 0, false
+	}
+	return len([]rune(line[:index])) + 1, true
+}
+
+// describeLocations returns locations as the files, lines and columns
+// they point at, with the text of each line, sorted.
+func describeLocations(locs []Location) []map[string]interface{} {
+	files := make(map[string][]string)
+	described := make([]map[string]interface{}, 0, len(locs))
+	for _, loc := range locs {
+		path := URIPath(loc.URI)
+		lines, ok := files[path]
+		if !ok {
+			if data, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(data), "\n")
+			}
+			files[path] = lines
+		}
+		entry := map[string]interface{}{
+			"file": path,
+			"line": loc.Range.Start.Line + 1,
+		}
+		if loc.Range.Start.Line < len(lines) {
+			text := strings.TrimSuffix(lines[loc.Range.Start.Line], "\r")
+			entry["column"] = characterColumn(text, loc.Range.Start.Character)
+			entry["text"] = strings.TrimSpace(text)
+		}
+		described = append(described, entry)
+	}
+	sort.SliceStable(described, func(i, j int) bool {
+		a, b := described[i], described[j]
+		if a["file"] != b["file"] {
+			return a["file"].(string) < b["file"].(string)
+		}
+		return a["line"].(int) < b["line"].(int)
+	})
+	return described
+}
+
+// DefinitionTool finds where a symbol is defined.
+type DefinitionTool struct {
+	manager *Manager
+}
+
+// NewDefinitionTool creates a new LSP definition tool.
+func NewDefinitionTool(m *Manager) *DefinitionTool {
+	return &DefinitionTool{manager: m}
+}
+
+// Name returns the tool name.
+func (t *DefinitionTool) Name() string {
+	return "lsp_definition"
+}
+
+// Description returns the tool description.
+func (t *DefinitionTool) Description() string {
+	return "Goes to the definition of the symbol at a position in a Go, TypeScript/JavaScript or Python file, resolved by the language server " +
+		"(imports, methods and shadowed names included)"
+}
+
+// Parameters returns the tool parameters.
+func (t *DefinitionTool) Parameters() []tools.Parameter {
+	return positionParameters
+}
+
+// RequiresPermission returns true as reading the workspace requires permission.
+func (t *DefinitionTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute finds the definition.
+func (t *DefinitionTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	path, line, column, err := symbolPosition(params)
+	if err != nil {
+		return failed(err, startTime), nil
+	}
+	locs, err := t.manager.Definition(ctx, path, line, column)
+	if err != nil {
+		return failed(err, startTime), nil
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  describeLocations(locs),
+		Metadata: map[string]interface{}{
+			"path":  path,
+			"count": len(locs),
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *DefinitionTool) Category() string {
+	return "lsp"
+}
+
+// Version returns the tool version.
+func (t *DefinitionTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *DefinitionTool) IsExternal() bool {
+	return false
+}
+
+// ReferencesTool finds the uses of a symbol.
+type ReferencesTool struct {
+	manager *Manager
+}
+
+// NewReferencesTool creates a new LSP references tool.
+func NewReferencesTool(m *Manager) *ReferencesTool {
+	return &ReferencesTool{manager: m}
+}
+
+// Name returns the tool name.
+func (t *ReferencesTool) Name() string {
+	return "lsp_references"
+}
+
+// Description returns the tool description.
+func (t *ReferencesTool) Description() string {
+	return "Finds every reference to the symbol at a position in a Go, TypeScript/JavaScript or Python file across the project, resolved by the language server"
+}
+
+// Parameters returns the tool parameters.
+func (t *ReferencesTool) Parameters() []tools.Parameter {
+	return append(append([]tools.Parameter{}, positionParameters...), tools.Parameter{
+		Name:        "include_declaration",
+		Type:        tools.TypeBool,
+		Required:    false,
+		Description: "Include the declaration itself (default: true)",
+		Default:     true,
+	})
+}
+
+// RequiresPermission returns true as reading the workspace requires permission.
+func (t *ReferencesTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute finds the references.
+func (t *ReferencesTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	path, line, column, err := symbolPosition(params)
+	if err != nil {
+		return failed(err, startTime), nil
+	}
+	includeDeclaration := true
+	if v, ok := params["include_declaration"].(bool); ok {
+		includeDeclaration = v
+	}
+	locs, err := t.manager.References(ctx, path, line, column, includeDeclaration)
+	if err != nil {
+		return failed(err, startTime), nil
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  describeLocations(locs),
+		Metadata: map[string]interface{}{
+			"path":  path,
+			"count": len(locs),
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *ReferencesTool) Category() string {
+	return "lsp"
+}
+
+// Version returns the tool version.
+func (t *ReferencesTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *ReferencesTool) IsExternal() bool {
+	return false
+}
+
+// RenameTool renames a symbol everywhere it is used.
+type RenameTool struct {
+	manager *Manager
+}
+
+// NewRenameTool creates a new LSP rename tool.
+func NewRenameTool(m *Manager) *RenameTool {
+	return &RenameTool{manager: m}
+}
+
+// Name returns the tool name.
+func (t *RenameTool) Name() string {
+	return "lsp_rename"
+}
+
+// Description returns the tool description.
+func (t *RenameTool) Description() string {
+	return "Renames the symbol at a position in a Go, TypeScript/JavaScript or Python file across the project, editing every file that uses it; " +
+		"safer than find-and-replace as only references to that symbol change"
+}
+
+// Parameters returns the tool parameters.
+func (t *RenameTool) Parameters() []tools.Parameter {
+	return append(append([]tools.Parameter{}, positionParameters...), tools.Parameter{
+		Name:        "new_name",
+		Type:        tools.TypeString,
+		Required:    true,
+		Description: "New name of the symbol",
+	})
+}
+
+// RequiresPermission returns true as renaming writes files.
+func (t *RenameTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute performs the rename.
+func (t *RenameTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	path, line, column, err := symbolPosition(params)
+	if err != nil {
+		return failed(err, startTime), nil
+	}
+	newName, _ := params["new_name"].(string)
+	if newName == "" {
+		return failed(fmt.Errorf("new_name is required"), startTime), nil
+	}
+	files, err := t.manager.Rename(ctx, path, line, column, newName)
+	if err != nil {
+		result := failed(err, startTime)
+		if len(files) > 0 {
+			result.Metadata = map[string]interface{}{"paths": files}
+		}
+		return result, nil
+	}
+
+	return &tools.Result{
+		Success: true,
+		Output:  fmt.Sprintf("Renamed to %s in %d file(s): %s", newName, len(files), strings.Join(files, ", ")),
+		Metadata: map[string]interface{}{
+			"paths":    files,
+			"new_name": newName,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *RenameTool) Category() string {
+	return "lsp"
+}
+
+// Version returns the tool version.
+func (t *RenameTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *RenameTool) IsExternal() bool {
+	return false
+}
+
+// DiagnosticsTool reports the problems a language server finds in a file.
+type DiagnosticsTool struct {
+	manager *Manager
+}
+
+// NewDiagnosticsTool creates a new LSP diagnostics tool.
+func NewDiagnosticsTool(m *Manager) *DiagnosticsTool {
+	return &DiagnosticsTool{manager: m}
+}
+
+// Name returns the tool name.
+func (t *DiagnosticsTool) Name() string {
+	return "lsp_diagnostics"
+}
+
+// Description returns the tool description.
+func (t *DiagnosticsTool) Description() string {
+	return "Reports the compile errors, type errors and warnings the language server finds in a Go, TypeScript/JavaScript or Python file " +
+		"(and files it reports on meanwhile), without running a build"
+}
+
+// Parameters returns the tool parameters.
+func (t *DiagnosticsTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "path",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "File to check",
+		},
+		{
+			Name:        "timeout",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "Seconds to wait for the server to finish analysis (default: 10)",
+			Default:     10,
+		},
+	}
+}
+
+// RequiresPermission returns true as reading the workspace requires permission.
+func (t *DiagnosticsTool) RequiresPermission() bool {
+	return true
+}
+
+// Execute collects the diagnostics.
+func (t *DiagnosticsTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	path, _ := params["path"].(string)
+	if path == "" {
+		return failed(fmt.Errorf("path is required"), startTime), nil
+	}
+	if _, err := t.manager.Client(ctx, path); err != nil {
+		return failed(err, startTime), nil
+	}
+	timeout := 10 * time.Second
+	if seconds := intParam(params, "timeout"); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	diags, err := t.manager.Diagnose(ctx, []string{path}, timeout)
+	if err != nil {
+		return failed(err, startTime), nil
+	}
+	errorCount := 0
+	for _, d := range diags {
+		errorCount += CountErrors(d)
+	}
+	return &tools.Result{
+		Success: true,
+		Output:  FormatDiagnostics(diags, ""),
+		Metadata: map[string]interface{}{
+			"path":   path,
+			"errors": errorCount,
+		},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// Category returns the tool category.
+func (t *DiagnosticsTool) Category() string {
+	return "lsp"
+}
+
+// Version returns the tool version.
+func (t *DiagnosticsTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *DiagnosticsTool) IsExternal() bool {
+	return false
+}
+
+// CountErrors returns how many diagnostics are errors.
+func CountErrors(diags []Diagnostic) int {
+	n := 0
+	for _, d := range diags {
+		if d.Severity == SeverityError || d.Severity == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// FormatDiagnostics renders diagnostics by file as "path:line:col:
+// severity: message" lines, with paths relative to base where they are
+// inside it. Files without diagnostics are left out.
+func FormatDiagnostics(diags map[string][]Diagnostic, base string) string {
+	paths := make([]string, 0, len(diags))
+	for path := range diags {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		name := path
+		if base != "" {
+			if rel, err := filepath.Rel(base, path); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+		}
+		list := append([]Diagnostic(nil), diags[path]...)
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Range.Start.Line < list[j].Range.Start.Line })
+		for _, d := range list {
+			fmt.Fprintf(&b, "%s:%d:%d: %s: %s", name, d.Range.Start.Line+1, d.Range.Start.Character+1, SeverityName(d.Severity), d.Message)
+			if d.Source != "" {
+				fmt.Fprintf(&b, " (%s)", d.Source)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	if b.Len() == 0 {
+		return "No problems found"
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// SeverityName returns the name of a diagnostic severity. Servers that
+// leave it out mean an error.
+func SeverityName(severity int) string {
+	switch severity {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// failed returns the result of a call that failed.
+func failed(err error, startTime time.Time) *tools.Result {
+	return &tools.Result{
+		Success:  false,
+		Error:    err,
+		Duration: time.Since(startTime),
+	}
+}
+
+// intParam returns an integer parameter, which arrives as a float64 when
+// decoded from JSON.
+func intParam(params map[string]interface{}, name string) int {
+	switch v := params[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,243 @@
+// Package plugin loads third-party tools compiled to WebAssembly, so users
+// can add tools without forking b+. Modules are discovered as *.wasm files
+// in a directory, ~/.config/bplus/plugins by default, and each provides
+// one tool, registered as plugin.<name>.
+//
+// # Tool ABI
+//
+// A module exports its linear memory as "memory" and three functions:
+//
+//	bplus_alloc(size i32) i32          // A buffer of size bytes for the host to write into
+//	bplus_describe() i64               // The tool's Descriptor as JSON
+//	bplus_call(ptr i32, len i32) i64   // Runs the tool on the JSON params at ptr
+//
+// Results are returned as ptr<<32 | len of a buffer in the module's
+// memory. bplus_call returns a Response as JSON. Modules run with WASI
+// preview 1 but no preopened directories, environment or arguments, so
+// a plugin sees only the params it is given; each call is bounded by
+// callTimeout.
+//
+// Modules run on wazero, a WebAssembly runtime written in Go.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// ABI functions
+const (
+	FuncAlloc    = "bplus_alloc"
+	FuncDescribe = "bplus_describe"
+	FuncCall     = "bplus_call"
+)
+
+// callTimeout bounds a single call of a plugin tool.
+const callTimeout = 30 * time.Second
+
+// Descriptor is what a module reports about its tool.
+type Descriptor struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Version     string      `json:"version,omitempty"`
+	Parameters  []ParamSpec `json:"parameters,omitempty"`
+}
+
+// ParamSpec describes a tool parameter.
+type ParamSpec struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // string, int, float, bool, array, object or any
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Response is what a module returns from a call. A non-empty Error fails
+// the call.
+type Response struct {
+	Output   string                 `json:"output"`
+	Error    string                 `json:"error,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Instance is an instantiated module.
+type Instance interface {
+	// Call invokes an ABI function with input written to the module's
+	// memory (none for bplus_describe) and returns the buffer it returned.
+	Call(ctx context.Context, function string, input []byte) ([]byte, error)
+	// Close releases the instance.
+	Close(ctx context.Context) error
+}
+
+// Runtime compiles and instantiates modules.
+type Runtime interface {
+	Instantiate(ctx context.Context, name string, wasm []byte) (Instance, error)
+	Close(ctx context.Context) error
+}
+
+// Option configures Load.
+type Option func(*loadOptions)
+
+type loadOptions struct {
+	runtime Runtime
+}
+
+// WithRuntime runs modules with rt instead of a wazero runtime.
+func WithRuntime(rt Runtime) Option {
+	return func(o *loadOptions) {
+		o.runtime = rt
+	}
+}
+
+// Discover returns the module files in dir, sorted. A missing dir has none.
+func Discover(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".wasm") {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Plugins are the tool plugins loaded from a directory.
+type Plugins struct {
+	runtime Runtime
+	owned   bool // The runtime was created by Load
+	tools   []*Tool
+}
+
+// Load instantiates the modules in dir. Modules that fail to load are
+// skipped and reported in the returned error, alongside the ones that did.
+func Load(ctx context.Context, dir string, opts ...Option) (*Plugins, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	paths, err := Discover(dir)
+	if err != nil || len(paths) == 0 {
+		return &Plugins{}, err
+	}
+
+	p := &Plugins{runtime: o.runtime}
+	if p.runtime == nil {
+		p.runtime = NewRuntime(ctx)
+		p.owned = true
+	}
+
+	var errs []error
+	seen := make(map[string]string)
+	for _, path := range paths {
+		tool, err := p.load(ctx, path)
+		if err == nil && seen[tool.Name()] != "" {
+			err = fmt.Errorf("tool %s is already provided by %s", tool.Name(), filepath.Base(seen[tool.Name()]))
+			tool.instance.Close(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", filepath.Base(path), err))
+			continue
+		}
+		seen[tool.Name()] = path
+		p.tools = append(p.tools, tool)
+	}
+	return p, errors.Join(errs...)
+}
+
+// toolName is what a plugin tool may be named.
+var toolName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// load instantiates one module and reads its descriptor.
+func (p *Plugins) load(ctx context.Context, path string) (*Tool, error) {
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	instance, err := p.runtime.Instantiate(ctx, name, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	raw, err := instance.Call(ctx, FuncDescribe, nil)
+	if err != nil {
+		instance.Close(ctx)
+		return nil, fmt.Errorf("%s failed: %w", FuncDescribe, err)
+	}
+	var d Descriptor
+	if err := json.Unmarshal(raw, &d); err != nil {
+		instance.Close(ctx)
+		return nil, fmt.Errorf("invalid descriptor: %w", err)
+	}
+	if err := d.validate(); err != nil {
+		instance.Close(ctx)
+		return nil, err
+	}
+	return &Tool{descriptor: d, path: path, instance: instance}, nil
+}
+
+// validate checks that a descriptor can be registered as a tool.
+func (d *Descriptor) validate() error {
+	if !toolName.MatchString(d.Name) {
+		return fmt.Errorf("invalid tool name %q: use lowercase letters, digits and underscores", d.Name)
+	}
+	if strings.TrimSpace(d.Description) == "" {
+		return fmt.Errorf("tool %s has no description", d.Name)
+	}
+	for _, param := range d.Parameters {
+		if param.Name == "" {
+			return fmt.Errorf("tool %s has a parameter without a name", d.Name)
+		}
+		switch tools.ParameterType(param.Type) {
+		case tools.TypeString, tools.TypeInt, tools.TypeFloat, tools.TypeBool, tools.TypeArray, tools.TypeObject, tools.TypeAny:
+		default:
+			return fmt.Errorf("parameter %s of tool %s has invalid type %q", param.Name, d.Name, param.Type)
+		}
+	}
+	return nil
+}
+
+// Tools returns the loaded tools.
+func (p *Plugins) Tools() []*Tool {
+	return p.tools
+}
+
+// Close releases the instances, and the runtime if Load started it. A nil
+// Plugins has nothing to close.
+func (p *Plugins) Close() error {
+	if p == nil {
+		return nil
+	}
+	ctx := context.Background()
+	var errs []error
+	for _, tool := range p.tools {
+		if err := tool.instance.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.tools = nil
+	if p.owned && p.runtime != nil {
+		if err := p.runtime.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRuntime treats a module's bytes as its descriptor; its tools echo
+// their params back upper-cased.
+type fakeRuntime struct {
+	open int
+}
+
+func (r *fakeRuntime) Instantiate(ctx context.Context, name string, wasm []byte) (Instance, error) {
+	if strings.HasPrefix(string(wasm), "broken") {
+		return nil, fmt.Errorf("invalid magic number")
+	}
+	r.open++
+	return &fakeInstance{runtime: r, descriptor: wasm}, nil
+}
+
+func (r *fakeRuntime) Close(ctx context.Context) error { return nil }
+
+type fakeInstance struct {
+	runtime    *fakeRuntime
+	descriptor []byte
+}
+
+func (i *fakeInstance) Call(ctx context.Context, function string, input []byte) ([]byte, error) {
+	switch function {
+	case FuncDescribe:
+		return i.descriptor, nil
+	case FuncCall:
+		var params map[string]interface{}
+		if err := json.Unmarshal(input, &params); err != nil {
+			return nil, err
+		}
+		text, _ := params["text"].(string)
+		if text == "" {
+			return json.Marshal(Response{Error: "text is required"})
+		}
+		return json.Marshal(Response{Output: strings.ToUpper(text), Metadata: map[string]interface{}{"length": len(text)}})
+	}
+	return nil, fmt.Errorf("unknown function %s", function)
+}
+
+func (i *fakeInstance) Close(ctx context.Context) error {
+	i.runtime.open--
+	return nil
+}
+
+const shoutModule = `{"name":"shout","description":"Upper-cases text","version":"0.2.0",
+	"parameters":[{"name":"text","type":"string","required":true,"description":"Text to shout"}]}`
+
+func writeModules(t *testing.T, modules map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range modules {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"shout.wasm":   shoutModule,
+		"README.md":    "not a module",
+		"broken.wasm":  "broken",
+		"badname.wasm": `{"name":"Bad Name","description":"x"}`,
+		"badtype.wasm": `{"name":"typed","description":"x","parameters":[{"name":"n","type":"number"}]}`,
+		"twice.wasm":   shoutModule,
+	})
+	rt := &fakeRuntime{}
+	p, err := Load(context.Background(), dir, WithRuntime(rt))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin broken.wasm: failed to instantiate")
+	assert.Contains(t, err.Error(), `invalid tool name "Bad Name"`)
+	assert.Contains(t, err.Error(), `invalid type "number"`)
+	assert.Contains(t, err.Error(), "tool shout is already provided by shout.wasm")
+
+	require.Len(t, p.Tools(), 1)
+	tool := p.Tools()[0]
+	assert.Equal(t, "shout", tool.Name())
+	assert.Equal(t, "0.2.0", tool.Version())
+	assert.Equal(t, filepath.Join(dir, "shout.wasm"), tool.Path())
+	assert.Equal(t, []tools.Parameter{{Name: "text", Type: tools.TypeString, Required: true, Description: "Text to shout"}}, tool.Parameters())
+	assert.Equal(t, 1, rt.open)
+
+	require.NoError(t, p.Close())
+	assert.Equal(t, 0, rt.open)
+}
+
+func TestLoad_NoModules(t *testing.T) {
+	p, err := Load(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, p.Tools())
+}
+
+func TestTool_Execute(t *testing.T) {
+	p, err := Load(context.Background(), writeModules(t, map[string]string{"shout.wasm": shoutModule}), WithRuntime(&fakeRuntime{}))
+	require.NoError(t, err)
+	defer p.Close()
+	tool := p.Tools()[0]
+
+	var _ tools.Tool = tool
+	assert.Equal(t, "plugin", tool.Category())
+	assert.True(t, tool.IsExternal())
+	assert.True(t, tool.RequiresPermission())
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"text": "hello"})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "HELLO", result.Output)
+	assert.Equal(t, float64(5), result.Metadata["length"])
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.EqualError(t, result.Error, "text is required")
+
+	registry := tools.NewRegistry()
+	require.NoError(t, registry.Register(tool))
+	_, err = registry.Get("plugin.shout")
+	assert.NoError(t, err)
+}
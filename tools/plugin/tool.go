@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Tool is a tool provided by a module. An instance runs one call at a
+// time.
+type Tool struct {
+	descriptor Descriptor
+	path       string
+
+	mu       sync.Mutex
+	instance Instance
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return t.descriptor.Name
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return t.descriptor.Description
+}
+
+// Parameters returns the tool parameters.
+func (t *Tool) Parameters() []tools.Parameter {
+	params := make([]tools.Parameter, len(t.descriptor.Parameters))
+	for i, p := range t.descriptor.Parameters {
+		params[i] = tools.Parameter{
+			Name:        p.Name,
+			Type:        tools.ParameterType(p.Type),
+			Required:    p.Required,
+			Description: p.Description,
+		}
+	}
+	return params
+}
+
+// Category returns the tool category.
+func (t *Tool) Category() string {
+	return "plugin"
+}
+
+// Version returns the version the module reports.
+func (t *Tool) Version() string {
+	if t.descriptor.Version == "" {
+		return "0.0.0"
+	}
+	return t.descriptor.Version
+}
+
+// IsExternal returns true: the tool is registered as plugin.<name>.
+func (t *Tool) IsExternal() bool {
+	return true
+}
+
+// RequiresPermission returns true: plugins are third-party code.
+func (t *Tool) RequiresPermission() bool {
+	return true
+}
+
+// Path returns the module file the tool was loaded from.
+func (t *Tool) Path() string {
+	return t.path
+}
+
+// Execute calls the module with the params.
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	input, err := json.Marshal(params)
+	if err != nil {
+		return failure(fmt.Errorf("failed to encode params: %w", err), startTime)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	t.mu.Lock()
+	raw, err := t.instance.Call(ctx, FuncCall, input)
+	t.mu.Unlock()
+	if err != nil {
+		return failure(fmt.Errorf("plugin %s failed: %w", t.Name(), err), startTime)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return failure(fmt.Errorf("plugin %s returned an invalid response: %w", t.Name(), err), startTime)
+	}
+	if resp.Error != "" {
+		return &tools.Result{
+			Success:  false,
+			Error:    fmt.Errorf("%s", resp.Error),
+			Metadata: resp.Metadata,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   resp.Output,
+		Metadata: resp.Metadata,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// failure is the result of a call that failed.
+func failure(err error, startTime time.Time) (*tools.Result, error) {
+	return &tools.Result{
+		Success:  false,
+		Error:    err,
+		Duration: time.Since(startTime),
+	}, nil
+}
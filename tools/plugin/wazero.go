@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wazeroRuntime runs modules on wazero.
+type wazeroRuntime struct {
+	runtime wazero.Runtime
+}
+
+// NewRuntime returns a wazero runtime with WASI preview 1. Modules are
+// stopped when the context of a call is done, so callTimeout holds for
+// modules that never return.
+func NewRuntime(ctx context.Context) Runtime {
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	return &wazeroRuntime{runtime: r}
+}
+
+// Instantiate compiles and instantiates a module. Reactor modules are
+// initialized through _initialize; _start is not run, as plugins are
+// called through the ABI rather than run as commands.
+func (r *wazeroRuntime) Instantiate(ctx context.Context, name string, wasm []byte) (Instance, error) {
+	compiled, err := r.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, err
+	}
+	config := wazero.NewModuleConfig().WithName(name).WithStartFunctions("_initialize")
+	module, err := r.runtime.InstantiateModule(ctx, compiled, config)
+	if err != nil {
+		compiled.Close(ctx)
+		return nil, err
+	}
+	memory := module.ExportedMemory("memory")
+	if memory == nil {
+		module.Close(ctx)
+		compiled.Close(ctx)
+		return nil, fmt.Errorf("module does not export memory")
+	}
+	return &wazeroInstance{module: module, compiled: compiled, memory: memory}, nil
+}
+
+// Close closes the runtime and every module in it.
+func (r *wazeroRuntime) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+// wazeroInstance is a module instantiated by wazero.
+type wazeroInstance struct {
+	module   api.Module
+	compiled wazero.CompiledModule
+	memory   api.Memory
+}
+
+// Call writes input to a buffer from bplus_alloc, calls function with it
+// and copies out the buffer the function returns.
+func (i *wazeroInstance) Call(ctx context.Context, function string, input []byte) ([]byte, error) {
+	fn := i.module.ExportedFunction(function)
+	if fn == nil {
+		return nil, fmt.Errorf("module does not export %s", function)
+	}
+
+	var args []uint64
+	if input != nil {
+		alloc := i.module.ExportedFunction(FuncAlloc)
+		if alloc == nil {
+			return nil, fmt.Errorf("module does not export %s", FuncAlloc)
+		}
+		results, err := alloc.Call(ctx, uint64(len(input)))
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %w", FuncAlloc, err)
+		}
+		if len(results) != 1 {
+			return nil, fmt.Errorf("%s returned %d values, want 1", FuncAlloc, len(results))
+		}
+		ptr := uint32(results[0])
+		if !i.memory.Write(ptr, input) {
+			return nil, fmt.Errorf("%s returned a buffer outside memory", FuncAlloc)
+		}
+		args = []uint64{uint64(ptr), uint64(len(input))}
+	}
+
+	results, err := fn.Call(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("%s returned %d values, want 1", function, len(results))
+	}
+	ptr, size := uint32(results[0]>>32), uint32(results[0])
+	out, ok := i.memory.Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("%s returned a buffer outside memory", function)
+	}
+	// Read aliases the module's memory, which the next call may reuse
+	return bytes.Clone(out), nil
+}
+
+// Close releases the module.
+func (i *wazeroInstance) Close(ctx context.Context) error {
+	err := i.module.Close(ctx)
+	if cerr := i.compiled.Close(ctx); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoModule assembles a module implementing the ABI: bplus_describe
+// returns descriptor from a data segment, bplus_alloc hands out a fixed
+// buffer and bplus_call returns its params as the response. With spin,
+// bplus_call never returns.
+func echoModule(descriptor string, spin bool) []byte {
+	call := []byte{
+		0x20, 0x00, 0xad, // local.get 0, i64.extend_i32_u
+		0x42, 0x20, 0x86, // i64.const 32, i64.shl
+		0x20, 0x01, 0xad, // local.get 1, i64.extend_i32_u
+		0x84, // i64.or
+	}
+	if spin {
+		call = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00} // loop, br 0, end, unreachable
+	}
+	describe := append([]byte{0x42}, sleb(int64(len(descriptor)))...) // i64.const 0<<32 | len
+
+	module := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(1, vec(
+		[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},       // (i32) -> i32
+		[]byte{0x60, 0x00, 0x01, 0x7e},             // () -> i64
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e}, // (i32, i32) -> i64
+	))...)
+	module = append(module, section(3, vec([]byte{0}, []byte{1}, []byte{2}))...)
+	module = append(module, section(5, vec([]byte{0x00, 0x01}))...) // One page
+	module = append(module, section(7, vec(
+		export("memory", 0x02, 0),
+		export(FuncAlloc, 0x00, 0),
+		export(FuncDescribe, 0x00, 1),
+		export(FuncCall, 0x00, 2),
+	))...)
+	module = append(module, section(10, vec(
+		body([]byte{0x41, 0x80, 0x08}), // i32.const 1024
+		body(describe),
+		body(call),
+	))...)
+	data := append([]byte{0x00, 0x41, 0x00, 0x0b}, name(descriptor)...) // At offset 0
+	return append(module, section(11, vec(data))...)
+}
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb(uint64(len(content)))...), content...)
+}
+
+func vec(items ...[]byte) []byte {
+	out := uleb(uint64(len(items)))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+func name(s string) []byte {
+	return append(uleb(uint64(len(s))), s...)
+}
+
+func export(s string, kind, index byte) []byte {
+	return append(name(s), kind, index)
+}
+
+func body(code []byte) []byte {
+	code = append(append([]byte{0x00}, code...), 0x0b) // No locals, end
+	return append(uleb(uint64(len(code))), code...)
+}
+
+func uleb(v uint64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func writeWasm(t *testing.T, modules map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, wasm := range modules {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), wasm, 0o644))
+	}
+	return dir
+}
+
+func TestWazero_Load(t *testing.T) {
+	dir := writeWasm(t, map[string][]byte{
+		"echo.wasm":    echoModule(`{"name":"echo","description":"Returns its params","version":"1.0.0"}`, false),
+		"garbage.wasm": []byte("not wasm"),
+	})
+	p, err := Load(context.Background(), dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin garbage.wasm: failed to instantiate")
+	defer p.Close()
+
+	require.Len(t, p.Tools(), 1)
+	tool := p.Tools()[0]
+	assert.Equal(t, "echo", tool.Name())
+	assert.Equal(t, "1.0.0", tool.Version())
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"output": "hello from wasm", "metadata": map[string]interface{}{"n": 1}})
+	require.NoError(t, err)
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "hello from wasm", result.Output)
+	assert.Equal(t, float64(1), result.Metadata["n"])
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"error": "boom"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.EqualError(t, result.Error, "boom")
+}
+
+func TestWazero_CallTimeout(t *testing.T) {
+	dir := writeWasm(t, map[string][]byte{
+		"spin.wasm": echoModule(`{"name":"spin","description":"Never returns"}`, true),
+	})
+	p, err := Load(context.Background(), dir)
+	require.NoError(t, err)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := p.Tools()[0].Execute(ctx, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "plugin spin failed")
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestWazero_MissingExports(t *testing.T) {
+	rt := NewRuntime(context.Background())
+	defer rt.Close(context.Background())
+
+	// An empty module exports no memory
+	_, err := rt.Instantiate(context.Background(), "empty", []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00})
+	assert.EqualError(t, err, "module does not export memory")
+}
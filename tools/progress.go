@@ -0,0 +1,34 @@
+package tools
+
+import "context"
+
+// Progress is how far a long-running tool call has got. Tools that write
+// large files report bytes; tools that work on several files also report
+// files; tools that run commands report their latest output. Zero totals
+// are unknown.
+type Progress struct {
+	Path       string // File being worked on
+	Bytes      int64  // Bytes of Path done
+	TotalBytes int64
+	Files      int // Files completed
+	TotalFiles int
+	Output     string // Tail of the output so far
+}
+
+// ProgressFunc receives the progress of a tool call.
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose tool calls report progress to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress reports the progress of the tool call running with ctx, if
+// anything is listening.
+func ReportProgress(ctx context.Context, p Progress) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(p)
+	}
+}
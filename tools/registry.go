@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -10,14 +11,16 @@ import (
 
 // Registry manages all available tools with support for namespacing and plugins.
 type Registry struct {
-	tools map[string]Tool // Namespaced tool name -> tool
-	mu    sync.RWMutex
+	tools    map[string]Tool // Namespaced tool name -> tool
+	disabled map[string]bool // Namespaced tool name -> disabled at runtime
+	mu       sync.RWMutex
 }
 
 // NewRegistry creates a new tool registry.
 func NewRegistry() *Registry {
 	return &Registry{
-		tools: make(map[string]Tool),
+		tools:    make(map[string]Tool),
+		disabled: make(map[string]bool),
 	}
 }
 
@@ -149,6 +152,7 @@ func (r *Registry) Unregister(name string) error {
 	// Try exact match
 	if _, exists := r.tools[name]; exists {
 		delete(r.tools, name)
+		delete(r.disabled, name)
 		return nil
 	}
 
@@ -157,6 +161,7 @@ func (r *Registry) Unregister(name string) error {
 		coreName := "core." + name
 		if _, exists := r.tools[coreName]; exists {
 			delete(r.tools, coreName)
+			delete(r.disabled, coreName)
 			return nil
 		}
 	}
@@ -164,6 +169,133 @@ func (r *Registry) Unregister(name string) error {
 	return fmt.Errorf("tool %s not found", name)
 }
 
+// resolveName returns the namespaced name a tool is registered under,
+// using the same fallbacks as Get. Caller must hold the lock.
+func (r *Registry) resolveName(name string) (string, bool) {
+	if _, exists := r.tools[name]; exists {
+		return name, true
+	}
+	if !strings.Contains(name, ".") {
+		for _, namespace := range []string{"core", "plugin"} {
+			if _, exists := r.tools[namespace+"."+name]; exists {
+				return namespace + "." + name, true
+			}
+		}
+	}
+	return name, false
+}
+
+// SetEnabled enables or disables a registered tool at runtime.
+// Disabled tools are hidden from the model and refuse to execute.
+func (r *Registry) SetEnabled(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resolved, ok := r.resolveName(name)
+	if !ok {
+		return fmt.Errorf("tool %s not found", name)
+	}
+
+	if enabled {
+		delete(r.disabled, resolved)
+	} else {
+		r.disabled[resolved] = true
+	}
+	return nil
+}
+
+// IsEnabled returns whether a tool is registered and enabled.
+func (r *Registry) IsEnabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resolved, ok := r.resolveName(name)
+	return ok && !r.disabled[resolved]
+}
+
+// ApplyFilter applies the tools.enabled_tools / tools.disabled_tools
+// configuration. A non-empty enabled list disables every registered tool
+// not in it; the disabled list is applied afterwards. Names may be given
+// with or without namespace. Unknown names are returned as an error after
+// the known ones have been applied.
+func (r *Registry) ApplyFilter(enabled, disabled []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unknown []string
+
+	if len(enabled) > 0 {
+		allowed := make(map[string]bool, len(enabled))
+		for _, name := range enabled {
+			resolved, ok := r.resolveName(name)
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			allowed[resolved] = true
+		}
+		for name := range r.tools {
+			if !allowed[name] {
+				r.disabled[name] = true
+			}
+		}
+	}
+
+	for _, name := range disabled {
+		resolved, ok := r.resolveName(name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		r.disabled[resolved] = true
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown tools in configuration: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// EnabledTools returns all registered tools that are currently enabled.
+func (r *Registry) EnabledTools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.tools))
+	for name, tool := range r.tools {
+		if !r.disabled[name] {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// ToolState describes a registered tool and whether it is enabled.
+type ToolState struct {
+	Name     string // Namespaced name (e.g. "core.bash")
+	Category string
+	Enabled  bool
+}
+
+// States returns the enabled state of every registered tool, sorted by name.
+func (r *Registry) States() []ToolState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make([]ToolState, 0, len(r.tools))
+	for name, tool := range r.tools {
+		states = append(states, ToolState{
+			Name:     name,
+			Category: tool.Category(),
+			Enabled:  !r.disabled[name],
+		})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
 // Execute executes a tool with the given parameters and context.
 // Includes permission checking and audit logging.
 func (r *Registry) Execute(ctx context.Context, toolName string, params map[string]interface{}, execCtx *ExecutionContext) (*Result, error) {
@@ -172,6 +304,10 @@ func (r *Registry) Execute(ctx context.Context, toolName string, params map[stri
 		return nil, err
 	}
 
+	if !r.IsEnabled(toolName) {
+		return nil, fmt.Errorf("tool %s is disabled", toolName)
+	}
+
 	// Validate parameters
 	if err := ValidateParameters(params, tool.Parameters()); err != nil {
 		return nil, err
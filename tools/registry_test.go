@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTool is a minimal Tool implementation for registry tests.
+type stubTool struct {
+	name     string
+	category string
+}
+
+func (t *stubTool) Name() string             { return t.name }
+func (t *stubTool) Description() string      { return "stub" }
+func (t *stubTool) Parameters() []Parameter  { return nil }
+func (t *stubTool) RequiresPermission() bool { return false }
+func (t *stubTool) Category() string         { return t.category }
+func (t *stubTool) Version() string          { return "1.0.0" }
+func (t *stubTool) IsExternal() bool         { return false }
+func (t *stubTool) Execute(ctx context.Context, params map[string]interface{}) (*Result, error) {
+	return &Result{Success: true, Output: t.name}, nil
+}
+
+func newTestRegistry(t *testing.T) *Registry {
+	reg := NewRegistry()
+	require.NoError(t, reg.Register(&stubTool{name: "read", category: "file"}))
+	require.NoError(t, reg.Register(&stubTool{name: "write", category: "file"}))
+	require.NoError(t, reg.Register(&stubTool{name: "bash", category: "exec"}))
+	return reg
+}
+
+// TestRegistry_SetEnabled tests runtime tool toggling.
+func TestRegistry_SetEnabled(t *testing.T) {
+	reg := newTestRegistry(t)
+	assert.True(t, reg.IsEnabled("bash"))
+	assert.Len(t, reg.EnabledTools(), 3)
+
+	require.NoError(t, reg.SetEnabled("bash", false))
+	assert.False(t, reg.IsEnabled("bash"))
+	assert.False(t, reg.IsEnabled("core.bash"))
+	assert.Len(t, reg.EnabledTools(), 2)
+
+	_, err := reg.Execute(context.Background(), "bash", nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disabled")
+
+	require.NoError(t, reg.SetEnabled("core.bash", true))
+	result, err := reg.Execute(context.Background(), "bash", nil, nil)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+
+	assert.Error(t, reg.SetEnabled("missing", false))
+	assert.False(t, reg.IsEnabled("missing"))
+}
+
+// TestRegistry_ApplyFilter tests enabled_tools/disabled_tools configuration.
+func TestRegistry_ApplyFilter(t *testing.T) {
+	t.Run("Disabled list", func(t *testing.T) {
+		reg := newTestRegistry(t)
+		require.NoError(t, reg.ApplyFilter(nil, []string{"bash"}))
+		assert.False(t, reg.IsEnabled("bash"))
+		assert.True(t, reg.IsEnabled("read"))
+	})
+
+	t.Run("Enabled list restricts", func(t *testing.T) {
+		reg := newTestRegistry(t)
+		require.NoError(t, reg.ApplyFilter([]string{"core.read"}, nil))
+		assert.True(t, reg.IsEnabled("read"))
+		assert.False(t, reg.IsEnabled("write"))
+		assert.False(t, reg.IsEnabled("bash"))
+	})
+
+	t.Run("Unknown names reported", func(t *testing.T) {
+		reg := newTestRegistry(t)
+		err := reg.ApplyFilter(nil, []string{"bash", "nope"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nope")
+		assert.False(t, reg.IsEnabled("bash"))
+	})
+}
+
+// TestRegistry_States tests the sorted tool state listing.
+func TestRegistry_States(t *testing.T) {
+	reg := newTestRegistry(t)
+	require.NoError(t, reg.SetEnabled("write", false))
+
+	states := reg.States()
+	require.Len(t, states, 3)
+	assert.Equal(t, "core.bash", states[0].Name)
+	assert.Equal(t, "core.read", states[1].Name)
+	assert.Equal(t, "core.write", states[2].Name)
+	assert.False(t, states[2].Enabled)
+	assert.Equal(t, "file", states[2].Category)
+}
@@ -0,0 +1,18 @@
+package tools
+
+import "context"
+
+type sessionKey struct{}
+
+// WithSession returns a context whose tool calls belong to the session
+// with the given ID, for tools that keep state per session.
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, id)
+}
+
+// SessionID returns the ID of the session the tool call running with ctx
+// belongs to, or "" if it isn't known.
+func SessionID(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return id
+}
@@ -0,0 +1,292 @@
+// Package todo provides the task list the agent keeps while it works, so
+// it can plan multi-step work and show the user how far it has got.
+//
+// Lists are kept per session by a Store; calls made outside a session
+// share a list that lasts as long as the tool.
+package todo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Todo statuses
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// Item is an entry of a task list.
+type Item struct {
+	ID      int64  `json:"id"`
+	Content string `json:"content"`
+	Status  string `json:"status"`
+}
+
+// Store keeps the task lists of sessions.
+type Store interface {
+	// List returns a session's items in order.
+	List(session string) ([]Item, error)
+	// Create appends an item to a session's list and returns it with its ID.
+	Create(session string, item Item) (Item, error)
+	// Update saves an item of a session's list.
+	Update(session string, item Item) error
+}
+
+// Listener receives a session's whole list after a call changes it.
+type Listener func(session string, items []Item)
+
+// Option configures a Tool.
+type Option func(*Tool)
+
+// WithListener sets the function told about every change to a list.
+func WithListener(fn Listener) Option {
+	return func(t *Tool) {
+		t.listener = fn
+	}
+}
+
+// Tool creates, updates and completes the items of the session's task
+// list.
+type Tool struct {
+	store    Store
+	listener Listener
+	local    string // Session of calls made outside one
+}
+
+// NewTodoTool creates a todo tool keeping its lists in store.
+func NewTodoTool(store Store, opts ...Option) *Tool {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	t := &Tool{store: store, local: "local-" + hex.EncodeToString(b)}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return "todo"
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return "Keeps your task list for this session, shown live to the user: create adds items, update changes an item's text or status, " +
+		"complete marks an item done and list shows the list. Every call returns the whole list"
+}
+
+// Parameters returns the tool parameters.
+func (t *Tool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "action",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "create, update, complete or list",
+		},
+		{
+			Name:        "items",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For create: the items to add in order, one per line",
+		},
+		{
+			Name:        "id",
+			Type:        tools.TypeInt,
+			Required:    false,
+			Description: "For update and complete: the item's ID",
+		},
+		{
+			Name:        "status",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For update: pending, in_progress or completed",
+		},
+		{
+			Name:        "content",
+			Type:        tools.TypeString,
+			Required:    false,
+			Description: "For update: the item's new text",
+		},
+	}
+}
+
+// Category returns the tool category.
+func (t *Tool) Category() string {
+	return "task"
+}
+
+// Version returns the tool version.
+func (t *Tool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *Tool) IsExternal() bool {
+	return false
+}
+
+// RequiresPermission returns false: the list is the agent's own notes.
+func (t *Tool) RequiresPermission() bool {
+	return false
+}
+
+// Execute runs the action.
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+	session := tools.SessionID(ctx)
+	if session == "" {
+		session = t.local
+	}
+
+	action, _ := params["action"].(string)
+	action = strings.TrimSpace(action)
+	var err error
+	switch action {
+	case "create":
+		err = t.create(session, params)
+	case "update":
+		err = t.update(session, params, false)
+	case "complete":
+		err = t.update(session, params, true)
+	case "list":
+	default:
+		err = fmt.Errorf("invalid action %q: must be create, update, complete or list", action)
+	}
+	if err != nil {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	items, err := t.store.List(session)
+	if err != nil {
+		return &tools.Result{
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+	if action != "list" && t.listener != nil {
+		t.listener(session, items)
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   format(items),
+		Metadata: map[string]interface{}{"todos": items},
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// create adds the items given one per line.
+func (t *Tool) create(session string, params map[string]interface{}) error {
+	text, _ := params["items"].(string)
+	var contents []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*"))
+		if line != "" {
+			contents = append(contents, line)
+		}
+	}
+	if len(contents) == 0 {
+		return fmt.Errorf("items is required for create")
+	}
+	for _, content := range contents {
+		if _, err := t.store.Create(session, Item{Content: content, Status: StatusPending}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update changes an item's content or status, or completes it.
+func (t *Tool) update(session string, params map[string]interface{}, complete bool) error {
+	id, ok := intParam(params, "id")
+	if !ok {
+		return fmt.Errorf("id is required")
+	}
+	items, err := t.store.List(session)
+	if err != nil {
+		return err
+	}
+	var item *Item
+	for i := range items {
+		if items[i].ID == id {
+			item = &items[i]
+		}
+	}
+	if item == nil {
+		return fmt.Errorf("no todo #%d in this session's list", id)
+	}
+
+	if complete {
+		item.Status = StatusCompleted
+	} else {
+		status, _ := params["status"].(string)
+		content, _ := params["content"].(string)
+		status, content = strings.TrimSpace(status), strings.TrimSpace(content)
+		if status == "" && content == "" {
+			return fmt.Errorf("status or content is required for update")
+		}
+		switch status {
+		case "":
+		case StatusPending, StatusInProgress, StatusCompleted:
+			item.Status = status
+		default:
+			return fmt.Errorf("invalid status %q: must be pending, in_progress or completed", status)
+		}
+		if content != "" {
+			item.Content = content
+		}
+	}
+	return t.store.Update(session, *item)
+}
+
+// intParam returns an integer parameter, and whether it was given.
+func intParam(params map[string]interface{}, name string) (int64, bool) {
+	switch v := params[name].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// statusMarks are how the list shows each status.
+var statusMarks = map[string]string{
+	StatusPending:    "[ ]",
+	StatusInProgress: "[~]",
+	StatusCompleted:  "[x]",
+}
+
+// format renders the list, an item per line, after a count of those done.
+func format(items []Item) string {
+	if len(items) == 0 {
+		return "The task list is empty"
+	}
+	done := 0
+	for _, item := range items {
+		if item.Status == StatusCompleted {
+			done++
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d done", done, len(items))
+	for _, item := range items {
+		fmt.Fprintf(&b, "\n#%d %s %s", item.ID, statusMarks[item.Status], item.Content)
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/abrksh22/bplus/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore keeps lists in memory.
+type memStore struct {
+	nextID int64
+	lists  map[string][]Item
+}
+
+func newMemStore() *memStore {
+	return &memStore{lists: make(map[string][]Item)}
+}
+
+func (s *memStore) List(session string) ([]Item, error) {
+	return append([]Item(nil), s.lists[session]...), nil
+}
+
+func (s *memStore) Create(session string, item Item) (Item, error) {
+	s.nextID++
+	item.ID = s.nextID
+	s.lists[session] = append(s.lists[session], item)
+	return item, nil
+}
+
+func (s *memStore) Update(session string, item Item) error {
+	for i, existing := range s.lists[session] {
+		if existing.ID == item.ID {
+			s.lists[session][i] = item
+			return nil
+		}
+	}
+	return fmt.Errorf("todo not found: #%d", item.ID)
+}
+
+func execute(t *testing.T, tool tools.Tool, ctx context.Context, params map[string]interface{}) *tools.Result {
+	t.Helper()
+	result, err := tool.Execute(ctx, params)
+	require.NoError(t, err)
+	return result
+}
+
+func TestTodoTool(t *testing.T) {
+	store := newMemStore()
+	var updates [][]Item
+	tool := NewTodoTool(store, WithListener(func(session string, items []Item) {
+		assert.Equal(t, "s1", session)
+		updates = append(updates, items)
+	}))
+	ctx := tools.WithSession(context.Background(), "s1")
+
+	result := execute(t, tool, ctx, map[string]interface{}{"action": "create", "items": "- Read the parser\n\n- Fix the off-by-one\nAdd tests"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "0 of 3 done\n#1 [ ] Read the parser\n#2 [ ] Fix the off-by-one\n#3 [ ] Add tests", result.Output)
+
+	result = execute(t, tool, ctx, map[string]interface{}{"action": "update", "id": float64(1), "status": "in_progress"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Contains(t, result.Output, "#1 [~] Read the parser")
+
+	result = execute(t, tool, ctx, map[string]interface{}{"action": "complete", "id": 1})
+	require.True(t, result.Success, "%v", result.Error)
+	result = execute(t, tool, ctx, map[string]interface{}{"action": "update", "id": 3, "content": "Add a regression test"})
+	require.True(t, result.Success, "%v", result.Error)
+	assert.Equal(t, "1 of 3 done\n#1 [x] Read the parser\n#2 [ ] Fix the off-by-one\n#3 [ ] Add a regression test", result.Output)
+	assert.Equal(t, store.lists["s1"], result.Metadata["todos"])
+
+	// Listing changes nothing, so nobody is told
+	result = execute(t, tool, ctx, map[string]interface{}{"action": "list"})
+	require.True(t, result.Success, "%v", result.Error)
+	require.Len(t, updates, 4)
+	assert.Equal(t, StatusCompleted, updates[3][0].Status)
+}
+
+func TestTodoToolErrors(t *testing.T) {
+	store := newMemStore()
+	tool := NewTodoTool(store)
+	ctx := tools.WithSession(context.Background(), "s1")
+	require.True(t, execute(t, tool, ctx, map[string]interface{}{"action": "create", "items": "Plan"}).Success)
+
+	for name, params := range map[string]map[string]interface{}{
+		"unknown action":  {"action": "delete"},
+		"no items":        {"action": "create", "items": "\n- \n"},
+		"no id":           {"action": "complete"},
+		"no change":       {"action": "update", "id": 1},
+		"invalid status":  {"action": "update", "id": 1, "status": "done"},
+		"another session": {"action": "complete", "id": 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.False(t, execute(t, tool, ctx, params).Success)
+		})
+	}
+
+	// Another session's items can't be reached
+	other := tools.WithSession(context.Background(), "s2")
+	result := execute(t, tool, other, map[string]interface{}{"action": "complete", "id": 1})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "no todo #1")
+}
+
+func TestTodoToolWithoutSession(t *testing.T) {
+	store := newMemStore()
+	tool := NewTodoTool(store)
+	result := execute(t, tool, context.Background(), map[string]interface{}{"action": "create", "items": "Plan"})
+	require.True(t, result.Success, "%v", result.Error)
+	result = execute(t, tool, context.Background(), map[string]interface{}{"action": "list"})
+	assert.Equal(t, "0 of 1 done\n#1 [ ] Plan", result.Output)
+
+	// Each tool keeps its own list outside sessions
+	result = execute(t, NewTodoTool(store), context.Background(), map[string]interface{}{"action": "list"})
+	assert.Equal(t, "The task list is empty", result.Output)
+}
+
+func TestToolMetadata(t *testing.T) {
+	tool := NewTodoTool(newMemStore())
+	assert.Equal(t, "todo", tool.Name())
+	assert.Equal(t, "task", tool.Category())
+	assert.Equal(t, "1.0.0", tool.Version())
+	assert.False(t, tool.IsExternal())
+	assert.False(t, tool.RequiresPermission())
+	assert.NotEmpty(t, tool.Description())
+}
@@ -26,6 +26,32 @@ type Tool interface {
 	IsExternal() bool // true if loaded from plugin
 }
 
+// ResourceDescriber is implemented by tools that can describe what a call
+// will do better than its raw arguments, for permission prompts.
+type ResourceDescriber interface {
+	DescribeResource(params map[string]interface{}) string
+}
+
+// Previewer is implemented by tools that can show the change a call would
+// make, such as a diff, so it can be reviewed before permission is given.
+// An error means the call would fail, so it isn't put to the user.
+type Previewer interface {
+	Preview(ctx context.Context, params map[string]interface{}) (string, error)
+}
+
+// SensitiveChecker is implemented by tools that can tell when a call would
+// expose credentials, e.g. by printing or uploading a key file. A non-empty
+// reason makes the call need elevated permission.
+type SensitiveChecker interface {
+	SensitiveReason(params map[string]interface{}) string
+}
+
+// Confirmer is implemented by tools with calls the user must approve each
+// time, whatever they approved before, such as starting containers.
+type Confirmer interface {
+	RequiresConfirmation(params map[string]interface{}) bool
+}
+
 // Parameter defines a tool parameter specification.
 type Parameter struct {
 	Name        string        // Parameter name
@@ -0,0 +1,374 @@
+// Package web provides tools that read from the web.
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/models/transport"
+	"github.com/abrksh22/bplus/tools"
+)
+
+// Fetch defaults
+const (
+	// defaultTTL is how long a fetched page is served from the cache.
+	defaultTTL = 15 * time.Minute
+
+	// defaultMaxBytes bounds the body read from a response.
+	defaultMaxBytes = 5 << 20
+
+	// defaultMaxOutput bounds the text returned to the agent.
+	defaultMaxOutput = 50000
+
+	// fetchTimeout bounds one fetch, redirects included.
+	fetchTimeout = 30 * time.Second
+
+	// maxRedirects is how many redirects within a host are followed.
+	maxRedirects = 10
+)
+
+// userAgent identifies b+ to the sites it fetches.
+const userAgent = "b+ webfetch (+https://github.com/abrksh22/bplus)"
+
+// FetchTool fetches a web page and returns it as Markdown. Redirects are
+// followed only within a host: one to a different host is reported instead,
+// so the agent fetches the new URL itself and the permission prompt names
+// the host actually contacted. Pages are cached on disk for a while.
+type FetchTool struct {
+	cacheDir  string
+	ttl       time.Duration
+	maxBytes  int64
+	maxOutput int
+	client    *http.Client
+}
+
+// Option is a functional option for configuring the fetch tool.
+type Option func(*FetchTool)
+
+// WithTTL sets how long fetched pages are served from the cache.
+func WithTTL(ttl time.Duration) Option {
+	return func(t *FetchTool) {
+		t.ttl = ttl
+	}
+}
+
+// WithMaxBytes sets how much of a response body is read.
+func WithMaxBytes(n int64) Option {
+	return func(t *FetchTool) {
+		t.maxBytes = n
+	}
+}
+
+// WithMaxOutput sets how much text is returned to the agent.
+func WithMaxOutput(n int) Option {
+	return func(t *FetchTool) {
+		t.maxOutput = n
+	}
+}
+
+// WithHTTPClient sets the HTTP client pages are fetched with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *FetchTool) {
+		t.client = client
+	}
+}
+
+// NewFetchTool creates a webfetch tool caching pages under cacheDir. An
+// empty cacheDir turns the cache off.
+func NewFetchTool(cacheDir string, opts ...Option) *FetchTool {
+	t := &FetchTool{
+		cacheDir:  cacheDir,
+		ttl:       defaultTTL,
+		maxBytes:  defaultMaxBytes,
+		maxOutput: defaultMaxOutput,
+		client:    transport.NewClient(fetchTimeout),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Name returns the tool name.
+func (t *FetchTool) Name() string {
+	return "webfetch"
+}
+
+// Description returns the tool description.
+func (t *FetchTool) Description() string {
+	return "Fetches a web page over HTTP(S) and returns its content as Markdown. A redirect to a different host is not followed: the result names the new URL to fetch instead"
+}
+
+// Parameters returns the tool parameters.
+func (t *FetchTool) Parameters() []tools.Parameter {
+	return []tools.Parameter{
+		{
+			Name:        "url",
+			Type:        tools.TypeString,
+			Required:    true,
+			Description: "Absolute http:// or https:// URL to fetch",
+		},
+		{
+			Name:        "refresh",
+			Type:        tools.TypeBool,
+			Required:    false,
+			Description: "Fetch the page again instead of using the cached copy",
+			Default:     false,
+		},
+	}
+}
+
+// RequiresPermission returns true as fetching contacts the network.
+func (t *FetchTool) RequiresPermission() bool {
+	return true
+}
+
+// DescribeResource names the URL in the permission prompt.
+func (t *FetchTool) DescribeResource(params map[string]interface{}) string {
+	raw, _ := params["url"].(string)
+	return fmt.Sprintf("fetch %s", raw)
+}
+
+// page is a fetched page, as cached.
+type page struct {
+	URL         string    `json:"url"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Title       string    `json:"title,omitempty"`
+	Content     string    `json:"content"`
+	Truncated   bool      `json:"truncated,omitempty"` // The body was larger than maxBytes
+	Redirect    string    `json:"redirect,omitempty"`  // URL on another host the page redirects to
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Execute fetches the page.
+func (t *FetchTool) Execute(ctx context.Context, params map[string]interface{}) (*tools.Result, error) {
+	startTime := time.Now()
+
+	raw, _ := params["url"].(string)
+	refresh, _ := params["refresh"].(bool)
+
+	target, err := parseURL(raw)
+	if err != nil {
+		return &tools.Result{
+			Success: false,
+			Error:   err,
+		}, nil
+	}
+
+	p, cached, err := t.load(ctx, target, refresh)
+	if err != nil {
+		return &tools.Result{
+			Success: false,
+			Error:   fmt.Errorf("failed to fetch %s: %w", target, err),
+		}, nil
+	}
+
+	metadata := map[string]interface{}{
+		"url":          target.String(),
+		"final_url":    p.URL,
+		"status":       p.Status,
+		"content_type": p.ContentType,
+		"cached":       cached,
+	}
+	if p.Redirect != "" {
+		metadata["redirect_url"] = p.Redirect
+		return &tools.Result{
+			Success:  true,
+			Output:   fmt.Sprintf("REDIRECT: %s redirects to a different host: %s\nFetch that URL to follow the redirect.", p.URL, p.Redirect),
+			Metadata: metadata,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	var b strings.Builder
+	if p.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", p.Title)
+	}
+	fmt.Fprintf(&b, "Source: %s\n\n", p.URL)
+	content := p.Content
+	truncated := p.Truncated
+	if len(content) > t.maxOutput {
+		content = strings.ToValidUTF8(content[:t.maxOutput], "")
+		truncated = true
+	}
+	b.WriteString(content)
+	if truncated {
+		b.WriteString("\n\n... (truncated)")
+	}
+	metadata["title"] = p.Title
+	metadata["size"] = len(p.Content)
+	metadata["truncated"] = truncated
+
+	if p.Status >= 400 {
+		return &tools.Result{
+			Success:  false,
+			Output:   b.String(),
+			Error:    fmt.Errorf("%s returned HTTP %d", p.URL, p.Status),
+			Metadata: metadata,
+			Duration: time.Since(startTime),
+		}, nil
+	}
+	return &tools.Result{
+		Success:  true,
+		Output:   b.String(),
+		Metadata: metadata,
+		Duration: time.Since(startTime),
+	}, nil
+}
+
+// parseURL checks that raw is an absolute web URL.
+func parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid URL %q: only http and https URLs can be fetched", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: no host", raw)
+	}
+	u.Fragment = ""
+	return u, nil
+}
+
+// load returns the page from the cache, fetching it when missing, stale or
+// refresh is set.
+func (t *FetchTool) load(ctx context.Context, target *url.URL, refresh bool) (*page, bool, error) {
+	path := t.cachePath(target)
+	if path != "" && !refresh {
+		if p, ok := t.readCache(path); ok {
+			return p, true, nil
+		}
+	}
+
+	p, err := t.fetch(ctx, target)
+	if err != nil {
+		return nil, false, err
+	}
+	if path != "" && p.Status < 400 {
+		if data, err := json.Marshal(p); err == nil && os.MkdirAll(filepath.Dir(path), 0o755) == nil {
+			_ = os.WriteFile(path, data, 0o644)
+		}
+	}
+	return p, false, nil
+}
+
+// fetch requests the page, following redirects within its host.
+func (t *FetchTool) fetch(ctx context.Context, target *url.URL) (*page, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	var redirect *url.URL
+	client := *t.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if req.URL.Hostname() != via[0].URL.Hostname() {
+			redirect = req.URL
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html, text/markdown, text/plain;q=0.9, application/json;q=0.8, */*;q=0.5")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if redirect != nil {
+		return &page{URL: resp.Request.URL.String(), Status: resp.StatusCode, Redirect: redirect.String(), FetchedAt: time.Now()}, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	p := &page{
+		URL:       resp.Request.URL.String(),
+		Status:    resp.StatusCode,
+		FetchedAt: time.Now(),
+	}
+	if int64(len(body)) > t.maxBytes {
+		body = body[:t.maxBytes]
+		p.Truncated = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
+	p.ContentType = mediaType
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		p.Title, p.Content, err = toMarkdown(string(body), resp.Request.URL)
+		if err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json",
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		p.Content = strings.ToValidUTF8(string(body), "")
+	default:
+		return nil, fmt.Errorf("unsupported content type %s; only web pages and text can be fetched", mediaType)
+	}
+	return p, nil
+}
+
+// readCache returns the cached page at path if it is fresh.
+func (t *FetchTool) readCache(path string) (*page, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var p page
+	if err := json.Unmarshal(data, &p); err != nil || time.Since(p.FetchedAt) >= t.ttl {
+		return nil, false
+	}
+	return &p, true
+}
+
+// cachePath returns where a page is cached, or "" without a cache.
+func (t *FetchTool) cachePath(target *url.URL) string {
+	if t.cacheDir == "" || t.ttl <= 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(target.String()))
+	return filepath.Join(t.cacheDir, "web", hex.EncodeToString(sum[:16])+".json")
+}
+
+// Category returns the tool category.
+func (t *FetchTool) Category() string {
+	return "web"
+}
+
+// Version returns the tool version.
+func (t *FetchTool) Version() string {
+	return "1.0.0"
+}
+
+// IsExternal returns false as this is a core tool.
+func (t *FetchTool) IsExternal() bool {
+	return false
+}
@@ -0,0 +1,344 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skipped elements never hold content worth reading.
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Form: true, atom.Button: true,
+	atom.Nav: true, atom.Footer: true, atom.Aside: true, atom.Head: true,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// toMarkdown converts an HTML page to Markdown, returning it with the page
+// title. Only the main or article element is converted when there is one,
+// leaving out navigation and boilerplate. Relative links are resolved
+// against base.
+func toMarkdown(page string, base *url.URL) (title, markdown string, err error) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	if t := find(doc, atom.Title); t != nil {
+		title = strings.TrimSpace(text(t))
+	}
+	root := find(doc, atom.Main)
+	if root == nil {
+		root = find(doc, atom.Article)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	c := &converter{base: base}
+	c.children(root)
+	markdown = blankLines.ReplaceAllString(c.b.String(), "\n\n")
+	return title, strings.TrimSpace(markdown) + "\n", nil
+}
+
+// converter writes Markdown for a tree of HTML nodes.
+type converter struct {
+	b     strings.Builder
+	base  *url.URL
+	lists []listState // Enclosing lists, innermost last
+	pre   bool        // Inside a pre element, where whitespace is kept
+}
+
+// listState is an enclosing list and the number of its next item.
+type listState struct {
+	ordered bool
+	next    int
+}
+
+func (c *converter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+func (c *converter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		c.children(n)
+		return
+	default:
+		return
+	}
+	if skipped[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		c.block()
+		c.b.WriteString(strings.Repeat("#", level) + " ")
+		c.b.WriteString(strings.TrimSpace(collapse(text(n))))
+		c.block()
+	case atom.P, atom.Div, atom.Section, atom.Header, atom.Main, atom.Article, atom.Figure:
+		c.block()
+		c.children(n)
+		c.block()
+	case atom.Br:
+		c.b.WriteString("\n")
+	case atom.Hr:
+		c.block()
+		c.b.WriteString("---")
+		c.block()
+	case atom.Strong, atom.B:
+		c.wrap(n, "**")
+	case atom.Em, atom.I:
+		c.wrap(n, "_")
+	case atom.Code:
+		if c.pre {
+			c.children(n)
+			return
+		}
+		c.b.WriteString("`" + collapse(text(n)) + "`")
+	case atom.Pre:
+		c.block()
+		c.b.WriteString("```" + language(n) + "\n")
+		c.pre = true
+		c.children(n)
+		c.pre = false
+		if !strings.HasSuffix(c.b.String(), "\n") {
+			c.b.WriteString("\n")
+		}
+		c.b.WriteString("```")
+		c.block()
+	case atom.A:
+		label := strings.TrimSpace(collapse(text(n)))
+		href := c.resolve(attr(n, "href"))
+		if label == "" || href == "" || strings.HasPrefix(href, "javascript:") {
+			c.children(n)
+			return
+		}
+		fmt.Fprintf(&c.b, "[%s](%s)", label, href)
+	case atom.Img:
+		if alt := attr(n, "alt"); alt != "" {
+			fmt.Fprintf(&c.b, "![%s](%s)", alt, c.resolve(attr(n, "src")))
+		}
+	case atom.Ul, atom.Ol:
+		if len(c.lists) == 0 {
+			c.block()
+		}
+		c.lists = append(c.lists, listState{ordered: n.DataAtom == atom.Ol, next: 1})
+		c.children(n)
+		c.lists = c.lists[:len(c.lists)-1]
+		if len(c.lists) == 0 {
+			c.block()
+		}
+	case atom.Li:
+		c.item(n)
+	case atom.Blockquote:
+		c.block()
+		inner := &converter{base: c.base}
+		inner.children(n)
+		for _, line := range strings.Split(strings.TrimSpace(inner.b.String()), "\n") {
+			c.b.WriteString("> " + line + "\n")
+		}
+		c.block()
+	case atom.Table:
+		c.block()
+		c.table(n)
+		c.block()
+	default:
+		c.children(n)
+	}
+}
+
+// text writes a text node, collapsing whitespace outside pre elements.
+func (c *converter) text(s string) {
+	if c.pre {
+		c.b.WriteString(s)
+		return
+	}
+	s = collapse(s)
+	if s == " " && (c.b.Len() == 0 || strings.HasSuffix(c.b.String(), "\n") || strings.HasSuffix(c.b.String(), " ")) {
+		return
+	}
+	c.b.WriteString(s)
+}
+
+// wrap writes the content of n between marks, such as ** for bold.
+func (c *converter) wrap(n *html.Node, mark string) {
+	inner := strings.TrimSpace(collapse(text(n)))
+	if inner == "" {
+		return
+	}
+	c.b.WriteString(mark + inner + mark)
+}
+
+// item writes a list item, indented by the depth of its list.
+func (c *converter) item(n *html.Node) {
+	depth := len(c.lists)
+	if depth == 0 {
+		c.lists = append(c.lists, listState{next: 1})
+		defer func() { c.lists = c.lists[:0] }()
+		depth = 1
+	}
+	list := &c.lists[depth-1]
+	marker := "- "
+	if list.ordered {
+		marker = fmt.Sprintf("%d. ", list.next)
+		list.next++
+	}
+	if !strings.HasSuffix(c.b.String(), "\n") && c.b.Len() > 0 {
+		c.b.WriteString("\n")
+	}
+	c.b.WriteString(strings.Repeat("  ", depth-1) + marker)
+	c.children(n)
+	c.b.WriteString("\n")
+}
+
+// table writes a table as a Markdown table, its first row as the header.
+func (c *converter) table(n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Tr {
+			var row []string
+			for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+					row = append(row, strings.ReplaceAll(strings.TrimSpace(collapse(text(cell))), "|", `\|`))
+				}
+			}
+			rows = append(rows, row)
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		c.b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			c.b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+}
+
+// block ends the current block with a blank line.
+func (c *converter) block() {
+	if c.b.Len() == 0 {
+		return
+	}
+	s := c.b.String()
+	switch {
+	case strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		c.b.WriteString("\n")
+	default:
+		c.b.WriteString("\n\n")
+	}
+}
+
+// resolve makes a link absolute.
+func (c *converter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || c.base == nil {
+		return href
+	}
+	u, err := c.base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+// find returns the first element of type a under n, depth first.
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// text returns the text under n, leaving out skipped elements.
+func text(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && skipped[n.DataAtom] && n.DataAtom != atom.Head {
+		return ""
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(text(child))
+	}
+	return b.String()
+}
+
+// attr returns the value of an attribute of n.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// language returns the language of a code block from its class, as in
+// class="language-go".
+func language(pre *html.Node) string {
+	for _, n := range []*html.Node{pre, pre.FirstChild} {
+		if n == nil || n.Type != html.ElementNode {
+			continue
+		}
+		for _, class := range strings.Fields(attr(n, "class")) {
+			if lang, ok := strings.CutPrefix(class, "language-"); ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// collapse replaces runs of whitespace with one space.
+func collapse(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
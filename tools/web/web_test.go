@@ -0,0 +1,162 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!DOCTYPE html>
+<html><head><title>Go Tour</title><script>track()</script></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<main>
+  <h1>Channels</h1>
+  <p>Channels are a <strong>typed</strong> conduit. See <a href="/spec#Channel_types">the spec</a>.</p>
+  <pre><code class="language-go">ch := make(chan int)
+ch &lt;- v</code></pre>
+  <ul><li>Buffered</li><li>Unbuffered<ol><li>Sync</li></ol></li></ul>
+  <table><tr><th>Op</th><th>Blocks</th></tr><tr><td>send</td><td>yes</td></tr></table>
+</main>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestToMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://go.dev/tour/concurrency/2")
+	title, md, err := toMarkdown(testPage, base)
+	require.NoError(t, err)
+	assert.Equal(t, "Go Tour", title)
+	assert.Equal(t, "# Channels\n\n"+
+		"Channels are a **typed** conduit. See [the spec](https://go.dev/spec#Channel_types).\n\n"+
+		"```go\nch := make(chan int)\nch <- v\n```\n\n"+
+		"- Buffered\n- Unbuffered\n  1. Sync\n\n"+
+		"| Op | Blocks |\n| --- | --- |\n| send | yes |\n", md)
+	assert.NotContains(t, md, "Home", "navigation is left out")
+	assert.NotContains(t, md, "track()")
+	assert.NotContains(t, md, "Copyright")
+}
+
+func TestFetchTool_Execute(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/tour", http.StatusMovedPermanently)
+		case "/tour":
+			assert.Contains(t, r.Header.Get("User-Agent"), "b+")
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, testPage)
+		case "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"ok":true}`)
+		case "/missing":
+			http.NotFound(w, r)
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		}
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	tool := NewFetchTool(cacheDir)
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/old"})
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	assert.Contains(t, result.Output, "# Go Tour\n\nSource: "+server.URL+"/tour")
+	assert.Contains(t, result.Output, "**typed**")
+	assert.Equal(t, server.URL+"/tour", result.Metadata["final_url"])
+	assert.Equal(t, false, result.Metadata["cached"])
+	assert.Equal(t, 2, requests, "the redirect within the host is followed")
+
+	// Served from the cache the second time
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/old"})
+	require.NoError(t, err)
+	assert.Equal(t, true, result.Metadata["cached"])
+	assert.Equal(t, 2, requests)
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/old", "refresh": true})
+	require.NoError(t, err)
+	assert.Equal(t, false, result.Metadata["cached"])
+
+	result, _ = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/data.json"})
+	require.True(t, result.Success)
+	assert.Contains(t, result.Output, `{"ok":true}`)
+
+	result, _ = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/missing"})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "HTTP 404")
+
+	result, _ = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/image.png"})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error.Error(), "unsupported content type image/png")
+
+	for _, bad := range []string{"file:///etc/passwd", "/relative", "ftp://example.com/x"} {
+		result, _ = tool.Execute(context.Background(), map[string]interface{}{"url": bad})
+		assert.False(t, result.Success, bad)
+	}
+}
+
+func TestFetchTool_RedirectToOtherHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a redirect to another host must not be followed")
+	}))
+	defer other.Close()
+	// Another hostname for the same test server counts as another host
+	target := strings.Replace(other.URL, "127.0.0.1", "localhost", 1) + "/docs"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target, http.StatusFound)
+	}))
+	defer server.Close()
+
+	result, err := NewFetchTool("").Execute(context.Background(), map[string]interface{}{"url": server.URL + "/docs"})
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.True(t, strings.HasPrefix(result.Output.(string), "REDIRECT:"))
+	assert.Contains(t, result.Output, target)
+	assert.Equal(t, target, result.Metadata["redirect_url"])
+}
+
+func TestFetchTool_Limits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, strings.Repeat("a", 1000))
+	}))
+	defer server.Close()
+
+	result, err := NewFetchTool("", WithMaxBytes(100)).Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.Equal(t, 100, result.Metadata["size"])
+	assert.Equal(t, true, result.Metadata["truncated"])
+	assert.True(t, strings.HasSuffix(result.Output.(string), "... (truncated)"))
+
+	result, _ = NewFetchTool("", WithMaxOutput(10)).Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	assert.Equal(t, 1000, result.Metadata["size"])
+	assert.Contains(t, result.Output, "aaaaaaaaaa\n\n... (truncated)")
+}
+
+func TestFetchTool_CacheExpires(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "plain")
+	}))
+	defer server.Close()
+
+	tool := NewFetchTool(t.TempDir(), WithTTL(time.Millisecond))
+	tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	time.Sleep(5 * time.Millisecond)
+	tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	assert.Equal(t, 2, requests)
+}
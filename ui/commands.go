@@ -0,0 +1,292 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/events"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SlashCommand is an in-session command entered as "/name args...".
+type SlashCommand struct {
+	Name        string
+	Description string
+	Run         func(m *Model, args []string) tea.Cmd
+}
+
+// defaultCommands returns the built-in slash commands.
+func defaultCommands() map[string]SlashCommand {
+	commands := []SlashCommand{
+		{
+			Name:        "help",
+			Description: "Show keyboard shortcuts and commands",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.showHelp()
+				return nil
+			},
+		},
+		{
+			Name:        "tools",
+			Description: "Enable or disable tools for this session",
+			Run: func(m *Model, args []string) tea.Cmd {
+				// "/tools enable <tool>" and "/tools disable <tool>" toggle directly
+				if len(args) == 2 && (args[0] == "enable" || args[0] == "disable") {
+					reg := m.toolRegistry()
+					if reg == nil {
+						m.SetError(fmt.Errorf("no tool registry available"))
+						return nil
+					}
+					if err := reg.SetEnabled(args[1], args[0] == "enable"); err != nil {
+						m.SetError(err)
+					}
+					return nil
+				}
+
+				m.toolCursor = 0
+				m.view = ViewTools
+				return nil
+			},
+		},
+		{
+			Name:        "models",
+			Description: "Pick a model by observed latency and throughput (/models sonnet switches by alias)",
+			Run: func(m *Model, args []string) tea.Cmd {
+				app, ok := m.app.(modelSwitcher)
+				if !ok {
+					m.SetError(fmt.Errorf("model switching is not available"))
+					return nil
+				}
+
+				// "/models <name>" switches without opening the picker
+				if len(args) == 1 {
+					if err := app.SetModel(args[0]); err != nil {
+						m.SetError(err)
+					}
+					return nil
+				}
+
+				m.modelList = nil
+				m.modelCursor = 0
+				m.view = ViewModels
+				return m.loadModels()
+			},
+		},
+		{
+			Name:        "providers",
+			Description: "Show provider connection health and re-test it",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.showProviders()
+				return nil
+			},
+		},
+		{
+			Name:        "flags",
+			Description: "Turn experimental features on or off for this session",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.showFlags()
+				return nil
+			},
+		},
+		{
+			Name:        "substitutions",
+			Description: "Show where another model was used than the one configured, and why",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.showSubstitutions()
+				return nil
+			},
+		},
+		{
+			Name:        "persona",
+			Description: "Change how the agent responds: default, terse, teacher or reviewer",
+			Run: func(m *Model, args []string) tea.Cmd {
+				// "/persona <name>" switches without opening the picker
+				if len(args) == 1 {
+					app, ok := m.app.(personaSwitcher)
+					if !ok {
+						m.SetError(fmt.Errorf("personas are not available"))
+						return nil
+					}
+					if err := app.SetPersona(args[0]); err != nil {
+						m.SetError(err)
+					}
+					return nil
+				}
+				m.showPersonas()
+				return nil
+			},
+		},
+		{
+			Name:        "allow-elevated",
+			Description: "Allow the next tool call that needs elevated permission, such as reading a .env file",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.allowElevated()
+				return nil
+			},
+		},
+		{
+			Name:        "shells",
+			Description: "List the agent's shell sessions and kill them",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.showShells()
+				return nil
+			},
+		},
+		{
+			Name:        "finish",
+			Description: "Test the worktree's changes, then merge, push or discard them",
+			Run: func(m *Model, args []string) tea.Cmd {
+				return m.showFinish()
+			},
+		},
+		{
+			Name:        "optimize",
+			Description: "Preview and prune the conversation context",
+			Run: func(m *Model, args []string) tea.Cmd {
+				app, ok := m.app.(contextOptimizer)
+				if !ok {
+					m.SetError(fmt.Errorf("context optimization is not available"))
+					return nil
+				}
+				plan := app.PreviewOptimization()
+				m.optimizePlan = &plan
+				m.view = ViewOptimize
+				return nil
+			},
+		},
+		{
+			Name:        "context",
+			Description: "Inspect the conversation context and where each item came from",
+			Run: func(m *Model, args []string) tea.Cmd {
+				app, ok := m.app.(contextInspector)
+				if !ok {
+					m.SetError(fmt.Errorf("context inspection is not available"))
+					return nil
+				}
+				m.contextItems = app.ContextItems()
+				m.view = ViewContext
+				return nil
+			},
+		},
+		{
+			Name:        "config",
+			Description: "Show the effective configuration and where each value comes from",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.configSettings = nil
+				m.configResult = ""
+				if !m.openConfig() {
+					m.SetError(fmt.Errorf("configuration inspection is not available"))
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "roots",
+			Description: "Attach or detach directories worked on in this session (/roots add [name=]path[:ro])",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.runRootsCommand(args)
+				return nil
+			},
+		},
+		{
+			Name:        "trust",
+			Description: "Trust or restrict this workspace (untrusted ones are read only)",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.showTrust()
+				return nil
+			},
+		},
+		{
+			Name:        "pull",
+			Description: "Download a local model that isn't installed (/pull llama3.2 pulls by name)",
+			Run: func(m *Model, args []string) tea.Cmd {
+				switch {
+				case len(args) == 1:
+					m.pullModel(args[0])
+				case m.modelLoad != nil && m.modelLoad.State == events.ModelMissing:
+					m.pullOffer = m.modelLoad.Model
+					m.promptPull()
+				default:
+					m.SetError(fmt.Errorf("usage: /pull <model>"))
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "redact",
+			Description: "Scrub a secret from the stored conversation and later prompts (/redact <pattern|#message-id>)",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.showRedaction(strings.Join(args, " "))
+				return nil
+			},
+		},
+		{
+			Name:        "export",
+			Description: "Save the conversation as markdown, with the cost of each answer (/export [file])",
+			Run: func(m *Model, args []string) tea.Cmd {
+				m.exportConversation(strings.Join(args, " "))
+				return nil
+			},
+		},
+		{
+			Name:        "runs",
+			Description: "Re-run a command from this project's history (/runs 12 re-runs #12)",
+			Run: func(m *Model, args []string) tea.Cmd {
+				if _, ok := m.app.(runHistory); !ok {
+					m.SetError(fmt.Errorf("command history is not available"))
+					return nil
+				}
+				m.runList = nil
+				m.runCursor = 0
+				m.runResult = ""
+				m.view = ViewRuns
+
+				if len(args) == 1 {
+					id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+					if err != nil {
+						m.SetError(fmt.Errorf("invalid run number: %s", args[0]))
+						return m.loadRuns()
+					}
+					return m.rerun(id)
+				}
+				return m.loadRuns()
+			},
+		},
+	}
+
+	registry := make(map[string]SlashCommand, len(commands))
+	for _, cmd := range commands {
+		registry[cmd.Name] = cmd
+	}
+	return registry
+}
+
+// Commands returns the registered slash commands sorted by name.
+func (m *Model) Commands() []SlashCommand {
+	list := make([]SlashCommand, 0, len(m.commands))
+	for _, cmd := range m.commands {
+		list = append(list, cmd)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// runSlashCommand parses and executes a slash command.
+func (m *Model) runSlashCommand(input string) tea.Cmd {
+	fields := strings.Fields(strings.TrimPrefix(input, "/"))
+	if len(fields) == 0 {
+		return nil
+	}
+
+	cmd, ok := m.commands[fields[0]]
+	if !ok {
+		m.SetError(fmt.Errorf("unknown command: /%s", fields[0]))
+		return nil
+	}
+
+	return cmd.Run(m, fields[1:])
+}
@@ -1,7 +1,9 @@
 package components
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
@@ -40,6 +42,44 @@ func TestInputComponent_History(t *testing.T) {
 	assert.Equal(t, "command 2", history[1])
 }
 
+func TestInputComponent_Abbreviations(t *testing.T) {
+	input := NewInput("", 80, 3)
+	input.Focus()
+	input.SetAbbreviations(map[string]string{";;t": "run the tests and fix failures"})
+
+	for _, r := range "please ;;t" {
+		input.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	input.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	assert.Equal(t, "please run the tests and fix failures ", input.Value())
+
+	// Expanded on submit too
+	var submitted string
+	input.OnSubmit(func(v string) { submitted = v })
+	input.SetValue(";;t")
+	input.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "run the tests and fix failures", submitted)
+}
+
+func TestExpandAbbreviation(t *testing.T) {
+	abbrevs := map[string]string{";;t": "run the tests", "brb": "be right back"}
+
+	got, ok := ExpandAbbreviation("now ;;t", abbrevs)
+	assert.True(t, ok)
+	assert.Equal(t, "now run the tests", got)
+
+	got, ok = ExpandAbbreviation("x;;t", abbrevs)
+	assert.False(t, ok, "only whole words expand")
+	assert.Equal(t, "x;;t", got)
+
+	_, ok = ExpandAbbreviation("now ", abbrevs)
+	assert.False(t, ok)
+
+	assert.Equal(t, "run the tests then be right back\nok",
+		ExpandAbbreviations(";;t then brb\nok", abbrevs))
+	assert.Equal(t, "as is", ExpandAbbreviations("as is", nil))
+}
+
 // Test OutputComponent
 func TestNewOutput(t *testing.T) {
 	output := NewOutput(80, 24)
@@ -89,6 +129,21 @@ func TestOutputComponent_Clear(t *testing.T) {
 	assert.Len(t, output.GetMessages(), 0)
 }
 
+func TestOutputComponent_Redact(t *testing.T) {
+	output := NewOutput(80, 24)
+	output.Init()
+	output.AddMessage("user", "my key is sk-live-abc123")
+	output.AddMessage("assistant", "Thanks")
+
+	n := output.Redact(func(s string) (string, bool) {
+		redacted := strings.ReplaceAll(s, "sk-live-abc123", "[redacted]")
+		return redacted, redacted != s
+	})
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "my key is [redacted]", output.GetMessages()[0].Content)
+	assert.NotContains(t, output.ExportToMarkdown(), "sk-live-abc123")
+}
+
 // Test StatusBar
 func TestNewStatusBar(t *testing.T) {
 	statusBar := NewStatusBar(80)
@@ -331,3 +386,54 @@ type simpleModel struct{}
 func (m *simpleModel) Init() tea.Cmd                           { return nil }
 func (m *simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
 func (m *simpleModel) View() string                            { return "test" }
+
+func TestOutputComponent_TurnStats(t *testing.T) {
+	output := NewOutput(80, 24)
+	output.Init()
+	output.AddMessage("user", "Hi")
+	output.AddMessage("assistant", "Hello!")
+
+	stats := TurnStats{Model: "gpt-4o", InputTokens: 1200, OutputTokens: 340, Cost: 0.0123, Duration: 4200 * time.Millisecond, ToolCalls: 2}
+	output.SetTurnStats(stats)
+	assert.Equal(t, "gpt-4o · 1200↑ 340↓ tokens · $0.0123 · 4.2s · 2 tools", stats.String())
+
+	messages := output.GetMessages()
+	assert.Nil(t, messages[0].Turn)
+	require.NotNil(t, messages[1].Turn)
+	assert.Contains(t, output.View(), "$0.0123")
+
+	output.SetShowStats(false)
+	assert.NotContains(t, output.View(), "$0.0123")
+
+	md := output.ExportToMarkdown()
+	assert.Contains(t, md, "## You")
+	assert.Contains(t, md, "_gpt-4o · 1200↑ 340↓ tokens · $0.0123 · 4.2s · 2 tools_")
+	assert.Contains(t, md, "_Total: 1200↑ 340↓ tokens")
+}
+
+func TestOutputComponent_TurnStatsPerAnswer(t *testing.T) {
+	output := NewOutput(80, 24)
+	output.Init()
+	output.AddMessage("user", "Hi")
+	output.StreamToken("Hello!")
+	output.FinishStreaming()
+	output.SetTurnStats(TurnStats{Cost: 0.01})
+
+	// The next answer is a message of its own, with its own stats
+	output.AddMessage("user", "And now?")
+	output.StreamToken("Done.")
+	output.FinishStreaming()
+	output.SetTurnStats(TurnStats{Cost: 0.02})
+
+	// A turn without an answer doesn't take over an earlier one's
+	output.AddMessage("user", "Stop")
+	output.SetTurnStats(TurnStats{Cost: 0.03})
+
+	messages := output.GetMessages()
+	require.Len(t, messages, 5)
+	assert.Equal(t, "Done.", messages[3].Content)
+	assert.Equal(t, 0.01, messages[1].Turn.Cost)
+	assert.Equal(t, 0.02, messages[3].Turn.Cost)
+	assert.Nil(t, messages[4].Turn)
+	assert.Contains(t, output.ExportToMarkdown(), "_Total: 0↑ 0↓ tokens · $0.0300_")
+}
@@ -21,6 +21,7 @@ type InputComponent struct {
 	maxChars    int
 	onSubmit    func(string)
 	theme       InputTheme
+	abbrevs     map[string]string // Abbreviation -> expansion, applied on space and submit
 }
 
 // InputTheme defines the color scheme for the input component.
@@ -73,8 +74,19 @@ func (i *InputComponent) Update(msg tea.Msg) (*InputComponent, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
+		case " ":
+			// Expand a trailing abbreviation, readline-style
+			if i.cursorAtEnd() {
+				if expanded, ok := ExpandAbbreviation(i.Value(), i.abbrevs); ok {
+					i.SetValue(expanded + " ")
+					return i, nil
+				}
+			}
 		case "enter":
 			// Submit on Enter
+			if expanded, ok := ExpandAbbreviation(i.Value(), i.abbrevs); ok && i.cursorAtEnd() {
+				i.SetValue(expanded)
+			}
 			value := i.Value()
 			if strings.TrimSpace(value) != "" {
 				i.addToHistory(value)
@@ -236,6 +248,66 @@ func (i *InputComponent) SetTheme(theme InputTheme) {
 	i.theme = theme
 }
 
+// SetAbbreviations sets the abbreviations expanded when followed by a space.
+func (i *InputComponent) SetAbbreviations(abbrevs map[string]string) {
+	i.abbrevs = abbrevs
+}
+
+// cursorAtEnd reports whether the cursor is at the end of the input.
+func (i *InputComponent) cursorAtEnd() bool {
+	if i.textarea.Line() != i.textarea.LineCount()-1 {
+		return false
+	}
+	lines := strings.Split(i.Value(), "\n")
+	li := i.textarea.LineInfo()
+	return li.StartColumn+li.ColumnOffset >= len([]rune(lines[len(lines)-1]))
+}
+
+// ExpandAbbreviation expands the last word of text if it is a defined
+// abbreviation. It reports whether an expansion happened.
+func ExpandAbbreviation(text string, abbrevs map[string]string) (string, bool) {
+	if len(abbrevs) == 0 || text == "" {
+		return text, false
+	}
+
+	start := strings.LastIndexAny(text, " \t\n") + 1
+	word := text[start:]
+	expansion, ok := abbrevs[word]
+	if !ok || word == "" {
+		return text, false
+	}
+	return text[:start] + expansion, true
+}
+
+// ExpandAbbreviations expands every whitespace-separated word of text that is
+// a defined abbreviation, for input submitted without per-keystroke expansion.
+func ExpandAbbreviations(text string, abbrevs map[string]string) string {
+	if len(abbrevs) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	word := strings.Builder{}
+	flush := func() {
+		if expansion, ok := abbrevs[word.String()]; ok && word.Len() > 0 {
+			b.WriteString(expansion)
+		} else {
+			b.WriteString(word.String())
+		}
+		word.Reset()
+	}
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' {
+			flush()
+			b.WriteRune(r)
+			continue
+		}
+		word.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
+
 // addToHistory adds a value to the history.
 func (i *InputComponent) addToHistory(value string) {
 	// Avoid duplicates of the last entry
@@ -16,7 +16,40 @@ type Message struct {
 	Role      string // "user", "assistant", "system"
 	Content   string // Message text (supports markdown)
 	Timestamp time.Time
-	Streaming bool // Currently streaming
+	Streaming bool       // Currently streaming
+	Turn      *TurnStats // Usage for an assistant turn, if known
+}
+
+// TurnStats summarizes the work behind one assistant turn.
+type TurnStats struct {
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+	Duration     time.Duration
+	ToolCalls    int
+}
+
+// String formats the stats as a compact footer line,
+// e.g. "claude-sonnet-4-5 · 1200↑ 340↓ tokens · $0.0123 · 4.2s · 2 tools".
+func (t TurnStats) String() string {
+	var parts []string
+	if t.Model != "" {
+		parts = append(parts, t.Model)
+	}
+	parts = append(parts, fmt.Sprintf("%d↑ %d↓ tokens", t.InputTokens, t.OutputTokens))
+	parts = append(parts, fmt.Sprintf("$%.4f", t.Cost))
+	if t.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("%.1fs", t.Duration.Seconds()))
+	}
+	switch t.ToolCalls {
+	case 0:
+	case 1:
+		parts = append(parts, "1 tool")
+	default:
+		parts = append(parts, fmt.Sprintf("%d tools", t.ToolCalls))
+	}
+	return strings.Join(parts, " · ")
 }
 
 // OutputComponent displays the conversation messages with markdown rendering.
@@ -29,6 +62,7 @@ type OutputComponent struct {
 	height      int
 	renderer    *glamour.TermRenderer
 	initialized bool
+	showStats   bool // Render turn stats under assistant messages
 }
 
 // OutputTheme defines the color scheme for the output component.
@@ -84,6 +118,7 @@ func NewOutput(width, height int) OutputComponent {
 		height:      height,
 		renderer:    renderer,
 		initialized: false,
+		showStats:   true,
 	}
 }
 
@@ -161,10 +196,11 @@ func (o *OutputComponent) AddMessage(role, content string) {
 	}
 }
 
-// StreamToken adds a token to the last message (for streaming).
+// StreamToken adds a token to the assistant message being streamed,
+// starting one if the last message isn't.
 func (o *OutputComponent) StreamToken(token string) {
-	if len(o.messages) == 0 {
-		// No messages yet, create a new one
+	if n := len(o.messages); n == 0 || o.messages[n-1].Role != "assistant" || !o.messages[n-1].Streaming {
+		// No answer streaming yet, create a new one
 		o.messages = append(o.messages, Message{
 			Role:      "assistant",
 			Content:   token,
@@ -192,6 +228,41 @@ func (o *OutputComponent) FinishStreaming() {
 	}
 }
 
+// Redact scrubs content from the conversation, and so from its exports,
+// using redact, which returns the scrubbed text and whether it changed. It
+// returns how many messages changed.
+func (o *OutputComponent) Redact(redact func(string) (string, bool)) int {
+	n := 0
+	for i := range o.messages {
+		if content, changed := redact(o.messages[i].Content); changed {
+			o.messages[i].Content = content
+			n++
+		}
+	}
+	return n
+}
+
+// SetTurnStats attaches usage stats to the assistant message answering the
+// last user message. A turn that ended without an answer has none to
+// annotate, so earlier answers keep their own stats.
+func (o *OutputComponent) SetTurnStats(stats TurnStats) {
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		switch o.messages[i].Role {
+		case "assistant":
+			o.messages[i].Turn = &stats
+			return
+		case "user":
+			return
+		}
+	}
+}
+
+// SetShowStats sets whether turn stats are rendered under assistant messages.
+// Exports include them regardless.
+func (o *OutputComponent) SetShowStats(show bool) {
+	o.showStats = show
+}
+
 // SetSize updates the dimensions of the output component.
 func (o *OutputComponent) SetSize(width, height int) {
 	o.width = width
@@ -234,6 +305,12 @@ func (o *OutputComponent) SetTheme(theme OutputTheme) {
 	o.theme = theme
 }
 
+// Transcript renders the messages as View does, without the viewport and
+// border, for views that lay out the conversation themselves.
+func (o *OutputComponent) Transcript() string {
+	return o.renderMessages()
+}
+
 // renderMessages renders all messages as a string.
 func (o *OutputComponent) renderMessages() string {
 	if len(o.messages) == 0 {
@@ -297,6 +374,10 @@ func (o *OutputComponent) renderMessage(msg Message) string {
 
 	// Combine header and content
 	messageContent := lipgloss.JoinVertical(lipgloss.Left, header, "", content)
+	if o.showStats && msg.Turn != nil && !msg.Streaming {
+		footerStyle := lipgloss.NewStyle().Foreground(o.theme.Timestamp).Faint(true)
+		messageContent = lipgloss.JoinVertical(lipgloss.Left, messageContent, footerStyle.Render(msg.Turn.String()))
+	}
 
 	// Apply bubble style
 	return bubbleStyle.Width(o.width - 6).Render(messageContent)
@@ -322,6 +403,46 @@ func (o *OutputComponent) ExportToString() string {
 	return strings.Join(lines, "\n")
 }
 
+// ExportToMarkdown exports all messages as a markdown document. Assistant
+// turns with known usage are followed by an italic stats line.
+func (o *OutputComponent) ExportToMarkdown() string {
+	var b strings.Builder
+	b.WriteString("# b+ Conversation\n\n")
+	fmt.Fprintf(&b, "_Exported %s_\n", time.Now().Format(time.RFC3339))
+
+	var total TurnStats
+	annotated := false
+	for _, msg := range o.messages {
+		label := msg.Role
+		switch msg.Role {
+		case "user":
+			label = "You"
+		case "assistant":
+			label = "b+"
+		case "system":
+			label = "System"
+		}
+		fmt.Fprintf(&b, "\n## %s (%s)\n\n", label, msg.Timestamp.Format("15:04:05"))
+		b.WriteString(strings.TrimRight(msg.Content, "\n"))
+		b.WriteString("\n")
+
+		if msg.Turn != nil {
+			fmt.Fprintf(&b, "\n_%s_\n", msg.Turn.String())
+			total.InputTokens += msg.Turn.InputTokens
+			total.OutputTokens += msg.Turn.OutputTokens
+			total.Cost += msg.Turn.Cost
+			total.Duration += msg.Turn.Duration
+			total.ToolCalls += msg.Turn.ToolCalls
+			annotated = true
+		}
+	}
+
+	if annotated {
+		fmt.Fprintf(&b, "\n---\n\n_Total: %s_\n", total.String())
+	}
+	return b.String()
+}
+
 // ScrollPercentage returns the current scroll percentage (0-100).
 func (o *OutputComponent) ScrollPercentage() float64 {
 	return o.viewport.ScrollPercent() * 100
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/abrksh22/bplus/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configInspector is implemented by applications that can list their
+// effective configuration with the source of each value.
+type configInspector interface {
+	ConfigSettings() []config.Setting
+}
+
+// openConfig shows the effective configuration, keeping the selection on
+// the same key when reopened or refreshed.
+func (m *Model) openConfig() bool {
+	app, ok := m.app.(configInspector)
+	if !ok {
+		return false
+	}
+
+	selected := ""
+	if m.configCursor < len(m.configSettings) {
+		selected = m.configSettings[m.configCursor].Key
+	}
+	m.configSettings = app.ConfigSettings()
+	m.configCursor = 0
+	for i, s := range m.configSettings {
+		if s.Key == selected {
+			m.configCursor = i
+		}
+	}
+	m.view = ViewConfig
+	return true
+}
+
+// editConfigSetting opens the file that sets the selected value in the
+// user's editor, at the line of its key. Values set elsewhere have no file
+// to edit, so their source is reported instead.
+func (m *Model) editConfigSetting() tea.Cmd {
+	if m.configCursor >= len(m.configSettings) {
+		return nil
+	}
+	s := m.configSettings[m.configCursor]
+	if s.Source != config.SourceUserFile && s.Source != config.SourceProjectFile {
+		m.configResult = fmt.Sprintf("%s is set by %s, not a config file", s.Key, describeSource(s))
+		return nil
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	m.configResult = ""
+	cmd := exec.Command(editor, fmt.Sprintf("+%d", s.Line), s.Origin)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return ConfigEditedMsg{Path: s.Origin, Err: err}
+	})
+}
+
+// handleConfigEdited refreshes the configuration view after editing.
+func (m *Model) handleConfigEdited(msg ConfigEditedMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		m.SetError(fmt.Errorf("failed to edit %s: %w", msg.Path, msg.Err))
+		return m, nil
+	}
+	m.configResult = "Saved " + msg.Path + "; changes apply from the next start"
+	if m.view == ViewConfig {
+		m.openConfig()
+	}
+	return m, nil
+}
+
+// describeSource names where a value came from for display.
+func describeSource(s config.Setting) string {
+	switch {
+	case s.Line > 0:
+		return fmt.Sprintf("%s (%s:%d)", s.Source, s.Origin, s.Line)
+	case s.Origin != "":
+		return fmt.Sprintf("%s (%s)", s.Source, s.Origin)
+	}
+	return string(s.Source)
+}
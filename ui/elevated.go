@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/internal/events"
+)
+
+// elevatedAllower is implemented by applications that deny elevated tool
+// calls, such as a command printing a .env file, unless the user allows
+// the next one.
+type elevatedAllower interface {
+	AllowElevatedOnce()
+}
+
+// allowElevated approves the next elevated tool call.
+func (m *Model) allowElevated() {
+	app, ok := m.app.(elevatedAllower)
+	if !ok {
+		m.SetError(fmt.Errorf("elevated permissions are not available"))
+		return
+	}
+	app.AllowElevatedOnce()
+	m.elevatedAllowed = true
+	m.output.AddMessage("system", "The next tool call that needs elevated permission will be allowed, once.")
+}
+
+// noteElevated tells the user about an elevated tool call and whether it
+// was allowed.
+func (m *Model) noteElevated(e events.PermissionRequested) {
+	if m.elevatedAllowed {
+		m.elevatedAllowed = false
+		m.output.AddMessage("system", fmt.Sprintf("Allowed %s once: %s.", e.Tool, e.Reason))
+		return
+	}
+	notice := fmt.Sprintf("Denied %s: %s.", e.Tool, e.Reason)
+	if _, ok := m.app.(elevatedAllower); ok {
+		notice += " Run /allow-elevated to allow the next such call, then ask again."
+	}
+	m.output.AddMessage("system", notice)
+}
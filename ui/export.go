@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// exportConversation writes the conversation as markdown, each answer
+// followed by the stats of its turn, to path or to a timestamped file in
+// the working directory. The file is private to the user, as conversations
+// can hold secrets.
+func (m *Model) exportConversation(path string) {
+	if len(m.output.GetMessages()) == 0 {
+		m.SetError(fmt.Errorf("no conversation to export yet"))
+		return
+	}
+	if path == "" {
+		path = "bplus-conversation-" + time.Now().Format("20060102-150405") + ".md"
+	}
+	if err := os.WriteFile(path, []byte(m.output.ExportToMarkdown()), 0o600); err != nil {
+		m.SetError(fmt.Errorf("failed to export conversation: %w", err))
+		return
+	}
+	m.output.AddMessage("system", "Conversation exported to "+path)
+}
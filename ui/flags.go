@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/internal/flags"
+)
+
+// featureFlagger is implemented by applications with experimental
+// subsystems that can be turned on or off.
+type featureFlagger interface {
+	FeatureFlags() []flags.State
+	ToggleFeatureFlag(name string, enabled bool) error
+}
+
+// showFlags opens the flags view.
+func (m *Model) showFlags() {
+	if _, ok := m.app.(featureFlagger); !ok {
+		m.SetError(fmt.Errorf("experimental flags are not available"))
+		return
+	}
+	m.flagCursor = 0
+	m.view = ViewFlags
+}
+
+// featureFlags returns the attached application's flags, if any.
+func (m *Model) featureFlags() []flags.State {
+	if app, ok := m.app.(featureFlagger); ok {
+		return app.FeatureFlags()
+	}
+	return nil
+}
+
+// toggleFlag flips the flag under the cursor for the rest of the session.
+func (m *Model) toggleFlag() {
+	app, ok := m.app.(featureFlagger)
+	if !ok {
+		return
+	}
+	states := app.FeatureFlags()
+	if m.flagCursor >= len(states) {
+		return
+	}
+	state := states[m.flagCursor]
+	if err := app.ToggleFeatureFlag(state.Name, !state.Enabled); err != nil {
+		m.SetError(err)
+	}
+}
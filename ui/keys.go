@@ -46,6 +46,36 @@ type KeyMap struct {
 	PageDown   key.Binding
 	Home       key.Binding
 	End        key.Binding
+
+	// Startup keys
+	Start key.Binding
+
+	// List view keys (tools, models, runs, config, providers)
+	ListUp   key.Binding
+	ListDown key.Binding
+	Select   key.Binding
+	Toggle   key.Binding
+	Favorite key.Binding
+	Edit     key.Binding
+	Retest   key.Binding
+	Kill     key.Binding
+	Back     key.Binding
+
+	// Confirmation keys (optimize, trust)
+	Confirm key.Binding
+	Reject  key.Binding
+
+	// Finish keys
+	Merge   key.Binding
+	Push    key.Binding
+	Discard key.Binding
+
+	// Inspector keys (context)
+	Close key.Binding
+
+	// Palette keys
+	PaletteUp   key.Binding
+	PaletteDown key.Binding
 }
 
 // DefaultKeyMap returns the default key bindings.
@@ -65,8 +95,8 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("?", "toggle help"),
 		),
 		ClearScreen: key.NewBinding(
-			key.WithKeys("ctrl+k"),
-			key.WithHelp("ctrl+k", "clear screen"),
+			key.WithKeys("ctrl+l"),
+			key.WithHelp("ctrl+l", "clear screen"),
 		),
 		Settings: key.NewBinding(
 			key.WithKeys("ctrl+/"),
@@ -141,8 +171,8 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("ctrl+m", "toggle fast/thorough"),
 		),
 		CommandPalette: key.NewBinding(
-			key.WithKeys("ctrl+p"),
-			key.WithHelp("ctrl+p", "command palette"),
+			key.WithKeys("ctrl+k"),
+			key.WithHelp("ctrl+k", "command palette"),
 		),
 		ToggleSidebar: key.NewBinding(
 			key.WithKeys("ctrl+\\"),
@@ -178,6 +208,90 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("end"),
 			key.WithHelp("end", "go to bottom"),
 		),
+
+		// Startup keys
+		Start: key.NewBinding(
+			key.WithKeys("enter", " "),
+			key.WithHelp("enter", "start"),
+		),
+
+		// List view keys
+		ListUp: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "move up"),
+		),
+		ListDown: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "move down"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "select"),
+		),
+		Toggle: key.NewBinding(
+			key.WithKeys(" ", "enter"),
+			key.WithHelp("space", "toggle"),
+		),
+		Favorite: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "toggle favorite"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit owning file"),
+		),
+		Retest: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "re-test"),
+		),
+		Kill: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "kill session"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "back to chat"),
+		),
+
+		// Confirmation keys
+		Confirm: key.NewBinding(
+			key.WithKeys("enter", "y"),
+			key.WithHelp("enter/y", "apply"),
+		),
+		Reject: key.NewBinding(
+			key.WithKeys("esc", "n"),
+			key.WithHelp("esc/n", "discard"),
+		),
+
+		// Finish keys
+		Merge: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "squash-merge"),
+		),
+		Push: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "push branch"),
+		),
+		Discard: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "discard"),
+		),
+
+		// Inspector keys
+		Close: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "close"),
+		),
+
+		// Palette keys
+		PaletteUp: key.NewBinding(
+			key.WithKeys("up", "ctrl+p"),
+			key.WithHelp("↑", "previous match"),
+		),
+		PaletteDown: key.NewBinding(
+			key.WithKeys("down", "ctrl+n"),
+			key.WithHelp("↓", "next match"),
+		),
 	}
 }
 
@@ -207,4 +321,92 @@ func (k KeyMap) FullHelp() [][]key.Binding {
 	}
 }
 
-// Note: key.Binding matching is done directly in update.go using key.Matches()
+// helpSection is a titled group of bindings shown in the help overlay.
+type helpSection struct {
+	Title    string
+	Bindings []key.Binding
+}
+
+// helpSections returns the bindings that apply in view with component
+// focused, most specific first, followed by the global bindings. Bindings
+// that are disabled are left out.
+func (k KeyMap) helpSections(view ViewMode, component string) []helpSection {
+	var sections []helpSection
+	switch view {
+	case ViewStartup:
+		sections = append(sections, helpSection{"Startup", []key.Binding{k.Start}})
+	case ViewChat:
+		switch component {
+		case "input":
+			sections = append(sections,
+				helpSection{"Input", []key.Binding{k.Send, k.NewLine, k.HistoryUp, k.HistoryDown, k.Cancel}},
+				helpSection{"Editing", []key.Binding{k.Undo, k.Redo}})
+		case "output":
+			sections = append(sections,
+				helpSection{"Output", []key.Binding{k.ScrollUp, k.ScrollDown, k.PageUp, k.PageDown, k.Home, k.End}})
+		}
+		sections = append(sections,
+			helpSection{"Navigation", []key.Binding{k.FocusNext, k.FocusPrevious, k.FocusInput, k.FocusOutput, k.FocusFiles, k.FocusSession}},
+			helpSection{"View", []key.Binding{k.ToggleMode, k.ToggleSidebar, k.ToggleBrowser}})
+	case ViewSettings:
+		sections = append(sections, helpSection{"Settings", []key.Binding{k.Back}})
+	case ViewTools:
+		sections = append(sections, helpSection{"Tools", []key.Binding{k.ListUp, k.ListDown, k.Toggle, k.Back}})
+	case ViewModels:
+		sections = append(sections, helpSection{"Models", []key.Binding{k.ListUp, k.ListDown, withHelpDesc(k.Select, "switch to model"), k.Back}})
+	case ViewRuns:
+		sections = append(sections, helpSection{"Runs", []key.Binding{k.ListUp, k.ListDown, withHelpDesc(k.Select, "re-run"), k.Favorite, k.Back}})
+	case ViewOptimize:
+		sections = append(sections, helpSection{"Optimize", []key.Binding{withHelpDesc(k.Confirm, "prune"), k.Reject}})
+	case ViewContext:
+		sections = append(sections, helpSection{"Context", []key.Binding{k.Close}})
+	case ViewRoots:
+		sections = append(sections, helpSection{"Roots", []key.Binding{k.Close}})
+	case ViewProviders:
+		sections = append(sections, helpSection{"Providers", []key.Binding{k.Retest, k.Back}})
+	case ViewTrust:
+		sections = append(sections, helpSection{"Trust", []key.Binding{withHelpDesc(k.Confirm, "trust"), withHelpDesc(k.Reject, "restrict")}})
+	case ViewPull:
+		sections = append(sections, helpSection{"Pull", []key.Binding{withHelpDesc(k.Confirm, "pull"), withHelpDesc(k.Reject, "not now")}})
+	case ViewFlags:
+		sections = append(sections, helpSection{"Flags", []key.Binding{k.ListUp, k.ListDown, k.Toggle, k.Back}})
+	case ViewSubstitutions:
+		sections = append(sections, helpSection{"Substitutions", []key.Binding{k.Back}})
+	case ViewPersona:
+		sections = append(sections, helpSection{"Persona", []key.Binding{k.ListUp, k.ListDown, k.Select, k.Back}})
+	case ViewShells:
+		sections = append(sections, helpSection{"Shells", []key.Binding{k.ListUp, k.ListDown, k.Kill, k.Back}})
+	case ViewFinish:
+		sections = append(sections, helpSection{"Finish", []key.Binding{k.Merge, k.Push, k.Discard, withHelpDesc(k.Retest, "re-run tests"), k.Back}})
+	case ViewRedact:
+		sections = append(sections, helpSection{"Redact", []key.Binding{withHelpDesc(k.Confirm, "redact"), k.Reject}})
+	case ViewConfig:
+		sections = append(sections, helpSection{"Config", []key.Binding{k.ListUp, k.ListDown, k.PageUp, k.PageDown, k.Edit, k.Back}})
+	case ViewPalette:
+		sections = append(sections, helpSection{"Palette", []key.Binding{k.PaletteUp, k.PaletteDown, withHelpDesc(k.Select, "run"), withHelpDesc(k.Cancel, "close")}})
+	}
+	sections = append(sections, helpSection{"Global", []key.Binding{k.Quit, k.ForceQuit, k.Help, k.ClearScreen, k.CommandPalette, k.Settings}})
+
+	// Drop disabled bindings and the sections they leave empty
+	kept := sections[:0]
+	for _, section := range sections {
+		enabled := section.Bindings[:0]
+		for _, b := range section.Bindings {
+			if b.Enabled() {
+				enabled = append(enabled, b)
+			}
+		}
+		if len(enabled) > 0 {
+			section.Bindings = enabled
+			kept = append(kept, section)
+		}
+	}
+	return kept
+}
+
+// withHelpDesc returns a copy of b described as desc, for bindings whose
+// action depends on the view they are used in.
+func withHelpDesc(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/abrksh22/bplus/internal/config"
+)
+
+// editorTemplates are the URI templates of the editors ui.editor_links can
+// name. {path} is absolute and starts with a slash on every platform.
+var editorTemplates = map[string]string{
+	"vscode":  "vscode://file{path}:{line}:{column}",
+	"cursor":  "cursor://file{path}:{line}:{column}",
+	"zed":     "zed://file{path}:{line}:{column}",
+	"idea":    "idea://open?file={path}&line={line}&column={column}",
+	"sublime": "subl://open?url=file://{path}&line={line}&column={column}",
+	"file":    "file://{path}",
+}
+
+// maxLinkedFiles bounds the cache of which references name existing files.
+const maxLinkedFiles = 1024
+
+var (
+	// fileReference matches path:line and path:line:column, where the path
+	// has an extension, as compilers, linters and stack traces print them
+	fileReference = regexp.MustCompile(`[\w./~-]*[\w-]\.[A-Za-z0-9]+:(\d+)(?::(\d+))?`)
+
+	// escapeSequence matches the CSI and OSC sequences of styled text, which
+	// references are not looked for in
+	escapeSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+)
+
+// editorLinks turns file:line references in output into OSC 8 hyperlinks
+// that open the file at that line in the user's editor, in terminals that
+// support them. Others show the text unchanged.
+type editorLinks struct {
+	template string // Empty when links are off
+	root     string // Directory relative references are resolved in
+	files    map[string]bool
+}
+
+// newEditorLinks creates links for a ui.editor_links setting, looking up
+// the editor for "auto" in env.
+func newEditorLinks(setting string, env func(string) string) *editorLinks {
+	if setting == "" || setting == "auto" {
+		setting = detectEditor(env)
+	}
+	template := setting
+	if t, ok := editorTemplates[setting]; ok {
+		template = t
+	}
+	if !strings.Contains(template, "{path}") {
+		template = ""
+	}
+	root, _ := os.Getwd()
+	return &editorLinks{template: template, root: root, files: make(map[string]bool)}
+}
+
+// detectEditor picks the editor links open in: the editor whose integrated
+// terminal bplus runs in, else the one $VISUAL or $EDITOR names, else the
+// system's handler for file URLs.
+func detectEditor(env func(string) string) string {
+	if env("TERM") == "dumb" {
+		return "off"
+	}
+	switch env("TERM_PROGRAM") {
+	case "vscode":
+		if env("CURSOR_TRACE_ID") != "" {
+			return "cursor"
+		}
+		return "vscode"
+	case "zed":
+		return "zed"
+	}
+	if strings.Contains(env("TERMINAL_EMULATOR"), "JetBrains") {
+		return "idea"
+	}
+
+	editor := env("VISUAL")
+	if editor == "" {
+		editor = env("EDITOR")
+	}
+	if fields := strings.Fields(editor); len(fields) > 0 {
+		switch name := strings.TrimSuffix(filepath.Base(fields[0]), ".exe"); name {
+		case "code", "code-insiders":
+			return "vscode"
+		case "cursor", "zed":
+			return name
+		case "subl":
+			return "sublime"
+		case "idea", "goland", "pycharm", "webstorm", "phpstorm", "clion", "rubymine", "rider":
+			return "idea"
+		}
+	}
+	return "file"
+}
+
+// link hyperlinks the references in text to files that exist. Styling
+// escape sequences in text are kept.
+func (l *editorLinks) link(text string) string {
+	if l == nil || l.template == "" || !strings.Contains(text, ":") {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, esc := range escapeSequence.FindAllStringIndex(text, -1) {
+		b.WriteString(l.linkPlain(text[last:esc[0]]))
+		b.WriteString(text[esc[0]:esc[1]])
+		last = esc[1]
+	}
+	b.WriteString(l.linkPlain(text[last:]))
+	return b.String()
+}
+
+// linkPlain hyperlinks the references in text without escape sequences.
+func (l *editorLinks) linkPlain(text string) string {
+	matches := fileReference.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		ref := text[m[0]:m[1]]
+		line := text[m[2]:m[3]]
+		column := "1"
+		if m[4] >= 0 {
+			column = text[m[4]:m[5]]
+		}
+		path := l.resolve(ref[:m[2]-m[0]-1])
+		if path == "" {
+			continue
+		}
+		b.WriteString(text[last:m[0]])
+		b.WriteString(hyperlink(l.uri(path, line, column), ref))
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// resolve returns the absolute path of a referenced file, or "" if it
+// isn't a file.
+func (l *editorLinks) resolve(name string) string {
+	path := name
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.root, path)
+	}
+	path = filepath.Clean(path)
+
+	exists, ok := l.files[path]
+	if !ok {
+		info, err := os.Stat(path)
+		exists = err == nil && info.Mode().IsRegular()
+		if len(l.files) >= maxLinkedFiles {
+			l.files = make(map[string]bool)
+		}
+		l.files[path] = exists
+	}
+	if !exists {
+		return ""
+	}
+	return path
+}
+
+// uri fills the template in for a file position.
+func (l *editorLinks) uri(path, line, column string) string {
+	slashed := filepath.ToSlash(path)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed // C:/dir/file
+	}
+	return strings.NewReplacer(
+		"{path}", (&url.URL{Path: slashed}).EscapedPath(),
+		"{line}", line,
+		"{column}", column,
+	).Replace(l.template)
+}
+
+// hyperlink wraps text in an OSC 8 hyperlink to uri.
+func hyperlink(uri, text string) string {
+	return "\x1b]8;;" + uri + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// editorLinks returns the hyperlinker for output, built from ui.editor_links
+// on first use. Without configuration, references are not linked.
+func (m *Model) editorLinks() *editorLinks {
+	if m.links == nil {
+		setting := "off"
+		if app, ok := m.app.(interface{ GetConfig() *config.Config }); ok && app.GetConfig() != nil {
+			setting = app.GetConfig().UI.EditorLinks
+		}
+		m.links = newEditorLinks(setting, os.Getenv)
+	}
+	return m.links
+}
@@ -1,6 +1,13 @@
 package ui
 
 import (
+	"time"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/internal/worktree"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/tools"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -97,6 +104,75 @@ type StatusUpdateMsg struct {
 	Tokens int
 }
 
+// AppEventMsg wraps an event received from the application event bus.
+type AppEventMsg struct {
+	Event events.Event
+}
+
+// ModelListMsg carries the available models and their observed performance.
+type ModelListMsg struct {
+	Models []models.ModelPerformance
+	Err    error
+}
+
+// RunListMsg carries the project's command run history.
+type RunListMsg struct {
+	Runs []*storage.CommandRun
+	Err  error
+}
+
+// RunFinishedMsg reports the outcome of re-running a past command.
+type RunFinishedMsg struct {
+	ID     int64
+	Run    *storage.CommandRun // Nil if the command did not run
+	Result *tools.Result
+	Err    error
+}
+
+// WorktreeMsg carries the linked worktree the session runs in and the work
+// done in it.
+type WorktreeMsg struct {
+	Tree    *worktree.Worktree
+	Changes *worktree.Changes
+	Err     error
+}
+
+// WorktreeTestedMsg carries the outcome of testing the merge result.
+type WorktreeTestedMsg struct {
+	Result *worktree.TestResult
+	Err    error
+}
+
+// WorktreeFinishedMsg reports the outcome of merging, pushing or discarding
+// the work of a worktree.
+type WorktreeFinishedMsg struct {
+	Action finishAction
+	Err    error
+}
+
+// IdleCheckMsg is sent when the session may have been idle long enough to
+// be suspended.
+type IdleCheckMsg struct {
+	Time time.Time
+}
+
+// SuspendedMsg reports that the application released its idle resources.
+type SuspendedMsg struct{}
+
+// PaletteSourcesMsg carries the sessions and project files offered by the
+// command palette.
+type PaletteSourcesMsg struct {
+	Sessions []*storage.Session
+	Files    []string
+	Err      error
+}
+
+// ConfigEditedMsg reports that the editor opened on a config file exited.
+type ConfigEditedMsg struct {
+	Path string
+	Err  error
+}
+
 // ShowHelpMsg is sent to show/hide the help overlay.
 type ShowHelpMsg struct {
 	Show bool
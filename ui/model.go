@@ -2,6 +2,19 @@
 package ui
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/internal/worktree"
+	"github.com/abrksh22/bplus/layers/contextmgr"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/security"
+	"github.com/abrksh22/bplus/tools"
+	"github.com/abrksh22/bplus/ui/components"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -17,6 +30,7 @@ type Model struct {
 	quitting         bool
 	err              error
 	view             ViewMode
+	helpReturn       ViewMode // View the help overlay was opened from
 	focusedComponent string
 
 	// Application reference (Phase 6)
@@ -34,6 +48,112 @@ type Model struct {
 
 	// Key bindings
 	keys KeyMap
+
+	// Slash commands
+	commands map[string]SlashCommand
+
+	// Tools view state
+	toolCursor int
+
+	// Models view state
+	modelList   []models.ModelPerformance
+	modelCursor int
+
+	// Optimize view state: the pruning preview awaiting confirmation
+	optimizePlan *contextmgr.Plan
+
+	// Context view state
+	contextItems []contextmgr.Item
+
+	// Command palette state
+	paletteReturn ViewMode // View the palette was opened from
+	paletteQuery  string
+	paletteCursor int
+	paletteItems  []paletteItem
+
+	// Config view state
+	configSettings []config.Setting
+	configCursor   int
+	configResult   string // Outcome of the last edit
+
+	// Providers view state
+	providerHealth    []models.ProviderHealth
+	providersChecking bool // A connection test is running
+
+	// Trust prompt state
+	trustLevel security.TrustLevel
+	trustPath  string // Workspace the decision is made for
+
+	// Redact view state: the redaction awaiting confirmation
+	redactTarget  string // Pattern or #message-id to redact
+	redactStored  int    // Stored messages it would change
+	redactContext int    // Context messages it would change
+
+	// Pull prompt state
+	pullOffer string // Model that isn't installed, offered to be pulled
+
+	// Flags view state
+	flagCursor int
+
+	// Hyperlinks for file:line references, built on first use
+	links *editorLinks
+
+	// Persona picker state
+	personaCursor int
+	shellCursor   int
+
+	// Finish view state
+	finishTree       *worktree.Worktree
+	finishChanges    *worktree.Changes
+	finishTest       *worktree.TestResult
+	finishBusy       string // Step running, if any
+	finishResult     string // Outcome of the last step
+	finishDiscarding bool   // Discard was pressed once and awaits confirmation
+
+	// Roots view state
+	rootList    []security.Root
+	rootsResult string // Outcome of the last attach or detach
+
+	// Runs view state
+	runList   []*storage.CommandRun
+	runCursor int
+	runResult string // Outcome of the last re-run
+
+	// Application events and the status they drive
+	events <-chan events.Event
+	mode   string
+	cost   float64
+	tokens int
+	// Share of cost and tokens spent on reasoning
+	reasoningCost   float64
+	reasoningTokens int
+	activeTool      string
+	progress        *events.ToolProgress        // Last progress reported by the active tool
+	rateLimit       *events.RateLimitUpdated    // Last limit reported by a provider
+	modelLoad       *events.ModelLoadChanged    // Last load state of a preloaded local model
+	degraded        map[string]bool             // Thorough Mode layers running on a substitute or skipped
+	largeRepo       *events.RepositoryScanned   // Set if the project is too large to explore cheaply
+	signIn          *events.SignInRequested     // Device code sign-in a provider gateway is waiting for
+	draft           *events.DraftStreamed       // Draft answer shown until the real one replaces it
+	change          *events.PermissionRequested // Change a tool call asked to make, shown until it finishes
+	todos           []events.Todo               // The agent's task list, as last updated
+	changed         []events.FileChanged        // Files changed outside b+ since the last prompt, latest last
+
+	// Idle suspension state
+	lastActivity time.Time // Last key press, input or streamed token
+	suspended    bool      // Showing the suspended screen until a key is pressed
+	suspending   bool      // The application is still releasing its resources
+
+	// Conversation shown in the chat view, each answer annotated with the
+	// stats of its turn
+	output *components.OutputComponent
+
+	// Stats for the assistant turn in progress
+	turn      components.TurnStats
+	turnStart time.Time
+	truncated *events.AnswerTruncated // Set if the turn's answer was cut off at the token limit
+
+	elevatedAllowed bool // The next elevated tool call was allowed with /allow-elevated
 }
 
 // ViewMode represents the current view mode.
@@ -44,10 +164,29 @@ const (
 	ViewChat
 	ViewSettings
 	ViewHelp
+	ViewTools
+	ViewModels
+	ViewOptimize
+	ViewRuns
+	ViewContext
+	ViewPalette
+	ViewConfig
+	ViewRoots
+	ViewProviders
+	ViewTrust
+	ViewRedact
+	ViewPull
+	ViewFinish
+	ViewFlags
+	ViewSubstitutions
+	ViewPersona
+	ViewShells
 )
 
 // New creates a new UI model with default settings.
 func New() *Model {
+	output := components.NewOutput(80, 24)
+	output.Init()
 	return &Model{
 		ready:            false,
 		quitting:         false,
@@ -55,6 +194,8 @@ func New() *Model {
 		focusedComponent: "input",
 		theme:            DefaultTheme(),
 		keys:             DefaultKeyMap(),
+		commands:         defaultCommands(),
+		output:           &output,
 	}
 }
 
@@ -62,14 +203,152 @@ func New() *Model {
 func NewWithApp(application interface{}) *Model {
 	m := New()
 	m.app = application
+	if app, ok := application.(interface{ GetEventBus() *events.Bus }); ok && app.GetEventBus() != nil {
+		m.events, _ = app.GetEventBus().Channel(eventBuffer)
+	}
+	m.output.SetShowStats(m.showCost())
 	return m
 }
 
+// eventBuffer is the number of application events queued for the UI before
+// new ones are dropped.
+const eventBuffer = 256
+
+// isOffline reports whether the attached application runs in offline mode.
+func (m *Model) isOffline() bool {
+	if app, ok := m.app.(interface{ IsOffline() bool }); ok {
+		return app.IsOffline()
+	}
+	return false
+}
+
+// abbreviations returns the user-defined input abbreviations, if any.
+func (m *Model) abbreviations() map[string]string {
+	if app, ok := m.app.(interface{ GetConfig() *config.Config }); ok && app.GetConfig() != nil {
+		return app.GetConfig().UI.Abbreviations
+	}
+	return nil
+}
+
+// showCost reports whether per-turn cost annotations are shown (ui.show_cost).
+func (m *Model) showCost() bool {
+	if app, ok := m.app.(interface{ GetConfig() *config.Config }); ok && app.GetConfig() != nil {
+		return app.GetConfig().UI.ShowCost
+	}
+	return true
+}
+
+// startTurn begins collecting stats for a new assistant turn.
+func (m *Model) startTurn() {
+	m.turn = components.TurnStats{}
+	m.turnStart = time.Now()
+	m.truncated = nil
+	m.changed = nil
+}
+
+// finishTurn closes the turn in progress, annotating its answer with the
+// turn's stats.
+func (m *Model) finishTurn() {
+	if m.turnStart.IsZero() {
+		return
+	}
+	stats := m.turn
+	stats.Duration = time.Since(m.turnStart)
+	m.output.SetTurnStats(stats)
+	if m.truncated != nil {
+		m.output.AddMessage("system", truncatedNotice(m.truncated))
+		m.truncated = nil
+	}
+	m.turnStart = time.Time{}
+}
+
+// truncatedNotice tells the user an answer is incomplete.
+func truncatedNotice(e *events.AnswerTruncated) string {
+	notice := "The answer above was cut off at the token limit"
+	if e.Continuations > 0 {
+		notice += fmt.Sprintf(" after %d continuations", e.Continuations)
+	}
+	return notice + ". Ask to continue where it stopped."
+}
+
+// contextOptimizer is implemented by applications that manage the conversation context.
+type contextOptimizer interface {
+	PreviewOptimization() contextmgr.Plan
+	OptimizeContext() (contextmgr.Plan, error)
+}
+
+// contextInspector is implemented by applications that can describe the
+// conversation context item by item.
+type contextInspector interface {
+	ContextItems() []contextmgr.Item
+}
+
+// runHistory is implemented by applications that keep a command run history.
+type runHistory interface {
+	CommandRuns(limit int) ([]*storage.CommandRun, error)
+	RerunCommand(ctx context.Context, id int64) (*storage.CommandRun, *tools.Result, error)
+	SetRunFavorite(id int64, favorite bool) error
+}
+
+// runListLimit is how many runs the runs view shows.
+const runListLimit = 50
+
+// loadRuns fetches the run history in the background.
+func (m *Model) loadRuns() tea.Cmd {
+	app, ok := m.app.(runHistory)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		runs, err := app.CommandRuns(runListLimit)
+		return RunListMsg{Runs: runs, Err: err}
+	}
+}
+
+// rerun executes a past run again in the background.
+func (m *Model) rerun(id int64) tea.Cmd {
+	app, ok := m.app.(runHistory)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		run, result, err := app.RerunCommand(context.Background(), id)
+		return RunFinishedMsg{ID: id, Run: run, Result: result, Err: err}
+	}
+}
+
+// modelSwitcher is implemented by applications that can list and switch models.
+type modelSwitcher interface {
+	ListModelPerformance(ctx context.Context) ([]models.ModelPerformance, error)
+	SetModel(name string) error
+}
+
+// loadModels fetches the model list with observed performance in the background.
+func (m *Model) loadModels() tea.Cmd {
+	app, ok := m.app.(modelSwitcher)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		list, err := app.ListModelPerformance(context.Background())
+		return ModelListMsg{Models: list, Err: err}
+	}
+}
+
+// toolRegistry returns the attached application's tool registry, if any.
+func (m *Model) toolRegistry() *tools.Registry {
+	if app, ok := m.app.(interface{ GetToolRegistry() *tools.Registry }); ok {
+		return app.GetToolRegistry()
+	}
+	return nil
+}
+
 // Init initializes the model (Bubble Tea lifecycle method).
 func (m *Model) Init() tea.Cmd {
-	// Return commands to run on initialization
-	// For now, we'll just return nil - more commands will be added as we build components
-	return nil
+	// Start listening for application events, if attached to an application,
+	// and watching for idleness
+	m.lastActivity = time.Now()
+	return tea.Batch(m.waitForEvent(), m.idleCheck())
 }
 
 // Width returns the current window width.
@@ -86,6 +365,7 @@ func (m *Model) Height() int {
 func (m *Model) SetSize(width, height int) {
 	m.width = width
 	m.height = height
+	m.output.SetSize(width, height)
 }
 
 // IsReady returns whether the UI is ready to display.
@@ -123,8 +403,13 @@ func (m *Model) CurrentView() ViewMode {
 	return m.view
 }
 
-// SetView changes the current view mode.
+// SetView changes the current view mode. Switching to ViewHelp opens the
+// help overlay for the view shown before.
 func (m *Model) SetView(view ViewMode) {
+	if view == ViewHelp {
+		m.showHelp()
+		return
+	}
 	m.view = view
 }
 
@@ -179,6 +464,40 @@ func viewModeToString(v ViewMode) string {
 		return "Settings"
 	case ViewHelp:
 		return "Help"
+	case ViewTools:
+		return "Tools"
+	case ViewModels:
+		return "Models"
+	case ViewOptimize:
+		return "Optimize"
+	case ViewRuns:
+		return "Runs"
+	case ViewContext:
+		return "Context"
+	case ViewPalette:
+		return "Palette"
+	case ViewConfig:
+		return "Config"
+	case ViewRoots:
+		return "Roots"
+	case ViewProviders:
+		return "Providers"
+	case ViewTrust:
+		return "Trust"
+	case ViewRedact:
+		return "Redact"
+	case ViewPull:
+		return "Pull"
+	case ViewFinish:
+		return "Finish"
+	case ViewFlags:
+		return "Flags"
+	case ViewSubstitutions:
+		return "Substitutions"
+	case ViewPersona:
+		return "Persona"
+	case ViewShells:
+		return "Shells"
 	default:
 		return "Unknown"
 	}
@@ -0,0 +1,267 @@
+package ui
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/abrksh22/bplus/internal/storage"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Palette item kinds
+const (
+	paletteCommand = "command"
+	paletteSetting = "setting"
+	paletteSession = "session"
+	paletteFile    = "file"
+)
+
+// paletteItem is one entry of the command palette.
+type paletteItem struct {
+	Kind   string
+	Title  string // Text the query is matched against
+	Detail string
+	Run    func(m *Model) tea.Cmd
+}
+
+// Palette limits
+const (
+	// paletteRows is how many matches the palette lists.
+	paletteRows = 10
+
+	// paletteMaxFiles bounds the project files offered.
+	paletteMaxFiles = 5000
+)
+
+// sessionResumer is implemented by applications that keep past sessions.
+type sessionResumer interface {
+	Sessions() ([]*storage.Session, error)
+	ResumeSession(ctx context.Context, id string) error
+}
+
+// fileAttacher is implemented by applications that can add project files to
+// the conversation.
+type fileAttacher interface {
+	ProjectFiles(ctx context.Context) ([]string, error)
+	AttachFile(path string) error
+}
+
+// openPalette shows the command palette over the current view, listing the
+// slash commands and settings actions at once and loading sessions and
+// files in the background.
+func (m *Model) openPalette() tea.Cmd {
+	if m.view != ViewPalette {
+		m.paletteReturn = m.view
+	}
+	m.view = ViewPalette
+	m.paletteQuery = ""
+	m.paletteCursor = 0
+	m.paletteItems = append(m.commandItems(), m.settingItems()...)
+	return m.loadPaletteSources()
+}
+
+// closePalette hides the command palette, returning to the view it was
+// opened from.
+func (m *Model) closePalette() {
+	if m.view != ViewPalette {
+		return
+	}
+	m.view = m.paletteReturn
+	m.paletteItems = nil
+}
+
+// commandItems returns a palette item per slash command.
+func (m *Model) commandItems() []paletteItem {
+	var items []paletteItem
+	for _, cmd := range m.Commands() {
+		cmd := cmd
+		items = append(items, paletteItem{
+			Kind:   paletteCommand,
+			Title:  "/" + cmd.Name,
+			Detail: cmd.Description,
+			Run: func(m *Model) tea.Cmd {
+				return cmd.Run(m, nil)
+			},
+		})
+	}
+	return items
+}
+
+// settingItems returns the settings actions: opening the settings view and
+// switching themes.
+func (m *Model) settingItems() []paletteItem {
+	items := []paletteItem{{
+		Kind:   paletteSetting,
+		Title:  "Open settings",
+		Detail: "Show the settings view",
+		Run: func(m *Model) tea.Cmd {
+			m.view = ViewSettings
+			return nil
+		},
+	}}
+	for _, name := range ThemeNames() {
+		name := name
+		items = append(items, paletteItem{
+			Kind:   paletteSetting,
+			Title:  "Theme: " + name,
+			Detail: "Switch the color theme",
+			Run: func(m *Model) tea.Cmd {
+				m.SetTheme(GetThemeByName(name))
+				return nil
+			},
+		})
+	}
+	return items
+}
+
+// loadPaletteSources fetches the stored sessions and project files in the
+// background.
+func (m *Model) loadPaletteSources() tea.Cmd {
+	sessions, hasSessions := m.app.(sessionResumer)
+	files, hasFiles := m.app.(fileAttacher)
+	if !hasSessions && !hasFiles {
+		return nil
+	}
+	return func() tea.Msg {
+		var msg PaletteSourcesMsg
+		if hasSessions {
+			msg.Sessions, msg.Err = sessions.Sessions()
+		}
+		if hasFiles && msg.Err == nil {
+			msg.Files, msg.Err = files.ProjectFiles(context.Background())
+		}
+		return msg
+	}
+}
+
+// handlePaletteSources adds loaded sessions and files to an open palette.
+func (m *Model) handlePaletteSources(msg PaletteSourcesMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		m.SetError(msg.Err)
+	}
+	if m.view != ViewPalette {
+		return m, nil
+	}
+
+	for _, session := range msg.Sessions {
+		id := session.ID
+		title := session.Name
+		if title == "" {
+			title = id
+		}
+		m.paletteItems = append(m.paletteItems, paletteItem{
+			Kind:   paletteSession,
+			Title:  title,
+			Detail: "Resume session from " + session.UpdatedAt.Format("Jan 2 15:04"),
+			Run: func(m *Model) tea.Cmd {
+				if app, ok := m.app.(sessionResumer); ok {
+					if err := app.ResumeSession(context.Background(), id); err != nil {
+						m.SetError(err)
+						return nil
+					}
+				}
+				m.view = ViewChat
+				return nil
+			},
+		})
+	}
+
+	if len(msg.Files) > paletteMaxFiles {
+		msg.Files = msg.Files[:paletteMaxFiles]
+	}
+	for _, path := range msg.Files {
+		path := path
+		m.paletteItems = append(m.paletteItems, paletteItem{
+			Kind:   paletteFile,
+			Title:  path,
+			Detail: "Attach to the conversation",
+			Run: func(m *Model) tea.Cmd {
+				if app, ok := m.app.(fileAttacher); ok {
+					if err := app.AttachFile(path); err != nil {
+						m.SetError(err)
+						return nil
+					}
+				}
+				m.view = ViewChat
+				return nil
+			},
+		})
+	}
+	return m, nil
+}
+
+// paletteMatches returns the palette items matching the query, best first.
+// Without a query every item is listed in the order it was added.
+func (m *Model) paletteMatches() []paletteItem {
+	query := strings.TrimSpace(m.paletteQuery)
+	if query == "" {
+		return m.paletteItems
+	}
+
+	type match struct {
+		item  paletteItem
+		score int
+	}
+	var matches []match
+	for _, item := range m.paletteItems {
+		if score, ok := fuzzyScore(query, item.Title); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	items := make([]paletteItem, len(matches))
+	for i, match := range matches {
+		items[i] = match.item
+	}
+	return items
+}
+
+// runPaletteSelection closes the palette and runs the selected item. A query
+// such as "/runs 12" that names a command with arguments runs that command.
+func (m *Model) runPaletteSelection() tea.Cmd {
+	query := strings.TrimSpace(m.paletteQuery)
+	if strings.HasPrefix(query, "/") && len(strings.Fields(query)) > 1 {
+		m.closePalette()
+		return m.runSlashCommand(query)
+	}
+
+	matches := m.paletteMatches()
+	if m.paletteCursor >= len(matches) {
+		return nil
+	}
+	item := matches[m.paletteCursor]
+	m.closePalette()
+	return item.Run(m)
+}
+
+// fuzzyScore reports whether the characters of query appear in text in
+// order, ignoring case and spaces, and scores the match. Consecutive
+// characters and characters starting a word score higher, and shorter texts
+// win ties, so the initials of a command or path find it.
+func fuzzyScore(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	t := []rune(strings.ToLower(text))
+
+	score, qi, prev := 0, 0, -2
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			continue
+		}
+		score++
+		if ti == prev+1 {
+			score += 3
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 2
+		}
+		prev = ti
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score*100 - len(t), true
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/prompts"
+)
+
+// personaSwitcher is implemented by applications whose agent can take on
+// another persona.
+type personaSwitcher interface {
+	Personas() []prompts.Persona
+	CurrentPersona() string
+	SetPersona(name string) error
+}
+
+// showPersonas opens the persona picker on the current persona.
+func (m *Model) showPersonas() {
+	app, ok := m.app.(personaSwitcher)
+	if !ok {
+		m.SetError(fmt.Errorf("personas are not available"))
+		return
+	}
+	m.personaCursor = 0
+	for i, p := range app.Personas() {
+		if p.Name == app.CurrentPersona() {
+			m.personaCursor = i
+		}
+	}
+	m.view = ViewPersona
+}
+
+// personas returns the attached application's personas and the current
+// one, if any.
+func (m *Model) personas() ([]prompts.Persona, string) {
+	if app, ok := m.app.(personaSwitcher); ok {
+		return app.Personas(), app.CurrentPersona()
+	}
+	return nil, ""
+}
+
+// selectPersona switches to the persona under the cursor.
+func (m *Model) selectPersona() {
+	app, ok := m.app.(personaSwitcher)
+	if !ok {
+		return
+	}
+	list := app.Personas()
+	if m.personaCursor >= len(list) {
+		return
+	}
+	if err := app.SetPersona(list[m.personaCursor].Name); err != nil {
+		m.SetError(err)
+		return
+	}
+	m.view = ViewChat
+}
@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/models"
+)
+
+// providerMonitor is implemented by applications that test the connection
+// of their configured providers.
+type providerMonitor interface {
+	ProviderHealth() ([]models.ProviderHealth, bool)
+	TestProviders()
+}
+
+// showProviders opens the providers view with the latest test results.
+func (m *Model) showProviders() {
+	if _, ok := m.app.(providerMonitor); !ok {
+		m.SetError(fmt.Errorf("provider health is not available"))
+		return
+	}
+	m.refreshProviders()
+	m.view = ViewProviders
+}
+
+// refreshProviders reloads the latest test results from the application.
+func (m *Model) refreshProviders() {
+	if app, ok := m.app.(providerMonitor); ok {
+		m.providerHealth, m.providersChecking = app.ProviderHealth()
+	}
+}
+
+// retestProviders starts another round of connection tests. The view is
+// refreshed when events.ProvidersChecked arrives.
+func (m *Model) retestProviders() {
+	if app, ok := m.app.(providerMonitor); ok {
+		app.TestProviders()
+		m.providersChecking = true
+	}
+}
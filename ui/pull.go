@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"fmt"
+)
+
+// modelDownloader is implemented by applications that can download models
+// a local provider doesn't have yet.
+type modelDownloader interface {
+	PullModel(name string) error
+}
+
+// promptPull asks whether to pull a model reported missing, once the chat
+// is shown, so other prompts like trust are answered first.
+func (m *Model) promptPull() {
+	if m.pullOffer != "" && m.view == ViewChat {
+		m.view = ViewPull
+	}
+}
+
+// pullModel starts downloading name and returns to chat, where the status
+// bar shows the progress.
+func (m *Model) pullModel(name string) {
+	m.pullOffer = ""
+	m.view = ViewChat
+	app, ok := m.app.(modelDownloader)
+	if !ok {
+		m.SetError(fmt.Errorf("model downloads are not available"))
+		return
+	}
+	if err := app.PullModel(name); err != nil {
+		m.SetError(err)
+	}
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conversationRedactor is implemented by applications that can scrub
+// content from the stored conversation and future prompts.
+type conversationRedactor interface {
+	PreviewRedaction(target string) (stored, inContext int, err error)
+	Redact(target string) (stored, inContext int, err error)
+}
+
+// showRedaction previews what redacting target would change and asks for
+// confirmation, since redaction cannot be undone.
+func (m *Model) showRedaction(target string) {
+	app, ok := m.app.(conversationRedactor)
+	if !ok {
+		m.SetError(fmt.Errorf("redaction is not available"))
+		return
+	}
+	if strings.TrimSpace(target) == "" {
+		m.SetError(fmt.Errorf("usage: /redact <pattern|#message-id>"))
+		return
+	}
+	stored, inContext, err := app.PreviewRedaction(target)
+	if err != nil {
+		m.SetError(err)
+		return
+	}
+	m.redactTarget = target
+	m.redactStored = stored
+	m.redactContext = inContext
+	m.view = ViewRedact
+}
+
+// confirmRedaction redacts the previewed target and returns to chat.
+func (m *Model) confirmRedaction() {
+	if app, ok := m.app.(conversationRedactor); ok && m.redactTarget != "" {
+		if _, _, err := app.Redact(m.redactTarget); err != nil {
+			m.SetError(err)
+		}
+	}
+	m.redactTarget = ""
+	m.view = ViewChat
+}
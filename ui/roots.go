@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/security"
+)
+
+// rootManager is implemented by applications that can attach several
+// directory roots to a session.
+type rootManager interface {
+	Roots() []security.Root
+	AttachRoot(spec string) (security.Root, error)
+	DetachRoot(name string) error
+}
+
+// runRootsCommand handles "/roots", "/roots add <[name=]path[:ro]>" and
+// "/roots remove <name>", then shows the attached roots.
+func (m *Model) runRootsCommand(args []string) {
+	app, ok := m.app.(rootManager)
+	if !ok {
+		m.SetError(fmt.Errorf("multiple roots are not available"))
+		return
+	}
+
+	m.rootsResult = ""
+	switch {
+	case len(args) == 2 && args[0] == "add":
+		root, err := app.AttachRoot(args[1])
+		if err != nil {
+			m.SetError(err)
+			break
+		}
+		m.rootsResult = fmt.Sprintf("Attached %s (%s); refer to its files as %s:path", root.Name, security.DescribePermissions(root), root.Name)
+	case len(args) == 2 && (args[0] == "remove" || args[0] == "rm"):
+		if err := app.DetachRoot(args[1]); err != nil {
+			m.SetError(err)
+			break
+		}
+		m.rootsResult = "Detached " + args[1]
+	case len(args) > 0:
+		m.SetError(fmt.Errorf("usage: /roots [add [name=]path[:ro] | remove name]"))
+	}
+
+	m.rootList = app.Roots()
+	m.view = ViewRoots
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/tools/exec"
+)
+
+// shellManager is implemented by applications that keep the agent's shell
+// sessions.
+type shellManager interface {
+	ShellSessions() []exec.ShellSession
+	KillShellSession(id string) error
+}
+
+// showShells opens the list of shell sessions.
+func (m *Model) showShells() {
+	if _, ok := m.app.(shellManager); !ok {
+		m.SetError(fmt.Errorf("shell sessions are not available"))
+		return
+	}
+	m.shellCursor = 0
+	m.view = ViewShells
+}
+
+// shellSessions returns the attached application's shell sessions, if
+// any.
+func (m *Model) shellSessions() []exec.ShellSession {
+	if app, ok := m.app.(shellManager); ok {
+		return app.ShellSessions()
+	}
+	return nil
+}
+
+// killShell kills the shell session under the cursor.
+func (m *Model) killShell() {
+	app, ok := m.app.(shellManager)
+	if !ok {
+		return
+	}
+	list := app.ShellSessions()
+	if m.shellCursor >= len(list) {
+		return
+	}
+	if err := app.KillShellSession(list[m.shellCursor].ID); err != nil {
+		m.SetError(err)
+		return
+	}
+	if m.shellCursor > 0 && m.shellCursor >= len(list)-1 {
+		m.shellCursor--
+	}
+}
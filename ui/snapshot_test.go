@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/ui/components"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/muesli/termenv"
+)
+
+// snapshotSizes are the terminal sizes every snapshot is rendered at: the
+// classic 80x24, a wide terminal and a narrow split pane.
+var snapshotSizes = []struct{ width, height int }{
+	{80, 24},
+	{120, 40},
+	{60, 20},
+}
+
+// requireSnapshot renders the view returned by render at every snapshot size
+// and compares it with testdata/<test>/<width>x<height>.golden. Colors are
+// forced on so theme changes show up as escape sequences in the diff.
+//
+// Regenerate the golden files after an intended change with:
+//
+//	go test ./ui -run TestSnapshot -update
+func requireSnapshot(t *testing.T, render func(width, height int) string) {
+	t.Helper()
+
+	profile := lipgloss.ColorProfile()
+	dark := lipgloss.HasDarkBackground()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	lipgloss.SetHasDarkBackground(true)
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(profile)
+		lipgloss.SetHasDarkBackground(dark)
+	})
+
+	for _, size := range snapshotSizes {
+		t.Run(fmt.Sprintf("%dx%d", size.width, size.height), func(t *testing.T) {
+			golden.RequireEqualEscape(t, []byte(render(size.width, size.height)), true)
+		})
+	}
+}
+
+// sizedModel returns a model that has received a window size, as Bubble Tea
+// sends before the first render.
+func sizedModel(width, height int) *Model {
+	m := New()
+	m.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	return m
+}
+
+func TestSnapshot_Startup(t *testing.T) {
+	requireSnapshot(t, func(width, height int) string {
+		return sizedModel(width, height).View()
+	})
+}
+
+func TestSnapshot_Chat(t *testing.T) {
+	requireSnapshot(t, func(width, height int) string {
+		m := sizedModel(width, height)
+		m.SetView(ViewChat)
+		m.activeTool = "bash"
+		m.cost = 0.42
+		m.tokens = 12345
+		return m.View()
+	})
+}
+
+func TestSnapshot_ChatError(t *testing.T) {
+	requireSnapshot(t, func(width, height int) string {
+		m := sizedModel(width, height)
+		m.SetView(ViewChat)
+		m.SetError(fmt.Errorf("provider anthropic: HTTP 529 overloaded"))
+		return m.View()
+	})
+}
+
+func TestSnapshot_Help(t *testing.T) {
+	requireSnapshot(t, func(width, height int) string {
+		m := sizedModel(width, height)
+		m.SetView(ViewChat)
+		m.SetView(ViewHelp)
+		return m.View()
+	})
+}
+
+func TestSnapshot_PermissionDialog(t *testing.T) {
+	requireSnapshot(t, func(width, height int) string {
+		modal := components.NewModal("Permission required",
+			"bash wants to run:\n\n  go test ./...\n\nin /home/dev/project")
+		modal.SetButtons([]string{"Allow once", "Always allow", "Deny"})
+		modal.SetSize(min(width-4, 60), 12)
+		modal.Show()
+		return modal.Center(width, height)
+	})
+}
+
+func TestSnapshot_Config(t *testing.T) {
+	requireSnapshot(t, func(width, height int) string {
+		m := sizedModel(width, height)
+		m.configSettings = []config.Setting{
+			{Key: "layers.main_agent.max_continuations", Value: "3", Source: config.SourceDefault},
+			{Key: "mode", Value: "thorough", Source: config.SourceFlag, Origin: "--thorough"},
+			{Key: "providers.anthropic.api_key", Value: "sk-a****1234", Secret: true, Source: config.SourceEnv, Origin: "ANTHROPIC_API_KEY"},
+			{Key: "ui.theme", Value: "dark", Source: config.SourceProjectFile, Origin: ".b+/config.yaml", Line: 2},
+		}
+		m.configCursor = 1
+		m.SetView(ViewConfig)
+		return m.View()
+	})
+}
+
+func TestSnapshot_Palette(t *testing.T) {
+	requireSnapshot(t, func(width, height int) string {
+		m := sizedModel(width, height)
+		m.SetView(ViewChat)
+		m.Update(tea.KeyMsg{Type: tea.KeyCtrlK})
+		typeKeys(m, "o")
+		return m.View()
+	})
+}
@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/models/router"
+)
+
+// substitutionReporter is implemented by applications that keep track of
+// the models used instead of the configured ones.
+type substitutionReporter interface {
+	ModelSubstitutions() []router.Substitution
+}
+
+// showSubstitutions opens the model substitutions report.
+func (m *Model) showSubstitutions() {
+	if _, ok := m.app.(substitutionReporter); !ok {
+		m.SetError(fmt.Errorf("model substitutions are not available"))
+		return
+	}
+	m.view = ViewSubstitutions
+}
+
+// modelSubstitutions returns the attached application's substitutions, if
+// any.
+func (m *Model) modelSubstitutions() []router.Substitution {
+	if app, ok := m.app.(substitutionReporter); ok {
+		return app.ModelSubstitutions()
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// idleSuspender is implemented by applications that release heavyweight
+// resources, such as a loaded local model, while the session is idle.
+type idleSuspender interface {
+	IdleSuspendAfter() time.Duration
+	Suspend()
+	Resume()
+}
+
+// idleCheck schedules a check for when the session will have been idle long
+// enough to suspend, or returns nil if it never is suspended.
+func (m *Model) idleCheck() tea.Cmd {
+	app, ok := m.app.(idleSuspender)
+	if !ok || app.IdleSuspendAfter() <= 0 {
+		return nil
+	}
+	wait := time.Until(m.lastActivity.Add(app.IdleSuspendAfter()))
+	return tea.Tick(wait, func(t time.Time) tea.Msg {
+		return IdleCheckMsg{Time: t}
+	})
+}
+
+// handleIdleCheck suspends the session if nothing happened since the
+// check was scheduled, and otherwise schedules the next check. A turn in
+// progress counts as activity.
+func (m *Model) handleIdleCheck(msg IdleCheckMsg) (tea.Model, tea.Cmd) {
+	app, ok := m.app.(idleSuspender)
+	if !ok || m.suspended {
+		return m, nil
+	}
+	if !m.turnStart.IsZero() {
+		m.lastActivity = msg.Time
+	}
+	if msg.Time.Sub(m.lastActivity) < app.IdleSuspendAfter() {
+		return m, m.idleCheck()
+	}
+
+	m.suspended = true
+	m.suspending = true
+	return m, func() tea.Msg {
+		app.Suspend()
+		return SuspendedMsg{}
+	}
+}
+
+// handleSuspended resumes straight away if a key was pressed while the
+// session was being suspended.
+func (m *Model) handleSuspended(SuspendedMsg) (tea.Model, tea.Cmd) {
+	m.suspending = false
+	if m.suspended {
+		return m, nil
+	}
+	return m, m.resume()
+}
+
+// wake leaves the suspended state on a key press. The key is only used to
+// wake up, not passed on to the view.
+func (m *Model) wake() (tea.Model, tea.Cmd) {
+	m.suspended = false
+	m.lastActivity = time.Now()
+	if m.suspending {
+		// Resumed once the suspension finishes
+		return m, nil
+	}
+	return m, m.resume()
+}
+
+// resume takes back up the resources released while suspended, and starts
+// watching for idleness again.
+func (m *Model) resume() tea.Cmd {
+	app, ok := m.app.(idleSuspender)
+	if !ok {
+		return nil
+	}
+	return tea.Batch(func() tea.Msg {
+		app.Resume()
+		return nil
+	}, m.idleCheck())
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/abrksh22/bplus/security"
+)
+
+// workspaceTruster is implemented by applications that restrict workspaces
+// the user has not trusted.
+type workspaceTruster interface {
+	WorkspaceTrust() (level security.TrustLevel, pending bool)
+	TrustWorkspace(trusted bool) error
+}
+
+// promptTrust opens the trust prompt if the user has not decided whether
+// to trust the workspace yet.
+func (m *Model) promptTrust() {
+	if app, ok := m.app.(workspaceTruster); ok {
+		if _, pending := app.WorkspaceTrust(); pending {
+			m.showTrust()
+		}
+	}
+}
+
+// showTrust opens the trust prompt for the workspace.
+func (m *Model) showTrust() {
+	app, ok := m.app.(workspaceTruster)
+	if !ok {
+		m.SetError(fmt.Errorf("workspace trust is not available"))
+		return
+	}
+	m.trustLevel, _ = app.WorkspaceTrust()
+	m.trustPath = ""
+	if roots, ok := m.app.(rootManager); ok {
+		if list := roots.Roots(); len(list) > 0 {
+			m.trustPath = list[0].Path
+		}
+	}
+	m.view = ViewTrust
+}
+
+// decideTrust records the user's decision and continues to chat.
+func (m *Model) decideTrust(trusted bool) {
+	if app, ok := m.app.(workspaceTruster); ok {
+		if err := app.TrustWorkspace(trusted); err != nil {
+			m.SetError(err)
+		}
+	}
+	m.view = ViewChat
+	m.promptPull()
+}
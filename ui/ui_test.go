@@ -1,10 +1,31 @@
 package ui
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/flags"
+	"github.com/abrksh22/bplus/internal/storage"
+	"github.com/abrksh22/bplus/internal/worktree"
+	"github.com/abrksh22/bplus/layers/contextmgr"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/models/router"
+	"github.com/abrksh22/bplus/prompts"
+	"github.com/abrksh22/bplus/security"
+	"github.com/abrksh22/bplus/tools"
+	"github.com/abrksh22/bplus/tools/exec"
+	"github.com/abrksh22/bplus/tools/file"
+	"github.com/abrksh22/bplus/ui/components"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -228,7 +249,8 @@ func TestView(t *testing.T) {
 			},
 			checkFn: func(t *testing.T, view string) {
 				assert.Contains(t, view, "Help")
-				assert.Contains(t, view, "Keyboard Shortcuts")
+				assert.Contains(t, view, "Global")
+				assert.Contains(t, view, "/tools")
 			},
 		},
 		{
@@ -321,6 +343,36 @@ func TestThemeNames(t *testing.T) {
 	assert.Len(t, names, 6)
 }
 
+// TestLoadThemeOverride tests themes added and overridden in an asset directory.
+func TestLoadThemeOverride(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BPLUS_ASSETS_PATH", dir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "themes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "themes", "mine.yaml"), []byte(`
+primary: "#112233"
+background: "#000000"
+foreground: "#EEEEEE"
+border: "#444444"
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "themes", "nord.yaml"), []byte("primary: [oops"), 0644))
+
+	theme, err := LoadTheme("mine")
+	require.NoError(t, err)
+	assert.Equal(t, lipgloss.Color("#112233"), theme.Primary)
+	assert.Equal(t, lipgloss.Color("#112233"), theme.InputBorder)
+	assert.Equal(t, lipgloss.Color("#444444"), theme.StatusBarBg)
+	assert.Equal(t, lipgloss.Color("#000000"), theme.ErrorFg)
+	assert.Equal(t, theme.Primary, GetThemeByName("mine").Primary)
+	assert.Equal(t, "mine", ThemeNames()[len(ThemeNames())-1])
+
+	_, err = LoadTheme("nord")
+	assert.Error(t, err)
+	assert.NotEmpty(t, NordTheme().Primary, "An invalid override falls back to the built-in theme")
+	_, err = LoadTheme("../secrets")
+	assert.Error(t, err)
+}
+
 // TestKeyBindings tests key bindings.
 func TestKeyBindings(t *testing.T) {
 	keys := DefaultKeyMap()
@@ -341,6 +393,43 @@ func TestKeyBindings(t *testing.T) {
 	assert.NotEmpty(t, fullHelp)
 }
 
+// TestHelpOverlay tests that help lists the bindings of the view it was
+// opened from and returns there when closed.
+func TestHelpOverlay(t *testing.T) {
+	reg := tools.NewRegistry()
+	m := NewWithApp(toolsApp{reg: reg})
+	m.SetSize(100, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+	assert.Equal(t, ViewHelp, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "send message")
+	assert.NotContains(t, view, "toggle favorite")
+	for _, cmd := range m.Commands() {
+		assert.Contains(t, view, "/"+cmd.Name)
+	}
+
+	m.SetFocus("output")
+	view = m.View()
+	assert.Contains(t, view, "scroll up")
+	assert.NotContains(t, view, "send message")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+
+	m.Update(UserInputMsg{Input: "/tools"})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+	view = m.View()
+	assert.Contains(t, view, "toggle")
+	assert.Contains(t, view, "back to chat")
+	assert.NotContains(t, view, "send message")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+	assert.Equal(t, ViewTools, m.CurrentView())
+}
+
 // TestMessageHelpers tests message helper functions.
 func TestMessageHelpers(t *testing.T) {
 	t.Run("NewErrorMsg", func(t *testing.T) {
@@ -403,3 +492,1464 @@ func BenchmarkView(b *testing.B) {
 		_ = m.View()
 	}
 }
+
+type offlineApp struct{}
+
+func (offlineApp) IsOffline() bool { return true }
+
+// TestOfflineBadge tests that the status bar shows the offline badge.
+func TestOfflineBadge(t *testing.T) {
+	m := New()
+	m.SetSize(100, 24)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+	assert.NotContains(t, m.View(), "OFFLINE")
+
+	m = NewWithApp(offlineApp{})
+	m.SetSize(100, 24)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+	assert.Contains(t, m.View(), "OFFLINE")
+}
+
+type toolsApp struct {
+	reg *tools.Registry
+}
+
+func (a toolsApp) GetToolRegistry() *tools.Registry { return a.reg }
+
+// TestToolsView tests the /tools command and toggling tools.
+func TestToolsView(t *testing.T) {
+	reg := tools.NewRegistry()
+	require.NoError(t, reg.Register(exec.NewBashTool()))
+	require.NoError(t, reg.Register(file.NewReadTool()))
+
+	m := NewWithApp(toolsApp{reg: reg})
+	m.SetSize(100, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/tools"})
+	assert.Equal(t, ViewTools, m.CurrentView())
+	assert.Contains(t, m.View(), "core.bash")
+
+	// First entry (core.bash) is selected; toggle it off
+	m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	assert.False(t, reg.IsEnabled("core.bash"))
+	assert.Contains(t, m.View(), "[off]")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+
+	m.Update(UserInputMsg{Input: "/tools enable bash"})
+	assert.True(t, reg.IsEnabled("core.bash"))
+	m.Update(UserInputMsg{Input: "/tools disable read"})
+	assert.False(t, reg.IsEnabled("core.read"))
+
+	m.Update(UserInputMsg{Input: "/nope"})
+	assert.Error(t, m.Error())
+}
+
+type eventsApp struct {
+	bus *events.Bus
+}
+
+func (a eventsApp) GetEventBus() *events.Bus { return a.bus }
+
+// TestAppEvents tests that bus events drive the status bar.
+func TestAppEvents(t *testing.T) {
+	bus := events.NewBus()
+	m := NewWithApp(eventsApp{bus: bus})
+	m.SetSize(120, 24)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	cmd := m.Init()
+	require.NotNil(t, cmd)
+
+	bus.Publish(events.ToolStarted{Tool: "core.bash"})
+	_, cmd = m.Update(cmd())
+	require.NotNil(t, cmd, "model should keep listening for events")
+	assert.Contains(t, m.View(), "core.bash")
+
+	bus.Publish(events.CostUpdated{TotalCost: 1.25, TotalTokens: 4200})
+	_, cmd = m.Update(cmd())
+	view := m.View()
+	assert.Contains(t, view, "$1.25")
+	assert.Contains(t, view, "4200")
+	assert.NotContains(t, view, "reasoning")
+
+	bus.Publish(events.CostUpdated{TotalCost: 1.5, TotalTokens: 5000, ReasoningTokens: 800, ReasoningCost: 0.4})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "Cost: $1.50 ($0.40 reasoning) | Tokens: 5000 (800 reasoning)")
+
+	bus.Publish(events.ToolFinished{Tool: "core.bash", Success: true})
+	_, cmd = m.Update(cmd())
+	assert.NotContains(t, m.View(), "core.bash")
+
+	bus.Publish(events.RateLimitUpdated{Host: "api.anthropic.com", RequestsRemaining: -1, BlockedUntil: time.Now().Add(30 * time.Second), Queued: 2})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "rate limited")
+	assert.Contains(t, m.View(), "2 queued")
+
+	bus.Publish(events.RateLimitUpdated{Host: "api.anthropic.com", RequestsLimit: 50, RequestsRemaining: 3})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "3/50 requests left")
+
+	bus.Publish(events.RateLimitUpdated{Host: "api.anthropic.com", RequestsLimit: 50, RequestsRemaining: 40})
+	_, cmd = m.Update(cmd())
+	assert.NotContains(t, m.View(), "requests left")
+
+	bus.Publish(events.ModelLoadChanged{Model: "ollama/qwen2.5-coder:7b", State: events.ModelLoading})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "loading qwen2.5-coder:7b")
+
+	bus.Publish(events.ModelLoadChanged{Model: "lmstudio/qwen2.5-coder-7b", State: events.ModelLoading, Progress: 0.42})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "loading qwen2.5-coder-7b 42%")
+
+	bus.Publish(events.ModelLoadChanged{Model: "ollama/qwen2.5-coder:7b", State: events.ModelFailed, Error: "connection refused"})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "qwen2.5-coder:7b failed to load")
+
+	bus.Publish(events.ModelLoadChanged{Model: "ollama/qwen2.5-coder:7b", State: events.ModelReady})
+	_, cmd = m.Update(cmd())
+	assert.NotContains(t, m.View(), "qwen2.5-coder:7b")
+
+	bus.Publish(events.LayerDegraded{Layer: "validation", Configured: "openai/gpt-4-turbo", Skipped: true})
+	m.Update(cmd())
+	assert.Contains(t, m.View(), "1 layer(s) degraded")
+}
+
+type modelsApp struct {
+	list    []models.ModelPerformance
+	current string
+}
+
+func (a *modelsApp) ListModelPerformance(ctx context.Context) ([]models.ModelPerformance, error) {
+	return a.list, nil
+}
+
+func (a *modelsApp) SetModel(name string) error {
+	a.current = name
+	return nil
+}
+
+// TestModelsView tests the /models picker.
+func TestModelsView(t *testing.T) {
+	app := &modelsApp{list: []models.ModelPerformance{
+		{Model: "ollama/llama3", Current: true, PerformanceStats: models.PerformanceStats{
+			Samples: 3, AvgTimeToFirstToken: 250 * time.Millisecond, AvgTokensPerSecond: 42.5,
+		}},
+		{Model: "ollama/qwen2.5-coder", Aliases: []string{"local"}},
+	}}
+
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	_, cmd := m.Update(UserInputMsg{Input: "/models"})
+	assert.Equal(t, ViewModels, m.CurrentView())
+	assert.Contains(t, m.View(), "Loading models")
+	require.NotNil(t, cmd)
+
+	m.Update(cmd())
+	view := m.View()
+	assert.Contains(t, view, "TTFT 250ms · 42.5 tok/s (n=3)")
+	assert.Contains(t, view, "no data")
+	assert.Contains(t, view, "ollama/qwen2.5-coder (local)")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "ollama/qwen2.5-coder", app.current)
+	assert.Equal(t, ViewChat, m.CurrentView())
+
+	// A name given with the command switches without the picker
+	m.Update(UserInputMsg{Input: "/models llama"})
+	assert.Equal(t, "llama", app.current)
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+// TestTurnStats tests the per-turn cost footer.
+func TestTurnStats(t *testing.T) {
+	bus := events.NewBus()
+	m := NewWithApp(eventsApp{bus: bus})
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	cmd := m.Init()
+	m.Update(UserInputMsg{Input: "fix the build"})
+
+	bus.Publish(events.CostUpdated{Model: "claude-sonnet-4-5", InputTokens: 1000, OutputTokens: 200, Cost: 0.01})
+	_, cmd = m.Update(cmd())
+	bus.Publish(events.ToolFinished{Tool: "core.bash", Success: true})
+	_, cmd = m.Update(cmd())
+	bus.Publish(events.CostUpdated{Model: "claude-sonnet-4-5", InputTokens: 1500, OutputTokens: 100, Cost: 0.0123})
+	_, cmd = m.Update(cmd())
+	// Reconciled cost carries no tokens and is not added to the turn
+	bus.Publish(events.CostUpdated{Model: "claude-sonnet-4-5", Cost: 0.5})
+	m.Update(cmd())
+
+	m.Update(StreamTokenMsg{Token: "Fixed the import."})
+	m.Update(StreamTokenMsg{Done: true})
+	view := m.View()
+	assert.Contains(t, view, "Fixed the import.")
+	assert.Contains(t, view, "claude-sonnet-4-5 · 2500↑ 300↓ tokens · $0.0223")
+	assert.Contains(t, view, "1 tool")
+
+	// The next turn's answer gets its own stats, and the first keeps its
+	m.Update(UserInputMsg{Input: "now run the tests"})
+	m.Update(StreamTokenMsg{Token: "All tests pass."})
+	m.Update(StreamTokenMsg{Done: true})
+	messages := m.output.GetMessages()
+	require.Len(t, messages, 4)
+	assert.Equal(t, 0.0223, messages[1].Turn.Cost)
+	require.NotNil(t, messages[3].Turn)
+	assert.Zero(t, messages[3].Turn.Cost)
+}
+
+func TestTruncatedNotice(t *testing.T) {
+	bus := events.NewBus()
+	m := NewWithApp(eventsApp{bus: bus})
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	cmd := m.Init()
+	m.Update(UserInputMsg{Input: "write the migration guide"})
+	bus.Publish(events.AnswerTruncated{Model: "claude-sonnet-4-5", Continuations: 3})
+	m.Update(cmd())
+	m.Update(StreamTokenMsg{Token: "## Migrating from v1"})
+	m.Update(StreamTokenMsg{Done: true})
+
+	messages := m.output.GetMessages()
+	require.Len(t, messages, 3)
+	assert.Equal(t, "system", messages[2].Role)
+	assert.Equal(t, "The answer above was cut off at the token limit after 3 continuations. Ask to continue where it stopped.", messages[2].Content)
+
+	// The next complete answer has no notice
+	m.Update(UserInputMsg{Input: "thanks"})
+	m.Update(StreamTokenMsg{Token: "You're welcome."})
+	m.Update(StreamTokenMsg{Done: true})
+	assert.Len(t, m.output.GetMessages(), 5)
+}
+
+// elevatedApp is an application that denies elevated tool calls unless
+// the next one is allowed.
+type elevatedApp struct {
+	eventsApp
+	allowed int
+}
+
+func (a *elevatedApp) AllowElevatedOnce() { a.allowed++ }
+
+func TestAllowElevated(t *testing.T) {
+	bus := events.NewBus()
+	app := &elevatedApp{eventsApp: eventsApp{bus: bus}}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	cmd := m.Init()
+
+	request := events.PermissionRequested{Tool: "core.bash", Permission: "execute", Elevated: true, Reason: "command prints .env, which may contain credentials"}
+	bus.Publish(request)
+	_, cmd = m.Update(cmd())
+	messages := m.output.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Denied core.bash: command prints .env, which may contain credentials. Run /allow-elevated to allow the next such call, then ask again.", messages[0].Content)
+
+	m.Update(UserInputMsg{Input: "/allow-elevated"})
+	assert.Equal(t, 1, app.allowed)
+	bus.Publish(request)
+	_, cmd = m.Update(cmd())
+	messages = m.output.GetMessages()
+	require.Len(t, messages, 3)
+	assert.Equal(t, "Allowed core.bash once: command prints .env, which may contain credentials.", messages[2].Content)
+
+	// The approval is used up
+	bus.Publish(request)
+	m.Update(cmd())
+	messages = m.output.GetMessages()
+	require.Len(t, messages, 4)
+	assert.True(t, strings.HasPrefix(messages[3].Content, "Denied core.bash"))
+}
+
+// uiConfigApp is an application with a configuration.
+type uiConfigApp struct {
+	cfg *config.Config
+}
+
+func (a uiConfigApp) GetConfig() *config.Config { return a.cfg }
+
+// TestTurnStats_ShowCost tests that ui.show_cost hides the stats in the
+// chat view but not in exports.
+func TestTurnStats_ShowCost(t *testing.T) {
+	m := NewWithApp(uiConfigApp{cfg: &config.Config{UI: config.UIConfig{ShowCost: false}}})
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "fix the build"})
+	m.turn = components.TurnStats{Model: "gpt-4o", InputTokens: 10, OutputTokens: 5, Cost: 0.0042}
+	m.Update(StreamTokenMsg{Token: "Fixed."})
+	m.Update(StreamTokenMsg{Done: true})
+	assert.Contains(t, m.View(), "Fixed.")
+	assert.NotContains(t, m.View(), "$0.0042")
+
+	path := filepath.Join(t.TempDir(), "conversation.md")
+	m.Update(UserInputMsg{Input: "/export " + path})
+	require.NoError(t, m.Error())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "## You")
+	assert.Contains(t, string(data), "Fixed.")
+	assert.Contains(t, string(data), "_gpt-4o · 10↑ 5↓ tokens · $0.0042")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	assert.Contains(t, m.View(), "Conversation exported to "+path)
+}
+
+func TestExportConversation_Empty(t *testing.T) {
+	m := New()
+	m.Update(UserInputMsg{Input: "/export"})
+	assert.EqualError(t, m.Error(), "no conversation to export yet")
+}
+
+type optimizeApp struct {
+	plan      contextmgr.Plan
+	optimized bool
+}
+
+func (a *optimizeApp) PreviewOptimization() contextmgr.Plan {
+	return a.plan
+}
+
+func (a *optimizeApp) OptimizeContext() (contextmgr.Plan, error) {
+	a.optimized = true
+	return a.plan, nil
+}
+
+// TestOptimizeView tests the /optimize preview and confirmation.
+func TestOptimizeView(t *testing.T) {
+	app := &optimizeApp{plan: contextmgr.Plan{
+		Prunes:       []contextmgr.Prune{{Index: 2, Role: "tool", Name: "core.bash", TokensBefore: 5000, TokensAfter: 60}},
+		TokensBefore: 9000,
+		TokensAfter:  4060,
+	}}
+
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/optimize"})
+	assert.Equal(t, ViewOptimize, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "freeing ~4940 tokens")
+	assert.Contains(t, view, "core.bash")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.False(t, app.optimized, "cancel must not prune")
+
+	m.Update(UserInputMsg{Input: "/optimize"})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.True(t, app.optimized)
+}
+
+type contextApp []contextmgr.Item
+
+func (a contextApp) ContextItems() []contextmgr.Item {
+	return a
+}
+
+// TestContextView tests the /context inspector.
+func TestContextView(t *testing.T) {
+	app := contextApp{
+		{Index: 0, Role: "user", Tokens: 12, Provenance: contextmgr.Provenance{Source: contextmgr.SourceUser, Trust: contextmgr.TrustHigh}},
+		{Index: 1, Role: "tool", Name: "webfetch", Tokens: 900, Provenance: contextmgr.Provenance{Source: contextmgr.SourceWeb, Origin: "webfetch", Trust: contextmgr.TrustLow}},
+	}
+
+	m := NewWithApp(app)
+	m.SetSize(140, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/context"})
+	assert.Equal(t, ViewContext, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "web (webfetch)")
+	assert.Contains(t, view, "low")
+	assert.Contains(t, view, "1 low-trust")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+type rootsApp struct {
+	roots []security.Root
+}
+
+func (a *rootsApp) Roots() []security.Root { return a.roots }
+
+func (a *rootsApp) AttachRoot(spec string) (security.Root, error) {
+	root, err := security.ParseRoot(spec)
+	if err != nil {
+		return security.Root{}, err
+	}
+	if root.Permissions == nil {
+		root.Permissions = []security.Permission{security.PermissionRead, security.PermissionWrite, security.PermissionExecute}
+	}
+	a.roots = append(a.roots, root)
+	return root, nil
+}
+
+func (a *rootsApp) DetachRoot(name string) error {
+	for i, root := range a.roots {
+		if root.Name == name {
+			a.roots = append(a.roots[:i], a.roots[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("no root named " + name)
+}
+
+// TestRootsView tests attaching and detaching roots with /roots.
+func TestRootsView(t *testing.T) {
+	app := &rootsApp{roots: []security.Root{
+		{Name: "backend", Path: "/src/backend", Permissions: []security.Permission{security.PermissionRead, security.PermissionWrite, security.PermissionExecute}},
+	}}
+
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/roots add infra=/src/infra:ro"})
+	assert.Equal(t, ViewRoots, m.CurrentView())
+	require.Len(t, app.roots, 2)
+	view := m.View()
+	assert.Contains(t, view, "/src/backend")
+	assert.Contains(t, view, "(primary)")
+	assert.Contains(t, view, "read only")
+	assert.Contains(t, view, "Attached infra (read only)")
+	assert.Contains(t, view, "infra:README.md")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+
+	m.Update(UserInputMsg{Input: "/roots remove infra"})
+	assert.Len(t, app.roots, 1)
+	assert.Contains(t, m.View(), "Detached infra")
+
+	m.Update(UserInputMsg{Input: "/roots remove missing"})
+	assert.Error(t, m.err)
+}
+
+type providersApp struct {
+	health   []models.ProviderHealth
+	checking bool
+	tests    int
+}
+
+func (a *providersApp) ProviderHealth() ([]models.ProviderHealth, bool) {
+	return a.health, a.checking
+}
+
+func (a *providersApp) TestProviders() {
+	a.tests++
+	a.checking = true
+}
+
+// TestProvidersView tests showing provider health with /providers and
+// re-testing from the view.
+func TestProvidersView(t *testing.T) {
+	app := &providersApp{health: []models.ProviderHealth{
+		{Provider: "anthropic", Latency: 180 * time.Millisecond, Auth: models.AuthOK, CheckedAt: time.Now()},
+		{Provider: "openai", Auth: models.AuthFailed, Err: errors.New("OPENAI_API_KEY not set"), CheckedAt: time.Now()},
+		{Provider: "vllm", Latency: 4 * time.Millisecond, Auth: models.AuthOK, Load: &models.ServerLoad{RequestsRunning: 3, RequestsWaiting: 7, KVCacheUsage: 0.42}, CheckedAt: time.Now()},
+	}}
+
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/providers"})
+	assert.Equal(t, ViewProviders, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "anthropic")
+	assert.Contains(t, view, "180ms")
+	assert.Contains(t, view, "OPENAI_API_KEY not set")
+	assert.Contains(t, view, "failed")
+	assert.Contains(t, view, "load: 3 running, 7 waiting, KV 42%")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	assert.Equal(t, 1, app.tests)
+	assert.Contains(t, m.View(), "Re-testing...")
+
+	// Results are reloaded when the tests finish
+	app.health[1] = models.ProviderHealth{Provider: "openai", Latency: 90 * time.Millisecond, Auth: models.AuthOK, CheckedAt: time.Now()}
+	app.checking = false
+	m.Update(AppEventMsg{Event: events.ProvidersChecked{Healthy: 2, Total: 2}})
+	view = m.View()
+	assert.NotContains(t, view, "Re-testing...")
+	assert.NotContains(t, view, "OPENAI_API_KEY not set")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+// TestLargeRepoWarning tests the warning shown when the project is too
+// large to explore cheaply.
+func TestLargeRepoWarning(t *testing.T) {
+	m := New()
+	m.SetSize(160, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+	assert.NotContains(t, m.View(), "Large repository")
+
+	m.Update(AppEventMsg{Event: events.RepositoryScanned{
+		Files:       48210,
+		Tokens:      23400000,
+		TurnTokens:  61000,
+		TurnCost:    0.198,
+		Model:       "anthropic/claude-sonnet-4-5",
+		LargestDirs: []string{"services", "web"},
+		LocalModel:  "ollama/qwen2.5-coder:7b",
+	}})
+	view := m.View()
+	assert.Contains(t, view, "Large repository (48210 files, ~23.4M tokens to index)")
+	assert.Contains(t, view, "~61k tokens (~$0.20 with claude-sonnet-4-5)")
+	assert.Contains(t, view, "subdirectory such as services, web")
+	assert.Contains(t, view, "/models ollama/qwen2.5-coder:7b")
+}
+
+// TestSignInNotice tests the device code shown while a provider gateway
+// waits for the user to sign in.
+func TestSignInNotice(t *testing.T) {
+	m := New()
+	m.SetSize(160, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(AppEventMsg{Event: events.SignInRequested{
+		Provider:        "openai",
+		UserCode:        "ABCD-EFGH",
+		VerificationURI: "https://microsoft.com/devicelogin",
+		ExpiresAt:       time.Now().Add(15 * time.Minute),
+	}})
+	assert.Contains(t, m.View(), "Sign in to openai: visit https://microsoft.com/devicelogin and enter code ABCD-EFGH")
+
+	// Gone once the provider answers
+	m.Update(AppEventMsg{Event: events.CostUpdated{Model: "openai/gpt-4o"}})
+	assert.NotContains(t, m.View(), "ABCD-EFGH")
+}
+
+type trustApp struct {
+	level     security.TrustLevel
+	decisions []bool
+}
+
+func (a *trustApp) WorkspaceTrust() (security.TrustLevel, bool) {
+	return a.level, a.level == security.TrustUnknown
+}
+
+func (a *trustApp) TrustWorkspace(trusted bool) error {
+	a.decisions = append(a.decisions, trusted)
+	a.level = security.TrustRestricted
+	if trusted {
+		a.level = security.TrustTrusted
+	}
+	return nil
+}
+
+// TestTrustPrompt tests asking whether to trust an unknown workspace when
+// the session starts, and changing the decision with /trust.
+func TestTrustPrompt(t *testing.T) {
+	app := &trustApp{}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewTrust, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "Trust this workspace?")
+	assert.Contains(t, view, "read only")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.Equal(t, []bool{false}, app.decisions)
+
+	m.Update(UserInputMsg{Input: "/trust"})
+	assert.Equal(t, ViewTrust, m.CurrentView())
+	assert.Contains(t, m.View(), "Currently restricted")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.Equal(t, []bool{false, true}, app.decisions)
+
+	// A decided workspace is not prompted for
+	m = NewWithApp(app)
+	m.SetReady(true)
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+type pullApp struct {
+	pulled []string
+}
+
+func (a *pullApp) PullModel(name string) error {
+	a.pulled = append(a.pulled, name)
+	return nil
+}
+
+// TestPullPrompt tests offering to pull a model that isn't installed and
+// showing the download progress.
+func TestPullPrompt(t *testing.T) {
+	app := &pullApp{}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+
+	// Reported before the chat is shown, offered once it is
+	m.Update(AppEventMsg{Event: events.ModelLoadChanged{Model: "ollama/llama3.2", State: events.ModelMissing}})
+	assert.Equal(t, ViewStartup, m.CurrentView())
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewPull, m.CurrentView())
+	assert.Contains(t, m.View(), "ollama/llama3.2 is not installed")
+
+	// Declined, it stays in the status bar until pulled with /pull
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.Contains(t, m.View(), "llama3.2 not installed (/pull)")
+	assert.Empty(t, app.pulled)
+
+	m.Update(UserInputMsg{Input: "/pull"})
+	assert.Equal(t, ViewPull, m.CurrentView())
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.Equal(t, []string{"ollama/llama3.2"}, app.pulled)
+
+	m.Update(AppEventMsg{Event: events.ModelLoadChanged{Model: "ollama/llama3.2", State: events.ModelPulling, Progress: 0.42}})
+	assert.Contains(t, m.View(), "pulling llama3.2 42%")
+
+	m.Update(UserInputMsg{Input: "/pull qwen2.5-coder"})
+	assert.Equal(t, []string{"ollama/llama3.2", "qwen2.5-coder"}, app.pulled)
+}
+
+// TestDraftAnswer tests showing a draft answer until the real one
+// replaces it.
+func TestDraftAnswer(t *testing.T) {
+	m := New()
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(AppEventMsg{Event: events.DraftStreamed{Model: "anthropic/claude-haiku-4-0", Content: "Go channels are typed conduits"}})
+	view := m.View()
+	assert.Contains(t, view, "Draft from claude-haiku-4-0")
+	assert.Contains(t, view, "Go channels are typed conduits")
+
+	m.Update(AppEventMsg{Event: events.DraftReplaced{Model: "anthropic/claude-haiku-4-0"}})
+	assert.NotContains(t, m.View(), "Go channels are typed conduits")
+
+	// Long drafts show their end
+	var long []string
+	for i := 1; i <= 30; i++ {
+		long = append(long, fmt.Sprintf("line %d", i))
+	}
+	m.Update(AppEventMsg{Event: events.DraftStreamed{Model: "openai/gpt-4o-mini", Content: strings.Join(long, "\n"), Done: true}})
+	draft := m.draftAnswer()
+	assert.Contains(t, draft, "still working")
+	assert.Contains(t, draft, "line 30")
+	assert.NotContains(t, draft, "line 2\n")
+}
+
+func TestTaskList(t *testing.T) {
+	m := New()
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+	assert.Empty(t, m.taskList())
+
+	m.Update(AppEventMsg{Event: events.TodosUpdated{Todos: []events.Todo{
+		{ID: 1, Content: "Read the parser", Status: "completed"},
+		{ID: 2, Content: "Fix the off-by-one", Status: "in_progress"},
+		{ID: 3, Content: "Add tests", Status: "pending"},
+	}}})
+	view := m.View()
+	assert.Contains(t, view, "Tasks (1/3 done)")
+	assert.Contains(t, view, "✓ Read the parser")
+	assert.Contains(t, view, "◐ Fix the off-by-one")
+	assert.Contains(t, view, "○ Add tests")
+
+	// Long lists leave out the earliest completed items
+	var todos []events.Todo
+	for i := 1; i <= 14; i++ {
+		status := "pending"
+		if i <= 6 {
+			status = "completed"
+		}
+		todos = append(todos, events.Todo{ID: int64(i), Content: fmt.Sprintf("step %d", i), Status: status})
+	}
+	m.Update(AppEventMsg{Event: events.TodosUpdated{Todos: todos}})
+	list := m.taskList()
+	assert.Contains(t, list, "Tasks (6/14 done)")
+	assert.Contains(t, list, "… 4 earlier completed")
+	assert.NotContains(t, list, "✓ step 4")
+	assert.Contains(t, list, "✓ step 5")
+	assert.Contains(t, list, "○ step 14")
+}
+
+func TestExternalChanges(t *testing.T) {
+	m := New()
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+	assert.Empty(t, m.externalChanges())
+
+	root, err := os.Getwd()
+	require.NoError(t, err)
+	m.Update(AppEventMsg{Event: events.FileChanged{Path: filepath.Join(root, "main.go"), Kind: "modified"}})
+	m.Update(AppEventMsg{Event: events.FileChanged{Path: "/elsewhere/old.go", Kind: "deleted"}})
+	m.Update(AppEventMsg{Event: events.FileChanged{Path: filepath.Join(root, "main.go"), Kind: "modified"}})
+	assert.Equal(t, "✎ Changed outside b+: /elsewhere/old.go (deleted), main.go", m.externalChanges())
+	assert.Contains(t, m.View(), "Changed outside b+")
+
+	for i := range 7 {
+		m.Update(AppEventMsg{Event: events.FileChanged{Path: filepath.Join(root, fmt.Sprintf("gen%d.go", i)), Kind: "created"}})
+	}
+	assert.True(t, strings.HasSuffix(m.externalChanges(), "gen6.go (created) and 4 more"), m.externalChanges())
+
+	// A new prompt clears them
+	m.startTurn()
+	assert.Empty(t, m.externalChanges())
+}
+
+type redactApp struct {
+	redacted []string
+}
+
+func (a *redactApp) PreviewRedaction(target string) (int, int, error) {
+	if target == "#99" {
+		return 0, 0, errors.New("message 99 not found")
+	}
+	return 2, 1, nil
+}
+
+func (a *redactApp) Redact(target string) (int, int, error) {
+	a.redacted = append(a.redacted, target)
+	return 2, 1, nil
+}
+
+// TestRedactView tests previewing and confirming /redact.
+func TestRedactView(t *testing.T) {
+	app := &redactApp{}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/redact sk-[a-z0-9]+"})
+	assert.Equal(t, ViewRedact, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "2 stored message(s) and 1 context message(s)")
+	assert.Contains(t, view, "cannot be undone")
+
+	// Cancelling leaves everything as it is
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.Empty(t, app.redacted)
+
+	m.Update(UserInputMsg{Input: "/redact sk-[a-z0-9]+"})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.Equal(t, []string{"sk-[a-z0-9]+"}, app.redacted)
+
+	m.Update(UserInputMsg{Input: "/redact #99"})
+	assert.Equal(t, ViewChat, m.CurrentView())
+	assert.Error(t, m.Error())
+}
+
+type runsApp struct {
+	runs     []*storage.CommandRun
+	reran    []int64
+	favorite map[int64]bool
+}
+
+func (a *runsApp) CommandRuns(limit int) ([]*storage.CommandRun, error) {
+	return a.runs, nil
+}
+
+func (a *runsApp) RerunCommand(ctx context.Context, id int64) (*storage.CommandRun, *tools.Result, error) {
+	a.reran = append(a.reran, id)
+	return &storage.CommandRun{ID: 3, Command: "go test ./...", ExitCode: 1, Duration: 1200 * time.Millisecond}, &tools.Result{}, nil
+}
+
+func (a *runsApp) SetRunFavorite(id int64, favorite bool) error {
+	a.favorite[id] = favorite
+	return nil
+}
+
+// TestRunsView tests the /runs history, re-running and favorites.
+func TestRunsView(t *testing.T) {
+	app := &runsApp{
+		runs: []*storage.CommandRun{
+			{ID: 2, Command: "go test ./...", ExitCode: 1},
+			{ID: 1, Command: "go build ./...", ExitCode: 0},
+		},
+		favorite: map[int64]bool{},
+	}
+
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	_, cmd := m.Update(UserInputMsg{Input: "/runs"})
+	assert.Equal(t, ViewRuns, m.CurrentView())
+	require.NotNil(t, cmd)
+	m.Update(cmd())
+	view := m.View()
+	assert.Contains(t, view, "#2")
+	assert.Contains(t, view, "go build ./...")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	assert.True(t, app.favorite[1])
+	assert.Contains(t, m.View(), "★")
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	m.Update(cmd())
+	assert.Equal(t, []int64{1}, app.reran)
+	assert.Contains(t, m.View(), "#1 → #3 exited 1 in 1.2s")
+
+	// "/runs 2" re-runs directly
+	_, cmd = m.Update(UserInputMsg{Input: "/runs #2"})
+	require.NotNil(t, cmd)
+	m.Update(cmd())
+	assert.Equal(t, []int64{1, 2}, app.reran)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+type paletteApp struct {
+	toolsApp
+	sessions []*storage.Session
+	files    []string
+	resumed  string
+	attached string
+}
+
+func (a *paletteApp) Sessions() ([]*storage.Session, error) { return a.sessions, nil }
+
+func (a *paletteApp) ResumeSession(ctx context.Context, id string) error {
+	a.resumed = id
+	return nil
+}
+
+func (a *paletteApp) ProjectFiles(ctx context.Context) ([]string, error) { return a.files, nil }
+
+func (a *paletteApp) AttachFile(path string) error {
+	a.attached = path
+	return nil
+}
+
+// typeKeys sends text to the model one key at a time.
+func typeKeys(m *Model, text string) {
+	for _, r := range text {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+// openPaletteKeys opens the command palette with Ctrl+K and delivers the
+// sessions and files it loads.
+func openPaletteKeys(t *testing.T, m *Model) {
+	t.Helper()
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlK})
+	require.Equal(t, ViewPalette, m.CurrentView())
+	if cmd != nil {
+		m.Update(cmd())
+	}
+}
+
+// TestCommandPalette tests that the palette fuzzy-searches commands,
+// settings, sessions and files together and runs the selection.
+func TestCommandPalette(t *testing.T) {
+	app := &paletteApp{
+		toolsApp: toolsApp{reg: tools.NewRegistry()},
+		sessions: []*storage.Session{{ID: "s1", Name: "refactor parser", UpdatedAt: time.Now()}},
+		files:    []string{"internal/config/loader.go", "ui/palette.go"},
+	}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	openPaletteKeys(t, m)
+	view := m.View()
+	assert.Contains(t, view, "/context")
+	var titles []string
+	for _, item := range m.paletteMatches() {
+		titles = append(titles, item.Title)
+	}
+	assert.Contains(t, titles, "Open settings")
+
+	// Typed text, including "?", goes to the query
+	typeKeys(m, "?")
+	assert.Equal(t, ViewPalette, m.CurrentView())
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+
+	typeKeys(m, "cfgload")
+	require.NotEmpty(t, m.paletteMatches())
+	assert.Equal(t, "internal/config/loader.go", m.paletteMatches()[0].Title)
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "internal/config/loader.go", app.attached)
+	assert.Equal(t, ViewChat, m.CurrentView())
+
+	openPaletteKeys(t, m)
+	typeKeys(m, "refpar")
+	assert.Contains(t, m.View(), "refactor parser")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "s1", app.resumed)
+
+	openPaletteKeys(t, m)
+	typeKeys(m, "theme nord")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, NordTheme(), m.Theme())
+
+	openPaletteKeys(t, m)
+	typeKeys(m, "tools")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, ViewTools, m.CurrentView())
+
+	// Closing returns to the view the palette was opened from
+	openPaletteKeys(t, m)
+	typeKeys(m, "zzzz")
+	assert.Contains(t, m.View(), "No matches")
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewTools, m.CurrentView())
+}
+
+// TestFuzzyScore tests palette match ranking.
+func TestFuzzyScore(t *testing.T) {
+	_, ok := fuzzyScore("xyz", "/tools")
+	assert.False(t, ok)
+
+	initials, ok := fuzzyScore("op", "/optimize")
+	require.True(t, ok)
+	scattered, ok := fuzzyScore("op", "/models open")
+	require.True(t, ok)
+	assert.Greater(t, initials, scattered)
+
+	short, _ := fuzzyScore("run", "/runs")
+	long, _ := fuzzyScore("run", "/runs-everything")
+	assert.Greater(t, short, long)
+}
+
+type configApp struct {
+	toolsApp
+	settings []config.Setting
+}
+
+func (a *configApp) ConfigSettings() []config.Setting { return a.settings }
+
+// TestConfigView tests that /config lists the effective values with their
+// sources and only offers editing for values set in a file.
+func TestConfigView(t *testing.T) {
+	app := &configApp{
+		toolsApp: toolsApp{reg: tools.NewRegistry()},
+		settings: []config.Setting{
+			{Key: "logging.level", Value: "debug", Source: config.SourceUserFile, Origin: "/home/u/.config/bplus/config.yaml", Line: 3},
+			{Key: "mode", Value: "thorough", Source: config.SourceFlag, Origin: "--thorough"},
+			{Key: "providers.anthropic.api_key", Value: "sk-a****1234", Secret: true, Source: config.SourceEnv, Origin: "ANTHROPIC_API_KEY"},
+		},
+	}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.runSlashCommand("/config")
+	require.Equal(t, ViewConfig, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "user file (/hom")
+	assert.Contains(t, view, "config.yaml:3)")
+	assert.Contains(t, view, "flag (--thorough)")
+	assert.Contains(t, view, "sk-a****1234")
+
+	// Values not set in a file have nothing to edit
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	assert.Nil(t, cmd)
+	assert.Contains(t, m.View(), "mode is set by flag (--thorough), not a config file")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	assert.NotNil(t, cmd)
+
+	// Refreshing after an edit keeps the selection
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(ConfigEditedMsg{Path: "/home/u/.config/bplus/config.yaml"})
+	assert.Equal(t, 1, m.configCursor)
+	assert.Contains(t, m.View(), "Saved /home/u/.config/bplus/config.yaml")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+type finishApp struct {
+	actions []string
+}
+
+func (a *finishApp) Worktree(ctx context.Context) (*worktree.Worktree, *worktree.Changes, error) {
+	return &worktree.Worktree{Branch: "feature", Base: "main"},
+		&worktree.Changes{Files: 1, Insertions: 2, Stat: " app.go | 2 ++", Diff: "+func New() {}"}, nil
+}
+
+func (a *finishApp) TestCommand(w *worktree.Worktree) string {
+	return "go test ./..."
+}
+
+func (a *finishApp) TestMerge(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) (*worktree.TestResult, error) {
+	a.actions = append(a.actions, "test")
+	return &worktree.TestResult{Command: "go test ./...", Output: "--- FAIL: TestNew"}, nil
+}
+
+func (a *finishApp) SquashMerge(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) error {
+	a.actions = append(a.actions, "merge")
+	return nil
+}
+
+func (a *finishApp) PushWorktree(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) error {
+	a.actions = append(a.actions, "push")
+	return fmt.Errorf("no remote origin")
+}
+
+func (a *finishApp) DiscardWorktree(ctx context.Context, w *worktree.Worktree) error {
+	a.actions = append(a.actions, "discard")
+	return nil
+}
+
+// TestFinishWorktree tests reviewing, testing and merging the work of a
+// worktree with /finish.
+func TestFinishWorktree(t *testing.T) {
+	app := &finishApp{}
+	m := NewWithApp(app)
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	// Each step runs in the background; its message is fed back in
+	update := func(msg tea.Msg) {
+		for _, cmd := m.Update(msg); cmd != nil; {
+			msg := cmd()
+			if _, ok := msg.(tea.QuitMsg); ok {
+				return
+			}
+			_, cmd = m.Update(msg)
+		}
+	}
+	press := func(k string) {
+		update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+
+	update(UserInputMsg{Input: "/finish"})
+	assert.Equal(t, ViewFinish, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "feature → main")
+	assert.Contains(t, view, "app.go | 2 ++")
+	assert.Contains(t, view, "+func New() {}")
+	assert.Contains(t, view, "go test ./... failed on the merge result")
+	assert.Contains(t, view, "--- FAIL: TestNew")
+	assert.Equal(t, []string{"test"}, app.actions)
+
+	press("p")
+	assert.Contains(t, m.View(), "no remote origin")
+	press("s")
+	assert.Contains(t, m.View(), "Squash-merged into main")
+
+	// Discarding takes a second press
+	press("d")
+	assert.Contains(t, m.View(), "Press d again")
+	press("x")
+	assert.NotContains(t, m.View(), "Press d again")
+	assert.Equal(t, []string{"test", "push", "merge"}, app.actions)
+	press("d")
+	press("d")
+	assert.Equal(t, []string{"test", "push", "merge", "discard"}, app.actions)
+}
+
+type flagsApp struct {
+	set *flags.Set
+}
+
+func (a *flagsApp) FeatureFlags() []flags.State {
+	return a.set.States()
+}
+
+func (a *flagsApp) ToggleFeatureFlag(name string, enabled bool) error {
+	return a.set.Toggle(name, enabled)
+}
+
+// TestFlagsView tests listing experimental flags and toggling them.
+func TestFlagsView(t *testing.T) {
+	set, _ := flags.New([]flags.Flag{
+		{Name: "http_server", Description: "Serve the agent over HTTP", Stage: flags.StageExperimental},
+		{Name: "old_router", Description: "Legacy routing", Stage: flags.StageDeprecated, Deprecation: "routing is always on", Default: true},
+	}, nil, nil)
+	app := &flagsApp{set: set}
+	m := NewWithApp(app)
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/flags"})
+	assert.Equal(t, ViewFlags, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "Serve the agent over HTTP")
+	assert.Contains(t, view, "routing is always on")
+
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	assert.True(t, set.Enabled("http_server"))
+	assert.Contains(t, m.View(), "session")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	assert.False(t, set.Enabled("old_router"))
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+type suspendApp struct {
+	idle    time.Duration
+	actions []string
+}
+
+func (a *suspendApp) IdleSuspendAfter() time.Duration { return a.idle }
+func (a *suspendApp) Suspend()                        { a.actions = append(a.actions, "suspend") }
+func (a *suspendApp) Resume()                         { a.actions = append(a.actions, "resume") }
+
+// TestIdleSuspend tests suspending an idle session and resuming it on a key
+// press.
+func TestIdleSuspend(t *testing.T) {
+	app := &suspendApp{idle: 30 * time.Minute}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+	m.Init()
+	start := m.lastActivity
+
+	// Not idle long enough yet
+	_, cmd := m.Update(IdleCheckMsg{Time: start.Add(10 * time.Minute)})
+	assert.NotNil(t, cmd, "the next check is scheduled")
+	assert.False(t, m.suspended)
+
+	// A turn in progress keeps the session awake
+	m.startTurn()
+	m.Update(IdleCheckMsg{Time: start.Add(time.Hour)})
+	assert.False(t, m.suspended)
+	m.finishTurn()
+	m.lastActivity = start
+
+	_, cmd = m.Update(IdleCheckMsg{Time: start.Add(time.Hour)})
+	assert.True(t, m.suspended)
+	assert.Contains(t, m.View(), "press any key to resume")
+	m.Update(cmd())
+	assert.Equal(t, []string{"suspend"}, app.actions)
+
+	// The key only wakes the session
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	assert.False(t, m.IsQuitting())
+	assert.False(t, m.suspended)
+	assert.Equal(t, ViewChat, m.CurrentView())
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2, "resume and the next idle check")
+	batch[0]()
+	assert.Equal(t, []string{"suspend", "resume"}, app.actions)
+}
+
+// TestIdleSuspendWhileSuspending tests a key pressed before the application
+// finished suspending.
+func TestIdleSuspendWhileSuspending(t *testing.T) {
+	app := &suspendApp{idle: time.Minute}
+	m := NewWithApp(app)
+	m.SetSize(120, 30)
+	m.SetReady(true)
+	m.Init()
+
+	_, suspend := m.Update(IdleCheckMsg{Time: m.lastActivity.Add(time.Hour)})
+	require.NotNil(t, suspend)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Nil(t, cmd, "resumed once suspended")
+
+	_, cmd = m.Update(suspend())
+	require.NotNil(t, cmd)
+	assert.False(t, m.suspending)
+	assert.False(t, m.suspended)
+}
+
+// TestToolProgress tests that progress events from the active tool show in
+// the status bar until it finishes.
+func TestToolProgress(t *testing.T) {
+	bus := events.NewBus()
+	m := NewWithApp(eventsApp{bus: bus})
+	m.SetSize(160, 24)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	cmd := m.Init()
+	require.NotNil(t, cmd)
+
+	bus.Publish(events.ToolStarted{Tool: "core.write_files"})
+	_, cmd = m.Update(cmd())
+	bus.Publish(events.ToolProgress{Tool: "core.write_files", Path: "/p/main.go", Bytes: 40, TotalBytes: 100, Files: 2, TotalFiles: 5})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "core.write_files 2/5 · main.go 40%")
+
+	bus.Publish(events.ToolProgress{Tool: "core.bash", Path: "/p/other.go", Files: 1, TotalFiles: 1})
+	_, cmd = m.Update(cmd())
+	assert.Contains(t, m.View(), "main.go 40%", "progress of other tools should be ignored")
+
+	bus.Publish(events.ToolFinished{Tool: "core.write_files", Success: true})
+	_, _ = m.Update(cmd())
+	assert.NotContains(t, m.View(), "main.go")
+}
+
+// TestPendingChange tests that the diff a tool call asks permission for is
+// shown until the call finishes.
+func TestPendingChange(t *testing.T) {
+	bus := events.NewBus()
+	m := NewWithApp(eventsApp{bus: bus})
+	m.SetSize(160, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	cmd := m.Init()
+	require.NotNil(t, cmd)
+
+	preview := "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n package main\n-var x = 1\n+var x = 2"
+	bus.Publish(events.PermissionRequested{Tool: "core.patch", Permission: "write", Resource: "patch /p/main.go", Preview: preview})
+	_, cmd = m.Update(cmd())
+	view := m.View()
+	assert.Contains(t, view, "core.patch: patch /p/main.go")
+	assert.Contains(t, view, "-var x = 1")
+	assert.Contains(t, view, "+var x = 2")
+
+	bus.Publish(events.ToolFinished{Tool: "core.patch", Success: true})
+	_, _ = m.Update(cmd())
+	assert.NotContains(t, m.View(), "var x")
+}
+
+type substitutionsApp struct {
+	subs *router.Substitutions
+}
+
+func (a *substitutionsApp) ModelSubstitutions() []router.Substitution {
+	return a.subs.List()
+}
+
+// TestSubstitutionsView tests the report of models used instead of the
+// configured ones.
+func TestSubstitutionsView(t *testing.T) {
+	app := &substitutionsApp{subs: router.NewSubstitutions()}
+	m := NewWithApp(app)
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/substitutions"})
+	assert.Equal(t, ViewSubstitutions, m.CurrentView())
+	assert.Contains(t, m.View(), "used the configured model")
+
+	for range 2 {
+		app.subs.Record(router.Substitution{Kind: router.SubstitutionQuick, Purpose: "quick task summary", Configured: "anthropic/claude-sonnet-4-5", Used: "ollama/llama3.2", Reason: "remote provider slow"})
+	}
+	app.subs.Record(router.Substitution{Kind: router.SubstitutionDegraded, Purpose: "layer validation", Configured: "openai/gpt-4o", Reason: "no API key"})
+	view := m.View()
+	assert.Contains(t, view, "quick task summary")
+	assert.Contains(t, view, "×2")
+	assert.Contains(t, view, "anthropic/claude-sonnet-4-5 → ollama/llama3.2")
+	assert.Contains(t, view, "openai/gpt-4o → skipped")
+	assert.Contains(t, view, "no API key")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+type personaApp struct {
+	current string
+}
+
+func (a *personaApp) Personas() []prompts.Persona {
+	return prompts.Personas()
+}
+
+func (a *personaApp) CurrentPersona() string {
+	return a.current
+}
+
+func (a *personaApp) SetPersona(name string) error {
+	p, ok := prompts.LookupPersona(name)
+	if !ok {
+		return fmt.Errorf("unknown persona %q", name)
+	}
+	a.current = p.Name
+	return nil
+}
+
+// TestPersonaCommand tests switching personas from the picker and by name.
+func TestPersonaCommand(t *testing.T) {
+	app := &personaApp{current: prompts.DefaultPersona}
+	m := NewWithApp(app)
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/persona terse"})
+	assert.Equal(t, "terse", app.current)
+	assert.Equal(t, ViewChat, m.CurrentView())
+
+	m.Update(UserInputMsg{Input: "/persona chatty"})
+	assert.Error(t, m.err)
+	assert.Equal(t, "terse", app.current)
+	m.err = nil
+
+	m.Update(UserInputMsg{Input: "/persona"})
+	assert.Equal(t, ViewPersona, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "reviewer")
+	assert.Contains(t, view, "~80 words")
+
+	// Personas are sorted: default, reviewer, teacher, terse
+	assert.Equal(t, 3, m.personaCursor)
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "teacher", app.current)
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+type shellsApp struct {
+	sessions []exec.ShellSession
+}
+
+func (a *shellsApp) ShellSessions() []exec.ShellSession {
+	return a.sessions
+}
+
+func (a *shellsApp) KillShellSession(id string) error {
+	for i, s := range a.sessions {
+		if s.ID == id {
+			a.sessions = append(a.sessions[:i], a.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("shell session %s not found", id)
+}
+
+// TestShellsView tests listing and killing shell sessions.
+func TestShellsView(t *testing.T) {
+	app := &shellsApp{sessions: []exec.ShellSession{
+		{ID: "default", Shell: "bash", Dir: "/p", PID: 41, Started: time.Now()},
+		{ID: "server", Shell: "bash", Dir: "/p/web", PID: 42, Command: "npm run dev", Started: time.Now()},
+	}}
+	m := NewWithApp(app)
+	m.SetSize(120, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	m.Update(UserInputMsg{Input: "/shells"})
+	assert.Equal(t, ViewShells, m.CurrentView())
+	view := m.View()
+	assert.Contains(t, view, "running npm run dev")
+	assert.Contains(t, view, "/p/web")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	require.Len(t, app.sessions, 1)
+	assert.Equal(t, "default", app.sessions[0].ID)
+	assert.Equal(t, 0, m.shellCursor)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	assert.Empty(t, app.sessions)
+	assert.Contains(t, m.View(), "no shell sessions open")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewChat, m.CurrentView())
+}
+
+// TestCommandOutput tests that the output of a running command streams
+// into the chat.
+func TestCommandOutput(t *testing.T) {
+	bus := events.NewBus()
+	m := NewWithApp(eventsApp{bus: bus})
+	m.SetSize(160, 40)
+	m.SetReady(true)
+	m.SetView(ViewChat)
+
+	cmd := m.Init()
+	require.NotNil(t, cmd)
+
+	bus.Publish(events.ToolStarted{Tool: "core.shell"})
+	_, cmd = m.Update(cmd())
+	bus.Publish(events.ToolProgress{Tool: "core.shell", Output: "compiling\nlistening on :3000\n"})
+	_, cmd = m.Update(cmd())
+	view := m.View()
+	assert.Contains(t, view, "core.shell output:")
+	assert.Contains(t, view, "listening on :3000")
+
+	bus.Publish(events.ToolFinished{Tool: "core.shell", Success: true})
+	_, _ = m.Update(cmd())
+	assert.NotContains(t, m.View(), "listening on :3000")
+}
+
+func TestEditorLinks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	assert.Equal(t, "vscode", detectEditor(env(map[string]string{"TERM_PROGRAM": "vscode"})))
+	assert.Equal(t, "cursor", detectEditor(env(map[string]string{"TERM_PROGRAM": "vscode", "CURSOR_TRACE_ID": "x"})))
+	assert.Equal(t, "idea", detectEditor(env(map[string]string{"TERMINAL_EMULATOR": "JetBrains-JediTerm"})))
+	assert.Equal(t, "sublime", detectEditor(env(map[string]string{"EDITOR": "/usr/local/bin/subl -w"})))
+	assert.Equal(t, "file", detectEditor(env(map[string]string{"EDITOR": "vim"})))
+	assert.Equal(t, "off", detectEditor(env(map[string]string{"TERM": "dumb", "TERM_PROGRAM": "vscode"})))
+
+	links := newEditorLinks("vscode", env(nil))
+	links.root = dir
+	path := filepath.ToSlash(filepath.Join(dir, "main.go"))
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	got := links.link("main.go:12:5: undefined: foo, see missing.go:3 and example.com:8080")
+	assert.Equal(t, hyperlink("vscode://file"+path+":12:5", "main.go:12:5")+": undefined: foo, see missing.go:3 and example.com:8080", got)
+
+	// References are found between styling, not inside it
+	styled := "\x1b[31mmain.go:7\x1b[0m"
+	assert.Equal(t, "\x1b[31m"+hyperlink("vscode://file"+path+":7:1", "main.go:7")+"\x1b[0m", links.link(styled))
+	rendered := lipgloss.NewStyle().Width(40).Render(links.link("at main.go:7"))
+	assert.Contains(t, rendered, hyperlink("vscode://file"+path+":7:1", "main.go:7"))
+	assert.Equal(t, 40, lipgloss.Width(rendered), "links take no width")
+
+	custom := newEditorLinks("nvim-remote://{path}?line={line}", env(nil))
+	custom.root = dir
+	assert.Contains(t, custom.link("main.go:3"), "nvim-remote://"+path+"?line=3")
+
+	off := newEditorLinks("off", env(map[string]string{"TERM_PROGRAM": "vscode"}))
+	assert.Equal(t, "main.go:3", off.link("main.go:3"))
+	assert.Equal(t, "main.go:3", NewWithApp(eventsApp{bus: events.NewBus()}).editorLinks().link("main.go:3"), "links are off without configuration")
+}
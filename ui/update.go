@@ -1,6 +1,15 @@
 package ui
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/ui/components"
+
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -76,6 +85,39 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StatusUpdateMsg:
 		return m.handleStatusUpdate(msg)
 
+	case AppEventMsg:
+		return m.handleAppEvent(msg)
+
+	case ModelListMsg:
+		return m.handleModelList(msg)
+
+	case RunListMsg:
+		return m.handleRunList(msg)
+
+	case RunFinishedMsg:
+		return m.handleRunFinished(msg)
+
+	case PaletteSourcesMsg:
+		return m.handlePaletteSources(msg)
+
+	case WorktreeMsg:
+		return m.handleWorktree(msg)
+
+	case WorktreeTestedMsg:
+		return m.handleWorktreeTested(msg)
+
+	case WorktreeFinishedMsg:
+		return m.handleWorktreeFinished(msg)
+
+	case IdleCheckMsg:
+		return m.handleIdleCheck(msg)
+
+	case SuspendedMsg:
+		return m.handleSuspended(msg)
+
+	case ConfigEditedMsg:
+		return m.handleConfigEdited(msg)
+
 	case ShowHelpMsg:
 		return m.handleShowHelp(msg)
 
@@ -100,39 +142,52 @@ func (m *Model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 
 	// TODO: Update component sizes when components are implemented
 	// m.input.SetWidth(m.width)
-	// m.output.SetSize(m.width, m.height - statusBarHeight)
+	m.output.SetSize(m.width, m.height)
 
 	return m, nil
 }
 
 // handleKeyPress handles keyboard input.
 func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Any key but force quit only wakes a suspended session
+	if m.suspended && !key.Matches(msg, m.keys.ForceQuit) {
+		return m.wake()
+	}
+	m.lastActivity = time.Now()
+
+	// The palette takes typed text, so only force quit reaches the global keys
+	if m.view == ViewPalette && !key.Matches(msg, m.keys.ForceQuit) {
+		return m.handlePaletteKeys(msg)
+	}
+
 	// Global key bindings (work in any view)
 	switch {
-	case msg.String() == "ctrl+d":
+	case key.Matches(msg, m.keys.Quit):
 		m.quitting = true
 		return m, tea.Quit
 
-	case msg.String() == "ctrl+c":
+	case key.Matches(msg, m.keys.ForceQuit):
 		m.quitting = true
 		return m, tea.Quit
 
-	case msg.String() == "?":
+	case key.Matches(msg, m.keys.Help):
 		// Toggle help overlay
 		if m.view == ViewHelp {
-			// Return to previous view
-			m.view = ViewChat
+			m.hideHelp()
 		} else {
-			m.view = ViewHelp
+			m.showHelp()
 		}
 		return m, nil
 
-	case msg.String() == "ctrl+k":
+	case key.Matches(msg, m.keys.ClearScreen):
 		// Clear the screen
 		// TODO: Implement when output component exists
 		return m, tea.ClearScreen
 
-	case msg.String() == "ctrl+/":
+	case key.Matches(msg, m.keys.CommandPalette):
+		return m, m.openPalette()
+
+	case key.Matches(msg, m.keys.Settings):
 		// Toggle settings view
 		if m.view == ViewSettings {
 			m.view = ViewChat
@@ -152,6 +207,40 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleSettingsKeys(msg)
 	case ViewHelp:
 		return m.handleHelpKeys(msg)
+	case ViewTools:
+		return m.handleToolsKeys(msg)
+	case ViewModels:
+		return m.handleModelsKeys(msg)
+	case ViewOptimize:
+		return m.handleOptimizeKeys(msg)
+	case ViewRuns:
+		return m.handleRunsKeys(msg)
+	case ViewContext:
+		return m.handleContextKeys(msg)
+	case ViewConfig:
+		return m.handleConfigKeys(msg)
+	case ViewRoots:
+		return m.handleRootsKeys(msg)
+	case ViewProviders:
+		return m.handleProvidersKeys(msg)
+	case ViewTrust:
+		return m.handleTrustKeys(msg)
+	case ViewRedact:
+		return m.handleRedactKeys(msg)
+	case ViewPull:
+		return m.handlePullKeys(msg)
+	case ViewFinish:
+		return m.handleFinishKeys(msg)
+	case ViewFlags:
+		return m.handleFlagsKeys(msg)
+	case ViewSubstitutions:
+		if key.Matches(msg, m.keys.Back) {
+			m.view = ViewChat
+		}
+	case ViewPersona:
+		return m.handlePersonaKeys(msg)
+	case ViewShells:
+		return m.handleShellsKeys(msg)
 	}
 
 	return m, nil
@@ -159,9 +248,10 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleStartupKeys handles keys in startup view.
 func (m *Model) handleStartupKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// Any key press in startup view transitions to chat view
-	if msg.Type == tea.KeyEnter || msg.Type == tea.KeySpace {
+	if key.Matches(msg, m.keys.Start) {
 		m.view = ViewChat
+		m.promptTrust()
+		m.promptPull()
 		return m, nil
 	}
 	return m, nil
@@ -187,7 +277,7 @@ func (m *Model) handleChatKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 // handleSettingsKeys handles keys in settings view.
 func (m *Model) handleSettingsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// TODO: Implement settings navigation
-	if msg.Type == tea.KeyEsc {
+	if key.Matches(msg, m.keys.Back) {
 		m.view = ViewChat
 		return m, nil
 	}
@@ -196,11 +286,396 @@ func (m *Model) handleSettingsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleHelpKeys handles keys in help view.
 func (m *Model) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// Any key exits help
-	if msg.Type == tea.KeyEsc || msg.String() == "?" {
+	if key.Matches(msg, m.keys.Cancel) {
+		m.hideHelp()
+		return m, nil
+	}
+	return m, nil
+}
+
+// handleToolsKeys handles keys in the tools view.
+func (m *Model) handleToolsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Back) {
 		m.view = ViewChat
 		return m, nil
 	}
+
+	reg := m.toolRegistry()
+	if reg == nil {
+		return m, nil
+	}
+	states := reg.States()
+	if len(states) == 0 {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.ListUp):
+		if m.toolCursor > 0 {
+			m.toolCursor--
+		}
+	case key.Matches(msg, m.keys.ListDown):
+		if m.toolCursor < len(states)-1 {
+			m.toolCursor++
+		}
+	case key.Matches(msg, m.keys.Toggle):
+		if m.toolCursor < len(states) {
+			state := states[m.toolCursor]
+			if err := reg.SetEnabled(state.Name, !state.Enabled); err != nil {
+				m.SetError(err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// handleModelsKeys handles keys in the models view.
+func (m *Model) handleModelsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Back) {
+		m.view = ViewChat
+		return m, nil
+	}
+	if len(m.modelList) == 0 {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.ListUp):
+		if m.modelCursor > 0 {
+			m.modelCursor--
+		}
+	case key.Matches(msg, m.keys.ListDown):
+		if m.modelCursor < len(m.modelList)-1 {
+			m.modelCursor++
+		}
+	case key.Matches(msg, m.keys.Select):
+		app, ok := m.app.(modelSwitcher)
+		if !ok {
+			return m, nil
+		}
+		if err := app.SetModel(m.modelList[m.modelCursor].Model); err != nil {
+			m.SetError(err)
+			return m, nil
+		}
+		m.view = ViewChat
+	}
+	return m, nil
+}
+
+// handleOptimizeKeys confirms or cancels the pruning preview.
+func (m *Model) handleOptimizeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Reject):
+		m.optimizePlan = nil
+		m.view = ViewChat
+	case key.Matches(msg, m.keys.Confirm):
+		if app, ok := m.app.(contextOptimizer); ok && m.optimizePlan != nil && !m.optimizePlan.Empty() {
+			if _, err := app.OptimizeContext(); err != nil {
+				m.SetError(err)
+			}
+		}
+		m.optimizePlan = nil
+		m.view = ViewChat
+	}
+	return m, nil
+}
+
+// handlePullKeys starts or declines pulling a missing model.
+func (m *Model) handlePullKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		m.pullModel(m.pullOffer)
+	case key.Matches(msg, m.keys.Reject):
+		m.pullOffer = ""
+		m.view = ViewChat
+	}
+	return m, nil
+}
+
+// handleFlagsKeys moves through the experimental flags and toggles them.
+func (m *Model) handleFlagsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.view = ViewChat
+	case key.Matches(msg, m.keys.ListUp):
+		if m.flagCursor > 0 {
+			m.flagCursor--
+		}
+	case key.Matches(msg, m.keys.ListDown):
+		if m.flagCursor < len(m.featureFlags())-1 {
+			m.flagCursor++
+		}
+	case key.Matches(msg, m.keys.Toggle):
+		m.toggleFlag()
+	}
+	return m, nil
+}
+
+// handlePersonaKeys moves through the personas and switches to one.
+func (m *Model) handlePersonaKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.view = ViewChat
+	case key.Matches(msg, m.keys.ListUp):
+		if m.personaCursor > 0 {
+			m.personaCursor--
+		}
+	case key.Matches(msg, m.keys.ListDown):
+		if list, _ := m.personas(); m.personaCursor < len(list)-1 {
+			m.personaCursor++
+		}
+	case key.Matches(msg, m.keys.Select):
+		m.selectPersona()
+	}
+	return m, nil
+}
+
+// handleShellsKeys moves through the shell sessions and kills them.
+func (m *Model) handleShellsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.view = ViewChat
+	case key.Matches(msg, m.keys.ListUp):
+		if m.shellCursor > 0 {
+			m.shellCursor--
+		}
+	case key.Matches(msg, m.keys.ListDown):
+		if m.shellCursor < len(m.shellSessions())-1 {
+			m.shellCursor++
+		}
+	case key.Matches(msg, m.keys.Kill):
+		m.killShell()
+	}
+	return m, nil
+}
+
+// handleFinishKeys merges, pushes or discards the work of the worktree.
+// Discarding takes a second press.
+func (m *Model) handleFinishKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	discarding := m.finishDiscarding
+	m.finishDiscarding = false
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		if m.finishBusy == "" {
+			m.view = ViewChat
+		}
+	case key.Matches(msg, m.keys.Merge):
+		return m, m.finish(finishMerge)
+	case key.Matches(msg, m.keys.Push):
+		return m, m.finish(finishPush)
+	case key.Matches(msg, m.keys.Discard):
+		if discarding {
+			return m, m.finish(finishDiscard)
+		}
+		m.finishDiscarding = m.finishTree != nil && m.finishBusy == ""
+	case key.Matches(msg, m.keys.Retest):
+		if m.finishBusy == "" {
+			return m, m.testMerge()
+		}
+	}
+	return m, nil
+}
+
+// handleRedactKeys confirms or cancels the redaction preview.
+func (m *Model) handleRedactKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Reject):
+		m.redactTarget = ""
+		m.view = ViewChat
+	case key.Matches(msg, m.keys.Confirm):
+		m.confirmRedaction()
+	}
+	return m, nil
+}
+
+// handleContextKeys closes the context inspector.
+func (m *Model) handleContextKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Close) {
+		m.contextItems = nil
+		m.view = ViewChat
+	}
+	return m, nil
+}
+
+// handleRootsKeys handles keys in the roots view.
+func (m *Model) handleRootsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Close) {
+		m.rootList = nil
+		m.rootsResult = ""
+		m.view = ViewChat
+	}
+	return m, nil
+}
+
+// handleProvidersKeys re-tests the providers or returns to chat.
+func (m *Model) handleProvidersKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.providerHealth = nil
+		m.view = ViewChat
+	case key.Matches(msg, m.keys.Retest):
+		m.retestProviders()
+	}
+	return m, nil
+}
+
+// handleTrustKeys trusts or restricts the workspace.
+func (m *Model) handleTrustKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		m.decideTrust(true)
+	case key.Matches(msg, m.keys.Reject):
+		m.decideTrust(false)
+	}
+	return m, nil
+}
+
+// handleConfigKeys moves through the configuration values and opens the
+// file that sets the selected one.
+func (m *Model) handleConfigKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m.configSettings = nil
+		m.configResult = ""
+		m.view = ViewChat
+	case key.Matches(msg, m.keys.ListUp):
+		if m.configCursor > 0 {
+			m.configCursor--
+		}
+	case key.Matches(msg, m.keys.ListDown):
+		if m.configCursor < len(m.configSettings)-1 {
+			m.configCursor++
+		}
+	case key.Matches(msg, m.keys.PageUp):
+		m.configCursor = max(m.configCursor-m.configRows(), 0)
+	case key.Matches(msg, m.keys.PageDown):
+		m.configCursor = max(min(m.configCursor+m.configRows(), len(m.configSettings)-1), 0)
+	case key.Matches(msg, m.keys.Edit):
+		return m, m.editConfigSetting()
+	}
+	return m, nil
+}
+
+// handlePaletteKeys edits the palette query, moves the selection through
+// the matches and runs the selected one.
+func (m *Model) handlePaletteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Cancel), key.Matches(msg, m.keys.CommandPalette):
+		m.closePalette()
+		return m, nil
+	case key.Matches(msg, m.keys.Select):
+		return m, m.runPaletteSelection()
+	case key.Matches(msg, m.keys.PaletteUp):
+		if m.paletteCursor > 0 {
+			m.paletteCursor--
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.PaletteDown):
+		if m.paletteCursor < len(m.paletteMatches())-1 {
+			m.paletteCursor++
+		}
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		m.paletteQuery += string(msg.Runes)
+	case tea.KeyBackspace:
+		if query := []rune(m.paletteQuery); len(query) > 0 {
+			m.paletteQuery = string(query[:len(query)-1])
+		}
+	default:
+		return m, nil
+	}
+	m.paletteCursor = 0
+	return m, nil
+}
+
+// handleRunsKeys handles keys in the runs view.
+func (m *Model) handleRunsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Back) {
+		m.view = ViewChat
+		return m, nil
+	}
+	if len(m.runList) == 0 {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.ListUp):
+		if m.runCursor > 0 {
+			m.runCursor--
+		}
+	case key.Matches(msg, m.keys.ListDown):
+		if m.runCursor < len(m.runList)-1 {
+			m.runCursor++
+		}
+	case key.Matches(msg, m.keys.Select):
+		run := m.runList[m.runCursor]
+		m.runResult = fmt.Sprintf("Running #%d: %s", run.ID, run.Command)
+		return m, m.rerun(run.ID)
+	case key.Matches(msg, m.keys.Favorite):
+		app, ok := m.app.(runHistory)
+		if !ok {
+			return m, nil
+		}
+		run := m.runList[m.runCursor]
+		if err := app.SetRunFavorite(run.ID, !run.Favorite); err != nil {
+			m.SetError(err)
+			return m, nil
+		}
+		run.Favorite = !run.Favorite
+	}
+	return m, nil
+}
+
+// handleRunList stores a loaded run history.
+func (m *Model) handleRunList(msg RunListMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		m.SetError(msg.Err)
+		return m, nil
+	}
+	m.runList = msg.Runs
+	if m.runCursor >= len(m.runList) {
+		m.runCursor = 0
+	}
+	return m, nil
+}
+
+// handleRunFinished records the outcome of a re-run and reloads the history,
+// which now includes it.
+func (m *Model) handleRunFinished(msg RunFinishedMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Err != nil:
+		m.SetError(msg.Err)
+		m.runResult = ""
+	case msg.Run == nil:
+		reason := "command did not run"
+		if msg.Result != nil && msg.Result.Error != nil {
+			reason = msg.Result.Error.Error()
+		}
+		m.runResult = fmt.Sprintf("#%d: %s", msg.ID, reason)
+	default:
+		m.runResult = fmt.Sprintf("#%d → #%d exited %d in %s", msg.ID, msg.Run.ID, msg.Run.ExitCode,
+			msg.Run.Duration.Round(time.Millisecond))
+	}
+	return m, m.loadRuns()
+}
+
+// handleModelList stores a loaded model list, selecting the current model.
+func (m *Model) handleModelList(msg ModelListMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		m.SetError(msg.Err)
+		return m, nil
+	}
+	m.modelList = msg.Models
+	m.modelCursor = 0
+	for i, model := range m.modelList {
+		if model.Current {
+			m.modelCursor = i
+			break
+		}
+	}
 	return m, nil
 }
 
@@ -212,6 +687,16 @@ func (m *Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 
 // handleUserInput handles user text input submission.
 func (m *Model) handleUserInput(msg UserInputMsg) (tea.Model, tea.Cmd) {
+	m.lastActivity = time.Now()
+	if strings.HasPrefix(msg.Input, "/") {
+		return m, m.runSlashCommand(msg.Input)
+	}
+
+	// Expand abbreviations typed without per-keystroke expansion
+	msg.Input = components.ExpandAbbreviations(msg.Input, m.abbreviations())
+	m.startTurn()
+	m.output.AddMessage("user", msg.Input)
+
 	// TODO: Process user input
 	// - Add to conversation history
 	// - Send to agent for processing
@@ -221,12 +706,14 @@ func (m *Model) handleUserInput(msg UserInputMsg) (tea.Model, tea.Cmd) {
 
 // handleStreamToken handles streaming tokens from LLM.
 func (m *Model) handleStreamToken(msg StreamTokenMsg) (tea.Model, tea.Cmd) {
-	// TODO: Update output component with streaming token
-	// if msg.Done {
-	//     // Streaming complete
-	// } else {
-	//     // Append token to current message
-	// }
+	m.lastActivity = time.Now()
+	if msg.Token != "" {
+		m.output.StreamToken(msg.Token)
+	}
+	if msg.Done {
+		m.output.FinishStreaming()
+		m.finishTurn()
+	}
 	return m, nil
 }
 
@@ -256,20 +743,140 @@ func (m *Model) handleModalResult(msg ModalResultMsg) (tea.Model, tea.Cmd) {
 
 // handleStatusUpdate handles status bar updates.
 func (m *Model) handleStatusUpdate(msg StatusUpdateMsg) (tea.Model, tea.Cmd) {
-	// TODO: Update status bar component
+	if msg.Mode != "" {
+		m.mode = msg.Mode
+	}
+	m.cost = msg.Cost
+	m.tokens = msg.Tokens
 	return m, nil
 }
 
+// handleAppEvent updates status from an application event and waits for the next one.
+func (m *Model) handleAppEvent(msg AppEventMsg) (tea.Model, tea.Cmd) {
+	switch e := msg.Event.(type) {
+	case events.CostUpdated:
+		// A provider answered, so any sign-in it waited for is done
+		m.signIn = nil
+		m.cost = e.TotalCost
+		m.tokens = e.TotalTokens
+		m.reasoningCost = e.ReasoningCost
+		m.reasoningTokens = e.ReasoningTokens
+		// Reconciliation updates carry no tokens and replace an earlier
+		// estimate, so only per-call updates count towards the turn
+		if !m.turnStart.IsZero() && e.InputTokens+e.OutputTokens > 0 {
+			m.turn.Model = e.Model
+			m.turn.InputTokens += e.InputTokens
+			m.turn.OutputTokens += e.OutputTokens
+			m.turn.Cost += e.Cost
+		}
+	case events.ToolStarted:
+		m.activeTool = e.Tool
+		m.progress = nil
+	case events.ToolProgress:
+		if m.activeTool == e.Tool {
+			m.progress = &e
+		}
+	case events.ToolFinished:
+		if m.activeTool == e.Tool {
+			m.activeTool = ""
+			m.progress = nil
+		}
+		if m.change != nil && m.change.Tool == e.Tool {
+			m.change = nil
+		}
+		if !m.turnStart.IsZero() {
+			m.turn.ToolCalls++
+		}
+	case events.LayerChanged:
+		m.mode = fmt.Sprintf("Layer %d", e.To)
+	case events.RateLimitUpdated:
+		m.rateLimit = &e
+	case events.ModelLoadChanged:
+		m.modelLoad = &e
+		if e.State == events.ModelMissing {
+			m.pullOffer = e.Model
+			m.promptPull()
+		}
+	case events.LayerDegraded:
+		if m.degraded == nil {
+			m.degraded = make(map[string]bool)
+		}
+		m.degraded[e.Layer] = true
+	case events.ProvidersChecked:
+		if m.view == ViewProviders {
+			m.refreshProviders()
+		}
+	case events.RepositoryScanned:
+		m.largeRepo = &e
+	case events.SignInRequested:
+		m.signIn = &e
+	case events.DraftStreamed:
+		m.draft = &e
+	case events.DraftReplaced:
+		m.draft = nil
+	case events.TodosUpdated:
+		m.todos = e.Todos
+	case events.AnswerTruncated:
+		if !m.turnStart.IsZero() {
+			m.truncated = &e
+		}
+	case events.FileChanged:
+		m.changed = slices.DeleteFunc(m.changed, func(c events.FileChanged) bool { return c.Path == e.Path })
+		m.changed = append(m.changed, e)
+	case events.PermissionRequested:
+		if e.Preview != "" {
+			m.change = &e
+		}
+		if e.Elevated {
+			m.noteElevated(e)
+		}
+	}
+	return m, m.waitForEvent()
+}
+
+// waitForEvent returns a command that delivers the next application event.
+func (m *Model) waitForEvent() tea.Cmd {
+	if m.events == nil {
+		return nil
+	}
+	ch := m.events
+	return func() tea.Msg {
+		e, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return AppEventMsg{Event: e}
+	}
+}
+
 // handleShowHelp handles help overlay toggle.
 func (m *Model) handleShowHelp(msg ShowHelpMsg) (tea.Model, tea.Cmd) {
 	if msg.Show {
-		m.view = ViewHelp
+		m.showHelp()
 	} else {
-		m.view = ViewChat
+		m.hideHelp()
 	}
 	return m, nil
 }
 
+// showHelp opens the help overlay for the current view, remembering the view
+// to return to.
+func (m *Model) showHelp() {
+	if m.view == ViewHelp {
+		return
+	}
+	m.helpReturn = m.view
+	m.view = ViewHelp
+}
+
+// hideHelp closes the help overlay, returning to the view it was opened from.
+func (m *Model) hideHelp() {
+	if m.view != ViewHelp {
+		return
+	}
+	m.view = m.helpReturn
+}
+
 // handleComponentMsg handles component-specific messages.
 func (m *Model) handleComponentMsg(msg ComponentMsg) (tea.Model, tea.Cmd) {
 	// TODO: Route to appropriate component
@@ -2,8 +2,18 @@ package ui
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/abrksh22/bplus/internal/config"
+	"github.com/abrksh22/bplus/internal/errors"
+	"github.com/abrksh22/bplus/internal/events"
+	"github.com/abrksh22/bplus/internal/flags"
+	"github.com/abrksh22/bplus/internal/util"
+	"github.com/abrksh22/bplus/models"
+	"github.com/abrksh22/bplus/security"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -18,6 +28,10 @@ func (m *Model) View() string {
 		return m.renderLoading()
 	}
 
+	if m.suspended {
+		return m.renderSuspended()
+	}
+
 	// Render based on current view mode
 	switch m.view {
 	case ViewStartup:
@@ -28,6 +42,40 @@ func (m *Model) View() string {
 		return m.renderSettings()
 	case ViewHelp:
 		return m.renderHelp()
+	case ViewTools:
+		return m.renderTools()
+	case ViewModels:
+		return m.renderModels()
+	case ViewOptimize:
+		return m.renderOptimize()
+	case ViewRuns:
+		return m.renderRuns()
+	case ViewContext:
+		return m.renderContext()
+	case ViewPalette:
+		return m.renderPalette()
+	case ViewConfig:
+		return m.renderConfig()
+	case ViewRoots:
+		return m.renderRoots()
+	case ViewProviders:
+		return m.renderProviders()
+	case ViewTrust:
+		return m.renderTrust()
+	case ViewRedact:
+		return m.renderRedact()
+	case ViewPull:
+		return m.renderPull()
+	case ViewFinish:
+		return m.renderFinish()
+	case ViewFlags:
+		return m.renderFlags()
+	case ViewSubstitutions:
+		return m.renderSubstitutions()
+	case ViewPersona:
+		return m.renderPersonas()
+	case ViewShells:
+		return m.renderShells()
 	default:
 		return m.renderError(fmt.Errorf("unknown view mode: %d", m.view))
 	}
@@ -123,15 +171,76 @@ func (m *Model) renderChat() string {
 	return view
 }
 
+// toolProgress describes how far a tool call has got, as in
+// " 2/5 · main.go 40%".
+func toolProgress(p *events.ToolProgress) string {
+	var s string
+	if p.TotalFiles > 0 {
+		s += fmt.Sprintf(" %d/%d", p.Files, p.TotalFiles)
+	}
+	if p.Path != "" && (p.TotalFiles == 0 || p.Files < p.TotalFiles) {
+		if s != "" {
+			s += " ·"
+		}
+		s += " " + filepath.Base(p.Path)
+		if p.TotalBytes > 0 {
+			s += fmt.Sprintf(" %d%%", int(p.Bytes*100/p.TotalBytes))
+		}
+	}
+	return s
+}
+
 // renderStatusBar renders the status bar.
 func (m *Model) renderStatusBar() string {
-	// TODO: Get actual values from status bar component
+	// TODO: Get model from status bar component
 	mode := "Fast Mode"
+	if m.mode != "" {
+		mode = m.mode
+	}
 	model := "anthropic/claude-sonnet-4-5"
-	cost := "$0.00"
-	tokens := "0"
+	cost := fmt.Sprintf("$%.2f", m.cost)
+	tokens := fmt.Sprintf("%d", m.tokens)
+	if m.reasoningTokens > 0 {
+		cost += fmt.Sprintf(" ($%.2f reasoning)", m.reasoningCost)
+		tokens += fmt.Sprintf(" (%d reasoning)", m.reasoningTokens)
+	}
 
 	left := fmt.Sprintf(" %s | %s", mode, model)
+	if m.activeTool != "" {
+		left += " | ⚙ " + m.activeTool
+		if p := m.progress; p != nil {
+			left += toolProgress(p)
+		}
+	}
+	if m.isOffline() {
+		left += " | " + m.theme.Bold.Foreground(m.theme.Warning).Render("OFFLINE")
+	}
+	if load := m.modelLoad; load != nil {
+		switch load.State {
+		case events.ModelLoading:
+			status := "⟳ loading " + modelID(load.Model)
+			if load.Progress > 0 {
+				status += fmt.Sprintf(" %d%%", int(load.Progress*100))
+			}
+			left += " | " + m.theme.Bold.Foreground(m.theme.Warning).Render(status)
+		case events.ModelFailed:
+			left += " | " + m.theme.Bold.Foreground(m.theme.Error).Render("✗ "+modelID(load.Model)+" failed to load")
+		case events.ModelMissing:
+			left += " | " + m.theme.Bold.Foreground(m.theme.Error).Render("✗ "+modelID(load.Model)+" not installed (/pull)")
+		case events.ModelPulling:
+			status := "⬇ pulling " + modelID(load.Model)
+			if load.Progress > 0 {
+				status += fmt.Sprintf(" %d%%", int(load.Progress*100))
+			}
+			left += " | " + m.theme.Bold.Foreground(m.theme.Warning).Render(status)
+		}
+	}
+	if n := len(m.degraded); n > 0 {
+		left += " | " + m.theme.Bold.Foreground(m.theme.Warning).Render(fmt.Sprintf("⚠ %d layer(s) degraded", n))
+	}
+	if limit := m.rateLimitStatus(time.Now()); limit != "" {
+		left += " | " + m.theme.Bold.Foreground(m.theme.Warning).Render(limit)
+	}
 	right := fmt.Sprintf("Cost: %s | Tokens: %s ", cost, tokens)
 
 	// Calculate spacing
@@ -145,6 +254,40 @@ func (m *Model) renderStatusBar() string {
 	return m.theme.StatusBar.Width(m.width).Render(statusBar)
 }
 
+// modelID returns a model name without its provider prefix.
+func modelID(model string) string {
+	if i := strings.Index(model, "/"); i >= 0 {
+		return model[i+1:]
+	}
+	return model
+}
+
+// rateLimitStatus describes the last reported provider rate limit when
+// requests are waiting for it or few remain, and is empty otherwise.
+func (m *Model) rateLimitStatus(now time.Time) string {
+	rl := m.rateLimit
+	if rl == nil {
+		return ""
+	}
+
+	if wait := rl.BlockedUntil.Sub(now); wait > 0 || rl.Queued > 0 {
+		status := "⏳ rate limited"
+		if wait > 0 {
+			status += fmt.Sprintf(" %ds", int(wait.Round(time.Second)/time.Second))
+		}
+		if rl.Queued > 0 {
+			status += fmt.Sprintf(" (%d queued)", rl.Queued)
+		}
+		return status
+	}
+
+	// Warn once fewer than a tenth of the requests in the window remain
+	if rl.RequestsLimit > 0 && rl.RequestsRemaining >= 0 && rl.RequestsRemaining*10 < rl.RequestsLimit {
+		return fmt.Sprintf("⚠ %d/%d requests left", rl.RequestsRemaining, rl.RequestsLimit)
+	}
+	return ""
+}
+
 // renderOutput renders the output/conversation area.
 func (m *Model) renderOutput(height int) string {
 	// TODO: Replace with actual output component
@@ -155,6 +298,37 @@ func (m *Model) renderOutput(height int) string {
 	placeholder += "  • Refactor\n"
 	placeholder += "  • Generate tests\n"
 	placeholder += "  • And much more!\n"
+	if transcript := m.transcript(height - 2); transcript != "" {
+		placeholder = transcript + "\n"
+	}
+
+	if notice := m.signInNotice(time.Now()); notice != "" {
+		placeholder += "\n" + m.theme.Bold.Foreground(m.theme.Warning).Render(notice) + "\n"
+	}
+
+	if warning := m.largeRepoWarning(); warning != "" {
+		placeholder += "\n" + lipgloss.NewStyle().Foreground(m.theme.Warning).Render(warning) + "\n"
+	}
+
+	if changes := m.externalChanges(); changes != "" {
+		placeholder += "\n" + lipgloss.NewStyle().Foreground(m.theme.Warning).Render(changes) + "\n"
+	}
+
+	if tasks := m.taskList(); tasks != "" {
+		placeholder += "\n" + tasks + "\n"
+	}
+
+	if draft := m.draftAnswer(); draft != "" {
+		placeholder += "\n" + lipgloss.NewStyle().Foreground(m.theme.Dim).Render(m.editorLinks().link(draft)) + "\n"
+	}
+
+	if change := m.pendingChange(); change != "" {
+		placeholder += "\n" + change + "\n"
+	}
+
+	if output := m.commandOutput(); output != "" {
+		placeholder += "\n" + lipgloss.NewStyle().Foreground(m.theme.Dim).Render(m.editorLinks().link(output)) + "\n"
+	}
 
 	box := lipgloss.NewStyle().
 		Width(m.width - 2).
@@ -167,6 +341,214 @@ func (m *Model) renderOutput(height int) string {
 	return box
 }
 
+// transcript renders the end of the conversation that fits in lines, or is
+// empty before the first message.
+func (m *Model) transcript(lines int) string {
+	if len(m.output.GetMessages()) == 0 {
+		return ""
+	}
+	out := strings.Split(m.output.Transcript(), "\n")
+	if lines > 0 && len(out) > lines {
+		out = out[len(out)-lines:]
+	}
+	return strings.Join(out, "\n")
+}
+
+// signInNotice tells the user how to sign in to a provider gateway, until
+// the device code expires.
+func (m *Model) signInNotice(now time.Time) string {
+	e := m.signIn
+	if e == nil || (!e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)) {
+		return ""
+	}
+	return fmt.Sprintf("🔑 Sign in to %s: visit %s and enter code %s", e.Provider, e.VerificationURI, e.UserCode)
+}
+
+// draftMaxLines is how much of a draft answer is shown; the end is kept as
+// it is the part still changing.
+const draftMaxLines = 12
+
+// draftAnswer renders the draft answer streaming ahead of the real one, or
+// is empty.
+func (m *Model) draftAnswer() string {
+	e := m.draft
+	if e == nil || e.Content == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(e.Content), "\n")
+	if len(lines) > draftMaxLines {
+		lines = append([]string{"…"}, lines[len(lines)-draftMaxLines:]...)
+	}
+	header := fmt.Sprintf("✎ Draft from %s, a fuller answer is on its way:", modelID(e.Model))
+	if e.Done {
+		header = fmt.Sprintf("✎ Draft from %s, still working on the fuller answer:", modelID(e.Model))
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
+
+// taskListMaxItems is how many items of the task list are shown; the
+// earliest completed ones are left out first.
+const taskListMaxItems = 10
+
+// taskList renders the agent's task list, marking the items in progress
+// and those completed, or is empty.
+func (m *Model) taskList() string {
+	if len(m.todos) == 0 {
+		return ""
+	}
+	done := 0
+	for _, t := range m.todos {
+		if t.Status == "completed" {
+			done++
+		}
+	}
+	hidden := min(max(len(m.todos)-taskListMaxItems, 0), done)
+
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	doneStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	var b strings.Builder
+	b.WriteString(m.theme.Bold.Render(fmt.Sprintf("☰ Tasks (%d/%d done)", done, len(m.todos))))
+	if hidden > 0 {
+		b.WriteString("\n" + dimStyle.Render(fmt.Sprintf("  … %d earlier completed", hidden)))
+	}
+	for _, t := range m.todos {
+		var line string
+		switch t.Status {
+		case "completed":
+			if hidden > 0 {
+				hidden--
+				continue
+			}
+			line = doneStyle.Render("  ✓ " + t.Content)
+		case "in_progress":
+			line = m.theme.Bold.Render("  ◐ " + t.Content)
+		default:
+			line = dimStyle.Render("  ○ " + t.Content)
+		}
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}
+
+// externalChangesMaxItems is how many files changed outside b+ are named.
+const externalChangesMaxItems = 5
+
+// externalChanges names the files changed outside b+, such as in the
+// user's editor, since the last prompt, or is empty. The agent is told
+// about them before it works on them again.
+func (m *Model) externalChanges() string {
+	if len(m.changed) == 0 {
+		return ""
+	}
+	root, _ := os.Getwd()
+	shown := m.changed[max(len(m.changed)-externalChangesMaxItems, 0):]
+	names := make([]string, len(shown))
+	for i, c := range shown {
+		name := c.Path
+		if rel, err := filepath.Rel(root, c.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = filepath.ToSlash(rel)
+		}
+		names[i] = name
+		if c.Kind != "modified" {
+			names[i] += " (" + c.Kind + ")"
+		}
+	}
+	text := "✎ Changed outside b+: " + strings.Join(names, ", ")
+	if more := len(m.changed) - len(shown); more > 0 {
+		text += fmt.Sprintf(" and %d more", more)
+	}
+	return text
+}
+
+// changeMaxLines is how much of a pending change is shown.
+const changeMaxLines = 20
+
+// pendingChange renders the diff a tool call asked permission to apply,
+// with added and removed lines coloured, or is empty.
+func (m *Model) pendingChange() string {
+	e := m.change
+	if e == nil {
+		return ""
+	}
+	addStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	removeStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+
+	lines := strings.Split(e.Preview, "\n")
+	more := 0
+	if len(lines) > changeMaxLines {
+		lines, more = lines[:changeMaxLines], len(lines)-changeMaxLines
+	}
+	var b strings.Builder
+	b.WriteString(m.theme.Bold.Render(fmt.Sprintf("± %s: %s", e.Tool, e.Resource)))
+	for _, line := range lines {
+		b.WriteString("\n")
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "@@"):
+			b.WriteString(dimStyle.Render(line))
+		case strings.HasPrefix(line, "+"):
+			b.WriteString(addStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			b.WriteString(removeStyle.Render(line))
+		default:
+			b.WriteString(line)
+		}
+	}
+	if more > 0 {
+		b.WriteString("\n" + dimStyle.Render(fmt.Sprintf("… %d more lines", more)))
+	}
+	return b.String()
+}
+
+// outputMaxLines is how much of a running command's output is shown; the
+// end is kept as it is the part still changing.
+const outputMaxLines = 8
+
+// commandOutput renders the latest output of the command the active tool
+// runs, or is empty.
+func (m *Model) commandOutput() string {
+	p := m.progress
+	if p == nil || strings.TrimSpace(p.Output) == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(p.Output, "\n"), "\n")
+	if len(lines) > outputMaxLines {
+		lines = append([]string{"…"}, lines[len(lines)-outputMaxLines:]...)
+	}
+	return fmt.Sprintf("▶ %s output:\n%s", p.Tool, strings.Join(lines, "\n"))
+}
+
+// largeRepoWarning describes a project too large to explore cheaply and how
+// to scope the session down, or is empty.
+func (m *Model) largeRepoWarning() string {
+	e := m.largeRepo
+	if e == nil {
+		return ""
+	}
+
+	size := fmt.Sprintf("%d files, ~%s tokens", e.Files, formatTokens(e.Tokens))
+	if e.Truncated {
+		size = "over " + size
+	}
+	warning := fmt.Sprintf("⚠ Large repository (%s to index). A typical exploring turn sends ~%s tokens", size, formatTokens(e.TurnTokens))
+	if e.TurnCost > 0 {
+		warning += fmt.Sprintf(" (~$%.2f with %s)", e.TurnCost, modelID(e.Model))
+	}
+	warning += "."
+
+	var tips []string
+	if len(e.LargestDirs) > 0 {
+		tips = append(tips, "start b+ in a subdirectory such as "+strings.Join(e.LargestDirs, ", "))
+	}
+	if e.LocalModel != "" {
+		tips = append(tips, "explore with a local model (/models "+e.LocalModel+")")
+	}
+	if len(tips) > 0 {
+		warning += "\n  Consider: " + strings.Join(tips, "; or ") + "."
+	}
+	return warning
+}
+
 // renderInput renders the input area.
 func (m *Model) renderInput(height int) string {
 	// TODO: Replace with actual input component
@@ -194,7 +576,7 @@ func (m *Model) renderErrorBanner() string {
 		return ""
 	}
 
-	errorText := fmt.Sprintf("⚠️  Error: %s", m.err.Error())
+	errorText := fmt.Sprintf("⚠️  Error: %s", errorMessage(m.err))
 	banner := lipgloss.NewStyle().
 		Width(m.width).
 		Background(m.theme.ErrorBg).
@@ -205,6 +587,16 @@ func (m *Model) renderErrorBanner() string {
 	return banner
 }
 
+// errorMessage returns the text shown for err: the user-facing message of a
+// classified error, such as a provider auth or quota failure, or else the
+// error itself.
+func errorMessage(err error) string {
+	if msg, ok := errors.UserMessage(err); ok {
+		return msg
+	}
+	return err.Error()
+}
+
 // renderSettings renders the settings view.
 func (m *Model) renderSettings() string {
 	subtleStyle := lipgloss.NewStyle().Foreground(m.theme.Subtle)
@@ -230,50 +622,57 @@ func (m *Model) renderSettings() string {
 	)
 }
 
-// renderHelp renders the help overlay.
+// helpKeyWidth is the width of the key column in the help overlay.
+const helpKeyWidth = 14
+
+// renderHelp renders the help overlay with the bindings that apply to the
+// view it was opened from and the focused component, followed by the slash
+// commands. Both lists come from the keymap and command registry.
 func (m *Model) renderHelp() string {
 	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	keyStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	sectionStyle := m.theme.Bold
 
 	title := m.theme.Bold.Render("📖 Help\n")
 
-	helpText := `
-Keyboard Shortcuts:
-  Ctrl+D, Ctrl+C    Quit
-  ?                 Toggle this help
-  Ctrl+K            Clear screen
-  Ctrl+/            Settings
-
-Navigation:
-  Tab               Focus next component
-  Shift+Tab         Focus previous component
-
-Chat:
-  Enter             Send message
-  Up/Down           Navigate history
-
-Coming soon:
-  • Custom commands
-  • Model switching
-  • Session management
-  • And much more!
-`
+	// Descriptions wrap beside their key within the box's border and padding
+	descStyle := lipgloss.NewStyle().Width(max(m.width-10-4-helpKeyWidth-3, 10))
+	row := func(keys, desc string) string {
+		return lipgloss.JoinHorizontal(lipgloss.Top, "  ",
+			keyStyle.Render(fmt.Sprintf("%-*s", helpKeyWidth, keys)), " ", descStyle.Render(desc)) + "\n"
+	}
+
+	var b strings.Builder
+	for _, section := range m.keys.helpSections(m.helpReturn, m.focusedComponent) {
+		b.WriteString(sectionStyle.Render(section.Title) + "\n")
+		for _, binding := range section.Bindings {
+			b.WriteString(row(binding.Help().Key, binding.Help().Desc))
+		}
+		b.WriteString("\n")
+	}
+
+	if commands := m.Commands(); len(commands) > 0 {
+		b.WriteString(sectionStyle.Render("Commands") + "\n")
+		for _, cmd := range commands {
+			b.WriteString(row("/"+cmd.Name, cmd.Description))
+		}
+	}
 
-	styledHelp := m.theme.Render.Render(helpText)
 	hint := dimStyle.Render("\nPress ? or ESC to close")
 
 	helpContent := lipgloss.JoinVertical(
 		lipgloss.Left,
 		title,
-		styledHelp,
+		strings.TrimRight(b.String(), "\n"),
 		hint,
 	)
 
 	// Add border
 	box := lipgloss.NewStyle().
-		Width(m.width - 10).
+		Width(m.width-10).
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(m.theme.Primary).
-		Padding(2).
+		Padding(1, 2).
 		Render(helpContent)
 
 	return lipgloss.Place(
@@ -285,9 +684,1037 @@ Coming soon:
 	)
 }
 
+// renderTools renders the per-session tool toggle view.
+func (m *Model) renderTools() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	onStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	offStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+
+	title := m.theme.Bold.Render("🔧 Tools\n")
+
+	var b strings.Builder
+	reg := m.toolRegistry()
+	if reg == nil || len(reg.States()) == 0 {
+		b.WriteString(dimStyle.Render("No tools registered"))
+	} else {
+		for i, state := range reg.States() {
+			cursor := "  "
+			if i == m.toolCursor {
+				cursor = cursorStyle.Render("> ")
+			}
+			toggle := onStyle.Render("[on] ")
+			if !state.Enabled {
+				toggle = offStyle.Render("[off]")
+			}
+			fmt.Fprintf(&b, "%s%s %-20s %s\n", cursor, toggle, state.Name, dimStyle.Render(state.Category))
+		}
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • space toggle • ESC to return (changes last for this session)")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderFlags renders the experimental flags, their stage and where their
+// value came from.
+func (m *Model) renderFlags() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	onStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	offStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+	warnStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+
+	title := m.theme.Bold.Render("🚩 Experimental flags\n")
+
+	var b strings.Builder
+	states := m.featureFlags()
+	if len(states) == 0 {
+		b.WriteString(dimStyle.Render("No experimental flags"))
+	}
+	for i, state := range states {
+		cursor := "  "
+		if i == m.flagCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		toggle := onStyle.Render("[on] ")
+		if !state.Enabled {
+			toggle = offStyle.Render("[off]")
+		}
+		stage := dimStyle.Render(fmt.Sprintf("%-12s", state.Stage))
+		if state.Stage == flags.StageDeprecated {
+			stage = warnStyle.Render(fmt.Sprintf("%-12s", state.Stage))
+		}
+		fmt.Fprintf(&b, "%s%s %-20s %s %s\n", cursor, toggle, state.Name, stage, dimStyle.Render(string(state.Source)))
+		fmt.Fprintf(&b, "        %s\n", dimStyle.Render(state.Description))
+		if state.Stage == flags.StageDeprecated && state.Deprecation != "" {
+			fmt.Fprintf(&b, "        %s\n", warnStyle.Render(state.Deprecation))
+		}
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • space toggle • ESC to return (changes last for this session;\nset experimental.<flag> in the config or " + flags.EnvPrefix + "<FLAG>=1 to keep them)")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderSubstitutions renders the models used this session instead of the
+// configured ones.
+func (m *Model) renderSubstitutions() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	warnStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("🔀 Model substitutions\n")
+
+	var b strings.Builder
+	subs := m.modelSubstitutions()
+	if len(subs) == 0 {
+		b.WriteString(dimStyle.Render("Every request this session used the configured model"))
+	}
+	for _, sub := range subs {
+		used := sub.Used
+		if sub.Skipped() {
+			used = warnStyle.Render("skipped")
+		}
+		times := ""
+		if sub.Count > 1 {
+			times = fmt.Sprintf(" ×%d", sub.Count)
+		}
+		fmt.Fprintf(&b, "%s %s%s\n", m.theme.Bold.Render(sub.Purpose), dimStyle.Render(sub.Kind), dimStyle.Render(times))
+		fmt.Fprintf(&b, "  %s → %s\n", sub.Configured, used)
+		detail := "since " + sub.First.Format("15:04")
+		if sub.Reason != "" {
+			detail = sub.Reason + " · " + detail
+		}
+		fmt.Fprintf(&b, "  %s\n", dimStyle.Render(detail))
+	}
+
+	hint := dimStyle.Render("\nESC to return")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderPersonas renders the persona picker.
+func (m *Model) renderPersonas() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	currentStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+
+	title := m.theme.Bold.Render("🎭 Persona\n")
+
+	var b strings.Builder
+	list, current := m.personas()
+	for i, p := range list {
+		cursor := "  "
+		if i == m.personaCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		name := fmt.Sprintf("%-10s", p.Name)
+		if p.Name == current {
+			name = currentStyle.Render(name)
+		}
+		length := "no length target"
+		if p.MaxWords > 0 {
+			length = fmt.Sprintf("~%d words", p.MaxWords)
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", cursor, name, dimStyle.Render(length))
+		fmt.Fprintf(&b, "    %s\n", dimStyle.Render(p.Description))
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • enter switch • ESC to return (set layers.main_agent.persona\nin the config to keep it)")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderShells renders the agent's shell sessions.
+func (m *Model) renderShells() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	runningStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("🐚 Shell sessions\n")
+
+	var b strings.Builder
+	sessions := m.shellSessions()
+	if len(sessions) == 0 {
+		b.WriteString(dimStyle.Render("The agent has no shell sessions open"))
+	}
+	for i, s := range sessions {
+		cursor := "  "
+		if i == m.shellCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		state := dimStyle.Render("idle")
+		if s.Command != "" {
+			command, _, multiline := strings.Cut(s.Command, "\n")
+			if runes := []rune(command); len(runes) > 60 {
+				command, multiline = string(runes[:60]), true
+			}
+			if multiline {
+				command += "…"
+			}
+			state = runningStyle.Render("running " + command)
+		}
+		fmt.Fprintf(&b, "%s%s %s %s\n", cursor, m.theme.Bold.Render(s.ID), dimStyle.Render(fmt.Sprintf("%s · pid %d", s.Shell, s.PID)), state)
+		fmt.Fprintf(&b, "    %s\n", dimStyle.Render(fmt.Sprintf("%s · since %s", s.Dir, s.Started.Format("15:04"))))
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • x kill • ESC to return")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderModels renders the model picker with observed streaming performance.
+func (m *Model) renderModels() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	currentStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+
+	title := m.theme.Bold.Render("🧠 Models\n")
+
+	var b strings.Builder
+	if m.modelList == nil {
+		b.WriteString(dimStyle.Render("Loading models..."))
+	} else if len(m.modelList) == 0 {
+		b.WriteString(dimStyle.Render("No models available"))
+	} else {
+		for i, model := range m.modelList {
+			cursor := "  "
+			if i == m.modelCursor {
+				cursor = cursorStyle.Render("> ")
+			}
+			marker := "  "
+			if model.Current {
+				marker = currentStyle.Render("● ")
+			}
+			name := model.Model
+			if len(model.Aliases) > 0 {
+				name += " (" + strings.Join(model.Aliases, ", ") + ")"
+			}
+			fmt.Fprintf(&b, "%s%s%-40s %s\n", cursor, marker, name, dimStyle.Render(formatPerformance(model.PerformanceStats)))
+		}
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • enter switch • ESC to return (averages of recent streaming calls)")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderOptimize renders the context pruning preview.
+func (m *Model) renderOptimize() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+
+	title := m.theme.Bold.Render("✂️  Optimize Context\n")
+
+	var b strings.Builder
+	var hint string
+	if m.optimizePlan == nil || m.optimizePlan.Empty() {
+		b.WriteString(dimStyle.Render("Nothing to prune: the context has no large tool outputs outside the recent messages."))
+		hint = dimStyle.Render("\nESC to return")
+	} else {
+		plan := m.optimizePlan
+		fmt.Fprintf(&b, "%d message(s) will be pruned, freeing ~%d tokens (%d → %d):\n\n",
+			len(plan.Prunes), plan.Saved(), plan.TokensBefore, plan.TokensAfter)
+		for _, p := range plan.Prunes {
+			fmt.Fprintf(&b, "  #%-4d %-20s %s\n", p.Index+1, p.Name,
+				dimStyle.Render(fmt.Sprintf("~%d → ~%d tokens", p.TokensBefore, p.TokensAfter)))
+		}
+		hint = dimStyle.Render("\nenter prune • ESC cancel (pruned tool outputs keep a short excerpt)")
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderContext renders the context inspector: every history item with its
+// provenance, low-trust items highlighted.
+func (m *Model) renderContext() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	lowStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("🔎 Context\n")
+
+	var b strings.Builder
+	if len(m.contextItems) == 0 {
+		b.WriteString(dimStyle.Render("The context is empty"))
+	} else {
+		total, untrusted := 0, 0
+		for _, item := range m.contextItems {
+			total += item.Tokens
+			source := string(item.Provenance.Source)
+			if item.Provenance.Origin != "" {
+				source += " (" + item.Provenance.Origin + ")"
+			}
+			line := fmt.Sprintf("  #%-4d %-10s %-28s %-7s", item.Index+1, item.Role,
+				util.Truncate(source, 28), item.Provenance.Trust)
+			if item.Provenance.Untrusted() {
+				untrusted++
+				line = lowStyle.Render(line)
+			}
+			b.WriteString(line + dimStyle.Render(fmt.Sprintf(" ~%d tokens", item.Tokens)) + "\n")
+		}
+		fmt.Fprintf(&b, "\n%d item(s), ~%d tokens", len(m.contextItems), total)
+		if untrusted > 0 {
+			b.WriteString(lowStyle.Render(fmt.Sprintf(" • %d low-trust: fenced for the model, tool calls after them always ask for permission", untrusted)))
+		}
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		dimStyle.Render("\nESC to return"),
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderRoots renders the directories attached to the session with what
+// each allows.
+func (m *Model) renderRoots() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	nameStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	readOnlyStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("📁 Roots\n")
+
+	var b strings.Builder
+	// Name, path, then permissions (up to 20 columns) and " (primary)"
+	pathWidth := max(m.width-16-2-16-2-2-20-10, 20)
+	for i, root := range m.rootList {
+		access := fmt.Sprintf("%-20s", security.DescribePermissions(root))
+		if root.ReadOnly() {
+			access = readOnlyStyle.Render(access)
+		}
+		primary := ""
+		if i == 0 {
+			primary = dimStyle.Render("(primary)")
+		}
+		fmt.Fprintf(&b, "  %s  %-*s  %s %s\n", nameStyle.Render(fmt.Sprintf("%-16s", util.Truncate(root.Name, 16))),
+			pathWidth, util.TruncateMiddle(root.Path, pathWidth), access, primary)
+	}
+	if len(m.rootList) > 1 {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("\nThe agent refers to files as root:path, e.g. %s:README.md", m.rootList[1].Name)))
+		b.WriteString("\n")
+	}
+	if m.rootsResult != "" {
+		b.WriteString("\n" + m.rootsResult + "\n")
+	}
+
+	hint := dimStyle.Render("\n/roots add [name=]path[:ro] • /roots remove name • ESC to return")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderTrust renders the prompt asking whether to trust the workspace and
+// what each choice allows.
+func (m *Model) renderTrust() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	warnStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("🛡️  Trust this workspace?\n")
+
+	var b strings.Builder
+	if m.trustPath != "" {
+		fmt.Fprintf(&b, "%s\n\n", util.TruncateMiddle(m.trustPath, max(m.width-14, 20)))
+	}
+	if m.trustLevel != security.TrustUnknown {
+		fmt.Fprintf(&b, "Currently %s.\n\n", security.DescribeTrust(m.trustLevel))
+	}
+	b.WriteString(warnStyle.Render("Files in a workspace you open can make the agent run commands or change settings."))
+	b.WriteString("\n\n")
+	b.WriteString("Trusted: files may be changed, commands may run and the project config in .b+/ is honored.\n")
+	b.WriteString("Restricted: files are read only, commands and MCP tools are blocked and .b+/ is ignored.\n\n")
+	b.WriteString(dimStyle.Render("The decision is remembered for this directory and everything inside it; /trust changes it."))
+
+	hint := dimStyle.Render("\nenter/y trust • esc/n restrict")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderSuspended renders the screen shown while an idle session is
+// suspended.
+func (m *Model) renderSuspended() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+
+	title := m.theme.Bold.Render("⏸  Suspended\n")
+
+	var b strings.Builder
+	b.WriteString("b+ was idle, so it unloaded the local model and released the resources it held.\n")
+	b.WriteString(dimStyle.Render("The model is loaded again when you resume."))
+
+	hint := dimStyle.Render("\nsuspended — press any key to resume")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderPull renders the offer to pull a model that isn't installed.
+func (m *Model) renderPull() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+
+	title := m.theme.Bold.Render("⬇  Model not installed\n")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s is not installed yet.\n\n", m.pullOffer)
+	b.WriteString("Pull it now? The download runs in the background and the status bar shows its progress.\n")
+	b.WriteString(dimStyle.Render("Models can be several gigabytes; /pull starts it later."))
+
+	hint := dimStyle.Render("\nenter/y pull • esc/n not now")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderFinish renders the work of the worktree, the outcome of testing it
+// merged into the base branch and what can be done with it.
+func (m *Model) renderFinish() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	okStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	failStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+
+	title := m.theme.Bold.Render("🏁 Finish worktree\n")
+
+	var b strings.Builder
+	w, c := m.finishTree, m.finishChanges
+	if w != nil && c != nil {
+		fmt.Fprintf(&b, "%s → %s\n\n", w.Branch, w.Base)
+		if c.Empty() {
+			b.WriteString(dimStyle.Render("No changes against " + w.Base + "."))
+			b.WriteString("\n")
+		} else {
+			fmt.Fprintf(&b, "%d files changed, +%d −%d\n", c.Files, c.Insertions, c.Deletions)
+			b.WriteString(c.Stat)
+			b.WriteString("\n\n")
+
+			// The diff gets what the rest of the view leaves of the screen
+			lines := strings.Split(c.Diff, "\n")
+			limit := max(m.height-strings.Count(c.Stat, "\n")-24, 5)
+			if len(lines) > limit {
+				b.WriteString(strings.Join(lines[:limit], "\n"))
+				b.WriteString(dimStyle.Render(fmt.Sprintf("\n… %d more lines", len(lines)-limit)))
+			} else {
+				b.WriteString(c.Diff)
+			}
+			b.WriteString("\n\n")
+		}
+
+		switch t := m.finishTest; {
+		case t == nil:
+		case t.Conflict:
+			b.WriteString(failStyle.Render("✗ The changes conflict with " + w.Base))
+			b.WriteString("\n" + dimStyle.Render(t.Output) + "\n")
+		case t.Passed:
+			b.WriteString(okStyle.Render(fmt.Sprintf("✓ %s passed on the merge result (%s)", t.Command, t.Duration.Round(time.Second))))
+			b.WriteString("\n")
+		default:
+			b.WriteString(failStyle.Render(fmt.Sprintf("✗ %s failed on the merge result", t.Command)))
+			output := strings.Split(strings.TrimRight(t.Output, "\n"), "\n")
+			b.WriteString("\n" + dimStyle.Render(strings.Join(output[max(len(output)-8, 0):], "\n")) + "\n")
+		}
+	}
+	if m.finishBusy != "" {
+		b.WriteString(dimStyle.Render(m.finishBusy))
+		b.WriteString("\n")
+	}
+	if m.finishResult != "" {
+		b.WriteString(m.finishResult)
+		b.WriteString("\n")
+	}
+
+	hint := dimStyle.Render("\ns squash-merge • p push branch • d discard • r re-run tests • esc back")
+	if m.finishDiscarding {
+		hint = failStyle.Render("\nPress d again to delete the worktree and its branch; anything else keeps them")
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderRedact renders what a redaction would change, awaiting confirmation.
+func (m *Model) renderRedact() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	warnStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("✂️  Redact\n")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", util.TruncateMiddle(m.redactTarget, max(m.width-14, 20)))
+	fmt.Fprintf(&b, "%d stored message(s) and %d context message(s) will be replaced with %s.\n",
+		m.redactStored, m.redactContext, models.RedactionPlaceholder)
+	b.WriteString("Search results and exports will no longer contain the content.\n")
+	if !strings.HasPrefix(m.redactTarget, "#") {
+		b.WriteString("Matches are also scrubbed from every prompt sent for the rest of the session.\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(warnStyle.Render("Redaction cannot be undone."))
+
+	hint := dimStyle.Render("\nenter redact • esc cancel")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderProviders renders the latest connection test of every configured
+// provider: its status, latency, authentication state and error.
+func (m *Model) renderProviders() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	okStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	failStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+
+	title := m.theme.Bold.Render("🩺 Providers\n")
+
+	var b strings.Builder
+	switch {
+	case len(m.providerHealth) == 0 && m.providersChecking:
+		b.WriteString(dimStyle.Render("Testing providers..."))
+		b.WriteString("\n")
+	case len(m.providerHealth) == 0:
+		b.WriteString(dimStyle.Render("No providers have been tested yet."))
+		b.WriteString("\n")
+	default:
+		// Name, status, latency and auth take 46 columns; the error gets
+		// what is left, less room for Truncate's ellipsis
+		errWidth := max(m.width-14-46-3, 10)
+		fmt.Fprintf(&b, "  %-12s  %-6s  %10s  %-8s  %s\n", "PROVIDER", "STATUS", "LATENCY", "AUTH", "LAST ERROR")
+		for _, h := range m.providerHealth {
+			status, latency, lastErr := okStyle.Render("ok    "), fmt.Sprintf("%10s", "-"), ""
+			if !h.Healthy() {
+				status = failStyle.Render("down  ")
+				lastErr = util.Truncate(h.Err.Error(), errWidth)
+			}
+			if h.Latency > 0 {
+				latency = fmt.Sprintf("%10s", h.Latency.Round(time.Millisecond))
+			}
+			fmt.Fprintf(&b, "  %-12s  %s  %s  %-8s  %s\n", util.Truncate(h.Provider, 9), status, latency, h.Auth, lastErr)
+			if h.Load != nil {
+				// Self-hosted servers that report it show how busy they are
+				b.WriteString(dimStyle.Render(fmt.Sprintf("  %-12s  load: %s", "", h.Load)))
+				b.WriteString("\n")
+			}
+		}
+		if checked := m.providerHealth[0].CheckedAt; !checked.IsZero() {
+			b.WriteString(dimStyle.Render(fmt.Sprintf("\nTested at %s", checked.Format("15:04:05"))))
+			b.WriteString("\n")
+		}
+	}
+	if m.providersChecking && len(m.providerHealth) > 0 {
+		b.WriteString(dimStyle.Render("Re-testing..."))
+		b.WriteString("\n")
+	}
+
+	hint := dimStyle.Render("\nr to re-test • ESC to return")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// configRows returns how many configuration values fit in the config view.
+func (m *Model) configRows() int {
+	return max(m.height-14, 5)
+}
+
+// renderConfig renders the effective configuration, one value per row with
+// the source that set it.
+func (m *Model) renderConfig() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	overrideStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("⚙  Config\n")
+
+	var b strings.Builder
+	if len(m.configSettings) == 0 {
+		b.WriteString(dimStyle.Render("No configuration loaded"))
+	} else {
+		rows := m.configRows()
+		start := min(max(m.configCursor-rows/2, 0), max(len(m.configSettings)-rows, 0))
+		end := min(start+rows, len(m.configSettings))
+		// Columns share the box's inner width: borders and padding take 16
+		inner := max(m.width-16, 30)
+		keyWidth := min(inner*2/5, 44)
+		valueWidth := min(inner/4, 36)
+		sourceWidth := inner - keyWidth - valueWidth - 4
+		for i := start; i < end; i++ {
+			s := m.configSettings[i]
+			cursor := "  "
+			if i == m.configCursor {
+				cursor = cursorStyle.Render("> ")
+			}
+			source := util.TruncateMiddle(describeSource(s), sourceWidth)
+			if s.Source == config.SourceDefault {
+				source = dimStyle.Render(source)
+			} else {
+				source = overrideStyle.Render(source)
+			}
+			fmt.Fprintf(&b, "%s%-*s %-*s %s\n", cursor,
+				keyWidth, util.TruncateMiddle(s.Key, keyWidth),
+				valueWidth, util.TruncateMiddle(s.Value, valueWidth), source)
+		}
+		b.WriteString(dimStyle.Render(fmt.Sprintf("\n%d-%d of %d values", start+1, end, len(m.configSettings))))
+	}
+	if m.configResult != "" {
+		b.WriteString("\n" + m.configResult)
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • pgup/pgdn scroll • e edit owning file • ESC to return")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderPalette renders the command palette: the query and the best
+// matches among commands, settings actions, sessions and files.
+func (m *Model) renderPalette() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+
+	title := m.theme.Bold.Render("⌘ Command Palette\n")
+	query := cursorStyle.Render("> ") + m.paletteQuery + cursorStyle.Render("▏")
+
+	// Titles and details share the box's width within its padding
+	width := max(m.width-10-4, 24)
+	titleWidth := min(width/2, 40)
+
+	var b strings.Builder
+	matches := m.paletteMatches()
+	if len(matches) == 0 {
+		b.WriteString(dimStyle.Render("No matches"))
+	}
+
+	// Scroll so the selection stays in view
+	start := max(m.paletteCursor-paletteRows+1, 0)
+	for i := start; i < len(matches) && i < start+paletteRows; i++ {
+		item := matches[i]
+		cursor := "  "
+		if i == m.paletteCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		// Leave room for the cursor, the kind and the truncation marker
+		detail := util.Truncate(item.Detail, max(width-titleWidth-15, 0))
+		fmt.Fprintf(&b, "%s%-*s %s\n", cursor, titleWidth, util.TruncateMiddle(item.Title, titleWidth),
+			dimStyle.Render(fmt.Sprintf("%-8s %s", item.Kind, detail)))
+	}
+	if n := len(matches); n > paletteRows {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("%d of %d matches", min(paletteRows, n), n)))
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • enter run • ESC close (/runs 12 runs a command with arguments)")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		query+"\n",
+		strings.TrimRight(b.String(), "\n"),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// renderRuns renders the project's command run history.
+func (m *Model) renderRuns() string {
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+	cursorStyle := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	okStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	failStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+	favoriteStyle := lipgloss.NewStyle().Foreground(m.theme.Warning)
+
+	title := m.theme.Bold.Render("⏱  Runs\n")
+
+	var b strings.Builder
+	if m.runList == nil {
+		b.WriteString(dimStyle.Render("Loading history..."))
+	} else if len(m.runList) == 0 {
+		b.WriteString(dimStyle.Render("No commands have been run in this project yet"))
+	} else {
+		for i, run := range m.runList {
+			cursor := "  "
+			if i == m.runCursor {
+				cursor = cursorStyle.Render("> ")
+			}
+			favorite := "  "
+			if run.Favorite {
+				favorite = favoriteStyle.Render("★ ")
+			}
+			status := okStyle.Render(fmt.Sprintf("%4d", run.ExitCode))
+			if run.ExitCode != 0 {
+				status = failStyle.Render(fmt.Sprintf("%4d", run.ExitCode))
+			}
+			fmt.Fprintf(&b, "%s%s#%-5d %s  %-50s %s\n", cursor, favorite, run.ID, status, util.Truncate(run.Command, 50),
+				dimStyle.Render(run.Timestamp.Format("Jan 2 15:04")+" · "+run.Duration.Round(time.Millisecond).String()))
+		}
+	}
+	if m.runResult != "" {
+		b.WriteString("\n" + m.runResult + "\n")
+	}
+
+	hint := dimStyle.Render("\n↑/↓ select • enter re-run • f favorite • ESC to return (or ask the agent to \"re-run #n\")")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		b.String(),
+		hint,
+	)
+
+	box := lipgloss.NewStyle().
+		Width(m.width-10).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+// formatPerformance formats rolling stream averages for the model picker.
+func formatPerformance(stats models.PerformanceStats) string {
+	if stats.Samples == 0 {
+		return "no data"
+	}
+	return fmt.Sprintf("TTFT %dms · %.1f tok/s (n=%d)",
+		stats.AvgTimeToFirstToken.Milliseconds(), stats.AvgTokensPerSecond, stats.Samples)
+}
+
+// formatTokens abbreviates a token count, e.g. 12.3M or 45k.
+func formatTokens(n int) string {
+	switch {
+	case n >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	case n >= 1000:
+		return fmt.Sprintf("%dk", n/1000)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
 // renderError renders an error screen.
 func (m *Model) renderError(err error) string {
-	errorText := fmt.Sprintf("❌ Error: %s", err.Error())
+	errorText := fmt.Sprintf("❌ Error: %s", errorMessage(err))
 	errorStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
 	styled := errorStyle.Render(errorText)
 
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abrksh22/bplus/internal/worktree"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// worktreeFinisher is implemented by applications that can finish a
+// session run in a linked git worktree.
+type worktreeFinisher interface {
+	Worktree(ctx context.Context) (*worktree.Worktree, *worktree.Changes, error)
+	TestCommand(w *worktree.Worktree) string
+	TestMerge(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) (*worktree.TestResult, error)
+	SquashMerge(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) error
+	PushWorktree(ctx context.Context, w *worktree.Worktree, c *worktree.Changes) error
+	DiscardWorktree(ctx context.Context, w *worktree.Worktree) error
+}
+
+// finishAction is what the finish view does with the work.
+type finishAction string
+
+const (
+	finishMerge   finishAction = "merge"
+	finishPush    finishAction = "push"
+	finishDiscard finishAction = "discard"
+)
+
+// showFinish opens the finish view and reads the work done in the
+// worktree.
+func (m *Model) showFinish() tea.Cmd {
+	app, ok := m.app.(worktreeFinisher)
+	if !ok {
+		m.SetError(fmt.Errorf("worktree merging is not available"))
+		return nil
+	}
+	m.finishTree = nil
+	m.finishChanges = nil
+	m.finishTest = nil
+	m.finishResult = ""
+	m.finishDiscarding = false
+	m.finishBusy = "Reading changes…"
+	m.view = ViewFinish
+	return func() tea.Msg {
+		w, c, err := app.Worktree(context.Background())
+		return WorktreeMsg{Tree: w, Changes: c, Err: err}
+	}
+}
+
+// testMerge runs the tests on the merge result in the background.
+func (m *Model) testMerge() tea.Cmd {
+	app, ok := m.app.(worktreeFinisher)
+	if !ok || m.finishChanges == nil || m.finishChanges.Empty() || app.TestCommand(m.finishTree) == "" {
+		return nil
+	}
+	m.finishTest = nil
+	m.finishBusy = "Running " + app.TestCommand(m.finishTree) + " on the merge result…"
+	w, c := m.finishTree, m.finishChanges
+	return func() tea.Msg {
+		result, err := app.TestMerge(context.Background(), w, c)
+		return WorktreeTestedMsg{Result: result, Err: err}
+	}
+}
+
+// finish merges, pushes or discards the work in the background.
+func (m *Model) finish(action finishAction) tea.Cmd {
+	app, ok := m.app.(worktreeFinisher)
+	if !ok || m.finishTree == nil || m.finishBusy != "" {
+		return nil
+	}
+	w, c := m.finishTree, m.finishChanges
+	var run func(ctx context.Context) error
+	switch action {
+	case finishMerge:
+		if c.Empty() {
+			return nil
+		}
+		m.finishBusy = fmt.Sprintf("Squash-merging into %s…", w.Base)
+		run = func(ctx context.Context) error { return app.SquashMerge(ctx, w, c) }
+	case finishPush:
+		m.finishBusy = fmt.Sprintf("Pushing %s…", w.Branch)
+		run = func(ctx context.Context) error { return app.PushWorktree(ctx, w, c) }
+	case finishDiscard:
+		m.finishBusy = "Discarding the worktree…"
+		run = func(ctx context.Context) error { return app.DiscardWorktree(ctx, w) }
+	}
+	m.finishResult = ""
+	return func() tea.Msg {
+		return WorktreeFinishedMsg{Action: action, Err: run(context.Background())}
+	}
+}
+
+// handleWorktree shows the work done and starts testing it.
+func (m *Model) handleWorktree(msg WorktreeMsg) (tea.Model, tea.Cmd) {
+	m.finishBusy = ""
+	if msg.Err != nil {
+		m.view = ViewChat
+		m.SetError(msg.Err)
+		return m, nil
+	}
+	m.finishTree = msg.Tree
+	m.finishChanges = msg.Changes
+	return m, m.testMerge()
+}
+
+// handleWorktreeTested records the outcome of the tests.
+func (m *Model) handleWorktreeTested(msg WorktreeTestedMsg) (tea.Model, tea.Cmd) {
+	m.finishBusy = ""
+	if msg.Err != nil {
+		m.finishResult = "Tests could not run: " + msg.Err.Error()
+		return m, nil
+	}
+	m.finishTest = msg.Result
+	return m, nil
+}
+
+// handleWorktreeFinished reports the outcome of merging, pushing or
+// discarding. A discarded worktree ends the session, as its directory is
+// gone.
+func (m *Model) handleWorktreeFinished(msg WorktreeFinishedMsg) (tea.Model, tea.Cmd) {
+	m.finishBusy = ""
+	if msg.Err != nil {
+		m.finishResult = msg.Err.Error()
+		return m, nil
+	}
+	w := m.finishTree
+	switch msg.Action {
+	case finishMerge:
+		m.finishResult = fmt.Sprintf("✓ Squash-merged into %s. Press d to remove the worktree.", w.Base)
+	case finishPush:
+		m.finishResult = fmt.Sprintf("✓ Pushed %s.", w.Branch)
+	case finishDiscard:
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}